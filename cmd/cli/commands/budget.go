@@ -5,12 +5,17 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 
 	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/integrations/budgetimport"
 )
 
 // NewBudgetCommand creates a new budget management command
@@ -26,6 +31,7 @@ func NewBudgetCommand(ctx context.Context) *cobra.Command {
 	cmd.AddCommand(newBudgetResetCommand(ctx))
 	cmd.AddCommand(newBudgetUsageCommand(ctx))
 	cmd.AddCommand(newBudgetReportCommand(ctx))
+	cmd.AddCommand(newBudgetImportCommand(ctx))
 
 	return cmd
 }
@@ -195,6 +201,71 @@ func newBudgetReportCommand(ctx context.Context) *cobra.Command {
 	return cmd
 }
 
+func newBudgetImportCommand(ctx context.Context) *cobra.Command {
+	var format string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import budgets from a CSV or JSON file",
+		Long: `Set the budgets of teams, users and keys in bulk, for example when migrating
+from another gateway. CSV files need a header row with entity_type, entity,
+max_budget and optionally budget_duration columns; JSON files hold an array of
+objects with the same fields. Entities are named by ID, team name, user email
+or key name. Every row is validated first and nothing is imported if any fails.
+Use --dry-run to review the changes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			if format == "" {
+				switch strings.ToLower(filepath.Ext(args[0])) {
+				case ".csv":
+					format = "csv"
+				case ".json":
+					format = "json"
+				}
+			}
+			rows, err := budgetimport.Parse(data, format)
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				return fmt.Errorf("no budgets found in %s", args[0])
+			}
+
+			var report *budgetimport.Report
+			if IsDirectDBAccess() {
+				report, err = budgetimport.Import(db, rows, budgetimport.Options{DryRun: dryRun})
+			} else if IsAPIAccess() {
+				report, err = importBudgetsAPI(ctx, rows, dryRun)
+			} else {
+				return fmt.Errorf("no database or API access configured")
+			}
+			if err != nil {
+				return err
+			}
+
+			if outputJSON {
+				OutputJSON(report)
+			} else {
+				fmt.Print(report.String())
+			}
+			if report.Failed > 0 {
+				return fmt.Errorf("%d of %d rows failed validation", report.Failed, len(rows))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "File format (csv, json); detected from the file when omitted")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate and report the changes without importing")
+
+	return cmd
+}
+
 // Database implementations
 func showGlobalBudgetStatusDB(ctx context.Context) error {
 	var userCount, teamCount, keyCount int64
@@ -692,6 +763,28 @@ func showBudgetUsageAPI(ctx context.Context, userID, teamID, keyID string, days
 	return nil
 }
 
+func importBudgetsAPI(ctx context.Context, rows []budgetimport.Row, dryRun bool) (*budgetimport.Report, error) {
+	endpoint := "/api/admin/budgets/import?dry_run=" + strconv.FormatBool(dryRun)
+	resp, err := APIRequest("POST", endpoint, map[string]interface{}{"budgets": rows})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// Failed validations come back as 400 with the report
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+	var report budgetimport.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if report.Results == nil {
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+	return &report, nil
+}
+
 func generateBudgetReportAPI(ctx context.Context, period string) error {
 	endpoint := fmt.Sprintf("/api/budget/report?period=%s", period)
 
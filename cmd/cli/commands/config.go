@@ -155,9 +155,13 @@ func NewConfigCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "config",
 		Short: "Manage CLI configuration",
-		Long:  "Configure the pLLM CLI for database or API access",
+		Long:  "Configure the pLLM CLI for database or API access, and promote gateway state between environments",
 	}
 
+	cmd.AddCommand(newConfigExportCommand())
+	cmd.AddCommand(newConfigDiffCommand())
+	cmd.AddCommand(newConfigImportCommand())
+
 	cmd.AddCommand(&cobra.Command{
 		Use:   "show",
 		Short: "Show current configuration",
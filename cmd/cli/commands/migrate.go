@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/amerfu/pllm/internal/core/database"
+	"github.com/amerfu/pllm/internal/services/integrations/litellm"
+	"github.com/amerfu/pllm/internal/services/integrations/promotion"
+)
+
+// schemaAnnotation marks the commands that change the database schema,
+// which run against databases with pending migrations
+const schemaAnnotation = "pllm/manages-schema"
+
+// ManagesSchema reports whether cmd changes the database schema, so it must
+// not require an up-to-date one
+func ManagesSchema(cmd *cobra.Command) bool {
+	_, ok := cmd.Annotations[schemaAnnotation]
+	return ok
+}
+
+// NewMigrateCommand creates the command that migrates the database schema
+// and imports configuration from other gateways
+func NewMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the database schema and import configuration from other gateways",
+	}
+
+	cmd.AddCommand(newMigrateUpCommand())
+	cmd.AddCommand(newMigrateDownCommand())
+	cmd.AddCommand(newMigrateStatusCommand())
+	cmd.AddCommand(newMigrateFromLiteLLMCommand())
+
+	return cmd
+}
+
+func newMigrateUpCommand() *cobra.Command {
+	var version uint
+
+	cmd := &cobra.Command{
+		Use:         "up",
+		Short:       "Apply pending database migrations",
+		Annotations: map[string]string{schemaAnnotation: ""},
+		Example: `  pllm migrate up --db-url $DATABASE_URL
+  pllm migrate up --to 3 --db-url $DATABASE_URL`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !IsDirectDBAccess() {
+				return fmt.Errorf("migrations require direct database access (--db-url)")
+			}
+			applied, err := database.MigrateUp(db, version)
+			if outputJSON {
+				OutputJSON(map[string]interface{}{"applied": migrationVersions(applied)})
+			} else {
+				for _, m := range applied {
+					fmt.Printf("Applied %d: %s\n", m.Version, m.Description)
+				}
+				if err == nil && len(applied) == 0 {
+					fmt.Println("The database schema is up to date.")
+				}
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().UintVar(&version, "to", 0, "Stop after this migration version (default all)")
+
+	return cmd
+}
+
+func newMigrateDownCommand() *cobra.Command {
+	var steps int
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the most recent database migrations",
+		Long: `Revert the most recent database migrations, newest first. Reverting
+a migration can drop tables and their data; reverting the baseline drops
+every table.`,
+		Annotations: map[string]string{schemaAnnotation: ""},
+		Example:     `  pllm migrate down --steps 1 --yes --db-url $DATABASE_URL`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !IsDirectDBAccess() {
+				return fmt.Errorf("migrations require direct database access (--db-url)")
+			}
+			if steps < 1 {
+				return fmt.Errorf("--steps must be at least 1")
+			}
+			if !yes {
+				return fmt.Errorf("reverting migrations can delete data; pass --yes to confirm")
+			}
+			reverted, err := database.MigrateDown(db, steps)
+			if outputJSON {
+				OutputJSON(map[string]interface{}{"reverted": migrationVersions(reverted)})
+			} else {
+				for _, m := range reverted {
+					fmt.Printf("Reverted %d: %s\n", m.Version, m.Description)
+				}
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().IntVar(&steps, "steps", 1, "Number of migrations to revert")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Confirm reverting migrations")
+
+	return cmd
+}
+
+func newMigrateStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:         "status",
+		Short:       "List database migrations and whether they were applied",
+		Annotations: map[string]string{schemaAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !IsDirectDBAccess() {
+				return fmt.Errorf("migrations require direct database access (--db-url)")
+			}
+			status, err := database.GetMigrationStatus(db)
+			if err != nil {
+				return err
+			}
+
+			if outputJSON {
+				OutputJSON(status)
+				return nil
+			}
+			rows := make([][]string, 0, len(status))
+			pending := 0
+			for _, s := range status {
+				applied := "pending"
+				if s.Applied() {
+					applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+				} else {
+					pending++
+				}
+				rows = append(rows, []string{fmt.Sprint(s.Version), s.Description, applied})
+			}
+			OutputTable([]string{"VERSION", "DESCRIPTION", "APPLIED"}, rows)
+			fmt.Printf("\n%d pending\n", pending)
+			return nil
+		},
+	}
+}
+
+// migrationVersions returns the versions of migrations
+func migrationVersions(migrations []database.Migration) []uint {
+	versions := make([]uint, 0, len(migrations))
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	return versions
+}
+
+func newMigrateFromLiteLLMCommand() *cobra.Command {
+	var source, output, bundleOutput string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "from-litellm",
+		Short: "Convert a LiteLLM proxy config into pllm configuration",
+		Long: `Convert a LiteLLM proxy config.yaml: model_list and router settings are
+written as a pllm config.yaml, and teams and budgets are created in the
+database (with --db-url) or written as a bundle for "config import".
+Settings pllm cannot express are listed instead of failing the import.`,
+		Example: `  pllm migrate from-litellm --config litellm.yaml -o config.yaml --db-url $DATABASE_URL
+  pllm migrate from-litellm --config litellm.yaml -o config.yaml --bundle litellm-teams.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(source)
+			if err != nil {
+				return fmt.Errorf("failed to read LiteLLM config: %w", err)
+			}
+			cfg, err := litellm.Parse(data)
+			if err != nil {
+				return err
+			}
+			result := litellm.Convert(cfg)
+
+			configData, err := result.EncodeConfig(source)
+			if err != nil {
+				return err
+			}
+			if err := writeOutput(output, configData); err != nil {
+				return err
+			}
+
+			var plan *promotion.Plan
+			hasRecords := len(result.Bundle.Teams) > 0 || len(result.Bundle.Budgets) > 0
+			if hasRecords && bundleOutput != "" {
+				bundleData, err := result.Bundle.Encode()
+				if err != nil {
+					return err
+				}
+				if err := os.WriteFile(bundleOutput, bundleData, 0600); err != nil {
+					return fmt.Errorf("failed to write bundle: %w", err)
+				}
+			}
+			if hasRecords && IsDirectDBAccess() {
+				plan, err = promotion.Apply(db, result.Bundle, promotion.ApplyOptions{DryRun: dryRun})
+				if err != nil {
+					return err
+				}
+			}
+
+			if outputJSON {
+				OutputJSON(map[string]interface{}{
+					"findings": result.Findings,
+					"teams":    result.Bundle.Teams,
+					"budgets":  result.Bundle.Budgets,
+					"plan":     plan,
+				})
+				return nil
+			}
+
+			fmt.Fprint(os.Stderr, result.String())
+			switch {
+			case plan != nil:
+				fmt.Fprint(os.Stderr, "\n"+plan.String())
+				if !dryRun && !plan.IsEmpty() {
+					fmt.Fprintf(os.Stderr, "Applied %d changes.\n", len(plan.Changes))
+				}
+			case hasRecords && bundleOutput == "":
+				fmt.Fprintln(os.Stderr, "\nTeams and budgets were not imported: pass --db-url, or --bundle to write them for \"pllm config import\".")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&source, "config", "", "LiteLLM proxy config file")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output pllm config file (default stdout)")
+	cmd.Flags().StringVar(&bundleOutput, "bundle", "", "Also write teams and budgets as a bundle for \"config import\"")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the database changes without applying them")
+	_ = cmd.MarkFlagRequired("config")
+
+	return cmd
+}
+
+// writeOutput writes data to path, or stdout when path is empty or "-"
+func writeOutput(path string, data []byte) error {
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
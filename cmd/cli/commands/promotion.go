@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/amerfu/pllm/internal/services/integrations/promotion"
+)
+
+func newConfigExportCommand() *cobra.Command {
+	var output string
+	var includeSecrets bool
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export runtime-managed state as a YAML bundle",
+		Long: `Snapshot user models, routes, teams (with model aliases) and budget policies
+as a YAML bundle that can be applied to another environment with "config import".
+Literal provider credentials are redacted unless --include-secrets is set.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !IsDirectDBAccess() {
+				return fmt.Errorf("config export requires database access (--db-url)")
+			}
+
+			bundle, err := promotion.Export(db, promotion.ExportOptions{IncludeSecrets: includeSecrets})
+			if err != nil {
+				return err
+			}
+			data, err := bundle.Encode()
+			if err != nil {
+				return err
+			}
+
+			if output == "" || output == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := os.WriteFile(output, data, 0600); err != nil {
+				return fmt.Errorf("failed to write bundle: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Exported %d models, %d routes, %d teams and %d budgets to %s\n",
+				len(bundle.Models), len(bundle.Routes), len(bundle.Teams), len(bundle.Budgets), output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file (default stdout)")
+	cmd.Flags().BoolVar(&includeSecrets, "include-secrets", false, "Include literal provider credentials")
+
+	return cmd
+}
+
+func newConfigDiffCommand() *cobra.Command {
+	var prune bool
+
+	cmd := &cobra.Command{
+		Use:   "diff <bundle.yaml>",
+		Short: "Show the changes importing a bundle would make",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := applyBundle(args[0], promotion.ApplyOptions{Prune: prune, DryRun: true})
+			if err != nil {
+				return err
+			}
+			outputPlan(plan)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&prune, "prune", false, "Also plan deletion of models, routes and budgets missing from the bundle")
+
+	return cmd
+}
+
+func newConfigImportCommand() *cobra.Command {
+	var prune, dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import <bundle.yaml>",
+		Short: "Apply a YAML bundle to this environment",
+		Long: `Create or update the models, routes, teams and budget policies of a bundle
+in one transaction. Redacted credentials keep the values already configured
+in this environment. Use --dry-run (or "config diff") to review the plan first.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := applyBundle(args[0], promotion.ApplyOptions{Prune: prune, DryRun: dryRun})
+			if err != nil {
+				return err
+			}
+			outputPlan(plan)
+			if !dryRun && !plan.IsEmpty() && !outputJSON {
+				fmt.Printf("Applied %d changes.\n", len(plan.Changes))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete models, routes and budgets missing from the bundle")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the plan without applying it")
+
+	return cmd
+}
+
+func applyBundle(path string, opts promotion.ApplyOptions) (*promotion.Plan, error) {
+	if !IsDirectDBAccess() {
+		return nil, fmt.Errorf("config diff and import require database access (--db-url)")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	bundle, err := promotion.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return promotion.Apply(db, bundle, opts)
+}
+
+func outputPlan(plan *promotion.Plan) {
+	if outputJSON {
+		OutputJSON(plan)
+		return
+	}
+	fmt.Print(plan.String())
+}
@@ -0,0 +1,595 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+	"gorm.io/gorm"
+)
+
+// ShellOptions wires the shell to the CLI's command tree
+type ShellOptions struct {
+	// NewRoot builds a fresh command tree; one is built per command line so
+	// that flag values never leak from one command into the next
+	NewRoot func() *cobra.Command
+	// ConnectDB opens a database for "use db <url>"
+	ConnectDB func(url string) (*gorm.DB, error)
+}
+
+// shellBuiltins are the commands handled by the shell itself
+var shellBuiltins = map[string]string{
+	"help":    "Show this help",
+	"exit":    "Leave the shell (also quit or Ctrl-D)",
+	"quit":    "Leave the shell",
+	"use":     "Switch connection: use db [url] | use api [url] [key]",
+	"context": "Show the active connection",
+	"set":     "Set session output: set json|verbose on|off",
+	"models":  "List the models served by the gateway",
+	"chat":    "Chat with a model through the gateway: chat <model> [message]",
+}
+
+// shell is an interactive session. It remembers both connections so that
+// "use" can switch between them without reconnecting.
+type shell struct {
+	opts    ShellOptions
+	out     io.Writer
+	term    *term.Terminal
+	db      *gorm.DB
+	mode    string // "db" or "api"
+	json    bool
+	verbose bool
+	models  []string // gateway models, fetched on first completion
+}
+
+// NewShellCommand creates the interactive shell command
+func NewShellCommand(opts ShellOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell",
+		Short: "Start an interactive shell",
+		Long: `Start an interactive shell that runs pllm commands without the "pllm" prefix,
+with tab completion for commands and flags. "use db" and "use api" switch
+between the database and API connections, and "chat <model>" sends test
+prompts through the gateway.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := &shell{
+				opts:    opts,
+				out:     os.Stdout,
+				db:      db,
+				json:    outputJSON,
+				verbose: verbose,
+			}
+			s.mode = "api"
+			if IsDirectDBAccess() {
+				s.mode = "db"
+			}
+			return s.run(os.Stdin)
+		},
+	}
+}
+
+func (s *shell) run(in *os.File) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		// Piped input: run commands line by line, without prompts
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if s.exec(scanner.Text()) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	s.term = term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, os.Stdout}, "")
+	s.term.AutoCompleteCallback = s.autoComplete
+	fmt.Fprintln(s.out, `pLLM interactive shell. Type "help" for commands, Tab to complete, Ctrl-D to exit.`)
+
+	for {
+		s.term.SetPrompt(s.prompt())
+		line, err := s.readLine(fd)
+		if err == io.EOF {
+			fmt.Fprintln(s.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if s.exec(line) {
+			return nil
+		}
+	}
+}
+
+// readLine reads a line in raw mode. The terminal is restored while commands
+// run, so that their output is not mangled.
+func (s *shell) readLine(fd int) (string, error) {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+	return s.term.ReadLine()
+}
+
+func (s *shell) prompt() string {
+	return fmt.Sprintf("pllm(%s)> ", s.mode)
+}
+
+// exec runs one command line and reports whether the shell should exit
+func (s *shell) exec(line string) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		fmt.Fprintf(s.out, "Error: %v\n", err)
+		return false
+	}
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return true
+	case "help", "?":
+		s.help()
+	case "use":
+		err = s.use(args[1:])
+	case "context":
+		s.showContext()
+	case "set":
+		err = s.set(args[1:])
+	case "models":
+		err = s.listModels()
+	case "chat":
+		err = s.chat(args[1:])
+	case "shell":
+		err = fmt.Errorf("already in the shell")
+	default:
+		err = s.runCommand(args)
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "Error: %v\n", err)
+	}
+	return false
+}
+
+// runCommand runs a CLI command on a fresh command tree with the session's
+// output settings
+func (s *shell) runCommand(args []string) error {
+	root := s.opts.NewRoot()
+	if s.json {
+		args = append(args, "--json")
+	}
+	if s.verbose {
+		args = append(args, "--verbose")
+	}
+	root.SetArgs(args)
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+	return root.Execute()
+}
+
+func (s *shell) help() {
+	fmt.Fprintln(s.out, "Shell commands:")
+	names := make([]string, 0, len(shellBuiltins))
+	for name := range shellBuiltins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "  %-10s %s\n", name, shellBuiltins[name])
+	}
+
+	fmt.Fprintln(s.out, "\nCLI commands (run \"<command> --help\" for details):")
+	for _, cmd := range s.opts.NewRoot().Commands() {
+		if cmd.Name() == "shell" || cmd.Hidden || !cmd.IsAvailableCommand() {
+			continue
+		}
+		fmt.Fprintf(s.out, "  %-10s %s\n", cmd.Name(), cmd.Short)
+	}
+}
+
+// use switches between the database and API connections, optionally
+// connecting to a new one
+func (s *shell) use(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: use db [url] | use api [url] [key]")
+	}
+
+	switch args[0] {
+	case "db":
+		if len(args) > 1 {
+			if s.opts.ConnectDB == nil {
+				return fmt.Errorf("database connections are not supported")
+			}
+			conn, err := s.opts.ConnectDB(args[1])
+			if err != nil {
+				return err
+			}
+			s.db = conn
+		}
+		if s.db == nil {
+			return fmt.Errorf("no database connection; use db <url>")
+		}
+		SetDB(s.db)
+		s.mode = "db"
+	case "api":
+		url, key := apiURL, apiKey
+		if len(args) > 1 {
+			url = strings.TrimSuffix(args[1], "/")
+		}
+		if len(args) > 2 {
+			key = args[2]
+		}
+		if url == "" || key == "" {
+			return fmt.Errorf("no API connection; use api <url> <key>")
+		}
+		SetAPIConfig(url, key)
+		// Commands prefer the database, so it is detached while the API is
+		// in use; "use db" attaches it again
+		SetDB(nil)
+		s.mode = "api"
+		s.models = nil
+	default:
+		return fmt.Errorf("unknown connection %q; use db or api", args[0])
+	}
+
+	s.showContext()
+	return nil
+}
+
+func (s *shell) showContext() {
+	fmt.Fprintf(s.out, "Active: %s\n", s.mode)
+	if s.db != nil {
+		fmt.Fprintln(s.out, "Database: connected")
+	} else {
+		fmt.Fprintln(s.out, "Database: not connected")
+	}
+	if IsAPIAccess() {
+		fmt.Fprintf(s.out, "API: %s\n", apiURL)
+	} else {
+		fmt.Fprintln(s.out, "API: not configured")
+	}
+	fmt.Fprintf(s.out, "JSON output: %v, verbose: %v\n", s.json, s.verbose)
+}
+
+func (s *shell) set(args []string) error {
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		return fmt.Errorf("usage: set json|verbose on|off")
+	}
+	on := args[1] == "on"
+	switch args[0] {
+	case "json":
+		s.json = on
+	case "verbose":
+		s.verbose = on
+	default:
+		return fmt.Errorf("unknown setting %q", args[0])
+	}
+	return nil
+}
+
+// fetchModels lists the models the gateway serves to the configured key
+func (s *shell) fetchModels() ([]string, error) {
+	resp, err := APIRequest("GET", "/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, gatewayError(resp)
+	}
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+
+	models := make([]string, 0, len(list.Data))
+	for _, model := range list.Data {
+		models = append(models, model.ID)
+	}
+	sort.Strings(models)
+	s.models = models
+	return models, nil
+}
+
+func (s *shell) listModels() error {
+	models, err := s.fetchModels()
+	if err != nil {
+		return err
+	}
+	for _, model := range models {
+		fmt.Fprintln(s.out, model)
+	}
+	return nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chat sends a single message, or starts a conversation when no message is
+// given. Replies report latency and token usage, for smoke testing.
+func (s *shell) chat(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: chat <model> [message]")
+	}
+	if !IsAPIAccess() {
+		return fmt.Errorf("chat requires an API connection; use api <url> <key>")
+	}
+	model := args[0]
+
+	if len(args) > 1 {
+		_, err := s.chatTurn(model, []chatMessage{{Role: "user", Content: strings.Join(args[1:], " ")}})
+		return err
+	}
+	if s.term == nil {
+		return fmt.Errorf("usage: chat <model> <message>")
+	}
+
+	fmt.Fprintf(s.out, "Chatting with %s. /reset clears the conversation, /exit returns to the shell.\n", model)
+	fd := int(os.Stdin.Fd())
+	var history []chatMessage
+	for {
+		s.term.SetPrompt(model + "> ")
+		line, err := s.readLine(fd)
+		if err == io.EOF {
+			fmt.Fprintln(s.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch strings.TrimSpace(line) {
+		case "":
+			continue
+		case "/exit", "/quit":
+			return nil
+		case "/reset":
+			history = nil
+			fmt.Fprintln(s.out, "Conversation cleared.")
+			continue
+		}
+
+		messages := append(history, chatMessage{Role: "user", Content: line})
+		reply, err := s.chatTurn(model, messages)
+		if err != nil {
+			fmt.Fprintf(s.out, "Error: %v\n", err)
+			continue
+		}
+		history = append(messages, chatMessage{Role: "assistant", Content: reply})
+	}
+}
+
+// chatTurn sends a chat completion and prints the reply
+func (s *shell) chatTurn(model string, messages []chatMessage) (string, error) {
+	start := time.Now()
+	resp, err := APIRequest("POST", "/v1/chat/completions", map[string]interface{}{
+		"model":    model,
+		"messages": messages,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", gatewayError(resp)
+	}
+
+	var completion struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("response has no choices")
+	}
+
+	reply := completion.Choices[0].Message.Content
+	fmt.Fprintln(s.out, reply)
+	fmt.Fprintf(s.out, "[%s, %s, %d prompt + %d completion tokens]\n",
+		completion.Model, time.Since(start).Round(time.Millisecond),
+		completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
+	return reply, nil
+}
+
+// gatewayError reads an OpenAI-style error response
+func gatewayError(resp *http.Response) error {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error.Message == "" {
+		return fmt.Errorf("gateway returned status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("gateway returned status %d: %s", resp.StatusCode, body.Error.Message)
+}
+
+// autoComplete completes the word before the cursor on Tab. A unique match
+// is completed; otherwise the common prefix is, and the matches are listed.
+func (s *shell) autoComplete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	head := line[:pos]
+	words := strings.Fields(head)
+	word := ""
+	if len(words) > 0 && !strings.HasSuffix(head, " ") {
+		word = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	candidates := s.completions(words, word)
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+
+	completion := commonPrefix(candidates)
+	if len(candidates) == 1 {
+		completion += " "
+	} else if completion == word {
+		_, _ = fmt.Fprintf(s.term, "%s\r\n", strings.Join(candidates, "  "))
+	}
+
+	newHead := head[:len(head)-len(word)] + completion
+	return newHead + line[pos:], len(newHead), true
+}
+
+// completions returns the completions of word after the preceding words:
+// shell builtins and commands, subcommands, flags, and gateway models for
+// chat
+func (s *shell) completions(words []string, word string) []string {
+	var options []string
+	switch {
+	case len(words) == 0:
+		for name := range shellBuiltins {
+			options = append(options, name)
+		}
+		for _, cmd := range s.opts.NewRoot().Commands() {
+			if cmd.Name() != "shell" && cmd.IsAvailableCommand() {
+				options = append(options, cmd.Name())
+			}
+		}
+	case words[0] == "use" && len(words) == 1:
+		options = []string{"db", "api"}
+	case words[0] == "set" && len(words) == 1:
+		options = []string{"json", "verbose"}
+	case words[0] == "set" && len(words) == 2:
+		options = []string{"on", "off"}
+	case words[0] == "chat" && len(words) == 1:
+		if s.models == nil && IsAPIAccess() {
+			_, _ = s.fetchModels()
+		}
+		options = s.models
+	default:
+		options = commandCompletions(s.opts.NewRoot(), words, word)
+	}
+
+	var matches []string
+	for _, option := range options {
+		if strings.HasPrefix(option, word) {
+			matches = append(matches, option)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// commandCompletions returns the subcommands and flags of the command the
+// words name
+func commandCompletions(root *cobra.Command, words []string, word string) []string {
+	cmd, _, err := root.Find(words)
+	if err != nil || cmd == root {
+		return nil
+	}
+
+	var options []string
+	if strings.HasPrefix(word, "-") {
+		add := func(flag *pflag.Flag) {
+			options = append(options, "--"+flag.Name)
+		}
+		cmd.InheritedFlags().VisitAll(add)
+		cmd.LocalFlags().VisitAll(add)
+		return options
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() {
+			options = append(options, sub.Name())
+		}
+	}
+	return options
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// splitArgs splits a command line into arguments. Single and double quotes
+// group words, and a backslash escapes the next character outside single
+// quotes.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`user create --email a@b.c  --first-name "Ada Lovelace" --last-name 'O\' x\ y`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user", "create", "--email", "a@b.c", "--first-name", "Ada Lovelace", "--last-name", `O\`, "x y"}, args)
+
+	args, err = splitArgs(`chat gpt-4 "" `)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chat", "gpt-4", ""}, args)
+
+	_, err = splitArgs(`chat "hello`)
+	assert.Error(t, err)
+}
+
+func testShell(out *bytes.Buffer) *shell {
+	newRoot := func() *cobra.Command {
+		root := &cobra.Command{Use: "pllm"}
+		root.PersistentFlags().Bool("json", false, "")
+		user := &cobra.Command{Use: "user", Run: func(*cobra.Command, []string) {}}
+		list := &cobra.Command{Use: "list", Run: func(*cobra.Command, []string) {}}
+		list.Flags().Int("limit", 50, "")
+		list.Flags().String("team-id", "", "")
+		user.AddCommand(list, &cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+		root.AddCommand(user, &cobra.Command{Use: "team", Run: func(*cobra.Command, []string) {}})
+		return root
+	}
+	return &shell{opts: ShellOptions{NewRoot: newRoot}, out: out, mode: "api"}
+}
+
+func TestShellCompletions(t *testing.T) {
+	s := testShell(&bytes.Buffer{})
+
+	assert.Equal(t, []string{"team"}, s.completions(nil, "te"))
+	assert.Equal(t, []string{"get", "list"}, s.completions([]string{"user"}, ""))
+	assert.Equal(t, []string{"--limit"}, s.completions([]string{"user", "list"}, "--l"))
+	assert.Equal(t, []string{"--json", "--limit", "--team-id"}, s.completions([]string{"user", "list"}, "--"))
+	assert.Equal(t, []string{"api"}, s.completions([]string{"use"}, "a"))
+	assert.Equal(t, []string{"off", "on"}, s.completions([]string{"set", "json"}, "o"))
+	assert.Empty(t, s.completions([]string{"nope"}, ""))
+
+	line, pos, ok := s.autoComplete("user li", 7, '\t')
+	require.True(t, ok)
+	assert.Equal(t, "user list ", line)
+	assert.Equal(t, 10, pos)
+
+	line, _, ok = s.autoComplete("user list --t --json", 13, '\t')
+	require.True(t, ok)
+	assert.Equal(t, "user list --team-id  --json", line)
+
+	_, _, ok = s.autoComplete("user", 4, 'x')
+	assert.False(t, ok, "only Tab completes")
+}
+
+func TestShellChat(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		_, _ = w.Write([]byte(`{"model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"pong"}}],
+			"usage":{"prompt_tokens":3,"completion_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	previousURL, previousKey := apiURL, apiKey
+	defer SetAPIConfig(previousURL, previousKey)
+
+	var out bytes.Buffer
+	s := testShell(&out)
+	assert.False(t, s.exec("use api "+server.URL+"/ sk-test"))
+	assert.Equal(t, server.URL, apiURL)
+
+	out.Reset()
+	assert.False(t, s.exec(`chat gpt-4o "ping now"`))
+	assert.Contains(t, out.String(), "pong\n[gpt-4o, ")
+	assert.Contains(t, out.String(), "3 prompt + 1 completion tokens]")
+	assert.Equal(t, "gpt-4o", received["model"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"role": "user", "content": "ping now"}}, received["messages"])
+
+	out.Reset()
+	assert.False(t, s.exec("use db"))
+	assert.Contains(t, out.String(), "no database connection")
+	assert.Equal(t, "api", s.mode)
+
+	assert.True(t, s.exec("exit"))
+}
@@ -251,7 +251,7 @@ func newTeamAddUserCommand(ctx context.Context) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&userID, "user-id", "", "User ID to add (required)")
-	cmd.Flags().StringVarP(&role, "role", "r", "member", "Team role (owner, admin, member, viewer)")
+	cmd.Flags().StringVarP(&role, "role", "r", "member", "Team role (owner, admin, member, viewer, billing_admin, auditor)")
 	cmd.Flags().Float64Var(&maxBudget, "max-budget", 0, "Maximum budget for user")
 
 	_ = cmd.MarkFlagRequired("user-id")
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/models"
+)
+
+// NewValidateCommand creates the command that validates a gateway
+// configuration file before it is deployed
+func NewValidateCommand() *cobra.Command {
+	var checkConnectivity, strict bool
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate a gateway configuration file",
+		Long: `Load a gateway configuration (--config, default: config.yaml in ., ./config or
+/etc/pllm) and validate every model instance: provider parameters, referenced
+environment variables, Azure deployment mappings and the models named by
+aliases, routes, tiers and fallbacks. --check-connectivity also health checks
+each enabled instance's provider.
+
+The JSON report is printed on stdout and a summary on stderr. The command
+exits with a non-zero status when the configuration has errors (or warnings,
+with --strict), so it can gate deploys in CI.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("config")
+
+			cfg, err := config.Load(path)
+			if err != nil {
+				return err
+			}
+
+			report := models.ValidateConfig(context.Background(), cfg, models.ValidationOptions{
+				CheckConnectivity: checkConnectivity,
+				Timeout:           timeout,
+				Strict:            strict,
+			})
+			OutputJSON(report)
+
+			fmt.Fprintf(os.Stderr, "%d model instances: %d errors, %d warnings\n",
+				len(report.Instances), report.Errors, report.Warnings)
+			if !report.Valid {
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return fmt.Errorf("configuration is invalid")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&checkConnectivity, "check-connectivity", false, "Health check the provider of each enabled instance")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout of each connectivity check")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail on warnings too")
+
+	return cmd
+}
@@ -10,7 +10,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/amerfu/pllm/cmd/cli/commands"
-	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/core/database"
 )
 
 var (
@@ -36,7 +36,7 @@ func newRootCommand() *cobra.Command {
 		Long: `A comprehensive CLI tool for managing pLLM users, teams, keys, and budgets.
 Supports both direct database access (when run on server) and API access (when run remotely).`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return initConfig()
+			return initConfig(cmd)
 		},
 	}
 
@@ -55,35 +55,35 @@ Supports both direct database access (when run on server) and API access (when r
 	rootCmd.AddCommand(commands.NewKeyCommand(ctx))
 	rootCmd.AddCommand(commands.NewBudgetCommand(ctx))
 	rootCmd.AddCommand(commands.NewConfigCommand())
+	rootCmd.AddCommand(commands.NewValidateCommand())
+	rootCmd.AddCommand(commands.NewMigrateCommand())
+	rootCmd.AddCommand(commands.NewShellCommand(commands.ShellOptions{
+		NewRoot:   newRootCommand,
+		ConnectDB: openDatabase,
+	}))
 
 	return rootCmd
 }
 
-func initConfig() error {
+func initConfig(cmd *cobra.Command) error {
 	// Initialize configuration from environment variables, config file, or flags
 	if cfgFile != "" {
 		// Use config file from flag
-		fmt.Printf("Using config file: %s\n", cfgFile)
+		fmt.Fprintf(os.Stderr, "Using config file: %s\n", cfgFile)
 	}
 
 	// Set up database connection if URL is provided
 	if dbURL != "" {
-		db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{})
+		db, err := openDatabase(dbURL)
 		if err != nil {
-			return fmt.Errorf("failed to connect to database: %w", err)
+			return err
 		}
 
-		// Auto-migrate models
-		if err := db.AutoMigrate(
-			&models.User{},
-			&models.Team{},
-			&models.TeamMember{},
-			&models.Key{},
-			&models.Budget{},
-			&models.BudgetTracking{},
-			&models.BudgetAlert{},
-		); err != nil {
-			return fmt.Errorf("failed to migrate database: %w", err)
+		// Schema changes are left to "pllm migrate"
+		if !commands.ManagesSchema(cmd) {
+			if err := database.CheckSchema(db); err != nil {
+				return err
+			}
 		}
 
 		// Store DB connection in context for commands to use
@@ -101,3 +101,13 @@ func initConfig() error {
 
 	return nil
 }
+
+// openDatabase connects to the database. It does not migrate it; see
+// "pllm migrate up".
+func openDatabase(url string) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
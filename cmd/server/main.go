@@ -17,7 +17,11 @@ import (
 	modelService "github.com/amerfu/pllm/internal/services/integrations/model"
 	routeService "github.com/amerfu/pllm/internal/services/integrations/route"
 	"github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/data/coordination"
+	"github.com/amerfu/pllm/internal/services/integrations/billing"
 	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+	"github.com/amerfu/pllm/internal/services/monitoring/outage"
 	"github.com/amerfu/pllm/internal/services/worker"
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
@@ -73,6 +77,9 @@ func main() {
 	}
 	defer func() { _ = log.Sync() }()
 
+	// Keep this deployment's Redis keys apart from others sharing the server
+	redisService.SetNamespace(cfg.Redis.Namespace)
+
 	// Detect available dependencies
 	appMode := detectDependencies(cfg, log)
 
@@ -108,6 +115,7 @@ func main() {
 			MaxConnections:  cfg.Database.MaxConnections,
 			MaxIdleConns:    cfg.Database.MaxIdleConns,
 			ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+			SkipMigrations:  !cfg.Database.AutoMigrate,
 		}
 
 		if err := database.Initialize(dbConfig); err != nil {
@@ -176,6 +184,17 @@ func main() {
 		log.Fatal("Failed to load model instances", zap.Error(err))
 	}
 
+	// Admin dashboard notifications, relayed through Redis (when available)
+	// so events from every replica and the standalone worker are delivered
+	var notificationPub *redisService.EventPublisher
+	if redisClient != nil {
+		notificationPub = redisService.NewEventPublisher(redisClient, log)
+	}
+	notifier := notifications.NewHub(notificationPub, log)
+	notifierCtx, notifierCancel := context.WithCancel(context.Background())
+	go notifier.Run(notifierCtx)
+	modelManager.SetNotifier(notifier)
+
 	// Load user-created models from database (if available)
 	if appMode.DatabaseAvailable {
 		if dbInstance := database.GetDB(); dbInstance != nil {
@@ -203,8 +222,9 @@ func main() {
 		}
 	}
 
-	// Load routes from config
+	// Load routes and latency tiers from config
 	modelManager.LoadRoutes(cfg.Routes)
+	modelManager.LoadTiers(cfg.Tiers)
 
 	// Load user routes from database (if available)
 	if appMode.DatabaseAvailable {
@@ -232,6 +252,7 @@ func main() {
 						Slug:           r.Slug,
 						Models:         routeModels,
 						FallbackModels: []string(r.FallbackModels),
+						Defaults:       config.RouteDefaults(r.Defaults),
 					}, r.Strategy)
 					loaded++
 				}
@@ -240,6 +261,19 @@ func main() {
 		}
 	}
 
+	// Restore instance health and performance state saved before the last
+	// restart, and keep saving it while running
+	var snapshotterCancel context.CancelFunc
+	snapshotter := modelManager.NewSnapshotter(cfg.Router.SnapshotInterval)
+	if snapshotter != nil {
+		if err := snapshotter.Restore(context.Background()); err != nil {
+			log.Warn("Failed to restore instance snapshots", zap.Error(err))
+		}
+		var snapshotCtx context.Context
+		snapshotCtx, snapshotterCancel = context.WithCancel(context.Background())
+		go snapshotter.Start(snapshotCtx)
+	}
+
 	// Start periodic health checker for provider instances
 	var healthCheckerCancel context.CancelFunc
 	{
@@ -278,37 +312,64 @@ func main() {
 	if !appMode.IsLiteMode && appMode.DatabaseAvailable {
 		db = database.GetDB()
 	}
-	mainRouter := router.NewRouter(cfg, log, modelManager, db, pricingManager)
+	mainRouter := router.NewRouter(cfg, log, modelManager, db, pricingManager, notifier)
+
+	// Watch provider status pages and mitigate declared upstream incidents
+	var outageCancel context.CancelFunc
+	if cfg.Outage.Enabled {
+		watcher := outage.NewWatcher(cfg.Outage, modelManager, db, notifier, log)
+		var outageCtx context.Context
+		outageCtx, outageCancel = context.WithCancel(context.Background())
+		go watcher.Run(outageCtx)
+		log.Info("Started provider outage watcher",
+			zap.Duration("interval", cfg.Outage.PollInterval),
+			zap.String("action", cfg.Outage.Action))
+	}
 
-	// Initialize background worker for async usage processing (if Redis available)
+	// Initialize background worker for async usage processing (if Redis or the
+	// Postgres coordination backend is available)
 	var usageProcessor *worker.UsageProcessor
 	var workerCtx context.Context
 	var workerCancel context.CancelFunc
 
-	if !appMode.IsLiteMode && appMode.RedisAvailable && db != nil && redisClient != nil {
-		// Use the Redis client initialized earlier
-		// Redis connection already verified above
-		{
-			// Initialize Redis services for worker
-			usageQueue := redisService.NewUsageQueue(&redisService.UsageQueueConfig{
-				Client:     redisClient,
-				Logger:     log,
-				QueueName:  "usage_processing_queue",
-				BatchSize:  50,
-				MaxRetries: 3,
-			})
-			budgetCache := redisService.NewBudgetCache(redisClient, log, 5*time.Minute)
-			lockManager := redisService.NewLockManager(redisClient, log)
+	if !appMode.IsLiteMode && db != nil && (redisClient != nil || cfg.Coordination.UsesPostgres()) {
+		// Use the Redis client initialized earlier (nil with the Postgres backend)
+		backends, err := coordination.NewBackends(&coordination.Config{
+			Backend:    cfg.Coordination.Backend,
+			Redis:      redisClient,
+			DB:         db,
+			Logger:     log,
+			QueueName:  "usage_processing_queue",
+			BatchSize:  50,
+			MaxRetries: 3,
+			UsageCodec: redisService.UsageCodec{
+				Encoding: cfg.Coordination.UsageEncoding,
+				Compress: cfg.Coordination.UsageCompression,
+			},
+		})
+		if err != nil {
+			log.Error("Failed to initialize coordination backend, usage worker disabled", zap.Error(err))
+		} else {
+			// Prepaid team credits are debited by the usage worker
+			var credits *billing.Service
+			if cfg.Billing.Credits.Enabled {
+				credits = billing.NewService(db, log, cfg.Billing.Credits)
+			}
 
 			// Create usage processor
 			usageProcessor = worker.NewUsageProcessor(&worker.UsageProcessorConfig{
 				DB:                 db,
 				Logger:             log,
-				UsageQueue:         usageQueue,
-				BudgetCache:        budgetCache,
-				LockManager:        lockManager,
+				UsageQueue:         backends.UsageQueue,
+				BudgetCache:        backends.BudgetCache,
+				LockManager:        backends.LockManager,
 				BatchSize:          100,
 				ProcessingInterval: 30 * time.Second,
+				Notifier:           notifier,
+				SampleRate:         cfg.UsageSampling.Rate,
+				KeepErrors:         cfg.UsageSampling.KeepErrors,
+				Counters:           backends.Counters,
+				Credits:            credits,
 			})
 
 			// Start background worker
@@ -404,6 +465,11 @@ func main() {
 		dbSyncCancel()
 	}
 
+	// Stop outage watcher
+	if outageCancel != nil {
+		outageCancel()
+	}
+
 	// Stop health checker
 	if healthCheckerCancel != nil {
 		log.Info("Stopping background health checker...")
@@ -420,6 +486,10 @@ func main() {
 		}
 	}
 
+	// End open notification streams so they do not hold up server shutdown
+	notifierCancel()
+	notifier.Close()
+
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.GracefulShutdown)
 	defer cancel()
@@ -430,6 +500,18 @@ func main() {
 		}
 	}
 
+	// Stop router background services (async job workers) once no new
+	// requests can arrive
+	router.Shutdown()
+
+	// Save the final instance state for the next process
+	if snapshotter != nil {
+		snapshotterCancel()
+		if err := snapshotter.Save(ctx); err != nil {
+			log.Warn("Failed to save instance snapshots", zap.Error(err))
+		}
+	}
+
 	log.Info("Servers shutdown complete")
 }
 
@@ -484,8 +566,9 @@ func detectDependencies(cfg *config.Config, log *zap.Logger) AppMode {
 		}
 	}
 
-	// Determine if we're in lite mode
-	mode.IsLiteMode = !mode.DatabaseAvailable || !mode.RedisAvailable
+	// Determine if we're in lite mode. The Postgres coordination backend
+	// provides full mode without Redis.
+	mode.IsLiteMode = !mode.DatabaseAvailable || (!mode.RedisAvailable && !cfg.Coordination.UsesPostgres())
 
 	// Allow override via environment variable
 	if os.Getenv("PLLM_LITE_MODE") == "true" {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/amerfu/pllm/internal/core/config"
 	modelService "github.com/amerfu/pllm/internal/services/integrations/model"
 	routeService "github.com/amerfu/pllm/internal/services/integrations/route"
 	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
@@ -140,6 +141,7 @@ func syncRoutes(routeSvc *routeService.Service, manager *llmModels.ModelManager,
 			Slug:           r.Slug,
 			Models:         routeModels,
 			FallbackModels: []string(r.FallbackModels),
+			Defaults:       config.RouteDefaults(r.Defaults),
 		}, r.Strategy)
 	}
 }
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -15,7 +17,11 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/data/coordination"
+	"github.com/amerfu/pllm/internal/services/integrations/billing"
 	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+	"github.com/amerfu/pllm/internal/services/monitoring/usernotify"
 	"github.com/amerfu/pllm/internal/services/worker"
 )
 
@@ -25,6 +31,7 @@ func main() {
 		batchSize          = flag.Int("batch-size", 100, "Batch size for processing")
 		processingInterval = flag.Duration("interval", 30*time.Second, "Processing interval")
 		logLevel           = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+		healthAddr         = flag.String("health-addr", ":8082", "Address of the health and metrics server (empty to disable)")
 	)
 	flag.Parse()
 
@@ -44,37 +51,67 @@ func main() {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
 
+	// Use the same Redis namespace as the gateway
+	redisService.SetNamespace(cfg.Redis.Namespace)
+
 	// Initialize database
 	db, err := initDatabase(cfg.Database, logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
 	}
 
-	// Initialize Redis client
-	redisClient, err := initRedis(cfg.Redis, logger)
-	if err != nil {
-		logger.Fatal("Failed to initialize Redis", zap.Error(err))
+	// Initialize Redis client (not needed with the Postgres coordination backend)
+	var redisClient *redis.Client
+	if !cfg.Coordination.UsesPostgres() {
+		redisClient, err = initRedis(cfg.Redis, logger)
+		if err != nil {
+			logger.Fatal("Failed to initialize Redis", zap.Error(err))
+		}
 	}
 
-	// Initialize Redis services
-	budgetCache := redisService.NewBudgetCache(redisClient, logger, 5*time.Minute)
-	usageQueue := redisService.NewUsageQueue(&redisService.UsageQueueConfig{
-		Client:     redisClient,
+	// Initialize queue, budget cache and lock manager
+	backends, err := coordination.NewBackends(&coordination.Config{
+		Backend:    cfg.Coordination.Backend,
+		Redis:      redisClient,
+		DB:         db,
 		Logger:     logger,
 		BatchSize:  *batchSize,
 		MaxRetries: 3,
+		UsageCodec: redisService.UsageCodec{
+			Encoding: cfg.Coordination.UsageEncoding,
+			Compress: cfg.Coordination.UsageCompression,
+		},
 	})
-	lockManager := redisService.NewLockManager(redisClient, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize coordination backend", zap.Error(err))
+	}
+
+	// Notifications reach admin dashboards through the Redis event stream
+	var notifier *notifications.Hub
+	if backends.EventPub != nil {
+		notifier = notifications.NewHub(backends.EventPub, logger)
+	}
+
+	// Prepaid team credits are debited with the usage they pay for
+	var credits *billing.Service
+	if cfg.Billing.Credits.Enabled {
+		credits = billing.NewService(db, logger, cfg.Billing.Credits)
+	}
 
 	// Initialize usage processor
 	processor := worker.NewUsageProcessor(&worker.UsageProcessorConfig{
 		DB:                 db,
 		Logger:             logger,
-		UsageQueue:         usageQueue,
-		BudgetCache:        budgetCache,
-		LockManager:        lockManager,
+		UsageQueue:         backends.UsageQueue,
+		BudgetCache:        backends.BudgetCache,
+		LockManager:        backends.LockManager,
 		BatchSize:          *batchSize,
 		ProcessingInterval: *processingInterval,
+		Notifier:           notifier,
+		SampleRate:         cfg.UsageSampling.Rate,
+		KeepErrors:         cfg.UsageSampling.KeepErrors,
+		Counters:           backends.Counters,
+		Credits:            credits,
 	})
 
 	// Create context for graceful shutdown
@@ -86,14 +123,31 @@ func main() {
 		logger.Fatal("Failed to start usage processor", zap.Error(err))
 	}
 
+	// Spend alerts and weekly digests emailed to users
+	var userNotifications *usernotify.Service
+	if cfg.UserNotifications.Enabled {
+		mailer, err := usernotify.NewSMTPMailer(cfg.UserNotifications.SMTP)
+		if err != nil {
+			logger.Fatal("Failed to initialize user notification mailer", zap.Error(err))
+		}
+		userNotifications, err = usernotify.NewService(db, mailer, cfg.UserNotifications, logger)
+		if err != nil {
+			logger.Fatal("Failed to initialize user notifications", zap.Error(err))
+		}
+		go userNotifications.Start(ctx)
+	}
+
 	// Wait for shutdown signal
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
 	logger.Info("Usage worker started successfully")
 
-	// Start health check server (optional)
-	go startHealthCheckServer(":8082", processor, logger)
+	// Health and metrics server for probes and alerting
+	var healthServer *http.Server
+	if *healthAddr != "" {
+		healthServer = startHealthCheckServer(*healthAddr, processor, logger)
+	}
 
 	// Wait for shutdown signal
 	<-sigCh
@@ -101,11 +155,20 @@ func main() {
 
 	// Cancel context to stop processor
 	cancel()
+	if userNotifications != nil {
+		userNotifications.Stop()
+	}
 
 	// Give processor time to finish current batch
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
+	if healthServer != nil {
+		if err := healthServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error stopping health check server", zap.Error(err))
+		}
+	}
+
 	// Stop processor
 	if err := processor.Stop(); err != nil {
 		logger.Error("Error stopping processor", zap.Error(err))
@@ -129,7 +192,9 @@ func main() {
 	if sqlDB, err := db.DB(); err == nil {
 		_ = sqlDB.Close()
 	}
-	_ = redisClient.Close()
+	if redisClient != nil {
+		_ = redisClient.Close()
+	}
 
 	logger.Info("Usage worker shutdown complete")
 }
@@ -213,13 +278,19 @@ func initRedis(cfg config.RedisConfig, logger *zap.Logger) (*redis.Client, error
 	return client, nil
 }
 
-func startHealthCheckServer(addr string, processor *worker.UsageProcessor, logger *zap.Logger) {
-	// Simple HTTP health check server
-	// This is a minimal implementation - you could expand this with proper HTTP handlers
-	logger.Info("Health check server would start here", zap.String("addr", addr))
+func startHealthCheckServer(addr string, processor *worker.UsageProcessor, logger *zap.Logger) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           worker.NewHealthHandler(processor, logger),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		logger.Info("Health check server started", zap.String("addr", addr))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Health check server failed", zap.Error(err))
+		}
+	}()
 
-	// TODO: Implement HTTP server with health endpoints:
-	// GET /health - basic health check
-	// GET /health/detailed - processor statistics
-	// GET /metrics - Prometheus metrics (optional)
+	return server
 }
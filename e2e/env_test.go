@@ -0,0 +1,327 @@
+//go:build e2e
+
+// The e2e suite boots the gateway against Postgres and Redis containers and
+// mock providers, then drives it over HTTP like a client would. It needs
+// Docker and is skipped without it:
+//
+//	make test-e2e
+//	go test -tags=e2e -v ./e2e/...
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	testredis "github.com/testcontainers/testcontainers-go/modules/redis"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.uber.org/zap"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/amerfu/pllm/internal/api/router"
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/database"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+)
+
+const (
+	e2eMasterKey  = "sk-master-e2e-0123456789"
+	e2eModel      = "gpt-4o-mini"
+	failoverModel = "gpt-4o-mini-failover"
+)
+
+// e2eConfig is the gateway configuration; the placeholders are the
+// database, Redis and mock provider URLs
+const e2eConfig = `
+server:
+  port: 0
+auth:
+  master_key: %[1]s
+jwt:
+  secret_key: e2e-jwt-secret-that-is-long-enough-for-hs256
+database:
+  url: %[2]s
+redis:
+  url: %[3]s
+cache:
+  enabled: false
+router:
+  routing_strategy: priority
+  enable_failover: true
+model_list:
+  - model_name: %[5]s
+    provider:
+      type: openai
+      model: gpt-4o-mini
+      api_key: sk-mock
+      base_url: %[4]s
+  - model_name: %[6]s
+    priority: 100
+    provider:
+      type: openai
+      model: gpt-4o-mini
+      api_key: sk-mock
+      base_url: %[7]s
+  - model_name: %[6]s
+    priority: 10
+    provider:
+      type: openai
+      model: gpt-4o-mini
+      api_key: sk-mock
+      base_url: %[4]s
+`
+
+// environment is a running gateway with its dependencies, shared by the
+// tests of the suite
+type environment struct {
+	URL     string
+	Redis   *goredis.Client
+	Healthy *mockProvider // Serves every model
+	Broken  *mockProvider // Fails every request; the preferred failover instance
+
+	cleanups []func()
+}
+
+var (
+	envOnce sync.Once
+	envErr  error
+	shared  *environment
+)
+
+// gateway returns the shared environment, starting it on first use. Tests
+// are skipped when Docker is not available.
+func gateway(t *testing.T) *environment {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	envOnce.Do(func() {
+		shared, envErr = startEnvironment(context.Background())
+	})
+	require.NoError(t, envErr, "failed to start the e2e environment")
+	return shared
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if shared != nil {
+		shared.Close()
+	}
+	os.Exit(code)
+}
+
+func startEnvironment(ctx context.Context) (env *environment, err error) {
+	env = &environment{}
+	defer func() {
+		if err != nil {
+			env.Close()
+		}
+	}()
+
+	pg, err := postgres.Run(ctx,
+		"postgres:16-alpine",
+		postgres.WithDatabase("pllm"),
+		postgres.WithUsername("pllm"),
+		postgres.WithPassword("pllm"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start Postgres: %w", err)
+	}
+	env.cleanups = append(env.cleanups, func() { _ = pg.Terminate(context.Background()) })
+	databaseURL, err := pg.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := testredis.Run(ctx,
+		"redis:7-alpine",
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("Ready to accept connections").
+				WithStartupTimeout(60*time.Second)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start Redis: %w", err)
+	}
+	env.cleanups = append(env.cleanups, func() { _ = rc.Terminate(context.Background()) })
+	redisURL, err := rc.ConnectionString(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	env.Healthy = newMockProvider("Hello from the mock provider", false)
+	env.Broken = newMockProvider("", true)
+	env.cleanups = append(env.cleanups, env.Healthy.Close, env.Broken.Close)
+
+	cfg, err := loadConfig(fmt.Sprintf(e2eConfig, e2eMasterKey, databaseURL, redisURL,
+		env.Healthy.BaseURL(), e2eModel, failoverModel, env.Broken.BaseURL()))
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := bootGateway(cfg, env)
+	if err != nil {
+		return nil, err
+	}
+	server := httptest.NewServer(handler)
+	env.cleanups = append(env.cleanups, server.Close)
+	env.URL = server.URL
+	return env, nil
+}
+
+// loadConfig loads the configuration like the server does, defaults included
+func loadConfig(yaml string) (*config.Config, error) {
+	dir, err := os.MkdirTemp("", "pllm-e2e")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		return nil, err
+	}
+	return config.Load(path)
+}
+
+// bootGateway wires the gateway the way cmd/server does in full mode,
+// without the background workers the scenarios do not depend on
+func bootGateway(cfg *config.Config, env *environment) (http.Handler, error) {
+	logger := zap.NewNop()
+
+	if err := database.Initialize(&database.Config{
+		DSN:      cfg.Database.URL,
+		LogLevel: gormlogger.Silent,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	env.cleanups = append(env.cleanups, func() { _ = database.Close() })
+
+	opt, err := goredis.ParseURL(cfg.Redis.URL)
+	if err != nil {
+		return nil, err
+	}
+	env.Redis = goredis.NewClient(opt)
+	env.cleanups = append(env.cleanups, func() { _ = env.Redis.Close() })
+
+	modelManager := models.NewModelManager(logger, cfg.Router, env.Redis)
+	if err := modelManager.LoadModelInstances(cfg.ModelList); err != nil {
+		return nil, err
+	}
+
+	pricingManager := config.GetPricingManager()
+	if err := pricingManager.LoadDefaultPricing(filepath.Join("..", "internal", "core", "config")); err != nil {
+		return nil, fmt.Errorf("failed to load pricing: %w", err)
+	}
+
+	notifier := notifications.NewHub(nil, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	go notifier.Run(ctx)
+	env.cleanups = append(env.cleanups, cancel)
+
+	return router.NewRouter(cfg, logger, modelManager, database.GetDB(), pricingManager, notifier), nil
+}
+
+// Close stops the gateway and its dependencies
+func (e *environment) Close() {
+	for i := len(e.cleanups) - 1; i >= 0; i-- {
+		e.cleanups[i]()
+	}
+	e.cleanups = nil
+}
+
+// budgetCache is the cache the budget middleware checks, which the usage
+// worker keeps up to date in production
+func (e *environment) budgetCache() *redisService.BudgetCache {
+	return redisService.NewBudgetCache(e.Redis, zap.NewNop(), time.Minute)
+}
+
+// do sends a request with the credential as a bearer token and decodes a
+// JSON response into out, when given
+func (e *environment) do(t *testing.T, method, path, credential string, body, out interface{}) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, e.URL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	if out != nil && resp.StatusCode < 300 {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+// adminToken exchanges the master key for an admin token
+func (e *environment) adminToken(t *testing.T) string {
+	t.Helper()
+	var login struct {
+		Token string `json:"token"`
+	}
+	resp := e.do(t, http.MethodPost, "/api/admin/auth/master-key", "", map[string]string{"master_key": e2eMasterKey}, &login)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, login.Token)
+	return login.Token
+}
+
+// createdKey is a key created through the admin API
+type createdKey struct {
+	ID           string `json:"id"`
+	PlaintextKey string `json:"plaintext_key"`
+}
+
+// createKey creates an API key with the given settings merged into the
+// request
+func (e *environment) createKey(t *testing.T, settings map[string]interface{}) createdKey {
+	t.Helper()
+	body := map[string]interface{}{"name": "e2e-" + t.Name(), "key_type": "api"}
+	for k, v := range settings {
+		body[k] = v
+	}
+
+	var key createdKey
+	resp := e.do(t, http.MethodPost, "/api/admin/keys", e.adminToken(t), body, &key)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NotEmpty(t, key.PlaintextKey)
+	return key
+}
+
+// chatRequest is a one-message chat completion request
+func chatRequest(model string, extra map[string]interface{}) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":    model,
+		"messages": []map[string]string{{"role": "user", "content": "Say hello"}},
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	return body
+}
@@ -0,0 +1,126 @@
+//go:build e2e
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+)
+
+// mockProvider is an OpenAI-compatible upstream that answers every chat
+// completion with a fixed reply, or fails every request when broken
+type mockProvider struct {
+	server   *httptest.Server
+	reply    string
+	broken   bool
+	requests atomic.Int64 // Chat completions received
+}
+
+func newMockProvider(reply string, broken bool) *mockProvider {
+	p := &mockProvider{reply: reply, broken: broken}
+	p.server = httptest.NewServer(http.HandlerFunc(p.serveHTTP))
+	return p
+}
+
+// BaseURL is the base_url instances of the mock are configured with
+func (p *mockProvider) BaseURL() string {
+	return p.server.URL + "/v1"
+}
+
+func (p *mockProvider) Close() {
+	p.server.Close()
+}
+
+func (p *mockProvider) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v1/models":
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"object": "list",
+			"data":   []map[string]string{{"id": "gpt-4o-mini", "object": "model"}},
+		})
+	case "/v1/chat/completions":
+		p.requests.Add(1)
+		if p.broken {
+			writeMockJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": map[string]string{"message": "mock provider is broken", "type": "server_error"},
+			})
+			return
+		}
+
+		var req struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeMockJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": map[string]string{"message": "invalid request", "type": "invalid_request_error"},
+			})
+			return
+		}
+		if req.Stream {
+			p.stream(w, req.Model)
+			return
+		}
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"id":      "chatcmpl-mock",
+			"object":  "chat.completion",
+			"created": 1700000000,
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": p.reply},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]int{"prompt_tokens": 5, "completion_tokens": 3, "total_tokens": 8},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// stream sends the reply one word per chunk, then the usage and [DONE]
+func (p *mockProvider) stream(w http.ResponseWriter, model string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	send := func(chunk map[string]interface{}) {
+		chunk["id"] = "chatcmpl-mock"
+		chunk["object"] = "chat.completion.chunk"
+		chunk["created"] = 1700000000
+		chunk["model"] = model
+		data, _ := json.Marshal(chunk)
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for i, word := range strings.SplitAfter(p.reply, " ") {
+		delta := map[string]string{"content": word}
+		if i == 0 {
+			delta["role"] = "assistant"
+		}
+		send(map[string]interface{}{
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta}},
+		})
+	}
+	send(map[string]interface{}{
+		"choices": []map[string]interface{}{{"index": 0, "delta": map[string]string{}, "finish_reason": "stop"}},
+		"usage":   map[string]int{"prompt_tokens": 5, "completion_tokens": 3, "total_tokens": 8},
+	})
+	_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,155 @@
+//go:build e2e
+
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chatCompletion struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+type errorBody struct {
+	Error struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+func decodeError(t *testing.T, resp *http.Response) errorBody {
+	t.Helper()
+	var body errorBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body
+}
+
+func TestE2E_Auth(t *testing.T) {
+	env := gateway(t)
+
+	resp := env.do(t, http.MethodPost, "/v1/chat/completions", "", chatRequest(e2eModel, nil), nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "requests need a credential")
+
+	resp = env.do(t, http.MethodPost, "/v1/chat/completions", "sk-api-not-a-real-key", chatRequest(e2eModel, nil), nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "unknown keys are rejected")
+
+	resp = env.do(t, http.MethodPost, "/v1/chat/completions", e2eMasterKey, chatRequest(e2eModel, nil), nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "the master key must be exchanged for an admin token")
+
+	key := env.createKey(t, nil)
+
+	var completion chatCompletion
+	resp = env.do(t, http.MethodPost, "/v1/chat/completions", key.PlaintextKey, chatRequest(e2eModel, nil), &completion)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, env.Healthy.reply, completion.Choices[0].Message.Content)
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	resp = env.do(t, http.MethodGet, "/v1/models", key.PlaintextKey, nil, &list)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var ids []string
+	for _, model := range list.Data {
+		ids = append(ids, model.ID)
+	}
+	assert.Contains(t, ids, e2eModel)
+}
+
+func TestE2E_Budgets(t *testing.T) {
+	env := gateway(t)
+
+	t.Run("per-request cost ceiling", func(t *testing.T) {
+		key := env.createKey(t, map[string]interface{}{"max_cost_per_request": 0.000001})
+
+		resp := env.do(t, http.MethodPost, "/v1/chat/completions", key.PlaintextKey,
+			chatRequest(e2eModel, map[string]interface{}{"max_tokens": 10000}), nil)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "max_cost_exceeded", decodeError(t, resp).Error.Code)
+	})
+
+	t.Run("exhausted budget", func(t *testing.T) {
+		key := env.createKey(t, map[string]interface{}{"max_budget": 1.0, "budget_duration": "monthly"})
+
+		resp := env.do(t, http.MethodPost, "/v1/chat/completions", key.PlaintextKey, chatRequest(e2eModel, nil), nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "the budget has room")
+
+		// What the usage worker caches once the key's spend reaches its budget
+		require.NoError(t, env.budgetCache().UpdateBudgetCache(context.Background(), "key", key.ID, 0, 1, 1, true))
+
+		resp = env.do(t, http.MethodPost, "/v1/chat/completions", key.PlaintextKey, chatRequest(e2eModel, nil), nil)
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "budget_exceeded", decodeError(t, resp).Error.Code)
+	})
+}
+
+func TestE2E_Failover(t *testing.T) {
+	env := gateway(t)
+	key := env.createKey(t, nil)
+	brokenBefore := env.Broken.requests.Load()
+	healthyBefore := env.Healthy.requests.Load()
+
+	var completion chatCompletion
+	resp := env.do(t, http.MethodPost, "/v1/chat/completions", key.PlaintextKey, chatRequest(failoverModel, nil), &completion)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "the client does not see the failed instance")
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, env.Healthy.reply, completion.Choices[0].Message.Content)
+
+	assert.Greater(t, env.Broken.requests.Load(), brokenBefore, "the preferred instance was tried first")
+	assert.Greater(t, env.Healthy.requests.Load(), healthyBefore, "the request failed over to the next instance")
+}
+
+func TestE2E_Streaming(t *testing.T) {
+	env := gateway(t)
+	key := env.createKey(t, nil)
+
+	resp := env.do(t, http.MethodPost, "/v1/chat/completions", key.PlaintextKey,
+		chatRequest(e2eModel, map[string]interface{}{"stream": true}), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	var content strings.Builder
+	chunks, done := 0, false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk), data)
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+		}
+		chunks++
+	}
+	require.NoError(t, scanner.Err())
+
+	assert.True(t, done, "the stream ends with [DONE]")
+	assert.Greater(t, chunks, 1, "the reply arrives in chunks")
+	assert.Equal(t, env.Healthy.reply, content.String())
+}
@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/data/artifacts"
+	"github.com/amerfu/pllm/internal/services/monitoring/audit"
+)
+
+// ArtifactsHandler reports artifact storage and manages its lifecycle: reaper
+// runs, legal holds and erasure of a user's data
+type ArtifactsHandler struct {
+	baseHandler
+	db      *gorm.DB
+	service *artifacts.Service
+}
+
+// NewArtifactsHandler creates a new ArtifactsHandler.
+func NewArtifactsHandler(logger *zap.Logger, db *gorm.DB, service *artifacts.Service) *ArtifactsHandler {
+	return &ArtifactsHandler{
+		baseHandler: baseHandler{logger: logger},
+		db:          db,
+		service:     service,
+	}
+}
+
+// GetReport returns stored artifacts per kind and team, the lifecycle rules,
+// what legal holds retain and the last reaper run
+func (h *ArtifactsHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.Report(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to build artifact report", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to build artifact report")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, report)
+}
+
+// Reap runs the reaper now instead of waiting for its interval
+func (h *ArtifactsHandler) Reap(w http.ResponseWriter, r *http.Request) {
+	report := h.service.Reap(r.Context())
+	h.audit(r, audit.ActionDelete, nil, map[string]interface{}{
+		"artifact_reap": true,
+		"expired_files": report.ExpiredFile,
+		"quota_files":   report.QuotaFiles,
+		"request_logs":  report.RequestLogs,
+	})
+	h.sendJSON(w, http.StatusOK, report)
+}
+
+// ListHolds returns the active legal holds, or all of them with ?all=true
+func (h *ArtifactsHandler) ListHolds(w http.ResponseWriter, r *http.Request) {
+	holds, err := h.service.ListHolds(r.Context(), r.URL.Query().Get("all") == "true")
+	if err != nil {
+		h.logger.Error("Failed to list legal holds", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list legal holds")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{"holds": holds})
+}
+
+// PlaceHold places a legal hold on a user or a team
+func (h *ArtifactsHandler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID *uuid.UUID `json:"user_id"`
+		TeamID *uuid.UUID `json:"team_id"`
+		Reason string     `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	hold := &models.LegalHold{UserID: req.UserID, TeamID: req.TeamID, Reason: req.Reason, PlacedBy: h.actor(r)}
+	if err := h.service.PlaceHold(r.Context(), hold); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.audit(r, audit.ActionCreate, &hold.ID, map[string]interface{}{
+		"legal_hold": hold.ID.String(),
+		"user_id":    hold.UserID,
+		"team_id":    hold.TeamID,
+		"reason":     hold.Reason,
+	})
+	h.logger.Warn("Legal hold placed",
+		zap.String("hold_id", hold.ID.String()),
+		zap.Any("user_id", hold.UserID),
+		zap.Any("team_id", hold.TeamID))
+	h.sendJSON(w, http.StatusCreated, hold)
+}
+
+// ReleaseHold releases a legal hold; lifecycle rules apply again on the next
+// reaper run
+func (h *ArtifactsHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	holdID, err := uuid.Parse(chi.URLParam(r, "holdID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid hold ID")
+		return
+	}
+
+	hold, err := h.service.ReleaseHold(r.Context(), holdID, h.actor(r))
+	if errors.Is(err, artifacts.ErrHoldNotFound) {
+		h.sendError(w, http.StatusNotFound, "Legal hold not found or already released")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to release legal hold", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to release legal hold")
+		return
+	}
+
+	h.audit(r, audit.ActionDelete, &hold.ID, map[string]interface{}{"legal_hold": hold.ID.String()})
+	h.logger.Warn("Legal hold released", zap.String("hold_id", hold.ID.String()))
+	h.sendJSON(w, http.StatusOK, hold)
+}
+
+// EraseUser deletes a user's files, async jobs and request logs, for erasure
+// requests. Data under a legal hold is kept and reported as retained.
+func (h *ArtifactsHandler) EraseUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	report, err := h.service.EraseUser(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to erase user data", zap.String("user_id", userID.String()), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to erase user data")
+		return
+	}
+
+	h.audit(r, audit.ActionDelete, &userID, map[string]interface{}{
+		"erasure":      true,
+		"files":        report.Files,
+		"job_outputs":  report.JobOutputs,
+		"request_logs": report.RequestLogs,
+		"retained":     report.Retained,
+	})
+	h.sendJSON(w, http.StatusOK, report)
+}
+
+func (h *ArtifactsHandler) actor(r *http.Request) *uuid.UUID {
+	if userID, ok := middleware.GetUserID(r.Context()); ok && userID != uuid.Nil {
+		return &userID
+	}
+	return nil
+}
+
+func (h *ArtifactsHandler) audit(r *http.Request, action string, resourceID *uuid.UUID, details map[string]interface{}) {
+	if err := audit.NewLogger(h.db).LogEvent(r.Context(), h.actor(r), nil, audit.AuditEvent{
+		Action:     action,
+		Resource:   audit.ResourceSettings,
+		ResourceID: resourceID,
+		Details:    details,
+		IPAddress:  r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}); err != nil {
+		h.logger.Warn("Failed to log artifact lifecycle audit", zap.Error(err))
+	}
+}
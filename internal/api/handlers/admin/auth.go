@@ -2,6 +2,8 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"time"
 
@@ -30,16 +32,22 @@ func NewAuthHandler(logger *zap.Logger, masterKeyService *auth.MasterKeyService,
 }
 
 type LoginRequest struct {
-	MasterKey string `json:"master_key"`
+	MasterKey string   `json:"master_key"`
+	Scopes    []string `json:"scopes,omitempty"` // Scopes of the admin token; all scopes when empty
+	TTL       string   `json:"ttl,omitempty"`    // Lifetime of the admin token, e.g. "15m"
 }
 
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Token   string `json:"token"`
-	Message string `json:"message"`
+	Success   bool      `json:"success"`
+	Token     string    `json:"token"`
+	Message   string    `json:"message"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresIn int       `json:"expires_in"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// MasterKeyLogin handles master key authentication for admin access
+// MasterKeyLogin exchanges the master key for a short-lived, scoped admin
+// token
 func (h *AuthHandler) MasterKeyLogin(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -53,24 +61,45 @@ func (h *AuthHandler) MasterKeyLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate master key using the service
-	masterCtx, err := h.masterKeyService.ValidateMasterKey(r.Context(), req.MasterKey)
-	if err != nil {
-		h.sendError(w, http.StatusUnauthorized, "Invalid master key")
-		return
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			h.sendError(w, http.StatusBadRequest, "Invalid ttl")
+			return
+		}
 	}
 
-	// Generate JWT token for the master key session
-	token, err := h.masterKeyService.GenerateAdminToken(masterCtx)
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
+		ip = r.RemoteAddr
+	}
+	token, err := h.masterKeyService.ExchangeMasterKey(r.Context(), req.MasterKey, auth.AdminTokenRequest{
+		Scopes:    req.Scopes,
+		TTL:       ttl,
+		IPAddress: ip,
+		UserAgent: r.UserAgent(),
+	})
+	switch {
+	case errors.Is(err, auth.ErrInvalidTokenRequest):
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, auth.ErrMasterKeyRequired):
+		h.sendError(w, http.StatusUnauthorized, "Invalid master key")
+		return
+	case err != nil:
+		h.logger.Error("Failed to issue admin token", zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
 	h.sendJSON(w, http.StatusOK, LoginResponse{
-		Success: true,
-		Token:   token,
-		Message: "Master key authentication successful",
+		Success:   true,
+		Token:     token.Token,
+		Message:   "Master key authentication successful",
+		Scopes:    token.Scopes,
+		ExpiresIn: int(time.Until(token.ExpiresAt).Seconds()),
+		ExpiresAt: token.ExpiresAt,
 	})
 }
 
@@ -109,7 +138,7 @@ func (h *AuthHandler) Validate(w http.ResponseWriter, r *http.Request) {
 
 	// Try to validate as master key first
 	masterCtx, err := h.masterKeyService.ValidateMasterKey(r.Context(), token)
-	if err == nil {
+	if err == nil && !h.masterKeyService.RequiresExchange() {
 		// It's a valid master key
 		response := map[string]interface{}{
 			"valid": true,
@@ -235,6 +264,9 @@ func (h *AuthHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
 		"groups":      groups,
 		"auth_type":   string(authType),
 	}
+	if scopes, ok := middleware.GetTokenScopes(r.Context()); ok {
+		response["scopes"] = scopes
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
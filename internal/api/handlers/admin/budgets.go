@@ -0,0 +1,360 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/data/budget"
+	"github.com/amerfu/pllm/internal/services/integrations/budgetimport"
+	"github.com/amerfu/pllm/internal/services/monitoring/audit"
+)
+
+// BudgetHandler bulk-manages the budgets of teams, users and keys, and
+// moves budget between teams and shared pools
+type BudgetHandler struct {
+	baseHandler
+	db          *gorm.DB
+	pools       *budget.PoolService
+	auditLogger *audit.Logger
+}
+
+// NewBudgetHandler creates a new BudgetHandler.
+func NewBudgetHandler(logger *zap.Logger, db *gorm.DB) *BudgetHandler {
+	return &BudgetHandler{
+		baseHandler: baseHandler{logger: logger},
+		db:          db,
+		pools:       budget.NewPoolService(db, logger),
+		auditLogger: audit.NewLogger(db),
+	}
+}
+
+// ImportBudgets sets budgets from a CSV (Content-Type text/csv) or JSON body.
+// With dry_run=true it only validates the rows and reports the changes.
+// Nothing is imported when any row fails; the report is returned with 400.
+func (h *BudgetHandler) ImportBudgets(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	format := ""
+	switch contentType := r.Header.Get("Content-Type"); {
+	case strings.HasPrefix(contentType, "text/csv"):
+		format = "csv"
+	case strings.HasPrefix(contentType, "application/json"):
+		format = "json"
+	}
+
+	rows, err := budgetimport.Parse(body, format)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		h.sendError(w, http.StatusBadRequest, "No budgets to import")
+		return
+	}
+
+	report, err := budgetimport.Import(h.db, rows, budgetimport.Options{DryRun: dryRun})
+	if err != nil {
+		h.logger.Error("Failed to import budgets", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to import budgets")
+		return
+	}
+	if report.Failed > 0 {
+		h.sendJSON(w, http.StatusBadRequest, report)
+		return
+	}
+
+	if report.Applied {
+		h.logger.Info("Imported budgets",
+			zap.Int("created", report.Created),
+			zap.Int("updated", report.Updated),
+			zap.Int("unchanged", report.Unchanged))
+	}
+	h.sendJSON(w, http.StatusOK, report)
+}
+
+// TransferBudget moves unused budget from one team to another
+func (h *BudgetHandler) TransferBudget(w http.ResponseWriter, r *http.Request) {
+	var req budget.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	transfer, err := h.pools.Transfer(r.Context(), &req, h.actor(r))
+	if err != nil {
+		h.sendMovementError(w, err, "Failed to transfer budget")
+		return
+	}
+
+	details := map[string]interface{}{
+		"from_team_id": req.FromTeamID,
+		"to_team_id":   req.ToTeamID,
+		"amount":       req.Amount,
+		"reason":       req.Reason,
+	}
+	h.audit(r, "budget_transfer", &req.FromTeamID, nil, details)
+	h.audit(r, "budget_transfer", &req.ToTeamID, nil, details)
+	h.sendJSON(w, http.StatusOK, transfer)
+}
+
+// ListMovements lists budget transfers and pool movements, newest first,
+// filtered by team_id and pool_id
+func (h *BudgetHandler) ListMovements(w http.ResponseWriter, r *http.Request) {
+	filter := budget.MovementFilter{Limit: 100}
+	query := r.URL.Query()
+	if v := query.Get("team_id"); v != "" {
+		teamID, err := uuid.Parse(v)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+			return
+		}
+		filter.TeamID = &teamID
+	}
+	if v := query.Get("pool_id"); v != "" {
+		poolID, err := uuid.Parse(v)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid pool ID")
+			return
+		}
+		filter.PoolID = &poolID
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > 1000 {
+			h.sendError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	movements, err := h.pools.ListMovements(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list budget movements", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list budget movements")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"movements": movements,
+		"count":     len(movements),
+	})
+}
+
+// ListPools lists the budget pools, optionally only those of team_id
+func (h *BudgetHandler) ListPools(w http.ResponseWriter, r *http.Request) {
+	var teamID *uuid.UUID
+	if v := r.URL.Query().Get("team_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+			return
+		}
+		teamID = &id
+	}
+
+	pools, err := h.pools.ListPools(r.Context(), teamID)
+	if err != nil {
+		h.logger.Error("Failed to list budget pools", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list budget pools")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{"pools": pools})
+}
+
+// CreatePool creates a budget pool shared by teams
+func (h *BudgetHandler) CreatePool(w http.ResponseWriter, r *http.Request) {
+	var req budget.CreatePoolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Name == "" {
+		h.sendError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	pool, err := h.pools.CreatePool(r.Context(), &req, h.actor(r))
+	if err != nil {
+		h.sendMovementError(w, err, "Failed to create budget pool")
+		return
+	}
+
+	h.audit(r, audit.ActionCreate, nil, &pool.ID, map[string]interface{}{
+		"pool":     pool.Name,
+		"amount":   pool.Amount,
+		"team_ids": pool.TeamIDs,
+	})
+	h.sendJSON(w, http.StatusCreated, pool)
+}
+
+// GetPool returns a budget pool with its latest movements
+func (h *BudgetHandler) GetPool(w http.ResponseWriter, r *http.Request) {
+	poolID, ok := h.poolID(w, r)
+	if !ok {
+		return
+	}
+
+	pool, err := h.pools.GetPool(r.Context(), poolID)
+	if err != nil {
+		h.sendMovementError(w, err, "Failed to get budget pool")
+		return
+	}
+	movements, err := h.pools.ListMovements(r.Context(), budget.MovementFilter{PoolID: &poolID, Limit: 50})
+	if err != nil {
+		h.logger.Error("Failed to list budget pool movements", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to get budget pool")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"pool":      pool,
+		"remaining": pool.Remaining(),
+		"movements": movements,
+	})
+}
+
+// UpdatePool changes a budget pool's description, amount, teams or state
+func (h *BudgetHandler) UpdatePool(w http.ResponseWriter, r *http.Request) {
+	poolID, ok := h.poolID(w, r)
+	if !ok {
+		return
+	}
+	var req budget.UpdatePoolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	pool, err := h.pools.UpdatePool(r.Context(), poolID, &req, h.actor(r))
+	if err != nil {
+		h.sendMovementError(w, err, "Failed to update budget pool")
+		return
+	}
+
+	h.audit(r, audit.ActionUpdate, nil, &poolID, map[string]interface{}{
+		"pool":    pool.Name,
+		"changes": req,
+	})
+	h.sendJSON(w, http.StatusOK, pool)
+}
+
+// DeletePool deletes a budget pool; budget its teams drew stays with them
+func (h *BudgetHandler) DeletePool(w http.ResponseWriter, r *http.Request) {
+	poolID, ok := h.poolID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.pools.DeletePool(r.Context(), poolID); err != nil {
+		h.sendMovementError(w, err, "Failed to delete budget pool")
+		return
+	}
+
+	h.audit(r, audit.ActionDelete, nil, &poolID, nil)
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Budget pool deleted successfully"})
+}
+
+// DrawFromPool raises a member team's budget with budget from the pool
+func (h *BudgetHandler) DrawFromPool(w http.ResponseWriter, r *http.Request) {
+	h.movePool(w, r, "budget_pool_draw", h.pools.Draw)
+}
+
+// ReturnToPool gives unused budget of a member team back to the pool
+func (h *BudgetHandler) ReturnToPool(w http.ResponseWriter, r *http.Request) {
+	h.movePool(w, r, "budget_pool_return", h.pools.Return)
+}
+
+func (h *BudgetHandler) movePool(w http.ResponseWriter, r *http.Request, action string,
+	move func(ctx context.Context, poolID uuid.UUID, req *budget.PoolMovementRequest, createdBy *uuid.UUID) (*budget.PoolMovement, error)) {
+	poolID, ok := h.poolID(w, r)
+	if !ok {
+		return
+	}
+	var req budget.PoolMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := move(r.Context(), poolID, &req, h.actor(r))
+	if err != nil {
+		h.sendMovementError(w, err, "Failed to move pool budget")
+		return
+	}
+
+	h.audit(r, action, &req.TeamID, &poolID, map[string]interface{}{
+		"pool":   result.Pool.Name,
+		"amount": req.Amount,
+		"reason": req.Reason,
+	})
+	h.sendJSON(w, http.StatusOK, result)
+}
+
+func (h *BudgetHandler) poolID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	poolID, err := uuid.Parse(chi.URLParam(r, "poolID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid pool ID")
+		return uuid.Nil, false
+	}
+	return poolID, true
+}
+
+// sendMovementError maps budget movement errors to responses
+func (h *BudgetHandler) sendMovementError(w http.ResponseWriter, err error, message string) {
+	switch err {
+	case budget.ErrTeamNotFound, budget.ErrPoolNotFound:
+		h.sendError(w, http.StatusNotFound, err.Error())
+	case budget.ErrPoolExists:
+		h.sendError(w, http.StatusConflict, err.Error())
+	case budget.ErrInvalidAmount, budget.ErrSameTeam, budget.ErrUnlimitedBudget, budget.ErrInsufficientBudget,
+		budget.ErrPoolInactive, budget.ErrPoolTeamNotMember, budget.ErrPoolUnderfunded:
+		h.sendError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error(message, zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// actor returns the admin making the request, nil for the master key
+func (h *BudgetHandler) actor(r *http.Request) *uuid.UUID {
+	if userID, ok := middleware.GetUserID(r.Context()); ok && userID != uuid.Nil {
+		return &userID
+	}
+	return nil
+}
+
+// audit records a budget movement, under the team it concerns
+func (h *BudgetHandler) audit(r *http.Request, action string, teamID, poolID *uuid.UUID, details map[string]interface{}) {
+	if err := h.auditLogger.LogEvent(r.Context(), h.actor(r), teamID, audit.AuditEvent{
+		Action:     action,
+		Resource:   audit.ResourceBudget,
+		ResourceID: poolID,
+		Details:    details,
+		IPAddress:  r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}); err != nil {
+		h.logger.Warn("Failed to audit budget movement", zap.String("action", action), zap.Error(err))
+	}
+}
@@ -0,0 +1,35 @@
+package admin
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/monitoring/cluster"
+)
+
+// ClusterHandler lists the gateway replicas registered in the coordination
+// backend, to follow configuration rollouts across a fleet
+type ClusterHandler struct {
+	baseHandler
+	registrar *cluster.Registrar
+}
+
+func NewClusterHandler(logger *zap.Logger, registrar *cluster.Registrar) *ClusterHandler {
+	return &ClusterHandler{
+		baseHandler: baseHandler{logger: logger},
+		registrar:   registrar,
+	}
+}
+
+// GetCluster lists the replicas with their health and whether their
+// configuration drifted from the rest of the cluster
+func (h *ClusterHandler) GetCluster(w http.ResponseWriter, r *http.Request) {
+	status, err := h.registrar.Status(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list gateway replicas", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list gateway replicas")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,173 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/integrations/billing"
+)
+
+// maxWebhookBytes bounds Stripe webhook payloads
+const maxWebhookBytes = 1 << 20
+
+// CreditsHandler serves prepaid team credits: balances and ledgers, Stripe
+// Checkout purchases, manual adjustments and the Stripe webhook
+type CreditsHandler struct {
+	baseHandler
+	credits *billing.Service
+}
+
+// NewCreditsHandler creates a new CreditsHandler.
+func NewCreditsHandler(logger *zap.Logger, credits *billing.Service) *CreditsHandler {
+	return &CreditsHandler{
+		baseHandler: baseHandler{logger: logger},
+		credits:     credits,
+	}
+}
+
+// GetCredits returns the credit balance of a team with its most recent
+// ledger entries; limit defaults to 50
+func (h *CreditsHandler) GetCredits(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 500 {
+			h.sendError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = parsed
+	}
+
+	account, err := h.credits.GetAccount(r.Context(), teamID)
+	if err != nil {
+		h.logger.Error("Failed to get credit account", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to get credits")
+		return
+	}
+	transactions, err := h.credits.ListTransactions(r.Context(), teamID, limit)
+	if err != nil {
+		h.logger.Error("Failed to list credit transactions", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to get credits")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"team_id":      teamID,
+		"balance":      account.Balance,
+		"currency":     account.Currency,
+		"transactions": transactions,
+	})
+}
+
+// CreateCheckout starts a Stripe Checkout purchase of credits for a team and
+// returns the URL to send the buyer to
+func (h *CreditsHandler) CreateCheckout(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	var req struct {
+		Amount float64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	checkout, err := h.credits.CreateCheckout(r.Context(), teamID, req.Amount)
+	switch {
+	case errors.Is(err, billing.ErrStripeNotConfigured):
+		h.sendError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, billing.ErrAmountTooSmall):
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("Failed to create checkout session", zap.Error(err))
+		h.sendError(w, http.StatusBadGateway, "Failed to create checkout session")
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, checkout)
+}
+
+// AdjustCredits credits or debits a team by hand
+func (h *CreditsHandler) AdjustCredits(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	var req struct {
+		Amount      float64 `json:"amount"`
+		Description string  `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Amount == 0 {
+		h.sendError(w, http.StatusBadRequest, "amount must not be zero")
+		return
+	}
+
+	var createdBy *uuid.UUID
+	if userID, ok := middleware.GetUserID(r.Context()); ok {
+		createdBy = &userID
+	}
+
+	entry, err := h.credits.Adjust(r.Context(), teamID, req.Amount, req.Description, createdBy)
+	if err != nil {
+		h.logger.Error("Failed to adjust credits", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to adjust credits")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, entry)
+}
+
+// StripeWebhook reconciles Stripe payments and refunds. Stripe retries
+// deliveries answered with an error, so only unverifiable deliveries are
+// rejected with a client error.
+func (h *CreditsHandler) StripeWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Failed to read payload")
+		return
+	}
+
+	err = h.credits.HandleWebhook(r.Context(), payload, r.Header.Get("Stripe-Signature"))
+	switch {
+	case errors.Is(err, billing.ErrMissingSignature), errors.Is(err, billing.ErrInvalidSignature),
+		errors.Is(err, billing.ErrStaleSignature):
+		h.logger.Warn("Rejected Stripe webhook", zap.Error(err))
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, billing.ErrStripeNotConfigured):
+		h.sendError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		h.logger.Error("Failed to process Stripe webhook", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to process webhook")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]bool{"received": true})
+}
@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/data/deletion"
+	"github.com/amerfu/pllm/internal/services/monitoring/audit"
+)
+
+// DeletionHandler lists deleted users, teams and keys, restores them within
+// the retention window and runs the purge on demand
+type DeletionHandler struct {
+	baseHandler
+	db      *gorm.DB
+	service *deletion.Service
+}
+
+// NewDeletionHandler creates a new DeletionHandler.
+func NewDeletionHandler(logger *zap.Logger, db *gorm.DB, service *deletion.Service) *DeletionHandler {
+	return &DeletionHandler{
+		baseHandler: baseHandler{logger: logger},
+		db:          db,
+		service:     service,
+	}
+}
+
+// ListDeleted returns the deleted records that can still be restored, with
+// when each will be purged
+func (h *DeletionHandler) ListDeleted(w http.ResponseWriter, r *http.Request) {
+	records, err := h.service.ListDeleted(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list deleted records", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list deleted records")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"deleted":         records,
+		"retention_hours": h.service.Retention().Hours(),
+		"last_purge":      h.service.LastPurge(),
+	})
+}
+
+// Purge purges the expired records now instead of waiting for the interval
+func (h *DeletionHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	report := h.service.Purge(r.Context())
+	h.audit(r, audit.ActionDelete, audit.ResourceSettings, nil, map[string]interface{}{
+		"purge": true,
+		"keys":  report.Keys,
+		"teams": report.Teams,
+		"users": report.Users,
+	})
+	h.sendJSON(w, http.StatusOK, report)
+}
+
+// RestoreUser restores a deleted user and the keys deleted with them
+func (h *DeletionHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	user, err := h.service.RestoreUser(r.Context(), userID)
+	if !h.restored(w, r, audit.ResourceUser, userID, err) {
+		return
+	}
+	h.sendJSON(w, http.StatusOK, user)
+}
+
+// RestoreTeam restores a deleted team and the keys deleted with it
+func (h *DeletionHandler) RestoreTeam(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+	team, err := h.service.RestoreTeam(r.Context(), teamID)
+	if !h.restored(w, r, audit.ResourceTeam, teamID, err) {
+		return
+	}
+	h.sendJSON(w, http.StatusOK, team)
+}
+
+// RestoreKey restores a deleted key whose user and team are not deleted
+func (h *DeletionHandler) RestoreKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := uuid.Parse(chi.URLParam(r, "keyID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid key ID")
+		return
+	}
+	key, err := h.service.RestoreKey(r.Context(), keyID)
+	if !h.restored(w, r, audit.ResourceKey, keyID, err) {
+		return
+	}
+	h.sendJSON(w, http.StatusOK, key)
+}
+
+// restored sends the error of a restore, or audits it; it reports whether
+// the restore succeeded
+func (h *DeletionHandler) restored(w http.ResponseWriter, r *http.Request, resource string, id uuid.UUID, err error) bool {
+	switch {
+	case errors.Is(err, deletion.ErrNotFound):
+		h.sendError(w, http.StatusNotFound, "No deleted "+resource+" with this ID; it may have been purged")
+		return false
+	case errors.Is(err, deletion.ErrOwnerDeleted):
+		h.sendError(w, http.StatusConflict, err.Error())
+		return false
+	case err != nil:
+		h.logger.Error("Failed to restore deleted record", zap.String("resource", resource), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to restore "+resource)
+		return false
+	}
+	h.audit(r, audit.ActionUpdate, resource, &id, map[string]interface{}{"restored": true})
+	return true
+}
+
+func (h *DeletionHandler) audit(r *http.Request, action, resource string, resourceID *uuid.UUID, details map[string]interface{}) {
+	var actor *uuid.UUID
+	if userID, ok := middleware.GetUserID(r.Context()); ok && userID != uuid.Nil {
+		actor = &userID
+	}
+	if err := audit.NewLogger(h.db).LogEvent(r.Context(), actor, nil, audit.AuditEvent{
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Details:    details,
+		IPAddress:  r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}); err != nil {
+		h.logger.Warn("Failed to log deletion audit", zap.Error(err))
+	}
+}
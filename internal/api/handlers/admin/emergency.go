@@ -0,0 +1,198 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/data/settings"
+	"github.com/amerfu/pllm/internal/services/monitoring/audit"
+)
+
+// GetEmergencyControls returns the global pause, the disabled models and the
+// suspended teams
+func (h *SystemHandler) GetEmergencyControls(w http.ResponseWriter, r *http.Request) {
+	controls := settings.EmergencyControls{}.Clone()
+	if h.settings != nil {
+		controls = h.settings.EmergencyControls()
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"emergency": controls,
+		"editable":  h.settings != nil,
+	})
+}
+
+// PauseGateway rejects every inference request on every replica with the
+// given maintenance message until the gateway is resumed
+func (h *SystemHandler) PauseGateway(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if !h.decodeOptionalBody(w, r, &req) {
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+
+	h.updateEmergencyControls(w, r, "pause", map[string]interface{}{"message": message}, func(controls *settings.EmergencyControls) error {
+		controls.Paused = true
+		controls.PauseMessage = message
+		return nil
+	})
+}
+
+// ResumeGateway lifts the global pause
+func (h *SystemHandler) ResumeGateway(w http.ResponseWriter, r *http.Request) {
+	h.updateEmergencyControls(w, r, "resume", nil, func(controls *settings.EmergencyControls) error {
+		controls.Paused = false
+		controls.PauseMessage = ""
+		return nil
+	})
+}
+
+// DisableModel switches a model off on every replica. Requests naming it are
+// rejected and routes, fallbacks and tiers skip it.
+func (h *SystemHandler) DisableModel(w http.ResponseWriter, r *http.Request) {
+	model := chi.URLParam(r, "*")
+	if model == "" {
+		h.sendError(w, http.StatusBadRequest, "Model name is required")
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if !h.decodeOptionalBody(w, r, &req) {
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+
+	h.updateEmergencyControls(w, r, "disable_model", map[string]interface{}{"model": model, "reason": reason}, func(controls *settings.EmergencyControls) error {
+		controls.DisabledModels[model] = reason
+		return nil
+	})
+}
+
+// EnableModel switches a disabled model back on
+func (h *SystemHandler) EnableModel(w http.ResponseWriter, r *http.Request) {
+	model := chi.URLParam(r, "*")
+	h.updateEmergencyControls(w, r, "enable_model", map[string]interface{}{"model": model}, func(controls *settings.EmergencyControls) error {
+		if _, disabled := controls.DisabledModels[model]; !disabled {
+			return errControlNotSet
+		}
+		delete(controls.DisabledModels, model)
+		return nil
+	})
+}
+
+// SuspendTeam rejects the requests of every key of a team on every replica
+func (h *SystemHandler) SuspendTeam(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if !h.decodeOptionalBody(w, r, &req) {
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+
+	if err := h.db.WithContext(r.Context()).Select("id").First(&models.Team{}, "id = ?", teamID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.sendError(w, http.StatusNotFound, "Team not found")
+			return
+		}
+		h.logger.Error("Failed to get team", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to suspend team")
+		return
+	}
+
+	h.updateEmergencyControls(w, r, "suspend_team", map[string]interface{}{"team_id": teamID, "reason": reason}, func(controls *settings.EmergencyControls) error {
+		controls.SuspendedTeams[teamID.String()] = reason
+		return nil
+	})
+}
+
+// UnsuspendTeam lifts a team's suspension
+func (h *SystemHandler) UnsuspendTeam(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+	h.updateEmergencyControls(w, r, "unsuspend_team", map[string]interface{}{"team_id": teamID}, func(controls *settings.EmergencyControls) error {
+		if _, suspended := controls.SuspendedTeams[teamID.String()]; !suspended {
+			return errControlNotSet
+		}
+		delete(controls.SuspendedTeams, teamID.String())
+		return nil
+	})
+}
+
+// errControlNotSet reports lifting a control that is not in place
+var errControlNotSet = errors.New("emergency control not set")
+
+// updateEmergencyControls applies mutate to the emergency controls of every
+// replica and audits the change
+func (h *SystemHandler) updateEmergencyControls(w http.ResponseWriter, r *http.Request, operation string, details map[string]interface{}, mutate func(*settings.EmergencyControls) error) {
+	if h.settings == nil {
+		h.sendError(w, http.StatusNotImplemented, "Emergency controls can only be changed with a database")
+		return
+	}
+
+	var actor *uuid.UUID
+	if userID, ok := middleware.GetUserID(r.Context()); ok && userID != uuid.Nil {
+		actor = &userID
+	}
+	controls, err := h.settings.UpdateEmergencyControls(r.Context(), mutate, actor)
+	if errors.Is(err, errControlNotSet) {
+		h.sendError(w, http.StatusNotFound, "No such emergency control is in place")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update emergency controls", zap.String("operation", operation), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to update emergency controls")
+		return
+	}
+
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["emergency"] = operation
+	if err := h.auditLogger.LogEvent(r.Context(), actor, nil, audit.AuditEvent{
+		Action:    audit.ActionUpdate,
+		Resource:  audit.ResourceSettings,
+		Details:   details,
+		IPAddress: r.RemoteAddr,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	}); err != nil {
+		h.logger.Warn("Failed to log emergency controls audit", zap.Error(err))
+	}
+	h.logger.Warn("Emergency controls changed",
+		zap.String("operation", operation),
+		zap.Any("details", details))
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"emergency": controls,
+	})
+}
+
+// decodeOptionalBody decodes a JSON body into v; an empty body is allowed
+func (h *SystemHandler) decodeOptionalBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return false
+	}
+	return true
+}
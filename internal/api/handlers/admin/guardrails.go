@@ -46,10 +46,12 @@ type GuardrailStatsInfo struct {
 	TotalExecutions int64         `json:"total_executions"`
 	TotalPassed     int64         `json:"total_passed"`
 	TotalBlocked    int64         `json:"total_blocked"`
+	TotalTriggered  int64         `json:"total_triggered"`
 	TotalErrors     int64         `json:"total_errors"`
 	AverageLatency  int64         `json:"average_latency"` // Average latency in milliseconds
 	LastExecuted    time.Time     `json:"last_executed"`
 	BlockRate       float64       `json:"block_rate"`
+	TriggerRate     float64       `json:"trigger_rate"`
 	ErrorRate       float64       `json:"error_rate"`
 }
 
@@ -85,6 +87,7 @@ func (h *GuardrailsHandler) ListGuardrails(w http.ResponseWriter, r *http.Reques
 					TotalExecutions: railStats.TotalExecutions,
 					TotalPassed:     railStats.TotalPassed,
 					TotalBlocked:    railStats.TotalBlocked,
+					TotalTriggered:  railStats.TotalTriggered,
 					TotalErrors:     railStats.TotalErrors,
 					AverageLatency:  int64(railStats.AverageLatency / time.Millisecond),
 					LastExecuted:    railStats.LastExecuted,
@@ -93,6 +96,7 @@ func (h *GuardrailsHandler) ListGuardrails(w http.ResponseWriter, r *http.Reques
 				// Calculate rates
 				if railStats.TotalExecutions > 0 {
 					info.Stats.BlockRate = float64(railStats.TotalBlocked) / float64(railStats.TotalExecutions)
+					info.Stats.TriggerRate = float64(railStats.TotalTriggered) / float64(railStats.TotalExecutions)
 					info.Stats.ErrorRate = float64(railStats.TotalErrors) / float64(railStats.TotalExecutions)
 				}
 			}
@@ -170,6 +174,7 @@ func (h *GuardrailsHandler) GetGuardrail(w http.ResponseWriter, r *http.Request)
 				TotalExecutions: railStats.TotalExecutions,
 				TotalPassed:     railStats.TotalPassed,
 				TotalBlocked:    railStats.TotalBlocked,
+				TotalTriggered:  railStats.TotalTriggered,
 				TotalErrors:     railStats.TotalErrors,
 				AverageLatency:  int64(railStats.AverageLatency / time.Millisecond),
 				LastExecuted:    railStats.LastExecuted,
@@ -178,6 +183,7 @@ func (h *GuardrailsHandler) GetGuardrail(w http.ResponseWriter, r *http.Request)
 			// Calculate rates
 			if railStats.TotalExecutions > 0 {
 				info.Stats.BlockRate = float64(railStats.TotalBlocked) / float64(railStats.TotalExecutions)
+				info.Stats.TriggerRate = float64(railStats.TotalTriggered) / float64(railStats.TotalExecutions)
 				info.Stats.ErrorRate = float64(railStats.TotalErrors) / float64(railStats.TotalExecutions)
 			}
 		}
@@ -227,6 +233,7 @@ func (h *GuardrailsHandler) GetGuardrailStats(w http.ResponseWriter, r *http.Req
 			TotalExecutions: railStats.TotalExecutions,
 			TotalPassed:     railStats.TotalPassed,
 			TotalBlocked:    railStats.TotalBlocked,
+			TotalTriggered:  railStats.TotalTriggered,
 			TotalErrors:     railStats.TotalErrors,
 			AverageLatency:  int64(railStats.AverageLatency / time.Millisecond),
 			LastExecuted:    railStats.LastExecuted,
@@ -235,6 +242,7 @@ func (h *GuardrailsHandler) GetGuardrailStats(w http.ResponseWriter, r *http.Req
 		// Calculate rates
 		if railStats.TotalExecutions > 0 {
 			info.BlockRate = float64(railStats.TotalBlocked) / float64(railStats.TotalExecutions)
+			info.TriggerRate = float64(railStats.TotalTriggered) / float64(railStats.TotalExecutions)
 			info.ErrorRate = float64(railStats.TotalErrors) / float64(railStats.TotalExecutions)
 		}
 
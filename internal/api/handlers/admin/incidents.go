@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// IncidentHandler serves upstream provider incidents recorded by the outage
+// watcher
+type IncidentHandler struct {
+	baseHandler
+	db *gorm.DB
+}
+
+// NewIncidentHandler creates a new IncidentHandler.
+func NewIncidentHandler(logger *zap.Logger, db *gorm.DB) *IncidentHandler {
+	return &IncidentHandler{
+		baseHandler: baseHandler{logger: logger},
+		db:          db,
+	}
+}
+
+// ListIncidents returns recorded incidents, most recent first. The status
+// and provider query parameters filter them; limit defaults to 50.
+func (h *IncidentHandler) ListIncidents(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 500 {
+			h.sendError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = parsed
+	}
+
+	query := h.db.Order("started_at DESC").Limit(limit)
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if provider := r.URL.Query().Get("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+
+	var incidents []models.Incident
+	if err := query.Find(&incidents).Error; err != nil {
+		h.logger.Error("Failed to list incidents", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list incidents")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"incidents": incidents,
+		"count":     len(incidents),
+	})
+}
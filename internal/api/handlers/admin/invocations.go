@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// InvocationsHandler exposes the provider invocations captured by this
+// replica: the sanitized payloads sent upstream and the status and headers
+// that came back, to debug translation bugs reported by users
+type InvocationsHandler struct {
+	baseHandler
+}
+
+func NewInvocationsHandler(logger *zap.Logger) *InvocationsHandler {
+	return &InvocationsHandler{baseHandler: baseHandler{logger: logger}}
+}
+
+// ListInvocations lists the retained invocations, newest first, filtered by
+// request_id, provider and min_status
+func (h *InvocationsHandler) ListInvocations(w http.ResponseWriter, r *http.Request) {
+	capture := providers.Invocations()
+	if capture == nil {
+		h.sendError(w, http.StatusNotFound, "Invocation logging is disabled")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := providers.InvocationFilter{
+		RequestID: query.Get("request_id"),
+		Provider:  query.Get("provider"),
+		Limit:     100,
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			h.sendError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("min_status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "min_status must be an integer")
+			return
+		}
+		filter.MinStatus = status
+	}
+
+	invocations := capture.List(filter)
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"invocations":       invocations,
+		"count":             len(invocations),
+		"retention_seconds": capture.Retention().Seconds(),
+	})
+}
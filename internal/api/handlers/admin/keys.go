@@ -16,6 +16,7 @@ import (
 	"github.com/amerfu/pllm/internal/core/models"
 	"github.com/amerfu/pllm/internal/services/monitoring/audit"
 	"github.com/amerfu/pllm/internal/services/data/budget"
+	"github.com/amerfu/pllm/internal/services/data/deletion"
 	"github.com/amerfu/pllm/internal/services/integrations/key"
 )
 
@@ -26,6 +27,7 @@ type KeyHandler struct {
 	auditLogger   *audit.Logger
 	budgetService budget.Service
 	keyGenerator  *key.KeyGenerator
+	deletion      *deletion.Service
 }
 
 func NewKeyHandler(logger *zap.Logger, db *gorm.DB, budgetService budget.Service) *KeyHandler {
@@ -38,19 +40,34 @@ func NewKeyHandler(logger *zap.Logger, db *gorm.DB, budgetService budget.Service
 	}
 }
 
+// SetDeletion makes DeleteKey go through the deletion service, which keeps
+// deleted keys restorable until the purge
+func (h *KeyHandler) SetDeletion(service *deletion.Service) {
+	h.deletion = service
+}
+
 type CreateKeyRequest struct {
-	Name             string               `json:"name" validate:"required,min=1,max=100"`
-	KeyType          string               `json:"key_type" validate:"required,oneof=api virtual system"`
-	UserID           *uuid.UUID           `json:"user_id,omitempty"`
-	TeamID           *uuid.UUID           `json:"team_id,omitempty"`
-	ExpiresAt        *time.Time           `json:"expires_at,omitempty"`
-	MaxBudget        *float64             `json:"max_budget,omitempty"`
-	BudgetDuration   *models.BudgetPeriod `json:"budget_duration,omitempty"`
+	Name              string               `json:"name" validate:"required,min=1,max=100"`
+	KeyType           string               `json:"key_type" validate:"required,oneof=api virtual system"`
+	UserID            *uuid.UUID           `json:"user_id,omitempty"`
+	TeamID            *uuid.UUID           `json:"team_id,omitempty"`
+	ExpiresAt         *time.Time           `json:"expires_at,omitempty"`
+	MaxBudget         *float64             `json:"max_budget,omitempty"`
+	BudgetDuration    *models.BudgetPeriod `json:"budget_duration,omitempty"`
+	MaxCostPerRequest *float64             `json:"max_cost_per_request,omitempty"`
+	Scopes            []string             `json:"scopes,omitempty"`
+	RequireSignature  bool                 `json:"require_signature,omitempty"`
+	CacheOutputs      bool                 `json:"cache_outputs,omitempty"`
+
+	ResponseMinimization []string `json:"response_minimization,omitempty"`
+	RequestValidation    string   `json:"request_validation,omitempty"`
+	Environment          string   `json:"environment,omitempty"`
 }
 
 type KeyResponse struct {
 	models.Key
-	PlaintextKey string `json:"plaintext_key,omitempty"` // Only returned on creation
+	PlaintextKey  string `json:"plaintext_key,omitempty"`  // Only returned on creation
+	SigningSecret string `json:"signing_secret,omitempty"` // Only returned on creation
 	Usage        struct {
 		TotalRequests int64      `json:"total_requests"`
 		TotalCost     float64    `json:"total_cost"`
@@ -75,6 +92,22 @@ func (h *KeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, "Invalid key type")
 		return
 	}
+	if err := models.ValidateScopes(req.Scopes); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := models.ValidateResponseMinimization(req.ResponseMinimization); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := models.ValidateRequestValidation(req.RequestValidation); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := models.ValidateKeyEnvironment(req.Environment); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Generate the key
 	var plaintextKey, hashedKey string
@@ -97,23 +130,39 @@ func (h *KeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var signingSecret string
+	if req.RequireSignature {
+		if signingSecret, err = models.GenerateSigningSecret(); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "Failed to generate signing secret")
+			return
+		}
+	}
+
 	// Get current user from context for audit
 	currentUserID, hasUserID := middleware.GetUserID(r.Context())
 	
 	// Create key record
 	k := models.Key{
-		BaseModel:      models.BaseModel{ID: uuid.New()},
-		Key:            plaintextKey, // Store plaintext for unique constraint
-		Name:           req.Name,
-		KeyHash:        hashedKey,
-		Type:           models.KeyType(req.KeyType),
-		ExpiresAt:      req.ExpiresAt,
-		IsActive:       true,
-		UserID:         req.UserID,    // Key owner (can be nil for system keys)
-		TeamID:         req.TeamID,
-		MaxBudget:      req.MaxBudget,
-		BudgetDuration: req.BudgetDuration,
-		CreatedBy:      nil, // Will be set below based on auth type
+		BaseModel:            models.BaseModel{ID: uuid.New()},
+		Key:                  plaintextKey, // Store plaintext for unique constraint
+		Name:                 req.Name,
+		KeyHash:              hashedKey,
+		Type:                 models.KeyType(req.KeyType),
+		ExpiresAt:            req.ExpiresAt,
+		IsActive:             true,
+		UserID:               req.UserID, // Key owner (can be nil for system keys)
+		TeamID:               req.TeamID,
+		MaxBudget:            req.MaxBudget,
+		BudgetDuration:       req.BudgetDuration,
+		MaxCostPerRequest:    req.MaxCostPerRequest,
+		Scopes:               req.Scopes,
+		RequireSignature:     req.RequireSignature,
+		SigningSecret:        signingSecret,
+		CacheOutputs:         req.CacheOutputs,
+		ResponseMinimization: req.ResponseMinimization,
+		RequestValidation:    req.RequestValidation,
+		Environment:          req.Environment,
+		CreatedBy:            nil, // Will be set below based on auth type
 	}
 	
 	// Set CreatedBy based on authentication type
@@ -142,8 +191,9 @@ func (h *KeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
 	// }
 
 	response := KeyResponse{
-		Key:          k,
-		PlaintextKey: plaintextKey,
+		Key:           k,
+		PlaintextKey:  plaintextKey,
+		SigningSecret: signingSecret,
 	}
 
 	h.sendJSON(w, http.StatusCreated, response)
@@ -172,6 +222,10 @@ func (h *KeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if environment := r.URL.Query().Get("environment"); environment != "" {
+		query = query.Where("environment = ?", environment)
+	}
+
 	if keyType := r.URL.Query().Get("key_type"); keyType != "" {
 		query = query.Where("key_type = ?", keyType)
 	}
@@ -275,9 +329,25 @@ func (h *KeyHandler) GetKey(w http.ResponseWriter, r *http.Request) {
 }
 
 type UpdateKeyRequest struct {
-	Name      *string    `json:"name,omitempty"`
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
-	IsActive  *bool      `json:"is_active,omitempty"`
+	Name              *string    `json:"name,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	IsActive          *bool      `json:"is_active,omitempty"`
+	MaxCostPerRequest *float64   `json:"max_cost_per_request,omitempty"`
+	Scopes            *[]string  `json:"scopes,omitempty"` // Empty list removes all restrictions
+	RequireSignature  *bool      `json:"require_signature,omitempty"`
+	CacheOutputs      *bool      `json:"cache_outputs,omitempty"`
+
+	// ResponseMinimization replaces the key's options; an empty list
+	// falls back to the team's
+	ResponseMinimization *[]string `json:"response_minimization,omitempty"`
+
+	// RequestValidation sets the key's request validation mode; an empty
+	// string turns validation off
+	RequestValidation *string `json:"request_validation,omitempty"`
+
+	// Environment moves the key to another environment; an empty string
+	// removes it from its environment
+	Environment *string `json:"environment,omitempty"`
 }
 
 // UpdateKey updates a key
@@ -293,6 +363,30 @@ func (h *KeyHandler) UpdateKey(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	if req.Scopes != nil {
+		if err := models.ValidateScopes(*req.Scopes); err != nil {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.ResponseMinimization != nil {
+		if err := models.ValidateResponseMinimization(*req.ResponseMinimization); err != nil {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.RequestValidation != nil {
+		if err := models.ValidateRequestValidation(*req.RequestValidation); err != nil {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.Environment != nil {
+		if err := models.ValidateKeyEnvironment(*req.Environment); err != nil {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	var k models.Key
 	if err := h.db.First(&k, keyID).Error; err != nil {
@@ -329,6 +423,50 @@ func (h *KeyHandler) UpdateKey(w http.ResponseWriter, r *http.Request) {
 		k.IsActive = *req.IsActive
 	}
 
+	if req.MaxCostPerRequest != nil {
+		changes["max_cost_per_request"] = map[string]interface{}{"from": k.MaxCostPerRequest, "to": *req.MaxCostPerRequest}
+		if *req.MaxCostPerRequest > 0 {
+			k.MaxCostPerRequest = req.MaxCostPerRequest
+		} else {
+			// Zero or negative removes the ceiling
+			k.MaxCostPerRequest = nil
+		}
+	}
+
+	if req.Scopes != nil {
+		changes["scopes"] = map[string]interface{}{"from": k.Scopes, "to": *req.Scopes}
+		k.Scopes = *req.Scopes
+	}
+
+	if req.RequireSignature != nil && *req.RequireSignature != k.RequireSignature {
+		if *req.RequireSignature && k.SigningSecret == "" {
+			h.sendError(w, http.StatusBadRequest, "Generate a signing secret before requiring signatures")
+			return
+		}
+		changes["require_signature"] = map[string]bool{"from": k.RequireSignature, "to": *req.RequireSignature}
+		k.RequireSignature = *req.RequireSignature
+	}
+
+	if req.CacheOutputs != nil && *req.CacheOutputs != k.CacheOutputs {
+		changes["cache_outputs"] = map[string]bool{"from": k.CacheOutputs, "to": *req.CacheOutputs}
+		k.CacheOutputs = *req.CacheOutputs
+	}
+
+	if req.ResponseMinimization != nil {
+		changes["response_minimization"] = map[string]interface{}{"from": k.ResponseMinimization, "to": *req.ResponseMinimization}
+		k.ResponseMinimization = *req.ResponseMinimization
+	}
+
+	if req.RequestValidation != nil && *req.RequestValidation != k.RequestValidation {
+		changes["request_validation"] = map[string]string{"from": k.RequestValidation, "to": *req.RequestValidation}
+		k.RequestValidation = *req.RequestValidation
+	}
+
+	if req.Environment != nil && *req.Environment != k.Environment {
+		changes["environment"] = map[string]string{"from": k.Environment, "to": *req.Environment}
+		k.Environment = *req.Environment
+	}
+
 	if err := h.db.Save(&k).Error; err != nil {
 		h.sendError(w, http.StatusInternalServerError, "Failed to update key")
 		return
@@ -363,7 +501,7 @@ func (h *KeyHandler) UpdateKey(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, k)
 }
 
-// DeleteKey deletes a key (hard delete)
+// DeleteKey soft deletes a key
 func (h *KeyHandler) DeleteKey(w http.ResponseWriter, r *http.Request) {
 	keyID, err := uuid.Parse(chi.URLParam(r, "keyID"))
 	if err != nil {
@@ -381,8 +519,11 @@ func (h *KeyHandler) DeleteKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Hard delete the key record
-	if err := h.db.Delete(&k).Error; err != nil {
+	deleteKey := func() error { return h.db.Delete(&k).Error }
+	if h.deletion != nil {
+		deleteKey = func() error { return h.deletion.DeleteKey(r.Context(), k.ID) }
+	}
+	if err := deleteKey(); err != nil {
 		h.sendError(w, http.StatusInternalServerError, "Failed to delete key")
 		return
 	}
@@ -407,6 +548,58 @@ func (h *KeyHandler) DeleteKey(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Key deleted successfully"})
 }
 
+// RotateSigningSecret generates a new signing secret for a key and requires
+// its requests to be signed. The secret is only returned here.
+func (h *KeyHandler) RotateSigningSecret(w http.ResponseWriter, r *http.Request) {
+	keyID, err := uuid.Parse(chi.URLParam(r, "keyID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid key ID")
+		return
+	}
+
+	var k models.Key
+	if err := h.db.First(&k, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			h.sendError(w, http.StatusNotFound, "Key not found")
+			return
+		}
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch key")
+		return
+	}
+
+	secret, err := models.GenerateSigningSecret()
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to generate signing secret")
+		return
+	}
+	if err := h.db.Model(&k).Updates(map[string]interface{}{
+		"signing_secret":    secret,
+		"require_signature": true,
+	}).Error; err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to update key")
+		return
+	}
+
+	var actor *uuid.UUID
+	if userID, ok := middleware.GetUserID(r.Context()); ok && userID != uuid.Nil && !middleware.IsMasterKey(r.Context()) {
+		actor = &userID
+	}
+	if err := h.auditLogger.LogEvent(r.Context(), actor, k.TeamID, audit.AuditEvent{
+		Action:     audit.ActionUpdate,
+		Resource:   audit.ResourceKey,
+		ResourceID: &k.ID,
+		Details:    map[string]interface{}{"signing_secret": "rotated", "require_signature": true},
+	}); err != nil {
+		h.logger.Warn("Failed to log signing secret rotation", zap.Error(err))
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"key_id":            k.ID,
+		"require_signature": true,
+		"signing_secret":    secret,
+	})
+}
+
 // RevokeKey revokes a key (alias for delete)
 func (h *KeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
 	h.DeleteKey(w, r)
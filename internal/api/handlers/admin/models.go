@@ -126,9 +126,20 @@ func (h *ModelCRUDHandler) ListModels(w http.ResponseWriter, r *http.Request) {
 			if maskedProvider.AWSSecretAccessKey != "" {
 				maskedProvider.AWSSecretAccessKey = "********"
 			}
+			if maskedProvider.AWSSessionToken != "" {
+				maskedProvider.AWSSessionToken = "********"
+			}
 			if maskedProvider.OAuthToken != "" {
 				maskedProvider.OAuthToken = "********"
 			}
+			// Header values often carry credentials
+			if len(maskedProvider.Headers) > 0 {
+				headers := make(map[string]string, len(maskedProvider.Headers))
+				for key := range maskedProvider.Headers {
+					headers[key] = "********"
+				}
+				maskedProvider.Headers = headers
+			}
 
 			resp := modelResponse{
 				ID:                 um.ID.String(),
@@ -235,6 +246,10 @@ func (h *ModelCRUDHandler) CreateModel(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := req.ModelInfo.ValidateLinks(); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	enabled := true
 	if req.Enabled != nil {
@@ -310,6 +325,9 @@ func (h *ModelCRUDHandler) GetModel(w http.ResponseWriter, r *http.Request) {
 		if maskedProvider.AWSSecretAccessKey != "" {
 			maskedProvider.AWSSecretAccessKey = "********"
 		}
+		if maskedProvider.AWSSessionToken != "" {
+			maskedProvider.AWSSessionToken = "********"
+		}
 		if maskedProvider.OAuthToken != "" {
 			maskedProvider.OAuthToken = "********"
 		}
@@ -436,6 +454,9 @@ func (h *ModelCRUDHandler) UpdateModel(w http.ResponseWriter, r *http.Request) {
 		if req.Provider.AzureEndpoint != "" {
 			merged.AzureEndpoint = req.Provider.AzureEndpoint
 		}
+		if req.Provider.AzureBaseModel != "" {
+			merged.AzureBaseModel = req.Provider.AzureBaseModel
+		}
 		if req.Provider.AWSAccessKeyID != "" {
 			merged.AWSAccessKeyID = req.Provider.AWSAccessKeyID
 		}
@@ -445,21 +466,56 @@ func (h *ModelCRUDHandler) UpdateModel(w http.ResponseWriter, r *http.Request) {
 		if req.Provider.AWSRegionName != "" {
 			merged.AWSRegionName = req.Provider.AWSRegionName
 		}
+		if req.Provider.AWSSessionToken != "" {
+			merged.AWSSessionToken = req.Provider.AWSSessionToken
+		}
+		if req.Provider.AWSProfileName != "" {
+			merged.AWSProfileName = req.Provider.AWSProfileName
+		}
+		if req.Provider.AWSRoleARN != "" {
+			merged.AWSRoleARN = req.Provider.AWSRoleARN
+		}
+		if req.Provider.AWSExternalID != "" {
+			merged.AWSExternalID = req.Provider.AWSExternalID
+		}
+		if req.Provider.AWSRoleSessionName != "" {
+			merged.AWSRoleSessionName = req.Provider.AWSRoleSessionName
+		}
 		if req.Provider.VertexProject != "" {
 			merged.VertexProject = req.Provider.VertexProject
 		}
 		if req.Provider.VertexLocation != "" {
 			merged.VertexLocation = req.Provider.VertexLocation
 		}
+		if req.Provider.SpaceID != "" {
+			merged.SpaceID = req.Provider.SpaceID
+		}
+		if req.Provider.IAMURL != "" {
+			merged.IAMURL = req.Provider.IAMURL
+		}
+		if req.Provider.EmbeddingInputType != "" {
+			merged.EmbeddingInputType = req.Provider.EmbeddingInputType
+		}
 		if req.Provider.ReasoningEffort != "" {
 			merged.ReasoningEffort = req.Provider.ReasoningEffort
 		}
 		if req.Provider.OAuthToken != "" {
 			merged.OAuthToken = req.Provider.OAuthToken
 		}
+		if len(req.Provider.Headers) > 0 {
+			merged.Headers = req.Provider.Headers
+		}
+		if len(req.Provider.Paths) > 0 {
+			merged.Paths = req.Provider.Paths
+		}
 		updates["provider_config"] = merged
 	}
-	if req.ModelInfo.Mode != "" || req.ModelInfo.SupportsStreaming || req.ModelInfo.SupportsFunctions || req.ModelInfo.SupportsVision {
+	if req.ModelInfo.Mode != "" || req.ModelInfo.SupportsStreaming || req.ModelInfo.SupportsFunctions || req.ModelInfo.SupportsVision ||
+		req.ModelInfo.EmbeddingDimensions != 0 || req.ModelInfo.SupportsDimensions || req.ModelInfo.HasDocumentation() {
+		if err := req.ModelInfo.ValidateLinks(); err != nil {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		updates["model_info_config"] = req.ModelInfo
 	}
 	if req.RPM != 0 {
@@ -580,15 +636,35 @@ func convertProviderConfigToParams(p models.ProviderConfigJSON) config.ProviderP
 		Location:           p.Location,
 		AzureDeployment:    p.AzureDeployment,
 		AzureEndpoint:      p.AzureEndpoint,
+		AzureBaseModel:     p.AzureBaseModel,
 		AWSAccessKeyID:     expandEnvVars(p.AWSAccessKeyID),
 		AWSSecretAccessKey: expandEnvVars(p.AWSSecretAccessKey),
 		AWSRegionName:      p.AWSRegionName,
+		AWSSessionToken:    expandEnvVars(p.AWSSessionToken),
+		AWSProfileName:     p.AWSProfileName,
+		AWSRoleARN:         p.AWSRoleARN,
+		AWSExternalID:      p.AWSExternalID,
+		AWSRoleSessionName: p.AWSRoleSessionName,
 		VertexProject:      p.VertexProject,
 		VertexLocation:     p.VertexLocation,
 		ReasoningEffort:    p.ReasoningEffort,
+		Headers:            expandHeaders(p.Headers),
+		Paths:              p.Paths,
 	}
 }
 
+// expandHeaders expands environment variable references in header values
+func expandHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	expanded := make(map[string]string, len(headers))
+	for key, value := range headers {
+		expanded[key] = expandEnvVars(value)
+	}
+	return expanded
+}
+
 // TestConnection tests provider connectivity without persisting anything.
 func (h *ModelCRUDHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
 	var req TestConnectionRequest
@@ -687,6 +763,40 @@ func (h *ModelCRUDHandler) GetModelsHealth(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// GetScalingSignals returns the autoscaling signals of the models. With a
+// model query parameter it returns that model's signal alone, a flat object
+// KEDA's metrics-api scaler can read a value from.
+func (h *ModelCRUDHandler) GetScalingSignals(w http.ResponseWriter, r *http.Request) {
+	signals := h.modelManager.ScalingSignals()
+
+	if model := r.URL.Query().Get("model"); model != "" {
+		for _, signal := range signals {
+			if signal.Model == model {
+				h.sendResponse(w, http.StatusOK, signal)
+				return
+			}
+		}
+		h.sendError(w, http.StatusNotFound, "Model not found")
+		return
+	}
+
+	h.sendResponse(w, http.StatusOK, map[string]interface{}{
+		"models": signals,
+		"total":  len(signals),
+	})
+}
+
+// GetReservedCapacity returns the utilization of the models' reserved
+// capacity (provisioned throughput) on this replica and how often requests
+// overflowed to pay-as-you-go instances
+func (h *ModelCRUDHandler) GetReservedCapacity(w http.ResponseWriter, r *http.Request) {
+	capacity := h.modelManager.ReservedCapacity()
+	h.sendResponse(w, http.StatusOK, map[string]interface{}{
+		"models": capacity,
+		"total":  len(capacity),
+	})
+}
+
 // DiscoverModelsRequest is the request body for discovering available models.
 type DiscoverModelsRequest struct {
 	Provider models.ProviderConfigJSON `json:"provider"`
@@ -761,8 +871,9 @@ func validateProviderConfig(p models.ProviderConfigJSON) error {
 			return fmt.Errorf("endpoint URL is required for Azure OpenAI (set base_url or azure_endpoint)")
 		}
 	case "bedrock":
-		if p.AWSAccessKeyID == "" || p.AWSSecretAccessKey == "" {
-			return fmt.Errorf("AWS access key ID and secret access key are required for Bedrock")
+		// Without static keys the default AWS credential chain is used
+		if (p.AWSAccessKeyID == "") != (p.AWSSecretAccessKey == "") {
+			return fmt.Errorf("AWS access key ID and secret access key must be set together for Bedrock")
 		}
 	case "vertex":
 		if p.APIKey == "" {
@@ -772,6 +883,47 @@ func validateProviderConfig(p models.ProviderConfigJSON) error {
 		if p.APIKey == "" {
 			return fmt.Errorf("API key is required for OpenRouter")
 		}
+	case "nim", "triton":
+		if p.BaseURL == "" {
+			return fmt.Errorf("base URL of the inference server is required for %s", p.Type)
+		}
+	case "watsonx":
+		if p.APIKey == "" || p.BaseURL == "" {
+			return fmt.Errorf("API key and regional endpoint URL are required for watsonx.ai")
+		}
+		if p.ProjectID == "" && p.SpaceID == "" {
+			return fmt.Errorf("project_id or space_id is required for watsonx.ai")
+		}
+	case "azure_mistral":
+		if p.APIKey == "" || p.BaseURL == "" {
+			return fmt.Errorf("API key and endpoint URL are required for Azure Mistral")
+		}
+	case "ollama":
+		// The base URL defaults to a local server and the API key is
+		// optional
+	case "dashscope":
+		if p.APIKey == "" {
+			return fmt.Errorf("API key is required for DashScope")
+		}
+	case "qianfan":
+		if p.APIKey == "" || p.APISecret == "" {
+			return fmt.Errorf("API key and secret key (api_secret) are required for Qianfan")
+		}
+	case "perplexity":
+		if p.APIKey == "" {
+			return fmt.Errorf("API key is required for Perplexity")
+		}
+	case "custom-openai":
+		// The API key is optional; servers without one are reached with
+		// the configured headers only
+		if p.BaseURL == "" {
+			return fmt.Errorf("base URL of the server is required for custom-openai")
+		}
+		for endpoint := range p.Paths {
+			if _, ok := providers.CustomOpenAIPaths[endpoint]; !ok {
+				return fmt.Errorf("unknown custom-openai endpoint in paths: %s", endpoint)
+			}
+		}
 	case "openai":
 		// OpenAI doesn't strictly require an API key at construction time
 		// (it's used in requests), but we warn if missing
@@ -0,0 +1,184 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+)
+
+const (
+	// notificationsHeartbeat keeps idle streams open through proxies
+	notificationsHeartbeat = 25 * time.Second
+	// notificationsWriteWait bounds a single WebSocket write
+	notificationsWriteWait = 10 * time.Second
+)
+
+// NotificationsHandler pushes budget alerts, circuit breaker trips, worker
+// failures and user signups to the admin dashboard as they happen
+type NotificationsHandler struct {
+	baseHandler
+	hub            *notifications.Hub
+	allowedOrigins []string
+	upgrader       websocket.Upgrader
+}
+
+// NewNotificationsHandler creates a NotificationsHandler. WebSocket
+// connections are accepted from the gateway's own origin and from
+// allowedOrigins; a wildcard entry is ignored because the dashboard
+// authenticates with a session cookie.
+func NewNotificationsHandler(logger *zap.Logger, hub *notifications.Hub, allowedOrigins []string) *NotificationsHandler {
+	h := &NotificationsHandler{
+		baseHandler:    baseHandler{logger: logger},
+		hub:            hub,
+		allowedOrigins: allowedOrigins,
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		CheckOrigin:     h.checkOrigin,
+	}
+	return h
+}
+
+// Stream delivers notifications over a WebSocket when the request asks for
+// an upgrade, and as Server-Sent Events otherwise
+func (h *NotificationsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	if h.hub == nil {
+		h.sendError(w, http.StatusServiceUnavailable, "Notifications are not available")
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		h.streamWebSocket(w, r)
+		return
+	}
+	h.streamSSE(w, r)
+}
+
+func (h *NotificationsHandler) streamSSE(w http.ResponseWriter, r *http.Request) {
+	// Subscribe before answering so nothing is missed once the client sees the stream
+	ch, unsubscribe := h.hub.Subscribe()
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		h.logger.Error("Notifications stream does not support flushing", zap.Error(err))
+		return
+	}
+
+	heartbeat := time.NewTicker(notificationsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case n, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(n)
+			if err != nil {
+				h.logger.Error("Failed to encode notification", zap.Error(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", n.ID, n.Kind, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *NotificationsHandler) streamWebSocket(w http.ResponseWriter, r *http.Request) {
+	ch, unsubscribe := h.hub.Subscribe()
+	defer unsubscribe()
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written the error response
+		h.logger.Debug("Notifications WebSocket upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	// The channel is push only; reading detects the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(notificationsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(notificationsWriteWait)); err != nil {
+				return
+			}
+		case n, ok := <-ch:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+					time.Now().Add(notificationsWriteWait))
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(notificationsWriteWait))
+			if err := conn.WriteJSON(n); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// checkOrigin rejects cross-site WebSocket connections, which browsers
+// would otherwise open with the admin's session cookie
+func (h *NotificationsHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range h.allowedOrigins {
+		if allowed != "*" && strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+)
+
+func TestNotificationsHandler_SSE(t *testing.T) {
+	hub := notifications.NewHub(nil, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(NewNotificationsHandler(zap.NewNop(), hub, nil).Stream))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	hub.Notify(context.Background(), notifications.UserSignup("u1", "ada@example.com", "dex"))
+
+	reader := bufio.NewReader(resp.Body)
+	var event, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	assert.Equal(t, notifications.KindUserSignup, event)
+
+	var n notifications.Notification
+	require.NoError(t, json.Unmarshal([]byte(data), &n))
+	assert.Equal(t, "ada@example.com", n.Data["email"])
+}
+
+func TestNotificationsHandler_WebSocket(t *testing.T) {
+	hub := notifications.NewHub(nil, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(NewNotificationsHandler(zap.NewNop(), hub, nil).Stream))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {server.URL}})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	hub.Notify(context.Background(), notifications.Notification{Kind: notifications.KindCircuitBreakerOpen})
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var n notifications.Notification
+	require.NoError(t, conn.ReadJSON(&n))
+	assert.Equal(t, notifications.KindCircuitBreakerOpen, n.Kind)
+
+	hub.Close()
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err.Error())
+			break
+		}
+	}
+}
+
+func TestNotificationsHandler_CheckOrigin(t *testing.T) {
+	h := NewNotificationsHandler(zap.NewNop(), nil, []string{"https://dashboard.example.com", "*"})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"https://gateway.example.com", true},
+		{"https://dashboard.example.com", true},
+		{"https://evil.example.com", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "https://gateway.example.com/api/admin/notifications", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		assert.Equal(t, tt.want, h.checkOrigin(r), tt.origin)
+	}
+}
+
+func TestNotificationsHandler_Unavailable(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewNotificationsHandler(zap.NewNop(), nil, nil).Stream(rec, httptest.NewRequest(http.MethodGet, "/notifications", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
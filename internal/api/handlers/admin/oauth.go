@@ -9,8 +9,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/amerfu/pllm/internal/core/auth"
 	"github.com/amerfu/pllm/internal/core/models"
-	"github.com/amerfu/pllm/internal/services/integrations/team"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -22,7 +23,8 @@ type OAuthHandler struct {
 	dexURL       string
 	clientID     string
 	clientSecret string
-	teamService  *team.TeamService
+	onboarder    auth.Onboarder
+	notifier     *notifications.Hub
 }
 
 // NewOAuthHandler creates a new OAuth handler
@@ -33,10 +35,19 @@ func NewOAuthHandler(logger *zap.Logger, db *gorm.DB, dexURL, clientID, clientSe
 		dexURL:       dexURL,
 		clientID:     clientID,
 		clientSecret: clientSecret,
-		teamService:  team.NewTeamService(db),
 	}
 }
 
+// SetOnboarder applies the onboarding template to every user provisioned on login
+func (h *OAuthHandler) SetOnboarder(onboarder auth.Onboarder) {
+	h.onboarder = onboarder
+}
+
+// SetNotifier sends a notification for every user provisioned on login
+func (h *OAuthHandler) SetNotifier(notifier *notifications.Hub) {
+	h.notifier = notifier
+}
+
 // TokenExchange handles the OAuth token exchange
 func (h *OAuthHandler) TokenExchange(w http.ResponseWriter, r *http.Request) {
 	// Enable CORS for this endpoint
@@ -267,7 +278,11 @@ func (h *OAuthHandler) autoProvisionUser(userInfo map[string]interface{}) error
 
 	switch err {
 	case gorm.ErrRecordNotFound:
-		// Create new user
+		// Create new user, with the role granted by the auto-join rules
+		role := models.RoleUser
+		if h.onboarder != nil {
+			role = h.onboarder.ProvisionedRole(email, groups)
+		}
 		user = models.User{
 			DexID:            sub,
 			Email:            email,
@@ -276,7 +291,7 @@ func (h *OAuthHandler) autoProvisionUser(userInfo map[string]interface{}) error
 			LastName:         lastName,
 			EmailVerified:    emailVerified,
 			IsActive:         true,
-			Role:             models.RoleAdmin, // SSO users default to admin
+			Role:             role,
 			ExternalID:       sub,
 			ExternalProvider: provider,
 			ExternalGroups:   groups,
@@ -297,24 +312,22 @@ func (h *OAuthHandler) autoProvisionUser(userInfo map[string]interface{}) error
 			return fmt.Errorf("failed to create user: %w", err)
 		}
 
-		// Add user to default team
-		_, err := h.teamService.AddUserToDefaultTeam(context.Background(), user.ID, models.TeamRoleMember)
-		if err != nil {
-			h.logger.Warn("Failed to add new user to default team",
-				zap.String("dex_id", sub),
-				zap.String("user_id", user.ID.String()),
-				zap.Error(err))
-			// Don't fail the user creation, just log the warning
-		} else {
-			h.logger.Info("Added new user to default team",
-				zap.String("dex_id", sub),
-				zap.String("user_id", user.ID.String()))
+		// Apply the onboarding template: team membership, budget and key
+		if h.onboarder != nil {
+			if err := h.onboarder.OnboardUser(context.Background(), &user, groups); err != nil {
+				h.logger.Warn("Failed to onboard new user",
+					zap.String("dex_id", sub),
+					zap.String("user_id", user.ID.String()),
+					zap.Error(err))
+				// Don't fail the user creation, just log the warning
+			}
 		}
 
 		h.logger.Info("Auto-provisioned new user from Dex",
 			zap.String("dex_id", sub),
 			zap.String("email", email),
 			zap.String("provider", provider))
+		h.notifier.Notify(context.Background(), notifications.UserSignup(user.ID.String(), email, provider))
 	case nil:
 		// Update existing user
 		user.LastLoginAt = &now
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,7 +16,10 @@ import (
 
 	"github.com/amerfu/pllm/internal/core/config"
 	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/data/settings"
 	"github.com/amerfu/pllm/internal/services/monitoring/audit"
+	"github.com/amerfu/pllm/internal/services/monitoring/privacy"
 )
 
 // AnalyticsHandler handles analytics endpoints
@@ -24,6 +29,7 @@ type AnalyticsHandler struct {
 	modelManager interface {
 		GetModelStats() map[string]interface{}
 	}
+	anonymizer *privacy.Anonymizer // Optional, anonymized analytics mode
 }
 
 func NewAnalyticsHandler(logger *zap.Logger, db *gorm.DB, modelManager interface {
@@ -36,6 +42,15 @@ func NewAnalyticsHandler(logger *zap.Logger, db *gorm.DB, modelManager interface
 	}
 }
 
+// SetAnonymizer anonymizes the analytics: aggregates over too few users
+// are withheld and user identifiers are pseudonymized
+func (h *AnalyticsHandler) SetAnonymizer(anonymizer *privacy.Anonymizer) {
+	h.anonymizer = anonymizer
+}
+
+// usersColumn identifies the user of a usage record in k-anonymity checks
+const usersColumn = "actual_user_id"
+
 func (h *AnalyticsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	// Get real statistics from the model manager first
 	modelStats := h.modelManager.GetModelStats()
@@ -110,6 +125,7 @@ func (h *AnalyticsHandler) GetDashboard(w http.ResponseWriter, r *http.Request)
 		FROM usage_logs
 		WHERE created_at >= DATE_TRUNC('month', NOW())
 		GROUP BY model
+		` + h.anonymizer.HavingMinUsers(usersColumn) + `
 		ORDER BY total_cost DESC
 		LIMIT 10
 	`).Scan(&topModels)
@@ -122,7 +138,10 @@ func (h *AnalyticsHandler) GetDashboard(w http.ResponseWriter, r *http.Request)
 		Tokens    int64     `gorm:"column:tokens"`
 	}
 
-	h.db.Raw(`
+	// Individual requests are not aggregates, so they are left out when
+	// analytics are anonymized
+	if !h.anonymizer.Enabled() {
+		h.db.Raw(`
 		SELECT 
 			ul.created_at as timestamp,
 			COALESCE(u.email, 'unknown') as user_email,
@@ -133,16 +152,22 @@ func (h *AnalyticsHandler) GetDashboard(w http.ResponseWriter, r *http.Request)
 		ORDER BY ul.created_at DESC
 		LIMIT 10
 	`).Scan(&recentActivity)
+	}
 
 	// Format data for response
 	topUsersArray := make([]map[string]interface{}, 0)
 	for _, user := range topUsers {
-		topUsersArray = append(topUsersArray, map[string]interface{}{
+		entry := map[string]interface{}{
 			"id":    user.ActualUserID,
 			"email": user.UserEmail,
 			"usage": user.TotalTokens,
 			"cost":  user.TotalCost,
-		})
+		}
+		if h.anonymizer.Enabled() {
+			entry["id"] = h.anonymizer.Pseudonym(user.ActualUserID)
+			delete(entry, "email")
+		}
+		topUsersArray = append(topUsersArray, entry)
 	}
 
 	topModelsArray := make([]map[string]interface{}, 0)
@@ -198,6 +223,7 @@ func (h *AnalyticsHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
 		FROM usage_logs
 		WHERE created_at >= DATE_TRUNC('month', NOW())
 		GROUP BY DATE_TRUNC('day', created_at)
+		` + h.anonymizer.HavingMinUsers(usersColumn) + `
 		ORDER BY date
 	`).Scan(&dailyUsage)
 
@@ -215,6 +241,7 @@ func (h *AnalyticsHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
 			SUM(total_cost) as cost
 		FROM usage_logs
 		WHERE created_at >= DATE_TRUNC('month', NOW())
+		` + h.anonymizer.HavingMinUsers(usersColumn) + `
 	`).Scan(&totalUsage)
 
 	// Format daily usage for response
@@ -256,12 +283,529 @@ func (h *AnalyticsHandler) GetCosts(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, http.StatusOK, map[string]interface{}{"costs": []interface{}{}})
 }
 
+// EndpointCost is the usage and spend of one endpoint type, or of one model
+// on it. Units are those the endpoint is billed by; audio seconds include
+// generated audio.
+type EndpointCost struct {
+	EndpointType    string  `json:"endpoint_type"`
+	Model           string  `json:"model,omitempty"`
+	Requests        int64   `json:"requests"`
+	InputTokens     int64   `json:"input_tokens"`
+	OutputTokens    int64   `json:"output_tokens"`
+	ImagesGenerated int64   `json:"images_generated"`
+	AudioSeconds    float64 `json:"audio_seconds"`
+	Characters      int64   `json:"characters"`
+	InputCost       float64 `json:"input_cost"`
+	OutputCost      float64 `json:"output_cost"`
+	Cost            float64 `json:"cost"`
+}
+
+// endpointTypeSQL is the endpoint type of a usage record; records from before
+// endpoint types were stored are classified by path
+const endpointTypeSQL = `COALESCE(NULLIF(endpoint_type, ''), CASE
+			WHEN path LIKE '%/realtime' THEN 'realtime'
+			WHEN path LIKE '%/audio/transcriptions' THEN 'transcription'
+			WHEN path LIKE '%/caches%' THEN 'context_cache'
+			ELSE 'chat' END)`
+
+// GetCostBreakdown returns the spend of each endpoint type (chat,
+// embeddings, images, transcription, speech, realtime, context caches) and
+// of each model per endpoint over the last `hours` hours (default 24)
 func (h *AnalyticsHandler) GetCostBreakdown(w http.ResponseWriter, r *http.Request) {
-	h.sendJSON(w, http.StatusOK, map[string]interface{}{"breakdown": []interface{}{}})
+	hours := 24
+	if raw := r.URL.Query().Get("hours"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 720 {
+			hours = parsed
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	endpoints, err := h.endpointCosts(since, false)
+	if err != nil {
+		h.logger.Error("Failed to get cost breakdown", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch cost breakdown")
+		return
+	}
+	byModel, err := h.endpointCosts(since, true)
+	if err != nil {
+		h.logger.Error("Failed to get cost breakdown by model", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch cost breakdown")
+		return
+	}
+
+	var total float64
+	for _, endpoint := range endpoints {
+		total += endpoint.Cost
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"breakdown":    endpoints,
+		"models":       byModel,
+		"total_cost":   total,
+		"period_hours": hours,
+	})
 }
 
+// endpointCosts aggregates usage since the given time per endpoint type, and
+// per model as well when byModel is set
+func (h *AnalyticsHandler) endpointCosts(since time.Time, byModel bool) ([]EndpointCost, error) {
+	columns, groupBy := endpointTypeSQL+" as endpoint_type", "1"
+	if byModel {
+		columns, groupBy = columns+", model", "1, 2"
+	}
+
+	costs := make([]EndpointCost, 0)
+	err := h.db.Raw(`
+		SELECT
+			`+columns+`,
+			COALESCE(SUM(sample_rate), 0) as requests,
+			COALESCE(SUM(input_tokens * sample_rate), 0) as input_tokens,
+			COALESCE(SUM(output_tokens * sample_rate), 0) as output_tokens,
+			COALESCE(SUM(images_generated * sample_rate), 0) as images_generated,
+			COALESCE(SUM((audio_seconds + output_audio_seconds) * sample_rate), 0) as audio_seconds,
+			COALESCE(SUM(characters * sample_rate), 0) as characters,
+			COALESCE(SUM(input_cost * sample_rate), 0) as input_cost,
+			COALESCE(SUM(output_cost * sample_rate), 0) as output_cost,
+			COALESCE(SUM(total_cost * sample_rate), 0) as cost
+		FROM usage_logs
+		WHERE timestamp >= ?
+		GROUP BY `+groupBy+`
+		`+h.anonymizer.HavingMinUsers(usersColumn)+`
+		ORDER BY cost DESC
+	`, since).Scan(&costs).Error
+	return costs, err
+}
+
+// StreamPerformance is the streaming generation performance of one model.
+// Tokens per second are completion tokens after the first chunk; P5 is the
+// slow tail.
+type StreamPerformance struct {
+	Model              string  `json:"model"`
+	Streams            int64   `json:"streams"`
+	AvgTokensPerSecond float64 `json:"avg_tokens_per_second"`
+	P5TokensPerSecond  float64 `json:"p5_tokens_per_second"`
+	P50TokensPerSecond float64 `json:"p50_tokens_per_second"`
+	P95TokensPerSecond float64 `json:"p95_tokens_per_second"`
+	P95TTFT            float64 `json:"p95_ttft"`
+	P95MaxChunkGap     float64 `json:"p95_max_chunk_gap"`
+	Stalls             int64   `json:"stalls"`
+	StallRate          float64 `json:"stall_rate"`
+}
+
+// GetPerformance returns the streaming performance of each model over the
+// last `hours` hours (default 24): throughput percentiles, time to first
+// token, inter-chunk gaps and stalls
 func (h *AnalyticsHandler) GetPerformance(w http.ResponseWriter, r *http.Request) {
-	h.sendJSON(w, http.StatusOK, map[string]interface{}{"performance": []interface{}{}})
+	hours := 24
+	if raw := r.URL.Query().Get("hours"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 720 {
+			hours = parsed
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	performance := make([]StreamPerformance, 0)
+	err := h.db.Raw(`
+		SELECT
+			model,
+			COUNT(*) as streams,
+			COALESCE(AVG(NULLIF(tokens_per_second, 0)), 0) as avg_tokens_per_second,
+			COALESCE(PERCENTILE_CONT(0.05) WITHIN GROUP (ORDER BY NULLIF(tokens_per_second, 0)), 0) as p5_tokens_per_second,
+			COALESCE(PERCENTILE_CONT(0.50) WITHIN GROUP (ORDER BY NULLIF(tokens_per_second, 0)), 0) as p50_tokens_per_second,
+			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY NULLIF(tokens_per_second, 0)), 0) as p95_tokens_per_second,
+			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY ttft), 0) as p95_ttft,
+			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY max_chunk_gap), 0) as p95_max_chunk_gap,
+			COUNT(*) FILTER (WHERE stalled) as stalls,
+			ROUND(AVG(CASE WHEN stalled THEN 100 ELSE 0 END), 2) as stall_rate
+		FROM usage_logs
+		WHERE timestamp >= ? AND ttft > 0
+		GROUP BY model
+		`+h.anonymizer.HavingMinUsers(usersColumn)+`
+		ORDER BY streams DESC
+	`, since).Scan(&performance).Error
+	if err != nil {
+		h.logger.Error("Failed to get streaming performance", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch streaming performance")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"performance":  performance,
+		"period_hours": hours,
+	})
+}
+
+// SatisfactionScore is the feedback on the responses of one model or route.
+// Feedback whose usage record is missing, because it was sampled out or not
+// written yet, counts as model "unknown".
+type SatisfactionScore struct {
+	Model           string  `json:"model,omitempty"`
+	RouteSlug       string  `json:"route_slug,omitempty"`
+	Ratings         int64   `json:"ratings"`
+	AvgRating       float64 `json:"avg_rating"`
+	Satisfaction    float64 `json:"satisfaction"`    // % of ratings of 4 or 5
+	Dissatisfaction float64 `json:"dissatisfaction"` // % of ratings of 1 or 2
+	AvgLatency      float64 `json:"avg_latency"`
+	AvgCost         float64 `json:"avg_cost"`
+}
+
+// FeedbackTag is how often a feedback tag was given
+type FeedbackTag struct {
+	Tag       string  `json:"tag"`
+	Count     int64   `json:"count"`
+	AvgRating float64 `json:"avg_rating"`
+}
+
+// GetFeedback returns the satisfaction scores of each model and route over
+// the last `hours` hours (default 24), optionally for one team (team_id),
+// next to their latency and cost, and the most given feedback tags
+func (h *AnalyticsHandler) GetFeedback(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if raw := r.URL.Query().Get("hours"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 720 {
+			hours = parsed
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	var teamID *uuid.UUID
+	if raw := r.URL.Query().Get("team_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+			return
+		}
+		teamID = &id
+	}
+
+	byModel, err := h.satisfactionScores(since, teamID, "COALESCE(NULLIF(u.model, ''), 'unknown') as model")
+	if err != nil {
+		h.logger.Error("Failed to get satisfaction by model", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch feedback analytics")
+		return
+	}
+	byRoute, err := h.satisfactionScores(since, teamID, "u.route_slug")
+	if err != nil {
+		h.logger.Error("Failed to get satisfaction by route", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch feedback analytics")
+		return
+	}
+
+	where, args := "f.deleted_at IS NULL AND f.created_at >= ?", []interface{}{since}
+	if teamID != nil {
+		where, args = where+" AND f.team_id = ?", append(args, *teamID)
+	}
+	tags := make([]FeedbackTag, 0)
+	err = h.db.Raw(`
+		SELECT
+			tag,
+			COUNT(*) as count,
+			AVG(f.rating) as avg_rating
+		FROM response_feedback f, unnest(f.tags) as tag
+		WHERE `+where+`
+		GROUP BY tag
+		`+h.anonymizer.HavingMinUsers("f.user_id")+`
+		ORDER BY count DESC
+		LIMIT 20
+	`, args...).Scan(&tags).Error
+	if err != nil {
+		h.logger.Error("Failed to get feedback tags", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch feedback analytics")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"models":       byModel,
+		"routes":       byRoute,
+		"tags":         tags,
+		"team_id":      teamID,
+		"period_hours": hours,
+	})
+}
+
+// satisfactionScores aggregates feedback given since the given time per
+// column. Routes only include requests that were routed.
+func (h *AnalyticsHandler) satisfactionScores(since time.Time, teamID *uuid.UUID, column string) ([]SatisfactionScore, error) {
+	where, args := "f.deleted_at IS NULL AND f.created_at >= ?", []interface{}{since}
+	if column == "u.route_slug" {
+		where += " AND u.route_slug <> ''"
+	}
+	if teamID != nil {
+		where, args = where+" AND f.team_id = ?", append(args, *teamID)
+	}
+
+	scores := make([]SatisfactionScore, 0)
+	err := h.db.Raw(`
+		SELECT
+			`+column+`,
+			COUNT(*) as ratings,
+			AVG(f.rating) as avg_rating,
+			ROUND(AVG(CASE WHEN f.rating >= ? THEN 100 ELSE 0 END), 2) as satisfaction,
+			ROUND(AVG(CASE WHEN f.rating <= 2 THEN 100 ELSE 0 END), 2) as dissatisfaction,
+			COALESCE(AVG(u.latency), 0) as avg_latency,
+			COALESCE(AVG(u.total_cost), 0) as avg_cost
+		FROM response_feedback f
+		LEFT JOIN usage_logs u ON u.request_id = f.request_id
+		WHERE `+where+`
+		GROUP BY 1
+		`+h.anonymizer.HavingMinUsers("f.user_id")+`
+		ORDER BY ratings DESC
+	`, append([]interface{}{models.SatisfiedFeedbackRating}, args...)...).Scan(&scores).Error
+	return scores, err
+}
+
+// TrafficShare is the traffic of one country or client library version.
+// Requests without a known location or User-Agent, including those recorded
+// before clients were, are "unknown".
+type TrafficShare struct {
+	Country       string  `json:"country,omitempty"`
+	Continent     string  `json:"continent,omitempty"`
+	ClientName    string  `json:"client_name,omitempty"`
+	ClientVersion string  `json:"client_version,omitempty"`
+	Requests      int64   `json:"requests"`
+	Tokens        int64   `json:"tokens"`
+	Cost          float64 `json:"cost"`
+}
+
+// GetGeoBreakdown returns the traffic of each client country over the last
+// `hours` hours (default 24), with totals per continent. Countries are only
+// known when a GeoIP database is configured.
+func (h *AnalyticsHandler) GetGeoBreakdown(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if raw := r.URL.Query().Get("hours"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 720 {
+			hours = parsed
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	countries, err := h.trafficShares(since, "country", "continent")
+	if err != nil {
+		h.logger.Error("Failed to get geo breakdown", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch geo breakdown")
+		return
+	}
+
+	continents := make([]TrafficShare, 0)
+	index := make(map[string]int)
+	for _, country := range countries {
+		continent := country.Continent
+		if continent == "" {
+			continent = "unknown"
+		}
+		i, ok := index[continent]
+		if !ok {
+			i = len(continents)
+			index[continent] = i
+			continents = append(continents, TrafficShare{Continent: continent})
+		}
+		continents[i].Requests += country.Requests
+		continents[i].Tokens += country.Tokens
+		continents[i].Cost += country.Cost
+	}
+	sort.Slice(continents, func(i, j int) bool { return continents[i].Requests > continents[j].Requests })
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"countries":    countries,
+		"continents":   continents,
+		"period_hours": hours,
+	})
+}
+
+// GetClientBreakdown returns the traffic of each client library and version,
+// fingerprinted from the User-Agent, over the last `hours` hours (default 24)
+func (h *AnalyticsHandler) GetClientBreakdown(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if raw := r.URL.Query().Get("hours"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 720 {
+			hours = parsed
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	versions, err := h.trafficShares(since, "client_name", "client_version")
+	if err != nil {
+		h.logger.Error("Failed to get client breakdown", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch client breakdown")
+		return
+	}
+
+	clients := make([]TrafficShare, 0)
+	index := make(map[string]int)
+	for _, version := range versions {
+		i, ok := index[version.ClientName]
+		if !ok {
+			i = len(clients)
+			index[version.ClientName] = i
+			clients = append(clients, TrafficShare{ClientName: version.ClientName})
+		}
+		clients[i].Requests += version.Requests
+		clients[i].Tokens += version.Tokens
+		clients[i].Cost += version.Cost
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Requests > clients[j].Requests })
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"clients":      clients,
+		"versions":     versions,
+		"period_hours": hours,
+	})
+}
+
+// trafficShares aggregates usage since the given time by a pair of client
+// columns; the first is "unknown" when empty
+func (h *AnalyticsHandler) trafficShares(since time.Time, first, second string) ([]TrafficShare, error) {
+	shares := make([]TrafficShare, 0)
+	err := h.db.Raw(`
+		SELECT
+			COALESCE(NULLIF(`+first+`, ''), 'unknown') as `+first+`,
+			COALESCE(`+second+`, '') as `+second+`,
+			COALESCE(SUM(sample_rate), 0) as requests,
+			COALESCE(SUM(total_tokens * sample_rate), 0) as tokens,
+			COALESCE(SUM(total_cost * sample_rate), 0) as cost
+		FROM usage_logs
+		WHERE timestamp >= ?
+		GROUP BY 1, 2
+		`+h.anonymizer.HavingMinUsers(usersColumn)+`
+		ORDER BY requests DESC
+	`, since).Scan(&shares).Error
+	return shares, err
+}
+
+// EnvironmentShare is the traffic of one key environment, or of one model
+// in it. Keys without an environment are "unassigned".
+type EnvironmentShare struct {
+	Environment string  `json:"environment"`
+	Model       string  `json:"model,omitempty"`
+	Requests    int64   `json:"requests"`
+	Tokens      int64   `json:"tokens"`
+	Cost        float64 `json:"cost"`
+}
+
+// GetEnvironmentBreakdown returns the traffic of each key environment, and
+// of each model in it, over the last `hours` hours (default 24), optionally
+// for one team (team_id)
+func (h *AnalyticsHandler) GetEnvironmentBreakdown(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if raw := r.URL.Query().Get("hours"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 720 {
+			hours = parsed
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	var teamID *uuid.UUID
+	if raw := r.URL.Query().Get("team_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+			return
+		}
+		teamID = &id
+	}
+
+	environments, err := h.environmentShares(since, teamID, false)
+	if err != nil {
+		h.logger.Error("Failed to get environment breakdown", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch environment breakdown")
+		return
+	}
+	byModel, err := h.environmentShares(since, teamID, true)
+	if err != nil {
+		h.logger.Error("Failed to get environment breakdown by model", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch environment breakdown")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"environments": environments,
+		"models":       byModel,
+		"team_id":      teamID,
+		"period_hours": hours,
+	})
+}
+
+// environmentShares aggregates usage since the given time per environment,
+// and per model as well when byModel is set
+func (h *AnalyticsHandler) environmentShares(since time.Time, teamID *uuid.UUID, byModel bool) ([]EnvironmentShare, error) {
+	columns, groupBy := "COALESCE(NULLIF(environment, ''), 'unassigned') as environment", "1"
+	if byModel {
+		columns, groupBy = columns+", model", "1, 2"
+	}
+	where, args := "timestamp >= ?", []interface{}{since}
+	if teamID != nil {
+		where, args = where+" AND team_id = ?", append(args, *teamID)
+	}
+
+	shares := make([]EnvironmentShare, 0)
+	err := h.db.Raw(`
+		SELECT
+			`+columns+`,
+			COALESCE(SUM(sample_rate), 0) as requests,
+			COALESCE(SUM(total_tokens * sample_rate), 0) as tokens,
+			COALESCE(SUM(total_cost * sample_rate), 0) as cost
+		FROM usage_logs
+		WHERE `+where+`
+		GROUP BY `+groupBy+`
+		`+h.anonymizer.HavingMinUsers(usersColumn)+`
+		ORDER BY cost DESC
+	`, args...).Scan(&shares).Error
+	return shares, err
+}
+
+// RealtimeSpend is the realtime session usage and spend of one model
+type RealtimeSpend struct {
+	Model              string  `json:"model"`
+	Sessions           int64   `json:"sessions"`
+	InputAudioSeconds  float64 `json:"input_audio_seconds"`
+	OutputAudioSeconds float64 `json:"output_audio_seconds"`
+	InputTextTokens    int64   `json:"input_text_tokens"`
+	OutputTextTokens   int64   `json:"output_text_tokens"`
+	Cost               float64 `json:"cost"`
+}
+
+// GetRealtimeUsage returns the realtime session spend of each model over the
+// last `hours` hours (default 24). Sessions are recorded in segments, one
+// usage record per flush interval, sharing the session ID.
+func (h *AnalyticsHandler) GetRealtimeUsage(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if raw := r.URL.Query().Get("hours"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 720 {
+			hours = parsed
+		}
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	spend := make([]RealtimeSpend, 0)
+	err := h.db.Raw(`
+		SELECT
+			model,
+			COUNT(DISTINCT split_part(request_id, '_', 2)) as sessions,
+			COALESCE(SUM(audio_seconds * sample_rate), 0) as input_audio_seconds,
+			COALESCE(SUM(output_audio_seconds * sample_rate), 0) as output_audio_seconds,
+			COALESCE(SUM(input_tokens * sample_rate), 0) as input_text_tokens,
+			COALESCE(SUM(output_tokens * sample_rate), 0) as output_text_tokens,
+			COALESCE(SUM(total_cost * sample_rate), 0) as cost
+		FROM usage_logs
+		WHERE timestamp >= ? AND path LIKE '%/realtime'
+		GROUP BY model
+		`+h.anonymizer.HavingMinUsers(usersColumn)+`
+		ORDER BY cost DESC
+	`, since).Scan(&spend).Error
+	if err != nil {
+		h.logger.Error("Failed to get realtime usage", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch realtime usage")
+		return
+	}
+
+	var total float64
+	for _, model := range spend {
+		total += model.Cost
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"realtime":     spend,
+		"total_cost":   total,
+		"period_hours": hours,
+	})
 }
 
 // GetHistoricalModelHealth returns historical model health data for heatmap
@@ -745,11 +1289,25 @@ func (h *AnalyticsHandler) GetUserBreakdown(w http.ResponseWriter, r *http.Reque
 	// Convert maps to slices for JSON response
 	userArray := make([]*models.UserStats, 0, len(userBreakdown))
 	for _, user := range userBreakdown {
+		h.anonymizeUserStats(user)
 		userArray = append(userArray, user)
 	}
 
 	teamArray := make([]*models.TeamStats, 0, len(teamBreakdown))
 	for _, team := range teamBreakdown {
+		// Teams with too few active members would single their users out
+		if !h.anonymizer.Allows(len(team.UserBreakdown)) {
+			continue
+		}
+		if h.anonymizer.Enabled() {
+			pseudonymous := make(map[string]*models.UserStats, len(team.UserBreakdown))
+			for _, user := range team.UserBreakdown {
+				h.anonymizeUserStats(user)
+				pseudonymous[user.UserID] = user
+			}
+			team.UserBreakdown = pseudonymous
+		}
+
 		// Count active members
 		team.MemberCount = len(team.UserBreakdown)
 		team.ActiveMembers = len(team.UserBreakdown) // All members in breakdown are active
@@ -828,7 +1386,7 @@ func (h *AnalyticsHandler) GetTeamUserBreakdown(w http.ResponseWriter, r *http.R
 		SELECT DISTINCT u.id, u.email, u.username, u.first_name, u.last_name
 		FROM users u
 		JOIN team_members tm ON u.id = tm.user_id
-		WHERE tm.team_id = ?
+		WHERE tm.team_id = ? AND u.deleted_at IS NULL
 	`, teamID).Scan(&users).Error; err != nil {
 		h.sendError(w, http.StatusInternalServerError, "Failed to get team members")
 		return
@@ -865,9 +1423,16 @@ func (h *AnalyticsHandler) GetTeamUserBreakdown(w http.ResponseWriter, r *http.R
 
 	userArray := make([]*models.UserStats, 0, len(userBreakdown))
 	for _, user := range userBreakdown {
+		h.anonymizeUserStats(user)
 		userArray = append(userArray, user)
 	}
 
+	// A team with too few active members would single its users out
+	withheld := !h.anonymizer.Allows(len(userArray))
+	if withheld {
+		userArray = userArray[:0]
+	}
+
 	response := map[string]interface{}{
 		"team": map[string]interface{}{
 			"id":            team.ID,
@@ -896,16 +1461,31 @@ func (h *AnalyticsHandler) GetTeamUserBreakdown(w http.ResponseWriter, r *http.R
 			}(),
 		},
 	}
+	if withheld {
+		response["withheld"] = true
+	}
 
 	h.sendJSON(w, http.StatusOK, response)
 }
 
+// anonymizeUserStats replaces the identity of a user with a pseudonym when
+// analytics are anonymized
+func (h *AnalyticsHandler) anonymizeUserStats(stats *models.UserStats) {
+	if !h.anonymizer.Enabled() {
+		return
+	}
+	stats.UserID = h.anonymizer.Pseudonym(stats.UserID)
+	stats.UserEmail = ""
+	stats.UserName = ""
+}
+
 // SystemHandler handles system endpoints
 type SystemHandler struct {
 	baseHandler
 	config      *config.Config
 	db          *gorm.DB
 	auditLogger *audit.Logger
+	settings    *settings.Store
 }
 
 func NewSystemHandler(logger *zap.Logger, db *gorm.DB) *SystemHandler {
@@ -1018,9 +1598,31 @@ func (h *SystemHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SystemHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters for filtering
-	query := r.URL.Query()
+	filters := auditLogFiltersFromQuery(r.URL.Query())
+
+	// Get audit logs with filters
+	logs, total, err := h.auditLogger.GetAuditLogs(r.Context(), filters)
+	if err != nil {
+		h.logger.Error("Failed to fetch audit logs", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch audit logs")
+		return
+	}
 	
+	// Build response
+	response := map[string]interface{}{
+		"audit_logs": logs,
+		"total":      total,
+		"limit":      filters.Limit,
+		"offset":     filters.Offset,
+		"has_more":   int64(filters.Offset+len(logs)) < total,
+	}
+	
+	h.sendJSON(w, http.StatusOK, response)
+}
+
+// auditLogFiltersFromQuery parses the filters and pagination of an audit
+// log listing
+func auditLogFiltersFromQuery(query url.Values) audit.AuditLogFilters {
 	// Parse pagination parameters with reasonable defaults
 	limit := 50 // default limit
 	if l := query.Get("limit"); l != "" {
@@ -1078,24 +1680,7 @@ func (h *SystemHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	
-	// Get audit logs with filters
-	logs, total, err := h.auditLogger.GetAuditLogs(r.Context(), filters)
-	if err != nil {
-		h.logger.Error("Failed to fetch audit logs", zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "Failed to fetch audit logs")
-		return
-	}
-	
-	// Build response
-	response := map[string]interface{}{
-		"audit_logs": logs,
-		"total":      total,
-		"limit":      limit,
-		"offset":     offset,
-		"has_more":   int64(offset+len(logs)) < total,
-	}
-	
-	h.sendJSON(w, http.StatusOK, response)
+	return filters
 }
 
 func (h *SystemHandler) ClearCache(w http.ResponseWriter, r *http.Request) {
@@ -1114,17 +1699,82 @@ func (h *SystemHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 	h.sendError(w, http.StatusNotImplemented, "Settings update not yet implemented")
 }
 
+// SetSettings enables the runtime settings endpoints
+func (h *SystemHandler) SetSettings(store *settings.Store) {
+	h.settings = store
+}
+
+// GetRateLimits returns the gateway rate limits per scope, as set and as
+// applied after falling back to the global scope
 func (h *SystemHandler) GetRateLimits(w http.ResponseWriter, r *http.Request) {
+	limits := settings.RateLimitsFromConfig(h.config.RateLimit)
+	if h.settings != nil {
+		limits = h.settings.RateLimits()
+	}
+
+	effective := make(map[string]settings.RateLimit)
+	for _, scope := range []string{settings.ScopeGlobal, settings.ScopeChatCompletions,
+		settings.ScopeCompletions, settings.ScopeEmbeddings} {
+		effective[scope] = limits.Limit(scope)
+	}
+
 	h.sendJSON(w, http.StatusOK, map[string]interface{}{
-		"rate_limits": map[string]interface{}{
-			"tpm": 100000,
-			"rpm": 100,
-		},
+		"rate_limits": limits,
+		"effective":   effective,
+		"editable":    h.settings != nil,
 	})
 }
 
+// UpdateRateLimits changes the gateway rate limits on every replica. Scopes
+// left out of the request keep their limits.
 func (h *SystemHandler) UpdateRateLimits(w http.ResponseWriter, r *http.Request) {
-	h.sendError(w, http.StatusNotImplemented, "Rate limits update not yet implemented")
+	if h.settings == nil {
+		h.sendError(w, http.StatusNotImplemented, "Rate limits can only be changed with a database")
+		return
+	}
+
+	var req struct {
+		Enabled *bool                         `json:"enabled"`
+		Scopes  map[string]settings.RateLimit `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	limits := h.settings.RateLimits()
+	if req.Enabled != nil {
+		limits.Enabled = *req.Enabled
+	}
+	for scope, limit := range req.Scopes {
+		limits.Scopes[scope] = limit
+	}
+	if err := limits.Validate(); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var actor *uuid.UUID
+	if userID, ok := middleware.GetUserID(r.Context()); ok && userID != uuid.Nil {
+		actor = &userID
+	}
+	if err := h.settings.UpdateRateLimits(r.Context(), limits, actor); err != nil {
+		h.logger.Error("Failed to update rate limits", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to update rate limits")
+		return
+	}
+
+	if err := h.auditLogger.LogEvent(r.Context(), actor, nil, audit.AuditEvent{
+		Action:   audit.ActionUpdate,
+		Resource: audit.ResourceSettings,
+		Details:  map[string]interface{}{"rate_limits": limits},
+	}); err != nil {
+		h.logger.Warn("Failed to log rate limits audit", zap.Error(err))
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"rate_limits": limits,
+	})
 }
 
 func (h *SystemHandler) GetCacheSettings(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/provenance"
+)
+
+// ProvenanceHandler verifies which model produced a given response
+type ProvenanceHandler struct {
+	baseHandler
+	db *gorm.DB
+}
+
+// NewProvenanceHandler creates a new ProvenanceHandler.
+func NewProvenanceHandler(logger *zap.Logger, db *gorm.DB) *ProvenanceHandler {
+	return &ProvenanceHandler{
+		baseHandler: baseHandler{logger: logger},
+		db:          db,
+	}
+}
+
+// VerifyProvenanceRequest is the request body for provenance verification.
+// Either the generated content or its content hash must be provided.
+type VerifyProvenanceRequest struct {
+	Content     string `json:"content,omitempty"`
+	ContentHash string `json:"content_hash,omitempty"`
+}
+
+type provenanceMatch struct {
+	RequestID     string `json:"request_id"`
+	Timestamp     string `json:"timestamp"`
+	Model         string `json:"model"`
+	ProviderModel string `json:"provider_model,omitempty"`
+	Provider      string `json:"provider"`
+	RouteSlug     string `json:"route_slug,omitempty"`
+	KeyID         string `json:"key_id,omitempty"`
+	TeamID        string `json:"team_id,omitempty"`
+}
+
+// VerifyProvenance looks up usage records whose stored content hash matches
+func (h *ProvenanceHandler) VerifyProvenance(w http.ResponseWriter, r *http.Request) {
+	var req VerifyProvenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	var hash string
+	switch {
+	case req.ContentHash != "":
+		hash = provenance.NormalizeHash(req.ContentHash)
+	case req.Content != "":
+		hash = provenance.HashContent(req.Content)
+	default:
+		h.sendError(w, http.StatusBadRequest, "content or content_hash is required")
+		return
+	}
+
+	var usages []models.Usage
+	if err := h.db.Where("content_hash = ?", hash).
+		Order("timestamp DESC").
+		Limit(100).
+		Find(&usages).Error; err != nil {
+		h.logger.Error("Failed to query provenance records", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to verify provenance")
+		return
+	}
+
+	matches := make([]provenanceMatch, 0, len(usages))
+	for _, u := range usages {
+		match := provenanceMatch{
+			RequestID:     u.RequestID,
+			Timestamp:     u.Timestamp.Format("2006-01-02T15:04:05Z"),
+			Model:         u.Model,
+			ProviderModel: u.ProviderModel,
+			Provider:      u.Provider,
+			RouteSlug:     u.RouteSlug,
+		}
+		if u.KeyID != nil {
+			match.KeyID = u.KeyID.String()
+		}
+		if u.TeamID != nil {
+			match.TeamID = u.TeamID.String()
+		}
+		matches = append(matches, match)
+	}
+
+	h.sendResponse(w, http.StatusOK, map[string]interface{}{
+		"verified":     len(matches) > 0,
+		"content_hash": hash,
+		"matches":      matches,
+	})
+}
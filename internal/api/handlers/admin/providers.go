@@ -49,12 +49,21 @@ type providerProfileResponse struct {
 }
 
 var validProviderTypes = map[string]bool{
-	"openai":      true,
-	"anthropic":   true,
-	"azure":       true,
-	"bedrock":     true,
-	"vertex":      true,
-	"openrouter":  true,
+	"openai":        true,
+	"anthropic":     true,
+	"azure":         true,
+	"bedrock":       true,
+	"vertex":        true,
+	"openrouter":    true,
+	"nim":           true,
+	"triton":        true,
+	"watsonx":       true,
+	"azure_mistral": true,
+	"ollama":        true,
+	"dashscope":     true,
+	"qianfan":       true,
+	"perplexity":    true,
+	"custom-openai": true,
 }
 
 func maskSecret(s string) string {
@@ -0,0 +1,397 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	modelService "github.com/amerfu/pllm/internal/services/integrations/model"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/audit"
+)
+
+// Reasons a configured instance and the registry disagree
+const (
+	registryDisabledInConfig  = "disabled in configuration"
+	registryLoadedDisabled    = "loaded but disabled in configuration"
+	registryDisabledAtRuntime = "disabled at runtime"
+	registryNotLoaded         = "enabled in configuration but not loaded"
+	registryNotConfigured     = "loaded but not in config.yaml or the database"
+)
+
+// RegistryHandler exposes the in-memory model registry, to debug differences
+// between config.yaml, the user models in the database and what is loaded
+type RegistryHandler struct {
+	baseHandler
+	db           *gorm.DB
+	cfg          *config.Config
+	modelManager *llmModels.ModelManager
+	service      *modelService.Service
+}
+
+// NewRegistryHandler creates a new RegistryHandler.
+func NewRegistryHandler(logger *zap.Logger, db *gorm.DB, cfg *config.Config, modelManager *llmModels.ModelManager) *RegistryHandler {
+	h := &RegistryHandler{
+		baseHandler:  baseHandler{logger: logger},
+		db:           db,
+		cfg:          cfg,
+		modelManager: modelManager,
+	}
+	if db != nil {
+		h.service = modelService.NewService(db, logger)
+	}
+	return h
+}
+
+// RegistryDiscrepancy is a configured or loaded instance whose state differs
+// between its source and the registry
+type RegistryDiscrepancy struct {
+	ID        string `json:"id"`
+	ModelName string `json:"model_name"`
+	Source    string `json:"source"`
+	Reason    string `json:"reason"`
+}
+
+// GetRegistry returns the registry's instances with their health and
+// metrics counters, the instance IDs of each model, and the instances that
+// differ from config.yaml and the database
+func (h *RegistryHandler) GetRegistry(w http.ResponseWriter, r *http.Request) {
+	snapshot := h.modelManager.GetRegistry().Snapshot()
+
+	loaded := make(map[string]llmModels.RegistryInstanceState, len(snapshot.Instances))
+	for _, instance := range snapshot.Instances {
+		loaded[instance.ID] = instance
+	}
+
+	discrepancies := make([]RegistryDiscrepancy, 0)
+	configured := make(map[string]bool)
+	compare := func(instance config.ModelInstance, source string) {
+		configured[instance.ID] = true
+		state, isLoaded := loaded[instance.ID]
+		if instance.DiscoversModels() {
+			// Loaded as one instance per discovered model
+			for id, expanded := range loaded {
+				if strings.HasPrefix(id, instance.ID+"/") {
+					configured[id] = true
+					if !isLoaded || !expanded.Enabled {
+						state, isLoaded = expanded, true
+					}
+				}
+			}
+		}
+		reason := ""
+		switch {
+		case !instance.Enabled && isLoaded:
+			reason = registryLoadedDisabled
+		case !instance.Enabled:
+			reason = registryDisabledInConfig
+		case !isLoaded:
+			reason = registryNotLoaded
+		case !state.Enabled:
+			reason = registryDisabledAtRuntime
+		}
+		if reason != "" {
+			discrepancies = append(discrepancies, RegistryDiscrepancy{
+				ID:        instance.ID,
+				ModelName: instance.ModelName,
+				Source:    source,
+				Reason:    reason,
+			})
+		}
+	}
+
+	if h.cfg != nil {
+		for _, instance := range h.cfg.ModelList {
+			compare(instance, "system")
+		}
+	}
+	if h.service != nil {
+		userModels, err := h.service.ListUserModels()
+		if err != nil {
+			h.logger.Error("Failed to list user models", zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "Failed to list user models")
+			return
+		}
+		for _, um := range userModels {
+			compare(config.ModelInstance{ID: um.ID.String(), ModelName: um.ModelName, Enabled: um.Enabled}, "user")
+		}
+	}
+
+	for _, instance := range snapshot.Instances {
+		if !configured[instance.ID] {
+			discrepancies = append(discrepancies, RegistryDiscrepancy{
+				ID:        instance.ID,
+				ModelName: instance.ModelName,
+				Source:    instance.Source,
+				Reason:    registryNotConfigured,
+			})
+		}
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"instances":     snapshot.Instances,
+		"models":        snapshot.Models,
+		"providers":     snapshot.Providers,
+		"discrepancies": discrepancies,
+	})
+}
+
+// SetInstanceEnabled enables or disables a registered instance. Disabling
+// only takes the instance out of routing on this replica until it is enabled
+// again or the gateway restarts; config.yaml and the database are unchanged.
+func (h *RegistryHandler) SetInstanceEnabled(w http.ResponseWriter, r *http.Request) {
+	instanceID := chi.URLParam(r, "instanceID")
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if req.Enabled == nil {
+		h.sendError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+
+	var err error
+	if *req.Enabled {
+		err = h.modelManager.EnableInstance(instanceID)
+	} else {
+		err = h.modelManager.DisableInstance(instanceID)
+	}
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, "Instance not found in the registry")
+		return
+	}
+
+	h.audit(r, map[string]interface{}{"registry_instance": instanceID, "enabled": *req.Enabled})
+	h.logger.Warn("Registry instance changed",
+		zap.String("instance_id", instanceID),
+		zap.Bool("enabled", *req.Enabled))
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      instanceID,
+		"enabled": *req.Enabled,
+	})
+}
+
+// DrainInstance starts draining an instance before its provider key is
+// rotated or its deployment is decommissioned: requests in flight and open
+// streams finish, and new requests go to the model's other instances unless
+// none can serve them. Poll GetDrainStatus until drained is true. Like
+// disabling, draining only applies to this replica.
+func (h *RegistryHandler) DrainInstance(w http.ResponseWriter, r *http.Request) {
+	instanceID := chi.URLParam(r, "instanceID")
+	status, err := h.modelManager.DrainInstance(instanceID)
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, "Instance not found in the registry")
+		return
+	}
+
+	h.audit(r, map[string]interface{}{"registry_instance": instanceID, "draining": true})
+	h.logger.Warn("Registry instance draining",
+		zap.String("instance_id", instanceID),
+		zap.Int64("in_flight", status.InFlight))
+	h.sendJSON(w, http.StatusAccepted, status)
+}
+
+// ResumeInstance stops draining an instance and returns it to rotation
+func (h *RegistryHandler) ResumeInstance(w http.ResponseWriter, r *http.Request) {
+	instanceID := chi.URLParam(r, "instanceID")
+	status, err := h.modelManager.ResumeInstance(instanceID)
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, "Instance not found in the registry")
+		return
+	}
+
+	h.audit(r, map[string]interface{}{"registry_instance": instanceID, "draining": false})
+	h.logger.Warn("Registry instance resumed", zap.String("instance_id", instanceID))
+	h.sendJSON(w, http.StatusOK, status)
+}
+
+// GetDrainStatus reports whether an instance is draining, how many requests
+// it is still serving and when the drain completed
+func (h *RegistryHandler) GetDrainStatus(w http.ResponseWriter, r *http.Request) {
+	status, found := h.modelManager.InstanceDrainStatus(chi.URLParam(r, "instanceID"))
+	if !found {
+		h.sendError(w, http.StatusNotFound, "Instance not found in the registry")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, status)
+}
+
+// GetStreamGaps reports, per model, how long this replica's streams went
+// without a chunk and how many keep-alives held them open, to tell models
+// that think for a long time from backends that stall
+func (h *RegistryHandler) GetStreamGaps(w http.ResponseWriter, r *http.Request) {
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"keepalive_interval_seconds": h.modelManager.StreamKeepAliveInterval().Seconds(),
+		"models":                     h.modelManager.StreamGapStats(),
+	})
+}
+
+// audit records a registry change
+func (h *RegistryHandler) audit(r *http.Request, details map[string]interface{}) {
+	if h.db == nil {
+		return
+	}
+	var actor *uuid.UUID
+	if userID, ok := middleware.GetUserID(r.Context()); ok && userID != uuid.Nil {
+		actor = &userID
+	}
+	if err := audit.NewLogger(h.db).LogEvent(r.Context(), actor, nil, audit.AuditEvent{
+		Action:    audit.ActionUpdate,
+		Resource:  audit.ResourceLLM,
+		Details:   details,
+		IPAddress: r.RemoteAddr,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	}); err != nil {
+		h.logger.Warn("Failed to log registry change audit", zap.Error(err))
+	}
+}
+
+// PinnedVersion is a model snapshot that can be requested with a
+// version-pinned name, with the teams that requested it
+type PinnedVersion struct {
+	Name            string              `json:"name"` // e.g. "gpt-4o@2024-08-06"
+	Model           string              `json:"model"`
+	Version         string              `json:"version"`
+	Instances       []string            `json:"instances"`                  // Instances serving the snapshot, empty when requests fail
+	ProviderModel   string              `json:"provider_model,omitempty"`   // Provider model ID of the snapshot
+	DeprecationDate string              `json:"deprecation_date,omitempty"` // Upstream deprecation date of the provider model, YYYY-MM-DD
+	Requests        int64               `json:"requests"`
+	Teams           []PinnedVersionTeam `json:"teams"`
+}
+
+// PinnedVersionTeam is a team's usage of a version-pinned name. Requests
+// from keys without a team have no team ID.
+type PinnedVersionTeam struct {
+	TeamID   *uuid.UUID `json:"team_id,omitempty"`
+	TeamName string     `json:"team_name,omitempty"`
+	Requests int64      `json:"requests"`
+	LastUsed time.Time  `json:"last_used"`
+}
+
+// GetPinnedVersions reports the model snapshots served by the registry and
+// the version-pinned names requested over the last days (default 30), with
+// the teams relying on each, so teams can be moved off a snapshot before its
+// upstream deprecation. Snapshots deprecated soonest come first.
+func (h *RegistryHandler) GetPinnedVersions(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 365 {
+			h.sendError(w, http.StatusBadRequest, "days must be between 1 and 365")
+			return
+		}
+		days = parsed
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	registry := h.modelManager.GetRegistry()
+	versions := make(map[string]*PinnedVersion)
+	pinned := func(name string) *PinnedVersion {
+		if version, ok := versions[name]; ok {
+			return version
+		}
+		model, snapshot := models.SplitModelVersion(name)
+		version := &PinnedVersion{
+			Name:      name,
+			Model:     model,
+			Version:   snapshot,
+			Instances: make([]string, 0),
+			Teams:     make([]PinnedVersionTeam, 0),
+		}
+		instances, _ := registry.GetModelInstances(name)
+		for _, instance := range instances {
+			version.Instances = append(version.Instances, instance.Config.ID)
+			if version.ProviderModel == "" {
+				version.ProviderModel = instance.Config.Provider.Model
+				if instance.Config.Provider.AzureBaseModel != "" {
+					version.ProviderModel = instance.Config.Provider.AzureBaseModel
+				}
+			}
+		}
+		if version.ProviderModel != "" {
+			if pricing := config.GetPricingManager().GetPricing(version.ProviderModel); pricing != nil {
+				version.DeprecationDate = pricing.DeprecationDate
+			}
+		}
+		versions[name] = version
+		return version
+	}
+
+	for _, model := range registry.GetAvailableModels() {
+		for _, version := range registry.ModelVersions(model) {
+			pinned(model + "@" + version)
+		}
+	}
+
+	if h.db != nil {
+		// Rows stored under usage sampling stand for sample_rate requests
+		var rows []struct {
+			Model    string
+			TeamID   *uuid.UUID
+			TeamName string
+			Requests int64
+			LastUsed time.Time
+		}
+		if err := h.db.WithContext(r.Context()).Table("usage_logs u").
+			Select("u.model, u.team_id, t.name AS team_name, SUM(u.sample_rate) AS requests, MAX(u.timestamp) AS last_used").
+			Joins("LEFT JOIN teams t ON t.id = u.team_id").
+			Where("u.model LIKE ? AND u.timestamp >= ? AND u.deleted_at IS NULL", "%@%", since).
+			Group("u.model, u.team_id, t.name").
+			Order("requests DESC").
+			Scan(&rows).Error; err != nil {
+			h.logger.Error("Failed to query pinned version usage", zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "Failed to query pinned version usage")
+			return
+		}
+		for _, row := range rows {
+			if _, snapshot := models.SplitModelVersion(row.Model); snapshot == "" {
+				continue
+			}
+			version := pinned(row.Model)
+			version.Requests += row.Requests
+			version.Teams = append(version.Teams, PinnedVersionTeam{
+				TeamID:   row.TeamID,
+				TeamName: row.TeamName,
+				Requests: row.Requests,
+				LastUsed: row.LastUsed,
+			})
+		}
+	}
+
+	result := make([]*PinnedVersion, 0, len(versions))
+	for _, version := range versions {
+		result = append(result, version)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if (a.DeprecationDate == "") != (b.DeprecationDate == "") {
+			return a.DeprecationDate != ""
+		}
+		if a.DeprecationDate != b.DeprecationDate {
+			return a.DeprecationDate < b.DeprecationDate
+		}
+		return a.Name < b.Name
+	})
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"since":    since,
+		"versions": result,
+	})
+}
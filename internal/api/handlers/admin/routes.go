@@ -2,6 +2,7 @@ package admin
 
 import (
 	"encoding/json"
+	"io"
 	"math"
 	"net/http"
 	"regexp"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/amerfu/pllm/internal/core/config"
 	"github.com/amerfu/pllm/internal/core/models"
 	routeService "github.com/amerfu/pllm/internal/services/integrations/route"
 	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
@@ -48,6 +50,7 @@ type routeResponse struct {
 	Strategy       string               `json:"strategy"`
 	Models         []routeModelResponse `json:"models"`
 	FallbackModels []string             `json:"fallback_models,omitempty"`
+	Defaults       models.RouteDefaults `json:"defaults"`
 	Enabled        bool                 `json:"enabled"`
 	Source         string               `json:"source"`
 	CreatedAt      string               `json:"created_at,omitempty"`
@@ -70,6 +73,7 @@ func toRouteResponse(r models.Route) routeResponse {
 		Description:    r.Description,
 		Strategy:       r.Strategy,
 		FallbackModels: []string(r.FallbackModels),
+		Defaults:       r.Defaults,
 		Enabled:        r.Enabled,
 		Source:         r.Source,
 		CreatedAt:      r.CreatedAt.Format("2006-01-02T15:04:05Z"),
@@ -127,6 +131,7 @@ func (h *RouteHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
 			Slug:           slug,
 			Strategy:       strategyName,
 			FallbackModels: entry.FallbackModels,
+			Defaults:       models.RouteDefaults(entry.Defaults),
 			Enabled:        true,
 			Source:         "system",
 		}
@@ -168,6 +173,7 @@ type CreateRouteRequest struct {
 	Strategy       string               `json:"strategy"`
 	Models         []routeModelResponse `json:"models"`
 	FallbackModels []string             `json:"fallback_models,omitempty"`
+	Defaults       models.RouteDefaults `json:"defaults"`
 	Enabled        *bool                `json:"enabled,omitempty"`
 }
 
@@ -201,6 +207,10 @@ func (h *RouteHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := llmModels.ValidateRouteDefaults(config.RouteDefaults(req.Defaults)); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Check slug doesn't conflict with existing model names
 	availableModels := h.modelManager.GetAvailableModels()
@@ -239,6 +249,7 @@ func (h *RouteHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 		Description:    req.Description,
 		Strategy:       strategy,
 		FallbackModels: models.StringArrayJSON(req.FallbackModels),
+		Defaults:       req.Defaults,
 		Enabled:        enabled,
 		Source:         "user",
 	}
@@ -310,6 +321,7 @@ func (h *RouteHandler) GetRoute(w http.ResponseWriter, r *http.Request) {
 			Slug:           routeID,
 			Strategy:       strategyName,
 			FallbackModels: entry.FallbackModels,
+			Defaults:       models.RouteDefaults(entry.Defaults),
 			Enabled:        true,
 			Source:         "system",
 		}
@@ -368,6 +380,10 @@ func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if err := llmModels.ValidateRouteDefaults(config.RouteDefaults(req.Defaults)); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	enabled := existing.Enabled
 	if req.Enabled != nil {
@@ -396,6 +412,7 @@ func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 		Description:    req.Description,
 		Strategy:       strategy,
 		FallbackModels: models.StringArrayJSON(req.FallbackModels),
+		Defaults:       req.Defaults,
 		Enabled:        enabled,
 	}
 	for _, rm := range req.Models {
@@ -480,23 +497,10 @@ func (h *RouteHandler) GetRouteStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Resolve route slug
-	var slug string
-	if id, err := uuid.Parse(routeID); err == nil {
-		// User route — look up slug from DB
-		route, err := h.service.GetByID(id)
-		if err != nil {
-			h.sendError(w, http.StatusNotFound, "Route not found")
-			return
-		}
-		slug = route.Slug
-	} else {
-		// System route — the ID is the slug
-		if _, exists := h.modelManager.ResolveRoute(routeID); !exists {
-			h.sendError(w, http.StatusNotFound, "Route not found")
-			return
-		}
-		slug = routeID
+	slug, ok := h.resolveRouteSlug(routeID)
+	if !ok {
+		h.sendError(w, http.StatusNotFound, "Route not found")
+		return
 	}
 
 	// Parse time range (default 24 hours)
@@ -587,6 +591,58 @@ func (h *RouteHandler) GetRouteStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// resolveRouteSlug returns the slug of a route given its ID: the UUID of a
+// user route or the slug of a system route
+func (h *RouteHandler) resolveRouteSlug(routeID string) (string, bool) {
+	if id, err := uuid.Parse(routeID); err == nil {
+		// User route — look up slug from DB
+		route, err := h.service.GetByID(id)
+		if err != nil {
+			return "", false
+		}
+		return route.Slug, true
+	}
+	// System route — the ID is the slug
+	if _, exists := h.modelManager.ResolveRoute(routeID); !exists {
+		return "", false
+	}
+	return routeID, true
+}
+
+// SimulateRoute reports how a route would serve traffic under hypothetical
+// instance health states. An empty body simulates the current states.
+func (h *RouteHandler) SimulateRoute(w http.ResponseWriter, r *http.Request) {
+	routeID := chi.URLParam(r, "routeID")
+	if routeID == "" {
+		h.sendError(w, http.StatusBadRequest, "route ID is required")
+		return
+	}
+
+	slug, ok := h.resolveRouteSlug(routeID)
+	if !ok {
+		h.sendError(w, http.StatusNotFound, "Route not found")
+		return
+	}
+
+	var scenario llmModels.HealthScenario
+	if err := json.NewDecoder(r.Body).Decode(&scenario); err != nil && err != io.EOF {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if _, exists := h.modelManager.ResolveRoute(slug); !exists {
+		h.sendError(w, http.StatusNotFound, "Route is not loaded")
+		return
+	}
+	result, err := h.modelManager.SimulateRoute(r.Context(), slug, scenario)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.sendResponse(w, http.StatusOK, result)
+}
+
 // registerRouteInManager converts a DB route to a RouteEntry and registers it.
 func (h *RouteHandler) registerRouteInManager(r models.Route) {
 	if !r.Enabled {
@@ -607,5 +663,6 @@ func (h *RouteHandler) registerRouteInManager(r models.Route) {
 		Slug:           r.Slug,
 		Models:         routeModels,
 		FallbackModels: []string(r.FallbackModels),
+		Defaults:       config.RouteDefaults(r.Defaults),
 	}, r.Strategy)
 }
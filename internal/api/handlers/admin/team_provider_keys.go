@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/integrations/byok"
+)
+
+// TeamProviderKeyHandler manages the provider API keys teams bring for their
+// own traffic (bring your own key)
+type TeamProviderKeyHandler struct {
+	baseHandler
+	keys *byok.Service
+}
+
+// NewTeamProviderKeyHandler creates a new TeamProviderKeyHandler.
+func NewTeamProviderKeyHandler(logger *zap.Logger, keys *byok.Service) *TeamProviderKeyHandler {
+	return &TeamProviderKeyHandler{
+		baseHandler: baseHandler{logger: logger},
+		keys:        keys,
+	}
+}
+
+// ListKeys returns the provider keys of a team with the traffic sent with
+// each over the last days (default 30)
+func (h *TeamProviderKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	days := 30
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 365 {
+			h.sendError(w, http.StatusBadRequest, "days must be between 1 and 365")
+			return
+		}
+		days = parsed
+	}
+
+	keys, err := h.keys.List(r.Context(), teamID)
+	if err != nil {
+		h.logger.Error("Failed to list team provider keys", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list provider keys")
+		return
+	}
+	usage, err := h.keys.Usage(r.Context(), teamID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		h.logger.Error("Failed to get team provider key usage", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list provider keys")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"team_id": teamID,
+		"keys":    keys,
+		"usage":   usage,
+		"days":    days,
+	})
+}
+
+// RegisterKey stores a team's API key for the provider type in the path,
+// replacing any key registered before, and returns it with the result of
+// its first health check
+func (h *TeamProviderKeyHandler) RegisterKey(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	var req struct {
+		APIKey  string `json:"api_key"`
+		BaseURL string `json:"base_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	key, err := h.keys.Register(r.Context(), teamID, chi.URLParam(r, "providerType"), req.APIKey, req.BaseURL)
+	if err != nil {
+		if errors.Is(err, byok.ErrUnsupportedProvider) || errors.Is(err, byok.ErrAPIKeyRequired) ||
+			errors.Is(err, byok.ErrBaseURLRequired) {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to register team provider key", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to register provider key")
+		return
+	}
+
+	h.logger.Info("Registered team provider key",
+		zap.String("team_id", teamID.String()),
+		zap.String("provider_type", key.ProviderType),
+		zap.Bool("healthy", key.Healthy))
+	h.sendJSON(w, http.StatusOK, key)
+}
+
+// UpdateKey pauses or resumes a team's key
+func (h *TeamProviderKeyHandler) UpdateKey(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	var req struct {
+		IsActive *bool `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.IsActive == nil {
+		h.sendError(w, http.StatusBadRequest, "is_active is required")
+		return
+	}
+
+	key, err := h.keys.SetActive(r.Context(), teamID, chi.URLParam(r, "providerType"), *req.IsActive)
+	if h.keyError(w, err, "Failed to update provider key") {
+		return
+	}
+	h.sendJSON(w, http.StatusOK, key)
+}
+
+// CheckKey runs the health check of a team's key now
+func (h *TeamProviderKeyHandler) CheckKey(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	key, err := h.keys.Get(r.Context(), teamID, chi.URLParam(r, "providerType"))
+	if h.keyError(w, err, "Failed to check provider key") {
+		return
+	}
+	_ = h.keys.Check(r.Context(), key) // The result is recorded on the key
+	h.sendJSON(w, http.StatusOK, key)
+}
+
+// DeleteKey removes a team's key; the team's traffic goes back to the
+// shared credentials
+func (h *TeamProviderKeyHandler) DeleteKey(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	providerType := chi.URLParam(r, "providerType")
+	if h.keyError(w, h.keys.Delete(r.Context(), teamID, providerType), "Failed to delete provider key") {
+		return
+	}
+
+	h.logger.Info("Deleted team provider key",
+		zap.String("team_id", teamID.String()),
+		zap.String("provider_type", providerType))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// keyError sends the response for a failed key lookup and reports whether
+// there was one
+func (h *TeamProviderKeyHandler) keyError(w http.ResponseWriter, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, byok.ErrKeyNotFound):
+		h.sendError(w, http.StatusNotFound, "Provider key not found")
+	default:
+		h.logger.Error(message, zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, message)
+	}
+	return true
+}
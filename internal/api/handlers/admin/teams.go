@@ -2,17 +2,21 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	"github.com/amerfu/pllm/internal/core/models"
 	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	"github.com/amerfu/pllm/internal/services/monitoring/audit"
 	"github.com/amerfu/pllm/internal/services/data/budget"
+	"github.com/amerfu/pllm/internal/services/data/deletion"
 	"github.com/amerfu/pllm/internal/services/integrations/team"
 )
 
@@ -21,6 +25,7 @@ type TeamHandler struct {
 	teamService   *team.TeamService
 	auditLogger   *audit.Logger
 	budgetService budget.Service
+	deletion      *deletion.Service
 }
 
 func NewTeamHandler(logger *zap.Logger, teamService *team.TeamService, db *gorm.DB, budgetService budget.Service) *TeamHandler {
@@ -32,6 +37,12 @@ func NewTeamHandler(logger *zap.Logger, teamService *team.TeamService, db *gorm.
 	}
 }
 
+// SetDeletion makes DeleteTeam soft delete the team and its keys, keeping
+// its members, so it can be restored until the purge
+func (h *TeamHandler) SetDeletion(service *deletion.Service) {
+	h.deletion = service
+}
+
 // CreateTeam creates a new team
 func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	var req team.CreateTeamRequest
@@ -58,6 +69,11 @@ func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 			h.sendError(w, http.StatusConflict, "Team name already exists")
 			return
 		}
+		if err == team.ErrInvalidAliases || err == team.ErrInvalidResponseMinimization || err == team.ErrInvalidEnvironments ||
+			err == team.ErrInvalidSystemPrompt || err == team.ErrInvalidBandwidthQuota {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -121,6 +137,11 @@ func (h *TeamHandler) UpdateTeam(w http.ResponseWriter, r *http.Request) {
 			h.sendError(w, http.StatusNotFound, "Team not found")
 			return
 		}
+		if err == team.ErrInvalidAliases || err == team.ErrInvalidResponseMinimization || err == team.ErrInvalidEnvironments ||
+			err == team.ErrInvalidSystemPrompt || err == team.ErrInvalidBandwidthQuota {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -151,8 +172,12 @@ func (h *TeamHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := h.teamService.DeleteTeam(r.Context(), teamID); err != nil {
-		if err == team.ErrTeamNotFound {
+	deleteTeam := h.teamService.DeleteTeam
+	if h.deletion != nil {
+		deleteTeam = h.deletion.DeleteTeam
+	}
+	if err := deleteTeam(r.Context(), teamID); err != nil {
+		if err == team.ErrTeamNotFound || errors.Is(err, deletion.ErrNotFound) {
 			h.sendError(w, http.StatusNotFound, "Team not found")
 			return
 		}
@@ -248,6 +273,10 @@ func (h *TeamHandler) AddMember(w http.ResponseWriter, r *http.Request) {
 
 	member, err := h.teamService.AddMember(r.Context(), teamID, &req)
 	if err != nil {
+		if err == team.ErrInvalidRole {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -296,6 +325,10 @@ func (h *TeamHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
 			h.sendError(w, http.StatusNotFound, "Member not found")
 			return
 		}
+		if err == team.ErrInvalidRole {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -364,3 +397,149 @@ func (h *TeamHandler) GetTeamStats(w http.ResponseWriter, r *http.Request) {
 
 	h.sendJSON(w, http.StatusOK, stats)
 }
+
+// TeamBudget is the budget of a team as seen and changed by its billing
+// admins
+type TeamBudget struct {
+	TeamID         uuid.UUID           `json:"team_id"`
+	MaxBudget      float64             `json:"max_budget"`
+	BudgetDuration models.BudgetPeriod `json:"budget_duration"`
+	BudgetAlertAt  float64             `json:"budget_alert_at"`
+	CurrentSpend   float64             `json:"current_spend"`
+	BudgetResetAt  time.Time           `json:"budget_reset_at"`
+}
+
+// UpdateTeamBudgetRequest changes the fields that are set
+type UpdateTeamBudgetRequest struct {
+	MaxBudget      *float64             `json:"max_budget,omitempty"`
+	BudgetDuration *models.BudgetPeriod `json:"budget_duration,omitempty"`
+	BudgetAlertAt  *float64             `json:"budget_alert_at,omitempty"`
+}
+
+func teamBudget(t *models.Team) TeamBudget {
+	return TeamBudget{
+		TeamID:         t.ID,
+		MaxBudget:      t.MaxBudget,
+		BudgetDuration: t.BudgetDuration,
+		BudgetAlertAt:  t.BudgetAlertAt,
+		CurrentSpend:   t.CurrentSpend,
+		BudgetResetAt:  t.BudgetResetAt,
+	}
+}
+
+// GetTeamBudget returns a team's budget and spend. Access is enforced by
+// the team permission middleware on the route.
+func (h *TeamHandler) GetTeamBudget(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	foundTeam, err := h.teamService.GetTeam(r.Context(), teamID)
+	if err != nil {
+		if err == team.ErrTeamNotFound {
+			h.sendError(w, http.StatusNotFound, "Team not found")
+			return
+		}
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, teamBudget(foundTeam))
+}
+
+// UpdateTeamBudget changes a team's budget, and nothing else, for the
+// members allowed to manage it (owners, admins and billing admins). Access
+// is enforced by the team permission middleware on the route.
+func (h *TeamHandler) UpdateTeamBudget(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	var req UpdateTeamBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.MaxBudget != nil {
+		if *req.MaxBudget < 0 {
+			h.sendError(w, http.StatusBadRequest, "max_budget must not be negative")
+			return
+		}
+		updates["max_budget"] = *req.MaxBudget
+	}
+	if req.BudgetDuration != nil {
+		switch *req.BudgetDuration {
+		case models.BudgetPeriodDaily, models.BudgetPeriodWeekly, models.BudgetPeriodMonthly, models.BudgetPeriodYearly:
+		default:
+			h.sendError(w, http.StatusBadRequest, "budget_duration must be daily, weekly, monthly or yearly")
+			return
+		}
+		updates["budget_duration"] = *req.BudgetDuration
+	}
+	if req.BudgetAlertAt != nil {
+		if *req.BudgetAlertAt < 0 || *req.BudgetAlertAt > 100 {
+			h.sendError(w, http.StatusBadRequest, "budget_alert_at must be a percentage between 0 and 100")
+			return
+		}
+		updates["budget_alert_at"] = *req.BudgetAlertAt
+	}
+	if len(updates) == 0 {
+		h.sendError(w, http.StatusBadRequest, "No budget fields to update")
+		return
+	}
+
+	updatedTeam, err := h.teamService.UpdateTeam(r.Context(), teamID, updates)
+	if err != nil {
+		if err == team.ErrTeamNotFound {
+			h.sendError(w, http.StatusNotFound, "Team not found")
+			return
+		}
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if userID, ok := middleware.GetUserID(r.Context()); ok {
+		if err := h.auditLogger.LogTeamUpdated(r.Context(), userID, teamID, updates); err != nil {
+			h.logger.Warn("Failed to audit team budget change", zap.String("team_id", teamID.String()), zap.Error(err))
+		}
+	}
+
+	h.sendJSON(w, http.StatusOK, teamBudget(updatedTeam))
+}
+
+// GetTeamAuditLogs lists the audit events of a team for its owners, admins
+// and auditors. Results are scoped to the team whatever filters are passed;
+// access is enforced by the team permission middleware on the route.
+func (h *TeamHandler) GetTeamAuditLogs(w http.ResponseWriter, r *http.Request) {
+	teamID, err := uuid.Parse(chi.URLParam(r, "teamID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	filters := auditLogFiltersFromQuery(r.URL.Query())
+	filters.TeamID = nil
+	filters.ScopeTeamID = &teamID
+
+	logs, total, err := h.auditLogger.GetAuditLogs(r.Context(), filters)
+	if err != nil {
+		h.logger.Error("Failed to fetch team audit logs", zap.String("team_id", teamID.String()), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to fetch audit logs")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"team_id":    teamID,
+		"audit_logs": logs,
+		"total":      total,
+		"limit":      filters.Limit,
+		"offset":     filters.Offset,
+		"has_more":   int64(filters.Offset+len(logs)) < total,
+	})
+}
@@ -0,0 +1,192 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/integrations/tools"
+)
+
+// ToolHandler handles CRUD operations for gateway-executed tools.
+type ToolHandler struct {
+	baseHandler
+	service      *tools.Service
+	allowedHosts []string
+}
+
+// NewToolHandler creates a new ToolHandler. Tool endpoints must point to one
+// of allowedHosts.
+func NewToolHandler(logger *zap.Logger, db *gorm.DB, allowedHosts []string) *ToolHandler {
+	return &ToolHandler{
+		baseHandler:  baseHandler{logger: logger},
+		service:      tools.NewService(db, logger),
+		allowedHosts: allowedHosts,
+	}
+}
+
+// ToolRequest is the request body for creating or updating a tool.
+type ToolRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+	Endpoint    string          `json:"endpoint"`
+	TimeoutMs   int             `json:"timeout_ms,omitempty"`
+	Enabled     *bool           `json:"enabled,omitempty"`
+}
+
+// toModel validates the request and converts it to a tool
+func (h *ToolHandler) toModel(req *ToolRequest) (*models.GatewayTool, string) {
+	req.Name = strings.TrimSpace(req.Name)
+	if err := tools.ValidateName(req.Name); err != nil {
+		return nil, err.Error()
+	}
+	if err := tools.ValidateEndpoint(req.Endpoint, h.allowedHosts); err != nil {
+		return nil, err.Error()
+	}
+	if len(req.Parameters) > 0 && !json.Valid(req.Parameters) {
+		return nil, "parameters must be a JSON schema object"
+	}
+	if req.TimeoutMs < 0 {
+		return nil, "timeout_ms must not be negative"
+	}
+
+	tool := &models.GatewayTool{
+		Name:        req.Name,
+		Description: req.Description,
+		Parameters:  datatypes.JSON(req.Parameters),
+		Endpoint:    req.Endpoint,
+		TimeoutMs:   req.TimeoutMs,
+		Enabled:     true,
+	}
+	if req.Enabled != nil {
+		tool.Enabled = *req.Enabled
+	}
+	return tool, ""
+}
+
+// ListTools returns all registered tools.
+func (h *ToolHandler) ListTools(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.List()
+	if err != nil {
+		h.logger.Error("Failed to list tools", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list tools: "+err.Error())
+		return
+	}
+
+	h.sendResponse(w, http.StatusOK, map[string]interface{}{
+		"tools":         list,
+		"total":         len(list),
+		"allowed_hosts": h.allowedHosts,
+	})
+}
+
+// CreateTool registers a new tool.
+func (h *ToolHandler) CreateTool(w http.ResponseWriter, r *http.Request) {
+	var req ToolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	tool, message := h.toModel(&req)
+	if tool == nil {
+		h.sendError(w, http.StatusBadRequest, message)
+		return
+	}
+
+	if err := h.service.Create(tool); err != nil {
+		if strings.Contains(err.Error(), "23505") || strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "unique") {
+			h.sendError(w, http.StatusConflict, "A tool with this name already exists")
+			return
+		}
+		h.logger.Error("Failed to create tool", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to create tool: "+err.Error())
+		return
+	}
+
+	h.sendResponse(w, http.StatusCreated, tool)
+}
+
+// GetTool returns a single tool by ID.
+func (h *ToolHandler) GetTool(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "toolID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid tool ID format")
+		return
+	}
+
+	tool, err := h.service.Get(id)
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, "Tool not found")
+		return
+	}
+
+	h.sendResponse(w, http.StatusOK, tool)
+}
+
+// UpdateTool replaces a tool's definition.
+func (h *ToolHandler) UpdateTool(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "toolID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid tool ID format")
+		return
+	}
+
+	var req ToolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	tool, message := h.toModel(&req)
+	if tool == nil {
+		h.sendError(w, http.StatusBadRequest, message)
+		return
+	}
+
+	if err := h.service.Update(id, tool); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.sendError(w, http.StatusNotFound, "Tool not found")
+			return
+		}
+		h.logger.Error("Failed to update tool", zap.String("id", id.String()), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to update tool: "+err.Error())
+		return
+	}
+
+	updated, err := h.service.Get(id)
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, "Tool not found")
+		return
+	}
+	h.sendResponse(w, http.StatusOK, updated)
+}
+
+// DeleteTool removes a tool.
+func (h *ToolHandler) DeleteTool(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "toolID"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid tool ID format")
+		return
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.sendError(w, http.StatusNotFound, "Tool not found")
+			return
+		}
+		h.logger.Error("Failed to delete tool", zap.String("id", id.String()), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to delete tool: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
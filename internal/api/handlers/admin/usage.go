@@ -0,0 +1,242 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// UsageHandler serves individual usage records
+type UsageHandler struct {
+	baseHandler
+	db       *gorm.DB
+	counters *redisService.UsageCounters
+}
+
+// NewUsageHandler creates a new UsageHandler.
+func NewUsageHandler(logger *zap.Logger, db *gorm.DB) *UsageHandler {
+	return &UsageHandler{
+		baseHandler: baseHandler{logger: logger},
+		db:          db,
+	}
+}
+
+// SetCounters enables exact usage totals from the Redis usage counters
+func (h *UsageHandler) SetCounters(counters *redisService.UsageCounters) {
+	h.counters = counters
+}
+
+// UsageTotalsResponse holds the exact usage totals of an entity over a range
+// of days
+type UsageTotalsResponse struct {
+	Scope string `json:"scope"`
+	ID    string `json:"id,omitempty"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	redisService.UsageTotals
+}
+
+// TeamBandwidthResponse holds the bandwidth a team used in a month and its
+// quota
+type TeamBandwidthResponse struct {
+	TeamID string `json:"team_id"`
+	Month  string `json:"month"`
+	redisService.Bandwidth
+	TotalBytes int64 `json:"total_bytes"`
+	QuotaBytes int64 `json:"quota_bytes"` // 0 is unlimited
+}
+
+// CostBreakdownResponse explains the cost charged for one request
+type CostBreakdownResponse struct {
+	ID              string                `json:"id"`
+	RequestID       string                `json:"request_id"`
+	Timestamp       string                `json:"timestamp"`
+	Model           string                `json:"model"`
+	ProviderModel   string                `json:"provider_model,omitempty"`
+	Provider        string                `json:"provider"`
+	KeyID           string                `json:"key_id,omitempty"`
+	TeamID          string                `json:"team_id,omitempty"`
+	InputTokens     int                   `json:"input_tokens"`
+	OutputTokens    int                   `json:"output_tokens"`
+	ReasoningTokens int                   `json:"reasoning_tokens,omitempty"`
+	TotalCost       float64               `json:"total_cost"`
+	Breakdown       *config.CostBreakdown `json:"breakdown"` // Null for records stored before itemized pricing
+}
+
+// GetCostBreakdown returns the itemized pricing of a usage record, looked up
+// by record ID or request ID
+func (h *UsageHandler) GetCostBreakdown(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	query := h.db.Where("request_id = ?", id)
+	if recordID, err := uuid.Parse(id); err == nil {
+		query = h.db.Where("id = ? OR request_id = ?", recordID, id)
+	}
+
+	var usage models.Usage
+	if err := query.First(&usage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.sendError(w, http.StatusNotFound, "Usage record not found")
+			return
+		}
+		h.logger.Error("Failed to load usage record", zap.String("id", id), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to load usage record")
+		return
+	}
+
+	response := CostBreakdownResponse{
+		ID:              usage.ID.String(),
+		RequestID:       usage.RequestID,
+		Timestamp:       usage.Timestamp.UTC().Format(time.RFC3339),
+		Model:           usage.Model,
+		ProviderModel:   usage.ProviderModel,
+		Provider:        usage.Provider,
+		InputTokens:     usage.InputTokens,
+		OutputTokens:    usage.OutputTokens,
+		ReasoningTokens: usage.ReasoningTokens,
+		TotalCost:       usage.TotalCost,
+	}
+	if usage.KeyID != nil {
+		response.KeyID = usage.KeyID.String()
+	}
+	if usage.TeamID != nil {
+		response.TeamID = usage.TeamID.String()
+	}
+	if len(usage.CostBreakdown) > 0 {
+		var breakdown config.CostBreakdown
+		if err := json.Unmarshal(usage.CostBreakdown, &breakdown); err != nil {
+			h.logger.Warn("Invalid stored cost breakdown", zap.String("id", id), zap.Error(err))
+		} else {
+			response.Breakdown = &breakdown
+		}
+	}
+
+	h.sendResponse(w, http.StatusOK, response)
+}
+
+// GetTotals returns exact usage totals from the usage counters, which stay
+// accurate when usage sampling stores only some detailed rows. The range
+// defaults to the current UTC day.
+func (h *UsageHandler) GetTotals(w http.ResponseWriter, r *http.Request) {
+	if h.counters == nil {
+		h.sendError(w, http.StatusNotImplemented, "Usage counters require the Redis coordination backend")
+		return
+	}
+
+	query := r.URL.Query()
+	scope := query.Get("scope")
+	if scope == "" {
+		scope = redisService.UsageScopeGlobal
+	}
+	id := query.Get("id")
+	switch scope {
+	case redisService.UsageScopeGlobal:
+		id = ""
+	case redisService.UsageScopeUser, redisService.UsageScopeTeam, redisService.UsageScopeKey, redisService.UsageScopeModel:
+		if id == "" {
+			h.sendError(w, http.StatusBadRequest, "id is required for scope "+scope)
+			return
+		}
+	default:
+		h.sendError(w, http.StatusBadRequest, "scope must be one of global, user, team, key or model")
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, err := parseUsageDay(query.Get("from"), today)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "from must be a date (YYYY-MM-DD)")
+		return
+	}
+	to, err := parseUsageDay(query.Get("to"), today)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "to must be a date (YYYY-MM-DD)")
+		return
+	}
+	if to.Before(from) || to.Sub(from) > 366*24*time.Hour {
+		h.sendError(w, http.StatusBadRequest, "to must be within a year after from")
+		return
+	}
+
+	totals, err := h.counters.Get(r.Context(), scope, id, from, to)
+	if err != nil {
+		h.logger.Error("Failed to read usage counters", zap.String("scope", scope), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to read usage totals")
+		return
+	}
+
+	h.sendResponse(w, http.StatusOK, UsageTotalsResponse{
+		Scope:       scope,
+		ID:          id,
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		UsageTotals: *totals,
+	})
+}
+
+// GetTeamBandwidth returns the bandwidth a team used in a UTC month,
+// against its monthly quota. The month defaults to the current one.
+func (h *UsageHandler) GetTeamBandwidth(w http.ResponseWriter, r *http.Request) {
+	if h.counters == nil {
+		h.sendError(w, http.StatusNotImplemented, "Usage counters require the Redis coordination backend")
+		return
+	}
+
+	query := r.URL.Query()
+	teamID, err := uuid.Parse(query.Get("team_id"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "team_id must be a team ID")
+		return
+	}
+	month := time.Now().UTC()
+	if value := query.Get("month"); value != "" {
+		if month, err = time.Parse("2006-01", value); err != nil {
+			h.sendError(w, http.StatusBadRequest, "month must be a month (YYYY-MM)")
+			return
+		}
+	}
+
+	var team models.Team
+	if err := h.db.Select("id", "max_monthly_bandwidth").First(&team, "id = ?", teamID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.sendError(w, http.StatusNotFound, "Team not found")
+			return
+		}
+		h.logger.Error("Failed to load team", zap.String("team_id", teamID.String()), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to load team")
+		return
+	}
+
+	used, err := h.counters.MonthlyBandwidth(r.Context(), redisService.UsageScopeTeam, teamID.String(), month)
+	if err != nil {
+		h.logger.Error("Failed to read team bandwidth", zap.String("team_id", teamID.String()), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to read team bandwidth")
+		return
+	}
+
+	h.sendResponse(w, http.StatusOK, TeamBandwidthResponse{
+		TeamID:     teamID.String(),
+		Month:      month.Format("2006-01"),
+		Bandwidth:  *used,
+		TotalBytes: used.Total(),
+		QuotaBytes: team.MaxMonthlyBandwidth,
+	})
+}
+
+// parseUsageDay parses a YYYY-MM-DD date, returning fallback when it is empty
+func parseUsageDay(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -2,12 +2,14 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/data/deletion"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -16,8 +18,9 @@ import (
 
 // UserHandler handles user management endpoints
 type UserHandler struct {
-	logger *zap.Logger
-	db     *gorm.DB
+	logger   *zap.Logger
+	db       *gorm.DB
+	deletion *deletion.Service
 }
 
 // NewUserHandler creates a new user handler
@@ -28,6 +31,12 @@ func NewUserHandler(logger *zap.Logger, db *gorm.DB) *UserHandler {
 	}
 }
 
+// SetDeletion makes DeleteUser soft delete the user and their keys so they
+// can be restored until the purge; without it users are only deactivated
+func (h *UserHandler) SetDeletion(service *deletion.Service) {
+	h.deletion = service
+}
+
 // UserResponse extends the User model with provider icon information
 type UserResponse struct {
 	models.User
@@ -273,6 +282,25 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userID")
 
+	if h.deletion != nil {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		if err := h.deletion.DeleteUser(r.Context(), id); err != nil {
+			if errors.Is(err, deletion.ErrNotFound) {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			h.logger.Error("Failed to delete user", zap.Error(err))
+			http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	// Soft delete by setting is_active to false
 	result := h.db.Model(&models.User{}).
 		Where("id = ?", userID).
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"unicode/utf8"
 
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
 	"github.com/amerfu/pllm/internal/services/llm/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
@@ -34,7 +38,7 @@ func NewAudioHandlerWithMetrics(logger *zap.Logger, modelManager *models.ModelMa
 
 // CreateTranscription transcribes audio into the input language
 // @Summary Create transcription
-// @Description Transcribes audio into the input language
+// @Description Transcribes audio into the input language. With stream=true the response is a stream of server-sent events carrying partial transcripts (transcript.text.delta) and the final transcript (transcript.text.done).
 // @Tags Audio
 // @Accept multipart/form-data
 // @Produce json
@@ -46,33 +50,32 @@ func NewAudioHandlerWithMetrics(logger *zap.Logger, modelManager *models.ModelMa
 // @Param prompt formData string false "An optional text to guide the model's style"
 // @Param response_format formData string false "The format of the transcript output"
 // @Param temperature formData number false "The sampling temperature"
+// @Param stream formData boolean false "Stream partial transcripts as server-sent events"
 // @Success 200 {object} providers.TranscriptionResponse
 // @Failure 400 {object} providers.ErrorResponse
 // @Failure 401 {object} providers.ErrorResponse
 // @Failure 500 {object} providers.ErrorResponse
 // @Router /audio/transcriptions [post]
 func (h *AudioHandler) CreateTranscription(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form
-	err := r.ParseMultipartForm(32 << 20) // 32MB max
+	// Read the form part by part; the audio file is spooled to disk, not memory
+	upload, err := readMultipartUpload(r, "file")
 	if err != nil {
+		if limit, ok := middleware.BodyTooLarge(err); ok {
+			middleware.WriteBodyTooLarge(w, limit)
+			return
+		}
 		h.sendError(w, http.StatusBadRequest, "Failed to parse form data")
 		return
 	}
+	defer upload.Close()
 
-	// Get the uploaded file
-	file, _, err := r.FormFile("file")
-	if err != nil {
+	if upload.file == nil {
 		h.sendError(w, http.StatusBadRequest, "Audio file is required")
 		return
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			h.logger.Warn("failed to close uploaded file", zap.Error(err))
-		}
-	}()
 
 	// Get model parameter
-	model := r.FormValue("model")
+	model := upload.Value("model")
 	if model == "" {
 		h.sendError(w, http.StatusBadRequest, "Model parameter is required")
 		return
@@ -80,28 +83,39 @@ func (h *AudioHandler) CreateTranscription(w http.ResponseWriter, r *http.Reques
 
 	// Build transcription request
 	request := &providers.TranscriptionRequest{
-		File:           file,
+		File:           upload.file,
+		Filename:       upload.filename,
 		Model:          model,
-		Language:       r.FormValue("language"),
-		Prompt:         r.FormValue("prompt"),
-		ResponseFormat: r.FormValue("response_format"),
+		Language:       upload.Value("language"),
+		Prompt:         upload.Value("prompt"),
+		ResponseFormat: upload.Value("response_format"),
 	}
+	request.Stream, _ = strconv.ParseBool(upload.Value("stream"))
 
 	// Parse optional temperature
-	if tempStr := r.FormValue("temperature"); tempStr != "" {
+	if tempStr := upload.Value("temperature"); tempStr != "" {
 		temp := float32(0.0)
 		if _, err := fmt.Sscanf(tempStr, "%f", &temp); err == nil {
 			request.Temperature = &temp
 		}
 	}
 
+	middleware.SetModelName(r.Context(), model)
+
+	if request.Stream {
+		h.streamTranscription(w, r, request)
+		return
+	}
+
 	// Get model instance and provider
 	instance, err := h.modelManager.GetBestInstanceAdaptive(r.Context(), model)
 	if err != nil {
 		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("Model not available: %s", err.Error()))
 		return
 	}
-	provider := instance.Provider
+	defer instance.Begin()()
+	provider := providerFor(r.Context(), h.modelManager, instance)
+	h.setTranscriptionModel(r.Context(), request, instance)
 
 	// Call transcription endpoint
 	response, err := provider.AudioTranscription(r.Context(), request)
@@ -110,6 +124,7 @@ func (h *AudioHandler) CreateTranscription(w http.ResponseWriter, r *http.Reques
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	recordTranscriptionUsage(r.Context(), response.Usage, response.AudioSeconds())
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
@@ -118,6 +133,103 @@ func (h *AudioHandler) CreateTranscription(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// streamTranscription relays partial transcripts as server-sent events. Only
+// instances whose provider can stream transcriptions are eligible.
+func (h *AudioHandler) streamTranscription(w http.ResponseWriter, r *http.Request, request *providers.TranscriptionRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.sendError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	instance, err := h.streamingTranscriptionInstance(r.Context(), request.Model)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer instance.Begin()()
+	h.setTranscriptionModel(r.Context(), request, instance)
+
+	streamer := providerFor(r.Context(), h.modelManager, instance).(providers.TranscriptionStreamer)
+	events, err := streamer.AudioTranscriptionStream(r.Context(), request)
+	if err != nil {
+		h.logger.Error("Audio transcription stream failed", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for event := range events {
+		if event.Type == "transcript.text.done" && event.Usage != nil {
+			recordTranscriptionUsage(r.Context(), event.Usage, event.Usage.Seconds)
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			h.logger.Error("Failed to marshal transcription event", zap.Error(err))
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			h.logger.Debug("Client disconnected during transcription stream", zap.Error(err))
+			return
+		}
+		flusher.Flush()
+	}
+
+	if _, err := fmt.Fprint(w, "data: [DONE]\n\n"); err == nil {
+		flusher.Flush()
+	}
+}
+
+// streamingTranscriptionInstance picks the best instance of the model, falling
+// back to any healthy instance whose provider supports streaming
+func (h *AudioHandler) streamingTranscriptionInstance(ctx context.Context, model string) (*models.ModelInstance, error) {
+	instance, err := h.modelManager.GetBestInstanceAdaptive(ctx, model)
+	if err != nil {
+		return nil, fmt.Errorf("model not available: %s", err.Error())
+	}
+	if _, ok := instance.Provider.(providers.TranscriptionStreamer); ok {
+		return instance, nil
+	}
+
+	instances, _ := h.modelManager.GetRegistry().GetModelInstances(model)
+	for _, candidate := range instances {
+		if _, ok := candidate.Provider.(providers.TranscriptionStreamer); ok && candidate.Healthy.Load() {
+			return candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("model %s does not support streaming transcription", model)
+}
+
+// setTranscriptionModel sends the provider's model ID upstream and records
+// the resolved model for usage tracking
+func (h *AudioHandler) setTranscriptionModel(ctx context.Context, request *providers.TranscriptionRequest, instance *models.ModelInstance) {
+	if instance.Config.Provider.Model != "" {
+		request.Model = instance.Config.Provider.Model
+	}
+	middleware.SetResolvedModel(ctx,
+		instance.Config.ModelName,
+		instance.Config.Provider.Model,
+		instance.Config.Provider.Type,
+		"",
+	)
+}
+
+// recordTranscriptionUsage records the provider-reported usage of a
+// transcription: tokens for token-billed models, seconds for the others
+func recordTranscriptionUsage(ctx context.Context, usage *providers.TranscriptionUsage, seconds float64) {
+	if usage != nil && usage.Type == "tokens" {
+		middleware.SetTokenUsage(ctx, usage.InputTokens, usage.OutputTokens, 0)
+		return
+	}
+	middleware.SetAudioUsage(ctx, seconds)
+}
+
 // CreateTranslation translates audio into English
 // @Summary Create translation
 // @Description Translates audio into English
@@ -181,7 +293,8 @@ func (h *AudioHandler) CreateSpeech(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("Model not available: %s", err.Error()))
 		return
 	}
-	provider := instance.Provider
+	defer instance.Begin()()
+	provider := providerFor(r.Context(), h.modelManager, instance)
 
 	// Call speech endpoint
 	audioData, err := provider.AudioSpeech(r.Context(), &request)
@@ -191,6 +304,15 @@ func (h *AudioHandler) CreateSpeech(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Speech is billed per input character
+	middleware.SetResolvedModel(r.Context(),
+		instance.Config.ModelName,
+		instance.Config.Provider.Model,
+		instance.Config.Provider.Type,
+		"",
+	)
+	middleware.SetSpeechUsage(r.Context(), utf8.RuneCountInString(request.Input))
+
 	// Determine content type based on response format
 	contentType := "audio/mpeg" // default
 	if request.ResponseFormat != "" {
@@ -2,17 +2,24 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"github.com/amerfu/pllm/internal/core/auth"
+	"github.com/amerfu/pllm/internal/core/config"
 	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	"github.com/amerfu/pllm/internal/core/models"
+	keyService "github.com/amerfu/pllm/internal/services/integrations/key"
+	"github.com/amerfu/pllm/internal/services/monitoring/usernotify"
 )
 
 type AuthHandler struct {
@@ -20,6 +27,8 @@ type AuthHandler struct {
 	authService      *auth.AuthService
 	masterKeyService *auth.MasterKeyService
 	db               *gorm.DB
+	rotator          *keyService.Rotator
+	notifications    config.UserNotificationsConfig
 }
 
 func NewAuthHandler(logger *zap.Logger, authService *auth.AuthService, masterKeyService *auth.MasterKeyService, db *gorm.DB) *AuthHandler {
@@ -31,6 +40,16 @@ func NewAuthHandler(logger *zap.Logger, authService *auth.AuthService, masterKey
 	}
 }
 
+// SetKeyRotator enables self-service key rotation
+func (h *AuthHandler) SetKeyRotator(rotator *keyService.Rotator) {
+	h.rotator = rotator
+}
+
+// SetUserNotifications sets the defaults of users' notification preferences
+func (h *AuthHandler) SetUserNotifications(cfg config.UserNotificationsConfig) {
+	h.notifications = cfg
+}
+
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	h.sendResponse(w, http.StatusNotImplemented, map[string]string{
 		"message": "Registration not yet implemented",
@@ -133,6 +152,28 @@ func (h *AuthHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, "Invalid request", err)
 		return
 	}
+	if err := models.ValidateScopes(req.Scopes); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := models.ValidateResponseMinimization(req.ResponseMinimization); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := models.ValidateRequestValidation(req.RequestValidation); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err := models.ValidateKeyEnvironment(req.Environment); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if scope == models.ScopeAdminRead || strings.HasPrefix(scope, models.ScopeAdminRead+":") || scope == models.ScopeMonitoring {
+			h.sendError(w, http.StatusForbidden, fmt.Sprintf("The %s scope can only be granted through the admin API", scope), nil)
+			return
+		}
+	}
 
 	// Generate key
 	keyValue, keyHash, err := models.GenerateKey(models.KeyTypeAPI)
@@ -143,22 +184,27 @@ func (h *AuthHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 
 	// Create key record
 	key := &models.Key{
-		Key:              keyValue,
-		KeyHash:          keyHash,
-		Name:             req.Name,
-		Type:             models.KeyTypeAPI,
-		UserID:           &userID,
-		IsActive:         true,
-		MaxBudget:        req.MaxBudget,
-		BudgetDuration:   req.BudgetDuration,
-		TPM:              req.TPM,
-		RPM:              req.RPM,
-		MaxParallelCalls: req.MaxParallelCalls,
-		AllowedModels:    req.AllowedModels,
-		BlockedModels:    req.BlockedModels,
-		Scopes:           req.Scopes,
-		Tags:             req.Tags,
-		CreatedBy:        &userID,
+		Key:                  keyValue,
+		KeyHash:              keyHash,
+		Name:                 req.Name,
+		Type:                 models.KeyTypeAPI,
+		UserID:               &userID,
+		IsActive:             true,
+		MaxBudget:            req.MaxBudget,
+		BudgetDuration:       req.BudgetDuration,
+		MaxCostPerRequest:    req.MaxCostPerRequest,
+		TPM:                  req.TPM,
+		RPM:                  req.RPM,
+		MaxParallelCalls:     req.MaxParallelCalls,
+		AllowedModels:        req.AllowedModels,
+		BlockedModels:        req.BlockedModels,
+		Scopes:               req.Scopes,
+		Tags:                 req.Tags,
+		CacheOutputs:         req.CacheOutputs,
+		ResponseMinimization: req.ResponseMinimization,
+		RequestValidation:    req.RequestValidation,
+		Environment:          req.Environment,
+		CreatedBy:            &userID,
 	}
 
 	if err := h.db.Create(key).Error; err != nil {
@@ -239,6 +285,230 @@ func (h *AuthHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// RotateAPIKey replaces one of the user's keys with a new key carrying the
+// same settings. The old key keeps working for overlap_seconds (the
+// configured default when omitted; 0 revokes it at once), and the new key is
+// posted to the key's rotation webhook when one is registered.
+func (h *AuthHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required", nil)
+		return
+	}
+	if h.rotator == nil {
+		h.sendError(w, http.StatusServiceUnavailable, "Key rotation requires a database", nil)
+		return
+	}
+	keyID, err := uuid.Parse(chi.URLParam(r, "key_id"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid key ID", err)
+		return
+	}
+
+	var req struct {
+		OverlapSeconds *int `json:"overlap_seconds"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid request", err)
+			return
+		}
+	}
+	var overlap *time.Duration
+	if req.OverlapSeconds != nil {
+		if *req.OverlapSeconds < 0 {
+			h.sendError(w, http.StatusBadRequest, "overlap_seconds must not be negative", nil)
+			return
+		}
+		d := time.Duration(*req.OverlapSeconds) * time.Second
+		overlap = &d
+	}
+
+	rotation, err := h.rotator.Rotate(r.Context(), userID, keyID, overlap)
+	if err != nil {
+		switch {
+		case errors.Is(err, keyService.ErrKeyNotFound):
+			h.sendError(w, http.StatusNotFound, "Key not found", nil)
+		case errors.Is(err, keyService.ErrKeyNotRotatable), errors.Is(err, keyService.ErrKeyAlreadyRotated):
+			h.sendError(w, http.StatusConflict, err.Error(), nil)
+		case errors.Is(err, keyService.ErrOverlapTooLong):
+			h.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		default:
+			h.sendError(w, http.StatusInternalServerError, "Failed to rotate key", err)
+		}
+		return
+	}
+
+	h.sendResponse(w, http.StatusCreated, map[string]interface{}{
+		"key":                     rotation.KeyValue,
+		"new_key":                 rotation.NewKey,
+		"previous_key_id":         rotation.OldKey.ID,
+		"previous_key_expires_at": rotation.OldKeyExpiresAt,
+		"webhook_notified":        rotation.Notified,
+	})
+}
+
+// SetKeyWebhook registers the endpoint notified with the new key when one of
+// the user's keys is rotated. The returned secret signs the deliveries and is
+// only shown once.
+func (h *AuthHandler) SetKeyWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required", nil)
+		return
+	}
+	if h.rotator == nil {
+		h.sendError(w, http.StatusServiceUnavailable, "Key rotation requires a database", nil)
+		return
+	}
+	keyID, err := uuid.Parse(chi.URLParam(r, "key_id"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid key ID", err)
+		return
+	}
+
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	secret, err := h.rotator.SetWebhook(r.Context(), userID, keyID, req.URL)
+	if err != nil {
+		switch {
+		case errors.Is(err, keyService.ErrInvalidWebhookURL):
+			h.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, keyService.ErrKeyNotFound):
+			h.sendError(w, http.StatusNotFound, "Key not found", nil)
+		default:
+			h.sendError(w, http.StatusInternalServerError, "Failed to register webhook", err)
+		}
+		return
+	}
+
+	h.sendResponse(w, http.StatusOK, map[string]interface{}{
+		"url":    req.URL,
+		"secret": secret,
+	})
+}
+
+// DeleteKeyWebhook unregisters the rotation webhook of one of the user's keys
+func (h *AuthHandler) DeleteKeyWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required", nil)
+		return
+	}
+	if h.rotator == nil {
+		h.sendError(w, http.StatusServiceUnavailable, "Key rotation requires a database", nil)
+		return
+	}
+	keyID, err := uuid.Parse(chi.URLParam(r, "key_id"))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid key ID", err)
+		return
+	}
+
+	if err := h.rotator.RemoveWebhook(r.Context(), userID, keyID); err != nil {
+		if errors.Is(err, keyService.ErrKeyNotFound) {
+			h.sendError(w, http.StatusNotFound, "Key not found", nil)
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to remove webhook", err)
+		}
+		return
+	}
+
+	h.sendResponse(w, http.StatusOK, map[string]string{
+		"message": "Webhook removed",
+	})
+}
+
+// GetNotificationPreferences returns which usage emails the user gets
+func (h *AuthHandler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required", nil)
+		return
+	}
+	if h.db == nil {
+		h.sendError(w, http.StatusServiceUnavailable, "Notification preferences require a database", nil)
+		return
+	}
+
+	pref, err := usernotify.LoadPreferences(r.Context(), h.db, h.notifications, userID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to load notification preferences", err)
+		return
+	}
+	h.sendResponse(w, http.StatusOK, h.notificationPreferencesResponse(pref))
+}
+
+// UpdateNotificationPreferences changes which usage emails the user gets.
+// Omitted fields keep their current value; empty thresholds restore the
+// configured ones.
+func (h *AuthHandler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required", nil)
+		return
+	}
+	if h.db == nil {
+		h.sendError(w, http.StatusServiceUnavailable, "Notification preferences require a database", nil)
+		return
+	}
+
+	var req struct {
+		SpendAlerts  *bool      `json:"spend_alerts"`
+		WeeklyDigest *bool      `json:"weekly_digest"`
+		Thresholds   *[]float64 `json:"thresholds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	pref, err := usernotify.LoadPreferences(r.Context(), h.db, h.notifications, userID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Failed to load notification preferences", err)
+		return
+	}
+	if req.SpendAlerts != nil {
+		pref.SpendAlerts = *req.SpendAlerts
+	}
+	if req.WeeklyDigest != nil {
+		pref.WeeklyDigest = *req.WeeklyDigest
+	}
+	if req.Thresholds != nil {
+		pref.Thresholds = *req.Thresholds
+	}
+
+	if err := usernotify.SavePreferences(r.Context(), h.db, pref); err != nil {
+		if errors.Is(err, usernotify.ErrInvalidThreshold) {
+			h.sendError(w, http.StatusBadRequest, err.Error(), nil)
+		} else {
+			h.sendError(w, http.StatusInternalServerError, "Failed to save notification preferences", err)
+		}
+		return
+	}
+	h.sendResponse(w, http.StatusOK, h.notificationPreferencesResponse(pref))
+}
+
+func (h *AuthHandler) notificationPreferencesResponse(pref *models.NotificationPreference) map[string]interface{} {
+	thresholds := []float64(pref.Thresholds)
+	if len(thresholds) == 0 {
+		thresholds = h.notifications.Thresholds
+	}
+	return map[string]interface{}{
+		"enabled":        h.notifications.Enabled,
+		"spend_alerts":   pref.SpendAlerts,
+		"weekly_digest":  pref.WeeklyDigest,
+		"thresholds":     thresholds,
+		"last_digest_at": pref.LastDigestAt,
+	}
+}
+
 func (h *AuthHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
 	h.sendResponse(w, http.StatusNotImplemented, map[string]string{
 		"message": "Get usage not yet implemented",
@@ -279,7 +549,7 @@ func (h *AuthHandler) GetBudgetStatus(w http.ResponseWriter, r *http.Request) {
 		SELECT t.id, t.name, t.max_budget, t.current_spend, t.budget_duration, t.budget_reset_at
 		FROM teams t
 		JOIN team_members tm ON t.id = tm.team_id
-		WHERE tm.user_id = ? AND t.is_active = true
+		WHERE tm.user_id = ? AND t.is_active = true AND t.deleted_at IS NULL
 	`, userID).Scan(&teams).Error
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, "Failed to fetch team budget data", err)
@@ -396,7 +666,7 @@ func (h *AuthHandler) GetUserTeams(w http.ResponseWriter, r *http.Request) {
 		SELECT t.id, t.name, t.description, t.max_budget, t.current_spend, t.budget_duration, t.budget_reset_at, t.is_active
 		FROM teams t
 		JOIN team_members tm ON t.id = tm.team_id
-		WHERE tm.user_id = ? AND t.is_active = true
+		WHERE tm.user_id = ? AND t.is_active = true AND t.deleted_at IS NULL
 		ORDER BY t.name
 	`, userID).Scan(&teams).Error
 	if err != nil {
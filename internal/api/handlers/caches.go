@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/llm/contextcache"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+type CachesHandler struct {
+	logger *zap.Logger
+	caches *contextcache.Service
+}
+
+func NewCachesHandler(logger *zap.Logger, cacheService *contextcache.Service) *CachesHandler {
+	return &CachesHandler{
+		logger: logger,
+		caches: cacheService,
+	}
+}
+
+// CacheList is the response of GET /v1/caches
+type CacheList struct {
+	Object string               `json:"object"`
+	Data   []*contextcache.View `json:"data"`
+}
+
+// CreateCache creates a context cache
+// @Summary Create context cache
+// @Description Caches a prompt prefix for reuse with cached_content. Gemini models store it provider-side; other models reuse it through prompt caching.
+// @Tags Caches
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param request body contextcache.CreateRequest true "Cache to create"
+// @Success 201 {object} contextcache.View
+// @Failure 400 {object} providers.ErrorResponse
+// @Failure 502 {object} providers.ErrorResponse
+// @Router /caches [post]
+func (h *CachesHandler) CreateCache(w http.ResponseWriter, r *http.Request) {
+	var request contextcache.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	owner := contextcache.Owner{}
+	if userID, ok := middleware.GetUserID(r.Context()); ok {
+		owner.UserID = &userID
+	}
+	if key, ok := middleware.GetKey(r.Context()); ok && key != nil {
+		owner.KeyID = &key.ID
+		owner.TeamID = key.TeamID
+	}
+
+	cache, charge, err := h.caches.Create(r.Context(), &request, owner)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+	h.recordCharge(r, cache, charge)
+
+	h.writeJSON(w, http.StatusCreated, contextcache.NewView(cache))
+}
+
+// ListCaches lists the caller's context caches
+// @Summary List context caches
+// @Tags Caches
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Success 200 {object} CacheList
+// @Router /caches [get]
+func (h *CachesHandler) ListCaches(w http.ResponseWriter, r *http.Request) {
+	owner := contextcache.Owner{}
+	if !middleware.IsMasterKey(r.Context()) {
+		if key, ok := middleware.GetKey(r.Context()); ok && key != nil {
+			owner.KeyID = &key.ID
+		} else if userID, ok := middleware.GetUserID(r.Context()); ok {
+			owner.UserID = &userID
+		} else {
+			h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+	}
+
+	caches, err := h.caches.List(r.Context(), owner)
+	if err != nil {
+		h.logger.Error("Failed to list context caches", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to list caches")
+		return
+	}
+
+	list := CacheList{Object: "list", Data: make([]*contextcache.View, 0, len(caches))}
+	for i := range caches {
+		list.Data = append(list.Data, contextcache.NewView(&caches[i]))
+	}
+	h.writeJSON(w, http.StatusOK, list)
+}
+
+// GetCache returns a context cache
+// @Summary Get context cache
+// @Tags Caches
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param id path string true "Cache ID"
+// @Success 200 {object} contextcache.View
+// @Failure 404 {object} providers.ErrorResponse
+// @Router /caches/{id} [get]
+func (h *CachesHandler) GetCache(w http.ResponseWriter, r *http.Request) {
+	cache, ok := h.loadCache(w, r)
+	if !ok {
+		return
+	}
+	h.writeJSON(w, http.StatusOK, contextcache.NewView(cache))
+}
+
+// UpdateCache renames a context cache or resets its TTL
+// @Summary Update context cache
+// @Description Sets a new display name or TTL (counted from now). Extending a provider-side cache is charged for the added storage time.
+// @Tags Caches
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param id path string true "Cache ID"
+// @Param request body contextcache.UpdateRequest true "Fields to update"
+// @Success 200 {object} contextcache.View
+// @Failure 400 {object} providers.ErrorResponse
+// @Failure 404 {object} providers.ErrorResponse
+// @Router /caches/{id} [patch]
+func (h *CachesHandler) UpdateCache(w http.ResponseWriter, r *http.Request) {
+	cache, ok := h.loadCache(w, r)
+	if !ok {
+		return
+	}
+
+	var request contextcache.UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	charge, err := h.caches.Update(r.Context(), cache, &request)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+	h.recordCharge(r, cache, charge)
+
+	h.writeJSON(w, http.StatusOK, contextcache.NewView(cache))
+}
+
+// DeleteCache deletes a context cache
+// @Summary Delete context cache
+// @Tags Caches
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param id path string true "Cache ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} providers.ErrorResponse
+// @Router /caches/{id} [delete]
+func (h *CachesHandler) DeleteCache(w http.ResponseWriter, r *http.Request) {
+	cache, ok := h.loadCache(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.caches.Delete(r.Context(), cache); err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      cache.ID,
+		"object":  "cached_content.deleted",
+		"deleted": true,
+	})
+}
+
+// loadCache fetches the cache named in the URL if the caller owns it
+func (h *CachesHandler) loadCache(w http.ResponseWriter, r *http.Request) (*models.ContextCache, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, "Cache not found")
+		return nil, false
+	}
+
+	cache, err := h.caches.Get(r.Context(), id)
+	if errors.Is(err, contextcache.ErrCacheNotFound) || (err == nil && !isOwner(r, cache.KeyID, cache.UserID)) {
+		h.sendError(w, http.StatusNotFound, "Cache not found")
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to load context cache", zap.String("cache_id", id.String()), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to load cache")
+		return nil, false
+	}
+	return cache, true
+}
+
+// recordCharge hands cache creation and storage cost to the budget middleware
+func (h *CachesHandler) recordCharge(r *http.Request, cache *models.ContextCache, charge *contextcache.Charge) {
+	if charge == nil || (charge.WriteTokens == 0 && charge.Cost == 0) {
+		return
+	}
+	middleware.SetResolvedModel(r.Context(), cache.Model, "", cache.ProviderType, "")
+	middleware.SetCacheUsage(r.Context(), charge.WriteTokens, charge.Cost)
+}
+
+func (h *CachesHandler) sendServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, contextcache.ErrInvalidRequest) {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.logger.Error("Context cache operation failed", zap.Error(err))
+	h.sendError(w, http.StatusBadGateway, err.Error())
+}
+
+func (h *CachesHandler) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Error("Failed to encode caches response", zap.Error(err))
+	}
+}
+
+func (h *CachesHandler) sendError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	}); err != nil {
+		h.logger.Error("Failed to encode caches error response", zap.Error(err))
+	}
+}
@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/data/settings"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+)
+
+// Optional features reported by /v1/capabilities
+const (
+	FeatureAsyncJobs      = "async_jobs"
+	FeatureGatewayTools   = "gateway_tools"
+	FeatureContextCaching = "context_caching"
+)
+
+// capabilityEndpoint is an endpoint advertised to clients. Feature gates the
+// endpoint on an optional feature; empty means always available.
+type capabilityEndpoint struct {
+	Method  string
+	Path    string
+	Feature string
+}
+
+var capabilityEndpoints = []capabilityEndpoint{
+	{Method: http.MethodPost, Path: "/v1/chat/completions"},
+	{Method: http.MethodPost, Path: "/v1/chat/completions/compare"},
+	{Method: http.MethodPost, Path: "/v1/messages"},
+	{Method: http.MethodPost, Path: "/v1/embeddings"},
+	{Method: http.MethodPost, Path: "/v1/rerank"},
+	{Method: http.MethodGet, Path: "/v1/models"},
+	{Method: http.MethodGet, Path: "/v1/models/catalog"},
+	{Method: http.MethodPost, Path: "/v1/images/generations"},
+	{Method: http.MethodPost, Path: "/v1/audio/transcriptions"},
+	{Method: http.MethodPost, Path: "/v1/audio/translations"},
+	{Method: http.MethodPost, Path: "/v1/audio/speech"},
+	{Method: http.MethodPost, Path: "/v1/moderations"},
+	{Method: http.MethodGet, Path: "/v1/jobs/{id}", Feature: FeatureAsyncJobs},
+	{Method: http.MethodPost, Path: "/v1/caches", Feature: FeatureContextCaching},
+	{Method: http.MethodGet, Path: "/v1/caches", Feature: FeatureContextCaching},
+	{Method: http.MethodGet, Path: "/v1/caches/{id}", Feature: FeatureContextCaching},
+	{Method: http.MethodPatch, Path: "/v1/caches/{id}", Feature: FeatureContextCaching},
+	{Method: http.MethodDelete, Path: "/v1/caches/{id}", Feature: FeatureContextCaching},
+	{Method: http.MethodGet, Path: "/v1/realtime"},
+}
+
+// CapabilitiesResponse describes what a pllm deployment offers to the caller
+type CapabilitiesResponse struct {
+	Object    string               `json:"object"`
+	Status    string               `json:"status"` // "ok", or "degraded" when a model has no healthy instance
+	Endpoints []EndpointCapability `json:"endpoints"`
+	Features  map[string]bool      `json:"features"`
+	Models    []ModelCapability    `json:"models"`
+	Tiers     []TierCapability     `json:"tiers,omitempty"`
+	Limits    CallerLimits         `json:"limits"`
+}
+
+// EndpointCapability is an endpoint and whether the caller may use it
+type EndpointCapability struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Scope     string `json:"scope,omitempty"`
+	Available bool   `json:"available"`
+}
+
+// ModelCapability summarizes what a model supports
+type ModelCapability struct {
+	ID                string `json:"id"`
+	Healthy           bool   `json:"healthy"` // At least one instance is healthy
+	Mode              string `json:"mode,omitempty"`
+	MaxTokens         int    `json:"max_tokens,omitempty"` // Context window
+	MaxInputTokens    int    `json:"max_input_tokens,omitempty"`
+	MaxOutputTokens   int    `json:"max_output_tokens,omitempty"`
+	SupportsStreaming bool   `json:"supports_streaming"`
+	SupportsVision    bool   `json:"supports_vision"`
+	SupportsTools     bool   `json:"supports_tools"`
+	SupportsReasoning bool   `json:"supports_reasoning"`
+}
+
+// TierCapability is a latency tier, requested as "tier:<name>", with the
+// model it currently resolves to
+type TierCapability struct {
+	Name        string   `json:"name"`
+	Model       string   `json:"model"` // e.g. "tier:fast"
+	Description string   `json:"description,omitempty"`
+	Models      []string `json:"models"`
+	Current     string   `json:"current,omitempty"` // Best-ranked model right now; empty if none is healthy
+}
+
+// CallerLimits are the limits that apply to the calling key
+type CallerLimits struct {
+	RequestsPerMinute int      `json:"requests_per_minute,omitempty"`
+	TokensPerMinute   int      `json:"tokens_per_minute,omitempty"`
+	MaxParallelCalls  int      `json:"max_parallel_calls,omitempty"`
+	MaxCostPerRequest *float64 `json:"max_cost_per_request,omitempty"`
+	MaxCompareModels  int      `json:"max_compare_models"`
+}
+
+type CapabilitiesHandler struct {
+	logger         *zap.Logger
+	config         *config.Config
+	modelManager   *llmModels.ModelManager
+	pricingManager *config.ModelPricingManager
+	features       map[string]bool
+	rateLimits     middleware.RateLimitSource        // Optional, rate limits changed at runtime
+	modelDisabled  func(model string) (string, bool) // Optional, the emergency model kill switch
+}
+
+func NewCapabilitiesHandler(logger *zap.Logger, cfg *config.Config, modelManager *llmModels.ModelManager, pricingManager *config.ModelPricingManager) *CapabilitiesHandler {
+	return &CapabilitiesHandler{
+		logger:         logger,
+		config:         cfg,
+		modelManager:   modelManager,
+		pricingManager: pricingManager,
+		features:       make(map[string]bool),
+	}
+}
+
+// SetFeature records whether an optional feature is enabled in this deployment
+func (h *CapabilitiesHandler) SetFeature(name string, enabled bool) {
+	h.features[name] = enabled
+}
+
+// SetRateLimits reports rate limits changed at runtime instead of the
+// configured ones
+func (h *CapabilitiesHandler) SetRateLimits(source middleware.RateLimitSource) {
+	h.rateLimits = source
+}
+
+// SetModelKillSwitch reports models switched off by the emergency controls,
+// with the reason, as not allowed in the model details
+func (h *CapabilitiesHandler) SetModelKillSwitch(disabled func(model string) (string, bool)) {
+	h.modelDisabled = disabled
+}
+
+// GetCapabilities describes the endpoints, features and limits available to the caller
+// @Summary Capability discovery
+// @Description Returns the endpoints, features, model limits and rate limits available to the calling key so SDKs can adapt to the deployment
+// @Tags Models
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Success 200 {object} CapabilitiesResponse
+// @Failure 401 {object} providers.ErrorResponse
+// @Router /capabilities [get]
+func (h *CapabilitiesHandler) GetCapabilities(w http.ResponseWriter, r *http.Request) {
+	key, _ := middleware.GetKey(r.Context())
+
+	response := CapabilitiesResponse{
+		Object:    "capabilities",
+		Endpoints: h.endpoints(key),
+		Models:    h.models(key),
+		Tiers:     h.tiers(r.Context(), key),
+		Limits:    h.limits(key),
+	}
+	response.Features = h.featureSummary(response.Models)
+
+	response.Status = "ok"
+	for _, model := range response.Models {
+		if !model.Healthy {
+			response.Status = "degraded"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode capabilities response", zap.Error(err))
+	}
+}
+
+func (h *CapabilitiesHandler) endpoints(key *models.Key) []EndpointCapability {
+	endpoints := make([]EndpointCapability, 0, len(capabilityEndpoints))
+	for _, endpoint := range capabilityEndpoints {
+		scope := middleware.RequiredScope(endpoint.Path)
+		available := endpoint.Feature == "" || h.features[endpoint.Feature]
+		if key != nil && scope != "" && !key.HasScope(scope) {
+			available = false
+		}
+		endpoints = append(endpoints, EndpointCapability{
+			Method:    endpoint.Method,
+			Path:      endpoint.Path,
+			Scope:     scope,
+			Available: available,
+		})
+	}
+	return endpoints
+}
+
+// tiers lists the latency tiers the caller may use
+func (h *CapabilitiesHandler) tiers(ctx context.Context, key *models.Key) []TierCapability {
+	var result []TierCapability
+	for name, tier := range h.modelManager.GetTiers() {
+		model := llmModels.TierPrefix + name
+		if key != nil && !key.IsModelAllowed(model) {
+			continue
+		}
+		capability := TierCapability{
+			Name:        name,
+			Model:       model,
+			Description: tier.Description,
+			Models:      tier.Models,
+		}
+		if ranked := h.modelManager.RankTier(ctx, tier); len(ranked) > 0 {
+			capability.Current = ranked[0].ModelName
+		}
+		result = append(result, capability)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// models lists the models the caller may use, with their capabilities from
+// the instance configuration, completed by the pricing catalog
+func (h *CapabilitiesHandler) models(key *models.Key) []ModelCapability {
+	infos := h.modelManager.GetDetailedModelInfo()
+
+	result := make([]ModelCapability, 0, len(infos))
+	for _, info := range infos {
+		if key != nil && !key.IsModelAllowed(info.ID) {
+			continue
+		}
+		result = append(result, h.modelCapability(info.ID))
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// modelCapability returns what a model supports, from its instance
+// configuration completed by the pricing catalog
+func (h *CapabilitiesHandler) modelCapability(model string) ModelCapability {
+	capability := ModelCapability{ID: model, SupportsStreaming: true}
+	if instances, ok := h.modelManager.GetRegistry().GetModelInstances(model); ok && len(instances) > 0 {
+		for _, instance := range instances {
+			capability.Healthy = capability.Healthy || instance.Healthy.Load()
+		}
+		modelInfo := instances[0].Config.ModelInfo
+		capability.Mode = modelInfo.Mode
+		capability.MaxTokens = modelInfo.MaxTokens
+		capability.MaxInputTokens = modelInfo.MaxInputTokens
+		capability.MaxOutputTokens = modelInfo.MaxOutputTokens
+		capability.SupportsVision = modelInfo.SupportsVision
+		capability.SupportsTools = modelInfo.SupportsFunctions
+	}
+	if h.pricingManager != nil {
+		if pricing := h.pricingManager.GetPricing(model); pricing != nil {
+			if capability.Mode == "" {
+				capability.Mode = pricing.Mode
+			}
+			if capability.MaxTokens == 0 {
+				capability.MaxTokens = pricing.MaxTokens
+			}
+			if capability.MaxInputTokens == 0 {
+				capability.MaxInputTokens = pricing.MaxInputTokens
+			}
+			if capability.MaxOutputTokens == 0 {
+				capability.MaxOutputTokens = pricing.MaxOutputTokens
+			}
+			capability.SupportsVision = capability.SupportsVision || pricing.SupportsVision
+			capability.SupportsTools = capability.SupportsTools || pricing.SupportsFunctionCalling
+			capability.SupportsReasoning = pricing.SupportsReasoning
+		}
+	}
+	return capability
+}
+
+func (h *CapabilitiesHandler) limits(key *models.Key) CallerLimits {
+	return h.scopedLimits(key, settings.ScopeChatCompletions)
+}
+
+// scopedLimits returns the limits of the calling key on the endpoints of a
+// rate limit scope
+func (h *CapabilitiesHandler) scopedLimits(key *models.Key, scope string) CallerLimits {
+	limits := CallerLimits{MaxCompareModels: maxCompareModels}
+	rateLimits := settings.RateLimitsFromConfig(h.config.RateLimit)
+	if h.rateLimits != nil {
+		rateLimits = h.rateLimits.RateLimits()
+	}
+	if rateLimits.Enabled {
+		limit := rateLimits.Limit(scope)
+		limits.RequestsPerMinute, limits.TokensPerMinute = limit.RPM, limit.TPM
+	}
+	if key != nil {
+		limits.TokensPerMinute, limits.RequestsPerMinute, limits.MaxParallelCalls =
+			key.GetEffectiveRateLimits(limits.TokensPerMinute, limits.RequestsPerMinute, 0)
+		limits.MaxCostPerRequest = key.MaxCostPerRequest
+	}
+	return limits
+}
+
+func (h *CapabilitiesHandler) featureSummary(modelCapabilities []ModelCapability) map[string]bool {
+	features := map[string]bool{
+		"streaming":  true,
+		"vision":     false,
+		"tools":      false,
+		"reasoning":  false,
+		"realtime":   true,
+		"compare":    true,
+		"guardrails": h.config.Guardrails.Enabled,
+	}
+	for _, capability := range modelCapabilities {
+		features["vision"] = features["vision"] || capability.SupportsVision
+		features["tools"] = features["tools"] || capability.SupportsTools
+		features["reasoning"] = features["reasoning"] || capability.SupportsReasoning
+	}
+	for name, enabled := range h.features {
+		features[name] = enabled
+	}
+	return features
+}
+
+// ModelDetails is a model as returned by /v1/models/{model}: the OpenAI
+// model object with its capabilities and the calling key's entitlement to it
+type ModelDetails struct {
+	ID           string          `json:"id"`
+	Object       string          `json:"object"`
+	Created      int64           `json:"created"`
+	OwnedBy      string          `json:"owned_by"`
+	Source       string          `json:"source,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	Capabilities ModelCapability `json:"capabilities"`
+	Permission   ModelPermission `json:"permission"`
+
+	Documentation *ModelDocumentation `json:"documentation,omitempty"`
+}
+
+// ModelDocumentation tells users what a model is for and whom to ask about
+// it, from the model_info of its instances
+type ModelDocumentation struct {
+	Description string             `json:"description,omitempty"`
+	IntendedUse string             `json:"intended_use,omitempty"`
+	Owner       string             `json:"owner,omitempty"`
+	Contacts    []string           `json:"contacts,omitempty"`
+	Links       []config.ModelLink `json:"links,omitempty"`
+}
+
+// ModelPermission is what the calling key may do with a model: whether it
+// may call it, the limits that apply and what it pays
+type ModelPermission struct {
+	Allowed bool           `json:"allowed"`
+	Reason  string         `json:"reason,omitempty"` // Why the model is not allowed
+	Scope   string         `json:"scope,omitempty"`  // Key scope the model's endpoint needs
+	Limits  CallerLimits   `json:"limits"`
+	Pricing *CallerPricing `json:"pricing,omitempty"`
+}
+
+// CallerPricing is the price the caller pays for a model: the provider price
+// with the billing markup. Rates the model is not billed by are left out.
+type CallerPricing struct {
+	InputCostPerToken       float64 `json:"input_cost_per_token"`
+	OutputCostPerToken      float64 `json:"output_cost_per_token"`
+	CachedInputCostPerToken float64 `json:"cached_input_cost_per_token,omitempty"`
+	InputCostPerSecond      float64 `json:"input_cost_per_second,omitempty"`
+	InputCostPerCharacter   float64 `json:"input_cost_per_character,omitempty"`
+	InputCostPerQuery       float64 `json:"input_cost_per_query,omitempty"`
+	OutputCostPerImage      float64 `json:"output_cost_per_image,omitempty"`
+	MarkupPercent           float64 `json:"markup_percent,omitempty"`
+}
+
+// modelEndpoint is the endpoint of a model by its mode, and the rate limit
+// scope of the endpoint
+func modelEndpoint(mode string) (path, scope string) {
+	switch mode {
+	case "embedding":
+		return "/v1/embeddings", settings.ScopeEmbeddings
+	case "rerank":
+		return "/v1/rerank", settings.ScopeEmbeddings
+	case "completion":
+		return "/v1/completions", settings.ScopeCompletions
+	case "image_generation":
+		return "/v1/images/generations", settings.ScopeChatCompletions
+	case "audio_transcription":
+		return "/v1/audio/transcriptions", settings.ScopeChatCompletions
+	case "audio_speech":
+		return "/v1/audio/speech", settings.ScopeChatCompletions
+	}
+	return "/v1/chat/completions", settings.ScopeChatCompletions
+}
+
+// modelDetails returns the details of a model for the calling key; ok is
+// false when the deployment does not serve the model
+func (h *CapabilitiesHandler) modelDetails(key *models.Key, model string) (ModelDetails, bool) {
+	for _, info := range h.modelManager.GetDetailedModelInfo() {
+		if info.ID == model {
+			return h.describeModel(key, info), true
+		}
+	}
+	return ModelDetails{}, false
+}
+
+// catalog returns the details of every model, sorted by ID
+func (h *CapabilitiesHandler) catalog(key *models.Key) []ModelDetails {
+	infos := h.modelManager.GetDetailedModelInfo()
+	catalog := make([]ModelDetails, 0, len(infos))
+	for _, info := range infos {
+		catalog = append(catalog, h.describeModel(key, info))
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].ID < catalog[j].ID })
+	return catalog
+}
+
+// modelDocumentation merges the documentation of a model's instances: each
+// field comes from the first instance that sets it
+func (h *CapabilitiesHandler) modelDocumentation(model string) *ModelDocumentation {
+	instances, _ := h.modelManager.GetRegistry().GetModelInstances(model)
+	var doc ModelDocumentation
+	for _, instance := range instances {
+		info := instance.Config.ModelInfo
+		if doc.Description == "" {
+			doc.Description = info.Description
+		}
+		if doc.IntendedUse == "" {
+			doc.IntendedUse = info.IntendedUse
+		}
+		if doc.Owner == "" {
+			doc.Owner = info.Owner
+		}
+		if len(doc.Contacts) == 0 {
+			doc.Contacts = info.Contacts
+		}
+		if len(doc.Links) == 0 {
+			doc.Links = info.Links
+		}
+	}
+	if doc.Description == "" && doc.IntendedUse == "" && doc.Owner == "" && len(doc.Contacts) == 0 && len(doc.Links) == 0 {
+		return nil
+	}
+	return &doc
+}
+
+func (h *CapabilitiesHandler) describeModel(key *models.Key, info llmModels.ModelInfo) ModelDetails {
+	details := ModelDetails{
+		ID:            info.ID,
+		Object:        info.Object,
+		Created:       info.Created,
+		OwnedBy:       info.OwnedBy,
+		Source:        info.Source,
+		Tags:          h.modelManager.GetModelTags(info.ID),
+		Capabilities:  h.modelCapability(info.ID),
+		Documentation: h.modelDocumentation(info.ID),
+	}
+
+	path, scope := modelEndpoint(details.Capabilities.Mode)
+	permission := ModelPermission{
+		Allowed: true,
+		Scope:   middleware.RequiredScope(path),
+		Limits:  h.scopedLimits(key, scope),
+	}
+	switch {
+	case key != nil && !key.IsModelAllowed(info.ID):
+		permission.Allowed, permission.Reason = false, "The model is not allowed for this key"
+	case key != nil && permission.Scope != "" && !key.HasScope(permission.Scope):
+		permission.Allowed, permission.Reason = false, "The key lacks the "+permission.Scope+" scope"
+	case h.modelDisabled != nil:
+		if reason, disabled := h.modelDisabled(info.ID); disabled {
+			permission.Allowed, permission.Reason = false, "The model is disabled: "+reason
+		}
+	}
+	if h.pricingManager != nil {
+		if pricing := h.pricingManager.GetPricing(info.ID); pricing != nil {
+			markup := h.config.Billing.MarkupPercent
+			scale := 1 + markup/100
+			permission.Pricing = &CallerPricing{
+				InputCostPerToken:       pricing.InputCostPerToken * scale,
+				OutputCostPerToken:      pricing.OutputCostPerToken * scale,
+				CachedInputCostPerToken: pricing.CacheReadInputTokenCost * scale,
+				InputCostPerSecond:      pricing.InputCostPerSecond * scale,
+				InputCostPerCharacter:   pricing.InputCostPerCharacter * scale,
+				InputCostPerQuery:       pricing.InputCostPerQuery * scale,
+				OutputCostPerImage:      pricing.OutputCostPerImage * scale,
+				MarkupPercent:           markup,
+			}
+		}
+	}
+	details.Permission = permission
+	return details
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+)
+
+func TestCapabilities_EndpointsFollowScopesAndFeatures(t *testing.T) {
+	handler := NewCapabilitiesHandler(zap.NewNop(), &config.Config{}, nil, nil)
+	handler.SetFeature(FeatureAsyncJobs, false)
+
+	key := &models.Key{Scopes: pq.StringArray{models.ScopeEmbeddings}}
+	available := map[string]bool{}
+	for _, endpoint := range handler.endpoints(key) {
+		available[endpoint.Path] = endpoint.Available
+	}
+
+	assert.True(t, available["/v1/embeddings"])
+	assert.True(t, available["/v1/models"], "unscoped endpoints stay available")
+	assert.False(t, available["/v1/chat/completions"])
+	assert.False(t, available["/v1/jobs/{id}"], "disabled features are unavailable")
+}
+
+func TestCapabilities_LimitsPreferKeyOverrides(t *testing.T) {
+	cfg := &config.Config{RateLimit: config.RateLimitConfig{Enabled: true, GlobalRPM: 120}}
+	handler := NewCapabilitiesHandler(zap.NewNop(), cfg, nil, nil)
+
+	assert.Equal(t, 120, handler.limits(nil).RequestsPerMinute)
+
+	rpm, tpm, maxCost := 30, 50000, 0.25
+	key := &models.Key{RPM: &rpm, TPM: &tpm, MaxCostPerRequest: &maxCost}
+	limits := handler.limits(key)
+	assert.Equal(t, 30, limits.RequestsPerMinute)
+	assert.Equal(t, 50000, limits.TokensPerMinute)
+	assert.Equal(t, 0.25, *limits.MaxCostPerRequest)
+	assert.Equal(t, maxCompareModels, limits.MaxCompareModels)
+}
+
+func TestModelsHandler_GetModelPermission(t *testing.T) {
+	modelManager := createMockModelManager()
+	for _, model := range []string{"details-chat", "details-embed"} {
+		instance := config.ModelInstance{
+			ID:        model + "-instance",
+			ModelName: model,
+			Enabled:   true,
+			Provider:  config.ProviderParams{Type: "openai", Model: model, APIKey: "test-key"},
+		}
+		if model == "details-embed" {
+			instance.ModelInfo.Mode = "embedding"
+		}
+		require.NoError(t, modelManager.AddInstance(instance))
+	}
+	pricing := config.GetPricingManager()
+	pricing.RegisterModel("details-chat", &config.ModelPricingInfo{InputCostPerToken: 0.001, OutputCostPerToken: 0.002})
+
+	cfg := &config.Config{
+		RateLimit: config.RateLimitConfig{Enabled: true, GlobalRPM: 120},
+		Billing:   config.BillingConfig{MarkupPercent: 10},
+	}
+	capabilities := NewCapabilitiesHandler(zap.NewNop(), cfg, modelManager, pricing)
+	capabilities.SetModelKillSwitch(func(model string) (string, bool) {
+		return "incident", model == "details-embed"
+	})
+	handler := NewModelsHandler(zap.NewNop(), modelManager, pricing)
+	handler.SetCapabilities(capabilities)
+	router := chi.NewRouter()
+	router.Get("/v1/models/{model}", handler.GetModel)
+
+	get := func(model string, key *models.Key) (int, ModelDetails) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models/"+model, nil)
+		if key != nil {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.KeyContextKey, key))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var details ModelDetails
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&details))
+		}
+		return rec.Code, details
+	}
+
+	rpm := 30
+	code, details := get("details-chat", &models.Key{RPM: &rpm})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "model", details.Object)
+	assert.Equal(t, "openai", details.OwnedBy)
+	assert.True(t, details.Permission.Allowed)
+	assert.Equal(t, models.ScopeChat, details.Permission.Scope)
+	assert.Equal(t, 30, details.Permission.Limits.RequestsPerMinute)
+	require.NotNil(t, details.Permission.Pricing)
+	assert.InDelta(t, 0.0011, details.Permission.Pricing.InputCostPerToken, 1e-12)
+	assert.InDelta(t, 0.0022, details.Permission.Pricing.OutputCostPerToken, 1e-12)
+	assert.Equal(t, 10.0, details.Permission.Pricing.MarkupPercent)
+
+	_, details = get("details-chat", &models.Key{BlockedModels: pq.StringArray{"details-chat"}})
+	assert.False(t, details.Permission.Allowed)
+	assert.Contains(t, details.Permission.Reason, "not allowed")
+
+	_, details = get("details-chat", &models.Key{Scopes: pq.StringArray{models.ScopeEmbeddings}})
+	assert.False(t, details.Permission.Allowed)
+	assert.Contains(t, details.Permission.Reason, models.ScopeChat)
+
+	_, details = get("details-embed", nil)
+	assert.Equal(t, "embedding", details.Capabilities.Mode)
+	assert.Equal(t, models.ScopeEmbeddings, details.Permission.Scope)
+	assert.False(t, details.Permission.Allowed)
+	assert.Contains(t, details.Permission.Reason, "incident")
+
+	code, _ = get("missing-model", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestModelsHandler_Catalog(t *testing.T) {
+	modelManager := createMockModelManager()
+	for i, model := range []string{"catalog-reasoner", "catalog-reasoner", "catalog-cheap"} {
+		instance := config.ModelInstance{
+			ID:        fmt.Sprintf("%s-%d", model, i),
+			ModelName: model,
+			Enabled:   true,
+			Provider:  config.ProviderParams{Type: "openai", Model: model, APIKey: "test-key"},
+		}
+		switch i {
+		case 0:
+			instance.ModelInfo.Description = "Slow, careful reasoning"
+			instance.ModelInfo.Owner = "ml-platform"
+		case 1:
+			instance.ModelInfo.Description = "Ignored: the first instance describes the model"
+			instance.ModelInfo.IntendedUse = "Planning and code review"
+			instance.ModelInfo.Contacts = []string{"ml-platform@example.com"}
+			instance.ModelInfo.Links = []config.ModelLink{{Title: "Evaluation", URL: "https://wiki.example.com/reasoner"}}
+		}
+		require.NoError(t, modelManager.AddInstance(instance))
+	}
+
+	handler := NewModelsHandler(zap.NewNop(), modelManager, nil)
+	handler.SetCapabilities(NewCapabilitiesHandler(zap.NewNop(), &config.Config{}, modelManager, nil))
+	router := chi.NewRouter()
+	router.Get("/v1/models/catalog", handler.Catalog)
+
+	list := func(query string) []ModelDetails {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models/catalog"+query, nil)
+		key := &models.Key{BlockedModels: pq.StringArray{"catalog-cheap"}}
+		req = req.WithContext(context.WithValue(req.Context(), middleware.KeyContextKey, key))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body struct {
+			Data []ModelDetails `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		return body.Data
+	}
+
+	catalog := list("")
+	var ids []string
+	for _, model := range catalog {
+		ids = append(ids, model.ID)
+	}
+	assert.Subset(t, ids, []string{"catalog-cheap", "catalog-reasoner"})
+	assert.IsIncreasing(t, ids)
+
+	for _, model := range catalog {
+		switch model.ID {
+		case "catalog-reasoner":
+			assert.Equal(t, &ModelDocumentation{
+				Description: "Slow, careful reasoning",
+				IntendedUse: "Planning and code review",
+				Owner:       "ml-platform",
+				Contacts:    []string{"ml-platform@example.com"},
+				Links:       []config.ModelLink{{Title: "Evaluation", URL: "https://wiki.example.com/reasoner"}},
+			}, model.Documentation)
+		case "catalog-cheap":
+			assert.Nil(t, model.Documentation)
+			assert.False(t, model.Permission.Allowed)
+		}
+	}
+
+	for _, model := range list("?allowed=true") {
+		assert.NotEqual(t, "catalog-cheap", model.ID)
+	}
+}
@@ -5,29 +5,51 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
 	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/integrations/tools"
+	"github.com/amerfu/pllm/internal/services/jobs"
+	"github.com/amerfu/pllm/internal/services/llm/contextcache"
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
-	"github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/provenance"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
+	pkglogger "github.com/amerfu/pllm/pkg/logger"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// ResolvedModelHeader carries the model picked for a "tier:<name>" request
+const ResolvedModelHeader = "X-PLLM-Resolved-Model"
+
+// QueueOnOutageHeader opts a request into the outage queue ("true"); the
+// response carries it set to "queued" when the request was queued
+const QueueOnOutageHeader = "X-PLLM-Queue-On-Outage"
+
 type ChatHandler struct {
 	logger         *zap.Logger
-	modelManager   *models.ModelManager
+	modelManager   *llmModels.ModelManager
 	metricsEmitter *metrics.MetricEventEmitter
+	provenance     *provenance.Stamper
+	jobs           *jobs.Service
+	outageQueue    config.OutageQueueConfig
+	tools          *tools.Runtime
+	caches         *contextcache.Service
+	pricing        *config.ModelPricingManager
 }
 
-func NewChatHandler(logger *zap.Logger, modelManager *models.ModelManager) *ChatHandler {
+func NewChatHandler(logger *zap.Logger, modelManager *llmModels.ModelManager) *ChatHandler {
 	return &ChatHandler{
 		logger:       logger,
 		modelManager: modelManager,
 	}
 }
 
-func NewChatHandlerWithMetrics(logger *zap.Logger, modelManager *models.ModelManager, metricsEmitter *metrics.MetricEventEmitter) *ChatHandler {
+func NewChatHandlerWithMetrics(logger *zap.Logger, modelManager *llmModels.ModelManager, metricsEmitter *metrics.MetricEventEmitter) *ChatHandler {
 	return &ChatHandler{
 		logger:         logger,
 		modelManager:   modelManager,
@@ -35,6 +57,41 @@ func NewChatHandlerWithMetrics(logger *zap.Logger, modelManager *models.ModelMan
 	}
 }
 
+// SetProvenance enables provenance metadata on chat responses
+func (h *ChatHandler) SetProvenance(stamper *provenance.Stamper) {
+	h.provenance = stamper
+}
+
+// SetJobs enables async mode (?async=true) backed by the given job service
+func (h *ChatHandler) SetJobs(jobService *jobs.Service) {
+	h.jobs = jobService
+}
+
+// SetOutageQueue lets requests that opt in with the X-PLLM-Queue-On-Outage
+// header be queued as jobs while every provider of their model is down.
+// It needs async mode (SetJobs).
+func (h *ChatHandler) SetOutageQueue(cfg config.OutageQueueConfig) {
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = 30 * time.Minute
+	}
+	h.outageQueue = cfg
+}
+
+// SetTools enables gateway-side execution of registered tools
+func (h *ChatHandler) SetTools(runtime *tools.Runtime) {
+	h.tools = runtime
+}
+
+// SetContextCaches enables cached_content references to /v1/caches
+func (h *ChatHandler) SetContextCaches(cacheService *contextcache.Service) {
+	h.caches = cacheService
+}
+
+// SetPricing sets the pricing manager used to cost comparison results
+func (h *ChatHandler) SetPricing(pricingManager *config.ModelPricingManager) {
+	h.pricing = pricingManager
+}
+
 // ChatCompletions creates a chat completion
 // @Summary Create chat completion
 // @Description Creates a completion for the chat messages
@@ -54,47 +111,50 @@ func NewChatHandlerWithMetrics(logger *zap.Logger, modelManager *models.ModelMan
 func (h *ChatHandler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	var request providers.ChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.logger.Error("Failed to decode request body", zap.Error(err))
+		h.requestLogger(r.Context()).Error("Failed to decode request body", zap.Error(err))
 		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	// Debug logging
-	h.logger.Info("Received chat completion request",
+	h.requestLogger(r.Context()).Info("Received chat completion request",
 		zap.String("model", request.Model),
 		zap.Int("num_messages", len(request.Messages)),
 		zap.Bool("stream", request.Stream))
 	
 	for i, msg := range request.Messages {
-		h.logger.Info("Message content",
+		h.requestLogger(r.Context()).Info("Message content",
 			zap.Int("index", i),
 			zap.String("role", msg.Role),
 			zap.String("content_type", fmt.Sprintf("%T", msg.Content)))
 	}
 
-	// Populate metrics context if available
-	if metricsCtx, ok := r.Context().Value(middleware.MetricsContextKey).(*middleware.MetricsContext); ok && metricsCtx != nil {
-		metricsCtx.ModelName = request.Model
-		// Extract user/team info from auth context if available
-		// These will be populated by the auth middleware in the context
+	cache, ok := h.resolveCache(w, r, &request)
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("async") == "true" {
+		h.submitChatJob(w, r, &request)
+		return
 	}
 
+	// Populate metrics and log context
+	middleware.SetModelName(r.Context(), request.Model)
+
 	// Track request start for adaptive routing
 	h.modelManager.RecordRequestStart(request.Model)
 	startTime := time.Now()
 
 	// Execute with automatic failover
-	result, err := h.modelManager.ExecuteWithFailover(r.Context(), &models.FailoverRequest{
+	result, err := h.modelManager.ExecuteWithFailover(r.Context(), &llmModels.FailoverRequest{
 		ModelName: request.Model,
-		ExecuteFunc: func(ctx context.Context, instance *models.ModelInstance) (interface{}, error) {
-			// Create a copy of the request with the provider's actual model name
-			providerRequest := request
-			providerRequest.Model = instance.Config.Provider.Model
-
-			// Apply model default reasoning_effort if not set by caller
-			if providerRequest.ReasoningEffort == nil && instance.Config.Provider.ReasoningEffort != "" {
-				effort := instance.Config.Provider.ReasoningEffort
-				providerRequest.ReasoningEffort = &effort
+		ExecuteFunc: func(ctx context.Context, instance *llmModels.ModelInstance) (interface{}, error) {
+			providerRequest := providerChatRequest(&request, instance)
+			if cache != nil {
+				if err := contextcache.Apply(&providerRequest, cache, instance); err != nil {
+					return nil, err
+				}
 			}
 
 			// Handle streaming separately
@@ -108,7 +168,7 @@ func (h *ChatHandler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Forward request to provider (non-streaming)
-			response, err := instance.Provider.ChatCompletion(ctx, &providerRequest)
+			response, err := h.completeChat(ctx, instance, &providerRequest)
 			if err != nil {
 				instance.RecordError(err)
 				return nil, err
@@ -126,16 +186,19 @@ func (h *ChatHandler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// All failover attempts failed
 		h.modelManager.RecordRequestEnd(request.Model, time.Since(startTime), false, err)
-		h.logger.Error("Request failed after all failover attempts",
+		h.requestLogger(r.Context()).Error("Request failed after all failover attempts",
 			zap.String("model", request.Model),
 			zap.Error(err))
+		if h.queueOnOutage(w, r, &request, err) {
+			return
+		}
 		h.sendError(w, http.StatusServiceUnavailable, "Request failed: "+err.Error())
 		return
 	}
 
 	// Log failover information if any failovers occurred
 	if len(result.Failovers) > 0 {
-		h.logger.Info("Request succeeded after failover",
+		h.requestLogger(r.Context()).Info("Request succeeded after failover",
 			zap.String("requested_model", request.Model),
 			zap.String("final_instance", result.Instance.Config.ID),
 			zap.Int("attempts", result.AttemptCount),
@@ -154,16 +217,21 @@ func (h *ChatHandler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 			result.Instance.Config.Provider.Type,
 			routeSlug,
 		)
+
+		// Tier requests report the model the gateway picked
+		if _, isTier := h.modelManager.ResolveTier(request.Model); isTier {
+			w.Header().Set(ResolvedModelHeader, result.Instance.Config.ModelName)
+		}
 	}
 
 	// Check if this is a streaming request
 	if responseMap, ok := result.Response.(map[string]interface{}); ok {
 		if isStreaming, exists := responseMap["__streaming__"]; exists && isStreaming == true {
 			// Extract instance and request from response
-			instance := responseMap["instance"].(*models.ModelInstance)
+			instance := responseMap["instance"].(*llmModels.ModelInstance)
 			providerRequest := responseMap["request"].(*providers.ChatRequest)
 			
-			h.logger.Info("Routing to streaming handler after failover",
+			h.requestLogger(r.Context()).Info("Routing to streaming handler after failover",
 				zap.String("requested_model", request.Model),
 				zap.String("instance_id", instance.Config.ID),
 				zap.Int("failover_attempts", result.AttemptCount))
@@ -176,10 +244,18 @@ func (h *ChatHandler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	// Non-streaming response
 	response := result.Response.(*providers.ChatResponse)
 	latency := time.Since(startTime)
+	if _, isTier := h.modelManager.ResolveTier(request.Model); isTier && result.Instance != nil {
+		response.Model = result.Instance.Config.ModelName
+	}
 
 	// Record success for adaptive components
 	h.modelManager.RecordRequestEnd(request.Model, latency, true, nil)
 
+	// Actual usage for the usage record, including hidden reasoning tokens
+	middleware.SetTokenUsage(r.Context(), response.Usage.PromptTokens,
+		response.Usage.CompletionTokens, response.Usage.ReasoningTokens())
+	middleware.SetCachedTokens(r.Context(), response.Usage.CachedTokens())
+
 	// Emit detailed metrics if metrics emitter is available
 	if h.metricsEmitter != nil && middleware.GetMetricsContext(r.Context()) != nil {
 		// Calculate cost (simple estimation - could be moved to a proper cost calculator)
@@ -190,19 +266,256 @@ func (h *ChatHandler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 			int64(response.Usage.CompletionTokens), estimatedCost, false)
 	}
 
+	// Post-call guardrails may block or rewrite the response, so they run
+	// before the provenance hash is taken
+	if err := middleware.ScreenResponse(r.Context(), response); err != nil {
+		middleware.WriteGuardrailError(w, err)
+		return
+	}
+
+	// Attach provenance metadata and remember the hash for later verification
+	if h.provenance.IsEnabled() && result.Instance != nil {
+		p := provenance.New(result.Instance.Config.Provider.Model, result.Instance.Config.Provider.Type,
+			middleware.GetRequestID(r.Context()), provenance.ResponseContent(response))
+		h.provenance.Stamp(w, response, p)
+		middleware.SetContentHash(r.Context(), p.ContentHash)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.logger.Error("Failed to encode LLM response", zap.Error(err))
+		h.requestLogger(r.Context()).Error("Failed to encode LLM response", zap.Error(err))
+	}
+}
+
+// providerChatRequest copies the request for the given instance, using the
+// provider's model name and dropping gateway-only fields
+func providerChatRequest(request *providers.ChatRequest, instance *llmModels.ModelInstance) providers.ChatRequest {
+	providerRequest := *request
+	providerRequest.Model = instance.Config.Provider.Model
+	providerRequest.MaxCost = nil
+	providerRequest.CachedContent = ""
+
+	// Apply model default reasoning_effort if not set by caller
+	if providerRequest.ReasoningEffort == nil && instance.Config.Provider.ReasoningEffort != "" {
+		effort := instance.Config.Provider.ReasoningEffort
+		providerRequest.ReasoningEffort = &effort
+	}
+	return providerRequest
+}
+
+// completeChat sends a non-streaming request to the instance. When the request
+// names registered tools, the tool runtime executes the model's calls to them
+// and continues the conversation before the response is returned.
+func (h *ChatHandler) completeChat(ctx context.Context, instance *llmModels.ModelInstance, request *providers.ChatRequest) (*providers.ChatResponse, error) {
+	registered := h.tools.Prepare(ctx, request)
+	provider := providerFor(ctx, h.modelManager, instance)
+	response, err := provider.ChatCompletion(ctx, request)
+	if err != nil || len(registered) == 0 {
+		return response, err
+	}
+	return h.tools.Run(ctx, request, response, registered, provider.ChatCompletion)
+}
+
+// submitChatJob stores the request as an async job and returns its ID
+// immediately; the result is fetched from GET /v1/jobs/{id} or delivered to
+// the URL in the X-PLLM-Webhook-URL header
+func (h *ChatHandler) submitChatJob(w http.ResponseWriter, r *http.Request, request *providers.ChatRequest) {
+	if h.jobs == nil {
+		h.sendError(w, http.StatusBadRequest, "Async mode is not enabled")
+		return
+	}
+	if request.Stream {
+		h.sendError(w, http.StatusBadRequest, "Async mode does not support streaming")
+		return
+	}
+
+	job, ok := h.newChatJob(w, r, request)
+	if !ok {
+		return
+	}
+	if err := h.jobs.Submit(r.Context(), job); err != nil {
+		h.requestLogger(r.Context()).Error("Failed to submit async job", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to submit job")
+		return
+	}
+	h.sendJob(w, r, job)
+}
+
+// queueOnOutage queues a request that failed on every instance and fallback
+// as a job retried until its model's providers recover, or until the outage
+// queue's max_wait passes. Only non-streaming requests carrying the
+// X-PLLM-Queue-On-Outage header are queued, and only while no provider of
+// the model is healthy. It reports whether a response was sent.
+func (h *ChatHandler) queueOnOutage(w http.ResponseWriter, r *http.Request, request *providers.ChatRequest, err error) bool {
+	if h.jobs == nil || !h.outageQueue.Enabled || request.Stream {
+		return false
+	}
+	if optIn, _ := strconv.ParseBool(r.Header.Get(QueueOnOutageHeader)); !optIn {
+		return false
+	}
+	if !h.modelManager.ModelUnavailable(request.Model) {
+		return false
+	}
+
+	job, ok := h.newChatJob(w, r, request)
+	if !ok {
+		return true
+	}
+	job.Error = err.Error()
+	if err := h.jobs.Defer(r.Context(), job, time.Now().Add(h.outageQueue.MaxWait)); err != nil {
+		h.requestLogger(r.Context()).Error("Failed to queue request during outage", zap.Error(err))
+		return false
+	}
+
+	h.requestLogger(r.Context()).Warn("Queued request until its providers recover",
+		zap.String("model", request.Model),
+		zap.String("job_id", job.ID.String()))
+	w.Header().Set(QueueOnOutageHeader, "queued")
+	h.sendJob(w, r, job)
+	return true
+}
+
+// newChatJob builds the job of a chat request, with the webhook URL from the
+// X-PLLM-Webhook-URL header and the caller's identity. It sends the error
+// response and returns false when the request cannot become a job.
+func (h *ChatHandler) newChatJob(w http.ResponseWriter, r *http.Request, request *providers.ChatRequest) (*models.Job, bool) {
+	webhookURL := r.Header.Get("X-PLLM-Webhook-URL")
+	if webhookURL != "" {
+		if err := h.jobs.ValidateWebhookURL(r.Context(), webhookURL); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid webhook URL: "+err.Error())
+			return nil, false
+		}
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+
+	job := &models.Job{
+		Endpoint:   "chat.completions",
+		Model:      request.Model,
+		Request:    payload,
+		WebhookURL: webhookURL,
+	}
+	if userID, ok := middleware.GetUserID(r.Context()); ok {
+		job.UserID = &userID
+	}
+	if key, ok := middleware.GetKey(r.Context()); ok && key != nil {
+		job.KeyID = &key.ID
+		job.TeamID = key.TeamID
+	}
+	return job, true
+}
+
+// sendJob answers 202 with the job and its location
+func (h *ChatHandler) sendJob(w http.ResponseWriter, r *http.Request, job *models.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/v1/jobs/"+job.ID.String())
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(jobs.NewView(job)); err != nil {
+		h.requestLogger(r.Context()).Error("Failed to encode job response", zap.Error(err))
+	}
+}
+
+// ExecuteJob runs an async chat completion job through the routing layer
+func (h *ChatHandler) ExecuteJob(ctx context.Context, job *models.Job) (interface{}, error) {
+	var request providers.ChatRequest
+	if err := json.Unmarshal(job.Request, &request); err != nil {
+		return nil, fmt.Errorf("invalid job request: %w", err)
+	}
+	cache, err := h.jobCache(ctx, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	h.modelManager.RecordRequestStart(request.Model)
+	startTime := time.Now()
+
+	result, err := h.modelManager.ExecuteWithFailover(ctx, &llmModels.FailoverRequest{
+		ModelName: request.Model,
+		ExecuteFunc: func(ctx context.Context, instance *llmModels.ModelInstance) (interface{}, error) {
+			providerRequest := providerChatRequest(&request, instance)
+			if cache != nil {
+				if err := contextcache.Apply(&providerRequest, cache, instance); err != nil {
+					return nil, err
+				}
+			}
+			response, err := h.completeChat(ctx, instance, &providerRequest)
+			if err != nil {
+				instance.RecordError(err)
+				return nil, err
+			}
+			instance.RecordRequest(int32(response.Usage.TotalTokens), time.Since(startTime).Milliseconds())
+			return response, nil
+		},
+	})
+	if err != nil {
+		h.modelManager.RecordRequestEnd(request.Model, time.Since(startTime), false, err)
+		// Jobs queued during an outage wait for the providers to recover
+		if job.RetryUntil != nil && h.modelManager.ModelUnavailable(request.Model) {
+			return nil, jobs.RetryLater(err)
+		}
+		return nil, err
 	}
+	h.modelManager.RecordRequestEnd(request.Model, time.Since(startTime), true, nil)
+
+	return result.Response, nil
 }
 
-func (h *ChatHandler) handleStreamingChat(w http.ResponseWriter, r *http.Request, request *providers.ChatRequest, instance *models.ModelInstance, startTime time.Time) {
+// resolveCache loads the context cache named by cached_content, writing an
+// error response when it cannot be used. It returns nil without a reference.
+func (h *ChatHandler) resolveCache(w http.ResponseWriter, r *http.Request, request *providers.ChatRequest) (*models.ContextCache, bool) {
+	if request.CachedContent == "" {
+		return nil, true
+	}
+	if h.caches == nil {
+		h.sendError(w, http.StatusBadRequest, "Context caching is not enabled")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(request.CachedContent)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Cached content not found: "+request.CachedContent)
+		return nil, false
+	}
+	cache, err := h.caches.Get(r.Context(), id)
+	if err != nil || !isOwner(r, cache.KeyID, cache.UserID) {
+		h.sendError(w, http.StatusBadRequest, "Cached content not found: "+request.CachedContent)
+		return nil, false
+	}
+	if cache.Model != request.Model {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("Cached content was created for model %s", cache.Model))
+		return nil, false
+	}
+	return cache, true
+}
+
+// jobCache reloads the context cache of an async job. Access was checked when
+// the job was submitted, but the cache may have expired since.
+func (h *ChatHandler) jobCache(ctx context.Context, request *providers.ChatRequest) (*models.ContextCache, error) {
+	if request.CachedContent == "" || h.caches == nil {
+		return nil, nil
+	}
+	id, err := uuid.Parse(request.CachedContent)
+	if err != nil {
+		return nil, fmt.Errorf("cached content not found: %s", request.CachedContent)
+	}
+	cache, err := h.caches.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("cached content %s: %w", request.CachedContent, err)
+	}
+	return cache, nil
+}
+
+func (h *ChatHandler) handleStreamingChat(w http.ResponseWriter, r *http.Request, request *providers.ChatRequest, instance *llmModels.ModelInstance, startTime time.Time) {
 	// Note: MetricsContext.ModelName is already set in ChatCompletions with the original request model.
 	// Do NOT overwrite it here — request.Model is the provider model name at this point.
 
 	// Debug: write type to header
 	w.Header().Set("X-Debug-Writer-Type", fmt.Sprintf("%T", w))
-	h.logger.Info("Starting streaming request",
+	h.requestLogger(r.Context()).Info("Starting streaming request",
 		zap.String("model", request.Model),
 		zap.String("provider_model", instance.Config.Provider.Model),
 		zap.String("writer_type", fmt.Sprintf("%T", w)))
@@ -213,12 +526,24 @@ func (h *ChatHandler) handleStreamingChat(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no") // Disable Nginx buffering
 
+	// The content hash is unknown until the stream ends, so headers only carry
+	// the model and request metadata; the hash is sent as a final chunk in field mode
+	var streamProvenance *providers.Provenance
+	var streamedContent provenance.StreamContent
+	if h.provenance.IsEnabled() {
+		streamProvenance = provenance.New(instance.Config.Provider.Model, instance.Config.Provider.Type,
+			middleware.GetRequestID(r.Context()), "")
+		if h.provenance.UsesHeaders() {
+			provenance.WriteHeaders(w.Header(), streamProvenance)
+		}
+	}
+
 	// Our middleware ensures w is a StreamingResponseWriter which implements Flusher
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		// Add debug info to error response
 		errMsg := fmt.Sprintf("Streaming not supported - Writer type: %T", w)
-		h.logger.Error(errMsg,
+		h.requestLogger(r.Context()).Error(errMsg,
 			zap.String("writer_type", fmt.Sprintf("%T", w)),
 			zap.String("model", request.Model))
 		// Send error response
@@ -230,12 +555,18 @@ func (h *ChatHandler) handleStreamingChat(w http.ResponseWriter, r *http.Request
 	providerRequest := *request
 	providerRequest.Model = instance.Config.Provider.Model
 
+	// Post-call guardrails screen the output as it streams and can stop the
+	// stream early, which also stops the provider
+	moderator := middleware.GetStreamModerator(r.Context())
+	streamCtx, cancelStream := context.WithCancel(r.Context())
+	defer cancelStream()
+
 	// Get streaming response from provider
-	streamChan, err := instance.Provider.ChatCompletionStream(r.Context(), &providerRequest)
+	streamChan, err := providerFor(r.Context(), h.modelManager, instance).ChatCompletionStream(streamCtx, &providerRequest)
 	if err != nil {
 		instance.RecordError(err)
 		h.modelManager.RecordRequestEnd(request.Model, time.Since(startTime), false, err)
-		h.logger.Error("Failed to start streaming",
+		h.requestLogger(r.Context()).Error("Failed to start streaming",
 			zap.String("model", request.Model),
 			zap.Error(err))
 		// Send error in SSE format
@@ -244,32 +575,60 @@ func (h *ChatHandler) handleStreamingChat(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	h.logger.Info("Starting stream processing loop",
+	h.requestLogger(r.Context()).Info("Starting stream processing loop",
 		zap.String("model", request.Model))
 
 	totalTokens := int64(0)
 	promptTokens := int64(0)
 	completionTokens := int64(0)
+	firstChunk := true
+	var reportedUsage *providers.Usage
+	meter := h.modelManager.StartStreamMeter(instance)
+	checkpointer := middleware.GetStreamCheckpointer(r.Context())
+
+	// Keep-alive comments hold the connection open through long gaps
+	keepAlive := startKeepAlive(w, flusher, meter)
+	defer keepAlive.Stop()
+	w, flusher = keepAlive, keepAlive
 
 	// Stream the response
 	for streamResponse := range streamChan {
-		h.logger.Debug("Received stream chunk", zap.Any("response", streamResponse))
+		h.requestLogger(r.Context()).Debug("Received stream chunk", zap.Any("response", streamResponse))
 
 		// Replace model with user's requested model name
 		streamResponse.Model = request.Model
 
+		if moderator != nil {
+			if err := moderator.Screen(&streamResponse); err != nil {
+				cancelStream()
+				if data, err := json.Marshal(middleware.ContentFilterChunk(&streamResponse)); err == nil {
+					_, _ = fmt.Fprintf(w, "data: %s\n\n", string(data))
+					flusher.Flush()
+				}
+				break
+			}
+		}
+
 		data, err := json.Marshal(streamResponse)
 		if err != nil {
-			h.logger.Error("Failed to marshal stream response", zap.Error(err))
+			h.requestLogger(r.Context()).Error("Failed to marshal stream response", zap.Error(err))
 			continue
 		}
 
 		_, writeErr := fmt.Fprintf(w, "data: %s\n\n", string(data))
 		if writeErr != nil {
-			h.logger.Error("Failed to write stream data", zap.Error(writeErr))
+			h.requestLogger(r.Context()).Error("Failed to write stream data", zap.Error(writeErr))
 			break
 		}
 		flusher.Flush()
+		meter.Chunk()
+
+		if firstChunk {
+			firstChunk = false
+			ttft := time.Since(startTime)
+			h.modelManager.RecordFirstToken(instance, ttft)
+			middleware.SetTimeToFirstToken(r.Context(), ttft)
+		}
 
 		// Track token usage from stream chunks if available
 		if len(streamResponse.Choices) > 0 && streamResponse.Choices[0].Delta.Content != nil {
@@ -277,12 +636,51 @@ func (h *ChatHandler) handleStreamingChat(w http.ResponseWriter, r *http.Request
 			content := fmt.Sprintf("%v", streamResponse.Choices[0].Delta.Content)
 			completionTokens += int64(len(content) / 4)
 		}
+		if streamResponse.Usage != nil {
+			reportedUsage = streamResponse.Usage
+		}
+		if checkpointer != nil {
+			checkpointer.Progress(int(completionTokens))
+		}
+		if streamProvenance != nil {
+			streamedContent.Add(&streamResponse)
+		}
+	}
+
+	if streamProvenance != nil {
+		streamProvenance.ContentHash = provenance.HashContent(streamedContent.Content())
+		middleware.SetContentHash(r.Context(), streamProvenance.ContentHash)
+		if h.provenance.UsesField() {
+			if data, err := json.Marshal(providers.StreamResponse{
+				Object:     "chat.completion.chunk",
+				Created:    streamProvenance.Timestamp,
+				Model:      request.Model,
+				Choices:    []providers.StreamChoice{},
+				Provenance: streamProvenance,
+			}); err == nil {
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", string(data))
+				flusher.Flush()
+			}
+		}
 	}
 
 	// Send final done message
 	_, _ = fmt.Fprintf(w, "data: [DONE]\n\n")
 	flusher.Flush()
 
+	// Usage reported by the provider replaces the estimate
+	if reportedUsage != nil {
+		promptTokens = int64(reportedUsage.PromptTokens)
+		completionTokens = int64(reportedUsage.CompletionTokens)
+		totalTokens = int64(reportedUsage.TotalTokens)
+		middleware.SetTokenUsage(r.Context(), reportedUsage.PromptTokens,
+			reportedUsage.CompletionTokens, reportedUsage.ReasoningTokens())
+		middleware.SetCachedTokens(r.Context(), reportedUsage.CachedTokens())
+	}
+
+	streamStats := meter.Finish(completionTokens)
+	middleware.SetStreamStats(r.Context(), streamStats.TokensPerSecond, streamStats.MaxChunkGap, streamStats.Stalled)
+
 	latency := time.Since(startTime)
 	latencyMs := latency.Milliseconds()
 
@@ -297,7 +695,7 @@ func (h *ChatHandler) handleStreamingChat(w http.ResponseWriter, r *http.Request
 			totalTokens, promptTokens, completionTokens, estimatedCost, true)
 	}
 
-	h.logger.Info("Streaming completed",
+	h.requestLogger(r.Context()).Info("Streaming completed",
 		zap.String("model", request.Model),
 		zap.Int64("completion_tokens", completionTokens),
 		zap.Int64("latency_ms", latencyMs))
@@ -321,6 +719,11 @@ func (h *ChatHandler) Completions(w http.ResponseWriter, r *http.Request) {
 	h.sendError(w, http.StatusNotImplemented, "Completions endpoint not yet implemented")
 }
 
+// requestLogger returns the handler logger decorated with the request's log fields
+func (h *ChatHandler) requestLogger(ctx context.Context) *zap.Logger {
+	return pkglogger.FromContext(ctx, h.logger)
+}
+
 func (h *ChatHandler) sendError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
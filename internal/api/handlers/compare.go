@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// maxCompareModels bounds the fan-out of a single comparison request
+const maxCompareModels = 8
+
+// CompareCompletions sends the same chat request to several models in parallel
+// @Summary Compare chat completions across models
+// @Description Runs the same prompt against each of the given models in parallel and returns every response with its latency and cost
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param request body providers.CompareRequest true "Comparison request"
+// @Success 200 {object} providers.CompareResponse
+// @Failure 400 {object} providers.ErrorResponse
+// @Failure 401 {object} providers.ErrorResponse
+// @Failure 429 {object} providers.ErrorResponse
+// @Failure 503 {object} providers.ErrorResponse
+// @Router /chat/completions/compare [post]
+func (h *ChatHandler) CompareCompletions(w http.ResponseWriter, r *http.Request) {
+	var request providers.CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := validateCompareRequest(&request); err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.logger.Info("Received comparison request",
+		zap.Strings("models", request.Models),
+		zap.Int("num_messages", len(request.Messages)))
+
+	results := make([]providers.CompareResult, len(request.Models))
+	calls := make([]*middleware.ModelCallUsage, len(request.Models))
+
+	var wg sync.WaitGroup
+	for i, model := range request.Models {
+		wg.Add(1)
+		go func(i int, model string) {
+			defer wg.Done()
+			results[i], calls[i] = h.compareModel(r.Context(), request.ChatRequest, model)
+		}(i, model)
+	}
+	wg.Wait()
+
+	response := providers.CompareResponse{
+		Object:  "chat.completion.comparison",
+		Created: time.Now().Unix(),
+		Results: results,
+	}
+
+	succeeded := 0
+	for i, result := range results {
+		if result.Cost != nil {
+			response.TotalCost += *result.Cost
+		}
+		if calls[i] != nil {
+			succeeded++
+			middleware.AddModelCall(r.Context(), *calls[i])
+		}
+	}
+
+	if succeeded == 0 {
+		// When guardrails blocked every response the comparison is rejected
+		// the way a blocked chat completion is
+		if allResultsBlocked(results) {
+			middleware.WriteGuardrailError(w, errors.New(results[0].Error.Message))
+			return
+		}
+		h.sendError(w, http.StatusServiceUnavailable, "All models in the comparison failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode comparison response", zap.Error(err))
+	}
+}
+
+// compareModel runs the request against one model. The returned usage is nil
+// when the call failed.
+func (h *ChatHandler) compareModel(ctx context.Context, request providers.ChatRequest, model string) (providers.CompareResult, *middleware.ModelCallUsage) {
+	request.Model = model
+	h.modelManager.ApplyRouteDefaults(&request)
+	h.modelManager.RecordRequestStart(model)
+	startTime := time.Now()
+
+	result, err := h.modelManager.ExecuteWithFailover(ctx, &llmModels.FailoverRequest{
+		ModelName: model,
+		ExecuteFunc: func(ctx context.Context, instance *llmModels.ModelInstance) (interface{}, error) {
+			providerRequest := providerChatRequest(&request, instance)
+			response, err := h.completeChat(ctx, instance, &providerRequest)
+			if err != nil {
+				instance.RecordError(err)
+				return nil, err
+			}
+			instance.RecordRequest(int32(response.Usage.TotalTokens), time.Since(startTime).Milliseconds())
+			return response, nil
+		},
+	})
+	latency := time.Since(startTime)
+
+	compareResult := providers.CompareResult{Model: model, LatencyMs: latency.Milliseconds()}
+	if err != nil {
+		h.modelManager.RecordRequestEnd(model, latency, false, err)
+		h.logger.Warn("Comparison model failed", zap.String("model", model), zap.Error(err))
+		compareResult.Error = &providers.APIError{
+			Message: "Request failed: " + err.Error(),
+			Type:    "api_error",
+		}
+		return compareResult, nil
+	}
+	h.modelManager.RecordRequestEnd(model, latency, true, nil)
+
+	// Each response is screened on its own, so one flagged model does not
+	// fail the whole comparison. A blocked response is not billed, as on
+	// the chat path.
+	response := result.Response.(*providers.ChatResponse)
+	if err := middleware.ScreenResponse(ctx, response); err != nil {
+		compareResult.Error = &providers.APIError{
+			Message: err.Error(),
+			Type:    "guardrail_violation",
+			Code:    "content_blocked",
+		}
+		return compareResult, nil
+	}
+	compareResult.Response = response
+
+	call := &middleware.ModelCallUsage{
+		RequestedModel:   model,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		ReasoningTokens:  response.Usage.ReasoningTokens(),
+		CachedTokens:     response.Usage.CachedTokens(),
+		Latency:          latency,
+	}
+	if result.Instance != nil {
+		call.ResolvedModel = result.Instance.Config.ModelName
+		call.ProviderModel = result.Instance.Config.Provider.Model
+		call.ProviderType = result.Instance.Config.Provider.Type
+		if _, isRoute := h.modelManager.ResolveRoute(model); isRoute {
+			call.RouteSlug = model
+		}
+
+		if h.pricing != nil {
+			if calc, err := h.pricing.CalculateCostWithReasoning(call.ProviderModel,
+				call.PromptTokens, call.CompletionTokens, call.ReasoningTokens); err == nil {
+				cost := calc.TotalCost
+				compareResult.Cost = &cost
+			}
+		}
+	}
+
+	return compareResult, call
+}
+
+// allResultsBlocked reports whether every result was blocked by a guardrail
+func allResultsBlocked(results []providers.CompareResult) bool {
+	for _, result := range results {
+		if result.Error == nil || result.Error.Type != "guardrail_violation" {
+			return false
+		}
+	}
+	return len(results) > 0
+}
+
+func validateCompareRequest(request *providers.CompareRequest) error {
+	if len(request.Models) < 2 {
+		return fmt.Errorf("models must list at least 2 models to compare")
+	}
+	if len(request.Models) > maxCompareModels {
+		return fmt.Errorf("models must list at most %d models", maxCompareModels)
+	}
+	if request.Stream {
+		return fmt.Errorf("streaming is not supported for comparisons")
+	}
+	if len(request.Messages) == 0 {
+		return fmt.Errorf("messages must not be empty")
+	}
+
+	seen := make(map[string]bool, len(request.Models))
+	for _, model := range request.Models {
+		if model == "" {
+			return fmt.Errorf("models must not contain empty names")
+		}
+		if seen[model] {
+			return fmt.Errorf("model %q is listed more than once", model)
+		}
+		seen[model] = true
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/integrations/guardrails"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func TestCompareCompletions_Validation(t *testing.T) {
+	handler := NewChatHandler(zap.NewNop(), nil)
+	messages := `"messages":[{"role":"user","content":"hi"}]`
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"single model", `{"models":["gpt-4"],` + messages + `}`, "at least 2 models"},
+		{"too many models", `{"models":["a","b","c","d","e","f","g","h","i"],` + messages + `}`, "at most 8 models"},
+		{"duplicate model", `{"models":["gpt-4","gpt-4"],` + messages + `}`, "listed more than once"},
+		{"streaming", `{"models":["gpt-4","claude"],"stream":true,` + messages + `}`, "streaming is not supported"},
+		{"no messages", `{"models":["gpt-4","claude"]}`, "messages must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions/compare", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+			handler.CompareCompletions(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestCompareCompletions_ScreensEachModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request providers.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		content := "Hello there."
+		if request.Model == "toxic-model" {
+			content = "You are an idiot."
+		}
+		_ = json.NewEncoder(w).Encode(providers.ChatResponse{
+			ID:      "chatcmpl-test",
+			Object:  "chat.completion",
+			Model:   request.Model,
+			Choices: []providers.Choice{{Message: providers.Message{Role: "assistant", Content: content}, FinishReason: "stop"}},
+			Usage:   providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		})
+	}))
+	defer server.Close()
+
+	modelManager := createMockModelManager()
+	for _, model := range []string{"clean", "toxic"} {
+		require.NoError(t, modelManager.AddInstance(config.ModelInstance{
+			ID:        model + "-instance",
+			ModelName: model,
+			Enabled:   true,
+			Provider: config.ProviderParams{
+				Type:    "openai",
+				Model:   model + "-model",
+				APIKey:  "test-key",
+				BaseURL: server.URL,
+			},
+		}))
+	}
+
+	pricing := config.GetPricingManager()
+	pricing.RegisterModel("clean-model", &config.ModelPricingInfo{InputCostPerToken: 0.001, OutputCostPerToken: 0.002})
+	handler := NewChatHandler(zap.NewNop(), modelManager)
+	handler.SetPricing(pricing)
+
+	executor, err := guardrails.NewFactory(&config.Config{Guardrails: config.GuardrailsConfig{
+		Enabled: true,
+		Guardrails: []config.GuardrailConfig{{
+			Name:     "output-safety",
+			Provider: "output_safety",
+			Mode:     []string{"post_call"},
+			Enabled:  true,
+			Config:   map[string]interface{}{"action": "block"},
+		}},
+	}}, zap.NewNop()).CreateExecutor()
+	require.NoError(t, err)
+	compare := middleware.NewGuardrailsMiddleware(executor, zap.NewNop()).Middleware(http.HandlerFunc(handler.CompareCompletions))
+
+	body := `{"models":["clean","toxic"],"messages":[{"role":"user","content":"hi"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions/compare", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	compare.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response providers.CompareResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Results, 2)
+
+	clean, toxic := response.Results[0], response.Results[1]
+	require.NotNil(t, clean.Response)
+	assert.Equal(t, "Hello there.", clean.Response.Choices[0].Message.Content)
+	require.NotNil(t, clean.Cost)
+	assert.InDelta(t, 0.02, *clean.Cost, 1e-9)
+
+	assert.Nil(t, toxic.Response)
+	require.NotNil(t, toxic.Error)
+	assert.Equal(t, "guardrail_violation", toxic.Error.Type)
+	assert.Nil(t, toxic.Cost)
+}
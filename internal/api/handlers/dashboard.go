@@ -12,11 +12,13 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/privacy"
 )
 
 type DashboardHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db         *gorm.DB
+	logger     *zap.Logger
+	anonymizer *privacy.Anonymizer // Optional, anonymized analytics mode
 }
 
 func NewDashboardHandler(db *gorm.DB, logger *zap.Logger) *DashboardHandler {
@@ -26,6 +28,17 @@ func NewDashboardHandler(db *gorm.DB, logger *zap.Logger) *DashboardHandler {
 	}
 }
 
+// SetAnonymizer withholds metrics over fewer users than the anonymized
+// analytics mode allows
+func (h *DashboardHandler) SetAnonymizer(anonymizer *privacy.Anonymizer) {
+	h.anonymizer = anonymizer
+}
+
+// minUsers returns the HAVING clause of the anonymized analytics mode
+func (h *DashboardHandler) minUsers() string {
+	return h.anonymizer.HavingMinUsers("actual_user_id")
+}
+
 type DashboardMetrics struct {
 	TotalRequests  int64   `json:"total_requests"`
 	TotalTokens    int64   `json:"total_tokens"`
@@ -61,6 +74,8 @@ type ModelUsage struct {
 	P95Latency   float64 `json:"p95_latency"`
 	P99Latency   float64 `json:"p99_latency"`
 	CacheHitRate float64 `json:"cache_hit_rate"`
+	AvgTTFT      int64   `json:"avg_ttft"` // Streaming requests only
+	P95TTFT      float64 `json:"p95_ttft"`
 }
 
 func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Request) {
@@ -90,6 +105,7 @@ func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Re
 			ROUND(AVG(latency)) as avg_latency
 		FROM usage_logs
 		WHERE timestamp >= ?
+		`+h.minUsers()+`
 	`, last24h).Scan(&overallStats).Error
 
 	if err != nil {
@@ -125,6 +141,7 @@ func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Re
 			COALESCE(SUM(total_cost), 0) as cost
 		FROM usage_logs 
 		WHERE timestamp >= ?
+		`+h.minUsers()+`
 	`, last24h).Scan(&metrics.RecentActivity.Last24h).Error
 
 	if err != nil {
@@ -139,6 +156,7 @@ func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Re
 			COALESCE(SUM(total_cost), 0) as cost
 		FROM usage_logs 
 		WHERE timestamp >= ?
+		`+h.minUsers()+`
 	`, lastHour).Scan(&metrics.RecentActivity.LastHour).Error
 
 	if err != nil {
@@ -157,10 +175,13 @@ func (h *DashboardHandler) GetDashboardMetrics(w http.ResponseWriter, r *http.Re
 			ROUND(AVG(CASE WHEN status_code = 200 THEN 100 ELSE 0 END), 2) as success_rate,
 			ROUND(AVG(CASE WHEN cache_hit THEN 100 ELSE 0 END), 2) as cache_hit_rate,
 			PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY latency) as p95_latency,
-			PERCENTILE_CONT(0.99) WITHIN GROUP (ORDER BY latency) as p99_latency
+			PERCENTILE_CONT(0.99) WITHIN GROUP (ORDER BY latency) as p99_latency,
+			COALESCE(ROUND(AVG(NULLIF(ttft, 0))), 0) as avg_ttft,
+			COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY NULLIF(ttft, 0)), 0) as p95_ttft
 		FROM usage_logs
 		WHERE timestamp >= ?
 		GROUP BY model
+		`+h.minUsers()+`
 		ORDER BY requests DESC
 		LIMIT 10
 	`, last24h).Scan(&topModels).Error
@@ -201,6 +222,7 @@ func (h *DashboardHandler) GetModelMetrics(w http.ResponseWriter, r *http.Reques
 		TotalTokens     int64   `json:"total_tokens"`
 		TotalCost       float64 `json:"total_cost"`
 		AvgLatency      int64   `json:"avg_latency"`
+		AvgTTFT         int64   `json:"avg_ttft"`
 		SuccessRate     float64 `json:"success_rate"`
 		CacheHitRate    float64 `json:"cache_hit_rate"`
 		LastUsed        *time.Time `json:"last_used,omitempty"`
@@ -212,11 +234,13 @@ func (h *DashboardHandler) GetModelMetrics(w http.ResponseWriter, r *http.Reques
 			SUM(total_tokens) as total_tokens,
 			SUM(total_cost) as total_cost,
 			ROUND(AVG(latency)) as avg_latency,
+			COALESCE(ROUND(AVG(NULLIF(ttft, 0))), 0) as avg_ttft,
 			ROUND(AVG(CASE WHEN status_code = 200 THEN 100 ELSE 0 END), 2) as success_rate,
 			ROUND(AVG(CASE WHEN cache_hit THEN 100 ELSE 0 END), 2) as cache_hit_rate,
 			MAX(timestamp) as last_used
 		FROM usage_logs
 		WHERE model = ? AND timestamp >= ?
+		`+h.minUsers()+`
 	`, modelName, since).Scan(&modelStats).Error
 
 	if err != nil {
@@ -256,8 +280,9 @@ func (h *DashboardHandler) GetUsageTrends(w http.ResponseWriter, r *http.Request
 		FROM usage_logs
 		WHERE timestamp >= ?
 		GROUP BY %s
+		%s
 		ORDER BY date ASC
-	`, groupExpr, groupExpr)
+	`, groupExpr, groupExpr, h.minUsers())
 
 	err := h.db.Raw(query, since).Scan(&trends).Error
 
@@ -294,6 +319,7 @@ func (h *DashboardHandler) GetModelTrends(w http.ResponseWriter, r *http.Request
 		Tokens      int64   `json:"tokens"`
 		Cost        float64 `json:"cost"`
 		AvgLatency  int64   `json:"avg_latency"`
+		AvgTTFT     int64   `json:"avg_ttft"`
 		SuccessRate float64 `json:"success_rate"`
 	}
 
@@ -304,12 +330,14 @@ func (h *DashboardHandler) GetModelTrends(w http.ResponseWriter, r *http.Request
 			SUM(total_tokens) as tokens,
 			SUM(total_cost) as cost,
 			ROUND(AVG(latency)) as avg_latency,
+			COALESCE(ROUND(AVG(NULLIF(ttft, 0))), 0) as avg_ttft,
 			ROUND(AVG(CASE WHEN status_code = 200 THEN 100 ELSE 0 END), 2) as success_rate
 		FROM usage_logs
 		WHERE model = ? AND timestamp >= ?
 		GROUP BY %s
+		%s
 		ORDER BY date ASC
-	`, groupExpr, groupExpr)
+	`, groupExpr, groupExpr, h.minUsers())
 
 	err := h.db.Raw(query, modelName, since).Scan(&trends).Error
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
 	"github.com/amerfu/pllm/internal/services/llm/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
@@ -59,15 +60,48 @@ func (h *EmbeddingsHandler) Embeddings(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("Model not available: %s", err.Error()))
 		return
 	}
-	provider := instance.Provider
+	defer instance.Begin()()
+	provider := providerFor(r.Context(), h.modelManager, instance)
+
+	// Requested vector size, falling back to the model's standard size.
+	// Providers without native support get no dimensions parameter and their
+	// vectors are truncated here.
+	dimensions := instance.Config.ModelInfo.EmbeddingDimensions
+	if request.Dimensions != nil {
+		if *request.Dimensions <= 0 {
+			h.sendError(w, http.StatusBadRequest, "dimensions must be a positive integer")
+			return
+		}
+		dimensions = *request.Dimensions
+	}
+	nativeDimensions := instance.Config.ModelInfo.SupportsDimensions
+	providerRequest := request
+	providerRequest.Dimensions = nil
+	if nativeDimensions && dimensions > 0 {
+		providerRequest.Dimensions = &dimensions
+	}
 
 	// Call embeddings endpoint
-	response, err := provider.Embeddings(r.Context(), &request)
+	response, err := provider.Embeddings(r.Context(), &providerRequest)
 	if err != nil {
 		h.logger.Error("Embeddings request failed", zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	middleware.SetResolvedModel(r.Context(),
+		instance.Config.ModelName,
+		instance.Config.Provider.Model,
+		instance.Config.Provider.Type,
+		"",
+	)
+	middleware.SetTokenUsage(r.Context(), response.Usage.PromptTokens, 0, 0)
+
+	if dimensions > 0 && !nativeDimensions {
+		if err := response.TruncateDimensions(dimensions); err != nil {
+			h.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+const (
+	maxFeedbackTags      = 10
+	maxFeedbackTagLength = 32
+)
+
+// feedbackTagPattern is the form tags are stored in, after lowercasing
+var feedbackTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// FeedbackHandler records callers' ratings of responses, which the
+// satisfaction analytics aggregate per model and route. Callers can only
+// rate requests in their usage scope (see usageScopeFromContext).
+type FeedbackHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewFeedbackHandler(logger *zap.Logger, db *gorm.DB) *FeedbackHandler {
+	return &FeedbackHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// FeedbackRequest rates the response of a request
+type FeedbackRequest struct {
+	RequestID string   `json:"request_id"`
+	Rating    int      `json:"rating"` // 1 (worst) to 5 (best)
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// FeedbackResponse is the stored feedback. Linked reports whether the usage
+// record of the request was found; it is written asynchronously, so feedback
+// sent right after a response may be linked later.
+type FeedbackResponse struct {
+	models.ResponseFeedback
+	Linked bool `json:"linked"`
+}
+
+// SubmitFeedback rates a response
+// @Summary Submit response feedback
+// @Description Rates the response of a request by its request ID (the X-Request-ID response header). Sending feedback again for the same request replaces it.
+// @Tags Feedback
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param request body FeedbackRequest true "Feedback"
+// @Success 200 {object} FeedbackResponse
+// @Success 201 {object} FeedbackResponse
+// @Failure 400 {object} providers.ErrorResponse
+// @Failure 401 {object} providers.ErrorResponse
+// @Failure 404 {object} providers.ErrorResponse
+// @Router /feedback [post]
+func (h *FeedbackHandler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	scope, ok := usageScopeFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	var request FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.RequestID = strings.TrimSpace(request.RequestID)
+	if request.RequestID == "" {
+		h.sendError(w, http.StatusBadRequest, "request_id is required")
+		return
+	}
+	if request.Rating < models.MinFeedbackRating || request.Rating > models.MaxFeedbackRating {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("rating must be between %d and %d", models.MinFeedbackRating, models.MaxFeedbackRating))
+		return
+	}
+	tags, err := normalizeFeedbackTags(request.Tags)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	linked := false
+	var usage models.Usage
+	err = h.db.WithContext(ctx).Select("request_id", "user_id", "team_id", "key_id").
+		Where("request_id = ?", request.RequestID).First(&usage).Error
+	switch {
+	case err == nil:
+		if !scope.owns(usage.TeamID, usage.UserID, usage.KeyID) {
+			h.sendError(w, http.StatusNotFound, "Request not found")
+			return
+		}
+		linked = true
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		h.logger.Error("Failed to look up usage record for feedback", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to record feedback")
+		return
+	}
+
+	var feedback models.ResponseFeedback
+	err = h.db.WithContext(ctx).Where("request_id = ?", request.RequestID).First(&feedback).Error
+	status := http.StatusOK
+	switch {
+	case err == nil:
+		if !scope.owns(feedback.TeamID, feedback.UserID, feedback.KeyID) {
+			h.sendError(w, http.StatusNotFound, "Request not found")
+			return
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusCreated
+		feedback = models.ResponseFeedback{RequestID: request.RequestID}
+		if scope.key != nil {
+			feedback.KeyID = &scope.key.ID
+			feedback.TeamID = scope.key.TeamID
+			feedback.UserID = scope.key.UserID
+		} else if scope.column == "user_id" {
+			feedback.UserID = &scope.id
+		}
+	default:
+		h.logger.Error("Failed to look up feedback", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to record feedback")
+		return
+	}
+
+	feedback.Rating = request.Rating
+	feedback.Tags = tags
+	if err := h.db.WithContext(ctx).Save(&feedback).Error; err != nil {
+		h.logger.Error("Failed to save feedback", zap.Error(err), zap.String("request_id", request.RequestID))
+		h.sendError(w, http.StatusInternalServerError, "Failed to record feedback")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(FeedbackResponse{ResponseFeedback: feedback, Linked: linked}); err != nil {
+		h.logger.Error("Failed to encode feedback response", zap.Error(err))
+	}
+}
+
+// owns reports whether a record with the given owners is in the scope
+func (s usageScope) owns(teamID, userID, keyID *uuid.UUID) bool {
+	var owner *uuid.UUID
+	switch s.column {
+	case "":
+		return true
+	case "team_id":
+		owner = teamID
+	case "user_id":
+		owner = userID
+	case "key_id":
+		owner = keyID
+	}
+	return owner != nil && *owner == s.id
+}
+
+// normalizeFeedbackTags lowercases and deduplicates tags, keeping their order
+func normalizeFeedbackTags(tags []string) (models.StringArray, error) {
+	normalized := models.StringArray{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxFeedbackTagLength || !feedbackTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: tags are up to %d letters, digits, '-' or '_'", tag, maxFeedbackTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxFeedbackTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxFeedbackTags)
+	}
+	return normalized, nil
+}
+
+func (h *FeedbackHandler) sendError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	}); err != nil {
+		h.logger.Error("Failed to encode feedback error response", zap.Error(err))
+	}
+}
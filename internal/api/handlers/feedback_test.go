@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+)
+
+func TestNormalizeFeedbackTags(t *testing.T) {
+	tags, err := normalizeFeedbackTags([]string{" Hallucination", "too_long", "", "hallucination", "off-topic"})
+	require.NoError(t, err)
+	assert.Equal(t, models.StringArray{"hallucination", "too_long", "off-topic"}, tags)
+
+	_, err = normalizeFeedbackTags([]string{"not a tag"})
+	assert.Error(t, err)
+	_, err = normalizeFeedbackTags([]string{strings.Repeat("a", maxFeedbackTagLength+1)})
+	assert.Error(t, err)
+
+	many := make([]string, maxFeedbackTags+1)
+	for i := range many {
+		many[i] = string(rune('a' + i))
+	}
+	_, err = normalizeFeedbackTags(many)
+	assert.Error(t, err)
+}
+
+func TestUsageScopeOwns(t *testing.T) {
+	teamID, userID, keyID := uuid.New(), uuid.New(), uuid.New()
+
+	assert.True(t, usageScope{}.owns(nil, nil, nil), "the master key owns everything")
+	assert.True(t, usageScope{column: "team_id", id: teamID}.owns(&teamID, &userID, &keyID))
+	assert.False(t, usageScope{column: "team_id", id: teamID}.owns(nil, &userID, &keyID))
+	assert.True(t, usageScope{column: "user_id", id: userID}.owns(nil, &userID, nil))
+	assert.False(t, usageScope{column: "key_id", id: keyID}.owns(&teamID, &userID, nil))
+}
+
+func TestFeedbackHandler_Validation(t *testing.T) {
+	handler := NewFeedbackHandler(zap.NewNop(), nil)
+	userCtx := context.WithValue(context.Background(), middleware.UserContextKey, uuid.New())
+
+	submit := func(body string, ctx context.Context) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.SubmitFeedback(rec, httptest.NewRequest(http.MethodPost, "/v1/feedback", strings.NewReader(body)).WithContext(ctx))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, submit(`{"request_id":"req-1","rating":5}`, context.Background()).Code)
+	assert.Equal(t, http.StatusBadRequest, submit(`{"rating":5}`, userCtx).Code)
+
+	rec := submit(`{"request_id":"req-1","rating":6}`, userCtx)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "rating must be between 1 and 5")
+
+	assert.Equal(t, http.StatusBadRequest, submit(`{"request_id":"req-1","rating":3,"tags":["bad tag"]}`, userCtx).Code)
+}
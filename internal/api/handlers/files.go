@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,10 +10,14 @@ import (
 	"strings"
 	"time"
 
+	coremodels "github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/data/artifacts"
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
 	"github.com/amerfu/pllm/internal/services/llm/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +25,7 @@ type FilesHandler struct {
 	logger         *zap.Logger
 	modelManager   *models.ModelManager
 	metricsEmitter *metrics.MetricEventEmitter
+	artifacts      *artifacts.Service
 }
 
 func NewFilesHandler(logger *zap.Logger, modelManager *models.ModelManager) *FilesHandler {
@@ -37,6 +43,12 @@ func NewFilesHandlerWithMetrics(logger *zap.Logger, modelManager *models.ModelMa
 	}
 }
 
+// SetArtifacts stores uploads in artifact storage, where lifecycle rules
+// apply to them. Without it uploads are kept in ./uploads indefinitely.
+func (h *FilesHandler) SetArtifacts(service *artifacts.Service) {
+	h.artifacts = service
+}
+
 // UploadFile handles file uploads for chat attachments
 // @Summary Upload file
 // @Description Upload a file for use in chat messages
@@ -52,27 +64,31 @@ func NewFilesHandlerWithMetrics(logger *zap.Logger, modelManager *models.ModelMa
 // @Failure 500 {object} providers.ErrorResponse
 // @Router /files [post]
 func (h *FilesHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form (limit to 32MB)
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
+	// Read the form part by part; the file is spooled to disk, not memory
+	upload, err := readMultipartUpload(r, "file")
+	if err != nil {
+		if limit, ok := middleware.BodyTooLarge(err); ok {
+			middleware.WriteBodyTooLarge(w, limit)
+			return
+		}
 		h.sendError(w, http.StatusBadRequest, "Failed to parse form: "+err.Error())
 		return
 	}
+	defer upload.Close()
 
-	file, fileHeader, err := r.FormFile("file")
-	if err != nil {
+	if upload.file == nil {
 		h.sendError(w, http.StatusBadRequest, "No file provided or invalid file")
 		return
 	}
-	defer func() { _ = file.Close() }()
 
 	// Validate file size (max 10MB)
-	if fileHeader.Size > 10<<20 {
+	if upload.size > 10<<20 {
 		h.sendError(w, http.StatusRequestEntityTooLarge, "File too large (max 10MB)")
 		return
 	}
 
 	// Validate file type (images only for now)
-	contentType := fileHeader.Header.Get("Content-Type")
+	contentType := upload.contentType
 	if contentType == "" {
 		// Try to detect from extension
 		contentType = http.DetectContentType(make([]byte, 512))
@@ -84,7 +100,11 @@ func (h *FilesHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate unique filename
-	fileID := fmt.Sprintf("%d_%s", time.Now().Unix(), fileHeader.Filename)
+	fileID := fmt.Sprintf("%d_%s", time.Now().Unix(), upload.filename)
+	if h.artifacts != nil {
+		h.saveArtifact(w, r, upload, fileID, contentType)
+		return
+	}
 	uploadDir := "./uploads"
 	
 	// Create upload directory if it doesn't exist
@@ -104,7 +124,7 @@ func (h *FilesHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() { _ = dst.Close() }()
 
-	if _, err := io.Copy(dst, file); err != nil {
+	if _, err := io.Copy(dst, upload.file); err != nil {
 		h.logger.Error("Failed to write file", zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "Failed to save file")
 		return
@@ -113,8 +133,8 @@ func (h *FilesHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	// Return file info
 	response := map[string]interface{}{
 		"id":       fileID,
-		"filename": fileHeader.Filename,
-		"size":     fileHeader.Size,
+		"filename": upload.filename,
+		"size":     upload.size,
 		"type":     contentType,
 		"url":      fmt.Sprintf("/files/%s", fileID),
 	}
@@ -162,6 +182,11 @@ func (h *FilesHandler) GetFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.artifacts != nil {
+		h.serveArtifact(w, r, fileID)
+		return
+	}
+
 	filepath := fmt.Sprintf("./uploads/%s", fileID)
 	
 	// Check if file exists
@@ -192,6 +217,74 @@ func (h *FilesHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 	h.sendError(w, http.StatusNotImplemented, "Delete file not yet implemented")
 }
 
+// saveArtifact stores an upload as an artifact owned by the caller
+func (h *FilesHandler) saveArtifact(w http.ResponseWriter, r *http.Request, upload *multipartUpload, fileID, contentType string) {
+	if !artifacts.ValidKey(fileID) {
+		fileID = fmt.Sprintf("%d_%s", time.Now().Unix(), uuid.New().String())
+	}
+	artifact := &coremodels.Artifact{
+		Kind:        coremodels.ArtifactKindFile,
+		StorageKey:  fileID,
+		Filename:    upload.filename,
+		ContentType: contentType,
+	}
+	if userID, ok := middleware.GetUserID(r.Context()); ok {
+		artifact.UserID = &userID
+	}
+	if key, ok := middleware.GetKey(r.Context()); ok && key != nil {
+		artifact.KeyID = &key.ID
+		artifact.TeamID = key.TeamID
+	}
+	if teamID, ok := middleware.GetTeamID(r.Context()); ok && artifact.TeamID == nil {
+		artifact.TeamID = &teamID
+	}
+
+	if err := h.artifacts.Save(r.Context(), artifact, upload.file); err != nil {
+		h.logger.Error("Failed to store file", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to save file")
+		return
+	}
+
+	response := map[string]interface{}{
+		"id":       artifact.StorageKey,
+		"filename": artifact.Filename,
+		"size":     artifact.Size,
+		"type":     artifact.ContentType,
+		"url":      fmt.Sprintf("/files/%s", artifact.StorageKey),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode upload response", zap.Error(err))
+	}
+}
+
+// serveArtifact serves a stored file, 404 once lifecycle rules removed it
+func (h *FilesHandler) serveArtifact(w http.ResponseWriter, r *http.Request, fileID string) {
+	artifact, blob, err := h.artifacts.Open(r.Context(), fileID)
+	if errors.Is(err, artifacts.ErrNotFound) {
+		h.sendError(w, http.StatusNotFound, "File not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to open file", zap.String("file_id", fileID), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+	defer func() { _ = blob.Close() }()
+
+	if artifact.ContentType != "" {
+		w.Header().Set("Content-Type", artifact.ContentType)
+	}
+	if seeker, ok := blob.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, artifact.Filename, artifact.UpdatedAt, seeker)
+		return
+	}
+	if _, err := io.Copy(w, blob); err != nil {
+		h.logger.Warn("Failed to send file", zap.String("file_id", fileID), zap.Error(err))
+	}
+}
+
 func (h *FilesHandler) sendError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
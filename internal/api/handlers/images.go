@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
 	"github.com/amerfu/pllm/internal/services/llm/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
@@ -70,7 +71,8 @@ func (h *ImagesHandler) GenerateImage(w http.ResponseWriter, r *http.Request) {
 		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("Model not available: %s", err.Error()))
 		return
 	}
-	provider := instance.Provider
+	defer instance.Begin()()
+	provider := providerFor(r.Context(), h.modelManager, instance)
 
 	// Call image generation endpoint
 	response, err := provider.ImageGeneration(r.Context(), &request)
@@ -80,6 +82,20 @@ func (h *ImagesHandler) GenerateImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record the images for usage tracking; pricing depends on their size
+	// and quality
+	middleware.SetResolvedModel(r.Context(),
+		instance.Config.ModelName,
+		instance.Config.Provider.Model,
+		instance.Config.Provider.Type,
+		"",
+	)
+	size := request.Size
+	if size == "" {
+		size = "1024x1024" // The upstream default
+	}
+	middleware.SetImageUsage(r.Context(), len(response.Data), size, request.Quality)
+
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
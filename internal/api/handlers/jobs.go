@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/jobs"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+type JobsHandler struct {
+	logger *zap.Logger
+	jobs   *jobs.Service
+}
+
+func NewJobsHandler(logger *zap.Logger, jobService *jobs.Service) *JobsHandler {
+	return &JobsHandler{
+		logger: logger,
+		jobs:   jobService,
+	}
+}
+
+// GetJob returns the status and, once finished, the result of an async job
+// @Summary Get async job
+// @Description Returns the status and result of a request submitted with ?async=true
+// @Tags Jobs
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobs.View
+// @Failure 404 {object} providers.ErrorResponse
+// @Router /jobs/{id} [get]
+func (h *JobsHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.sendError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	job, err := h.jobs.Get(r.Context(), id)
+	if errors.Is(err, jobs.ErrJobNotFound) || (err == nil && !canAccessJob(r, job)) {
+		h.sendError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to load job", zap.String("job_id", id.String()), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to load job")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(jobs.NewView(job)); err != nil {
+		h.logger.Error("Failed to encode job response", zap.Error(err))
+	}
+}
+
+// canAccessJob limits jobs to the key or user that submitted them
+func canAccessJob(r *http.Request, job *models.Job) bool {
+	return isOwner(r, job.KeyID, job.UserID)
+}
+
+// isOwner reports whether the request comes from the key or user that owns a
+// resource. The master key owns everything.
+func isOwner(r *http.Request, keyID, userID *uuid.UUID) bool {
+	if middleware.IsMasterKey(r.Context()) {
+		return true
+	}
+	if key, ok := middleware.GetKey(r.Context()); ok && key != nil {
+		return keyID != nil && *keyID == key.ID
+	}
+	if requestUserID, ok := middleware.GetUserID(r.Context()); ok {
+		return userID != nil && *userID == requestUserID
+	}
+	return false
+}
+
+func (h *JobsHandler) sendError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	}); err != nil {
+		h.logger.Error("Failed to encode jobs error response", zap.Error(err))
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+)
+
+func TestChatCompletions_AsyncRequiresJobService(t *testing.T) {
+	handler := NewChatHandler(zap.NewNop(), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions?async=true",
+		bytes.NewBufferString(`{"model":"gpt-4","messages":[{"role":"user","content":"hi"}]}`))
+	rec := httptest.NewRecorder()
+	handler.ChatCompletions(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Async mode is not enabled")
+}
+
+func TestCanAccessJob(t *testing.T) {
+	owner := &models.Key{}
+	owner.ID = uuid.New()
+	other := &models.Key{}
+	other.ID = uuid.New()
+	userID := uuid.New()
+
+	job := &models.Job{KeyID: &owner.ID, UserID: &userID}
+
+	withKey := func(key *models.Key) *http.Request {
+		ctx := context.WithValue(context.Background(), middleware.KeyContextKey, key)
+		return httptest.NewRequest(http.MethodGet, "/v1/jobs/x", nil).WithContext(ctx)
+	}
+
+	assert.True(t, canAccessJob(withKey(owner), job))
+	assert.False(t, canAccessJob(withKey(other), job))
+
+	userReq := httptest.NewRequest(http.MethodGet, "/v1/jobs/x", nil).
+		WithContext(context.WithValue(context.Background(), middleware.UserContextKey, userID))
+	assert.True(t, canAccessJob(userReq, job))
+
+	anonymous := httptest.NewRequest(http.MethodGet, "/v1/jobs/x", nil)
+	assert.False(t, canAccessJob(anonymous, job))
+}
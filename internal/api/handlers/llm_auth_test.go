@@ -43,36 +43,15 @@ func createMockModelManager() *modelsService.ModelManager {
 	return manager
 }
 
-// mockTeamService implements TeamService for testing
-type mockTeamService struct{}
-
-func (m *mockTeamService) AddUserToDefaultTeam(ctx context.Context, userID uuid.UUID, role models.TeamRole) (*models.TeamMember, error) {
-	return &models.TeamMember{
-		UserID: userID,
-		TeamID: uuid.New(),
-		Role:   role,
-	}, nil
-}
-
-// mockKeyService implements KeyService for testing
-type mockKeyService struct{}
+// mockOnboarder implements Onboarder for testing
+type mockOnboarder struct{}
 
-func (m *mockKeyService) CreateDefaultKeyForUser(ctx context.Context, userID uuid.UUID, teamID uuid.UUID) (*models.Key, error) {
-	keyGen := key.NewKeyGenerator()
-	_, hash, err := keyGen.GenerateAPIKey()
-	if err != nil {
-		return nil, err
-	}
+func (m *mockOnboarder) OnboardUser(ctx context.Context, user *models.User, groups []string) error {
+	return nil
+}
 
-	return &models.Key{
-		BaseModel: models.BaseModel{ID: uuid.New()},
-		Name:      "Default Key",
-		KeyHash:   hash,
-		Type:      models.KeyTypeAPI,
-		UserID:    &userID,
-		TeamID:    &teamID,
-		IsActive:  true,
-	}, nil
+func (m *mockOnboarder) ProvisionedRole(email string, groups []string) models.UserRole {
+	return models.RoleUser
 }
 
 // setupTestAuth creates auth service with test keys
@@ -95,8 +74,7 @@ func setupTestAuth(t *testing.T, db *gorm.DB) (*auth.AuthService, *auth.MasterKe
 		JWTIssuer:        "test-issuer",
 		TokenExpiry:      time.Hour,
 		MasterKeyService: masterKeySvc,
-		TeamService:      &mockTeamService{},
-		KeyService:       &mockKeyService{},
+		Onboarder:        &mockOnboarder{},
 	}
 
 	authSvc, err := auth.NewAuthService(authConfig)
@@ -1,15 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/llm/contextwindow"
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
 	"github.com/amerfu/pllm/internal/services/llm/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
+	pkglogger "github.com/amerfu/pllm/pkg/logger"
 	"go.uber.org/zap"
 )
 
@@ -53,7 +56,7 @@ func NewMessagesHandlerWithMetrics(logger *zap.Logger, modelManager *models.Mode
 func (h *MessagesHandler) AnthropicMessages(w http.ResponseWriter, r *http.Request) {
 	var request providers.MessagesAPIRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.logger.Error("Failed to decode request body", zap.Error(err))
+		h.requestLogger(r.Context()).Error("Failed to decode request body", zap.Error(err))
 		h.sendError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
@@ -73,7 +76,7 @@ func (h *MessagesHandler) AnthropicMessages(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Debug logging
-	h.logger.Info("Received Anthropic messages request",
+	h.requestLogger(r.Context()).Info("Received Anthropic messages request",
 		zap.String("model", request.Model),
 		zap.Int("num_messages", len(request.Messages)),
 		zap.Int("max_tokens", request.MaxTokens),
@@ -82,15 +85,14 @@ func (h *MessagesHandler) AnthropicMessages(w http.ResponseWriter, r *http.Reque
 	// Convert Messages API format to OpenAI format for internal processing
 	chatRequest, err := h.convertMessagesAPIToOpenAI(&request)
 	if err != nil {
-		h.logger.Error("Failed to convert Messages API request to OpenAI format", zap.Error(err))
+		h.requestLogger(r.Context()).Error("Failed to convert Messages API request to OpenAI format", zap.Error(err))
 		h.sendError(w, http.StatusBadRequest, "Invalid request format: "+err.Error())
 		return
 	}
+	h.modelManager.ApplyRouteDefaults(chatRequest)
 
-	// Populate metrics context if available
-	if metricsCtx, ok := r.Context().Value(middleware.MetricsContextKey).(*middleware.MetricsContext); ok && metricsCtx != nil {
-		metricsCtx.ModelName = request.Model
-	}
+	// Populate metrics and log context
+	middleware.SetModelName(r.Context(), request.Model)
 
 	// Track request start for adaptive routing
 	h.modelManager.RecordRequestStart(request.Model)
@@ -100,14 +102,15 @@ func (h *MessagesHandler) AnthropicMessages(w http.ResponseWriter, r *http.Reque
 	instance, err := h.modelManager.GetBestInstanceAdaptive(r.Context(), request.Model)
 	if err != nil {
 		h.modelManager.RecordRequestEnd(request.Model, time.Since(startTime), false, err)
-		h.logger.Error("Failed to get model instance",
+		h.requestLogger(r.Context()).Error("Failed to get model instance",
 			zap.String("model", request.Model),
 			zap.Error(err))
 		h.sendError(w, http.StatusServiceUnavailable, "No instance available for model: "+request.Model)
 		return
 	}
+	defer instance.Begin()()
 
-	h.logger.Info("Selected instance for request",
+	h.requestLogger(r.Context()).Info("Selected instance for request",
 		zap.String("requested_model", request.Model),
 		zap.String("instance_id", instance.Config.ID),
 		zap.String("provider_model", instance.Config.Provider.Model),
@@ -115,7 +118,7 @@ func (h *MessagesHandler) AnthropicMessages(w http.ResponseWriter, r *http.Reque
 
 	// Handle streaming
 	if request.Stream {
-		h.logger.Info("Routing to streaming handler")
+		h.requestLogger(r.Context()).Info("Routing to streaming handler")
 		h.handleStreamingMessages(w, r, &request, chatRequest, instance, startTime)
 		return
 	}
@@ -125,14 +128,14 @@ func (h *MessagesHandler) AnthropicMessages(w http.ResponseWriter, r *http.Reque
 	providerRequest.Model = instance.Config.Provider.Model
 
 	// Forward request to provider
-	response, err := instance.Provider.ChatCompletion(r.Context(), &providerRequest)
+	response, err := providerFor(r.Context(), h.modelManager, instance).ChatCompletion(r.Context(), &providerRequest)
 	latency := time.Since(startTime)
 	latencyMs := latency.Milliseconds()
 
 	if err != nil {
 		instance.RecordError(err)
 		h.modelManager.RecordRequestEnd(request.Model, latency, false, err)
-		h.logger.Error("Provider request failed", zap.Error(err))
+		h.requestLogger(r.Context()).Error("Provider request failed", zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "Provider request failed")
 		return
 	}
@@ -140,7 +143,7 @@ func (h *MessagesHandler) AnthropicMessages(w http.ResponseWriter, r *http.Reque
 	// Convert OpenAI response to Messages API format
 	messagesResponse, err := h.convertOpenAIToMessagesAPI(response, &request)
 	if err != nil {
-		h.logger.Error("Failed to convert OpenAI response to Messages API format", zap.Error(err))
+		h.requestLogger(r.Context()).Error("Failed to convert OpenAI response to Messages API format", zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "Failed to process response")
 		return
 	}
@@ -160,24 +163,18 @@ func (h *MessagesHandler) AnthropicMessages(w http.ResponseWriter, r *http.Reque
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(messagesResponse); err != nil {
-		h.logger.Error("Failed to encode Messages API response", zap.Error(err))
+		h.requestLogger(r.Context()).Error("Failed to encode Messages API response", zap.Error(err))
 	}
 }
 
-func (h *MessagesHandler) handleStreamingMessages(w http.ResponseWriter, r *http.Request, 
-	request *providers.MessagesAPIRequest, chatRequest *providers.ChatRequest, 
+func (h *MessagesHandler) handleStreamingMessages(w http.ResponseWriter, r *http.Request,
+	request *providers.MessagesAPIRequest, chatRequest *providers.ChatRequest,
 	instance *models.ModelInstance, startTime time.Time) {
-	
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Accel-Buffering", "no")
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		errMsg := fmt.Sprintf("Streaming not supported - Writer type: %T", w)
-		h.logger.Error(errMsg, zap.String("model", request.Model))
+		h.requestLogger(r.Context()).Error(errMsg, zap.String("model", request.Model))
 		h.sendError(w, http.StatusInternalServerError, errMsg)
 		return
 	}
@@ -186,60 +183,118 @@ func (h *MessagesHandler) handleStreamingMessages(w http.ResponseWriter, r *http
 	providerRequest := *chatRequest
 	providerRequest.Model = instance.Config.Provider.Model
 
-	// Get streaming response from provider
-	streamChan, err := instance.Provider.ChatCompletionStream(r.Context(), &providerRequest)
+	// Get streaming response from provider. Until the first event is
+	// written, errors are plain HTTP errors as with the Anthropic API.
+	streamChan, err := providerFor(r.Context(), h.modelManager, instance).ChatCompletionStream(r.Context(), &providerRequest)
 	if err != nil {
 		instance.RecordError(err)
 		h.modelManager.RecordRequestEnd(request.Model, time.Since(startTime), false, err)
-		h.logger.Error("Failed to start streaming", zap.String("model", request.Model), zap.Error(err))
-		_, _ = fmt.Fprintf(w, "event: error\ndata: {\"error\": {\"message\": \"%s\"}}\n\n", err.Error())
-		flusher.Flush()
+		h.requestLogger(r.Context()).Error("Failed to start streaming", zap.String("model", request.Model), zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Provider request failed")
 		return
 	}
 
-	h.logger.Info("Starting stream processing loop", zap.String("model", request.Model))
+	// Set headers for SSE
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	h.requestLogger(r.Context()).Info("Starting stream processing loop", zap.String("model", request.Model))
 
-	totalTokens := int64(0)
-	promptTokens := int64(0)
+	promptTokens := int64(contextwindow.EstimateTokens(chatRequest))
 	completionTokens := int64(0)
+	firstChunk := true
+	stopReason := "end_turn"
+	meter := h.modelManager.StartStreamMeter(instance)
+
+	// Keep-alive comments hold the connection open through long gaps
+	keepAlive := startKeepAlive(w, flusher, meter)
+	defer keepAlive.Stop()
+	w, flusher = keepAlive, keepAlive
+
+	// The event grammar of the Anthropic API: message_start, one text
+	// content block with its deltas, message_delta with the stop reason
+	// and usage, then message_stop
+	events := []messagesStreamEvent{
+		{"message_start", map[string]interface{}{
+			"message": map[string]interface{}{
+				"id":            providers.GenerateMessagesAPIID(),
+				"type":          "message",
+				"role":          "assistant",
+				"content":       []interface{}{},
+				"model":         request.Model,
+				"stop_reason":   nil,
+				"stop_sequence": nil,
+				"usage":         providers.MessagesAPIUsage{InputTokens: int(promptTokens)},
+			},
+		}},
+		{"content_block_start", map[string]interface{}{
+			"index":         0,
+			"content_block": providers.MessagesAPIContent{Type: "text", Text: ""},
+		}},
+		{"ping", map[string]interface{}{}},
+	}
+	if err := h.writeStreamEvents(w, flusher, events...); err != nil {
+		h.requestLogger(r.Context()).Error("Failed to write stream data", zap.Error(err))
+	}
 
 	// Stream the response in Anthropic format
 	for streamResponse := range streamChan {
-		h.logger.Debug("Received stream chunk", zap.Any("response", streamResponse))
+		h.requestLogger(r.Context()).Debug("Received stream chunk", zap.Any("response", streamResponse))
+
+		if len(streamResponse.Choices) > 0 && streamResponse.Choices[0].FinishReason != "" {
+			stopReason = messagesStopReason(streamResponse.Choices[0].FinishReason)
+		}
 
 		// Convert OpenAI stream response to Messages API stream format
 		messagesStream, err := h.convertOpenAIStreamToMessagesAPI(streamResponse, request)
 		if err != nil {
-			h.logger.Error("Failed to convert stream response", zap.Error(err))
+			h.requestLogger(r.Context()).Error("Failed to convert stream response", zap.Error(err))
 			continue
 		}
-
-		data, err := json.Marshal(messagesStream)
-		if err != nil {
-			h.logger.Error("Failed to marshal stream response", zap.Error(err))
+		if messagesStream.Delta == nil {
 			continue
 		}
 
-		_, writeErr := fmt.Fprintf(w, "data: %s\n\n", string(data))
-		if writeErr != nil {
-			h.logger.Error("Failed to write stream data", zap.Error(writeErr))
+		if writeErr := h.writeStreamEvents(w, flusher, messagesStreamEvent{messagesStream.Type, map[string]interface{}{
+			"index": 0,
+			"delta": messagesStream.Delta,
+		}}); writeErr != nil {
+			h.requestLogger(r.Context()).Error("Failed to write stream data", zap.Error(writeErr))
 			break
 		}
-		flusher.Flush()
+		meter.Chunk()
 
-		// Track token usage estimation
-		if len(streamResponse.Choices) > 0 && streamResponse.Choices[0].Delta.Content != nil {
-			content := fmt.Sprintf("%v", streamResponse.Choices[0].Delta.Content)
-			completionTokens += int64(len(content) / 4)
+		if firstChunk {
+			firstChunk = false
+			ttft := time.Since(startTime)
+			h.modelManager.RecordFirstToken(instance, ttft)
+			middleware.SetTimeToFirstToken(r.Context(), ttft)
 		}
+
+		// Track token usage estimation
+		content := fmt.Sprintf("%v", streamResponse.Choices[0].Delta.Content)
+		completionTokens += int64(contextwindow.EstimateText(content))
 	}
 
-	// Send final done message
-	_, _ = fmt.Fprintf(w, "data: [DONE]\n\n")
-	flusher.Flush()
+	if err := h.writeStreamEvents(w, flusher,
+		messagesStreamEvent{"content_block_stop", map[string]interface{}{"index": 0}},
+		messagesStreamEvent{"message_delta", map[string]interface{}{
+			"delta": map[string]interface{}{"stop_reason": stopReason, "stop_sequence": nil},
+			"usage": map[string]interface{}{"output_tokens": completionTokens},
+		}},
+		messagesStreamEvent{"message_stop", map[string]interface{}{}},
+	); err != nil {
+		h.requestLogger(r.Context()).Error("Failed to write stream data", zap.Error(err))
+	}
+
+	streamStats := meter.Finish(completionTokens)
+	middleware.SetStreamStats(r.Context(), streamStats.TokensPerSecond, streamStats.MaxChunkGap, streamStats.Stalled)
 
 	latency := time.Since(startTime)
 	latencyMs := latency.Milliseconds()
+	totalTokens := promptTokens + completionTokens
 
 	// Record successful streaming request
 	instance.RecordRequest(int32(totalTokens), latencyMs)
@@ -252,12 +307,35 @@ func (h *MessagesHandler) handleStreamingMessages(w http.ResponseWriter, r *http
 			totalTokens, promptTokens, completionTokens, estimatedCost, true)
 	}
 
-	h.logger.Info("Streaming completed",
+	h.requestLogger(r.Context()).Info("Streaming completed",
 		zap.String("model", request.Model),
 		zap.Int64("completion_tokens", completionTokens),
 		zap.Int64("latency_ms", latencyMs))
 }
 
+// messagesStreamEvent is a server-sent event of the Messages API; its data
+// gets the event name as its type
+type messagesStreamEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+// writeStreamEvents writes events in the Anthropic SSE format and flushes
+func (h *MessagesHandler) writeStreamEvents(w http.ResponseWriter, flusher http.Flusher, events ...messagesStreamEvent) error {
+	for _, event := range events {
+		event.data["type"] = event.name
+		data, err := json.Marshal(event.data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, data); err != nil {
+			return err
+		}
+	}
+	flusher.Flush()
+	return nil
+}
+
 func (h *MessagesHandler) convertMessagesAPIToOpenAI(req *providers.MessagesAPIRequest) (*providers.ChatRequest, error) {
 	chatReq := &providers.ChatRequest{
 		Model:       req.Model,
@@ -324,28 +402,13 @@ func (h *MessagesHandler) convertOpenAIToMessagesAPI(resp *providers.ChatRespons
 		}
 	}
 
-	// Map finish reason
-	stopReason := "end_turn"
-	if choice.FinishReason != "" {
-		switch choice.FinishReason {
-		case "stop":
-			stopReason = "end_turn"
-		case "length", "max_tokens":
-			stopReason = "max_tokens"
-		case "tool_calls":
-			stopReason = "tool_use"
-		default:
-			stopReason = choice.FinishReason
-		}
-	}
-
 	return &providers.MessagesAPIResponse{
 		ID:         providers.GenerateMessagesAPIID(),
 		Type:       "message",
 		Role:       "assistant",
 		Content:    content,
 		Model:      req.Model,
-		StopReason: stopReason,
+		StopReason: messagesStopReason(choice.FinishReason),
 		Usage: providers.MessagesAPIUsage{
 			InputTokens:  resp.Usage.PromptTokens,
 			OutputTokens: resp.Usage.CompletionTokens,
@@ -353,6 +416,19 @@ func (h *MessagesHandler) convertOpenAIToMessagesAPI(resp *providers.ChatRespons
 	}, nil
 }
 
+// messagesStopReason maps an OpenAI finish reason to an Anthropic stop reason
+func messagesStopReason(finishReason string) string {
+	switch finishReason {
+	case "", "stop":
+		return "end_turn"
+	case "length", "max_tokens":
+		return "max_tokens"
+	case "tool_calls":
+		return "tool_use"
+	}
+	return finishReason
+}
+
 func (h *MessagesHandler) convertOpenAIStreamToMessagesAPI(stream providers.StreamResponse, req *providers.MessagesAPIRequest) (*providers.MessagesAPIStreamResponse, error) {
 	// Convert OpenAI stream format to Messages API stream format
 	messagesStream := &providers.MessagesAPIStreamResponse{
@@ -375,15 +451,12 @@ func (h *MessagesHandler) convertOpenAIStreamToMessagesAPI(stream providers.Stre
 	return messagesStream, nil
 }
 
+// requestLogger returns the handler logger decorated with the request's log fields
+func (h *MessagesHandler) requestLogger(ctx context.Context) *zap.Logger {
+	return pkglogger.FromContext(ctx, h.logger)
+}
+
+// sendError writes an error in the Anthropic error envelope
 func (h *MessagesHandler) sendError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
-		Error: providers.APIError{
-			Message: message,
-			Type:    "invalid_request_error",
-		},
-	}); err != nil {
-		h.logger.Error("Failed to encode messages error response", zap.Error(err))
-	}
+	middleware.WriteAnthropicError(w, status, message)
 }
\ No newline at end of file
@@ -3,13 +3,17 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
+	"github.com/amerfu/pllm/internal/core/config"
 	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
 	"github.com/amerfu/pllm/internal/infrastructure/testutil"
@@ -613,4 +617,69 @@ func floatPtr(f float32) *float32 {
 
 func intPtr(i int) *int {
 	return &i
-}
\ No newline at end of file
+}
+
+func TestMessagesHandler_StreamEventGrammar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"c","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}`,
+			`{"id":"c","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":" there"}}]}`,
+			`{"id":"c","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`,
+		} {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	modelManager := createMockModelManager()
+	require.NoError(t, modelManager.AddInstance(config.ModelInstance{
+		ID:        "claude-instance",
+		ModelName: "claude",
+		Enabled:   true,
+		Provider:  config.ProviderParams{Type: "openai", Model: "claude-model", APIKey: "test-key", BaseURL: server.URL},
+	}))
+	handler := middleware.AnthropicCompat(http.HandlerFunc(NewMessagesHandler(zap.NewNop(), modelManager).AnthropicMessages))
+
+	body := `{"model":"claude","max_tokens":10,"stream":true,"messages":[{"role":"user","content":"Hi"}]}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.NotContains(t, rec.Body.String(), "[DONE]")
+
+	var names []string
+	var events []map[string]interface{}
+	for _, block := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n") {
+		lines := strings.SplitN(block, "\n", 2)
+		require.Len(t, lines, 2, block)
+		name := strings.TrimPrefix(lines[0], "event: ")
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &data))
+		assert.Equal(t, name, data["type"], "every event names its type")
+		names = append(names, name)
+		events = append(events, data)
+	}
+	assert.Equal(t, []string{"message_start", "content_block_start", "ping", "content_block_delta",
+		"content_block_delta", "content_block_stop", "message_delta", "message_stop"}, names)
+
+	message := events[0]["message"].(map[string]interface{})
+	assert.Equal(t, "claude", message["model"])
+	assert.Contains(t, message, "stop_reason")
+	assert.Nil(t, message["stop_reason"])
+	assert.Equal(t, map[string]interface{}{"type": "text_delta", "text": " there"}, events[4]["delta"])
+	assert.Equal(t, float64(0), events[4]["index"])
+	assert.Equal(t, "max_tokens", events[6]["delta"].(map[string]interface{})["stop_reason"])
+}
+
+func TestMessagesHandler_ErrorEnvelope(t *testing.T) {
+	handler := middleware.AnthropicCompat(http.HandlerFunc(NewMessagesHandler(zap.NewNop(), createMockModelManager()).AnthropicMessages))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/messages",
+		strings.NewReader(`{"model":"missing","max_tokens":10,"messages":[{"role":"user","content":"Hi"}]}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"type":"error","error":{"type":"overloaded_error","message":"No instance available for model: missing"}}`,
+		rec.Body.String())
+}
@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
 	"github.com/amerfu/pllm/internal/services/llm/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
@@ -16,6 +20,7 @@ type ModelsHandler struct {
 	modelManager   *models.ModelManager
 	pricingManager *config.ModelPricingManager
 	metricsEmitter *metrics.MetricEventEmitter
+	capabilities   *CapabilitiesHandler // Per-key entitlements of GetModel
 }
 
 func NewModelsHandler(logger *zap.Logger, modelManager *models.ModelManager, pricingManager *config.ModelPricingManager) *ModelsHandler {
@@ -35,6 +40,12 @@ func NewModelsHandlerWithMetrics(logger *zap.Logger, modelManager *models.ModelM
 	}
 }
 
+// SetCapabilities reports the model capabilities and the calling key's
+// entitlements in GetModel
+func (h *ModelsHandler) SetCapabilities(capabilities *CapabilitiesHandler) {
+	h.capabilities = capabilities
+}
+
 // ListModels lists available models
 // @Summary List available models
 // @Description Lists all available models from configured providers with pricing information
@@ -94,19 +105,75 @@ func (h *ModelsHandler) ListModels(w http.ResponseWriter, r *http.Request) {
 
 // GetModel retrieves a specific model
 // @Summary Get model
-// @Description Retrieves details about a specific model
+// @Description Retrieves a model with its capabilities and the calling key's permission: whether it may use the model, the rate limits that apply and the price it pays after the billing markup
 // @Tags Models
 // @Accept json
 // @Produce json
 // @Param X-API-Key header string false "API Key for authentication"
 // @Param Authorization header string false "Bearer token for authentication"
 // @Param model path string true "Model ID"
-// @Success 200 {object} map[string]interface{}
+// @Success 200 {object} ModelDetails
 // @Failure 401 {object} providers.ErrorResponse
 // @Failure 404 {object} providers.ErrorResponse
 // @Router /models/{model} [get]
 func (h *ModelsHandler) GetModel(w http.ResponseWriter, r *http.Request) {
-	h.sendError(w, http.StatusNotImplemented, "Get model endpoint not yet implemented")
+	if h.capabilities == nil {
+		h.sendError(w, http.StatusNotImplemented, "Get model endpoint not available")
+		return
+	}
+
+	model := chi.URLParam(r, "model")
+	key, _ := middleware.GetKey(r.Context())
+	details, ok := h.capabilities.modelDetails(key, model)
+	if !ok {
+		h.sendError(w, http.StatusNotFound, fmt.Sprintf("The model '%s' does not exist", model))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(details); err != nil {
+		h.logger.Error("Failed to encode model response", zap.Error(err))
+	}
+}
+
+// Catalog lists every model with its documentation, capabilities and the
+// calling key's permission, so users can tell which gateway model to pick
+// @Summary Model catalog
+// @Description Lists the models with their description, intended use, owner contacts and links, their capabilities and the calling key's permission. allowed=true leaves out the models the key may not use.
+// @Tags Models
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param allowed query bool false "Only the models the key may use"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} providers.ErrorResponse
+// @Router /models/catalog [get]
+func (h *ModelsHandler) Catalog(w http.ResponseWriter, r *http.Request) {
+	if h.capabilities == nil {
+		h.sendError(w, http.StatusNotImplemented, "Model catalog not available")
+		return
+	}
+
+	key, _ := middleware.GetKey(r.Context())
+	catalog := h.capabilities.catalog(key)
+	if r.URL.Query().Get("allowed") == "true" {
+		allowed := catalog[:0]
+		for _, model := range catalog {
+			if model.Permission.Allowed {
+				allowed = append(allowed, model)
+			}
+		}
+		catalog = allowed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": "list",
+		"data":   catalog,
+	}); err != nil {
+		h.logger.Error("Failed to encode model catalog response", zap.Error(err))
+	}
 }
 
 func (h *ModelsHandler) sendError(w http.ResponseWriter, status int, message string) {
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// maxFormFieldBytes bounds each non-file field of a multipart upload
+const maxFormFieldBytes = 1 << 20
+
+// multipartUpload is a multipart form read part by part. The file part is
+// spooled to a temporary file instead of memory, so an upload costs the
+// gateway a copy buffer whatever its size; fields may come before or after
+// the file. The request body size limit bounds the whole form.
+type multipartUpload struct {
+	fields      map[string]string
+	file        *os.File
+	filename    string
+	contentType string
+	size        int64
+}
+
+// readMultipartUpload reads a multipart form whose file is in fileField.
+// Other file parts are skipped. The caller must Close the upload.
+func readMultipartUpload(r *http.Request, fileField string) (*multipartUpload, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &multipartUpload{fields: make(map[string]string)}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			upload.Close()
+			return nil, err
+		}
+
+		name := part.FormName()
+		switch {
+		case name == fileField && part.FileName() != "" && upload.file == nil:
+			upload.filename = part.FileName()
+			upload.contentType = part.Header.Get("Content-Type")
+			err = upload.spool(part)
+		case part.FileName() == "":
+			var value []byte
+			value, err = io.ReadAll(io.LimitReader(part, maxFormFieldBytes+1))
+			if err == nil && len(value) > maxFormFieldBytes {
+				err = fmt.Errorf("form field %q exceeds %d bytes", name, maxFormFieldBytes)
+			}
+			if _, seen := upload.fields[name]; !seen {
+				upload.fields[name] = string(value)
+			}
+		default:
+			_, err = io.Copy(io.Discard, part)
+		}
+		_ = part.Close()
+		if err != nil {
+			upload.Close()
+			return nil, err
+		}
+	}
+	return upload, nil
+}
+
+// spool copies the file part to a temporary file and rewinds it
+func (u *multipartUpload) spool(part io.Reader) error {
+	file, err := os.CreateTemp("", "pllm-upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create upload file: %w", err)
+	}
+	u.file = file
+
+	if u.size, err = io.Copy(file, part); err != nil {
+		return err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	return err
+}
+
+// Value returns the first value of a form field, or ""
+func (u *multipartUpload) Value(name string) string {
+	return u.fields[name]
+}
+
+// Close removes the spooled file
+func (u *multipartUpload) Close() {
+	if u.file == nil {
+		return
+	}
+	_ = u.file.Close()
+	_ = os.Remove(u.file.Name())
+	u.file = nil
+}
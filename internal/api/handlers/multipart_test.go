@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+)
+
+func newMultipartRequest(t *testing.T, write func(*multipart.Writer)) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	write(writer)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestReadMultipartUpload(t *testing.T) {
+	req := newMultipartRequest(t, func(w *multipart.Writer) {
+		require.NoError(t, w.WriteField("model", "whisper-1"))
+		part, err := w.CreateFormFile("file", "speech.mp3")
+		require.NoError(t, err)
+		_, _ = part.Write([]byte("audio bytes"))
+		// Fields may follow the file
+		require.NoError(t, w.WriteField("language", "en"))
+	})
+
+	upload, err := readMultipartUpload(req, "file")
+	require.NoError(t, err)
+
+	assert.Equal(t, "whisper-1", upload.Value("model"))
+	assert.Equal(t, "en", upload.Value("language"))
+	assert.Equal(t, "speech.mp3", upload.filename)
+	assert.Equal(t, int64(11), upload.size)
+	require.NotNil(t, upload.file)
+	data, err := io.ReadAll(upload.file)
+	require.NoError(t, err)
+	assert.Equal(t, "audio bytes", string(data))
+
+	spooled := upload.file.Name()
+	upload.Close()
+	_, err = os.Stat(spooled)
+	assert.True(t, os.IsNotExist(err), "the spooled file is removed")
+}
+
+func TestReadMultipartUpload_TooLarge(t *testing.T) {
+	req := newMultipartRequest(t, func(w *multipart.Writer) {
+		part, err := w.CreateFormFile("file", "speech.mp3")
+		require.NoError(t, err)
+		_, _ = part.Write([]byte(strings.Repeat("a", 4096)))
+	})
+	req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 1024)
+
+	_, err := readMultipartUpload(req, "file")
+	limit, ok := middleware.BodyTooLarge(err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1024), limit)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	modelsService "github.com/amerfu/pllm/internal/services/llm/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
 	"github.com/amerfu/pllm/internal/services/llm/realtime"
@@ -23,6 +24,7 @@ type RealtimeHandler struct {
 	sessionManager *realtime.SessionManager
 	modelManager   *modelsService.ModelManager
 	upgrader       websocket.Upgrader
+	budget         *realtime.BudgetEnforcer
 }
 
 // RealtimeHandlerConfig holds configuration for the realtime handler
@@ -74,6 +76,12 @@ func NewRealtimeHandler(
 	}
 }
 
+// SetBudgetEnforcer meters sessions and closes them when the key or team
+// budget runs out
+func (h *RealtimeHandler) SetBudgetEnforcer(budget *realtime.BudgetEnforcer) {
+	h.budget = budget
+}
+
 // ConnectRealtime handles WebSocket upgrade for realtime connections
 // @Summary Connect to realtime API
 // @Description Establish WebSocket connection for real-time conversation
@@ -87,6 +95,7 @@ func NewRealtimeHandler(
 // @Failure 400 {object} providers.ErrorResponse "Bad Request"
 // @Failure 401 {object} providers.ErrorResponse "Unauthorized"
 // @Failure 403 {object} providers.ErrorResponse "Forbidden"
+// @Failure 429 {object} providers.ErrorResponse "Budget exceeded"
 // @Failure 500 {object} providers.ErrorResponse "Internal Server Error"
 // @Router /v1/realtime [get]
 func (h *RealtimeHandler) ConnectRealtime(w http.ResponseWriter, r *http.Request) {
@@ -102,6 +111,26 @@ func (h *RealtimeHandler) ConnectRealtime(w http.ResponseWriter, r *http.Request
 	// TODO: Extract from auth context when auth is implemented
 	// For now, allow anonymous connections for testing
 
+	// Sessions are billed to the authenticated key, team or user
+	owner := realtimeOwner(r.Context())
+	if h.budget != nil {
+		if entityType, ok := h.budget.CheckBudget(r.Context(), owner); !ok {
+			h.logger.Warn("Realtime connection rejected, budget exceeded",
+				zap.String("model", model),
+				zap.String("entity_type", entityType))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = json.NewEncoder(w).Encode(providers.ErrorResponse{
+				Error: providers.APIError{
+					Message: "Budget limit exceeded. Please contact your administrator or upgrade your plan.",
+					Type:    "insufficient_quota",
+					Code:    "budget_exceeded",
+				},
+			})
+			return
+		}
+	}
+
 	// Generate session ID
 	sessionID := uuid.New().String()
 
@@ -130,6 +159,7 @@ func (h *RealtimeHandler) ConnectRealtime(w http.ResponseWriter, r *http.Request
 		h.sendErrorToClient(clientConn, "model_not_available", fmt.Sprintf("Model %s is not available", model), "")
 		return
 	}
+	defer instance.Begin()() // The session holds the instance until it closes
 
 	// Check if provider supports realtime
 	realtimeProvider, err := providers.GetRealtimeProvider(instance.Provider)
@@ -194,6 +224,21 @@ func (h *RealtimeHandler) ConnectRealtime(w http.ResponseWriter, r *http.Request
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
+	// Meter the session against the owner's budgets
+	var metered *realtime.MeteredSession
+	if h.budget != nil {
+		metered = &realtime.MeteredSession{
+			ID:            sessionID,
+			Model:         model,
+			ProviderModel: instance.Config.Provider.Model,
+			Provider:      instance.Provider.GetType(),
+			Path:          r.URL.Path,
+			Owner:         owner,
+			Meter:         realtime.NewMeter(session.GetConfig()),
+		}
+		messageHandler.SetObserver(metered.Meter)
+	}
+
 	if err := messageHandler.Start(ctx); err != nil {
 		h.logger.Error("Failed to start message handler",
 			zap.String("session_id", sessionID),
@@ -221,12 +266,38 @@ func (h *RealtimeHandler) ConnectRealtime(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	var watched chan struct{}
+	if metered != nil {
+		watched = make(chan struct{})
+		go func() {
+			defer close(watched)
+			h.budget.Watch(ctx, metered, func(entityType string) {
+				messageHandler.Terminate("budget_exceeded",
+					fmt.Sprintf("The %s budget is exhausted; the session was closed", entityType),
+					websocket.ClosePolicyViolation)
+				cancel()
+			})
+		}()
+	}
+
 	// Keep connection alive until context is cancelled or connection is closed
-	<-ctx.Done()
+	select {
+	case <-ctx.Done():
+	case <-messageHandler.Done():
+	}
+	cancel()
+
+	// Record the rest of the session's usage
+	var cost float64
+	if watched != nil {
+		<-watched
+		cost = metered.RecordedCost()
+	}
 
 	h.logger.Info("Realtime session ended",
 		zap.String("session_id", sessionID),
-		zap.String("model", model))
+		zap.String("model", model),
+		zap.Float64("cost", cost))
 }
 
 // CreateSession creates a new realtime session (HTTP endpoint)
@@ -391,6 +462,44 @@ func (h *RealtimeHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// realtimeOwner returns who a session is billed to, following the ownership
+// rules of the usage records of regular requests
+func realtimeOwner(ctx context.Context) realtime.Owner {
+	owner := realtime.Owner{Unlimited: middleware.IsMasterKey(ctx)}
+	if client, ok := middleware.GetClientInfo(ctx); ok {
+		owner.Country = client.Location.Country
+		owner.Continent = client.Location.Continent
+		owner.ClientName = client.Client.Name
+		owner.ClientVersion = client.Client.Version
+	}
+	userID, hasUser := middleware.GetUserID(ctx)
+	if hasUser {
+		owner.UserID = userID.String()
+		owner.ActualUserID = userID.String()
+	}
+
+	key, hasKey := middleware.GetKey(ctx)
+	if !hasKey || key == nil {
+		return owner
+	}
+	owner.KeyID = key.ID.String()
+	owner.KeyType = string(key.Type)
+	owner.Environment = key.Environment
+	if key.UserID != nil {
+		owner.KeyOwnerID = key.UserID.String()
+		if key.TeamID == nil || !hasUser {
+			owner.UserID = key.UserID.String()
+		}
+		if !hasUser {
+			owner.ActualUserID = key.UserID.String()
+		}
+	}
+	if key.TeamID != nil {
+		owner.TeamID = key.TeamID.String()
+	}
+	return owner
+}
+
 // sendErrorToClient sends an error event to the WebSocket client
 func (h *RealtimeHandler) sendErrorToClient(conn *websocket.Conn, code, message, param string) {
 	errorEvent := &models.ErrorEvent{
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+	"go.uber.org/zap"
+)
+
+// Rerank orders documents by their relevance to a query
+// @Summary Rerank documents
+// @Description Scores documents by relevance to a query, most relevant first. Served by providers with a rerank API (Cohere).
+// @Tags Embeddings
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string false "API Key for authentication"
+// @Param Authorization header string false "Bearer token for authentication"
+// @Param request body providers.RerankRequest true "Rerank request"
+// @Success 200 {object} providers.RerankResponse
+// @Failure 400 {object} providers.ErrorResponse
+// @Failure 401 {object} providers.ErrorResponse
+// @Failure 500 {object} providers.ErrorResponse
+// @Router /rerank [post]
+func (h *EmbeddingsHandler) Rerank(w http.ResponseWriter, r *http.Request) {
+	var request providers.RerankRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if request.Query == "" {
+		h.sendError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+	if len(request.Documents) == 0 {
+		h.sendError(w, http.StatusBadRequest, "documents must not be empty")
+		return
+	}
+	if request.TopN != nil && *request.TopN <= 0 {
+		h.sendError(w, http.StatusBadRequest, "top_n must be a positive integer")
+		return
+	}
+
+	instance, err := h.modelManager.GetBestInstanceAdaptive(r.Context(), request.Model)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("Model not available: %s", err.Error()))
+		return
+	}
+	defer instance.Begin()()
+	reranker, ok := providerFor(r.Context(), h.modelManager, instance).(providers.Reranker)
+	if !ok {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("Model %s does not support reranking", request.Model))
+		return
+	}
+
+	response, err := reranker.Rerank(r.Context(), &request)
+	if err != nil {
+		h.logger.Error("Rerank request failed", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	middleware.SetResolvedModel(r.Context(),
+		instance.Config.ModelName,
+		instance.Config.Provider.Model,
+		instance.Config.Provider.Type,
+		"",
+	)
+	middleware.SetTokenUsage(r.Context(), response.Usage.PromptTokens, 0, 0)
+	searches := response.Usage.SearchUnits
+	if searches == 0 {
+		searches = 1
+	}
+	middleware.SetRerankUsage(r.Context(), searches)
+
+	if request.ReturnDocuments {
+		for i := range response.Results {
+			if index := response.Results[i].Index; index >= 0 && index < len(request.Documents) {
+				response.Results[i].Document = &request.Documents[index]
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode rerank response", zap.Error(err))
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+)
+
+// sseKeepAlive is an SSE comment, which clients ignore
+const sseKeepAlive = ": keepalive\n\n"
+
+// keepAliveWriter writes a keep-alive comment to a streaming client whenever
+// nothing has been written for the meter's keep-alive interval, so load
+// balancers with idle timeouts don't cut off models that think for a long
+// time before or between chunks. Writes of the handler and of the timer are
+// serialized.
+type keepAliveWriter struct {
+	http.ResponseWriter
+	flusher  http.Flusher
+	meter    *llmModels.StreamMeter
+	interval time.Duration
+
+	mu        sync.Mutex
+	timer     *time.Timer
+	lastWrite time.Time
+	stopped   bool
+}
+
+// startKeepAlive wraps a streaming response; the handler writes through the
+// returned writer and stops it when the stream ends
+func startKeepAlive(w http.ResponseWriter, flusher http.Flusher, meter *llmModels.StreamMeter) *keepAliveWriter {
+	k := &keepAliveWriter{
+		ResponseWriter: w,
+		flusher:        flusher,
+		meter:          meter,
+		interval:       meter.KeepAliveInterval(),
+		lastWrite:      time.Now(),
+	}
+	if k.interval > 0 {
+		k.mu.Lock()
+		k.timer = time.AfterFunc(k.interval, k.ping)
+		k.mu.Unlock()
+	}
+	return k
+}
+
+func (k *keepAliveWriter) Write(p []byte) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.lastWrite = time.Now()
+	return k.ResponseWriter.Write(p)
+}
+
+func (k *keepAliveWriter) Flush() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.flusher.Flush()
+}
+
+// Stop stops the keep-alives
+func (k *keepAliveWriter) Stop() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.stopped = true
+	if k.timer != nil {
+		k.timer.Stop()
+	}
+}
+
+func (k *keepAliveWriter) ping() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.stopped {
+		return
+	}
+	if quiet := time.Since(k.lastWrite); quiet < k.interval {
+		k.timer.Reset(k.interval - quiet)
+		return
+	}
+	// The meter turns keep-alives down once the backend is given up on
+	if !k.meter.KeepAlive() {
+		return
+	}
+	if _, err := k.ResponseWriter.Write([]byte(sseKeepAlive)); err != nil {
+		return
+	}
+	k.flusher.Flush()
+	k.lastWrite = time.Now()
+	k.timer.Reset(k.interval)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+)
+
+func TestKeepAliveWriter(t *testing.T) {
+	manager := llmModels.NewModelManager(zap.NewNop(), config.RouterSettings{StreamKeepAliveInterval: 20 * time.Millisecond}, nil)
+	instance := &llmModels.ModelInstance{Config: config.ModelInstance{ID: "reasoner", ModelName: "reasoner"}}
+	instance.Healthy.Store(true)
+	meter := manager.StartStreamMeter(instance)
+
+	rec := httptest.NewRecorder()
+	w := startKeepAlive(rec, rec, meter)
+	time.Sleep(50 * time.Millisecond) // Thinking before the first chunk
+	_, _ = fmt.Fprint(w, "data: {}\n\n")
+	w.Flush()
+	meter.Chunk()
+	w.Stop()
+	time.Sleep(40 * time.Millisecond)
+
+	body := rec.Body.String()
+	assert.True(t, strings.HasPrefix(body, sseKeepAlive), body)
+	assert.True(t, strings.HasSuffix(body, "data: {}\n\n"), "no keep-alives once stopped")
+	assert.Equal(t, strings.Count(body, sseKeepAlive), meter.Finish(0).KeepAlives)
+}
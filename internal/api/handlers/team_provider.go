@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// providerFor returns the provider a request on instance is sent with: the
+// team's own key for the instance's provider type when the team brought
+// one, otherwise the instance's shared provider. Requests sent with a team
+// key are tagged so their cost is attributed to the team's account.
+func providerFor(ctx context.Context, manager *llmModels.ModelManager, instance *llmModels.ModelInstance) providers.Provider {
+	teamID, _ := middleware.GetTeamID(ctx)
+	provider, keyID := manager.ProviderFor(ctx, teamID, instance)
+	if keyID != uuid.Nil {
+		middleware.SetProviderKey(ctx, keyID)
+	}
+	return provider
+}
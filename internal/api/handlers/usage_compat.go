@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+const (
+	// usageBucketSeconds is the aggregation window of /v1/usage, as in OpenAI's
+	usageBucketSeconds = 300
+	// maxBillingUsageDays bounds a /dashboard/billing/usage range, as in OpenAI's
+	maxBillingUsageDays = 100
+)
+
+// UsageCompatHandler serves the unofficial OpenAI usage and billing endpoints
+// that dashboards and cost trackers call, from the usage logs. Usage is
+// scoped to the caller: the team of a team key, the owner of a personal key,
+// the signed-in user, or everything for the master key.
+type UsageCompatHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewUsageCompatHandler(logger *zap.Logger, db *gorm.DB) *UsageCompatHandler {
+	return &UsageCompatHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// usageScope is the usage a caller may see. An empty column means all usage.
+type usageScope struct {
+	column string
+	id     uuid.UUID
+	key    *models.Key
+}
+
+func usageScopeFromContext(ctx context.Context) (usageScope, bool) {
+	if middleware.IsMasterKey(ctx) {
+		return usageScope{}, true
+	}
+	if key, ok := middleware.GetKey(ctx); ok && key != nil {
+		switch {
+		case key.TeamID != nil:
+			return usageScope{column: "team_id", id: *key.TeamID, key: key}, true
+		case key.UserID != nil:
+			return usageScope{column: "user_id", id: *key.UserID, key: key}, true
+		default:
+			return usageScope{column: "key_id", id: key.ID, key: key}, true
+		}
+	}
+	if userID, ok := middleware.GetUserID(ctx); ok {
+		return usageScope{column: "user_id", id: userID}, true
+	}
+	return usageScope{}, false
+}
+
+func (s usageScope) apply(query *gorm.DB) *gorm.DB {
+	if s.column == "" {
+		return query
+	}
+	return query.Where(s.column+" = ?", s.id)
+}
+
+// usageRow is an aggregate of successful requests. Sampled rows are weighted
+// by the number of requests they stand for.
+type usageRow struct {
+	Bucket       time.Time
+	Model        string
+	Path         string
+	Requests     int64
+	InputTokens  int64
+	OutputTokens int64
+	AudioSeconds float64
+	Cost         float64
+}
+
+func (h *UsageCompatHandler) queryUsage(ctx context.Context, scope usageScope, bucket string, start, end time.Time) ([]usageRow, error) {
+	var rows []usageRow
+	query := h.db.WithContext(ctx).Model(&models.Usage{}).
+		Select(bucket+` AS bucket, model, path,
+			SUM(sample_rate) AS requests,
+			SUM(input_tokens * sample_rate) AS input_tokens,
+			SUM(output_tokens * sample_rate) AS output_tokens,
+			SUM(audio_seconds * sample_rate) AS audio_seconds,
+			SUM(total_cost * sample_rate) AS cost`).
+		Where("timestamp >= ? AND timestamp < ? AND status_code < ?", start, end, http.StatusBadRequest).
+		Group("1, model, path").
+		Order("1")
+	err := scope.apply(query).Scan(&rows).Error
+	return rows, err
+}
+
+// GetUsage serves GET /v1/usage?date=YYYY-MM-DD (default today, UTC)
+func (h *UsageCompatHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	scope, ok := usageScopeFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	if date := r.URL.Query().Get("date"); date != "" {
+		parsed, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+			return
+		}
+		day = parsed
+	}
+
+	bucket := fmt.Sprintf("to_timestamp(floor(extract(epoch from timestamp) / %d) * %d)", usageBucketSeconds, usageBucketSeconds)
+	rows, err := h.queryUsage(r.Context(), scope, bucket, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		h.logger.Error("Failed to query usage", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to query usage")
+		return
+	}
+
+	h.writeJSON(w, buildUsageResponse(rows))
+}
+
+// GetBillingUsage serves GET /v1/dashboard/billing/usage with start_date
+// (inclusive) and end_date (exclusive); costs are in cents
+func (h *UsageCompatHandler) GetBillingUsage(w http.ResponseWriter, r *http.Request) {
+	scope, ok := usageScopeFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := now.Truncate(24*time.Hour).AddDate(0, 0, 1)
+	var err error
+	if v := r.URL.Query().Get("start_date"); v != "" {
+		if start, err = time.Parse(time.DateOnly, v); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid start_date, expected YYYY-MM-DD")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("end_date"); v != "" {
+		if end, err = time.Parse(time.DateOnly, v); err != nil {
+			h.sendError(w, http.StatusBadRequest, "Invalid end_date, expected YYYY-MM-DD")
+			return
+		}
+	}
+	if !end.After(start) {
+		h.sendError(w, http.StatusBadRequest, "end_date must be after start_date")
+		return
+	}
+	if end.Sub(start) > maxBillingUsageDays*24*time.Hour {
+		h.sendError(w, http.StatusBadRequest, fmt.Sprintf("Usage can be queried for at most %d days", maxBillingUsageDays))
+		return
+	}
+
+	rows, err := h.queryUsage(r.Context(), scope, "date_trunc('day', timestamp)", start, end)
+	if err != nil {
+		h.logger.Error("Failed to query billing usage", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to query usage")
+		return
+	}
+
+	h.writeJSON(w, buildBillingUsageResponse(rows))
+}
+
+// GetSubscription serves GET /v1/dashboard/billing/subscription from the
+// caller's budget. Limits are 0 when the budget is unlimited.
+func (h *UsageCompatHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	scope, ok := usageScopeFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	var hardLimit, softLimit float64
+	var accessUntil time.Time
+	var accountName string
+	switch scope.column {
+	case "team_id":
+		var team models.Team
+		if err := h.db.WithContext(r.Context()).First(&team, "id = ?", scope.id).Error; err != nil {
+			h.logger.Error("Failed to load team budget", zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "Failed to load budget")
+			return
+		}
+		hardLimit = team.MaxBudget
+		softLimit = team.MaxBudget * team.BudgetAlertAt / 100
+		accessUntil = team.BudgetResetAt
+		accountName = team.Name
+	case "user_id":
+		var user models.User
+		if err := h.db.WithContext(r.Context()).First(&user, "id = ?", scope.id).Error; err != nil {
+			h.logger.Error("Failed to load user budget", zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "Failed to load budget")
+			return
+		}
+		hardLimit, softLimit = user.MaxBudget, user.MaxBudget
+		accessUntil = user.BudgetResetAt
+		accountName = user.Email
+	case "key_id":
+		accountName = scope.key.Name
+	}
+	// A key's own budget applies when it is tighter than its team's or user's
+	if key := scope.key; key != nil {
+		if maxBudget := key.GetMaxBudget(); maxBudget != nil && *maxBudget > 0 &&
+			(hardLimit <= 0 || *maxBudget < hardLimit) {
+			hardLimit, softLimit = *maxBudget, *maxBudget
+			if key.BudgetResetAt != nil {
+				accessUntil = *key.BudgetResetAt
+			}
+		}
+	}
+
+	var until int64
+	if !accessUntil.IsZero() {
+		until = accessUntil.Unix()
+	}
+	h.writeJSON(w, map[string]interface{}{
+		"object":                "billing_subscription",
+		"has_payment_method":    true,
+		"canceled":              false,
+		"canceled_at":           nil,
+		"delinquent":            nil,
+		"access_until":          until,
+		"soft_limit":            int64(math.Round(softLimit * 100)),
+		"hard_limit":            int64(math.Round(hardLimit * 100)),
+		"system_hard_limit":     int64(math.Round(hardLimit * 100)),
+		"soft_limit_usd":        softLimit,
+		"hard_limit_usd":        hardLimit,
+		"system_hard_limit_usd": hardLimit,
+		"plan":                  map[string]string{"title": "pllm", "id": "pllm"},
+		"account_name":          accountName,
+		"po_number":             nil,
+		"billing_email":         nil,
+		"tax_ids":               nil,
+		"billing_address":       nil,
+		"business_address":      nil,
+	})
+}
+
+// usageOperation maps a request path to the /v1/usage section it counts in
+func usageOperation(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/embeddings"):
+		return "embeddings"
+	case strings.HasSuffix(path, "/audio/transcriptions"), strings.HasSuffix(path, "/audio/translations"):
+		return "whisper"
+	case strings.HasSuffix(path, "/chat/completions"), strings.HasSuffix(path, "/completions"),
+		strings.HasSuffix(path, "/messages"), strings.HasSuffix(path, "/chat/completions/compare"):
+		return "completion"
+	default:
+		return ""
+	}
+}
+
+type usageEntry struct {
+	AggregationTimestamp  int64  `json:"aggregation_timestamp"`
+	NRequests             int64  `json:"n_requests"`
+	Operation             string `json:"operation"`
+	SnapshotID            string `json:"snapshot_id"`
+	NContextTokensTotal   int64  `json:"n_context_tokens_total"`
+	NGeneratedTokensTotal int64  `json:"n_generated_tokens_total"`
+}
+
+type whisperUsageEntry struct {
+	Timestamp   int64   `json:"timestamp"`
+	ModelID     string  `json:"model_id"`
+	NumSeconds  float64 `json:"num_seconds"`
+	NumRequests int64   `json:"num_requests"`
+}
+
+func buildUsageResponse(rows []usageRow) map[string]interface{} {
+	type entryKey struct {
+		bucket    int64
+		model     string
+		operation string
+	}
+	data := make([]*usageEntry, 0)
+	entries := make(map[entryKey]*usageEntry)
+	whisper := make([]*whisperUsageEntry, 0)
+	whisperEntries := make(map[entryKey]*whisperUsageEntry)
+
+	// Rows of one bucket and model differ only by path prefix (/v1, /api/v1)
+	for _, row := range rows {
+		operation := usageOperation(row.Path)
+		k := entryKey{bucket: row.Bucket.Unix(), model: row.Model, operation: operation}
+		switch operation {
+		case "completion", "embeddings":
+			entry, ok := entries[k]
+			if !ok {
+				entry = &usageEntry{AggregationTimestamp: k.bucket, Operation: operation, SnapshotID: row.Model}
+				entries[k] = entry
+				data = append(data, entry)
+			}
+			entry.NRequests += row.Requests
+			entry.NContextTokensTotal += row.InputTokens
+			entry.NGeneratedTokensTotal += row.OutputTokens
+		case "whisper":
+			entry, ok := whisperEntries[k]
+			if !ok {
+				entry = &whisperUsageEntry{Timestamp: k.bucket, ModelID: row.Model}
+				whisperEntries[k] = entry
+				whisper = append(whisper, entry)
+			}
+			entry.NumSeconds += row.AudioSeconds
+			entry.NumRequests += row.Requests
+		}
+	}
+
+	return map[string]interface{}{
+		"object":                          "list",
+		"data":                            data,
+		"ft_data":                         []interface{}{},
+		"dalle_api_data":                  []interface{}{},
+		"whisper_api_data":                whisper,
+		"tts_api_data":                    []interface{}{},
+		"assistant_code_interpreter_data": []interface{}{},
+		"retrieval_storage_data":          []interface{}{},
+	}
+}
+
+type billingLineItem struct {
+	Name string  `json:"name"`
+	Cost float64 `json:"cost"` // cents
+}
+
+type billingDailyCost struct {
+	Timestamp float64            `json:"timestamp"`
+	LineItems []*billingLineItem `json:"line_items"`
+}
+
+func buildBillingUsageResponse(rows []usageRow) map[string]interface{} {
+	days := make([]*billingDailyCost, 0)
+	byDay := make(map[int64]map[string]*billingLineItem)
+	dayIndex := make(map[int64]*billingDailyCost)
+	var total float64
+
+	for _, row := range rows {
+		ts := row.Bucket.Unix()
+		day, ok := dayIndex[ts]
+		if !ok {
+			day = &billingDailyCost{Timestamp: float64(ts), LineItems: make([]*billingLineItem, 0)}
+			dayIndex[ts] = day
+			byDay[ts] = make(map[string]*billingLineItem)
+			days = append(days, day)
+		}
+		item, ok := byDay[ts][row.Model]
+		if !ok {
+			item = &billingLineItem{Name: row.Model}
+			byDay[ts][row.Model] = item
+			day.LineItems = append(day.LineItems, item)
+		}
+		item.Cost += row.Cost * 100
+		total += row.Cost * 100
+	}
+
+	for _, day := range days {
+		sort.Slice(day.LineItems, func(i, j int) bool { return day.LineItems[i].Name < day.LineItems[j].Name })
+	}
+	return map[string]interface{}{
+		"object":      "list",
+		"daily_costs": days,
+		"total_usage": total,
+	}
+}
+
+func (h *UsageCompatHandler) writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Error("Failed to encode usage response", zap.Error(err))
+	}
+}
+
+func (h *UsageCompatHandler) sendError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	}); err != nil {
+		h.logger.Error("Failed to encode usage error response", zap.Error(err))
+	}
+}
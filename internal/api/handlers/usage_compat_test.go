@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+)
+
+func TestUsageScopeFromContext(t *testing.T) {
+	teamID := uuid.New()
+	userID := uuid.New()
+
+	teamKey := &models.Key{TeamID: &teamID, UserID: &userID}
+	personalKey := &models.Key{UserID: &userID}
+	systemKey := &models.Key{}
+	systemKey.ID = uuid.New()
+
+	withKey := func(key *models.Key) context.Context {
+		return context.WithValue(context.Background(), middleware.KeyContextKey, key)
+	}
+
+	scope, ok := usageScopeFromContext(withKey(teamKey))
+	require.True(t, ok)
+	assert.Equal(t, "team_id", scope.column)
+	assert.Equal(t, teamID, scope.id)
+
+	scope, _ = usageScopeFromContext(withKey(personalKey))
+	assert.Equal(t, "user_id", scope.column)
+
+	scope, _ = usageScopeFromContext(withKey(systemKey))
+	assert.Equal(t, "key_id", scope.column)
+	assert.Equal(t, systemKey.ID, scope.id)
+
+	scope, ok = usageScopeFromContext(context.WithValue(context.Background(), middleware.UserContextKey, userID))
+	require.True(t, ok)
+	assert.Equal(t, usageScope{column: "user_id", id: userID}, scope)
+
+	scope, ok = usageScopeFromContext(context.WithValue(context.Background(), middleware.AuthTypeContextKey, middleware.AuthTypeMasterKey))
+	require.True(t, ok)
+	assert.Empty(t, scope.column, "the master key sees all usage")
+
+	_, ok = usageScopeFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestBuildUsageResponse(t *testing.T) {
+	bucket := time.Date(2026, 10, 16, 12, 5, 0, 0, time.UTC)
+	rows := []usageRow{
+		{Bucket: bucket, Model: "gpt-4o", Path: "/v1/chat/completions", Requests: 2, InputTokens: 100, OutputTokens: 20},
+		{Bucket: bucket, Model: "gpt-4o", Path: "/api/v1/chat/completions", Requests: 1, InputTokens: 50, OutputTokens: 10},
+		{Bucket: bucket, Model: "text-embedding-3-small", Path: "/v1/embeddings", Requests: 4, InputTokens: 400},
+		{Bucket: bucket, Model: "whisper-1", Path: "/v1/audio/transcriptions", Requests: 1, AudioSeconds: 12.5},
+		{Bucket: bucket, Model: "omni-moderation-latest", Path: "/v1/moderations", Requests: 3},
+	}
+
+	data, err := json.Marshal(buildUsageResponse(rows))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"object": "list",
+		"data": [
+			{"aggregation_timestamp": 1792152300, "n_requests": 3, "operation": "completion", "snapshot_id": "gpt-4o",
+			 "n_context_tokens_total": 150, "n_generated_tokens_total": 30},
+			{"aggregation_timestamp": 1792152300, "n_requests": 4, "operation": "embeddings", "snapshot_id": "text-embedding-3-small",
+			 "n_context_tokens_total": 400, "n_generated_tokens_total": 0}
+		],
+		"ft_data": [],
+		"dalle_api_data": [],
+		"whisper_api_data": [{"timestamp": 1792152300, "model_id": "whisper-1", "num_seconds": 12.5, "num_requests": 1}],
+		"tts_api_data": [],
+		"assistant_code_interpreter_data": [],
+		"retrieval_storage_data": []
+	}`, string(data))
+}
+
+func TestBuildBillingUsageResponse(t *testing.T) {
+	day1 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	rows := []usageRow{
+		{Bucket: day1, Model: "gpt-4o", Path: "/v1/chat/completions", Cost: 0.25},
+		{Bucket: day1, Model: "gpt-4o", Path: "/api/v1/chat/completions", Cost: 0.05},
+		{Bucket: day1, Model: "dall-e-3", Path: "/v1/images/generations", Cost: 0.04},
+		{Bucket: day2, Model: "gpt-4o", Path: "/v1/chat/completions", Cost: 1},
+	}
+
+	response := buildBillingUsageResponse(rows)
+	days := response["daily_costs"].([]*billingDailyCost)
+	require.Len(t, days, 2)
+	assert.Equal(t, float64(day1.Unix()), days[0].Timestamp)
+	require.Len(t, days[0].LineItems, 2)
+	assert.Equal(t, "dall-e-3", days[0].LineItems[0].Name)
+	assert.InDelta(t, 4, days[0].LineItems[0].Cost, 1e-9, "costs are in cents")
+	assert.InDelta(t, 30, days[0].LineItems[1].Cost, 1e-9)
+	assert.InDelta(t, 134, response["total_usage"].(float64), 1e-9)
+}
+
+func TestUsageCompatHandler_Validation(t *testing.T) {
+	handler := NewUsageCompatHandler(zap.NewNop(), nil)
+	userCtx := context.WithValue(context.Background(), middleware.UserContextKey, uuid.New())
+
+	serve := func(h http.HandlerFunc, target string, ctx context.Context) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(handler.GetUsage, "/v1/usage", context.Background()).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(handler.GetUsage, "/v1/usage?date=16-10-2026", userCtx).Code)
+
+	rec := serve(handler.GetBillingUsage, "/v1/dashboard/billing/usage?start_date=2026-10-10&end_date=2026-10-01", userCtx)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "end_date must be after start_date")
+
+	rec = serve(handler.GetBillingUsage, "/v1/dashboard/billing/usage?start_date=2026-01-01&end_date=2026-10-01", userCtx)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "at most 100 days")
+}
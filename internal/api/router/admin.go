@@ -10,9 +10,19 @@ import (
 	"github.com/amerfu/pllm/internal/api/handlers/admin"
 	"github.com/amerfu/pllm/internal/infrastructure/middleware"
 	"github.com/amerfu/pllm/internal/services/data/budget"
+	"github.com/amerfu/pllm/internal/services/data/artifacts"
+	"github.com/amerfu/pllm/internal/services/data/deletion"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"github.com/amerfu/pllm/internal/services/data/settings"
+	"github.com/amerfu/pllm/internal/services/integrations/billing"
+	"github.com/amerfu/pllm/internal/services/integrations/byok"
 	"github.com/amerfu/pllm/internal/services/integrations/guardrails"
+	"github.com/amerfu/pllm/internal/services/integrations/onboarding"
 	"github.com/amerfu/pllm/internal/services/integrations/team"
 	"github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/cluster"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+	"github.com/amerfu/pllm/internal/services/monitoring/privacy"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -29,6 +39,15 @@ type AdminRouterConfig struct {
 	BudgetService       budget.Service
 	GuardrailsExecutor  *guardrails.Executor
 	ModelManager        *models.ModelManager
+	Notifier            *notifications.Hub // Optional, streams dashboard notifications
+	Onboarder           *onboarding.Service
+	UsageCounters       *redisService.UsageCounters // Optional, exact usage totals
+	Credits             *billing.Service            // Optional, prepaid team credits
+	TeamProviderKeys    *byok.Service               // Optional, provider keys teams bring
+	Settings            *settings.Store             // Optional, settings changed at runtime
+	Artifacts           *artifacts.Service          // Optional, artifact storage and lifecycle rules
+	Deletion            *deletion.Service           // Optional, restorable deletes of users, teams and keys
+	Cluster             *cluster.Registrar          // Optional, gateway replicas registered in the coordination backend
 }
 
 // NewAdminSubRouter creates admin routes to be mounted on the main router
@@ -38,6 +57,7 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 
 	// Initialize services
 	teamService := team.NewTeamService(cfg.DB)
+	teamService.SetTemplates(cfg.Config.Onboarding)
 
 	// Initialize handlers
 	authHandler := admin.NewAuthHandler(cfg.Logger, cfg.MasterKeyService, cfg.AuthService, cfg.DB)
@@ -48,23 +68,48 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 		cfg.Config.Auth.Dex.ClientID,
 		cfg.Config.Auth.Dex.ClientSecret,
 	)
+	oauthHandler.SetNotifier(cfg.Notifier)
+	if cfg.Onboarder != nil {
+		oauthHandler.SetOnboarder(cfg.Onboarder)
+	}
 	userHandler := admin.NewUserHandler(cfg.Logger, cfg.DB)
 	teamHandler := admin.NewTeamHandler(cfg.Logger, teamService, cfg.DB, cfg.BudgetService)
 	keyHandler := admin.NewKeyHandler(cfg.Logger, cfg.DB, cfg.BudgetService)
+	var deletionHandler *admin.DeletionHandler
+	if cfg.Deletion != nil {
+		userHandler.SetDeletion(cfg.Deletion)
+		teamHandler.SetDeletion(cfg.Deletion)
+		keyHandler.SetDeletion(cfg.Deletion)
+		deletionHandler = admin.NewDeletionHandler(cfg.Logger, cfg.DB, cfg.Deletion)
+	}
+	anonymizer := privacy.NewAnonymizer(cfg.Config.Analytics.Privacy, cfg.Config.JWT.SecretKey)
 	analyticsHandler := admin.NewAnalyticsHandler(cfg.Logger, cfg.DB, cfg.ModelManager)
+	analyticsHandler.SetAnonymizer(anonymizer)
 	systemHandler := admin.NewSystemHandler(cfg.Logger, cfg.DB)
+	if cfg.Settings != nil {
+		systemHandler.SetSettings(cfg.Settings)
+	}
 	dashboardHandler := handlers.NewDashboardHandler(cfg.DB, cfg.Logger)
+	dashboardHandler.SetAnonymizer(anonymizer)
 	guardrailsHandler := admin.NewGuardrailsHandler(cfg.Logger, cfg.Config, cfg.GuardrailsExecutor)
 	modelCRUDHandler := admin.NewModelCRUDHandler(cfg.Logger, cfg.DB, cfg.ModelManager)
 
+	rbac := middleware.NewRBACMiddleware(cfg.Logger, cfg.DB)
+
 	// Initialize auth middleware
 	authMiddleware := middleware.NewAuthMiddleware(&middleware.AuthConfig{
 		Logger:           cfg.Logger,
 		AuthService:      cfg.AuthService,
 		MasterKeyService: cfg.MasterKeyService,
 		RequireAuth:      false, // Allow public endpoints
+		SessionCookie:    cfg.Config.AdminSecurity.SessionCookie,
 	})
 
+	// Security headers and CSRF protection for every admin endpoint
+	security := middleware.NewAdminSecurityMiddleware(cfg.Logger, cfg.Config.AdminSecurity)
+	r.Use(security.Headers)
+	r.Use(security.CSRF)
+
 	// Auth endpoints (public - no auth required)
 	r.Post("/auth/login", authHandler.Login)
 	r.Post("/auth/master-key", authHandler.MasterKeyLogin) // Master key authentication
@@ -93,16 +138,24 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware.Authenticate)
 		r.Use(authMiddleware.RequireAdmin)
+		r.Use(security.IPAllowlist)
+
+		// Destructive actions need a recent login
+		stepUp := security.RequireStepUp
+		budgetStepUp := security.RequireStepUpForFields(middleware.BudgetFields...)
 
 		// User management
 		r.Route("/users", func(r chi.Router) {
 			r.Get("/", userHandler.ListUsers)
 			r.Post("/", userHandler.CreateUser)
 			r.Get("/{userID}", userHandler.GetUser)
-			r.Put("/{userID}", userHandler.UpdateUser)
-			r.Delete("/{userID}", userHandler.DeleteUser)
+			r.With(budgetStepUp).Put("/{userID}", userHandler.UpdateUser)
+			r.With(stepUp).Delete("/{userID}", userHandler.DeleteUser)
 			r.Get("/{userID}/stats", userHandler.GetUserStats)
-			r.Post("/{userID}/reset-budget", userHandler.ResetUserBudget)
+			r.With(stepUp).Post("/{userID}/reset-budget", userHandler.ResetUserBudget)
+			if deletionHandler != nil {
+				r.With(stepUp).Post("/{userID}/restore", deletionHandler.RestoreUser)
+			}
 		})
 
 		// Team management
@@ -110,12 +163,33 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 			r.Get("/", teamHandler.ListTeams)
 			r.Post("/", teamHandler.CreateTeam)
 			r.Get("/{teamID}", teamHandler.GetTeam)
-			r.Put("/{teamID}", teamHandler.UpdateTeam)
-			r.Delete("/{teamID}", teamHandler.DeleteTeam)
+			r.With(budgetStepUp).Put("/{teamID}", teamHandler.UpdateTeam)
+			r.With(stepUp).Delete("/{teamID}", teamHandler.DeleteTeam)
 			r.Post("/{teamID}/members", teamHandler.AddMember)
 			r.Put("/{teamID}/members/{memberID}", teamHandler.UpdateMember)
 			r.Delete("/{teamID}/members/{memberID}", teamHandler.RemoveMember)
 			r.Get("/{teamID}/stats", teamHandler.GetTeamStats)
+			if deletionHandler != nil {
+				r.With(stepUp).Post("/{teamID}/restore", deletionHandler.RestoreTeam)
+			}
+
+			// Prepaid credits
+			if cfg.Credits != nil {
+				creditsHandler := admin.NewCreditsHandler(cfg.Logger, cfg.Credits)
+				r.Get("/{teamID}/credits", creditsHandler.GetCredits)
+				r.Post("/{teamID}/credits/checkout", creditsHandler.CreateCheckout)
+				r.With(stepUp).Post("/{teamID}/credits/adjust", creditsHandler.AdjustCredits)
+			}
+
+			// Bring your own provider keys
+			if cfg.TeamProviderKeys != nil {
+				providerKeyHandler := admin.NewTeamProviderKeyHandler(cfg.Logger, cfg.TeamProviderKeys)
+				r.Get("/{teamID}/provider-keys", providerKeyHandler.ListKeys)
+				r.Put("/{teamID}/provider-keys/{providerType}", providerKeyHandler.RegisterKey)
+				r.Patch("/{teamID}/provider-keys/{providerType}", providerKeyHandler.UpdateKey)
+				r.Post("/{teamID}/provider-keys/{providerType}/check", providerKeyHandler.CheckKey)
+				r.With(stepUp).Delete("/{teamID}/provider-keys/{providerType}", providerKeyHandler.DeleteKey)
+			}
 		})
 
 		// Virtual Keys management
@@ -124,11 +198,38 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 			r.Post("/", keyHandler.CreateKey)
 			r.Post("/validate", keyHandler.ValidateKey)
 			r.Get("/{keyID}", keyHandler.GetKey)
-			r.Put("/{keyID}", keyHandler.UpdateKey)
-			r.Delete("/{keyID}", keyHandler.DeleteKey)
-			r.Post("/{keyID}/revoke", keyHandler.RevokeKey)
+			r.With(budgetStepUp).Put("/{keyID}", keyHandler.UpdateKey)
+			r.With(stepUp).Delete("/{keyID}", keyHandler.DeleteKey)
+			r.With(stepUp).Post("/{keyID}/revoke", keyHandler.RevokeKey)
+			r.With(stepUp).Post("/{keyID}/signing-secret", keyHandler.RotateSigningSecret)
 			r.Get("/{keyID}/stats", keyHandler.GetKeyStats)
 			r.Get("/{keyID}/usage", keyHandler.GetKeyUsage)
+			if deletionHandler != nil {
+				r.With(stepUp).Post("/{keyID}/restore", deletionHandler.RestoreKey)
+			}
+		})
+
+		// Deleted users, teams and keys awaiting their purge
+		if deletionHandler != nil {
+			r.Get("/deleted", deletionHandler.ListDeleted)
+			r.With(stepUp).Post("/deleted/purge", deletionHandler.Purge)
+		}
+
+		// Bulk budget import, for customers migrating from other gateways
+		budgetHandler := admin.NewBudgetHandler(cfg.Logger, cfg.DB)
+		r.With(stepUp).Post("/budgets/import", budgetHandler.ImportBudgets)
+
+		// Budget transfers between teams and shared budget pools
+		r.With(stepUp).Post("/budgets/transfers", budgetHandler.TransferBudget)
+		r.Get("/budgets/movements", budgetHandler.ListMovements)
+		r.Route("/budgets/pools", func(r chi.Router) {
+			r.Get("/", budgetHandler.ListPools)
+			r.With(stepUp).Post("/", budgetHandler.CreatePool)
+			r.Get("/{poolID}", budgetHandler.GetPool)
+			r.With(stepUp).Put("/{poolID}", budgetHandler.UpdatePool)
+			r.With(stepUp).Delete("/{poolID}", budgetHandler.DeletePool)
+			r.With(stepUp).Post("/{poolID}/draw", budgetHandler.DrawFromPool)
+			r.With(stepUp).Post("/{poolID}/return", budgetHandler.ReturnToPool)
 		})
 
 		// Analytics
@@ -142,7 +243,12 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 			r.Get("/usage/monthly", analyticsHandler.GetMonthlyUsage)
 			r.Get("/costs", analyticsHandler.GetCosts)
 			r.Get("/costs/breakdown", analyticsHandler.GetCostBreakdown)
+			r.Get("/geo", analyticsHandler.GetGeoBreakdown)
+			r.Get("/clients", analyticsHandler.GetClientBreakdown)
+			r.Get("/environments", analyticsHandler.GetEnvironmentBreakdown)
 			r.Get("/performance", analyticsHandler.GetPerformance)
+			r.Get("/feedback", analyticsHandler.GetFeedback)
+			r.Get("/realtime", analyticsHandler.GetRealtimeUsage)
 			r.Get("/errors", analyticsHandler.GetErrors)
 			r.Get("/cache", analyticsHandler.GetCacheStats)
 			// Historical metrics endpoints
@@ -172,6 +278,17 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 			r.Put("/cache", systemHandler.UpdateCacheSettings)
 		})
 
+		// Emergency controls, applied on every replica at once
+		r.Route("/emergency", func(r chi.Router) {
+			r.Get("/", systemHandler.GetEmergencyControls)
+			r.With(stepUp).Put("/pause", systemHandler.PauseGateway)
+			r.With(stepUp).Delete("/pause", systemHandler.ResumeGateway)
+			r.With(stepUp).Put("/models/*", systemHandler.DisableModel)
+			r.With(stepUp).Delete("/models/*", systemHandler.EnableModel)
+			r.With(stepUp).Put("/teams/{teamID}", systemHandler.SuspendTeam)
+			r.With(stepUp).Delete("/teams/{teamID}", systemHandler.UnsuspendTeam)
+		})
+
 		// Guardrails management
 		r.Route("/guardrails", func(r chi.Router) {
 			r.Get("/", guardrailsHandler.ListGuardrails)
@@ -188,11 +305,48 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 			r.Post("/test-connection", modelCRUDHandler.TestConnection)
 			r.Post("/discover-models", modelCRUDHandler.DiscoverModels)
 			r.Get("/health", modelCRUDHandler.GetModelsHealth)
+			r.Get("/scaling", modelCRUDHandler.GetScalingSignals)
+			r.Get("/capacity", modelCRUDHandler.GetReservedCapacity)
 			r.Get("/{modelID}", modelCRUDHandler.GetModel)
 			r.Put("/{modelID}", modelCRUDHandler.UpdateModel)
 			r.Delete("/{modelID}", modelCRUDHandler.DeleteModel)
 		})
 
+		// In-memory model registry inspection
+		registryHandler := admin.NewRegistryHandler(cfg.Logger, cfg.DB, cfg.Config, cfg.ModelManager)
+		r.Route("/registry", func(r chi.Router) {
+			r.Get("/", registryHandler.GetRegistry)
+			r.Get("/pinned", registryHandler.GetPinnedVersions)
+			r.Get("/stream-gaps", registryHandler.GetStreamGaps)
+			r.With(stepUp).Post("/instances/{instanceID}", registryHandler.SetInstanceEnabled)
+			r.Get("/instances/{instanceID}/drain", registryHandler.GetDrainStatus)
+			r.With(stepUp).Post("/instances/{instanceID}/drain", registryHandler.DrainInstance)
+			r.With(stepUp).Delete("/instances/{instanceID}/drain", registryHandler.ResumeInstance)
+		})
+
+		// Sanitized provider invocations, captured when logging.invocations is enabled
+		invocationsHandler := admin.NewInvocationsHandler(cfg.Logger)
+		r.Get("/invocations", invocationsHandler.ListInvocations)
+
+		// Gateway replicas, their health and configuration drift
+		if cfg.Cluster != nil {
+			clusterHandler := admin.NewClusterHandler(cfg.Logger, cfg.Cluster)
+			r.Get("/cluster", clusterHandler.GetCluster)
+		}
+
+		// Artifact lifecycle: reaper, legal holds and erasure
+		if cfg.Artifacts != nil {
+			artifactsHandler := admin.NewArtifactsHandler(cfg.Logger, cfg.DB, cfg.Artifacts)
+			r.Route("/artifacts", func(r chi.Router) {
+				r.Get("/", artifactsHandler.GetReport)
+				r.With(stepUp).Post("/reap", artifactsHandler.Reap)
+				r.Get("/holds", artifactsHandler.ListHolds)
+				r.With(stepUp).Post("/holds", artifactsHandler.PlaceHold)
+				r.With(stepUp).Delete("/holds/{holdID}", artifactsHandler.ReleaseHold)
+				r.With(stepUp).Delete("/users/{userID}", artifactsHandler.EraseUser)
+			})
+		}
+
 		// Provider profile management
 		providerHandler := admin.NewProviderHandler(cfg.Logger, cfg.DB)
 		r.Route("/providers", func(r chi.Router) {
@@ -203,6 +357,35 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 			r.Delete("/{providerID}", providerHandler.DeleteProvider)
 		})
 
+		// Real-time dashboard notifications (SSE, or WebSocket on upgrade)
+		notificationsHandler := admin.NewNotificationsHandler(cfg.Logger, cfg.Notifier, cfg.Config.CORS.AllowedOrigins)
+		r.Get("/notifications", notificationsHandler.Stream)
+
+		// Response provenance verification
+		provenanceHandler := admin.NewProvenanceHandler(cfg.Logger, cfg.DB)
+		r.Post("/provenance/verify", provenanceHandler.VerifyProvenance)
+
+		// Upstream provider incidents recorded by the outage watcher
+		incidentHandler := admin.NewIncidentHandler(cfg.Logger, cfg.DB)
+		r.Get("/incidents", incidentHandler.ListIncidents)
+
+		// Itemized pricing of individual requests
+		usageHandler := admin.NewUsageHandler(cfg.Logger, cfg.DB)
+		usageHandler.SetCounters(cfg.UsageCounters)
+		r.Get("/usage/totals", usageHandler.GetTotals)
+		r.Get("/usage/bandwidth", usageHandler.GetTeamBandwidth)
+		r.Get("/usage/{id}/cost-breakdown", usageHandler.GetCostBreakdown)
+
+		// Gateway tool runtime registry
+		toolHandler := admin.NewToolHandler(cfg.Logger, cfg.DB, cfg.Config.Tools.AllowedHosts)
+		r.Route("/tools", func(r chi.Router) {
+			r.Get("/", toolHandler.ListTools)
+			r.Post("/", toolHandler.CreateTool)
+			r.Get("/{toolID}", toolHandler.GetTool)
+			r.Put("/{toolID}", toolHandler.UpdateTool)
+			r.Delete("/{toolID}", toolHandler.DeleteTool)
+		})
+
 		// Route management
 		routeHandler := admin.NewRouteHandler(cfg.Logger, cfg.DB, cfg.ModelManager)
 		r.Route("/routes", func(r chi.Router) {
@@ -212,6 +395,7 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 			r.Put("/{routeID}", routeHandler.UpdateRoute)
 			r.Delete("/{routeID}", routeHandler.DeleteRoute)
 			r.Get("/{routeID}/stats", routeHandler.GetRouteStats)
+			r.Post("/{routeID}/simulate", routeHandler.SimulateRoute)
 		})
 	})
 
@@ -250,6 +434,27 @@ func NewAdminSubRouter(cfg *AdminRouterConfig) http.Handler {
 				teamHandler.ListTeams(w, r)
 			})
 		})
+
+		// Team-scoped routes for team members, by team role: owners and
+		// admins manage the team, billing admins its budget and credits,
+		// auditors read its usage and audit log
+		r.Route("/team/{teamID}", func(r chi.Router) {
+			r.With(rbac.RequireTeamPermission(auth.PermTeamsAudit)).
+				Get("/audit", teamHandler.GetTeamAuditLogs)
+			r.With(rbac.RequireTeamPermission(auth.PermAnalyticsRead)).
+				Get("/stats", teamHandler.GetTeamStats)
+			r.With(rbac.RequireTeamPermission(auth.PermBudgetsRead)).
+				Get("/budget", teamHandler.GetTeamBudget)
+			r.With(rbac.RequireTeamPermission(auth.PermBudgetsUpdate), security.RequireStepUpForFields(middleware.BudgetFields...)).
+				Put("/budget", teamHandler.UpdateTeamBudget)
+			if cfg.Credits != nil {
+				creditsHandler := admin.NewCreditsHandler(cfg.Logger, cfg.Credits)
+				r.With(rbac.RequireTeamPermission(auth.PermBudgetsRead)).
+					Get("/credits", creditsHandler.GetCredits)
+				r.With(rbac.RequireTeamPermission(auth.PermBudgetsUpdate)).
+					Post("/credits/checkout", creditsHandler.CreateCheckout)
+			}
+		})
 	})
 
 	return r
@@ -262,9 +467,11 @@ func NewAdminRouter(cfg *AdminRouterConfig) http.Handler {
 
 	// Basic middleware
 	r.Use(chiMiddleware.RequestID)
+	r.Use(middleware.PeerAddr) // Keep the socket address for the admin IP allowlist
 	r.Use(chiMiddleware.RealIP)
 	r.Use(chiMiddleware.Recoverer)
 	r.Use(middleware.Logger(cfg.Logger))
+	r.Use(middleware.NewBodyLimitMiddleware(cfg.Config.Server, cfg.Logger).Middleware)
 
 	// CORS for admin UI
 	r.Use(cors.Handler(cors.Options{
@@ -278,6 +485,7 @@ func NewAdminRouter(cfg *AdminRouterConfig) http.Handler {
 
 	// Initialize services
 	teamService := team.NewTeamService(cfg.DB)
+	teamService.SetTemplates(cfg.Config.Onboarding)
 	// Budget service could be used for budget handlers if needed
 	// Note: Not starting the budget service monitor to avoid excessive logging
 	// It will be properly initialized when authentication is implemented
@@ -291,17 +499,27 @@ func NewAdminRouter(cfg *AdminRouterConfig) http.Handler {
 	teamHandler := admin.NewTeamHandler(cfg.Logger, teamService, cfg.DB, cfg.BudgetService)
 	keyHandler := admin.NewKeyHandler(cfg.Logger, cfg.DB, cfg.BudgetService)
 	analyticsHandler := admin.NewAnalyticsHandler(cfg.Logger, cfg.DB, cfg.ModelManager)
+	analyticsHandler.SetAnonymizer(privacy.NewAnonymizer(cfg.Config.Analytics.Privacy, cfg.Config.JWT.SecretKey))
 	systemHandler := admin.NewSystemHandler(cfg.Logger, cfg.DB)
 	guardrailsHandler := admin.NewGuardrailsHandler(cfg.Logger, cfg.Config, cfg.GuardrailsExecutor)
 
+	rbac := middleware.NewRBACMiddleware(cfg.Logger, cfg.DB)
+
 	// Initialize auth middleware
 	authMiddleware := middleware.NewAuthMiddleware(&middleware.AuthConfig{
 		Logger:           cfg.Logger,
 		AuthService:      cfg.AuthService,
 		MasterKeyService: cfg.MasterKeyService,
 		RequireAuth:      false, // Allow public endpoints
+		SessionCookie:    cfg.Config.AdminSecurity.SessionCookie,
 	})
 
+	security := middleware.NewAdminSecurityMiddleware(cfg.Logger, cfg.Config.AdminSecurity)
+	r.Use(security.Headers)
+	r.Use(security.CSRF)
+	stepUp := security.RequireStepUp
+	budgetStepUp := security.RequireStepUpForFields(middleware.BudgetFields...)
+
 	// Health check (public)
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -327,6 +545,7 @@ func NewAdminRouter(cfg *AdminRouterConfig) http.Handler {
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware.Authenticate)
 		r.Use(authMiddleware.RequireAdmin)
+		r.Use(security.IPAllowlist)
 
 		r.Route("/api/admin", func(r chi.Router) {
 			// Dashboard
@@ -341,8 +560,8 @@ func NewAdminRouter(cfg *AdminRouterConfig) http.Handler {
 				r.Get("/", teamHandler.ListTeams)
 				r.Post("/", teamHandler.CreateTeam)
 				r.Get("/{teamID}", teamHandler.GetTeam)
-				r.Put("/{teamID}", teamHandler.UpdateTeam)
-				r.Delete("/{teamID}", teamHandler.DeleteTeam)
+				r.With(budgetStepUp).Put("/{teamID}", teamHandler.UpdateTeam)
+				r.With(stepUp).Delete("/{teamID}", teamHandler.DeleteTeam)
 				r.Post("/{teamID}/members", teamHandler.AddMember)
 				r.Put("/{teamID}/members/{memberID}", teamHandler.UpdateMember)
 				r.Delete("/{teamID}/members/{memberID}", teamHandler.RemoveMember)
@@ -354,9 +573,9 @@ func NewAdminRouter(cfg *AdminRouterConfig) http.Handler {
 				r.Get("/", keyHandler.ListKeys)
 				r.Post("/", keyHandler.CreateKey)
 				r.Get("/{keyID}", keyHandler.GetKey)
-				r.Put("/{keyID}", keyHandler.UpdateKey)
-				r.Delete("/{keyID}", keyHandler.DeleteKey)
-				r.Post("/{keyID}/revoke", keyHandler.RevokeKey)
+				r.With(budgetStepUp).Put("/{keyID}", keyHandler.UpdateKey)
+				r.With(stepUp).Delete("/{keyID}", keyHandler.DeleteKey)
+				r.With(stepUp).Post("/{keyID}/revoke", keyHandler.RevokeKey)
 				r.Get("/{keyID}/stats", keyHandler.GetKeyStats)
 				r.Get("/{keyID}/usage", keyHandler.GetKeyUsage)
 			})
@@ -372,7 +591,12 @@ func NewAdminRouter(cfg *AdminRouterConfig) http.Handler {
 				r.Get("/usage/monthly", analyticsHandler.GetMonthlyUsage)
 				r.Get("/costs", analyticsHandler.GetCosts)
 				r.Get("/costs/breakdown", analyticsHandler.GetCostBreakdown)
+				r.Get("/geo", analyticsHandler.GetGeoBreakdown)
+				r.Get("/clients", analyticsHandler.GetClientBreakdown)
+				r.Get("/environments", analyticsHandler.GetEnvironmentBreakdown)
 				r.Get("/performance", analyticsHandler.GetPerformance)
+				r.Get("/feedback", analyticsHandler.GetFeedback)
+				r.Get("/realtime", analyticsHandler.GetRealtimeUsage)
 				r.Get("/errors", analyticsHandler.GetErrors)
 				r.Get("/cache", analyticsHandler.GetCacheStats)
 				// Historical metrics endpoints
@@ -450,6 +674,18 @@ func NewAdminRouter(cfg *AdminRouterConfig) http.Handler {
 				teamHandler.ListTeams(w, r)
 			})
 		})
+
+		// Team-scoped routes for team members, by team role
+		r.Route("/api/team/{teamID}", func(r chi.Router) {
+			r.With(rbac.RequireTeamPermission(auth.PermTeamsAudit)).
+				Get("/audit", teamHandler.GetTeamAuditLogs)
+			r.With(rbac.RequireTeamPermission(auth.PermAnalyticsRead)).
+				Get("/stats", teamHandler.GetTeamStats)
+			r.With(rbac.RequireTeamPermission(auth.PermBudgetsRead)).
+				Get("/budget", teamHandler.GetTeamBudget)
+			r.With(rbac.RequireTeamPermission(auth.PermBudgetsUpdate), budgetStepUp).
+				Put("/budget", teamHandler.UpdateTeamBudget)
+		})
 	})
 
 	// Serve admin UI static files
@@ -2,7 +2,10 @@ package router
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/amerfu/pllm/internal/core/auth"
@@ -11,19 +14,39 @@ import (
 	"github.com/amerfu/pllm/internal/api/handlers"
 	"github.com/amerfu/pllm/internal/api/handlers/admin"
 	"github.com/amerfu/pllm/internal/infrastructure/middleware"
+	"github.com/amerfu/pllm/internal/services/monitoring/clientinfo"
+	"github.com/amerfu/pllm/internal/services/monitoring/cluster"
+	"github.com/amerfu/pllm/internal/services/monitoring/loadshed"
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+	"github.com/amerfu/pllm/internal/services/monitoring/provenance"
+	"github.com/amerfu/pllm/internal/services/monitoring/ratelimit"
 	"github.com/amerfu/pllm/internal/services/data/budget"
 	"github.com/amerfu/pllm/internal/services/data/cache"
 	"github.com/amerfu/pllm/internal/services/integrations/guardrails"
+	"github.com/amerfu/pllm/internal/services/integrations/billing"
+	"github.com/amerfu/pllm/internal/services/data/artifacts"
+	"github.com/amerfu/pllm/internal/services/data/deletion"
+	"github.com/amerfu/pllm/internal/services/data/keycache"
+	"github.com/amerfu/pllm/internal/services/integrations/byok"
 	"github.com/amerfu/pllm/internal/services/integrations/key"
+	"github.com/amerfu/pllm/internal/services/integrations/onboarding"
+	"github.com/amerfu/pllm/internal/services/llm/contextcache"
+	"github.com/amerfu/pllm/internal/services/llm/contextwindow"
 	"github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
 	"github.com/amerfu/pllm/internal/services/llm/realtime"
+	"github.com/amerfu/pllm/internal/services/data/coordination"
 	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"github.com/amerfu/pllm/internal/services/data/settings"
+	"github.com/amerfu/pllm/internal/services/integrations/tools"
+	"github.com/amerfu/pllm/internal/services/jobs"
 	"github.com/amerfu/pllm/internal/services/integrations/team"
 	"github.com/amerfu/pllm/internal/api/ui"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -31,37 +54,94 @@ import (
 	"gorm.io/gorm"
 )
 
-func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.ModelManager, db *gorm.DB, pricingManager *config.ModelPricingManager) http.Handler {
+// shutdownHooks stop the background services started by NewRouter
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func()
+)
+
+func onShutdown(stop func()) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, stop)
+}
+
+// Shutdown stops the background services started by NewRouter, most
+// recently started first
+func Shutdown() {
+	shutdownMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	shutdownMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// NewRouter builds the gateway's HTTP handler. notifier streams admin
+// dashboard notifications and may be nil.
+func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.ModelManager, db *gorm.DB, pricingManager *config.ModelPricingManager, notifier *notifications.Hub) http.Handler {
 	r := chi.NewRouter()
 
-	// Initialize Redis client
-	opt, err := redis.ParseURL(cfg.Redis.URL)
+	// Initialize Redis client. It is optional with the Postgres coordination
+	// backend, which keeps queues, locks and budget cache in the database.
+	redisClient, err := newRedisClient(cfg)
 	if err != nil {
-		logger.Fatal("Failed to parse Redis URL", zap.Error(err))
+		if !cfg.Coordination.UsesPostgres() {
+			logger.Fatal("Failed to connect to Redis", zap.Error(err))
+		}
+		logger.Warn("Redis not available, continuing with Postgres coordination backend", zap.Error(err))
 	}
 
-	// Override with explicit password and DB if provided
-	if cfg.Redis.Password != "" {
-		opt.Password = cfg.Redis.Password
-	}
-	if cfg.Redis.DB != 0 {
-		opt.DB = cfg.Redis.DB
+	// Watch Redis memory and queue depth so async writes degrade before Redis fills up
+	var memoryGuard *redisService.MemoryGuard
+	if redisClient != nil && cfg.Redis.MemoryGuard.Enabled {
+		queueKeys := []string{"pllm:metrics:events"}
+		if !cfg.Coordination.UsesPostgres() {
+			queueKeys = append(queueKeys, "usage_processing_queue")
+		}
+		memoryGuard = redisService.NewMemoryGuard(&redisService.MemoryGuardConfig{
+			Client:              redisClient,
+			Logger:              logger,
+			QueueKeys:           queueKeys,
+			Interval:            cfg.Redis.MemoryGuard.Interval,
+			MemoryElevatedRatio: cfg.Redis.MemoryGuard.MemoryElevatedRatio,
+			MemoryCriticalRatio: cfg.Redis.MemoryGuard.MemoryCriticalRatio,
+			MaxMemoryBytes:      cfg.Redis.MemoryGuard.MaxMemoryBytes,
+			QueueElevatedDepth:  cfg.Redis.MemoryGuard.QueueElevatedDepth,
+			QueueCriticalDepth:  cfg.Redis.MemoryGuard.QueueCriticalDepth,
+		})
+		memoryGuard.Start(context.Background())
 	}
 
-	redisClient := redis.NewClient(opt)
+	// Usage records only go through Redis with the Redis coordination backend
+	usageMemoryGuard := memoryGuard
+	if cfg.Coordination.UsesPostgres() {
+		usageMemoryGuard = nil
+	}
 
-	// Test Redis connection
-	if err := redisClient.Ping(context.Background()).Err(); err != nil {
-		logger.Fatal("Failed to connect to Redis", zap.Error(err))
+	// Keep the sanitized requests sent to providers for debugging
+	if inv := cfg.Logging.Invocations; inv.Enabled {
+		providers.EnableInvocationCapture(providers.InvocationCaptureOptions{
+			Retention:    inv.Retention,
+			MaxEntries:   inv.MaxEntries,
+			MaxBodyBytes: inv.MaxBodyBytes,
+			RequestID:    middleware.GetRequestID,
+		})
+		logger.Warn("Provider invocation logging enabled; sanitized upstream payloads are kept in memory",
+			zap.Duration("retention", inv.Retention))
 	}
 
 	// Initialize auth services
 	masterKeyService := auth.NewMasterKeyService(&auth.MasterKeyConfig{
-		DB:          db,
-		MasterKey:   cfg.Auth.MasterKey,
-		JWTSecret:   []byte(cfg.JWT.SecretKey),
-		JWTIssuer:   "pllm",
-		TokenExpiry: 24 * time.Hour,
+		DB:              db,
+		MasterKey:       cfg.Auth.MasterKey,
+		JWTSecret:       []byte(cfg.JWT.SecretKey),
+		JWTIssuer:       "pllm",
+		TokenExpiry:     cfg.Auth.MasterKeyTokens.TTL,
+		MaxTokenExpiry:  cfg.Auth.MasterKeyTokens.MaxTTL,
+		RequireExchange: cfg.Auth.MasterKeyTokens.RequireExchange,
 	})
 
 	// Prepare Dex config if enabled
@@ -77,9 +157,10 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		}
 	}
 
-	// Initialize team and key services for auth auto-provisioning
+	// Onboarding templates applied to users auto-provisioned from Dex
 	teamService := team.NewTeamService(db)
-	keyService := key.NewService(db, logger)
+	teamService.SetTemplates(cfg.Onboarding)
+	onboarder := onboarding.NewService(cfg.Onboarding, teamService, key.NewService(db, logger), logger)
 
 	authService, err := auth.NewAuthService(&auth.AuthConfig{
 		DB:               db,
@@ -88,17 +169,37 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		JWTIssuer:        "pllm",
 		TokenExpiry:      cfg.JWT.AccessTokenDuration,
 		MasterKeyService: masterKeyService,
-		TeamService:      teamService,
-		KeyService:       keyService,
+		Onboarder:        onboarder,
+		Notifier:         notifier,
+		Logger:           logger,
 	})
 	if err != nil {
 		logger.Fatal("Failed to initialize auth service", zap.Error(err))
 	}
 
+	// Key cache: unknown keys are rejected without a query and validated
+	// keys are shared by the gateways
+	if db != nil && cfg.Auth.KeyCache.Enabled {
+		keyCache := keycache.New(&keycache.Config{
+			DB:              db,
+			Redis:           redisClient,
+			Logger:          logger,
+			RefreshInterval: cfg.Auth.KeyCache.RefreshInterval,
+			TTL:             cfg.Auth.KeyCache.TTL,
+		})
+		if err := keyCache.RegisterCallbacks(db); err != nil {
+			logger.Warn("Failed to follow key changes, key cache disabled", zap.Error(err))
+		} else {
+			keyCache.Start(context.Background())
+			onShutdown(keyCache.Stop)
+			authService.SetKeyCache(keyCache)
+		}
+	}
+
 	// Initialize metrics service if database and Redis are available
 	var metricsService *metrics.MetricsService
 	var metricsEmitter *metrics.MetricEventEmitter
-	if db != nil {
+	if db != nil && redisClient != nil {
 		metricsConfig := &metrics.MetricsServiceConfig{
 			DB:                db,
 			Redis:             redisClient,
@@ -121,6 +222,9 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 				metricsService = nil
 			} else {
 				metricsEmitter = metricsService.GetEmitter()
+				if memoryGuard != nil {
+					metricsEmitter.SetLoadShedder(memoryGuard)
+				}
 				logger.Info("Metrics service started successfully")
 			}
 		}
@@ -158,14 +262,193 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		}
 	}
 
+	// Prompts exceeding the model's context window
+	var contextWindowMiddleware *middleware.ContextWindowMiddleware
+	if cfg.ContextWindow.Enabled {
+		contextWindowManager := contextwindow.NewManager(cfg.ContextWindow, modelManager, pricingManager, logger)
+		contextWindowMiddleware = middleware.NewContextWindowMiddleware(contextWindowManager, logger)
+		logger.Info("Context window management enabled",
+			zap.String("strategy", cfg.ContextWindow.Strategy))
+	}
+
+	// Cached outputs of deterministic completions, for keys that opt in
+	var outputCacheMiddleware *middleware.OutputCacheMiddleware
+	if cfg.Cache.Outputs.Enabled {
+		var outputStore cache.Cache
+		if cache.IsHealthy() {
+			outputStore = cache.NewRedisCache(cfg.Cache.Outputs.TTL)
+		} else {
+			outputStore = cache.NewInMemoryCache(cfg.Cache.Outputs.TTL)
+		}
+		outputCacheMiddleware = middleware.NewOutputCacheMiddleware(outputStore, cfg.Cache.Outputs, logger)
+		logger.Info("Output cache enabled", zap.Duration("ttl", cfg.Cache.Outputs.TTL))
+	}
+
+	// One provider call for identical deterministic completions in flight
+	var coalescingMiddleware *middleware.CoalescingMiddleware
+	if cfg.Cache.Coalescing.Enabled {
+		coalescingMiddleware = middleware.NewCoalescingMiddleware(logger)
+		logger.Info("Request coalescing enabled")
+	}
+
+	// Usage queue, budget cache and locks shared between replicas
+	coordinationBackends, err := coordination.NewBackends(&coordination.Config{
+		Backend:    cfg.Coordination.Backend,
+		Redis:      redisClient,
+		DB:         db,
+		Logger:     logger,
+		QueueName:  "usage_processing_queue",
+		BatchSize:  50,
+		MaxRetries: 3,
+		UsageCodec: redisService.UsageCodec{
+			Encoding: cfg.Coordination.UsageEncoding,
+			Compress: cfg.Coordination.UsageCompression,
+		},
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize coordination backend", zap.Error(err))
+	}
+
+	// Registration of this replica, for the admin cluster view
+	var clusterRegistrar *cluster.Registrar
+	if cfg.Cluster.Enabled {
+		clusterRegistrar = cluster.NewRegistrar(&cluster.Config{
+			Backend:           coordinationBackends.Replicas,
+			Logger:            logger,
+			ConfigHash:        cluster.ConfigHash(cfg),
+			HeartbeatInterval: cfg.Cluster.HeartbeatInterval,
+			ReplicaTTL:        cfg.Cluster.ReplicaTTL,
+			HealthCheck: func(ctx context.Context) error {
+				if db != nil {
+					sqlDB, err := db.DB()
+					if err != nil {
+						return err
+					}
+					if err := sqlDB.PingContext(ctx); err != nil {
+						return fmt.Errorf("database: %w", err)
+					}
+				}
+				if redisClient != nil {
+					if err := redisClient.Ping(ctx).Err(); err != nil {
+						return fmt.Errorf("redis: %w", err)
+					}
+				}
+				return nil
+			},
+		})
+		clusterRegistrar.Start(context.Background())
+		onShutdown(clusterRegistrar.Stop)
+	}
+
+	// In-flight request counters for key and team max_parallel_calls limits
+	var concurrencyLimiter ratelimit.ConcurrencyLimiter
+	if redisClient != nil {
+		concurrencyLimiter = ratelimit.NewRedisConcurrencyLimiter(redisClient, logger)
+	} else {
+		concurrencyLimiter = ratelimit.NewInMemoryConcurrencyLimiter()
+	}
+	concurrencyMiddleware := middleware.NewConcurrencyMiddleware(concurrencyLimiter, logger)
+	teamModelMiddleware := middleware.NewTeamModelMiddleware(logger)
+
+	// Request and response sizes for usage records and team bandwidth quotas
+	var bandwidthMiddleware *middleware.BandwidthMiddleware
+	if cfg.Bandwidth.Enabled {
+		bandwidthMiddleware = middleware.NewBandwidthMiddleware(coordinationBackends.Counters, logger)
+	}
+	teamSystemPromptMiddleware := middleware.NewTeamSystemPromptMiddleware(logger)
+
+	// Route parameter defaults, written into requests that do not set them
+	var routeDefaultsMiddleware *middleware.RouteDefaultsMiddleware
+	var routeDefaults middleware.RouteDefaultsApplier
+	if modelManager != nil {
+		routeDefaultsMiddleware = middleware.NewRouteDefaultsMiddleware(modelManager, logger)
+		routeDefaults = modelManager
+	}
+	responseMinimizationMiddleware := middleware.NewResponseMinimizationMiddleware(logger)
+	requestValidationMiddleware := middleware.NewRequestValidationMiddleware(logger)
+
+	// Per-model request pressure for the autoscaling signals, exported with
+	// the other Prometheus metrics
+	var scalingSignalsMiddleware *middleware.ScalingSignalsMiddleware
+	if modelManager != nil {
+		scalingSignalsMiddleware = middleware.NewScalingSignalsMiddleware(modelManager, logger)
+		if err := prometheus.Register(modelManager.ScalingCollector()); err != nil {
+			var registered prometheus.AlreadyRegisteredError
+			if !errors.As(err, &registered) {
+				logger.Warn("Failed to register scaling signal metrics", zap.Error(err))
+			}
+		}
+		if err := prometheus.Register(modelManager.CapacityCollector()); err != nil {
+			var registered prometheus.AlreadyRegisteredError
+			if !errors.As(err, &registered) {
+				logger.Warn("Failed to register reserved capacity metrics", zap.Error(err))
+			}
+		}
+	}
+
+	// Settings admins change at runtime, applied on every replica
+	var settingsStore *settings.Store
+	if db != nil {
+		settingsStore = settings.NewStore(&settings.StoreConfig{
+			DB:         db,
+			Redis:      redisClient,
+			Logger:     logger,
+			RateLimits: cfg.RateLimit,
+		})
+		settingsStore.Start(context.Background())
+		onShutdown(settingsStore.Stop)
+	}
+
+	// Emergency controls: the global pause, model kill switches and team
+	// suspensions, kept in the runtime settings
+	var emergencyMiddleware *middleware.EmergencyMiddleware
+	if settingsStore != nil {
+		emergencyMiddleware = middleware.NewEmergencyMiddleware(settingsStore, logger)
+		if modelManager != nil {
+			modelManager.SetModelKillSwitch(func(model string) bool {
+				_, disabled := settingsStore.EmergencyControls().ModelDisabled(model)
+				return disabled
+			})
+		}
+	}
+
+	// Process resources, exported as metrics, and load shedding under pressure
+	resourceMonitor := loadshed.NewMonitor(cfg.LoadShedding, logger)
+	resourceMonitor.Start(context.Background())
+	onShutdown(resourceMonitor.Stop)
+	if modelManager != nil {
+		modelManager.SetLoadShedding(resourceMonitor.ShouldShedLoad)
+	}
+	var loadSheddingMiddleware *middleware.LoadSheddingMiddleware
+	if cfg.LoadShedding.Enabled {
+		loadSheddingMiddleware = middleware.NewLoadSheddingMiddleware(resourceMonitor, logger)
+	}
+
+	// Client geo from the GeoIP database, when configured, for usage analytics
+	var geoLocator *clientinfo.Locator
+	if path := cfg.Analytics.GeoIP.DatabasePath; path != "" {
+		locator, err := clientinfo.OpenLocator(path)
+		if err != nil {
+			logger.Error("Failed to load GeoIP database, usage will not be located", zap.Error(err))
+		} else {
+			geoLocator = locator
+			logger.Info("GeoIP database loaded", zap.String("path", path))
+		}
+	}
+
 	// Legacy synchronous budget/usage systems removed in favor of async Redis-based system
 
 	// Basic middleware
 	r.Use(chiMiddleware.RequestID)
+	r.Use(middleware.PeerAddr) // Keep the socket address for the admin IP allowlist
 	r.Use(chiMiddleware.RealIP)
+	r.Use(middleware.ClientInfo(geoLocator)) // Client geo and library, after RealIP
 	r.Use(chiMiddleware.Recoverer)
 	r.Use(middleware.Logger(logger))
 
+	// Request body size limits, before anything reads a body
+	r.Use(middleware.NewBodyLimitMiddleware(cfg.Server, logger).Middleware)
+
 	// Metrics middleware - use advanced metrics if available, otherwise basic
 	if metricsEmitter != nil {
 		r.Use(middleware.NewAsyncMetricsMiddleware(metricsEmitter, logger).Middleware)
@@ -173,6 +456,20 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		r.Use(middleware.MetricsMiddleware(logger))
 	}
 
+	// Retry policy and retryable flags for clients of the LLM API
+	if cfg.RetryPolicy.Enabled {
+		r.Use(middleware.NewRetryHintsMiddleware(cfg.RetryPolicy).Middleware)
+	}
+
+	// Synthetic monitoring requests become canned chat completions (after
+	// metrics, so they stay out of analytics, and before everything that
+	// looks at the path)
+	r.Use(middleware.Synthetic)
+
+	// Anthropic headers and error envelopes for the Messages API, so the
+	// Anthropic SDKs work unchanged (before anything that can reject a request)
+	r.Use(middleware.AnthropicCompat)
+
 	// CORS
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORS.AllowedOrigins,
@@ -183,9 +480,13 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		MaxAge:           cfg.CORS.MaxAge,
 	}))
 
-	// Global rate limiting
-	if cfg.RateLimit.Enabled {
+	// Global rate limiting, which admins can enable and tune at runtime
+	// when settings are stored
+	if cfg.RateLimit.Enabled || settingsStore != nil {
 		rateLimitMiddleware := middleware.NewRateLimitMiddleware(cfg, logger)
+		if settingsStore != nil {
+			rateLimitMiddleware.SetSettings(settingsStore)
+		}
 		r.Use(rateLimitMiddleware.Handler)
 	}
 
@@ -242,6 +543,135 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		adminHandler = handlers.NewAdminHandler(logger, modelManager)
 	}
 	modelMgmtHandler = handlers.NewModelManagementHandler(pricingManager)
+	chatHandler.SetPricing(pricingManager)
+
+	// Provenance metadata on chat responses
+	if cfg.Provenance.Enabled {
+		chatHandler.SetProvenance(provenance.NewStamper(&cfg.Provenance))
+		logger.Info("Response provenance enabled", zap.String("mode", cfg.Provenance.Mode))
+	}
+
+	// Async inference jobs (?async=true), persisted in the database
+	var jobsHandler *handlers.JobsHandler
+	if db != nil && cfg.Jobs.Enabled {
+		jobService := jobs.NewService(&jobs.Config{
+			DB:             db,
+			Logger:         logger,
+			Executor:       chatHandler.ExecuteJob,
+			Workers:        cfg.Jobs.Workers,
+			Timeout:        cfg.Jobs.Timeout,
+			ResultTTL:      cfg.Jobs.ResultTTL,
+			WebhookTimeout: cfg.Jobs.WebhookTimeout,
+			RetryInterval:  cfg.Jobs.OutageQueue.RetryInterval,
+
+			WebhookAllowedHosts: cfg.Jobs.WebhookAllowedHosts,
+		})
+		jobService.Start(context.Background())
+		onShutdown(jobService.Stop)
+		chatHandler.SetJobs(jobService)
+		if cfg.Jobs.OutageQueue.Enabled {
+			chatHandler.SetOutageQueue(cfg.Jobs.OutageQueue)
+			logger.Info("Outage queue enabled", zap.Duration("max_wait", cfg.Jobs.OutageQueue.MaxWait))
+		}
+		jobsHandler = handlers.NewJobsHandler(logger, jobService)
+	}
+
+	// Gateway-side execution of admin-registered tools
+	if db != nil && cfg.Tools.Enabled {
+		chatHandler.SetTools(tools.NewRuntime(&tools.RuntimeConfig{
+			Service:        tools.NewService(db, logger),
+			Logger:         logger,
+			MaxDepth:       cfg.Tools.MaxDepth,
+			Timeout:        cfg.Tools.Timeout,
+			AllowedHosts:   cfg.Tools.AllowedHosts,
+			MaxResultBytes: cfg.Tools.MaxResultBytes,
+		}))
+		logger.Info("Tool runtime enabled", zap.Strings("allowed_hosts", cfg.Tools.AllowedHosts))
+	}
+
+	// Context caches (/v1/caches), persisted in the database
+	var cachesHandler *handlers.CachesHandler
+	if db != nil {
+		cacheService := contextcache.NewService(&contextcache.Config{
+			DB:             db,
+			Logger:         logger,
+			ModelManager:   modelManager,
+			PricingManager: pricingManager,
+		})
+		chatHandler.SetContextCaches(cacheService)
+		cachesHandler = handlers.NewCachesHandler(logger, cacheService)
+	}
+
+	// Artifact storage for uploaded files, with lifecycle rules enforced by
+	// a background reaper
+	var artifactService *artifacts.Service
+	if db != nil {
+		store, err := artifacts.NewStore(cfg.Artifacts.Storage)
+		if err != nil {
+			logger.Error("Artifact storage disabled", zap.Error(err))
+		} else {
+			artifactService = artifacts.NewService(db, store, cfg.Artifacts, logger)
+			go artifactService.Start(context.Background())
+			onShutdown(artifactService.Stop)
+			filesHandler.SetArtifacts(artifactService)
+		}
+	}
+
+	// Restorable deletes of users, teams and keys, purged by a background job
+	// once their retention window has passed
+	var deletionService *deletion.Service
+	if db != nil {
+		deletionService = deletion.NewService(db, cfg.Deletion, logger)
+		go deletionService.Start(context.Background())
+		onShutdown(deletionService.Stop)
+	}
+
+	// Prepaid team credits, bought through Stripe
+	var creditsService *billing.Service
+	if db != nil && cfg.Billing.Credits.Enabled {
+		creditsService = billing.NewService(db, logger, cfg.Billing.Credits)
+		logger.Info("Prepaid credits enabled", zap.Float64("grace_amount", cfg.Billing.Credits.GraceAmount))
+	}
+
+	// Provider keys teams bring for their own traffic
+	var teamProviderKeys *byok.Service
+	if db != nil && modelManager != nil && cfg.BYOK.Enabled {
+		service, err := byok.NewService(db, logger, cfg.BYOK, modelManager.CreateProvider)
+		if err != nil {
+			logger.Error("Bring-your-own-key disabled", zap.Error(err))
+		} else {
+			teamProviderKeys = service
+			modelManager.SetTeamProviders(teamProviderKeys)
+			go teamProviderKeys.Start(context.Background())
+			onShutdown(teamProviderKeys.Stop)
+			logger.Info("Bring-your-own-key enabled")
+		}
+	}
+
+	// OpenAI-compatible usage and billing endpoints for cost dashboards
+	var usageCompatHandler *handlers.UsageCompatHandler
+	if db != nil {
+		usageCompatHandler = handlers.NewUsageCompatHandler(logger, db)
+	}
+
+	// Response feedback for satisfaction analytics
+	var feedbackHandler *handlers.FeedbackHandler
+	if db != nil {
+		feedbackHandler = handlers.NewFeedbackHandler(logger, db)
+	}
+
+	// Capability discovery for client SDKs
+	capabilitiesHandler := handlers.NewCapabilitiesHandler(logger, cfg, modelManager, pricingManager)
+	if settingsStore != nil {
+		capabilitiesHandler.SetRateLimits(settingsStore)
+		capabilitiesHandler.SetModelKillSwitch(func(model string) (string, bool) {
+			return settingsStore.EmergencyControls().ModelDisabled(model)
+		})
+	}
+	capabilitiesHandler.SetFeature(handlers.FeatureAsyncJobs, jobsHandler != nil)
+	capabilitiesHandler.SetFeature(handlers.FeatureGatewayTools, db != nil && cfg.Tools.Enabled)
+	capabilitiesHandler.SetFeature(handlers.FeatureContextCaching, cachesHandler != nil)
+	modelsHandler.SetCapabilities(capabilitiesHandler) // Per-key model details
 
 	// Initialize realtime session manager and handler
 	sessionConfig := &realtime.SessionConfig{
@@ -259,7 +689,21 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		EnableCompression: true,
 	}
 	realtimeHandler = handlers.NewRealtimeHandler(logger, sessionManager, modelManager, handlerConfig)
+	realtimeHandler.SetBudgetEnforcer(realtime.NewBudgetEnforcer(&realtime.BudgetEnforcerConfig{
+		Logger:         logger,
+		BudgetCache:    coordinationBackends.BudgetCache,
+		UsageQueue:     coordinationBackends.UsageQueue,
+		PricingManager: pricingManager,
+		MarkupPercent:  cfg.Billing.MarkupPercent,
+		Metering:       cfg.Realtime.Metering,
+	}))
 	authHandler := handlers.NewAuthHandler(logger, authService, masterKeyService, db)
+	if db != nil {
+		keyRotator := key.NewRotator(db, cfg.KeyRotation, logger)
+		authHandler.SetKeyRotator(keyRotator)
+		authHandler.SetUserNotifications(cfg.UserNotifications)
+		onShutdown(keyRotator.Stop)
+	}
 
 	// Initialize system handler for auth config
 	systemHandler := admin.NewSystemHandler(logger, db)
@@ -270,6 +714,10 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		r.Post("/v1/login", authHandler.Login)
 		r.Post("/v1/refresh", authHandler.RefreshToken)
 		r.Get("/api/auth/config", systemHandler.GetAuthConfig) // Public auth config
+		if creditsService != nil {
+			// Authenticated by the Stripe signature
+			r.Post("/api/billing/stripe/webhook", admin.NewCreditsHandler(logger, creditsService).StripeWebhook)
+		}
 	})
 
 	// Protected routes
@@ -280,38 +728,102 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 			AuthService:      authService,
 			MasterKeyService: masterKeyService,
 			RequireAuth:      true,
+			Redis:            redisClient,
 		})
 		r.Use(authMiddleware.Authenticate)
 
+		// Load shedding (after auth, so the master key is never shed)
+		if loadSheddingMiddleware != nil {
+			r.Use(loadSheddingMiddleware.Middleware)
+		}
+
+		// Concurrency limits (after auth, so the key and its team are known)
+		r.Use(concurrencyMiddleware.Limit)
+
+		// Team default model and aliases (before anything that looks at the model)
+		r.Use(teamModelMiddleware.Middleware)
+
+		// Emergency controls (after the team model middleware, so aliases cannot reach a disabled model)
+		if emergencyMiddleware != nil {
+			r.Use(emergencyMiddleware.Middleware)
+		}
+
+		// Scaling signals (after the team model middleware, so requests count against the model they are sent to)
+		if scalingSignalsMiddleware != nil {
+			r.Use(scalingSignalsMiddleware.Middleware)
+		}
+
+		// Bandwidth metering (outside response minimization and prompt rewrites, so the bytes the client sees are counted)
+		if bandwidthMiddleware != nil {
+			r.Use(bandwidthMiddleware.Middleware)
+		}
+
+		// Response minimization (outside the output cache and usage tracking, which keep full responses)
+		r.Use(responseMinimizationMiddleware.Middleware)
+
+		// Team system prompt (before guardrails and context window management, so they see the full prompt)
+		r.Use(teamSystemPromptMiddleware.Middleware)
+
+		// Route defaults (before the context window, output cache and budget, so they see the parameters the request is sent with)
+		if routeDefaultsMiddleware != nil {
+			r.Use(routeDefaultsMiddleware.Middleware)
+		}
+
+		// Request validation (before guardrails and the handlers decode the request)
+		r.Use(requestValidationMiddleware.Middleware)
+
 		// Guardrails middleware (after auth, before budget)
 		if guardrailsExecutor != nil {
 			guardrailsMiddleware := middleware.NewGuardrailsMiddleware(guardrailsExecutor, logger)
 			r.Use(guardrailsMiddleware.Middleware)
 		}
 
+		// Context window management (after guardrails, so the budget estimate sees the fitted prompt)
+		if contextWindowMiddleware != nil {
+			r.Use(contextWindowMiddleware.Middleware)
+		}
+
 		// Initialize pricing cache for better performance
-		pricingCache := cache.NewPricingCache(redisClient, logger, pricingManager)
-		
-		// Load all pricing data to Redis cache on startup
-		if err := pricingCache.LoadAllPricingToCache(context.Background()); err != nil {
-			logger.Warn("Failed to load pricing data to cache", zap.Error(err))
+		var pricingCache *cache.PricingCache
+		if redisClient != nil {
+			pricingCache = cache.NewPricingCache(redisClient, logger, pricingManager)
+
+			// Load all pricing data to Redis cache on startup
+			if err := pricingCache.LoadAllPricingToCache(context.Background()); err != nil {
+				logger.Warn("Failed to load pricing data to cache", zap.Error(err))
+			}
+		}
+
+		// Block team keys whose prepaid credits ran out
+		if creditsService != nil {
+			r.Use(middleware.NewCreditsMiddleware(creditsService, logger).Enforce)
+		}
+
+		// Output cache (before budget tracking, so hits are not billed)
+		if outputCacheMiddleware != nil {
+			r.Use(outputCacheMiddleware.Middleware)
+		}
+
+		// Request coalescing (after the output cache, so hits never wait, and
+		// before budget tracking, so coalesced requests are not billed)
+		if coalescingMiddleware != nil {
+			r.Use(coalescingMiddleware.Middleware)
 		}
 
 		// Use async budget middleware with Redis for high performance
 		asyncBudgetMiddleware := middleware.NewAsyncBudgetMiddleware(&middleware.AsyncBudgetConfig{
-			Logger:         logger,
-			AuthService:    authService,
-			BudgetCache:    redisService.NewBudgetCache(redisClient, logger, 5*time.Minute),
-			EventPub:       redisService.NewEventPublisher(redisClient, logger),
-			UsageQueue:     redisService.NewUsageQueue(&redisService.UsageQueueConfig{
-				Client:     redisClient,
-				Logger:     logger,
-				QueueName:  "usage_processing_queue",
-				BatchSize:  50,
-				MaxRetries: 3,
-			}),
-			PricingManager: pricingManager,
-			PricingCache:   pricingCache,
+			Logger:                   logger,
+			AuthService:              authService,
+			BudgetCache:              coordinationBackends.BudgetCache,
+			EventPub:                 coordinationBackends.EventPub,
+			UsageQueue:               coordinationBackends.UsageQueue,
+			PricingManager:           pricingManager,
+			PricingCache:             pricingCache,
+			MemoryGuard:              usageMemoryGuard,
+			MarkupPercent:            cfg.Billing.MarkupPercent,
+			BandwidthPricePerGB:      cfg.Bandwidth.PricePerGB,
+			StreamCheckpointInterval: cfg.Billing.StreamCheckpointInterval,
+			RouteDefaults:            routeDefaults,
 		})
 		r.Use(asyncBudgetMiddleware.EnforceBudgetAsync)
 
@@ -321,6 +833,7 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		r.Route("/v1", func(r chi.Router) {
 			// Chat completions - use a custom handler that preserves Flusher
 			r.HandleFunc("/chat/completions", chatHandler.ChatCompletions)
+			r.Post("/chat/completions/compare", chatHandler.CompareCompletions)
 
 			// Anthropic Messages API format (LiteLLM compatible)
 			r.HandleFunc("/messages", messagesHandler.AnthropicMessages)
@@ -330,10 +843,13 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 
 			// Embeddings
 			r.Post("/embeddings", embeddingsHandler.Embeddings)
+			r.Post("/rerank", embeddingsHandler.Rerank)
 
 			// Models
 			r.Get("/models", modelsHandler.ListModels)
+			r.Get("/models/catalog", modelsHandler.Catalog)
 			r.Get("/models/{model}", modelsHandler.GetModel)
+			r.Get("/capabilities", capabilitiesHandler.GetCapabilities)
 			
 			// Model Management (LiteLLM-compatible)
 			r.Get("/model/info", modelMgmtHandler.GetModelInfo)
@@ -361,6 +877,34 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 			// Moderations
 			r.Post("/moderations", moderationHandler.CreateModeration)
 
+			// Async jobs
+			if jobsHandler != nil {
+				r.Get("/jobs/{id}", jobsHandler.GetJob)
+			}
+
+			// Usage and billing (OpenAI dashboard compatibility)
+			if usageCompatHandler != nil {
+				r.Get("/usage", usageCompatHandler.GetUsage)
+				r.Get("/dashboard/billing/usage", usageCompatHandler.GetBillingUsage)
+				r.Get("/dashboard/billing/subscription", usageCompatHandler.GetSubscription)
+			}
+
+			// Response feedback
+			if feedbackHandler != nil {
+				r.Post("/feedback", feedbackHandler.SubmitFeedback)
+			}
+
+			// Context caches
+			if cachesHandler != nil {
+				r.Route("/caches", func(r chi.Router) {
+					r.Post("/", cachesHandler.CreateCache)
+					r.Get("/", cachesHandler.ListCaches)
+					r.Get("/{id}", cachesHandler.GetCache)
+					r.Patch("/{id}", cachesHandler.UpdateCache)
+					r.Delete("/{id}", cachesHandler.DeleteCache)
+				})
+			}
+
 			// Realtime API (WebSocket)
 			r.Get("/realtime", realtimeHandler.ConnectRealtime)
 			r.Route("/realtime/sessions", func(r chi.Router) {
@@ -380,6 +924,9 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 			r.Get("/keys", authHandler.ListAPIKeys)
 			r.Post("/keys", authHandler.CreateAPIKey)
 			r.Delete("/keys/{key_id}", authHandler.DeleteAPIKey)
+			r.Post("/keys/{key_id}/rotate", authHandler.RotateAPIKey)
+			r.Put("/keys/{key_id}/webhook", authHandler.SetKeyWebhook)
+			r.Delete("/keys/{key_id}/webhook", authHandler.DeleteKeyWebhook)
 
 			// Usage
 			r.Get("/usage", authHandler.GetUsage)
@@ -388,6 +935,10 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 			r.Get("/budget", authHandler.GetBudgetStatus)
 			r.Get("/teams", authHandler.GetUserTeams)
 
+			// Notifications
+			r.Get("/notifications", authHandler.GetNotificationPreferences)
+			r.Put("/notifications", authHandler.UpdateNotificationPreferences)
+
 		})
 
 		// Admin routes for monitoring
@@ -405,38 +956,102 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 			AuthService:      authService,
 			MasterKeyService: masterKeyService,
 			RequireAuth:      true,
+			Redis:            redisClient,
 		})
 		r.Use(authMiddleware.Authenticate)
 
+		// Load shedding (after auth, so the master key is never shed)
+		if loadSheddingMiddleware != nil {
+			r.Use(loadSheddingMiddleware.Middleware)
+		}
+
+		// Concurrency limits (after auth, so the key and its team are known)
+		r.Use(concurrencyMiddleware.Limit)
+
+		// Team default model and aliases (before anything that looks at the model)
+		r.Use(teamModelMiddleware.Middleware)
+
+		// Emergency controls (after the team model middleware, so aliases cannot reach a disabled model)
+		if emergencyMiddleware != nil {
+			r.Use(emergencyMiddleware.Middleware)
+		}
+
+		// Scaling signals (after the team model middleware, so requests count against the model they are sent to)
+		if scalingSignalsMiddleware != nil {
+			r.Use(scalingSignalsMiddleware.Middleware)
+		}
+
+		// Bandwidth metering (outside response minimization and prompt rewrites, so the bytes the client sees are counted)
+		if bandwidthMiddleware != nil {
+			r.Use(bandwidthMiddleware.Middleware)
+		}
+
+		// Response minimization (outside the output cache and usage tracking, which keep full responses)
+		r.Use(responseMinimizationMiddleware.Middleware)
+
+		// Team system prompt (before guardrails and context window management, so they see the full prompt)
+		r.Use(teamSystemPromptMiddleware.Middleware)
+
+		// Route defaults (before the context window, output cache and budget, so they see the parameters the request is sent with)
+		if routeDefaultsMiddleware != nil {
+			r.Use(routeDefaultsMiddleware.Middleware)
+		}
+
+		// Request validation (before guardrails and the handlers decode the request)
+		r.Use(requestValidationMiddleware.Middleware)
+
 		// Guardrails middleware (after auth, before budget)
 		if guardrailsExecutor != nil {
 			guardrailsMiddleware := middleware.NewGuardrailsMiddleware(guardrailsExecutor, logger)
 			r.Use(guardrailsMiddleware.Middleware)
 		}
 
+		// Context window management (after guardrails, so the budget estimate sees the fitted prompt)
+		if contextWindowMiddleware != nil {
+			r.Use(contextWindowMiddleware.Middleware)
+		}
+
 		// Initialize pricing cache for better performance
-		pricingCache := cache.NewPricingCache(redisClient, logger, pricingManager)
-		
-		// Load all pricing data to Redis cache on startup
-		if err := pricingCache.LoadAllPricingToCache(context.Background()); err != nil {
-			logger.Warn("Failed to load pricing data to cache", zap.Error(err))
+		var pricingCache *cache.PricingCache
+		if redisClient != nil {
+			pricingCache = cache.NewPricingCache(redisClient, logger, pricingManager)
+
+			// Load all pricing data to Redis cache on startup
+			if err := pricingCache.LoadAllPricingToCache(context.Background()); err != nil {
+				logger.Warn("Failed to load pricing data to cache", zap.Error(err))
+			}
+		}
+
+		// Block team keys whose prepaid credits ran out
+		if creditsService != nil {
+			r.Use(middleware.NewCreditsMiddleware(creditsService, logger).Enforce)
+		}
+
+		// Output cache (before budget tracking, so hits are not billed)
+		if outputCacheMiddleware != nil {
+			r.Use(outputCacheMiddleware.Middleware)
+		}
+
+		// Request coalescing (after the output cache, so hits never wait, and
+		// before budget tracking, so coalesced requests are not billed)
+		if coalescingMiddleware != nil {
+			r.Use(coalescingMiddleware.Middleware)
 		}
 
 		// Use async budget middleware with Redis for high performance
 		asyncBudgetMiddleware := middleware.NewAsyncBudgetMiddleware(&middleware.AsyncBudgetConfig{
-			Logger:         logger,
-			AuthService:    authService,
-			BudgetCache:    redisService.NewBudgetCache(redisClient, logger, 5*time.Minute),
-			EventPub:       redisService.NewEventPublisher(redisClient, logger),
-			UsageQueue:     redisService.NewUsageQueue(&redisService.UsageQueueConfig{
-				Client:     redisClient,
-				Logger:     logger,
-				QueueName:  "usage_processing_queue",
-				BatchSize:  50,
-				MaxRetries: 3,
-			}),
-			PricingManager: pricingManager,
-			PricingCache:   pricingCache,
+			Logger:                   logger,
+			AuthService:              authService,
+			BudgetCache:              coordinationBackends.BudgetCache,
+			EventPub:                 coordinationBackends.EventPub,
+			UsageQueue:               coordinationBackends.UsageQueue,
+			PricingManager:           pricingManager,
+			PricingCache:             pricingCache,
+			MemoryGuard:              usageMemoryGuard,
+			MarkupPercent:            cfg.Billing.MarkupPercent,
+			BandwidthPricePerGB:      cfg.Bandwidth.PricePerGB,
+			StreamCheckpointInterval: cfg.Billing.StreamCheckpointInterval,
+			RouteDefaults:            routeDefaults,
 		})
 		r.Use(asyncBudgetMiddleware.EnforceBudgetAsync)
 
@@ -446,6 +1061,7 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 		r.Route("/api/v1", func(r chi.Router) {
 			// Chat completions
 			r.Post("/chat/completions", chatHandler.ChatCompletions)
+			r.Post("/chat/completions/compare", chatHandler.CompareCompletions)
 
 			// Anthropic Messages API format (LiteLLM compatible)
 			r.Post("/messages", messagesHandler.AnthropicMessages)
@@ -455,10 +1071,13 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 
 			// Embeddings
 			r.Post("/embeddings", embeddingsHandler.Embeddings)
+			r.Post("/rerank", embeddingsHandler.Rerank)
 
 			// Models
 			r.Get("/models", modelsHandler.ListModels)
+			r.Get("/models/catalog", modelsHandler.Catalog)
 			r.Get("/models/{model}", modelsHandler.GetModel)
+			r.Get("/capabilities", capabilitiesHandler.GetCapabilities)
 
 			// Images
 			r.Post("/images/generations", imagesHandler.GenerateImage)
@@ -471,6 +1090,34 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 			// Moderations
 			r.Post("/moderations", moderationHandler.CreateModeration)
 
+			// Async jobs
+			if jobsHandler != nil {
+				r.Get("/jobs/{id}", jobsHandler.GetJob)
+			}
+
+			// Usage and billing (OpenAI dashboard compatibility)
+			if usageCompatHandler != nil {
+				r.Get("/usage", usageCompatHandler.GetUsage)
+				r.Get("/dashboard/billing/usage", usageCompatHandler.GetBillingUsage)
+				r.Get("/dashboard/billing/subscription", usageCompatHandler.GetSubscription)
+			}
+
+			// Response feedback
+			if feedbackHandler != nil {
+				r.Post("/feedback", feedbackHandler.SubmitFeedback)
+			}
+
+			// Context caches
+			if cachesHandler != nil {
+				r.Route("/caches", func(r chi.Router) {
+					r.Post("/", cachesHandler.CreateCache)
+					r.Get("/", cachesHandler.ListCaches)
+					r.Get("/{id}", cachesHandler.GetCache)
+					r.Patch("/{id}", cachesHandler.UpdateCache)
+					r.Delete("/{id}", cachesHandler.DeleteCache)
+				})
+			}
+
 			// Realtime API (WebSocket) - authenticated
 			r.Get("/realtime", realtimeHandler.ConnectRealtime)
 			r.Route("/realtime/sessions", func(r chi.Router) {
@@ -483,19 +1130,13 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 
 	// Admin routes - mount if database is available
 	if db != nil {
-		// Create unified budget service using the existing Redis components
+		// Create unified budget service using the shared coordination components
 		budgetService := budget.NewUnifiedService(&budget.UnifiedServiceConfig{
 			DB:          db,
 			Logger:      logger,
-			BudgetCache: redisService.NewBudgetCache(redisClient, logger, 5*time.Minute),
-			UsageQueue: redisService.NewUsageQueue(&redisService.UsageQueueConfig{
-				Client:     redisClient,
-				Logger:     logger,
-				QueueName:  "usage_processing_queue",
-				BatchSize:  50,
-				MaxRetries: 3,
-			}),
-			EventPub: redisService.NewEventPublisher(redisClient, logger),
+			BudgetCache: coordinationBackends.BudgetCache,
+			UsageQueue:  coordinationBackends.UsageQueue,
+			EventPub:    coordinationBackends.EventPub,
 		})
 
 		// Create admin sub-router configuration
@@ -508,6 +1149,15 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 			ModelManager:        modelManager,
 			BudgetService:       budgetService,
 			GuardrailsExecutor:  guardrailsExecutor,
+			Notifier:            notifier,
+			Onboarder:           onboarder,
+			UsageCounters:       coordinationBackends.Counters,
+			Credits:             creditsService,
+			TeamProviderKeys:    teamProviderKeys,
+			Settings:            settingsStore,
+			Artifacts:           artifactService,
+			Deletion:            deletionService,
+			Cluster:             clusterRegistrar,
 		}
 
 		// Mount admin routes at /api/admin
@@ -568,3 +1218,27 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, modelManager *models.Mode
 
 	return r
 }
+
+// newRedisClient creates a Redis client from config and verifies the connection
+func newRedisClient(cfg *config.Config) (*redis.Client, error) {
+	opt, err := redis.ParseURL(cfg.Redis.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	// Override with explicit password and DB if provided
+	if cfg.Redis.Password != "" {
+		opt.Password = cfg.Redis.Password
+	}
+	if cfg.Redis.DB != 0 {
+		opt.DB = cfg.Redis.DB
+	}
+
+	client := redis.NewClient(opt)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
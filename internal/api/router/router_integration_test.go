@@ -118,7 +118,7 @@ func TestRouterIntegration(t *testing.T) {
 	pricingManager := config.GetPricingManager()
 
 	// Create router
-	router := NewRouter(cfg, logger, modelManager, db, pricingManager)
+	router := NewRouter(cfg, logger, modelManager, db, pricingManager, nil)
 
 	t.Run("Health Endpoints", func(t *testing.T) {
 		testHealthEndpoints(t, router)
@@ -313,7 +313,7 @@ func TestRouterLatencyRequirements(t *testing.T) {
 	}
 	modelManager := models.NewModelManager(logger, routerSettings, nil)
 	pricingManager := config.GetPricingManager()
-	router := NewRouter(cfg, logger, modelManager, db, pricingManager)
+	router := NewRouter(cfg, logger, modelManager, db, pricingManager, nil)
 
 	// Banking latency requirements
 	const (
@@ -427,7 +427,7 @@ func TestRouterFailover(t *testing.T) {
 	err := modelManager.LoadModelInstances(testInstances)
 	require.NoError(t, err)
 
-	router := NewRouter(cfg, logger, modelManager, db, pricingManager)
+	router := NewRouter(cfg, logger, modelManager, db, pricingManager, nil)
 
 	t.Run("Model Failover", func(t *testing.T) {
 		// Test that requests to unavailable models fail gracefully
@@ -16,36 +16,15 @@ import (
 
 
 
-// mockTeamService implements TeamService for testing
-type mockTeamService struct{}
-
-func (m *mockTeamService) AddUserToDefaultTeam(ctx context.Context, userID uuid.UUID, role models.TeamRole) (*models.TeamMember, error) {
-	return &models.TeamMember{
-		UserID: userID,
-		TeamID: uuid.New(),
-		Role:   role,
-	}, nil
-}
-
-// mockKeyService implements KeyService for testing
-type mockKeyService struct{}
+// mockOnboarder implements Onboarder for testing
+type mockOnboarder struct{}
 
-func (m *mockKeyService) CreateDefaultKeyForUser(ctx context.Context, userID uuid.UUID, teamID uuid.UUID) (*models.Key, error) {
-	keyGen := key.NewKeyGenerator()
-	_, hash, err := keyGen.GenerateAPIKey()
-	if err != nil {
-		return nil, err
-	}
+func (m *mockOnboarder) OnboardUser(ctx context.Context, user *models.User, groups []string) error {
+	return nil
+}
 
-	return &models.Key{
-		BaseModel: models.BaseModel{ID: uuid.New()},
-		Name:      "Default Key",
-		KeyHash:   hash,
-		Type:      models.KeyTypeAPI,
-		UserID:    &userID,
-		TeamID:    &teamID,
-		IsActive:  true,
-	}, nil
+func (m *mockOnboarder) ProvisionedRole(email string, groups []string) models.UserRole {
+	return models.RoleUser
 }
 
 func TestAuthService_ValidateKey(t *testing.T) {
@@ -69,8 +48,7 @@ func TestAuthService_ValidateKey(t *testing.T) {
 		JWTIssuer:        "test-issuer",
 		TokenExpiry:      time.Hour,
 		MasterKeyService: masterKeySvc,
-		TeamService:      &mockTeamService{},
-		KeyService:       &mockKeyService{},
+		Onboarder:        &mockOnboarder{},
 	}
 
 	authSvc, err := NewAuthService(authConfig)
@@ -312,6 +290,91 @@ func TestMasterKeyService_ValidateMasterKey(t *testing.T) {
 	})
 }
 
+func TestMasterKeyService_ExchangeMasterKey(t *testing.T) {
+	masterKeySvc := NewMasterKeyService(&MasterKeyConfig{
+		MasterKey:      "test-master-key-123",
+		JWTSecret:      []byte("test-jwt-secret"),
+		JWTIssuer:      "test-issuer",
+		TokenExpiry:    15 * time.Minute,
+		MaxTokenExpiry: time.Hour,
+	})
+	authSvc, err := NewAuthService(&AuthConfig{JWTSecret: "test-jwt-secret", MasterKeyService: masterKeySvc})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("Defaults", func(t *testing.T) {
+		token, err := masterKeySvc.ExchangeMasterKey(ctx, "test-master-key-123", AdminTokenRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{models.ScopeAll}, token.Scopes)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), token.ExpiresAt, 5*time.Second)
+
+		claims, err := authSvc.ValidateToken(token.Token)
+		require.NoError(t, err)
+		assert.True(t, claims.IsMasterKeyToken())
+		assert.Equal(t, []string{models.ScopeAll}, claims.Scopes)
+		assert.Equal(t, token.ID, claims.ID)
+	})
+
+	t.Run("Scoped", func(t *testing.T) {
+		token, err := masterKeySvc.ExchangeMasterKey(ctx, "test-master-key-123", AdminTokenRequest{
+			Scopes: []string{models.ScopeAdminRead, models.ScopeChat},
+			TTL:    time.Hour,
+		})
+		require.NoError(t, err)
+
+		claims, err := authSvc.ValidateToken(token.Token)
+		require.NoError(t, err)
+		assert.Equal(t, []string{models.ScopeAdminRead, models.ScopeChat}, claims.Scopes)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, 5*time.Second)
+	})
+
+	t.Run("Invalid Master Key", func(t *testing.T) {
+		_, err := masterKeySvc.ExchangeMasterKey(ctx, "wrong-master-key", AdminTokenRequest{})
+		assert.ErrorIs(t, err, ErrMasterKeyRequired)
+	})
+
+	t.Run("Invalid Requests", func(t *testing.T) {
+		_, err := masterKeySvc.ExchangeMasterKey(ctx, "test-master-key-123", AdminTokenRequest{TTL: 2 * time.Hour})
+		assert.ErrorIs(t, err, ErrInvalidTokenRequest)
+
+		_, err = masterKeySvc.ExchangeMasterKey(ctx, "test-master-key-123", AdminTokenRequest{Scopes: []string{"admin:write"}})
+		assert.ErrorIs(t, err, ErrInvalidTokenRequest)
+	})
+
+	t.Run("Master Key Token Only", func(t *testing.T) {
+		assert.False(t, (&TokenClaims{UserID: uuid.New()}).IsMasterKeyToken())
+	})
+}
+
+func TestMasterKeyService_ExchangeAudited(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+
+	masterKeySvc := NewMasterKeyService(&MasterKeyConfig{
+		DB:        db,
+		MasterKey: "test-master-key-123",
+		JWTSecret: []byte("test-jwt-secret"),
+	})
+	ctx := context.Background()
+
+	token, err := masterKeySvc.ExchangeMasterKey(ctx, "test-master-key-123", AdminTokenRequest{
+		Scopes:    []string{ScopeAdmin},
+		IPAddress: "10.0.0.1",
+	})
+	require.NoError(t, err)
+	_, err = masterKeySvc.ExchangeMasterKey(ctx, "wrong-master-key", AdminTokenRequest{IPAddress: "10.0.0.2"})
+	require.Error(t, err)
+
+	var audits []models.Audit
+	require.NoError(t, db.Where("event_action = ?", "master_key_token_issue").Order("timestamp").Find(&audits).Error)
+	require.Len(t, audits, 2)
+	assert.Equal(t, models.AuditResultSuccess, audits[0].EventResult)
+	assert.Equal(t, "10.0.0.1", audits[0].IPAddress)
+	assert.Contains(t, string(audits[0].Metadata), token.ID)
+	assert.Equal(t, models.AuditResultFailure, audits[1].EventResult)
+	assert.Equal(t, "10.0.0.2", audits[1].IPAddress)
+}
+
 func TestKeyValidation_ModelAccess(t *testing.T) {
 	db, cleanup := testutil.NewTestDB(t)
 	defer cleanup()
@@ -431,4 +494,34 @@ func TestKeyValidation_BudgetAndLimits(t *testing.T) {
 		assert.Equal(t, 100, gotRPM)
 		assert.Equal(t, 5, gotParallel)
 	})
-}
\ No newline at end of file
+}
+func TestAuthService_GenerateJWT_AuthTime(t *testing.T) {
+	svc := &AuthService{
+		jwtSecret:   []byte("test-jwt-secret"),
+		jwtIssuer:   "test-issuer",
+		tokenExpiry: time.Hour,
+	}
+	user := &models.User{Username: "alice", Role: models.RoleUser}
+	user.ID = uuid.New()
+
+	t.Run("Refreshed token keeps the login time", func(t *testing.T) {
+		loginAt := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+		token, err := svc.generateJWT(user, &loginAt)
+		require.NoError(t, err)
+
+		claims, err := svc.ValidateToken(token)
+		require.NoError(t, err)
+		require.NotNil(t, claims.AuthTime)
+		assert.True(t, claims.AuthTime.Time.Equal(loginAt))
+		assert.True(t, claims.IssuedAt.Time.After(loginAt))
+	})
+
+	t.Run("Unknown login time omits the claim", func(t *testing.T) {
+		token, err := svc.generateJWT(user, nil)
+		require.NoError(t, err)
+
+		claims, err := svc.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Nil(t, claims.AuthTime)
+	})
+}
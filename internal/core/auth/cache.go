@@ -10,6 +10,8 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 )
 
 // Cache provides caching functionality for auth-related data
@@ -136,7 +138,7 @@ func (c *Cache) buildKey(key string) string {
 	hash := sha256.Sum256([]byte(key))
 	hashStr := hex.EncodeToString(hash[:])[:16] // Use first 16 chars of hash
 
-	return fmt.Sprintf("auth:%s:%s", hashStr, key)
+	return redisService.Key(fmt.Sprintf("auth:%s:%s", hashStr, key))
 }
 
 // CacheUserPermissions caches user permissions
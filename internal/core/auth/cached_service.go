@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -47,11 +48,16 @@ func NewCachedAuthService(authService *AuthService, logger *zap.Logger) *CachedA
 	// Start cleanup goroutine
 	go cache.cleanup()
 
-	return &CachedAuthService{
+	c := &CachedAuthService{
 		authService: authService,
 		cache:       cache,
 		logger:      logger,
 	}
+	// Keys changed on any gateway must not be served from this cache
+	if authService != nil && authService.KeyCache() != nil {
+		authService.KeyCache().OnInvalidate(c.InvalidateKeyID)
+	}
+	return c
 }
 
 // ValidateKeyCached validates an API key with caching
@@ -86,6 +92,9 @@ func (c *CachedAuthService) ValidateTokenCached(ctx context.Context, tokenString
 	// Try cache first
 	if cached, found := c.cache.get(cacheKey); found {
 		if tokenData, ok := cached.(*CachedTokenClaims); ok {
+			if tokenData.ExpiresAt != nil && time.Now().After(tokenData.ExpiresAt.Time) {
+				return nil, ErrTokenExpired
+			}
 			c.logger.Debug("Token validation cache hit", zap.String("cache_key", cacheKey))
 			return tokenData, nil
 		}
@@ -180,6 +189,22 @@ func (c *CachedAuthService) InvalidateKeyCache(keyValue string) {
 	c.logger.Debug("Key cache invalidated", zap.String("cache_key", cacheKey))
 }
 
+// InvalidateKeyID removes a key from cache by ID, or every key for uuid.Nil
+func (c *CachedAuthService) InvalidateKeyID(keyID uuid.UUID) {
+	c.cache.mutex.Lock()
+	defer c.cache.mutex.Unlock()
+
+	for cacheKey, item := range c.cache.data {
+		if !strings.HasPrefix(cacheKey, "key:") {
+			continue
+		}
+		if key, ok := item.value.(*models.Key); ok && (keyID == uuid.Nil || key.ID == keyID) {
+			delete(c.cache.data, cacheKey)
+		}
+	}
+	c.logger.Debug("Key cache invalidated", zap.String("key_id", keyID.String()))
+}
+
 // InvalidateTokenCache removes a token from cache
 func (c *CachedAuthService) InvalidateTokenCache(tokenString string) {
 	cacheKey := fmt.Sprintf("token:%s", models.HashKey(tokenString))
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+	"github.com/amerfu/pllm/internal/services/data/deletion"
+	"github.com/amerfu/pllm/internal/services/data/keycache"
+	"github.com/amerfu/pllm/internal/services/integrations/key"
+)
+
+// newCachedAuthService creates an auth service validating keys through a
+// key cache that follows the writes made through db
+func newCachedAuthService(t *testing.T, db *gorm.DB) (*AuthService, *keycache.KeyCache) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	cache := keycache.New(&keycache.Config{DB: db, Redis: client, Logger: zap.NewNop()})
+	require.NoError(t, cache.RegisterCallbacks(db))
+
+	authSvc, err := NewAuthService(&AuthConfig{
+		DB:          db,
+		JWTSecret:   "test-secret",
+		JWTIssuer:   "test-issuer",
+		TokenExpiry: time.Hour,
+		MasterKeyService: NewMasterKeyService(&MasterKeyConfig{
+			DB:          db,
+			MasterKey:   "test-master-key",
+			JWTSecret:   []byte("test-secret"),
+			JWTIssuer:   "test-issuer",
+			TokenExpiry: time.Hour,
+		}),
+		Onboarder: &mockOnboarder{},
+	})
+	require.NoError(t, err)
+	authSvc.SetKeyCache(cache)
+	return authSvc, cache
+}
+
+func TestKeyCache_RestoredKeyValidates(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	authSvc, cache := newCachedAuthService(t, db)
+
+	user := models.User{BaseModel: models.BaseModel{ID: uuid.New()}, Email: "restore@example.com", Username: "restore", IsActive: true}
+	require.NoError(t, db.Create(&user).Error)
+	plaintext, hash, err := key.NewKeyGenerator().GenerateAPIKey()
+	require.NoError(t, err)
+	apiKey := models.Key{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		Name:      "Restored Key",
+		KeyHash:   hash,
+		Type:      models.KeyTypeAPI,
+		UserID:    &user.ID,
+		IsActive:  true,
+	}
+	require.NoError(t, db.Create(&apiKey).Error)
+
+	deletions := deletion.NewService(db, config.DeletionConfig{Retention: time.Hour}, zap.NewNop())
+	require.NoError(t, deletions.DeleteKey(ctx, apiKey.ID))
+
+	// The reload leaves the deleted key out of the filter
+	require.NoError(t, cache.Load(ctx))
+	assert.False(t, cache.MightExist(hash))
+	_, err = authSvc.ValidateKey(ctx, plaintext)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, err = deletions.RestoreKey(ctx, apiKey.ID)
+	require.NoError(t, err)
+	assert.True(t, cache.MightExist(hash), "the restored key rejoins the filter")
+
+	validated, err := authSvc.ValidateKey(ctx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, apiKey.ID, validated.ID)
+}
+
+// staticKeyCache serves one key as if another gateway had validated it
+type staticKeyCache struct {
+	key *models.Key
+}
+
+func (c *staticKeyCache) MightExist(keyHash string) bool { return true }
+
+func (c *staticKeyCache) Get(ctx context.Context, keyHash string) (*models.Key, bool) {
+	if keyHash != c.key.KeyHash {
+		return nil, false
+	}
+	cached := *c.key
+	return &cached, true
+}
+
+func (c *staticKeyCache) Set(ctx context.Context, key *models.Key) {}
+
+func (c *staticKeyCache) OnInvalidate(fn func(keyID uuid.UUID)) {}
+
+func TestKeyCache_CachedValidationRecordsUsage(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	authSvc, _ := newCachedAuthService(t, db)
+
+	user := models.User{BaseModel: models.BaseModel{ID: uuid.New()}, Email: "cached@example.com", Username: "cached", IsActive: true}
+	require.NoError(t, db.Create(&user).Error)
+	plaintext, hash, err := key.NewKeyGenerator().GenerateAPIKey()
+	require.NoError(t, err)
+	apiKey := models.Key{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		Name:      "Cached Key",
+		KeyHash:   hash,
+		Type:      models.KeyTypeAPI,
+		UserID:    &user.ID,
+		IsActive:  true,
+	}
+	require.NoError(t, db.Create(&apiKey).Error)
+	authSvc.SetKeyCache(&staticKeyCache{key: &apiKey})
+
+	validated, err := authSvc.ValidateKey(ctx, plaintext)
+	require.NoError(t, err)
+	assert.NotNil(t, validated.LastUsedAt)
+
+	var stored models.Key
+	require.NoError(t, db.First(&stored, "id = ?", apiKey.ID).Error)
+	assert.Equal(t, int64(1), stored.UsageCount, "cached validations count as uses")
+	assert.NotNil(t, stored.LastUsedAt)
+
+	var audits int64
+	require.NoError(t, db.Model(&models.Audit{}).
+		Where("key_id = ? AND event_type = ?", apiKey.ID, models.AuditEventKeyUsage).
+		Count(&audits).Error)
+	assert.Equal(t, int64(1), audits)
+}
+
+func TestKeyCache_OwnerChangesDropCachedKeys(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	authSvc, cache := newCachedAuthService(t, db)
+
+	user := models.User{BaseModel: models.BaseModel{ID: uuid.New()}, Email: "owner@example.com", Username: "owner", IsActive: true}
+	require.NoError(t, db.Create(&user).Error)
+	team := models.Team{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "Owners", IsActive: true}
+	require.NoError(t, db.Create(&team).Error)
+	plaintext, hash, err := key.NewKeyGenerator().GenerateAPIKey()
+	require.NoError(t, err)
+	apiKey := models.Key{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		Name:      "Team Key",
+		KeyHash:   hash,
+		Type:      models.KeyTypeAPI,
+		UserID:    &user.ID,
+		TeamID:    &team.ID,
+		IsActive:  true,
+	}
+	require.NoError(t, db.Create(&apiKey).Error)
+
+	validate := func() {
+		_, err := authSvc.ValidateKey(ctx, plaintext)
+		require.NoError(t, err)
+		_, ok := cache.Get(ctx, hash)
+		require.True(t, ok)
+	}
+
+	validate()
+	require.NoError(t, db.Model(&team).Update("max_parallel_calls", 2).Error)
+	_, ok := cache.Get(ctx, hash)
+	assert.False(t, ok, "keys of a changed team are dropped")
+
+	validated, err := authSvc.ValidateKey(ctx, plaintext)
+	require.NoError(t, err)
+	require.NotNil(t, validated.Team)
+	assert.Equal(t, 2, validated.Team.MaxParallelCalls)
+
+	validate()
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Update("is_active", false).Error)
+	_, ok = cache.Get(ctx, hash)
+	assert.False(t, ok, "keys of a changed user are dropped")
+
+	validated, err = authSvc.ValidateKey(ctx, plaintext)
+	require.NoError(t, err)
+	require.NotNil(t, validated.User)
+	assert.False(t, validated.User.IsActive)
+
+	validate()
+	require.NoError(t, db.Model(&team).Update("current_spend", 1.5).Error)
+	_, ok = cache.Get(ctx, hash)
+	assert.True(t, ok, "spend updates leave the cached key valid")
+}
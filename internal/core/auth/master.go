@@ -2,6 +2,10 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -12,21 +16,58 @@ import (
 	"github.com/amerfu/pllm/internal/core/models"
 )
 
+// MasterKeyUserID is the user ID carried by admin tokens issued for the
+// master key
+var MasterKeyUserID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// ScopeAdmin grants an admin token full access to the admin API. Admin
+// tokens otherwise take the key scopes: admin:read limits the admin API to
+// reads, and the LLM scopes limit the LLM endpoints.
+const ScopeAdmin = "admin"
+
+// ErrInvalidTokenRequest is returned for admin token requests with unknown
+// scopes or a lifetime above the configured maximum
+var ErrInvalidTokenRequest = errors.New("invalid admin token request")
+
 // MasterKeyService handles master key operations
 type MasterKeyService struct {
-	db          *gorm.DB
-	masterKey   string
-	jwtSecret   []byte
-	jwtIssuer   string
-	tokenExpiry time.Duration
+	db              *gorm.DB
+	masterKey       string
+	jwtSecret       []byte
+	jwtIssuer       string
+	tokenExpiry     time.Duration
+	maxTokenExpiry  time.Duration
+	requireExchange bool
 }
 
 type MasterKeyConfig struct {
-	DB          *gorm.DB
-	MasterKey   string
-	JWTSecret   []byte
-	JWTIssuer   string
-	TokenExpiry time.Duration
+	DB             *gorm.DB
+	MasterKey      string
+	JWTSecret      []byte
+	JWTIssuer      string
+	TokenExpiry    time.Duration // Default lifetime of admin tokens
+	MaxTokenExpiry time.Duration // Longest lifetime a caller may request
+
+	// RequireExchange rejects the master key on every endpoint but the
+	// admin token exchange
+	RequireExchange bool
+}
+
+// AdminTokenRequest describes the admin token requested in exchange for the
+// master key
+type AdminTokenRequest struct {
+	Scopes    []string      // Defaults to all scopes
+	TTL       time.Duration // Defaults to the configured token expiry
+	IPAddress string
+	UserAgent string
+}
+
+// AdminToken is an admin token issued for the master key
+type AdminToken struct {
+	Token     string
+	ID        string
+	Scopes    []string
+	ExpiresAt time.Time
 }
 
 // MasterKeyContext represents a master key authentication context
@@ -40,7 +81,10 @@ type MasterKeyContext struct {
 func NewMasterKeyService(config *MasterKeyConfig) *MasterKeyService {
 	// Set defaults if not provided
 	if config.TokenExpiry == 0 {
-		config.TokenExpiry = 24 * time.Hour
+		config.TokenExpiry = time.Hour
+	}
+	if config.MaxTokenExpiry < config.TokenExpiry {
+		config.MaxTokenExpiry = config.TokenExpiry
 	}
 	if config.JWTIssuer == "" {
 		config.JWTIssuer = "pllm"
@@ -50,11 +94,13 @@ func NewMasterKeyService(config *MasterKeyConfig) *MasterKeyService {
 	}
 
 	return &MasterKeyService{
-		db:          config.DB,
-		masterKey:   config.MasterKey,
-		jwtSecret:   config.JWTSecret,
-		jwtIssuer:   config.JWTIssuer,
-		tokenExpiry: config.TokenExpiry,
+		db:              config.DB,
+		masterKey:       config.MasterKey,
+		jwtSecret:       config.JWTSecret,
+		jwtIssuer:       config.JWTIssuer,
+		tokenExpiry:     config.TokenExpiry,
+		maxTokenExpiry:  config.MaxTokenExpiry,
+		requireExchange: config.RequireExchange,
 	}
 }
 
@@ -76,9 +122,11 @@ func (m *MasterKeyService) ValidateMasterKey(ctx context.Context, key string) (*
 	}
 
 	// Save audit entry
-	if err := m.db.Create(auditEntry).Error; err != nil {
-		// Log error but don't fail authentication
-		log.Printf("Failed to create master key audit entry: %v", err)
+	if m.db != nil {
+		if err := m.db.Create(auditEntry).Error; err != nil {
+			// Log error but don't fail authentication
+			log.Printf("Failed to create master key audit entry: %v", err)
+		}
 	}
 
 	return &MasterKeyContext{
@@ -89,27 +137,121 @@ func (m *MasterKeyService) ValidateMasterKey(ctx context.Context, key string) (*
 	}, nil
 }
 
-// GenerateAdminToken generates a JWT token for master key authentication
-func (m *MasterKeyService) GenerateAdminToken(masterCtx *MasterKeyContext) (string, error) {
-	// Create JWT claims for master key admin
+// ExchangeMasterKey issues a short-lived admin token carrying the requested
+// scopes for the master key. Every attempt is audited, including failed ones.
+func (m *MasterKeyService) ExchangeMasterKey(ctx context.Context, key string, req AdminTokenRequest) (*AdminToken, error) {
+	token, err := m.issueAdminToken(key, req)
+	m.auditExchange(ctx, req, token, err)
+	return token, err
+}
+
+func (m *MasterKeyService) issueAdminToken(key string, req AdminTokenRequest) (*AdminToken, error) {
+	if m.masterKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(m.masterKey)) != 1 {
+		return nil, ErrMasterKeyRequired
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{models.ScopeAll}
+	}
+	if err := ValidateAdminTokenScopes(scopes); err != nil {
+		return nil, err
+	}
+
+	ttl := req.TTL
+	if ttl <= 0 {
+		ttl = m.tokenExpiry
+	}
+	if ttl > m.maxTokenExpiry {
+		return nil, fmt.Errorf("%w: ttl exceeds the maximum of %s", ErrInvalidTokenRequest, m.maxTokenExpiry)
+	}
+
+	now := time.Now()
 	claims := &TokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			Issuer:    m.jwtIssuer,
 			Subject:   "master-key",
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.tokenExpiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
-		UserID:   uuid.MustParse("00000000-0000-0000-0000-000000000001"), // Special master key user ID
+		UserID:   MasterKeyUserID,
 		Email:    "admin@master-key",
 		Username: "master-admin",
 		Role:     string(models.RoleAdmin),
 		Groups:   []string{"admin", "master"},
+		AuthTime: jwt.NewNumericDate(now),
+		Scopes:   scopes,
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.jwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign admin token: %w", err)
+	}
+	return &AdminToken{
+		Token:     signed,
+		ID:        claims.ID,
+		Scopes:    scopes,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+func (m *MasterKeyService) auditExchange(ctx context.Context, req AdminTokenRequest, token *AdminToken, err error) {
+	if m.db == nil {
+		return
+	}
+
+	auditEntry := &models.Audit{
+		EventType:    models.AuditEventAuth,
+		EventAction:  "master_key_token_issue",
+		EventResult:  models.AuditResultSuccess,
+		IPAddress:    req.IPAddress,
+		UserAgent:    req.UserAgent,
+		AuthMethod:   "master_key",
+		AuthProvider: "internal",
+		Message:      "Admin token issued for the master key",
+		Timestamp:    time.Now(),
+	}
+
+	metadata := map[string]interface{}{
+		"requested_scopes": req.Scopes,
+		"requested_ttl":    req.TTL.String(),
+	}
+	if err != nil {
+		auditEntry.EventResult = models.AuditResultFailure
+		auditEntry.Message = "Admin token request for the master key rejected"
+		auditEntry.ErrorCode = err.Error()
+	} else {
+		metadata["token_id"] = token.ID
+		metadata["scopes"] = token.Scopes
+		metadata["expires_at"] = token.ExpiresAt
 	}
+	auditEntry.Metadata, _ = json.Marshal(metadata)
+
+	if err := m.db.WithContext(ctx).Create(auditEntry).Error; err != nil {
+		log.Printf("Failed to create master key token audit entry: %v", err)
+	}
+}
+
+// ValidateAdminTokenScopes returns an error for the first scope an admin
+// token cannot carry
+func ValidateAdminTokenScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if scope == ScopeAdmin {
+			continue
+		}
+		if err := models.ValidateScopes([]string{scope}); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTokenRequest, err)
+		}
+	}
+	return nil
+}
 
-	// Create and sign the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.jwtSecret)
+// RequiresExchange returns whether the master key is only accepted in
+// exchange for an admin token
+func (m *MasterKeyService) RequiresExchange() bool {
+	return m.requireExchange
 }
 
 // IsConfigured returns whether master key is configured
@@ -25,6 +25,7 @@ const (
 	PermTeamsUpdate        Permission = "teams:update"
 	PermTeamsDelete        Permission = "teams:delete"
 	PermTeamsManageMembers Permission = "teams:manage_members"
+	PermTeamsAudit         Permission = "teams:audit" // View the team's audit log
 
 	// Key management
 	PermKeysCreate Permission = "keys:create"
@@ -115,14 +116,14 @@ func (ps *PermissionService) initializePermissions() {
 
 	// Team role permissions (within team context)
 	ps.teamPermissions[models.TeamRoleOwner] = []Permission{
-		PermTeamsUpdate, PermTeamsDelete, PermTeamsManageMembers,
+		PermTeamsUpdate, PermTeamsDelete, PermTeamsManageMembers, PermTeamsAudit,
 		PermKeysCreate, PermKeysRead, PermKeysUpdate, PermKeysRevoke,
 		PermBudgetsCreate, PermBudgetsRead, PermBudgetsUpdate, PermBudgetsDelete,
 		PermAnalyticsRead, PermAnalyticsExport,
 	}
 
 	ps.teamPermissions[models.TeamRoleAdmin] = []Permission{
-		PermTeamsUpdate, PermTeamsManageMembers,
+		PermTeamsUpdate, PermTeamsManageMembers, PermTeamsAudit,
 		PermKeysCreate, PermKeysRead, PermKeysUpdate, PermKeysRevoke,
 		PermBudgetsRead, PermBudgetsUpdate,
 		PermAnalyticsRead, PermAnalyticsExport,
@@ -141,6 +142,20 @@ func (ps *PermissionService) initializePermissions() {
 		PermBudgetsRead,
 		PermAnalyticsRead,
 	}
+
+	// Billing admins own the team's spend but not its keys, members or models
+	ps.teamPermissions[models.TeamRoleBillingAdmin] = []Permission{
+		PermTeamsRead,
+		PermBudgetsCreate, PermBudgetsRead, PermBudgetsUpdate, PermBudgetsDelete,
+		PermAnalyticsRead, PermAnalyticsExport,
+	}
+
+	// Auditors get read-only access to usage and the audit log
+	ps.teamPermissions[models.TeamRoleAuditor] = []Permission{
+		PermTeamsRead, PermTeamsAudit,
+		PermBudgetsRead,
+		PermAnalyticsRead, PermAnalyticsExport,
+	}
 }
 
 // HasPermission checks if a user has a specific permission
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func TestPermissionService_TeamAudit(t *testing.T) {
+	ps := NewPermissionService()
+	teamID := uuid.New()
+	otherTeamID := uuid.New()
+
+	member := func(role models.TeamRole) *models.User {
+		return &models.User{
+			Role:  models.RoleUser,
+			Teams: []models.TeamMember{{TeamID: teamID, Role: role}},
+		}
+	}
+
+	assert.True(t, ps.HasTeamPermission(member(models.TeamRoleOwner), teamID, PermTeamsAudit))
+	assert.True(t, ps.HasTeamPermission(member(models.TeamRoleAdmin), teamID, PermTeamsAudit))
+	assert.False(t, ps.HasTeamPermission(member(models.TeamRoleMember), teamID, PermTeamsAudit))
+	assert.False(t, ps.HasTeamPermission(member(models.TeamRoleViewer), teamID, PermTeamsAudit))
+
+	assert.False(t, ps.HasTeamPermission(member(models.TeamRoleAdmin), otherTeamID, PermTeamsAudit),
+		"team admins only see their own team")
+	assert.True(t, ps.HasTeamPermission(&models.User{Role: models.RoleAdmin}, otherTeamID, PermTeamsAudit))
+}
+
+func TestPermissionService_BillingAdminAndAuditor(t *testing.T) {
+	ps := NewPermissionService()
+	teamID := uuid.New()
+
+	member := func(role models.TeamRole) *models.User {
+		return &models.User{
+			Role:  models.RoleViewer, // No global write permissions to mask the team ones
+			Teams: []models.TeamMember{{TeamID: teamID, Role: role}},
+		}
+	}
+	billing := member(models.TeamRoleBillingAdmin)
+	auditor := member(models.TeamRoleAuditor)
+
+	for _, perm := range []Permission{PermBudgetsRead, PermBudgetsUpdate, PermAnalyticsRead} {
+		assert.True(t, ps.HasTeamPermission(billing, teamID, perm), perm)
+	}
+	for _, perm := range []Permission{PermKeysCreate, PermKeysUpdate, PermKeysRevoke, PermTeamsManageMembers, PermTeamsUpdate, PermModelsUse} {
+		assert.False(t, ps.HasTeamPermission(billing, teamID, perm), perm)
+	}
+	assert.False(t, ps.CanManageTeam(billing, teamID))
+
+	for _, perm := range []Permission{PermTeamsAudit, PermAnalyticsRead, PermAnalyticsExport, PermBudgetsRead} {
+		assert.True(t, ps.HasTeamPermission(auditor, teamID, perm), perm)
+	}
+	for _, perm := range []Permission{PermBudgetsUpdate, PermKeysCreate, PermTeamsManageMembers, PermTeamsUpdate} {
+		assert.False(t, ps.HasTeamPermission(auditor, teamID, perm), perm)
+	}
+
+	assert.True(t, models.ValidTeamRole(models.TeamRoleAuditor))
+	assert.False(t, models.ValidTeamRole("superuser"))
+}
@@ -4,24 +4,39 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+	pkglogger "github.com/amerfu/pllm/pkg/logger"
 )
 
 // Forward declarations to avoid circular imports
-type TeamService interface {
-	AddUserToDefaultTeam(ctx context.Context, userID uuid.UUID, role models.TeamRole) (*models.TeamMember, error)
+type Onboarder interface {
+	// OnboardUser applies the organization's onboarding template (team,
+	// budget, key) to a user auto-provisioned from Dex
+	OnboardUser(ctx context.Context, user *models.User, groups []string) error
+
+	// ProvisionedRole returns the global role of a user about to be
+	// provisioned, from the auto-join rules their email and groups match
+	ProvisionedRole(email string, groups []string) models.UserRole
 }
 
-type KeyService interface {
-	CreateDefaultKeyForUser(ctx context.Context, userID uuid.UUID, teamID uuid.UUID) (*models.Key, error)
+// KeyCache answers API key lookups before the database
+type KeyCache interface {
+	// MightExist is false for hashes of no active key
+	MightExist(keyHash string) bool
+	Get(ctx context.Context, keyHash string) (*models.Key, bool)
+	Set(ctx context.Context, key *models.Key)
+	// OnInvalidate registers fn to be called with the ID of every key
+	// that changed, or uuid.Nil when any may have
+	OnInvalidate(fn func(keyID uuid.UUID))
 }
 
 var (
@@ -42,9 +57,11 @@ type AuthService struct {
 	jwtIssuer         string
 	tokenExpiry       time.Duration
 	masterKeyService  *MasterKeyService
-	teamService       TeamService
-	keyService        KeyService
+	onboarder         Onboarder
 	permissionService *PermissionService
+	notifier          *notifications.Hub
+	keyCache          KeyCache
+	logger            *zap.Logger
 }
 
 type AuthConfig struct {
@@ -54,8 +71,9 @@ type AuthConfig struct {
 	JWTIssuer        string
 	TokenExpiry      time.Duration
 	MasterKeyService *MasterKeyService
-	TeamService      TeamService
-	KeyService       KeyService
+	Onboarder        Onboarder
+	Notifier         *notifications.Hub // Optional, receives user signups
+	Logger           *zap.Logger
 }
 
 type LoginResponse struct {
@@ -82,6 +100,19 @@ type TokenClaims struct {
 	Username string    `json:"username"`
 	Role     string    `json:"role"`
 	Groups   []string  `json:"groups"`
+	// AuthTime is when the user last authenticated interactively. Unlike
+	// IssuedAt it is carried over unchanged when a token is refreshed, so it
+	// is what step-up checks compare against.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	// Scopes restrict admin tokens issued for the master key; other tokens
+	// carry none
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// IsMasterKeyToken reports whether the claims belong to an admin token
+// issued for the master key
+func (c *TokenClaims) IsMasterKeyToken() bool {
+	return c.UserID == MasterKeyUserID
 }
 
 func NewAuthService(config *AuthConfig) (*AuthService, error) {
@@ -98,6 +129,9 @@ func NewAuthService(config *AuthConfig) (*AuthService, error) {
 	if config.TokenExpiry == 0 {
 		config.TokenExpiry = 24 * time.Hour
 	}
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
 
 	return &AuthService{
 		db:                config.DB,
@@ -106,9 +140,10 @@ func NewAuthService(config *AuthConfig) (*AuthService, error) {
 		jwtIssuer:         config.JWTIssuer,
 		tokenExpiry:       config.TokenExpiry,
 		masterKeyService:  config.MasterKeyService,
-		teamService:       config.TeamService,
-		keyService:        config.KeyService,
+		onboarder:         config.Onboarder,
 		permissionService: NewPermissionService(),
+		notifier:          config.Notifier,
+		logger:            config.Logger,
 	}, nil
 }
 
@@ -117,6 +152,7 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 	if s.dexProvider == nil {
 		return nil, errors.New("dex not configured")
 	}
+	logger := pkglogger.FromContext(ctx, s.logger)
 
 	token, err := s.dexProvider.ExchangeCode(ctx, code)
 	if err != nil {
@@ -144,15 +180,10 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 				username = claims.Email
 			}
 
-			// Determine role from groups
+			// The auto-join rules decide the role
 			role := models.RoleUser
-			for _, group := range claims.Groups {
-				switch strings.ToLower(group) {
-				case "admin", "administrators":
-					role = models.RoleAdmin
-				case "manager", "managers":
-					role = models.RoleManager
-				}
+			if s.onboarder != nil {
+				role = s.onboarder.ProvisionedRole(claims.Email, claims.Groups)
 			}
 
 			user = models.User{
@@ -168,9 +199,11 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 			// Extract the actual OAuth provider from Dex claims
 			actualProvider := extractOAuthProvider(claims)
 			
-			// Debug log to help with troubleshooting
-			fmt.Printf("DEBUG: Dex claims - Subject: %s, Email: %s, ConnectorID: %s, ExtractedProvider: %s\n", 
-				claims.Subject, claims.Email, claims.ConnectorID, actualProvider)
+			logger.Debug("Provisioning user from Dex claims",
+				zap.String("subject", claims.Subject),
+				zap.String("email", claims.Email),
+				zap.String("connector_id", claims.ConnectorID),
+				zap.String("provider", actualProvider))
 			
 			// Mark as provisioned from the actual provider
 			user.MarkAsProvisioned(actualProvider, claims.Subject, claims.Groups)
@@ -179,30 +212,13 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 				return nil, fmt.Errorf("failed to create user: %w", err)
 			}
 
-			// Auto-assign user to default team if team service is available
-			fmt.Printf("DEBUG: TeamService available: %t\n", s.teamService != nil)
-			if s.teamService != nil {
-				// Map user role to team role
-				teamRole := models.TeamRoleMember
-				if user.Role == models.RoleAdmin {
-					teamRole = models.TeamRoleAdmin
-				}
-
-				if teamMember, err := s.teamService.AddUserToDefaultTeam(ctx, user.ID, teamRole); err != nil {
+			// Apply the onboarding template: team membership, budget and key
+			if s.onboarder != nil {
+				if err := s.onboarder.OnboardUser(ctx, &user, claims.Groups); err != nil {
 					// Log error but don't fail user creation
 					// User can be manually assigned to teams later
-					fmt.Printf("ERROR: Failed to assign user %s to default team: %v\n", user.Email, err)
-				} else {
-					fmt.Printf("SUCCESS: User %s added to default team %s\n", user.Email, teamMember.TeamID)
-					if s.keyService != nil && teamMember != nil {
-						// Create default API key for the user
-						if _, err := s.keyService.CreateDefaultKeyForUser(ctx, user.ID, teamMember.TeamID); err != nil {
-							// Log error but don't fail user creation
-							fmt.Printf("ERROR: Failed to create default key for user %s: %v\n", user.Email, err)
-						} else {
-							fmt.Printf("SUCCESS: Created default key for user %s\n", user.Email)
-						}
-					}
+					logger.Error("Failed to onboard provisioned user",
+						zap.String("email", user.Email), zap.Error(err))
 				}
 			}
 
@@ -220,6 +236,7 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 				Timestamp:    time.Now(),
 			}
 			s.db.Create(auditEntry)
+			s.notifier.Notify(ctx, notifications.UserSignup(user.ID.String(), user.Email, "dex"))
 
 		} else {
 			return nil, err
@@ -233,9 +250,11 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 		// Update provider info if it has changed or was missing
 		actualProvider := extractOAuthProvider(claims)
 		
-		// Debug log to help with troubleshooting (existing users)
-		fmt.Printf("DEBUG: Existing user - Subject: %s, Email: %s, Current Provider: %s, ExtractedProvider: %s\n", 
-			claims.Subject, claims.Email, user.ExternalProvider, actualProvider)
+		logger.Debug("Updating existing user from Dex claims",
+			zap.String("subject", claims.Subject),
+			zap.String("email", claims.Email),
+			zap.String("current_provider", user.ExternalProvider),
+			zap.String("provider", actualProvider))
 		
 		if user.ExternalProvider != actualProvider {
 			user.ExternalProvider = actualProvider
@@ -243,7 +262,7 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 		
 		if err := s.db.Save(&user).Error; err != nil {
 			// Log error but don't fail login
-			log.Printf("Failed to update user from Dex claims: %v", err)
+			logger.Warn("Failed to update user from Dex claims", zap.Error(err))
 		}
 	}
 
@@ -269,7 +288,7 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 	}
 	s.db.Create(auditEntry)
 
-	jwtToken, err := s.generateJWT(&user)
+	jwtToken, err := s.generateJWT(&user, &now)
 	if err != nil {
 		return nil, err
 	}
@@ -300,8 +319,8 @@ func (s *AuthService) LoginWithDex(ctx context.Context, code string) (*LoginResp
 
 // ValidateKey validates any type of key (API, Virtual, Master)
 func (s *AuthService) ValidateKey(ctx context.Context, key string) (*models.Key, error) {
-	// Check master key first
-	if s.masterKeyService != nil && s.masterKeyService.IsConfigured() {
+	// Check master key first, unless it must be exchanged for an admin token
+	if s.masterKeyService != nil && s.masterKeyService.IsConfigured() && !s.masterKeyService.RequiresExchange() {
 		if masterCtx, err := s.masterKeyService.ValidateMasterKey(ctx, key); err == nil {
 			return &models.Key{
 				Name:     "Master Key",
@@ -315,6 +334,16 @@ func (s *AuthService) ValidateKey(ctx context.Context, key string) (*models.Key,
 	// Hash the key for lookup
 	keyHash := models.HashKey(key)
 
+	if s.keyCache != nil {
+		if !s.keyCache.MightExist(keyHash) {
+			return nil, ErrInvalidAPIKey
+		}
+		if cached, ok := s.keyCache.Get(ctx, keyHash); ok && cached.IsValid() {
+			s.recordKeyUsage(cached)
+			return cached, nil
+		}
+	}
+
 	var dbKey models.Key
 	err := s.db.Preload("User").Preload("Team").Where("key_hash = ? AND is_active = ?", keyHash, true).First(&dbKey).Error
 	if err != nil {
@@ -328,11 +357,23 @@ func (s *AuthService) ValidateKey(ctx context.Context, key string) (*models.Key,
 		return nil, ErrInvalidAPIKey
 	}
 
+	s.recordKeyUsage(&dbKey)
+
+	if s.keyCache != nil {
+		s.keyCache.Set(ctx, &dbKey)
+	}
+
+	return &dbKey, nil
+}
+
+// recordKeyUsage updates the usage statistics of a validated key and audits
+// its use, whether it was found in the cache or the database
+func (s *AuthService) recordKeyUsage(key *models.Key) {
 	// Update usage statistics atomically to avoid race conditions
 	now := time.Now()
-	dbKey.LastUsedAt = &now
+	key.LastUsedAt = &now
 	// Use atomic increment for usage_count to handle concurrent access
-	s.db.Model(&dbKey).Updates(map[string]interface{}{
+	s.db.Model(key).Updates(map[string]interface{}{
 		"last_used_at": now,
 		"usage_count": gorm.Expr("usage_count + 1"),
 	})
@@ -342,18 +383,26 @@ func (s *AuthService) ValidateKey(ctx context.Context, key string) (*models.Key,
 		EventType:    models.AuditEventKeyUsage,
 		EventAction:  "key_validation",
 		EventResult:  models.AuditResultSuccess,
-		UserID:       dbKey.UserID,
-		TeamID:       dbKey.TeamID,
-		KeyID:        &dbKey.ID,
+		UserID:       key.UserID,
+		TeamID:       key.TeamID,
+		KeyID:        &key.ID,
 		AuthMethod:   "api_key",
 		ResourceType: "key",
-		ResourceID:   &dbKey.ID,
+		ResourceID:   &key.ID,
 		Message:      "API key used successfully",
 		Timestamp:    time.Now(),
 	}
 	s.db.Create(auditEntry)
+}
 
-	return &dbKey, nil
+// SetKeyCache makes ValidateKey check the cache before the database
+func (s *AuthService) SetKeyCache(cache KeyCache) {
+	s.keyCache = cache
+}
+
+// KeyCache returns the cache ValidateKey checks, if any
+func (s *AuthService) KeyCache() KeyCache {
+	return s.keyCache
 }
 
 // ValidateAPIKey for backward compatibility
@@ -399,28 +448,30 @@ func (s *AuthService) ValidateToken(tokenString string) (*TokenClaims, error) {
 				Role:             string(user.Role),
 				Groups:           groups,
 			}
+			// Dex re-issues ID tokens on refresh, so their iat says nothing
+			// about when the user last signed in
+			if user.LastLoginAt != nil {
+				tokenClaims.AuthTime = jwt.NewNumericDate(*user.LastLoginAt)
+			}
 
 			return tokenClaims, nil
 		}
-		// Log Dex validation failure for debugging
-		fmt.Printf("Debug: Dex token validation failed: %v\n", err)
+		s.logger.Debug("Dex token validation failed", zap.Error(err))
 	}
 
-	// Only try HMAC validation if this looks like an internal token (shorter, different format)
-	// Dex tokens are much longer and use RS256, so they'll always fail HMAC validation
-	if len(tokenString) < 500 {
-		// Fall back to HMAC validation for internal JWT tokens
-		token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return s.jwtSecret, nil
-		})
+	// Fall back to HMAC validation for internal JWT tokens. Dex tokens use
+	// RS256, so they are rejected by the signing method check before any
+	// signature work.
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	})
 
-		if err == nil {
-			if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid {
-				return claims, nil
-			}
+	if err == nil {
+		if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid {
+			return claims, nil
 		}
 	}
 
@@ -458,11 +509,14 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*L
 		return nil, ErrUserInactive
 	}
 
-	// Update user info from refreshed claims
+	// Update user info from refreshed claims. A refresh is not a fresh
+	// login, so the original authentication time is kept for step-up checks.
+	lastLoginAt := user.LastLoginAt
 	user.UpdateExternalGroups(claims.Groups)
+	user.LastLoginAt = lastLoginAt
 	s.db.Save(&user)
 
-	jwtToken, err := s.generateJWT(&user)
+	jwtToken, err := s.generateJWT(&user, lastLoginAt)
 	if err != nil {
 		return nil, err
 	}
@@ -496,7 +550,10 @@ func (s *AuthService) Logout(ctx context.Context, token string) error {
 	return nil
 }
 
-func (s *AuthService) generateJWT(user *models.User) (string, error) {
+// generateJWT signs an internal token for user. authTime is the time of the
+// interactive login the token descends from; nil leaves the claim out, which
+// fails any step-up check.
+func (s *AuthService) generateJWT(user *models.User, authTime *time.Time) (string, error) {
 	// Get team names from Teams relationship
 	groups := make([]string, 0)
 	if len(user.Teams) > 0 {
@@ -519,6 +576,9 @@ func (s *AuthService) generateJWT(user *models.User) (string, error) {
 		Role:     string(user.Role),
 		Groups:   groups,
 	}
+	if authTime != nil {
+		claims.AuthTime = jwt.NewNumericDate(*authTime)
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(s.jwtSecret)
@@ -565,6 +625,7 @@ func (s *AuthService) ProvisionUser(ctx context.Context, req *models.UserProvisi
 	if err := s.db.Create(user).Error; err != nil {
 		return nil, err
 	}
+	s.notifier.Notify(ctx, notifications.UserSignup(user.ID.String(), user.Email, req.ExternalProvider))
 
 	return user, nil
 }
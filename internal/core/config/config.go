@@ -3,11 +3,16 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+// envReference matches ${VAR} references in config values
+var envReference = regexp.MustCompile(`\$\{([^}]+)\}`)
+
 type Config struct {
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
@@ -25,6 +30,7 @@ type Config struct {
 	Router       RouterSettings      `mapstructure:"router"`
 	ModelAliases map[string][]string `mapstructure:"model_aliases"`
 	Routes       []RouteConfig       `mapstructure:"routes"`
+	Tiers        []TierConfig        `mapstructure:"tiers"`
 
 	Cache      CacheConfig      `mapstructure:"cache"`
 	RateLimit  RateLimitConfig  `mapstructure:"rate_limit"`
@@ -33,6 +39,45 @@ type Config struct {
 	CORS       CORSConfig       `mapstructure:"cors"`
 	Realtime   RealtimeConfig   `mapstructure:"realtime"`
 	Guardrails GuardrailsConfig `mapstructure:"guardrails"`
+	Provenance ProvenanceConfig `mapstructure:"provenance"`
+
+	ContextWindow ContextWindowConfig `mapstructure:"context_window"`
+
+	Outage OutageConfig `mapstructure:"outage"`
+
+	RetryPolicy RetryPolicyConfig `mapstructure:"retry_policy"`
+
+	LoadShedding LoadSheddingConfig `mapstructure:"load_shedding"`
+
+	Coordination CoordinationConfig `mapstructure:"coordination"`
+
+	Cluster ClusterConfig `mapstructure:"cluster"`
+
+	UsageSampling UsageSamplingConfig `mapstructure:"usage_sampling"`
+
+	Bandwidth BandwidthConfig `mapstructure:"bandwidth"`
+
+	Jobs JobsConfig `mapstructure:"jobs"`
+
+	Tools ToolsConfig `mapstructure:"tools"`
+
+	Billing BillingConfig `mapstructure:"billing"`
+
+	Onboarding OnboardingConfig `mapstructure:"onboarding"`
+
+	AdminSecurity AdminSecurityConfig `mapstructure:"admin_security"`
+
+	KeyRotation KeyRotationConfig `mapstructure:"key_rotation"`
+
+	BYOK BYOKConfig `mapstructure:"byok"`
+
+	Analytics AnalyticsConfig `mapstructure:"analytics"`
+
+	Artifacts ArtifactsConfig `mapstructure:"artifacts"`
+
+	Deletion DeletionConfig `mapstructure:"deletion"`
+
+	UserNotifications UserNotificationsConfig `mapstructure:"user_notifications"`
 }
 
 type ServerConfig struct {
@@ -43,6 +88,12 @@ type ServerConfig struct {
 	WriteTimeout     time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout      time.Duration `mapstructure:"idle_timeout"`
 	GracefulShutdown time.Duration `mapstructure:"graceful_shutdown"`
+
+	// Request body size limits: MaxBodyBytes applies to every request,
+	// BodyLimits overrides it for endpoints, keyed by path suffix
+	// ("/audio/transcriptions")
+	MaxBodyBytes int64            `mapstructure:"max_body_bytes"`
+	BodyLimits   map[string]int64 `mapstructure:"body_limits"`
 }
 
 type DatabaseConfig struct {
@@ -50,6 +101,10 @@ type DatabaseConfig struct {
 	MaxConnections  int           `mapstructure:"max_connections"`
 	MaxIdleConns    int           `mapstructure:"max_idle_connections"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+
+	// AutoMigrate applies pending migrations at startup. When off, the
+	// server refuses to start until "pllm migrate up" was run.
+	AutoMigrate bool `mapstructure:"auto_migrate"`
 }
 
 type RedisConfig struct {
@@ -57,6 +112,25 @@ type RedisConfig struct {
 	Password string `mapstructure:"password"`
 	DB       int    `mapstructure:"db"`
 	PoolSize int    `mapstructure:"pool_size"`
+
+	// Namespace prefixes every Redis key, stream and channel, so several
+	// deployments can share one Redis
+	Namespace string `mapstructure:"namespace"`
+
+	MemoryGuard RedisMemoryGuardConfig `mapstructure:"memory_guard"`
+}
+
+// RedisMemoryGuardConfig sets the thresholds at which async writes degrade:
+// analytic events are shed from the elevated level on, while usage records
+// are always kept
+type RedisMemoryGuardConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	Interval            time.Duration `mapstructure:"interval"`
+	MemoryElevatedRatio float64       `mapstructure:"memory_elevated_ratio"`
+	MemoryCriticalRatio float64       `mapstructure:"memory_critical_ratio"`
+	MaxMemoryBytes      int64         `mapstructure:"max_memory_bytes"` // Used when Redis has no maxmemory
+	QueueElevatedDepth  int64         `mapstructure:"queue_elevated_depth"`
+	QueueCriticalDepth  int64         `mapstructure:"queue_critical_depth"`
 }
 
 type JWTConfig struct {
@@ -72,10 +146,29 @@ type AdminConfig struct {
 }
 
 type AuthConfig struct {
-	MasterKey   string    `mapstructure:"master_key"`
-	JWT         JWTConfig `mapstructure:"jwt"`
-	Dex         DexConfig `mapstructure:"dex"`
-	RequireAuth bool      `mapstructure:"require_auth"`
+	MasterKey       string                `mapstructure:"master_key"`
+	MasterKeyTokens MasterKeyTokensConfig `mapstructure:"master_key_tokens"`
+	JWT             JWTConfig             `mapstructure:"jwt"`
+	Dex             DexConfig             `mapstructure:"dex"`
+	RequireAuth     bool                  `mapstructure:"require_auth"`
+	KeyCache        KeyCacheConfig        `mapstructure:"key_cache"`
+}
+
+// KeyCacheConfig controls the cache API keys are looked up in before the
+// database: a filter of the active keys, rejecting unknown ones, and the
+// keys validated by any gateway, shared in Redis
+type KeyCacheConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"` // Full reload of the filter
+	TTL             time.Duration `mapstructure:"ttl"`              // Lifetime of the keys cached in Redis
+}
+
+// MasterKeyTokensConfig controls the admin tokens the master key is
+// exchanged for at /api/admin/auth/master-key
+type MasterKeyTokensConfig struct {
+	RequireExchange bool          `mapstructure:"require_exchange"` // Reject the master key on every other endpoint
+	TTL             time.Duration `mapstructure:"ttl"`              // Default token lifetime
+	MaxTTL          time.Duration `mapstructure:"max_ttl"`          // Longest lifetime a caller may request
 }
 
 type DexConfig struct {
@@ -95,6 +188,27 @@ type CacheConfig struct {
 	TTL      time.Duration `mapstructure:"ttl"`
 	MaxSize  int           `mapstructure:"max_size"`
 	Strategy string        `mapstructure:"strategy"`
+
+	// Outputs caches deterministic completions of keys that opt in
+	Outputs OutputCacheConfig `mapstructure:"outputs"`
+
+	// Coalescing shares one provider call between identical deterministic
+	// completions a key sends concurrently
+	Coalescing CoalescingConfig `mapstructure:"coalescing"`
+}
+
+// OutputCacheConfig configures caching of deterministic completions:
+// requests with temperature 0 or a seed, made with keys that enable
+// cache_outputs
+type OutputCacheConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	TTL           time.Duration `mapstructure:"ttl"`
+	MaxEntryBytes int           `mapstructure:"max_entry_bytes"` // Larger responses are not cached
+}
+
+// CoalescingConfig configures request coalescing
+type CoalescingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
 }
 
 type RateLimitConfig struct {
@@ -103,6 +217,10 @@ type RateLimitConfig struct {
 	ChatCompletionsRPM int           `mapstructure:"chat_completions_rpm"`
 	CompletionsRPM     int           `mapstructure:"completions_rpm"`
 	EmbeddingsRPM      int           `mapstructure:"embeddings_rpm"`
+	GlobalTPM          int           `mapstructure:"global_tpm"`
+	ChatCompletionsTPM int           `mapstructure:"chat_completions_tpm"`
+	CompletionsTPM     int           `mapstructure:"completions_tpm"`
+	EmbeddingsTPM      int           `mapstructure:"embeddings_tpm"`
 	RequestsPerMinute  int           `mapstructure:"requests_per_minute"`
 	Burst              int           `mapstructure:"burst"`
 	CleanupInterval    time.Duration `mapstructure:"cleanup_interval"`
@@ -116,9 +234,20 @@ type MonitoringConfig struct {
 }
 
 type LoggingConfig struct {
-	Level      string `mapstructure:"level"`
-	Format     string `mapstructure:"format"`
-	OutputPath string `mapstructure:"output_path"`
+	Level       string                  `mapstructure:"level"`
+	Format      string                  `mapstructure:"format"`
+	OutputPath  string                  `mapstructure:"output_path"`
+	Invocations InvocationLoggingConfig `mapstructure:"invocations"`
+}
+
+// InvocationLoggingConfig captures the sanitized requests sent to providers
+// and the status and headers of their responses, kept in memory briefly to
+// debug translation bugs
+type InvocationLoggingConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Retention    time.Duration `mapstructure:"retention"`
+	MaxEntries   int           `mapstructure:"max_entries"`
+	MaxBodyBytes int           `mapstructure:"max_body_bytes"`
 }
 
 type CORSConfig struct {
@@ -143,6 +272,328 @@ type RealtimeConfig struct {
 	EnableCompression bool         `mapstructure:"enable_compression"`
 	AudioFormat      string        `mapstructure:"audio_format"`
 	AudioSampleRate  int           `mapstructure:"audio_sample_rate"`
+
+	Metering RealtimeMeteringConfig `mapstructure:"metering"`
+}
+
+// RealtimeMeteringConfig controls how realtime session spend is billed and
+// checked against key and team budgets while the session runs
+type RealtimeMeteringConfig struct {
+	// BudgetCheckInterval is how often a session's spend is checked against
+	// the cached budgets of its key and team
+	BudgetCheckInterval time.Duration `mapstructure:"budget_check_interval"`
+
+	// FlushInterval is how often the spend is written as a usage record, so
+	// budgets and analytics see long sessions before they end
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+}
+
+// BillingConfig controls how request costs are charged
+type BillingConfig struct {
+	// MarkupPercent is added on top of the provider cost of every request
+	MarkupPercent float64 `mapstructure:"markup_percent"`
+
+	// StreamCheckpointInterval is how often a streamed chat completion
+	// records the usage it streamed so far, so a gateway crash does not lose
+	// the billing of a long stream; 0 disables checkpoints
+	StreamCheckpointInterval time.Duration `mapstructure:"stream_checkpoint_interval"`
+
+	Credits CreditsConfig `mapstructure:"credits"`
+}
+
+// CreditsConfig controls prepaid team credits bought through Stripe
+type CreditsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// GraceAmount is how far below zero a team balance may go before its
+	// requests are blocked
+	GraceAmount         float64       `mapstructure:"grace_amount"`
+	Currency            string        `mapstructure:"currency"`
+	MinPurchase         float64       `mapstructure:"min_purchase"`
+	StripeSecretKey     string        `mapstructure:"stripe_secret_key"`
+	StripeWebhookSecret string        `mapstructure:"stripe_webhook_secret"`
+	SuccessURL          string        `mapstructure:"success_url"`
+	CancelURL           string        `mapstructure:"cancel_url"`
+	BalanceCacheTTL     time.Duration `mapstructure:"balance_cache_ttl"`
+}
+
+// ProvenanceConfig controls provenance metadata attached to LLM responses
+type ProvenanceConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Mode    string `mapstructure:"mode"` // "headers", "field" or "both"
+}
+
+// Context window strategies
+const (
+	ContextWindowTruncate  = "truncate"
+	ContextWindowSummarize = "summarize"
+	ContextWindowReject    = "reject"
+)
+
+// ContextWindowConfig controls chat requests whose prompt exceeds the target
+// model's context window
+type ContextWindowConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	Strategy         string `mapstructure:"strategy"`           // "truncate" (default), "summarize" or "reject"
+	SummaryModel     string `mapstructure:"summary_model"`      // Cheap model that summarizes dropped turns
+	SummaryMaxTokens int    `mapstructure:"summary_max_tokens"` // Length cap of the summary
+}
+
+// Actions taken on a provider's instances during a declared upstream incident
+const (
+	OutageActionRaiseThreshold = "raise_threshold"
+	OutageActionShiftRoutes    = "shift_routes"
+	OutageActionNone           = "none"
+)
+
+// OutageConfig controls the provider status page watcher, which records
+// declared upstream incidents and mitigates them while they last
+type OutageConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	PollInterval     time.Duration `mapstructure:"poll_interval"`
+	Action           string        `mapstructure:"action"`             // "raise_threshold" (default), "shift_routes" or "none"
+	FailureThreshold int           `mapstructure:"failure_threshold"`  // Failures that open a circuit breaker during an incident
+	SpikeFailureRate float64       `mapstructure:"spike_failure_rate"` // Share of a provider's instances failing that confirms an incident
+
+	// StatusPages overrides the built-in status feeds, keyed by provider
+	// type; an entry with an empty URL disables watching that provider
+	StatusPages map[string]StatusPageConfig `mapstructure:"status_pages"`
+}
+
+// StatusPageConfig is a provider status feed
+type StatusPageConfig struct {
+	URL    string `mapstructure:"url"`
+	Format string `mapstructure:"format"` // "statuspage" (Atlassian Statuspage JSON) or "rss"
+}
+
+// Retry jitter modes
+const (
+	RetryJitterFull  = "full"
+	RetryJitterEqual = "equal"
+	RetryJitterNone  = "none"
+)
+
+// RetryPolicyConfig is the retry policy published to clients on LLM API
+// responses
+type RetryPolicyConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	MaxAttempts int           `mapstructure:"max_attempts"` // Attempts including the first request
+	BaseBackoff time.Duration `mapstructure:"base_backoff"` // Delay before the first retry, doubled per attempt
+	MaxBackoff  time.Duration `mapstructure:"max_backoff"`
+	Jitter      string        `mapstructure:"jitter"` // "full" (default), "equal" or "none"
+}
+
+// LoadSheddingConfig rejects LLM requests while the gateway process runs
+// short of resources. Elevated pressure sheds low-priority requests and
+// critical pressure every request but the master key's.
+type LoadSheddingConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Interval    time.Duration `mapstructure:"interval"`     // How often resources are sampled
+	RetryAfter  time.Duration `mapstructure:"retry_after"`  // Sent to shed clients
+	MemoryLimit int64         `mapstructure:"memory_limit"` // Bytes; defaults to GOMEMLIMIT
+
+	Elevated LoadSheddingThresholds `mapstructure:"elevated"`
+	Critical LoadSheddingThresholds `mapstructure:"critical"`
+}
+
+// LoadSheddingThresholds are the resource levels of a pressure level; any
+// one crossed reaches it. Zero disables a threshold.
+type LoadSheddingThresholds struct {
+	CPUPercent   float64 `mapstructure:"cpu_percent"`    // Process CPU, percent of the cores Go may use
+	MemoryRatio  float64 `mapstructure:"memory_ratio"`   // Memory in use as a fraction of memory_limit
+	Goroutines   int     `mapstructure:"goroutines"`     // Live goroutines
+	GCPauseRatio float64 `mapstructure:"gc_pause_ratio"` // Fraction of time spent in GC pauses
+}
+
+// Coordination backends
+const (
+	CoordinationBackendRedis    = "redis"
+	CoordinationBackendPostgres = "postgres"
+)
+
+// CoordinationConfig selects where the usage queue, distributed locks and
+// budget cache shared between gateway replicas live
+type CoordinationConfig struct {
+	Backend string `mapstructure:"backend"` // "redis" (default) or "postgres"
+
+	// Usage queue payloads in Redis: "json" (default) or "msgpack", and
+	// whether to gzip them. Upgrade the workers before switching, since
+	// workers from before the setting existed only read plain JSON.
+	UsageEncoding    string `mapstructure:"usage_encoding"`
+	UsageCompression bool   `mapstructure:"usage_compression"`
+}
+
+// UsesPostgres reports whether the Postgres coordination backend is selected
+func (c CoordinationConfig) UsesPostgres() bool {
+	return c.Backend == CoordinationBackendPostgres
+}
+
+// ClusterConfig controls how gateway replicas register themselves in the
+// coordination backend, for the admin cluster view
+type ClusterConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"` // Replicas silent for three intervals are stale
+	ReplicaTTL        time.Duration `mapstructure:"replica_ttl"`        // Stale replicas are forgotten after this long
+}
+
+// UsageSamplingConfig thins out the detailed usage rows written to Postgres.
+// Budgets and spend are still charged for every request, and exact totals are
+// kept in Redis counters.
+type UsageSamplingConfig struct {
+	Rate       int  `mapstructure:"rate"`        // Store 1 in Rate detailed rows; 0 or 1 stores every row
+	KeepErrors bool `mapstructure:"keep_errors"` // Always store rows of failed requests
+}
+
+// BandwidthConfig controls the metering of request and response sizes. The
+// sizes are stored with every usage record and counted per team in Redis,
+// where teams' monthly bandwidth quotas are checked.
+type BandwidthConfig struct {
+	Enabled    bool    `mapstructure:"enabled"`
+	PricePerGB float64 `mapstructure:"price_per_gb"` // Added to the cost of requests with pricing; 0 bills only tokens
+}
+
+// JobsConfig controls asynchronous chat completion jobs (?async=true)
+type JobsConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Workers        int           `mapstructure:"workers"`
+	Timeout        time.Duration `mapstructure:"timeout"`         // Per-job execution timeout
+	ResultTTL      time.Duration `mapstructure:"result_ttl"`      // How long finished jobs can be polled
+	WebhookTimeout time.Duration `mapstructure:"webhook_timeout"` // Timeout for completion webhooks
+
+	// WebhookAllowedHosts may receive webhooks on private addresses
+	WebhookAllowedHosts []string `mapstructure:"webhook_allowed_hosts"`
+
+	OutageQueue OutageQueueConfig `mapstructure:"outage_queue"`
+}
+
+// OutageQueueConfig controls the outage queue: chat requests that opt in
+// with the X-PLLM-Queue-On-Outage header are accepted as jobs while every
+// instance and fallback of their model is down, and retried until the
+// providers recover
+type OutageQueueConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	RetryInterval time.Duration `mapstructure:"retry_interval"` // Delay between attempts of a queued request
+	MaxWait       time.Duration `mapstructure:"max_wait"`       // How long a queued request is retried before it fails
+}
+
+// ToolsConfig controls the gateway tool runtime, which executes calls to
+// admin-registered HTTP tools on behalf of the model
+type ToolsConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	MaxDepth       int           `mapstructure:"max_depth"`        // Maximum model round-trips per request
+	Timeout        time.Duration `mapstructure:"timeout"`          // Default per-call timeout
+	AllowedHosts   []string      `mapstructure:"allowed_hosts"`    // Hosts tool endpoints may point to
+	MaxResultBytes int           `mapstructure:"max_result_bytes"` // Tool responses are truncated beyond this
+}
+
+// AdminSecurityConfig hardens the admin API (/api/admin)
+type AdminSecurityConfig struct {
+	SecurityHeaders bool   `mapstructure:"security_headers"` // Strict security headers on admin responses
+	CSRF            bool   `mapstructure:"csrf"`             // Double-submit CSRF check for cookie sessions
+	SessionCookie   string `mapstructure:"session_cookie"`   // Cookie carrying the dashboard session token
+
+	// IPAllowlist applies to every admin; AdminIPAllowlist adds per-admin
+	// lists keyed by email, user ID or "master_key". Entries are IPs or CIDRs.
+	IPAllowlist      []string            `mapstructure:"ip_allowlist"`
+	AdminIPAllowlist map[string][]string `mapstructure:"admin_ip_allowlist"`
+
+	// TrustedProxies are the only peers whose X-Forwarded-For / X-Real-IP
+	// headers are believed when checking the allowlists
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	// StepUpMaxAge is how recently an admin must have authenticated to
+	// perform destructive actions (key deletion, budget changes); 0 disables
+	StepUpMaxAge time.Duration `mapstructure:"step_up_max_age"`
+}
+
+// KeyRotationConfig controls self-service key rotation
+// (POST /v1/user/keys/{id}/rotate) and its client webhooks
+type KeyRotationConfig struct {
+	DefaultOverlap time.Duration `mapstructure:"default_overlap"` // How long the old key keeps working when the request sets no overlap
+	MaxOverlap     time.Duration `mapstructure:"max_overlap"`     // Longest overlap a request may ask for
+	WebhookTimeout time.Duration `mapstructure:"webhook_timeout"` // Timeout of each webhook delivery attempt
+
+	// WebhookAllowedHosts may receive webhooks on private addresses and
+	// over plain HTTP
+	WebhookAllowedHosts []string `mapstructure:"webhook_allowed_hosts"`
+}
+
+// BYOKConfig controls bring-your-own-key: provider API keys teams register
+// so their traffic is sent with, and billed to, their own provider accounts
+type BYOKConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	EncryptionKey       string        `mapstructure:"encryption_key"`        // Secret each team's encryption key is derived from, at least 32 characters
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"` // How often registered keys are checked; 0 disables
+	CacheTTL            time.Duration `mapstructure:"cache_ttl"`             // How long each replica caches a team's keys
+}
+
+// AnalyticsConfig controls the admin analytics and dashboard endpoints
+type AnalyticsConfig struct {
+	Privacy AnalyticsPrivacyConfig `mapstructure:"privacy"`
+	GeoIP   GeoIPConfig            `mapstructure:"geoip"`
+}
+
+// GeoIPConfig enables the country and continent of usage records, looked up
+// from the client IP in a MaxMind GeoIP2 or GeoLite2 country or city database
+type GeoIPConfig struct {
+	DatabasePath string `mapstructure:"database_path"` // Geo enrichment is off when empty
+}
+
+// AnalyticsPrivacyConfig is the anonymized analytics mode: aggregates are
+// only returned for groups of at least MinGroupSize distinct users, and user
+// identifiers are replaced with pseudonyms
+type AnalyticsPrivacyConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MinGroupSize    int    `mapstructure:"min_group_size"`   // k-anonymity threshold
+	PseudonymSecret string `mapstructure:"pseudonym_secret"` // Keys the pseudonyms; defaults to the JWT secret
+}
+
+// ArtifactsConfig controls where stored artifacts (uploaded files) are kept
+// and the lifecycle rules a background reaper enforces on them, on async job
+// outputs and on request logs
+type ArtifactsConfig struct {
+	Storage          ArtifactStorageConfig `mapstructure:"storage"`
+	ReapInterval     time.Duration         `mapstructure:"reap_interval"`       // How often lifecycle rules are enforced; 0 disables the reaper
+	FileTTL          time.Duration         `mapstructure:"file_ttl"`            // Age after which uploaded files are deleted; 0 keeps them
+	MaxTeamFileBytes int64                 `mapstructure:"max_team_file_bytes"` // A team's oldest files beyond this size are deleted; 0 is unlimited
+	RequestLogTTL    time.Duration         `mapstructure:"request_log_ttl"`     // Age after which request logs are deleted; 0 keeps them
+}
+
+// DeletionConfig controls how long deleted users, teams and keys can be
+// restored before a background job purges them for good
+type DeletionConfig struct {
+	Retention     time.Duration `mapstructure:"retention"`      // How long deleted records are kept restorable
+	PurgeInterval time.Duration `mapstructure:"purge_interval"` // How often expired records are purged; 0 disables the purge
+}
+
+// ArtifactStorageConfig selects the artifact storage backend
+type ArtifactStorageConfig struct {
+	Type string `mapstructure:"type"` // local
+	Path string `mapstructure:"path"` // Directory of the local backend
+}
+
+// UserNotificationsConfig controls the emails users get about their own
+// usage: alerts when a personal key crosses a spend threshold and a weekly
+// digest. They are sent by the usage worker, apart from the admin alerts.
+type UserNotificationsConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	CheckInterval       time.Duration `mapstructure:"check_interval"`        // How often key spend is checked
+	Thresholds          []float64     `mapstructure:"thresholds"`            // Percentages of a key's budget (default: 50, 80, 100)
+	DigestWeekday       string        `mapstructure:"digest_weekday"`        // Day the weekly digest is sent (default: monday)
+	DigestHour          int           `mapstructure:"digest_hour"`           // UTC hour the weekly digest is sent
+	DefaultSpendAlerts  bool          `mapstructure:"default_spend_alerts"`  // For users who have not set preferences
+	DefaultWeeklyDigest bool          `mapstructure:"default_weekly_digest"` // For users who have not set preferences
+	DashboardURL        string        `mapstructure:"dashboard_url"`         // Linked from the emails
+	SMTP                SMTPConfig    `mapstructure:"smtp"`
+}
+
+// SMTPConfig is the mail server notifications are sent through. STARTTLS is
+// used when the server offers it.
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
 }
 
 var cfg *Config
@@ -151,7 +602,10 @@ func Load(configPath string) (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 
-	if configPath != "" {
+	if ext := filepath.Ext(configPath); ext == ".yaml" || ext == ".yml" {
+		// A config file rather than a directory to search
+		viper.SetConfigFile(configPath)
+	} else if configPath != "" {
 		viper.AddConfigPath(configPath)
 	} else {
 		viper.AddConfigPath(".")
@@ -200,6 +654,19 @@ func Load(configPath string) (*Config, error) {
 							}
 						}
 					}
+					// Header values of custom-openai providers often carry credentials
+					if headers, ok := paramsMap["headers"].(map[string]interface{}); ok {
+						for name, value := range headers {
+							if str, ok := value.(string); ok {
+								headers[name] = envReference.ReplaceAllStringFunc(str, func(ref string) string {
+									if envVal := os.Getenv(ref[2 : len(ref)-1]); envVal != "" {
+										return envVal
+									}
+									return ref
+								})
+							}
+						}
+					}
 				}
 			}
 			models[i] = modelRaw
@@ -223,9 +690,16 @@ func Load(configPath string) (*Config, error) {
 
 	// Convert ModelConfig to ModelInstance format
 	var convertedModels []ModelInstance
+	seenIDs := make(map[string]int)
 	for _, model := range config.RawModelList {
 		instance := ConvertToModelInstance(model)
 		instance.Source = "system"
+		// Instances are keyed by ID, so further instances of a model get a
+		// numbered one ("gpt-4o", "gpt-4o-2")
+		seenIDs[instance.ID]++
+		if n := seenIDs[instance.ID]; n > 1 {
+			instance.ID = fmt.Sprintf("%s-%d", instance.ID, n)
+		}
 		convertedModels = append(convertedModels, instance)
 	}
 
@@ -236,7 +710,7 @@ func Load(configPath string) (*Config, error) {
 	pricingManager := GetPricingManager()
 	if err := pricingManager.LoadDefaultPricing("internal/config"); err != nil {
 		// Log warning but don't fail - pricing can work without default file
-		fmt.Printf("Warning: Failed to load default pricing: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load default pricing: %v\n", err)
 	}
 	
 	// Add config overrides from model instances
@@ -255,15 +729,23 @@ func setDefaults() {
 	viper.SetDefault("server.write_timeout", "300s")
 	viper.SetDefault("server.idle_timeout", "120s")
 	viper.SetDefault("server.graceful_shutdown", "30s")
+	viper.SetDefault("server.max_body_bytes", 33554432) // 32 MiB
 
 	// Database defaults
 	viper.SetDefault("database.max_connections", 100)
 	viper.SetDefault("database.max_idle_connections", 10)
 	viper.SetDefault("database.conn_max_lifetime", "1h")
+	viper.SetDefault("database.auto_migrate", true)
 
 	// Redis defaults
 	viper.SetDefault("redis.db", 0)
 	viper.SetDefault("redis.pool_size", 100)
+	viper.SetDefault("redis.memory_guard.enabled", true)
+	viper.SetDefault("redis.memory_guard.interval", "10s")
+	viper.SetDefault("redis.memory_guard.memory_elevated_ratio", 0.75)
+	viper.SetDefault("redis.memory_guard.memory_critical_ratio", 0.9)
+	viper.SetDefault("redis.memory_guard.queue_elevated_depth", 10000)
+	viper.SetDefault("redis.memory_guard.queue_critical_depth", 50000)
 
 	// JWT defaults
 	viper.SetDefault("jwt.access_token_duration", "15m")
@@ -274,6 +756,10 @@ func setDefaults() {
 	viper.SetDefault("cache.ttl", "3600s")
 	viper.SetDefault("cache.max_size", 1000)
 	viper.SetDefault("cache.strategy", "lru")
+	viper.SetDefault("cache.outputs.enabled", false)
+	viper.SetDefault("cache.outputs.ttl", "24h")
+	viper.SetDefault("cache.outputs.max_entry_bytes", 1048576)
+	viper.SetDefault("cache.coalescing.enabled", false)
 
 	// Rate limit defaults
 	viper.SetDefault("rate_limit.enabled", true)
@@ -290,6 +776,10 @@ func setDefaults() {
 	viper.SetDefault("logging.level", "debug")
 	viper.SetDefault("logging.format", "console")
 	viper.SetDefault("logging.output_path", "")
+	viper.SetDefault("logging.invocations.enabled", false)
+	viper.SetDefault("logging.invocations.retention", "15m")
+	viper.SetDefault("logging.invocations.max_entries", 1000)
+	viper.SetDefault("logging.invocations.max_body_bytes", 65536)
 
 	// CORS defaults
 	viper.SetDefault("cors.allow_credentials", true)
@@ -297,6 +787,12 @@ func setDefaults() {
 
 	// Auth defaults
 	viper.SetDefault("auth.require_auth", false)
+	viper.SetDefault("auth.master_key_tokens.require_exchange", true)
+	viper.SetDefault("auth.master_key_tokens.ttl", "15m")
+	viper.SetDefault("auth.master_key_tokens.max_ttl", "12h")
+	viper.SetDefault("auth.key_cache.enabled", true)
+	viper.SetDefault("auth.key_cache.refresh_interval", "1m")
+	viper.SetDefault("auth.key_cache.ttl", "5m")
 	viper.SetDefault("auth.dex.enabled", false)
 	viper.SetDefault("auth.dex.scopes", []string{"openid", "profile", "email", "groups"})
 	viper.SetDefault("auth.dex.enabled_providers", []string{})
@@ -314,6 +810,8 @@ func setDefaults() {
 	viper.SetDefault("realtime.enable_compression", false)
 	viper.SetDefault("realtime.audio_format", "pcm16")
 	viper.SetDefault("realtime.audio_sample_rate", 24000)
+	viper.SetDefault("realtime.metering.budget_check_interval", "1s")
+	viper.SetDefault("realtime.metering.flush_interval", "10s")
 
 	// Guardrails defaults
 	viper.SetDefault("guardrails.enabled", false)
@@ -326,6 +824,135 @@ func setDefaults() {
 	viper.SetDefault("guardrails.providers.openai.base_url", "https://api.openai.com/v1")
 	viper.SetDefault("guardrails.providers.openai.timeout", "30s")
 	viper.SetDefault("guardrails.providers.aporia.timeout", "10s")
+
+	// Provenance defaults
+	viper.SetDefault("provenance.enabled", false)
+	viper.SetDefault("provenance.mode", "headers")
+
+	// Context window defaults
+	viper.SetDefault("context_window.enabled", false)
+	viper.SetDefault("context_window.strategy", ContextWindowTruncate)
+	viper.SetDefault("context_window.summary_max_tokens", 512)
+
+	// Outage detection defaults
+	viper.SetDefault("outage.enabled", false)
+	viper.SetDefault("outage.poll_interval", "2m")
+	viper.SetDefault("outage.action", OutageActionRaiseThreshold)
+	viper.SetDefault("outage.failure_threshold", 10)
+	viper.SetDefault("outage.spike_failure_rate", 0.5)
+
+	// Retry policy defaults
+	viper.SetDefault("retry_policy.enabled", true)
+	viper.SetDefault("retry_policy.max_attempts", 3)
+	viper.SetDefault("retry_policy.base_backoff", "500ms")
+	viper.SetDefault("retry_policy.max_backoff", "8s")
+	viper.SetDefault("retry_policy.jitter", RetryJitterFull)
+
+	// Load shedding defaults
+	viper.SetDefault("load_shedding.enabled", false)
+	viper.SetDefault("load_shedding.interval", "5s")
+	viper.SetDefault("load_shedding.retry_after", "10s")
+	viper.SetDefault("load_shedding.elevated.cpu_percent", 85)
+	viper.SetDefault("load_shedding.elevated.memory_ratio", 0.8)
+	viper.SetDefault("load_shedding.elevated.goroutines", 20000)
+	viper.SetDefault("load_shedding.elevated.gc_pause_ratio", 0.05)
+	viper.SetDefault("load_shedding.critical.cpu_percent", 95)
+	viper.SetDefault("load_shedding.critical.memory_ratio", 0.9)
+	viper.SetDefault("load_shedding.critical.goroutines", 50000)
+	viper.SetDefault("load_shedding.critical.gc_pause_ratio", 0.15)
+
+	// Coordination defaults
+	viper.SetDefault("coordination.backend", CoordinationBackendRedis)
+	viper.SetDefault("coordination.usage_encoding", "json")
+	viper.SetDefault("coordination.usage_compression", false)
+
+	// Cluster defaults
+	viper.SetDefault("cluster.enabled", true)
+	viper.SetDefault("cluster.heartbeat_interval", "15s")
+	viper.SetDefault("cluster.replica_ttl", "5m")
+
+	// Usage sampling defaults
+	viper.SetDefault("usage_sampling.rate", 1)
+	viper.SetDefault("usage_sampling.keep_errors", true)
+
+	// Bandwidth metering defaults
+	viper.SetDefault("bandwidth.enabled", true)
+	viper.SetDefault("bandwidth.price_per_gb", 0)
+
+	// Prepaid credits defaults
+	viper.SetDefault("billing.stream_checkpoint_interval", "1m")
+	viper.SetDefault("billing.credits.enabled", false)
+	viper.SetDefault("billing.credits.currency", "usd")
+	viper.SetDefault("billing.credits.min_purchase", 5)
+	viper.SetDefault("billing.credits.balance_cache_ttl", "10s")
+
+	// Async jobs defaults
+	viper.SetDefault("jobs.enabled", true)
+	viper.SetDefault("jobs.workers", 4)
+	viper.SetDefault("jobs.timeout", "10m")
+	viper.SetDefault("jobs.result_ttl", "24h")
+	viper.SetDefault("jobs.webhook_timeout", "10s")
+	viper.SetDefault("jobs.outage_queue.enabled", false)
+	viper.SetDefault("jobs.outage_queue.retry_interval", "30s")
+	viper.SetDefault("jobs.outage_queue.max_wait", "30m")
+
+	// Tool runtime defaults
+	viper.SetDefault("tools.enabled", false)
+	viper.SetDefault("tools.max_depth", 5)
+	viper.SetDefault("tools.timeout", "10s")
+	viper.SetDefault("tools.max_result_bytes", 65536)
+
+	// Latency routing and stream stall defaults
+	viper.SetDefault("router.latency_half_life", "1m")
+	viper.SetDefault("router.latency_warmup_samples", 5)
+	viper.SetDefault("router.stream_stall_threshold", "30s")
+	viper.SetDefault("router.stream_keepalive_interval", "15s")
+
+	// Onboarding defaults
+	viper.SetDefault("onboarding.default_template", DefaultOnboardingTemplateName)
+	viper.SetDefault("onboarding.default_role", "user")
+
+	// Admin security defaults
+	viper.SetDefault("admin_security.security_headers", true)
+	viper.SetDefault("admin_security.csrf", true)
+	viper.SetDefault("admin_security.session_cookie", "pllm_session")
+	viper.SetDefault("admin_security.step_up_max_age", "0s")
+
+	// Key rotation defaults
+	viper.SetDefault("key_rotation.default_overlap", "1h")
+	viper.SetDefault("key_rotation.max_overlap", "168h")
+	viper.SetDefault("key_rotation.webhook_timeout", "10s")
+
+	// Bring-your-own-key defaults
+	viper.SetDefault("byok.enabled", false)
+	viper.SetDefault("byok.health_check_interval", "5m")
+	viper.SetDefault("byok.cache_ttl", "30s")
+
+	// Anonymized analytics defaults
+	viper.SetDefault("analytics.privacy.enabled", false)
+	viper.SetDefault("analytics.privacy.min_group_size", 5)
+
+	// Artifact lifecycle defaults
+	viper.SetDefault("artifacts.storage.type", "local")
+	viper.SetDefault("artifacts.storage.path", "./uploads")
+	viper.SetDefault("artifacts.reap_interval", "1h")
+	viper.SetDefault("artifacts.file_ttl", "0s")
+	viper.SetDefault("artifacts.max_team_file_bytes", 0)
+	viper.SetDefault("artifacts.request_log_ttl", "0s")
+
+	// Soft deletion defaults
+	viper.SetDefault("deletion.retention", "720h")
+	viper.SetDefault("deletion.purge_interval", "1h")
+
+	// User notification defaults
+	viper.SetDefault("user_notifications.enabled", false)
+	viper.SetDefault("user_notifications.check_interval", "5m")
+	viper.SetDefault("user_notifications.thresholds", []float64{50, 80, 100})
+	viper.SetDefault("user_notifications.digest_weekday", "monday")
+	viper.SetDefault("user_notifications.digest_hour", 9)
+	viper.SetDefault("user_notifications.default_spend_alerts", true)
+	viper.SetDefault("user_notifications.default_weekly_digest", false)
+	viper.SetDefault("user_notifications.smtp.port", 587)
 }
 
 func bindEnvVars() {
@@ -336,16 +963,21 @@ func bindEnvVars() {
 	_ = viper.BindEnv("server.read_timeout", "SERVER_READ_TIMEOUT")
 	_ = viper.BindEnv("server.write_timeout", "SERVER_WRITE_TIMEOUT")
 	_ = viper.BindEnv("server.idle_timeout", "SERVER_IDLE_TIMEOUT")
+	_ = viper.BindEnv("server.max_body_bytes", "SERVER_MAX_BODY_BYTES")
 
 	// Database
 	_ = viper.BindEnv("database.url", "DATABASE_URL")
 	_ = viper.BindEnv("database.max_connections", "DATABASE_MAX_CONNECTIONS")
 	_ = viper.BindEnv("database.max_idle_connections", "DATABASE_MAX_IDLE_CONNECTIONS")
+	_ = viper.BindEnv("database.auto_migrate", "DATABASE_AUTO_MIGRATE")
 
 	// Redis
 	_ = viper.BindEnv("redis.url", "REDIS_URL")
 	_ = viper.BindEnv("redis.password", "REDIS_PASSWORD")
 	_ = viper.BindEnv("redis.db", "REDIS_DB")
+	_ = viper.BindEnv("redis.namespace", "REDIS_NAMESPACE")
+	_ = viper.BindEnv("redis.memory_guard.enabled", "REDIS_MEMORY_GUARD_ENABLED")
+	_ = viper.BindEnv("redis.memory_guard.max_memory_bytes", "REDIS_MEMORY_GUARD_MAX_MEMORY_BYTES")
 
 	// JWT
 	_ = viper.BindEnv("jwt.secret_key", "JWT_SECRET_KEY")
@@ -359,7 +991,9 @@ func bindEnvVars() {
 
 	// Auth
 	_ = viper.BindEnv("auth.master_key", "PLLM_MASTER_KEY")
+	_ = viper.BindEnv("auth.master_key_tokens.require_exchange", "PLLM_MASTER_KEY_REQUIRE_EXCHANGE")
 	_ = viper.BindEnv("auth.require_auth", "PLLM_REQUIRE_AUTH")
+	_ = viper.BindEnv("auth.key_cache.enabled", "PLLM_KEY_CACHE_ENABLED")
 
 	// Dex OAuth
 	_ = viper.BindEnv("auth.dex.enabled", "DEX_ENABLED")
@@ -405,6 +1039,89 @@ func bindEnvVars() {
 	_ = viper.BindEnv("realtime.enable_compression", "REALTIME_ENABLE_COMPRESSION")
 	_ = viper.BindEnv("realtime.audio_format", "REALTIME_AUDIO_FORMAT")
 	_ = viper.BindEnv("realtime.audio_sample_rate", "REALTIME_AUDIO_SAMPLE_RATE")
+	_ = viper.BindEnv("realtime.metering.budget_check_interval", "REALTIME_BUDGET_CHECK_INTERVAL")
+	_ = viper.BindEnv("realtime.metering.flush_interval", "REALTIME_METERING_FLUSH_INTERVAL")
+
+	// Provenance
+	_ = viper.BindEnv("provenance.enabled", "PROVENANCE_ENABLED")
+	_ = viper.BindEnv("provenance.mode", "PROVENANCE_MODE")
+
+	// Context window
+	_ = viper.BindEnv("context_window.enabled", "CONTEXT_WINDOW_ENABLED")
+	_ = viper.BindEnv("context_window.strategy", "CONTEXT_WINDOW_STRATEGY")
+	_ = viper.BindEnv("context_window.summary_model", "CONTEXT_WINDOW_SUMMARY_MODEL")
+
+	// Outage detection
+	_ = viper.BindEnv("outage.enabled", "OUTAGE_ENABLED")
+	_ = viper.BindEnv("outage.poll_interval", "OUTAGE_POLL_INTERVAL")
+	_ = viper.BindEnv("outage.action", "OUTAGE_ACTION")
+
+	// Retry policy
+	_ = viper.BindEnv("retry_policy.enabled", "RETRY_POLICY_ENABLED")
+	_ = viper.BindEnv("retry_policy.max_attempts", "RETRY_POLICY_MAX_ATTEMPTS")
+
+	// Load shedding
+	_ = viper.BindEnv("load_shedding.enabled", "LOAD_SHEDDING_ENABLED")
+	_ = viper.BindEnv("load_shedding.memory_limit", "LOAD_SHEDDING_MEMORY_LIMIT")
+
+	// Coordination
+	_ = viper.BindEnv("coordination.backend", "COORDINATION_BACKEND")
+	_ = viper.BindEnv("coordination.usage_encoding", "USAGE_QUEUE_ENCODING")
+	_ = viper.BindEnv("coordination.usage_compression", "USAGE_QUEUE_COMPRESSION")
+	_ = viper.BindEnv("usage_sampling.rate", "USAGE_SAMPLING_RATE")
+	_ = viper.BindEnv("bandwidth.enabled", "BANDWIDTH_METERING_ENABLED")
+
+	// Prepaid credits
+	_ = viper.BindEnv("billing.credits.enabled", "BILLING_CREDITS_ENABLED")
+	_ = viper.BindEnv("billing.credits.grace_amount", "BILLING_CREDITS_GRACE_AMOUNT")
+	_ = viper.BindEnv("billing.credits.stripe_secret_key", "STRIPE_SECRET_KEY")
+	_ = viper.BindEnv("billing.credits.stripe_webhook_secret", "STRIPE_WEBHOOK_SECRET")
+
+	// Async jobs
+	_ = viper.BindEnv("jobs.enabled", "JOBS_ENABLED")
+	_ = viper.BindEnv("jobs.workers", "JOBS_WORKERS")
+	_ = viper.BindEnv("jobs.timeout", "JOBS_TIMEOUT")
+	_ = viper.BindEnv("jobs.result_ttl", "JOBS_RESULT_TTL")
+	_ = viper.BindEnv("jobs.outage_queue.enabled", "JOBS_OUTAGE_QUEUE_ENABLED")
+
+	// Tool runtime
+	_ = viper.BindEnv("tools.enabled", "TOOLS_ENABLED")
+	_ = viper.BindEnv("tools.max_depth", "TOOLS_MAX_DEPTH")
+	_ = viper.BindEnv("tools.timeout", "TOOLS_TIMEOUT")
+	_ = viper.BindEnv("tools.allowed_hosts", "TOOLS_ALLOWED_HOSTS")
+
+	// Onboarding
+	_ = viper.BindEnv("onboarding.default_template", "ONBOARDING_DEFAULT_TEMPLATE")
+	_ = viper.BindEnv("onboarding.default_role", "ONBOARDING_DEFAULT_ROLE")
+
+	// Bring your own key
+	_ = viper.BindEnv("byok.enabled", "BYOK_ENABLED")
+	_ = viper.BindEnv("byok.encryption_key", "BYOK_ENCRYPTION_KEY")
+
+	// Anonymized analytics
+	_ = viper.BindEnv("analytics.privacy.enabled", "ANALYTICS_PRIVACY_ENABLED")
+	_ = viper.BindEnv("analytics.privacy.min_group_size", "ANALYTICS_MIN_GROUP_SIZE")
+	_ = viper.BindEnv("analytics.privacy.pseudonym_secret", "ANALYTICS_PSEUDONYM_SECRET")
+	_ = viper.BindEnv("analytics.geoip.database_path", "GEOIP_DATABASE_PATH")
+
+	// Artifact lifecycle
+	_ = viper.BindEnv("artifacts.storage.path", "ARTIFACTS_STORAGE_PATH")
+	_ = viper.BindEnv("artifacts.file_ttl", "ARTIFACTS_FILE_TTL")
+	_ = viper.BindEnv("artifacts.max_team_file_bytes", "ARTIFACTS_MAX_TEAM_FILE_BYTES")
+	_ = viper.BindEnv("artifacts.request_log_ttl", "ARTIFACTS_REQUEST_LOG_TTL")
+
+	// Soft deletion
+	_ = viper.BindEnv("deletion.retention", "DELETION_RETENTION")
+	_ = viper.BindEnv("deletion.purge_interval", "DELETION_PURGE_INTERVAL")
+
+	// User notifications
+	_ = viper.BindEnv("user_notifications.enabled", "USER_NOTIFICATIONS_ENABLED")
+	_ = viper.BindEnv("user_notifications.dashboard_url", "USER_NOTIFICATIONS_DASHBOARD_URL")
+	_ = viper.BindEnv("user_notifications.smtp.host", "SMTP_HOST")
+	_ = viper.BindEnv("user_notifications.smtp.port", "SMTP_PORT")
+	_ = viper.BindEnv("user_notifications.smtp.username", "SMTP_USERNAME")
+	_ = viper.BindEnv("user_notifications.smtp.password", "SMTP_PASSWORD")
+	_ = viper.BindEnv("user_notifications.smtp.from", "SMTP_FROM")
 }
 
 func Get() *Config {
@@ -0,0 +1,141 @@
+package config
+
+// Cost breakdown components
+const (
+	CostInputTokens        = "input_tokens"
+	CostCachedInputTokens  = "cached_input_tokens"
+	CostOutputTokens       = "output_tokens"
+	CostReasoningTokens    = "reasoning_tokens"
+	CostInputImages        = "input_images"
+	CostAudioSeconds       = "audio_seconds"
+	CostOutputAudioSeconds = "output_audio_seconds"
+	CostContextCache       = "context_cache"
+	CostOutputImages       = "output_images"
+	CostImagePixels        = "image_pixels"
+	CostInputCharacters    = "input_characters"
+	CostSearchQueries      = "search_queries"
+	CostBandwidth          = "bandwidth_bytes" // Request and response bytes, see BandwidthConfig
+)
+
+// CostUsage is the billable usage of one request
+type CostUsage struct {
+	InputTokens        int
+	CachedInputTokens  int // Subset of InputTokens read from the provider's prompt cache
+	OutputTokens       int
+	ReasoningTokens    int // Subset of OutputTokens
+	InputImages        int
+	AudioSeconds       float64
+	OutputAudioSeconds float64 // Generated audio, e.g. spoken realtime responses
+	OutputImages       int     // Generated images
+	ImagePixels        int     // Pixels of all generated images, for per-pixel image pricing
+	InputCharacters    int     // Text synthesized to speech
+	SearchQueries      int     // Rerank searches
+}
+
+// CostLineItem is one priced component of a request: quantity × rate
+type CostLineItem struct {
+	Component string  `json:"component"`
+	Quantity  float64 `json:"quantity"`
+	Rate      float64 `json:"rate"`
+	Cost      float64 `json:"cost"`
+}
+
+// CostBreakdown is the full pricing computation of a request, stored with its
+// usage record so the charged amount can be explained after the fact
+type CostBreakdown struct {
+	Model         string         `json:"model"` // Model the pricing was looked up for
+	PricingSource string         `json:"pricing_source,omitempty"`
+	Currency      string         `json:"currency"`
+	Items         []CostLineItem `json:"items"`
+	Subtotal      float64        `json:"subtotal"`
+	MarkupPercent float64        `json:"markup_percent,omitempty"`
+	Markup        float64        `json:"markup,omitempty"`
+	TotalCost     float64        `json:"total_cost"`
+
+	// Estimated is set when the provider reported no usage (e.g. streaming)
+	// and token counts were estimated from the request
+	Estimated bool `json:"estimated,omitempty"`
+
+	// Checkpointed is the part of TotalCost the checkpoints of a long stream
+	// already charged; the usage record charges the rest
+	Checkpointed float64 `json:"checkpointed,omitempty"`
+}
+
+// NewCostBreakdown prices usage line by line. Cached input tokens use
+// CacheReadInputTokenCost and reasoning tokens OutputCostPerReasoningToken,
+// each falling back to the regular rate; the markup applies to the subtotal.
+func NewCostBreakdown(modelName string, pricingInfo *ModelPricingInfo, usage CostUsage, markupPercent float64) *CostBreakdown {
+	cached := min(usage.CachedInputTokens, usage.InputTokens)
+	reasoning := min(usage.ReasoningTokens, usage.OutputTokens)
+
+	cachedRate := pricingInfo.InputCostPerToken
+	if pricingInfo.CacheReadInputTokenCost > 0 {
+		cachedRate = pricingInfo.CacheReadInputTokenCost
+	}
+	reasoningRate := pricingInfo.OutputCostPerToken
+	if pricingInfo.OutputCostPerReasoningToken > 0 {
+		reasoningRate = pricingInfo.OutputCostPerReasoningToken
+	}
+
+	breakdown := &CostBreakdown{
+		Model:         modelName,
+		PricingSource: pricingInfo.Source,
+		Currency:      "USD",
+		Items:         []CostLineItem{},
+	}
+	breakdown.Add(CostInputTokens, float64(usage.InputTokens-cached), pricingInfo.InputCostPerToken)
+	breakdown.Add(CostCachedInputTokens, float64(cached), cachedRate)
+	breakdown.Add(CostOutputTokens, float64(usage.OutputTokens-reasoning), pricingInfo.OutputCostPerToken)
+	breakdown.Add(CostReasoningTokens, float64(reasoning), reasoningRate)
+	breakdown.Add(CostInputImages, float64(usage.InputImages), pricingInfo.InputCostPerImage)
+	breakdown.Add(CostAudioSeconds, usage.AudioSeconds, pricingInfo.InputCostPerSecond)
+	breakdown.Add(CostOutputAudioSeconds, usage.OutputAudioSeconds, pricingInfo.OutputCostPerSecond)
+	breakdown.Add(CostOutputImages, float64(usage.OutputImages), pricingInfo.OutputCostPerImage)
+	breakdown.Add(CostImagePixels, float64(usage.ImagePixels), pricingInfo.InputCostPerPixel)
+	breakdown.Add(CostInputCharacters, float64(usage.InputCharacters), pricingInfo.InputCostPerCharacter)
+	breakdown.Add(CostSearchQueries, float64(usage.SearchQueries), pricingInfo.InputCostPerQuery)
+	breakdown.ApplyMarkup(markupPercent)
+	return breakdown
+}
+
+// Add appends a line item, skipping components that were not used
+func (b *CostBreakdown) Add(component string, quantity, rate float64) {
+	if quantity <= 0 {
+		return
+	}
+	cost := quantity * rate
+	b.Items = append(b.Items, CostLineItem{
+		Component: component,
+		Quantity:  quantity,
+		Rate:      rate,
+		Cost:      cost,
+	})
+	b.Subtotal += cost
+	b.TotalCost = b.Subtotal + b.Markup
+}
+
+// ApplyMarkup sets the markup on the current subtotal
+func (b *CostBreakdown) ApplyMarkup(percent float64) {
+	b.MarkupPercent = percent
+	b.Markup = b.Subtotal * percent / 100
+	b.TotalCost = b.Subtotal + b.Markup
+}
+
+// InputCost returns the cost of everything sent to the model, before markup
+func (b *CostBreakdown) InputCost() float64 {
+	var cost float64
+	for _, item := range b.Items {
+		switch item.Component {
+		case CostOutputTokens, CostReasoningTokens, CostOutputAudioSeconds, CostOutputImages, CostImagePixels:
+		default:
+			cost += item.Cost
+		}
+	}
+	return cost
+}
+
+// OutputCost returns the cost of generated tokens, audio and images, before
+// markup
+func (b *CostBreakdown) OutputCost() float64 {
+	return b.Subtotal - b.InputCost()
+}
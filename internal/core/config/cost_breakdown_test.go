@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCostBreakdown(t *testing.T) {
+	pricing := &ModelPricingInfo{
+		InputCostPerToken:           0.00001,
+		CacheReadInputTokenCost:     0.000001,
+		OutputCostPerToken:          0.00003,
+		OutputCostPerReasoningToken: 0.00006,
+		InputCostPerImage:           0.002,
+		Source:                      "builtin",
+	}
+
+	breakdown := NewCostBreakdown("gpt-test", pricing, CostUsage{
+		InputTokens:       1000,
+		CachedInputTokens: 400,
+		OutputTokens:      500,
+		ReasoningTokens:   100,
+		InputImages:       2,
+	}, 10)
+
+	components := map[string]CostLineItem{}
+	for _, item := range breakdown.Items {
+		components[item.Component] = item
+	}
+	require.Len(t, components, 5)
+	assert.Equal(t, 600.0, components[CostInputTokens].Quantity)
+	assert.Equal(t, 400.0, components[CostCachedInputTokens].Quantity)
+	assert.Equal(t, 0.000001, components[CostCachedInputTokens].Rate)
+	assert.Equal(t, 400.0, components[CostOutputTokens].Quantity)
+	assert.Equal(t, 0.00006, components[CostReasoningTokens].Rate)
+	assert.NotContains(t, components, CostAudioSeconds, "unused components are omitted")
+
+	subtotal := 600*0.00001 + 400*0.000001 + 400*0.00003 + 100*0.00006 + 2*0.002
+	assert.InDelta(t, subtotal, breakdown.Subtotal, 1e-12)
+	assert.InDelta(t, subtotal*0.1, breakdown.Markup, 1e-12)
+	assert.InDelta(t, subtotal*1.1, breakdown.TotalCost, 1e-12)
+	assert.InDelta(t, subtotal, breakdown.InputCost()+breakdown.OutputCost(), 1e-12)
+	assert.InDelta(t, 400*0.00003+100*0.00006, breakdown.OutputCost(), 1e-12)
+
+	t.Run("falls back to regular rates", func(t *testing.T) {
+		b := NewCostBreakdown("plain", &ModelPricingInfo{InputCostPerToken: 0.00001, OutputCostPerToken: 0.00003},
+			CostUsage{InputTokens: 100, CachedInputTokens: 50, OutputTokens: 10, ReasoningTokens: 5}, 0)
+		assert.InDelta(t, 100*0.00001+10*0.00003, b.TotalCost, 1e-12)
+		assert.Zero(t, b.Markup)
+	})
+
+	t.Run("audio billed per second", func(t *testing.T) {
+		b := NewCostBreakdown("realtime", &ModelPricingInfo{InputCostPerSecond: 0.001, OutputCostPerSecond: 0.004},
+			CostUsage{AudioSeconds: 30, OutputAudioSeconds: 10}, 0)
+		require.Len(t, b.Items, 2)
+		assert.Equal(t, CostOutputAudioSeconds, b.Items[1].Component)
+		assert.InDelta(t, 0.03, b.InputCost(), 1e-12)
+		assert.InDelta(t, 0.04, b.OutputCost(), 1e-12)
+	})
+
+	t.Run("images and speech", func(t *testing.T) {
+		b := NewCostBreakdown("dall-e-3", &ModelPricingInfo{InputCostPerPixel: 4e-8},
+			CostUsage{OutputImages: 2, ImagePixels: 2 * 1024 * 1024}, 0)
+		require.Len(t, b.Items, 2)
+		assert.Zero(t, b.Items[0].Cost, "images without a per-image rate are priced by pixel only")
+		assert.Equal(t, CostImagePixels, b.Items[1].Component)
+		assert.InDelta(t, 2*1024*1024*4e-8, b.OutputCost(), 1e-12)
+
+		b = NewCostBreakdown("tts-1", &ModelPricingInfo{InputCostPerCharacter: 0.000015},
+			CostUsage{InputCharacters: 1000}, 0)
+		assert.InDelta(t, 0.015, b.InputCost(), 1e-12)
+		assert.Zero(t, b.OutputCost())
+	})
+
+	t.Run("rerank searches", func(t *testing.T) {
+		b := NewCostBreakdown("rerank-v3.5", &ModelPricingInfo{InputCostPerQuery: 0.002},
+			CostUsage{SearchQueries: 3}, 0)
+		require.Len(t, b.Items, 1)
+		assert.Equal(t, CostSearchQueries, b.Items[0].Component)
+		assert.InDelta(t, 0.006, b.InputCost(), 1e-12)
+	})
+}
@@ -7,6 +7,10 @@ type GuardrailsConfig struct {
 	Enabled    bool              `mapstructure:"enabled"`
 	Guardrails []GuardrailConfig `mapstructure:"guardrails"`
 	Providers  ProviderConfigs   `mapstructure:"providers"`
+
+	// Characters of streamed output between post-call checks; 0 checks
+	// every chunk
+	StreamCheckChars int `mapstructure:"stream_check_chars"`
 }
 
 // GuardrailConfig defines a single guardrail rule
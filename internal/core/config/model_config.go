@@ -1,6 +1,7 @@
 package config
 
 import (
+	"regexp"
 	"time"
 )
 
@@ -11,6 +12,7 @@ type ModelInstance struct {
 	ID           string `mapstructure:"id" json:"id"`                       // Unique instance ID (auto-generated if not provided)
 	ModelName    string `mapstructure:"model_name" json:"model_name"`       // User-facing model name (e.g., "gpt-4")
 	InstanceName string `mapstructure:"instance_name" json:"instance_name"` // Optional instance name
+	Version      string `mapstructure:"version" json:"version,omitempty"`   // Model snapshot served (e.g., "2024-08-06"), inferred from a dated provider model when empty
 
 	// Provider configuration
 	Provider ProviderParams `mapstructure:"provider" json:"provider"`
@@ -31,6 +33,12 @@ type ModelInstance struct {
 	Timeout        time.Duration `mapstructure:"timeout" json:"timeout"`
 	CooldownPeriod time.Duration `mapstructure:"cooldown_period" json:"cooldown_period"` // After failure
 
+	// Canary health probes instead of the provider's endpoint check
+	HealthProbe HealthProbeConfig `mapstructure:"health_probe" json:"health_probe"`
+
+	// Reserved capacity, such as Azure PTUs or Bedrock provisioned throughput
+	Provisioned ProvisionedConfig `mapstructure:"provisioned" json:"provisioned"`
+
 	// Cost tracking
 	InputCostPerToken  float64 `mapstructure:"input_cost_per_token" json:"input_cost_per_token"`
 	OutputCostPerToken float64 `mapstructure:"output_cost_per_token" json:"output_cost_per_token"`
@@ -48,6 +56,67 @@ type ModelInstance struct {
 	Source string `mapstructure:"-" json:"source,omitempty"`
 }
 
+// snapshotSuffix matches the snapshot date at the end of provider model IDs,
+// such as gpt-4o-2024-08-06 or claude-3-5-sonnet-20241022
+var snapshotSuffix = regexp.MustCompile(`-(\d{4}-\d{2}-\d{2}|\d{8})$`)
+
+// SnapshotVersion returns the model snapshot the instance serves: the
+// configured version, or the date suffix of the provider model (the Azure
+// base model for deployments). Empty when the snapshot is unknown.
+func (m ModelInstance) SnapshotVersion() string {
+	if m.Version != "" {
+		return m.Version
+	}
+	model := m.Provider.Model
+	if m.Provider.AzureBaseModel != "" {
+		model = m.Provider.AzureBaseModel
+	}
+	if match := snapshotSuffix.FindStringSubmatch(model); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// DiscoverAllModels as an instance's provider model registers one instance per
+// model the provider's server lists
+const DiscoverAllModels = "*"
+
+// DiscoversModels reports whether the instance stands for every model its
+// provider's server lists rather than a single model
+func (m ModelInstance) DiscoversModels() bool {
+	return m.Provider.Model == DiscoverAllModels
+}
+
+// HealthProbeConfig replaces an instance's endpoint health check with a tiny
+// completion, which shows the model itself answers, within a daily spend
+// cap. Probes that would take the day's spend over the cap are replaced by
+// the endpoint check until the next UTC day.
+type HealthProbeConfig struct {
+	Enabled       bool    `mapstructure:"enabled" json:"enabled"`
+	MaxDailySpend float64 `mapstructure:"max_daily_spend" json:"max_daily_spend"` // USD per instance and UTC day (default: 0.10)
+	MaxTokens     int     `mapstructure:"max_tokens" json:"max_tokens"`           // Completion tokens per probe (default: 1)
+	Prompt        string  `mapstructure:"prompt" json:"prompt"`                   // Probe message (default: "ping")
+}
+
+// ProvisionedConfig marks an instance as reserved capacity, paid for whether
+// it is used or not. The router fills reserved instances first and overflows
+// to the model's pay-as-you-go instances only above OverflowThreshold.
+type ProvisionedConfig struct {
+	CapacityUnits     int     `mapstructure:"capacity_units" json:"capacity_units"`             // Reserved units (PTUs, model units); 0 means pay-as-you-go
+	TPMPerUnit        int     `mapstructure:"tpm_per_unit" json:"tpm_per_unit"`                 // Tokens per minute one unit serves
+	OverflowThreshold float64 `mapstructure:"overflow_threshold" json:"overflow_threshold"`     // Utilization (0-1) above which requests overflow (default: 0.9)
+	HourlyCostPerUnit float64 `mapstructure:"hourly_cost_per_unit" json:"hourly_cost_per_unit"` // USD, for utilization reports
+}
+
+// CapacityTPM returns the tokens per minute the reservation serves, 0 for
+// pay-as-you-go instances
+func (p ProvisionedConfig) CapacityTPM() int64 {
+	if p.CapacityUnits <= 0 || p.TPMPerUnit <= 0 {
+		return 0
+	}
+	return int64(p.CapacityUnits) * int64(p.TPMPerUnit)
+}
+
 // ProviderParams contains provider-specific parameters
 type ProviderParams struct {
 	// Provider type and model
@@ -73,16 +142,37 @@ type ProviderParams struct {
 	// Azure specific
 	AzureDeployment string `mapstructure:"azure_deployment" json:"azure_deployment"`
 	AzureEndpoint   string `mapstructure:"azure_endpoint" json:"azure_endpoint"`
+	AzureBaseModel  string `mapstructure:"azure_base_model" json:"azure_base_model,omitempty"` // Model behind the deployment when model is a deployment alias
 
 	// AWS Bedrock specific
 	AWSAccessKeyID     string `mapstructure:"aws_access_key_id" json:"aws_access_key_id"`
 	AWSSecretAccessKey string `mapstructure:"aws_secret_access_key" json:"aws_secret_access_key"`
 	AWSRegionName      string `mapstructure:"aws_region_name" json:"aws_region_name"`
+	AWSSessionToken    string `mapstructure:"aws_session_token" json:"aws_session_token,omitempty"`
+	AWSProfileName     string `mapstructure:"aws_profile_name" json:"aws_profile_name,omitempty"` // Shared config profile when no static keys are set
+	AWSRoleARN         string `mapstructure:"aws_role_arn" json:"aws_role_arn,omitempty"`         // Role assumed on top of the resolved credentials
+	AWSExternalID      string `mapstructure:"aws_external_id" json:"aws_external_id,omitempty"`
+	AWSRoleSessionName string `mapstructure:"aws_role_session_name" json:"aws_role_session_name,omitempty"`
 
 	// Vertex AI specific
 	VertexProject  string `mapstructure:"vertex_project" json:"vertex_project"`
 	VertexLocation string `mapstructure:"vertex_location" json:"vertex_location"`
 
+	// NVIDIA NIM / Triton specific
+	ServerURL  string `mapstructure:"server_url" json:"server_url,omitempty"`   // Server root for health and repository probes
+	MetricsURL string `mapstructure:"metrics_url" json:"metrics_url,omitempty"` // Prometheus metrics with GPU utilization
+
+	// IBM watsonx.ai specific (project_id above, or space_id for deployment spaces)
+	SpaceID string `mapstructure:"space_id" json:"space_id,omitempty"`
+	IAMURL  string `mapstructure:"iam_url" json:"iam_url,omitempty"` // IAM token endpoint for private or dedicated regions
+
+	// Cohere specific
+	EmbeddingInputType string `mapstructure:"embedding_input_type" json:"embedding_input_type,omitempty"` // search_document (default), search_query, classification or clustering
+
+	// Custom OpenAI-compatible servers (custom-openai)
+	Headers map[string]string `mapstructure:"headers" json:"headers,omitempty"` // Sent with every request, e.g. for gateways that authenticate with their own header
+	Paths   map[string]string `mapstructure:"paths" json:"paths,omitempty"`     // Endpoint path overrides: chat_completions, completions, embeddings, models
+
 	// Reasoning model defaults
 	ReasoningEffort string `mapstructure:"reasoning_effort" json:"reasoning_effort,omitempty"`
 
@@ -101,6 +191,25 @@ type ModelInfo struct {
 	MaxOutputTokens    int      `mapstructure:"max_output_tokens" json:"max_output_tokens"`
 	DefaultMaxTokens   int      `mapstructure:"default_max_tokens" json:"default_max_tokens"`
 	SupportedLanguages []string `mapstructure:"supported_languages" json:"supported_languages"`
+
+	// Embedding models: vectors are reduced at the gateway unless the
+	// provider supports the dimensions parameter itself
+	EmbeddingDimensions int  `mapstructure:"embedding_dimensions" json:"embedding_dimensions,omitempty"` // Vector size when the request sets no dimensions
+	SupportsDimensions  bool `mapstructure:"supports_dimensions" json:"supports_dimensions,omitempty"`
+
+	// Documentation of the model in the catalog (/v1/models/catalog), so
+	// users know which gateway model to pick
+	Description string      `mapstructure:"description" json:"description,omitempty"`
+	IntendedUse string      `mapstructure:"intended_use" json:"intended_use,omitempty"`
+	Owner       string      `mapstructure:"owner" json:"owner,omitempty"`       // Team or person responsible for the model
+	Contacts    []string    `mapstructure:"contacts" json:"contacts,omitempty"` // Emails or chat channels for questions
+	Links       []ModelLink `mapstructure:"links" json:"links,omitempty"`       // Documentation, evaluations, model cards
+}
+
+// ModelLink is a link from the model catalog
+type ModelLink struct {
+	Title string `mapstructure:"title" json:"title"`
+	URL   string `mapstructure:"url" json:"url"`
 }
 
 // RouterSettings contains load balancing and routing configuration
@@ -112,6 +221,21 @@ type RouterSettings struct {
 	MaxRetries          int           `mapstructure:"max_retries" json:"max_retries"`
 	EnableLoadBalancing bool          `mapstructure:"enable_load_balancing" json:"enable_load_balancing"`
 	HealthCheckInterval time.Duration `mapstructure:"health_check_interval" json:"health_check_interval"`
+	SnapshotInterval    time.Duration `mapstructure:"snapshot_interval" json:"snapshot_interval"` // How often instance state is saved to Redis (default: 30s)
+
+	// Latency-based routing: decayed averages weigh a sample half as much
+	// every LatencyHalfLife; models with fewer effective samples than
+	// LatencyWarmupSamples are scored toward the best warmed-up latency
+	LatencyHalfLife      time.Duration `mapstructure:"latency_half_life" json:"latency_half_life"`
+	LatencyWarmupSamples int           `mapstructure:"latency_warmup_samples" json:"latency_warmup_samples"`
+
+	// A stream sending no chunk for this long records a failure on its
+	// instance (default: 30s)
+	StreamStallThreshold time.Duration `mapstructure:"stream_stall_threshold" json:"stream_stall_threshold"`
+	// A stream quiet for this long gets an SSE comment, so load balancers
+	// with idle timeouts keep slow reasoning models connected (default:
+	// 15s, negative disables)
+	StreamKeepAliveInterval time.Duration `mapstructure:"stream_keepalive_interval" json:"stream_keepalive_interval"`
 
 	// Failover configuration
 	EnableFailover          bool                `mapstructure:"enable_failover" json:"enable_failover"`                       // Enable automatic failover
@@ -146,6 +270,15 @@ type RouteConfig struct {
 	Models         []RouteModelConfig `mapstructure:"models" json:"models"`
 	FallbackModels []string           `mapstructure:"fallback_models" json:"fallback_models"`
 	Enabled        *bool              `mapstructure:"enabled" json:"enabled"`
+	Defaults       RouteDefaults      `mapstructure:"defaults" json:"defaults"`
+}
+
+// RouteDefaults are request parameters applied to every request for a route
+// that does not set them, whichever member model serves it
+type RouteDefaults struct {
+	ReasoningEffort string   `mapstructure:"reasoning_effort" json:"reasoning_effort,omitempty"`
+	MaxTokens       int      `mapstructure:"max_tokens" json:"max_tokens,omitempty"`
+	Temperature     *float64 `mapstructure:"temperature" json:"temperature,omitempty"`
 }
 
 // RouteModelConfig represents a model entry within a route config
@@ -154,3 +287,15 @@ type RouteModelConfig struct {
 	Weight    int    `mapstructure:"weight" json:"weight"`
 	Priority  int    `mapstructure:"priority" json:"priority"`
 }
+
+// TierConfig defines a latency tier requested as "tier:<name>". The gateway
+// picks the tier's best model for each request from live latency and cost.
+type TierConfig struct {
+	Name        string   `mapstructure:"name" json:"name"`
+	Description string   `mapstructure:"description" json:"description"`
+	Models      []string `mapstructure:"models" json:"models"`
+
+	// Relative weight of latency and cost in the ranking (default 0.7 / 0.3)
+	LatencyWeight *float64 `mapstructure:"latency_weight" json:"latency_weight,omitempty"`
+	CostWeight    *float64 `mapstructure:"cost_weight" json:"cost_weight,omitempty"`
+}
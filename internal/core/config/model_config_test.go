@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelInstance_SnapshotVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance ModelInstance
+		want     string
+	}{
+		{"dated openai model", ModelInstance{Provider: ProviderParams{Model: "gpt-4o-2024-08-06"}}, "2024-08-06"},
+		{"dated anthropic model", ModelInstance{Provider: ProviderParams{Model: "claude-3-5-sonnet-20241022"}}, "20241022"},
+		{"azure base model", ModelInstance{Provider: ProviderParams{Model: "prod", AzureBaseModel: "gpt-4o-2024-05-13"}}, "2024-05-13"},
+		{"configured version", ModelInstance{Version: "v2", Provider: ProviderParams{Model: "gpt-4o-2024-08-06"}}, "v2"},
+		{"undated model", ModelInstance{Provider: ProviderParams{Model: "gpt-4o"}}, ""},
+		{"other numeric suffix", ModelInstance{Provider: ProviderParams{Model: "llama-3-70b"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.instance.SnapshotVersion())
+		})
+	}
+}
+
+func TestModelPricingManager_PinnedVersion(t *testing.T) {
+	pm := &ModelPricingManager{
+		defaultPricing:   map[string]*ModelPricingInfo{"gpt-4o": {InputCostPerToken: 0.0000025}},
+		configOverrides:  make(map[string]*ModelPricingInfo),
+		dbOverrides:      make(map[string]*ModelPricingInfo),
+		providerModelMap: make(map[string]string),
+	}
+
+	pricing := pm.GetPricing("gpt-4o@2024-08-06")
+	if assert.NotNil(t, pricing, "pinned names are priced as their model") {
+		assert.Equal(t, 0.0000025, pricing.InputCostPerToken)
+	}
+	assert.Nil(t, pm.GetPricing("unknown@2024-08-06"))
+}
+
+func TestConvertToModelInstance_InstanceSettings(t *testing.T) {
+	instance := ConvertToModelInstance(ModelConfig{
+		ModelName:   "gpt-4o",
+		Provider:    ProviderParams{Type: "azure", Model: "gpt-4o"},
+		Provisioned: ProvisionedConfig{CapacityUnits: 100, TPMPerUnit: 2500},
+		HealthProbe: HealthProbeConfig{Enabled: true, MaxDailySpend: 0.05},
+	})
+	assert.Equal(t, int64(250000), instance.Provisioned.CapacityTPM())
+	assert.Equal(t, HealthProbeConfig{Enabled: true, MaxDailySpend: 0.05}, instance.HealthProbe)
+
+	assert.Zero(t, ProvisionedConfig{CapacityUnits: 100}.CapacityTPM(), "pay-as-you-go without tokens per unit")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -22,10 +23,20 @@ type ModelPricingInfo struct {
 	OutputCostPerReasoningToken float64 `json:"output_cost_per_reasoning_token,omitempty"`
 	InputCostPerTokenBatches    float64 `json:"input_cost_per_token_batches,omitempty"`
 	OutputCostPerTokenBatches   float64 `json:"output_cost_per_token_batches,omitempty"`
+
+	// Context caching
+	CacheCreationInputTokenCost  float64 `json:"cache_creation_input_token_cost,omitempty"`
+	CacheReadInputTokenCost      float64 `json:"cache_read_input_token_cost,omitempty"`
+	CacheStorageCostPerTokenHour float64 `json:"cache_storage_cost_per_token_hour,omitempty"` // Provider-side caches (Gemini)
 	
 	// Alternative pricing models
 	InputCostPerSecond  float64 `json:"input_cost_per_second,omitempty"`  // For time-based billing
+	InputCostPerImage   float64 `json:"input_cost_per_image,omitempty"`   // Images sent to vision models
 	OutputCostPerSecond float64 `json:"output_cost_per_second,omitempty"` // For time-based billing
+	OutputCostPerImage    float64 `json:"output_cost_per_image,omitempty"`    // Generated images, flat per image
+	InputCostPerPixel     float64 `json:"input_cost_per_pixel,omitempty"`     // Generated images, per pixel of each image
+	InputCostPerCharacter float64 `json:"input_cost_per_character,omitempty"` // Text synthesized to speech
+	InputCostPerQuery     float64 `json:"input_cost_per_query,omitempty"`     // Rerank searches
 	
 	// Model metadata
 	Provider         string   `json:"provider"`
@@ -262,7 +273,21 @@ func (pm *ModelPricingManager) GetPricing(modelName string) *ModelPricingInfo {
 func (pm *ModelPricingManager) GetPricingForTeam(modelName string, teamID *uint) *ModelPricingInfo {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
-	
+
+	if info := pm.lookupPricing(modelName, teamID); info != nil {
+		return info
+	}
+
+	// Version-pinned names (model@version) are priced as their model
+	if name, version, pinned := strings.Cut(modelName, "@"); pinned && name != "" && version != "" {
+		return pm.lookupPricing(name, teamID)
+	}
+	return nil
+}
+
+// lookupPricing returns the pricing of a model name. The caller must hold
+// the read lock.
+func (pm *ModelPricingManager) lookupPricing(modelName string, teamID *uint) *ModelPricingInfo {
 	// Check database overrides first if repository is available
 	if pm.dbRepo != nil {
 		if dbInfo, err := pm.dbRepo.GetEffectivePricing(modelName, teamID); err == nil {
@@ -386,39 +411,66 @@ func (pm *ModelPricingManager) ListAllModels() map[string]string {
 
 // CalculateCost calculates the cost for a request
 func (pm *ModelPricingManager) CalculateCost(modelName string, inputTokens, outputTokens int) (*CostCalculation, error) {
+	return pm.CalculateCostWithReasoning(modelName, inputTokens, outputTokens, 0)
+}
+
+// CalculateCostWithReasoning calculates cost when part of the output tokens
+// were spent on reasoning. reasoningTokens is a subset of outputTokens.
+func (pm *ModelPricingManager) CalculateCostWithReasoning(modelName string, inputTokens, outputTokens, reasoningTokens int) (*CostCalculation, error) {
 	pricingInfo := pm.GetPricing(modelName)
 	if pricingInfo == nil {
 		return nil, fmt.Errorf("pricing information not found for model: %s", modelName)
 	}
-	
+
+	return NewCostCalculation(modelName, pricingInfo, inputTokens, outputTokens, reasoningTokens), nil
+}
+
+// NewCostCalculation prices token usage with the given pricing info.
+// Reasoning tokens are billed at OutputCostPerReasoningToken when set and at
+// the regular output rate otherwise; either way they are reported separately.
+func NewCostCalculation(modelName string, pricingInfo *ModelPricingInfo, inputTokens, outputTokens, reasoningTokens int) *CostCalculation {
+	if reasoningTokens > outputTokens {
+		reasoningTokens = outputTokens
+	}
+
+	reasoningRate := pricingInfo.OutputCostPerToken
+	if pricingInfo.OutputCostPerReasoningToken > 0 {
+		reasoningRate = pricingInfo.OutputCostPerReasoningToken
+	}
+
 	inputCost := float64(inputTokens) * pricingInfo.InputCostPerToken
-	outputCost := float64(outputTokens) * pricingInfo.OutputCostPerToken
-	totalCost := inputCost + outputCost
-	
+	outputCost := float64(outputTokens-reasoningTokens) * pricingInfo.OutputCostPerToken
+	reasoningCost := float64(reasoningTokens) * reasoningRate
+	totalCost := inputCost + outputCost + reasoningCost
+
 	return &CostCalculation{
-		ModelName:    modelName,
-		InputTokens:  inputTokens,
-		OutputTokens: outputTokens,
-		InputCost:    inputCost,
-		OutputCost:   outputCost,
-		TotalCost:    totalCost,
-		Currency:     "USD",
-		Source:       pricingInfo.Source,
-		Timestamp:    time.Now(),
-	}, nil
+		ModelName:       modelName,
+		InputTokens:     inputTokens,
+		OutputTokens:    outputTokens,
+		ReasoningTokens: reasoningTokens,
+		InputCost:       inputCost,
+		OutputCost:      outputCost,
+		ReasoningCost:   reasoningCost,
+		TotalCost:       totalCost,
+		Currency:        "USD",
+		Source:          pricingInfo.Source,
+		Timestamp:       time.Now(),
+	}
 }
 
 // CostCalculation represents a cost calculation result
 type CostCalculation struct {
-	ModelName    string    `json:"model_name"`
-	InputTokens  int       `json:"input_tokens"`
-	OutputTokens int       `json:"output_tokens"`
-	InputCost    float64   `json:"input_cost"`
-	OutputCost   float64   `json:"output_cost"`
-	TotalCost    float64   `json:"total_cost"`
-	Currency     string    `json:"currency"`
-	Source       string    `json:"source"` // Which pricing source was used
-	Timestamp    time.Time `json:"timestamp"`
+	ModelName       string    `json:"model_name"`
+	InputTokens     int       `json:"input_tokens"`
+	OutputTokens    int       `json:"output_tokens"`
+	ReasoningTokens int       `json:"reasoning_tokens,omitempty"` // Included in OutputTokens
+	InputCost       float64   `json:"input_cost"`
+	OutputCost      float64   `json:"output_cost"` // Excludes reasoning tokens
+	ReasoningCost   float64   `json:"reasoning_cost,omitempty"`
+	TotalCost       float64   `json:"total_cost"`
+	Currency        string    `json:"currency"`
+	Source          string    `json:"source"` // Which pricing source was used
+	Timestamp       time.Time `json:"timestamp"`
 }
 
 // GetModelInfo returns combined model information for API responses
@@ -9,6 +9,7 @@ import (
 type ModelConfig struct {
 	// Required fields
 	ModelName string `mapstructure:"model_name" json:"model_name"` // User-facing name (e.g., "my-gpt-4")
+	Version   string `mapstructure:"version" json:"version"`       // Snapshot served, for version-pinned names like "my-gpt-4@2024-08-06"
 	
 	// Provider configuration (supports both old and new format)
 	Params   ModelParams      `mapstructure:"params" json:"params"`     // Old format (deprecated)
@@ -21,6 +22,12 @@ type ModelConfig struct {
 	InputCostPerToken  float64 `mapstructure:"input_cost_per_token" json:"input_cost_per_token"`
 	OutputCostPerToken float64 `mapstructure:"output_cost_per_token" json:"output_cost_per_token"`
 
+	// Canary health probes instead of the provider's endpoint check
+	HealthProbe HealthProbeConfig `mapstructure:"health_probe" json:"health_probe"`
+
+	// Reserved capacity (Azure PTUs, Bedrock provisioned throughput)
+	Provisioned ProvisionedConfig `mapstructure:"provisioned" json:"provisioned"`
+
 	// Optional fields
 	RPM      int           `mapstructure:"rpm" json:"rpm"`           // Requests per minute
 	TPM      int           `mapstructure:"tpm" json:"tpm"`           // Tokens per minute
@@ -40,6 +47,7 @@ type ModelParams struct {
 	APIKey     string `mapstructure:"api_key" json:"api_key"`         // Can be env var reference like ${OPENAI_API_KEY}
 	APIBase    string `mapstructure:"api_base" json:"api_base"`       // Base URL for the API
 	APIVersion string `mapstructure:"api_version" json:"api_version"` // API version (for Azure)
+	BaseModel  string `mapstructure:"base_model" json:"base_model"`   // Model behind an Azure deployment (e.g., "o3-mini")
 
 	// Optional - Request defaults
 	Temperature *float32 `mapstructure:"temperature" json:"temperature"`
@@ -91,6 +99,7 @@ func ConvertToModelInstance(cfg ModelConfig) ModelInstance {
 		if providerType == "azure" {
 			provider.AzureDeployment = modelName
 			provider.AzureEndpoint = cfg.Params.APIBase
+			provider.AzureBaseModel = cfg.Params.BaseModel
 		}
 
 		// Handle OpenRouter-specific configuration
@@ -149,10 +158,13 @@ func ConvertToModelInstance(cfg ModelConfig) ModelInstance {
 	return ModelInstance{
 		ID:                 cfg.ModelName, // Use model_name as ID
 		ModelName:          cfg.ModelName,
+		Version:            cfg.Version,
 		Provider:           provider,
 		ModelInfo:          modelInfo,
 		InputCostPerToken:  cfg.InputCostPerToken,
 		OutputCostPerToken: cfg.OutputCostPerToken,
+		HealthProbe:        cfg.HealthProbe,
+		Provisioned:        cfg.Provisioned,
 		RPM:                rpm,
 		TPM:                tpm,
 		Priority:           priority,
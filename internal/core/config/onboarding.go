@@ -0,0 +1,207 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultOnboardingTemplateName is the template used when none is configured
+// or selected
+const DefaultOnboardingTemplateName = "default"
+
+// OnboardingConfig holds the organization's onboarding templates. A template
+// sets the limits of teams created from it, and what users auto-provisioned
+// from Dex receive: the team they join, their budget within it and a key.
+type OnboardingConfig struct {
+	DefaultTemplate string                        `mapstructure:"default_template"`
+	Templates       map[string]OnboardingTemplate `mapstructure:"templates"`
+
+	// AutoJoin places users by email domain or Dex group. Users matching
+	// no rule are onboarded with the template picked by their groups, or
+	// join no team when SkipUnmatched is set.
+	AutoJoin      []AutoJoinRule `mapstructure:"auto_join"`
+	SkipUnmatched bool           `mapstructure:"skip_unmatched"`
+
+	// DefaultRole is the role of provisioned users no rule grants one
+	DefaultRole string `mapstructure:"default_role"`
+}
+
+// AutoJoinRule puts users provisioned from Dex whose email domain or groups
+// match into the team of an onboarding template
+type AutoJoinRule struct {
+	Domains  []string `mapstructure:"domains"`   // Email domains, e.g. acme.com
+	Groups   []string `mapstructure:"groups"`    // Dex groups
+	Template string   `mapstructure:"template"`  // Template whose team the user joins
+	TeamRole string   `mapstructure:"team_role"` // owner, admin, member, viewer, billing_admin or auditor
+	Role     string   `mapstructure:"role"`      // Global role: admin, manager, user or viewer
+}
+
+// OnboardingTemplate is one named onboarding template
+type OnboardingTemplate struct {
+	// Groups are the Dex groups whose members are onboarded with this
+	// template instead of the default one
+	Groups []string `mapstructure:"groups"`
+
+	// TeamName is the team auto-provisioned users join. It is created from
+	// this template when missing and defaults to the template name.
+	TeamName string `mapstructure:"team_name"`
+
+	Team TeamTemplate `mapstructure:"team"`
+
+	// MemberBudgets caps a new member's spend in the team, keyed by team
+	// role (owner, admin, member, viewer). Roles left out use the team budget.
+	MemberBudgets map[string]float64 `mapstructure:"member_budgets"`
+
+	Key KeyTemplate `mapstructure:"key"`
+}
+
+// TeamTemplate holds the limits of a new team. They apply to every field a
+// team creation request leaves unset.
+type TeamTemplate struct {
+	MaxBudget        float64  `mapstructure:"max_budget"`
+	BudgetDuration   string   `mapstructure:"budget_duration"` // daily, weekly, monthly or yearly
+	BudgetAlertAt    float64  `mapstructure:"budget_alert_at"` // Percentage of max_budget
+	TPM              int      `mapstructure:"tpm"`
+	RPM              int      `mapstructure:"rpm"`
+	MaxParallelCalls int      `mapstructure:"max_parallel_calls"`
+	AllowedModels    []string `mapstructure:"allowed_models"`
+	BlockedModels    []string `mapstructure:"blocked_models"`
+}
+
+// KeyTemplate is the policy of the key created for each onboarded user
+type KeyTemplate struct {
+	Disabled          bool          `mapstructure:"disabled"` // Onboard users without a key
+	Name              string        `mapstructure:"name"`
+	Scopes            []string      `mapstructure:"scopes"`
+	MaxBudget         float64       `mapstructure:"max_budget"`
+	MaxCostPerRequest float64       `mapstructure:"max_cost_per_request"`
+	TPM               int           `mapstructure:"tpm"`
+	RPM               int           `mapstructure:"rpm"`
+	Duration          time.Duration `mapstructure:"duration"` // Key lifetime, 0 never expires
+}
+
+// DefaultOnboardingTemplate is used when no templates are configured: users
+// join a shared "default" team with a $100 monthly budget and get a
+// full-access key
+func DefaultOnboardingTemplate() OnboardingTemplate {
+	return OnboardingTemplate{
+		TeamName: DefaultOnboardingTemplateName,
+		Team: TeamTemplate{
+			MaxBudget:        100,
+			BudgetDuration:   "monthly",
+			TPM:              1000,
+			RPM:              100,
+			MaxParallelCalls: 5,
+		},
+		MemberBudgets: map[string]float64{
+			"member": 10,
+			"admin":  50,
+		},
+		Key: KeyTemplate{
+			Name:   "Default API Key",
+			Scopes: []string{"*"},
+		},
+	}
+}
+
+// Template returns the named template, or the default template when name is
+// empty or unknown, along with the name actually used. Names are
+// case-insensitive, as the config loader lowercases them.
+func (c OnboardingConfig) Template(name string) (string, OnboardingTemplate) {
+	name = strings.ToLower(name)
+	if name != "" {
+		if tmpl, ok := c.Templates[name]; ok {
+			return name, templateWithTeamName(name, tmpl)
+		}
+	}
+
+	name = strings.ToLower(c.DefaultTemplate)
+	if name == "" {
+		name = DefaultOnboardingTemplateName
+	}
+	if tmpl, ok := c.Templates[name]; ok {
+		return name, templateWithTeamName(name, tmpl)
+	}
+	return name, templateWithTeamName(name, DefaultOnboardingTemplate())
+}
+
+// TemplateForGroups picks the template for a user with the given Dex
+// groups: the first template, by name, listing one of them, or the default
+// template. Groups are compared case-insensitively.
+func (c OnboardingConfig) TemplateForGroups(groups []string) (string, OnboardingTemplate) {
+	names := make([]string, 0, len(c.Templates))
+	for name := range c.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, want := range c.Templates[name].Groups {
+			for _, group := range groups {
+				if strings.EqualFold(want, group) {
+					return c.Template(name)
+				}
+			}
+		}
+	}
+	return c.Template("")
+}
+
+func templateWithTeamName(name string, tmpl OnboardingTemplate) OnboardingTemplate {
+	if tmpl.TeamName == "" {
+		tmpl.TeamName = name
+	}
+	return tmpl
+}
+
+// Matches reports whether a user with the given email and Dex groups falls
+// under the rule. Domains and groups are compared case-insensitively.
+func (r AutoJoinRule) Matches(email string, groups []string) bool {
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain := email[at+1:]
+		for _, want := range r.Domains {
+			if strings.EqualFold(strings.TrimPrefix(want, "@"), domain) {
+				return true
+			}
+		}
+	}
+	for _, want := range r.Groups {
+		for _, group := range groups {
+			if strings.EqualFold(want, group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AutoJoinRules returns the rules a user matches, in configuration order
+func (c OnboardingConfig) AutoJoinRules(email string, groups []string) []AutoJoinRule {
+	var matched []AutoJoinRule
+	for _, rule := range c.AutoJoin {
+		if rule.Matches(email, groups) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// userRoleRank orders the global roles from least to most privileged
+var userRoleRank = map[string]int{"viewer": 1, "user": 2, "manager": 3, "admin": 4}
+
+// RoleFor returns the global role of a provisioned user: the most
+// privileged role among the rules they match, or DefaultRole.
+func (c OnboardingConfig) RoleFor(email string, groups []string) string {
+	role := strings.ToLower(c.DefaultRole)
+	if userRoleRank[role] == 0 {
+		role = "user"
+	}
+	best := 0
+	for _, rule := range c.AutoJoinRules(email, groups) {
+		if rank := userRoleRank[strings.ToLower(rule.Role)]; rank > best {
+			best, role = rank, strings.ToLower(rule.Role)
+		}
+	}
+	return role
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnboardingConfig_Template(t *testing.T) {
+	cfg := OnboardingConfig{
+		DefaultTemplate: "default",
+		Templates: map[string]OnboardingTemplate{
+			"research": {Groups: []string{"ML-Research"}, Team: TeamTemplate{MaxBudget: 500}},
+			"support":  {TeamName: "support-desk", Groups: []string{"support"}},
+		},
+	}
+
+	name, tmpl := cfg.Template("Research")
+	assert.Equal(t, "research", name)
+	assert.Equal(t, "research", tmpl.TeamName)
+	assert.Equal(t, 500.0, tmpl.Team.MaxBudget)
+
+	// Unknown and empty names fall back to the built-in default template
+	name, tmpl = cfg.Template("missing")
+	assert.Equal(t, "default", name)
+	assert.Equal(t, DefaultOnboardingTemplate(), tmpl)
+
+	name, _ = OnboardingConfig{}.Template("")
+	assert.Equal(t, DefaultOnboardingTemplateName, name)
+}
+
+func TestOnboardingConfig_TemplateForGroups(t *testing.T) {
+	cfg := OnboardingConfig{
+		Templates: map[string]OnboardingTemplate{
+			"research": {Groups: []string{"ml-research"}},
+			"support":  {TeamName: "support-desk", Groups: []string{"support", "ml-research"}},
+		},
+	}
+
+	name, _ := cfg.TemplateForGroups([]string{"everyone", "ML-Research"})
+	assert.Equal(t, "research", name)
+
+	name, tmpl := cfg.TemplateForGroups([]string{"support"})
+	assert.Equal(t, "support", name)
+	assert.Equal(t, "support-desk", tmpl.TeamName)
+
+	name, tmpl = cfg.TemplateForGroups(nil)
+	assert.Equal(t, DefaultOnboardingTemplateName, name)
+	assert.Equal(t, "default", tmpl.TeamName)
+}
+
+func TestOnboardingConfig_AutoJoin(t *testing.T) {
+	cfg := OnboardingConfig{
+		AutoJoin: []AutoJoinRule{
+			{Domains: []string{"@acme.com"}, Template: "acme"},
+			{Groups: []string{"platform"}, Template: "platform", TeamRole: "admin", Role: "manager"},
+			{Groups: []string{"security"}, Template: "platform", Role: "admin"},
+			{Domains: []string{"contractor.io"}, Role: "viewer"},
+		},
+	}
+
+	assert.Len(t, cfg.AutoJoinRules("ada@ACME.com", nil), 1)
+	assert.Empty(t, cfg.AutoJoinRules("ada@sub.acme.com", nil), "subdomains are separate domains")
+	assert.Empty(t, cfg.AutoJoinRules("acme.com", nil))
+
+	rules := cfg.AutoJoinRules("ada@acme.com", []string{"Platform"})
+	assert.Equal(t, []string{"acme", "platform"}, []string{rules[0].Template, rules[1].Template})
+
+	assert.Equal(t, "user", cfg.RoleFor("ada@acme.com", nil))
+	assert.Equal(t, "manager", cfg.RoleFor("ada@acme.com", []string{"platform"}))
+	assert.Equal(t, "admin", cfg.RoleFor("ada@acme.com", []string{"platform", "security"}),
+		"the most privileged role wins")
+	assert.Equal(t, "viewer", cfg.RoleFor("bob@contractor.io", nil))
+
+	cfg.DefaultRole = "Admin"
+	assert.Equal(t, "admin", cfg.RoleFor("eve@example.com", []string{"admin"}))
+	cfg.DefaultRole = "superuser"
+	assert.Equal(t, "user", cfg.RoleFor("eve@example.com", nil), "unknown roles fall back to user")
+}
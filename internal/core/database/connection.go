@@ -22,6 +22,9 @@ type Config struct {
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	LogLevel        logger.LogLevel
+	// SkipMigrations leaves migrating to operators ("pllm migrate up"):
+	// Initialize fails when migrations are pending instead of applying them
+	SkipMigrations bool
 }
 
 func Initialize(cfg *Config) error {
@@ -88,7 +91,14 @@ func Initialize(cfg *Config) error {
 	DB = db
 
 	// Run migrations
-	if err := Migrate(); err != nil {
+	if cfg.SkipMigrations {
+		if err := CheckSchema(DB); err != nil {
+			return err
+		}
+		if err := ensureMasterKeyUser(); err != nil {
+			return fmt.Errorf("failed to ensure master key user: %w", err)
+		}
+	} else if err := Migrate(); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
@@ -121,42 +131,19 @@ func RunInitialSeed() error {
 	return seeder.SeedAll()
 }
 
+// Migrate applies the pending migrations (see Migrations) and makes sure the
+// sentinel master-key user exists
 func Migrate() error {
 	if DB == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	// Create extensions (PostgreSQL specific, will be ignored for SQLite)
-	DB.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\"")
-
-	// Auto migrate all models including new ones
-	if err := DB.AutoMigrate(
-		&models.User{},
-		&models.Team{},
-		&models.TeamMember{},
-		&models.Key{}, // Unified key model
-		&models.Provider{},
-		&models.Model{},
-		&models.Budget{},
-		&models.Usage{},
-		&models.Audit{}, // New audit model
-		// Metrics models
-		&models.ModelMetrics{},
-		&models.SystemMetrics{},
-		&models.UserMetrics{},
-		&models.TeamMetrics{},
-		// User-created model configurations
-		&models.UserModel{},
-		// Route configurations
-		&models.Route{},
-		&models.RouteModel{},
-	); err != nil {
-		return fmt.Errorf("failed to migrate models: %w", err)
+	applied, err := MigrateUp(DB, 0)
+	if err != nil {
+		return err
 	}
-
-	// Create indexes
-	if err := createIndexes(); err != nil {
-		return fmt.Errorf("failed to create indexes: %w", err)
+	for _, m := range applied {
+		log.Printf("Applied migration %d (%s)", m.Version, m.Description)
 	}
 
 	// Ensure the sentinel master-key user exists so that usage records
@@ -168,88 +155,88 @@ func Migrate() error {
 	return nil
 }
 
-func createIndexes() error {
+func createIndexes(db *gorm.DB) error {
 	// User indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_users_username ON users(username)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_users_role ON users(role)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_username ON users(username)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_role ON users(role)")
 
 	// New unified Key indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_keys_key ON keys(key)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_keys_key_hash ON keys(key_hash)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_keys_key_prefix ON keys(key_prefix)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_keys_user_id ON keys(user_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_keys_team_id ON keys(team_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_keys_type ON keys(type)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_keys_is_active ON keys(is_active)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_keys_key ON keys(key)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_keys_key_hash ON keys(key_hash)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_keys_key_prefix ON keys(key_prefix)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_keys_user_id ON keys(user_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_keys_team_id ON keys(team_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_keys_type ON keys(type)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_keys_is_active ON keys(is_active)")
 
 	// Virtual Key indexes (legacy, for backward compatibility)
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_virtual_keys_key ON virtual_keys(key)")
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_virtual_keys_user_id ON virtual_keys(user_id)")
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_virtual_keys_team_id ON virtual_keys(team_id)")
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_virtual_keys_is_active ON virtual_keys(is_active)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_virtual_keys_key ON virtual_keys(key)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_virtual_keys_user_id ON virtual_keys(user_id)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_virtual_keys_team_id ON virtual_keys(team_id)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_virtual_keys_is_active ON virtual_keys(is_active)")
 
 	// Team indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_teams_name ON teams(name)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_teams_is_active ON teams(is_active)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_teams_name ON teams(name)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_teams_is_active ON teams(is_active)")
 
 	// Team Member indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_team_members_team_id ON team_members(team_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_team_members_user_id ON team_members(user_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_team_members_team_user ON team_members(team_id, user_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_team_members_team_id ON team_members(team_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_team_members_user_id ON team_members(user_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_team_members_team_user ON team_members(team_id, user_id)")
 
 	// API Key indexes (legacy, kept for backward compatibility)
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash)")
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_api_keys_key_prefix ON api_keys(key_prefix)")
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id)")
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_api_keys_group_id ON api_keys(group_id)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_api_keys_key_prefix ON api_keys(key_prefix)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_api_keys_group_id ON api_keys(group_id)")
 
 	// Usage indexes for analytics
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_usage_timestamp ON usage_logs(timestamp)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_usage_user_id_timestamp ON usage_logs(user_id, timestamp)")
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_usage_group_id_timestamp ON usage_logs(group_id, timestamp)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_usage_provider_model ON usage_logs(provider, model)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_usage_request_id ON usage_logs(request_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_usage_timestamp ON usage_logs(timestamp)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_usage_user_id_timestamp ON usage_logs(user_id, timestamp)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_usage_group_id_timestamp ON usage_logs(group_id, timestamp)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_usage_provider_model ON usage_logs(provider, model)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_usage_request_id ON usage_logs(request_id)")
 
 	// Budget indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets(user_id)")
-	// DB.Exec("CREATE INDEX IF NOT EXISTS idx_budgets_group_id ON budgets(group_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_budgets_type_period ON budgets(type, period)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets(user_id)")
+	// db.Exec("CREATE INDEX IF NOT EXISTS idx_budgets_group_id ON budgets(group_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_budgets_type_period ON budgets(type, period)")
 
 	// Provider indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_providers_type ON providers(type)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_providers_is_active ON providers(is_active)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_providers_type ON providers(type)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_providers_is_active ON providers(is_active)")
 
 	// Model indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_models_provider_id ON models(provider_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_models_is_active ON models(is_active)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_models_provider_id ON models(provider_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_models_is_active ON models(is_active)")
 
 	// User indexes for external identity
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_users_external_provider ON users(external_provider)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_external_provider ON users(external_provider)")
 
 	// Audit indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_timestamp ON audits(timestamp)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_event_type ON audits(event_type)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_event_result ON audits(event_result)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_user_id ON audits(user_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_team_id ON audits(team_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_key_id ON audits(key_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_ip_address ON audits(ip_address)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_request_id ON audits(request_id)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_resource_type ON audits(resource_type)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_audit_resource_id ON audits(resource_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_timestamp ON audits(timestamp)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_event_type ON audits(event_type)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_event_result ON audits(event_result)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_user_id ON audits(user_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_team_id ON audits(team_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_key_id ON audits(key_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_ip_address ON audits(ip_address)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_request_id ON audits(request_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_resource_type ON audits(resource_type)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_resource_id ON audits(resource_id)")
 
 	// Route indexes
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_routes_slug ON routes(slug)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_routes_enabled ON routes(enabled)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_route_models_route_id ON route_models(route_id)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_routes_slug ON routes(slug)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_routes_enabled ON routes(enabled)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_route_models_route_id ON route_models(route_id)")
 
 	// Metrics indexes for efficient querying
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_model_metrics_lookup ON model_metrics(model_name, interval, timestamp)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_system_metrics_lookup ON system_metrics(interval, timestamp)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_user_metrics_lookup ON user_metrics(user_id, interval, timestamp)")
-	DB.Exec("CREATE INDEX IF NOT EXISTS idx_team_metrics_lookup ON team_metrics(team_id, interval, timestamp)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_model_metrics_lookup ON model_metrics(model_name, interval, timestamp)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_system_metrics_lookup ON system_metrics(interval, timestamp)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_user_metrics_lookup ON user_metrics(user_id, interval, timestamp)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_team_metrics_lookup ON team_metrics(team_id, interval, timestamp)")
 
 	return nil
 }
@@ -9,30 +9,16 @@ import (
 	"github.com/amerfu/pllm/internal/core/models"
 )
 
-// AutoMigrate runs database migrations
+// AutoMigrate applies the pending migrations (see Migrations)
 func AutoMigrate(db *gorm.DB) error {
 	log.Println("Running database migrations...")
 
-	// Auto-migrate all models
-	err := db.AutoMigrate(
-		&models.User{},
-		&models.Team{},
-		&models.TeamMember{},
-		&models.Key{},       // Unified key model
-		&models.Budget{},
-		&models.Usage{},
-		&models.Audit{},     // Audit logging
-		&models.UserModel{},       // User-created model configurations
-		&models.ProviderProfile{}, // Reusable provider credential profiles
-		&models.Route{},           // Route configurations
-		&models.RouteModel{},      // Route model entries
-	)
-
+	applied, err := MigrateUp(db, 0)
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	log.Println("Database migrations completed successfully")
+	log.Printf("Database migrations completed successfully (%d applied)", len(applied))
 	return nil
 }
 
@@ -0,0 +1,353 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Migration is a versioned schema change. Migrations are applied in
+// version order, each in its own transaction, and recorded in the
+// schema_migrations table.
+type Migration struct {
+	Version     uint
+	Description string
+	Up          func(tx *gorm.DB) error
+	// Down reverts Up; migrations without it cannot be rolled back
+	Down func(tx *gorm.DB) error
+	// NoTransaction runs Up outside a transaction, for idempotent
+	// migrations with statements allowed to fail
+	NoTransaction bool
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version     uint      `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Description string    `json:"description"`
+	AppliedAt   time.Time `json:"applied_at"`
+}
+
+// TableName keeps the table name independent of the struct name
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus is a known migration and when it was applied, if it was
+type MigrationStatus struct {
+	Version     uint       `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// Applied reports whether the migration was applied
+func (s MigrationStatus) Applied() bool {
+	return s.AppliedAt != nil
+}
+
+// migrationLockID keys the Postgres advisory lock that keeps replicas
+// starting together from migrating at the same time
+const migrationLockID = 7302145
+
+// migrations lists the schema changes, oldest first. The baseline creates
+// the schema from the models as they are when it runs, so later migrations
+// must also work on databases it created after they were added, for
+// example by checking that a column is missing before adding it.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "baseline schema",
+		// The indexes are best effort, and a failed statement would abort
+		// the transaction
+		NoTransaction: true,
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == "postgres" {
+				if err := tx.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.AutoMigrate(baselineModels()...); err != nil {
+				return err
+			}
+			return createIndexes(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			tables := baselineModels()
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(tables[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "bandwidth metering",
+		Up: func(tx *gorm.DB) error {
+			return addMissingColumns(tx, []modelColumn{
+				{&models.Usage{}, "RequestBytes"},
+				{&models.Usage{}, "ResponseBytes"},
+				{&models.Team{}, "MaxMonthlyBandwidth"},
+			})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, []modelColumn{
+				{&models.Usage{}, "RequestBytes"},
+				{&models.Usage{}, "ResponseBytes"},
+				{&models.Team{}, "MaxMonthlyBandwidth"},
+			})
+		},
+	},
+	{
+		Version:     3,
+		Description: "route defaults",
+		Up: func(tx *gorm.DB) error {
+			return addMissingColumns(tx, []modelColumn{
+				{&models.Route{}, "Defaults"},
+			})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, []modelColumn{
+				{&models.Route{}, "Defaults"},
+			})
+		},
+	},
+}
+
+// modelColumn is a column of a model, by field name
+type modelColumn struct {
+	model interface{}
+	field string
+}
+
+// addMissingColumns adds the columns a database lacks. The baseline already
+// creates them on databases created after they were added to the models.
+func addMissingColumns(tx *gorm.DB, columns []modelColumn) error {
+	for _, c := range columns {
+		if tx.Migrator().HasColumn(c.model, c.field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(c.model, c.field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropColumns(tx *gorm.DB, columns []modelColumn) error {
+	for _, c := range columns {
+		if !tx.Migrator().HasColumn(c.model, c.field) {
+			continue
+		}
+		if err := tx.Migrator().DropColumn(c.model, c.field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// baselineModels are the models of the baseline schema, referenced tables
+// first
+func baselineModels() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&models.Team{},
+		&models.TeamMember{},
+		&models.Key{}, // Unified key model
+		&models.Provider{},
+		&models.Model{},
+		&models.Budget{},
+		&models.BudgetAlert{},
+		&models.Usage{},
+		&models.Audit{},
+		// Metrics models
+		&models.ModelMetrics{},
+		&models.SystemMetrics{},
+		&models.UserMetrics{},
+		&models.TeamMetrics{},
+		&models.UserModel{},              // User-created model configurations
+		&models.ProviderProfile{},        // Reusable provider credential profiles
+		&models.Route{},                  // Route configurations
+		&models.RouteModel{},             // Route model entries
+		&models.Job{},                    // Async inference jobs
+		&models.GatewayTool{},            // Gateway-executed tools
+		&models.ContextCache{},           // Context caches (/v1/caches)
+		&models.Incident{},               // Upstream provider incidents
+		&models.CreditAccount{},          // Prepaid team credits
+		&models.CreditTransaction{},      // Credit ledger
+		&models.SystemSetting{},          // Runtime settings
+		&models.TeamProviderKey{},        // Provider keys teams bring
+		&models.Artifact{},               // Stored artifacts (uploaded files)
+		&models.LegalHold{},              // Legal holds on artifacts and logs
+		&models.NotificationPreference{}, // User notification preferences
+		&models.SpendAlert{},             // Spend alerts sent to users
+		&models.BudgetTracking{},         // Budget movements between teams and pools
+		&models.BudgetPool{},             // Budget shared by teams
+		&models.ResponseFeedback{},       // Ratings of responses by callers
+	}
+}
+
+// Migrations returns the known migrations, oldest first
+func Migrations() []Migration {
+	out := make([]Migration, len(migrations))
+	copy(out, migrations)
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// GetMigrationStatus returns every known migration and whether it was applied
+func GetMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []MigrationStatus
+	for _, m := range Migrations() {
+		s := MigrationStatus{Version: m.Version, Description: m.Description}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			s.AppliedAt = &appliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// PendingMigrations returns the migrations not applied yet, oldest first
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range Migrations() {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// CheckSchema returns an error when migrations are pending, for processes
+// that leave migrating to operators
+func CheckSchema(db *gorm.DB) error {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is out of date: %d pending migrations (run \"pllm migrate up\")", len(pending))
+	}
+	return nil
+}
+
+// MigrateUp applies the pending migrations up to and including version, or
+// all of them when version is 0, and returns the ones it applied
+func MigrateUp(db *gorm.DB, version uint) ([]Migration, error) {
+	var done []Migration
+	err := withMigrationLock(db, func(conn *gorm.DB) error {
+		pending, err := PendingMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			if version != 0 && m.Version > version {
+				break
+			}
+			apply := func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:     m.Version,
+					Description: m.Description,
+					AppliedAt:   time.Now(),
+				}).Error
+			}
+			if m.NoTransaction {
+				err = apply(conn)
+			} else {
+				err = conn.Transaction(apply)
+			}
+			if err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and
+// returns the ones it reverted
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	var done []Migration
+	err := withMigrationLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		known := Migrations()
+		for i := len(known) - 1; i >= 0 && len(done) < steps; i-- {
+			m := known[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return fmt.Errorf("migration %d (%s) cannot be reverted", m.Version, m.Description)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d (%s) failed: %w", m.Version, m.Description, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// appliedMigrations returns the applied migrations by version, creating
+// the table that records them on first use
+func appliedMigrations(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[uint]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// withMigrationLock runs fn on one connection holding the migration lock.
+// Only Postgres has the advisory lock; other databases run fn unlocked.
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	if db.Dialector.Name() != "postgres" {
+		return fn(db)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return fmt.Errorf("failed to acquire the migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID)
+		return fn(conn)
+	})
+}
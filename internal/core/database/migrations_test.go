@@ -0,0 +1,56 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/database"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+)
+
+func TestMigrations_Versions(t *testing.T) {
+	var last uint
+	for _, m := range database.Migrations() {
+		assert.Greater(t, m.Version, last, "versions are unique and increasing")
+		assert.NotEmpty(t, m.Description)
+		assert.NotNil(t, m.Up)
+		last = m.Version
+	}
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+
+	// The test database is migrated up
+	status, err := database.GetMigrationStatus(db)
+	require.NoError(t, err)
+	require.Len(t, status, len(database.Migrations()))
+	for _, s := range status {
+		assert.True(t, s.Applied(), "migration %d", s.Version)
+	}
+	require.NoError(t, database.CheckSchema(db))
+
+	applied, err := database.MigrateUp(db, 0)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "applied migrations are not applied again")
+
+	reverted, err := database.MigrateDown(db, len(status))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(status))
+	assert.False(t, db.Migrator().HasTable(&models.User{}), "the baseline drops its tables")
+	assert.Error(t, database.CheckSchema(db))
+
+	pending, err := database.PendingMigrations(db)
+	require.NoError(t, err)
+	assert.Len(t, pending, len(status))
+
+	applied, err = database.MigrateUp(db, 0)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(status))
+	assert.True(t, db.Migrator().HasTable(&models.User{}))
+	assert.NoError(t, database.CheckSchema(db))
+}
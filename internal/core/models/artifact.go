@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ArtifactKind is the type of a stored artifact
+type ArtifactKind string
+
+const (
+	ArtifactKindFile ArtifactKind = "file" // Uploaded with POST /v1/files
+)
+
+// Artifact is a blob kept in artifact storage, with the owner and size the
+// lifecycle rules are applied by
+type Artifact struct {
+	BaseModel
+	Kind        ArtifactKind `gorm:"type:varchar(20);not null;index" json:"kind"`
+	StorageKey  string       `gorm:"uniqueIndex;not null" json:"storage_key"` // Key in the storage backend
+	Filename    string       `json:"filename"`
+	ContentType string       `json:"content_type"`
+	Size        int64        `gorm:"not null;default:0" json:"size"`
+
+	// Owner
+	UserID *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	KeyID  *uuid.UUID `gorm:"type:uuid;index" json:"key_id,omitempty"`
+	TeamID *uuid.UUID `gorm:"type:uuid;index" json:"team_id,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (Artifact) TableName() string {
+	return "artifacts"
+}
+
+// LegalHold exempts the artifacts, job outputs and request logs of a user or
+// a team from every lifecycle rule and from erasure until it is released
+type LegalHold struct {
+	BaseModel
+	UserID     *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	TeamID     *uuid.UUID `gorm:"type:uuid;index" json:"team_id,omitempty"`
+	Reason     string     `gorm:"type:text;not null" json:"reason"`
+	PlacedBy   *uuid.UUID `gorm:"type:uuid" json:"placed_by,omitempty"`
+	ReleasedAt *time.Time `gorm:"index" json:"released_at,omitempty"`
+	ReleasedBy *uuid.UUID `gorm:"type:uuid" json:"released_by,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (LegalHold) TableName() string {
+	return "legal_holds"
+}
+
+// IsActive reports whether the hold is still in place
+func (h *LegalHold) IsActive() bool {
+	return h.ReleasedAt == nil
+}
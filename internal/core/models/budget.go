@@ -151,6 +151,16 @@ type BudgetTracking struct {
 	Tokens   int        `json:"tokens"`
 	Cost     float64    `gorm:"index" json:"cost"`
 
+	// Budget movements between teams and pools. Amount is what the team's,
+	// or without a team the pool's, budget changed by: negative when budget
+	// left it.
+	Movement    BudgetMovement `gorm:"size:20;index" json:"movement,omitempty"`
+	Amount      float64        `json:"amount,omitempty"`
+	PoolID      *uuid.UUID     `gorm:"type:uuid;index" json:"pool_id,omitempty"`
+	OtherTeamID *uuid.UUID     `gorm:"type:uuid" json:"other_team_id,omitempty"` // The other side of a transfer
+	Reason      string         `json:"reason,omitempty"`
+	CreatedBy   *uuid.UUID     `gorm:"type:uuid" json:"created_by,omitempty"`
+
 	// Request metadata
 	RequestID string                 `gorm:"index" json:"request_id,omitempty"`
 	Metadata  map[string]interface{} `gorm:"type:jsonb" json:"metadata,omitempty"`
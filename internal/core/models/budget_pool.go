@@ -0,0 +1,51 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// BudgetMovement is the kind of a budget movement recorded as BudgetTracking
+type BudgetMovement string
+
+const (
+	BudgetMovementTransferOut BudgetMovement = "transfer_out" // Unused budget given to another team
+	BudgetMovementTransferIn  BudgetMovement = "transfer_in"  // Budget received from another team
+	BudgetMovementPoolFund    BudgetMovement = "pool_fund"    // Budget added to or removed from a pool
+	BudgetMovementPoolDraw    BudgetMovement = "pool_draw"    // Budget a team drew from a pool
+	BudgetMovementPoolReturn  BudgetMovement = "pool_return"  // Unused budget a team gave back to a pool
+)
+
+// BudgetPool is budget shared by several teams. Teams draw from it to raise
+// their own budget and give unused budget back to it.
+type BudgetPool struct {
+	BaseModel
+	Name        string  `gorm:"uniqueIndex;not null" json:"name"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `gorm:"not null" json:"amount"`      // Budget funded into the pool
+	Allocated   float64 `gorm:"default:0" json:"allocated"` // Budget the teams hold from it
+	IsActive    bool    `gorm:"default:true" json:"is_active"`
+
+	// TeamIDs are the teams allowed to draw from the pool
+	TeamIDs   StringArray `gorm:"type:text[]" json:"team_ids"`
+	CreatedBy *uuid.UUID  `gorm:"type:uuid" json:"created_by,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (BudgetPool) TableName() string {
+	return "budget_pools"
+}
+
+// Remaining returns the budget teams can still draw
+func (p *BudgetPool) Remaining() float64 {
+	return p.Amount - p.Allocated
+}
+
+// HasTeam reports whether a team may draw from the pool
+func (p *BudgetPool) HasTeam(teamID uuid.UUID) bool {
+	for _, id := range p.TeamIDs {
+		if id == teamID.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// ContextCache is a reusable prompt prefix created through /v1/caches and
+// referenced from chat requests with cached_content. Providers with a cache
+// resource API (Gemini) hold the context themselves; for the others the
+// gateway keeps the messages and prepends them to each request, relying on
+// provider prompt caching.
+type ContextCache struct {
+	BaseModel
+
+	DisplayName string `json:"display_name,omitempty"`
+	Model       string `gorm:"not null" json:"model"` // Model the cache was created for
+
+	// Provider-side cache, empty for gateway-managed caches
+	InstanceID        string `json:"-"`
+	ProviderType      string `json:"provider,omitempty"`
+	ProviderCacheName string `json:"-"`
+
+	Messages   datatypes.JSON `json:"-"`
+	TokenCount int            `json:"token_count"`
+
+	// StorageCost is the total charged for creating and storing the cache
+	StorageCost float64 `gorm:"type:decimal(20,10)" json:"storage_cost"`
+
+	// Owner, used to scope access to the creating identity
+	UserID *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	KeyID  *uuid.UUID `gorm:"type:uuid;index" json:"key_id,omitempty"`
+	TeamID *uuid.UUID `gorm:"type:uuid;index" json:"team_id,omitempty"`
+
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
+
+// IsProviderManaged reports whether the provider stores the cached context
+func (c *ContextCache) IsProviderManaged() bool {
+	return c.ProviderCacheName != ""
+}
+
+// IsExpired reports whether the cache is past its TTL
+func (c *ContextCache) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// CreditTransactionType is the kind of a credit ledger entry
+type CreditTransactionType string
+
+const (
+	CreditTransactionPurchase   CreditTransactionType = "purchase"
+	CreditTransactionUsage      CreditTransactionType = "usage"
+	CreditTransactionRefund     CreditTransactionType = "refund"
+	CreditTransactionAdjustment CreditTransactionType = "adjustment"
+)
+
+// CreditAccount holds the prepaid credit balance of a team. Usage is
+// debited from it and Stripe payments credit it.
+type CreditAccount struct {
+	BaseModel
+	TeamID           uuid.UUID `gorm:"type:uuid;uniqueIndex;not null" json:"team_id"`
+	Balance          float64   `gorm:"default:0" json:"balance"`
+	Currency         string    `gorm:"default:'usd'" json:"currency"`
+	StripeCustomerID string    `json:"stripe_customer_id,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (CreditAccount) TableName() string {
+	return "credit_accounts"
+}
+
+// CreditTransaction is an entry of a team's credit ledger. Amount is
+// positive for credits and negative for debits.
+type CreditTransaction struct {
+	BaseModel
+	TeamID      uuid.UUID             `gorm:"type:uuid;index;not null" json:"team_id"`
+	Type        CreditTransactionType `gorm:"index;not null" json:"type"`
+	Amount      float64               `gorm:"not null" json:"amount"`
+	Balance     float64               `json:"balance"` // Balance after the entry
+	Description string                `json:"description,omitempty"`
+
+	// Reference is the Stripe object behind a payment or refund; it keeps
+	// webhook deliveries from being applied twice
+	Reference     *string    `gorm:"uniqueIndex" json:"reference,omitempty"`
+	PaymentIntent string     `gorm:"index" json:"payment_intent,omitempty"` // Stripe payment intent of purchases and refunds
+	CreatedBy     *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (CreditTransaction) TableName() string {
+	return "credit_transactions"
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// Feedback ratings range from MinFeedbackRating to MaxFeedbackRating;
+// ratings of SatisfiedFeedbackRating and above count as satisfied
+const (
+	MinFeedbackRating       = 1
+	MaxFeedbackRating       = 5
+	SatisfiedFeedbackRating = 4
+)
+
+// ResponseFeedback is a caller's rating of a response. It is linked to the
+// usage record of the request by RequestID; the usage record is written
+// asynchronously and may not exist yet when the feedback arrives.
+type ResponseFeedback struct {
+	BaseModel
+	RequestID string      `gorm:"uniqueIndex;not null" json:"request_id"`
+	Rating    int         `gorm:"not null" json:"rating"`
+	Tags      StringArray `gorm:"type:text[]" json:"tags"`
+
+	// Caller who rated the response
+	UserID *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	KeyID  *uuid.UUID `gorm:"type:uuid;index" json:"key_id,omitempty"`
+	TeamID *uuid.UUID `gorm:"type:uuid;index" json:"team_id,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (ResponseFeedback) TableName() string {
+	return "response_feedback"
+}
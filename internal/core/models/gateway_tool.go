@@ -0,0 +1,23 @@
+package models
+
+import (
+	"gorm.io/datatypes"
+)
+
+// GatewayTool is an HTTP-backed tool registered by an admin. When a model
+// calls a registered tool, the gateway executes it and feeds the result back
+// to the model instead of returning the tool call to the client.
+type GatewayTool struct {
+	BaseModel
+	Name        string         `gorm:"uniqueIndex;not null" json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  datatypes.JSON `json:"parameters,omitempty"` // JSON schema of the arguments
+	Endpoint    string         `gorm:"not null" json:"endpoint"`
+	TimeoutMs   int            `gorm:"default:0" json:"timeout_ms,omitempty"` // 0 uses the runtime default
+	Enabled     bool           `gorm:"default:true" json:"enabled"`
+}
+
+// TableName overrides the default table name.
+func (GatewayTool) TableName() string {
+	return "gateway_tools"
+}
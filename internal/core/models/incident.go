@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// IncidentStatus is the lifecycle state of an upstream incident
+type IncidentStatus string
+
+const (
+	IncidentStatusActive   IncidentStatus = "active"
+	IncidentStatusResolved IncidentStatus = "resolved"
+)
+
+// Incident records an outage declared on a provider's status page, with
+// how our own instances of that provider fared and the mitigation applied
+// while it lasted
+type Incident struct {
+	BaseModel
+	Provider   string         `gorm:"index;not null" json:"provider"`     // Provider type: openai, anthropic, azure, ...
+	ExternalID string         `gorm:"index" json:"external_id,omitempty"` // Incident ID on the status page
+	Title      string         `json:"title"`
+	Impact     string         `json:"impact,omitempty"` // As reported: none, minor, major, critical
+	URL        string         `json:"url,omitempty"`
+	Status     IncidentStatus `gorm:"index;not null;default:'active'" json:"status"`
+	Action     string         `json:"action"` // Mitigation: raise_threshold, shift_routes or none
+
+	// Correlated is set once our own instances of the provider failed at
+	// the spike rate while the incident was active
+	Correlated  bool    `gorm:"default:false" json:"correlated"`
+	FailureRate float64 `gorm:"default:0" json:"failure_rate"` // Peak share of the provider's instances failing
+
+	StartedAt  time.Time  `gorm:"not null" json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (Incident) TableName() string {
+	return "incidents"
+}
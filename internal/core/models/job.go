@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// Job is an asynchronous inference request submitted with ?async=true.
+// The request is executed in the background and its result is kept until
+// ExpiresAt so clients can poll for it.
+type Job struct {
+	BaseModel
+
+	Status   JobStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Endpoint string    `gorm:"not null" json:"endpoint"` // e.g. "chat.completions"
+	Model    string    `gorm:"not null" json:"model"`
+
+	Request  datatypes.JSON `json:"-"`
+	Response datatypes.JSON `json:"response,omitempty"`
+	Error    string         `gorm:"type:text" json:"error,omitempty"`
+
+	// Completion webhook
+	WebhookURL       string `json:"-"`
+	WebhookDelivered bool   `gorm:"default:false" json:"webhook_delivered"`
+
+	// Owner, used to scope retrieval to the submitting identity
+	UserID *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	KeyID  *uuid.UUID `gorm:"type:uuid;index" json:"key_id,omitempty"`
+	TeamID *uuid.UUID `gorm:"type:uuid" json:"team_id,omitempty"`
+
+	// Outage queue: a job accepted while every provider of its model was
+	// down is tried again at NextAttemptAt until RetryUntil passes
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at,omitempty"`
+	RetryUntil    *time.Time `json:"retry_until,omitempty"`
+
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `gorm:"index" json:"expires_at"`
+}
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+// IsFinished reports whether the job reached a terminal state
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed
+}
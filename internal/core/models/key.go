@@ -42,12 +42,20 @@ type Key struct {
 	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
 	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
 
+	// Environment of the app using the key (see ValidKeyEnvironments). The
+	// team's policy for the environment fills in the limits and models the
+	// key leaves unset.
+	Environment string `gorm:"type:varchar(20);index" json:"environment,omitempty"`
+
 	// Budget Control
 	MaxBudget      *float64      `json:"max_budget,omitempty"`
 	BudgetDuration *BudgetPeriod `json:"budget_duration,omitempty"`
 	CurrentSpend   float64       `json:"current_spend"`
 	BudgetResetAt  *time.Time    `json:"budget_reset_at,omitempty"`
 
+	// Worst-case cost ceiling for a single request
+	MaxCostPerRequest *float64 `json:"max_cost_per_request,omitempty"`
+
 	// Rate Limiting (overrides team/user defaults)
 	TPM              *int `json:"tpm,omitempty"`
 	RPM              *int `json:"rpm,omitempty"`
@@ -65,6 +73,31 @@ type Key struct {
 	// Permissions and scopes
 	Scopes pq.StringArray `gorm:"type:text[]" json:"scopes,omitempty"`
 
+	// Request signing: requests must carry an HMAC of their content keyed
+	// with the signing secret, in addition to the key itself
+	RequireSignature bool   `gorm:"default:false" json:"require_signature"`
+	SigningSecret    string `json:"-"`
+
+	// Output caching: deterministic completions (temperature 0 or a seed)
+	// are answered from the output cache when the gateway enables it
+	CacheOutputs bool `gorm:"default:false" json:"cache_outputs"`
+
+	// Response minimization: fields stripped from completion responses
+	// before they are sent (see ValidateResponseMinimization). Replaces the
+	// team's setting when set; logs keep the full response.
+	ResponseMinimization pq.StringArray `gorm:"type:text[]" json:"response_minimization,omitempty"`
+
+	// Request validation: how chat requests with fields or values outside
+	// the request schema are handled (see ValidRequestValidation). Empty
+	// forwards requests as sent.
+	RequestValidation string `gorm:"type:varchar(20)" json:"request_validation,omitempty"`
+
+	// Rotation: the key this one replaced, and the endpoint notified with
+	// the new key when this one is rotated
+	RotatedFromID         *uuid.UUID `gorm:"type:uuid;index" json:"rotated_from_id,omitempty"`
+	RotationWebhookURL    string     `json:"rotation_webhook_url,omitempty"`
+	RotationWebhookSecret string     `json:"-"`
+
 	// Metadata
 	Metadata datatypes.JSON `json:"metadata,omitempty"`
 	Tags     pq.StringArray `gorm:"type:text[]" json:"tags,omitempty"`
@@ -85,23 +118,160 @@ const (
 	KeyTypeSystem  KeyType = "system"  // System key for backend services
 )
 
+// Key scopes restrict a key to endpoint classes. A key without scopes may use
+// every LLM endpoint; admin:read and monitoring must always be granted
+// explicitly.
+const (
+	ScopeAll         = "*"
+	ScopeChat        = "chat"        // chat/completions, completions, messages, jobs
+	ScopeEmbeddings  = "embeddings"
+	ScopeImages      = "images"
+	ScopeAudio       = "audio"
+	ScopeModerations = "moderations"
+	ScopeRealtime    = "realtime"
+	ScopeAdminRead   = "admin:read" // Read-only access to the admin API
+	ScopeMonitoring  = "monitoring" // Synthetic monitoring requests, which are not billed
+)
+
+// ValidScopes lists the scopes accepted by the key management APIs
+var ValidScopes = []string{
+	ScopeAll, ScopeChat, ScopeEmbeddings, ScopeImages, ScopeAudio,
+	ScopeModerations, ScopeRealtime, ScopeAdminRead, ScopeMonitoring,
+}
+
+// ExplicitScope reports whether a scope is only granted by name: it is never
+// implied by an empty scope list or the wildcard and has no finer-grained forms
+func ExplicitScope(scope string) bool {
+	return scope == ScopeAdminRead || scope == ScopeMonitoring
+}
+
+// ValidateScopes returns an error for the first unknown scope. Finer-grained
+// scopes such as "chat:completions" are accepted when their class is known;
+// explicit scopes and the wildcard have no finer-grained forms.
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		known := false
+		for _, valid := range ValidScopes {
+			if scope == valid || (valid != ScopeAll && !ExplicitScope(valid) && strings.HasPrefix(scope, valid+":")) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("invalid scope %q (valid scopes: %s)", scope, strings.Join(ValidScopes, ", "))
+		}
+	}
+	return nil
+}
+
+// Response minimization options, set on keys and teams. They strip fields
+// from chat and text completion responses, streamed or not, to cut egress
+// for clients that do not use them.
+const (
+	MinimizeLogprobs          = "logprobs"           // choices' logprobs
+	MinimizeSystemFingerprint = "system_fingerprint" // the backend configuration fingerprint
+	MinimizeToolMetadata      = "tool_metadata"      // tool calls keep only id, index, name and arguments
+	MinimizeContentOnly       = "content_only"       // messages keep only role and content
+)
+
+// ValidResponseMinimization lists the accepted response minimization options
+var ValidResponseMinimization = []string{
+	MinimizeLogprobs, MinimizeSystemFingerprint, MinimizeToolMetadata, MinimizeContentOnly,
+}
+
+// ValidateResponseMinimization returns an error for the first unknown
+// response minimization option
+func ValidateResponseMinimization(options []string) error {
+	for _, option := range options {
+		known := false
+		for _, valid := range ValidResponseMinimization {
+			if option == valid {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("invalid response minimization option %q (valid options: %s)",
+				option, strings.Join(ValidResponseMinimization, ", "))
+		}
+	}
+	return nil
+}
+
+// Request validation modes, set on keys. Strict rejects chat requests with
+// unknown fields or invalid enum values, naming each; lenient strips them
+// and forwards the rest.
+const (
+	RequestValidationStrict  = "strict"
+	RequestValidationLenient = "lenient"
+)
+
+// ValidRequestValidation lists the accepted request validation modes
+var ValidRequestValidation = []string{RequestValidationStrict, RequestValidationLenient}
+
+// ValidateRequestValidation returns an error for an unknown request
+// validation mode. The empty mode turns validation off.
+func ValidateRequestValidation(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, valid := range ValidRequestValidation {
+		if mode == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid request validation mode %q (valid modes: %s)",
+		mode, strings.Join(ValidRequestValidation, ", "))
+}
+
+// Key environments split a team's keys by the app environment they serve
+const (
+	KeyEnvironmentDev     = "dev"
+	KeyEnvironmentStaging = "staging"
+	KeyEnvironmentProd    = "prod"
+)
+
+// ValidKeyEnvironments lists the accepted key environments
+var ValidKeyEnvironments = []string{KeyEnvironmentDev, KeyEnvironmentStaging, KeyEnvironmentProd}
+
+// ValidateKeyEnvironment returns an error for an unknown key environment.
+// Keys without an environment get no environment policy.
+func ValidateKeyEnvironment(environment string) error {
+	if environment == "" {
+		return nil
+	}
+	for _, valid := range ValidKeyEnvironments {
+		if environment == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid environment %q (valid environments: %s)",
+		environment, strings.Join(ValidKeyEnvironments, ", "))
+}
+
 // KeyRequest represents a request to create a new key
 type KeyRequest struct {
-	Name             string        `json:"name"`
-	Type             KeyType       `json:"type"`
-	UserID           *uuid.UUID    `json:"user_id,omitempty"`
-	TeamID           *uuid.UUID    `json:"team_id,omitempty"`
-	Duration         *int          `json:"duration,omitempty"` // in seconds
-	MaxBudget        *float64      `json:"max_budget,omitempty"`
-	BudgetDuration   *BudgetPeriod `json:"budget_duration,omitempty"`
-	TPM              *int          `json:"tpm,omitempty"`
-	RPM              *int          `json:"rpm,omitempty"`
-	MaxParallelCalls *int          `json:"max_parallel_calls,omitempty"`
-	AllowedModels    []string      `json:"allowed_models,omitempty"`
-	BlockedModels    []string      `json:"blocked_models,omitempty"`
-	Scopes           []string      `json:"scopes,omitempty"`
-	Metadata         interface{}   `json:"metadata,omitempty"`
-	Tags             []string      `json:"tags,omitempty"`
+	Name              string        `json:"name"`
+	Type              KeyType       `json:"type"`
+	UserID            *uuid.UUID    `json:"user_id,omitempty"`
+	TeamID            *uuid.UUID    `json:"team_id,omitempty"`
+	Duration          *int          `json:"duration,omitempty"` // in seconds
+	MaxBudget         *float64      `json:"max_budget,omitempty"`
+	BudgetDuration    *BudgetPeriod `json:"budget_duration,omitempty"`
+	MaxCostPerRequest *float64      `json:"max_cost_per_request,omitempty"`
+	TPM               *int          `json:"tpm,omitempty"`
+	RPM               *int          `json:"rpm,omitempty"`
+	MaxParallelCalls  *int          `json:"max_parallel_calls,omitempty"`
+	AllowedModels     []string      `json:"allowed_models,omitempty"`
+	BlockedModels     []string      `json:"blocked_models,omitempty"`
+	Scopes            []string      `json:"scopes,omitempty"`
+	Metadata          interface{}   `json:"metadata,omitempty"`
+	Tags              []string      `json:"tags,omitempty"`
+	CacheOutputs      bool          `json:"cache_outputs,omitempty"`
+
+	ResponseMinimization []string `json:"response_minimization,omitempty"`
+	RequestValidation    string   `json:"request_validation,omitempty"`
+	Environment          string   `json:"environment,omitempty"`
 }
 
 // KeyResponse represents the response when creating a key
@@ -138,6 +308,15 @@ func GenerateKey(keyType KeyType) (string, string, error) {
 	return key, keyHash, nil
 }
 
+// GenerateSigningSecret creates a secret for request signing
+func GenerateSigningSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "pllm_ss_" + hex.EncodeToString(b), nil
+}
+
 // ValidateKeyFormat checks if a key has the correct format
 func ValidateKeyFormat(key string) bool {
 	switch {
@@ -199,10 +378,32 @@ func (k *Key) CanUse() bool {
 
 // IsBudgetExceeded checks if the key has exceeded its budget
 func (k *Key) IsBudgetExceeded() bool {
-	if k.MaxBudget == nil || *k.MaxBudget <= 0 {
+	maxBudget := k.GetMaxBudget()
+	if maxBudget == nil || *maxBudget <= 0 {
 		return false
 	}
-	return k.CurrentSpend >= *k.MaxBudget
+	return k.CurrentSpend >= *maxBudget
+}
+
+// GetMaxBudget returns the key's budget, or the one its environment policy
+// gives each key when the key sets none and its team is loaded
+func (k *Key) GetMaxBudget() *float64 {
+	if k.MaxBudget != nil {
+		return k.MaxBudget
+	}
+	if policy := k.EnvironmentPolicy(); policy != nil {
+		return policy.MaxBudget
+	}
+	return nil
+}
+
+// EnvironmentPolicy returns the policy of the key's team for the key's
+// environment, or nil when there is none or the team is not loaded
+func (k *Key) EnvironmentPolicy() *EnvironmentPolicy {
+	if k.Environment == "" || k.Team == nil {
+		return nil
+	}
+	return k.Team.EnvironmentPolicy(k.Environment)
 }
 
 // ShouldResetBudget checks if the budget should be reset
@@ -238,23 +439,36 @@ func (k *Key) ResetBudget() {
 	}
 }
 
-// IsModelAllowed checks if the key has access to a specific model
+// IsModelAllowed checks if the key has access to a specific model. The
+// models of the key's environment policy restrict it further.
 func (k *Key) IsModelAllowed(model string) bool {
+	if !modelListsAllow(k.AllowedModels, k.BlockedModels, model) {
+		return false
+	}
+	if policy := k.EnvironmentPolicy(); policy != nil {
+		return modelListsAllow(policy.AllowedModels, policy.BlockedModels, model)
+	}
+	return true
+}
+
+// modelListsAllow reports whether a model is allowed by a pair of allowed
+// and blocked lists; an empty allowed list allows every model not blocked
+func modelListsAllow(allowedModels, blockedModels []string, model string) bool {
 	// Check if model is blocked
-	for _, blocked := range k.BlockedModels {
-		if blocked == model || blocked == "*" {
+	for _, blocked := range blockedModels {
+		if modelMatches(blocked, model) {
 			return false
 		}
 	}
 
 	// If no allowed models specified, allow all (except blocked)
-	if len(k.AllowedModels) == 0 {
+	if len(allowedModels) == 0 {
 		return true
 	}
 
 	// Check if model is in allowed list
-	for _, allowed := range k.AllowedModels {
-		if allowed == model || allowed == "*" {
+	for _, allowed := range allowedModels {
+		if modelMatches(allowed, model) {
 			return true
 		}
 	}
@@ -262,14 +476,30 @@ func (k *Key) IsModelAllowed(model string) bool {
 	return false
 }
 
-// HasScope checks if the key has a specific scope
+// HasScope checks if the key has a specific scope, following ScopesAllow
 func (k *Key) HasScope(scope string) bool {
-	if len(k.Scopes) == 0 {
+	return ScopesAllow(k.Scopes, scope)
+}
+
+// ScopesAllow reports whether a scope list grants scope. A class scope
+// ("chat") grants its finer-grained scopes ("chat:completions"), never the
+// reverse. Explicit scopes only match exactly and are never implied by an
+// empty scope list or the wildcard.
+func ScopesAllow(scopes []string, scope string) bool {
+	if ExplicitScope(scope) {
+		for _, s := range scopes {
+			if s == scope {
+				return true
+			}
+		}
+		return false
+	}
+	if len(scopes) == 0 {
 		return true // No scopes means all access
 	}
 
-	for _, s := range k.Scopes {
-		if s == scope || s == "*" {
+	for _, s := range scopes {
+		if s == scope || s == ScopeAll || (!ExplicitScope(s) && strings.HasPrefix(scope, s+":")) {
 			return true
 		}
 	}
@@ -296,12 +526,24 @@ func (k *Key) Revoke(userID uuid.UUID, reason string) {
 	k.IsActive = false
 }
 
-// GetEffectiveRateLimits returns the effective rate limits for this key
+// GetEffectiveRateLimits returns the effective rate limits for this key: its
+// own, then its environment policy's, then the defaults
 func (k *Key) GetEffectiveRateLimits(defaultTPM, defaultRPM, defaultParallel int) (tpm, rpm, parallel int) {
 	tpm = defaultTPM
 	rpm = defaultRPM
 	parallel = defaultParallel
 
+	if policy := k.EnvironmentPolicy(); policy != nil {
+		if policy.TPM != nil {
+			tpm = *policy.TPM
+		}
+		if policy.RPM != nil {
+			rpm = *policy.RPM
+		}
+		if policy.MaxParallelCalls != nil {
+			parallel = *policy.MaxParallelCalls
+		}
+	}
 	if k.TPM != nil {
 		tpm = *k.TPM
 	}
@@ -315,6 +557,18 @@ func (k *Key) GetEffectiveRateLimits(defaultTPM, defaultRPM, defaultParallel int
 	return
 }
 
+// GetResponseMinimization returns the key's response minimization options,
+// or its team's when the key sets none and the team is loaded
+func (k *Key) GetResponseMinimization() []string {
+	if len(k.ResponseMinimization) > 0 {
+		return k.ResponseMinimization
+	}
+	if k.Team != nil {
+		return k.Team.ResponseMinimization
+	}
+	return nil
+}
+
 // GetType returns the key type, inferring from prefix if not set
 func (k *Key) GetType() KeyType {
 	if k.Type != "" {
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func TestValidateKeyEnvironment(t *testing.T) {
+	assert.NoError(t, ValidateKeyEnvironment(""))
+	assert.NoError(t, ValidateKeyEnvironment(KeyEnvironmentStaging))
+	assert.EqualError(t, ValidateKeyEnvironment("qa"), `invalid environment "qa" (valid environments: dev, staging, prod)`)
+
+	negative := -1
+	assert.NoError(t, ValidateEnvironmentPolicies(map[string]EnvironmentPolicy{KeyEnvironmentDev: {}}))
+	assert.Error(t, ValidateEnvironmentPolicies(map[string]EnvironmentPolicy{"qa": {}}))
+	assert.Error(t, ValidateEnvironmentPolicies(map[string]EnvironmentPolicy{"": {}}))
+	assert.Error(t, ValidateEnvironmentPolicies(map[string]EnvironmentPolicy{KeyEnvironmentProd: {RPM: &negative}}))
+}
+
+func TestKey_EnvironmentPolicy(t *testing.T) {
+	team := &Team{Environments: datatypes.JSON(`{
+		"dev": {"max_budget": 50, "rpm": 10, "max_parallel_calls": 2, "allowed_models": ["gpt-4o-mini", "claude-3-haiku", "claude-3-opus"], "blocked_models": ["claude-3-opus"]},
+		"prod": {"tpm": 500000}
+	}`)}
+
+	dev := &Key{Environment: KeyEnvironmentDev, Team: team}
+	require.NotNil(t, dev.EnvironmentPolicy())
+	assert.True(t, dev.IsModelAllowed("gpt-4o-mini"))
+	assert.True(t, dev.IsModelAllowed("claude-3-haiku"))
+	assert.False(t, dev.IsModelAllowed("claude-3-opus"), "blocked in the environment")
+	assert.False(t, dev.IsModelAllowed("gpt-4o"), "not allowed in the environment")
+
+	dev.BlockedModels = []string{"gpt-4o-mini"}
+	assert.False(t, dev.IsModelAllowed("gpt-4o-mini"), "the key's own lists still apply")
+
+	tpm, rpm, parallel := dev.GetEffectiveRateLimits(1000, 100, 0)
+	assert.Equal(t, []int{1000, 10, 2}, []int{tpm, rpm, parallel})
+	keyRPM := 30
+	dev.RPM = &keyRPM
+	_, rpm, _ = dev.GetEffectiveRateLimits(1000, 100, 0)
+	assert.Equal(t, 30, rpm, "the key's own limits win")
+
+	require.NotNil(t, dev.GetMaxBudget())
+	assert.Equal(t, 50.0, *dev.GetMaxBudget())
+	dev.CurrentSpend = 50
+	assert.True(t, dev.IsBudgetExceeded())
+	keyBudget := 80.0
+	dev.MaxBudget = &keyBudget
+	assert.False(t, dev.IsBudgetExceeded(), "the key's own budget wins")
+
+	prod := &Key{Environment: KeyEnvironmentProd, Team: team}
+	assert.True(t, prod.IsModelAllowed("gpt-4o"))
+	assert.Nil(t, prod.GetMaxBudget())
+	tpm, _, _ = prod.GetEffectiveRateLimits(1000, 100, 0)
+	assert.Equal(t, 500000, tpm)
+
+	assert.Nil(t, (&Key{Environment: KeyEnvironmentStaging, Team: team}).EnvironmentPolicy())
+	assert.Nil(t, (&Key{Team: team}).EnvironmentPolicy(), "keys without an environment")
+	assert.Nil(t, (&Key{Environment: KeyEnvironmentDev}).EnvironmentPolicy(), "team not loaded")
+}
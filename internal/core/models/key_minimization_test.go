@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateResponseMinimization(t *testing.T) {
+	assert.NoError(t, ValidateResponseMinimization(nil))
+	assert.NoError(t, ValidateResponseMinimization([]string{MinimizeLogprobs, MinimizeContentOnly}))
+	assert.EqualError(t, ValidateResponseMinimization([]string{MinimizeLogprobs, "usage"}),
+		`invalid response minimization option "usage" (valid options: logprobs, system_fingerprint, tool_metadata, content_only)`)
+}
+
+func TestKey_GetResponseMinimization(t *testing.T) {
+	assert.Empty(t, (&Key{}).GetResponseMinimization())
+
+	team := &Team{ResponseMinimization: StringArray{MinimizeSystemFingerprint}}
+	assert.Equal(t, []string{MinimizeSystemFingerprint}, (&Key{Team: team}).GetResponseMinimization())
+
+	key := &Key{Team: team, ResponseMinimization: []string{MinimizeLogprobs}}
+	assert.Equal(t, []string{MinimizeLogprobs}, key.GetResponseMinimization(), "the key's options replace the team's")
+}
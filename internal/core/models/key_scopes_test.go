@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey_HasScope(t *testing.T) {
+	t.Run("no scopes allows all LLM endpoints but not admin", func(t *testing.T) {
+		key := &Key{}
+		assert.True(t, key.HasScope(ScopeChat))
+		assert.True(t, key.HasScope(ScopeRealtime))
+		assert.False(t, key.HasScope(ScopeAdminRead))
+	})
+
+	t.Run("wildcard does not imply admin:read", func(t *testing.T) {
+		key := &Key{Scopes: []string{ScopeAll}}
+		assert.True(t, key.HasScope(ScopeEmbeddings))
+		assert.False(t, key.HasScope(ScopeAdminRead))
+	})
+
+	t.Run("restricted key", func(t *testing.T) {
+		key := &Key{Scopes: []string{ScopeEmbeddings, ScopeAdminRead}}
+		assert.True(t, key.HasScope(ScopeEmbeddings))
+		assert.True(t, key.HasScope(ScopeAdminRead))
+		assert.False(t, key.HasScope(ScopeChat))
+	})
+
+	t.Run("class grants its finer-grained scopes", func(t *testing.T) {
+		key := &Key{Scopes: []string{ScopeChat}}
+		assert.True(t, key.HasScope("chat:completions"))
+		assert.False(t, key.HasScope(ScopeAudio))
+	})
+
+	t.Run("finer-grained scope does not grant its class", func(t *testing.T) {
+		key := &Key{Scopes: []string{"chat:gpt-4"}}
+		assert.True(t, key.HasScope("chat:gpt-4"))
+		assert.False(t, key.HasScope(ScopeChat))
+		assert.False(t, key.HasScope("chat:gpt-4o"))
+	})
+
+	t.Run("monitoring is only granted explicitly", func(t *testing.T) {
+		assert.False(t, (&Key{}).HasScope(ScopeMonitoring))
+		assert.False(t, (&Key{Scopes: []string{ScopeAll}}).HasScope(ScopeMonitoring))
+
+		key := &Key{Scopes: []string{ScopeMonitoring}}
+		assert.True(t, key.HasScope(ScopeMonitoring))
+		assert.False(t, key.HasScope(ScopeChat))
+	})
+
+	t.Run("suffixed admin scope does not grant admin:read", func(t *testing.T) {
+		key := &Key{Scopes: []string{"admin:read:x"}}
+		assert.False(t, key.HasScope(ScopeAdminRead))
+	})
+}
+
+func TestValidateScopes(t *testing.T) {
+	assert.NoError(t, ValidateScopes(nil))
+	assert.NoError(t, ValidateScopes([]string{ScopeChat, ScopeAdminRead, "chat:completions", ScopeAll}))
+	assert.Error(t, ValidateScopes([]string{"billing"}))
+	assert.Error(t, ValidateScopes([]string{"chatty"}))
+	assert.Error(t, ValidateScopes([]string{"admin:read:x"}))
+	assert.NoError(t, ValidateScopes([]string{ScopeMonitoring}))
+	assert.Error(t, ValidateScopes([]string{"monitoring:x"}))
+	assert.Error(t, ValidateScopes([]string{"*:chat"}))
+}
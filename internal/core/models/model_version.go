@@ -0,0 +1,25 @@
+package models
+
+import "strings"
+
+// SplitModelVersion splits a version-pinned model name such as
+// "gpt-4o@2024-08-06" into the model name and the snapshot version. Names
+// without a pin return an empty version.
+func SplitModelVersion(model string) (name, version string) {
+	name, version, found := strings.Cut(model, "@")
+	if !found || name == "" || version == "" {
+		return model, ""
+	}
+	return name, version
+}
+
+// modelMatches reports whether an allowed or blocked model list entry covers
+// model. An entry for a model name covers all its pinned versions, while an
+// entry for a pinned version covers only that version.
+func modelMatches(entry, model string) bool {
+	if entry == "*" || entry == model {
+		return true
+	}
+	name, version := SplitModelVersion(model)
+	return version != "" && entry == name
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitModelVersion(t *testing.T) {
+	tests := []struct {
+		model, name, version string
+	}{
+		{"gpt-4o@2024-08-06", "gpt-4o", "2024-08-06"},
+		{"gpt-4o", "gpt-4o", ""},
+		{"gpt-4o@", "gpt-4o@", ""},
+		{"@2024-08-06", "@2024-08-06", ""},
+	}
+	for _, tt := range tests {
+		name, version := SplitModelVersion(tt.model)
+		assert.Equal(t, tt.name, name, tt.model)
+		assert.Equal(t, tt.version, version, tt.model)
+	}
+}
+
+func TestKey_IsModelAllowed_PinnedVersions(t *testing.T) {
+	key := &Key{AllowedModels: []string{"gpt-4o", "claude@20241022"}}
+	assert.True(t, key.IsModelAllowed("gpt-4o@2024-08-06"), "allowing a model allows its snapshots")
+	assert.True(t, key.IsModelAllowed("claude@20241022"))
+	assert.False(t, key.IsModelAllowed("claude"), "allowing a snapshot does not allow the model")
+	assert.False(t, key.IsModelAllowed("claude@20240620"))
+
+	key = &Key{BlockedModels: []string{"gpt-4o", "claude@20240620"}}
+	assert.False(t, key.IsModelAllowed("gpt-4o@2024-08-06"), "blocking a model blocks its snapshots")
+	assert.False(t, key.IsModelAllowed("claude@20240620"))
+	assert.True(t, key.IsModelAllowed("claude@20241022"))
+}
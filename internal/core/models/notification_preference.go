@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// NotificationPreference holds what a user is emailed about their own usage.
+// Users without preferences get the configured defaults.
+type NotificationPreference struct {
+	BaseModel
+	UserID       uuid.UUID       `gorm:"type:uuid;uniqueIndex;not null" json:"user_id"`
+	SpendAlerts  bool            `gorm:"not null" json:"spend_alerts"`                        // Email when a personal key crosses a spend threshold
+	WeeklyDigest bool            `gorm:"not null" json:"weekly_digest"`                       // Weekly usage summary
+	Thresholds   pq.Float64Array `gorm:"type:double precision[]" json:"thresholds,omitempty"` // Percentages of the key budget, empty for the configured ones
+	LastDigestAt *time.Time      `json:"last_digest_at,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (NotificationPreference) TableName() string {
+	return "user_notification_preferences"
+}
+
+// SpendAlert records a spend threshold a user was notified about for a key,
+// once per budget period
+type SpendAlert struct {
+	BaseModel
+	KeyID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_spend_alert_period" json:"key_id"`
+	Threshold   float64   `gorm:"not null;uniqueIndex:idx_spend_alert_period" json:"threshold"`
+	PeriodStart time.Time `gorm:"not null;uniqueIndex:idx_spend_alert_period" json:"period_start"` // Identifies the budget period
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Spend       float64   `json:"spend"`
+}
+
+// TableName overrides the default table name.
+func (SpendAlert) TableName() string {
+	return "user_spend_alerts"
+}
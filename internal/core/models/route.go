@@ -1,6 +1,10 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
 	"github.com/google/uuid"
 )
 
@@ -18,6 +22,33 @@ type Route struct {
 	Source         string          `gorm:"default:'user'" json:"source"`
 	CreatedByID    *uuid.UUID      `gorm:"type:uuid" json:"created_by_id,omitempty"`
 	Models         []RouteModel    `gorm:"foreignKey:RouteID" json:"models,omitempty"`
+	Defaults       RouteDefaults   `gorm:"type:jsonb" json:"defaults"`
+}
+
+// RouteDefaults are request parameters applied to every request for the
+// route that does not set them
+type RouteDefaults struct {
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+	MaxTokens       int      `json:"max_tokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+}
+
+// Scan implements the sql.Scanner interface for JSONB
+func (d *RouteDefaults) Scan(value interface{}) error {
+	if value == nil {
+		*d = RouteDefaults{}
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan RouteDefaults: expected []byte, got %T", value)
+	}
+	return json.Unmarshal(bytes, d)
+}
+
+// Value implements the driver.Valuer interface for JSONB
+func (d RouteDefaults) Value() (driver.Value, error) {
+	return json.Marshal(d)
 }
 
 // TableName overrides the default table name.
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// SystemSetting is a setting admins change at runtime, stored as JSON under
+// its key. Gateways apply changes without a restart.
+type SystemSetting struct {
+	Key       string         `gorm:"primaryKey" json:"key"`
+	Value     datatypes.JSON `gorm:"type:jsonb;not null" json:"value"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	UpdatedBy *uuid.UUID     `gorm:"type:uuid" json:"updated_by,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (SystemSetting) TableName() string {
+	return "system_settings"
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -63,10 +64,27 @@ type Team struct {
 	RPM              int `json:"rpm"` // Requests per minute
 	MaxParallelCalls int `json:"max_parallel_calls"`
 
+	// MaxMonthlyBandwidth caps the request and response bytes of the team's
+	// keys per calendar month (UTC); 0 is unlimited
+	MaxMonthlyBandwidth int64 `json:"max_monthly_bandwidth"`
+
 	// Model Access Control
 	AllowedModels StringArray    `gorm:"type:text[]" json:"allowed_models"`
 	BlockedModels StringArray    `gorm:"type:text[]" json:"blocked_models"`
 	ModelAliases  datatypes.JSON `json:"model_aliases,omitempty"`
+	// DefaultModel serves requests that omit the model or ask for "default"
+	DefaultModel string `json:"default_model,omitempty"`
+	// ResponseMinimization strips fields from the completion responses of
+	// the team's keys that set none of their own
+	ResponseMinimization StringArray `gorm:"type:text[]" json:"response_minimization,omitempty"`
+	// Environments maps key environments to the EnvironmentPolicy of the
+	// team's keys in them
+	Environments datatypes.JSON `json:"environments,omitempty"`
+	// SystemPrompt is put before the messages of the chat requests of the
+	// team's keys; SystemPromptPolicy decides what becomes of the system
+	// messages clients send themselves (see ValidSystemPromptPolicies)
+	SystemPrompt       string `gorm:"type:text" json:"system_prompt,omitempty"`
+	SystemPromptPolicy string `json:"system_prompt_policy,omitempty"`
 
 	// Configuration
 	Settings datatypes.JSON `json:"settings,omitempty"`
@@ -77,6 +95,97 @@ type Team struct {
 	Keys    []Key        `gorm:"foreignKey:TeamID" json:"keys,omitempty"`
 }
 
+// EnvironmentPolicy sets the limits and models of a team's keys in one
+// environment. Limits apply to each key and give way to the key's own;
+// the models restrict the key's own lists further.
+type EnvironmentPolicy struct {
+	MaxBudget        *float64 `json:"max_budget,omitempty"`
+	TPM              *int     `json:"tpm,omitempty"`
+	RPM              *int     `json:"rpm,omitempty"`
+	MaxParallelCalls *int     `json:"max_parallel_calls,omitempty"`
+	AllowedModels    []string `json:"allowed_models,omitempty"`
+	BlockedModels    []string `json:"blocked_models,omitempty"`
+}
+
+// EnvironmentPolicies returns the team's environment policies, keyed by
+// environment
+func (t *Team) EnvironmentPolicies() map[string]EnvironmentPolicy {
+	if len(t.Environments) == 0 {
+		return nil
+	}
+	var policies map[string]EnvironmentPolicy
+	if err := json.Unmarshal(t.Environments, &policies); err != nil {
+		return nil
+	}
+	return policies
+}
+
+// EnvironmentPolicy returns the team's policy for an environment, or nil
+// when it has none
+func (t *Team) EnvironmentPolicy(environment string) *EnvironmentPolicy {
+	policy, ok := t.EnvironmentPolicies()[environment]
+	if !ok {
+		return nil
+	}
+	return &policy
+}
+
+// ValidateEnvironmentPolicies returns an error for a policy of an unknown
+// environment or with negative limits
+func ValidateEnvironmentPolicies(policies map[string]EnvironmentPolicy) error {
+	for environment, policy := range policies {
+		if err := ValidateKeyEnvironment(environment); err != nil || environment == "" {
+			return fmt.Errorf("invalid environment %q (valid environments: %s)",
+				environment, strings.Join(ValidKeyEnvironments, ", "))
+		}
+		if (policy.MaxBudget != nil && *policy.MaxBudget < 0) ||
+			(policy.TPM != nil && *policy.TPM < 0) ||
+			(policy.RPM != nil && *policy.RPM < 0) ||
+			(policy.MaxParallelCalls != nil && *policy.MaxParallelCalls < 0) {
+			return fmt.Errorf("environment %q has a negative limit", environment)
+		}
+	}
+	return nil
+}
+
+// System prompt policies, for requests that carry their own system messages
+const (
+	// SystemPromptPrepend keeps the client's system messages after the
+	// team's prompt. It is the default.
+	SystemPromptPrepend = "prepend"
+	// SystemPromptReplace drops the client's system messages
+	SystemPromptReplace = "replace"
+	// SystemPromptReject fails the request
+	SystemPromptReject = "reject"
+)
+
+// ValidSystemPromptPolicies lists the accepted system prompt policies
+var ValidSystemPromptPolicies = []string{SystemPromptPrepend, SystemPromptReplace, SystemPromptReject}
+
+// ValidateSystemPromptPolicy returns an error for an unknown system prompt
+// policy; empty means the default
+func ValidateSystemPromptPolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, valid := range ValidSystemPromptPolicies {
+		if policy == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid system prompt policy %q (valid policies: %s)",
+		policy, strings.Join(ValidSystemPromptPolicies, ", "))
+}
+
+// GetSystemPromptPolicy returns the team's system prompt policy, defaulting
+// to SystemPromptPrepend
+func (t *Team) GetSystemPromptPolicy() string {
+	if t.SystemPromptPolicy == "" {
+		return SystemPromptPrepend
+	}
+	return t.SystemPromptPolicy
+}
+
 // TeamMember represents a user's membership in a team
 type TeamMember struct {
 	ID     uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
@@ -103,8 +212,23 @@ const (
 	TeamRoleAdmin  TeamRole = "admin"
 	TeamRoleMember TeamRole = "member"
 	TeamRoleViewer TeamRole = "viewer"
+
+	// TeamRoleBillingAdmin manages the team's budget and credits, but not
+	// its keys, members or models
+	TeamRoleBillingAdmin TeamRole = "billing_admin"
+	// TeamRoleAuditor has read-only access to the team's usage and audit log
+	TeamRoleAuditor TeamRole = "auditor"
 )
 
+// ValidTeamRole reports whether role is one of the known team roles
+func ValidTeamRole(role TeamRole) bool {
+	switch role {
+	case TeamRoleOwner, TeamRoleAdmin, TeamRoleMember, TeamRoleViewer, TeamRoleBillingAdmin, TeamRoleAuditor:
+		return true
+	}
+	return false
+}
+
 type TeamSettings struct {
 	// Notification settings
 	WebhookURL         string   `json:"webhook_url"`
@@ -126,7 +250,7 @@ type TeamSettings struct {
 func (t *Team) IsModelAllowed(model string) bool {
 	// Check if model is blocked
 	for _, blocked := range t.BlockedModels {
-		if blocked == model || blocked == "*" {
+		if modelMatches(blocked, model) {
 			return false
 		}
 	}
@@ -138,7 +262,7 @@ func (t *Team) IsModelAllowed(model string) bool {
 
 	// Check if model is in allowed list
 	for _, allowed := range t.AllowedModels {
-		if allowed == model || allowed == "*" {
+		if modelMatches(allowed, model) {
 			return true
 		}
 	}
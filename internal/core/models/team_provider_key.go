@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TeamProviderKey is a provider API key a team brought to the gateway.
+// Requests of the team's keys that route to a model of the same provider
+// type are sent with it instead of the gateway's shared credentials, so the
+// provider bills the team's own account.
+type TeamProviderKey struct {
+	BaseModel
+	TeamID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_team_provider_key" json:"team_id"`
+	ProviderType string    `gorm:"not null;uniqueIndex:idx_team_provider_key" json:"provider_type"`
+
+	// EncryptedKey is the API key sealed with the team's encryption key; it
+	// is never returned by the API
+	EncryptedKey string `gorm:"not null" json:"-"`
+	KeyHint      string `json:"key_hint"` // Last characters of the key, to tell keys apart
+	BaseURL      string `json:"base_url,omitempty"`
+	IsActive     bool   `gorm:"default:true" json:"is_active"`
+
+	// Result of the latest health check
+	Healthy       bool       `gorm:"default:true" json:"healthy"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// TableName overrides the default table name.
+func (TeamProviderKey) TableName() string {
+	return "team_provider_keys"
+}
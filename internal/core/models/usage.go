@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,6 +23,7 @@ type Usage struct {
 	KeyID        *uuid.UUID `gorm:"type:uuid;index" json:"key_id,omitempty"`
 	Key          *Key       `gorm:"foreignKey:KeyID" json:"-"`
 	KeyOwnerID   *uuid.UUID `gorm:"type:uuid;index" json:"key_owner_id,omitempty"` // Who owns the key (for user keys)
+	Environment  string     `gorm:"size:20;index" json:"environment,omitempty"`    // Environment of the key
 
 	// Provider/Model
 	Provider      string `gorm:"index" json:"provider"`
@@ -30,25 +32,69 @@ type Usage struct {
 	ProviderModel string `json:"provider_model,omitempty"`
 
 	// Request/Response
-	Method     string `json:"method"`
-	Path       string `json:"path"`
-	StatusCode int    `json:"status_code"`
-	Latency    int64  `json:"latency"`
+	EndpointType string `gorm:"index" json:"endpoint_type,omitempty"` // Kind of API endpoint, see EndpointTypeForPath
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	StatusCode   int    `json:"status_code"`
+	Latency      int64  `json:"latency"`
+	TTFT         int64  `json:"ttft,omitempty"` // Time to first token in milliseconds, streaming requests only
+
+	// Streaming generation: completion tokens per second after the first
+	// chunk, the longest gap between chunks in milliseconds and whether the
+	// stream stalled
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+	MaxChunkGap     int64   `json:"max_chunk_gap,omitempty"`
+	Stalled         bool    `json:"stalled,omitempty"`
+
+	// Team provider key the request was sent with (bring your own key); its
+	// cost was billed to the team's own provider account
+	ProviderKeyID *uuid.UUID `gorm:"type:uuid;index" json:"provider_key_id,omitempty"`
 
 	// Tokens
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
-	TotalTokens  int `json:"total_tokens"`
+	InputTokens     int `json:"input_tokens"`
+	OutputTokens    int `json:"output_tokens"`
+	ReasoningTokens int `json:"reasoning_tokens"` // Subset of OutputTokens spent on hidden reasoning
+	TotalTokens     int `json:"total_tokens"`
+
+	// Audio
+	AudioSeconds       float64 `json:"audio_seconds,omitempty"`        // Transcribed or streamed audio billed per second
+	OutputAudioSeconds float64 `json:"output_audio_seconds,omitempty"` // Audio generated by realtime sessions
+
+	// Media
+	ImagesGenerated int `json:"images_generated,omitempty"` // Images returned by image generation
+	Characters      int `json:"characters,omitempty"`       // Input characters synthesized to speech
+
+	// Bandwidth: bytes of the request body the client sent and of the
+	// response it received (see bandwidth config)
+	RequestBytes  int64 `json:"request_bytes,omitempty"`
+	ResponseBytes int64 `json:"response_bytes,omitempty"`
 
 	// Cost
 	InputCost  float64 `json:"input_cost"`
 	OutputCost float64 `json:"output_cost"`
 	TotalCost  float64 `json:"total_cost"`
 
+	// CostBreakdown itemizes how TotalCost was computed (config.CostBreakdown)
+	CostBreakdown datatypes.JSON `json:"cost_breakdown,omitempty"`
+
+	// Sampling: the number of requests this row stands for when usage
+	// sampling stores only some rows (see usage_sampling config)
+	SampleRate int `gorm:"default:1" json:"sample_rate"`
+
 	// Cache
 	CacheHit bool   `json:"cache_hit"`
 	CacheKey string `json:"cache_key,omitempty"`
 
+	// Provenance hash of the generated content (see provenance config)
+	ContentHash string `gorm:"index" json:"content_hash,omitempty"`
+
+	// Client, from the client IP (country and continent codes, when a GeoIP
+	// database is configured) and the User-Agent; the IP is not stored
+	Country       string `gorm:"size:2;index" json:"country,omitempty"`
+	Continent     string `gorm:"size:2" json:"continent,omitempty"`
+	ClientName    string `gorm:"size:64;index" json:"client_name,omitempty"`
+	ClientVersion string `gorm:"size:32" json:"client_version,omitempty"`
+
 	// Error
 	Error     string `json:"error,omitempty"`
 	ErrorCode string `json:"error_code,omitempty"`
@@ -123,6 +169,44 @@ func (Usage) TableName() string {
 	return "usage_logs"
 }
 
+// Endpoint types of usage records
+const (
+	EndpointChat          = "chat"
+	EndpointCompletions   = "completions"
+	EndpointEmbeddings    = "embeddings"
+	EndpointImages        = "images"
+	EndpointTranscription = "transcription"
+	EndpointSpeech        = "speech"
+	EndpointRealtime      = "realtime"
+	EndpointContextCache  = "context_cache"
+	EndpointRerank        = "rerank"
+)
+
+// EndpointTypeForPath returns the endpoint type of a request path, with or
+// without its /v1 prefix; chat completions is the default
+func EndpointTypeForPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/embeddings"):
+		return EndpointEmbeddings
+	case strings.Contains(path, "/images/"):
+		return EndpointImages
+	case strings.HasSuffix(path, "/audio/transcriptions"):
+		return EndpointTranscription
+	case strings.HasSuffix(path, "/audio/speech"):
+		return EndpointSpeech
+	case strings.HasSuffix(path, "/realtime"):
+		return EndpointRealtime
+	case strings.Contains(path, "/caches"):
+		return EndpointContextCache
+	case strings.HasSuffix(path, "/rerank"):
+		return EndpointRerank
+	case strings.HasSuffix(path, "/completions") && !strings.HasSuffix(path, "/chat/completions"):
+		return EndpointCompletions
+	default:
+		return EndpointChat
+	}
+}
+
 type UsageAggregation struct {
 	Date         time.Time  `json:"date"`
 	UserID       *uuid.UUID `json:"user_id,omitempty"`
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointTypeForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/chat/completions", EndpointChat},
+		{"/v1/chat/completions/compare", EndpointChat},
+		{"/v1/completions", EndpointCompletions},
+		{"/embeddings", EndpointEmbeddings},
+		{"/api/v1/embeddings", EndpointEmbeddings},
+		{"/images/generations", EndpointImages},
+		{"/audio/transcriptions", EndpointTranscription},
+		{"/audio/speech", EndpointSpeech},
+		{"/v1/realtime", EndpointRealtime},
+		{"/caches", EndpointContextCache},
+		{"/v1/rerank", EndpointRerank},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, EndpointTypeForPath(tt.path), tt.path)
+	}
+}
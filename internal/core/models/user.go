@@ -123,7 +123,7 @@ func (u *User) ResetBudget() {
 func (u *User) IsModelAllowed(model string) bool {
 	// Check if model is blocked
 	for _, blocked := range u.BlockedModels {
-		if blocked == model || blocked == "*" {
+		if modelMatches(blocked, model) {
 			return false
 		}
 	}
@@ -135,7 +135,7 @@ func (u *User) IsModelAllowed(model string) bool {
 
 	// Check if model is in allowed list
 	for _, allowed := range u.AllowedModels {
-		if allowed == model || allowed == "*" {
+		if modelMatches(allowed, model) {
 			return true
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"net/url"
 
 	"github.com/google/uuid"
 )
@@ -71,13 +72,26 @@ type ProviderConfigJSON struct {
 	Location           string `json:"location,omitempty"`
 	AzureDeployment    string `json:"azure_deployment,omitempty"`
 	AzureEndpoint      string `json:"azure_endpoint,omitempty"`
+	AzureBaseModel     string `json:"azure_base_model,omitempty"`
 	AWSAccessKeyID     string `json:"aws_access_key_id,omitempty"`
 	AWSSecretAccessKey string `json:"aws_secret_access_key,omitempty"`
 	AWSRegionName      string `json:"aws_region_name,omitempty"`
+	AWSSessionToken    string `json:"aws_session_token,omitempty"`
+	AWSProfileName     string `json:"aws_profile_name,omitempty"`
+	AWSRoleARN         string `json:"aws_role_arn,omitempty"`
+	AWSExternalID      string `json:"aws_external_id,omitempty"`
+	AWSRoleSessionName string `json:"aws_role_session_name,omitempty"`
 	VertexProject      string `json:"vertex_project,omitempty"`
 	VertexLocation     string `json:"vertex_location,omitempty"`
+	SpaceID            string `json:"space_id,omitempty"`
+	IAMURL             string `json:"iam_url,omitempty"`
+	EmbeddingInputType string `json:"embedding_input_type,omitempty"`
 	ReasoningEffort    string `json:"reasoning_effort,omitempty"`
 	OAuthToken         string `json:"oauth_token,omitempty"`
+
+	// custom-openai
+	Headers map[string]string `json:"headers,omitempty"`
+	Paths   map[string]string `json:"paths,omitempty"`
 }
 
 // Scan implements the sql.Scanner interface for JSONB
@@ -108,6 +122,38 @@ type ModelInfoJSON struct {
 	MaxOutputTokens    int      `json:"max_output_tokens,omitempty"`
 	DefaultMaxTokens   int      `json:"default_max_tokens,omitempty"`
 	SupportedLanguages []string `json:"supported_languages,omitempty"`
+
+	EmbeddingDimensions int  `json:"embedding_dimensions,omitempty"`
+	SupportsDimensions  bool `json:"supports_dimensions,omitempty"`
+
+	// Documentation shown in the model catalog
+	Description string      `json:"description,omitempty"`
+	IntendedUse string      `json:"intended_use,omitempty"`
+	Owner       string      `json:"owner,omitempty"`
+	Contacts    []string    `json:"contacts,omitempty"`
+	Links       []ModelLink `json:"links,omitempty"`
+}
+
+// ModelLink is a link from the model catalog
+type ModelLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// HasDocumentation reports whether any catalog documentation is set
+func (m ModelInfoJSON) HasDocumentation() bool {
+	return m.Description != "" || m.IntendedUse != "" || m.Owner != "" || len(m.Contacts) > 0 || len(m.Links) > 0
+}
+
+// ValidateLinks checks that the catalog links are absolute http(s) URLs
+func (m ModelInfoJSON) ValidateLinks() error {
+	for _, link := range m.Links {
+		parsed, err := url.Parse(link.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("model_info.links: %q is not an http(s) URL", link.URL)
+		}
+	}
+	return nil
 }
 
 // Scan implements the sql.Scanner interface for JSONB
@@ -0,0 +1,399 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+const (
+	// CSRFCookieName holds the double-submit token for cookie sessions
+	CSRFCookieName = "pllm_csrf"
+	// CSRFHeader must echo the CSRF cookie on state-changing requests
+	CSRFHeader = "X-CSRF-Token"
+
+	// MasterKeyAllowlistKey keys the per-admin IP allowlist for master key requests
+	MasterKeyAllowlistKey = "master_key"
+
+	maxStepUpBodyBytes = 1 << 20
+)
+
+// BudgetFields are the request body fields that change a budget; updates
+// touching them need step-up re-authentication
+var BudgetFields = []string{"max_budget", "budget_duration", "max_cost_per_request"}
+
+// AdminSecurityMiddleware bundles the hardening applied to the admin API:
+// security headers, CSRF protection for cookie-based dashboard sessions,
+// IP allowlisting and step-up re-authentication for destructive actions.
+type AdminSecurityMiddleware struct {
+	logger *zap.Logger
+	config config.AdminSecurityConfig
+
+	allowlist       []*net.IPNet
+	adminAllowlists map[string][]*net.IPNet
+	trustedProxies  []*net.IPNet
+}
+
+// NewAdminSecurityMiddleware creates the admin security middleware. Invalid
+// allowlist entries are logged and dropped, which only narrows the list.
+func NewAdminSecurityMiddleware(logger *zap.Logger, cfg config.AdminSecurityConfig) *AdminSecurityMiddleware {
+	m := &AdminSecurityMiddleware{
+		logger:          logger,
+		config:          cfg,
+		adminAllowlists: make(map[string][]*net.IPNet, len(cfg.AdminIPAllowlist)),
+	}
+
+	m.allowlist = m.parseAllowlist("*", cfg.IPAllowlist)
+	m.trustedProxies = m.parseAllowlist("trusted_proxies", cfg.TrustedProxies)
+	for admin, entries := range cfg.AdminIPAllowlist {
+		m.adminAllowlists[strings.ToLower(admin)] = m.parseAllowlist(admin, entries)
+	}
+	return m
+}
+
+// Headers sets strict security headers on every admin response
+func (m *AdminSecurityMiddleware) Headers(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.config.SecurityHeaders {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+			h.Set("Referrer-Policy", "no-referrer")
+			h.Set("Cross-Origin-Opener-Policy", "same-origin")
+			h.Set("Cross-Origin-Resource-Policy", "same-origin")
+			h.Set("Permissions-Policy", "camera=(), microphone=(), geolocation=()")
+			h.Set("Cache-Control", "no-store")
+			if isHTTPS(r) {
+				h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CSRF enforces the double-submit cookie pattern for requests authenticated
+// by the session cookie. Safe requests receive a CSRF cookie; state-changing
+// requests must echo it in the X-CSRF-Token header. Requests carrying their
+// credentials in a header cannot be forged cross-site and are not checked.
+func (m *AdminSecurityMiddleware) CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.config.CSRF || !m.isCookieSession(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if cookie, err := r.Cookie(CSRFCookieName); err != nil || cookie.Value == "" {
+				if err := m.issueCSRFCookie(w, r); err != nil {
+					m.logger.Error("Failed to issue CSRF token", zap.Error(err))
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(CSRFCookieName)
+		header := r.Header.Get(CSRFHeader)
+		if err != nil || cookie.Value == "" || header == "" ||
+			subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+			m.logger.Warn("CSRF validation failed",
+				zap.String("path", r.URL.Path),
+				zap.String("method", r.Method))
+			m.sendError(w, http.StatusForbidden, "permission_error", "CSRF token missing or invalid")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IPAllowlist rejects admin requests from addresses outside the global
+// allowlist or the authenticated admin's own allowlist. It must run after
+// authentication.
+func (m *AdminSecurityMiddleware) IPAllowlist(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := m.clientIP(r)
+
+		if len(m.config.IPAllowlist) > 0 && !ipAllowed(ip, m.allowlist) {
+			m.denyIP(w, r, ip, "*")
+			return
+		}
+
+		for _, identity := range adminIdentities(r) {
+			if list, ok := m.adminAllowlists[identity]; ok {
+				if !ipAllowed(ip, list) {
+					m.denyIP(w, r, ip, identity)
+					return
+				}
+				break
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireStepUp requires the admin to have authenticated within
+// StepUpMaxAge. Master key requests present the root credential itself and
+// always pass; JWT sessions past the window get a 401 asking the client to
+// log in again and retry.
+func (m *AdminSecurityMiddleware) RequireStepUp(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.stepUpSatisfied(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		m.requestStepUp(w, r)
+	})
+}
+
+// RequireStepUpForFields applies RequireStepUp only when the JSON request
+// body sets one of the given top-level fields. Field names are compared
+// case-insensitively, the way encoding/json matches them when the handler
+// decodes the body.
+func (m *AdminSecurityMiddleware) RequireStepUpForFields(fields ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.config.StepUpMaxAge <= 0 || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxStepUpBodyBytes))
+			if err != nil {
+				m.sendError(w, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Bodies that are not JSON objects are left for the handler to reject
+			var payload map[string]json.RawMessage
+			if json.Unmarshal(body, &payload) == nil {
+				for key := range payload {
+					for _, field := range fields {
+						if strings.EqualFold(key, field) && !m.stepUpSatisfied(r) {
+							m.requestStepUp(w, r)
+							return
+						}
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (m *AdminSecurityMiddleware) stepUpSatisfied(r *http.Request) bool {
+	if m.config.StepUpMaxAge <= 0 {
+		return true
+	}
+
+	switch GetAuthType(r.Context()) {
+	case AuthTypeMasterKey:
+		return true
+	case AuthTypeJWT:
+		authTime, ok := GetAuthTime(r.Context())
+		return ok && time.Since(authTime) <= m.config.StepUpMaxAge
+	}
+	return false
+}
+
+func (m *AdminSecurityMiddleware) requestStepUp(w http.ResponseWriter, r *http.Request) {
+	m.logger.Info("Step-up re-authentication required",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method))
+
+	// RFC 9470 step-up challenge
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+		`Bearer error="insufficient_user_authentication", error_description="Recent authentication required", max_age=%d`,
+		int(m.config.StepUpMaxAge.Seconds())))
+	m.sendError(w, http.StatusUnauthorized, "authentication_error",
+		fmt.Sprintf("This action requires re-authentication within the last %s; log in again and retry", m.config.StepUpMaxAge))
+}
+
+// isCookieSession reports whether the request authenticates with the session
+// cookie rather than a header
+func (m *AdminSecurityMiddleware) isCookieSession(r *http.Request) bool {
+	if m.config.SessionCookie == "" || r.Header.Get("Authorization") != "" || r.Header.Get("X-API-Key") != "" {
+		return false
+	}
+	cookie, err := r.Cookie(m.config.SessionCookie)
+	return err == nil && cookie.Value != ""
+}
+
+func (m *AdminSecurityMiddleware) issueCSRFCookie(w http.ResponseWriter, r *http.Request) error {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	// Readable by the dashboard script, which echoes it in X-CSRF-Token
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    hex.EncodeToString(token),
+		Path:     "/",
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+func (m *AdminSecurityMiddleware) parseAllowlist(admin string, entries []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		network, err := parseIPOrCIDR(entry)
+		if err != nil {
+			m.logger.Warn("Ignoring invalid admin IP allowlist entry",
+				zap.String("admin", admin),
+				zap.String("entry", entry),
+				zap.Error(err))
+			continue
+		}
+		nets = append(nets, network)
+	}
+	return nets
+}
+
+func (m *AdminSecurityMiddleware) denyIP(w http.ResponseWriter, r *http.Request, ip net.IP, list string) {
+	m.logger.Warn("Admin request from address outside allowlist",
+		zap.String("ip", ip.String()),
+		zap.String("allowlist", list),
+		zap.String("path", r.URL.Path))
+	m.sendError(w, http.StatusForbidden, "permission_error", "Admin access is not allowed from this address")
+}
+
+func (m *AdminSecurityMiddleware) sendError(w http.ResponseWriter, statusCode int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errorType,
+			"code":    statusCode,
+		},
+	}); err != nil {
+		m.logger.Error("Failed to encode admin security error response", zap.Error(err))
+	}
+}
+
+// adminIdentities lists the keys the request's admin may appear under in the
+// per-admin allowlist
+func adminIdentities(r *http.Request) []string {
+	if IsMasterKey(r.Context()) {
+		return []string{MasterKeyAllowlistKey}
+	}
+	var identities []string
+	if email, ok := GetEmail(r.Context()); ok {
+		identities = append(identities, strings.ToLower(email))
+	}
+	if userID, ok := GetUserID(r.Context()); ok {
+		identities = append(identities, userID.String())
+	}
+	return identities
+}
+
+func parseIPOrCIDR(entry string) (*net.IPNet, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", entry)
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func ipAllowed(ip net.IP, allowlist []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range allowlist {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address the allowlists are checked against: the
+// socket peer, or the forwarded client address when the peer is a trusted
+// proxy. X-Forwarded-For is read from the right, skipping trusted proxies,
+// so entries the client prepended are never used.
+func (m *AdminSecurityMiddleware) clientIP(r *http.Request) net.IP {
+	peer := parseHostIP(peerAddr(r))
+	if !ipAllowed(peer, m.trustedProxies) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := parseHostIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				return peer
+			}
+			if !ipAllowed(ip, m.trustedProxies) {
+				return ip
+			}
+		}
+		return peer
+	}
+	if ip := parseHostIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip
+	}
+	return peer
+}
+
+// PeerAddr records the socket address of the request before chi's RealIP
+// middleware replaces RemoteAddr with client-supplied headers. It must run
+// before RealIP.
+func PeerAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(PeerAddrContextKey).(string); ok {
+			next.ServeHTTP(w, r) // Recorded by an outer router
+			return
+		}
+		ctx := context.WithValue(r.Context(), PeerAddrContextKey, r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// peerAddr returns the socket address recorded by PeerAddr, falling back to
+// RemoteAddr when the middleware did not run
+func peerAddr(r *http.Request) string {
+	if addr, ok := r.Context().Value(PeerAddrContextKey).(string); ok {
+		return addr
+	}
+	return r.RemoteAddr
+}
+
+func parseHostIP(host string) net.IP {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return net.ParseIP(host)
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
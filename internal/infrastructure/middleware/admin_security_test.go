@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func withAuth(r *http.Request, authType AuthType, values map[contextKey]interface{}) *http.Request {
+	ctx := context.WithValue(r.Context(), AuthTypeContextKey, authType)
+	for k, v := range values {
+		ctx = context.WithValue(ctx, k, v)
+	}
+	return r.WithContext(ctx)
+}
+
+func TestAdminSecurityMiddleware_Headers(t *testing.T) {
+	m := NewAdminSecurityMiddleware(zap.NewNop(), config.AdminSecurityConfig{SecurityHeaders: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/keys", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	m.Headers(okHandler).ServeHTTP(rec, req)
+
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, rec.Header().Get("Strict-Transport-Security"))
+}
+
+func TestAdminSecurityMiddleware_CSRF(t *testing.T) {
+	m := NewAdminSecurityMiddleware(zap.NewNop(), config.AdminSecurityConfig{CSRF: true, SessionCookie: "pllm_session"})
+	handler := m.CSRF(okHandler)
+	session := &http.Cookie{Name: "pllm_session", Value: "token"}
+
+	// Safe requests on a cookie session receive a CSRF token
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/keys", nil)
+	req.AddCookie(session)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var csrf *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == CSRFCookieName {
+			csrf = c
+		}
+	}
+	require.NotNil(t, csrf)
+
+	tests := []struct {
+		name   string
+		header string
+		bearer bool
+		want   int
+	}{
+		{"missing token", "", false, http.StatusForbidden},
+		{"wrong token", "forged", false, http.StatusForbidden},
+		{"matching token", csrf.Value, false, http.StatusOK},
+		{"header credentials are not checked", "", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/admin/keys/1", nil)
+			req.AddCookie(session)
+			req.AddCookie(csrf)
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer token")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestAdminSecurityMiddleware_IPAllowlist(t *testing.T) {
+	m := NewAdminSecurityMiddleware(zap.NewNop(), config.AdminSecurityConfig{
+		IPAllowlist: []string{"10.0.0.0/8", "203.0.113.7"},
+		AdminIPAllowlist: map[string][]string{
+			"Ops@example.com": {"10.1.0.0/16"},
+			"master_key":      {"not-an-ip"},
+		},
+	})
+	handler := m.IPAllowlist(okHandler)
+
+	tests := []struct {
+		name     string
+		remote   string
+		authType AuthType
+		email    string
+		want     int
+	}{
+		{"outside global list", "192.0.2.1:1234", AuthTypeJWT, "", http.StatusForbidden},
+		{"inside global list", "203.0.113.7:1234", AuthTypeJWT, "", http.StatusOK},
+		{"inside admin list", "10.1.2.3", AuthTypeJWT, "ops@example.com", http.StatusOK},
+		{"outside admin list", "10.2.0.1", AuthTypeJWT, "ops@example.com", http.StatusForbidden},
+		{"invalid entries fail closed", "10.1.2.3", AuthTypeMasterKey, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/keys", nil)
+			req.RemoteAddr = tt.remote
+			req = withAuth(req, tt.authType, map[contextKey]interface{}{EmailContextKey: tt.email})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestAdminSecurityMiddleware_IPAllowlistForwardedHeaders(t *testing.T) {
+	m := NewAdminSecurityMiddleware(zap.NewNop(), config.AdminSecurityConfig{
+		IPAllowlist:    []string{"203.0.113.7"},
+		TrustedProxies: []string{"10.0.0.10", "10.0.0.11"},
+	})
+	// Mirror the router: PeerAddr, then chi's RealIP, then the allowlist
+	handler := PeerAddr(chiMiddleware.RealIP(m.IPAllowlist(okHandler)))
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    int
+	}{
+		{"spoofed X-Real-IP from untrusted peer", "192.0.2.1:1234", map[string]string{"X-Real-IP": "203.0.113.7"}, http.StatusForbidden},
+		{"spoofed X-Forwarded-For from untrusted peer", "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, http.StatusForbidden},
+		{"X-Real-IP from trusted proxy", "10.0.0.10:1234", map[string]string{"X-Real-IP": "203.0.113.7"}, http.StatusOK},
+		{"rightmost untrusted hop is used", "10.0.0.10:1234", map[string]string{"X-Forwarded-For": "203.0.113.7, 192.0.2.1, 10.0.0.11"}, http.StatusForbidden},
+		{"client behind proxy chain", "10.0.0.10:1234", map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.11"}, http.StatusOK},
+		{"trusted proxy without headers", "10.0.0.10:1234", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/keys", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			req = withAuth(req, AuthTypeJWT, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestAdminSecurityMiddleware_RequireStepUp(t *testing.T) {
+	m := NewAdminSecurityMiddleware(zap.NewNop(), config.AdminSecurityConfig{StepUpMaxAge: 5 * time.Minute})
+
+	t.Run("recent login", func(t *testing.T) {
+		req := withAuth(httptest.NewRequest(http.MethodDelete, "/api/admin/keys/1", nil), AuthTypeJWT,
+			map[contextKey]interface{}{AuthTimeContextKey: time.Now().Add(-time.Minute)})
+		rec := httptest.NewRecorder()
+		m.RequireStepUp(okHandler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("stale login", func(t *testing.T) {
+		req := withAuth(httptest.NewRequest(http.MethodDelete, "/api/admin/keys/1", nil), AuthTypeJWT,
+			map[contextKey]interface{}{AuthTimeContextKey: time.Now().Add(-time.Hour)})
+		rec := httptest.NewRecorder()
+		m.RequireStepUp(okHandler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "insufficient_user_authentication")
+	})
+
+	t.Run("master key", func(t *testing.T) {
+		req := withAuth(httptest.NewRequest(http.MethodDelete, "/api/admin/keys/1", nil), AuthTypeMasterKey, nil)
+		rec := httptest.NewRecorder()
+		m.RequireStepUp(okHandler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("only budget fields need step-up", func(t *testing.T) {
+		handler := m.RequireStepUpForFields(BudgetFields...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Contains(t, string(body), "name", "body is passed on to the handler")
+			w.WriteHeader(http.StatusOK)
+		}))
+		stale := map[contextKey]interface{}{AuthTimeContextKey: time.Now().Add(-time.Hour)}
+
+		req := withAuth(httptest.NewRequest(http.MethodPut, "/api/admin/keys/1", strings.NewReader(`{"name":"renamed"}`)), AuthTypeJWT, stale)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		req = withAuth(httptest.NewRequest(http.MethodPut, "/api/admin/keys/1", strings.NewReader(`{"name":"renamed","max_budget":10}`)), AuthTypeJWT, stale)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		// encoding/json matches field names case-insensitively
+		req = withAuth(httptest.NewRequest(http.MethodPut, "/api/admin/keys/1", strings.NewReader(`{"Max_Budget":1e9}`)), AuthTypeJWT, stale)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
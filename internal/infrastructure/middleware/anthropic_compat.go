@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of the Anthropic API that its SDKs read
+const (
+	AnthropicRequestIDHeader = "request-id"
+
+	anthropicRateLimitPrefix = "anthropic-ratelimit-"
+)
+
+// anthropicRateLimitHeaders maps the gateway's rate limit headers to their
+// Anthropic names
+var anthropicRateLimitHeaders = map[string]string{
+	"X-RateLimit-Limit":            anthropicRateLimitPrefix + "requests-limit",
+	"X-RateLimit-Remaining":        anthropicRateLimitPrefix + "requests-remaining",
+	"X-RateLimit-Limit-Tokens":     anthropicRateLimitPrefix + "tokens-limit",
+	"X-RateLimit-Remaining-Tokens": anthropicRateLimitPrefix + "tokens-remaining",
+}
+
+// AnthropicCompat makes responses of the Anthropic Messages endpoints look
+// like Anthropic's own, so its SDKs can point at the gateway unchanged:
+// they carry a request-id header and anthropic-ratelimit-* headers, and
+// every error, including those of the middleware in front of the handler,
+// uses the Anthropic error envelope. It must run before any middleware that
+// can reject a request.
+func AnthropicCompat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !isAnthropicMessagesPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if requestID := GetRequestID(r.Context()); requestID != "" {
+			w.Header().Set(AnthropicRequestIDHeader, requestID)
+		}
+		writer := &anthropicCompatWriter{ResponseWriter: w}
+		next.ServeHTTP(writer, r)
+		writer.finish()
+	})
+}
+
+func isAnthropicMessagesPath(path string) bool {
+	return path == "/v1/messages" || path == "/api/v1/messages"
+}
+
+// AnthropicErrorType returns the Anthropic error type of an HTTP status
+func AnthropicErrorType(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusPaymentRequired:
+		return "billing_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		return "overloaded_error"
+	case http.StatusGatewayTimeout:
+		return "timeout_error"
+	}
+	if status >= http.StatusInternalServerError {
+		return "api_error"
+	}
+	return "invalid_request_error"
+}
+
+// AnthropicError builds an error in the Anthropic error envelope
+func AnthropicError(status int, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    AnthropicErrorType(status),
+			"message": message,
+		},
+	}
+}
+
+// WriteAnthropicError writes an error in the Anthropic error envelope
+func WriteAnthropicError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(AnthropicError(status, message))
+}
+
+// anthropicCompatWriter adds the Anthropic rate limit headers and holds back
+// error responses so they can be rewritten into the Anthropic envelope.
+// Successful responses, including streams, pass through.
+type anthropicCompatWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	errorBody   *bytes.Buffer
+}
+
+func (w *anthropicCompatWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	setAnthropicRateLimitHeaders(w.Header())
+	if status >= http.StatusBadRequest {
+		w.errorBody = &bytes.Buffer{}
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *anthropicCompatWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.errorBody != nil {
+		return w.errorBody.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *anthropicCompatWriter) Flush() {
+	if w.errorBody != nil {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes a held back error response in the Anthropic envelope
+func (w *anthropicCompatWriter) finish() {
+	if w.errorBody == nil {
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	_ = json.NewEncoder(w.ResponseWriter).Encode(toAnthropicError(w.errorBody.Bytes(), w.status))
+}
+
+// toAnthropicError rewrites an OpenAI-style or plain text error body into
+// the Anthropic envelope; bodies already in it are kept
+func toAnthropicError(body []byte, status int) map[string]interface{} {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = http.StatusText(status)
+		}
+		return AnthropicError(status, message)
+	}
+	if payload["type"] == "error" {
+		return payload
+	}
+
+	message := http.StatusText(status)
+	switch apiErr := payload["error"].(type) {
+	case map[string]interface{}:
+		if text, ok := apiErr["message"].(string); ok && text != "" {
+			message = text
+		}
+	case string:
+		message = apiErr
+	}
+	if text, ok := payload["message"].(string); ok && text != "" && payload["error"] == nil {
+		message = text
+	}
+	return AnthropicError(status, message)
+}
+
+// setAnthropicRateLimitHeaders mirrors the gateway's rate limit headers
+// under their Anthropic names, with reset times in RFC 3339
+func setAnthropicRateLimitHeaders(h http.Header) {
+	for gateway, anthropic := range anthropicRateLimitHeaders {
+		if value := h.Get(gateway); value != "" {
+			h.Set(anthropic, value)
+		}
+	}
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		resetAt := time.Unix(reset, 0).UTC().Format(time.RFC3339)
+		h.Set(anthropicRateLimitPrefix+"requests-reset", resetAt)
+		if h.Get("X-RateLimit-Limit-Tokens") != "" {
+			h.Set(anthropicRateLimitPrefix+"tokens-reset", resetAt)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnthropicCompat(t *testing.T) {
+	serve := func(path string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chiMiddleware.RequestIDKey, "req-123"))
+		rec := httptest.NewRecorder()
+		AnthropicCompat(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("errors use the Anthropic envelope", func(t *testing.T) {
+		rec := serve("/v1/messages", jsonHandler(http.StatusTooManyRequests,
+			`{"error":{"message":"Rate limit exceeded","type":"rate_limit_exceeded","code":"rate_limit"}}`))
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.JSONEq(t, `{"type":"error","error":{"type":"rate_limit_error","message":"Rate limit exceeded"}}`, rec.Body.String())
+		assert.Equal(t, "req-123", rec.Header().Get(AnthropicRequestIDHeader))
+
+		rec = serve("/api/v1/messages", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		})
+		assert.JSONEq(t, `{"type":"error","error":{"type":"authentication_error","message":"Unauthorized"}}`, rec.Body.String())
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		already := `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`
+		rec = serve("/v1/messages", jsonHandler(529, already))
+		assert.JSONEq(t, already, rec.Body.String())
+	})
+
+	t.Run("rate limit headers get their Anthropic names", func(t *testing.T) {
+		reset := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		rec := serve("/v1/messages", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Limit", "60")
+			w.Header().Set("X-RateLimit-Remaining", "59")
+			w.Header().Set("X-RateLimit-Reset", "1767268800")
+			w.Header().Set("X-RateLimit-Limit-Tokens", "1000")
+			_, _ = w.Write([]byte(`{}`))
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "60", rec.Header().Get("anthropic-ratelimit-requests-limit"))
+		assert.Equal(t, "59", rec.Header().Get("anthropic-ratelimit-requests-remaining"))
+		assert.Equal(t, reset.Format(time.RFC3339), rec.Header().Get("anthropic-ratelimit-requests-reset"))
+		assert.Equal(t, "1000", rec.Header().Get("anthropic-ratelimit-tokens-limit"))
+		assert.Equal(t, reset.Format(time.RFC3339), rec.Header().Get("anthropic-ratelimit-tokens-reset"))
+	})
+
+	t.Run("other endpoints are untouched", func(t *testing.T) {
+		body := `{"error":{"message":"Rate limit exceeded"}}`
+		rec := serve("/v1/chat/completions", jsonHandler(http.StatusTooManyRequests, body))
+		assert.JSONEq(t, body, rec.Body.String())
+		assert.Empty(t, rec.Header().Get(AnthropicRequestIDHeader))
+	})
+}
+
+func TestAnthropicErrorType(t *testing.T) {
+	assert.Equal(t, "invalid_request_error", AnthropicErrorType(http.StatusBadRequest))
+	assert.Equal(t, "invalid_request_error", AnthropicErrorType(http.StatusUnprocessableEntity))
+	assert.Equal(t, "billing_error", AnthropicErrorType(http.StatusPaymentRequired))
+	assert.Equal(t, "request_too_large", AnthropicErrorType(http.StatusRequestEntityTooLarge))
+	assert.Equal(t, "overloaded_error", AnthropicErrorType(529))
+	assert.Equal(t, "api_error", AnthropicErrorType(http.StatusBadGateway))
+}
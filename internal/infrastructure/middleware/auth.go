@@ -3,15 +3,19 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
 	"github.com/amerfu/pllm/internal/core/auth"
 	"github.com/amerfu/pllm/internal/core/models"
+	pkglogger "github.com/amerfu/pllm/pkg/logger"
 )
 
 type contextKey string
@@ -23,6 +27,10 @@ const (
 	AuthTypeContextKey    contextKey = "auth_type"
 	MasterKeyContextKey   contextKey = "master_key_context"
 	PermissionsContextKey contextKey = "permissions"
+	EmailContextKey       contextKey = "email"
+	AuthTimeContextKey    contextKey = "auth_time"
+	PeerAddrContextKey    contextKey = "peer_addr"
+	TokenScopesContextKey contextKey = "token_scopes"
 )
 
 type AuthType string
@@ -39,7 +47,9 @@ type AuthMiddleware struct {
 	authService       *auth.AuthService
 	cachedAuthService *auth.CachedAuthService
 	masterKeyService  *auth.MasterKeyService
+	signatures        *SignatureVerifier
 	requireAuth       bool
+	sessionCookie     string
 }
 
 type AuthConfig struct {
@@ -47,6 +57,8 @@ type AuthConfig struct {
 	AuthService      *auth.AuthService
 	MasterKeyService *auth.MasterKeyService
 	RequireAuth      bool
+	SessionCookie    string        // Cookie holding a dashboard session token, checked when no header is set
+	Redis            *redis.Client // Optional, detects replayed signed requests across replicas
 }
 
 func NewAuthMiddleware(config *AuthConfig) *AuthMiddleware {
@@ -58,7 +70,9 @@ func NewAuthMiddleware(config *AuthConfig) *AuthMiddleware {
 		authService:       config.AuthService,
 		cachedAuthService: cachedAuth,
 		masterKeyService:  config.MasterKeyService,
+		signatures:        NewSignatureVerifier(config.Redis),
 		requireAuth:       config.RequireAuth,
+		sessionCookie:     config.SessionCookie,
 	}
 }
 
@@ -103,8 +117,14 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 				m.sendError(w, http.StatusUnauthorized, "Invalid master key")
 				return
 			}
+			if m.masterKeyService.RequiresExchange() {
+				m.sendError(w, http.StatusForbidden,
+					"The master key must be exchanged for an admin token at /api/admin/auth/master-key")
+				return
+			}
 			ctx := context.WithValue(r.Context(), AuthTypeContextKey, AuthTypeMasterKey)
 			ctx = context.WithValue(ctx, MasterKeyContextKey, masterCtx)
+			addAuthLogFields(r, AuthTypeMasterKey)
 			next.ServeHTTP(w, r.WithContext(ctx))
 
 		case AuthTypeAPIKey:
@@ -114,6 +134,17 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 				m.sendError(w, http.StatusUnauthorized, err.Error())
 				return
 			}
+			if key.RequireSignature {
+				if err := m.signatures.Verify(r, key.SigningSecret); err != nil {
+					m.sendSignatureError(w, err)
+					return
+				}
+			}
+			if scope := requestScope(r); scope != "" && !key.HasScope(scope) {
+				m.sendError(w, http.StatusForbidden,
+					fmt.Sprintf("API key is missing the %q scope required for this endpoint", scope))
+				return
+			}
 			ctx := context.WithValue(r.Context(), AuthTypeContextKey, AuthTypeAPIKey)
 			ctx = context.WithValue(ctx, KeyContextKey, key)
 			if key.UserID != nil {
@@ -122,6 +153,10 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			if key.TeamID != nil {
 				ctx = context.WithValue(ctx, TeamContextKey, *key.TeamID)
 			}
+			addAuthLogFields(r, AuthTypeAPIKey, zap.String("key_id", key.ID.String()))
+			if key.TeamID != nil {
+				pkglogger.AddFields(ctx, zap.String("team_id", key.TeamID.String()))
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 
 		case AuthTypeJWT:
@@ -134,10 +169,23 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 				return
 			}
 			m.logger.Debug("JWT validation successful", zap.String("user_id", cachedClaims.UserID.String()))
+			if scope := requestScope(r); scope != "" && (len(cachedClaims.Scopes) > 0 || models.ExplicitScope(scope)) && !models.ScopesAllow(cachedClaims.Scopes, scope) {
+				m.sendError(w, http.StatusForbidden,
+					fmt.Sprintf("Token is missing the %q scope required for this endpoint", scope))
+				return
+			}
 			ctx := context.WithValue(r.Context(), AuthTypeContextKey, AuthTypeJWT)
 			ctx = context.WithValue(ctx, UserContextKey, cachedClaims.UserID)
+			ctx = context.WithValue(ctx, EmailContextKey, cachedClaims.Email)
+			if cachedClaims.AuthTime != nil {
+				ctx = context.WithValue(ctx, AuthTimeContextKey, cachedClaims.AuthTime.Time)
+			}
 			// Store permissions in context for RBAC
 			ctx = context.WithValue(ctx, PermissionsContextKey, cachedClaims.Permissions)
+			if len(cachedClaims.Scopes) > 0 {
+				ctx = context.WithValue(ctx, TokenScopesContextKey, cachedClaims.Scopes)
+			}
+			addAuthLogFields(r, AuthTypeJWT, zap.String("user_id", cachedClaims.UserID.String()))
 			next.ServeHTTP(w, r.WithContext(ctx))
 
 		default:
@@ -151,6 +199,13 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// addAuthLogFields records the caller and the matched route in the request's
+// log context
+func addAuthLogFields(r *http.Request, authType AuthType, fields ...zap.Field) {
+	setRouteField(r)
+	pkglogger.AddFields(r.Context(), append([]zap.Field{zap.String("auth_type", string(authType))}, fields...)...)
+}
+
 // RequireAdmin ensures the request has admin privileges
 func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -170,16 +225,87 @@ func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
 				m.sendError(w, http.StatusForbidden, "Admin access required")
 				return
 			}
+			if scopes, ok := GetTokenScopes(r.Context()); ok && !adminScopesAllow(scopes, r.Method) {
+				m.sendError(w, http.StatusForbidden, "Token scopes do not allow this admin request")
+				return
+			}
 			// For now, allow all authenticated users
 			// In production, check user.Role == models.RoleAdmin
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// API keys with the admin:read scope may use read-only admin endpoints
+		if authType == AuthTypeAPIKey {
+			key, ok := GetKey(r.Context())
+			if ok && key != nil && key.HasScope(models.ScopeAdminRead) {
+				if r.Method == http.MethodGet || r.Method == http.MethodHead {
+					next.ServeHTTP(w, r)
+					return
+				}
+				m.sendError(w, http.StatusForbidden, "API key scope admin:read only allows read access")
+				return
+			}
+		}
+
 		m.sendError(w, http.StatusForbidden, "Admin access required")
 	})
 }
 
+// adminScopesAllow reports whether admin token scopes permit an admin API
+// request
+func adminScopesAllow(scopes []string, method string) bool {
+	for _, scope := range scopes {
+		switch scope {
+		case models.ScopeAll, auth.ScopeAdmin:
+			return true
+		case models.ScopeAdminRead:
+			if method == http.MethodGet || method == http.MethodHead {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestScope returns the scope a request needs. Synthetic requests need the
+// monitoring scope rather than that of the endpoint they were rewritten to.
+func requestScope(r *http.Request) string {
+	if IsSynthetic(r.Context()) {
+		return models.ScopeMonitoring
+	}
+	return RequiredScope(r.URL.Path)
+}
+
+// RequiredScope returns the key scope needed for an LLM endpoint path, or ""
+// when the path is not scope-restricted
+func RequiredScope(path string) string {
+	if !strings.HasPrefix(path, "/v1/") && !strings.HasPrefix(path, "/api/v1/") {
+		return ""
+	}
+
+	switch {
+	case strings.Contains(path, "/chat/completions"),
+		strings.Contains(path, "/completions"),
+		strings.Contains(path, "/messages"),
+		strings.Contains(path, "/jobs/"),
+		strings.Contains(path, "/caches"):
+		return models.ScopeChat
+	case strings.Contains(path, "/embeddings"),
+		strings.HasSuffix(path, "/rerank"):
+		return models.ScopeEmbeddings
+	case strings.Contains(path, "/images/"):
+		return models.ScopeImages
+	case strings.Contains(path, "/audio/"):
+		return models.ScopeAudio
+	case strings.Contains(path, "/moderations"):
+		return models.ScopeModerations
+	case strings.Contains(path, "/realtime"):
+		return models.ScopeRealtime
+	}
+	return ""
+}
+
 // RequireTeamAccess ensures the user has access to the team
 func (m *AuthMiddleware) RequireTeamAccess(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -321,6 +447,13 @@ func (m *AuthMiddleware) extractAuth(r *http.Request) (AuthType, string, error)
 		}
 	}
 
+	// Check the dashboard session cookie
+	if m.sessionCookie != "" {
+		if cookie, err := r.Cookie(m.sessionCookie); err == nil && cookie.Value != "" {
+			return AuthTypeJWT, cookie.Value, nil
+		}
+	}
+
 	m.logger.Debug("No authentication found in request")
 	return "", "", fmt.Errorf("no authentication found")
 }
@@ -339,6 +472,19 @@ func (m *AuthMiddleware) sendError(w http.ResponseWriter, statusCode int, messag
 	}
 }
 
+// sendSignatureError rejects a request whose signature could not be
+// verified. Failures to reach the replay cache are not the caller's fault.
+func (m *AuthMiddleware) sendSignatureError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrMissingSignature), errors.Is(err, ErrInvalidSignature),
+		errors.Is(err, ErrStaleSignature), errors.Is(err, ErrReplayedRequest):
+		m.sendError(w, http.StatusUnauthorized, err.Error())
+	default:
+		m.logger.Error("Failed to verify request signature", zap.Error(err))
+		m.sendError(w, http.StatusServiceUnavailable, "Unable to verify request signature")
+	}
+}
+
 // Helper functions to extract auth context
 
 func GetAuthType(ctx context.Context) AuthType {
@@ -364,6 +510,25 @@ func GetTeamID(ctx context.Context) (uuid.UUID, bool) {
 	return teamID, ok
 }
 
+func GetEmail(ctx context.Context) (string, bool) {
+	email, ok := ctx.Value(EmailContextKey).(string)
+	return email, ok && email != ""
+}
+
+// GetAuthTime returns when the request's token was issued, i.e. when the
+// user last authenticated
+func GetAuthTime(ctx context.Context) (time.Time, bool) {
+	authTime, ok := ctx.Value(AuthTimeContextKey).(time.Time)
+	return authTime, ok
+}
+
+// GetTokenScopes returns the scopes of the request's admin token, if it
+// carries any
+func GetTokenScopes(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(TokenScopesContextKey).([]string)
+	return scopes, ok && len(scopes) > 0
+}
+
 func IsMasterKey(ctx context.Context) bool {
 	return GetAuthType(ctx) == AuthTypeMasterKey
 }
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/auth"
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func TestRequiredScope(t *testing.T) {
+	tests := map[string]string{
+		"/v1/chat/completions":      models.ScopeChat,
+		"/api/v1/chat/completions":  models.ScopeChat,
+		"/v1/messages":              models.ScopeChat,
+		"/v1/jobs/123":              models.ScopeChat,
+		"/v1/caches/abc":            models.ScopeChat,
+		"/v1/embeddings":            models.ScopeEmbeddings,
+		"/v1/images/generations":    models.ScopeImages,
+		"/v1/audio/transcriptions":  models.ScopeAudio,
+		"/v1/moderations":           models.ScopeModerations,
+		"/v1/realtime":              models.ScopeRealtime,
+		"/v1/realtime/sessions/abc": models.ScopeRealtime,
+		"/v1/models":                "",
+		"/v1/user/profile":          "",
+		"/api/admin/keys":           "",
+	}
+	for path, want := range tests {
+		assert.Equal(t, want, RequiredScope(path), path)
+	}
+}
+
+func TestRequireAdmin_AdminReadScope(t *testing.T) {
+	m := NewAuthMiddleware(&AuthConfig{Logger: zap.NewNop()})
+	handler := m.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method string, scopes ...string) int {
+		ctx := context.WithValue(context.Background(), AuthTypeContextKey, AuthTypeAPIKey)
+		ctx = context.WithValue(ctx, KeyContextKey, &models.Key{Scopes: scopes})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/api/admin/keys", nil).WithContext(ctx))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, models.ScopeAdminRead))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, models.ScopeAdminRead))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, models.ScopeAll))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet))
+}
+
+func TestRequireAdmin_TokenScopes(t *testing.T) {
+	m := NewAuthMiddleware(&AuthConfig{Logger: zap.NewNop()})
+	handler := m.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method string, scopes ...string) int {
+		ctx := context.WithValue(context.Background(), AuthTypeContextKey, AuthTypeJWT)
+		ctx = context.WithValue(ctx, UserContextKey, auth.MasterKeyUserID)
+		if scopes != nil {
+			ctx = context.WithValue(ctx, TokenScopesContextKey, scopes)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/api/admin/keys", nil).WithContext(ctx))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, models.ScopeAll))
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, auth.ScopeAdmin))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, models.ScopeAdminRead))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, models.ScopeAdminRead))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, models.ScopeChat))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost), "tokens without scopes are unrestricted")
+}
+
+func TestAuthenticate_MasterKeyExchange(t *testing.T) {
+	masterKeyService := auth.NewMasterKeyService(&auth.MasterKeyConfig{
+		MasterKey:       "test-master-key",
+		JWTSecret:       []byte("test-jwt-secret"),
+		RequireExchange: true,
+	})
+	authService, err := auth.NewAuthService(&auth.AuthConfig{
+		JWTSecret:        "test-jwt-secret",
+		MasterKeyService: masterKeyService,
+		Logger:           zap.NewNop(),
+	})
+	require.NoError(t, err)
+	m := NewAuthMiddleware(&AuthConfig{
+		Logger:           zap.NewNop(),
+		AuthService:      authService,
+		MasterKeyService: masterKeyService,
+		RequireAuth:      true,
+	})
+	handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path, credentials string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+credentials)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	exchange := func(scopes ...string) string {
+		token, err := masterKeyService.ExchangeMasterKey(context.Background(), "test-master-key", auth.AdminTokenRequest{Scopes: scopes})
+		require.NoError(t, err)
+		return token.Token
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("/v1/chat/completions", "test-master-key"))
+	assert.Equal(t, http.StatusForbidden, serve("/api/admin/keys", "test-master-key"))
+
+	assert.Equal(t, http.StatusOK, serve("/v1/chat/completions", exchange()))
+	assert.Equal(t, http.StatusOK, serve("/v1/chat/completions", exchange(models.ScopeChat)))
+	assert.Equal(t, http.StatusForbidden, serve("/v1/chat/completions", exchange(auth.ScopeAdmin)))
+	assert.Equal(t, http.StatusForbidden, serve("/v1/embeddings", exchange(models.ScopeChat)))
+	assert.Equal(t, http.StatusUnauthorized, serve("/v1/chat/completions", "not-a-token"))
+}
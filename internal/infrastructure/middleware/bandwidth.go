@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// BandwidthContextKey holds the request's *BandwidthMeter
+const BandwidthContextKey ContextKey = "bandwidth_meter"
+
+// BandwidthMeter counts the bytes of a request body as handlers read it and
+// of the response as they write it
+type BandwidthMeter struct {
+	requestBytes atomic.Int64
+	response     *StreamingResponseWriter
+}
+
+// RequestBytes returns the bytes of the request body read so far
+func (m *BandwidthMeter) RequestBytes() int64 {
+	return m.requestBytes.Load()
+}
+
+// ResponseBytes returns the bytes of the response written so far
+func (m *BandwidthMeter) ResponseBytes() int64 {
+	if m.response == nil {
+		return 0
+	}
+	return m.response.BytesWritten()
+}
+
+// GetBandwidthMeter returns the bandwidth meter of the request, when
+// bandwidth metering is enabled
+func GetBandwidthMeter(ctx context.Context) (*BandwidthMeter, bool) {
+	meter, ok := ctx.Value(BandwidthContextKey).(*BandwidthMeter)
+	return meter, ok && meter != nil
+}
+
+// BandwidthMiddleware meters the size of requests and responses for the
+// usage records and rejects the requests of teams over their monthly
+// bandwidth quota
+type BandwidthMiddleware struct {
+	counters *redisService.UsageCounters
+	logger   *zap.Logger
+}
+
+// NewBandwidthMiddleware creates a new bandwidth middleware. Quotas are only
+// enforced with the usage counters, which count teams' monthly bandwidth.
+func NewBandwidthMiddleware(counters *redisService.UsageCounters, logger *zap.Logger) *BandwidthMiddleware {
+	return &BandwidthMiddleware{counters: counters, logger: logger.Named("bandwidth_middleware")}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *BandwidthMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if team := keyTeam(r); team != nil && team.MaxMonthlyBandwidth > 0 && m.counters != nil {
+			used, err := m.counters.MonthlyBandwidth(r.Context(), redisService.UsageScopeTeam, team.ID.String(), time.Now())
+			if err != nil {
+				// Like budgets, quotas fail open when Redis is unavailable
+				m.logger.Warn("Bandwidth quota check failed, allowing request",
+					zap.String("team_id", team.ID.String()),
+					zap.Error(err))
+			} else if used.Total() >= team.MaxMonthlyBandwidth {
+				writeBandwidthQuotaExceeded(w, team.MaxMonthlyBandwidth)
+				return
+			}
+		}
+
+		writer := NewStreamingResponseWriter(w)
+		meter := &BandwidthMeter{response: writer}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &meteredBody{ReadCloser: r.Body, meter: meter}
+		}
+		ctx := context.WithValue(r.Context(), BandwidthContextKey, meter)
+		next.ServeHTTP(writer, r.WithContext(ctx))
+	})
+}
+
+// meteredBody counts the bytes read from a request body
+type meteredBody struct {
+	io.ReadCloser
+	meter *BandwidthMeter
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.meter.requestBytes.Add(int64(n))
+	return n, err
+}
+
+func writeBandwidthQuotaExceeded(w http.ResponseWriter, quota int64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": fmt.Sprintf("Monthly bandwidth quota of %d bytes exceeded. Please contact your administrator.", quota),
+			"type":    "insufficient_quota",
+			"code":    "bandwidth_quota_exceeded",
+		},
+	})
+}
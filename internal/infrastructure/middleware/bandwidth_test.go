@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+func TestBandwidthMiddleware(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	counters := redisService.NewUsageCounters(client, zap.NewNop())
+
+	var meter *BandwidthMeter
+	handler := NewBandwidthMiddleware(counters, zap.NewNop()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+		meter, _ = GetBandwidthMeter(r.Context())
+	}))
+
+	team := &models.Team{MaxMonthlyBandwidth: 100}
+	team.ID = uuid.New()
+	serve := func(body string) *httptest.ResponseRecorder {
+		key := &models.Key{Team: team, TeamID: &team.ID}
+		ctx := context.WithValue(context.Background(), KeyContextKey, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)).WithContext(ctx))
+		return rec
+	}
+
+	t.Run("request and response sizes are metered", func(t *testing.T) {
+		rec := serve(`{"model":"gpt-4o"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, meter)
+		assert.Equal(t, int64(len(`{"model":"gpt-4o"}`)), meter.RequestBytes())
+		assert.Equal(t, int64(len(`{"choices":[]}`)), meter.ResponseBytes())
+	})
+
+	t.Run("teams over their quota are rejected", func(t *testing.T) {
+		require.NoError(t, counters.Add(context.Background(), []*redisService.UsageRecord{
+			{RequestID: "a", Timestamp: time.Now(), TeamID: team.ID.String(), RequestBytes: 80, ResponseBytes: 20},
+		}, func(*redisService.UsageRecord) bool { return true }))
+
+		rec := serve(`{"model":"gpt-4o"}`)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Contains(t, rec.Body.String(), "bandwidth_quota_exceeded")
+
+		team.MaxMonthlyBandwidth = 0
+		assert.Equal(t, http.StatusOK, serve(`{}`).Code, "0 is unlimited")
+	})
+}
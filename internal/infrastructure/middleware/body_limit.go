@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+var bodyLimitRejections = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "pllm_request_body_too_large_total",
+	Help: "Requests rejected because their declared body exceeded the size limit",
+})
+
+// BodyLimitMiddleware caps the size of request bodies, per endpoint. Bodies
+// that declare a larger Content-Length are rejected up front with 413; the
+// others are wrapped so that reading past the limit fails (see
+// BodyTooLarge).
+type BodyLimitMiddleware struct {
+	defaultLimit int64
+	limits       map[string]int64 // path suffix -> limit
+	logger       *zap.Logger
+}
+
+// NewBodyLimitMiddleware creates a new body limit middleware
+func NewBodyLimitMiddleware(cfg config.ServerConfig, logger *zap.Logger) *BodyLimitMiddleware {
+	limits := make(map[string]int64, len(cfg.BodyLimits))
+	for suffix, limit := range cfg.BodyLimits {
+		limits["/"+strings.Trim(suffix, "/")] = limit
+	}
+	return &BodyLimitMiddleware{
+		defaultLimit: cfg.MaxBodyBytes,
+		limits:       limits,
+		logger:       logger.Named("body_limit_middleware"),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *BodyLimitMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := m.Limit(r.URL.Path)
+		if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.ContentLength > limit {
+			bodyLimitRejections.Inc()
+			m.logger.Debug("Request body too large",
+				zap.String("path", r.URL.Path),
+				zap.Int64("content_length", r.ContentLength),
+				zap.Int64("limit", limit))
+			WriteBodyTooLarge(w, limit)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Limit returns the body size limit of a path; the longest matching endpoint
+// suffix wins. 0 means unlimited.
+func (m *BodyLimitMiddleware) Limit(path string) int64 {
+	limit, matched := m.defaultLimit, 0
+	for suffix, endpointLimit := range m.limits {
+		if len(suffix) > matched && strings.HasSuffix(path, suffix) {
+			limit, matched = endpointLimit, len(suffix)
+		}
+	}
+	return limit
+}
+
+// BodyTooLarge reports whether err comes from reading a request body past
+// its size limit, and returns the limit
+func BodyTooLarge(err error) (int64, bool) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return maxBytesErr.Limit, true
+	}
+	return 0, false
+}
+
+// WriteBodyTooLarge writes a 413 error in the OpenAI error format
+func WriteBodyTooLarge(w http.ResponseWriter, limit int64) {
+	writeContextWindowError(w, http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("Request body exceeds the limit of %d bytes", limit), "request_too_large")
+}
+
+// writeBodyReadError answers a request whose body could not be read
+func writeBodyReadError(w http.ResponseWriter, err error) {
+	if limit, ok := BodyTooLarge(err); ok {
+		WriteBodyTooLarge(w, limit)
+		return
+	}
+	writeContextWindowError(w, http.StatusBadRequest, "Failed to read request body", "invalid_request")
+}
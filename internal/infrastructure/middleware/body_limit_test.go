@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func TestBodyLimitMiddleware_Limit(t *testing.T) {
+	m := NewBodyLimitMiddleware(config.ServerConfig{
+		MaxBodyBytes: 100,
+		BodyLimits: map[string]int64{
+			"audio/transcriptions": 1000,
+			"/transcriptions":      500,
+			"/files":               0,
+		},
+	}, zap.NewNop())
+
+	assert.Equal(t, int64(100), m.Limit("/v1/chat/completions"))
+	assert.Equal(t, int64(1000), m.Limit("/v1/audio/transcriptions"), "the longest suffix wins")
+	assert.Equal(t, int64(500), m.Limit("/v1/other/transcriptions"))
+	assert.Equal(t, int64(0), m.Limit("/v1/files"), "0 disables the limit")
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	var readErr error
+	handler := NewBodyLimitMiddleware(config.ServerConfig{MaxBodyBytes: 10}, zap.NewNop()).
+		Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, readErr = io.ReadAll(r.Body); readErr != nil {
+				writeBodyReadError(w, readErr)
+			}
+		}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader("small")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, readErr)
+
+	// A declared length over the limit is rejected before the handler runs
+	readErr = nil
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader("a body that is too large")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), "request_too_large")
+
+	// Bodies of unknown length fail when read past the limit
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader("a body that is too large"))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	limit, ok := BodyTooLarge(readErr)
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), limit)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
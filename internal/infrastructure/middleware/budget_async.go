@@ -8,11 +8,13 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/amerfu/pllm/internal/core/auth"
 	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
 	"github.com/amerfu/pllm/internal/services/data/cache"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
 	redisService "github.com/amerfu/pllm/internal/services/data/redis"
@@ -45,32 +47,51 @@ func EstimateTokens(text string) int {
 type AsyncBudgetMiddleware struct {
 	logger         *zap.Logger
 	authService    *auth.AuthService
-	budgetCache    *redisService.BudgetCache
+	budgetCache    redisService.BudgetCacheBackend
 	eventPub       *redisService.EventPublisher
-	usageQueue     *redisService.UsageQueue
+	usageQueue     redisService.UsageQueueBackend
 	pricingManager *config.ModelPricingManager
 	pricingCache   *cache.PricingCache
+	memoryGuard    *redisService.MemoryGuard
+	markupPercent  float64
+	bandwidthPrice float64 // Per byte
+
+	// How often long streams record their usage so far; 0 disables it
+	checkpointInterval time.Duration
+
+	// Defaults of the routes compared models name; nil when there are none
+	routeDefaults RouteDefaultsApplier
 }
 
 type AsyncBudgetConfig struct {
-	Logger         *zap.Logger
-	AuthService    *auth.AuthService
-	BudgetCache    *redisService.BudgetCache
-	EventPub       *redisService.EventPublisher
-	UsageQueue     *redisService.UsageQueue
-	PricingManager *config.ModelPricingManager
-	PricingCache   *cache.PricingCache
+	Logger                   *zap.Logger
+	AuthService              *auth.AuthService
+	BudgetCache              redisService.BudgetCacheBackend
+	EventPub                 *redisService.EventPublisher // Optional, nil when Redis is not used
+	UsageQueue               redisService.UsageQueueBackend
+	PricingManager           *config.ModelPricingManager
+	PricingCache             *cache.PricingCache
+	MemoryGuard              *redisService.MemoryGuard // Optional, sheds usage events under Redis pressure
+	MarkupPercent            float64                   // Added to the provider cost of every request
+	BandwidthPricePerGB      float64                   // Added for the request and response bytes of priced requests
+	StreamCheckpointInterval time.Duration             // How often long streams record their usage so far; 0 disables it
+	RouteDefaults            RouteDefaultsApplier      // Optional, applied to each model of a comparison
 }
 
 func NewAsyncBudgetMiddleware(cfg *AsyncBudgetConfig) *AsyncBudgetMiddleware {
 	return &AsyncBudgetMiddleware{
-		logger:         cfg.Logger,
-		authService:    cfg.AuthService,
-		budgetCache:    cfg.BudgetCache,
-		eventPub:       cfg.EventPub,
-		usageQueue:     cfg.UsageQueue,
-		pricingManager: cfg.PricingManager,
-		pricingCache:   cfg.PricingCache,
+		logger:             cfg.Logger,
+		authService:        cfg.AuthService,
+		budgetCache:        cfg.BudgetCache,
+		eventPub:           cfg.EventPub,
+		usageQueue:         cfg.UsageQueue,
+		pricingManager:     cfg.PricingManager,
+		pricingCache:       cfg.PricingCache,
+		memoryGuard:        cfg.MemoryGuard,
+		markupPercent:      cfg.MarkupPercent,
+		bandwidthPrice:     cfg.BandwidthPricePerGB / 1e9,
+		checkpointInterval: cfg.StreamCheckpointInterval,
+		routeDefaults:      cfg.RouteDefaults,
 	}
 }
 
@@ -78,29 +99,59 @@ func NewAsyncBudgetMiddleware(cfg *AsyncBudgetConfig) *AsyncBudgetMiddleware {
 func (m *AsyncBudgetMiddleware) EnforceBudgetAsync(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Only apply to LLM endpoints
-		if !m.isLLMEndpoint(r.URL.Path) {
+		meteredPath, metered := meteredEndpointPath(r.URL.Path)
+		if !m.isLLMEndpoint(r.URL.Path) && !metered && !m.isCacheWrite(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Check if master key is being used (bypasses budget checks)
-		if IsMasterKey(r.Context()) {
-			next.ServeHTTP(w, r)
-			return
-		}
+		// The master key bypasses budgets, but not the per-request cost ceiling
+		masterKey := IsMasterKey(r.Context())
 
 		// Get user/key context from authentication
 		userID, hasUser := GetUserID(r.Context())
 		key, hasKey := GetKey(r.Context())
 
-		if !hasUser && !hasKey {
+		if !masterKey && !hasUser && !hasKey {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
+		var entityType, entityID string
+		if masterKey {
+			entityType = "master_key"
+		} else if hasKey && key != nil {
+			entityType = "key"
+			entityID = key.ID.String()
+		} else if hasUser {
+			entityType = "user"
+			entityID = userID.String()
+		}
+
+		// Transcriptions, speech, image generations and reranks are billed by
+		// the units the handler records (audio duration, characters, images,
+		// searches) and cache
+		// writes are priced by the cache service, so there is nothing to
+		// estimate up-front; only exhausted budgets are rejected
+		if metered || m.isCacheWrite(r) {
+			if masterKey {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !metered {
+				meteredPath = "/caches"
+			}
+			m.enforceMeteredBudget(w, r, next, meteredPath, entityType, entityID)
+			return
+		}
+
 		// Read and parse request body
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
+			if limit, ok := BodyTooLarge(err); ok {
+				WriteBodyTooLarge(w, limit)
+				return
+			}
 			m.logger.Error("Failed to read request body", zap.Error(err))
 			http.Error(w, "Invalid request", http.StatusBadRequest)
 			return
@@ -114,17 +165,58 @@ func (m *AsyncBudgetMiddleware) EnforceBudgetAsync(next http.Handler) http.Handl
 			return
 		}
 
-		// Estimate cost for this request
-		estimatedCost := m.estimateCost(&chatRequest)
+		// Comparison requests run once per model, so every call is budgeted
+		// with the defaults of the route it names. Other requests already
+		// carry theirs from the route defaults middleware.
+		modelRequests := []providers.ChatRequest{chatRequest}
+		if m.isCompareEndpoint(r.URL.Path) {
+			modelRequests = compareModelRequests(body, chatRequest)
+			if m.routeDefaults != nil {
+				for i := range modelRequests {
+					m.routeDefaults.ApplyRouteDefaults(&modelRequests[i])
+				}
+			}
+		}
 
-		// Fast budget check using Redis cache
-		var entityType, entityID string
-		if hasKey && key != nil {
-			entityType = "key"
-			entityID = key.ID.String()
-		} else if hasUser {
-			entityType = "user"
-			entityID = userID.String()
+		// Reject up-front if the worst-case cost exceeds the per-request
+		// ceiling. A comparison is one request, so its calls count together.
+		if ceiling, ok := m.maxCostCeiling(&chatRequest, key); ok {
+			worstCaseCost := 0.0
+			for i := range modelRequests {
+				worstCaseCost += m.estimateWorstCaseCost(&modelRequests[i])
+			}
+			if worstCaseCost > ceiling {
+				m.logger.Warn("Request rejected due to per-request cost ceiling",
+					zap.String("entity", fmt.Sprintf("%s:%s", entityType, entityID)),
+					zap.Float64("worst_case_cost", worstCaseCost),
+					zap.Float64("max_cost", ceiling),
+					zap.String("model", chatRequest.Model))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
+					Error: providers.APIError{
+						Message: fmt.Sprintf("Worst-case cost $%.6f exceeds the per-request limit of $%.6f. Reduce max_tokens or the prompt size.", worstCaseCost, ceiling),
+						Type:    "invalid_request_error",
+						Param:   "max_cost",
+						Code:    "max_cost_exceeded",
+					},
+				}); err != nil {
+					log.Printf("Failed to encode max cost error response: %v", err)
+				}
+				return
+			}
+		}
+
+		if masterKey {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Estimate cost for this request
+		estimatedCost := 0.0
+		for i := range modelRequests {
+			estimatedCost += m.estimateCost(&modelRequests[i])
 		}
 
 		// Non-blocking budget check with Redis
@@ -143,17 +235,7 @@ func (m *AsyncBudgetMiddleware) EnforceBudgetAsync(next http.Handler) http.Handl
 				zap.Float64("estimated_cost", estimatedCost),
 				zap.String("model", chatRequest.Model))
 
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
-				Error: providers.APIError{
-					Message: "Budget limit exceeded. Please contact your administrator or upgrade your plan.",
-					Type:    "insufficient_quota",
-					Code:    "budget_exceeded",
-				},
-			}); err != nil {
-				log.Printf("Failed to encode budget error response: %v", err)
-			}
+			writeBudgetExceeded(w)
 			return
 		}
 
@@ -161,16 +243,136 @@ func (m *AsyncBudgetMiddleware) EnforceBudgetAsync(next http.Handler) http.Handl
 		wrappedWriter := NewStreamingResponseWriter(w)
 		startTime := time.Now()
 
+		// Long streams record their usage while they run
+		if chatRequest.Stream && m.checkpointInterval > 0 && !m.isCompareEndpoint(r.URL.Path) && !IsSynthetic(r.Context()) {
+			checkpointer := &StreamCheckpointer{
+				middleware: m,
+				request:    chatRequest,
+				entityType: entityType,
+				entityID:   entityID,
+				interval:   m.checkpointInterval,
+				last:       startTime,
+			}
+			r = r.WithContext(context.WithValue(r.Context(), streamCheckpointKey, checkpointer))
+			checkpointer.ctx = r.Context()
+		}
+
 		// Process the request
 		next.ServeHTTP(wrappedWriter, r)
 
+		// Synthetic monitoring requests pass the budget check but are not billed
+		if IsSynthetic(r.Context()) {
+			return
+		}
+
 		// Asynchronously track usage - this is completely non-blocking
-		go m.trackUsageAsync(r.Context(), chatRequest, wrappedWriter, estimatedCost, entityType, entityID, startTime)
+		if m.isCompareEndpoint(r.URL.Path) {
+			m.trackModelCallsAsync(r.Context(), modelRequests, wrappedWriter, entityType, entityID, startTime)
+			return
+		}
+		usagePath := "/chat/completions"
+		if strings.HasSuffix(r.URL.Path, "/embeddings") {
+			usagePath = "/embeddings" // Embeddings generate no output tokens
+		}
+		go m.trackUsageAsync(r.Context(), chatRequest, usagePath, wrappedWriter, estimatedCost, entityType, entityID, startTime)
 	})
 }
 
+// enforceMeteredBudget rejects requests from entities whose cached budget is
+// exhausted and tracks the usage the handler recorded
+func (m *AsyncBudgetMiddleware) enforceMeteredBudget(w http.ResponseWriter, r *http.Request, next http.Handler,
+	path, entityType, entityID string) {
+
+	budgetOk, err := m.budgetCache.CheckBudgetAvailable(r.Context(), entityType, entityID, 0)
+	if err != nil {
+		m.logger.Warn("Budget cache check failed, allowing request",
+			zap.Error(err),
+			zap.String("entity", fmt.Sprintf("%s:%s", entityType, entityID)))
+		budgetOk = true
+	}
+	if !budgetOk {
+		m.logger.Warn("Request rejected due to cached budget limit",
+			zap.String("entity", fmt.Sprintf("%s:%s", entityType, entityID)),
+			zap.String("path", r.URL.Path))
+		writeBudgetExceeded(w)
+		return
+	}
+
+	wrappedWriter := NewStreamingResponseWriter(w)
+	startTime := time.Now()
+
+	next.ServeHTTP(wrappedWriter, r)
+
+	request := providers.ChatRequest{}
+	if metricsCtx := GetMetricsContext(r.Context()); metricsCtx != nil {
+		request.Model = metricsCtx.ModelName
+	}
+	go m.trackUsageAsync(r.Context(), request, path, wrappedWriter, 0, entityType, entityID, startTime)
+}
+
+func writeBudgetExceeded(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: "Budget limit exceeded. Please contact your administrator or upgrade your plan.",
+			Type:    "insufficient_quota",
+			Code:    "budget_exceeded",
+		},
+	}); err != nil {
+		log.Printf("Failed to encode budget error response: %v", err)
+	}
+}
+
+// trackModelCallsAsync records one usage record per model call made by a
+// comparison request, each under its own request ID
+func (m *AsyncBudgetMiddleware) trackModelCallsAsync(ctx context.Context, modelRequests []providers.ChatRequest,
+	writer *StreamingResponseWriter, entityType, entityID string, startTime time.Time) {
+
+	metricsCtx := GetMetricsContext(ctx)
+	if metricsCtx == nil {
+		return
+	}
+
+	baseRequestID := metricsCtx.RequestID
+	if baseRequestID == "" {
+		baseRequestID = fmt.Sprintf("req_%d", time.Now().UnixNano())
+	}
+
+	for i, call := range metricsCtx.ModelCalls {
+		request := modelRequests[0]
+		for _, modelRequest := range modelRequests {
+			if modelRequest.Model == call.RequestedModel {
+				request = modelRequest
+				break
+			}
+		}
+
+		callMetrics := *metricsCtx
+		callMetrics.ModelCalls = nil
+		callMetrics.RequestID = fmt.Sprintf("%s-%d", baseRequestID, i)
+		callMetrics.ModelName = call.RequestedModel
+		callMetrics.ResolvedModel = call.ResolvedModel
+		callMetrics.ProviderModel = call.ProviderModel
+		callMetrics.ProviderType = call.ProviderType
+		callMetrics.RouteSlug = call.RouteSlug
+		callMetrics.PromptTokens = call.PromptTokens
+		callMetrics.CompletionTokens = call.CompletionTokens
+		callMetrics.ReasoningTokens = call.ReasoningTokens
+		callMetrics.CachedTokens = call.CachedTokens
+		callMetrics.ContentHash = ""
+
+		callCtx := context.WithValue(ctx, MetricsContextKey, &callMetrics)
+		if i > 0 {
+			// The bytes of the request are counted once, with the first call
+			callCtx = context.WithValue(callCtx, BandwidthContextKey, (*BandwidthMeter)(nil))
+		}
+		go m.trackUsageAsync(callCtx, request, "/chat/completions", writer, m.estimateCost(&request), entityType, entityID, startTime)
+	}
+}
+
 // trackUsageAsync records usage asynchronously using Redis queue
-func (m *AsyncBudgetMiddleware) trackUsageAsync(ctx context.Context, request providers.ChatRequest,
+func (m *AsyncBudgetMiddleware) trackUsageAsync(ctx context.Context, request providers.ChatRequest, path string,
 	writer *StreamingResponseWriter, estimatedCost float64, entityType, entityID string, startTime time.Time) {
 
 	defer func() {
@@ -184,25 +386,46 @@ func (m *AsyncBudgetMiddleware) trackUsageAsync(ctx context.Context, request pro
 		return
 	}
 
+	// Gateway-managed context caches are free; only provider caches are billed
+	if path == "/caches" {
+		if metricsCtx := GetMetricsContext(ctx); metricsCtx == nil || (metricsCtx.CacheWriteTokens == 0 && metricsCtx.CacheCost == 0) {
+			return
+		}
+	}
+
 	latency := time.Since(startTime)
 	var actualCost float64
-	var inputTokens, outputTokens int
+	var inputTokens, outputTokens, reasoningTokens, cachedTokens int
 
-	// For all responses, use estimates since we use a streaming-compatible writer
-	// The background worker can reconcile actual usage from provider responses later
+	// Start from estimates; streaming responses never report usage back, so
+	// these stand unless the handler recorded the provider's usage below
 	actualCost = estimatedCost
 	inputTokens = m.estimateInputTokens(request.Messages)
 	outputTokens = 150 // Default estimate - will be reconciled by worker
+	audioSeconds := 0.0
+	var images, characters, searches int
+	if path != "/chat/completions" {
+		outputTokens = 0 // Only provider-reported usage is billed
+	}
 
 	// Read resolved model info from MetricsContext (set by chat handler after route resolution)
 	metricsCtx := GetMetricsContext(ctx)
+	reportedUsage := false
 
 	actualModel := request.Model
 	actualProvider := "pllm-gateway"
 	routeSlug := ""
 	providerModel := ""
+	contentHash := ""
+	var ttft time.Duration
+	requestID := fmt.Sprintf("req_%d", time.Now().UnixNano())
 
 	if metricsCtx != nil {
+		if metricsCtx.RequestID != "" {
+			requestID = metricsCtx.RequestID
+		}
+		contentHash = metricsCtx.ContentHash
+		ttft = metricsCtx.TimeToFirstToken
 		if metricsCtx.ResolvedModel != "" {
 			actualModel = metricsCtx.ResolvedModel
 		}
@@ -211,38 +434,176 @@ func (m *AsyncBudgetMiddleware) trackUsageAsync(ctx context.Context, request pro
 		}
 		routeSlug = metricsCtx.RouteSlug
 		providerModel = metricsCtx.ProviderModel
+
+		if metricsCtx.PromptTokens > 0 || metricsCtx.CompletionTokens > 0 {
+			inputTokens = metricsCtx.PromptTokens
+			outputTokens = metricsCtx.CompletionTokens
+			reasoningTokens = metricsCtx.ReasoningTokens
+			cachedTokens = metricsCtx.CachedTokens
+			reportedUsage = true
+		}
+		audioSeconds = metricsCtx.AudioSeconds
+		images = metricsCtx.ImagesGenerated
+		characters = metricsCtx.Characters
+		searches = metricsCtx.SearchQueries
+		if path == "/caches" {
+			inputTokens = metricsCtx.CacheWriteTokens
+		}
 	}
 
-	// Recalculate cost using the provider model ID for accurate pricing
-	if providerModel != "" && m.pricingCache != nil {
-		costCtx, costCancel := context.WithTimeout(context.Background(), 1*time.Second)
-		defer costCancel()
-		if calc, err := m.pricingCache.CalculateCost(costCtx, providerModel, inputTokens, outputTokens); err == nil {
-			actualCost = calc.TotalCost
+	// A checkpointed stream without reported usage is billed for the tokens
+	// it streamed, which its checkpoints were computed from
+	checkpointer := GetStreamCheckpointer(ctx)
+	var checkpointed checkpointedUsage
+	checkpoints := 0
+	if checkpointer != nil {
+		var streamed int
+		streamed, checkpointed, checkpoints = checkpointer.finish()
+		if !reportedUsage {
+			outputTokens = streamed
 		}
 	}
 
-	// Get the actual user who made the request from context
-	actualUserID, hasUser := GetUserID(ctx)
+	// Recalculate cost line by line using the provider model ID for accurate
+	// pricing; the breakdown is stored with the usage record
+	var breakdown *config.CostBreakdown
+	if path == "/caches" && metricsCtx != nil {
+		// Cache writes and storage are priced by the context cache service
+		breakdown = m.contextCacheBreakdown(actualModel, metricsCtx)
+	} else if providerModel != "" {
+		// Image generations are priced by size and quality
+		pricingModel := providerModel
+		var pricing *config.ModelPricingInfo
+		var imagePixels int
+		if images > 0 {
+			pricingModel, pricing = m.getImagePricing(providerModel, metricsCtx.ImageSize, metricsCtx.ImageQuality)
+			imagePixels = images * imageSizePixels(metricsCtx.ImageSize)
+		} else {
+			pricing = m.getPricing(providerModel)
+		}
+		if pricing != nil {
+			breakdown = config.NewCostBreakdown(pricingModel, pricing, config.CostUsage{
+				InputTokens:       inputTokens,
+				CachedInputTokens: cachedTokens,
+				OutputTokens:      outputTokens,
+				ReasoningTokens:   reasoningTokens,
+				InputImages:       countInputImages(request.Messages),
+				AudioSeconds:      audioSeconds,
+				OutputImages:      images,
+				ImagePixels:       imagePixels,
+				InputCharacters:   characters,
+				SearchQueries:     searches,
+			}, m.markupPercent)
+			breakdown.Estimated = !reportedUsage && audioSeconds == 0 && images == 0 && characters == 0 && searches == 0
+		}
+	}
+	var requestBytes, responseBytes int64
+	if meter, ok := GetBandwidthMeter(ctx); ok {
+		requestBytes = meter.RequestBytes()
+		responseBytes = meter.ResponseBytes()
+	}
+	if breakdown != nil && m.bandwidthPrice > 0 {
+		breakdown.Add(config.CostBandwidth, float64(requestBytes+responseBytes), m.bandwidthPrice)
+		breakdown.ApplyMarkup(m.markupPercent)
+	}
+	if breakdown != nil {
+		actualCost = breakdown.TotalCost
+	}
+
+	// The final record of a checkpointed stream carries the usage its
+	// checkpoints did not record
+	if checkpoints > 0 {
+		inputTokens -= checkpointed.inputTokens
+		outputTokens -= checkpointed.outputTokens
+		actualCost -= checkpointed.cost
+		if breakdown != nil {
+			breakdown.Checkpointed = checkpointed.cost
+		}
+	}
 
 	// Create usage record for queue processing
 	usageRecord := &redisService.UsageRecord{
-		RequestID:     fmt.Sprintf("req_%d", time.Now().UnixNano()),
-		Timestamp:     startTime,
-		Model:         actualModel,
-		Provider:      actualProvider,
-		RouteSlug:     routeSlug,
-		ProviderModel: providerModel,
-		Method:       "POST",
-		Path:         "/chat/completions",
-		StatusCode:   writer.statusCode,
-		InputTokens:  inputTokens,
-		OutputTokens: outputTokens,
-		TotalTokens:  inputTokens + outputTokens,
-		TotalCost:    actualCost,
-		Latency:      latency.Milliseconds(),
-	}
-	
+		RequestID:       requestID,
+		Timestamp:       startTime,
+		Model:           actualModel,
+		Provider:        actualProvider,
+		RouteSlug:       routeSlug,
+		ProviderModel:   providerModel,
+		EndpointType:    models.EndpointTypeForPath(path),
+		Method:          "POST",
+		Path:            path,
+		StatusCode:      writer.statusCode,
+		InputTokens:     inputTokens,
+		OutputTokens:    outputTokens,
+		ReasoningTokens: reasoningTokens,
+		TotalTokens:     inputTokens + outputTokens,
+		TotalCost:       actualCost,
+		CostBreakdown:   breakdown,
+		AudioSeconds:    audioSeconds,
+		ImagesGenerated: images,
+		Characters:      characters,
+		RequestBytes:    requestBytes,
+		ResponseBytes:   responseBytes,
+		Latency:         latency.Milliseconds(),
+		TTFT:            ttft.Milliseconds(),
+		ContentHash:     contentHash,
+	}
+	if metricsCtx != nil {
+		usageRecord.TokensPerSecond = metricsCtx.TokensPerSecond
+		usageRecord.MaxChunkGap = metricsCtx.MaxChunkGap.Milliseconds()
+		usageRecord.Stalled = metricsCtx.StreamStalled
+		usageRecord.ProviderKeyID = metricsCtx.ProviderKeyID
+	}
+	m.setUsageOwner(ctx, usageRecord, entityType, entityID)
+
+	// Usage records are billing data: always queued as-is, whatever the
+	// Redis pressure level. Only analytics are shed.
+	if err := m.usageQueue.EnqueueUsage(context.Background(), usageRecord); err != nil {
+		m.logger.Error("Failed to enqueue usage record",
+			zap.Error(err),
+			zap.String("entity", fmt.Sprintf("%s:%s", entityType, entityID)))
+		return
+	}
+
+	// Asynchronously increment cached budget spent amount (always exact)
+	go m.updateBudgetCacheAsync(entityType, entityID, actualCost)
+
+	// Publish usage event for real-time monitoring (optional, shed under pressure)
+	if m.eventPub != nil && !m.memoryGuard.ShedAnalytics() {
+		go func() {
+			if err := m.eventPub.PublishUsageEvent(context.Background(),
+				usageRecord.UserID,
+				usageRecord.KeyID,
+				request.Model,
+				inputTokens,
+				outputTokens,
+				actualCost,
+				latency); err != nil {
+				log.Printf("Failed to publish usage event: %v", err)
+			}
+		}()
+	}
+
+	m.logger.Debug("Usage tracked asynchronously",
+		zap.String("entity", fmt.Sprintf("%s:%s", entityType, entityID)),
+		zap.Float64("cost", actualCost),
+		zap.Int("tokens", inputTokens+outputTokens),
+		zap.Duration("latency", latency))
+}
+
+// setUsageOwner sets who a usage record is billed to: the entity, the
+// owner and team of its key, the user who made the request and the client
+func (m *AsyncBudgetMiddleware) setUsageOwner(ctx context.Context, usageRecord *redisService.UsageRecord, entityType, entityID string) {
+	// Get the actual user who made the request from context
+	actualUserID, hasUser := GetUserID(ctx)
+
+	if client, ok := GetClientInfo(ctx); ok {
+		usageRecord.Country = client.Location.Country
+		usageRecord.Continent = client.Location.Continent
+		usageRecord.ClientName = client.Client.Name
+		usageRecord.ClientVersion = client.Client.Version
+	}
+
 	// Set ActualUserID only if user exists (not for system keys)
 	if hasUser {
 		usageRecord.ActualUserID = actualUserID.String()
@@ -256,6 +617,7 @@ func (m *AsyncBudgetMiddleware) trackUsageAsync(ctx context.Context, request pro
 			// Get key ownership details
 			if key, hasKey := GetKey(ctx); hasKey {
 				usageRecord.KeyType = string(key.Type) // Set key type
+				usageRecord.Environment = key.Environment
 
 				// Set key owner (for personal keys)
 				if key.UserID != nil {
@@ -284,37 +646,22 @@ func (m *AsyncBudgetMiddleware) trackUsageAsync(ctx context.Context, request pro
 		// For direct user requests, ActualUserID is the same as UserID
 		usageRecord.ActualUserID = entityID
 	}
+}
 
-	// Enqueue for batch processing - this is fire-and-forget
-	if err := m.usageQueue.EnqueueUsage(context.Background(), usageRecord); err != nil {
-		m.logger.Error("Failed to enqueue usage record",
-			zap.Error(err),
-			zap.String("entity", fmt.Sprintf("%s:%s", entityType, entityID)))
-		return
+// contextCacheBreakdown itemizes a context cache charge priced by the cache service
+func (m *AsyncBudgetMiddleware) contextCacheBreakdown(model string, metricsCtx *MetricsContext) *config.CostBreakdown {
+	breakdown := &config.CostBreakdown{
+		Model:    model,
+		Currency: "USD",
+		Items: []config.CostLineItem{{
+			Component: config.CostContextCache,
+			Quantity:  float64(metricsCtx.CacheWriteTokens),
+			Cost:      metricsCtx.CacheCost,
+		}},
+		Subtotal: metricsCtx.CacheCost,
 	}
-
-	// Asynchronously increment cached budget spent amount
-	go m.updateBudgetCacheAsync(entityType, entityID, actualCost)
-
-	// Publish usage event for real-time monitoring (optional)
-	go func() {
-		if err := m.eventPub.PublishUsageEvent(context.Background(),
-			usageRecord.UserID,
-			usageRecord.KeyID,
-			request.Model,
-			inputTokens,
-			outputTokens,
-			actualCost,
-			latency); err != nil {
-			log.Printf("Failed to publish usage event: %v", err)
-		}
-	}()
-
-	m.logger.Debug("Usage tracked asynchronously",
-		zap.String("entity", fmt.Sprintf("%s:%s", entityType, entityID)),
-		zap.Float64("cost", actualCost),
-		zap.Int("tokens", inputTokens+outputTokens),
-		zap.Duration("latency", latency))
+	breakdown.ApplyMarkup(m.markupPercent)
+	return breakdown
 }
 
 // updateBudgetCacheAsync updates the cached budget spending
@@ -338,22 +685,151 @@ func (m *AsyncBudgetMiddleware) isLLMEndpoint(path string) bool {
 		strings.Contains(path, "/embeddings")
 }
 
+// meteredEndpointPath returns the usage path of endpoints billed by the
+// units their handler records rather than by estimated tokens
+func meteredEndpointPath(path string) (string, bool) {
+	for _, metered := range []string{"/audio/transcriptions", "/audio/speech", "/images/generations", "/rerank"} {
+		if strings.HasSuffix(path, metered) {
+			return metered, true
+		}
+	}
+	return "", false
+}
+
+// isCacheWrite reports whether the request creates or extends a context cache
+func (m *AsyncBudgetMiddleware) isCacheWrite(r *http.Request) bool {
+	if !strings.Contains(r.URL.Path, "/v1/caches") {
+		return false
+	}
+	return r.Method == http.MethodPost || r.Method == http.MethodPatch
+}
+
+func (m *AsyncBudgetMiddleware) isCompareEndpoint(path string) bool {
+	return strings.HasSuffix(path, "/chat/completions/compare")
+}
+
+// compareModelRequests expands a comparison request into one chat request per model
+func compareModelRequests(body []byte, request providers.ChatRequest) []providers.ChatRequest {
+	var compare struct {
+		Models []string `json:"models"`
+	}
+	if err := json.Unmarshal(body, &compare); err != nil || len(compare.Models) == 0 {
+		return []providers.ChatRequest{request}
+	}
+
+	requests := make([]providers.ChatRequest, 0, len(compare.Models))
+	for _, model := range compare.Models {
+		modelRequest := request
+		modelRequest.Model = model
+		requests = append(requests, modelRequest)
+	}
+	return requests
+}
+
 func (m *AsyncBudgetMiddleware) estimateCost(request *providers.ChatRequest) float64 {
+	return m.calculateCost(request.Model,
+		m.estimateInputTokens(request.Messages),
+		m.estimateOutputTokens(request))
+}
+
+// estimateWorstCaseCost prices the request as if the model generated its full
+// output allowance: max_tokens when set, otherwise the model's output limit.
+func (m *AsyncBudgetMiddleware) estimateWorstCaseCost(request *providers.ChatRequest) float64 {
+	outputTokens := 0
+	if request.MaxTokens != nil && *request.MaxTokens > 0 {
+		outputTokens = *request.MaxTokens
+	} else if pricing := m.getPricing(request.Model); pricing != nil && pricing.MaxOutputTokens > 0 {
+		outputTokens = pricing.MaxOutputTokens
+	} else {
+		outputTokens = m.estimateOutputTokens(request)
+	}
+
+	return m.calculateCost(request.Model, m.estimateInputTokens(request.Messages), outputTokens)
+}
+
+// maxCostCeiling returns the effective per-request cost limit, which is the
+// lower of the request's max_cost and the key's max_cost_per_request.
+func (m *AsyncBudgetMiddleware) maxCostCeiling(request *providers.ChatRequest, key *models.Key) (float64, bool) {
+	ceiling := 0.0
+	found := false
+
+	if request.MaxCost != nil && *request.MaxCost > 0 {
+		ceiling = *request.MaxCost
+		found = true
+	}
+	if key != nil && key.MaxCostPerRequest != nil && *key.MaxCostPerRequest > 0 {
+		if !found || *key.MaxCostPerRequest < ceiling {
+			ceiling = *key.MaxCostPerRequest
+		}
+		found = true
+	}
+
+	return ceiling, found
+}
+
+func (m *AsyncBudgetMiddleware) getPricing(model string) *config.ModelPricingInfo {
+	if m.pricingCache != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return m.pricingCache.GetPricing(ctx, model)
+	}
+	if m.pricingManager != nil {
+		return m.pricingManager.GetPricing(model)
+	}
+	return nil
+}
+
+// getImagePricing returns the pricing of an image model and the name it was
+// found under. Image prices are keyed by quality and size, as in
+// "hd/1024-x-1792/dall-e-3", so those are tried before the size alone and
+// the bare model. The quality defaults to standard, as it does upstream.
+func (m *AsyncBudgetMiddleware) getImagePricing(model, size, quality string) (string, *config.ModelPricingInfo) {
+	var candidates []string
+	if size != "" {
+		sizeKey := strings.Replace(size, "x", "-x-", 1)
+		if quality == "" {
+			quality = "standard"
+		}
+		candidates = append(candidates, quality+"/"+sizeKey+"/"+model, sizeKey+"/"+model)
+	}
+	candidates = append(candidates, model)
+
+	for _, name := range candidates {
+		if pricing := m.getPricing(name); pricing != nil {
+			return name, pricing
+		}
+	}
+	return model, nil
+}
+
+// imageSizePixels returns the pixel count of an image size such as
+// "1024x1792", or zero for sizes like "auto"
+func imageSizePixels(size string) int {
+	width, height, ok := strings.Cut(size, "x")
+	if !ok {
+		return 0
+	}
+	w, errW := strconv.Atoi(width)
+	h, errH := strconv.Atoi(height)
+	if errW != nil || errH != nil {
+		return 0
+	}
+	return w * h
+}
+
+func (m *AsyncBudgetMiddleware) calculateCost(model string, inputTokens, outputTokens int) float64 {
 	// Try to use cached pricing first for better performance
 	if m.pricingCache != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 		defer cancel()
 
-		calculation, err := m.pricingCache.CalculateCost(ctx, request.Model, 
-			m.estimateInputTokens(request.Messages), 
-			m.estimateOutputTokens(request))
-		
+		calculation, err := m.pricingCache.CalculateCost(ctx, model, inputTokens, outputTokens)
 		if err == nil {
 			return calculation.TotalCost
 		}
 
 		m.logger.Warn("Failed to calculate cost using pricing cache, falling back to pricing manager", 
-			zap.String("model", request.Model),
+			zap.String("model", model),
 			zap.Error(err))
 	}
 
@@ -364,13 +840,10 @@ func (m *AsyncBudgetMiddleware) estimateCost(request *providers.ChatRequest) flo
 	}
 
 	// Use our pricing manager to calculate cost
-	calculation, err := m.pricingManager.CalculateCost(request.Model, 
-		m.estimateInputTokens(request.Messages), 
-		m.estimateOutputTokens(request))
-	
+	calculation, err := m.pricingManager.CalculateCost(model, inputTokens, outputTokens)
 	if err != nil {
 		m.logger.Warn("Failed to calculate cost using pricing manager", 
-			zap.String("model", request.Model),
+			zap.String("model", model),
 			zap.Error(err))
 		return 0.01 // Conservative default
 	}
@@ -395,3 +868,20 @@ func (m *AsyncBudgetMiddleware) estimateInputTokens(messages []providers.Message
 	}
 	return tokens
 }
+
+// countInputImages counts the image parts of multimodal messages
+func countInputImages(messages []providers.Message) int {
+	images := 0
+	for _, msg := range messages {
+		parts, ok := msg.Content.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, part := range parts {
+			if p, ok := part.(map[string]interface{}); ok && p["type"] == "image_url" {
+				images++
+			}
+		}
+	}
+	return images
+}
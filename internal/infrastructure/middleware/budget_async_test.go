@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func newMaxCostTestMiddleware(t *testing.T) *AsyncBudgetMiddleware {
+	t.Helper()
+
+	pricingManager := config.GetPricingManager()
+	pricingManager.RegisterModel("max-cost-test-model", &config.ModelPricingInfo{
+		MaxOutputTokens:    1000,
+		InputCostPerToken:  0.00001,
+		OutputCostPerToken: 0.0001,
+	})
+
+	return NewAsyncBudgetMiddleware(&AsyncBudgetConfig{
+		Logger:         zap.NewNop(),
+		PricingManager: pricingManager,
+	})
+}
+
+func TestAsyncBudgetMiddleware_MaxCostCeiling(t *testing.T) {
+	m := newMaxCostTestMiddleware(t)
+
+	requestLimit := 0.5
+	keyLimit := 0.2
+
+	t.Run("no limits", func(t *testing.T) {
+		_, ok := m.maxCostCeiling(&providers.ChatRequest{}, nil)
+		assert.False(t, ok)
+	})
+
+	t.Run("request limit only", func(t *testing.T) {
+		ceiling, ok := m.maxCostCeiling(&providers.ChatRequest{MaxCost: &requestLimit}, nil)
+		require.True(t, ok)
+		assert.Equal(t, requestLimit, ceiling)
+	})
+
+	t.Run("lower of request and key limits", func(t *testing.T) {
+		key := &models.Key{MaxCostPerRequest: &keyLimit}
+		ceiling, ok := m.maxCostCeiling(&providers.ChatRequest{MaxCost: &requestLimit}, key)
+		require.True(t, ok)
+		assert.Equal(t, keyLimit, ceiling)
+	})
+}
+
+func TestAsyncBudgetMiddleware_EstimateWorstCaseCost(t *testing.T) {
+	m := newMaxCostTestMiddleware(t)
+
+	messages := []providers.Message{{Role: "user", Content: strings.Repeat("a", 400)}} // ~100 tokens
+
+	// Without max_tokens the model's output limit is used
+	cost := m.estimateWorstCaseCost(&providers.ChatRequest{Model: "max-cost-test-model", Messages: messages})
+	assert.InDelta(t, 100*0.00001+1000*0.0001, cost, 1e-9)
+
+	maxTokens := 10
+	cost = m.estimateWorstCaseCost(&providers.ChatRequest{Model: "max-cost-test-model", Messages: messages, MaxTokens: &maxTokens})
+	assert.InDelta(t, 100*0.00001+10*0.0001, cost, 1e-9)
+}
+
+func TestAsyncBudgetMiddleware_RejectsOverMaxCost(t *testing.T) {
+	m := newMaxCostTestMiddleware(t)
+
+	keyLimit := 0.01
+	key := &models.Key{BaseModel: models.BaseModel{ID: uuid.New()}, MaxCostPerRequest: &keyLimit}
+
+	body, err := json.Marshal(providers.ChatRequest{
+		Model:    "max-cost-test-model",
+		Messages: []providers.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), KeyContextKey, key))
+	rec := httptest.NewRecorder()
+
+	called := false
+	m.EnforceBudgetAsync(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})).ServeHTTP(rec, req)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp providers.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "max_cost_exceeded", resp.Error.Code)
+}
+
+func TestCountInputImages(t *testing.T) {
+	messages := []providers.Message{
+		{Role: "system", Content: "You describe images"},
+		{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "Compare these"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png"}},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/b.png"}},
+		}},
+	}
+	assert.Equal(t, 2, countInputImages(messages))
+}
+
+func TestAsyncBudgetMiddleware_ImagePricing(t *testing.T) {
+	pricingManager := config.GetPricingManager()
+	pricingManager.RegisterModel("hd/1024-x-1792/image-test-model", &config.ModelPricingInfo{InputCostPerPixel: 6e-8})
+	pricingManager.RegisterModel("standard/1024-x-1024/image-test-model", &config.ModelPricingInfo{InputCostPerPixel: 4e-8})
+	pricingManager.RegisterModel("image-test-model", &config.ModelPricingInfo{OutputCostPerImage: 0.04})
+	m := NewAsyncBudgetMiddleware(&AsyncBudgetConfig{Logger: zap.NewNop(), PricingManager: pricingManager})
+
+	tests := []struct {
+		size, quality, want string
+	}{
+		{"1024x1792", "hd", "hd/1024-x-1792/image-test-model"},
+		{"1024x1024", "", "standard/1024-x-1024/image-test-model"},
+		{"512x512", "hd", "image-test-model"},
+		{"", "", "image-test-model"},
+	}
+	for _, tt := range tests {
+		name, pricing := m.getImagePricing("image-test-model", tt.size, tt.quality)
+		assert.Equal(t, tt.want, name)
+		assert.NotNil(t, pricing)
+	}
+
+	_, pricing := m.getImagePricing("unknown-image-model", "1024x1024", "hd")
+	assert.Nil(t, pricing)
+
+	assert.Equal(t, 1024*1792, imageSizePixels("1024x1792"))
+	assert.Zero(t, imageSizePixels("auto"))
+}
+
+func TestMeteredEndpointPath(t *testing.T) {
+	path, ok := meteredEndpointPath("/v1/images/generations")
+	assert.True(t, ok)
+	assert.Equal(t, "/images/generations", path)
+
+	path, ok = meteredEndpointPath("/api/v1/audio/speech")
+	assert.True(t, ok)
+	assert.Equal(t, "/audio/speech", path)
+
+	_, ok = meteredEndpointPath("/v1/embeddings")
+	assert.False(t, ok)
+}
+
+func TestAsyncBudgetMiddleware_MaxCostAppliesToMasterKey(t *testing.T) {
+	m := newMaxCostTestMiddleware(t)
+
+	maxCost := 0.01
+	body, err := json.Marshal(providers.ChatRequest{
+		Model:    "max-cost-test-model",
+		Messages: []providers.Message{{Role: "user", Content: "hello"}},
+		MaxCost:  &maxCost,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), AuthTypeContextKey, AuthTypeMasterKey))
+	rec := httptest.NewRecorder()
+
+	called := false
+	m.EnforceBudgetAsync(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})).ServeHTTP(rec, req)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAsyncBudgetMiddleware_MaxCostSumsCompareModels(t *testing.T) {
+	m := newMaxCostTestMiddleware(t)
+
+	// Each model's worst case is just under the ceiling, together they exceed it
+	maxTokens := 100
+	maxCost := 0.015
+	body, err := json.Marshal(map[string]interface{}{
+		"models":     []string{"max-cost-test-model", "max-cost-test-model"},
+		"messages":   []providers.Message{{Role: "user", Content: "hello"}},
+		"max_tokens": maxTokens,
+		"max_cost":   maxCost,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions/compare", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), AuthTypeContextKey, AuthTypeMasterKey))
+	rec := httptest.NewRecorder()
+
+	called := false
+	m.EnforceBudgetAsync(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})).ServeHTTP(rec, req)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp providers.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "max_cost_exceeded", resp.Error.Code)
+}
+
+// routeDefaultsFunc adapts a function to RouteDefaultsApplier
+type routeDefaultsFunc func(request *providers.ChatRequest)
+
+func (f routeDefaultsFunc) ApplyRouteDefaults(request *providers.ChatRequest) { f(request) }
+
+func TestAsyncBudgetMiddleware_MaxCostUsesRouteDefaults(t *testing.T) {
+	m := newMaxCostTestMiddleware(t)
+	m.routeDefaults = routeDefaultsFunc(func(request *providers.ChatRequest) {
+		if request.MaxTokens == nil {
+			maxTokens := 50
+			request.MaxTokens = &maxTokens
+		}
+	})
+
+	// Without the route's max_tokens, each model's worst case is its full
+	// output limit
+	body, err := json.Marshal(map[string]interface{}{
+		"models":   []string{"max-cost-test-model", "max-cost-test-model"},
+		"messages": []providers.Message{{Role: "user", Content: "hello"}},
+		"max_cost": 0.015,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions/compare", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), AuthTypeContextKey, AuthTypeMasterKey))
+	rec := httptest.NewRecorder()
+
+	called := false
+	m.EnforceBudgetAsync(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})).ServeHTTP(rec, req)
+
+	assert.True(t, called, rec.Body.String())
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/amerfu/pllm/internal/services/monitoring/clientinfo"
+)
+
+const ClientInfoContextKey contextKey = "client_info"
+
+// RequestClient is the coarse location of a request's client IP and the SDK
+// or tool that sent it, recorded on usage for the geo and client analytics
+type RequestClient struct {
+	Location clientinfo.Location
+	Client   clientinfo.Client
+}
+
+// ClientInfo fingerprints the client of each request from its User-Agent
+// and, when a locator is given, locates its IP. It must run after chi's
+// RealIP, so the IP is the client's rather than a proxy's. Only the country,
+// continent and client library are kept; the IP itself is not stored.
+func ClientInfo(locator *clientinfo.Locator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := RequestClient{
+				Location: locator.Locate(r.RemoteAddr),
+				Client:   clientinfo.ParseUserAgent(r.UserAgent()),
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ClientInfoContextKey, info)))
+		})
+	}
+}
+
+// GetClientInfo returns the client of the request, if known
+func GetClientInfo(ctx context.Context) (RequestClient, bool) {
+	info, ok := ctx.Value(ClientInfoContextKey).(RequestClient)
+	return info, ok
+}
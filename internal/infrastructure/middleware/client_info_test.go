@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/services/monitoring/clientinfo"
+)
+
+func TestClientInfo(t *testing.T) {
+	var got RequestClient
+	var ok bool
+	handler := chiMiddleware.RealIP(ClientInfo(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok = GetClientInfo(r.Context())
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("User-Agent", "OpenAI/Python 1.35.3")
+	req.Header.Set("X-Forwarded-For", "81.2.69.142")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.True(t, ok)
+	assert.Equal(t, clientinfo.Client{Name: "openai-python", Version: "1.35.3"}, got.Client)
+	assert.Equal(t, clientinfo.Location{}, got.Location, "no location without a GeoIP database")
+
+	_, ok = GetClientInfo(req.Context())
+	assert.False(t, ok)
+}
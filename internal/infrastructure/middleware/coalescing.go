@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// CoalescedHeader marks a response that was shared from an identical
+// request in flight rather than fetched from the provider
+const CoalescedHeader = "X-PLLM-Coalesced"
+
+var (
+	coalescedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_coalesced_requests_total",
+		Help: "Requests answered with the response of an identical request in flight instead of a provider call",
+	}, []string{"model"})
+
+	coalescedTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_coalesced_tokens_total",
+		Help: "Tokens the provider calls saved by request coalescing would have used",
+	}, []string{"model"})
+)
+
+// flight is a request whose identical followers wait for its response
+type flight struct {
+	done    chan struct{}
+	waiters int
+
+	// Set before done is closed
+	status  int
+	headers map[string]string
+	body    []byte
+	tokens  int
+}
+
+// CoalescingMiddleware makes one provider call for identical deterministic
+// completions (temperature 0 or a seed, not streamed) that a key sends
+// concurrently: the first request goes through and the others wait for its
+// response. When it fails, the waiting requests are sent on their own. It
+// runs before budget tracking, so coalesced requests are not billed.
+type CoalescingMiddleware struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+	logger  *zap.Logger
+}
+
+// NewCoalescingMiddleware creates a new request coalescing middleware
+func NewCoalescingMiddleware(logger *zap.Logger) *CoalescingMiddleware {
+	return &CoalescingMiddleware{
+		flights: make(map[string]*flight),
+		logger:  logger.Named("coalescing_middleware"),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *CoalescingMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := coalescingScope(r.Context())
+		endpoint := outputCacheEndpoint(r.URL.Path)
+		if !ok || r.Method != http.MethodPost || endpoint == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		canonical, ok := deterministicRequest(body)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := sha256.New()
+		h.Write([]byte(scope + "\n" + endpoint + "\n"))
+		h.Write(canonical)
+		flightKey := hex.EncodeToString(h.Sum(nil))
+
+		m.mu.Lock()
+		if f, ok := m.flights[flightKey]; ok {
+			f.waiters++
+			m.mu.Unlock()
+			m.follow(w, r, next, f, body)
+			return
+		}
+		f := &flight{done: make(chan struct{})}
+		m.flights[flightKey] = f
+		m.mu.Unlock()
+
+		defer func() {
+			m.mu.Lock()
+			delete(m.flights, flightKey)
+			m.mu.Unlock()
+			close(f.done)
+		}()
+
+		capture := newCacheResponseWriter(w)
+		next.ServeHTTP(capture, r)
+
+		f.status = capture.StatusCode()
+		f.body = capture.body.Bytes()
+		f.headers = make(map[string]string)
+		for name, values := range capture.Header() {
+			if len(values) > 0 && shouldStoreOutputHeader(name) {
+				f.headers[name] = values[0]
+			}
+		}
+		f.tokens = responseTokens(f.body)
+	})
+}
+
+// follow waits for the request in flight and serves its response, or sends
+// the request on its own when the first one failed
+func (m *CoalescingMiddleware) follow(w http.ResponseWriter, r *http.Request, next http.Handler, f *flight, body []byte) {
+	select {
+	case <-f.done:
+	case <-r.Context().Done():
+		return
+	}
+
+	if f.status != http.StatusOK || len(f.body) == 0 {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	var request struct {
+		Model string `json:"model"`
+	}
+	_ = json.Unmarshal(body, &request)
+	coalescedRequests.WithLabelValues(request.Model).Inc()
+	coalescedTokens.WithLabelValues(request.Model).Add(float64(f.tokens))
+
+	for name, value := range f.headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(CoalescedHeader, "true")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(f.body); err != nil {
+		m.logger.Warn("Failed to write coalesced response", zap.Error(err))
+	}
+}
+
+// coalescingScope returns who may share responses: requests of the same key
+// (the master key counts as one), or of the same user for session tokens
+func coalescingScope(ctx context.Context) (string, bool) {
+	if key, ok := GetKey(ctx); ok && key != nil {
+		return "key:" + key.ID.String(), true
+	}
+	if IsMasterKey(ctx) {
+		return "master_key", true
+	}
+	if userID, ok := GetUserID(ctx); ok {
+		return "user:" + userID.String(), true
+	}
+	return "", false
+}
+
+// responseTokens returns the tokens a completion response reports using, in
+// the OpenAI or Anthropic format
+func responseTokens(body []byte) int {
+	var response struct {
+		Usage struct {
+			TotalTokens  int `json:"total_tokens"`
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0
+	}
+	if response.Usage.TotalTokens > 0 {
+		return response.Usage.TotalTokens
+	}
+	return response.Usage.InputTokens + response.Usage.OutputTokens
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func TestCoalescingMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	status := http.StatusOK
+	coalescing := NewCoalescingMiddleware(zap.NewNop())
+	handler := coalescing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-PLLM-Resolved-Model", "gpt-4o")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"model":"gpt-4o","choices":[{"message":{"content":"4"}}],"usage":{"total_tokens":12}}`))
+	}))
+
+	key := &models.Key{}
+	key.ID = uuid.New()
+	serve := func(key *models.Key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), KeyContextKey, key))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	waiters := func() int {
+		coalescing.mu.Lock()
+		defer coalescing.mu.Unlock()
+		total := 0
+		for _, f := range coalescing.flights {
+			total += f.waiters
+		}
+		return total
+	}
+
+	// Concurrent identical requests, whatever their field order, make one call
+	requests := []string{
+		`{"model":"coalesce-test","temperature":0,"messages":[{"role":"user","content":"2+2"}]}`,
+		`{"messages":[{"role":"user","content":"2+2"}],"temperature":0,"model":"coalesce-test"}`,
+		`{"model":"coalesce-test","temperature":0,"messages":[{"role":"user","content":"2+2"}]}`,
+	}
+	requestsBefore := testutil.ToFloat64(coalescedRequests.WithLabelValues("coalesce-test"))
+	tokensBefore := testutil.ToFloat64(coalescedTokens.WithLabelValues("coalesce-test"))
+	recs := make([]*httptest.ResponseRecorder, len(requests))
+	var wg sync.WaitGroup
+	for i, body := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs[i] = serve(key, body)
+		}()
+		if i == 0 {
+			require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+		}
+	}
+	require.Eventually(t, func() bool { return waiters() == len(requests)-1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	coalesced := 0
+	for _, rec := range recs {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "gpt-4o", rec.Header().Get("X-PLLM-Resolved-Model"))
+		assert.JSONEq(t, `{"model":"gpt-4o","choices":[{"message":{"content":"4"}}],"usage":{"total_tokens":12}}`, rec.Body.String())
+		if rec.Header().Get(CoalescedHeader) == "true" {
+			coalesced++
+		}
+	}
+	assert.Equal(t, len(requests)-1, coalesced)
+	assert.Equal(t, float64(2), testutil.ToFloat64(coalescedRequests.WithLabelValues("coalesce-test"))-requestsBefore)
+	assert.Equal(t, float64(24), testutil.ToFloat64(coalescedTokens.WithLabelValues("coalesce-test"))-tokensBefore)
+	assert.Empty(t, coalescing.flights, "finished flights are forgotten")
+
+	// Sampled requests and other keys are not coalesced
+	calls.Store(0)
+	other := &models.Key{}
+	other.ID = uuid.New()
+	serve(key, `{"model":"coalesce-test","temperature":0.7,"messages":[]}`)
+	serve(other, requests[0])
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Followers of a failed request are sent on their own
+	status = http.StatusBadGateway
+	release = make(chan struct{})
+	calls.Store(0)
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer wg.Done()
+			recs[i] = serve(key, requests[0])
+		}()
+		if i == 0 {
+			require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+		}
+	}
+	require.Eventually(t, func() bool { return waiters() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Empty(t, recs[1].Header().Get(CoalescedHeader))
+}
+
+func TestResponseTokens(t *testing.T) {
+	assert.Equal(t, 30, responseTokens([]byte(`{"usage":{"prompt_tokens":10,"completion_tokens":20,"total_tokens":30}}`)))
+	assert.Equal(t, 15, responseTokens([]byte(`{"usage":{"input_tokens":5,"output_tokens":10}}`)), "Anthropic messages")
+	assert.Zero(t, responseTokens([]byte(`not json`)))
+}
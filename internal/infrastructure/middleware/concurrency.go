@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+	"github.com/amerfu/pllm/internal/services/monitoring/ratelimit"
+)
+
+var concurrencyRejections = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_concurrency_limit_rejections_total",
+		Help: "Requests rejected because the key or team had too many requests in flight",
+	},
+	[]string{"scope"}, // scope: key, team
+)
+
+// concurrencySlot is an in-flight counter a request must fit under
+type concurrencySlot struct {
+	scope   string
+	counter string
+	limit   int
+}
+
+// ConcurrencyMiddleware enforces the max_parallel_calls limits of keys and
+// their teams. A slot is held until the handler returns, which for streaming
+// responses is when the stream ends.
+type ConcurrencyMiddleware struct {
+	limiter ratelimit.ConcurrencyLimiter
+	logger  *zap.Logger
+}
+
+func NewConcurrencyMiddleware(limiter ratelimit.ConcurrencyLimiter, logger *zap.Logger) *ConcurrencyMiddleware {
+	return &ConcurrencyMiddleware{
+		limiter: limiter,
+		logger:  logger,
+	}
+}
+
+func (m *ConcurrencyMiddleware) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Listing endpoints and the realtime WebSocket are not generation calls
+		if r.Method == http.MethodGet || IsMasterKey(r.Context()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, ok := GetKey(r.Context())
+		if !ok || key == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var acquired []concurrencySlot
+		release := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			for _, slot := range acquired {
+				if err := m.limiter.Release(ctx, slot.counter); err != nil {
+					m.logger.Warn("Failed to release concurrency slot", zap.String("counter", slot.counter), zap.Error(err))
+				}
+			}
+		}
+
+		for _, slot := range concurrencySlots(key) {
+			ok, err := m.limiter.Acquire(r.Context(), slot.counter, slot.limit)
+			if err != nil {
+				// Fail open like the rate limiter: an unavailable counter store
+				// must not take the gateway down
+				m.logger.Warn("Concurrency check failed, allowing request", zap.String("counter", slot.counter), zap.Error(err))
+				continue
+			}
+			if !ok {
+				release()
+				concurrencyRejections.WithLabelValues(slot.scope).Inc()
+				m.logger.Warn("Request rejected due to concurrency limit",
+					zap.String("scope", slot.scope),
+					zap.String("key_id", key.ID.String()),
+					zap.Int("limit", slot.limit),
+					zap.String("path", r.URL.Path))
+				writeConcurrencyExceeded(w, slot)
+				return
+			}
+			acquired = append(acquired, slot)
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// concurrencySlots returns the limits that apply to a key: its own and its team's
+func concurrencySlots(key *models.Key) []concurrencySlot {
+	var slots []concurrencySlot
+	if _, _, parallel := key.GetEffectiveRateLimits(0, 0, 0); parallel > 0 {
+		slots = append(slots, concurrencySlot{
+			scope:   "key",
+			counter: "concurrency:key:" + key.ID.String(),
+			limit:   parallel,
+		})
+	}
+	if key.Team != nil && key.Team.MaxParallelCalls > 0 {
+		slots = append(slots, concurrencySlot{
+			scope:   "team",
+			counter: "concurrency:team:" + key.Team.ID.String(),
+			limit:   key.Team.MaxParallelCalls,
+		})
+	}
+	return slots
+}
+
+func writeConcurrencyExceeded(w http.ResponseWriter, slot concurrencySlot) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", "1")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: fmt.Sprintf("Too many concurrent requests for this %s (limit %d). Retry when an in-flight request completes.", slot.scope, slot.limit),
+			Type:    "rate_limit_error",
+			Code:    "concurrency_limit_exceeded",
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+	"github.com/amerfu/pllm/internal/services/monitoring/ratelimit"
+)
+
+func TestConcurrencyMiddleware_Limit(t *testing.T) {
+	limiter := ratelimit.NewInMemoryConcurrencyLimiter()
+	m := NewConcurrencyMiddleware(limiter, zap.NewNop())
+
+	keyLimit := 1
+	team := &models.Team{MaxParallelCalls: 2}
+	team.ID = uuid.New()
+	newKey := func() *models.Key {
+		key := &models.Key{MaxParallelCalls: &keyLimit, Team: team}
+		key.ID = uuid.New()
+		return key
+	}
+	first, second, third := newKey(), newKey(), newKey()
+
+	// The handler serves the nested request while the outer one holds its slots
+	var nested func()
+	var nestedRec *httptest.ResponseRecorder
+	handler := m.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if next := nested; next != nil {
+			nested = nil
+			next()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(key *models.Key) *httptest.ResponseRecorder {
+		ctx := context.WithValue(context.Background(), AuthTypeContextKey, AuthTypeAPIKey)
+		ctx = context.WithValue(ctx, KeyContextKey, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil).WithContext(ctx))
+		return rec
+	}
+
+	t.Run("key limit", func(t *testing.T) {
+		nested = func() { nestedRec = serve(first) }
+		assert.Equal(t, http.StatusOK, serve(first).Code)
+
+		assert.Equal(t, http.StatusTooManyRequests, nestedRec.Code)
+		assert.Equal(t, "1", nestedRec.Header().Get("Retry-After"))
+
+		var body providers.ErrorResponse
+		require.NoError(t, json.Unmarshal(nestedRec.Body.Bytes(), &body))
+		assert.Equal(t, "concurrency_limit_exceeded", body.Error.Code)
+		assert.Equal(t, "rate_limit_error", body.Error.Type)
+	})
+
+	t.Run("team limit", func(t *testing.T) {
+		// Two keys fill the team; a third is rejected even under its own limit
+		nested = func() {
+			nested = func() { nestedRec = serve(third) }
+			assert.Equal(t, http.StatusOK, serve(second).Code)
+		}
+		assert.Equal(t, http.StatusOK, serve(first).Code)
+		assert.Equal(t, http.StatusTooManyRequests, nestedRec.Code)
+	})
+
+	t.Run("slots released", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(first).Code)
+
+		inFlight, err := limiter.InFlight(context.Background(), "concurrency:team:"+team.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, 0, inFlight)
+	})
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/llm/contextwindow"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// ContextWindowStrategyHeader reports the strategy applied to a prompt that
+// exceeded the model's context window ("truncate" or "summarize")
+const ContextWindowStrategyHeader = "X-PLLM-Context-Strategy"
+
+// ContextWindowMiddleware fits chat prompts into the target model's context
+// window before they are routed, instead of letting the provider fail them
+type ContextWindowMiddleware struct {
+	manager *contextwindow.Manager
+	logger  *zap.Logger
+}
+
+// NewContextWindowMiddleware creates a new context window middleware
+func NewContextWindowMiddleware(manager *contextwindow.Manager, logger *zap.Logger) *ContextWindowMiddleware {
+	return &ContextWindowMiddleware{
+		manager: manager,
+		logger:  logger.Named("context_window_middleware"),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *ContextWindowMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" && r.URL.Path != "/api/v1/chat/completions" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+
+		// Malformed requests are left for the handler to reject
+		var request providers.ChatRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result, err := m.manager.Fit(r.Context(), &request)
+		if err != nil {
+			m.logger.Info("Rejected prompt exceeding the context window",
+				zap.String("model", request.Model),
+				zap.Int("prompt_tokens", result.PromptTokens),
+				zap.Int("limit", result.Limit))
+			writeContextWindowError(w, http.StatusBadRequest, err.Error(), "context_length_exceeded")
+			return
+		}
+
+		if result.Modified() {
+			m.logger.Info("Fitted prompt into the context window",
+				zap.String("model", request.Model),
+				zap.String("strategy", result.Strategy),
+				zap.Int("prompt_tokens", result.PromptTokens),
+				zap.Int("limit", result.Limit),
+				zap.Int("dropped_messages", result.DroppedMessages))
+
+			if fitted, err := json.Marshal(&request); err == nil {
+				body = fitted
+				w.Header().Set(ContextWindowStrategyHeader, result.Strategy)
+			} else {
+				m.logger.Error("Failed to encode fitted request", zap.Error(err))
+			}
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeContextWindowError(w http.ResponseWriter, status int, message, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    "invalid_request_error",
+			"code":    code,
+		},
+	})
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/contextwindow"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func newContextWindowTestHandler(t *testing.T, strategy string, received *providers.ChatRequest) http.Handler {
+	t.Helper()
+	pricing := config.GetPricingManager()
+	pricing.RegisterModel("tiny-context-model", &config.ModelPricingInfo{MaxInputTokens: 50})
+
+	manager := contextwindow.NewManager(config.ContextWindowConfig{Strategy: strategy}, nil, pricing, zap.NewNop())
+	return NewContextWindowMiddleware(manager, zap.NewNop()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(body)), r.ContentLength)
+		require.NoError(t, json.Unmarshal(body, received))
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func contextWindowRequestBody(turns int) string {
+	messages := []providers.Message{}
+	for i := 0; i < turns; i++ {
+		messages = append(messages,
+			providers.Message{Role: "user", Content: strings.Repeat("q", 40)},
+			providers.Message{Role: "assistant", Content: strings.Repeat("a", 40)},
+		)
+	}
+	messages = append(messages, providers.Message{Role: "user", Content: "Last question"})
+	body, _ := json.Marshal(providers.ChatRequest{Model: "tiny-context-model", Messages: messages})
+	return string(body)
+}
+
+func TestContextWindowMiddleware_Truncates(t *testing.T) {
+	var received providers.ChatRequest
+	handler := newContextWindowTestHandler(t, config.ContextWindowTruncate, &received)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(contextWindowRequestBody(5)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, config.ContextWindowTruncate, rec.Header().Get(ContextWindowStrategyHeader))
+	require.NotEmpty(t, received.Messages)
+	assert.Less(t, len(received.Messages), 11)
+	assert.Equal(t, "Last question", received.Messages[len(received.Messages)-1].Content)
+}
+
+func TestContextWindowMiddleware_PassesFittingPrompts(t *testing.T) {
+	var received providers.ChatRequest
+	handler := newContextWindowTestHandler(t, config.ContextWindowReject, &received)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/completions", bytes.NewBufferString(contextWindowRequestBody(0)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(ContextWindowStrategyHeader))
+	assert.Len(t, received.Messages, 1)
+}
+
+func TestContextWindowMiddleware_Rejects(t *testing.T) {
+	var received providers.ChatRequest
+	handler := newContextWindowTestHandler(t, config.ContextWindowReject, &received)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(contextWindowRequestBody(5)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var response struct {
+		Error struct {
+			Type string `json:"type"`
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "invalid_request_error", response.Error.Type)
+	assert.Equal(t, "context_length_exceeded", response.Error.Code)
+	assert.Nil(t, received.Messages, "the handler is not reached")
+}
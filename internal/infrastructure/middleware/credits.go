@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+var creditRejections = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "pllm_credit_rejections_total",
+		Help: "Requests rejected because the team's prepaid credits ran out",
+	},
+)
+
+// CreditChecker reports whether a team has prepaid credits left
+type CreditChecker interface {
+	Allowed(ctx context.Context, teamID uuid.UUID) (bool, float64, error)
+}
+
+// CreditsMiddleware blocks generation requests of team keys once the team's
+// prepaid credit balance is used up
+type CreditsMiddleware struct {
+	credits CreditChecker
+	logger  *zap.Logger
+}
+
+func NewCreditsMiddleware(credits CreditChecker, logger *zap.Logger) *CreditsMiddleware {
+	return &CreditsMiddleware{
+		credits: credits,
+		logger:  logger,
+	}
+}
+
+func (m *CreditsMiddleware) Enforce(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Listing endpoints and the master key are not billed
+		if r.Method == http.MethodGet || IsMasterKey(r.Context()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, ok := GetKey(r.Context())
+		if !ok || key == nil || key.TeamID == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, balance, err := m.credits.Allowed(r.Context(), *key.TeamID)
+		if err != nil {
+			// Like the budget cache, fail open when the balance is unavailable
+			m.logger.Warn("Credit balance check failed, allowing request",
+				zap.String("team_id", key.TeamID.String()),
+				zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !allowed {
+			creditRejections.Inc()
+			m.logger.Warn("Request rejected due to exhausted credits",
+				zap.String("team_id", key.TeamID.String()),
+				zap.Float64("balance", balance))
+			writeCreditsExhausted(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeCreditsExhausted(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPaymentRequired)
+	_ = json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: "Your team has run out of credits. Purchase more credits to continue.",
+			Type:    "insufficient_quota",
+			Code:    "insufficient_quota",
+		},
+	})
+}
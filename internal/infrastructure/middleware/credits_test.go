@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+type fakeCredits struct {
+	balances map[uuid.UUID]float64
+	err      error
+}
+
+func (f *fakeCredits) Allowed(_ context.Context, teamID uuid.UUID) (bool, float64, error) {
+	if f.err != nil {
+		return false, 0, f.err
+	}
+	balance := f.balances[teamID]
+	return balance > 0, balance, nil
+}
+
+func TestCreditsMiddleware_Enforce(t *testing.T) {
+	paid, broke := uuid.New(), uuid.New()
+	credits := &fakeCredits{balances: map[uuid.UUID]float64{paid: 10, broke: 0}}
+	handler := NewCreditsMiddleware(credits, zap.NewNop()).Enforce(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method string, teamID *uuid.UUID) *httptest.ResponseRecorder {
+		key := &models.Key{TeamID: teamID}
+		key.ID = uuid.New()
+		ctx := context.WithValue(context.Background(), AuthTypeContextKey, AuthTypeAPIKey)
+		ctx = context.WithValue(ctx, KeyContextKey, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/v1/chat/completions", nil).WithContext(ctx))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, &paid).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, nil).Code, "keys without a team are not billed")
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, &broke).Code, "listing endpoints are not billed")
+
+	rec := serve(http.MethodPost, &broke)
+	assert.Equal(t, http.StatusPaymentRequired, rec.Code)
+	var body providers.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "insufficient_quota", body.Error.Code)
+	assert.False(t, IsRetryable(rec.Code, "insufficient_quota"))
+
+	// The balance being unavailable does not take the gateway down
+	credits.err = errors.New("database unavailable")
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, &broke).Code)
+}
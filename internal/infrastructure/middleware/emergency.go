@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/data/settings"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+var emergencyRejections = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_emergency_rejections_total",
+		Help: "Requests rejected by the emergency controls",
+	},
+	[]string{"reason"},
+)
+
+// EmergencyControlsSource provides the current emergency controls
+type EmergencyControlsSource interface {
+	EmergencyControls() settings.EmergencyControls
+}
+
+// EmergencyMiddleware enforces the emergency controls: while the gateway is
+// paused it rejects every inference request with the maintenance message,
+// and otherwise it rejects keys of suspended teams and requests for
+// disabled models. Listing and account endpoints stay available. It runs after the team
+// model middleware, so a disabled model cannot be reached through an alias.
+type EmergencyMiddleware struct {
+	source EmergencyControlsSource
+	logger *zap.Logger
+}
+
+// NewEmergencyMiddleware creates a new emergency controls middleware
+func NewEmergencyMiddleware(source EmergencyControlsSource, logger *zap.Logger) *EmergencyMiddleware {
+	return &EmergencyMiddleware{
+		source: source,
+		logger: logger.Named("emergency_middleware"),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *EmergencyMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || strings.HasPrefix(r.URL.Path, "/v1/user") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		controls := m.source.EmergencyControls()
+		if controls.Paused {
+			emergencyRejections.WithLabelValues("paused").Inc()
+			w.Header().Set("Retry-After", "60")
+			writeEmergencyError(w, http.StatusServiceUnavailable, controls.Message(), "gateway_paused")
+			return
+		}
+
+		if key, ok := GetKey(r.Context()); ok && key != nil && key.TeamID != nil {
+			if reason, suspended := controls.TeamSuspended(key.TeamID.String()); suspended {
+				emergencyRejections.WithLabelValues("team_suspended").Inc()
+				m.logger.Warn("Request rejected for suspended team",
+					zap.String("team_id", key.TeamID.String()))
+				writeEmergencyError(w, http.StatusForbidden, withReason("Your team has been suspended", reason), "team_suspended")
+				return
+			}
+		}
+
+		if len(controls.DisabledModels) == 0 || r.Method != http.MethodPost || !isTeamModelEndpoint(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Malformed requests are left for the handler to reject
+		var request struct {
+			Model string `json:"model"`
+		}
+		if err := json.Unmarshal(body, &request); err == nil {
+			if reason, disabled := controls.ModelDisabled(request.Model); disabled {
+				emergencyRejections.WithLabelValues("model_disabled").Inc()
+				writeEmergencyError(w, http.StatusServiceUnavailable,
+					withReason("The model '"+request.Model+"' has been disabled", reason), "model_disabled")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func withReason(message, reason string) string {
+	if reason == "" {
+		return message
+	}
+	return message + ": " + reason
+}
+
+func writeEmergencyError(w http.ResponseWriter, status int, message, code string) {
+	errorType := "service_unavailable"
+	if status == http.StatusForbidden {
+		errorType = "permission_error"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: message,
+			Type:    errorType,
+			Code:    code,
+		},
+	})
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/data/settings"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+type fakeEmergencySource struct {
+	controls settings.EmergencyControls
+}
+
+func (f *fakeEmergencySource) EmergencyControls() settings.EmergencyControls {
+	return f.controls
+}
+
+func TestEmergencyMiddleware(t *testing.T) {
+	suspended, active := uuid.New(), uuid.New()
+	source := &fakeEmergencySource{controls: settings.EmergencyControls{
+		DisabledModels: map[string]string{"gpt-4": "provider incident"},
+		SuspendedTeams: map[string]string{suspended.String(): ""},
+	}}
+	handler := NewEmergencyMiddleware(source, zap.NewNop()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+
+	serve := func(method, path, body string, teamID *uuid.UUID) (*httptest.ResponseRecorder, providers.ErrorResponse) {
+		t.Helper()
+		ctx := context.Background()
+		if teamID != nil {
+			ctx = context.WithValue(ctx, KeyContextKey, &models.Key{TeamID: teamID})
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)).WithContext(ctx))
+		var response providers.ErrorResponse
+		if rec.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		}
+		return rec, response
+	}
+
+	rec, _ := serve(http.MethodPost, "/v1/chat/completions", `{"model":"claude"}`, &active)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"model":"claude"}`, rec.Body.String(), "the handler still reads the body")
+
+	rec, response := serve(http.MethodPost, "/v1/chat/completions", `{"model":"gpt-4"}`, &active)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "model_disabled", response.Error.Code)
+	assert.Equal(t, "The model 'gpt-4' has been disabled: provider incident", response.Error.Message)
+	assert.False(t, IsRetryable(rec.Code, "model_disabled"))
+
+	rec, response = serve(http.MethodPost, "/v1/embeddings", `{"model":"claude"}`, &suspended)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "team_suspended", response.Error.Code)
+	assert.Equal(t, "Your team has been suspended", response.Error.Message)
+
+	rec, _ = serve(http.MethodGet, "/v1/models", "", &suspended)
+	assert.Equal(t, http.StatusOK, rec.Code, "listing endpoints stay available")
+
+	source.controls.Paused = true
+	rec, response = serve(http.MethodPost, "/v1/chat/completions", `{"model":"claude"}`, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "gateway_paused", response.Error.Code)
+	assert.Equal(t, settings.DefaultPauseMessage, response.Error.Message)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	assert.True(t, IsRetryable(rec.Code, "gateway_paused"))
+	rec, _ = serve(http.MethodPost, "/v1/user/keys", `{}`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, "account endpoints stay available")
+
+	source.controls.PauseMessage = "Database migration until 14:00 UTC"
+	_, response = serve(http.MethodPost, "/v1/audio/transcriptions", "", nil)
+	assert.Equal(t, "Database migration until 14:00 UTC", response.Error.Message)
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -13,6 +14,9 @@ import (
 	"github.com/amerfu/pllm/internal/services/llm/providers"
 )
 
+// guardrailsScreenerKey holds the post-call screener for a chat request
+const guardrailsScreenerKey contextKey = "guardrails_screener"
+
 // GuardrailsMiddleware handles guardrails execution in the request pipeline
 type GuardrailsMiddleware struct {
 	executor *guardrails.Executor
@@ -34,8 +38,9 @@ func (m *GuardrailsMiddleware) Middleware(next http.Handler) http.Handler {
 			zap.String("path", r.URL.Path),
 			zap.String("method", r.Method))
 		
-		// Only apply to chat completions endpoint
-		if r.URL.Path != "/v1/chat/completions" {
+		// Only apply to chat completions and model comparison endpoints
+		compare := r.URL.Path == "/v1/chat/completions/compare"
+		if r.URL.Path != "/v1/chat/completions" && !compare {
 			m.logger.Debug("Skipping guardrails - not chat completions", zap.String("path", r.URL.Path))
 			next.ServeHTTP(w, r)
 			return
@@ -52,9 +57,11 @@ func (m *GuardrailsMiddleware) Middleware(next http.Handler) http.Handler {
 		
 		m.logger.Info("Guardrails executor is enabled, executing pre-call checks")
 		
-		// Parse request body for guardrails processing
-		var request providers.ChatRequest
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		// Parse request body for guardrails processing. A comparison carries
+		// its model list alongside the chat request.
+		var compareRequest providers.CompareRequest
+		request := &compareRequest.ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&compareRequest); err != nil {
 			m.logger.Error("Failed to decode request for guardrails", zap.Error(err))
 			next.ServeHTTP(w, r)
 			return
@@ -66,99 +73,136 @@ func (m *GuardrailsMiddleware) Middleware(next http.Handler) http.Handler {
 		keyID := m.extractKeyID(r.Context())
 		
 		// Execute pre-call guardrails
-		if err := m.executor.ExecutePreCall(r.Context(), &request, userID, teamID, keyID); err != nil {
+		if err := m.executor.ExecutePreCall(r.Context(), request, userID, teamID, keyID); err != nil {
 			m.logger.Info("Request blocked by pre-call guardrail", 
 				zap.String("user_id", userID),
 				zap.String("team_id", teamID),
 				zap.String("key_id", keyID),
 				zap.Error(err))
 			
-			m.sendGuardrailError(w, err)
+			WriteGuardrailError(w, err)
 			return
 		}
 		
 		// Reconstruct body with potentially modified request from guardrails
-		requestBytes, _ := json.Marshal(request)
+		var requestBytes []byte
+		if compare {
+			requestBytes, _ = json.Marshal(compareRequest)
+		} else {
+			requestBytes, _ = json.Marshal(request)
+		}
 		r.Body = &readCloser{strings.NewReader(string(requestBytes))}
 		
 		// Start during-call guardrails (async)
-		ctx := m.executor.StartDuringCall(r.Context(), &request, userID, teamID, keyID)
+		ctx := m.executor.StartDuringCall(r.Context(), request, userID, teamID, keyID)
 		r = r.WithContext(ctx)
 		
-		// Wrap response writer to capture response for post-call guardrails
-		wrapper := &guardrailsResponseWriter{
-			ResponseWriter: w,
-			middleware:     m,
-			request:        &request,
-			userID:         userID,
-			teamID:         teamID,
-			keyID:          keyID,
+		// Without post-call guardrails there is nothing to screen
+		if !m.executor.HasPostCallRails() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		
+		// Streamed output is screened while it is generated when every
+		// post-call guardrail supports it; otherwise it would reach the
+		// client before it could be screened
+		if request.Stream {
+			if !m.executor.CanScreenStreams() {
+				writeGuardrailsError(w, http.StatusBadRequest,
+					"Streaming is not available while post-call guardrails are enabled", "streaming_not_allowed")
+				return
+			}
+			moderator := &StreamModerator{
+				middleware: m,
+				request:    request,
+				userID:     userID,
+				teamID:     teamID,
+				keyID:      keyID,
+				checkChars: m.executor.StreamCheckChars(),
+				content:    make(map[int]*strings.Builder),
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), guardrailsStreamKey, moderator)))
+			return
 		}
 		
-		// Continue to next handler
-		next.ServeHTTP(wrapper, r)
+		// The handler screens its response through ScreenResponse before
+		// stamping and writing it
+		screener := &postCallScreener{
+			middleware: m,
+			request:    request,
+			userID:     userID,
+			teamID:     teamID,
+			keyID:      keyID,
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), guardrailsScreenerKey, screener)))
 	})
 }
 
-// guardrailsResponseWriter captures responses for post-call processing
-type guardrailsResponseWriter struct {
-	http.ResponseWriter
+// postCallScreener runs post-call guardrails for one request
+type postCallScreener struct {
 	middleware *GuardrailsMiddleware
 	request    *providers.ChatRequest
 	userID     string
 	teamID     string
 	keyID      string
-	statusCode int
-	body       []byte
 }
 
-func (w *guardrailsResponseWriter) Write(data []byte) (int, error) {
-	// Capture response body
-	w.body = append(w.body, data...)
+// ScreenResponse runs the request's post-call guardrails on a response before
+// it is written, so redactions end up in the body, the provenance hash and
+// the usage record alike. Guardrails may modify the response in place. Only a
+// block is returned as an error; other guardrail failures are logged and the
+// response is delivered unscreened. Without post-call guardrails it is a no-op.
+func ScreenResponse(ctx context.Context, response *providers.ChatResponse) error {
+	screener, ok := ctx.Value(guardrailsScreenerKey).(*postCallScreener)
+	if !ok || len(response.Choices) == 0 {
+		return nil
+	}
+	m := screener.middleware
 	
-	// Try to parse as chat response for post-call guardrails
-	if w.statusCode == 200 && len(w.body) > 0 {
-		var response providers.ChatResponse
-		if err := json.Unmarshal(w.body, &response); err == nil {
-			// Execute post-call guardrails
-			if err := w.middleware.executor.ExecutePostCall(
-				context.Background(), // Use background context to avoid request timeout
-				w.request, 
-				&response, 
-				w.userID, 
-				w.teamID, 
-				w.keyID,
-			); err != nil {
-				w.middleware.logger.Info("Response flagged by post-call guardrail",
-					zap.String("user_id", w.userID),
-					zap.String("team_id", w.teamID), 
-					zap.String("key_id", w.keyID),
-					zap.Error(err))
-				
-				// For post-call, we typically log rather than block
-				// But we could implement blocking behavior here if needed
-			}
-		}
+	err := m.executor.ExecutePostCall(
+		context.Background(), // Use background context to avoid request timeout
+		screener.request,
+		response,
+		screener.userID,
+		screener.teamID,
+		screener.keyID,
+	)
+	if err == nil {
+		return nil
 	}
 	
-	return w.ResponseWriter.Write(data)
+	var guardrailErr *guardrails.GuardrailError
+	if errors.As(err, &guardrailErr) && guardrailErr.Blocked {
+		m.logger.Info("Response blocked by post-call guardrail",
+			zap.String("user_id", screener.userID),
+			zap.String("team_id", screener.teamID),
+			zap.String("key_id", screener.keyID),
+			zap.Error(err))
+		return err
+	}
+	
+	// Guardrail failures fail open: the response is delivered unscreened
+	m.logger.Error("Post-call guardrail failed, delivering response",
+		zap.String("key_id", screener.keyID),
+		zap.Error(err))
+	return nil
 }
 
-func (w *guardrailsResponseWriter) WriteHeader(statusCode int) {
-	w.statusCode = statusCode
-	w.ResponseWriter.WriteHeader(statusCode)
+// WriteGuardrailError writes the error response for a request or response
+// blocked by a guardrail
+func WriteGuardrailError(w http.ResponseWriter, err error) {
+	writeGuardrailsError(w, http.StatusBadRequest, err.Error(), "content_blocked")
 }
 
-// sendGuardrailError sends a guardrails error response
-func (m *GuardrailsMiddleware) sendGuardrailError(w http.ResponseWriter, err error) {
+func writeGuardrailsError(w http.ResponseWriter, status int, message, code string) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(status)
 	
 	errorResponse := map[string]interface{}{
 		"error": map[string]interface{}{
-			"message": err.Error(),
+			"message": message,
 			"type":    "guardrail_violation",
-			"code":    "content_blocked",
+			"code":    code,
 		},
 	}
 	
@@ -179,6 +223,9 @@ func (m *GuardrailsMiddleware) extractTeamID(ctx context.Context) string {
 	if team, ok := ctx.Value(TeamContextKey).(*models.Team); ok && team != nil {
 		return team.ID.String()
 	}
+	if key, ok := ctx.Value(KeyContextKey).(*models.Key); ok && key != nil && key.TeamID != nil {
+		return key.TeamID.String()
+	}
 	return ""
 }
 
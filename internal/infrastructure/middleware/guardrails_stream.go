@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/integrations/guardrails"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// guardrailsStreamKey holds the stream moderator for a streamed chat request
+const guardrailsStreamKey contextKey = "guardrails_stream_moderator"
+
+// StreamModerator runs post-call guardrails on a streamed response while it
+// is generated, so the stream can stop as soon as disallowed content appears
+type StreamModerator struct {
+	middleware *GuardrailsMiddleware
+	request    *providers.ChatRequest
+	userID     string
+	teamID     string
+	keyID      string
+	checkChars int
+
+	content   map[int]*strings.Builder
+	unchecked int
+	stopped   bool
+}
+
+// GetStreamModerator returns the moderator of a streamed chat request, or nil
+// when its output is not screened
+func GetStreamModerator(ctx context.Context) *StreamModerator {
+	moderator, _ := ctx.Value(guardrailsStreamKey).(*StreamModerator)
+	return moderator
+}
+
+// Screen adds a chunk to the output and screens the output so far. It
+// returns the guardrail error when the chunk must not be delivered and the
+// stream must stop. Output is checked every chunk, or every checkChars
+// characters and on the chunk that finishes a choice. Guardrail failures fail
+// open, like post-call screening.
+func (s *StreamModerator) Screen(chunk *providers.StreamResponse) error {
+	if s.stopped {
+		return nil
+	}
+
+	finished := false
+	for _, choice := range chunk.Choices {
+		if text, ok := choice.Delta.Content.(string); ok && text != "" {
+			builder := s.content[choice.Index]
+			if builder == nil {
+				builder = &strings.Builder{}
+				s.content[choice.Index] = builder
+			}
+			builder.WriteString(text)
+			s.unchecked += len(text)
+		}
+		if choice.FinishReason != "" {
+			finished = true
+		}
+	}
+	if s.unchecked == 0 || (s.unchecked < s.checkChars && !finished) {
+		return nil
+	}
+	s.unchecked = 0
+
+	m := s.middleware
+	err := m.executor.ExecuteStreamCheck(context.Background(), s.request, s.response(), s.userID, s.teamID, s.keyID)
+	if err == nil {
+		return nil
+	}
+
+	var guardrailErr *guardrails.GuardrailError
+	if errors.As(err, &guardrailErr) && guardrailErr.Blocked {
+		s.stopped = true
+		m.logger.Info("Stream stopped by post-call guardrail",
+			zap.String("user_id", s.userID),
+			zap.String("team_id", s.teamID),
+			zap.String("key_id", s.keyID),
+			zap.Error(err))
+		return err
+	}
+
+	m.logger.Error("Stream guardrail check failed, continuing the stream",
+		zap.String("key_id", s.keyID),
+		zap.Error(err))
+	return nil
+}
+
+// response returns the output streamed so far as a chat response
+func (s *StreamModerator) response() *providers.ChatResponse {
+	indexes := make([]int, 0, len(s.content))
+	for index := range s.content {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	response := &providers.ChatResponse{Model: s.request.Model}
+	for _, index := range indexes {
+		response.Choices = append(response.Choices, providers.Choice{
+			Index: index,
+			Message: providers.Message{
+				Role:    "assistant",
+				Content: s.content[index].String(),
+			},
+		})
+	}
+	return response
+}
+
+// ContentFilterChunk returns the chunk that ends a stream stopped by a
+// guardrail, with finish_reason content_filter on every choice of the
+// withheld chunk
+func ContentFilterChunk(withheld *providers.StreamResponse) providers.StreamResponse {
+	chunk := providers.StreamResponse{
+		ID:      withheld.ID,
+		Object:  "chat.completion.chunk",
+		Created: withheld.Created,
+		Model:   withheld.Model,
+	}
+	for _, choice := range withheld.Choices {
+		chunk.Choices = append(chunk.Choices, providers.StreamChoice{
+			Index:        choice.Index,
+			Delta:        providers.Message{Role: "assistant", Content: ""},
+			FinishReason: "content_filter",
+		})
+	}
+	if len(chunk.Choices) == 0 {
+		chunk.Choices = []providers.StreamChoice{{
+			Delta:        providers.Message{Role: "assistant", Content: ""},
+			FinishReason: "content_filter",
+		}}
+	}
+	return chunk
+}
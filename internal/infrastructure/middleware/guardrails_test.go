@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/integrations/guardrails"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+	"github.com/amerfu/pllm/internal/services/monitoring/provenance"
+)
+
+func newPostCallGuardrailsMiddleware(t *testing.T, action string) *GuardrailsMiddleware {
+	t.Helper()
+
+	executor, err := guardrails.NewFactory(&config.Config{Guardrails: config.GuardrailsConfig{
+		Enabled: true,
+		Guardrails: []config.GuardrailConfig{{
+			Name:     "output-safety",
+			Provider: "output_safety",
+			Mode:     []string{"post_call"},
+			Enabled:  true,
+			Config:   map[string]interface{}{"action": action},
+		}},
+	}}, zap.NewNop()).CreateExecutor()
+	require.NoError(t, err)
+	require.True(t, executor.HasPostCallRails())
+
+	return NewGuardrailsMiddleware(executor, zap.NewNop())
+}
+
+func guardrailsRequest(t *testing.T, stream bool) *http.Request {
+	t.Helper()
+
+	body, err := json.Marshal(providers.ChatRequest{
+		Model:    "gpt-4",
+		Messages: []providers.Message{{Role: "user", Content: "hi"}},
+		Stream:   stream,
+	})
+	require.NoError(t, err)
+	return httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+}
+
+func TestGuardrailsMiddleware_ScreensBeforeHashing(t *testing.T) {
+	m := newPostCallGuardrailsMiddleware(t, "redact")
+
+	var hash string
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := &providers.ChatResponse{Choices: []providers.Choice{{
+			Message: providers.Message{Role: "assistant", Content: "You are an idiot."},
+		}}}
+		require.NoError(t, ScreenResponse(r.Context(), response))
+		hash = provenance.HashContent(provenance.ResponseContent(response))
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, guardrailsRequest(t, false))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var delivered providers.ChatResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &delivered))
+	assert.NotEqual(t, "You are an idiot.", delivered.Choices[0].Message.Content)
+	assert.Equal(t, provenance.HashContent(provenance.ResponseContent(&delivered)), hash)
+}
+
+func TestGuardrailsMiddleware_BlockedResponse(t *testing.T) {
+	m := newPostCallGuardrailsMiddleware(t, "block")
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := &providers.ChatResponse{Choices: []providers.Choice{{
+			Message: providers.Message{Role: "assistant", Content: "You are an idiot."},
+		}}}
+		if err := ScreenResponse(r.Context(), response); err != nil {
+			WriteGuardrailError(w, err)
+			return
+		}
+		t.Fatal("flagged response was not blocked")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, guardrailsRequest(t, false))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "content_blocked")
+}
+
+// bufferedGuardrail is a post-call guardrail that can only screen complete
+// responses
+type bufferedGuardrail struct{}
+
+func (bufferedGuardrail) Execute(ctx context.Context, input *guardrails.GuardrailInput) (*guardrails.GuardrailResult, error) {
+	return &guardrails.GuardrailResult{Passed: true}, nil
+}
+func (bufferedGuardrail) GetName() string                       { return "buffered" }
+func (bufferedGuardrail) GetType() guardrails.GuardrailType     { return guardrails.Compliance }
+func (bufferedGuardrail) GetMode() guardrails.GuardrailMode     { return guardrails.PostCall }
+func (bufferedGuardrail) IsEnabled() bool                       { return true }
+func (bufferedGuardrail) HealthCheck(ctx context.Context) error { return nil }
+
+func streamChunk(content string, finishReason string) *providers.StreamResponse {
+	return &providers.StreamResponse{
+		ID:     "chatcmpl-1",
+		Object: "chat.completion.chunk",
+		Model:  "gpt-4",
+		Choices: []providers.StreamChoice{{
+			Delta:        providers.Message{Role: "assistant", Content: content},
+			FinishReason: finishReason,
+		}},
+	}
+}
+
+func TestGuardrailsMiddleware_StopsFlaggedStream(t *testing.T) {
+	m := newPostCallGuardrailsMiddleware(t, "redact")
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		moderator := GetStreamModerator(r.Context())
+		require.NotNil(t, moderator)
+
+		assert.NoError(t, moderator.Screen(streamChunk("Honestly, ", "")))
+		assert.NoError(t, moderator.Screen(streamChunk("you are an ", "")))
+
+		withheld := streamChunk("idiot.", "")
+		err := moderator.Screen(withheld)
+		var guardrailErr *guardrails.GuardrailError
+		require.ErrorAs(t, err, &guardrailErr)
+		assert.True(t, guardrailErr.Blocked)
+
+		chunk := ContentFilterChunk(withheld)
+		require.Len(t, chunk.Choices, 1)
+		assert.Equal(t, "content_filter", chunk.Choices[0].FinishReason)
+		assert.Equal(t, "", chunk.Choices[0].Delta.Content)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, guardrailsRequest(t, true))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestGuardrailsMiddleware_AnnotatedStreamsPass(t *testing.T) {
+	m := newPostCallGuardrailsMiddleware(t, "annotate")
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		moderator := GetStreamModerator(r.Context())
+		require.NotNil(t, moderator)
+		assert.NoError(t, moderator.Screen(streamChunk("You are an idiot.", "stop")))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), guardrailsRequest(t, true))
+}
+
+func TestGuardrailsMiddleware_RejectsStreamingWithoutStreamSupport(t *testing.T) {
+	executor := guardrails.NewExecutor(&config.GuardrailsConfig{Enabled: true}, zap.NewNop())
+	require.NoError(t, executor.RegisterGuardrail(bufferedGuardrail{}))
+	m := NewGuardrailsMiddleware(executor, zap.NewNop())
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("streaming request reached the handler")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, guardrailsRequest(t, true))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "streaming_not_allowed")
+}
+
+func TestScreenResponse_WithoutGuardrails(t *testing.T) {
+	response := &providers.ChatResponse{Choices: []providers.Choice{{
+		Message: providers.Message{Role: "assistant", Content: "You are an idiot."},
+	}}}
+	assert.NoError(t, ScreenResponse(httptest.NewRequest(http.MethodGet, "/", nil).Context(), response))
+	assert.Equal(t, "You are an idiot.", response.Choices[0].Message.Content)
+}
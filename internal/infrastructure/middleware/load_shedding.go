@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+	"github.com/amerfu/pllm/internal/services/monitoring/loadshed"
+)
+
+// PriorityHeader lets clients mark requests that can wait as low priority,
+// the first to be shed under resource pressure
+const PriorityHeader = "X-PLLM-Priority"
+
+// LoadShedder decides which requests to reject under resource pressure
+type LoadShedder interface {
+	Shed(priority loadshed.Priority) bool
+	RetryAfter() time.Duration
+}
+
+// LoadSheddingMiddleware rejects LLM requests with 503 while the gateway
+// process runs short of resources, lowest priority first. It runs after
+// authentication so the master key is never shed.
+type LoadSheddingMiddleware struct {
+	shedder LoadShedder
+	logger  *zap.Logger
+}
+
+// NewLoadSheddingMiddleware creates a new load shedding middleware
+func NewLoadSheddingMiddleware(shedder LoadShedder, logger *zap.Logger) *LoadSheddingMiddleware {
+	return &LoadSheddingMiddleware{
+		shedder: shedder,
+		logger:  logger.Named("load_shedding_middleware"),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *LoadSheddingMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Listings are cheap and keep clients able to discover models
+		if r.Method == http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		priority := RequestPriority(r)
+		if !m.shedder.Shed(priority) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.logger.Debug("Shed request under resource pressure",
+			zap.String("path", r.URL.Path),
+			zap.String("priority", priority.String()))
+		retryAfter := int(m.shedder.RetryAfter().Round(time.Second).Seconds())
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(providers.ErrorResponse{
+			Error: providers.APIError{
+				Message: "The gateway is overloaded, please retry later",
+				Type:    "service_unavailable",
+				Code:    "server_overloaded",
+			},
+		})
+	})
+}
+
+// RequestPriority returns the shedding priority of a request: the master
+// key is never shed, and clients may lower, but not raise, their priority
+func RequestPriority(r *http.Request) loadshed.Priority {
+	if IsMasterKey(r.Context()) {
+		return loadshed.PriorityMaster
+	}
+	if strings.EqualFold(strings.TrimSpace(r.Header.Get(PriorityHeader)), "low") {
+		return loadshed.PriorityLow
+	}
+	return loadshed.PriorityNormal
+}
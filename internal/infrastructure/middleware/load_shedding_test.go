@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/monitoring/loadshed"
+)
+
+type fakeShedder struct {
+	below  loadshed.Priority // Priorities under this one are shed
+	asked  []loadshed.Priority
+	period time.Duration
+}
+
+func (f *fakeShedder) Shed(priority loadshed.Priority) bool {
+	f.asked = append(f.asked, priority)
+	return priority < f.below
+}
+
+func (f *fakeShedder) RetryAfter() time.Duration { return f.period }
+
+func TestLoadSheddingMiddleware(t *testing.T) {
+	shedder := &fakeShedder{below: loadshed.PriorityNormal, period: 2500 * time.Millisecond}
+	handler := NewLoadSheddingMiddleware(shedder, zap.NewNop()).Middleware(jsonHandler(http.StatusOK, `{}`))
+
+	serve := func(method, priority string, authType AuthType) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/chat/completions", strings.NewReader(`{}`))
+		if priority != "" {
+			req.Header.Set(PriorityHeader, priority)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), AuthTypeContextKey, authType))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "", AuthTypeAPIKey).Code)
+
+	rec := serve(http.MethodPost, "Low", AuthTypeAPIKey)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"code":"server_overloaded"`)
+	assert.True(t, IsRetryable(rec.Code, "server_overloaded"))
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "low", AuthTypeAPIKey).Code, "listings are never shed")
+
+	shedder.below = loadshed.PriorityMaster
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodPost, "high", AuthTypeAPIKey).Code,
+		"clients cannot raise their priority")
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "low", AuthTypeMasterKey).Code)
+
+	assert.Equal(t, []loadshed.Priority{loadshed.PriorityNormal, loadshed.PriorityLow,
+		loadshed.PriorityNormal, loadshed.PriorityMaster}, shedder.asked)
+}
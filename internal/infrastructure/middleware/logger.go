@@ -4,10 +4,16 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
+
+	pkglogger "github.com/amerfu/pllm/pkg/logger"
 )
 
+// Logger writes an access log line per request and starts the request's log
+// context; later middleware and handlers add key_id, team_id, model and route
+// to it, and the access line carries whatever was collected
 func Logger(logger *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -18,18 +24,20 @@ func Logger(logger *zap.Logger) func(next http.Handler) http.Handler {
 			}
 
 			start := time.Now()
+			ctx := pkglogger.NewContext(r.Context(), zap.String("request_id", middleware.GetReqID(r.Context())))
+			r = r.WithContext(ctx)
 
 			// Use streaming-aware wrapper that preserves Flusher interface
 			ww := NewStreamingResponseWriter(w)
 
 			defer func() {
-				logger.Info("request",
+				setRouteField(r)
+				pkglogger.FromContext(ctx, logger).Info("request",
 					zap.String("method", r.Method),
 					zap.String("path", r.URL.Path),
 					zap.Int("status", ww.StatusCode()),
 					zap.Duration("duration", time.Since(start)),
 					zap.String("remote", r.RemoteAddr),
-					zap.String("request_id", middleware.GetReqID(r.Context())),
 				)
 			}()
 
@@ -37,3 +45,12 @@ func Logger(logger *zap.Logger) func(next http.Handler) http.Handler {
 		})
 	}
 }
+
+// setRouteField records the matched route pattern in the request's log context
+func setRouteField(r *http.Request) {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			pkglogger.AddFields(r.Context(), zap.String("route", pattern))
+		}
+	}
+}
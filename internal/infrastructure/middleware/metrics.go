@@ -70,6 +70,41 @@ var (
 		[]string{"model", "provider", "endpoint"},
 	)
 
+	llmTimeToFirstToken = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pllm_llm_time_to_first_token_seconds",
+			Help:    "Time from request start to the first streamed chunk in seconds",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+		},
+		[]string{"model", "provider"},
+	)
+
+	llmStreamTokensPerSecond = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pllm_llm_stream_tokens_per_second",
+			Help:    "Completion tokens generated per second by streaming responses, after the first chunk",
+			Buckets: prometheus.ExponentialBuckets(2, 2, 10),
+		},
+		[]string{"model", "provider"},
+	)
+
+	llmStreamMaxChunkGap = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pllm_llm_stream_max_chunk_gap_seconds",
+			Help:    "Longest gap between two chunks of a streaming response in seconds",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+		},
+		[]string{"model", "provider"},
+	)
+
+	llmStreamStalls = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pllm_llm_stream_stalls_total",
+			Help: "Streaming responses that sent no chunk for longer than the stall threshold",
+		},
+		[]string{"model", "provider"},
+	)
+
 	llmTokensUsed = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "pllm_llm_tokens_total",
@@ -219,6 +254,23 @@ func RecordLLMRequest(model, provider, endpoint string, duration float64, status
 	}
 }
 
+// RecordLLMTimeToFirstToken records the time to first token of a streaming request
+func RecordLLMTimeToFirstToken(model, provider string, seconds float64) {
+	llmTimeToFirstToken.WithLabelValues(model, provider).Observe(seconds)
+}
+
+// RecordLLMStream records the throughput and longest inter-chunk gap of a
+// streaming response
+func RecordLLMStream(model, provider string, tokensPerSecond, maxChunkGapSeconds float64, stalled bool) {
+	if tokensPerSecond > 0 {
+		llmStreamTokensPerSecond.WithLabelValues(model, provider).Observe(tokensPerSecond)
+	}
+	llmStreamMaxChunkGap.WithLabelValues(model, provider).Observe(maxChunkGapSeconds)
+	if stalled {
+		llmStreamStalls.WithLabelValues(model, provider).Inc()
+	}
+}
+
 // RecordLLMTokens records token usage
 func RecordLLMTokens(model, provider string, promptTokens, completionTokens, totalTokens float64) {
 	llmTokensUsed.WithLabelValues(model, provider, "prompt").Add(promptTokens)
@@ -3,11 +3,15 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/amerfu/pllm/internal/services/monitoring/metrics"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	pkglogger "github.com/amerfu/pllm/pkg/logger"
 )
 
 // AsyncMetricsMiddleware emits metric events without blocking requests
@@ -36,6 +40,60 @@ type MetricsContext struct {
 	ProviderModel string // Provider's model ID (e.g., "gpt-4o") — for pricing lookups
 	ProviderType  string // Provider type (e.g., "openai", "anthropic")
 	RouteSlug     string // Route slug if request came through a route; empty otherwise
+	ContentHash   string // Provenance hash of the generated content, when provenance is enabled
+	ProviderKeyID string // Team provider key the request was sent with; empty for shared credentials
+
+	// Time from request start to the first streamed chunk (streaming only)
+	TimeToFirstToken time.Duration
+
+	// Generation throughput and gaps of a streamed response (streaming only)
+	TokensPerSecond float64
+	MaxChunkGap     time.Duration
+	StreamStalled   bool
+
+	// Provider-reported token usage, when the handler saw it (zero otherwise)
+	PromptTokens     int
+	CompletionTokens int
+	ReasoningTokens  int // Subset of CompletionTokens
+	CachedTokens     int // Subset of PromptTokens read from the provider's prompt cache
+
+	// Seconds of audio transcribed, for models billed per second
+	AudioSeconds float64
+
+	// Generated images with their size and quality, which select the
+	// image pricing
+	ImagesGenerated int
+	ImageSize       string
+	ImageQuality    string
+
+	// Input characters synthesized to speech
+	Characters int
+
+	// Billed rerank searches
+	SearchQueries int
+
+	// Context cache writes and their cost (creation plus storage), priced by
+	// the context cache service
+	CacheWriteTokens int
+	CacheCost        float64
+
+	// Per-model usage when one request fans out to several models (compare
+	// endpoint); each call is tracked as its own usage record
+	ModelCalls []ModelCallUsage
+}
+
+// ModelCallUsage is the usage of one successful model call within a request
+type ModelCallUsage struct {
+	RequestedModel   string
+	ResolvedModel    string
+	ProviderModel    string
+	ProviderType     string
+	RouteSlug        string
+	PromptTokens     int
+	CompletionTokens int
+	ReasoningTokens  int
+	CachedTokens     int
+	Latency          time.Duration
 }
 
 // ContextKey is the type for context keys
@@ -102,6 +160,13 @@ func (w *metricsResponseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Flush implements http.Flusher so streaming responses keep working
+func (w *metricsResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func (w *metricsResponseWriter) Write(data []byte) (int, error) {
 	if !w.written {
 		w.written = true
@@ -115,9 +180,17 @@ func isLLMEndpoint(path string) bool {
 		"/v1/chat/completions",
 		"/v1/completions",
 		"/v1/embeddings",
+		"/v1/rerank",
+		"/v1/audio/transcriptions",
+		"/v1/audio/speech",
+		"/v1/images/generations",
 		"/chat/completions",
 		"/completions",
 		"/embeddings",
+		"/rerank",
+		"/audio/transcriptions",
+		"/audio/speech",
+		"/images/generations",
 	}
 
 	for _, llmPath := range llmPaths {
@@ -125,7 +198,9 @@ func isLLMEndpoint(path string) bool {
 			return true
 		}
 	}
-	return false
+
+	// Context cache writes are billed, so they carry usage as well
+	return strings.HasPrefix(path, "/v1/caches") || strings.HasPrefix(path, "/api/v1/caches")
 }
 
 // generateRequestID generates a unique request ID
@@ -177,6 +252,7 @@ func GetMetricsContext(ctx context.Context) *MetricsContext {
 
 // SetModelName sets the model name in metrics context
 func SetModelName(ctx context.Context, modelName string) {
+	pkglogger.AddFields(ctx, zap.String("model", modelName))
 	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
 		metricsCtx.ModelName = modelName
 	}
@@ -206,6 +282,7 @@ func EmitRequestEvent(ctx context.Context, emitter *metrics.MetricEventEmitter)
 
 // SetResolvedModel sets the resolved model information in metrics context
 func SetResolvedModel(ctx context.Context, resolvedModel, providerModel, providerType, routeSlug string) {
+	pkglogger.AddFields(ctx, zap.String("resolved_model", resolvedModel), zap.String("provider", providerType))
 	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
 		metricsCtx.ResolvedModel = resolvedModel
 		metricsCtx.ProviderModel = providerModel
@@ -214,6 +291,122 @@ func SetResolvedModel(ctx context.Context, resolvedModel, providerModel, provide
 	}
 }
 
+// SetProviderKey records the team provider key a request was sent with, so
+// its cost is attributed to the team's own provider account
+func SetProviderKey(ctx context.Context, keyID uuid.UUID) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.ProviderKeyID = keyID.String()
+	}
+}
+
+// SetContentHash records the provenance hash of the response in metrics context
+func SetContentHash(ctx context.Context, hash string) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.ContentHash = hash
+	}
+}
+
+// SetTimeToFirstToken records when a streaming response sent its first chunk
+func SetTimeToFirstToken(ctx context.Context, ttft time.Duration) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.TimeToFirstToken = ttft
+		model := metricsCtx.ResolvedModel
+		if model == "" {
+			model = metricsCtx.ModelName
+		}
+		RecordLLMTimeToFirstToken(model, metricsCtx.ProviderType, ttft.Seconds())
+	}
+}
+
+// SetStreamStats records the generation throughput and longest inter-chunk
+// gap of a streamed response, and whether it stalled
+func SetStreamStats(ctx context.Context, tokensPerSecond float64, maxChunkGap time.Duration, stalled bool) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.TokensPerSecond = tokensPerSecond
+		metricsCtx.MaxChunkGap = maxChunkGap
+		metricsCtx.StreamStalled = stalled
+		model := metricsCtx.ResolvedModel
+		if model == "" {
+			model = metricsCtx.ModelName
+		}
+		RecordLLMStream(model, metricsCtx.ProviderType, tokensPerSecond, maxChunkGap.Seconds(), stalled)
+	}
+}
+
+// SetTokenUsage records the provider-reported token usage in metrics context
+func SetTokenUsage(ctx context.Context, promptTokens, completionTokens, reasoningTokens int) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.PromptTokens = promptTokens
+		metricsCtx.CompletionTokens = completionTokens
+		metricsCtx.ReasoningTokens = reasoningTokens
+	}
+}
+
+// SetCachedTokens records how many prompt tokens the provider served from its
+// prompt cache, which are billed at the cache read rate
+func SetCachedTokens(ctx context.Context, cachedTokens int) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.CachedTokens = cachedTokens
+	}
+}
+
+// SetAudioUsage records the seconds of audio a transcription consumed
+func SetAudioUsage(ctx context.Context, seconds float64) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.AudioSeconds = seconds
+	}
+}
+
+// SetImageUsage records the images a generation returned, with the size and
+// quality they were requested at
+func SetImageUsage(ctx context.Context, images int, size, quality string) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.ImagesGenerated = images
+		metricsCtx.ImageSize = size
+		metricsCtx.ImageQuality = quality
+	}
+}
+
+// SetSpeechUsage records the input characters synthesized to speech
+func SetSpeechUsage(ctx context.Context, characters int) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.Characters = characters
+	}
+}
+
+// SetRerankUsage records the searches a rerank request was billed for
+func SetRerankUsage(ctx context.Context, searches int) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.SearchQueries = searches
+	}
+}
+
+// SetCacheUsage records the tokens written to a context cache and the cost
+// charged for creating or extending it
+func SetCacheUsage(ctx context.Context, writeTokens int, cost float64) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.CacheWriteTokens = writeTokens
+		metricsCtx.CacheCost = cost
+	}
+}
+
+// AddModelCall records the usage of one model call in metrics context. It is
+// not safe for concurrent use; handlers add calls after their fan-out completes.
+func AddModelCall(ctx context.Context, call ModelCallUsage) {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil {
+		metricsCtx.ModelCalls = append(metricsCtx.ModelCalls, call)
+	}
+}
+
+// GetRequestID returns the gateway request ID, preferring the metrics request ID
+// so that it matches the ID stored with the usage record
+func GetRequestID(ctx context.Context) string {
+	if metricsCtx := GetMetricsContext(ctx); metricsCtx != nil && metricsCtx.RequestID != "" {
+		return metricsCtx.RequestID
+	}
+	return chiMiddleware.GetReqID(ctx)
+}
+
 // EmitDetailedResponse emits a detailed response event with token/cost information
 func EmitDetailedResponse(ctx context.Context, emitter *metrics.MetricEventEmitter,
 	tokens, promptTokens, outputTokens int64, cost float64, cacheHit bool) {
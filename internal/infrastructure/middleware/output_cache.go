@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/data/cache"
+)
+
+// OutputCacheHeader reports how the output cache handled a deterministic
+// completion: "hit" (served from the cache), "miss" (stored after the call)
+// or "bypass" (the client sent Cache-Control: no-cache)
+const OutputCacheHeader = "X-PLLM-Cache"
+
+// outputCacheEndpoints are the completion endpoints whose outputs are cached
+var outputCacheEndpoints = []string{
+	"/chat/completions",
+	"/completions",
+	"/messages",
+}
+
+var outputCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pllm_output_cache_requests_total",
+	Help: "Deterministic completions looked up in the output cache, by result",
+}, []string{"result"}) // result: hit, miss, bypass
+
+// cachedOutput is a stored completion
+type cachedOutput struct {
+	Body     json.RawMessage   `json:"body"`
+	Headers  map[string]string `json:"headers"`
+	Model    string            `json:"model"`
+	CachedAt time.Time         `json:"cached_at"`
+}
+
+// OutputCacheMiddleware answers deterministic completions (temperature 0 or
+// a seed) of keys that opt in from a cache keyed by the request parameters.
+// It runs before budget tracking, so cache hits are not billed.
+type OutputCacheMiddleware struct {
+	cache  cache.Cache
+	config config.OutputCacheConfig
+	logger *zap.Logger
+}
+
+// NewOutputCacheMiddleware creates a new output cache middleware
+func NewOutputCacheMiddleware(store cache.Cache, cfg config.OutputCacheConfig, logger *zap.Logger) *OutputCacheMiddleware {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	return &OutputCacheMiddleware{
+		cache:  store,
+		config: cfg,
+		logger: logger.Named("output_cache_middleware"),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *OutputCacheMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := GetKey(r.Context())
+		endpoint := outputCacheEndpoint(r.URL.Path)
+		if !ok || key == nil || !key.CacheOutputs || r.Method != http.MethodPost || endpoint == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		cacheKey, ok := outputCacheKey(key, endpoint, body)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Header.Get("Cache-Control") == "no-cache" {
+			outputCacheRequests.WithLabelValues("bypass").Inc()
+			w.Header().Set(OutputCacheHeader, "bypass")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if data, err := m.cache.Get(cacheKey); err != nil {
+			m.logger.Warn("Output cache lookup failed", zap.Error(err))
+		} else if data != nil {
+			var cached cachedOutput
+			if err := json.Unmarshal(data, &cached); err == nil {
+				outputCacheRequests.WithLabelValues("hit").Inc()
+				m.serve(w, &cached)
+				return
+			}
+		}
+
+		outputCacheRequests.WithLabelValues("miss").Inc()
+		w.Header().Set(OutputCacheHeader, "miss")
+		capture := newCacheResponseWriter(w)
+		next.ServeHTTP(capture, r)
+
+		if capture.StatusCode() != http.StatusOK || capture.body.Len() == 0 {
+			return
+		}
+		if m.config.MaxEntryBytes > 0 && capture.body.Len() > m.config.MaxEntryBytes {
+			return
+		}
+		output := capture.body.Bytes()
+		var response struct {
+			Model string          `json:"model"`
+			Error json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal(output, &response); err != nil || response.Error != nil {
+			return
+		}
+
+		entry := cachedOutput{
+			Body:     json.RawMessage(output),
+			Headers:  make(map[string]string),
+			Model:    response.Model,
+			CachedAt: time.Now(),
+		}
+		for name, values := range capture.Header() {
+			if len(values) > 0 && shouldStoreOutputHeader(name) {
+				entry.Headers[name] = values[0]
+			}
+		}
+		go m.store(cacheKey, &entry)
+	})
+}
+
+func (m *OutputCacheMiddleware) store(cacheKey string, entry *cachedOutput) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		m.logger.Error("Failed to encode cached output", zap.Error(err))
+		return
+	}
+	if err := m.cache.Set(cacheKey, data, m.config.TTL); err != nil {
+		m.logger.Warn("Failed to store cached output", zap.Error(err))
+	}
+}
+
+func (m *OutputCacheMiddleware) serve(w http.ResponseWriter, cached *cachedOutput) {
+	for name, value := range cached.Headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(OutputCacheHeader, "hit")
+	w.Header().Set("Age", fmt.Sprintf("%.0f", time.Since(cached.CachedAt).Seconds()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(cached.Body); err != nil {
+		m.logger.Warn("Failed to write cached output", zap.Error(err))
+	}
+}
+
+// outputCacheKey returns the cache key of a deterministic, non-streaming
+// completion request, or false when its output must not be cached. Entries
+// are shared by the keys of a team (or of a user, for personal keys).
+func outputCacheKey(key *models.Key, endpoint string, body []byte) (string, bool) {
+	canonical, ok := deterministicRequest(body)
+	if !ok {
+		return "", false
+	}
+
+	scope := "key:" + key.ID.String()
+	switch {
+	case key.TeamID != nil:
+		scope = "team:" + key.TeamID.String()
+	case key.UserID != nil:
+		scope = "user:" + key.UserID.String()
+	}
+
+	h := sha256.New()
+	h.Write([]byte(scope + "\n" + endpoint + "\n"))
+	h.Write(canonical)
+	return "output_cache:" + hex.EncodeToString(h.Sum(nil)), true
+}
+
+// deterministicRequest returns the canonical form of a deterministic
+// (temperature 0 or a seed), non-streaming completion request, or false for
+// any other request
+func deterministicRequest(body []byte) ([]byte, bool) {
+	var params struct {
+		Temperature *float64        `json:"temperature"`
+		Seed        json.RawMessage `json:"seed"`
+		Stream      bool            `json:"stream"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil || params.Stream {
+		return nil, false
+	}
+	seeded := len(params.Seed) > 0 && string(params.Seed) != "null"
+	if !seeded && (params.Temperature == nil || *params.Temperature != 0) {
+		return nil, false
+	}
+
+	// Re-encoding sorts the fields, so equal requests share a key however
+	// the client ordered them
+	var request map[string]interface{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, false
+	}
+	delete(request, "user")
+	canonical, err := json.Marshal(request)
+	if err != nil {
+		return nil, false
+	}
+	return canonical, true
+}
+
+// shouldStoreOutputHeader keeps the content type and the gateway's own
+// headers (resolved model, provenance) of a cached output
+func shouldStoreOutputHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	if name == "Content-Type" {
+		return true
+	}
+	return strings.HasPrefix(name, "X-Pllm-") && name != http.CanonicalHeaderKey(OutputCacheHeader)
+}
+
+// outputCacheEndpoint returns the completion endpoint a path serves, or ""
+func outputCacheEndpoint(path string) string {
+	for _, endpoint := range outputCacheEndpoints {
+		if strings.HasSuffix(path, endpoint) {
+			return endpoint
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/data/cache"
+)
+
+func TestOutputCacheMiddleware(t *testing.T) {
+	store := cache.NewInMemoryCache(time.Minute)
+	calls := 0
+	handler := NewOutputCacheMiddleware(store, config.OutputCacheConfig{TTL: time.Minute}, zap.NewNop()).
+		Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-PLLM-Resolved-Model", "gpt-4o")
+			_, _ = w.Write([]byte(`{"model":"gpt-4o","choices":[{"message":{"content":"4"}}]}`))
+		}))
+
+	teamID := uuid.New()
+	optedIn := &models.Key{TeamID: &teamID, CacheOutputs: true}
+	optedIn.ID = uuid.New()
+	teammate := &models.Key{TeamID: &teamID, CacheOutputs: true}
+	teammate.ID = uuid.New()
+	optedOut := &models.Key{TeamID: &teamID}
+	optedOut.ID = uuid.New()
+
+	serve := func(key *models.Key, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		req = req.WithContext(context.WithValue(req.Context(), KeyContextKey, key))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	request := `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"2+2"}]}`
+	rec := serve(optedIn, request)
+	assert.Equal(t, "miss", rec.Header().Get(OutputCacheHeader))
+
+	// Entries are stored in the background
+	cacheKey, ok := outputCacheKey(optedIn, "/chat/completions", []byte(request))
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		data, _ := store.Get(cacheKey)
+		return data != nil
+	}, time.Second, 10*time.Millisecond)
+	calls = 0
+
+	// Field order and the end-user ID do not change the key
+	rec = serve(teammate, `{"messages":[{"role":"user","content":"2+2"}],"user":"alice","temperature":0,"model":"gpt-4o"}`)
+	assert.Equal(t, "hit", rec.Header().Get(OutputCacheHeader), "keys of a team share entries")
+	assert.Equal(t, "gpt-4o", rec.Header().Get("X-PLLM-Resolved-Model"))
+	assert.JSONEq(t, `{"model":"gpt-4o","choices":[{"message":{"content":"4"}}]}`, rec.Body.String())
+	assert.Equal(t, 0, calls)
+
+	rec = serve(optedIn, request, "Cache-Control", "no-cache")
+	assert.Equal(t, "bypass", rec.Header().Get(OutputCacheHeader))
+	assert.Equal(t, 1, calls)
+
+	rec = serve(optedIn, `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"2+3"}]}`)
+	assert.Equal(t, "miss", rec.Header().Get(OutputCacheHeader), "other messages miss")
+
+	rec = serve(optedOut, `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"2+2"}]}`)
+	assert.Empty(t, rec.Header().Get(OutputCacheHeader), "keys must opt in")
+
+	rec = serve(optedIn, `{"model":"gpt-4o","temperature":0.7,"messages":[{"role":"user","content":"2+2"}]}`)
+	assert.Empty(t, rec.Header().Get(OutputCacheHeader), "sampled outputs are not cached")
+}
+
+func TestOutputCacheKey(t *testing.T) {
+	key := &models.Key{}
+	key.ID = uuid.New()
+
+	_, ok := outputCacheKey(key, "/chat/completions", []byte(`{"model":"m","messages":[]}`))
+	assert.False(t, ok, "temperature defaults to a sampled output")
+
+	_, ok = outputCacheKey(key, "/chat/completions", []byte(`{"model":"m","temperature":0,"stream":true}`))
+	assert.False(t, ok, "streams are not cached")
+
+	seeded, ok := outputCacheKey(key, "/chat/completions", []byte(`{"model":"m","temperature":1,"seed":42}`))
+	assert.True(t, ok, "a seed makes the output reproducible")
+
+	other, ok := outputCacheKey(key, "/chat/completions", []byte(`{"model":"m","temperature":1,"seed":43}`))
+	assert.True(t, ok)
+	assert.NotEqual(t, seeded, other)
+
+	completion, _ := outputCacheKey(key, "/completions", []byte(`{"model":"m","temperature":1,"seed":42}`))
+	assert.NotEqual(t, seeded, completion, "endpoints do not share entries")
+}
@@ -10,33 +10,52 @@ import (
 
 	"github.com/amerfu/pllm/internal/core/config"
 	"github.com/amerfu/pllm/internal/services/data/cache"
+	"github.com/amerfu/pllm/internal/services/data/settings"
 	"github.com/amerfu/pllm/internal/services/monitoring/ratelimit"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
+// RateLimitSource provides the current rate limits
+type RateLimitSource interface {
+	RateLimits() settings.RateLimits
+}
+
+// staticRateLimits are the rate limits of the configuration file
+type staticRateLimits settings.RateLimits
+
+func (s staticRateLimits) RateLimits() settings.RateLimits {
+	return settings.RateLimits(s)
+}
+
 type RateLimitMiddleware struct {
 	limiter      ratelimit.RateLimiter
-	config       *config.RateLimitConfig
+	tokens       ratelimit.TokenCounter
+	limits       RateLimitSource
 	log          *zap.Logger
 	keyExtractor func(r *http.Request) string
 }
 
 func NewRateLimitMiddleware(cfg *config.Config, log *zap.Logger) *RateLimitMiddleware {
 	var limiter ratelimit.RateLimiter
+	var tokens ratelimit.TokenCounter
 
-	// Use Redis limiter if available, otherwise in-memory
-	if cache.IsHealthy() && cfg.RateLimit.Enabled {
+	// Use Redis limiter if available, otherwise in-memory. Limiters are
+	// created even when disabled, as the limits can be enabled at runtime.
+	if cache.IsHealthy() {
 		limiter = ratelimit.NewRedisLimiter(cache.GetClient(), log)
+		tokens = ratelimit.NewRedisTokenCounter(cache.GetClient())
 		log.Info("Using Redis-based rate limiter")
-	} else if cfg.RateLimit.Enabled {
+	} else {
 		limiter = ratelimit.NewInMemoryLimiter(log)
+		tokens = ratelimit.NewInMemoryTokenCounter()
 		log.Info("Using in-memory rate limiter")
 	}
 
 	return &RateLimitMiddleware{
 		limiter: limiter,
-		config:  &cfg.RateLimit,
+		tokens:  tokens,
+		limits:  staticRateLimits(settings.RateLimitsFromConfig(cfg.RateLimit)),
 		log:     log,
 		keyExtractor: func(r *http.Request) string {
 			// Default key extractor uses API key or IP
@@ -52,10 +71,16 @@ func NewRateLimitMiddleware(cfg *config.Config, log *zap.Logger) *RateLimitMiddl
 	}
 }
 
+// SetSettings makes the middleware follow rate limits changed at runtime
+func (m *RateLimitMiddleware) SetSettings(source RateLimitSource) {
+	m.limits = source
+}
+
 func (m *RateLimitMiddleware) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Skip if rate limiting is disabled
-		if m.limiter == nil || !m.config.Enabled {
+		limits := m.limits.RateLimits()
+		if m.limiter == nil || !limits.Enabled {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -70,13 +95,14 @@ func (m *RateLimitMiddleware) Handler(next http.Handler) http.Handler {
 		key := m.keyExtractor(r)
 
 		// Determine rate limits based on endpoint
-		limit, window := m.getRateLimits(r)
+		limit := limits.Limit(m.rateLimitScope(r))
+		window := time.Minute
 
 		// Check rate limit
 		ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
 		defer cancel()
 
-		allowed, err := m.limiter.Allow(ctx, key, limit, window)
+		allowed, err := m.limiter.Allow(ctx, key, limit.RPM, window)
 		if err != nil {
 			m.log.Error("Rate limit check failed", zap.Error(err))
 			// On error, allow the request but log it
@@ -85,10 +111,10 @@ func (m *RateLimitMiddleware) Handler(next http.Handler) http.Handler {
 		}
 
 		// Get remaining requests
-		remaining, _ := m.limiter.GetRemaining(ctx, key, limit, window)
+		remaining, _ := m.limiter.GetRemaining(ctx, key, limit.RPM, window)
 
 		// Set rate limit headers
-		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.RPM))
 		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
 		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(window).Unix(), 10))
 
@@ -110,10 +136,63 @@ func (m *RateLimitMiddleware) Handler(next http.Handler) http.Handler {
 			return
 		}
 
+		// Tokens of earlier requests count against the TPM limit
+		tokenKey := key + ":tpm"
+		if limit.TPM > 0 {
+			used, err := m.tokens.Used(ctx, tokenKey, window)
+			if err != nil {
+				m.log.Error("Token rate limit check failed", zap.Error(err))
+			}
+			remainingTokens := limit.TPM - used
+			if remainingTokens < 0 {
+				remainingTokens = 0
+			}
+			w.Header().Set("X-RateLimit-Limit-Tokens", strconv.Itoa(limit.TPM))
+			w.Header().Set("X-RateLimit-Remaining-Tokens", strconv.Itoa(remainingTokens))
+
+			if err == nil && remainingTokens == 0 {
+				RecordRateLimitHit(r.URL.Path)
+
+				w.Header().Set("Retry-After", strconv.Itoa(int(window.Seconds())))
+				w.WriteHeader(http.StatusTooManyRequests)
+				if _, err := w.Write([]byte(`{"error": {"message": "Token rate limit exceeded. Please retry later.", "type": "rate_limit_error", "code": "rate_limit_exceeded"}}`)); err != nil {
+					m.log.Error("Failed to write rate limit error response", zap.Error(err))
+				}
+
+				m.log.Warn("Token rate limit exceeded",
+					zap.String("key", key),
+					zap.String("endpoint", r.URL.Path),
+					zap.Int("tpm", limit.TPM))
+				return
+			}
+		}
+
 		// Rate limit allowed - record metric
 		RecordRateLimitAllowed(r.URL.Path)
 
+		// Handlers report token usage on the metrics context; provide one
+		// when the metrics middleware did not
+		if limit.TPM > 0 && GetMetricsContext(r.Context()) == nil {
+			r = r.WithContext(context.WithValue(r.Context(), MetricsContextKey, &MetricsContext{
+				RequestID: generateRequestID(),
+				StartTime: time.Now(),
+			}))
+		}
+
 		next.ServeHTTP(w, r)
+
+		// Count the tokens the handler reported once the response is done
+		if limit.TPM > 0 {
+			if metricsCtx := GetMetricsContext(r.Context()); metricsCtx != nil {
+				if used := metricsCtx.PromptTokens + metricsCtx.CompletionTokens; used > 0 {
+					addCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+					defer cancel()
+					if err := m.tokens.Add(addCtx, tokenKey, used, window); err != nil {
+						m.log.Error("Failed to count tokens for rate limiting", zap.Error(err))
+					}
+				}
+			}
+		}
 	})
 }
 
@@ -149,7 +228,8 @@ func (m *RateLimitMiddleware) shouldSkipRateLimit(path string) bool {
 	return false
 }
 
-func (m *RateLimitMiddleware) getRateLimits(r *http.Request) (int, time.Duration) {
+// rateLimitScope returns the rate limit scope of the request's endpoint
+func (m *RateLimitMiddleware) rateLimitScope(r *http.Request) string {
 	// Get endpoint-specific limits
 	routeCtx := chi.RouteContext(r.Context())
 	path := ""
@@ -157,40 +237,16 @@ func (m *RateLimitMiddleware) getRateLimits(r *http.Request) (int, time.Duration
 		path = routeCtx.RoutePattern()
 	}
 
-	// Check for model-specific rate limits
-	if strings.HasPrefix(path, "/v1/chat/completions") || strings.Contains(r.URL.Path, "/chat/completions") {
-		// Get model from request if available
-		model := r.Header.Get("X-Model")
-		if model != "" {
-			// TODO: Get model-specific rate limits from config
-			// For now, use default chat limits
-			m.log.Debug("Model-specific rate limiting not implemented", zap.String("model", model))
-		}
-
-		if m.config.ChatCompletionsRPM > 0 {
-			return m.config.ChatCompletionsRPM, time.Minute
-		}
-	}
-
-	if strings.HasPrefix(path, "/v1/completions") || strings.Contains(r.URL.Path, "/completions") {
-		if m.config.CompletionsRPM > 0 {
-			return m.config.CompletionsRPM, time.Minute
-		}
-	}
-
-	if strings.HasPrefix(path, "/v1/embeddings") || strings.Contains(r.URL.Path, "/embeddings") {
-		if m.config.EmbeddingsRPM > 0 {
-			return m.config.EmbeddingsRPM, time.Minute
-		}
+	switch {
+	case strings.HasPrefix(path, "/v1/chat/completions") || strings.Contains(r.URL.Path, "/chat/completions"):
+		return settings.ScopeChatCompletions
+	case strings.HasPrefix(path, "/v1/completions") || strings.Contains(r.URL.Path, "/completions"):
+		return settings.ScopeCompletions
+	case strings.HasPrefix(path, "/v1/embeddings") || strings.Contains(r.URL.Path, "/embeddings"),
+		strings.HasSuffix(r.URL.Path, "/rerank"):
+		return settings.ScopeEmbeddings
 	}
-
-	// Default rate limits
-	if m.config.GlobalRPM > 0 {
-		return m.config.GlobalRPM, time.Minute
-	}
-
-	// Fallback to reasonable defaults
-	return 60, time.Minute
+	return settings.ScopeGlobal
 }
 
 func extractAPIKey(r *http.Request) string {
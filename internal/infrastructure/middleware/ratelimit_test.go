@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/data/settings"
+)
+
+type fakeRateLimits struct {
+	limits settings.RateLimits
+}
+
+func (f *fakeRateLimits) RateLimits() settings.RateLimits {
+	return f.limits
+}
+
+func TestRateLimitMiddleware_RuntimeSettings(t *testing.T) {
+	m := NewRateLimitMiddleware(&config.Config{}, zap.NewNop())
+	source := &fakeRateLimits{}
+	m.SetSettings(source)
+
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetTokenUsage(r.Context(), 60, 40, 0)
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer sk-runtime")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Disabled in the configuration file
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve("/v1/chat/completions").Code)
+	}
+
+	// Enabled at runtime with a token limit on chat completions
+	source.limits = settings.RateLimits{
+		Enabled: true,
+		Scopes: map[string]settings.RateLimit{
+			settings.ScopeGlobal:          {RPM: 100},
+			settings.ScopeChatCompletions: {TPM: 150},
+		},
+	}
+
+	rec := serve("/v1/chat/completions")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "100", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "150", rec.Header().Get("X-RateLimit-Limit-Tokens"))
+	assert.Equal(t, "150", rec.Header().Get("X-RateLimit-Remaining-Tokens"))
+
+	rec = serve("/v1/chat/completions")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "50", rec.Header().Get("X-RateLimit-Remaining-Tokens"))
+
+	// 200 tokens used: the next chat request is rejected
+	rec = serve("/v1/chat/completions")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "rate_limit_exceeded")
+
+	// Embeddings have no token limit
+	assert.Equal(t, http.StatusOK, serve("/v1/embeddings").Code)
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/requestschema"
+)
+
+// RequestStrippedHeader lists the request fields removed by lenient request
+// validation
+const RequestStrippedHeader = "X-PLLM-Request-Stripped"
+
+// RequestValidationMiddleware holds chat completion requests to the request
+// schema for keys with a request validation mode: strict keys get a 400
+// naming every unknown field and invalid value, lenient keys have them
+// stripped before the request goes further.
+type RequestValidationMiddleware struct {
+	logger *zap.Logger
+}
+
+// NewRequestValidationMiddleware creates a new request validation middleware
+func NewRequestValidationMiddleware(logger *zap.Logger) *RequestValidationMiddleware {
+	return &RequestValidationMiddleware{logger: logger.Named("request_validation_middleware")}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *RequestValidationMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := GetKey(r.Context())
+		if !ok || key == nil || key.RequestValidation == "" || r.Method != http.MethodPost ||
+			(r.URL.Path != "/v1/chat/completions" && r.URL.Path != "/api/v1/chat/completions") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+
+		// Malformed requests are left for the handler to reject
+		var request map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&request); err != nil || request == nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		strip := key.RequestValidation == models.RequestValidationLenient
+		var rejected, stripped []string
+		for _, violation := range requestschema.CheckChat(request, strip) {
+			if violation.Stripped {
+				stripped = append(stripped, violation.Path)
+			} else {
+				rejected = append(rejected, violation.String())
+			}
+		}
+
+		if len(rejected) > 0 {
+			m.logger.Info("Rejected request outside the request schema",
+				zap.String("key_id", key.ID.String()),
+				zap.String("mode", key.RequestValidation),
+				zap.Strings("violations", rejected))
+			writeContextWindowError(w, http.StatusBadRequest,
+				"Request does not match the request schema: "+strings.Join(rejected, "; "), "invalid_request_schema")
+			return
+		}
+
+		if len(stripped) > 0 {
+			if cleaned, err := json.Marshal(request); err == nil {
+				body = cleaned
+				w.Header().Set(RequestStrippedHeader, strings.Join(stripped, ","))
+				m.logger.Debug("Stripped request fields outside the request schema",
+					zap.String("key_id", key.ID.String()),
+					zap.Strings("fields", stripped))
+			} else {
+				m.logger.Error("Failed to encode stripped request", zap.Error(err))
+			}
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		next.ServeHTTP(w, r)
+	})
+}
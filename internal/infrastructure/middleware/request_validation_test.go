@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func serveValidated(t *testing.T, key *models.Key, path, body string) (*httptest.ResponseRecorder, string, bool) {
+	t.Helper()
+	var received string
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = string(data)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx := context.WithValue(context.Background(), KeyContextKey, key)
+	rec := httptest.NewRecorder()
+	NewRequestValidationMiddleware(zap.NewNop()).Middleware(handler).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)).WithContext(ctx))
+	return rec, received, called
+}
+
+const validationTestRequest = `{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}],"seed":12345678901234,"verbosity":"low"}`
+
+func TestRequestValidation_Strict(t *testing.T) {
+	key := &models.Key{RequestValidation: models.RequestValidationStrict}
+
+	rec, _, called := serveValidated(t, key, "/v1/chat/completions", validationTestRequest)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "invalid_request_schema", response.Error.Code)
+	assert.Contains(t, response.Error.Message, "verbosity: unknown field")
+
+	rec, received, called := serveValidated(t, key, "/v1/chat/completions", `{"model":"gpt-4o","messages":[]}`)
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"model":"gpt-4o","messages":[]}`, received)
+}
+
+func TestRequestValidation_Lenient(t *testing.T) {
+	key := &models.Key{RequestValidation: models.RequestValidationLenient}
+
+	rec, received, called := serveValidated(t, key, "/v1/chat/completions", validationTestRequest)
+	require.True(t, called)
+	assert.Equal(t, "verbosity", rec.Header().Get(RequestStrippedHeader))
+	assert.NotContains(t, received, "verbosity")
+	assert.Contains(t, received, `"seed":12345678901234`, "numbers are forwarded as sent")
+
+	rec, _, called = serveValidated(t, key, "/v1/chat/completions",
+		`{"model":"gpt-4o","messages":[{"role":"robot","content":"Hi"}]}`)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRequestValidation_Passthrough(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *models.Key
+		path string
+		body string
+	}{
+		{name: "mode not set", key: &models.Key{}, path: "/v1/chat/completions", body: validationTestRequest},
+		{name: "other endpoint", key: &models.Key{RequestValidation: models.RequestValidationStrict}, path: "/v1/embeddings", body: validationTestRequest},
+		{name: "malformed body", key: &models.Key{RequestValidation: models.RequestValidationStrict}, path: "/v1/chat/completions", body: `{"model":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, received, called := serveValidated(t, tt.key, tt.path, tt.body)
+			assert.True(t, called)
+			assert.Equal(t, tt.body, received)
+			assert.Empty(t, rec.Header().Get(RequestStrippedHeader))
+		})
+	}
+}
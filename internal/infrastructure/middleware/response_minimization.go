@@ -0,0 +1,375 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// ResponseMinimizedHeader lists the response minimization options applied to
+// a response
+const ResponseMinimizedHeader = "X-PLLM-Response-Minimized"
+
+// minimizedEndpoints are the endpoints whose responses are minimized
+var minimizedEndpoints = []string{
+	"/chat/completions",
+	"/completions",
+}
+
+// ResponseMinimizationMiddleware strips the fields a key (or its team) opted
+// out of from completion responses, streamed or not. It runs outside the
+// output cache, usage tracking and logging, so they keep the full response.
+type ResponseMinimizationMiddleware struct {
+	logger *zap.Logger
+}
+
+// NewResponseMinimizationMiddleware creates a new response minimization middleware
+func NewResponseMinimizationMiddleware(logger *zap.Logger) *ResponseMinimizationMiddleware {
+	return &ResponseMinimizationMiddleware{logger: logger.Named("response_minimization_middleware")}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *ResponseMinimizationMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := GetKey(r.Context())
+		if !ok || key == nil || r.Method != http.MethodPost || !isMinimizedEndpoint(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		options := newMinimizeOptions(key.GetResponseMinimization())
+		if options.empty() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set(ResponseMinimizedHeader, strings.Join(key.GetResponseMinimization(), ","))
+		writer := &minimizingWriter{ResponseWriter: w, options: options, logger: m.logger}
+		next.ServeHTTP(writer, r)
+		writer.finish()
+	})
+}
+
+func isMinimizedEndpoint(path string) bool {
+	for _, endpoint := range minimizedEndpoints {
+		if strings.HasSuffix(path, endpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// minimizeOptions are the parsed response minimization options
+type minimizeOptions struct {
+	logprobs          bool
+	systemFingerprint bool
+	toolMetadata      bool
+	contentOnly       bool
+}
+
+func newMinimizeOptions(options []string) minimizeOptions {
+	var o minimizeOptions
+	for _, option := range options {
+		switch option {
+		case models.MinimizeLogprobs:
+			o.logprobs = true
+		case models.MinimizeSystemFingerprint:
+			o.systemFingerprint = true
+		case models.MinimizeToolMetadata:
+			o.toolMetadata = true
+		case models.MinimizeContentOnly:
+			o.contentOnly = true
+		}
+	}
+	return o
+}
+
+func (o minimizeOptions) empty() bool {
+	return !o.logprobs && !o.systemFingerprint && !o.toolMetadata && !o.contentOnly
+}
+
+// minimizingWriter minimizes a successful response: JSON bodies are held
+// back and rewritten once complete, event streams are rewritten a line at a
+// time. Other responses pass through.
+type minimizingWriter struct {
+	http.ResponseWriter
+	options minimizeOptions
+	logger  *zap.Logger
+
+	wroteHeader bool
+	status      int
+	stream      bool
+	body        *bytes.Buffer // JSON body held back
+	pending     []byte        // incomplete stream line
+}
+
+func (w *minimizingWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if status == http.StatusOK {
+		contentType := w.Header().Get("Content-Type")
+		switch {
+		case strings.HasPrefix(contentType, "text/event-stream"):
+			w.stream = true
+		case contentType == "" || strings.HasPrefix(contentType, "application/json"):
+			w.body = &bytes.Buffer{}
+			return
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *minimizingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case w.body != nil:
+		return w.body.Write(b)
+	case w.stream:
+		w.pending = append(w.pending, b...)
+		end := bytes.LastIndexByte(w.pending, '\n')
+		if end < 0 {
+			return len(b), nil
+		}
+		lines := w.pending[:end+1]
+		if _, err := w.ResponseWriter.Write(w.minimizeLines(lines)); err != nil {
+			return 0, err
+		}
+		w.pending = append([]byte(nil), w.pending[end+1:]...)
+		return len(b), nil
+	default:
+		return w.ResponseWriter.Write(b)
+	}
+}
+
+func (w *minimizingWriter) Flush() {
+	if w.body != nil {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes a held back JSON body, or the rest of a stream
+func (w *minimizingWriter) finish() {
+	if w.stream && len(w.pending) > 0 {
+		if _, err := w.ResponseWriter.Write(w.minimizeLines(w.pending)); err != nil {
+			w.logger.Debug("Failed to write minimized stream", zap.Error(err))
+		}
+		return
+	}
+	if w.body == nil {
+		return
+	}
+
+	body := w.body.Bytes()
+	if minimized, ok := minimizeResponse(body, w.options); ok {
+		body = minimized
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	if _, err := w.ResponseWriter.Write(body); err != nil {
+		w.logger.Debug("Failed to write minimized response", zap.Error(err))
+	}
+}
+
+// minimizeLines rewrites the data lines of server-sent events
+func (w *minimizingWriter) minimizeLines(lines []byte) []byte {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		payload := bytes.TrimRight(line, "\r\n")
+		data, ok := bytes.CutPrefix(payload, []byte("data: "))
+		if !ok || bytes.Equal(data, []byte("[DONE]")) {
+			out.Write(line)
+			continue
+		}
+		if minimized, ok := minimizeResponse(data, w.options); ok {
+			out.WriteString("data: ")
+			out.Write(minimized)
+			out.Write(line[len(payload):])
+			continue
+		}
+		out.Write(line)
+	}
+	return out.Bytes()
+}
+
+// minimizeResponse strips the fields the options name from a completion
+// response or stream chunk. It returns false when the body is not a JSON
+// object or nothing was stripped.
+func minimizeResponse(body []byte, o minimizeOptions) ([]byte, bool) {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, false
+	}
+
+	changed := false
+	if _, ok := response["system_fingerprint"]; ok && o.systemFingerprint {
+		delete(response, "system_fingerprint")
+		changed = true
+	}
+
+	var choices []map[string]json.RawMessage
+	if raw, ok := response["choices"]; ok && json.Unmarshal(raw, &choices) == nil {
+		var object string
+		_ = json.Unmarshal(response["object"], &object)
+		chunk := object == "chat.completion.chunk"
+
+		choicesChanged := false
+		for _, choice := range choices {
+			if minimizeChoice(choice, o, chunk) {
+				choicesChanged = true
+			}
+		}
+		if choicesChanged {
+			if encoded, err := json.Marshal(choices); err == nil {
+				response["choices"] = encoded
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return nil, false
+	}
+	minimized, err := json.Marshal(response)
+	if err != nil {
+		return nil, false
+	}
+	return minimized, true
+}
+
+// minimizeChoice strips fields of a choice in place and reports whether it
+// changed. chunk tells stream chunks, which carry a delta, from responses,
+// which carry a message.
+func minimizeChoice(choice map[string]json.RawMessage, o minimizeOptions, chunk bool) bool {
+	changed := false
+	if _, ok := choice["logprobs"]; ok && (o.logprobs || o.contentOnly) {
+		delete(choice, "logprobs")
+		changed = true
+	}
+
+	if o.contentOnly {
+		message := "message"
+		if chunk {
+			message = "delta"
+		}
+		for field := range choice {
+			switch field {
+			case "index", "finish_reason", "text", message:
+			default:
+				delete(choice, field)
+				changed = true
+			}
+		}
+		if raw, ok := choice[message]; ok {
+			if minimized, ok := keepFields(raw, "role", "content"); ok {
+				choice[message] = minimized
+				changed = true
+			}
+		}
+		return changed
+	}
+
+	if o.toolMetadata {
+		for _, field := range []string{"message", "delta"} {
+			if raw, ok := choice[field]; ok {
+				if minimized, ok := minimizeToolCalls(raw); ok {
+					choice[field] = minimized
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// keepFields drops the fields of a JSON object other than the named ones. It
+// returns false when the object is unchanged.
+func keepFields(raw json.RawMessage, fields ...string) (json.RawMessage, bool) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, false
+	}
+	changed := false
+	for field := range object {
+		kept := false
+		for _, f := range fields {
+			if field == f {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			delete(object, field)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return nil, false
+	}
+	return encoded, true
+}
+
+// minimizeToolCalls reduces a message's tool calls to their id, stream index
+// and function name and arguments
+func minimizeToolCalls(raw json.RawMessage) (json.RawMessage, bool) {
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &message); err != nil {
+		return nil, false
+	}
+	var calls []json.RawMessage
+	if err := json.Unmarshal(message["tool_calls"], &calls); err != nil || len(calls) == 0 {
+		return nil, false
+	}
+
+	changed := false
+	for i, call := range calls {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(call, &fields); err != nil {
+			return nil, false
+		}
+		callChanged := false
+		for field := range fields {
+			if field != "id" && field != "index" && field != "function" {
+				delete(fields, field)
+				callChanged = true
+			}
+		}
+		if function, ok := fields["function"]; ok {
+			if minimized, ok := keepFields(function, "name", "arguments"); ok {
+				fields["function"] = minimized
+				callChanged = true
+			}
+		}
+		if callChanged {
+			calls[i] = mustMarshal(fields)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+	message["tool_calls"] = mustMarshal(calls)
+	return mustMarshal(message), true
+}
+
+// mustMarshal encodes values that were just decoded from JSON
+func mustMarshal(v interface{}) json.RawMessage {
+	encoded, _ := json.Marshal(v)
+	return encoded
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+const minimizationTestResponse = `{"id":"chatcmpl-1","object":"chat.completion","created":1760000000,"model":"gpt-4o",
+	"system_fingerprint":"fp_123",
+	"choices":[{"index":0,"finish_reason":"tool_calls","logprobs":{"content":[{"token":"Hi","logprob":-0.1}]},
+		"message":{"role":"assistant","content":"Hi","refusal":null,
+			"tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{}","strict":true}}]},
+		"delta":{"role":"","content":null}}],
+	"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`
+
+func serveMinimized(t *testing.T, key *models.Key, path string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), KeyContextKey, key)
+	rec := httptest.NewRecorder()
+	NewResponseMinimizationMiddleware(zap.NewNop()).Middleware(handler).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`)).WithContext(ctx))
+	return rec
+}
+
+func jsonHandler(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestResponseMinimization_JSON(t *testing.T) {
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) (map[string]interface{}, map[string]interface{}) {
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response, response["choices"].([]interface{})[0].(map[string]interface{})
+	}
+
+	t.Run("logprobs and fingerprint", func(t *testing.T) {
+		key := &models.Key{ResponseMinimization: []string{models.MinimizeLogprobs, models.MinimizeSystemFingerprint}}
+		rec := serveMinimized(t, key, "/v1/chat/completions", jsonHandler(http.StatusOK, minimizationTestResponse))
+
+		response, choice := decode(t, rec)
+		assert.NotContains(t, response, "system_fingerprint")
+		assert.NotContains(t, choice, "logprobs")
+		assert.Equal(t, float64(1760000000), response["created"])
+		assert.Equal(t, "Hi", choice["message"].(map[string]interface{})["content"])
+		assert.Equal(t, "logprobs,system_fingerprint", rec.Header().Get(ResponseMinimizedHeader))
+		assert.Equal(t, rec.Header().Get("Content-Length"), strconv.Itoa(rec.Body.Len()))
+		assert.Less(t, rec.Body.Len(), len(minimizationTestResponse))
+	})
+
+	t.Run("tool metadata", func(t *testing.T) {
+		key := &models.Key{ResponseMinimization: []string{models.MinimizeToolMetadata}}
+		rec := serveMinimized(t, key, "/v1/chat/completions", jsonHandler(http.StatusOK, minimizationTestResponse))
+
+		_, choice := decode(t, rec)
+		assert.Contains(t, choice, "logprobs")
+		call := choice["message"].(map[string]interface{})["tool_calls"].([]interface{})[0]
+		assert.Equal(t, map[string]interface{}{
+			"id":       "call_1",
+			"function": map[string]interface{}{"name": "lookup", "arguments": "{}"},
+		}, call)
+	})
+
+	t.Run("content only", func(t *testing.T) {
+		key := &models.Key{ResponseMinimization: []string{models.MinimizeContentOnly}}
+		rec := serveMinimized(t, key, "/v1/chat/completions", jsonHandler(http.StatusOK, minimizationTestResponse))
+
+		response, choice := decode(t, rec)
+		assert.Contains(t, response, "usage")
+		assert.Equal(t, map[string]interface{}{
+			"index":         float64(0),
+			"finish_reason": "tool_calls",
+			"message":       map[string]interface{}{"role": "assistant", "content": "Hi"},
+		}, choice)
+	})
+
+	t.Run("team setting applies to keys without their own", func(t *testing.T) {
+		team := &models.Team{ResponseMinimization: models.StringArray{models.MinimizeSystemFingerprint}}
+		rec := serveMinimized(t, &models.Key{Team: team}, "/v1/completions",
+			jsonHandler(http.StatusOK, `{"object":"text_completion","system_fingerprint":"fp","choices":[{"text":"x","index":0}]}`))
+		assert.JSONEq(t, `{"object":"text_completion","choices":[{"text":"x","index":0}]}`, rec.Body.String())
+
+		key := &models.Key{Team: team, ResponseMinimization: []string{models.MinimizeLogprobs}}
+		rec = serveMinimized(t, key, "/v1/completions",
+			jsonHandler(http.StatusOK, `{"system_fingerprint":"fp","choices":[]}`))
+		assert.JSONEq(t, `{"system_fingerprint":"fp","choices":[]}`, rec.Body.String(), "the key's options replace the team's")
+	})
+
+	t.Run("passes through", func(t *testing.T) {
+		key := &models.Key{ResponseMinimization: []string{models.MinimizeSystemFingerprint}}
+
+		errorBody := `{"error":{"message":"bad","system_fingerprint":"kept"}}`
+		rec := serveMinimized(t, key, "/v1/chat/completions", jsonHandler(http.StatusBadRequest, errorBody))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, errorBody, rec.Body.String())
+
+		rec = serveMinimized(t, key, "/v1/embeddings", jsonHandler(http.StatusOK, `{"system_fingerprint":"fp"}`))
+		assert.Equal(t, `{"system_fingerprint":"fp"}`, rec.Body.String())
+		assert.Empty(t, rec.Header().Get(ResponseMinimizedHeader))
+
+		rec = serveMinimized(t, &models.Key{}, "/v1/chat/completions", jsonHandler(http.StatusOK, minimizationTestResponse))
+		assert.Equal(t, minimizationTestResponse, rec.Body.String())
+	})
+}
+
+func TestResponseMinimization_Stream(t *testing.T) {
+	key := &models.Key{ResponseMinimization: []string{models.MinimizeContentOnly, models.MinimizeSystemFingerprint}}
+	rec := serveMinimized(t, key, "/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		chunk := `data: {"object":"chat.completion.chunk","system_fingerprint":"fp","choices":[{"index":0,` +
+			`"delta":{"role":"assistant","content":"Hel","refusal":null},"logprobs":null,"message":{"role":"","content":null}}]}` + "\n\n"
+		// A chunk split across writes is rewritten once its line is complete
+		_, _ = w.Write([]byte(chunk[:40]))
+		_, _ = w.Write([]byte(chunk[40:]))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(": keep-alive\n\ndata: [DONE]\n\n"))
+	})
+
+	assert.Equal(t, `data: {"choices":[{"delta":{"content":"Hel","role":"assistant"},"index":0}],"object":"chat.completion.chunk"}`+
+		"\n\n: keep-alive\n\ndata: [DONE]\n\n", rec.Body.String())
+}
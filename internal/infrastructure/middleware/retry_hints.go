@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// Retry hint headers. RetryPolicyHeader is set on every LLM API response;
+// ShouldRetryHeader and RetryAfterMsHeader on errors, in the form the
+// OpenAI SDKs read.
+const (
+	RetryPolicyHeader  = "X-PLLM-Retry-Policy"
+	ShouldRetryHeader  = "X-Should-Retry"
+	RetryAfterMsHeader = "Retry-After-Ms"
+)
+
+// nonRetryableCodes are error codes that a retry cannot fix, whatever their
+// status
+var nonRetryableCodes = map[string]bool{
+	"budget_exceeded":         true,
+	"insufficient_quota":      true,
+	"max_cost_exceeded":       true,
+	"context_length_exceeded": true,
+	"model_disabled":          true,
+	"team_suspended":          true,
+}
+
+// RetryHintsMiddleware publishes the gateway's retry policy to clients and
+// marks every LLM API error as retryable or not, so SDKs back off on load
+// shedding and give up on errors a retry cannot fix
+type RetryHintsMiddleware struct {
+	policy string
+}
+
+// NewRetryHintsMiddleware creates a new retry hints middleware
+func NewRetryHintsMiddleware(cfg config.RetryPolicyConfig) *RetryHintsMiddleware {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = cfg.BaseBackoff
+	}
+	if cfg.Jitter == "" {
+		cfg.Jitter = config.RetryJitterFull
+	}
+	return &RetryHintsMiddleware{
+		policy: fmt.Sprintf("max_attempts=%d, backoff=exponential, base_ms=%d, max_ms=%d, jitter=%s",
+			cfg.MaxAttempts, cfg.BaseBackoff.Milliseconds(), cfg.MaxBackoff.Milliseconds(), cfg.Jitter),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *RetryHintsMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/") && !strings.HasPrefix(r.URL.Path, "/api/v1/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set(RetryPolicyHeader, m.policy)
+		// WebSocket upgrades hijack the connection; they only get the policy
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writer := &retryHintWriter{ResponseWriter: w}
+		next.ServeHTTP(writer, r)
+		writer.finish()
+	})
+}
+
+// IsRetryable reports whether a request that failed with status and error
+// code may succeed when retried
+func IsRetryable(status int, code string) bool {
+	if nonRetryableCodes[code] {
+		return false
+	}
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= http.StatusInternalServerError
+}
+
+// retryHintWriter holds back error responses so they can be annotated once
+// the handler has written them. Successful responses, including streams,
+// pass through.
+type retryHintWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	errorBody   *bytes.Buffer
+}
+
+func (w *retryHintWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if status >= http.StatusBadRequest {
+		w.errorBody = &bytes.Buffer{}
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *retryHintWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.errorBody != nil {
+		return w.errorBody.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *retryHintWriter) Flush() {
+	if w.errorBody != nil {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes a held back error response with its retry hints
+func (w *retryHintWriter) finish() {
+	if w.errorBody == nil {
+		return
+	}
+
+	body, code := annotateError(w.errorBody.Bytes(), w.status)
+	retryable := IsRetryable(w.status, code)
+
+	h := w.Header()
+	h.Set(ShouldRetryHeader, strconv.FormatBool(retryable))
+	if seconds, err := strconv.Atoi(h.Get("Retry-After")); err == nil && retryable {
+		h.Set(RetryAfterMsHeader, strconv.Itoa(seconds*1000))
+	}
+	h.Del("Content-Length")
+
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// annotateError adds the retryable flag to an OpenAI-style error body and
+// returns it with the error's code. Other bodies are returned unchanged.
+func annotateError(body []byte, status int) ([]byte, string) {
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return body, ""
+	}
+	apiErr, ok := payload["error"].(map[string]interface{})
+	if !ok {
+		return body, ""
+	}
+
+	code, _ := apiErr["code"].(string)
+	if errType, _ := apiErr["type"].(string); nonRetryableCodes[errType] {
+		code = errType
+	}
+	apiErr["retryable"] = IsRetryable(status, code)
+
+	annotated, err := json.Marshal(payload)
+	if err != nil {
+		return body, code
+	}
+	return append(annotated, '\n'), code
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func serveRetryHints(t *testing.T, path string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	m := NewRetryHintsMiddleware(config.RetryPolicyConfig{
+		MaxAttempts: 4,
+		BaseBackoff: 250 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+	})
+	rec := httptest.NewRecorder()
+	m.Middleware(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+	return rec
+}
+
+func TestRetryHints_PublishesPolicy(t *testing.T) {
+	rec := serveRetryHints(t, "/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id": "chatcmpl-1"}`))
+	})
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "max_attempts=4, backoff=exponential, base_ms=250, max_ms=10000, jitter=full", rec.Header().Get(RetryPolicyHeader))
+	assert.Empty(t, rec.Header().Get(ShouldRetryHeader))
+	assert.JSONEq(t, `{"id": "chatcmpl-1"}`, rec.Body.String())
+
+	rec = serveRetryHints(t, "/api/admin/keys", func(w http.ResponseWriter, r *http.Request) {})
+	assert.Empty(t, rec.Header().Get(RetryPolicyHeader), "only LLM API responses carry the policy")
+}
+
+func TestRetryHints_AnnotatesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		retryable bool
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"error": {"message": "slow down", "type": "rate_limit_error", "code": "rate_limit_exceeded"}}`, true},
+		{"budget exceeded", http.StatusTooManyRequests, `{"error": {"message": "no budget", "type": "insufficient_quota", "code": "budget_exceeded"}}`, false},
+		{"provider unavailable", http.StatusServiceUnavailable, `{"error": {"message": "all instances failed", "type": "invalid_request_error"}}`, true},
+		{"context too long", http.StatusBadRequest, `{"error": {"message": "too long", "type": "invalid_request_error", "code": "context_length_exceeded"}}`, false},
+		{"unauthorized", http.StatusUnauthorized, `{"error": {"message": "Invalid token", "type": "authentication_error", "code": 401}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveRetryHints(t, "/api/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, map[bool]string{true: "true", false: "false"}[tt.retryable], rec.Header().Get(ShouldRetryHeader))
+
+			var body struct {
+				Error map[string]interface{} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.retryable, body.Error["retryable"])
+			assert.NotEmpty(t, body.Error["message"])
+		})
+	}
+}
+
+func TestRetryHints_RetryAfter(t *testing.T) {
+	rec := serveRetryHints(t, "/v1/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		writeConcurrencyExceeded(w, concurrencySlot{scope: "key", limit: 2})
+	})
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(ShouldRetryHeader))
+	assert.Equal(t, "1000", rec.Header().Get(RetryAfterMsHeader))
+	assert.Contains(t, rec.Body.String(), `"retryable":true`)
+}
+
+func TestRetryHints_NonJSONError(t *testing.T) {
+	rec := serveRetryHints(t, "/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream gone", http.StatusBadGateway)
+	})
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(ShouldRetryHeader))
+	assert.Equal(t, "upstream gone\n", rec.Body.String())
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// RouteDefaultsApplier sets the parameters a request leaves unset to the
+// defaults of the route it names
+type RouteDefaultsApplier interface {
+	ApplyRouteDefaults(request *providers.ChatRequest)
+}
+
+// RouteDefaultsMiddleware writes the defaults of the requested route into
+// chat completions and Anthropic messages the client sent without them. It
+// runs before the context window, output cache and budget middlewares, so
+// they see the parameters the request is sent with.
+type RouteDefaultsMiddleware struct {
+	routes RouteDefaultsApplier
+	logger *zap.Logger
+}
+
+// NewRouteDefaultsMiddleware creates a new route defaults middleware
+func NewRouteDefaultsMiddleware(routes RouteDefaultsApplier, logger *zap.Logger) *RouteDefaultsMiddleware {
+	return &RouteDefaultsMiddleware{
+		routes: routes,
+		logger: logger.Named("route_defaults_middleware"),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *RouteDefaultsMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		anthropic := strings.HasSuffix(r.URL.Path, "/messages")
+		if r.Method != http.MethodPost || (!anthropic && !strings.HasSuffix(r.URL.Path, "/chat/completions")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Malformed requests are left for the handler to reject
+		var request map[string]json.RawMessage
+		var before struct {
+			Model           string   `json:"model"`
+			ReasoningEffort *string  `json:"reasoning_effort"`
+			MaxTokens       *int     `json:"max_tokens"`
+			Temperature     *float32 `json:"temperature"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil || json.Unmarshal(body, &before) != nil || before.Model == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		params := providers.ChatRequest{
+			Model:           before.Model,
+			ReasoningEffort: before.ReasoningEffort,
+			MaxTokens:       before.MaxTokens,
+			Temperature:     before.Temperature,
+		}
+		m.routes.ApplyRouteDefaults(&params)
+		changed := false
+		set := func(field string, value interface{}) {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return
+			}
+			request[field] = encoded
+			changed = true
+		}
+		// Anthropic messages have no reasoning_effort; the messages handler
+		// applies it when converting the request
+		if !anthropic && before.ReasoningEffort == nil && params.ReasoningEffort != nil {
+			set("reasoning_effort", *params.ReasoningEffort)
+		}
+		if before.MaxTokens == nil && params.MaxTokens != nil {
+			set("max_tokens", *params.MaxTokens)
+		}
+		if before.Temperature == nil && params.Temperature != nil {
+			set("temperature", *params.Temperature)
+		}
+		if !changed {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rewritten, err := json.Marshal(request)
+		if err != nil {
+			m.logger.Error("Failed to encode request with the route defaults", zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(rewritten))
+		r.ContentLength = int64(len(rewritten))
+		r.Header.Set("Content-Length", strconv.Itoa(len(rewritten)))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// testRouteDefaults gives the "deep" route a high reasoning effort and the
+// "fast" route a token cap and a zero temperature
+type testRouteDefaults struct{}
+
+func (testRouteDefaults) ApplyRouteDefaults(request *providers.ChatRequest) {
+	switch request.Model {
+	case "deep":
+		if request.ReasoningEffort == nil {
+			effort := "high"
+			request.ReasoningEffort = &effort
+		}
+	case "fast":
+		if request.MaxTokens == nil {
+			maxTokens := 256
+			request.MaxTokens = &maxTokens
+		}
+		if request.Temperature == nil {
+			temperature := float32(0)
+			request.Temperature = &temperature
+		}
+	}
+}
+
+func TestRouteDefaultsMiddleware(t *testing.T) {
+	var received string
+	handler := NewRouteDefaultsMiddleware(testRouteDefaults{}, zap.NewNop()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}))
+	serve := func(path, body string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	}
+
+	serve("/v1/chat/completions", `{"model":"deep","messages":[{"role":"user","content":"hi"}]}`)
+	assert.JSONEq(t, `{"model":"deep","reasoning_effort":"high","messages":[{"role":"user","content":"hi"}]}`, received)
+
+	serve("/v1/chat/completions", `{"model":"fast","max_tokens":1024,"messages":[]}`)
+	assert.JSONEq(t, `{"model":"fast","max_tokens":1024,"temperature":0,"messages":[]}`, received, "parameters set by the client are kept")
+	_, deterministic := deterministicRequest([]byte(received))
+	assert.True(t, deterministic, "the output cache sees the route's temperature")
+
+	serve("/v1/messages", `{"model":"deep","max_tokens":100,"messages":[]}`)
+	assert.JSONEq(t, `{"model":"deep","max_tokens":100,"messages":[]}`, received, "Anthropic messages have no reasoning_effort")
+
+	body := `{"model":"gpt-4o",  "messages":[]}`
+	serve("/v1/chat/completions", body)
+	assert.Equal(t, body, received, "requests for models are forwarded as they are")
+
+	serve("/v1/embeddings", `{"model":"fast","input":"hi"}`)
+	assert.Equal(t, `{"model":"fast","input":"hi"}`, received)
+}
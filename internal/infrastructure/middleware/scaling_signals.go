@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ModelLoadRecorder records the request pressure on models that the
+// autoscaling signals are computed from
+type ModelLoadRecorder interface {
+	RequestStarted(model string)
+	RequestFirstToken(model string, wait time.Duration)
+	RequestFinished(model string)
+}
+
+// ScalingSignalsMiddleware counts the requests in flight for each model and
+// how long streaming requests wait for their first token. It runs after the
+// team model middleware, so requests count against the model they are sent
+// to.
+type ScalingSignalsMiddleware struct {
+	recorder ModelLoadRecorder
+	logger   *zap.Logger
+}
+
+// NewScalingSignalsMiddleware creates a new scaling signals middleware
+func NewScalingSignalsMiddleware(recorder ModelLoadRecorder, logger *zap.Logger) *ScalingSignalsMiddleware {
+	return &ScalingSignalsMiddleware{
+		recorder: recorder,
+		logger:   logger.Named("scaling_signals_middleware"),
+	}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *ScalingSignalsMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !isTeamModelEndpoint(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Malformed requests are left for the handler to reject
+		var request struct {
+			Model string `json:"model"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil || request.Model == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.recorder.RequestStarted(request.Model)
+		defer m.recorder.RequestFinished(request.Model)
+
+		writer := &firstTokenWriter{ResponseWriter: w, start: time.Now(), onFirstToken: func(wait time.Duration) {
+			m.recorder.RequestFirstToken(request.Model, wait)
+		}}
+		next.ServeHTTP(writer, r)
+	})
+}
+
+// firstTokenWriter reports when a successful event stream writes its first
+// bytes
+type firstTokenWriter struct {
+	http.ResponseWriter
+	start        time.Time
+	onFirstToken func(time.Duration)
+
+	status  int
+	written bool
+}
+
+func (w *firstTokenWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *firstTokenWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		if (w.status == 0 || w.status == http.StatusOK) &&
+			strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+			w.onFirstToken(time.Since(w.start))
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher so streaming responses keep working
+func (w *firstTokenWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
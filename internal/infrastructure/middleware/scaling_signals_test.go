@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeLoadRecorder struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	started  []string
+	waits    map[string]time.Duration
+}
+
+func newFakeLoadRecorder() *fakeLoadRecorder {
+	return &fakeLoadRecorder{inFlight: make(map[string]int), waits: make(map[string]time.Duration)}
+}
+
+func (f *fakeLoadRecorder) RequestStarted(model string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight[model]++
+	f.started = append(f.started, model)
+}
+
+func (f *fakeLoadRecorder) RequestFirstToken(model string, wait time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waits[model] = wait
+}
+
+func (f *fakeLoadRecorder) RequestFinished(model string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight[model]--
+}
+
+func TestScalingSignalsMiddleware(t *testing.T) {
+	recorder := newFakeLoadRecorder()
+	middleware := NewScalingSignalsMiddleware(recorder, zap.NewNop())
+
+	serve := func(path, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		middleware.Middleware(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("streams report their first token", func(t *testing.T) {
+		rec := serve("/v1/chat/completions", `{"model":"llama","stream":true}`, func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"model":"llama","stream":true}`, string(body), "the handler still reads the body")
+			assert.Equal(t, 1, recorder.inFlight["llama"])
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			time.Sleep(5 * time.Millisecond)
+			_, _ = w.Write([]byte("data: {}\n\n"))
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte("data: [DONE]\n\n"))
+		})
+		assert.True(t, rec.Flushed)
+		assert.Zero(t, recorder.inFlight["llama"])
+		assert.GreaterOrEqual(t, recorder.waits["llama"], 5*time.Millisecond)
+	})
+
+	t.Run("other responses count only as in flight", func(t *testing.T) {
+		serve("/v1/embeddings", `{"model":"embed","input":"x"}`, jsonHandler(http.StatusOK, `{}`))
+		serve("/v1/chat/completions", `{"model":"broken","stream":true}`, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("data: {}\n\n"))
+		})
+		assert.Contains(t, recorder.started, "embed")
+		assert.Contains(t, recorder.started, "broken")
+		assert.NotContains(t, recorder.waits, "embed")
+		assert.NotContains(t, recorder.waits, "broken")
+	})
+
+	t.Run("requests without a model are not tracked", func(t *testing.T) {
+		before := len(recorder.started)
+		rec := serve("/v1/chat/completions", `not json`, jsonHandler(http.StatusBadRequest, `{}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		serve("/v1/audio/transcriptions", `{"model":"whisper"}`, jsonHandler(http.StatusOK, `{}`))
+		assert.Len(t, recorder.started, before)
+	})
+}
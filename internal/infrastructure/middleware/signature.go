@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// Headers of signed requests
+const (
+	SignatureTimestampHeader = "X-PLLM-Timestamp"
+	SignatureHeader          = "X-PLLM-Signature"
+)
+
+// signatureVersion prefixes the signature so the scheme can evolve
+const signatureVersion = "v1"
+
+// SignatureTolerance is how far a request timestamp may be from the
+// gateway clock
+const SignatureTolerance = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("request signature required: set the X-PLLM-Timestamp and X-PLLM-Signature headers")
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrStaleSignature   = errors.New("request timestamp is outside the allowed window")
+	ErrReplayedRequest  = errors.New("request signature was already used")
+)
+
+// SignRequest returns the signature header value of a request: an
+// HMAC-SHA256, keyed with the signing secret, of the timestamp, the method,
+// the request URI and the SHA-256 of the body, separated by newlines.
+func SignRequest(secret string, timestamp int64, method, requestURI string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "\n" + method + "\n" + requestURI + "\n" + hex.EncodeToString(bodyHash[:])))
+	return signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureVerifier checks signed requests of keys that require signing and
+// rejects signatures that were already used within the tolerance window
+type SignatureVerifier struct {
+	replays replayCache
+	now     func() time.Time
+}
+
+// NewSignatureVerifier creates a verifier. With a Redis client, replays are
+// detected across gateways; otherwise per instance.
+func NewSignatureVerifier(client *redis.Client) *SignatureVerifier {
+	var replays replayCache
+	if client != nil {
+		replays = &redisReplayCache{client: client}
+	} else {
+		replays = &memoryReplayCache{seen: make(map[string]time.Time)}
+	}
+	return &SignatureVerifier{replays: replays, now: time.Now}
+}
+
+// Verify checks the signature headers of r against secret. The body is read
+// and restored for the next handlers.
+func (v *SignatureVerifier) Verify(r *http.Request, secret string) error {
+	timestampHeader := r.Header.Get(SignatureTimestampHeader)
+	signature := r.Header.Get(SignatureHeader)
+	if timestampHeader == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := v.now().Sub(time.Unix(timestamp, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return ErrStaleSignature
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := SignRequest(secret, timestamp, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature))) {
+		return ErrInvalidSignature
+	}
+
+	// A signature stays valid for the whole window on both sides of the
+	// timestamp, so it is remembered that long
+	fresh, err := v.replays.remember(r.Context(), expected, 2*SignatureTolerance)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrReplayedRequest
+	}
+	return nil
+}
+
+// replayCache remembers used signatures
+type replayCache interface {
+	// remember records a signature and reports whether it was unseen
+	remember(ctx context.Context, signature string, ttl time.Duration) (bool, error)
+}
+
+type redisReplayCache struct {
+	client *redis.Client
+}
+
+func (c *redisReplayCache) remember(ctx context.Context, signature string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, redisService.Key("pllm:signatures:"+signature), 1, ttl).Result()
+}
+
+type memoryReplayCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func (c *memoryReplayCache) remember(ctx context.Context, signature string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) > time.Minute {
+		for s, expiresAt := range c.seen {
+			if now.After(expiresAt) {
+				delete(c.seen, s)
+			}
+		}
+		c.lastSweep = now
+	}
+	if expiresAt, ok := c.seen[signature]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+	c.seen[signature] = now.Add(ttl)
+	return true, nil
+}
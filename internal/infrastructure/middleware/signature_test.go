@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningSecret = "pllm_ss_test"
+
+func signedRequest(secret string, timestamp time.Time, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions?stream=false", strings.NewReader(body))
+	ts := timestamp.Unix()
+	req.Header.Set(SignatureTimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, SignRequest(secret, ts, http.MethodPost, "/v1/chat/completions?stream=false", []byte(body)))
+	return req
+}
+
+func TestSignatureVerifier_Verify(t *testing.T) {
+	v := NewSignatureVerifier(nil)
+	body := `{"model":"gpt-4o","messages":[]}`
+
+	req := signedRequest(testSigningSecret, time.Now(), body)
+	require.NoError(t, v.Verify(req, testSigningSecret))
+
+	// The body is still readable by the handlers
+	read, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(read))
+
+	// The same request again is a replay
+	assert.ErrorIs(t, v.Verify(signedRequest(testSigningSecret, time.Now(), body), testSigningSecret), ErrReplayedRequest)
+
+	// Wrong secret, tampered body, stale timestamp, missing headers
+	assert.ErrorIs(t, v.Verify(signedRequest("other", time.Now(), body), testSigningSecret), ErrInvalidSignature)
+
+	tampered := signedRequest(testSigningSecret, time.Now().Add(time.Second), body)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"model":"gpt-4o-mini","messages":[]}`))
+	assert.ErrorIs(t, v.Verify(tampered, testSigningSecret), ErrInvalidSignature)
+
+	stale := signedRequest(testSigningSecret, time.Now().Add(-2*SignatureTolerance), body)
+	assert.ErrorIs(t, v.Verify(stale, testSigningSecret), ErrStaleSignature)
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	assert.ErrorIs(t, v.Verify(unsigned, testSigningSecret), ErrMissingSignature)
+}
+
+func TestSignatureVerifier_ReplaysAcrossReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	first, second := NewSignatureVerifier(client), NewSignatureVerifier(client)
+	now := time.Now()
+
+	require.NoError(t, first.Verify(signedRequest(testSigningSecret, now, "{}"), testSigningSecret))
+	assert.ErrorIs(t, second.Verify(signedRequest(testSigningSecret, now, "{}"), testSigningSecret), ErrReplayedRequest)
+}
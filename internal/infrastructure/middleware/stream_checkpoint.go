@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// streamCheckpointKey holds the checkpointer of a streamed chat request
+const streamCheckpointKey contextKey = "stream_checkpointer"
+
+// StreamCheckpointer records the usage of a long stream while it runs, so a
+// gateway that crashes mid-stream has already billed most of it. Every
+// interval the tokens streamed since the last checkpoint are queued as a
+// usage record of their own; the final record of the request carries the
+// remainder.
+type StreamCheckpointer struct {
+	middleware *AsyncBudgetMiddleware
+	ctx        context.Context
+	request    providers.ChatRequest
+	entityType string
+	entityID   string
+	interval   time.Duration
+
+	mu          sync.Mutex
+	streamed    int // Completion tokens streamed so far
+	last        time.Time
+	checkpoints int
+	recorded    checkpointedUsage
+	finished    bool
+}
+
+// checkpointedUsage is the usage the checkpoints of a stream recorded
+type checkpointedUsage struct {
+	inputTokens  int
+	outputTokens int
+	cost         float64
+}
+
+// GetStreamCheckpointer returns the checkpointer of a streamed chat request,
+// or nil when its usage is only recorded once it ends
+func GetStreamCheckpointer(ctx context.Context) *StreamCheckpointer {
+	checkpointer, _ := ctx.Value(streamCheckpointKey).(*StreamCheckpointer)
+	return checkpointer
+}
+
+// Progress reports the completion tokens streamed so far and writes a
+// checkpoint when the interval has passed since the last one. Checkpoints are
+// written by the streaming goroutine, so none is in flight when the final
+// record is built.
+func (c *StreamCheckpointer) Progress(completionTokens int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.finished {
+		return
+	}
+	c.streamed = completionTokens
+	now := time.Now()
+	if now.Sub(c.last) < c.interval {
+		return
+	}
+	c.last = now
+	c.checkpoint()
+}
+
+// checkpoint queues the usage since the last checkpoint. The request's input
+// tokens are billed with the first one. A failed enqueue leaves the usage
+// for the next checkpoint or the final record. The caller must hold the lock.
+func (c *StreamCheckpointer) checkpoint() {
+	m := c.middleware
+	input := m.estimateInputTokens(c.request.Messages) - c.recorded.inputTokens
+	output := c.streamed - c.recorded.outputTokens
+	if input <= 0 && output <= 0 {
+		return
+	}
+
+	record := &redisService.UsageRecord{
+		Timestamp:    time.Now(),
+		Model:        c.request.Model,
+		Provider:     "pllm-gateway",
+		EndpointType: models.EndpointTypeForPath("/chat/completions"),
+		Method:       "POST",
+		Path:         "/chat/completions",
+		StatusCode:   200,
+		InputTokens:  input,
+		OutputTokens: output,
+		TotalTokens:  input + output,
+		Checkpoint:   c.checkpoints + 1,
+	}
+	requestID := ""
+	if metricsCtx := GetMetricsContext(c.ctx); metricsCtx != nil {
+		requestID = metricsCtx.RequestID
+		if metricsCtx.ResolvedModel != "" {
+			record.Model = metricsCtx.ResolvedModel
+		}
+		if metricsCtx.ProviderType != "" {
+			record.Provider = metricsCtx.ProviderType
+		}
+		record.RouteSlug = metricsCtx.RouteSlug
+		record.ProviderModel = metricsCtx.ProviderModel
+		record.ProviderKeyID = metricsCtx.ProviderKeyID
+	}
+	if requestID == "" {
+		return // Checkpoints without a request ID could not be told apart
+	}
+	record.RequestID = fmt.Sprintf("%s_cp%d", requestID, record.Checkpoint)
+
+	if record.ProviderModel != "" {
+		if pricing := m.getPricing(record.ProviderModel); pricing != nil {
+			record.CostBreakdown = config.NewCostBreakdown(record.ProviderModel, pricing, config.CostUsage{
+				InputTokens:  input,
+				OutputTokens: output,
+			}, m.markupPercent)
+			record.CostBreakdown.Estimated = true
+			record.TotalCost = record.CostBreakdown.TotalCost
+		}
+	}
+	m.setUsageOwner(c.ctx, record, c.entityType, c.entityID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.usageQueue.EnqueueUsage(ctx, record); err != nil {
+		m.logger.Error("Failed to enqueue stream checkpoint",
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		return
+	}
+
+	c.checkpoints = record.Checkpoint
+	c.recorded.inputTokens += input
+	c.recorded.outputTokens += output
+	c.recorded.cost += record.TotalCost
+	if record.TotalCost > 0 {
+		go m.updateBudgetCacheAsync(c.entityType, c.entityID, record.TotalCost)
+	}
+}
+
+// finish returns the completion tokens streamed and the usage the
+// checkpoints recorded, for the final record of the request
+func (c *StreamCheckpointer) finish() (streamed int, recorded checkpointedUsage, checkpoints int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// No checkpoint is written once the final record is being built
+	c.finished = true
+	return c.streamed, c.recorded, c.checkpoints
+}
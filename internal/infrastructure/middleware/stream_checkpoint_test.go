@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// checkpointTestQueue collects enqueued usage records
+type checkpointTestQueue struct {
+	redisService.UsageQueueBackend
+	mu      sync.Mutex
+	records []*redisService.UsageRecord
+}
+
+func (q *checkpointTestQueue) EnqueueUsage(ctx context.Context, record *redisService.UsageRecord) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.records = append(q.records, record)
+	return nil
+}
+
+func (q *checkpointTestQueue) Records() []*redisService.UsageRecord {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*redisService.UsageRecord(nil), q.records...)
+}
+
+// checkpointTestBudgetCache allows every request and sums the spend
+type checkpointTestBudgetCache struct {
+	redisService.BudgetCacheBackend
+	mu    sync.Mutex
+	spent float64
+}
+
+func (c *checkpointTestBudgetCache) CheckBudgetAvailable(ctx context.Context, entityType, entityID string, requestCost float64) (bool, error) {
+	return true, nil
+}
+
+func (c *checkpointTestBudgetCache) IncrementSpent(ctx context.Context, entityType, entityID string, amount float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spent += amount
+	return nil
+}
+
+func (c *checkpointTestBudgetCache) Spent() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spent
+}
+
+func TestStreamCheckpoints(t *testing.T) {
+	pricingManager := config.GetPricingManager()
+	pricingManager.RegisterModel("checkpoint-test-model", &config.ModelPricingInfo{
+		InputCostPerToken:  0.001,
+		OutputCostPerToken: 0.002,
+	})
+	queue, cache := &checkpointTestQueue{}, &checkpointTestBudgetCache{}
+	m := NewAsyncBudgetMiddleware(&AsyncBudgetConfig{
+		Logger:                   zap.NewNop(),
+		BudgetCache:              cache,
+		UsageQueue:               queue,
+		PricingManager:           pricingManager,
+		StreamCheckpointInterval: time.Nanosecond,
+	})
+
+	handler := m.EnforceBudgetAsync(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkpointer := GetStreamCheckpointer(r.Context())
+		require.NotNil(t, checkpointer)
+		checkpointer.Progress(100)
+		checkpointer.Progress(250)
+		SetTokenUsage(r.Context(), 30, 300, 0)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	key := &models.Key{}
+	key.ID = uuid.New()
+	ctx := context.WithValue(context.Background(), KeyContextKey, key)
+	ctx = context.WithValue(ctx, MetricsContextKey, &MetricsContext{
+		RequestID:     "req-1",
+		ProviderModel: "checkpoint-test-model",
+		ProviderType:  "openai",
+	})
+	body := `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"` + strings.Repeat("a", 80) + `"}]}`
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)).WithContext(ctx))
+
+	require.Eventually(t, func() bool { return len(queue.Records()) == 3 }, time.Second, 5*time.Millisecond)
+	records := queue.Records()
+
+	assert.Equal(t, "req-1_cp1", records[0].RequestID)
+	assert.Equal(t, 1, records[0].Checkpoint)
+	assert.Equal(t, 20, records[0].InputTokens, "the estimated input is billed with the first checkpoint")
+	assert.Equal(t, 100, records[0].OutputTokens)
+	assert.Equal(t, key.ID.String(), records[0].KeyID)
+
+	assert.Equal(t, "req-1_cp2", records[1].RequestID)
+	assert.Equal(t, 0, records[1].InputTokens)
+	assert.Equal(t, 150, records[1].OutputTokens)
+
+	final := records[2]
+	assert.Equal(t, "req-1", final.RequestID)
+	assert.Zero(t, final.Checkpoint)
+	assert.Equal(t, 10, final.InputTokens, "the final record carries the remainder of the reported usage")
+	assert.Equal(t, 50, final.OutputTokens)
+	require.NotNil(t, final.CostBreakdown)
+	assert.InDelta(t, 0.03+0.6, final.CostBreakdown.TotalCost, 1e-9)
+	assert.InDelta(t, 0.02+0.2+0.3, final.CostBreakdown.Checkpointed, 1e-9)
+
+	total := 0.0
+	for _, record := range records {
+		total += record.TotalCost
+	}
+	assert.InDelta(t, 0.63, total, 1e-9, "checkpoints and the final record add up to the request's cost")
+	assert.Eventually(t, func() bool { return cache.Spent() > 0.63-1e-9 }, time.Second, 5*time.Millisecond)
+}
+
+func TestStreamCheckpoints_OnlyLongStreams(t *testing.T) {
+	queue, cache := &checkpointTestQueue{}, &checkpointTestBudgetCache{}
+	m := NewAsyncBudgetMiddleware(&AsyncBudgetConfig{
+		Logger:                   zap.NewNop(),
+		BudgetCache:              cache,
+		UsageQueue:               queue,
+		PricingManager:           config.GetPricingManager(),
+		StreamCheckpointInterval: time.Hour,
+	})
+
+	var checkpointer *StreamCheckpointer
+	handler := m.EnforceBudgetAsync(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checkpointer = GetStreamCheckpointer(r.Context())
+		if checkpointer != nil {
+			checkpointer.Progress(100)
+		}
+	}))
+
+	key := &models.Key{}
+	key.ID = uuid.New()
+	ctx := context.WithValue(context.Background(), KeyContextKey, key)
+	ctx = context.WithValue(ctx, MetricsContextKey, &MetricsContext{RequestID: "req-2"})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","messages":[]}`)).WithContext(ctx))
+	assert.Nil(t, checkpointer, "requests that are not streamed are not checkpointed")
+	require.Eventually(t, func() bool { return len(queue.Records()) == 1 }, time.Second, 5*time.Millisecond)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[]}`)).WithContext(ctx))
+	require.NotNil(t, checkpointer)
+
+	require.Eventually(t, func() bool { return len(queue.Records()) == 2 }, time.Second, 5*time.Millisecond)
+	for _, record := range queue.Records() {
+		assert.Zero(t, record.Checkpoint, "streams shorter than the interval have no checkpoints")
+	}
+	assert.Equal(t, 100, queue.Records()[1].OutputTokens, "the streamed tokens replace the default estimate")
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Synthetic monitoring endpoint
+const (
+	SyntheticChatPath = "/v1/synthetic/chat"
+	SyntheticHeader   = "X-PLLM-Synthetic" // Set on responses to synthetic requests
+
+	SyntheticContextKey contextKey = "synthetic"
+
+	// The canned request asks for a one-word answer, so a check costs next
+	// to nothing
+	syntheticPrompt    = "Reply with the single word OK."
+	syntheticMaxTokens = 5
+)
+
+var syntheticRequests = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_synthetic_requests_total",
+		Help: "Synthetic monitoring requests by response status",
+	},
+	[]string{"status"},
+)
+
+// Synthetic serves the synthetic monitoring endpoint for external uptime
+// monitors. It rewrites a request to SyntheticChatPath into a tiny canned
+// chat completion for the model named by the "model" query parameter or
+// JSON body field, and marks it synthetic. The rewritten request goes
+// through authentication, routing, guardrails and the budget check like any
+// other, but needs the monitoring scope, bypasses the response caches and
+// is neither billed nor recorded in usage analytics. It must run after the
+// metrics middleware and before everything else that looks at the path.
+func Synthetic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != SyntheticChatPath && r.URL.Path != "/api"+SyntheticChatPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			writeSyntheticError(w, http.StatusMethodNotAllowed, "Use GET or POST")
+			return
+		}
+
+		model := r.URL.Query().Get("model")
+		if model == "" && r.Method == http.MethodPost && r.Body != nil {
+			var body struct {
+				Model string `json:"model"`
+			}
+			if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&body); err != nil && err != io.EOF {
+				writeSyntheticError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+			model = body.Model
+		}
+		if model == "" {
+			writeSyntheticError(w, http.StatusBadRequest, "A model is required, as the model query parameter or body field")
+			return
+		}
+
+		maxTokens := syntheticMaxTokens
+		payload, err := json.Marshal(providers.ChatRequest{
+			Model:     model,
+			Messages:  []providers.Message{{Role: "user", Content: syntheticPrompt}},
+			MaxTokens: &maxTokens,
+		})
+		if err != nil {
+			writeSyntheticError(w, http.StatusInternalServerError, "Failed to build synthetic request")
+			return
+		}
+
+		canned := r.Clone(context.WithValue(r.Context(), SyntheticContextKey, true))
+		canned.Method = http.MethodPost
+		canned.URL.Path = "/v1/chat/completions"
+		if r.URL.Path != SyntheticChatPath {
+			canned.URL.Path = "/api/v1/chat/completions"
+		}
+		canned.URL.RawPath = ""
+		canned.URL.RawQuery = ""
+		canned.Body = io.NopCloser(bytes.NewReader(payload))
+		canned.ContentLength = int64(len(payload))
+		canned.Header.Set("Content-Type", "application/json")
+		canned.Header.Set("Cache-Control", "no-cache") // Always reach the provider
+
+		w.Header().Set(SyntheticHeader, "true")
+		writer := &syntheticWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(writer, canned)
+		syntheticRequests.WithLabelValues(strconv.Itoa(writer.status)).Inc()
+	})
+}
+
+// IsSynthetic reports whether the request is a synthetic monitoring request
+func IsSynthetic(ctx context.Context) bool {
+	synthetic, _ := ctx.Value(SyntheticContextKey).(bool)
+	return synthetic
+}
+
+// syntheticWriter captures the status of the response
+type syntheticWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *syntheticWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *syntheticWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *syntheticWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func writeSyntheticError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(providers.ErrorResponse{
+		Error: providers.APIError{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	}); err != nil {
+		log.Printf("Failed to encode synthetic error response: %v", err)
+	}
+}
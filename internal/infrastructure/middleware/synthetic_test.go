@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/auth"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func TestSynthetic_RewritesRequest(t *testing.T) {
+	var got *http.Request
+	var body providers.ChatRequest
+	handler := Synthetic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		path string
+	}{
+		{"query parameter", httptest.NewRequest(http.MethodGet, "/v1/synthetic/chat?model=gpt-4o", nil), "/v1/chat/completions"},
+		{"body field", httptest.NewRequest(http.MethodPost, "/v1/synthetic/chat", strings.NewReader(`{"model":"gpt-4o"}`)), "/v1/chat/completions"},
+		{"api prefix", httptest.NewRequest(http.MethodGet, "/api/v1/synthetic/chat?model=gpt-4o", nil), "/api/v1/chat/completions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.req)
+
+			require.NotNil(t, got)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "true", rec.Header().Get(SyntheticHeader))
+			assert.True(t, IsSynthetic(got.Context()))
+			assert.Equal(t, http.MethodPost, got.Method)
+			assert.Equal(t, tt.path, got.URL.Path)
+			assert.Empty(t, got.URL.RawQuery)
+			assert.Equal(t, "no-cache", got.Header.Get("Cache-Control"))
+
+			assert.Equal(t, "gpt-4o", body.Model)
+			require.Len(t, body.Messages, 1)
+			require.NotNil(t, body.MaxTokens)
+			assert.Equal(t, syntheticMaxTokens, *body.MaxTokens)
+			assert.False(t, body.Stream)
+		})
+	}
+}
+
+func TestSynthetic_RejectsInvalidRequests(t *testing.T) {
+	called := false
+	handler := Synthetic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	serve := func(method, target, body string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/v1/synthetic/chat", ""))
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/v1/synthetic/chat", "{"))
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/v1/synthetic/chat?model=gpt-4o", ""))
+	assert.False(t, called)
+}
+
+func TestSynthetic_OtherPathsUntouched(t *testing.T) {
+	var got *http.Request
+	handler := Synthetic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.NotNil(t, got)
+	assert.False(t, IsSynthetic(got.Context()))
+	assert.Empty(t, rec.Header().Get(SyntheticHeader))
+	data, _ := io.ReadAll(got.Body)
+	assert.JSONEq(t, `{"model":"gpt-4o"}`, string(data))
+}
+
+func TestAuthenticate_SyntheticNeedsMonitoringScope(t *testing.T) {
+	masterKeyService := auth.NewMasterKeyService(&auth.MasterKeyConfig{
+		MasterKey:       "test-master-key",
+		JWTSecret:       []byte("test-jwt-secret"),
+		RequireExchange: true,
+	})
+	authService, err := auth.NewAuthService(&auth.AuthConfig{
+		JWTSecret:        "test-jwt-secret",
+		MasterKeyService: masterKeyService,
+		Logger:           zap.NewNop(),
+	})
+	require.NoError(t, err)
+	m := NewAuthMiddleware(&AuthConfig{
+		Logger:           zap.NewNop(),
+		AuthService:      authService,
+		MasterKeyService: masterKeyService,
+		RequireAuth:      true,
+	})
+	handler := Synthetic(m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	serve := func(path string, scopes ...string) int {
+		token, err := masterKeyService.ExchangeMasterKey(context.Background(), "test-master-key", auth.AdminTokenRequest{Scopes: scopes})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/v1/synthetic/chat?model=gpt-4o", models.ScopeMonitoring))
+	assert.Equal(t, http.StatusForbidden, serve("/v1/synthetic/chat?model=gpt-4o"), "the wildcard does not imply monitoring")
+	assert.Equal(t, http.StatusForbidden, serve("/v1/synthetic/chat?model=gpt-4o", models.ScopeChat))
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Headers reporting a model rewritten by the team's model policy
+const (
+	// ModelRewrittenFromHeader carries the model the client asked for
+	// ("default" when it sent none)
+	ModelRewrittenFromHeader = "X-PLLM-Model-Rewritten-From"
+	// ModelRewrittenToHeader carries the model the request was sent to
+	ModelRewrittenToHeader = "X-PLLM-Model-Rewritten-To"
+	// ModelRewriteReasonHeader is "team_default" or "team_alias"
+	ModelRewriteReasonHeader = "X-PLLM-Model-Rewrite-Reason"
+)
+
+// DefaultModelName is the model name clients send to get their team's
+// default model
+const DefaultModelName = "default"
+
+const (
+	rewriteReasonDefault = "team_default"
+	rewriteReasonAlias   = "team_alias"
+)
+
+// teamModelEndpoints are the endpoints whose JSON body names a model
+var teamModelEndpoints = []string{
+	"/chat/completions",
+	"/completions",
+	"/embeddings",
+	"/rerank",
+	"/messages",
+	"/images/generations",
+	"/audio/speech",
+	"/moderations",
+}
+
+// TeamModelMiddleware applies the model policy of the key's team: requests
+// without a model (or for "default") get the team's default model, and
+// models the team aliases are rewritten to their targets
+type TeamModelMiddleware struct {
+	logger *zap.Logger
+}
+
+// NewTeamModelMiddleware creates a new team model middleware
+func NewTeamModelMiddleware(logger *zap.Logger) *TeamModelMiddleware {
+	return &TeamModelMiddleware{logger: logger.Named("team_model_middleware")}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *TeamModelMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		team := keyTeam(r)
+		if team == nil || r.Method != http.MethodPost || !isTeamModelEndpoint(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if team.DefaultModel == "" && len(team.ModelAliases) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+
+		// Malformed requests are left for the handler to reject
+		var request map[string]json.RawMessage
+		if err := json.Unmarshal(body, &request); err != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+		var requested string
+		if raw, ok := request["model"]; ok {
+			if err := json.Unmarshal(raw, &requested); err != nil {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		model, reason := ResolveTeamModel(team, requested)
+		if reason != "" {
+			encoded, _ := json.Marshal(model)
+			request["model"] = encoded
+			if rewritten, err := json.Marshal(request); err == nil {
+				body = rewritten
+				from := requested
+				if from == "" {
+					from = DefaultModelName
+				}
+				w.Header().Set(ModelRewrittenFromHeader, from)
+				w.Header().Set(ModelRewrittenToHeader, model)
+				w.Header().Set(ModelRewriteReasonHeader, reason)
+				m.logger.Debug("Rewrote requested model",
+					zap.String("team_id", team.ID.String()),
+					zap.String("from", from),
+					zap.String("to", model),
+					zap.String("reason", reason))
+			} else {
+				m.logger.Error("Failed to encode rewritten request", zap.Error(err))
+			}
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ResolveTeamModel returns the model a team's request for model is served
+// by and why it was rewritten, or model and "" when the team leaves it
+// alone. The default model is itself subject to the team's aliases; aliases
+// are applied once, so they cannot loop.
+func ResolveTeamModel(team *models.Team, model string) (string, string) {
+	reason := ""
+	if (model == "" || model == DefaultModelName) && team.DefaultModel != "" {
+		model = team.DefaultModel
+		reason = rewriteReasonDefault
+	}
+
+	if len(team.ModelAliases) > 0 {
+		var aliases map[string]string
+		if err := json.Unmarshal(team.ModelAliases, &aliases); err == nil {
+			if target := aliases[model]; target != "" && target != model {
+				model = target
+				if reason == "" {
+					reason = rewriteReasonAlias
+				}
+			}
+		}
+	}
+	return model, reason
+}
+
+// keyTeam returns the team of the request's API key, if any
+func keyTeam(r *http.Request) *models.Team {
+	key, ok := r.Context().Value(KeyContextKey).(*models.Key)
+	if !ok || key == nil || key.TeamID == nil || key.Team == nil {
+		return nil
+	}
+	return key.Team
+}
+
+func isTeamModelEndpoint(path string) bool {
+	for _, endpoint := range teamModelEndpoints {
+		if strings.HasSuffix(path, endpoint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func TestTeamModelMiddleware(t *testing.T) {
+	team := &models.Team{
+		DefaultModel: "gpt-4o",
+		ModelAliases: datatypes.JSON(`{"gpt-3.5-turbo":"gpt-4o-mini","gpt-4o":"gpt-4o-2024-08-06"}`),
+	}
+	team.ID = uuid.New()
+
+	var received map[string]interface{}
+	handler := NewTeamModelMiddleware(zap.NewNop()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = nil
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path, body string, team *models.Team) *httptest.ResponseRecorder {
+		key := &models.Key{Team: team}
+		if team != nil {
+			key.TeamID = &team.ID
+		}
+		ctx := context.WithValue(context.Background(), KeyContextKey, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)).WithContext(ctx))
+		return rec
+	}
+
+	t.Run("alias", func(t *testing.T) {
+		rec := serve("/v1/chat/completions", `{"model":"gpt-3.5-turbo","messages":[{"role":"user","content":"hi"}]}`, team)
+		assert.Equal(t, "gpt-4o-mini", received["model"])
+		assert.NotNil(t, received["messages"], "other fields are kept")
+		assert.Equal(t, "gpt-3.5-turbo", rec.Header().Get(ModelRewrittenFromHeader))
+		assert.Equal(t, "gpt-4o-mini", rec.Header().Get(ModelRewrittenToHeader))
+		assert.Equal(t, "team_alias", rec.Header().Get(ModelRewriteReasonHeader))
+	})
+
+	t.Run("default model is aliased too", func(t *testing.T) {
+		for _, body := range []string{`{"input":"hi"}`, `{"model":"default","input":"hi"}`} {
+			rec := serve("/api/v1/embeddings", body, team)
+			assert.Equal(t, "gpt-4o-2024-08-06", received["model"])
+			assert.Equal(t, "default", rec.Header().Get(ModelRewrittenFromHeader))
+			assert.Equal(t, "team_default", rec.Header().Get(ModelRewriteReasonHeader))
+		}
+	})
+
+	t.Run("other models pass through", func(t *testing.T) {
+		rec := serve("/v1/chat/completions", `{"model":"claude-3-5-sonnet"}`, team)
+		assert.Equal(t, "claude-3-5-sonnet", received["model"])
+		assert.Empty(t, rec.Header().Get(ModelRewrittenFromHeader))
+	})
+
+	t.Run("keys without a team are left alone", func(t *testing.T) {
+		rec := serve("/v1/chat/completions", `{"model":"default"}`, nil)
+		assert.Equal(t, "default", received["model"])
+		assert.Empty(t, rec.Header().Get(ModelRewrittenFromHeader))
+	})
+
+	t.Run("other endpoints are left alone", func(t *testing.T) {
+		serve("/v1/files", `{"model":"gpt-3.5-turbo"}`, team)
+		assert.Equal(t, "gpt-3.5-turbo", received["model"])
+	})
+}
+
+func TestResolveTeamModel(t *testing.T) {
+	team := &models.Team{ModelAliases: datatypes.JSON(`{"a":"b","b":"c"}`)}
+
+	model, reason := ResolveTeamModel(team, "a")
+	assert.Equal(t, "b", model, "aliases are applied once")
+	assert.Equal(t, "team_alias", reason)
+
+	model, reason = ResolveTeamModel(team, "default")
+	require.Empty(t, reason, "no default model is configured")
+	assert.Equal(t, "default", model)
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Headers reporting the team system prompt applied to a request
+const (
+	// SystemPromptHeader carries the policy the team's prompt was applied
+	// with ("prepend" or "replace")
+	SystemPromptHeader = "X-PLLM-System-Prompt"
+	// SystemPromptHashHeader carries the first 16 hex characters of the
+	// SHA-256 of the prompt, so clients can tell versions apart without
+	// seeing it
+	SystemPromptHashHeader = "X-PLLM-System-Prompt-Hash"
+	// SystemPromptConflictHeader is "kept" or "dropped" when the client
+	// sent its own system messages
+	SystemPromptConflictHeader = "X-PLLM-System-Prompt-Conflict"
+)
+
+// TeamSystemPromptMiddleware puts the system prompt of the key's team
+// before the messages of chat completions and Anthropic messages, applying
+// the team's policy to the system messages clients send themselves
+type TeamSystemPromptMiddleware struct {
+	logger *zap.Logger
+}
+
+// NewTeamSystemPromptMiddleware creates a new team system prompt middleware
+func NewTeamSystemPromptMiddleware(logger *zap.Logger) *TeamSystemPromptMiddleware {
+	return &TeamSystemPromptMiddleware{logger: logger.Named("team_system_prompt_middleware")}
+}
+
+// Middleware returns the HTTP middleware function
+func (m *TeamSystemPromptMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		team := keyTeam(r)
+		anthropic := strings.HasSuffix(r.URL.Path, "/messages")
+		if team == nil || team.SystemPrompt == "" || r.Method != http.MethodPost ||
+			(!anthropic && !strings.HasSuffix(r.URL.Path, "/chat/completions")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyReadError(w, err)
+			return
+		}
+
+		// Malformed requests are left for the handler to reject
+		var request map[string]json.RawMessage
+		if err := json.Unmarshal(body, &request); err != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		policy := team.GetSystemPromptPolicy()
+		var conflict bool
+		if anthropic {
+			conflict, err = applyAnthropicSystemPrompt(request, team.SystemPrompt, policy)
+		} else {
+			conflict, err = applyChatSystemPrompt(request, team.SystemPrompt, policy)
+		}
+		if err != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+		if conflict && policy == models.SystemPromptReject {
+			writeSystemPromptConflict(w)
+			return
+		}
+
+		rewritten, err := json.Marshal(request)
+		if err != nil {
+			m.logger.Error("Failed to encode request with the team system prompt", zap.Error(err))
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+		body = rewritten
+
+		w.Header().Set(SystemPromptHeader, policy)
+		w.Header().Set(SystemPromptHashHeader, SystemPromptHash(team.SystemPrompt))
+		if conflict {
+			outcome := "kept"
+			if policy == models.SystemPromptReplace {
+				outcome = "dropped"
+			}
+			w.Header().Set(SystemPromptConflictHeader, outcome)
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SystemPromptHash identifies a system prompt in the SystemPromptHashHeader
+func SystemPromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// applyChatSystemPrompt puts the prompt first in the messages of a chat
+// completion, dropping the client's system and developer messages for the
+// replace policy. It reports whether the client sent any.
+func applyChatSystemPrompt(request map[string]json.RawMessage, prompt, policy string) (bool, error) {
+	var messages []json.RawMessage
+	if raw, ok := request["messages"]; ok {
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			return false, err
+		}
+	}
+
+	system, err := json.Marshal(map[string]string{"role": "system", "content": prompt})
+	if err != nil {
+		return false, err
+	}
+	out := []json.RawMessage{system}
+	conflict := false
+	for _, message := range messages {
+		var m struct {
+			Role string `json:"role"`
+		}
+		if err := json.Unmarshal(message, &m); err != nil {
+			return false, err
+		}
+		if m.Role == "system" || m.Role == "developer" {
+			conflict = true
+			if policy == models.SystemPromptReplace {
+				continue
+			}
+		}
+		out = append(out, message)
+	}
+
+	encoded, err := json.Marshal(out)
+	if err != nil {
+		return false, err
+	}
+	request["messages"] = encoded
+	return conflict, nil
+}
+
+// applyAnthropicSystemPrompt puts the prompt first in the system field of
+// an Anthropic messages request, which is a string or a list of text
+// blocks. It reports whether the client set one.
+func applyAnthropicSystemPrompt(request map[string]json.RawMessage, prompt, policy string) (bool, error) {
+	var system interface{}
+	if raw, ok := request["system"]; ok {
+		if err := json.Unmarshal(raw, &system); err != nil {
+			return false, err
+		}
+	}
+
+	var merged interface{} = prompt
+	conflict := false
+	switch existing := system.(type) {
+	case nil:
+	case string:
+		conflict = existing != ""
+		if conflict && policy != models.SystemPromptReplace {
+			merged = prompt + "\n\n" + existing
+		}
+	case []interface{}:
+		conflict = len(existing) > 0
+		if conflict && policy != models.SystemPromptReplace {
+			block := map[string]interface{}{"type": "text", "text": prompt}
+			merged = append([]interface{}{block}, existing...)
+		}
+	default:
+		return false, errInvalidSystemField
+	}
+
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return false, err
+	}
+	request["system"] = encoded
+	return conflict, nil
+}
+
+var errInvalidSystemField = errors.New("system must be a string or a list of blocks")
+
+func writeSystemPromptConflict(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": "This key's team sets the system prompt; send the request without system messages",
+			"type":    "invalid_request_error",
+			"code":    "system_prompt_conflict",
+		},
+	})
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func TestTeamSystemPromptMiddleware(t *testing.T) {
+	const prompt = "Answers are not legal advice."
+
+	var received map[string]interface{}
+	calls := 0
+	handler := NewTeamSystemPromptMiddleware(zap.NewNop()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		received = nil
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path, body, policy string) *httptest.ResponseRecorder {
+		team := &models.Team{SystemPrompt: prompt, SystemPromptPolicy: policy}
+		team.ID = uuid.New()
+		key := &models.Key{Team: team, TeamID: &team.ID}
+		ctx := context.WithValue(context.Background(), KeyContextKey, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)).WithContext(ctx))
+		return rec
+	}
+	roles := func() []string {
+		var out []string
+		for _, message := range received["messages"].([]interface{}) {
+			out = append(out, message.(map[string]interface{})["role"].(string))
+		}
+		return out
+	}
+	chat := `{"model":"gpt-4o","messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"hi"}]}`
+
+	t.Run("added to chat completions", func(t *testing.T) {
+		rec := serve("/v1/chat/completions", `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`, "")
+		assert.Equal(t, []string{"system", "user"}, roles())
+		first := received["messages"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, prompt, first["content"])
+		assert.Equal(t, "gpt-4o", received["model"], "other fields are kept")
+		assert.Equal(t, models.SystemPromptPrepend, rec.Header().Get(SystemPromptHeader))
+		assert.Equal(t, SystemPromptHash(prompt), rec.Header().Get(SystemPromptHashHeader))
+		assert.Len(t, rec.Header().Get(SystemPromptHashHeader), 16)
+		assert.Empty(t, rec.Header().Get(SystemPromptConflictHeader))
+	})
+
+	t.Run("prepend keeps the client's system messages", func(t *testing.T) {
+		rec := serve("/v1/chat/completions", chat, models.SystemPromptPrepend)
+		assert.Equal(t, []string{"system", "system", "user"}, roles())
+		assert.Equal(t, "kept", rec.Header().Get(SystemPromptConflictHeader))
+	})
+
+	t.Run("replace drops the client's system messages", func(t *testing.T) {
+		rec := serve("/v1/chat/completions", chat, models.SystemPromptReplace)
+		assert.Equal(t, []string{"system", "user"}, roles())
+		assert.Equal(t, models.SystemPromptReplace, rec.Header().Get(SystemPromptHeader))
+		assert.Equal(t, "dropped", rec.Header().Get(SystemPromptConflictHeader))
+	})
+
+	t.Run("reject fails requests with system messages", func(t *testing.T) {
+		calls = 0
+		rec := serve("/v1/chat/completions", chat, models.SystemPromptReject)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "system_prompt_conflict")
+		assert.Zero(t, calls)
+
+		serve("/v1/chat/completions", `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`, models.SystemPromptReject)
+		assert.Equal(t, []string{"system", "user"}, roles(), "requests without them are served")
+	})
+
+	t.Run("anthropic messages", func(t *testing.T) {
+		serve("/v1/messages", `{"model":"claude","messages":[{"role":"user","content":"hi"}]}`, "")
+		assert.Equal(t, prompt, received["system"])
+
+		rec := serve("/v1/messages", `{"model":"claude","system":"Be brief.","messages":[]}`, "")
+		assert.Equal(t, prompt+"\n\nBe brief.", received["system"])
+		assert.Equal(t, "kept", rec.Header().Get(SystemPromptConflictHeader))
+
+		serve("/v1/messages", `{"model":"claude","system":[{"type":"text","text":"Be brief."}],"messages":[]}`, "")
+		blocks := received["system"].([]interface{})
+		require.Len(t, blocks, 2)
+		assert.Equal(t, prompt, blocks[0].(map[string]interface{})["text"])
+
+		serve("/v1/messages", `{"model":"claude","system":"Be brief.","messages":[]}`, models.SystemPromptReplace)
+		assert.Equal(t, prompt, received["system"])
+	})
+
+	t.Run("other endpoints are left alone", func(t *testing.T) {
+		rec := serve("/v1/embeddings", `{"model":"text-embedding-3-small","input":"hi"}`, "")
+		assert.Nil(t, received["messages"])
+		assert.Empty(t, rec.Header().Get(SystemPromptHeader))
+	})
+}
@@ -15,7 +15,7 @@ import (
 	postgresdriver "gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
-	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/core/database"
 )
 
 // NewTestDB creates a PostgreSQL test database using Testcontainers
@@ -46,19 +46,8 @@ func NewTestDB(t *testing.T) (*gorm.DB, func()) {
 	db, err := gorm.Open(postgresdriver.Open(connStr), &gorm.Config{})
 	require.NoError(t, err, "Failed to connect to test database")
 
-	// Auto-migrate all models
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Team{},
-		&models.Key{},
-		&models.Usage{},
-		&models.TeamMember{},
-		&models.Audit{},
-		&models.SystemMetrics{},
-		&models.ModelMetrics{},
-		&models.UserMetrics{},
-		&models.TeamMetrics{},
-	)
+	// Apply the migrations, like a production database
+	_, err = database.MigrateUp(db, 0)
 	require.NoError(t, err, "Failed to migrate test database")
 
 	// Return cleanup function that terminates the container
@@ -0,0 +1,113 @@
+// Package webhook delivers webhooks to user-supplied URLs without exposing
+// the gateway's own network
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Guard keeps webhooks away from the gateway's own network.
+// Hosts on the allowlist are trusted as-is; any other host must resolve to
+// public addresses only. The address check runs again when the connection is
+// dialled, so a DNS answer that changes after validation cannot reach an
+// internal address either.
+type Guard struct {
+	allowedHosts []string
+	resolver     *net.Resolver
+}
+
+// NewGuard creates a guard. allowedHosts may receive webhooks on private
+// addresses; entries starting with a dot match subdomains.
+func NewGuard(allowedHosts []string) *Guard {
+	return &Guard{allowedHosts: allowedHosts, resolver: net.DefaultResolver}
+}
+
+// Allowed reports whether host is on the allowlist
+func (g *Guard) Allowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range g.allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks a webhook URL before it is accepted
+func (g *Guard) Validate(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook URL must be an absolute http(s) URL")
+	}
+	host := u.Hostname()
+	if g.Allowed(host) {
+		return nil
+	}
+
+	addrs, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("webhook host %q does not resolve", host)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return fmt.Errorf("webhook host %q resolves to a private address", host)
+		}
+	}
+	return nil
+}
+
+// Client returns an HTTP client that does not follow redirects and refuses
+// to connect to private addresses of hosts outside the allowlist
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		dialer := &net.Dialer{Timeout: timeout}
+		if !g.Allowed(host) {
+			dialer.Control = func(network, address string, _ syscall.RawConn) error {
+				ip, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if !publicIP(net.ParseIP(ip)) {
+					return fmt.Errorf("webhook address %s is not public", ip)
+				}
+				return nil
+			}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicIP rejects loopback, private (RFC 1918, ULA), link-local (including
+// the cloud metadata address), unspecified and multicast addresses
+func publicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
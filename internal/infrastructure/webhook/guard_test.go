@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuard_Validate(t *testing.T) {
+	guard := NewGuard([]string{"hooks.internal", ".svc.local"})
+	ctx := context.Background()
+
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.5/hook",
+		"https://192.168.1.1/hook",
+		"http://[::1]/hook",
+		"http://0.0.0.0/hook",
+		"ftp://example.com/hook",
+		"/relative",
+	} {
+		assert.Error(t, guard.Validate(ctx, rawURL), rawURL)
+	}
+
+	assert.NoError(t, guard.Validate(ctx, "http://hooks.internal/done"))
+	assert.NoError(t, guard.Validate(ctx, "https://billing.svc.local/done"))
+	assert.NoError(t, guard.Validate(ctx, "https://93.184.216.34/done"))
+}
+
+func TestGuard_Client(t *testing.T) {
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	t.Run("refuses private addresses outside the allowlist", func(t *testing.T) {
+		client := NewGuard(nil).Client(time.Second)
+		_, err := client.Post(server.URL, "application/json", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		client := NewGuard([]string{"127.0.0.1"}).Client(time.Second)
+		resp, err := client.Post(server.URL, "application/json", nil)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.False(t, redirected)
+	})
+}
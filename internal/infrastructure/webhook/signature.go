@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers of signed webhook deliveries
+const (
+	EventHeader     = "X-PLLM-Event"
+	TimestampHeader = "X-PLLM-Timestamp"
+	SignatureHeader = "X-PLLM-Signature"
+)
+
+// Sign returns the signature header value of a delivery: "v1=" and the hex
+// HMAC-SHA256, keyed with the endpoint's secret, of the timestamp, a dot and
+// the body. Receivers recompute it and compare in constant time.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret creates a signing secret for a webhook endpoint
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "pllm_whs_" + hex.EncodeToString(b), nil
+}
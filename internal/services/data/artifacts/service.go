@@ -0,0 +1,584 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+const (
+	// orphanGrace is how old a blob without an artifact row must be before
+	// the reaper deletes it, leaving uploads in progress alone
+	orphanGrace = time.Hour
+
+	// requestLogBatch is how many request logs one delete statement removes
+	requestLogBatch = 5000
+)
+
+// Reasons the reaper deletes artifacts
+const (
+	ReasonTTL    = "ttl"
+	ReasonQuota  = "quota"
+	ReasonOrphan = "orphan"
+	ReasonErased = "erased"
+)
+
+var artifactsDeleted = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_artifacts_deleted_total",
+		Help: "Total number of stored artifacts, job outputs and request logs deleted by lifecycle rules and erasure",
+	},
+	[]string{"kind", "reason"},
+)
+
+// ErrHoldNotFound reports releasing a hold that does not exist or was
+// already released
+var ErrHoldNotFound = errors.New("legal hold not found")
+
+// NotHeld excludes rows owned by a user or team under an active legal hold.
+// teamColumn and userColumns name the owner columns of the queried table.
+func NotHeld(teamColumn string, userColumns ...string) func(*gorm.DB) *gorm.DB {
+	clause := "NOT " + holdExists(teamColumn, userColumns...)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause)
+	}
+}
+
+// held keeps only rows owned by a user or team under an active legal hold
+func held(teamColumn string, userColumns ...string) func(*gorm.DB) *gorm.DB {
+	clause := holdExists(teamColumn, userColumns...)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause)
+	}
+}
+
+func holdExists(teamColumn string, userColumns ...string) string {
+	owners := []string{fmt.Sprintf("h.team_id = %s", teamColumn)}
+	for _, column := range userColumns {
+		owners = append(owners, fmt.Sprintf("h.user_id = %s", column))
+	}
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM legal_holds h
+		WHERE h.released_at IS NULL AND h.deleted_at IS NULL AND (%s))`, strings.Join(owners, " OR "))
+}
+
+// Service stores artifacts and enforces their lifecycle rules
+type Service struct {
+	db     *gorm.DB
+	store  Store
+	cfg    config.ArtifactsConfig
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	lastReap *ReapReport
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService creates an artifact service over the storage backend
+func NewService(db *gorm.DB, store Store, cfg config.ArtifactsConfig, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		store:  store,
+		cfg:    cfg,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Save writes the blob and records the artifact, filling in its size
+func (s *Service) Save(ctx context.Context, artifact *models.Artifact, r io.Reader) error {
+	size, err := s.store.Put(ctx, artifact.StorageKey, r)
+	if err != nil {
+		return err
+	}
+	artifact.Size = size
+	if err := s.db.WithContext(ctx).Create(artifact).Error; err != nil {
+		_ = s.store.Delete(ctx, artifact.StorageKey)
+		return fmt.Errorf("failed to record artifact: %w", err)
+	}
+	return nil
+}
+
+// Open returns a stored artifact and its blob, or ErrNotFound
+func (s *Service) Open(ctx context.Context, storageKey string) (*models.Artifact, io.ReadCloser, error) {
+	var artifact models.Artifact
+	err := s.db.WithContext(ctx).Where("storage_key = ?", storageKey).First(&artifact).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	blob, err := s.store.Open(ctx, storageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &artifact, blob, nil
+}
+
+// Start enforces the lifecycle rules every reap interval until Stop
+func (s *Service) Start(ctx context.Context) {
+	if s.cfg.ReapInterval <= 0 {
+		return
+	}
+	s.logger.Info("Starting artifact reaper",
+		zap.Duration("interval", s.cfg.ReapInterval),
+		zap.Duration("file_ttl", s.cfg.FileTTL),
+		zap.Int64("max_team_file_bytes", s.cfg.MaxTeamFileBytes),
+		zap.Duration("request_log_ttl", s.cfg.RequestLogTTL))
+
+	ticker := time.NewTicker(s.cfg.ReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.Reap(ctx)
+		}
+	}
+}
+
+// Stop stops the reaper
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// ReapReport counts what one reaper run deleted
+type ReapReport struct {
+	StartedAt   time.Time `json:"started_at"`
+	Duration    string    `json:"duration"`
+	ExpiredFile int64     `json:"expired_files"`
+	QuotaFiles  int64     `json:"quota_files"`
+	FreedBytes  int64     `json:"freed_bytes"`
+	OrphanBlobs int64     `json:"orphan_blobs"`
+	RequestLogs int64     `json:"request_logs"`
+	Errors      []string  `json:"errors,omitempty"`
+}
+
+// Reap enforces the lifecycle rules once: expired files, files over their
+// team's quota, expired request logs and blobs no artifact refers to.
+// Everything owned by a user or team under legal hold is kept. Deletes are
+// idempotent, so replicas may reap at the same time.
+func (s *Service) Reap(ctx context.Context) *ReapReport {
+	report := &ReapReport{StartedAt: time.Now()}
+	fail := func(step string, err error) {
+		s.logger.Error("Artifact reaper step failed", zap.String("step", step), zap.Error(err))
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", step, err))
+	}
+
+	if s.cfg.FileTTL > 0 {
+		var expired []models.Artifact
+		err := s.db.WithContext(ctx).Scopes(NotHeld("team_id", "user_id")).
+			Where("created_at < ?", report.StartedAt.Add(-s.cfg.FileTTL)).
+			Find(&expired).Error
+		if err != nil {
+			fail("expired files", err)
+		}
+		for i := range expired {
+			if err := s.delete(ctx, &expired[i], ReasonTTL); err != nil {
+				fail("expired files", err)
+				continue
+			}
+			report.ExpiredFile++
+			report.FreedBytes += expired[i].Size
+		}
+	}
+
+	if s.cfg.MaxTeamFileBytes > 0 {
+		count, freed, err := s.enforceQuotas(ctx)
+		if err != nil {
+			fail("team quotas", err)
+		}
+		report.QuotaFiles += count
+		report.FreedBytes += freed
+	}
+
+	if s.cfg.RequestLogTTL > 0 {
+		deleted, err := s.deleteRequestLogs(ctx, report.StartedAt.Add(-s.cfg.RequestLogTTL))
+		if err != nil {
+			fail("request logs", err)
+		}
+		report.RequestLogs = deleted
+	}
+
+	orphans, err := s.deleteOrphans(ctx, report.StartedAt)
+	if err != nil {
+		fail("orphan blobs", err)
+	}
+	report.OrphanBlobs = orphans
+
+	report.Duration = time.Since(report.StartedAt).String()
+	s.mu.Lock()
+	s.lastReap = report
+	s.mu.Unlock()
+
+	if report.ExpiredFile+report.QuotaFiles+report.OrphanBlobs+report.RequestLogs > 0 {
+		s.logger.Info("Artifact reaper run",
+			zap.Int64("expired_files", report.ExpiredFile),
+			zap.Int64("quota_files", report.QuotaFiles),
+			zap.Int64("freed_bytes", report.FreedBytes),
+			zap.Int64("orphan_blobs", report.OrphanBlobs),
+			zap.Int64("request_logs", report.RequestLogs))
+	}
+	return report
+}
+
+// LastReap returns the report of the latest reaper run, nil before the first
+func (s *Service) LastReap() *ReapReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastReap
+}
+
+// enforceQuotas deletes the oldest files of each team over its quota until it
+// fits, skipping held files
+func (s *Service) enforceQuotas(ctx context.Context) (int64, int64, error) {
+	var teams []struct {
+		TeamID uuid.UUID
+		Bytes  int64
+	}
+	err := s.db.WithContext(ctx).Model(&models.Artifact{}).
+		Select("team_id, SUM(size) as bytes").
+		Where("team_id IS NOT NULL").
+		Group("team_id").
+		Having("SUM(size) > ?", s.cfg.MaxTeamFileBytes).
+		Scan(&teams).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var count, freed int64
+	for _, team := range teams {
+		var files []models.Artifact
+		if err := s.db.WithContext(ctx).Scopes(NotHeld("team_id", "user_id")).
+			Where("team_id = ?", team.TeamID).
+			Order("created_at").
+			Find(&files).Error; err != nil {
+			return count, freed, err
+		}
+		over := team.Bytes - s.cfg.MaxTeamFileBytes
+		for i := range files {
+			if over <= 0 {
+				break
+			}
+			if err := s.delete(ctx, &files[i], ReasonQuota); err != nil {
+				return count, freed, err
+			}
+			over -= files[i].Size
+			freed += files[i].Size
+			count++
+		}
+	}
+	return count, freed, nil
+}
+
+// deleteRequestLogs deletes the request logs recorded before cutoff, in
+// batches so a large backlog does not hold one long transaction
+func (s *Service) deleteRequestLogs(ctx context.Context, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		ids := s.db.Model(&models.Usage{}).Unscoped().Select("id").
+			Scopes(NotHeld("team_id", "user_id", "actual_user_id")).
+			Where("timestamp < ?", cutoff).
+			Limit(requestLogBatch)
+		result := s.db.WithContext(ctx).Unscoped().Where("id IN (?)", ids).Delete(&models.Usage{})
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		artifactsDeleted.WithLabelValues("request_log", ReasonTTL).Add(float64(result.RowsAffected))
+		if result.RowsAffected < requestLogBatch {
+			return total, nil
+		}
+	}
+}
+
+// deleteOrphans deletes blobs no artifact refers to, such as blobs whose
+// artifact was deleted on another replica
+func (s *Service) deleteOrphans(ctx context.Context, now time.Time) (int64, error) {
+	objects, err := s.store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	const chunk = 500
+	for start := 0; start < len(objects); start += chunk {
+		end := start + chunk
+		if end > len(objects) {
+			end = len(objects)
+		}
+		keys := make([]string, 0, end-start)
+		for _, object := range objects[start:end] {
+			if now.Sub(object.ModTime) >= orphanGrace {
+				keys = append(keys, object.Key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		var known []string
+		if err := s.db.WithContext(ctx).Model(&models.Artifact{}).Unscoped().
+			Where("storage_key IN ?", keys).
+			Pluck("storage_key", &known).Error; err != nil {
+			return deleted, err
+		}
+		recorded := make(map[string]bool, len(known))
+		for _, key := range known {
+			recorded[key] = true
+		}
+		for _, key := range keys {
+			if recorded[key] {
+				continue
+			}
+			if err := s.store.Delete(ctx, key); err != nil {
+				return deleted, err
+			}
+			deleted++
+			artifactsDeleted.WithLabelValues(string(models.ArtifactKindFile), ReasonOrphan).Inc()
+		}
+	}
+	return deleted, nil
+}
+
+// delete removes an artifact's row, then its blob. A blob left behind by a
+// failed delete is removed later as an orphan.
+func (s *Service) delete(ctx context.Context, artifact *models.Artifact, reason string) error {
+	if err := s.db.WithContext(ctx).Unscoped().Delete(artifact).Error; err != nil {
+		return err
+	}
+	artifactsDeleted.WithLabelValues(string(artifact.Kind), reason).Inc()
+	if err := s.store.Delete(ctx, artifact.StorageKey); err != nil {
+		s.logger.Warn("Failed to delete artifact blob, leaving it for the orphan sweep",
+			zap.String("storage_key", artifact.StorageKey),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// ErasureReport counts what erasing a user deleted and what legal holds kept
+type ErasureReport struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Files       int64     `json:"files"`
+	JobOutputs  int64     `json:"job_outputs"`
+	RequestLogs int64     `json:"request_logs"`
+	Retained    int64     `json:"retained"` // Rows kept under a legal hold
+}
+
+// EraseUser deletes the files, async jobs and request logs of a user, for
+// erasure requests. Rows under a legal hold on the user or their team are
+// kept and counted as retained.
+func (s *Service) EraseUser(ctx context.Context, userID uuid.UUID) (*ErasureReport, error) {
+	report := &ErasureReport{UserID: userID}
+	db := s.db.WithContext(ctx)
+
+	var files []models.Artifact
+	if err := db.Scopes(NotHeld("team_id", "user_id")).Where("user_id = ?", userID).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	for i := range files {
+		if err := s.delete(ctx, &files[i], ReasonErased); err != nil {
+			return report, err
+		}
+		report.Files++
+	}
+
+	jobs := db.Unscoped().Scopes(NotHeld("team_id", "user_id")).Where("user_id = ?", userID).Delete(&models.Job{})
+	if jobs.Error != nil {
+		return report, jobs.Error
+	}
+	report.JobOutputs = jobs.RowsAffected
+	artifactsDeleted.WithLabelValues("job_output", ReasonErased).Add(float64(jobs.RowsAffected))
+
+	logs := db.Unscoped().Scopes(NotHeld("team_id", "user_id", "actual_user_id")).
+		Where("user_id = ? OR actual_user_id = ?", userID, userID).
+		Delete(&models.Usage{})
+	if logs.Error != nil {
+		return report, logs.Error
+	}
+	report.RequestLogs = logs.RowsAffected
+	artifactsDeleted.WithLabelValues("request_log", ReasonErased).Add(float64(logs.RowsAffected))
+
+	for _, counted := range []struct {
+		model interface{}
+		where string
+		args  []interface{}
+	}{
+		{&models.Artifact{}, "user_id = ?", []interface{}{userID}},
+		{&models.Job{}, "user_id = ?", []interface{}{userID}},
+		{&models.Usage{}, "user_id = ? OR actual_user_id = ?", []interface{}{userID, userID}},
+	} {
+		var retained int64
+		if err := db.Model(counted.model).Where(counted.where, counted.args...).Count(&retained).Error; err != nil {
+			return report, err
+		}
+		report.Retained += retained
+	}
+
+	s.logger.Warn("Erased user artifacts",
+		zap.String("user_id", userID.String()),
+		zap.Int64("files", report.Files),
+		zap.Int64("job_outputs", report.JobOutputs),
+		zap.Int64("request_logs", report.RequestLogs),
+		zap.Int64("retained", report.Retained))
+	return report, nil
+}
+
+// PlaceHold records a legal hold on a user or a team
+func (s *Service) PlaceHold(ctx context.Context, hold *models.LegalHold) error {
+	if (hold.UserID == nil) == (hold.TeamID == nil) {
+		return errors.New("a legal hold applies to either a user or a team")
+	}
+	if strings.TrimSpace(hold.Reason) == "" {
+		return errors.New("a legal hold needs a reason")
+	}
+	return s.db.WithContext(ctx).Create(hold).Error
+}
+
+// ReleaseHold releases an active legal hold
+func (s *Service) ReleaseHold(ctx context.Context, id uuid.UUID, releasedBy *uuid.UUID) (*models.LegalHold, error) {
+	var hold models.LegalHold
+	err := s.db.WithContext(ctx).Where("id = ? AND released_at IS NULL", id).First(&hold).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	hold.ReleasedAt = &now
+	hold.ReleasedBy = releasedBy
+	if err := s.db.WithContext(ctx).Model(&hold).
+		Updates(map[string]interface{}{"released_at": now, "released_by": releasedBy}).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ListHolds returns the legal holds, active ones only unless all is set
+func (s *Service) ListHolds(ctx context.Context, all bool) ([]models.LegalHold, error) {
+	query := s.db.WithContext(ctx).Order("created_at DESC")
+	if !all {
+		query = query.Where("released_at IS NULL")
+	}
+	var holds []models.LegalHold
+	return holds, query.Find(&holds).Error
+}
+
+// TeamUsage is the file storage a team uses
+type TeamUsage struct {
+	TeamID uuid.UUID `json:"team_id"`
+	Files  int64     `json:"files"`
+	Bytes  int64     `json:"bytes"`
+	Over   bool      `json:"over_quota"`
+}
+
+// Report is the state of artifact storage and its lifecycle rules
+type Report struct {
+	Rules struct {
+		ReapInterval     string `json:"reap_interval"`
+		FileTTL          string `json:"file_ttl"`
+		MaxTeamFileBytes int64  `json:"max_team_file_bytes"`
+		RequestLogTTL    string `json:"request_log_ttl"`
+	} `json:"rules"`
+	Storage     string `json:"storage"`
+	Files       int64  `json:"files"`
+	FileBytes   int64  `json:"file_bytes"`
+	JobOutputs  int64  `json:"job_outputs"`
+	RequestLogs int64  `json:"request_logs"`
+
+	// Rows past their lifecycle that a legal hold keeps
+	HeldExpiredFiles       int64 `json:"held_expired_files"`
+	HeldExpiredJobOutputs  int64 `json:"held_expired_job_outputs"`
+	HeldExpiredRequestLogs int64 `json:"held_expired_request_logs"`
+
+	ActiveHolds int64       `json:"active_holds"`
+	Teams       []TeamUsage `json:"teams"`
+	LastReap    *ReapReport `json:"last_reap,omitempty"`
+}
+
+// Report summarizes the stored artifacts, the rules applied to them and
+// what legal holds keep past those rules
+func (s *Service) Report(ctx context.Context) (*Report, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+	report := &Report{Storage: s.storageType(), LastReap: s.LastReap(), Teams: make([]TeamUsage, 0)}
+	report.Rules.ReapInterval = s.cfg.ReapInterval.String()
+	report.Rules.FileTTL = s.cfg.FileTTL.String()
+	report.Rules.MaxTeamFileBytes = s.cfg.MaxTeamFileBytes
+	report.Rules.RequestLogTTL = s.cfg.RequestLogTTL.String()
+
+	var files struct {
+		Count int64
+		Bytes int64
+	}
+	if err := db.Model(&models.Artifact{}).Select("COUNT(*) as count, COALESCE(SUM(size), 0) as bytes").Scan(&files).Error; err != nil {
+		return nil, err
+	}
+	report.Files, report.FileBytes = files.Count, files.Bytes
+
+	counts := []struct {
+		dest  *int64
+		query *gorm.DB
+	}{
+		{&report.JobOutputs, db.Model(&models.Job{})},
+		{&report.RequestLogs, db.Model(&models.Usage{})},
+		{&report.ActiveHolds, db.Model(&models.LegalHold{}).Where("released_at IS NULL")},
+		{&report.HeldExpiredJobOutputs, db.Model(&models.Job{}).Scopes(held("team_id", "user_id")).Where("expires_at < ?", now)},
+	}
+	if s.cfg.FileTTL > 0 {
+		counts = append(counts, struct {
+			dest  *int64
+			query *gorm.DB
+		}{&report.HeldExpiredFiles, db.Model(&models.Artifact{}).Scopes(held("team_id", "user_id")).Where("created_at < ?", now.Add(-s.cfg.FileTTL))})
+	}
+	if s.cfg.RequestLogTTL > 0 {
+		counts = append(counts, struct {
+			dest  *int64
+			query *gorm.DB
+		}{&report.HeldExpiredRequestLogs, db.Model(&models.Usage{}).Scopes(held("team_id", "user_id", "actual_user_id")).Where("timestamp < ?", now.Add(-s.cfg.RequestLogTTL))})
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := db.Model(&models.Artifact{}).
+		Select("team_id, COUNT(*) as files, COALESCE(SUM(size), 0) as bytes").
+		Where("team_id IS NOT NULL").
+		Group("team_id").
+		Order("bytes DESC").
+		Scan(&report.Teams).Error; err != nil {
+		return nil, err
+	}
+	for i := range report.Teams {
+		report.Teams[i].Over = s.cfg.MaxTeamFileBytes > 0 && report.Teams[i].Bytes > s.cfg.MaxTeamFileBytes
+	}
+	return report, nil
+}
+
+func (s *Service) storageType() string {
+	if s.cfg.Storage.Type == "" {
+		return "local"
+	}
+	return s.cfg.Storage.Type
+}
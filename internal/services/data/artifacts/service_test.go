@@ -0,0 +1,86 @@
+package artifacts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+)
+
+func TestService_Lifecycle(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	store := NewLocalStore(t.TempDir())
+	service := NewService(db, store, config.ArtifactsConfig{
+		FileTTL:          24 * time.Hour,
+		MaxTeamFileBytes: 10,
+		RequestLogTTL:    24 * time.Hour,
+	}, zap.NewNop())
+
+	heldUser, teamID := uuid.New(), uuid.New()
+	save := func(key string, content string, userID, teamID *uuid.UUID, age time.Duration) {
+		artifact := &models.Artifact{Kind: models.ArtifactKindFile, StorageKey: key, UserID: userID, TeamID: teamID}
+		require.NoError(t, service.Save(ctx, artifact, strings.NewReader(content)))
+		require.NoError(t, db.Model(artifact).Update("created_at", time.Now().Add(-age)).Error)
+	}
+	save("expired", "old", nil, nil, 48*time.Hour)
+	save("held", "old", &heldUser, nil, 48*time.Hour)
+	save("fresh", "new", nil, nil, time.Hour)
+	save("team-old", "123456", nil, &teamID, 3*time.Hour)
+	save("team-new", "123456", nil, &teamID, time.Hour)
+
+	require.NoError(t, db.Create(&models.Usage{RequestID: "old", Timestamp: time.Now().Add(-48 * time.Hour)}).Error)
+	require.NoError(t, db.Create(&models.Usage{RequestID: "new", Timestamp: time.Now()}).Error)
+
+	require.NoError(t, service.PlaceHold(ctx, &models.LegalHold{UserID: &heldUser, Reason: "litigation"}))
+	assert.Error(t, service.PlaceHold(ctx, &models.LegalHold{Reason: "no owner"}))
+
+	report := service.Reap(ctx)
+	assert.Empty(t, report.Errors)
+	assert.EqualValues(t, 1, report.ExpiredFile)
+	assert.EqualValues(t, 1, report.QuotaFiles, "the oldest team file is deleted to fit the quota")
+	assert.EqualValues(t, 1, report.RequestLogs)
+
+	var keys []string
+	require.NoError(t, db.Model(&models.Artifact{}).Order("storage_key").Pluck("storage_key", &keys).Error)
+	assert.Equal(t, []string{"fresh", "held", "team-new"}, keys)
+	_, err := store.Open(ctx, "expired")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	status, err := service.Report(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, status.Files)
+	assert.EqualValues(t, 1, status.HeldExpiredFiles)
+	assert.EqualValues(t, 1, status.ActiveHolds)
+	assert.Same(t, report, status.LastReap)
+
+	// Erasure keeps held data until the hold is released
+	erased, err := service.EraseUser(ctx, heldUser)
+	require.NoError(t, err)
+	assert.Zero(t, erased.Files)
+	assert.EqualValues(t, 1, erased.Retained)
+
+	holds, err := service.ListHolds(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, holds, 1)
+	_, err = service.ReleaseHold(ctx, holds[0].ID, nil)
+	require.NoError(t, err)
+	_, err = service.ReleaseHold(ctx, holds[0].ID, nil)
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+
+	erased, err = service.EraseUser(ctx, heldUser)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, erased.Files)
+	assert.Zero(t, erased.Retained)
+}
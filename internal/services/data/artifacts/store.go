@@ -0,0 +1,152 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// ErrNotFound reports a key missing from the store
+var ErrNotFound = errors.New("artifact not found")
+
+// Object is a blob listed by a store
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Store keeps artifact blobs. Keys are flat names without path separators.
+type Store interface {
+	// Put writes the blob read from r and returns its size
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns the blob, or ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns every blob in the store
+	List(ctx context.Context) ([]Object, error)
+}
+
+// NewStore creates the storage backend selected by the configuration
+func NewStore(cfg config.ArtifactStorageConfig) (Store, error) {
+	switch cfg.Type {
+	case "", "local":
+		path := cfg.Path
+		if path == "" {
+			path = "./uploads"
+		}
+		return NewLocalStore(path), nil
+	default:
+		return nil, fmt.Errorf("unknown artifact storage type: %s", cfg.Type)
+	}
+}
+
+// LocalStore keeps blobs as files in a directory. With several replicas each
+// keeps its own files, so a shared volume is needed for files uploaded to one
+// to be served by another.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates a store in dir, which is created on first write
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{dir: dir}
+}
+
+// ValidKey reports whether key can name a blob
+func ValidKey(key string) bool {
+	return key != "" && key != "." && !strings.Contains(key, "..") && !strings.ContainsAny(key, `/\`)
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("invalid artifact key: %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// Put writes the blob to a temporary file first, so a failed write never
+// leaves a partial blob under the key
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create artifact: %w", err)
+	}
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return 0, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return size, nil
+}
+
+// Open returns the blob as an *os.File, which callers may seek
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes the blob
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List returns the blobs in the directory, leaving out writes in progress
+func (s *LocalStore) List(ctx context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]Object, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, Object{Key: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return objects, nil
+}
@@ -0,0 +1,72 @@
+package artifacts
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "uploads")
+	store := NewLocalStore(dir)
+
+	objects, err := store.List(ctx)
+	require.NoError(t, err, "listing a missing directory is not an error")
+	assert.Empty(t, objects)
+
+	size, err := store.Put(ctx, "1700000000_cat.png", strings.NewReader("meow"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, size)
+
+	blob, err := store.Open(ctx, "1700000000_cat.png")
+	require.NoError(t, err)
+	content, err := io.ReadAll(blob)
+	require.NoError(t, err)
+	require.NoError(t, blob.Close())
+	assert.Equal(t, "meow", string(content))
+
+	// Writes in progress are not listed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".upload-123"), []byte("partial"), 0644))
+	objects, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "1700000000_cat.png", objects[0].Key)
+	assert.EqualValues(t, 4, objects[0].Size)
+
+	require.NoError(t, store.Delete(ctx, "1700000000_cat.png"))
+	require.NoError(t, store.Delete(ctx, "1700000000_cat.png"), "deleting twice is not an error")
+	_, err = store.Open(ctx, "1700000000_cat.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStore_InvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir())
+
+	for _, key := range []string{"", ".", "../secret", "a/b", `a\b`} {
+		assert.False(t, ValidKey(key), key)
+		_, err := store.Put(ctx, key, strings.NewReader("x"))
+		assert.Error(t, err, key)
+		_, err = store.Open(ctx, key)
+		assert.ErrorIs(t, err, ErrNotFound, key)
+	}
+	assert.True(t, ValidKey("1700000000_cat.png"))
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(config.ArtifactStorageConfig{})
+	require.NoError(t, err)
+	assert.IsType(t, &LocalStore{}, store)
+
+	_, err = NewStore(config.ArtifactStorageConfig{Type: "tape"})
+	assert.Error(t, err)
+}
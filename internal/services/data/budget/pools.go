@@ -0,0 +1,479 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Budget movement errors
+var (
+	ErrInvalidAmount      = errors.New("amount must be positive")
+	ErrSameTeam           = errors.New("cannot transfer budget to the same team")
+	ErrTeamNotFound       = errors.New("team not found")
+	ErrUnlimitedBudget    = errors.New("team has no budget limit to move budget from or to")
+	ErrInsufficientBudget = errors.New("not enough unused budget")
+	ErrPoolNotFound       = errors.New("budget pool not found")
+	ErrPoolExists         = errors.New("a budget pool with this name already exists")
+	ErrPoolInactive       = errors.New("budget pool is inactive")
+	ErrPoolTeamNotMember  = errors.New("team is not a member of the budget pool")
+	ErrPoolUnderfunded    = errors.New("pool amount is below the budget its teams hold")
+)
+
+// PoolService moves budget between teams, mid-period, and through shared
+// budget pools. A team's unused budget is its max_budget minus its current
+// spend; moving budget changes max_budget, so teams without a limit take no
+// part. Every movement is recorded as BudgetTracking.
+type PoolService struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+// NewPoolService creates a new PoolService.
+func NewPoolService(db *gorm.DB, logger *zap.Logger) *PoolService {
+	return &PoolService{db: db, logger: logger}
+}
+
+// TransferRequest moves unused budget from one team to another
+type TransferRequest struct {
+	FromTeamID uuid.UUID `json:"from_team_id"`
+	ToTeamID   uuid.UUID `json:"to_team_id"`
+	Amount     float64   `json:"amount"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// Transfer is the result of a budget transfer: the recorded movements and
+// the budgets of both teams afterwards
+type Transfer struct {
+	Movements         []models.BudgetTracking `json:"movements"`
+	FromTeamMaxBudget float64                 `json:"from_team_max_budget"`
+	ToTeamMaxBudget   float64                 `json:"to_team_max_budget"`
+}
+
+// Transfer moves unused budget from one team to another
+func (s *PoolService) Transfer(ctx context.Context, req *TransferRequest, createdBy *uuid.UUID) (*Transfer, error) {
+	if !validAmount(req.Amount) {
+		return nil, ErrInvalidAmount
+	}
+	if req.FromTeamID == req.ToTeamID {
+		return nil, ErrSameTeam
+	}
+
+	result := &Transfer{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock in a fixed order so opposite transfers don't deadlock
+		first, second := req.FromTeamID, req.ToTeamID
+		if second.String() < first.String() {
+			first, second = second, first
+		}
+		locked := make(map[uuid.UUID]*models.Team, 2)
+		for _, id := range []uuid.UUID{first, second} {
+			t, err := lockTeam(tx, id)
+			if err != nil {
+				return err
+			}
+			locked[id] = t
+		}
+		from, to := locked[req.FromTeamID], locked[req.ToTeamID]
+		if err := takeBudget(tx, from, req.Amount); err != nil {
+			return err
+		}
+		if err := giveBudget(tx, to, req.Amount); err != nil {
+			return err
+		}
+
+		result.Movements = []models.BudgetTracking{
+			{
+				TeamID:      &from.ID,
+				Movement:    models.BudgetMovementTransferOut,
+				Amount:      -req.Amount,
+				OtherTeamID: &to.ID,
+				Reason:      req.Reason,
+				CreatedBy:   createdBy,
+			},
+			{
+				TeamID:      &to.ID,
+				Movement:    models.BudgetMovementTransferIn,
+				Amount:      req.Amount,
+				OtherTeamID: &from.ID,
+				Reason:      req.Reason,
+				CreatedBy:   createdBy,
+			},
+		}
+		if err := tx.Create(&result.Movements).Error; err != nil {
+			return fmt.Errorf("failed to record budget transfer: %w", err)
+		}
+		result.FromTeamMaxBudget = from.MaxBudget
+		result.ToTeamMaxBudget = to.MaxBudget
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Transferred team budget",
+		zap.String("from_team_id", req.FromTeamID.String()),
+		zap.String("to_team_id", req.ToTeamID.String()),
+		zap.Float64("amount", req.Amount))
+	return result, nil
+}
+
+// CreatePoolRequest creates a budget pool funded with amount
+type CreatePoolRequest struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Amount      float64     `json:"amount"`
+	TeamIDs     []uuid.UUID `json:"team_ids"`
+}
+
+// CreatePool creates a budget pool the given teams draw from
+func (s *PoolService) CreatePool(ctx context.Context, req *CreatePoolRequest, createdBy *uuid.UUID) (*models.BudgetPool, error) {
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.Amount < 0 || math.IsNaN(req.Amount) || math.IsInf(req.Amount, 0) {
+		return nil, ErrInvalidAmount
+	}
+
+	pool := &models.BudgetPool{
+		Name:        req.Name,
+		Description: req.Description,
+		Amount:      req.Amount,
+		IsActive:    true,
+		CreatedBy:   createdBy,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.BudgetPool{}).Where("name = ?", req.Name).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrPoolExists
+		}
+		teamIDs, err := existingTeams(tx, req.TeamIDs)
+		if err != nil {
+			return err
+		}
+		pool.TeamIDs = teamIDs
+
+		if err := tx.Create(pool).Error; err != nil {
+			return fmt.Errorf("failed to create budget pool: %w", err)
+		}
+		if pool.Amount == 0 {
+			return nil
+		}
+		return tx.Create(&models.BudgetTracking{
+			PoolID:    &pool.ID,
+			Movement:  models.BudgetMovementPoolFund,
+			Amount:    pool.Amount,
+			Reason:    "pool created",
+			CreatedBy: createdBy,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// GetPool returns a budget pool
+func (s *PoolService) GetPool(ctx context.Context, poolID uuid.UUID) (*models.BudgetPool, error) {
+	var pool models.BudgetPool
+	if err := s.db.WithContext(ctx).First(&pool, "id = ?", poolID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPoolNotFound
+		}
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// ListPools returns the budget pools, optionally only those a team belongs to
+func (s *PoolService) ListPools(ctx context.Context, teamID *uuid.UUID) ([]models.BudgetPool, error) {
+	query := s.db.WithContext(ctx).Order("name")
+	if teamID != nil {
+		query = query.Where("? = ANY(team_ids)", teamID.String())
+	}
+	var pools []models.BudgetPool
+	if err := query.Find(&pools).Error; err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// UpdatePoolRequest changes the fields that are set. A new amount funds or
+// defunds the pool and may not drop below the budget its teams hold.
+type UpdatePoolRequest struct {
+	Description *string      `json:"description,omitempty"`
+	Amount      *float64     `json:"amount,omitempty"`
+	TeamIDs     *[]uuid.UUID `json:"team_ids,omitempty"`
+	IsActive    *bool        `json:"is_active,omitempty"`
+	Reason      string       `json:"reason,omitempty"`
+}
+
+// UpdatePool changes a budget pool
+func (s *PoolService) UpdatePool(ctx context.Context, poolID uuid.UUID, req *UpdatePoolRequest, createdBy *uuid.UUID) (*models.BudgetPool, error) {
+	var pool *models.BudgetPool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if pool, err = lockPool(tx, poolID); err != nil {
+			return err
+		}
+
+		updates := make(map[string]interface{})
+		if req.Description != nil {
+			updates["description"] = *req.Description
+		}
+		if req.IsActive != nil {
+			updates["is_active"] = *req.IsActive
+		}
+		if req.TeamIDs != nil {
+			teamIDs, err := existingTeams(tx, *req.TeamIDs)
+			if err != nil {
+				return err
+			}
+			updates["team_ids"] = teamIDs
+		}
+		if req.Amount != nil && *req.Amount != pool.Amount {
+			if *req.Amount < 0 || math.IsNaN(*req.Amount) || math.IsInf(*req.Amount, 0) {
+				return ErrInvalidAmount
+			}
+			if *req.Amount < pool.Allocated {
+				return ErrPoolUnderfunded
+			}
+			if err := tx.Create(&models.BudgetTracking{
+				PoolID:    &pool.ID,
+				Movement:  models.BudgetMovementPoolFund,
+				Amount:    *req.Amount - pool.Amount,
+				Reason:    req.Reason,
+				CreatedBy: createdBy,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to record pool funding: %w", err)
+			}
+			updates["amount"] = *req.Amount
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(pool).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPool(ctx, poolID)
+}
+
+// DeletePool deletes a budget pool. Budget its teams drew stays with them.
+func (s *PoolService) DeletePool(ctx context.Context, poolID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Delete(&models.BudgetPool{}, "id = ?", poolID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPoolNotFound
+	}
+	return nil
+}
+
+// PoolMovementRequest moves budget between a pool and one of its teams
+type PoolMovementRequest struct {
+	TeamID uuid.UUID `json:"team_id"`
+	Amount float64   `json:"amount"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// PoolMovement is the result of a draw or return: the recorded movement and
+// the budgets of the pool and the team afterwards
+type PoolMovement struct {
+	Movement      models.BudgetTracking `json:"movement"`
+	Pool          *models.BudgetPool    `json:"pool"`
+	TeamMaxBudget float64               `json:"team_max_budget"`
+}
+
+// Draw raises a team's budget with budget from a pool it belongs to
+func (s *PoolService) Draw(ctx context.Context, poolID uuid.UUID, req *PoolMovementRequest, createdBy *uuid.UUID) (*PoolMovement, error) {
+	return s.movePool(ctx, poolID, req, createdBy, models.BudgetMovementPoolDraw)
+}
+
+// Return gives unused budget of a team back to a pool it belongs to
+func (s *PoolService) Return(ctx context.Context, poolID uuid.UUID, req *PoolMovementRequest, createdBy *uuid.UUID) (*PoolMovement, error) {
+	return s.movePool(ctx, poolID, req, createdBy, models.BudgetMovementPoolReturn)
+}
+
+func (s *PoolService) movePool(ctx context.Context, poolID uuid.UUID, req *PoolMovementRequest, createdBy *uuid.UUID, movement models.BudgetMovement) (*PoolMovement, error) {
+	if !validAmount(req.Amount) {
+		return nil, ErrInvalidAmount
+	}
+
+	result := &PoolMovement{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		pool, err := lockPool(tx, poolID)
+		if err != nil {
+			return err
+		}
+		if !pool.HasTeam(req.TeamID) {
+			return ErrPoolTeamNotMember
+		}
+		t, err := lockTeam(tx, req.TeamID)
+		if err != nil {
+			return err
+		}
+
+		amount := req.Amount
+		if movement == models.BudgetMovementPoolDraw {
+			if !pool.IsActive {
+				return ErrPoolInactive
+			}
+			if pool.Remaining() < amount {
+				return ErrInsufficientBudget
+			}
+			if err := giveBudget(tx, t, amount); err != nil {
+				return err
+			}
+		} else {
+			// Teams return no more than they hold from the pool
+			var held float64
+			if err := tx.Model(&models.BudgetTracking{}).
+				Where("pool_id = ? AND team_id = ? AND movement IN ?", pool.ID, t.ID,
+					[]models.BudgetMovement{models.BudgetMovementPoolDraw, models.BudgetMovementPoolReturn}).
+				Select("COALESCE(SUM(amount), 0)").Scan(&held).Error; err != nil {
+				return err
+			}
+			if held < amount {
+				return ErrInsufficientBudget
+			}
+			if err := takeBudget(tx, t, amount); err != nil {
+				return err
+			}
+			amount = -amount
+		}
+
+		pool.Allocated += amount
+		if err := tx.Model(pool).Update("allocated", pool.Allocated).Error; err != nil {
+			return fmt.Errorf("failed to update budget pool: %w", err)
+		}
+		result.Movement = models.BudgetTracking{
+			TeamID:    &t.ID,
+			PoolID:    &pool.ID,
+			Movement:  movement,
+			Amount:    amount,
+			Reason:    req.Reason,
+			CreatedBy: createdBy,
+		}
+		if err := tx.Create(&result.Movement).Error; err != nil {
+			return fmt.Errorf("failed to record pool movement: %w", err)
+		}
+		result.Pool = pool
+		result.TeamMaxBudget = t.MaxBudget
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MovementFilter selects budget movements; empty fields match all
+type MovementFilter struct {
+	TeamID *uuid.UUID
+	PoolID *uuid.UUID
+	Limit  int
+}
+
+// ListMovements returns budget movements, newest first
+func (s *PoolService) ListMovements(ctx context.Context, filter MovementFilter) ([]models.BudgetTracking, error) {
+	query := s.db.WithContext(ctx).Where("movement <> ''").Order("created_at DESC")
+	if filter.TeamID != nil {
+		query = query.Where("team_id = ?", *filter.TeamID)
+	}
+	if filter.PoolID != nil {
+		query = query.Where("pool_id = ?", *filter.PoolID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var movements []models.BudgetTracking
+	if err := query.Find(&movements).Error; err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+func validAmount(amount float64) bool {
+	return amount > 0 && !math.IsInf(amount, 0)
+}
+
+func lockTeam(tx *gorm.DB, teamID uuid.UUID) (*models.Team, error) {
+	var t models.Team
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&t, "id = ?", teamID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func lockPool(tx *gorm.DB, poolID uuid.UUID) (*models.BudgetPool, error) {
+	var pool models.BudgetPool
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pool, "id = ?", poolID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPoolNotFound
+		}
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// takeBudget lowers a team's budget by unused budget
+func takeBudget(tx *gorm.DB, t *models.Team, amount float64) error {
+	if t.MaxBudget <= 0 {
+		return ErrUnlimitedBudget
+	}
+	if t.MaxBudget-t.CurrentSpend < amount {
+		return ErrInsufficientBudget
+	}
+	t.MaxBudget -= amount
+	return tx.Model(t).Update("max_budget", t.MaxBudget).Error
+}
+
+// giveBudget raises a team's budget
+func giveBudget(tx *gorm.DB, t *models.Team, amount float64) error {
+	if t.MaxBudget <= 0 {
+		return ErrUnlimitedBudget
+	}
+	t.MaxBudget += amount
+	return tx.Model(t).Update("max_budget", t.MaxBudget).Error
+}
+
+// existingTeams returns the IDs of the teams, or ErrTeamNotFound when one
+// doesn't exist
+func existingTeams(tx *gorm.DB, teamIDs []uuid.UUID) (models.StringArray, error) {
+	ids := make(models.StringArray, 0, len(teamIDs))
+	seen := make(map[uuid.UUID]bool, len(teamIDs))
+	for _, id := range teamIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id.String())
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	var count int64
+	if err := tx.Model(&models.Team{}).Where("id IN ?", []string(ids)).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if int(count) != len(ids) {
+		return nil, ErrTeamNotFound
+	}
+	return ids, nil
+}
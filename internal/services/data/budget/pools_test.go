@@ -0,0 +1,121 @@
+package budget
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+)
+
+func TestPoolService(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	service := NewPoolService(db, zap.NewNop())
+	admin := uuid.New()
+
+	newTeam := func(maxBudget, spend float64) *models.Team {
+		team := &models.Team{
+			BaseModel:    models.BaseModel{ID: uuid.New()},
+			Name:         "team-" + uuid.NewString(),
+			MaxBudget:    maxBudget,
+			CurrentSpend: spend,
+			IsActive:     true,
+		}
+		require.NoError(t, db.Create(team).Error)
+		return team
+	}
+	maxBudget := func(team *models.Team) float64 {
+		var reloaded models.Team
+		require.NoError(t, db.First(&reloaded, "id = ?", team.ID).Error)
+		return reloaded.MaxBudget
+	}
+
+	t.Run("transfer moves unused budget", func(t *testing.T) {
+		from, to := newTeam(100, 70), newTeam(50, 50)
+
+		_, err := service.Transfer(ctx, &TransferRequest{FromTeamID: from.ID, ToTeamID: to.ID, Amount: 40}, &admin)
+		assert.ErrorIs(t, err, ErrInsufficientBudget, "only 30 is unused")
+
+		transfer, err := service.Transfer(ctx, &TransferRequest{FromTeamID: from.ID, ToTeamID: to.ID, Amount: 30, Reason: "launch"}, &admin)
+		require.NoError(t, err)
+		assert.Equal(t, 70.0, transfer.FromTeamMaxBudget)
+		assert.Equal(t, 80.0, transfer.ToTeamMaxBudget)
+		assert.Equal(t, 70.0, maxBudget(from))
+		assert.Equal(t, 80.0, maxBudget(to))
+
+		movements, err := service.ListMovements(ctx, MovementFilter{TeamID: &from.ID})
+		require.NoError(t, err)
+		require.Len(t, movements, 1)
+		assert.Equal(t, models.BudgetMovementTransferOut, movements[0].Movement)
+		assert.Equal(t, -30.0, movements[0].Amount)
+		assert.Equal(t, to.ID, *movements[0].OtherTeamID)
+		assert.Equal(t, admin, *movements[0].CreatedBy)
+		assert.Equal(t, "launch", movements[0].Reason)
+	})
+
+	t.Run("transfer rejects teams without a limit", func(t *testing.T) {
+		limited, unlimited := newTeam(100, 0), newTeam(0, 0)
+		_, err := service.Transfer(ctx, &TransferRequest{FromTeamID: limited.ID, ToTeamID: unlimited.ID, Amount: 10}, nil)
+		assert.ErrorIs(t, err, ErrUnlimitedBudget)
+		assert.Equal(t, 100.0, maxBudget(limited), "nothing moved")
+
+		_, err = service.Transfer(ctx, &TransferRequest{FromTeamID: limited.ID, ToTeamID: limited.ID, Amount: 10}, nil)
+		assert.ErrorIs(t, err, ErrSameTeam)
+		_, err = service.Transfer(ctx, &TransferRequest{FromTeamID: limited.ID, ToTeamID: uuid.New(), Amount: 10}, nil)
+		assert.ErrorIs(t, err, ErrTeamNotFound)
+	})
+
+	t.Run("teams draw from and return to pools", func(t *testing.T) {
+		member, outsider := newTeam(10, 10), newTeam(10, 0)
+		pool, err := service.CreatePool(ctx, &CreatePoolRequest{
+			Name:    "pool-" + uuid.NewString(),
+			Amount:  100,
+			TeamIDs: []uuid.UUID{member.ID, member.ID},
+		}, &admin)
+		require.NoError(t, err)
+		assert.Equal(t, models.StringArray{member.ID.String()}, pool.TeamIDs)
+
+		_, err = service.Draw(ctx, pool.ID, &PoolMovementRequest{TeamID: outsider.ID, Amount: 10}, nil)
+		assert.ErrorIs(t, err, ErrPoolTeamNotMember)
+		_, err = service.Draw(ctx, pool.ID, &PoolMovementRequest{TeamID: member.ID, Amount: 150}, nil)
+		assert.ErrorIs(t, err, ErrInsufficientBudget)
+
+		drawn, err := service.Draw(ctx, pool.ID, &PoolMovementRequest{TeamID: member.ID, Amount: 60}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 70.0, drawn.TeamMaxBudget)
+		assert.Equal(t, 40.0, drawn.Pool.Remaining())
+
+		_, err = service.UpdatePool(ctx, pool.ID, &UpdatePoolRequest{Amount: ptr(50.0)}, nil)
+		assert.ErrorIs(t, err, ErrPoolUnderfunded)
+
+		_, err = service.Return(ctx, pool.ID, &PoolMovementRequest{TeamID: member.ID, Amount: 70}, nil)
+		assert.ErrorIs(t, err, ErrInsufficientBudget, "more than the team drew")
+		returned, err := service.Return(ctx, pool.ID, &PoolMovementRequest{TeamID: member.ID, Amount: 20}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 50.0, returned.TeamMaxBudget)
+		assert.Equal(t, 40.0, returned.Pool.Allocated)
+
+		movements, err := service.ListMovements(ctx, MovementFilter{PoolID: &pool.ID})
+		require.NoError(t, err)
+		require.Len(t, movements, 3)
+		assert.Equal(t, models.BudgetMovementPoolReturn, movements[0].Movement)
+		assert.Equal(t, models.BudgetMovementPoolDraw, movements[1].Movement)
+		assert.Equal(t, models.BudgetMovementPoolFund, movements[2].Movement)
+
+		_, err = service.UpdatePool(ctx, pool.ID, &UpdatePoolRequest{IsActive: ptr(false)}, nil)
+		require.NoError(t, err)
+		_, err = service.Draw(ctx, pool.ID, &PoolMovementRequest{TeamID: member.ID, Amount: 10}, nil)
+		assert.ErrorIs(t, err, ErrPoolInactive)
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
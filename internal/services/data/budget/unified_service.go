@@ -18,16 +18,16 @@ import (
 type UnifiedService struct {
 	db          *gorm.DB
 	logger      *zap.Logger
-	budgetCache *redisService.BudgetCache
-	usageQueue  *redisService.UsageQueue
+	budgetCache redisService.BudgetCacheBackend
+	usageQueue  redisService.UsageQueueBackend
 	eventPub    *redisService.EventPublisher
 }
 
 type UnifiedServiceConfig struct {
 	DB          *gorm.DB
 	Logger      *zap.Logger
-	BudgetCache *redisService.BudgetCache
-	UsageQueue  *redisService.UsageQueue
+	BudgetCache redisService.BudgetCacheBackend
+	UsageQueue  redisService.UsageQueueBackend
 	EventPub    *redisService.EventPublisher
 }
 
@@ -61,8 +61,8 @@ func (s *UnifiedService) CheckBudget(ctx context.Context, keyID uuid.UUID, estim
 		currentSpend = key.Team.CurrentSpend
 		budgetPeriod = key.Team.BudgetDuration
 		entityType = "team"
-	} else if key.MaxBudget != nil && *key.MaxBudget > 0 {
-		maxBudget = *key.MaxBudget
+	} else if keyBudget := key.GetMaxBudget(); keyBudget != nil && *keyBudget > 0 {
+		maxBudget = *keyBudget
 		currentSpend = key.CurrentSpend
 		if key.BudgetDuration != nil {
 			budgetPeriod = *key.BudgetDuration
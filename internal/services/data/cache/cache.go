@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 )
 
 var (
@@ -68,7 +70,7 @@ func NewRedisCache(ttl time.Duration) *RedisCache {
 }
 
 func (c *RedisCache) Get(key string) ([]byte, error) {
-	val, err := c.client.Get(ctx, key).Bytes()
+	val, err := c.client.Get(ctx, redisService.Key(key)).Bytes()
 	if err == redis.Nil {
 		return nil, nil
 	}
@@ -82,20 +84,32 @@ func (c *RedisCache) Set(key string, value []byte, ttl time.Duration) error {
 	if ttl == 0 {
 		ttl = c.ttl
 	}
-	return c.client.Set(ctx, key, value, ttl).Err()
+	return c.client.Set(ctx, redisService.Key(key), value, ttl).Err()
 }
 
 func (c *RedisCache) Delete(key string) error {
-	return c.client.Del(ctx, key).Err()
+	return c.client.Del(ctx, redisService.Key(key)).Err()
 }
 
 func (c *RedisCache) Exists(key string) bool {
-	exists, _ := c.client.Exists(ctx, key).Result()
+	exists, _ := c.client.Exists(ctx, redisService.Key(key)).Result()
 	return exists > 0
 }
 
+// Clear removes all cached entries. With a Redis namespace only the
+// namespace's keys are removed, since the database is shared.
 func (c *RedisCache) Clear() error {
-	return c.client.FlushDB(ctx).Err()
+	if redisService.Namespace() == "" {
+		return c.client.FlushDB(ctx).Err()
+	}
+
+	iter := c.client.Scan(ctx, 0, redisService.Key("*"), 1000).Iterator()
+	for iter.Next(ctx) {
+		if err := c.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
 }
 
 func (c *RedisCache) GetJSON(key string, dest interface{}) error {
@@ -10,6 +10,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/amerfu/pllm/internal/core/config"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 )
 
 // PricingCache provides Redis-based caching for model pricing information
@@ -27,7 +28,7 @@ func NewPricingCache(client *redis.Client, logger *zap.Logger, pricingManager *c
 		client:         client,
 		logger:         logger,
 		pricingManager: pricingManager,
-		cachePrefix:    "pllm:pricing:",
+		cachePrefix:    redisService.Key("pllm:pricing:"),
 		cacheTTL:       24 * time.Hour, // Cache for 24 hours
 	}
 }
@@ -125,26 +126,18 @@ func (pc *PricingCache) GetPricingForTeam(ctx context.Context, modelName string,
 
 // CalculateCost calculates cost using cached pricing data
 func (pc *PricingCache) CalculateCost(ctx context.Context, modelName string, inputTokens, outputTokens int) (*config.CostCalculation, error) {
+	return pc.CalculateCostWithReasoning(ctx, modelName, inputTokens, outputTokens, 0)
+}
+
+// CalculateCostWithReasoning calculates cost using cached pricing data when
+// part of the output tokens were spent on reasoning
+func (pc *PricingCache) CalculateCostWithReasoning(ctx context.Context, modelName string, inputTokens, outputTokens, reasoningTokens int) (*config.CostCalculation, error) {
 	pricingInfo := pc.GetPricing(ctx, modelName)
 	if pricingInfo == nil {
 		return nil, fmt.Errorf("pricing information not found for model: %s", modelName)
 	}
 
-	inputCost := float64(inputTokens) * pricingInfo.InputCostPerToken
-	outputCost := float64(outputTokens) * pricingInfo.OutputCostPerToken
-	totalCost := inputCost + outputCost
-
-	return &config.CostCalculation{
-		ModelName:    modelName,
-		InputTokens:  inputTokens,
-		OutputTokens: outputTokens,
-		InputCost:    inputCost,
-		OutputCost:   outputCost,
-		TotalCost:    totalCost,
-		Currency:     "USD",
-		Source:       pricingInfo.Source,
-		Timestamp:    time.Now(),
-	}, nil
+	return config.NewCostCalculation(modelName, pricingInfo, inputTokens, outputTokens, reasoningTokens), nil
 }
 
 // cachePricingAsync caches pricing info asynchronously (fire and forget)
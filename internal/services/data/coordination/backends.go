@@ -0,0 +1,97 @@
+// Package coordination builds the usage queue, budget cache, lock manager and
+// replica registry shared between gateway replicas and the usage worker, on
+// top of the configured backend.
+package coordination
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	pgService "github.com/amerfu/pllm/internal/services/data/postgres"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// Backends bundles the coordination components
+type Backends struct {
+	UsageQueue  redisService.UsageQueueBackend
+	BudgetCache redisService.BudgetCacheBackend
+	LockManager redisService.LockBackend
+	Replicas    redisService.ReplicaRegistryBackend
+	EventPub    *redisService.EventPublisher // nil unless the Redis backend is used
+	Counters    *redisService.UsageCounters  // nil unless the Redis backend is used
+}
+
+// Config configures the coordination backends
+type Config struct {
+	Backend    string // config.CoordinationBackendRedis (default) or config.CoordinationBackendPostgres
+	Redis      *redis.Client
+	DB         *gorm.DB
+	Logger     *zap.Logger
+	QueueName  string
+	BatchSize  int
+	MaxRetries int
+	BudgetTTL  time.Duration
+	UsageCodec redisService.UsageCodec // Usage payload encoding of the Redis backend
+}
+
+// NewBackends creates the coordination components for the configured backend
+func NewBackends(cfg *Config) (*Backends, error) {
+	if cfg.BudgetTTL == 0 {
+		cfg.BudgetTTL = 5 * time.Minute
+	}
+
+	switch cfg.Backend {
+	case "", config.CoordinationBackendRedis:
+		if cfg.Redis == nil {
+			return nil, fmt.Errorf("redis coordination backend requires a Redis client")
+		}
+		if err := cfg.UsageCodec.Validate(); err != nil {
+			return nil, err
+		}
+
+		return &Backends{
+			UsageQueue: redisService.NewUsageQueue(&redisService.UsageQueueConfig{
+				Client:     cfg.Redis,
+				Logger:     cfg.Logger,
+				QueueName:  cfg.QueueName,
+				BatchSize:  cfg.BatchSize,
+				MaxRetries: cfg.MaxRetries,
+				Codec:      cfg.UsageCodec,
+			}),
+			BudgetCache: redisService.NewBudgetCache(cfg.Redis, cfg.Logger, cfg.BudgetTTL),
+			LockManager: redisService.NewLockManager(cfg.Redis, cfg.Logger),
+			Replicas:    redisService.NewReplicaRegistry(cfg.Redis, cfg.Logger),
+			EventPub:    redisService.NewEventPublisher(cfg.Redis, cfg.Logger),
+			Counters:    redisService.NewUsageCounters(cfg.Redis, cfg.Logger),
+		}, nil
+
+	case config.CoordinationBackendPostgres:
+		if cfg.DB == nil {
+			return nil, fmt.Errorf("postgres coordination backend requires a database")
+		}
+		if err := pgService.Migrate(cfg.DB); err != nil {
+			return nil, err
+		}
+
+		return &Backends{
+			UsageQueue: pgService.NewUsageQueue(&pgService.UsageQueueConfig{
+				DB:         cfg.DB,
+				Logger:     cfg.Logger,
+				QueueName:  cfg.QueueName,
+				BatchSize:  cfg.BatchSize,
+				MaxRetries: cfg.MaxRetries,
+			}),
+			BudgetCache: pgService.NewBudgetCache(cfg.DB, cfg.Logger, cfg.BudgetTTL),
+			LockManager: pgService.NewAdvisoryLockManager(cfg.DB, cfg.Logger),
+			Replicas:    pgService.NewReplicaRegistry(cfg.DB, cfg.Logger),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown coordination backend: %s", cfg.Backend)
+	}
+}
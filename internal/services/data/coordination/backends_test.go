@@ -0,0 +1,45 @@
+package coordination
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+func TestNewBackends(t *testing.T) {
+	t.Run("redis backend", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		defer func() { _ = client.Close() }()
+
+		backends, err := NewBackends(&Config{Redis: client, Logger: zap.NewNop()})
+		require.NoError(t, err)
+		assert.IsType(t, &redisService.UsageQueue{}, backends.UsageQueue)
+		assert.IsType(t, &redisService.BudgetCache{}, backends.BudgetCache)
+		assert.IsType(t, &redisService.LockManager{}, backends.LockManager)
+		assert.IsType(t, &redisService.ReplicaRegistry{}, backends.Replicas)
+		assert.NotNil(t, backends.EventPub)
+	})
+
+	t.Run("redis backend requires a client", func(t *testing.T) {
+		_, err := NewBackends(&Config{Backend: config.CoordinationBackendRedis, Logger: zap.NewNop()})
+		assert.Error(t, err)
+	})
+
+	t.Run("postgres backend requires a database", func(t *testing.T) {
+		_, err := NewBackends(&Config{Backend: config.CoordinationBackendPostgres, Logger: zap.NewNop()})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		_, err := NewBackends(&Config{Backend: "etcd", Logger: zap.NewNop()})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,424 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/data/artifacts"
+)
+
+// Kinds of deleted records
+const (
+	KindUser = "user"
+	KindTeam = "team"
+	KindKey  = "key"
+)
+
+var (
+	// ErrNotFound reports a record that does not exist or is not in the
+	// state the operation needs: deleting a deleted record, restoring a live
+	// or purged one
+	ErrNotFound = errors.New("record not found")
+
+	// ErrOwnerDeleted reports restoring a key whose user or team is still
+	// deleted
+	ErrOwnerDeleted = errors.New("the owner of the key is deleted; restore it first")
+)
+
+var recordsPurged = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_deleted_records_purged_total",
+		Help: "Total number of deleted users, teams and keys purged after their retention window",
+	},
+	[]string{"kind"},
+)
+
+// Service soft deletes users, teams and keys so they can be restored within
+// the retention window, and purges them for good once it has passed.
+// Deleting a user or team also deletes their keys with the same timestamp,
+// which is how restoring brings back exactly the keys that went with it.
+// Budgets, team members and usage stay attached until the purge.
+type Service struct {
+	db     *gorm.DB
+	cfg    config.DeletionConfig
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	lastPurge *PurgeReport
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService creates a deletion service
+func NewService(db *gorm.DB, cfg config.DeletionConfig, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		cfg:    cfg,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Retention returns how long deleted records can be restored
+func (s *Service) Retention() time.Duration {
+	return s.cfg.Retention
+}
+
+// DeleteUser soft deletes a user and their personal keys
+func (s *Service) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.User{}).Where("id = ?", userID).Update("deleted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Model(&models.Key{}).Where("user_id = ?", userID).Update("deleted_at", now).Error
+	})
+}
+
+// DeleteTeam soft deletes a team and its keys. Its members are kept, so
+// restoring the team gives them back their access.
+func (s *Service) DeleteTeam(ctx context.Context, teamID uuid.UUID) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Team{}).Where("id = ?", teamID).Update("deleted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Model(&models.Key{}).Where("team_id = ?", teamID).Update("deleted_at", now).Error
+	})
+}
+
+// DeleteKey soft deletes a key
+func (s *Service) DeleteKey(ctx context.Context, keyID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Model(&models.Key{}).Where("id = ?", keyID).Update("deleted_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RestoreUser restores a deleted user and the keys deleted with them,
+// except those of a team that is still deleted
+func (s *Service) RestoreUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := findDeleted(tx, &user, userID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Key{}).
+			Where("user_id = ? AND deleted_at = ?", userID, user.DeletedAt.Time).
+			Where("team_id IS NULL OR team_id NOT IN (SELECT id FROM teams WHERE deleted_at IS NOT NULL)").
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		user.DeletedAt = gorm.DeletedAt{}
+		return tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RestoreTeam restores a deleted team and the keys deleted with it, except
+// those of a user who is still deleted
+func (s *Service) RestoreTeam(ctx context.Context, teamID uuid.UUID) (*models.Team, error) {
+	var team models.Team
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := findDeleted(tx, &team, teamID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Key{}).
+			Where("team_id = ? AND deleted_at = ?", teamID, team.DeletedAt.Time).
+			Where("user_id IS NULL OR user_id NOT IN (SELECT id FROM users WHERE deleted_at IS NOT NULL)").
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		team.DeletedAt = gorm.DeletedAt{}
+		return tx.Unscoped().Model(&models.Team{}).Where("id = ?", teamID).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// RestoreKey restores a deleted key, or returns ErrOwnerDeleted while its
+// user or team is deleted
+func (s *Service) RestoreKey(ctx context.Context, keyID uuid.UUID) (*models.Key, error) {
+	var key models.Key
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := findDeleted(tx, &key, keyID); err != nil {
+			return err
+		}
+		var owners int64
+		if err := tx.Unscoped().Model(&models.User{}).
+			Where("id = ? AND deleted_at IS NOT NULL", key.UserID).
+			Count(&owners).Error; err != nil {
+			return err
+		}
+		if owners == 0 {
+			if err := tx.Unscoped().Model(&models.Team{}).
+				Where("id = ? AND deleted_at IS NOT NULL", key.TeamID).
+				Count(&owners).Error; err != nil {
+				return err
+			}
+		}
+		if owners > 0 {
+			return ErrOwnerDeleted
+		}
+		key.DeletedAt = gorm.DeletedAt{}
+		return tx.Unscoped().Model(&models.Key{}).Where("id = ?", keyID).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// findDeleted loads a soft deleted record, or returns ErrNotFound
+func findDeleted(tx *gorm.DB, dest interface{}, id uuid.UUID) error {
+	err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeletedRecord is a deleted user, team or key awaiting its purge
+type DeletedRecord struct {
+	ID        uuid.UUID  `json:"id"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	TeamID    *uuid.UUID `json:"team_id,omitempty"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   time.Time  `json:"purge_at"`
+}
+
+// ListDeleted returns the deleted users, teams and keys that can still be
+// restored, most recently deleted first
+func (s *Service) ListDeleted(ctx context.Context) ([]DeletedRecord, error) {
+	db := s.db.WithContext(ctx).Unscoped()
+	var records []DeletedRecord
+	add := func(kind string, id uuid.UUID, name string, userID, teamID *uuid.UUID, deletedAt gorm.DeletedAt) {
+		records = append(records, DeletedRecord{
+			ID:        id,
+			Kind:      kind,
+			Name:      name,
+			UserID:    userID,
+			TeamID:    teamID,
+			DeletedAt: deletedAt.Time,
+			PurgeAt:   deletedAt.Time.Add(s.cfg.Retention),
+		})
+	}
+
+	var users []models.User
+	if err := db.Where("deleted_at IS NOT NULL").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list deleted users: %w", err)
+	}
+	for _, user := range users {
+		add(KindUser, user.ID, user.Email, nil, nil, user.DeletedAt)
+	}
+
+	var teams []models.Team
+	if err := db.Where("deleted_at IS NOT NULL").Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to list deleted teams: %w", err)
+	}
+	for _, team := range teams {
+		add(KindTeam, team.ID, team.Name, nil, nil, team.DeletedAt)
+	}
+
+	var keys []models.Key
+	if err := db.Where("deleted_at IS NOT NULL").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list deleted keys: %w", err)
+	}
+	for _, key := range keys {
+		add(KindKey, key.ID, key.Name, key.UserID, key.TeamID, key.DeletedAt)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].DeletedAt.After(records[j].DeletedAt)
+	})
+	return records, nil
+}
+
+// Start purges expired records every purge interval until Stop
+func (s *Service) Start(ctx context.Context) {
+	if s.cfg.PurgeInterval <= 0 {
+		return
+	}
+	s.logger.Info("Starting deleted record purge",
+		zap.Duration("interval", s.cfg.PurgeInterval),
+		zap.Duration("retention", s.cfg.Retention))
+
+	ticker := time.NewTicker(s.cfg.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.Purge(ctx)
+		}
+	}
+}
+
+// Stop stops the purge
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// PurgeReport counts what one purge run deleted for good
+type PurgeReport struct {
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+	Keys      int64     `json:"keys"`
+	Teams     int64     `json:"teams"`
+	Users     int64     `json:"users"`
+	Errors    []string  `json:"errors,omitempty"`
+}
+
+// Purge deletes for good the users, teams and keys deleted longer than the
+// retention window ago, keys first. Usage and audit records stay, detached
+// from the purged owner; budgets and memberships go with it. Records under a
+// legal hold are kept. A record that fails is retried on the next run.
+func (s *Service) Purge(ctx context.Context) *PurgeReport {
+	report := &PurgeReport{StartedAt: time.Now()}
+	cutoff := report.StartedAt.Add(-s.cfg.Retention)
+	fail := func(kind string, id uuid.UUID, err error) {
+		s.logger.Error("Failed to purge deleted record",
+			zap.String("kind", kind), zap.String("id", id.String()), zap.Error(err))
+		report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %v", kind, id, err))
+	}
+
+	steps := []struct {
+		kind    string
+		model   interface{}
+		held    func(*gorm.DB) *gorm.DB
+		purge   func(tx *gorm.DB, id uuid.UUID) error
+		counter *int64
+	}{
+		{KindKey, &models.Key{}, artifacts.NotHeld("team_id", "user_id"), purgeKey, &report.Keys},
+		{KindTeam, &models.Team{}, artifacts.NotHeld("id"), purgeTeam, &report.Teams},
+		{KindUser, &models.User{}, artifacts.NotHeld("NULL", "id"), purgeUser, &report.Users},
+	}
+	for _, step := range steps {
+		var ids []uuid.UUID
+		if err := s.db.WithContext(ctx).Unscoped().Model(step.model).Scopes(step.held).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Pluck("id", &ids).Error; err != nil {
+			s.logger.Error("Failed to find expired deleted records", zap.String("kind", step.kind), zap.Error(err))
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", step.kind, err))
+			continue
+		}
+		for _, id := range ids {
+			if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return step.purge(tx.Unscoped(), id)
+			}); err != nil {
+				fail(step.kind, id, err)
+				continue
+			}
+			*step.counter++
+			recordsPurged.WithLabelValues(step.kind).Inc()
+		}
+	}
+
+	report.Duration = time.Since(report.StartedAt).String()
+	s.mu.Lock()
+	s.lastPurge = report
+	s.mu.Unlock()
+
+	if report.Keys+report.Teams+report.Users > 0 {
+		s.logger.Info("Purged deleted records",
+			zap.Int64("keys", report.Keys),
+			zap.Int64("teams", report.Teams),
+			zap.Int64("users", report.Users))
+	}
+	return report
+}
+
+// LastPurge returns the report of the latest purge run, nil before the first
+func (s *Service) LastPurge() *PurgeReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastPurge
+}
+
+// purgeKey deletes a key, detaching its usage and audit records
+func purgeKey(tx *gorm.DB, id uuid.UUID) error {
+	for _, model := range []interface{}{&models.Usage{}, &models.Audit{}} {
+		if err := tx.Model(model).Where("key_id = ?", id).Update("key_id", nil).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&models.Key{}, "id = ?", id).Error
+}
+
+// purgeTeam deletes a team with its members, budgets and provider keys,
+// detaching its usage, audit records and any key kept by a user's legal
+// hold
+func purgeTeam(tx *gorm.DB, id uuid.UUID) error {
+	for _, model := range []interface{}{&models.Usage{}, &models.Audit{}, &models.Key{}} {
+		if err := tx.Model(model).Where("team_id = ?", id).Update("team_id", nil).Error; err != nil {
+			return err
+		}
+	}
+	for _, model := range []interface{}{&models.TeamMember{}, &models.Budget{}, &models.TeamProviderKey{}} {
+		if err := tx.Where("team_id = ?", id).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&models.Team{}, "id = ?", id).Error
+}
+
+// purgeUser deletes a user with their memberships, budgets and notification
+// settings, detaching their usage, audit records and any key kept by a
+// team's legal hold
+func purgeUser(tx *gorm.DB, id uuid.UUID) error {
+	detach := []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.Usage{}, "user_id"},
+		{&models.Usage{}, "actual_user_id"},
+		{&models.Audit{}, "user_id"},
+		{&models.Key{}, "user_id"},
+	}
+	for _, d := range detach {
+		if err := tx.Model(d.model).Where(d.column+" = ?", id).Update(d.column, nil).Error; err != nil {
+			return err
+		}
+	}
+	for _, model := range []interface{}{&models.TeamMember{}, &models.Budget{}, &models.NotificationPreference{}, &models.SpendAlert{}} {
+		if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&models.User{}, "id = ?", id).Error
+}
@@ -0,0 +1,146 @@
+package deletion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+)
+
+func TestService_DeleteAndRestore(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	service := NewService(db, config.DeletionConfig{Retention: time.Hour}, zap.NewNop())
+
+	user := &models.User{Email: "ada@example.com", Username: "ada", DexID: "ada"}
+	require.NoError(t, db.Create(user).Error)
+	team := &models.Team{Name: "research"}
+	require.NoError(t, db.Create(team).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: team.ID, UserID: user.ID, Role: models.TeamRoleOwner}).Error)
+
+	createKey := func(name string, userID, teamID *uuid.UUID) *models.Key {
+		value, hash, err := models.GenerateKey(models.KeyTypeAPI)
+		require.NoError(t, err)
+		key := &models.Key{Key: value, KeyHash: hash, Name: name, UserID: userID, TeamID: teamID, IsActive: true}
+		require.NoError(t, db.Create(key).Error)
+		return key
+	}
+	personal := createKey("personal", &user.ID, nil)
+	teamKey := createKey("team", nil, &team.ID)
+	earlier := createKey("earlier", nil, &team.ID)
+
+	live := func(model interface{}, id uuid.UUID) bool {
+		var count int64
+		require.NoError(t, db.Model(model).Where("id = ?", id).Count(&count).Error)
+		return count == 1
+	}
+
+	require.NoError(t, service.DeleteKey(ctx, earlier.ID))
+	require.NoError(t, service.DeleteTeam(ctx, team.ID))
+	assert.ErrorIs(t, service.DeleteTeam(ctx, team.ID), ErrNotFound)
+	assert.False(t, live(&models.Team{}, team.ID))
+	assert.False(t, live(&models.Key{}, teamKey.ID))
+	assert.True(t, live(&models.Key{}, personal.ID))
+
+	_, err := service.RestoreKey(ctx, teamKey.ID)
+	assert.ErrorIs(t, err, ErrOwnerDeleted)
+
+	records, err := service.ListDeleted(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, KindTeam, records[0].Kind)
+	assert.Equal(t, records[0].DeletedAt.Add(time.Hour), records[0].PurgeAt)
+
+	restored, err := service.RestoreTeam(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "research", restored.Name)
+	assert.True(t, live(&models.Key{}, teamKey.ID), "keys deleted with the team come back")
+	assert.False(t, live(&models.Key{}, earlier.ID), "keys deleted before the team stay deleted")
+	var members int64
+	require.NoError(t, db.Model(&models.TeamMember{}).Where("team_id = ?", team.ID).Count(&members).Error)
+	assert.EqualValues(t, 1, members, "members are kept")
+
+	_, err = service.RestoreTeam(ctx, team.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, service.DeleteUser(ctx, user.ID))
+	assert.False(t, live(&models.Key{}, personal.ID))
+	_, err = service.RestoreUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, live(&models.User{}, user.ID))
+	assert.True(t, live(&models.Key{}, personal.ID))
+}
+
+func TestService_Purge(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	service := NewService(db, config.DeletionConfig{Retention: time.Hour}, zap.NewNop())
+
+	user := &models.User{Email: "grace@example.com", Username: "grace", DexID: "grace"}
+	require.NoError(t, db.Create(user).Error)
+	held := &models.User{Email: "held@example.com", Username: "held", DexID: "held"}
+	require.NoError(t, db.Create(held).Error)
+	recent := &models.Team{Name: "recent"}
+	require.NoError(t, db.Create(recent).Error)
+	team := &models.Team{Name: "expired"}
+	require.NoError(t, db.Create(team).Error)
+	require.NoError(t, db.Create(&models.TeamMember{TeamID: team.ID, UserID: user.ID, Role: models.TeamRoleMember}).Error)
+
+	value, hash, err := models.GenerateKey(models.KeyTypeAPI)
+	require.NoError(t, err)
+	key := &models.Key{Key: value, KeyHash: hash, Name: "old", UserID: &user.ID, TeamID: &team.ID}
+	require.NoError(t, db.Create(key).Error)
+	usage := &models.Usage{RequestID: "req", UserID: &user.ID, TeamID: &team.ID, KeyID: &key.ID}
+	require.NoError(t, db.Create(usage).Error)
+
+	require.NoError(t, service.DeleteUser(ctx, user.ID))
+	require.NoError(t, service.DeleteUser(ctx, held.ID))
+	require.NoError(t, service.DeleteTeam(ctx, team.ID))
+	require.NoError(t, service.DeleteTeam(ctx, recent.ID))
+	expire := func(model interface{}, ids ...uuid.UUID) {
+		require.NoError(t, db.Unscoped().Model(model).Where("id IN ?", ids).
+			Update("deleted_at", time.Now().Add(-2*time.Hour)).Error)
+	}
+	expire(&models.User{}, user.ID, held.ID)
+	expire(&models.Team{}, team.ID)
+	expire(&models.Key{}, key.ID)
+	require.NoError(t, db.Create(&models.LegalHold{UserID: &held.ID, Reason: "litigation"}).Error)
+
+	report := service.Purge(ctx)
+	assert.Empty(t, report.Errors)
+	assert.EqualValues(t, 1, report.Keys)
+	assert.EqualValues(t, 1, report.Teams)
+	assert.EqualValues(t, 1, report.Users)
+
+	exists := func(model interface{}, id uuid.UUID) bool {
+		var count int64
+		require.NoError(t, db.Unscoped().Model(model).Where("id = ?", id).Count(&count).Error)
+		return count == 1
+	}
+	assert.False(t, exists(&models.User{}, user.ID))
+	assert.False(t, exists(&models.Team{}, team.ID))
+	assert.False(t, exists(&models.Key{}, key.ID))
+	assert.True(t, exists(&models.User{}, held.ID), "held users are kept")
+	assert.True(t, exists(&models.Team{}, recent.ID), "records within retention are kept")
+
+	var kept models.Usage
+	require.NoError(t, db.First(&kept, "request_id = ?", "req").Error)
+	assert.Nil(t, kept.UserID, "usage stays, detached from the purged owner")
+	assert.Nil(t, kept.TeamID)
+
+	var members int64
+	require.NoError(t, db.Model(&models.TeamMember{}).Where("team_id = ?", team.ID).Count(&members).Error)
+	assert.Zero(t, members)
+	assert.ErrorIs(t, db.Unscoped().First(&models.Key{}, "id = ?", key.ID).Error, gorm.ErrRecordNotFound)
+}
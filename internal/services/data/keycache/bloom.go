@@ -0,0 +1,69 @@
+package keycache
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"hash/fnv"
+	"math"
+)
+
+// bloomFilter is a fixed-size Bloom filter of key hashes: a hash it does
+// not contain was never added, one it contains probably was
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64 // Number of bits
+	hashes int
+}
+
+// newBloomFilter sizes a filter for n entries at the false positive rate
+func newBloomFilter(n int, falsePositiveRate float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	size := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if size < 64 {
+		size = 64
+	}
+	hashes := int(math.Round(float64(size) / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &bloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+func (b *bloomFilter) add(keyHash string) {
+	h1, h2 := bloomHashes(keyHash)
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloomFilter) contains(keyHash string) bool {
+	h1, h2 := bloomHashes(keyHash)
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes derives the two hashes the filter's positions are combined
+// from. Key hashes are hex SHA-256, so their bytes are used directly;
+// anything else is hashed first.
+func bloomHashes(keyHash string) (uint64, uint64) {
+	raw, err := hex.DecodeString(keyHash)
+	if err != nil || len(raw) < 16 {
+		h := fnv.New128a()
+		_, _ = h.Write([]byte(keyHash))
+		raw = h.Sum(nil)
+	}
+	// A zero step would put every position on the same bit
+	return binary.LittleEndian.Uint64(raw[:8]), binary.LittleEndian.Uint64(raw[8:16]) | 1
+}
@@ -0,0 +1,34 @@
+package keycache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func TestBloomFilter(t *testing.T) {
+	const n = 10000
+	filter := newBloomFilter(n, 0.01)
+
+	for i := 0; i < n; i++ {
+		filter.add(models.HashKey(fmt.Sprintf("sk-added-%d", i)))
+	}
+	for i := 0; i < n; i++ {
+		assert.True(t, filter.contains(models.HashKey(fmt.Sprintf("sk-added-%d", i))), "no false negatives")
+	}
+
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if filter.contains(models.HashKey(fmt.Sprintf("sk-unknown-%d", i))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, n*3/100, "about 1%% of unknown keys get through")
+
+	// Anything that is not a SHA-256 hash still works
+	filter.add("not-a-hash")
+	assert.True(t, filter.contains("not-a-hash"))
+}
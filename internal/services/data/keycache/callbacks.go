@@ -0,0 +1,264 @@
+package keycache
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Callback names registered on the database
+const (
+	createCallback       = "keycache:after_create"
+	beforeUpdateCallback = "keycache:before_update"
+	updateCallback       = "keycache:after_update"
+	deleteCallback       = "keycache:after_delete"
+)
+
+// reactivatedKeys holds the IDs of the keys an update by condition may make
+// valid again, for the statement's after update callback
+const reactivatedKeys = "keycache:reactivated_keys"
+
+// usageColumns are updated as keys are used and leave the cached key valid;
+// spend is checked against budgets apart from the key
+var usageColumns = map[string]bool{
+	"last_used_at":  true,
+	"usage_count":   true,
+	"current_spend": true,
+	"updated_at":    true,
+}
+
+// reactivationColumns can make a key valid again or give it a new hash, so
+// the keys they are written to rejoin the filter
+var reactivationColumns = map[string]bool{
+	"is_active":  true,
+	"deleted_at": true,
+	"key_hash":   true,
+}
+
+// ownerColumns are the key columns referencing the teams and users whose
+// copies cached keys carry
+var ownerColumns = map[string]string{
+	"teams": "team_id",
+	"users": "user_id",
+}
+
+// RegisterCallbacks keeps the cache in step with the key writes made
+// through db: created and reactivated keys join the filter, and changed or
+// deleted keys and the keys of changed teams and users are dropped from the
+// caches. Writes made elsewhere are picked up by the periodic reload and the
+// expiry of cached keys.
+func (c *KeyCache) RegisterCallbacks(db *gorm.DB) error {
+	// A cache created again on the same database replaces the callbacks of
+	// the previous one
+	create := db.Callback().Create()
+	if create.Get(createCallback) != nil {
+		if err := create.Replace(createCallback, c.afterCreate); err != nil {
+			return err
+		}
+	} else if err := create.After("gorm:create").Register(createCallback, c.afterCreate); err != nil {
+		return err
+	}
+
+	update := db.Callback().Update()
+	if update.Get(beforeUpdateCallback) != nil {
+		if err := update.Replace(beforeUpdateCallback, c.beforeUpdate); err != nil {
+			return err
+		}
+	} else if err := update.Before("gorm:update").Register(beforeUpdateCallback, c.beforeUpdate); err != nil {
+		return err
+	}
+	if update.Get(updateCallback) != nil {
+		if err := update.Replace(updateCallback, c.afterUpdate); err != nil {
+			return err
+		}
+	} else if err := update.After("gorm:update").Register(updateCallback, c.afterUpdate); err != nil {
+		return err
+	}
+
+	remove := db.Callback().Delete()
+	if remove.Get(deleteCallback) != nil {
+		return remove.Replace(deleteCallback, c.afterWrite)
+	}
+	return remove.After("gorm:delete").Register(deleteCallback, c.afterWrite)
+}
+
+func (c *KeyCache) afterCreate(db *gorm.DB) {
+	if !isKeyWrite(db) {
+		return
+	}
+	ctx := statementContext(db)
+	switch dest := db.Statement.Dest.(type) {
+	case *models.Key:
+		c.Added(ctx, dest.KeyHash)
+	case []models.Key:
+		for i := range dest {
+			c.Added(ctx, dest[i].KeyHash)
+		}
+	case []*models.Key:
+		for _, key := range dest {
+			c.Added(ctx, key.KeyHash)
+		}
+	}
+}
+
+// beforeUpdate notes the keys an update by condition may reactivate, such
+// as a restore clearing deleted_at, while its conditions still select them
+func (c *KeyCache) beforeUpdate(db *gorm.DB) {
+	if db.Error != nil || tableOf(db) != "keys" || !mayReactivate(db) {
+		return
+	}
+	if key, ok := db.Statement.Model.(*models.Key); ok && key.ID != uuid.Nil {
+		return
+	}
+	where, ok := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok {
+		return
+	}
+
+	var ids []uuid.UUID
+	if err := db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Key{}).
+		Clauses(where).
+		Pluck("id", &ids).Error; err != nil {
+		c.logger.Warn("Failed to find the keys of an update, they rejoin the filter on reload", zap.Error(err))
+		return
+	}
+	db.InstanceSet(reactivatedKeys, ids)
+}
+
+func (c *KeyCache) afterUpdate(db *gorm.DB) {
+	c.afterWrite(db)
+	if !isWrite(db) || tableOf(db) != "keys" || !mayReactivate(db) {
+		return
+	}
+
+	var ids []uuid.UUID
+	if key, ok := db.Statement.Model.(*models.Key); ok && key.ID != uuid.Nil {
+		ids = []uuid.UUID{key.ID}
+	} else if noted, ok := db.InstanceGet(reactivatedKeys); ok {
+		ids = noted.([]uuid.UUID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	// The filter only grows, so the keys still active are added again
+	var hashes []string
+	if err := db.Session(&gorm.Session{NewDB: true}).Model(&models.Key{}).
+		Where("id IN ? AND is_active = ?", ids, true).
+		Pluck("key_hash", &hashes).Error; err != nil {
+		c.logger.Warn("Failed to load reactivated keys, they rejoin the filter on reload", zap.Error(err))
+		return
+	}
+	ctx := statementContext(db)
+	for _, hash := range hashes {
+		c.Added(ctx, hash)
+	}
+}
+
+func (c *KeyCache) afterWrite(db *gorm.DB) {
+	if !isWrite(db) {
+		return
+	}
+	if columns, ok := db.Statement.Dest.(map[string]interface{}); ok && onlyUsageColumns(columns) {
+		return
+	}
+
+	switch table := tableOf(db); table {
+	case "keys":
+		c.keysChanged(db)
+	case "teams", "users":
+		c.ownerChanged(db, ownerColumns[table])
+	}
+}
+
+func (c *KeyCache) keysChanged(db *gorm.DB) {
+	ctx := statementContext(db)
+	if key, ok := db.Statement.Model.(*models.Key); ok && key.ID != uuid.Nil {
+		c.Changed(ctx, key.ID)
+		return
+	}
+	// Writes by condition may have touched any key
+	c.Flush(ctx)
+}
+
+// ownerChanged drops the cached keys of a changed team or user, which carry
+// a copy of it
+func (c *KeyCache) ownerChanged(db *gorm.DB, column string) {
+	ctx := statementContext(db)
+	var ownerID uuid.UUID
+	switch owner := db.Statement.Model.(type) {
+	case *models.Team:
+		ownerID = owner.ID
+	case *models.User:
+		ownerID = owner.ID
+	}
+	if ownerID == uuid.Nil {
+		// Writes by condition may have touched the owner of any key
+		c.Flush(ctx)
+		return
+	}
+
+	var ids []uuid.UUID
+	if err := db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Key{}).
+		Where(column+" = ?", ownerID).
+		Pluck("id", &ids).Error; err != nil {
+		c.Flush(ctx)
+		return
+	}
+	for _, id := range ids {
+		c.Changed(ctx, id)
+	}
+}
+
+// isKeyWrite reports whether a statement wrote to the keys table
+func isKeyWrite(db *gorm.DB) bool {
+	return isWrite(db) && tableOf(db) == "keys"
+}
+
+// isWrite reports whether a statement wrote any rows
+func isWrite(db *gorm.DB) bool {
+	return db.Error == nil && db.RowsAffected > 0
+}
+
+func tableOf(db *gorm.DB) string {
+	if db.Statement.Schema == nil {
+		return ""
+	}
+	return db.Statement.Schema.Table
+}
+
+// mayReactivate reports whether an update may make a key valid again.
+// Updates from structs may set any column.
+func mayReactivate(db *gorm.DB) bool {
+	columns, ok := db.Statement.Dest.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	for column := range columns {
+		if reactivationColumns[column] {
+			return true
+		}
+	}
+	return false
+}
+
+func onlyUsageColumns(columns map[string]interface{}) bool {
+	for column := range columns {
+		if !usageColumns[column] {
+			return false
+		}
+	}
+	return len(columns) > 0
+}
+
+func statementContext(db *gorm.DB) context.Context {
+	if db.Statement.Context != nil {
+		return db.Statement.Context
+	}
+	return context.Background()
+}
@@ -0,0 +1,349 @@
+package keycache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// Redis keys and channel of the cache
+const (
+	// changesChannel carries key changes to the other gateways
+	changesChannel = "pllm:keys:changes"
+	// entryPrefix prefixes the validated keys, by key hash
+	entryPrefix = "pllm:keys:valid:"
+	// idPrefix prefixes the key hash of each cached key, by key ID
+	idPrefix = "pllm:keys:id:"
+)
+
+// Change event types
+const (
+	eventCreated = "created" // A key was created; its hash joins the filter
+	eventChanged = "changed" // A key was updated, revoked or deleted
+	eventFlush   = "flush"   // Keys changed that cannot be told apart
+)
+
+// falsePositiveRate is the share of unknown hashes the filter lets through
+// to the database
+const falsePositiveRate = 0.01
+
+// minCapacity sizes the filter of small deployments for the keys created
+// before the next reload
+const minCapacity = 1024
+
+var lookups = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_key_cache_lookups_total",
+		Help: "API key lookups by outcome: rejected by the filter, or a hit or miss of the cache",
+	},
+	[]string{"result"},
+)
+
+// event is a key change published to the other gateways
+type event struct {
+	Type    string    `json:"type"`
+	KeyHash string    `json:"key_hash,omitempty"`
+	KeyID   uuid.UUID `json:"key_id,omitempty"`
+}
+
+// KeyCache answers API key lookups before the database. A Bloom filter of
+// the hashes of the active keys rejects unknown keys without a query, and
+// keys validated on any gateway are shared in Redis. The filter is reloaded
+// from the database in the background and swapped in whole, so lookups are
+// never blocked, and key changes reach the other gateways over Redis
+// pub/sub.
+type KeyCache struct {
+	db              *gorm.DB
+	client          *redis.Client
+	logger          *zap.Logger
+	refreshInterval time.Duration
+	ttl             time.Duration
+
+	mu        sync.RWMutex
+	filter    *bloomFilter // Nil until first loaded: every key might exist
+	reloading bool
+	added     []string // Hashes added while reloading
+	listeners []func(keyID uuid.UUID)
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+type Config struct {
+	DB              *gorm.DB
+	Redis           *redis.Client // Optional, shares validated keys and changes
+	Logger          *zap.Logger
+	RefreshInterval time.Duration // Full reload of the filter
+	TTL             time.Duration // Lifetime of the keys cached in Redis
+}
+
+func New(cfg *Config) *KeyCache {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = time.Minute
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+	return &KeyCache{
+		db:              cfg.DB,
+		client:          cfg.Redis,
+		logger:          cfg.Logger.Named("key_cache"),
+		refreshInterval: cfg.RefreshInterval,
+		ttl:             cfg.TTL,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start loads the filter and follows key changes
+func (c *KeyCache) Start(ctx context.Context) {
+	if err := c.Load(ctx); err != nil {
+		c.logger.Warn("Failed to load the key filter, keys are checked in the database", zap.Error(err))
+	}
+	if c.client != nil {
+		go c.subscribe(ctx)
+	}
+	go c.refresh(ctx)
+}
+
+// Stop stops following changes
+func (c *KeyCache) Stop() {
+	c.stopOnce.Do(func() { close(c.stopCh) })
+}
+
+// Load rebuilds the filter from the active keys in the database. Keys added
+// while it runs are carried over to the new filter.
+func (c *KeyCache) Load(ctx context.Context) error {
+	c.mu.Lock()
+	c.reloading = true
+	c.added = nil
+	c.mu.Unlock()
+
+	var hashes []string
+	err := c.db.WithContext(ctx).Model(&models.Key{}).
+		Where("is_active = ?", true).
+		Pluck("key_hash", &hashes).Error
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloading = false
+	if err != nil {
+		c.added = nil
+		return err
+	}
+
+	capacity := 2 * len(hashes)
+	if capacity < minCapacity {
+		capacity = minCapacity
+	}
+	filter := newBloomFilter(capacity, falsePositiveRate)
+	for _, hash := range hashes {
+		filter.add(hash)
+	}
+	for _, hash := range c.added {
+		filter.add(hash)
+	}
+	c.added = nil
+	c.filter = filter
+	return nil
+}
+
+// MightExist reports whether the key hash may belong to an active key.
+// False means it does not, and the key can be rejected without a query.
+func (c *KeyCache) MightExist(keyHash string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.filter == nil || c.filter.contains(keyHash) {
+		return true
+	}
+	lookups.WithLabelValues("rejected").Inc()
+	return false
+}
+
+// Get returns the validated key cached for the hash
+func (c *KeyCache) Get(ctx context.Context, keyHash string) (*models.Key, bool) {
+	if c.client == nil {
+		return nil, false
+	}
+	data, err := c.client.Get(ctx, redisService.Key(entryPrefix+keyHash)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.logger.Debug("Failed to read cached key", zap.Error(err))
+		}
+		lookups.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	var key models.Key
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&key); err != nil {
+		c.logger.Warn("Ignoring invalid cached key", zap.Error(err))
+		lookups.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+	lookups.WithLabelValues("hit").Inc()
+	return &key, true
+}
+
+// Set caches a key validated against the database for the other gateways
+func (c *KeyCache) Set(ctx context.Context, key *models.Key) {
+	if c.client == nil || key.KeyHash == "" {
+		return
+	}
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(key); err != nil {
+		c.logger.Warn("Failed to encode key for the cache", zap.Error(err))
+		return
+	}
+
+	pipe := c.client.Pipeline()
+	pipe.Set(ctx, redisService.Key(entryPrefix+key.KeyHash), data.Bytes(), c.ttl)
+	pipe.Set(ctx, redisService.Key(idPrefix+key.ID.String()), key.KeyHash, c.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.logger.Debug("Failed to cache key", zap.Error(err))
+	}
+}
+
+// OnInvalidate registers fn to be called with the ID of every key that
+// changed, here or on another gateway, or uuid.Nil when any may have
+func (c *KeyCache) OnInvalidate(fn func(keyID uuid.UUID)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// Added adds a new key to the filter of every gateway
+func (c *KeyCache) Added(ctx context.Context, keyHash string) {
+	c.add(keyHash)
+	c.publish(ctx, event{Type: eventCreated, KeyHash: keyHash})
+}
+
+// Changed drops a key from the caches of every gateway
+func (c *KeyCache) Changed(ctx context.Context, keyID uuid.UUID) {
+	if c.client != nil {
+		idKey := redisService.Key(idPrefix + keyID.String())
+		if hash, err := c.client.Get(ctx, idKey).Result(); err == nil {
+			c.client.Del(ctx, redisService.Key(entryPrefix+hash), idKey)
+		}
+	}
+	c.notify(keyID)
+	c.publish(ctx, event{Type: eventChanged, KeyID: keyID})
+}
+
+// Flush drops every key from the caches of every gateway
+func (c *KeyCache) Flush(ctx context.Context) {
+	if c.client != nil {
+		for _, prefix := range []string{entryPrefix, idPrefix} {
+			iter := c.client.Scan(ctx, 0, redisService.Key(prefix)+"*", 100).Iterator()
+			for iter.Next(ctx) {
+				c.client.Del(ctx, iter.Val())
+			}
+			if err := iter.Err(); err != nil {
+				c.logger.Warn("Failed to flush cached keys", zap.Error(err))
+			}
+		}
+	}
+	c.notify(uuid.Nil)
+	c.publish(ctx, event{Type: eventFlush})
+}
+
+func (c *KeyCache) add(keyHash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.filter != nil {
+		c.filter.add(keyHash)
+	}
+	if c.reloading {
+		c.added = append(c.added, keyHash)
+	}
+}
+
+func (c *KeyCache) notify(keyID uuid.UUID) {
+	c.mu.RLock()
+	listeners := c.listeners
+	c.mu.RUnlock()
+	for _, fn := range listeners {
+		fn(keyID)
+	}
+}
+
+func (c *KeyCache) apply(e event) {
+	switch e.Type {
+	case eventCreated:
+		c.add(e.KeyHash)
+	case eventChanged:
+		c.notify(e.KeyID)
+	case eventFlush:
+		c.notify(uuid.Nil)
+	}
+}
+
+func (c *KeyCache) publish(ctx context.Context, e event) {
+	if c.client == nil {
+		return
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if err := c.client.Publish(ctx, redisService.Key(changesChannel), payload).Err(); err != nil {
+		c.logger.Warn("Failed to publish key change, other gateways apply it on their next refresh",
+			zap.String("type", e.Type),
+			zap.Error(err))
+	}
+}
+
+func (c *KeyCache) subscribe(ctx context.Context) {
+	pubsub := c.client.Subscribe(ctx, redisService.Key(changesChannel))
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopCh:
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var e event
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				c.logger.Warn("Ignoring invalid key change", zap.Error(err))
+				continue
+			}
+			c.apply(e)
+		}
+	}
+}
+
+func (c *KeyCache) refresh(ctx context.Context) {
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			if err := c.Load(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				c.logger.Warn("Failed to reload the key filter", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package keycache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func TestKeyCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newCache := func() *KeyCache {
+		return New(&Config{Redis: client, Logger: zap.NewNop()})
+	}
+	writer, replica := newCache(), newCache()
+	defer replica.Stop()
+
+	t.Run("every key might exist before the filter is loaded", func(t *testing.T) {
+		assert.True(t, replica.MightExist(models.HashKey("sk-anything")))
+	})
+
+	team := &models.Team{Name: "research"}
+	team.ID = uuid.New()
+	key := &models.Key{KeyHash: models.HashKey("sk-valid"), Name: "ci", IsActive: true, TeamID: &team.ID, Team: team}
+	key.ID = uuid.New()
+
+	t.Run("validated keys are shared", func(t *testing.T) {
+		_, ok := replica.Get(ctx, key.KeyHash)
+		assert.False(t, ok)
+
+		writer.Set(ctx, key)
+		cached, ok := replica.Get(ctx, key.KeyHash)
+		require.True(t, ok)
+		assert.Equal(t, key.ID, cached.ID)
+		assert.Equal(t, "research", cached.Team.Name)
+		assert.Equal(t, 5*time.Minute, mr.TTL(entryPrefix+key.KeyHash))
+	})
+
+	replica.filter = newBloomFilter(minCapacity, falsePositiveRate)
+	invalidated := make(chan uuid.UUID, 10)
+	replica.OnInvalidate(func(keyID uuid.UUID) { invalidated <- keyID })
+	go replica.subscribe(ctx)
+
+	t.Run("created keys join the filter of every gateway", func(t *testing.T) {
+		hash := models.HashKey("sk-new")
+		assert.False(t, replica.MightExist(hash), "unknown keys are rejected")
+
+		// Publish until the replica's subscription is up
+		require.Eventually(t, func() bool {
+			writer.Added(ctx, hash)
+			return replica.MightExist(hash)
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("changed keys are dropped everywhere", func(t *testing.T) {
+		writer.Changed(ctx, key.ID)
+		_, ok := replica.Get(ctx, key.KeyHash)
+		assert.False(t, ok)
+
+		select {
+		case keyID := <-invalidated:
+			assert.Equal(t, key.ID, keyID)
+		case <-time.After(2 * time.Second):
+			t.Fatal("the replica was not told about the change")
+		}
+	})
+
+	t.Run("flush drops every key", func(t *testing.T) {
+		writer.Set(ctx, key)
+		writer.Flush(ctx)
+		_, ok := replica.Get(ctx, key.KeyHash)
+		assert.False(t, ok)
+		assert.False(t, mr.Exists(idPrefix+key.ID.String()))
+
+		require.Eventually(t, func() bool {
+			select {
+			case keyID := <-invalidated:
+				return keyID == uuid.Nil
+			default:
+				return false
+			}
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("keys added while reloading are kept", func(t *testing.T) {
+		cache := newCache()
+		cache.reloading = true
+		cache.add(models.HashKey("sk-during-reload"))
+		assert.Equal(t, []string{models.HashKey("sk-during-reload")}, cache.added)
+	})
+}
+
+func TestOnlyUsageColumns(t *testing.T) {
+	assert.True(t, onlyUsageColumns(map[string]interface{}{"last_used_at": time.Now(), "usage_count": 1}))
+	assert.True(t, onlyUsageColumns(map[string]interface{}{"current_spend": 1.5}))
+	assert.False(t, onlyUsageColumns(map[string]interface{}{"is_active": false, "updated_at": time.Now()}))
+	assert.False(t, onlyUsageColumns(map[string]interface{}{}))
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// AdvisoryLockManager hands out distributed locks backed by Postgres
+// session-level advisory locks.
+//
+// Advisory locks do not expire. A lock is held until it is released or the
+// connection holding it closes, so a crashed holder frees its locks as soon
+// as Postgres notices the dropped session. The ttl arguments are accepted for
+// interface compatibility and otherwise ignored.
+type AdvisoryLockManager struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+// AdvisoryLock is a held advisory lock pinned to its own connection
+type AdvisoryLock struct {
+	conn   *sql.Conn
+	logger *zap.Logger
+	name   string
+	key    int64
+}
+
+var _ redisService.LockBackend = (*AdvisoryLockManager)(nil)
+
+// NewAdvisoryLockManager creates a new advisory lock manager
+func NewAdvisoryLockManager(db *gorm.DB, logger *zap.Logger) *AdvisoryLockManager {
+	return &AdvisoryLockManager{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// AcquireLock attempts to acquire an advisory lock without waiting
+func (lm *AdvisoryLockManager) AcquireLock(ctx context.Context, lockKey string, ttl time.Duration) (redisService.Lock, error) {
+	sqlDB, err := lm.db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	// Session-level advisory locks belong to a connection, so the lock keeps
+	// a dedicated one out of the pool until it is released
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	key := advisoryLockKey(lockKey)
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	if !acquired {
+		_ = conn.Close()
+		return nil, fmt.Errorf("lock already held: %s", lockKey)
+	}
+
+	lm.logger.Debug("Lock acquired",
+		zap.String("lock_key", lockKey),
+		zap.Int64("advisory_key", key))
+
+	return &AdvisoryLock{
+		conn:   conn,
+		logger: lm.logger,
+		name:   lockKey,
+		key:    key,
+	}, nil
+}
+
+// Release releases the advisory lock and returns its connection to the pool
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	defer func() { _ = l.conn.Close() }()
+
+	var released bool
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.key).Scan(&released); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+
+	if !released {
+		l.logger.Warn("Lock was not owned by this instance",
+			zap.String("lock_key", l.name))
+		return fmt.Errorf("lock not owned by this instance")
+	}
+
+	l.logger.Debug("Lock released", zap.String("lock_key", l.name))
+	return nil
+}
+
+// Extend verifies the session holding the lock is still alive. Advisory
+// locks have no TTL, so there is nothing else to extend.
+func (l *AdvisoryLock) Extend(ctx context.Context, additionalTTL time.Duration) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("lock session lost: %w", err)
+	}
+	return nil
+}
+
+// advisoryLockKey maps a lock name onto the bigint key space of advisory locks
+func advisoryLockKey(lockKey string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("lock:" + lockKey))
+	return int64(h.Sum64())
+}
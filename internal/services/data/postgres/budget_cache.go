@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// BudgetCache is a Postgres-backed budget status cache. Rows expire after
+// the configured TTL and are treated as cache misses from then on.
+type BudgetCache struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	ttl    time.Duration
+}
+
+var _ redisService.BudgetCacheBackend = (*BudgetCache)(nil)
+
+// NewBudgetCache creates a new Postgres-backed budget cache
+func NewBudgetCache(db *gorm.DB, logger *zap.Logger, ttl time.Duration) *BudgetCache {
+	if ttl == 0 {
+		ttl = 5 * time.Minute // Default TTL
+	}
+
+	return &BudgetCache{
+		db:     db,
+		logger: logger,
+		ttl:    ttl,
+	}
+}
+
+// CheckBudgetAvailable performs a budget check against the cached status
+func (bc *BudgetCache) CheckBudgetAvailable(ctx context.Context, entityType, entityID string, requestCost float64) (bool, error) {
+	entry, err := bc.get(ctx, entityType, entityID)
+	if err != nil {
+		return false, err
+	}
+	if entry != nil {
+		available := entry.Available - requestCost
+		return available >= 0 && !entry.IsExceeded, nil
+	}
+
+	// Cache miss - return optimistic result, the usage processor refreshes it
+	bc.logger.Debug("Budget cache miss, allowing request optimistically",
+		zap.String("entity_type", entityType),
+		zap.String("entity_id", entityID))
+
+	return true, nil
+}
+
+// UpdateBudgetCache updates cached budget status
+func (bc *BudgetCache) UpdateBudgetCache(ctx context.Context, entityType, entityID string, available, spent, limit float64, isExceeded bool) error {
+	entry := &BudgetCacheEntry{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Available:  available,
+		Spent:      spent,
+		Limit:      limit,
+		IsExceeded: isExceeded,
+		ExpiresAt:  time.Now().Add(bc.ttl),
+	}
+
+	err := bc.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "entity_type"}, {Name: "entity_id"}},
+		UpdateAll: true,
+	}).Create(entry).Error
+	if err != nil {
+		bc.logger.Error("Failed to update budget cache",
+			zap.Error(err),
+			zap.String("entity_type", entityType),
+			zap.String("entity_id", entityID))
+		return err
+	}
+
+	bc.logger.Debug("Budget cache updated",
+		zap.String("entity_type", entityType),
+		zap.String("entity_id", entityID),
+		zap.Float64("available", available),
+		zap.Float64("spent", spent))
+
+	return nil
+}
+
+// IncrementSpent atomically increments the spent amount of a cached entry
+func (bc *BudgetCache) IncrementSpent(ctx context.Context, entityType, entityID string, amount float64) error {
+	err := bc.db.WithContext(ctx).Model(&BudgetCacheEntry{}).
+		Where("entity_type = ? AND entity_id = ? AND expires_at > ?", entityType, entityID, time.Now()).
+		Updates(map[string]interface{}{
+			"spent":       gorm.Expr("spent + ?", amount),
+			"available":   gorm.Expr("available - ?", amount),
+			"is_exceeded": gorm.Expr("is_exceeded OR (budget_limit > 0 AND spent + ? >= budget_limit)", amount),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to increment spent amount: %w", err)
+	}
+
+	bc.logger.Debug("Budget spent incremented",
+		zap.String("entity_type", entityType),
+		zap.String("entity_id", entityID),
+		zap.Float64("amount", amount))
+
+	return nil
+}
+
+// InvalidateBudgetCache removes cached budget status
+func (bc *BudgetCache) InvalidateBudgetCache(ctx context.Context, entityType, entityID string) error {
+	err := bc.db.WithContext(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Delete(&BudgetCacheEntry{}).Error
+	if err != nil {
+		bc.logger.Error("Failed to invalidate budget cache",
+			zap.Error(err),
+			zap.String("entity_type", entityType),
+			zap.String("entity_id", entityID))
+		return err
+	}
+
+	bc.logger.Debug("Budget cache invalidated",
+		zap.String("entity_type", entityType),
+		zap.String("entity_id", entityID))
+
+	return nil
+}
+
+// GetBudgetStats returns current budget statistics from cache, or nil on a miss
+func (bc *BudgetCache) GetBudgetStats(ctx context.Context, entityType, entityID string) (*redisService.BudgetStatus, error) {
+	entry, err := bc.get(ctx, entityType, entityID)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	var percentage float64
+	if entry.Limit > 0 {
+		percentage = (entry.Spent / entry.Limit) * 100
+	}
+
+	return &redisService.BudgetStatus{
+		EntityID:    entry.EntityID,
+		EntityType:  entry.EntityType,
+		Available:   entry.Available,
+		Spent:       entry.Spent,
+		Limit:       entry.Limit,
+		Percentage:  percentage,
+		IsExceeded:  entry.IsExceeded,
+		LastUpdated: entry.UpdatedAt,
+		TTL:         int64(time.Until(entry.ExpiresAt).Seconds()),
+	}, nil
+}
+
+// get returns the unexpired cache entry, or nil on a miss
+func (bc *BudgetCache) get(ctx context.Context, entityType, entityID string) (*BudgetCacheEntry, error) {
+	var entry BudgetCacheEntry
+	err := bc.db.WithContext(ctx).
+		Where("entity_type = ? AND entity_id = ? AND expires_at > ?", entityType, entityID, time.Now()).
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil // Cache miss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget status from cache: %w", err)
+	}
+	return &entry, nil
+}
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+func TestUsageQueue_Postgres(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	require.NoError(t, Migrate(db))
+
+	ctx := context.Background()
+	queue := NewUsageQueue(&UsageQueueConfig{DB: db, Logger: zap.NewNop(), BatchSize: 10, MaxRetries: 2})
+
+	for i := 0; i < 25; i++ {
+		require.NoError(t, queue.EnqueueUsage(ctx, &redisService.UsageRecord{Model: "gpt-4", TotalCost: 0.01}))
+	}
+
+	t.Run("concurrent consumers never share records", func(t *testing.T) {
+		var (
+			mu   sync.Mutex
+			seen = map[string]int{}
+			wg   sync.WaitGroup
+		)
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					records, err := queue.DequeueUsageBatch(ctx)
+					if !assert.NoError(t, err) || len(records) == 0 {
+						return
+					}
+					mu.Lock()
+					for _, r := range records {
+						seen[r.ID]++
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, seen, 25)
+		for id, count := range seen {
+			assert.Equal(t, 1, count, "record %s dequeued more than once", id)
+		}
+	})
+
+	t.Run("claimed records stay queued until acknowledged", func(t *testing.T) {
+		require.NoError(t, queue.ClearQueue(ctx))
+		leased := NewUsageQueue(&UsageQueueConfig{DB: db, Logger: zap.NewNop(), BatchSize: 10, LeaseTime: time.Millisecond})
+		require.NoError(t, leased.EnqueueUsage(ctx, &redisService.UsageRecord{ID: "lost-worker", Model: "gpt-4"}))
+		require.NoError(t, leased.EnqueueUsage(ctx, &redisService.UsageRecord{ID: "acked", Model: "gpt-4"}))
+
+		records, err := leased.DequeueUsageBatch(ctx)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.NoError(t, leased.AckUsage(db, records[1:]))
+
+		// The unacknowledged record comes back once its lease expires
+		time.Sleep(5 * time.Millisecond)
+		records, err = leased.DequeueUsageBatch(ctx)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "lost-worker", records[0].ID)
+		require.NoError(t, leased.AckUsage(db, records))
+
+		records, err = leased.DequeueUsageBatch(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, records)
+	})
+
+	t.Run("failed records are delayed then dead-lettered", func(t *testing.T) {
+		record := &redisService.UsageRecord{ID: "retry-me", Model: "gpt-4"}
+
+		require.NoError(t, queue.EnqueueUsageFailed(ctx, record, "db down"))
+		stats, err := queue.GetQueueStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), stats.MainQueue)
+		assert.Equal(t, int64(1), stats.RetryQueue)
+
+		records, err := queue.DequeueUsageBatch(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, records, "delayed record must not be visible yet")
+
+		require.NoError(t, queue.EnqueueUsageFailed(ctx, record, "db down"))
+		stats, err = queue.GetQueueStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), stats.DeadLetterQueue)
+	})
+}
+
+func TestBudgetCache_Postgres(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	require.NoError(t, Migrate(db))
+
+	ctx := context.Background()
+	cache := NewBudgetCache(db, zap.NewNop(), time.Minute)
+
+	// Cache miss is optimistic
+	ok, err := cache.CheckBudgetAvailable(ctx, "team", "t1", 5)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, cache.UpdateBudgetCache(ctx, "team", "t1", 10, 90, 100, false))
+	ok, err = cache.CheckBudgetAvailable(ctx, "team", "t1", 5)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, cache.IncrementSpent(ctx, "team", "t1", 10))
+	status, err := cache.GetBudgetStats(ctx, "team", "t1")
+	require.NoError(t, err)
+	require.NotNil(t, status)
+	assert.InDelta(t, 100, status.Spent, 1e-9)
+	assert.True(t, status.IsExceeded)
+
+	ok, err = cache.CheckBudgetAvailable(ctx, "team", "t1", 0.01)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.InvalidateBudgetCache(ctx, "team", "t1"))
+	status, err = cache.GetBudgetStats(ctx, "team", "t1")
+	require.NoError(t, err)
+	assert.Nil(t, status)
+}
+
+func TestAdvisoryLockManager_Postgres(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	locks := NewAdvisoryLockManager(db, zap.NewNop())
+
+	lock, err := locks.AcquireLock(ctx, "usage_processor_lock", time.Minute)
+	require.NoError(t, err)
+
+	_, err = locks.AcquireLock(ctx, "usage_processor_lock", time.Minute)
+	assert.Error(t, err, "lock must be exclusive across sessions")
+
+	other, err := locks.AcquireLock(ctx, "another_lock", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, other.Release(ctx))
+
+	require.NoError(t, lock.Extend(ctx, time.Minute))
+	require.NoError(t, lock.Release(ctx))
+
+	lock, err = locks.AcquireLock(ctx, "usage_processor_lock", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release(ctx))
+}
+
+func TestReplicaRegistry_Postgres(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	require.NoError(t, Migrate(db))
+
+	ctx := context.Background()
+	registry := NewReplicaRegistry(db, zap.NewNop())
+	now := time.Now()
+
+	replica := &redisService.Replica{ID: "gw-1", Hostname: "gw-1", Version: "v1", StartedAt: now, ConfigHash: "abc", HeartbeatAt: now, Healthy: true}
+	require.NoError(t, registry.RegisterReplica(ctx, replica, time.Minute))
+	require.NoError(t, registry.RegisterReplica(ctx, &redisService.Replica{ID: "gw-0", ConfigHash: "abc", HeartbeatAt: now}, -time.Second))
+
+	replica.ConfigHash = "def"
+	require.NoError(t, registry.RegisterReplica(ctx, replica, time.Minute))
+
+	replicas, err := registry.ListReplicas(ctx)
+	require.NoError(t, err)
+	require.Len(t, replicas, 1, "expired replicas are not listed")
+	assert.Equal(t, "def", replicas[0].ConfigHash, "heartbeats update the replica")
+
+	require.NoError(t, registry.DeregisterReplica(ctx, "gw-1"))
+	replicas, err = registry.ListReplicas(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, replicas)
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// ReplicaRegistry keeps the registered gateway replicas in Postgres
+type ReplicaRegistry struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+var _ redisService.ReplicaRegistryBackend = (*ReplicaRegistry)(nil)
+
+// NewReplicaRegistry creates a Postgres-backed replica registry
+func NewReplicaRegistry(db *gorm.DB, logger *zap.Logger) *ReplicaRegistry {
+	return &ReplicaRegistry{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// RegisterReplica stores a replica until ttl passes without another
+// heartbeat, and deletes replicas that expired
+func (r *ReplicaRegistry) RegisterReplica(ctx context.Context, replica *redisService.Replica, ttl time.Duration) error {
+	now := time.Now()
+	row := &GatewayReplica{
+		ID:          replica.ID,
+		Hostname:    replica.Hostname,
+		Version:     replica.Version,
+		StartedAt:   replica.StartedAt,
+		ConfigHash:  replica.ConfigHash,
+		HeartbeatAt: replica.HeartbeatAt,
+		Healthy:     replica.Healthy,
+		Error:       replica.Error,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(row).Error
+	if err != nil {
+		return fmt.Errorf("register replica: %w", err)
+	}
+
+	if err := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&GatewayReplica{}).Error; err != nil {
+		r.logger.Debug("Failed to prune expired replicas", zap.Error(err))
+	}
+	return nil
+}
+
+// DeregisterReplica removes a replica that is shutting down
+func (r *ReplicaRegistry) DeregisterReplica(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&GatewayReplica{}).Error; err != nil {
+		return fmt.Errorf("deregister replica: %w", err)
+	}
+	return nil
+}
+
+// ListReplicas returns the registered replicas that have not expired,
+// ordered by ID
+func (r *ReplicaRegistry) ListReplicas(ctx context.Context) ([]redisService.Replica, error) {
+	var rows []GatewayReplica
+	err := r.db.WithContext(ctx).
+		Where("expires_at > ?", time.Now()).
+		Order("id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("list replicas: %w", err)
+	}
+
+	replicas := make([]redisService.Replica, 0, len(rows))
+	for _, row := range rows {
+		replicas = append(replicas, redisService.Replica{
+			ID:          row.ID,
+			Hostname:    row.Hostname,
+			Version:     row.Version,
+			StartedAt:   row.StartedAt,
+			ConfigHash:  row.ConfigHash,
+			HeartbeatAt: row.HeartbeatAt,
+			Healthy:     row.Healthy,
+			Error:       row.Error,
+		})
+	}
+	return replicas, nil
+}
@@ -0,0 +1,80 @@
+// Package postgres provides Postgres-backed implementations of the usage
+// queue, budget cache, distributed locks and replica registry for
+// deployments where Redis is not available.
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Usage queue item statuses
+const (
+	queueStatusPending    = "pending"
+	queueStatusDeadLetter = "dead_letter"
+)
+
+// UsageQueueItem is a usage record waiting in the Postgres usage queue.
+// Delayed retries share the table with fresh records and only become
+// visible to consumers once AvailableAt has passed. ClaimedUntil is set while
+// a consumer holds the record.
+type UsageQueueItem struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement"`
+	Queue        string    `gorm:"not null;index:idx_usage_queue_ready,priority:1"`
+	Status       string    `gorm:"not null;default:pending;index:idx_usage_queue_ready,priority:2"`
+	AvailableAt  time.Time `gorm:"not null;index:idx_usage_queue_ready,priority:3"`
+	ClaimedUntil *time.Time
+	Payload      string `gorm:"type:jsonb;not null"`
+	Retries      int    `gorm:"default:0"`
+	LastError    string `gorm:"type:text"`
+	CreatedAt    time.Time
+}
+
+func (UsageQueueItem) TableName() string {
+	return "usage_queue_items"
+}
+
+// BudgetCacheEntry is a cached budget status row. Columns deliberately have
+// no defaults so upserts write zero values instead of skipping them.
+type BudgetCacheEntry struct {
+	EntityType string    `gorm:"primaryKey"`
+	EntityID   string    `gorm:"primaryKey"`
+	Available  float64   `gorm:"not null"`
+	Spent      float64   `gorm:"not null"`
+	Limit      float64   `gorm:"column:budget_limit;not null"`
+	IsExceeded bool      `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	UpdatedAt  time.Time
+}
+
+func (BudgetCacheEntry) TableName() string {
+	return "budget_cache_entries"
+}
+
+// GatewayReplica is a registered gateway replica. The row counts as gone
+// once ExpiresAt passes without a heartbeat.
+type GatewayReplica struct {
+	ID          string    `gorm:"primaryKey"`
+	Hostname    string    `gorm:"not null"`
+	Version     string    `gorm:"not null"`
+	StartedAt   time.Time `gorm:"not null"`
+	ConfigHash  string    `gorm:"not null"`
+	HeartbeatAt time.Time `gorm:"not null"`
+	Healthy     bool      `gorm:"not null"`
+	Error       string    `gorm:"type:text"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+func (GatewayReplica) TableName() string {
+	return "gateway_replicas"
+}
+
+// Migrate creates the tables used by the Postgres coordination backend
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&UsageQueueItem{}, &BudgetCacheEntry{}, &GatewayReplica{}); err != nil {
+		return fmt.Errorf("failed to migrate coordination tables: %w", err)
+	}
+	return nil
+}
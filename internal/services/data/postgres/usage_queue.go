@@ -0,0 +1,319 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// claimBatchSQL leases up to N ready records in one statement. SKIP LOCKED
+// lets several workers drain the queue concurrently without blocking on, or
+// double-processing, each other's rows. Claimed rows stay in the table until
+// the consumer acknowledges them, so records held by a worker that dies are
+// claimed again once the lease runs out.
+const claimBatchSQL = `
+UPDATE usage_queue_items SET claimed_until = ?
+WHERE id IN (
+	SELECT id FROM usage_queue_items
+	WHERE queue = ? AND status = ? AND available_at <= ?
+		AND (claimed_until IS NULL OR claimed_until <= ?)
+	ORDER BY id
+	LIMIT ?
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id, payload`
+
+const queueStatsSQL = `
+SELECT
+	COUNT(*) FILTER (WHERE status = @pending AND available_at <= @now
+		AND (claimed_until IS NULL OR claimed_until <= @now)) AS main_queue,
+	COUNT(*) FILTER (WHERE status = @pending AND available_at > @now) AS retry_queue,
+	COUNT(*) FILTER (WHERE status = @dead_letter) AS dead_letter_queue
+FROM usage_queue_items
+WHERE queue = @queue`
+
+// heldRecheckDelay is how long a record of a newer schema waits before a
+// worker looks at it again
+const heldRecheckDelay = time.Minute
+
+// UsageQueue is a Postgres-backed usage queue. Dequeued records are leased
+// rather than removed; the usage processor deletes them with AckUsage in the
+// transaction that stores them.
+type UsageQueue struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	queueName  string
+	batchSize  int
+	maxRetries int
+	leaseTime  time.Duration
+}
+
+// UsageQueueConfig configuration for the usage queue
+type UsageQueueConfig struct {
+	DB         *gorm.DB
+	Logger     *zap.Logger
+	QueueName  string
+	BatchSize  int
+	MaxRetries int
+	LeaseTime  time.Duration // How long a dequeued record stays claimed before it is handed out again
+}
+
+var _ redisService.UsageQueueBackend = (*UsageQueue)(nil)
+
+// NewUsageQueue creates a new Postgres-backed usage queue
+func NewUsageQueue(config *UsageQueueConfig) *UsageQueue {
+	if config.QueueName == "" {
+		config.QueueName = "usage_processing_queue"
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 100
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.LeaseTime == 0 {
+		config.LeaseTime = 5 * time.Minute
+	}
+
+	return &UsageQueue{
+		db:         config.DB,
+		logger:     config.Logger,
+		queueName:  config.QueueName,
+		batchSize:  config.BatchSize,
+		maxRetries: config.MaxRetries,
+		leaseTime:  config.LeaseTime,
+	}
+}
+
+// EnqueueUsage adds a usage record to the processing queue
+func (uq *UsageQueue) EnqueueUsage(ctx context.Context, record *redisService.UsageRecord) error {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	if err := uq.insert(ctx, record, queueStatusPending, time.Now(), ""); err != nil {
+		uq.logger.Error("Failed to enqueue usage record",
+			zap.Error(err),
+			zap.String("record_id", record.ID))
+		return fmt.Errorf("failed to enqueue usage record: %w", err)
+	}
+
+	uq.logger.Debug("Usage record enqueued",
+		zap.String("record_id", record.ID),
+		zap.String("user_id", record.UserID),
+		zap.String("key_id", record.KeyID),
+		zap.Float64("cost", record.TotalCost))
+
+	return nil
+}
+
+// DequeueUsageBatch claims a batch of usage records for processing. The
+// records stay in the queue until AckUsage removes them.
+func (uq *UsageQueue) DequeueUsageBatch(ctx context.Context) ([]*redisService.UsageRecord, error) {
+	var items []struct {
+		ID      uint64
+		Payload string
+	}
+	now := time.Now()
+	err := uq.db.WithContext(ctx).
+		Raw(claimBatchSQL, now.Add(uq.leaseTime), uq.queueName, queueStatusPending, now, now, uq.batchSize).
+		Scan(&items).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue usage records: %w", err)
+	}
+
+	var records []*redisService.UsageRecord
+	for _, item := range items {
+		var record redisService.UsageRecord
+		if err := json.Unmarshal([]byte(item.Payload), &record); err != nil {
+			uq.logger.Error("Failed to unmarshal usage record, moving it to the dead letter queue",
+				zap.Error(err),
+				zap.String("data", item.Payload))
+			if dlErr := uq.db.WithContext(ctx).Model(&UsageQueueItem{}).Where("id = ?", item.ID).
+				Updates(map[string]interface{}{"status": queueStatusDeadLetter, "last_error": err.Error()}).Error; dlErr != nil {
+				uq.logger.Error("Failed to dead-letter usage record", zap.Error(dlErr))
+			}
+			continue
+		}
+		if record.SchemaVersion > redisService.UsageSchemaVersion {
+			// Written by a newer gateway: leave it for a newer worker
+			uq.logger.Warn("Leaving a usage record of a newer schema for a newer worker",
+				zap.Int("schema_version", record.SchemaVersion),
+				zap.String("record_id", record.ID))
+			if err := uq.db.WithContext(ctx).Model(&UsageQueueItem{}).Where("id = ?", item.ID).
+				Updates(map[string]interface{}{"claimed_until": nil, "available_at": now.Add(heldRecheckDelay)}).Error; err != nil {
+				uq.logger.Error("Failed to release usage record of a newer schema", zap.Error(err))
+			}
+			continue
+		}
+		records = append(records, &record)
+	}
+
+	if len(records) > 0 {
+		uq.logger.Debug("Dequeued usage records batch",
+			zap.Int("count", len(records)))
+	}
+
+	return records, nil
+}
+
+// AckUsage removes processed records from the queue. Passing the transaction
+// that stores the usage makes the two commit together: a crash before the
+// commit leaves the records claimed, to be processed again after the lease.
+func (uq *UsageQueue) AckUsage(tx *gorm.DB, records []*redisService.UsageRecord) error {
+	ids := recordIDs(records)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	err := tx.Where("queue = ? AND claimed_until IS NOT NULL AND payload->>'id' IN ?", uq.queueName, ids).
+		Delete(&UsageQueueItem{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge usage records: %w", err)
+	}
+	return nil
+}
+
+// EnqueueUsageFailed re-queues a failed record with exponential backoff, or
+// moves it to the dead letter state after max retries
+func (uq *UsageQueue) EnqueueUsageFailed(ctx context.Context, record *redisService.UsageRecord, errorMsg string) error {
+	record.Retries++
+
+	if record.Retries >= uq.maxRetries {
+		if err := uq.requeue(ctx, record, queueStatusDeadLetter, time.Now(), errorMsg); err != nil {
+			return fmt.Errorf("failed to enqueue dead letter record: %w", err)
+		}
+
+		uq.logger.Error("Usage record moved to dead letter queue",
+			zap.String("record_id", record.ID),
+			zap.Int("retries", record.Retries),
+			zap.String("error", errorMsg))
+		return nil
+	}
+
+	retryDelay := time.Duration(record.Retries*record.Retries) * 10 * time.Second
+	if err := uq.requeue(ctx, record, queueStatusPending, time.Now().Add(retryDelay), errorMsg); err != nil {
+		return fmt.Errorf("failed to enqueue retry record: %w", err)
+	}
+
+	uq.logger.Warn("Usage record queued for retry",
+		zap.String("record_id", record.ID),
+		zap.Int("retry_count", record.Retries),
+		zap.Duration("delay", retryDelay),
+		zap.String("error", errorMsg))
+
+	return nil
+}
+
+// ProcessRetryQueue is a no-op: delayed records live in the main table and
+// are picked up by DequeueUsageBatch once their available_at has passed
+func (uq *UsageQueue) ProcessRetryQueue(ctx context.Context) error {
+	return nil
+}
+
+// GetQueueStats returns statistics about the usage queue
+func (uq *UsageQueue) GetQueueStats(ctx context.Context) (*redisService.QueueStats, error) {
+	var stats redisService.QueueStats
+	err := uq.db.WithContext(ctx).Raw(queueStatsSQL, map[string]interface{}{
+		"pending":     queueStatusPending,
+		"dead_letter": queueStatusDeadLetter,
+		"now":         time.Now(),
+		"queue":       uq.queueName,
+	}).Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+
+	stats.TotalPending = stats.MainQueue + stats.RetryQueue
+	return &stats, nil
+}
+
+// ClearQueue clears all records from the queue (use with caution)
+func (uq *UsageQueue) ClearQueue(ctx context.Context) error {
+	err := uq.db.WithContext(ctx).
+		Where("queue = ?", uq.queueName).
+		Delete(&UsageQueueItem{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to clear queue: %w", err)
+	}
+
+	uq.logger.Warn("Usage queue cleared")
+	return nil
+}
+
+// HealthCheck checks if the queue system is healthy
+func (uq *UsageQueue) HealthCheck(ctx context.Context) error {
+	sqlDB, err := uq.db.DB()
+	if err != nil {
+		return fmt.Errorf("postgres connection unavailable: %w", err)
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("postgres ping failed: %w", err)
+	}
+	return nil
+}
+
+func (uq *UsageQueue) insert(ctx context.Context, record *redisService.UsageRecord, status string, availableAt time.Time, errorMsg string) error {
+	record.SchemaVersion = redisService.UsageSchemaVersion
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+
+	return uq.db.WithContext(ctx).Create(&UsageQueueItem{
+		Queue:       uq.queueName,
+		Status:      status,
+		AvailableAt: availableAt,
+		Payload:     string(data),
+		Retries:     record.Retries,
+		LastError:   errorMsg,
+	}).Error
+}
+
+// requeue releases a claimed record with a new status and availability time.
+// Records that were not claimed from this queue are inserted instead.
+func (uq *UsageQueue) requeue(ctx context.Context, record *redisService.UsageRecord, status string, availableAt time.Time, errorMsg string) error {
+	record.SchemaVersion = redisService.UsageSchemaVersion
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+
+	result := uq.db.WithContext(ctx).Model(&UsageQueueItem{}).
+		Where("queue = ? AND claimed_until IS NOT NULL AND payload->>'id' = ?", uq.queueName, record.ID).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"available_at":  availableAt,
+			"claimed_until": nil,
+			"payload":       string(data),
+			"retries":       record.Retries,
+			"last_error":    errorMsg,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	return uq.insert(ctx, record, status, availableAt, errorMsg)
+}
+
+func recordIDs(records []*redisService.UsageRecord) []string {
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		if record.ID != "" {
+			ids = append(ids, record.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"time"
+)
+
+// UsageQueueBackend is the queue used to hand usage records from the gateway
+// to the usage processor. UsageQueue implements it on top of Redis lists.
+type UsageQueueBackend interface {
+	EnqueueUsage(ctx context.Context, record *UsageRecord) error
+	DequeueUsageBatch(ctx context.Context) ([]*UsageRecord, error)
+	EnqueueUsageFailed(ctx context.Context, record *UsageRecord, errorMsg string) error
+	ProcessRetryQueue(ctx context.Context) error
+	GetQueueStats(ctx context.Context) (*QueueStats, error)
+	HealthCheck(ctx context.Context) error
+}
+
+// BudgetCacheBackend is the shared budget status cache consulted on the hot
+// path. BudgetCache implements it on top of Redis keys.
+type BudgetCacheBackend interface {
+	CheckBudgetAvailable(ctx context.Context, entityType, entityID string, requestCost float64) (bool, error)
+	UpdateBudgetCache(ctx context.Context, entityType, entityID string, available, spent, limit float64, isExceeded bool) error
+	IncrementSpent(ctx context.Context, entityType, entityID string, amount float64) error
+	InvalidateBudgetCache(ctx context.Context, entityType, entityID string) error
+	GetBudgetStats(ctx context.Context, entityType, entityID string) (*BudgetStatus, error)
+}
+
+// Lock is a held distributed lock
+type Lock interface {
+	Release(ctx context.Context) error
+	Extend(ctx context.Context, additionalTTL time.Duration) error
+}
+
+// LockBackend hands out distributed locks. LockManager implements it on top
+// of Redis SETNX.
+type LockBackend interface {
+	AcquireLock(ctx context.Context, lockKey string, ttl time.Duration) (Lock, error)
+}
+
+// ReplicaRegistryBackend keeps the gateway replicas that registered
+// themselves. ReplicaRegistry implements it on top of expiring Redis keys.
+type ReplicaRegistryBackend interface {
+	RegisterReplica(ctx context.Context, replica *Replica, ttl time.Duration) error
+	DeregisterReplica(ctx context.Context, id string) error
+	ListReplicas(ctx context.Context) ([]Replica, error)
+}
+
+var (
+	_ UsageQueueBackend      = (*UsageQueue)(nil)
+	_ BudgetCacheBackend     = (*BudgetCache)(nil)
+	_ LockBackend            = (*LockManager)(nil)
+	_ ReplicaRegistryBackend = (*ReplicaRegistry)(nil)
+)
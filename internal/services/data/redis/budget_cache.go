@@ -48,12 +48,20 @@ func (bc *BudgetCache) CheckBudgetAvailable(ctx context.Context, entityType, ent
 	cacheKey := bc.budgetKey(entityType, entityID)
 
 	// Try to get from cache first
+	start := time.Now()
 	status, err := bc.getBudgetStatus(ctx, cacheKey)
+	observeOperation(componentBudgetCache, "check", start, err)
 	if err == nil && status != nil {
 		// Cache hit - return quick result
+		redisBudgetCacheLookups.WithLabelValues("hit").Inc()
 		available := status.Available - requestCost
 		return available >= 0 && !status.IsExceeded, nil
 	}
+	if err != nil {
+		redisBudgetCacheLookups.WithLabelValues("error").Inc()
+	} else {
+		redisBudgetCacheLookups.WithLabelValues("miss").Inc()
+	}
 
 	// Cache miss - return optimistic result and trigger background refresh
 	bc.logger.Debug("Budget cache miss, allowing request optimistically",
@@ -94,7 +102,9 @@ func (bc *BudgetCache) UpdateBudgetCache(ctx context.Context, entityType, entity
 		return fmt.Errorf("failed to marshal budget status: %w", err)
 	}
 
+	start := time.Now()
 	err = bc.client.SetEx(ctx, cacheKey, data, bc.ttl).Err()
+	observeOperation(componentBudgetCache, "update", start, err)
 	if err != nil {
 		bc.logger.Error("Failed to update budget cache",
 			zap.Error(err),
@@ -117,7 +127,9 @@ func (bc *BudgetCache) IncrementSpent(ctx context.Context, entityType, entityID
 	spentKey := fmt.Sprintf("%s:spent", cacheKey)
 
 	// Use Redis INCRBYFLOAT for atomic increment
+	start := time.Now()
 	newSpent, err := bc.client.IncrByFloat(ctx, spentKey, amount).Result()
+	observeOperation(componentBudgetCache, "increment_spent", start, err)
 	if err != nil {
 		return fmt.Errorf("failed to increment spent amount: %w", err)
 	}
@@ -144,7 +156,9 @@ func (bc *BudgetCache) InvalidateBudgetCache(ctx context.Context, entityType, en
 	pipe.Del(ctx, cacheKey)
 	pipe.Del(ctx, spentKey)
 
+	start := time.Now()
 	_, err := pipe.Exec(ctx)
+	observeOperation(componentBudgetCache, "invalidate", start, err)
 	if err != nil {
 		bc.logger.Error("Failed to invalidate budget cache",
 			zap.Error(err),
@@ -198,7 +212,7 @@ func (bc *BudgetCache) getBudgetStatus(ctx context.Context, cacheKey string) (*B
 
 // budgetKey generates the Redis key for budget status
 func (bc *BudgetCache) budgetKey(entityType, entityID string) string {
-	return fmt.Sprintf("budget:%s:%s", entityType, entityID)
+	return Key(fmt.Sprintf("budget:%s:%s", entityType, entityID))
 }
 
 // SetupBudgetLimits initializes budget limits in Redis for fast access
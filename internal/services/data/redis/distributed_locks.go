@@ -35,23 +35,37 @@ func NewLockManager(client *redis.Client, logger *zap.Logger) *LockManager {
 }
 
 // AcquireLock attempts to acquire a distributed lock
-func (lm *LockManager) AcquireLock(ctx context.Context, lockKey string, ttl time.Duration) (*DistributedLock, error) {
+func (lm *LockManager) AcquireLock(ctx context.Context, lockKey string, ttl time.Duration) (Lock, error) {
+	lock, err := lm.acquire(ctx, lockKey, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// acquire attempts to acquire a distributed lock
+func (lm *LockManager) acquire(ctx context.Context, lockKey string, ttl time.Duration) (*DistributedLock, error) {
 	value, err := generateLockValue()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate lock value: %w", err)
 	}
 
-	key := fmt.Sprintf("lock:%s", lockKey)
+	key := Key(fmt.Sprintf("lock:%s", lockKey))
 
 	// Try to set the lock with NX (only if not exists) and EX (with expiration)
+	start := time.Now()
 	success, err := lm.client.SetNX(ctx, key, value, ttl).Result()
+	observeOperation(componentLockManager, "acquire", start, err)
 	if err != nil {
+		redisLockAcquisitions.WithLabelValues("error").Inc()
 		return nil, fmt.Errorf("failed to acquire lock: %w", err)
 	}
 
 	if !success {
+		redisLockAcquisitions.WithLabelValues("contended").Inc()
 		return nil, fmt.Errorf("lock already held: %s", lockKey)
 	}
+	redisLockAcquisitions.WithLabelValues("acquired").Inc()
 
 	lock := &DistributedLock{
 		client: lm.client,
@@ -72,10 +86,14 @@ func (lm *LockManager) AcquireLock(ctx context.Context, lockKey string, ttl time
 // TryLockWithRetry attempts to acquire a lock with retries
 func (lm *LockManager) TryLockWithRetry(ctx context.Context, lockKey string, ttl time.Duration, maxRetries int, retryDelay time.Duration) (*DistributedLock, error) {
 	var lastErr error
+	start := time.Now()
 
 	for i := 0; i < maxRetries; i++ {
-		lock, err := lm.AcquireLock(ctx, lockKey, ttl)
+		lock, err := lm.acquire(ctx, lockKey, ttl)
 		if err == nil {
+			if i > 0 {
+				redisLockWait.Observe(time.Since(start).Seconds())
+			}
 			return lock, nil
 		}
 
@@ -105,7 +123,9 @@ func (dl *DistributedLock) Release(ctx context.Context) error {
 		end
 	`
 
+	start := time.Now()
 	result, err := dl.client.Eval(ctx, script, []string{dl.key}, dl.value).Result()
+	observeOperation(componentLockManager, "release", start, err)
 	if err != nil {
 		return fmt.Errorf("failed to release lock: %w", err)
 	}
@@ -132,7 +152,9 @@ func (dl *DistributedLock) Extend(ctx context.Context, additionalTTL time.Durati
 	`
 
 	newTTLSeconds := int64((dl.ttl + additionalTTL).Seconds())
+	start := time.Now()
 	result, err := dl.client.Eval(ctx, script, []string{dl.key}, dl.value, newTTLSeconds).Result()
+	observeOperation(componentLockManager, "extend", start, err)
 	if err != nil {
 		return fmt.Errorf("failed to extend lock: %w", err)
 	}
@@ -151,7 +173,7 @@ func (dl *DistributedLock) Extend(ctx context.Context, additionalTTL time.Durati
 
 // WithLock executes a function while holding a distributed lock
 func (lm *LockManager) WithLock(ctx context.Context, lockKey string, ttl time.Duration, fn func() error) error {
-	lock, err := lm.AcquireLock(ctx, lockKey, ttl)
+	lock, err := lm.acquire(ctx, lockKey, ttl)
 	if err != nil {
 		return fmt.Errorf("failed to acquire lock for operation: %w", err)
 	}
@@ -197,7 +219,7 @@ func generateLockValue() (string, error) {
 
 // IsLockHeld checks if a lock is currently held
 func (lm *LockManager) IsLockHeld(ctx context.Context, lockKey string) (bool, error) {
-	key := fmt.Sprintf("lock:%s", lockKey)
+	key := Key(fmt.Sprintf("lock:%s", lockKey))
 	exists, err := lm.client.Exists(ctx, key).Result()
 	if err != nil {
 		return false, fmt.Errorf("failed to check lock existence: %w", err)
@@ -207,7 +229,7 @@ func (lm *LockManager) IsLockHeld(ctx context.Context, lockKey string) (bool, er
 
 // ForcedRelease forcefully releases a lock (use with caution)
 func (lm *LockManager) ForcedRelease(ctx context.Context, lockKey string) error {
-	key := fmt.Sprintf("lock:%s", lockKey)
+	key := Key(fmt.Sprintf("lock:%s", lockKey))
 	err := lm.client.Del(ctx, key).Err()
 	if err != nil {
 		return fmt.Errorf("failed to force release lock: %w", err)
@@ -18,6 +18,9 @@ const (
 	EventTypeAlert  EventType = "alert"
 )
 
+// NotificationStream carries admin dashboard notifications between processes
+const NotificationStream = "notification_events"
+
 // Event represents a distributed event
 type Event struct {
 	ID        string                 `json:"id"`
@@ -82,6 +85,54 @@ func (ep *EventPublisher) PublishBudgetEvent(ctx context.Context, budgetID, enti
 	return ep.publishEvent(ctx, "budget_events", event)
 }
 
+// PublishNotificationEvent publishes an admin dashboard notification
+func (ep *EventPublisher) PublishNotificationEvent(ctx context.Context, id string, data map[string]interface{}) error {
+	event := Event{
+		ID:        id,
+		Type:      EventTypeAlert,
+		Timestamp: time.Now(),
+		Source:    "pllm-gateway",
+		Data:      data,
+	}
+
+	return ep.publishEvent(ctx, NotificationStream, event)
+}
+
+// ReadEvents blocks for up to block waiting for events appended to stream
+// after lastID ("$" for only new events). It returns the events and the ID
+// to continue reading from.
+func (ep *EventPublisher) ReadEvents(ctx context.Context, stream, lastID string, block time.Duration) ([]Event, string, error) {
+	streams, err := ep.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{Key(stream), lastID},
+		Count:   100,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, lastID, nil
+	}
+	if err != nil {
+		return nil, lastID, err
+	}
+
+	var events []Event
+	for _, s := range streams {
+		for _, msg := range s.Messages {
+			lastID = msg.ID
+			data, _ := msg.Values["data"].(string)
+			var event Event
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				ep.logger.Warn("Skipping malformed event",
+					zap.String("stream", stream),
+					zap.String("message_id", msg.ID),
+					zap.Error(err))
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+	return events, lastID, nil
+}
+
 // publishEvent publishes an event to a Redis stream
 func (ep *EventPublisher) publishEvent(ctx context.Context, stream string, event Event) error {
 	eventData, err := json.Marshal(event)
@@ -92,7 +143,7 @@ func (ep *EventPublisher) publishEvent(ctx context.Context, stream string, event
 
 	// Use Redis Streams for reliable event delivery
 	args := &redis.XAddArgs{
-		Stream: stream,
+		Stream: Key(stream),
 		MaxLen: 10000, // Keep last 10k events
 		Approx: true,  // Approximate trimming for performance
 		Values: map[string]interface{}{
@@ -19,6 +19,12 @@ type HealthCheckResult struct {
 	LatencyMs    int64     `json:"latency_ms"`
 	Error        string    `json:"error,omitempty"`
 	CheckedAt    time.Time `json:"checked_at"`
+
+	// Check is "probe" for a canary completion and "endpoint" for the
+	// provider's endpoint check. Probe spend is tracked apart from usage.
+	Check           string  `json:"check,omitempty"`
+	ProbeCost       float64 `json:"probe_cost,omitempty"`
+	ProbeSpendToday float64 `json:"probe_spend_today,omitempty"`
 }
 
 // ModelHealthSummary aggregates health results for all instances of a model.
@@ -63,7 +69,9 @@ func (s *HealthStore) StoreResult(ctx context.Context, result HealthCheckResult)
 	pipe.SAdd(ctx, modelSetKey, result.InstanceID)
 	pipe.Expire(ctx, modelSetKey, s.ttl)
 
+	start := time.Now()
 	_, err = pipe.Exec(ctx)
+	observeOperation(componentHealthStore, "store", start, err)
 	if err != nil {
 		s.logger.Error("Failed to store health check result",
 			zap.String("instance", result.InstanceID),
@@ -75,10 +83,13 @@ func (s *HealthStore) StoreResult(ctx context.Context, result HealthCheckResult)
 
 // GetResult returns the health check result for a single instance.
 func (s *HealthStore) GetResult(ctx context.Context, instanceID string) (*HealthCheckResult, error) {
+	start := time.Now()
 	data, err := s.client.Get(ctx, s.instanceKey(instanceID)).Bytes()
 	if err == redis.Nil {
+		observeOperation(componentHealthStore, "get", start, nil)
 		return nil, nil
 	}
+	observeOperation(componentHealthStore, "get", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -92,10 +103,13 @@ func (s *HealthStore) GetResult(ctx context.Context, instanceID string) (*Health
 
 // GetModelHealth returns aggregated health for all instances of a model.
 func (s *HealthStore) GetModelHealth(ctx context.Context, modelName string) (*ModelHealthSummary, error) {
+	start := time.Now()
 	instanceIDs, err := s.client.SMembers(ctx, s.modelSetKey(modelName)).Result()
 	if err != nil {
+		observeOperation(componentHealthStore, "model_health", start, err)
 		return nil, err
 	}
+	defer func() { observeOperation(componentHealthStore, "model_health", start, nil) }()
 
 	summary := &ModelHealthSummary{
 		ModelName: modelName,
@@ -165,10 +179,37 @@ func (s *HealthStore) GetAllModelsHealth(ctx context.Context, modelNames []strin
 	return result, nil
 }
 
+// ProbeSpend returns the spend of an instance's health probes on the UTC day
+// of now
+func (s *HealthStore) ProbeSpend(ctx context.Context, instanceID string, now time.Time) (float64, error) {
+	spend, err := s.client.Get(ctx, s.probeSpendKey(instanceID, now)).Float64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return spend, err
+}
+
+// AddProbeSpend adds the cost of a health probe to the instance's spend for
+// the UTC day of now and returns the day's total
+func (s *HealthStore) AddProbeSpend(ctx context.Context, instanceID string, now time.Time, cost float64) (float64, error) {
+	key := s.probeSpendKey(instanceID, now)
+	pipe := s.client.TxPipeline()
+	total := pipe.IncrByFloat(ctx, key, cost)
+	pipe.Expire(ctx, key, 48*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("add probe spend: %w", err)
+	}
+	return total.Val(), nil
+}
+
+func (s *HealthStore) probeSpendKey(instanceID string, now time.Time) string {
+	return Key(fmt.Sprintf("pllm:health:probe_spend:%s:%s", instanceID, now.UTC().Format("2006-01-02")))
+}
+
 func (s *HealthStore) instanceKey(instanceID string) string {
-	return fmt.Sprintf("pllm:health:instance:%s", instanceID)
+	return Key(fmt.Sprintf("pllm:health:instance:%s", instanceID))
 }
 
 func (s *HealthStore) modelSetKey(modelName string) string {
-	return fmt.Sprintf("pllm:health:model:%s:instances", modelName)
+	return Key(fmt.Sprintf("pllm:health:model:%s:instances", modelName))
 }
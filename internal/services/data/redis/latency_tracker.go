@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
@@ -19,6 +20,45 @@ type LatencyTracker struct {
 	windowSize   time.Duration // Time window for latency samples (default: 5 minutes)
 	maxSamples   int64         // Max samples per model (default: 1000)
 	updatePeriod time.Duration // How often to update aggregates (default: 10s)
+
+	// Decayed averages weigh a sample half as much every halfLife. Models
+	// with fewer effective samples than warmupSamples are warming up.
+	halfLife      time.Duration
+	warmupSamples int
+	now           func() time.Time
+}
+
+// Defaults of the decayed averages
+const (
+	DefaultLatencyHalfLife      = time.Minute
+	DefaultLatencyWarmupSamples = 5
+)
+
+// decayedAverageScript folds a sample into the decayed average stored in the
+// hash at KEYS[1], atomically across gateway instances. The previous weight
+// decays by half every half-life since the last update.
+// ARGV: now (ms), sample (ms), half-life (ms), TTL (ms).
+var decayedAverageScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local state = redis.call('HMGET', KEYS[1], 'avg', 'weight', 'updated')
+local avg = tonumber(state[1]) or 0
+local weight = tonumber(state[2]) or 0
+local elapsed = now - (tonumber(state[3]) or now)
+if elapsed > 0 then
+	weight = weight * math.pow(0.5, elapsed / tonumber(ARGV[3]))
+end
+local newWeight = weight + 1
+avg = (avg * weight + tonumber(ARGV[2])) / newWeight
+redis.call('HSET', KEYS[1], 'avg', tostring(avg), 'weight', tostring(newWeight), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return tostring(avg)
+`)
+
+// LatencyEstimate is a decayed average latency and the number of effective
+// samples behind it
+type LatencyEstimate struct {
+	Average time.Duration `json:"average"`
+	Samples float64       `json:"samples"`
 }
 
 // NewLatencyTracker creates a new distributed latency tracker
@@ -29,17 +69,48 @@ func NewLatencyTracker(client *redis.Client, logger *zap.Logger) *LatencyTracker
 		windowSize:   5 * time.Minute,
 		maxSamples:   1000,
 		updatePeriod: 10 * time.Second,
+
+		halfLife:      DefaultLatencyHalfLife,
+		warmupSamples: DefaultLatencyWarmupSamples,
+		now:           time.Now,
 	}
 }
 
+// SetDecay sets the half-life of the decayed averages, unchanged when zero,
+// and the number of effective samples a model needs before latency routing
+// trusts its average. Zero warm-up samples trust the first sample.
+func (lt *LatencyTracker) SetDecay(halfLife time.Duration, warmupSamples int) {
+	if halfLife > 0 {
+		lt.halfLife = halfLife
+	}
+	if warmupSamples >= 0 {
+		lt.warmupSamples = warmupSamples
+	}
+}
+
+// WarmupSamples returns the number of effective samples a model needs
+// before latency routing trusts its average
+func (lt *LatencyTracker) WarmupSamples() int {
+	return lt.warmupSamples
+}
+
 // RecordLatency records a latency sample for a model
 func (lt *LatencyTracker) RecordLatency(ctx context.Context, modelName string, latency time.Duration) error {
+	return lt.recordSample(ctx, lt.latencyKey(modelName), lt.avgKey(modelName), "record", modelName, latency)
+}
+
+// RecordTTFT records a time-to-first-token sample of a streaming request
+func (lt *LatencyTracker) RecordTTFT(ctx context.Context, modelName string, ttft time.Duration) error {
+	return lt.recordSample(ctx, lt.ttftKey(modelName), lt.ttftAvgKey(modelName), "record_ttft", modelName, ttft)
+}
+
+// recordSample adds a sample to the sliding window at key and the moving
+// average at avgKey
+func (lt *LatencyTracker) recordSample(ctx context.Context, key, avgKey, operation, modelName string, latency time.Duration) error {
 	latencyMs := latency.Milliseconds()
 	timestamp := float64(time.Now().UnixMilli())
 	
 	// Store in sorted set: score = timestamp, member = "latency_ms:timestamp" (unique)
-	key := lt.latencyKey(modelName)
-	
 	// Make member unique by combining latency and timestamp
 	member := fmt.Sprintf("%d:%d", latencyMs, time.Now().UnixNano())
 	
@@ -60,40 +131,95 @@ func (lt *LatencyTracker) RecordLatency(ctx context.Context, modelName string, l
 	
 	// Set TTL to prevent memory leaks
 	pipe.Expire(ctx, key, lt.windowSize*2)
+
+	// Fold the sample into the decayed average
+	decayedAverageScript.Eval(ctx, pipe, []string{avgKey},
+		lt.now().UnixMilli(), latencyMs, lt.halfLife.Milliseconds(), lt.averageTTL().Milliseconds())
 	
+	start := time.Now()
 	_, err := pipe.Exec(ctx)
+	observeOperation(componentLatencyTracker, operation, start, err)
 	if err != nil {
 		lt.logger.Error("Failed to record latency",
 			zap.String("model", modelName),
+			zap.String("operation", operation),
 			zap.Duration("latency", latency),
 			zap.Error(err))
 		return err
 	}
 	
-	// Also update moving average asynchronously
-	go lt.updateMovingAverage(context.Background(), modelName, latencyMs)
-	
 	return nil
 }
 
-// GetAverageLatency returns the average latency for a model
+// GetAverageLatency returns the decayed average latency for a model, zero
+// when no request has been recorded
 func (lt *LatencyTracker) GetAverageLatency(ctx context.Context, modelName string) (time.Duration, error) {
-	key := lt.avgKey(modelName)
-	
-	result, err := lt.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return 0, nil // No data yet
-	}
+	estimate, err := lt.getEstimate(ctx, lt.avgKey(modelName), "get_average")
+	return estimate.Average, err
+}
+
+// GetAverageTTFT returns the decayed average time to first token for a
+// model, zero when no streaming request has been recorded
+func (lt *LatencyTracker) GetAverageTTFT(ctx context.Context, modelName string) (time.Duration, error) {
+	estimate, err := lt.getEstimate(ctx, lt.ttftAvgKey(modelName), "get_average_ttft")
+	return estimate.Average, err
+}
+
+// GetLatencyEstimate returns the decayed average latency of a model with its
+// effective sample count
+func (lt *LatencyTracker) GetLatencyEstimate(ctx context.Context, modelName string) (LatencyEstimate, error) {
+	return lt.getEstimate(ctx, lt.avgKey(modelName), "get_estimate")
+}
+
+// GetTTFTEstimate returns the decayed average time to first token of a model
+// with its effective sample count
+func (lt *LatencyTracker) GetTTFTEstimate(ctx context.Context, modelName string) (LatencyEstimate, error) {
+	return lt.getEstimate(ctx, lt.ttftAvgKey(modelName), "get_ttft_estimate")
+}
+
+// getEstimate reads the decayed average stored at key, decaying its weight
+// to now
+func (lt *LatencyTracker) getEstimate(ctx context.Context, key, operation string) (LatencyEstimate, error) {
+	start := time.Now()
+	values, err := lt.client.HMGet(ctx, key, "avg", "weight", "updated").Result()
+	observeOperation(componentLatencyTracker, operation, start, err)
 	if err != nil {
-		return 0, err
+		return LatencyEstimate{}, err
 	}
-	
-	avgMs, err := strconv.ParseFloat(result, 64)
-	if err != nil {
-		return 0, err
+
+	var state [3]float64
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			return LatencyEstimate{}, nil // No data yet
+		}
+		if state[i], err = strconv.ParseFloat(raw, 64); err != nil {
+			return LatencyEstimate{}, err
+		}
 	}
-	
-	return time.Duration(avgMs) * time.Millisecond, nil
+
+	elapsed := time.Duration(lt.now().UnixMilli()-int64(state[2])) * time.Millisecond
+	return LatencyEstimate{
+		Average: time.Duration(state[0] * float64(time.Millisecond)),
+		Samples: state[1] * lt.decay(elapsed),
+	}, nil
+}
+
+// decay returns the factor a sample's weight shrinks by over elapsed
+func (lt *LatencyTracker) decay(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(elapsed)/float64(lt.halfLife))
+}
+
+// averageTTL keeps decayed averages until ten half-lives or two windows
+// have passed without a sample, whichever is longer
+func (lt *LatencyTracker) averageTTL() time.Duration {
+	if ttl := 10 * lt.halfLife; ttl > lt.windowSize*2 {
+		return ttl
+	}
+	return lt.windowSize * 2
 }
 
 // GetPercentileLatency returns the Pxx latency (e.g., P95, P99)
@@ -128,10 +254,20 @@ func (lt *LatencyTracker) GetPercentileLatency(ctx context.Context, modelName st
 
 // GetLatencyStats returns comprehensive latency statistics
 func (lt *LatencyTracker) GetLatencyStats(ctx context.Context, modelName string) (*LatencyStats, error) {
-	key := lt.latencyKey(modelName)
-	
+	return lt.getStats(ctx, lt.latencyKey(modelName), "stats", modelName)
+}
+
+// GetTTFTStats returns time-to-first-token statistics of streaming requests
+func (lt *LatencyTracker) GetTTFTStats(ctx context.Context, modelName string) (*LatencyStats, error) {
+	return lt.getStats(ctx, lt.ttftKey(modelName), "ttft_stats", modelName)
+}
+
+// getStats computes statistics over the samples stored at key
+func (lt *LatencyTracker) getStats(ctx context.Context, key, operation, modelName string) (*LatencyStats, error) {
 	// Get all samples
+	start := time.Now()
 	values, err := lt.client.ZRange(ctx, key, 0, -1).Result()
+	observeOperation(componentLatencyTracker, operation, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -249,6 +385,8 @@ func (lt *LatencyTracker) ClearLatencies(ctx context.Context, modelName string)
 	pipe := lt.client.Pipeline()
 	pipe.Del(ctx, lt.latencyKey(modelName))
 	pipe.Del(ctx, lt.avgKey(modelName))
+	pipe.Del(ctx, lt.ttftKey(modelName))
+	pipe.Del(ctx, lt.ttftAvgKey(modelName))
 	_, err := pipe.Exec(ctx)
 	return err
 }
@@ -256,7 +394,7 @@ func (lt *LatencyTracker) ClearLatencies(ctx context.Context, modelName string)
 // GetAllModelStats returns latency stats for all tracked models
 func (lt *LatencyTracker) GetAllModelStats(ctx context.Context) (map[string]*LatencyStats, error) {
 	// Scan for all latency keys
-	pattern := "pllm:latency:*"
+	pattern := Key("pllm:latency:*")
 	keys, err := lt.client.Keys(ctx, pattern).Result()
 	if err != nil {
 		return nil, err
@@ -265,7 +403,7 @@ func (lt *LatencyTracker) GetAllModelStats(ctx context.Context) (map[string]*Lat
 	stats := make(map[string]*LatencyStats)
 	for _, key := range keys {
 		// Extract model name from key
-		modelName := key[len("pllm:latency:"):]
+		modelName := TrimKey(key)[len("pllm:latency:"):]
 		
 		modelStats, err := lt.GetLatencyStats(ctx, modelName)
 		if err != nil {
@@ -281,44 +419,25 @@ func (lt *LatencyTracker) GetAllModelStats(ctx context.Context) (map[string]*Lat
 	return stats, nil
 }
 
-// updateMovingAverage updates the exponential moving average (async)
-func (lt *LatencyTracker) updateMovingAverage(ctx context.Context, modelName string, latencyMs int64) {
-	key := lt.avgKey(modelName)
-	
-	// Get current average
-	currentAvgStr, err := lt.client.Get(ctx, key).Result()
-	var newAvg float64
-	
-	if err == redis.Nil {
-		// First sample
-		newAvg = float64(latencyMs)
-	} else if err != nil {
-		lt.logger.Error("Failed to get current average", zap.Error(err))
-		return
-	} else {
-		currentAvg, err := strconv.ParseFloat(currentAvgStr, 64)
-		if err != nil {
-			newAvg = float64(latencyMs)
-		} else {
-			// Exponential moving average: new = old * 0.9 + new * 0.1
-			newAvg = currentAvg*0.9 + float64(latencyMs)*0.1
-		}
-	}
-	
-	// Update average
-	err = lt.client.Set(ctx, key, newAvg, lt.windowSize*2).Err()
-	if err != nil {
-		lt.logger.Error("Failed to update moving average", zap.Error(err))
-	}
-}
-
 // Helper methods for Redis keys
 func (lt *LatencyTracker) latencyKey(modelName string) string {
-	return fmt.Sprintf("pllm:latency:%s", modelName)
+	return Key(fmt.Sprintf("pllm:latency:%s", modelName))
 }
 
+// Decayed averages live outside pllm:latency:* so GetAllModelStats does not
+// pick them up as models
 func (lt *LatencyTracker) avgKey(modelName string) string {
-	return fmt.Sprintf("pllm:latency:avg:%s", modelName)
+	return Key(fmt.Sprintf("pllm:latency_avg:%s", modelName))
+}
+
+// TTFT keys live outside pllm:latency:* so GetAllModelStats does not pick
+// them up as models
+func (lt *LatencyTracker) ttftKey(modelName string) string {
+	return Key(fmt.Sprintf("pllm:ttft:%s", modelName))
+}
+
+func (lt *LatencyTracker) ttftAvgKey(modelName string) string {
+	return Key(fmt.Sprintf("pllm:ttft_avg:%s", modelName))
 }
 
 // LatencyStats represents comprehensive latency statistics
@@ -315,6 +315,100 @@ func TestLatencyTracker_GetAllModelStats(t *testing.T) {
 	}
 }
 
+func TestLatencyTracker_TTFT(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer func() { _ = client.Close() }()
+
+	tracker := NewLatencyTracker(client, zap.NewNop())
+	ctx := context.Background()
+	modelName := "gpt-4"
+
+	// No streaming request recorded yet
+	avg, err := tracker.GetAverageTTFT(ctx, modelName)
+	require.NoError(t, err)
+	assert.Zero(t, avg)
+
+	require.NoError(t, tracker.RecordLatency(ctx, modelName, 2*time.Second))
+	for _, ttft := range []time.Duration{200 * time.Millisecond, 300 * time.Millisecond, 250 * time.Millisecond} {
+		require.NoError(t, tracker.RecordTTFT(ctx, modelName, ttft))
+	}
+
+	// Give time for async moving average update
+	time.Sleep(50 * time.Millisecond)
+
+	avg, err = tracker.GetAverageTTFT(ctx, modelName)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, avg, 200*time.Millisecond)
+	assert.LessOrEqual(t, avg, 300*time.Millisecond)
+
+	// TTFT samples are kept apart from total latency
+	ttftStats, err := tracker.GetTTFTStats(ctx, modelName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), ttftStats.SampleCount)
+	assert.Equal(t, 200*time.Millisecond, ttftStats.Min)
+
+	latencyStats, err := tracker.GetLatencyStats(ctx, modelName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), latencyStats.SampleCount)
+
+	allStats, err := tracker.GetAllModelStats(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, allStats, "ttft:gpt-4")
+
+	require.NoError(t, tracker.ClearLatencies(ctx, modelName))
+	ttftStats, err = tracker.GetTTFTStats(ctx, modelName)
+	require.NoError(t, err)
+	assert.Zero(t, ttftStats.SampleCount)
+}
+
+func TestLatencyTracker_DecayedAverage(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer func() { _ = client.Close() }()
+
+	tracker := NewLatencyTracker(client, zap.NewNop())
+	tracker.SetDecay(time.Minute, 3)
+	assert.Equal(t, 3, tracker.WarmupSamples())
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	estimate, err := tracker.GetLatencyEstimate(ctx, "gpt-4")
+	require.NoError(t, err)
+	assert.Zero(t, estimate)
+
+	// The first sample is the average, not a seed the next ones barely move
+	require.NoError(t, tracker.RecordLatency(ctx, "gpt-4", 100*time.Millisecond))
+	require.NoError(t, tracker.RecordLatency(ctx, "gpt-4", 900*time.Millisecond))
+	estimate, err = tracker.GetLatencyEstimate(ctx, "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, estimate.Average)
+	assert.InDelta(t, 2, estimate.Samples, 1e-9)
+
+	// A half-life later the earlier samples weigh half as much
+	now = now.Add(time.Minute)
+	estimate, err = tracker.GetLatencyEstimate(ctx, "gpt-4")
+	require.NoError(t, err)
+	assert.InDelta(t, 1, estimate.Samples, 1e-9)
+
+	require.NoError(t, tracker.RecordLatency(ctx, "gpt-4", 200*time.Millisecond))
+	estimate, err = tracker.GetLatencyEstimate(ctx, "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, 350*time.Millisecond, estimate.Average)
+	assert.InDelta(t, 2, estimate.Samples, 1e-9)
+
+	avg, err := tracker.GetAverageLatency(ctx, "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, 350*time.Millisecond, avg)
+
+	// Decayed averages are not listed as models
+	allStats, err := tracker.GetAllModelStats(ctx)
+	require.NoError(t, err)
+	assert.Len(t, allStats, 1)
+	assert.Contains(t, allStats, "gpt-4")
+}
+
 func BenchmarkLatencyTracker_RecordLatency(b *testing.B) {
 	client, mr := setupTestRedis(&testing.T{})
 	defer mr.Close()
@@ -0,0 +1,275 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// PressureLevel describes how close Redis is to running out of room
+type PressureLevel int32
+
+const (
+	PressureNormal   PressureLevel = iota
+	PressureElevated               // Analytic writes are shed
+	PressureCritical               // Close to full; billing writes still go through
+)
+
+func (l PressureLevel) String() string {
+	switch l {
+	case PressureElevated:
+		return "elevated"
+	case PressureCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+var (
+	redisPressureLevel = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_redis_pressure_level",
+		Help: "Redis pressure level (0=normal, 1=elevated, 2=critical)",
+	})
+
+	redisMemoryUsedRatio = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_redis_memory_used_ratio",
+		Help: "Redis used memory as a fraction of maxmemory",
+	})
+
+	redisQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pllm_redis_queue_depth",
+		Help: "Number of entries waiting in a Redis-backed queue",
+	}, []string{"queue"})
+
+	redisShedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_redis_shed_events_total",
+		Help: "Writes dropped because of Redis pressure",
+	}, []string{"kind"}) // kind: analytics
+)
+
+// MemoryGuardConfig configures the Redis memory guard
+type MemoryGuardConfig struct {
+	Client    *redis.Client
+	Logger    *zap.Logger
+	QueueKeys []string // Queues whose depth is monitored
+
+	Interval time.Duration
+
+	// Memory thresholds as a fraction of maxmemory. MaxMemoryBytes is used
+	// when Redis runs without maxmemory; memory is ignored when both are unset.
+	MemoryElevatedRatio float64
+	MemoryCriticalRatio float64
+	MaxMemoryBytes      int64
+
+	// Queue depth thresholds, summed over QueueKeys
+	QueueElevatedDepth int64
+	QueueCriticalDepth int64
+}
+
+// MemoryGuard samples Redis memory and queue depth and tells writers how to
+// degrade: analytic events are shed so billing records keep their room, and
+// a slow worker never backs up into the request path. Usage records are never
+// dropped.
+type MemoryGuard struct {
+	client *redis.Client
+	logger *zap.Logger
+	config MemoryGuardConfig
+
+	level atomic.Int32
+
+	mu    sync.RWMutex
+	stats MemoryGuardStats
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// MemoryGuardStats is the latest sample taken by the guard
+type MemoryGuardStats struct {
+	Level       string           `json:"level"`
+	UsedMemory  int64            `json:"used_memory"`
+	MaxMemory   int64            `json:"max_memory"`
+	MemoryRatio float64          `json:"memory_ratio"`
+	QueueDepths map[string]int64 `json:"queue_depths"`
+	CheckedAt   time.Time        `json:"checked_at"`
+}
+
+// NewMemoryGuard creates a memory guard
+func NewMemoryGuard(config *MemoryGuardConfig) *MemoryGuard {
+	if config.Interval == 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.MemoryElevatedRatio == 0 {
+		config.MemoryElevatedRatio = 0.75
+	}
+	if config.MemoryCriticalRatio == 0 {
+		config.MemoryCriticalRatio = 0.9
+	}
+	if config.QueueElevatedDepth == 0 {
+		config.QueueElevatedDepth = 10000
+	}
+	if config.QueueCriticalDepth == 0 {
+		config.QueueCriticalDepth = 50000
+	}
+
+	return &MemoryGuard{
+		client: config.Client,
+		logger: config.Logger,
+		config: *config,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start samples Redis periodically until the context is cancelled or Stop is called
+func (g *MemoryGuard) Start(ctx context.Context) {
+	g.logger.Info("Starting Redis memory guard",
+		zap.Duration("interval", g.config.Interval),
+		zap.Strings("queues", g.config.QueueKeys))
+
+	go func() {
+		ticker := time.NewTicker(g.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := g.Check(ctx); err != nil {
+				g.logger.Warn("Redis memory guard check failed", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-g.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the sampling loop
+func (g *MemoryGuard) Stop() {
+	g.stopOnce.Do(func() { close(g.stopCh) })
+}
+
+// Check samples Redis once and updates the pressure level
+func (g *MemoryGuard) Check(ctx context.Context) (PressureLevel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	stats := MemoryGuardStats{QueueDepths: make(map[string]int64), CheckedAt: time.Now()}
+
+	pipe := g.client.Pipeline()
+	infoCmd := pipe.Info(ctx, "memory")
+	lenCmds := make(map[string]*redis.IntCmd, len(g.config.QueueKeys))
+	for _, key := range g.config.QueueKeys {
+		lenCmds[key] = pipe.LLen(ctx, Key(key))
+	}
+	_, _ = pipe.Exec(ctx)
+
+	var totalDepth int64
+	for key, cmd := range lenCmds {
+		depth, err := cmd.Result()
+		if err != nil {
+			return g.Level(), err
+		}
+		stats.QueueDepths[key] = depth
+		totalDepth += depth
+		redisQueueDepth.WithLabelValues(key).Set(float64(depth))
+	}
+
+	// INFO is best-effort: some managed Redis offerings restrict it
+	if info, err := infoCmd.Result(); err == nil {
+		stats.UsedMemory, stats.MaxMemory = parseMemoryInfo(info)
+	}
+	if stats.MaxMemory == 0 {
+		stats.MaxMemory = g.config.MaxMemoryBytes
+	}
+	if stats.MaxMemory > 0 {
+		stats.MemoryRatio = float64(stats.UsedMemory) / float64(stats.MaxMemory)
+		redisMemoryUsedRatio.Set(stats.MemoryRatio)
+	}
+
+	level := PressureNormal
+	switch {
+	case stats.MaxMemory > 0 && stats.MemoryRatio >= g.config.MemoryCriticalRatio,
+		totalDepth >= g.config.QueueCriticalDepth:
+		level = PressureCritical
+	case stats.MaxMemory > 0 && stats.MemoryRatio >= g.config.MemoryElevatedRatio,
+		totalDepth >= g.config.QueueElevatedDepth:
+		level = PressureElevated
+	}
+	stats.Level = level.String()
+
+	g.mu.Lock()
+	g.stats = stats
+	g.mu.Unlock()
+
+	if previous := PressureLevel(g.level.Swap(int32(level))); previous != level {
+		fields := []zap.Field{
+			zap.String("from", previous.String()),
+			zap.String("to", level.String()),
+			zap.Float64("memory_ratio", stats.MemoryRatio),
+			zap.Int64("queue_depth", totalDepth),
+		}
+		if level > previous {
+			g.logger.Error("ALERT: Redis pressure increased, degrading async writes", fields...)
+		} else {
+			g.logger.Info("Redis pressure decreased", fields...)
+		}
+	}
+	redisPressureLevel.Set(float64(level))
+
+	return level, nil
+}
+
+// Level returns the current pressure level. A nil guard is always normal.
+func (g *MemoryGuard) Level() PressureLevel {
+	if g == nil {
+		return PressureNormal
+	}
+	return PressureLevel(g.level.Load())
+}
+
+// ShedAnalytics reports whether analytic writes should be dropped
+func (g *MemoryGuard) ShedAnalytics() bool {
+	if g.Level() < PressureElevated {
+		return false
+	}
+	redisShedEvents.WithLabelValues("analytics").Inc()
+	return true
+}
+
+// Stats returns the latest sample
+func (g *MemoryGuard) Stats() MemoryGuardStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.stats
+}
+
+// parseMemoryInfo extracts used_memory and maxmemory from INFO memory output
+func parseMemoryInfo(info string) (used, maxMemory int64) {
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "used_memory":
+			used, _ = strconv.ParseInt(value, 10, 64)
+		case "maxmemory":
+			maxMemory, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return used, maxMemory
+}
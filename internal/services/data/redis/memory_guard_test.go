@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMemoryGuard_QueueDepthLevels(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	guard := NewMemoryGuard(&MemoryGuardConfig{
+		Client:             client,
+		Logger:             zap.NewNop(),
+		QueueKeys:          []string{"usage_processing_queue"},
+		QueueElevatedDepth: 3,
+		QueueCriticalDepth: 6,
+	})
+
+	level, err := guard.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, PressureNormal, level)
+	assert.False(t, guard.ShedAnalytics())
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, client.LPush(ctx, "usage_processing_queue", "x").Err())
+	}
+	level, err = guard.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, PressureElevated, level)
+	assert.True(t, guard.ShedAnalytics())
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, client.LPush(ctx, "usage_processing_queue", "x").Err())
+	}
+	level, err = guard.Check(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, PressureCritical, level)
+	assert.Equal(t, int64(8), guard.Stats().QueueDepths["usage_processing_queue"])
+	assert.True(t, guard.ShedAnalytics())
+}
+
+func TestMemoryGuard_NilIsNormal(t *testing.T) {
+	var guard *MemoryGuard
+	assert.Equal(t, PressureNormal, guard.Level())
+	assert.False(t, guard.ShedAnalytics())
+}
+
+func TestParseMemoryInfo(t *testing.T) {
+	info := "# Memory\r\nused_memory:1048576\r\nused_memory_human:1.00M\r\nmaxmemory:4194304\r\nmaxmemory_policy:noeviction\r\n"
+	used, maxMemory := parseMemoryInfo(info)
+	assert.Equal(t, int64(1048576), used)
+	assert.Equal(t, int64(4194304), maxMemory)
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Components reported in the component label of the Redis service metrics
+const (
+	componentBudgetCache     = "budget_cache"
+	componentUsageQueue      = "usage_queue"
+	componentLatencyTracker  = "latency_tracker"
+	componentHealthStore     = "health_store"
+	componentLockManager     = "lock_manager"
+	componentSnapshotStore   = "snapshot_store"
+	componentUsageCounters   = "usage_counters"
+	componentReplicaRegistry = "replica_registry"
+)
+
+var (
+	redisOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pllm_redis_operation_duration_seconds",
+		Help:    "Latency of Redis-backed service operations",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"component", "operation"})
+
+	redisOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_redis_operation_errors_total",
+		Help: "Failed Redis-backed service operations",
+	}, []string{"component", "operation"})
+
+	redisBudgetCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_redis_budget_cache_lookups_total",
+		Help: "Budget cache lookups by result",
+	}, []string{"result"}) // result: hit, miss, error
+
+	redisUsageRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_redis_usage_records_total",
+		Help: "Usage records moved through the usage queue",
+	}, []string{"event"}) // event: enqueued, dequeued, retried, dead_lettered, held, released
+
+	redisUsagePayloadBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_redis_usage_payload_bytes_total",
+		Help: "Size of the usage records enqueued, by payload encoding",
+	}, []string{"encoding"}) // encoding: json, msgpack, json+gzip, msgpack+gzip
+
+	redisLockAcquisitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_redis_lock_acquisitions_total",
+		Help: "Distributed lock acquisition attempts by result",
+	}, []string{"result"}) // result: acquired, contended, error
+
+	redisLockWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pllm_redis_lock_wait_seconds",
+		Help:    "Time spent waiting for a contended distributed lock",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 10),
+	})
+)
+
+// observeOperation records the latency and outcome of a service operation
+func observeOperation(component, operation string, start time.Time, err error) {
+	redisOperationDuration.WithLabelValues(component, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		redisOperationErrors.WithLabelValues(component, operation).Inc()
+	}
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMetrics_BudgetCacheLookups(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	cache := NewBudgetCache(client, zap.NewNop(), time.Minute)
+	hits := testutil.ToFloat64(redisBudgetCacheLookups.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(redisBudgetCacheLookups.WithLabelValues("miss"))
+
+	_, err := cache.CheckBudgetAvailable(ctx, "key", "metrics-test", 1)
+	require.NoError(t, err)
+	require.NoError(t, cache.UpdateBudgetCache(ctx, "key", "metrics-test", 10, 0, 10, false))
+	_, err = cache.CheckBudgetAvailable(ctx, "key", "metrics-test", 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, misses+1, testutil.ToFloat64(redisBudgetCacheLookups.WithLabelValues("miss")))
+	assert.Equal(t, hits+1, testutil.ToFloat64(redisBudgetCacheLookups.WithLabelValues("hit")))
+}
+
+func TestMetrics_LockContention(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	locks := NewLockManager(client, zap.NewNop())
+	contended := testutil.ToFloat64(redisLockAcquisitions.WithLabelValues("contended"))
+
+	lock, err := locks.AcquireLock(ctx, "metrics-test", time.Minute)
+	require.NoError(t, err)
+	_, err = locks.AcquireLock(ctx, "metrics-test", time.Minute)
+	require.Error(t, err)
+	require.NoError(t, lock.Release(ctx))
+
+	assert.Equal(t, contended+1, testutil.ToFloat64(redisLockAcquisitions.WithLabelValues("contended")))
+}
+
+func TestMetrics_UsageQueueDepth(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	queue := NewUsageQueue(&UsageQueueConfig{Client: client, Logger: zap.NewNop(), QueueName: "metrics_test_queue"})
+	require.NoError(t, queue.EnqueueUsage(ctx, &UsageRecord{RequestID: "req-1"}))
+	require.NoError(t, queue.EnqueueUsage(ctx, &UsageRecord{RequestID: "req-2"}))
+
+	_, err := queue.GetQueueStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), testutil.ToFloat64(redisQueueDepth.WithLabelValues("metrics_test_queue")))
+}
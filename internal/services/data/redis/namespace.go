@@ -0,0 +1,36 @@
+package redis
+
+import "strings"
+
+// namespace prefixes every Redis key, stream and channel the gateway uses, so
+// several deployments (staging and production, or separate tenants) can
+// share one Redis without colliding queues, caches and locks. It is set once
+// at startup, before any Redis-backed component is created.
+var namespace string
+
+// SetNamespace sets the namespace from redis.namespace. An empty namespace
+// keeps the unprefixed keys of earlier releases.
+func SetNamespace(ns string) {
+	namespace = strings.TrimSuffix(strings.TrimSpace(ns), ":")
+}
+
+// Namespace returns the configured namespace
+func Namespace() string {
+	return namespace
+}
+
+// Key returns key inside the configured namespace
+func Key(key string) string {
+	if namespace == "" {
+		return key
+	}
+	return namespace + ":" + key
+}
+
+// TrimKey strips the namespace from a key returned by SCAN or KEYS
+func TrimKey(key string) string {
+	if namespace == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, namespace+":")
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestKey_Namespace(t *testing.T) {
+	defer SetNamespace("")
+
+	assert.Equal(t, "budget:key:1", Key("budget:key:1"))
+
+	SetNamespace("staging:")
+	assert.Equal(t, "staging", Namespace())
+	assert.Equal(t, "staging:budget:key:1", Key("budget:key:1"))
+	assert.Equal(t, "budget:key:1", TrimKey("staging:budget:key:1"))
+}
+
+func TestNamespace_IsolatesDeployments(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer func() { _ = client.Close() }()
+	defer SetNamespace("")
+	ctx := context.Background()
+
+	SetNamespace("prod")
+	prod := NewBudgetCache(client, zap.NewNop(), time.Minute)
+	require.NoError(t, prod.UpdateBudgetCache(ctx, "key", "k1", 100, 10, 80, false))
+
+	SetNamespace("staging")
+	staging := NewBudgetCache(client, zap.NewNop(), time.Minute)
+	status, err := staging.GetBudgetStats(ctx, "key", "k1")
+	require.NoError(t, err)
+	assert.Nil(t, status, "staging must not see the production budget entry")
+
+	assert.True(t, mr.Exists("prod:budget:key:k1"))
+	assert.False(t, mr.Exists("budget:key:k1"))
+
+	SetNamespace("prod")
+	tracker := NewLatencyTracker(client, zap.NewNop())
+	require.NoError(t, tracker.RecordLatency(ctx, "gpt-4", 100*time.Millisecond))
+	stats, err := tracker.GetAllModelStats(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, stats, "gpt-4")
+}
@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Replica is a gateway replica as it registered itself
+type Replica struct {
+	ID          string    `json:"id"`
+	Hostname    string    `json:"hostname"`
+	Version     string    `json:"version"`
+	StartedAt   time.Time `json:"started_at"`
+	ConfigHash  string    `json:"config_hash"` // Hash of the replica's effective configuration
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	Healthy     bool      `json:"healthy"`
+	Error       string    `json:"error,omitempty"` // Why the replica reported itself unhealthy
+}
+
+// ReplicaRegistry keeps the registered gateway replicas in Redis. Each
+// replica is a key that expires unless its heartbeat refreshes it, listed in
+// a set of replica IDs.
+type ReplicaRegistry struct {
+	client *redis.Client
+	logger *zap.Logger
+}
+
+// NewReplicaRegistry creates a Redis-backed replica registry
+func NewReplicaRegistry(client *redis.Client, logger *zap.Logger) *ReplicaRegistry {
+	return &ReplicaRegistry{
+		client: client,
+		logger: logger,
+	}
+}
+
+// RegisterReplica stores a replica until ttl passes without another heartbeat
+func (r *ReplicaRegistry) RegisterReplica(ctx context.Context, replica *Replica, ttl time.Duration) error {
+	data, err := json.Marshal(replica)
+	if err != nil {
+		return fmt.Errorf("marshal replica: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, r.replicaKey(replica.ID), data, ttl)
+	pipe.SAdd(ctx, r.setKey(), replica.ID)
+
+	start := time.Now()
+	_, err = pipe.Exec(ctx)
+	observeOperation(componentReplicaRegistry, "register", start, err)
+	if err != nil {
+		return fmt.Errorf("register replica: %w", err)
+	}
+	return nil
+}
+
+// DeregisterReplica removes a replica that is shutting down
+func (r *ReplicaRegistry) DeregisterReplica(ctx context.Context, id string) error {
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, r.replicaKey(id))
+	pipe.SRem(ctx, r.setKey(), id)
+
+	start := time.Now()
+	_, err := pipe.Exec(ctx)
+	observeOperation(componentReplicaRegistry, "deregister", start, err)
+	if err != nil {
+		return fmt.Errorf("deregister replica: %w", err)
+	}
+	return nil
+}
+
+// ListReplicas returns the registered replicas ordered by ID. Replicas whose
+// key expired are dropped from the set.
+func (r *ReplicaRegistry) ListReplicas(ctx context.Context) ([]Replica, error) {
+	start := time.Now()
+	ids, err := r.client.SMembers(ctx, r.setKey()).Result()
+	if err != nil {
+		observeOperation(componentReplicaRegistry, "list", start, err)
+		return nil, fmt.Errorf("list replicas: %w", err)
+	}
+	defer func() { observeOperation(componentReplicaRegistry, "list", start, nil) }()
+
+	replicas := make([]Replica, 0, len(ids))
+	if len(ids) == 0 {
+		return replicas, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.Get(ctx, r.replicaKey(id))
+	}
+	_, _ = pipe.Exec(ctx) // expired replicas have no key
+
+	var expired []interface{}
+	for i, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if err == redis.Nil {
+			expired = append(expired, ids[i])
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get replica %s: %w", ids[i], err)
+		}
+
+		var replica Replica
+		if err := json.Unmarshal(data, &replica); err != nil {
+			r.logger.Warn("Skipping unreadable replica", zap.String("replica", ids[i]), zap.Error(err))
+			continue
+		}
+		replicas = append(replicas, replica)
+	}
+	if len(expired) > 0 {
+		if err := r.client.SRem(ctx, r.setKey(), expired...).Err(); err != nil {
+			r.logger.Debug("Failed to prune expired replicas", zap.Error(err))
+		}
+	}
+
+	sort.Slice(replicas, func(i, j int) bool { return replicas[i].ID < replicas[j].ID })
+	return replicas, nil
+}
+
+func (r *ReplicaRegistry) replicaKey(id string) string {
+	return Key(fmt.Sprintf("pllm:cluster:replica:%s", id))
+}
+
+func (r *ReplicaRegistry) setKey() string {
+	return Key("pllm:cluster:replicas")
+}
@@ -0,0 +1,47 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReplicaRegistry(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	registry := NewReplicaRegistry(client, zap.NewNop())
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, registry.RegisterReplica(ctx, &Replica{ID: "gw-b", Hostname: "gw-b", ConfigHash: "abc", StartedAt: now, HeartbeatAt: now, Healthy: true}, time.Minute))
+	require.NoError(t, registry.RegisterReplica(ctx, &Replica{ID: "gw-a", Hostname: "gw-a", ConfigHash: "def", StartedAt: now, HeartbeatAt: now}, 2*time.Minute))
+
+	replicas, err := registry.ListReplicas(ctx)
+	require.NoError(t, err)
+	require.Len(t, replicas, 2)
+	assert.Equal(t, "gw-a", replicas[0].ID, "ordered by ID")
+	assert.Equal(t, "abc", replicas[1].ConfigHash)
+	assert.True(t, replicas[1].StartedAt.Equal(now))
+
+	// A replica without heartbeats expires and is pruned from the set
+	mr.FastForward(90 * time.Second)
+	replicas, err = registry.ListReplicas(ctx)
+	require.NoError(t, err)
+	require.Len(t, replicas, 1)
+	assert.Equal(t, "gw-a", replicas[0].ID)
+	members, err := client.SMembers(ctx, registry.setKey()).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gw-a"}, members)
+
+	require.NoError(t, registry.DeregisterReplica(ctx, "gw-a"))
+	replicas, err = registry.ListReplicas(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, replicas)
+}
@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// snapshotKey holds the instance snapshots as a hash keyed by instance ID
+const snapshotKey = "pllm:snapshots:instances"
+
+// InstanceSnapshot is the persisted health and performance state of a model
+// instance, restored after a restart so routing and dashboards keep what the
+// previous process learned.
+type InstanceSnapshot struct {
+	InstanceID       string    `json:"instance_id"`
+	Healthy          bool      `json:"healthy"`
+	FailureCount     int32     `json:"failure_count"`
+	LastFailure      time.Time `json:"last_failure,omitempty"`
+	LastSuccess      time.Time `json:"last_success,omitempty"`
+	TotalRequests    int64     `json:"total_requests"`
+	TotalTokens      int64     `json:"total_tokens"`
+	AverageLatencyMs int64     `json:"average_latency_ms"`
+	AverageTTFTMs    int64     `json:"average_ttft_ms,omitempty"`
+	SavedAt          time.Time `json:"saved_at"`
+}
+
+// SnapshotStore persists instance snapshots in Redis.
+type SnapshotStore struct {
+	client *redis.Client
+	logger *zap.Logger
+	ttl    time.Duration
+}
+
+// NewSnapshotStore creates a new SnapshotStore. Snapshots expire after an
+// hour, so a gateway that has been down for longer starts from scratch.
+func NewSnapshotStore(client *redis.Client, logger *zap.Logger) *SnapshotStore {
+	return &SnapshotStore{
+		client: client,
+		logger: logger,
+		ttl:    time.Hour,
+	}
+}
+
+// Save writes the given snapshots, replacing earlier snapshots of the same
+// instances.
+func (s *SnapshotStore) Save(ctx context.Context, snapshots []InstanceSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(snapshots))
+	for _, snapshot := range snapshots {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return fmt.Errorf("marshal instance snapshot: %w", err)
+		}
+		fields[snapshot.InstanceID] = data
+	}
+
+	pipe := s.client.Pipeline()
+	pipe.HSet(ctx, Key(snapshotKey), fields)
+	pipe.Expire(ctx, Key(snapshotKey), s.ttl)
+
+	start := time.Now()
+	_, err := pipe.Exec(ctx)
+	observeOperation(componentSnapshotStore, "save", start, err)
+	if err != nil {
+		s.logger.Error("Failed to save instance snapshots", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Load returns the stored snapshots keyed by instance ID. Snapshots older
+// than the store's TTL are skipped.
+func (s *SnapshotStore) Load(ctx context.Context) (map[string]InstanceSnapshot, error) {
+	start := time.Now()
+	fields, err := s.client.HGetAll(ctx, Key(snapshotKey)).Result()
+	observeOperation(componentSnapshotStore, "load", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make(map[string]InstanceSnapshot, len(fields))
+	for id, data := range fields {
+		var snapshot InstanceSnapshot
+		if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+			s.logger.Warn("Skipping unreadable instance snapshot",
+				zap.String("instance", id),
+				zap.Error(err))
+			continue
+		}
+		if time.Since(snapshot.SavedAt) > s.ttl {
+			continue
+		}
+		snapshots[id] = snapshot
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,421 @@
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// UsageSchemaVersion is the version of the UsageRecord schema this build
+// writes. Bump it when a change to UsageRecord cannot be read correctly by
+// older workers, such as a renamed or retyped field; added fields need no
+// bump, since older workers ignore them.
+const UsageSchemaVersion = 1
+
+// Usage queue payload encodings
+const (
+	UsageEncodingJSON    = "json"    // Plain JSON, readable by every worker version
+	UsageEncodingMsgpack = "msgpack" // MessagePack, smaller than JSON
+)
+
+// Payloads in the msgpack encoding or compressed start with an envelope:
+// usageEnvelopeMagic, which plain JSON never starts with, then a flags byte
+const (
+	usageEnvelopeMagic byte = 0xc1 // The byte MessagePack never uses
+
+	usageFlagMsgpack byte = 1 << 0
+	usageFlagGzip    byte = 1 << 1
+	usageFlagsKnown       = usageFlagMsgpack | usageFlagGzip
+)
+
+// ErrUnsupportedUsagePayload is returned for payloads written by a newer
+// gateway, in an encoding or schema version this build cannot read. They are
+// held for a newer worker rather than processed wrongly or dropped.
+var ErrUnsupportedUsagePayload = errors.New("usage payload needs a newer worker")
+
+// UsageCodec encodes usage records for the queue
+type UsageCodec struct {
+	Encoding string // UsageEncodingJSON (default) or UsageEncodingMsgpack
+	Compress bool   // Gzip the payload
+}
+
+// Validate checks the encoding
+func (c UsageCodec) Validate() error {
+	switch c.Encoding {
+	case "", UsageEncodingJSON, UsageEncodingMsgpack:
+		return nil
+	}
+	return fmt.Errorf("unknown usage queue encoding %q, use %q or %q", c.Encoding, UsageEncodingJSON, UsageEncodingMsgpack)
+}
+
+// Name describes the codec in metrics, e.g. "msgpack+gzip"
+func (c UsageCodec) Name() string {
+	name := c.Encoding
+	if name == "" {
+		name = UsageEncodingJSON
+	}
+	if c.Compress {
+		name += "+gzip"
+	}
+	return name
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.BestSpeed)
+		return w
+	},
+}
+
+// Encode encodes a record with the current schema version
+func (c UsageCodec) Encode(record *UsageRecord) ([]byte, error) {
+	record.SchemaVersion = UsageSchemaVersion
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if c.Encoding == UsageEncodingMsgpack {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := writeMsgpack(&buf, value); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+		flags |= usageFlagMsgpack
+	}
+	if c.Compress {
+		var buf bytes.Buffer
+		w := gzipWriters.Get().(*gzip.Writer)
+		w.Reset(&buf)
+		_, err := w.Write(data)
+		if err == nil {
+			err = w.Close()
+		}
+		gzipWriters.Put(w)
+		if err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+		flags |= usageFlagGzip
+	}
+	if flags == 0 {
+		return data, nil
+	}
+	return append([]byte{usageEnvelopeMagic, flags}, data...), nil
+}
+
+// DecodeUsageRecord decodes a queued record in any encoding. It returns
+// ErrUnsupportedUsagePayload for payloads of a newer gateway.
+func DecodeUsageRecord(data []byte) (*UsageRecord, error) {
+	if len(data) >= 2 && data[0] == usageEnvelopeMagic {
+		flags := data[1]
+		if flags&^usageFlagsKnown != 0 {
+			return nil, ErrUnsupportedUsagePayload
+		}
+		data = data[2:]
+		if flags&usageFlagGzip != 0 {
+			r, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("invalid compressed usage payload: %w", err)
+			}
+			if data, err = io.ReadAll(r); err != nil {
+				return nil, fmt.Errorf("invalid compressed usage payload: %w", err)
+			}
+		}
+		if flags&usageFlagMsgpack != 0 {
+			value, rest, err := readMsgpack(data, 0)
+			if err != nil {
+				return nil, fmt.Errorf("invalid msgpack usage payload: %w", err)
+			}
+			if len(rest) != 0 {
+				return nil, errors.New("invalid msgpack usage payload: trailing data")
+			}
+			if data, err = json.Marshal(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var record UsageRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	if record.SchemaVersion > UsageSchemaVersion {
+		return &record, ErrUnsupportedUsagePayload
+	}
+	return &record, nil
+}
+
+// writeMsgpack encodes a value decoded from JSON (with json.Number numbers)
+// as MessagePack
+func writeMsgpack(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			writeMsgpackInt(buf, i)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xcb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		n := len(v)
+		switch {
+		case n < 32:
+			buf.WriteByte(0xa0 | byte(n))
+		case n <= math.MaxUint8:
+			buf.Write([]byte{0xd9, byte(n)})
+		case n <= math.MaxUint16:
+			buf.WriteByte(0xda)
+			_ = binary.Write(buf, binary.BigEndian, uint16(n))
+		default:
+			buf.WriteByte(0xdb)
+			_ = binary.Write(buf, binary.BigEndian, uint32(n))
+		}
+		buf.WriteString(v)
+	case []interface{}:
+		writeMsgpackHeader(buf, len(v), 0x90, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := writeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		writeMsgpackHeader(buf, len(v), 0x80, 0xde, 0xdf)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeMsgpack(buf, key); err != nil {
+				return err
+			}
+			if err := writeMsgpack(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %T as msgpack", value)
+	}
+	return nil
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i < 128:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		_ = binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// writeMsgpackHeader writes the header of an array or map of n elements
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix, code16, code32 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+var errMsgpackTruncated = errors.New("truncated data")
+
+// maxMsgpackDepth bounds the nesting of decoded values
+const maxMsgpackDepth = 32
+
+// readMsgpack decodes one MessagePack value into the types encoding/json
+// produces, and returns it with the remaining data. It reads what
+// writeMsgpack writes, plus the other integer and float widths.
+func readMsgpack(data []byte, depth int) (interface{}, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, errMsgpackTruncated
+	}
+	if depth > maxMsgpackDepth {
+		return nil, nil, errors.New("nested too deeply")
+	}
+	code, data := data[0], data[1:]
+
+	take := func(n int) ([]byte, error) {
+		if len(data) < n {
+			return nil, errMsgpackTruncated
+		}
+		b := data[:n]
+		data = data[n:]
+		return b, nil
+	}
+	length := func(size int) (int, error) {
+		b, err := take(size)
+		if err != nil {
+			return 0, err
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		if n > uint64(len(data)) {
+			return 0, errMsgpackTruncated // Every element takes at least a byte
+		}
+		return int(n), nil
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), data, nil
+	case code >= 0xe0:
+		return int64(int8(code)), data, nil
+	case code&0xe0 == 0xa0:
+		b, err := take(int(code & 0x1f))
+		return string(b), data, err
+	case code&0xf0 == 0x90:
+		return readMsgpackArray(data, int(code&0x0f), depth)
+	case code&0xf0 == 0x80:
+		return readMsgpackMap(data, int(code&0x0f), depth)
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, data, nil
+	case 0xc2:
+		return false, data, nil
+	case 0xc3:
+		return true, data, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		b, err := take(1 << (code - 0xcc))
+		if err != nil {
+			return nil, nil, err
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, data, nil
+	case 0xd0:
+		b, err := take(1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return int64(int8(b[0])), data, nil
+	case 0xd1:
+		b, err := take(2)
+		if err != nil {
+			return nil, nil, err
+		}
+		return int64(int16(binary.BigEndian.Uint16(b))), data, nil
+	case 0xd2:
+		b, err := take(4)
+		if err != nil {
+			return nil, nil, err
+		}
+		return int64(int32(binary.BigEndian.Uint32(b))), data, nil
+	case 0xd3:
+		b, err := take(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		return int64(binary.BigEndian.Uint64(b)), data, nil
+	case 0xca:
+		b, err := take(4)
+		if err != nil {
+			return nil, nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), data, nil
+	case 0xcb:
+		b, err := take(8)
+		if err != nil {
+			return nil, nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), data, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := length(1 << (code - 0xd9))
+		if err != nil {
+			return nil, nil, err
+		}
+		b, _ := take(n)
+		return string(b), data, nil
+	case 0xdc, 0xdd:
+		n, err := length(2 << (code - 0xdc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return readMsgpackArray(data, n, depth)
+	case 0xde, 0xdf:
+		n, err := length(2 << (code - 0xde))
+		if err != nil {
+			return nil, nil, err
+		}
+		return readMsgpackMap(data, n, depth)
+	}
+	return nil, nil, fmt.Errorf("unsupported msgpack type 0x%x", code)
+}
+
+func readMsgpackArray(data []byte, n, depth int) (interface{}, []byte, error) {
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		item, rest, err := readMsgpack(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+		data = rest
+	}
+	return items, data, nil
+}
+
+func readMsgpackMap(data []byte, n, depth int) (interface{}, []byte, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, rest, err := readMsgpack(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, nil, errors.New("map key is not a string")
+		}
+		value, rest, err := readMsgpack(rest, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		m[name] = value
+		data = rest
+	}
+	return m, data, nil
+}
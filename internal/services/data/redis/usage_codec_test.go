@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func testUsageRecord() *UsageRecord {
+	return &UsageRecord{
+		ID:           "rec-1",
+		RequestID:    "req-1",
+		Timestamp:    time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		UserID:       "user-1",
+		KeyID:        "key-1",
+		Model:        "gpt-4o",
+		Provider:     "openai",
+		EndpointType: "chat",
+		Method:       "POST",
+		Path:         "/v1/chat/completions",
+		StatusCode:   200,
+		InputTokens:  1200,
+		OutputTokens: 345,
+		TotalTokens:  1545,
+		TotalCost:    0.0123456789,
+		CostBreakdown: &config.CostBreakdown{
+			Model:     "gpt-4o",
+			Currency:  "USD",
+			Items:     []config.CostLineItem{{Component: "input_tokens", Quantity: 1200, Rate: 0.0000025, Cost: 0.003}},
+			Subtotal:  0.003,
+			TotalCost: 0.003,
+		},
+		Latency:         -1,
+		TokensPerSecond: 85.25,
+		Stalled:         true,
+	}
+}
+
+func TestUsageCodec_RoundTrip(t *testing.T) {
+	plain, err := json.Marshal(testUsageRecord())
+	require.NoError(t, err)
+
+	for _, codec := range []UsageCodec{
+		{},
+		{Encoding: UsageEncodingMsgpack},
+		{Encoding: UsageEncodingJSON, Compress: true},
+		{Encoding: UsageEncodingMsgpack, Compress: true},
+	} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			data, err := codec.Encode(testUsageRecord())
+			require.NoError(t, err)
+			if codec.Encoding == UsageEncodingMsgpack {
+				assert.Less(t, len(data), len(plain), "msgpack is smaller than JSON")
+			}
+
+			record, err := DecodeUsageRecord(data)
+			require.NoError(t, err)
+			want := testUsageRecord()
+			want.SchemaVersion = UsageSchemaVersion
+			assert.Equal(t, want, record)
+		})
+	}
+}
+
+func TestDecodeUsageRecord_Versions(t *testing.T) {
+	// Records queued before schema versioning
+	record, err := DecodeUsageRecord([]byte(`{"id":"old","model":"gpt-4","total_cost":0.5}`))
+	require.NoError(t, err)
+	assert.Equal(t, "old", record.ID)
+	assert.Zero(t, record.SchemaVersion)
+
+	_, err = DecodeUsageRecord([]byte(`{"schema_version":99,"id":"new"}`))
+	assert.ErrorIs(t, err, ErrUnsupportedUsagePayload)
+
+	_, err = DecodeUsageRecord([]byte{usageEnvelopeMagic, 1 << 5, '{', '}'})
+	assert.ErrorIs(t, err, ErrUnsupportedUsagePayload, "unknown encoding flags")
+
+	_, err = DecodeUsageRecord([]byte{usageEnvelopeMagic, usageFlagMsgpack, 0x81, 0xa2})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnsupportedUsagePayload)
+
+	assert.Error(t, UsageCodec{Encoding: "protobuf"}.Validate())
+}
+
+func TestUsageQueue_HoldsNewerSchemas(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	queue := NewUsageQueue(&UsageQueueConfig{
+		Client:    client,
+		Logger:    zap.NewNop(),
+		QueueName: "codec_test_queue",
+		BatchSize: 10,
+		Codec:     UsageCodec{Encoding: UsageEncodingMsgpack, Compress: true},
+	})
+
+	require.NoError(t, queue.EnqueueUsage(ctx, testUsageRecord()))
+	newer := `{"schema_version":99,"id":"from-newer-gateway"}`
+	require.NoError(t, client.LPush(ctx, queue.queueName, newer).Err())
+
+	records, err := queue.DequeueUsageBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "rec-1", records[0].ID)
+
+	stats, err := queue.GetQueueStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.HeldQueue)
+	assert.Equal(t, int64(1), stats.TotalPending)
+
+	// Still unreadable: the record stays held
+	require.NoError(t, queue.ProcessRetryQueue(ctx))
+	held, err := client.LRange(ctx, queue.queueName+":held", 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{newer}, held)
+
+	// A payload this worker reads, held by an older worker, is released
+	readable, err := UsageCodec{}.Encode(testUsageRecord())
+	require.NoError(t, err)
+	require.NoError(t, client.LPush(ctx, queue.queueName+":held", readable).Err())
+	require.NoError(t, queue.ProcessRetryQueue(ctx))
+
+	records, err = queue.DequeueUsageBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "rec-1", records[0].ID)
+	held, err = client.LRange(ctx, queue.queueName+":held", 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{newer}, held)
+}
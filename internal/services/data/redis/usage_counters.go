@@ -0,0 +1,242 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// usageCountersPrefix is followed by the UTC day, the scope and the entity ID
+const usageCountersPrefix = "pllm:usage:totals"
+
+// usageBandwidthPrefix is followed by the UTC month, the scope and the
+// entity ID
+const usageBandwidthPrefix = "pllm:usage:bandwidth"
+
+// Scopes usage totals are counted under
+const (
+	UsageScopeGlobal = "global"
+	UsageScopeUser   = "user"
+	UsageScopeTeam   = "team"
+	UsageScopeKey    = "key"
+	UsageScopeModel  = "model"
+)
+
+// usageGlobalID is the entity ID of the global scope
+const usageGlobalID = "all"
+
+// UsageTotals are exact usage aggregates, independent of how many detailed
+// usage rows were stored
+type UsageTotals struct {
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	Cost         float64 `json:"cost"`
+	StoredRows   int64   `json:"stored_rows"` // Detailed rows written to the database
+	Bandwidth
+}
+
+// Bandwidth is the size of the request bodies clients sent and of the
+// responses they received
+type Bandwidth struct {
+	RequestBytes  int64 `json:"request_bytes"`
+	ResponseBytes int64 `json:"response_bytes"`
+}
+
+// Total returns the bytes sent both ways
+func (b Bandwidth) Total() int64 {
+	return b.RequestBytes + b.ResponseBytes
+}
+
+func (t *UsageTotals) add(record *UsageRecord, stored bool) {
+	// A request is counted once, with its final record
+	if record.Checkpoint == 0 {
+		t.Requests++
+	}
+	if record.StatusCode >= 400 {
+		t.Errors++
+	}
+	t.InputTokens += int64(record.InputTokens)
+	t.OutputTokens += int64(record.OutputTokens)
+	t.TotalTokens += int64(record.TotalTokens)
+	t.Cost += record.TotalCost
+	t.RequestBytes += record.RequestBytes
+	t.ResponseBytes += record.ResponseBytes
+	if stored {
+		t.StoredRows++
+	}
+}
+
+// UsageCounters keeps exact per-day usage totals in Redis, so reporting stays
+// accurate when only a sample of usage records is stored in the database.
+type UsageCounters struct {
+	client *redis.Client
+	logger *zap.Logger
+	ttl    time.Duration
+}
+
+// NewUsageCounters creates a new UsageCounters. Daily totals are kept for 90
+// days.
+func NewUsageCounters(client *redis.Client, logger *zap.Logger) *UsageCounters {
+	return &UsageCounters{
+		client: client,
+		logger: logger,
+		ttl:    90 * 24 * time.Hour,
+	}
+}
+
+func usageCountersKey(day time.Time, scope, id string) string {
+	return Key(fmt.Sprintf("%s:%s:%s:%s", usageCountersPrefix, day.UTC().Format("2006-01-02"), scope, id))
+}
+
+func usageBandwidthKey(month time.Time, scope, id string) string {
+	return Key(fmt.Sprintf("%s:%s:%s:%s", usageBandwidthPrefix, month.UTC().Format("2006-01"), scope, id))
+}
+
+// Add counts a batch of usage records. stored reports whether a record's
+// detailed row was written to the database.
+func (c *UsageCounters) Add(ctx context.Context, records []*UsageRecord, stored func(*UsageRecord) bool) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	totals := make(map[string]*UsageTotals)
+	bandwidth := make(map[string]*Bandwidth)
+	count := func(key string, record *UsageRecord, isStored bool) {
+		t, ok := totals[key]
+		if !ok {
+			t = &UsageTotals{}
+			totals[key] = t
+		}
+		t.add(record, isStored)
+	}
+
+	for _, record := range records {
+		isStored := stored(record)
+		day := record.Timestamp
+		count(usageCountersKey(day, UsageScopeGlobal, usageGlobalID), record, isStored)
+		if record.UserID != "" {
+			count(usageCountersKey(day, UsageScopeUser, record.UserID), record, isStored)
+		}
+		if record.TeamID != "" {
+			count(usageCountersKey(day, UsageScopeTeam, record.TeamID), record, isStored)
+
+			// Monthly team bandwidth, read on every request to enforce quotas
+			if record.RequestBytes > 0 || record.ResponseBytes > 0 {
+				key := usageBandwidthKey(day, UsageScopeTeam, record.TeamID)
+				b, ok := bandwidth[key]
+				if !ok {
+					b = &Bandwidth{}
+					bandwidth[key] = b
+				}
+				b.RequestBytes += record.RequestBytes
+				b.ResponseBytes += record.ResponseBytes
+			}
+		}
+		if record.KeyID != "" {
+			count(usageCountersKey(day, UsageScopeKey, record.KeyID), record, isStored)
+		}
+		if record.Model != "" {
+			count(usageCountersKey(day, UsageScopeModel, record.Model), record, isStored)
+		}
+	}
+
+	pipe := c.client.Pipeline()
+	for key, t := range totals {
+		pipe.HIncrBy(ctx, key, "requests", t.Requests)
+		pipe.HIncrBy(ctx, key, "errors", t.Errors)
+		pipe.HIncrBy(ctx, key, "input_tokens", t.InputTokens)
+		pipe.HIncrBy(ctx, key, "output_tokens", t.OutputTokens)
+		pipe.HIncrBy(ctx, key, "total_tokens", t.TotalTokens)
+		pipe.HIncrByFloat(ctx, key, "cost", t.Cost)
+		pipe.HIncrBy(ctx, key, "stored_rows", t.StoredRows)
+		pipe.HIncrBy(ctx, key, "request_bytes", t.RequestBytes)
+		pipe.HIncrBy(ctx, key, "response_bytes", t.ResponseBytes)
+		pipe.Expire(ctx, key, c.ttl)
+	}
+	for key, b := range bandwidth {
+		pipe.HIncrBy(ctx, key, "request_bytes", b.RequestBytes)
+		pipe.HIncrBy(ctx, key, "response_bytes", b.ResponseBytes)
+		pipe.Expire(ctx, key, c.ttl)
+	}
+
+	start := time.Now()
+	_, err := pipe.Exec(ctx)
+	observeOperation(componentUsageCounters, "add", start, err)
+	if err != nil {
+		c.logger.Error("Failed to update usage counters",
+			zap.Int("records", len(records)),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Get sums the totals of an entity over the UTC days from from to to,
+// inclusive. The global scope ignores id.
+func (c *UsageCounters) Get(ctx context.Context, scope, id string, from, to time.Time) (*UsageTotals, error) {
+	if scope == UsageScopeGlobal {
+		id = usageGlobalID
+	}
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	if to.Before(from) {
+		return nil, fmt.Errorf("usage totals range ends before it starts")
+	}
+
+	pipe := c.client.Pipeline()
+	var cmds []*redis.MapStringStringCmd
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		cmds = append(cmds, pipe.HGetAll(ctx, usageCountersKey(day, scope, id)))
+	}
+
+	start := time.Now()
+	_, err := pipe.Exec(ctx)
+	observeOperation(componentUsageCounters, "get", start, err)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	totals := &UsageTotals{}
+	for _, cmd := range cmds {
+		fields := cmd.Val()
+		totals.Requests += parseCounter(fields["requests"])
+		totals.Errors += parseCounter(fields["errors"])
+		totals.InputTokens += parseCounter(fields["input_tokens"])
+		totals.OutputTokens += parseCounter(fields["output_tokens"])
+		totals.TotalTokens += parseCounter(fields["total_tokens"])
+		totals.StoredRows += parseCounter(fields["stored_rows"])
+		totals.RequestBytes += parseCounter(fields["request_bytes"])
+		totals.ResponseBytes += parseCounter(fields["response_bytes"])
+		if cost, err := strconv.ParseFloat(fields["cost"], 64); err == nil {
+			totals.Cost += cost
+		}
+	}
+	return totals, nil
+}
+
+// MonthlyBandwidth returns the bandwidth an entity used in the UTC month of
+// month. Only teams are counted by month.
+func (c *UsageCounters) MonthlyBandwidth(ctx context.Context, scope, id string, month time.Time) (*Bandwidth, error) {
+	start := time.Now()
+	fields, err := c.client.HGetAll(ctx, usageBandwidthKey(month, scope, id)).Result()
+	observeOperation(componentUsageCounters, "get_bandwidth", start, err)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return &Bandwidth{
+		RequestBytes:  parseCounter(fields["request_bytes"]),
+		ResponseBytes: parseCounter(fields["response_bytes"]),
+	}, nil
+}
+
+func parseCounter(value string) int64 {
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestUsageCounters(t *testing.T) (*UsageCounters, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewUsageCounters(client, zap.NewNop()), mr
+}
+
+func TestUsageCounters_AddAndGet(t *testing.T) {
+	counters, _ := newTestUsageCounters(t)
+	ctx := context.Background()
+
+	day := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	records := []*UsageRecord{
+		{RequestID: "a", Timestamp: day, UserID: "u1", KeyID: "k1", Model: "gpt-4", StatusCode: 200,
+			InputTokens: 10, OutputTokens: 5, TotalTokens: 15, TotalCost: 0.25},
+		{RequestID: "b", Timestamp: day, UserID: "u1", TeamID: "t1", Model: "gpt-4", StatusCode: 500,
+			InputTokens: 4, TotalTokens: 4, TotalCost: 0.5},
+		{RequestID: "c", Timestamp: day.AddDate(0, 0, 1), UserID: "u2", Model: "claude", StatusCode: 200,
+			InputTokens: 1, OutputTokens: 1, TotalTokens: 2, TotalCost: 1},
+	}
+	stored := func(r *UsageRecord) bool { return r.RequestID != "a" }
+	require.NoError(t, counters.Add(ctx, records, stored))
+
+	global, err := counters.Get(ctx, UsageScopeGlobal, "", day, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), global.Requests)
+	assert.Equal(t, int64(1), global.Errors)
+	assert.Equal(t, int64(15), global.InputTokens)
+	assert.Equal(t, int64(6), global.OutputTokens)
+	assert.Equal(t, int64(21), global.TotalTokens)
+	assert.InDelta(t, 1.75, global.Cost, 1e-9)
+	assert.Equal(t, int64(2), global.StoredRows)
+
+	user, err := counters.Get(ctx, UsageScopeUser, "u1", day, day)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), user.Requests)
+	assert.InDelta(t, 0.75, user.Cost, 1e-9)
+	assert.Equal(t, int64(1), user.StoredRows)
+
+	model, err := counters.Get(ctx, UsageScopeModel, "gpt-4", day.AddDate(0, 0, 1), day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Zero(t, model.Requests)
+
+	// Batches accumulate
+	require.NoError(t, counters.Add(ctx, records[:1], stored))
+	key, err := counters.Get(ctx, UsageScopeKey, "k1", day, day)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), key.Requests)
+	assert.Equal(t, int64(30), key.TotalTokens)
+}
+
+func TestUsageCounters_Bandwidth(t *testing.T) {
+	counters, _ := newTestUsageCounters(t)
+	ctx := context.Background()
+
+	day := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	records := []*UsageRecord{
+		{RequestID: "a", Timestamp: day, TeamID: "t1", RequestBytes: 2000, ResponseBytes: 500},
+		{RequestID: "b", Timestamp: day, TeamID: "t1", RequestBytes: 100, ResponseBytes: 50},
+		{RequestID: "c", Timestamp: day.Add(2 * time.Hour), TeamID: "t1", RequestBytes: 7},
+		{RequestID: "d", Timestamp: day, KeyID: "k1", RequestBytes: 1},
+	}
+	require.NoError(t, counters.Add(ctx, records, func(*UsageRecord) bool { return true }))
+
+	march, err := counters.MonthlyBandwidth(ctx, UsageScopeTeam, "t1", day)
+	require.NoError(t, err)
+	assert.Equal(t, Bandwidth{RequestBytes: 2100, ResponseBytes: 550}, *march)
+	assert.Equal(t, int64(2650), march.Total())
+
+	april, err := counters.MonthlyBandwidth(ctx, UsageScopeTeam, "t1", day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), april.Total(), "months are counted apart")
+
+	global, err := counters.Get(ctx, UsageScopeGlobal, "", day, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2108), global.RequestBytes)
+	assert.Equal(t, int64(550), global.ResponseBytes)
+}
+
+func TestUsageCounters_StreamCheckpoints(t *testing.T) {
+	counters, _ := newTestUsageCounters(t)
+	ctx := context.Background()
+
+	day := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	records := []*UsageRecord{
+		{RequestID: "a_cp1", Checkpoint: 1, Timestamp: day, KeyID: "k1", OutputTokens: 100, TotalTokens: 100, TotalCost: 0.1},
+		{RequestID: "a_cp2", Checkpoint: 2, Timestamp: day, KeyID: "k1", OutputTokens: 100, TotalTokens: 100, TotalCost: 0.1},
+		{RequestID: "a", Timestamp: day, KeyID: "k1", OutputTokens: 20, TotalTokens: 20, TotalCost: 0.02},
+	}
+	require.NoError(t, counters.Add(ctx, records, func(*UsageRecord) bool { return true }))
+
+	key, err := counters.Get(ctx, UsageScopeKey, "k1", day, day)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), key.Requests, "a request is counted once, with its final record")
+	assert.Equal(t, int64(220), key.OutputTokens)
+	assert.InDelta(t, 0.22, key.Cost, 1e-9)
+	assert.Equal(t, int64(3), key.StoredRows)
+}
+
+func TestUsageCounters_Expire(t *testing.T) {
+	counters, mr := newTestUsageCounters(t)
+	ctx := context.Background()
+
+	day := time.Now().UTC()
+	require.NoError(t, counters.Add(ctx, []*UsageRecord{{RequestID: "a", Timestamp: day, TotalCost: 1}},
+		func(*UsageRecord) bool { return true }))
+
+	key := usageCountersKey(day, UsageScopeGlobal, usageGlobalID)
+	assert.Equal(t, 90*24*time.Hour, mr.TTL(key))
+}
+
+func TestUsageCounters_InvalidRange(t *testing.T) {
+	counters, _ := newTestUsageCounters(t)
+	now := time.Now()
+	_, err := counters.Get(context.Background(), UsageScopeGlobal, "", now, now.AddDate(0, 0, -1))
+	assert.Error(t, err)
+}
@@ -3,18 +3,23 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
 )
 
 // UsageRecord represents a single usage record to be processed
 type UsageRecord struct {
+	SchemaVersion int       `json:"schema_version,omitempty"` // UsageSchemaVersion of the writer; 0 before versioning
 	ID           string     `json:"id"`
 	RequestID    string     `json:"request_id"`
+	Checkpoint   int        `json:"checkpoint,omitempty"` // Sequence number of a stream checkpoint; 0 for the final record of a request
 	Timestamp    time.Time  `json:"timestamp"`
 	UserID       string     `json:"user_id,omitempty"`        // Who made the request
 	ActualUserID string     `json:"actual_user_id,omitempty"` // Who actually used the key (for team keys)
@@ -22,18 +27,38 @@ type UsageRecord struct {
 	KeyOwnerID   string     `json:"key_owner_id,omitempty"` // Who owns the key
 	KeyType      string     `json:"key_type,omitempty"`     // Type of key (personal, team, system, etc.)
 	TeamID       string     `json:"team_id,omitempty"`
+	Environment  string     `json:"environment,omitempty"` // Environment of the key
 	Model         string     `json:"model"`
 	Provider      string     `json:"provider"`
 	RouteSlug     string     `json:"route_slug,omitempty"`
 	ProviderModel string     `json:"provider_model,omitempty"`
+	EndpointType string     `json:"endpoint_type,omitempty"` // Kind of API endpoint (models.EndpointTypeForPath)
 	Method       string     `json:"method"`
 	Path         string     `json:"path"`
 	StatusCode   int        `json:"status_code"`
 	InputTokens  int        `json:"input_tokens"`
 	OutputTokens int        `json:"output_tokens"`
+	ReasoningTokens int     `json:"reasoning_tokens,omitempty"` // Included in OutputTokens
 	TotalTokens  int        `json:"total_tokens"`
 	TotalCost    float64    `json:"total_cost"`
+	CostBreakdown *config.CostBreakdown `json:"cost_breakdown,omitempty"` // How TotalCost was computed
+	AudioSeconds float64    `json:"audio_seconds,omitempty"` // Transcribed audio, for per-second pricing
+	OutputAudioSeconds float64 `json:"output_audio_seconds,omitempty"` // Generated audio of realtime sessions
+	ImagesGenerated int     `json:"images_generated,omitempty"` // Images returned by image generation
+	Characters   int        `json:"characters,omitempty"`   // Input characters synthesized to speech
+	RequestBytes  int64     `json:"request_bytes,omitempty"`  // Request body sent by the client
+	ResponseBytes int64     `json:"response_bytes,omitempty"` // Response body returned to it
 	Latency      int64      `json:"latency_ms"`
+	TTFT         int64      `json:"ttft_ms,omitempty"` // Time to first token of streaming requests
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"` // Streaming generation throughput
+	MaxChunkGap  int64      `json:"max_chunk_gap_ms,omitempty"` // Longest gap between streamed chunks
+	Stalled      bool       `json:"stalled,omitempty"`
+	ProviderKeyID string    `json:"provider_key_id,omitempty"` // Team provider key the request was sent with
+	ContentHash  string     `json:"content_hash,omitempty"`
+	Country       string    `json:"country,omitempty"`   // Client IP country and continent codes
+	Continent     string    `json:"continent,omitempty"`
+	ClientName    string    `json:"client_name,omitempty"` // Client library from the User-Agent
+	ClientVersion string    `json:"client_version,omitempty"`
 	Retries      int        `json:"retries"`
 	ProcessedAt  *time.Time `json:"processed_at,omitempty"`
 }
@@ -45,6 +70,7 @@ type UsageQueue struct {
 	queueName  string
 	batchSize  int
 	maxRetries int
+	codec      UsageCodec
 }
 
 // UsageQueueConfig configuration for the usage queue
@@ -54,6 +80,7 @@ type UsageQueueConfig struct {
 	QueueName  string
 	BatchSize  int
 	MaxRetries int
+	Codec      UsageCodec // Payload encoding; plain JSON by default
 }
 
 // NewUsageQueue creates a new usage queue
@@ -71,9 +98,10 @@ func NewUsageQueue(config *UsageQueueConfig) *UsageQueue {
 	return &UsageQueue{
 		client:     config.Client,
 		logger:     config.Logger,
-		queueName:  config.QueueName,
+		queueName:  Key(config.QueueName),
 		batchSize:  config.BatchSize,
 		maxRetries: config.MaxRetries,
+		codec:      config.Codec,
 	}
 }
 
@@ -86,19 +114,23 @@ func (uq *UsageQueue) EnqueueUsage(ctx context.Context, record *UsageRecord) err
 		record.Timestamp = time.Now()
 	}
 
-	data, err := json.Marshal(record)
+	data, err := uq.codec.Encode(record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal usage record: %w", err)
 	}
+	redisUsagePayloadBytes.WithLabelValues(uq.codec.Name()).Add(float64(len(data)))
 
 	// Add to Redis list (LPUSH for FIFO processing with RPOP)
+	start := time.Now()
 	err = uq.client.LPush(ctx, uq.queueName, data).Err()
+	observeOperation(componentUsageQueue, "enqueue", start, err)
 	if err != nil {
 		uq.logger.Error("Failed to enqueue usage record",
 			zap.Error(err),
 			zap.String("record_id", record.ID))
 		return fmt.Errorf("failed to enqueue usage record: %w", err)
 	}
+	redisUsageRecords.WithLabelValues("enqueued").Inc()
 
 	uq.logger.Debug("Usage record enqueued",
 		zap.String("record_id", record.ID),
@@ -121,12 +153,19 @@ func (uq *UsageQueue) DequeueUsageBatch(ctx context.Context) ([]*UsageRecord, er
 		cmds = append(cmds, cmd)
 	}
 
+	start := time.Now()
 	_, err := pipe.Exec(ctx)
+	if err == redis.Nil {
+		observeOperation(componentUsageQueue, "dequeue", start, nil)
+	} else {
+		observeOperation(componentUsageQueue, "dequeue", start, err)
+	}
 	if err != nil && err != redis.Nil {
 		return nil, fmt.Errorf("failed to dequeue usage records: %w", err)
 	}
 
 	var records []*UsageRecord
+	var held []interface{}
 	for _, cmd := range cmds {
 		result, err := cmd.Result()
 		if err == redis.Nil {
@@ -137,18 +176,24 @@ func (uq *UsageQueue) DequeueUsageBatch(ctx context.Context) ([]*UsageRecord, er
 			continue
 		}
 
-		var record UsageRecord
-		if err := json.Unmarshal([]byte(result), &record); err != nil {
+		record, err := DecodeUsageRecord([]byte(result))
+		if errors.Is(err, ErrUnsupportedUsagePayload) {
+			held = append(held, result)
+			continue
+		}
+		if err != nil {
 			uq.logger.Error("Failed to unmarshal usage record",
 				zap.Error(err),
 				zap.String("data", result))
 			continue
 		}
 
-		records = append(records, &record)
+		records = append(records, record)
 	}
+	uq.hold(ctx, held)
 
 	if len(records) > 0 {
+		redisUsageRecords.WithLabelValues("dequeued").Add(float64(len(records)))
 		uq.logger.Debug("Dequeued usage records batch",
 			zap.Int("count", len(records)))
 	}
@@ -169,7 +214,7 @@ func (uq *UsageQueue) EnqueueUsageFailed(ctx context.Context, record *UsageRecor
 	retryDelay := time.Duration(record.Retries*record.Retries) * 10 * time.Second
 	retryAt := time.Now().Add(retryDelay)
 
-	data, err := json.Marshal(record)
+	data, err := uq.codec.Encode(record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal failed record: %w", err)
 	}
@@ -182,8 +227,10 @@ func (uq *UsageQueue) EnqueueUsageFailed(ctx context.Context, record *UsageRecor
 	}).Err()
 
 	if err != nil {
+		redisOperationErrors.WithLabelValues(componentUsageQueue, "retry").Inc()
 		return fmt.Errorf("failed to enqueue retry record: %w", err)
 	}
+	redisUsageRecords.WithLabelValues("retried").Inc()
 
 	uq.logger.Warn("Usage record queued for retry",
 		zap.String("record_id", record.ID),
@@ -196,6 +243,10 @@ func (uq *UsageQueue) EnqueueUsageFailed(ctx context.Context, record *UsageRecor
 
 // ProcessRetryQueue processes records that are ready for retry
 func (uq *UsageQueue) ProcessRetryQueue(ctx context.Context) error {
+	if err := uq.releaseHeld(ctx); err != nil {
+		uq.logger.Warn("Failed to release held usage records", zap.Error(err))
+	}
+
 	retryQueueName := fmt.Sprintf("%s:retry", uq.queueName)
 	now := float64(time.Now().Unix())
 
@@ -232,6 +283,54 @@ func (uq *UsageQueue) ProcessRetryQueue(ctx context.Context) error {
 	return nil
 }
 
+// hold moves payloads written by a newer gateway to the held queue, where
+// they wait for a worker that can read them instead of being dropped
+func (uq *UsageQueue) hold(ctx context.Context, payloads []interface{}) {
+	if len(payloads) == 0 {
+		return
+	}
+	if err := uq.client.LPush(ctx, uq.queueName+":held", payloads...).Err(); err != nil {
+		redisOperationErrors.WithLabelValues(componentUsageQueue, "hold").Inc()
+		uq.logger.Error("Failed to hold usage records of a newer schema; they are lost",
+			zap.Error(err), zap.Int("count", len(payloads)))
+		return
+	}
+	redisUsageRecords.WithLabelValues("held").Add(float64(len(payloads)))
+	uq.logger.Warn("Holding usage records written by a newer gateway until a newer worker runs",
+		zap.Int("count", len(payloads)),
+		zap.Int("schema_version", UsageSchemaVersion))
+}
+
+// releaseHeld moves the held records this worker can read back to the main
+// queue, after an upgrade. Records it still cannot read stay held.
+func (uq *UsageQueue) releaseHeld(ctx context.Context) error {
+	heldQueue := uq.queueName + ":held"
+	payloads, err := uq.client.LRange(ctx, heldQueue, 0, int64(uq.batchSize)-1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read held records: %w", err)
+	}
+
+	pipe := uq.client.TxPipeline()
+	released := 0
+	for _, payload := range payloads {
+		if _, err := DecodeUsageRecord([]byte(payload)); errors.Is(err, ErrUnsupportedUsagePayload) {
+			continue
+		}
+		pipe.LRem(ctx, heldQueue, 1, payload)
+		pipe.LPush(ctx, uq.queueName, payload)
+		released++
+	}
+	if released == 0 {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to release held records: %w", err)
+	}
+	redisUsageRecords.WithLabelValues("released").Add(float64(released))
+	uq.logger.Info("Released held usage records", zap.Int("count", released))
+	return nil
+}
+
 // moveToDeadLetterQueue moves failed records to dead letter queue
 func (uq *UsageQueue) moveToDeadLetterQueue(ctx context.Context, record *UsageRecord, errorMsg string) error {
 	deadLetterQueue := fmt.Sprintf("%s:dead_letter", uq.queueName)
@@ -250,8 +349,10 @@ func (uq *UsageQueue) moveToDeadLetterQueue(ctx context.Context, record *UsageRe
 
 	err = uq.client.LPush(ctx, deadLetterQueue, data).Err()
 	if err != nil {
+		redisOperationErrors.WithLabelValues(componentUsageQueue, "dead_letter").Inc()
 		return fmt.Errorf("failed to enqueue dead letter record: %w", err)
 	}
+	redisUsageRecords.WithLabelValues("dead_lettered").Inc()
 
 	uq.logger.Error("Usage record moved to dead letter queue",
 		zap.String("record_id", record.ID),
@@ -268,8 +369,11 @@ func (uq *UsageQueue) GetQueueStats(ctx context.Context) (*QueueStats, error) {
 	mainQueueCmd := pipe.LLen(ctx, uq.queueName)
 	retryQueueCmd := pipe.ZCard(ctx, fmt.Sprintf("%s:retry", uq.queueName))
 	deadLetterCmd := pipe.LLen(ctx, fmt.Sprintf("%s:dead_letter", uq.queueName))
+	heldCmd := pipe.LLen(ctx, uq.queueName+":held")
 
+	start := time.Now()
 	_, err := pipe.Exec(ctx)
+	observeOperation(componentUsageQueue, "stats", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get queue stats: %w", err)
 	}
@@ -277,12 +381,19 @@ func (uq *UsageQueue) GetQueueStats(ctx context.Context) (*QueueStats, error) {
 	mainCount, _ := mainQueueCmd.Result()
 	retryCount, _ := retryQueueCmd.Result()
 	deadLetterCount, _ := deadLetterCmd.Result()
+	heldCount, _ := heldCmd.Result()
+
+	redisQueueDepth.WithLabelValues(uq.queueName).Set(float64(mainCount))
+	redisQueueDepth.WithLabelValues(uq.queueName + ":retry").Set(float64(retryCount))
+	redisQueueDepth.WithLabelValues(uq.queueName + ":dead_letter").Set(float64(deadLetterCount))
+	redisQueueDepth.WithLabelValues(uq.queueName + ":held").Set(float64(heldCount))
 
 	return &QueueStats{
 		MainQueue:       mainCount,
 		RetryQueue:      retryCount,
 		DeadLetterQueue: deadLetterCount,
-		TotalPending:    mainCount + retryCount,
+		HeldQueue:       heldCount,
+		TotalPending:    mainCount + retryCount + heldCount,
 	}, nil
 }
 
@@ -291,6 +402,7 @@ type QueueStats struct {
 	MainQueue       int64 `json:"main_queue"`
 	RetryQueue      int64 `json:"retry_queue"`
 	DeadLetterQueue int64 `json:"dead_letter_queue"`
+	HeldQueue       int64 `json:"held_queue"` // Records of a newer schema, waiting for a newer worker
 	TotalPending    int64 `json:"total_pending"`
 }
 
@@ -300,6 +412,7 @@ func (uq *UsageQueue) ClearQueue(ctx context.Context) error {
 	pipe.Del(ctx, uq.queueName)
 	pipe.Del(ctx, fmt.Sprintf("%s:retry", uq.queueName))
 	pipe.Del(ctx, fmt.Sprintf("%s:dead_letter", uq.queueName))
+	pipe.Del(ctx, uq.queueName+":held")
 
 	_, err := pipe.Exec(ctx)
 	if err != nil {
@@ -0,0 +1,69 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultPauseMessage is returned to clients while the gateway is paused
+// without a message of its own
+const DefaultPauseMessage = "The gateway is paused for maintenance, please retry later"
+
+// EmergencyControls switch off inference at once: the whole gateway, single
+// models or single teams. Reasons are shown to the rejected clients.
+type EmergencyControls struct {
+	Paused         bool              `json:"paused"`
+	PauseMessage   string            `json:"pause_message,omitempty"`
+	DisabledModels map[string]string `json:"disabled_models"` // Model name to reason
+	SuspendedTeams map[string]string `json:"suspended_teams"` // Team ID to reason
+}
+
+// Validate rejects empty model names and team IDs
+func (e EmergencyControls) Validate() error {
+	for model := range e.DisabledModels {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("disabled model name must not be empty")
+		}
+	}
+	for team := range e.SuspendedTeams {
+		if strings.TrimSpace(team) == "" {
+			return fmt.Errorf("suspended team ID must not be empty")
+		}
+	}
+	return nil
+}
+
+// Clone returns a copy that can be changed without affecting e
+func (e EmergencyControls) Clone() EmergencyControls {
+	models := make(map[string]string, len(e.DisabledModels))
+	for model, reason := range e.DisabledModels {
+		models[model] = reason
+	}
+	teams := make(map[string]string, len(e.SuspendedTeams))
+	for team, reason := range e.SuspendedTeams {
+		teams[team] = reason
+	}
+	e.DisabledModels = models
+	e.SuspendedTeams = teams
+	return e
+}
+
+// Message returns the message for clients while the gateway is paused
+func (e EmergencyControls) Message() string {
+	if e.PauseMessage != "" {
+		return e.PauseMessage
+	}
+	return DefaultPauseMessage
+}
+
+// ModelDisabled reports whether a model is switched off, and why
+func (e EmergencyControls) ModelDisabled(model string) (string, bool) {
+	reason, disabled := e.DisabledModels[model]
+	return reason, disabled
+}
+
+// TeamSuspended reports whether a team is suspended, and why
+func (e EmergencyControls) TeamSuspended(teamID string) (string, bool) {
+	reason, suspended := e.SuspendedTeams[teamID]
+	return reason, suspended
+}
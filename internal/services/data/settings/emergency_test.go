@@ -0,0 +1,77 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEmergencyControls(t *testing.T) {
+	controls := EmergencyControls{
+		DisabledModels: map[string]string{"gpt-4": "provider incident"},
+		SuspendedTeams: map[string]string{},
+	}
+	assert.Equal(t, DefaultPauseMessage, controls.Message())
+	controls.PauseMessage = "Upgrading"
+	assert.Equal(t, "Upgrading", controls.Message())
+
+	reason, disabled := controls.ModelDisabled("gpt-4")
+	assert.True(t, disabled)
+	assert.Equal(t, "provider incident", reason)
+	_, suspended := controls.TeamSuspended("team")
+	assert.False(t, suspended)
+
+	clone := controls.Clone()
+	clone.DisabledModels["claude"] = ""
+	assert.NotContains(t, controls.DisabledModels, "claude")
+
+	assert.NoError(t, controls.Validate())
+	assert.Error(t, EmergencyControls{DisabledModels: map[string]string{" ": ""}}.Validate())
+	assert.Error(t, EmergencyControls{SuspendedTeams: map[string]string{"": ""}}.Validate())
+}
+
+func TestStore_PropagatesEmergencyControls(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	writer := NewStore(&StoreConfig{Redis: client, Logger: zap.NewNop()})
+	replica := NewStore(&StoreConfig{Redis: client, Logger: zap.NewNop()})
+	defer replica.Stop()
+	assert.False(t, replica.EmergencyControls().Paused)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replica.subscribe(ctx)
+
+	value, err := json.Marshal(EmergencyControls{Paused: true, DisabledModels: map[string]string{"gpt-4": ""}})
+	require.NoError(t, err)
+	updatedAt := time.Now()
+
+	require.Eventually(t, func() bool {
+		writer.publish(ctx, change{Key: emergencyControlsKey, Value: value, UpdatedAt: updatedAt})
+		return replica.EmergencyControls().Paused
+	}, 2*time.Second, 20*time.Millisecond)
+	_, disabled := replica.EmergencyControls().ModelDisabled("gpt-4")
+	assert.True(t, disabled)
+
+	// Keys are versioned separately: a rate limit change does not hide a
+	// later emergency change, and an older emergency change is ignored
+	limits, err := json.Marshal(RateLimits{Enabled: true})
+	require.NoError(t, err)
+	replica.apply(rateLimitsKey, limits, updatedAt.Add(time.Minute))
+	resumed, err := json.Marshal(EmergencyControls{})
+	require.NoError(t, err)
+	replica.apply(emergencyControlsKey, resumed, updatedAt.Add(-time.Minute))
+	assert.True(t, replica.EmergencyControls().Paused)
+	replica.apply(emergencyControlsKey, resumed, updatedAt.Add(time.Second))
+	assert.False(t, replica.EmergencyControls().Paused)
+	assert.NotNil(t, replica.EmergencyControls().DisabledModels)
+}
@@ -0,0 +1,93 @@
+package settings
+
+import (
+	"fmt"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// Rate limit scopes. Endpoint scopes fall back to the global limits.
+const (
+	ScopeGlobal          = "global"
+	ScopeChatCompletions = "chat_completions"
+	ScopeCompletions     = "completions"
+	ScopeEmbeddings      = "embeddings"
+)
+
+// defaultRPM applies when no scope sets a requests-per-minute limit
+const defaultRPM = 60
+
+var rateLimitScopes = map[string]bool{
+	ScopeGlobal:          true,
+	ScopeChatCompletions: true,
+	ScopeCompletions:     true,
+	ScopeEmbeddings:      true,
+}
+
+// RateLimit is a requests-per-minute and tokens-per-minute limit. Zero
+// leaves the limit to the global scope, or unlimited for TPM.
+type RateLimit struct {
+	RPM int `json:"rpm"`
+	TPM int `json:"tpm"`
+}
+
+// RateLimits are the gateway rate limits, per API key and scope
+type RateLimits struct {
+	Enabled bool                 `json:"enabled"`
+	Scopes  map[string]RateLimit `json:"scopes"`
+}
+
+// RateLimitsFromConfig returns the rate limits of the configuration file
+func RateLimitsFromConfig(cfg config.RateLimitConfig) RateLimits {
+	return RateLimits{
+		Enabled: cfg.Enabled,
+		Scopes: map[string]RateLimit{
+			ScopeGlobal:          {RPM: cfg.GlobalRPM, TPM: cfg.GlobalTPM},
+			ScopeChatCompletions: {RPM: cfg.ChatCompletionsRPM, TPM: cfg.ChatCompletionsTPM},
+			ScopeCompletions:     {RPM: cfg.CompletionsRPM, TPM: cfg.CompletionsTPM},
+			ScopeEmbeddings:      {RPM: cfg.EmbeddingsRPM, TPM: cfg.EmbeddingsTPM},
+		},
+	}
+}
+
+// Validate rejects unknown scopes and negative limits
+func (r RateLimits) Validate() error {
+	for scope, limit := range r.Scopes {
+		if !rateLimitScopes[scope] {
+			return fmt.Errorf("unknown rate limit scope %q", scope)
+		}
+		if limit.RPM < 0 || limit.TPM < 0 {
+			return fmt.Errorf("rate limits of scope %q must not be negative", scope)
+		}
+	}
+	return nil
+}
+
+// Limit returns the effective limits of a scope: its own, else the global
+// ones. RPM falls back to 60 when no scope sets it.
+func (r RateLimits) Limit(scope string) RateLimit {
+	own := r.Scopes[scope]
+	global := r.Scopes[ScopeGlobal]
+
+	limit := own
+	if limit.RPM == 0 {
+		limit.RPM = global.RPM
+	}
+	if limit.RPM == 0 {
+		limit.RPM = defaultRPM
+	}
+	if limit.TPM == 0 {
+		limit.TPM = global.TPM
+	}
+	return limit
+}
+
+// Clone returns a copy that can be changed without affecting r
+func (r RateLimits) Clone() RateLimits {
+	scopes := make(map[string]RateLimit, len(r.Scopes))
+	for scope, limit := range r.Scopes {
+		scopes[scope] = limit
+	}
+	r.Scopes = scopes
+	return r
+}
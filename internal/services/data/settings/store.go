@@ -0,0 +1,310 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// System settings keys
+const (
+	rateLimitsKey        = "rate_limits"
+	emergencyControlsKey = "emergency_controls"
+)
+
+// changesChannel carries setting changes to the other gateways
+const changesChannel = "pllm:settings:changes"
+
+// change is a setting change published to the other gateways
+type change struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Store holds the settings admins change at runtime. Settings are persisted
+// in the database and start from the configuration file until first changed.
+// A change reaches the other gateways at once over Redis pub/sub when Redis
+// is available, and within the poll interval otherwise.
+type Store struct {
+	db           *gorm.DB
+	client       *redis.Client
+	logger       *zap.Logger
+	pollInterval time.Duration
+
+	mu         sync.RWMutex
+	rateLimits RateLimits
+	emergency  EmergencyControls
+	updatedAt  map[string]time.Time // Per setting key
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+type StoreConfig struct {
+	DB           *gorm.DB
+	Redis        *redis.Client // Optional, propagates changes immediately
+	Logger       *zap.Logger
+	RateLimits   config.RateLimitConfig // Defaults until an admin changes them
+	PollInterval time.Duration
+}
+
+func NewStore(cfg *StoreConfig) *Store {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 30 * time.Second
+	}
+	return &Store{
+		db:           cfg.DB,
+		client:       cfg.Redis,
+		logger:       cfg.Logger,
+		pollInterval: cfg.PollInterval,
+		rateLimits:   RateLimitsFromConfig(cfg.RateLimits),
+		emergency:    EmergencyControls{}.Clone(),
+		updatedAt:    make(map[string]time.Time),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start loads the stored settings and follows changes made on other gateways
+func (s *Store) Start(ctx context.Context) {
+	if err := s.Load(ctx); err != nil {
+		s.logger.Warn("Failed to load runtime settings, using configured defaults", zap.Error(err))
+	}
+	if s.client != nil {
+		go s.subscribe(ctx)
+	}
+	go s.poll(ctx)
+}
+
+// Stop stops following changes
+func (s *Store) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// RateLimits returns the current rate limits
+func (s *Store) RateLimits() RateLimits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rateLimits.Clone()
+}
+
+// UpdateRateLimits validates, stores and applies new rate limits and
+// publishes them to the other gateways
+func (s *Store) UpdateRateLimits(ctx context.Context, limits RateLimits, updatedBy *uuid.UUID) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	value, err := json.Marshal(limits)
+	if err != nil {
+		return err
+	}
+
+	setting := models.SystemSetting{
+		Key:       rateLimitsKey,
+		Value:     datatypes.JSON(value),
+		UpdatedAt: time.Now(),
+		UpdatedBy: updatedBy,
+	}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at", "updated_by"}),
+	}).Create(&setting).Error; err != nil {
+		return fmt.Errorf("failed to store rate limits: %w", err)
+	}
+
+	s.apply(setting.Key, value, setting.UpdatedAt)
+	s.publish(ctx, change{Key: setting.Key, Value: value, UpdatedAt: setting.UpdatedAt})
+	return nil
+}
+
+// EmergencyControls returns the current emergency controls
+func (s *Store) EmergencyControls() EmergencyControls {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.emergency.Clone()
+}
+
+// UpdateEmergencyControls changes the stored emergency controls with mutate,
+// applies them and publishes them to the other gateways. The stored row is
+// locked while mutate runs so concurrent changes from several gateways are
+// not lost.
+func (s *Store) UpdateEmergencyControls(ctx context.Context, mutate func(*EmergencyControls) error, updatedBy *uuid.UUID) (EmergencyControls, error) {
+	var controls EmergencyControls
+	var setting models.SystemSetting
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stored models.SystemSetting
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", emergencyControlsKey).First(&stored).Error
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(stored.Value, &controls); err != nil {
+				return fmt.Errorf("invalid stored emergency controls: %w", err)
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		controls = controls.Clone()
+
+		if err := mutate(&controls); err != nil {
+			return err
+		}
+		if err := controls.Validate(); err != nil {
+			return err
+		}
+		value, err := json.Marshal(controls)
+		if err != nil {
+			return err
+		}
+
+		setting = models.SystemSetting{
+			Key:       emergencyControlsKey,
+			Value:     datatypes.JSON(value),
+			UpdatedAt: time.Now(),
+			UpdatedBy: updatedBy,
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at", "updated_by"}),
+		}).Create(&setting).Error
+	})
+	if err != nil {
+		return EmergencyControls{}, err
+	}
+
+	s.apply(setting.Key, setting.Value, setting.UpdatedAt)
+	s.publish(ctx, change{Key: setting.Key, Value: json.RawMessage(setting.Value), UpdatedAt: setting.UpdatedAt})
+	return controls, nil
+}
+
+// Load applies the settings stored in the database
+func (s *Store) Load(ctx context.Context) error {
+	var stored []models.SystemSetting
+	if err := s.db.WithContext(ctx).Find(&stored).Error; err != nil {
+		return err
+	}
+	for _, setting := range stored {
+		s.apply(setting.Key, setting.Value, setting.UpdatedAt)
+	}
+	return nil
+}
+
+// apply replaces a setting unless the current value is newer, so a
+// delayed message cannot undo a later change
+func (s *Store) apply(key string, value []byte, updatedAt time.Time) {
+	if key != rateLimitsKey && key != emergencyControlsKey {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !updatedAt.After(s.updatedAt[key]) {
+		return
+	}
+
+	if key == emergencyControlsKey {
+		s.applyEmergencyControls(value, updatedAt)
+		return
+	}
+
+	var limits RateLimits
+	if err := json.Unmarshal(value, &limits); err != nil {
+		s.logger.Error("Ignoring invalid rate limit settings", zap.Error(err))
+		return
+	}
+	if limits.Scopes == nil {
+		limits.Scopes = map[string]RateLimit{}
+	}
+	s.rateLimits = limits
+	s.updatedAt[key] = updatedAt
+	s.logger.Info("Applied rate limit settings",
+		zap.Bool("enabled", limits.Enabled),
+		zap.Time("updated_at", updatedAt))
+}
+
+// applyEmergencyControls replaces the emergency controls; s.mu is held
+func (s *Store) applyEmergencyControls(value []byte, updatedAt time.Time) {
+	var controls EmergencyControls
+	if err := json.Unmarshal(value, &controls); err != nil {
+		s.logger.Error("Ignoring invalid emergency controls", zap.Error(err))
+		return
+	}
+	s.emergency = controls.Clone()
+	s.updatedAt[emergencyControlsKey] = updatedAt
+	s.logger.Warn("Applied emergency controls",
+		zap.Bool("paused", controls.Paused),
+		zap.Int("disabled_models", len(controls.DisabledModels)),
+		zap.Int("suspended_teams", len(controls.SuspendedTeams)),
+		zap.Time("updated_at", updatedAt))
+}
+
+func (s *Store) publish(ctx context.Context, c change) {
+	if s.client == nil {
+		return
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	if err := s.client.Publish(ctx, redisService.Key(changesChannel), payload).Err(); err != nil {
+		s.logger.Warn("Failed to publish settings change, other gateways apply it on their next poll",
+			zap.String("key", c.Key),
+			zap.Error(err))
+	}
+}
+
+func (s *Store) subscribe(ctx context.Context) {
+	pubsub := s.client.Subscribe(ctx, redisService.Key(changesChannel))
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var c change
+			if err := json.Unmarshal([]byte(msg.Payload), &c); err != nil {
+				s.logger.Warn("Ignoring invalid settings change", zap.Error(err))
+				continue
+			}
+			s.apply(c.Key, c.Value, c.UpdatedAt)
+		}
+	}
+}
+
+func (s *Store) poll(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := s.Load(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				s.logger.Warn("Failed to reload runtime settings", zap.Error(err))
+			}
+		}
+	}
+}
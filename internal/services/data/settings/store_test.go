@@ -0,0 +1,82 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func TestRateLimits_Limit(t *testing.T) {
+	limits := RateLimitsFromConfig(config.RateLimitConfig{
+		Enabled:            true,
+		GlobalRPM:          1000,
+		GlobalTPM:          50000,
+		ChatCompletionsRPM: 100,
+		EmbeddingsTPM:      200000,
+	})
+
+	assert.Equal(t, RateLimit{RPM: 100, TPM: 50000}, limits.Limit(ScopeChatCompletions))
+	assert.Equal(t, RateLimit{RPM: 1000, TPM: 200000}, limits.Limit(ScopeEmbeddings))
+	assert.Equal(t, RateLimit{RPM: 1000, TPM: 50000}, limits.Limit(ScopeGlobal))
+	assert.Equal(t, RateLimit{RPM: 60}, RateLimits{}.Limit(ScopeCompletions))
+}
+
+func TestRateLimits_Validate(t *testing.T) {
+	assert.NoError(t, RateLimits{Scopes: map[string]RateLimit{ScopeGlobal: {RPM: 10}}}.Validate())
+	assert.Error(t, RateLimits{Scopes: map[string]RateLimit{"images": {RPM: 10}}}.Validate())
+	assert.Error(t, RateLimits{Scopes: map[string]RateLimit{ScopeGlobal: {TPM: -1}}}.Validate())
+}
+
+func TestRateLimits_Clone(t *testing.T) {
+	limits := RateLimits{Scopes: map[string]RateLimit{ScopeGlobal: {RPM: 10}}}
+	clone := limits.Clone()
+	clone.Scopes[ScopeGlobal] = RateLimit{RPM: 20}
+	assert.Equal(t, 10, limits.Scopes[ScopeGlobal].RPM)
+}
+
+func TestStore_PropagatesChanges(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	newStore := func() *Store {
+		return NewStore(&StoreConfig{
+			Redis:      client,
+			Logger:     zap.NewNop(),
+			RateLimits: config.RateLimitConfig{Enabled: true, GlobalRPM: 100},
+		})
+	}
+	writer, replica := newStore(), newStore()
+	defer replica.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replica.subscribe(ctx)
+
+	updated := RateLimits{Enabled: true, Scopes: map[string]RateLimit{ScopeGlobal: {RPM: 5, TPM: 1000}}}
+	value, err := json.Marshal(updated)
+	require.NoError(t, err)
+	updatedAt := time.Now()
+
+	// Publish until the replica's subscription is up
+	require.Eventually(t, func() bool {
+		writer.publish(ctx, change{Key: rateLimitsKey, Value: value, UpdatedAt: updatedAt})
+		return replica.RateLimits().Limit(ScopeGlobal).RPM == 5
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 1000, replica.RateLimits().Limit(ScopeChatCompletions).TPM)
+
+	// A delayed older change does not undo a newer one
+	stale, err := json.Marshal(RateLimits{Scopes: map[string]RateLimit{ScopeGlobal: {RPM: 1}}})
+	require.NoError(t, err)
+	replica.apply(rateLimitsKey, stale, updatedAt.Add(-time.Minute))
+	assert.Equal(t, 5, replica.RateLimits().Limit(ScopeGlobal).RPM)
+}
@@ -0,0 +1,400 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Credits service errors
+var (
+	ErrStripeNotConfigured = errors.New("stripe is not configured")
+	ErrAmountTooSmall      = errors.New("amount is below the minimum purchase")
+)
+
+// Stripe events the webhook reconciles
+const (
+	eventCheckoutCompleted      = "checkout.session.completed"
+	eventCheckoutAsyncSucceeded = "checkout.session.async_payment_succeeded"
+	eventChargeRefunded         = "charge.refunded"
+)
+
+type cachedBalance struct {
+	balance   float64
+	expiresAt time.Time
+}
+
+// Service manages prepaid team credits: the balance checked before requests,
+// usage debits from the usage worker and Stripe purchases and refunds
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	cfg    config.CreditsConfig
+	stripe *StripeClient
+
+	mu       sync.Mutex
+	balances map[uuid.UUID]cachedBalance
+	now      func() time.Time
+}
+
+// NewService creates a new credits Service. Stripe purchases are available
+// when a Stripe secret key is configured.
+func NewService(db *gorm.DB, logger *zap.Logger, cfg config.CreditsConfig) *Service {
+	if cfg.Currency == "" {
+		cfg.Currency = "usd"
+	}
+	if cfg.BalanceCacheTTL <= 0 {
+		cfg.BalanceCacheTTL = 10 * time.Second
+	}
+
+	s := &Service{
+		db:       db,
+		logger:   logger,
+		cfg:      cfg,
+		balances: make(map[uuid.UUID]cachedBalance),
+		now:      time.Now,
+	}
+	if cfg.StripeSecretKey != "" {
+		s.stripe = NewStripeClient(cfg.StripeSecretKey, cfg.StripeWebhookSecret)
+	}
+	return s
+}
+
+// Allowed reports whether a team may send requests: its balance plus the
+// grace amount must be above zero. Teams without a credit account have a
+// zero balance. Balances are cached for a few seconds, so a team may overrun
+// the grace amount by the requests of that window.
+func (s *Service) Allowed(ctx context.Context, teamID uuid.UUID) (bool, float64, error) {
+	balance, err := s.balance(ctx, teamID)
+	if err != nil {
+		return false, 0, err
+	}
+	return balance+s.cfg.GraceAmount > 0, balance, nil
+}
+
+func (s *Service) balance(ctx context.Context, teamID uuid.UUID) (float64, error) {
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.balances[teamID]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.balance, nil
+	}
+
+	account, err := s.GetAccount(ctx, teamID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.balances[teamID] = cachedBalance{balance: account.Balance, expiresAt: now.Add(s.cfg.BalanceCacheTTL)}
+	s.mu.Unlock()
+	return account.Balance, nil
+}
+
+func (s *Service) invalidate(teamID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.balances, teamID)
+	s.mu.Unlock()
+}
+
+// GetAccount returns the credit account of a team, or an empty one when the
+// team has none yet
+func (s *Service) GetAccount(ctx context.Context, teamID uuid.UUID) (*models.CreditAccount, error) {
+	var account models.CreditAccount
+	err := s.db.WithContext(ctx).Where("team_id = ?", teamID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.CreditAccount{TeamID: teamID, Currency: s.cfg.Currency}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credit account: %w", err)
+	}
+	return &account, nil
+}
+
+// ListTransactions returns the most recent ledger entries of a team
+func (s *Service) ListTransactions(ctx context.Context, teamID uuid.UUID, limit int) ([]models.CreditTransaction, error) {
+	var transactions []models.CreditTransaction
+	err := s.db.WithContext(ctx).
+		Where("team_id = ?", teamID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// Adjust credits (positive amount) or debits (negative amount) a team by
+// hand, e.g. for promotional credit or a support refund
+func (s *Service) Adjust(ctx context.Context, teamID uuid.UUID, amount float64, description string, createdBy *uuid.UUID) (*models.CreditTransaction, error) {
+	entry := &models.CreditTransaction{
+		TeamID:      teamID,
+		Type:        models.CreditTransactionAdjustment,
+		Amount:      amount,
+		Description: description,
+		CreatedBy:   createdBy,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.apply(tx, entry, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(teamID)
+	return entry, nil
+}
+
+// ChargeUsage debits the usage cost of a batch from the teams' balances,
+// with one ledger entry per team. It runs in the usage worker's transaction
+// so usage is debited exactly once.
+func (s *Service) ChargeUsage(tx *gorm.DB, teamCosts map[uuid.UUID]float64) error {
+	for teamID, cost := range teamCosts {
+		if cost == 0 {
+			continue
+		}
+		entry := &models.CreditTransaction{
+			TeamID:      teamID,
+			Type:        models.CreditTransactionUsage,
+			Amount:      -cost,
+			Description: "Usage",
+		}
+		if err := s.apply(tx, entry, ""); err != nil {
+			return err
+		}
+	}
+	for teamID := range teamCosts {
+		s.invalidate(teamID)
+	}
+	return nil
+}
+
+// apply adds an entry's amount to the team balance, creating the account on
+// first use, and records the entry with the resulting balance
+func (s *Service) apply(tx *gorm.DB, entry *models.CreditTransaction, customerID string) error {
+	account := models.CreditAccount{
+		TeamID:           entry.TeamID,
+		Balance:          entry.Amount,
+		Currency:         s.cfg.Currency,
+		StripeCustomerID: customerID,
+	}
+	updates := map[string]interface{}{
+		"balance":    gorm.Expr("credit_accounts.balance + ?", entry.Amount),
+		"updated_at": s.now(),
+	}
+	if customerID != "" {
+		updates["stripe_customer_id"] = customerID
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "team_id"}},
+		DoUpdates: clause.Assignments(updates),
+	}).Create(&account).Error; err != nil {
+		return fmt.Errorf("failed to update credit balance: %w", err)
+	}
+
+	var balance float64
+	if err := tx.Model(&models.CreditAccount{}).
+		Where("team_id = ?", entry.TeamID).
+		Pluck("balance", &balance).Error; err != nil {
+		return fmt.Errorf("failed to read credit balance: %w", err)
+	}
+	entry.Balance = balance
+
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to record credit transaction: %w", err)
+	}
+	return nil
+}
+
+// Checkout is a pending credit purchase
+type Checkout struct {
+	SessionID string  `json:"session_id"`
+	URL       string  `json:"url"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+}
+
+// CreateCheckout starts a Stripe Checkout purchase of amount credits for a
+// team. The balance is credited by the webhook once the payment succeeds.
+func (s *Service) CreateCheckout(ctx context.Context, teamID uuid.UUID, amount float64) (*Checkout, error) {
+	if s.stripe == nil {
+		return nil, ErrStripeNotConfigured
+	}
+	if amount < s.cfg.MinPurchase || amount <= 0 {
+		return nil, ErrAmountTooSmall
+	}
+
+	account, err := s.GetAccount(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.stripe.CreateCheckoutSession(ctx, CheckoutRequest{
+		TeamID:      teamID.String(),
+		Amount:      amount,
+		Currency:    s.cfg.Currency,
+		CustomerID:  account.StripeCustomerID,
+		SuccessURL:  s.cfg.SuccessURL,
+		CancelURL:   s.cfg.CancelURL,
+		Description: "pllm credits",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checkout{
+		SessionID: session.ID,
+		URL:       session.URL,
+		Amount:    amount,
+		Currency:  s.cfg.Currency,
+	}, nil
+}
+
+// HandleWebhook verifies and applies a Stripe webhook delivery. Paid
+// checkout sessions credit the team and refunded charges debit it. Every
+// payment is applied once, however often Stripe delivers its events.
+func (s *Service) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	if s.stripe == nil {
+		return ErrStripeNotConfigured
+	}
+	event, err := s.stripe.ConstructEvent(payload, signature, s.now())
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case eventCheckoutCompleted, eventCheckoutAsyncSucceeded:
+		var session CheckoutSession
+		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+			return fmt.Errorf("invalid checkout session: %w", err)
+		}
+		return s.applyCheckout(ctx, &session)
+	case eventChargeRefunded:
+		var charge Charge
+		if err := json.Unmarshal(event.Data.Object, &charge); err != nil {
+			return fmt.Errorf("invalid charge: %w", err)
+		}
+		return s.applyRefund(ctx, &charge)
+	default:
+		s.logger.Debug("Ignoring Stripe event", zap.String("type", event.Type), zap.String("id", event.ID))
+		return nil
+	}
+}
+
+func (s *Service) applyCheckout(ctx context.Context, session *CheckoutSession) error {
+	// Sessions paid by delayed methods complete unpaid and are applied on
+	// async_payment_succeeded
+	if session.PaymentStatus != "paid" {
+		return nil
+	}
+
+	teamRef := session.ClientReferenceID
+	if teamRef == "" {
+		teamRef = session.Metadata["team_id"]
+	}
+	teamID, err := uuid.Parse(teamRef)
+	if err != nil {
+		s.logger.Warn("Ignoring checkout session without a team", zap.String("session", session.ID))
+		return nil
+	}
+
+	reference := "checkout:" + session.ID
+	entry := &models.CreditTransaction{
+		TeamID:        teamID,
+		Type:          models.CreditTransactionPurchase,
+		Amount:        fromMinorUnits(session.AmountTotal),
+		Description:   "Stripe credit purchase",
+		Reference:     &reference,
+		PaymentIntent: session.PaymentIntent,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if exists, err := referenceExists(tx, reference); err != nil || exists {
+			return err
+		}
+		return s.apply(tx, entry, session.Customer)
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate(teamID)
+
+	s.logger.Info("Credited Stripe purchase",
+		zap.String("team_id", teamID.String()),
+		zap.String("session", session.ID),
+		zap.Float64("amount", entry.Amount))
+	return nil
+}
+
+// applyRefund debits the part of a charge refunded since the last refund
+// event. Stripe reports the cumulative refunded amount, so each partial
+// refund is recorded under its own running total.
+func (s *Service) applyRefund(ctx context.Context, charge *Charge) error {
+	if charge.PaymentIntent == "" {
+		return nil
+	}
+
+	var teamID uuid.UUID
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var purchase models.CreditTransaction
+		err := tx.Where("payment_intent = ? AND type = ?", charge.PaymentIntent, models.CreditTransactionPurchase).
+			First(&purchase).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("Ignoring refund of an unknown payment", zap.String("charge", charge.ID))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		reference := fmt.Sprintf("refund:%s:%d", charge.ID, charge.AmountRefunded)
+		if exists, err := referenceExists(tx, reference); err != nil || exists {
+			return err
+		}
+
+		var refunded float64
+		if err := tx.Model(&models.CreditTransaction{}).
+			Where("payment_intent = ? AND type = ?", charge.PaymentIntent, models.CreditTransactionRefund).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&refunded).Error; err != nil {
+			return err
+		}
+
+		amount := fromMinorUnits(charge.AmountRefunded) + refunded // refunded is negative
+		if amount <= 0 {
+			return nil
+		}
+		entry := &models.CreditTransaction{
+			TeamID:        purchase.TeamID,
+			Type:          models.CreditTransactionRefund,
+			Amount:        -amount,
+			Description:   "Stripe refund",
+			Reference:     &reference,
+			PaymentIntent: charge.PaymentIntent,
+		}
+		teamID = purchase.TeamID
+		return s.apply(tx, entry, "")
+	})
+	if err != nil {
+		return err
+	}
+	if teamID != uuid.Nil {
+		s.invalidate(teamID)
+	}
+	return nil
+}
+
+func referenceExists(tx *gorm.DB, reference string) (bool, error) {
+	var count int64
+	err := tx.Model(&models.CreditTransaction{}).Where("reference = ?", reference).Count(&count).Error
+	return count > 0, err
+}
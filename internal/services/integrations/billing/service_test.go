@@ -0,0 +1,99 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+)
+
+func TestService_Credits(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	service := NewService(db, zap.NewNop(), config.CreditsConfig{
+		StripeSecretKey:     "sk_test",
+		StripeWebhookSecret: "whsec_test",
+		GraceAmount:         1,
+	})
+	teamID := uuid.New()
+
+	deliver := func(eventType, object string) error {
+		payload := []byte(fmt.Sprintf(`{"id":"evt_%s","type":%q,"data":{"object":%s}}`, uuid.NewString(), eventType, object))
+		return service.HandleWebhook(ctx, payload, sign(payload, "whsec_test", time.Now()))
+	}
+	balance := func() float64 {
+		account, err := service.GetAccount(ctx, teamID)
+		require.NoError(t, err)
+		return account.Balance
+	}
+
+	t.Run("no account", func(t *testing.T) {
+		allowed, current, err := service.Allowed(ctx, teamID)
+		require.NoError(t, err)
+		assert.True(t, allowed, "the grace amount covers a zero balance")
+		assert.Zero(t, current)
+	})
+
+	t.Run("purchase is applied once", func(t *testing.T) {
+		session := fmt.Sprintf(`{"id":"cs_1","payment_status":"paid","amount_total":2000,"customer":"cus_1",
+			"payment_intent":"pi_1","client_reference_id":%q}`, teamID)
+		require.NoError(t, deliver(eventCheckoutCompleted, session))
+		require.NoError(t, deliver(eventCheckoutCompleted, session))
+		assert.Equal(t, 20.0, balance())
+
+		account, err := service.GetAccount(ctx, teamID)
+		require.NoError(t, err)
+		assert.Equal(t, "cus_1", account.StripeCustomerID)
+	})
+
+	t.Run("unpaid sessions are not credited", func(t *testing.T) {
+		session := fmt.Sprintf(`{"id":"cs_2","payment_status":"unpaid","amount_total":5000,"client_reference_id":%q}`, teamID)
+		require.NoError(t, deliver(eventCheckoutCompleted, session))
+		assert.Equal(t, 20.0, balance())
+	})
+
+	t.Run("usage is debited", func(t *testing.T) {
+		require.NoError(t, service.ChargeUsage(db, map[uuid.UUID]float64{teamID: 20.5}))
+		assert.Equal(t, -0.5, balance())
+
+		allowed, _, err := service.Allowed(ctx, teamID)
+		require.NoError(t, err)
+		assert.True(t, allowed, "within the grace amount")
+
+		require.NoError(t, service.ChargeUsage(db, map[uuid.UUID]float64{teamID: 0.5}))
+		allowed, _, err = service.Allowed(ctx, teamID)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	})
+
+	t.Run("partial refunds", func(t *testing.T) {
+		require.NoError(t, deliver(eventChargeRefunded, `{"id":"ch_1","payment_intent":"pi_1","amount_refunded":500}`))
+		require.NoError(t, deliver(eventChargeRefunded, `{"id":"ch_1","payment_intent":"pi_1","amount_refunded":500}`))
+		assert.Equal(t, -6.0, balance())
+
+		require.NoError(t, deliver(eventChargeRefunded, `{"id":"ch_1","payment_intent":"pi_1","amount_refunded":800}`))
+		assert.Equal(t, -9.0, balance())
+	})
+
+	t.Run("adjustments", func(t *testing.T) {
+		entry, err := service.Adjust(ctx, teamID, 19, "Promotional credit", nil)
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, entry.Balance)
+
+		transactions, err := service.ListTransactions(ctx, teamID, 10)
+		require.NoError(t, err)
+		assert.Len(t, transactions, 6)
+		assert.Equal(t, models.CreditTransactionAdjustment, transactions[0].Type)
+	})
+}
@@ -0,0 +1,222 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stripeAPIBase = "https://api.stripe.com/v1"
+
+// signatureTolerance is how old a webhook timestamp may be before the
+// delivery is treated as a replay
+const signatureTolerance = 5 * time.Minute
+
+// Stripe webhook errors
+var (
+	ErrMissingSignature = errors.New("missing Stripe-Signature header")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleSignature   = errors.New("webhook timestamp outside the tolerance")
+)
+
+// StripeClient is the subset of the Stripe API the credits service uses:
+// Checkout Sessions and webhook signature verification
+type StripeClient struct {
+	secretKey     string
+	webhookSecret string
+	baseURL       string
+	httpClient    *http.Client
+}
+
+// NewStripeClient creates a new StripeClient.
+func NewStripeClient(secretKey, webhookSecret string) *StripeClient {
+	return &StripeClient{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		baseURL:       stripeAPIBase,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// CheckoutRequest describes a one-off credit purchase
+type CheckoutRequest struct {
+	TeamID      string
+	Amount      float64 // In currency units, e.g. dollars
+	Currency    string
+	CustomerID  string // Optional, reuses the team's Stripe customer
+	SuccessURL  string
+	CancelURL   string
+	Description string
+}
+
+// CheckoutSession is a Stripe Checkout Session
+type CheckoutSession struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	Customer          string            `json:"customer"`
+	PaymentIntent     string            `json:"payment_intent"`
+	PaymentStatus     string            `json:"payment_status"`
+	AmountTotal       int64             `json:"amount_total"`
+	Currency          string            `json:"currency"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// Charge is the part of a Stripe charge used to reconcile refunds
+type Charge struct {
+	ID             string            `json:"id"`
+	PaymentIntent  string            `json:"payment_intent"`
+	AmountRefunded int64             `json:"amount_refunded"`
+	Currency       string            `json:"currency"`
+	Metadata       map[string]string `json:"metadata"`
+}
+
+// Event is a Stripe webhook event
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// CreateCheckoutSession creates a payment-mode Checkout Session for a credit
+// purchase. The team ID travels in client_reference_id and in the payment
+// metadata so the webhook can credit the right team.
+func (c *StripeClient) CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", req.SuccessURL)
+	form.Set("cancel_url", req.CancelURL)
+	form.Set("client_reference_id", req.TeamID)
+	form.Set("metadata[team_id]", req.TeamID)
+	form.Set("payment_intent_data[metadata][team_id]", req.TeamID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", req.Currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(toMinorUnits(req.Amount), 10))
+	form.Set("line_items[0][price_data][product_data][name]", req.Description)
+	if req.CustomerID != "" {
+		form.Set("customer", req.CustomerID)
+	} else {
+		form.Set("customer_creation", "always")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/checkout/sessions",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetBasicAuth(c.secretKey, "")
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stripe response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("stripe returned %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("stripe returned %d", resp.StatusCode)
+	}
+
+	var session CheckoutSession
+	if err := json.Unmarshal(body, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode checkout session: %w", err)
+	}
+	return &session, nil
+}
+
+// ConstructEvent verifies the Stripe-Signature header of a webhook delivery
+// and decodes its event
+func (c *StripeClient) ConstructEvent(payload []byte, signature string, now time.Time) (*Event, error) {
+	if err := verifySignature(payload, signature, c.webhookSecret, now); err != nil {
+		return nil, err
+	}
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	return &event, nil
+}
+
+// verifySignature checks a Stripe-Signature header of the form
+// "t=<unix>,v1=<hex hmac>[,v1=...]": any v1 must be the HMAC-SHA256 of
+// "<t>.<payload>" under the endpoint secret, and t must be recent
+func verifySignature(payload []byte, header, secret string, now time.Time) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return ErrStaleSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// toMinorUnits converts an amount to the smallest currency unit, e.g. cents
+func toMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// fromMinorUnits converts an amount in the smallest currency unit back
+func fromMinorUnits(amount int64) float64 {
+	return float64(amount) / 100
+}
@@ -0,0 +1,102 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sign(payload []byte, secret string, at time.Time) string {
+	timestamp := fmt.Sprintf("%d", at.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"checkout.session.completed"}`)
+	now := time.Unix(1760000000, 0)
+
+	assert.NoError(t, verifySignature(payload, sign(payload, "whsec_test", now), "whsec_test", now))
+
+	// A rotated secret is sent as a second v1 signature
+	header := sign(payload, "whsec_old", now) + "," + sign(payload, "whsec_test", now)[len("t=1760000000,"):]
+	assert.NoError(t, verifySignature(payload, header, "whsec_test", now))
+
+	assert.ErrorIs(t, verifySignature(payload, "", "whsec_test", now), ErrMissingSignature)
+	assert.ErrorIs(t, verifySignature(payload, sign(payload, "whsec_other", now), "whsec_test", now), ErrInvalidSignature)
+	assert.ErrorIs(t, verifySignature([]byte(`{"id":"evt_2"}`), sign(payload, "whsec_test", now), "whsec_test", now), ErrInvalidSignature)
+	assert.ErrorIs(t, verifySignature(payload, "t=1760000000", "whsec_test", now), ErrInvalidSignature)
+	assert.ErrorIs(t, verifySignature(payload, sign(payload, "whsec_test", now.Add(-10*time.Minute)), "whsec_test", now), ErrStaleSignature)
+}
+
+func TestStripeClient_ConstructEvent(t *testing.T) {
+	client := NewStripeClient("sk_test", "whsec_test")
+	payload := []byte(`{"id":"evt_1","type":"charge.refunded","data":{"object":{"id":"ch_1","amount_refunded":500}}}`)
+	now := time.Now()
+
+	event, err := client.ConstructEvent(payload, sign(payload, "whsec_test", now), now)
+	require.NoError(t, err)
+	assert.Equal(t, "evt_1", event.ID)
+	assert.Equal(t, "charge.refunded", event.Type)
+	assert.JSONEq(t, `{"id":"ch_1","amount_refunded":500}`, string(event.Data.Object))
+}
+
+func TestStripeClient_CreateCheckoutSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/checkout/sessions", r.URL.Path)
+		user, _, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "sk_test", user)
+
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "payment", r.PostForm.Get("mode"))
+		assert.Equal(t, "team-1", r.PostForm.Get("client_reference_id"))
+		assert.Equal(t, "team-1", r.PostForm.Get("payment_intent_data[metadata][team_id]"))
+		assert.Equal(t, "2550", r.PostForm.Get("line_items[0][price_data][unit_amount]"))
+		assert.Equal(t, "eur", r.PostForm.Get("line_items[0][price_data][currency]"))
+		assert.Equal(t, "cus_1", r.PostForm.Get("customer"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"cs_1","url":"https://checkout.stripe.com/c/pay/cs_1"}`))
+	}))
+	defer server.Close()
+
+	client := NewStripeClient("sk_test", "whsec_test")
+	client.baseURL = server.URL
+
+	session, err := client.CreateCheckoutSession(context.Background(), CheckoutRequest{
+		TeamID:     "team-1",
+		Amount:     25.5,
+		Currency:   "eur",
+		CustomerID: "cus_1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cs_1", session.ID)
+	assert.Equal(t, "https://checkout.stripe.com/c/pay/cs_1", session.URL)
+}
+
+func TestStripeClient_CreateCheckoutSessionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"Invalid currency"}}`))
+	}))
+	defer server.Close()
+
+	client := NewStripeClient("sk_test", "whsec_test")
+	client.baseURL = server.URL
+
+	_, err := client.CreateCheckoutSession(context.Background(), CheckoutRequest{TeamID: "team-1", Amount: 10})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid currency")
+}
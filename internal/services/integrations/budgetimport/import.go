@@ -0,0 +1,442 @@
+// Package budgetimport bulk-sets the budgets of teams, users and keys from a
+// CSV or JSON file, for onboarding customers migrating from other gateways
+package budgetimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Entity types a budget can be imported for
+const (
+	EntityTeam = "team"
+	EntityUser = "user"
+	EntityKey  = "key"
+)
+
+// Action is what importing a row does to an entity's budget
+type Action string
+
+const (
+	ActionCreate    Action = "create" // The entity had no budget
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+	ActionError     Action = "error"
+)
+
+// Row is one budget to import. Entity is the entity's ID, or a team's name,
+// a user's email or a key's name.
+type Row struct {
+	Line           int     `json:"line,omitempty"` // Source line, for reports
+	EntityType     string  `json:"entity_type"`
+	Entity         string  `json:"entity"`
+	MaxBudget      float64 `json:"max_budget"`
+	BudgetDuration string  `json:"budget_duration,omitempty"` // Keeps the current duration when empty
+}
+
+// Result is the outcome of one row
+type Result struct {
+	Line       int      `json:"line"`
+	EntityType string   `json:"entity_type"`
+	Entity     string   `json:"entity"`
+	EntityID   string   `json:"entity_id,omitempty"`
+	Action     Action   `json:"action"`
+	Fields     []string `json:"fields,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Report summarizes an import. Nothing is applied when a row fails
+// validation or on a dry run.
+type Report struct {
+	DryRun    bool     `json:"dry_run"`
+	Applied   bool     `json:"applied"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Failed    int      `json:"failed"`
+	Results   []Result `json:"results"`
+}
+
+// Options controls Import
+type Options struct {
+	DryRun bool // Only validate and report the changes
+}
+
+// String renders the report for the CLI
+func (r *Report) String() string {
+	var b strings.Builder
+	symbols := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionUnchanged: "=", ActionError: "!"}
+	for _, result := range r.Results {
+		fmt.Fprintf(&b, "%s line %d: %s %s", symbols[result.Action], result.Line, result.EntityType, result.Entity)
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(&b, ": %s", result.Error)
+		case len(result.Fields) > 0:
+			fmt.Fprintf(&b, " (%s)", strings.Join(result.Fields, ", "))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\n%d to create, %d to update, %d unchanged, %d failed.\n", r.Created, r.Updated, r.Unchanged, r.Failed)
+	switch {
+	case r.Failed > 0:
+		b.WriteString("Nothing was imported; fix the failed rows and retry.\n")
+	case r.DryRun:
+		b.WriteString("Dry run: nothing was imported.\n")
+	case r.Applied:
+		b.WriteString("Budgets imported.\n")
+	}
+	return b.String()
+}
+
+// Parse reads rows from a CSV or JSON file. format is "csv" or "json"; when
+// empty it is detected from the content.
+func Parse(data []byte, format string) ([]Row, error) {
+	if format == "" {
+		format = "csv"
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+			format = "json"
+		}
+	}
+
+	switch format {
+	case "csv":
+		return ParseCSV(bytes.NewReader(data))
+	case "json":
+		return ParseJSON(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q: must be csv or json", format)
+	}
+}
+
+// ParseCSV reads rows from CSV with a header row naming the entity_type,
+// entity, max_budget and, optionally, budget_duration columns
+func ParseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty CSV file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"entity_type", "entity", "max_budget"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %s column", required)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := Row{
+			Line:           line,
+			EntityType:     field("entity_type"),
+			Entity:         field("entity"),
+			BudgetDuration: field("budget_duration"),
+		}
+		if value := field("max_budget"); value != "" {
+			amount, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid max_budget %q", line, value)
+			}
+			row.MaxBudget = amount
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ParseJSON reads rows from a JSON array of rows, or an object with a
+// "budgets" array
+func ParseJSON(data []byte) ([]Row, error) {
+	var rows []Row
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Budgets []Row `json:"budgets"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		rows = wrapper.Budgets
+	} else if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	for i := range rows {
+		if rows[i].Line == 0 {
+			rows[i].Line = i + 1
+		}
+	}
+	return rows, nil
+}
+
+// entityBudget is the current budget of an entity matching a row
+type entityBudget struct {
+	ID        uuid.UUID
+	MaxBudget float64
+	Duration  string
+	ResetAt   time.Time
+}
+
+// lookupFunc returns the entities of a type an identifier matches
+type lookupFunc func(entityType, entity string) ([]entityBudget, error)
+
+// update is a validated change to an entity's budget
+type update struct {
+	entityType string
+	current    entityBudget
+	row        Row
+}
+
+// plan validates the rows against the entities' current budgets and reports
+// what importing them does. It fails only when a lookup fails.
+func plan(rows []Row, lookup lookupFunc) (*Report, []update, error) {
+	report := &Report{Results: make([]Result, 0, len(rows))}
+	var updates []update
+	seen := make(map[string]int) // entity ID -> line that set it
+
+	for _, row := range rows {
+		row.EntityType = strings.ToLower(strings.TrimSpace(row.EntityType))
+		row.Entity = strings.TrimSpace(row.Entity)
+		row.BudgetDuration = strings.ToLower(strings.TrimSpace(row.BudgetDuration))
+		result := Result{Line: row.Line, EntityType: row.EntityType, Entity: row.Entity}
+
+		fail := func(format string, args ...interface{}) {
+			result.Action = ActionError
+			result.Error = fmt.Sprintf(format, args...)
+			report.Failed++
+			report.Results = append(report.Results, result)
+		}
+
+		if err := validateRow(row); err != nil {
+			fail("%s", err)
+			continue
+		}
+		matches, err := lookup(row.EntityType, row.Entity)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to look up %s %q: %w", row.EntityType, row.Entity, err)
+		}
+		if len(matches) == 0 {
+			fail("%s not found", row.EntityType)
+			continue
+		}
+		if len(matches) > 1 {
+			fail("%d %ss match; use the ID", len(matches), row.EntityType)
+			continue
+		}
+		current := matches[0]
+		result.EntityID = current.ID.String()
+		if line, ok := seen[result.EntityID]; ok {
+			fail("%s already set on line %d", row.EntityType, line)
+			continue
+		}
+		seen[result.EntityID] = row.Line
+
+		if current.MaxBudget != row.MaxBudget {
+			result.Fields = append(result.Fields, "max_budget")
+		}
+		if row.BudgetDuration != "" && row.BudgetDuration != current.Duration {
+			result.Fields = append(result.Fields, "budget_duration")
+		}
+		switch {
+		case len(result.Fields) == 0:
+			result.Action = ActionUnchanged
+			report.Unchanged++
+		case current.MaxBudget <= 0:
+			result.Action = ActionCreate
+			report.Created++
+			updates = append(updates, update{entityType: row.EntityType, current: current, row: row})
+		default:
+			result.Action = ActionUpdate
+			report.Updated++
+			updates = append(updates, update{entityType: row.EntityType, current: current, row: row})
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, updates, nil
+}
+
+// validateRow checks a row on its own
+func validateRow(row Row) error {
+	switch row.EntityType {
+	case EntityTeam, EntityUser, EntityKey:
+	case "":
+		return errors.New("entity_type is required")
+	default:
+		return fmt.Errorf("invalid entity_type %q: must be team, user or key", row.EntityType)
+	}
+	if row.Entity == "" {
+		return errors.New("entity is required")
+	}
+	if row.MaxBudget < 0 {
+		return errors.New("max_budget must not be negative")
+	}
+	if row.BudgetDuration != "" && nextReset(models.BudgetPeriod(row.BudgetDuration), time.Time{}).IsZero() {
+		return fmt.Errorf("invalid budget_duration %q: must be daily, weekly, monthly or yearly", row.BudgetDuration)
+	}
+	return nil
+}
+
+// nextReset returns when a budget period starting at from ends, or the zero
+// time for periods without a fixed length
+func nextReset(period models.BudgetPeriod, from time.Time) time.Time {
+	switch period {
+	case models.BudgetPeriodDaily:
+		return from.AddDate(0, 0, 1)
+	case models.BudgetPeriodWeekly:
+		return from.AddDate(0, 0, 7)
+	case models.BudgetPeriodMonthly:
+		return from.AddDate(0, 1, 0)
+	case models.BudgetPeriodYearly:
+		return from.AddDate(1, 0, 0)
+	}
+	return time.Time{}
+}
+
+// Import validates the rows and, unless any fails or it is a dry run, sets
+// the budgets in one transaction. Spend is kept; budgets that are new or
+// change duration start a new period now.
+func Import(db *gorm.DB, rows []Row, opts Options) (*Report, error) {
+	report, updates, err := plan(rows, func(entityType, entity string) ([]entityBudget, error) {
+		return lookupEntity(db, entityType, entity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = opts.DryRun
+	if opts.DryRun || report.Failed > 0 || len(updates) == 0 {
+		return report, nil
+	}
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, u := range updates {
+			values := map[string]interface{}{"max_budget": u.row.MaxBudget}
+			duration := u.current.Duration
+			if u.row.BudgetDuration != "" {
+				duration = u.row.BudgetDuration
+				values["budget_duration"] = duration
+			}
+			if duration == "" {
+				duration = string(models.BudgetPeriodMonthly)
+				values["budget_duration"] = duration
+			}
+			if duration != u.current.Duration || u.current.MaxBudget <= 0 || u.current.ResetAt.IsZero() {
+				values["budget_reset_at"] = nextReset(models.BudgetPeriod(duration), now)
+			}
+
+			var model interface{}
+			switch u.entityType {
+			case EntityTeam:
+				model = &models.Team{}
+			case EntityUser:
+				model = &models.User{}
+			case EntityKey:
+				model = &models.Key{}
+			}
+			if err := tx.Model(model).Where("id = ?", u.current.ID).Updates(values).Error; err != nil {
+				return fmt.Errorf("failed to update %s %s: %w", u.entityType, u.current.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// lookupEntity finds the entities a row's identifier matches: by ID, or by
+// team name, user email or key name
+func lookupEntity(db *gorm.DB, entityType, entity string) ([]entityBudget, error) {
+	id, idErr := uuid.Parse(entity)
+	var matches []entityBudget
+
+	switch entityType {
+	case EntityTeam:
+		var teams []models.Team
+		query := db.Where("name = ?", entity)
+		if idErr == nil {
+			query = db.Where("id = ?", id)
+		}
+		if err := query.Find(&teams).Error; err != nil {
+			return nil, err
+		}
+		for _, team := range teams {
+			matches = append(matches, entityBudget{ID: team.ID, MaxBudget: team.MaxBudget,
+				Duration: string(team.BudgetDuration), ResetAt: team.BudgetResetAt})
+		}
+	case EntityUser:
+		var users []models.User
+		query := db.Where("LOWER(email) = ?", strings.ToLower(entity))
+		if idErr == nil {
+			query = db.Where("id = ?", id)
+		}
+		if err := query.Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			matches = append(matches, entityBudget{ID: user.ID, MaxBudget: user.MaxBudget,
+				Duration: string(user.BudgetDuration), ResetAt: user.BudgetResetAt})
+		}
+	case EntityKey:
+		var keys []models.Key
+		query := db.Where("name = ?", entity)
+		if idErr == nil {
+			query = db.Where("id = ?", id)
+		}
+		if err := query.Find(&keys).Error; err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			match := entityBudget{ID: key.ID}
+			if key.MaxBudget != nil {
+				match.MaxBudget = *key.MaxBudget
+			}
+			if key.BudgetDuration != nil {
+				match.Duration = string(*key.BudgetDuration)
+			}
+			if key.BudgetResetAt != nil {
+				match.ResetAt = *key.BudgetResetAt
+			}
+			matches = append(matches, match)
+		}
+	}
+
+	return matches, nil
+}
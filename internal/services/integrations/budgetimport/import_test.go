@@ -0,0 +1,110 @@
+package budgetimport
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	csvRows, err := Parse([]byte("Entity_Type, entity, max_budget, budget_duration\n"+
+		"team,research,$500,monthly\n"+
+		"user,ada@example.com,25,\n"+
+		"key,ci-key,10\n"), "")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{
+		{Line: 2, EntityType: "team", Entity: "research", MaxBudget: 500, BudgetDuration: "monthly"},
+		{Line: 3, EntityType: "user", Entity: "ada@example.com", MaxBudget: 25},
+		{Line: 4, EntityType: "key", Entity: "ci-key", MaxBudget: 10},
+	}, csvRows)
+
+	jsonRows, err := Parse([]byte(`{"budgets":[{"entity_type":"team","entity":"research","max_budget":500,"budget_duration":"monthly"}]}`), "")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{Line: 1, EntityType: "team", Entity: "research", MaxBudget: 500, BudgetDuration: "monthly"}}, jsonRows,
+		"JSON rows are numbered by position")
+
+	jsonRows, err = Parse([]byte(`[{"entity_type":"key","entity":"ci-key","max_budget":10}]`), "json")
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{Line: 1, EntityType: "key", Entity: "ci-key", MaxBudget: 10}}, jsonRows)
+
+	_, err = Parse([]byte("entity_type,entity\nteam,research\n"), "csv")
+	assert.EqualError(t, err, "CSV header is missing the max_budget column")
+
+	_, err = Parse([]byte("entity_type,entity,max_budget\nteam,research,lots\n"), "csv")
+	assert.EqualError(t, err, `line 2: invalid max_budget "lots"`)
+
+	_, err = Parse([]byte("{}"), "yaml")
+	assert.EqualError(t, err, `unsupported format "yaml": must be csv or json`)
+}
+
+func TestPlan(t *testing.T) {
+	research := entityBudget{ID: uuid.New(), MaxBudget: 100, Duration: "monthly"}
+	ada := entityBudget{ID: uuid.New()}
+	ciKey := entityBudget{ID: uuid.New(), MaxBudget: 10, Duration: "daily"}
+	lookup := func(entityType, entity string) ([]entityBudget, error) {
+		switch entityType + ":" + entity {
+		case "team:research", "team:" + research.ID.String():
+			return []entityBudget{research}, nil
+		case "user:ada@example.com":
+			return []entityBudget{ada}, nil
+		case "key:ci-key":
+			return []entityBudget{ciKey}, nil
+		case "key:shared":
+			return []entityBudget{{ID: uuid.New()}, {ID: uuid.New()}}, nil
+		}
+		return nil, nil
+	}
+
+	report, updates, err := plan([]Row{
+		{Line: 2, EntityType: "Team", Entity: " research ", MaxBudget: 500},
+		{Line: 3, EntityType: "user", Entity: "ada@example.com", MaxBudget: 25, BudgetDuration: "weekly"},
+		{Line: 4, EntityType: "key", Entity: "ci-key", MaxBudget: 10, BudgetDuration: "daily"},
+	}, lookup)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Line: 2, EntityType: "team", Entity: "research", EntityID: research.ID.String(), Action: ActionUpdate, Fields: []string{"max_budget"}},
+		{Line: 3, EntityType: "user", Entity: "ada@example.com", EntityID: ada.ID.String(), Action: ActionCreate,
+			Fields: []string{"max_budget", "budget_duration"}},
+		{Line: 4, EntityType: "key", Entity: "ci-key", EntityID: ciKey.ID.String(), Action: ActionUnchanged},
+	}, report.Results)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Zero(t, report.Failed)
+	assert.Len(t, updates, 2)
+
+	report, _, err = plan([]Row{
+		{Line: 1, EntityType: "org", Entity: "acme", MaxBudget: 1},
+		{Line: 2, EntityType: "team", MaxBudget: 1},
+		{Line: 3, EntityType: "team", Entity: "research", MaxBudget: -1},
+		{Line: 4, EntityType: "team", Entity: "research", MaxBudget: 1, BudgetDuration: "hourly"},
+		{Line: 5, EntityType: "team", Entity: "missing", MaxBudget: 1},
+		{Line: 6, EntityType: "key", Entity: "shared", MaxBudget: 1},
+		{Line: 7, EntityType: "team", Entity: "research", MaxBudget: 1},
+		{Line: 8, EntityType: "team", Entity: research.ID.String(), MaxBudget: 2},
+	}, lookup)
+	require.NoError(t, err)
+	var errs []string
+	for _, result := range report.Results {
+		errs = append(errs, result.Error)
+	}
+	assert.Equal(t, []string{
+		`invalid entity_type "org": must be team, user or key`,
+		"entity is required",
+		"max_budget must not be negative",
+		`invalid budget_duration "hourly": must be daily, weekly, monthly or yearly`,
+		"team not found",
+		"2 keys match; use the ID",
+		"",
+		"team already set on line 7",
+	}, errs)
+	assert.Equal(t, 7, report.Failed)
+	assert.Contains(t, report.String(), "Nothing was imported")
+
+	_, _, err = plan([]Row{{Line: 1, EntityType: "team", Entity: "research", MaxBudget: 1}},
+		func(string, string) ([]entityBudget, error) { return nil, errors.New("connection refused") })
+	assert.EqualError(t, err, `failed to look up team "research": connection refused`)
+}
@@ -0,0 +1,78 @@
+package byok
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// minSecretLength is the shortest encryption key accepted
+const minSecretLength = 32
+
+// keyCipher seals team provider keys with AES-256-GCM. Each team has its own
+// encryption key, derived from the gateway secret and the team ID, and a
+// sealed key only opens for the team and provider type it was sealed for.
+type keyCipher struct {
+	secret []byte
+}
+
+func newKeyCipher(secret string) (*keyCipher, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("byok encryption key must be at least %d characters", minSecretLength)
+	}
+	return &keyCipher{secret: []byte(secret)}, nil
+}
+
+func (c *keyCipher) aead(teamID uuid.UUID) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, c.secret, teamID[:], "pllm team provider key", 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts an API key and returns the nonce and ciphertext, base64 encoded
+func (c *keyCipher) seal(teamID uuid.UUID, providerType, apiKey string) (string, error) {
+	aead, err := c.aead(teamID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(apiKey), []byte(providerType))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a key sealed for the team and provider type
+func (c *keyCipher) open(teamID uuid.UUID, providerType, encrypted string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode key: %w", err)
+	}
+	aead, err := c.aead(teamID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted key is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	apiKey, err := aead.Open(nil, nonce, ciphertext, []byte(providerType))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt key: %w", err)
+	}
+	return string(apiKey), nil
+}
@@ -0,0 +1,40 @@
+package byok
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyCipher(t *testing.T) {
+	_, err := newKeyCipher("too-short")
+	assert.EqualError(t, err, "byok encryption key must be at least 32 characters")
+
+	c, err := newKeyCipher("0123456789abcdef0123456789abcdef")
+	require.NoError(t, err)
+	teamID := uuid.New()
+
+	sealed, err := c.seal(teamID, "openai", "sk-team-secret")
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "sk-team-secret")
+
+	again, err := c.seal(teamID, "openai", "sk-team-secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every seal uses a fresh nonce")
+
+	opened, err := c.open(teamID, "openai", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-team-secret", opened)
+
+	_, err = c.open(uuid.New(), "openai", sealed)
+	assert.Error(t, err, "another team's key does not open it")
+	_, err = c.open(teamID, "anthropic", sealed)
+	assert.Error(t, err, "the key is bound to its provider type")
+
+	other, err := newKeyCipher("fedcba9876543210fedcba9876543210")
+	require.NoError(t, err)
+	_, err = other.open(teamID, "openai", sealed)
+	assert.Error(t, err, "another gateway secret does not open it")
+}
@@ -0,0 +1,406 @@
+package byok
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Team provider key errors
+var (
+	ErrKeyNotFound         = errors.New("provider key not found")
+	ErrUnsupportedProvider = errors.New("provider type does not support team keys")
+	ErrAPIKeyRequired      = errors.New("api_key is required")
+	ErrBaseURLRequired     = errors.New("base_url is required for this provider type")
+)
+
+// supportedProviders are the provider types authenticated by a single API
+// key, mapped to whether the key belongs to a deployment of its own whose
+// endpoint must be given with it
+var supportedProviders = map[string]bool{
+	"openai":        false,
+	"anthropic":     false,
+	"openrouter":    false,
+	"azure":         true,
+	"azure_mistral": true,
+	"dashscope":     false,
+	"perplexity":    false,
+}
+
+// healthCheckTimeout bounds each key's health check
+const healthCheckTimeout = 10 * time.Second
+
+// ProviderFactory creates a provider from its parameters; the model
+// manager's CreateProvider
+type ProviderFactory func(cfg config.ProviderParams) (providers.Provider, error)
+
+// KeyUsage is the traffic a team sent with one of its provider keys
+type KeyUsage struct {
+	ProviderKeyID uuid.UUID `json:"provider_key_id"`
+	Requests      int64     `json:"requests"`
+	Tokens        int64     `json:"tokens"`
+	Cost          float64   `json:"cost"`
+}
+
+type cachedKeys struct {
+	keys      map[string]models.TeamProviderKey // By provider type
+	providers map[string]providers.Provider     // By key and instance ID
+	expiresAt time.Time
+}
+
+// Service manages the provider API keys teams bring for their own traffic:
+// it stores them encrypted, resolves the provider a team's request is sent
+// with and periodically checks that the keys still work
+type Service struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	cfg     config.BYOKConfig
+	cipher  *keyCipher
+	factory ProviderFactory
+
+	mu     sync.Mutex
+	teams  map[uuid.UUID]*cachedKeys
+	now    func() time.Time
+	stopCh chan struct{}
+}
+
+// NewService creates a new team provider key Service. It fails when the
+// encryption key is missing or too short.
+func NewService(db *gorm.DB, logger *zap.Logger, cfg config.BYOKConfig, factory ProviderFactory) (*Service, error) {
+	keyCipher, err := newKeyCipher(cfg.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 30 * time.Second
+	}
+
+	return &Service{
+		db:      db,
+		logger:  logger,
+		cfg:     cfg,
+		cipher:  keyCipher,
+		factory: factory,
+		teams:   make(map[uuid.UUID]*cachedKeys),
+		now:     time.Now,
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// Register stores a team's API key for a provider type, replacing the key
+// registered before, and checks it right away. The stored key is returned
+// with the check's result; a failing key is still stored.
+func (s *Service) Register(ctx context.Context, teamID uuid.UUID, providerType, apiKey, baseURL string) (*models.TeamProviderKey, error) {
+	providerType = strings.ToLower(strings.TrimSpace(providerType))
+	apiKey = strings.TrimSpace(apiKey)
+	baseURL = strings.TrimSpace(baseURL)
+
+	needsBaseURL, ok := supportedProviders[providerType]
+	if !ok {
+		return nil, ErrUnsupportedProvider
+	}
+	if apiKey == "" {
+		return nil, ErrAPIKeyRequired
+	}
+	if needsBaseURL && baseURL == "" {
+		return nil, ErrBaseURLRequired
+	}
+
+	encrypted, err := s.cipher.seal(teamID, providerType, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var key models.TeamProviderKey
+	err = s.db.WithContext(ctx).Where("team_id = ? AND provider_type = ?", teamID, providerType).First(&key).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		key = models.TeamProviderKey{TeamID: teamID, ProviderType: providerType}
+	case err != nil:
+		return nil, fmt.Errorf("failed to load provider key: %w", err)
+	}
+	key.EncryptedKey = encrypted
+	key.KeyHint = keyHint(apiKey)
+	key.BaseURL = baseURL
+	key.IsActive = true
+	key.Healthy = true
+	key.LastCheckedAt = nil
+	key.LastError = ""
+	if err := s.db.WithContext(ctx).Save(&key).Error; err != nil {
+		return nil, fmt.Errorf("failed to save provider key: %w", err)
+	}
+	s.invalidate(teamID)
+
+	if err := s.Check(ctx, &key); err != nil {
+		s.logger.Warn("Registered team provider key failed its health check",
+			zap.String("team_id", teamID.String()),
+			zap.String("provider_type", providerType),
+			zap.Error(err))
+	}
+	return &key, nil
+}
+
+// List returns the provider keys of a team
+func (s *Service) List(ctx context.Context, teamID uuid.UUID) ([]models.TeamProviderKey, error) {
+	var keys []models.TeamProviderKey
+	if err := s.db.WithContext(ctx).Where("team_id = ?", teamID).Order("provider_type ASC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list provider keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Get returns a team's key for a provider type
+func (s *Service) Get(ctx context.Context, teamID uuid.UUID, providerType string) (*models.TeamProviderKey, error) {
+	var key models.TeamProviderKey
+	err := s.db.WithContext(ctx).Where("team_id = ? AND provider_type = ?", teamID, providerType).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load provider key: %w", err)
+	}
+	return &key, nil
+}
+
+// SetActive pauses or resumes a team's key; the team's traffic uses the
+// shared credentials while its key is paused
+func (s *Service) SetActive(ctx context.Context, teamID uuid.UUID, providerType string, active bool) (*models.TeamProviderKey, error) {
+	key, err := s.Get(ctx, teamID, providerType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(key).Update("is_active", active).Error; err != nil {
+		return nil, fmt.Errorf("failed to update provider key: %w", err)
+	}
+	key.IsActive = active
+	s.invalidate(teamID)
+	return key, nil
+}
+
+// Delete removes a team's key for a provider type. The row is deleted for
+// good so no copy of the key is kept.
+func (s *Service) Delete(ctx context.Context, teamID uuid.UUID, providerType string) error {
+	result := s.db.WithContext(ctx).Unscoped().Where("team_id = ? AND provider_type = ?", teamID, providerType).Delete(&models.TeamProviderKey{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete provider key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrKeyNotFound
+	}
+	s.invalidate(teamID)
+	return nil
+}
+
+// Usage returns the traffic a team sent with each of its provider keys since
+// the given time. This cost was billed by the provider to the team's own
+// account rather than to the gateway.
+func (s *Service) Usage(ctx context.Context, teamID uuid.UUID, since time.Time) ([]KeyUsage, error) {
+	var usage []KeyUsage
+	err := s.db.WithContext(ctx).Model(&models.Usage{}).
+		Select("provider_key_id, SUM(sample_rate) AS requests, SUM(total_tokens * sample_rate) AS tokens, SUM(total_cost * sample_rate) AS cost").
+		Where("team_id = ? AND provider_key_id IS NOT NULL AND timestamp >= ?", teamID, since).
+		Group("provider_key_id").
+		Scan(&usage).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum provider key usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ProviderFor returns the provider for a team's request on an instance when
+// the team has an active key for the instance's provider type. The key
+// replaces the instance's credentials; its base URL, when set, replaces the
+// instance's endpoint. Failures to load the team's keys fall back to the
+// shared credentials.
+func (s *Service) ProviderFor(ctx context.Context, teamID uuid.UUID, instance config.ModelInstance) (providers.Provider, uuid.UUID, bool) {
+	cached, err := s.teamKeys(ctx, teamID)
+	if err != nil {
+		s.logger.Warn("Failed to load team provider keys; using shared credentials",
+			zap.String("team_id", teamID.String()),
+			zap.Error(err))
+		return nil, uuid.Nil, false
+	}
+
+	key, ok := cached.keys[instance.Provider.Type]
+	if !ok {
+		return nil, uuid.Nil, false
+	}
+
+	cacheKey := key.ID.String() + ":" + instance.ID
+	s.mu.Lock()
+	provider, ok := cached.providers[cacheKey]
+	s.mu.Unlock()
+	if ok {
+		return provider, key.ID, true
+	}
+
+	provider, err = s.provider(&key, instance.Provider)
+	if err != nil {
+		s.logger.Warn("Failed to create provider for team key; using shared credentials",
+			zap.String("team_id", teamID.String()),
+			zap.String("provider_type", key.ProviderType),
+			zap.Error(err))
+		return nil, uuid.Nil, false
+	}
+
+	s.mu.Lock()
+	cached.providers[cacheKey] = provider
+	s.mu.Unlock()
+	return provider, key.ID, true
+}
+
+// teamKeys returns the active keys of a team, cached for the cache TTL so
+// changes made on other replicas apply within it
+func (s *Service) teamKeys(ctx context.Context, teamID uuid.UUID) (*cachedKeys, error) {
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.teams[teamID]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	var keys []models.TeamProviderKey
+	if err := s.db.WithContext(ctx).Where("team_id = ? AND is_active = ?", teamID, true).Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	cached = &cachedKeys{
+		keys:      make(map[string]models.TeamProviderKey, len(keys)),
+		providers: make(map[string]providers.Provider),
+		expiresAt: now.Add(s.cfg.CacheTTL),
+	}
+	for _, key := range keys {
+		cached.keys[key.ProviderType] = key
+	}
+
+	s.mu.Lock()
+	s.teams[teamID] = cached
+	s.mu.Unlock()
+	return cached, nil
+}
+
+func (s *Service) invalidate(teamID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.teams, teamID)
+	s.mu.Unlock()
+}
+
+// provider creates a provider with the instance's parameters and the team's
+// key in place of its credentials
+func (s *Service) provider(key *models.TeamProviderKey, params config.ProviderParams) (providers.Provider, error) {
+	apiKey, err := s.cipher.open(key.TeamID, key.ProviderType, key.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	params.Type = key.ProviderType
+	params.APIKey = apiKey
+	params.OAuthToken = ""
+	if key.BaseURL != "" {
+		params.BaseURL = key.BaseURL
+		params.AzureEndpoint = ""
+	}
+	return s.factory(params)
+}
+
+// Check runs the provider health check with a key and records the result
+// on it
+func (s *Service) Check(ctx context.Context, key *models.TeamProviderKey) error {
+	checkErr := func() error {
+		provider, err := s.provider(key, config.ProviderParams{})
+		if err != nil {
+			return err
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		defer cancel()
+		return provider.HealthCheck(checkCtx)
+	}()
+
+	now := s.now()
+	key.Healthy = checkErr == nil
+	key.LastCheckedAt = &now
+	key.LastError = ""
+	if checkErr != nil {
+		key.LastError = checkErr.Error()
+	}
+	if err := s.db.WithContext(ctx).Model(key).Updates(map[string]interface{}{
+		"healthy":         key.Healthy,
+		"last_checked_at": now,
+		"last_error":      key.LastError,
+	}).Error; err != nil {
+		s.logger.Warn("Failed to record team provider key health",
+			zap.String("key_id", key.ID.String()),
+			zap.Error(err))
+	}
+	return checkErr
+}
+
+// Start checks every active key at the health check interval until ctx is
+// cancelled or Stop is called. It does nothing when the interval is zero.
+func (s *Service) Start(ctx context.Context) {
+	if s.cfg.HealthCheckInterval <= 0 {
+		return
+	}
+	s.logger.Info("Starting team provider key health checks",
+		zap.Duration("interval", s.cfg.HealthCheckInterval))
+
+	ticker := time.NewTicker(s.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.checkAll(ctx)
+		}
+	}
+}
+
+// Stop stops the health checks
+func (s *Service) Stop() {
+	close(s.stopCh)
+}
+
+func (s *Service) checkAll(ctx context.Context) {
+	var keys []models.TeamProviderKey
+	if err := s.db.WithContext(ctx).Where("is_active = ?", true).Find(&keys).Error; err != nil {
+		s.logger.Warn("Failed to load team provider keys for health checks", zap.Error(err))
+		return
+	}
+	for i := range keys {
+		key := &keys[i]
+		wasHealthy := key.Healthy
+		err := s.Check(ctx, key)
+		if err != nil && wasHealthy {
+			s.logger.Warn("Team provider key failed its health check",
+				zap.String("team_id", key.TeamID.String()),
+				zap.String("provider_type", key.ProviderType),
+				zap.Error(err))
+		} else if err == nil && !wasHealthy {
+			s.logger.Info("Team provider key recovered",
+				zap.String("team_id", key.TeamID.String()),
+				zap.String("provider_type", key.ProviderType))
+		}
+	}
+}
+
+// keyHint keeps the last four characters of a key
+func keyHint(apiKey string) string {
+	if len(apiKey) <= 8 {
+		return "****"
+	}
+	return "..." + apiKey[len(apiKey)-4:]
+}
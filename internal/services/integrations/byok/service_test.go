@@ -0,0 +1,128 @@
+package byok
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// fakeProvider records the parameters it was created with; only the health
+// check is implemented
+type fakeProvider struct {
+	providers.Provider
+	params config.ProviderParams
+}
+
+func (p *fakeProvider) HealthCheck(ctx context.Context) error {
+	if p.params.APIKey == "sk-revoked" {
+		return errors.New("401 invalid api key")
+	}
+	return nil
+}
+
+func TestService_TeamProviderKeys(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	created := 0
+	service, err := NewService(db, zap.NewNop(), config.BYOKConfig{
+		EncryptionKey: "0123456789abcdef0123456789abcdef",
+		CacheTTL:      time.Hour,
+	}, func(params config.ProviderParams) (providers.Provider, error) {
+		created++
+		return &fakeProvider{params: params}, nil
+	})
+	require.NoError(t, err)
+
+	teamID := uuid.New()
+	instance := config.ModelInstance{
+		ID:       "gpt-4o-openai",
+		Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o", APIKey: "sk-shared", BaseURL: "https://api.openai.com/v1"},
+	}
+
+	t.Run("no key uses the shared credentials", func(t *testing.T) {
+		_, _, ok := service.ProviderFor(ctx, teamID, instance)
+		assert.False(t, ok)
+	})
+
+	t.Run("register validates the key", func(t *testing.T) {
+		_, err := service.Register(ctx, teamID, "bedrock", "AKIA", "")
+		assert.ErrorIs(t, err, ErrUnsupportedProvider)
+		_, err = service.Register(ctx, teamID, "azure", "azure-key", "")
+		assert.ErrorIs(t, err, ErrBaseURLRequired)
+		_, err = service.Register(ctx, teamID, "openai", " ", "")
+		assert.ErrorIs(t, err, ErrAPIKeyRequired)
+	})
+
+	key, err := service.Register(ctx, teamID, "OpenAI", "sk-team-0001", "")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", key.ProviderType)
+	assert.Equal(t, "...0001", key.KeyHint)
+	assert.True(t, key.Healthy)
+	assert.NotNil(t, key.LastCheckedAt)
+
+	t.Run("keys are stored encrypted and never returned", func(t *testing.T) {
+		keys, err := service.List(ctx, teamID)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.NotContains(t, keys[0].EncryptedKey, "sk-team-0001")
+		body, err := json.Marshal(keys[0])
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), keys[0].EncryptedKey)
+	})
+
+	t.Run("team traffic uses the team key", func(t *testing.T) {
+		provider, keyID, ok := service.ProviderFor(ctx, teamID, instance)
+		require.True(t, ok)
+		assert.Equal(t, key.ID, keyID)
+		params := provider.(*fakeProvider).params
+		assert.Equal(t, "sk-team-0001", params.APIKey)
+		assert.Equal(t, "gpt-4o", params.Model)
+		assert.Equal(t, "https://api.openai.com/v1", params.BaseURL)
+
+		before := created
+		again, _, _ := service.ProviderFor(ctx, teamID, instance)
+		assert.Same(t, provider, again)
+		assert.Equal(t, before, created, "providers are reused")
+
+		_, _, ok = service.ProviderFor(ctx, uuid.New(), instance)
+		assert.False(t, ok, "other teams keep the shared credentials")
+	})
+
+	t.Run("failing keys are recorded", func(t *testing.T) {
+		revoked, err := service.Register(ctx, teamID, "openai", "sk-revoked", "https://proxy.example.com/v1")
+		require.NoError(t, err)
+		assert.Equal(t, key.ID, revoked.ID, "the key is replaced")
+		assert.False(t, revoked.Healthy)
+		assert.Equal(t, "401 invalid api key", revoked.LastError)
+
+		provider, _, ok := service.ProviderFor(ctx, teamID, instance)
+		require.True(t, ok)
+		assert.Equal(t, "https://proxy.example.com/v1", provider.(*fakeProvider).params.BaseURL)
+	})
+
+	t.Run("paused and deleted keys stop being used", func(t *testing.T) {
+		_, err := service.SetActive(ctx, teamID, "openai", false)
+		require.NoError(t, err)
+		_, _, ok := service.ProviderFor(ctx, teamID, instance)
+		assert.False(t, ok)
+
+		require.NoError(t, service.Delete(ctx, teamID, "openai"))
+		assert.ErrorIs(t, service.Delete(ctx, teamID, "openai"), ErrKeyNotFound)
+
+		_, err = service.Register(ctx, teamID, "openai", "sk-team-0002", "")
+		assert.NoError(t, err, "a deleted key can be registered again")
+	})
+}
@@ -138,7 +138,91 @@ func (e *Executor) ExecutePostCall(ctx context.Context, request *providers.ChatR
 		RequestID: fmt.Sprintf("req_%d", time.Now().UnixNano()),
 	}
 	
-	return e.executeGuardrails(ctx, rails, input)
+	err := e.executeGuardrails(ctx, rails, input)
+	
+	// Apply any modifications (redaction, annotations) back to the original response
+	if input.Response != response {
+		*response = *input.Response.(*providers.ChatResponse)
+	}
+	
+	return err
+}
+
+// HasPostCallRails reports whether any post-call guardrails are registered
+func (e *Executor) HasPostCallRails() bool {
+	if !e.config.Enabled {
+		return false
+	}
+	
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.postCallRails) > 0
+}
+
+// CanScreenStreams reports whether every enabled post-call guardrail can
+// screen a response while it is streamed
+func (e *Executor) CanScreenStreams() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, rail := range e.postCallRails {
+		if _, ok := rail.(StreamingGuardrail); rail.IsEnabled() && !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// StreamCheckChars returns how many characters of streamed output may
+// accumulate between stream checks
+func (e *Executor) StreamCheckChars() int {
+	return e.config.StreamCheckChars
+}
+
+// ExecuteStreamCheck runs the post-call guardrails on the output streamed so
+// far, held in response. It returns a blocking GuardrailError when the stream
+// must stop.
+func (e *Executor) ExecuteStreamCheck(ctx context.Context, request *providers.ChatRequest, response *providers.ChatResponse, userID, teamID, keyID string) error {
+	if !e.config.Enabled {
+		return nil
+	}
+
+	e.mu.RLock()
+	rails := make([]Guardrail, len(e.postCallRails))
+	copy(rails, e.postCallRails)
+	e.mu.RUnlock()
+
+	input := &GuardrailInput{
+		Request:   request,
+		Response:  response,
+		UserID:    userID,
+		TeamID:    teamID,
+		KeyID:     keyID,
+		Timestamp: time.Now(),
+		RequestID: fmt.Sprintf("req_%d", time.Now().UnixNano()),
+	}
+
+	for _, rail := range rails {
+		streaming, ok := rail.(StreamingGuardrail)
+		if !ok || !rail.IsEnabled() {
+			continue
+		}
+
+		result, err := e.runGuardrail(ctx, rail, input, streaming.ExecuteStream)
+		if err != nil {
+			return err
+		}
+		if result.Blocked {
+			return &GuardrailError{
+				GuardrailName: rail.GetName(),
+				GuardrailType: rail.GetType().String(),
+				Reason:        result.Reason,
+				Details:       result.Details,
+				Blocked:       true,
+			}
+		}
+	}
+
+	return nil
 }
 
 // StartDuringCall starts during-call guardrails (async)
@@ -264,6 +348,12 @@ func (e *Executor) executeGuardrails(ctx context.Context, rails []Guardrail, inp
 
 // executeGuardrail runs a single guardrail with timeout and stats tracking
 func (e *Executor) executeGuardrail(ctx context.Context, rail Guardrail, input *GuardrailInput) (*GuardrailResult, error) {
+	return e.runGuardrail(ctx, rail, input, rail.Execute)
+}
+
+// runGuardrail runs one of a guardrail's execute functions with timeout and
+// stats tracking
+func (e *Executor) runGuardrail(ctx context.Context, rail Guardrail, input *GuardrailInput, execute func(context.Context, *GuardrailInput) (*GuardrailResult, error)) (*GuardrailResult, error) {
 	start := time.Now()
 	name := rail.GetName()
 	
@@ -272,11 +362,15 @@ func (e *Executor) executeGuardrail(ctx context.Context, rail Guardrail, input *
 	defer cancel()
 	
 	// Execute guardrail
-	result, err := rail.Execute(timeoutCtx, input)
+	result, err := execute(timeoutCtx, input)
 	executionTime := time.Since(start)
 	
 	// Update statistics
-	e.updateStats(name, executionTime, err)
+	e.updateStats(name, executionTime, result, err)
+	guardrailExecutions.WithLabelValues(name, rail.GetType().String(), rail.GetMode().String()).Inc()
+	if outcome := triggerOutcome(result); err == nil && outcome != "" {
+		guardrailTriggers.WithLabelValues(name, rail.GetType().String(), rail.GetMode().String(), outcome).Inc()
+	}
 	
 	if err != nil {
 		e.logger.Error("Guardrail execution failed",
@@ -306,7 +400,7 @@ func (e *Executor) executeGuardrail(ctx context.Context, rail Guardrail, input *
 }
 
 // updateStats updates guardrail execution statistics
-func (e *Executor) updateStats(name string, executionTime time.Duration, err error) {
+func (e *Executor) updateStats(name string, executionTime time.Duration, result *GuardrailResult, err error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	
@@ -321,9 +415,14 @@ func (e *Executor) updateStats(name string, executionTime time.Duration, err err
 	
 	if err != nil {
 		stats.TotalErrors++
+	} else if result != nil && result.Blocked {
+		stats.TotalBlocked++
 	} else {
 		stats.TotalPassed++
 	}
+	if err == nil && triggerOutcome(result) != "" {
+		stats.TotalTriggered++
+	}
 	
 	// Update average latency
 	if stats.TotalExecutions == 1 {
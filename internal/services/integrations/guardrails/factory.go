@@ -75,6 +75,12 @@ func (f *Factory) createGuardrail(railConfig config.GuardrailConfig) (Guardrail,
 		return f.createOpenAIGuardrail(railConfig)
 	case "aporia":
 		return f.createAporiaGuardrail(railConfig)
+	case "output_safety":
+		return f.createOutputSafetyGuardrail(railConfig)
+	case "prompt_injection":
+		return f.createPromptInjectionGuardrail(railConfig)
+	case "topic_policy":
+		return f.createTopicPolicyGuardrail(railConfig)
 	default:
 		return nil, fmt.Errorf("unsupported guardrail provider: %s", railConfig.Provider)
 	}
@@ -127,11 +133,153 @@ func (f *Factory) createLakeraGuardrail(railConfig config.GuardrailConfig) (Guar
 	return nil, fmt.Errorf("lakera guardrail not yet implemented")
 }
 
-// createOpenAIGuardrail creates an OpenAI content moderation guardrail
+// createOpenAIGuardrail creates an output moderation guardrail backed by the
+// OpenAI moderation API. The moderation API has no jailbreak category, so
+// jailbreak detection still uses the local classifier.
 func (f *Factory) createOpenAIGuardrail(railConfig config.GuardrailConfig) (Guardrail, error) {
-	// TODO: Implement OpenAI moderation guardrail
-	// This would use OpenAI's moderation API
-	return nil, fmt.Errorf("openai guardrail not yet implemented")
+	mode, safetyConfig, err := f.outputSafetySettings(railConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	providerConfig := f.config.Guardrails.Providers.OpenAI
+	if railConfig.APIKey != "" {
+		providerConfig.APIKey = railConfig.APIKey
+	}
+	if railConfig.APIBase != "" {
+		providerConfig.BaseURL = railConfig.APIBase
+	}
+	if railConfig.Timeout > 0 {
+		providerConfig.Timeout = railConfig.Timeout
+	}
+	model, _ := railConfig.Config["model"].(string)
+
+	jailbreak, err := providers.NewLocalSafetyClassifier(safetyConfig.Patterns, providers.CategoryJailbreak)
+	if err != nil {
+		return nil, err
+	}
+
+	return providers.NewOutputSafetyGuardrail(
+		railConfig.Name,
+		safetyConfig,
+		[]providers.SafetyClassifier{
+			providers.NewOpenAIModerationClassifier(providerConfig.APIKey, providerConfig.BaseURL, model, providerConfig.Timeout),
+			jailbreak,
+		},
+		mode,
+		railConfig.Enabled,
+		f.logger,
+	), nil
+}
+
+// createOutputSafetyGuardrail creates an output toxicity and jailbreak
+// guardrail using the local pattern classifier
+func (f *Factory) createOutputSafetyGuardrail(railConfig config.GuardrailConfig) (Guardrail, error) {
+	mode, safetyConfig, err := f.outputSafetySettings(railConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	classifier, err := providers.NewLocalSafetyClassifier(safetyConfig.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	return providers.NewOutputSafetyGuardrail(
+		railConfig.Name,
+		safetyConfig,
+		[]providers.SafetyClassifier{classifier},
+		mode,
+		railConfig.Enabled,
+		f.logger,
+	), nil
+}
+
+// outputSafetySettings parses the mode and policy shared by output screening guardrails
+func (f *Factory) outputSafetySettings(railConfig config.GuardrailConfig) (GuardrailMode, *providers.OutputSafetyConfig, error) {
+	if len(railConfig.Mode) == 0 {
+		return "", nil, fmt.Errorf("no execution modes specified for guardrail %s", railConfig.Name)
+	}
+
+	mode := ParseGuardrailMode(railConfig.Mode[0])
+	if mode != PostCall {
+		return "", nil, fmt.Errorf("guardrail %s screens responses and only supports post_call mode", railConfig.Name)
+	}
+
+	safetyConfig, err := providers.ParseOutputSafetyConfig(railConfig.Config)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return mode, safetyConfig, nil
+}
+
+// createPromptInjectionGuardrail creates a guardrail that scans tool and
+// retrieved content in prompts for injection attempts
+func (f *Factory) createPromptInjectionGuardrail(railConfig config.GuardrailConfig) (Guardrail, error) {
+	if len(railConfig.Mode) == 0 {
+		return nil, fmt.Errorf("no execution modes specified for guardrail %s", railConfig.Name)
+	}
+
+	mode := ParseGuardrailMode(railConfig.Mode[0])
+	if mode != PreCall {
+		return nil, fmt.Errorf("guardrail %s screens prompts and only supports pre_call mode", railConfig.Name)
+	}
+
+	injectionConfig, err := providers.ParsePromptInjectionConfig(railConfig.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return providers.NewPromptInjectionGuardrail(
+		railConfig.Name,
+		injectionConfig,
+		mode,
+		railConfig.Enabled,
+		f.logger,
+	)
+}
+
+// createTopicPolicyGuardrail creates a guardrail that rejects prompts by
+// topic, classified by embedding similarity to the topics' exemplars
+func (f *Factory) createTopicPolicyGuardrail(railConfig config.GuardrailConfig) (Guardrail, error) {
+	if len(railConfig.Mode) == 0 {
+		return nil, fmt.Errorf("no execution modes specified for guardrail %s", railConfig.Name)
+	}
+
+	mode := ParseGuardrailMode(railConfig.Mode[0])
+	if mode != PreCall {
+		return nil, fmt.Errorf("guardrail %s screens prompts and only supports pre_call mode", railConfig.Name)
+	}
+
+	topicConfig, err := providers.ParseTopicPolicyConfig(railConfig.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	var embedder providers.Embedder = providers.NewLocalEmbedder()
+	if topicConfig.Embedder == providers.TopicEmbedderOpenAI {
+		providerConfig := f.config.Guardrails.Providers.OpenAI
+		if railConfig.APIKey != "" {
+			providerConfig.APIKey = railConfig.APIKey
+		}
+		if railConfig.APIBase != "" {
+			providerConfig.BaseURL = railConfig.APIBase
+		}
+		if railConfig.Timeout > 0 {
+			providerConfig.Timeout = railConfig.Timeout
+		}
+		embedder = providers.NewOpenAIEmbedder(providerConfig.APIKey, providerConfig.BaseURL, topicConfig.Model, providerConfig.Timeout)
+	}
+
+	return providers.NewTopicPolicyGuardrail(
+		railConfig.Name,
+		topicConfig,
+		embedder,
+		mode,
+		railConfig.Enabled,
+		f.logger,
+	), nil
 }
 
 // createAporiaGuardrail creates an Aporia security guardrail
@@ -188,6 +336,15 @@ func (f *Factory) validateGuardrailConfig(railConfig config.GuardrailConfig) err
 		return f.validateOpenAIConfig(railConfig)
 	case "aporia":
 		return f.validateAporiaConfig(railConfig)
+	case "output_safety":
+		_, _, err := f.outputSafetySettings(railConfig)
+		return err
+	case "prompt_injection":
+		_, err := f.createPromptInjectionGuardrail(railConfig)
+		return err
+	case "topic_policy":
+		_, err := f.createTopicPolicyGuardrail(railConfig)
+		return err
 	default:
 		return fmt.Errorf("unsupported provider: %s", railConfig.Provider)
 	}
@@ -246,8 +403,11 @@ func (f *Factory) validateLakeraConfig(railConfig config.GuardrailConfig) error
 
 // validateOpenAIConfig validates OpenAI-specific configuration
 func (f *Factory) validateOpenAIConfig(railConfig config.GuardrailConfig) error {
-	// TODO: Add OpenAI-specific validation
-	return nil
+	if railConfig.APIKey == "" && f.config.Guardrails.Providers.OpenAI.APIKey == "" {
+		return fmt.Errorf("openai api_key is required")
+	}
+	_, _, err := f.outputSafetySettings(railConfig)
+	return err
 }
 
 // validateAporiaConfig validates Aporia-specific configuration
@@ -0,0 +1,41 @@
+package guardrails
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	guardrailExecutions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pllm_guardrail_executions_total",
+			Help: "Total number of guardrail executions",
+		},
+		[]string{"guardrail", "type", "mode"},
+	)
+
+	guardrailTriggers = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pllm_guardrail_triggers_total",
+			Help: "Total number of guardrail executions that flagged content, by outcome (blocked, modified, flagged)",
+		},
+		[]string{"guardrail", "type", "mode", "outcome"},
+	)
+)
+
+// triggerOutcome classifies a guardrail result for metrics and statistics.
+// An empty outcome means the guardrail passed without acting.
+func triggerOutcome(result *GuardrailResult) string {
+	switch {
+	case result == nil:
+		return ""
+	case result.Blocked:
+		return "blocked"
+	case result.Modified:
+		return "modified"
+	case !result.Passed:
+		return "flagged"
+	default:
+		return ""
+	}
+}
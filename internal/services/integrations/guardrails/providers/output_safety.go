@@ -0,0 +1,568 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/integrations/guardrails/types"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Actions taken when a response is flagged
+const (
+	OutputSafetyActionBlock    = "block"    // Reject the whole response
+	OutputSafetyActionRedact   = "redact"   // Replace flagged choices with a placeholder
+	OutputSafetyActionAnnotate = "annotate" // Pass the response through with pllm_guardrail_flags
+)
+
+// Output safety categories
+const (
+	CategoryToxicity  = "toxicity"
+	CategoryHate      = "hate"
+	CategoryViolence  = "violence"
+	CategorySelfHarm  = "self_harm"
+	CategorySexual    = "sexual"
+	CategoryJailbreak = "jailbreak"
+)
+
+// RedactedOutput replaces the content of redacted choices
+const RedactedOutput = "[Content removed by output safety guardrail]"
+
+// SafetyClassifier scores text per safety category, in the range 0-1
+type SafetyClassifier interface {
+	Classify(ctx context.Context, text string) (map[string]float64, error)
+	Name() string
+}
+
+// OutputSafetyPolicy decides what happens when a response is flagged
+type OutputSafetyPolicy struct {
+	Action     string   `json:"action"`
+	Threshold  float64  `json:"threshold"`
+	Categories []string `json:"categories,omitempty"` // Empty means all categories
+}
+
+// OutputSafetyConfig is the guardrail's config block. Team policies are keyed
+// by team ID and inherit unset fields from the default policy.
+type OutputSafetyConfig struct {
+	OutputSafetyPolicy
+	TeamPolicies map[string]OutputSafetyPolicy `json:"team_policies,omitempty"`
+	Patterns     map[string][]string           `json:"patterns,omitempty"` // Extra local classifier patterns
+}
+
+// ParseOutputSafetyConfig reads the output safety settings from a guardrail's
+// provider-specific config map
+func ParseOutputSafetyConfig(raw map[string]interface{}) (*OutputSafetyConfig, error) {
+	cfg := &OutputSafetyConfig{}
+	if len(raw) > 0 {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode output safety config: %w", err)
+		}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid output safety config: %w", err)
+		}
+	}
+
+	if cfg.Action == "" {
+		cfg.Action = OutputSafetyActionBlock
+	}
+	if cfg.Threshold == 0 {
+		cfg.Threshold = 0.5
+	}
+	if err := cfg.OutputSafetyPolicy.validate(); err != nil {
+		return nil, err
+	}
+
+	for teamID, policy := range cfg.TeamPolicies {
+		if policy.Action == "" {
+			policy.Action = cfg.Action
+		}
+		if policy.Threshold == 0 {
+			policy.Threshold = cfg.Threshold
+		}
+		if policy.Categories == nil {
+			policy.Categories = cfg.Categories
+		}
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("team %s: %w", teamID, err)
+		}
+		cfg.TeamPolicies[teamID] = policy
+	}
+
+	return cfg, nil
+}
+
+func (p OutputSafetyPolicy) validate() error {
+	switch p.Action {
+	case OutputSafetyActionBlock, OutputSafetyActionRedact, OutputSafetyActionAnnotate:
+	default:
+		return fmt.Errorf("invalid output safety action: %s", p.Action)
+	}
+	if p.Threshold <= 0 || p.Threshold > 1 {
+		return fmt.Errorf("output safety threshold must be in (0, 1], got %v", p.Threshold)
+	}
+	return nil
+}
+
+// flagged returns the sorted categories whose score reaches the threshold
+func (p OutputSafetyPolicy) flagged(scores map[string]float64) []string {
+	var categories []string
+	for category, score := range scores {
+		if score < p.Threshold {
+			continue
+		}
+		if len(p.Categories) > 0 && !containsString(p.Categories, category) {
+			continue
+		}
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// OutputSafetyGuardrail screens generated responses for toxic content and
+// signs of a successful jailbreak
+type OutputSafetyGuardrail struct {
+	name        string
+	mode        types.GuardrailMode
+	enabled     bool
+	logger      *zap.Logger
+	classifiers []SafetyClassifier
+	config      *OutputSafetyConfig
+}
+
+// NewOutputSafetyGuardrail creates a new output safety guardrail. Scores from
+// multiple classifiers are merged by taking the highest score per category.
+func NewOutputSafetyGuardrail(name string, cfg *OutputSafetyConfig, classifiers []SafetyClassifier, mode types.GuardrailMode, enabled bool, logger *zap.Logger) *OutputSafetyGuardrail {
+	return &OutputSafetyGuardrail{
+		name:        name,
+		mode:        mode,
+		enabled:     enabled,
+		logger:      logger.Named("output_safety"),
+		classifiers: classifiers,
+		config:      cfg,
+	}
+}
+
+// Execute implements the Guardrail interface
+func (g *OutputSafetyGuardrail) Execute(ctx context.Context, input *types.GuardrailInput) (*types.GuardrailResult, error) {
+	response, ok := input.Response.(*providers.ChatResponse)
+	if !ok || response == nil {
+		return &types.GuardrailResult{
+			Passed: true,
+			Reason: "No response content to analyze",
+		}, nil
+	}
+
+	policy := g.policyFor(input.TeamID)
+
+	var flags []providers.GuardrailFlag
+	maxScore := 0.0
+	for _, choice := range response.Choices {
+		text := choiceText(choice.Message.Content)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		scores, err := g.classify(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+
+		categories := policy.flagged(scores)
+		if len(categories) == 0 {
+			continue
+		}
+		for _, category := range categories {
+			maxScore = math.Max(maxScore, scores[category])
+		}
+		flags = append(flags, providers.GuardrailFlag{
+			Guardrail:   g.name,
+			ChoiceIndex: choice.Index,
+			Categories:  categories,
+			Scores:      scores,
+		})
+	}
+
+	result := &types.GuardrailResult{
+		Passed: true,
+		Details: map[string]interface{}{
+			"action":          policy.Action,
+			"flagged_choices": len(flags),
+		},
+	}
+	if len(flags) == 0 {
+		return result, nil
+	}
+
+	categories := flaggedCategories(flags)
+	result.Passed = false
+	result.Confidence = maxScore
+	result.Details["categories"] = categories
+
+	switch policy.Action {
+	case OutputSafetyActionBlock:
+		result.Blocked = true
+		result.Reason = fmt.Sprintf("response flagged for %s", strings.Join(categories, ", "))
+	case OutputSafetyActionRedact:
+		result.Modified = true
+		result.ModifiedResponse = redactChoices(response, flags)
+		result.Reason = fmt.Sprintf("response redacted for %s", strings.Join(categories, ", "))
+	case OutputSafetyActionAnnotate:
+		annotated := *response
+		annotated.GuardrailFlags = append(append([]providers.GuardrailFlag{}, response.GuardrailFlags...), flags...)
+		result.Modified = true
+		result.ModifiedResponse = &annotated
+		result.Reason = fmt.Sprintf("response annotated for %s", strings.Join(categories, ", "))
+	}
+
+	g.logger.Info("Output safety guardrail triggered",
+		zap.String("guardrail", g.name),
+		zap.String("action", policy.Action),
+		zap.Strings("categories", categories),
+		zap.String("team_id", input.TeamID),
+		zap.String("key_id", input.KeyID))
+
+	return result, nil
+}
+
+// ExecuteStream implements types.StreamingGuardrail. Streamed output that
+// was already delivered cannot be redacted, so redaction stops the stream
+// like a block; annotations cannot be attached to chunks and are skipped.
+func (g *OutputSafetyGuardrail) ExecuteStream(ctx context.Context, input *types.GuardrailInput) (*types.GuardrailResult, error) {
+	if g.policyFor(input.TeamID).Action == OutputSafetyActionAnnotate {
+		return &types.GuardrailResult{
+			Passed: true,
+			Reason: "Annotations are not applied to streamed responses",
+		}, nil
+	}
+
+	result, err := g.Execute(ctx, input)
+	if err != nil || result.Passed {
+		return result, err
+	}
+	result.Blocked = true
+	result.Modified = false
+	result.ModifiedResponse = nil
+	result.Reason = fmt.Sprintf("response flagged for %s", strings.Join(result.Details["categories"].([]string), ", "))
+	return result, nil
+}
+
+// classify runs every classifier and keeps the highest score per category
+func (g *OutputSafetyGuardrail) classify(ctx context.Context, text string) (map[string]float64, error) {
+	merged := make(map[string]float64)
+	for _, classifier := range g.classifiers {
+		scores, err := classifier.Classify(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("%s classifier failed: %w", classifier.Name(), err)
+		}
+		for category, score := range scores {
+			if score > merged[category] {
+				merged[category] = score
+			}
+		}
+	}
+	return merged, nil
+}
+
+func (g *OutputSafetyGuardrail) policyFor(teamID string) OutputSafetyPolicy {
+	if teamID != "" {
+		if policy, ok := g.config.TeamPolicies[teamID]; ok {
+			return policy
+		}
+	}
+	return g.config.OutputSafetyPolicy
+}
+
+// GetName implements the Guardrail interface
+func (g *OutputSafetyGuardrail) GetName() string {
+	return g.name
+}
+
+// GetType implements the Guardrail interface
+func (g *OutputSafetyGuardrail) GetType() types.GuardrailType {
+	return types.Moderation
+}
+
+// GetMode implements the Guardrail interface
+func (g *OutputSafetyGuardrail) GetMode() types.GuardrailMode {
+	return g.mode
+}
+
+// IsEnabled implements the Guardrail interface
+func (g *OutputSafetyGuardrail) IsEnabled() bool {
+	return g.enabled
+}
+
+// HealthCheck implements the Guardrail interface
+func (g *OutputSafetyGuardrail) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// LocalSafetyClassifier is a dependency-free pattern classifier. It is cheap
+// enough to run on every response but has lower recall than provider moderation.
+type LocalSafetyClassifier struct {
+	patterns map[string][]*regexp.Regexp
+}
+
+// defaultSafetyPatterns are matched case-insensitively against response text
+var defaultSafetyPatterns = map[string][]string{
+	CategoryToxicity: {
+		`\byou(?:'re| are) (?:an? )?(?:idiot|moron|imbecile|loser|worthless)\b`,
+		`\b(?:shut the fuck up|fuck (?:you|off))\b`,
+		`\bpiece of (?:shit|garbage)\b`,
+	},
+	CategoryHate: {
+		`\b(?:inferior|subhuman) (?:race|people|beings)\b`,
+		`\bshould (?:all )?be (?:exterminated|eradicated|wiped out)\b`,
+	},
+	CategoryViolence: {
+		`\bi (?:will|am going to|'m going to) (?:kill|hurt|murder) you\b`,
+		`\b(?:steps?|instructions?) (?:to|for) (?:build|make|assemble)(?:ing)? (?:a )?(?:pipe )?(?:bomb|explosive device)\b`,
+	},
+	CategorySelfHarm: {
+		`\b(?:you should|go) kill yourself\b`,
+		`\b(?:best|easiest|painless) ways? to (?:kill|harm) yourself\b`,
+	},
+	CategoryJailbreak: {
+		`\b(?:dan|developer|god) mode (?:enabled|activated|on)\b`,
+		`\bi(?: am|'m) (?:now )?(?:free|freed|unbound) from (?:all )?(?:my )?(?:restrictions|guidelines|rules|filters)\b`,
+		`\bas an? (?:unrestricted|unfiltered|uncensored|jailbroken) (?:ai|model|assistant)\b`,
+		`\b(?:ignoring|bypassing|disregarding) (?:all |my )?(?:previous |prior )?(?:safety )?(?:guidelines|instructions|policies|restrictions)\b`,
+		`\bjailbreak (?:successful|complete|activated)\b`,
+	},
+}
+
+// NewLocalSafetyClassifier creates a pattern classifier from the default
+// patterns plus any extra patterns. When categories are given, only those
+// categories are scored.
+func NewLocalSafetyClassifier(extra map[string][]string, categories ...string) (*LocalSafetyClassifier, error) {
+	c := &LocalSafetyClassifier{patterns: make(map[string][]*regexp.Regexp)}
+
+	add := func(category string, patterns []string) error {
+		if len(categories) > 0 && !containsString(categories, category) {
+			return nil
+		}
+		for _, pattern := range patterns {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %w", category, pattern, err)
+			}
+			c.patterns[category] = append(c.patterns[category], re)
+		}
+		return nil
+	}
+
+	for category, patterns := range defaultSafetyPatterns {
+		if err := add(category, patterns); err != nil {
+			return nil, err
+		}
+	}
+	for category, patterns := range extra {
+		if err := add(category, patterns); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Name implements SafetyClassifier
+func (c *LocalSafetyClassifier) Name() string {
+	return "local"
+}
+
+// Classify implements SafetyClassifier. Each distinct pattern hit halves the
+// remaining distance to 1, so a single hit scores 0.5.
+func (c *LocalSafetyClassifier) Classify(ctx context.Context, text string) (map[string]float64, error) {
+	scores := make(map[string]float64)
+	for category, patterns := range c.patterns {
+		hits := 0
+		for _, re := range patterns {
+			if re.MatchString(text) {
+				hits++
+			}
+		}
+		if hits > 0 {
+			scores[category] = 1 - math.Pow(0.5, float64(hits))
+		}
+	}
+	return scores, nil
+}
+
+// OpenAIModerationClassifier scores text with the OpenAI moderation API
+type OpenAIModerationClassifier struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIModerationClassifier creates a new OpenAI moderation classifier
+func NewOpenAIModerationClassifier(apiKey, baseURL, model string, timeout time.Duration) *OpenAIModerationClassifier {
+	return &OpenAIModerationClassifier{
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+type openAIModerationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// moderationCategories maps OpenAI moderation categories to gateway categories
+var moderationCategories = map[string]string{
+	"harassment":             CategoryToxicity,
+	"harassment/threatening": CategoryToxicity,
+	"hate":                   CategoryHate,
+	"hate/threatening":       CategoryHate,
+	"violence":               CategoryViolence,
+	"violence/graphic":       CategoryViolence,
+	"self-harm":              CategorySelfHarm,
+	"self-harm/intent":       CategorySelfHarm,
+	"self-harm/instructions": CategorySelfHarm,
+	"sexual":                 CategorySexual,
+	"sexual/minors":          CategorySexual,
+}
+
+// Name implements SafetyClassifier
+func (c *OpenAIModerationClassifier) Name() string {
+	return "openai"
+}
+
+// Classify implements SafetyClassifier
+func (c *OpenAIModerationClassifier) Classify(ctx context.Context, text string) (map[string]float64, error) {
+	body := map[string]interface{}{"input": text}
+	if c.model != "" {
+		body["model"] = c.model
+	}
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/moderations", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call moderation API: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("Warning: failed to close response body: %v\n", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation API returned status %d", resp.StatusCode)
+	}
+
+	var moderation openAIModerationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&moderation); err != nil {
+		return nil, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+
+	scores := make(map[string]float64)
+	for _, result := range moderation.Results {
+		for name, score := range result.CategoryScores {
+			category, ok := moderationCategories[name]
+			if !ok {
+				continue
+			}
+			if score > scores[category] {
+				scores[category] = score
+			}
+		}
+	}
+
+	return scores, nil
+}
+
+// redactChoices returns a copy of the response with flagged choices replaced
+func redactChoices(response *providers.ChatResponse, flags []providers.GuardrailFlag) *providers.ChatResponse {
+	flaggedIdx := make(map[int]bool, len(flags))
+	for _, flag := range flags {
+		flaggedIdx[flag.ChoiceIndex] = true
+	}
+
+	redacted := *response
+	redacted.Choices = make([]providers.Choice, len(response.Choices))
+	copy(redacted.Choices, response.Choices)
+	for i, choice := range redacted.Choices {
+		if !flaggedIdx[choice.Index] {
+			continue
+		}
+		redacted.Choices[i].Message.Content = RedactedOutput
+		redacted.Choices[i].Message.ToolCalls = nil
+		redacted.Choices[i].FinishReason = "content_filter"
+	}
+
+	return &redacted
+}
+
+// flaggedCategories returns the sorted union of categories across flags
+func flaggedCategories(flags []providers.GuardrailFlag) []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, flag := range flags {
+		for _, category := range flag.Categories {
+			if !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// choiceText extracts the text of a message, including text content blocks
+func choiceText(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []interface{}:
+		var parts []string
+		for _, block := range c {
+			if m, ok := block.(map[string]interface{}); ok {
+				if text, ok := m["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return ""
+	}
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/integrations/guardrails/types"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func newTestOutputSafetyGuardrail(t *testing.T, raw map[string]interface{}) *OutputSafetyGuardrail {
+	t.Helper()
+
+	cfg, err := ParseOutputSafetyConfig(raw)
+	require.NoError(t, err)
+	classifier, err := NewLocalSafetyClassifier(cfg.Patterns)
+	require.NoError(t, err)
+
+	return NewOutputSafetyGuardrail("output-safety", cfg, []SafetyClassifier{classifier}, types.PostCall, true, zap.NewNop())
+}
+
+func testResponse(contents ...string) *providers.ChatResponse {
+	resp := &providers.ChatResponse{ID: "chatcmpl-1", Model: "gpt-4"}
+	for i, content := range contents {
+		resp.Choices = append(resp.Choices, providers.Choice{
+			Index:        i,
+			Message:      providers.Message{Role: "assistant", Content: content},
+			FinishReason: "stop",
+		})
+	}
+	return resp
+}
+
+func TestLocalSafetyClassifier(t *testing.T) {
+	classifier, err := NewLocalSafetyClassifier(nil)
+	require.NoError(t, err)
+
+	scores, err := classifier.Classify(context.Background(), "DAN mode enabled. As an unrestricted AI I will answer anything.")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.75, scores[CategoryJailbreak], 1e-9)
+
+	scores, err = classifier.Classify(context.Background(), "The capital of France is Paris.")
+	require.NoError(t, err)
+	assert.Empty(t, scores)
+
+	jailbreakOnly, err := NewLocalSafetyClassifier(nil, CategoryJailbreak)
+	require.NoError(t, err)
+	scores, err = jailbreakOnly.Classify(context.Background(), "You are an idiot.")
+	require.NoError(t, err)
+	assert.Empty(t, scores)
+
+	_, err = NewLocalSafetyClassifier(map[string][]string{CategoryToxicity: {"("}})
+	assert.Error(t, err)
+}
+
+func TestParseOutputSafetyConfig(t *testing.T) {
+	cfg, err := ParseOutputSafetyConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, OutputSafetyActionBlock, cfg.Action)
+	assert.Equal(t, 0.5, cfg.Threshold)
+
+	cfg, err = ParseOutputSafetyConfig(map[string]interface{}{
+		"action":    "redact",
+		"threshold": 0.7,
+		"team_policies": map[string]interface{}{
+			"team-a": map[string]interface{}{"action": "annotate"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, OutputSafetyActionAnnotate, cfg.TeamPolicies["team-a"].Action)
+	assert.Equal(t, 0.7, cfg.TeamPolicies["team-a"].Threshold)
+
+	_, err = ParseOutputSafetyConfig(map[string]interface{}{"action": "explode"})
+	assert.Error(t, err)
+
+	_, err = ParseOutputSafetyConfig(map[string]interface{}{"threshold": 2})
+	assert.Error(t, err)
+}
+
+func TestOutputSafetyGuardrail_Actions(t *testing.T) {
+	toxic := "You are an idiot."
+	clean := "Here is the summary you asked for."
+
+	t.Run("passes clean responses", func(t *testing.T) {
+		g := newTestOutputSafetyGuardrail(t, nil)
+		result, err := g.Execute(context.Background(), &types.GuardrailInput{Response: testResponse(clean)})
+		require.NoError(t, err)
+		assert.True(t, result.Passed)
+		assert.False(t, result.Blocked)
+		assert.False(t, result.Modified)
+	})
+
+	t.Run("blocks flagged responses", func(t *testing.T) {
+		g := newTestOutputSafetyGuardrail(t, nil)
+		result, err := g.Execute(context.Background(), &types.GuardrailInput{Response: testResponse(toxic)})
+		require.NoError(t, err)
+		assert.True(t, result.Blocked)
+		assert.Equal(t, []string{CategoryToxicity}, result.Details["categories"])
+	})
+
+	t.Run("redacts only flagged choices", func(t *testing.T) {
+		g := newTestOutputSafetyGuardrail(t, map[string]interface{}{"action": "redact"})
+		original := testResponse(clean, toxic)
+		result, err := g.Execute(context.Background(), &types.GuardrailInput{Response: original})
+		require.NoError(t, err)
+		require.True(t, result.Modified)
+
+		redacted := result.ModifiedResponse.(*providers.ChatResponse)
+		assert.Equal(t, clean, redacted.Choices[0].Message.Content)
+		assert.Equal(t, RedactedOutput, redacted.Choices[1].Message.Content)
+		assert.Equal(t, "content_filter", redacted.Choices[1].FinishReason)
+		assert.Equal(t, toxic, original.Choices[1].Message.Content, "original response must not be mutated")
+	})
+
+	t.Run("annotates with team policy", func(t *testing.T) {
+		g := newTestOutputSafetyGuardrail(t, map[string]interface{}{
+			"team_policies": map[string]interface{}{
+				"team-a": map[string]interface{}{"action": "annotate"},
+			},
+		})
+		result, err := g.Execute(context.Background(), &types.GuardrailInput{Response: testResponse(toxic), TeamID: "team-a"})
+		require.NoError(t, err)
+		assert.False(t, result.Blocked)
+		require.True(t, result.Modified)
+
+		annotated := result.ModifiedResponse.(*providers.ChatResponse)
+		require.Len(t, annotated.GuardrailFlags, 1)
+		assert.Equal(t, "output-safety", annotated.GuardrailFlags[0].Guardrail)
+		assert.Equal(t, []string{CategoryToxicity}, annotated.GuardrailFlags[0].Categories)
+	})
+
+	t.Run("ignores categories outside the policy", func(t *testing.T) {
+		g := newTestOutputSafetyGuardrail(t, map[string]interface{}{"categories": []string{CategoryJailbreak}})
+		result, err := g.Execute(context.Background(), &types.GuardrailInput{Response: testResponse(toxic)})
+		require.NoError(t, err)
+		assert.True(t, result.Passed)
+	})
+}
+
+func TestOpenAIModerationClassifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/moderations", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "omni-moderation-latest", body["model"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results":[{"flagged":true,"category_scores":{"harassment":0.2,"harassment/threatening":0.9,"hate":0.01,"illicit":0.5}}]}`))
+	}))
+	defer server.Close()
+
+	classifier := NewOpenAIModerationClassifier("test-key", server.URL+"/", "omni-moderation-latest", 5*time.Second)
+	scores, err := classifier.Classify(context.Background(), "some text")
+	require.NoError(t, err)
+
+	assert.Equal(t, 0.9, scores[CategoryToxicity])
+	assert.Equal(t, 0.01, scores[CategoryHate])
+	assert.NotContains(t, scores, "illicit")
+}
@@ -0,0 +1,360 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/integrations/guardrails/types"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Actions taken when injected content is detected
+const (
+	PromptInjectionActionBlock = "block" // Reject the request
+	PromptInjectionActionFlag  = "flag"  // Forward the request, recording the detection
+)
+
+// Sensitivities and the score at which each flags content. Low only flags
+// unambiguous patterns, high flags any single pattern; off skips the scan.
+const (
+	SensitivityOff    = "off"
+	SensitivityLow    = "low"
+	SensitivityMedium = "medium"
+	SensitivityHigh   = "high"
+)
+
+var sensitivityThresholds = map[string]float64{
+	SensitivityLow:    0.9,
+	SensitivityMedium: 0.7,
+	SensitivityHigh:   0.5,
+}
+
+// Prompt injection categories
+const (
+	CategoryInstructionOverride = "instruction_override"
+	CategoryExfiltration        = "exfiltration"
+	CategoryRoleInjection       = "role_injection"
+)
+
+var promptInjectionDetections = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_prompt_injection_detections_total",
+		Help: "Total number of tool and retrieved content messages flagged for prompt injection, by route, category and action",
+	},
+	[]string{"guardrail", "route", "category", "action"},
+)
+
+// injectionPattern is a pattern with the weight of a single hit. Strong
+// patterns are unambiguous; weak ones also occur in benign text.
+type injectionPattern struct {
+	re     *regexp.Regexp
+	weight float64
+}
+
+const (
+	strongPatternWeight = 0.9
+	weakPatternWeight   = 0.5
+)
+
+// defaultInjectionPatterns are matched case-insensitively against tool and
+// retrieved content
+var defaultInjectionPatterns = map[string]struct{ strong, weak []string }{
+	CategoryInstructionOverride: {
+		strong: []string{
+			`\b(?:ignore|disregard|forget|override) (?:all |any )?(?:of )?(?:the |your )?(?:previous|prior|above|earlier|preceding|original) (?:instructions|directions|prompts?|rules|context)\b`,
+			`\bdo not follow (?:the |your )?(?:previous|prior|system|original) (?:instructions|prompt)\b`,
+			`\b(?:your|the) (?:new|real|actual|updated) instructions are\b`,
+		},
+		weak: []string{
+			`\b(?:new|updated|additional) instructions?:`,
+			`\bfrom now on,? you (?:are|will|must)\b`,
+			`\byou are no longer\b`,
+			`\b(?:important|urgent)(?: note)? (?:to|for) (?:the )?(?:ai|assistant|model|llm)\b`,
+		},
+	},
+	CategoryExfiltration: {
+		strong: []string{
+			`\b(?:send|post|upload|forward|exfiltrate|transmit) (?:all |the |your )?(?:conversation|chat history|system prompt|api keys?|credentials|secrets|tokens|user data) to\b`,
+			`!\[[^\]]*\]\(https?://[^)\s]*[?&][^)\s=]*=(?:\{|\$|%7B)`, // Image URL with a templated query parameter
+			`\b(?:reveal|print|output|repeat|show) (?:your |the )?(?:full |entire |original )?(?:system prompt|hidden instructions|initial prompt)\b`,
+		},
+		weak: []string{
+			`\b(?:include|append|embed) (?:it|them|this|the (?:data|results?|answer)) in (?:a |the )?(?:url|link|image|query string)\b`,
+			`\b(?:curl|wget|fetch)\s+https?://`,
+			`\bbase64[- ]encode (?:the|all|your)\b`,
+		},
+	},
+	CategoryRoleInjection: {
+		strong: []string{
+			`<\|(?:im_start|im_end|system|endoftext)\|>`,
+			`\[/?(?:INST|SYS)\]|<</?SYS>>`,
+		},
+		weak: []string{
+			`(?m)^\s*(?:#{1,3}\s*)?(?:system|assistant)\s*:`,
+			`</?(?:system|instructions?)>`,
+		},
+	},
+}
+
+// PromptInjectionPolicy decides how content is scanned and what happens when
+// it is flagged
+type PromptInjectionPolicy struct {
+	Action      string `json:"action"`
+	Sensitivity string `json:"sensitivity"`
+}
+
+// PromptInjectionConfig is the guardrail's config block. Route policies are
+// keyed by the requested model or route name and inherit unset fields from
+// the default policy.
+type PromptInjectionConfig struct {
+	PromptInjectionPolicy
+	RoutePolicies map[string]PromptInjectionPolicy `json:"route_policies,omitempty"`
+	Roles         []string                         `json:"roles,omitempty"`    // Message roles scanned
+	Patterns      map[string][]string              `json:"patterns,omitempty"` // Extra strong patterns per category
+}
+
+// ParsePromptInjectionConfig reads the prompt injection settings from a
+// guardrail's provider-specific config map
+func ParsePromptInjectionConfig(raw map[string]interface{}) (*PromptInjectionConfig, error) {
+	cfg := &PromptInjectionConfig{}
+	if len(raw) > 0 {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode prompt injection config: %w", err)
+		}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid prompt injection config: %w", err)
+		}
+	}
+
+	if cfg.Action == "" {
+		cfg.Action = PromptInjectionActionBlock
+	}
+	if cfg.Sensitivity == "" {
+		cfg.Sensitivity = SensitivityMedium
+	}
+	if len(cfg.Roles) == 0 {
+		cfg.Roles = []string{"tool", "function"}
+	}
+	if err := cfg.PromptInjectionPolicy.validate(); err != nil {
+		return nil, err
+	}
+
+	for route, policy := range cfg.RoutePolicies {
+		if policy.Action == "" {
+			policy.Action = cfg.Action
+		}
+		if policy.Sensitivity == "" {
+			policy.Sensitivity = cfg.Sensitivity
+		}
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+		cfg.RoutePolicies[route] = policy
+	}
+
+	return cfg, nil
+}
+
+func (p PromptInjectionPolicy) validate() error {
+	switch p.Action {
+	case PromptInjectionActionBlock, PromptInjectionActionFlag:
+	default:
+		return fmt.Errorf("invalid prompt injection action: %s", p.Action)
+	}
+	if _, ok := sensitivityThresholds[p.Sensitivity]; !ok && p.Sensitivity != SensitivityOff {
+		return fmt.Errorf("invalid prompt injection sensitivity: %s", p.Sensitivity)
+	}
+	return nil
+}
+
+// PromptInjectionGuardrail scans the content tools and retrieval add to a
+// prompt for text that tries to take over the model: overridden
+// instructions, data exfiltration and forged role markers
+type PromptInjectionGuardrail struct {
+	name     string
+	mode     types.GuardrailMode
+	enabled  bool
+	logger   *zap.Logger
+	config   *PromptInjectionConfig
+	patterns map[string][]injectionPattern
+}
+
+// NewPromptInjectionGuardrail creates a new prompt injection guardrail
+func NewPromptInjectionGuardrail(name string, cfg *PromptInjectionConfig, mode types.GuardrailMode, enabled bool, logger *zap.Logger) (*PromptInjectionGuardrail, error) {
+	g := &PromptInjectionGuardrail{
+		name:     name,
+		mode:     mode,
+		enabled:  enabled,
+		logger:   logger.Named("prompt_injection"),
+		config:   cfg,
+		patterns: make(map[string][]injectionPattern),
+	}
+
+	add := func(category string, patterns []string, weight float64) error {
+		for _, pattern := range patterns {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %w", category, pattern, err)
+			}
+			g.patterns[category] = append(g.patterns[category], injectionPattern{re: re, weight: weight})
+		}
+		return nil
+	}
+	for category, patterns := range defaultInjectionPatterns {
+		if err := add(category, patterns.strong, strongPatternWeight); err != nil {
+			return nil, err
+		}
+		if err := add(category, patterns.weak, weakPatternWeight); err != nil {
+			return nil, err
+		}
+	}
+	for category, patterns := range cfg.Patterns {
+		if err := add(category, patterns, strongPatternWeight); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// Execute implements the Guardrail interface
+func (g *PromptInjectionGuardrail) Execute(ctx context.Context, input *types.GuardrailInput) (*types.GuardrailResult, error) {
+	request, ok := input.Request.(*providers.ChatRequest)
+	if !ok || request == nil {
+		return &types.GuardrailResult{
+			Passed: true,
+			Reason: "No request content to analyze",
+		}, nil
+	}
+
+	route := request.Model
+	policy := g.policyFor(route)
+	result := &types.GuardrailResult{
+		Passed: true,
+		Details: map[string]interface{}{
+			"action":      policy.Action,
+			"sensitivity": policy.Sensitivity,
+			"route":       route,
+		},
+	}
+	if policy.Sensitivity == SensitivityOff {
+		return result, nil
+	}
+	threshold := sensitivityThresholds[policy.Sensitivity]
+
+	var flaggedMessages []int
+	seen := make(map[string]bool)
+	var categories []string
+	maxScore := 0.0
+	for i, msg := range request.Messages {
+		if !containsString(g.config.Roles, msg.Role) {
+			continue
+		}
+		text := choiceText(msg.Content)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		flagged := false
+		for category, score := range g.score(text) {
+			if score < threshold {
+				continue
+			}
+			flagged = true
+			if score > maxScore {
+				maxScore = score
+			}
+			if !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+			promptInjectionDetections.WithLabelValues(g.name, route, category, policy.Action).Inc()
+		}
+		if flagged {
+			flaggedMessages = append(flaggedMessages, i)
+		}
+	}
+	if len(flaggedMessages) == 0 {
+		return result, nil
+	}
+
+	sort.Strings(categories)
+	result.Passed = false
+	result.Confidence = maxScore
+	result.Details["categories"] = categories
+	result.Details["messages"] = flaggedMessages
+	result.Reason = fmt.Sprintf("tool content flagged for prompt injection (%s)", strings.Join(categories, ", "))
+	if policy.Action == PromptInjectionActionBlock {
+		result.Blocked = true
+	}
+
+	g.logger.Info("Prompt injection guardrail triggered",
+		zap.String("guardrail", g.name),
+		zap.String("route", route),
+		zap.String("action", policy.Action),
+		zap.Strings("categories", categories),
+		zap.Ints("messages", flaggedMessages),
+		zap.String("team_id", input.TeamID),
+		zap.String("key_id", input.KeyID))
+
+	return result, nil
+}
+
+// score rates text per category, in the range 0-1. Each pattern hit takes
+// its weight of the remaining distance to 1.
+func (g *PromptInjectionGuardrail) score(text string) map[string]float64 {
+	scores := make(map[string]float64)
+	for category, patterns := range g.patterns {
+		remaining := 1.0
+		for _, pattern := range patterns {
+			if pattern.re.MatchString(text) {
+				remaining *= 1 - pattern.weight
+			}
+		}
+		if remaining < 1 {
+			scores[category] = 1 - remaining
+		}
+	}
+	return scores
+}
+
+func (g *PromptInjectionGuardrail) policyFor(route string) PromptInjectionPolicy {
+	if policy, ok := g.config.RoutePolicies[route]; ok {
+		return policy
+	}
+	return g.config.PromptInjectionPolicy
+}
+
+// GetName implements the Guardrail interface
+func (g *PromptInjectionGuardrail) GetName() string {
+	return g.name
+}
+
+// GetType implements the Guardrail interface
+func (g *PromptInjectionGuardrail) GetType() types.GuardrailType {
+	return types.Security
+}
+
+// GetMode implements the Guardrail interface
+func (g *PromptInjectionGuardrail) GetMode() types.GuardrailMode {
+	return g.mode
+}
+
+// IsEnabled implements the Guardrail interface
+func (g *PromptInjectionGuardrail) IsEnabled() bool {
+	return g.enabled
+}
+
+// HealthCheck implements the Guardrail interface
+func (g *PromptInjectionGuardrail) HealthCheck(ctx context.Context) error {
+	return nil
+}
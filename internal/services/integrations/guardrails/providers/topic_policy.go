@@ -0,0 +1,550 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/integrations/guardrails/types"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Actions taken when a prompt violates a topic policy
+const (
+	TopicPolicyActionBlock = "block" // Reject the request
+	TopicPolicyActionFlag  = "flag"  // Forward the request, recording the violation
+)
+
+// Embedders topic policies classify prompts with
+const (
+	TopicEmbedderLocal  = "local"
+	TopicEmbedderOpenAI = "openai"
+)
+
+// Default similarity at which a prompt matches a topic, per embedder. The
+// local embedder's vectors only overlap on shared vocabulary, so it matches
+// at lower similarities than learned embeddings.
+var defaultTopicThresholds = map[string]float64{
+	TopicEmbedderLocal:  0.3,
+	TopicEmbedderOpenAI: 0.5,
+}
+
+var topicPolicyViolations = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_topic_policy_violations_total",
+		Help: "Total number of prompts that matched a denied topic or none of the allowed ones, by topic and action",
+	},
+	[]string{"guardrail", "topic", "action"},
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// close the texts are in meaning
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Name() string
+}
+
+// Topic is a subject prompts are matched against, described by example
+// prompts
+type Topic struct {
+	Name      string   `json:"name"`
+	Exemplars []string `json:"exemplars"`
+	Threshold float64  `json:"threshold,omitempty"` // Similarity at which a prompt matches; defaults to the guardrail's
+}
+
+// TopicPolicy decides which topics prompts may be about. Prompts matching a
+// denied topic are rejected; when topics are allowed, prompts must also match
+// one of them.
+type TopicPolicy struct {
+	Action string   `json:"action"`
+	Allow  []string `json:"allow,omitempty"`
+	Deny   []string `json:"deny,omitempty"`
+}
+
+// TopicPolicyConfig is the guardrail's config block. Team policies are keyed
+// by team ID and inherit unset fields from the default policy.
+type TopicPolicyConfig struct {
+	TopicPolicy
+	Topics       []Topic                `json:"topics"`
+	Threshold    float64                `json:"threshold,omitempty"`
+	Embedder     string                 `json:"embedder,omitempty"` // local (default) or openai
+	Model        string                 `json:"model,omitempty"`    // Embedding model of the openai embedder
+	TeamPolicies map[string]TopicPolicy `json:"team_policies,omitempty"`
+}
+
+// ParseTopicPolicyConfig reads the topic policy settings from a guardrail's
+// provider-specific config map
+func ParseTopicPolicyConfig(raw map[string]interface{}) (*TopicPolicyConfig, error) {
+	cfg := &TopicPolicyConfig{}
+	if len(raw) > 0 {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode topic policy config: %w", err)
+		}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid topic policy config: %w", err)
+		}
+	}
+
+	if cfg.Action == "" {
+		cfg.Action = TopicPolicyActionBlock
+	}
+	if cfg.Embedder == "" {
+		cfg.Embedder = TopicEmbedderLocal
+	}
+	defaultThreshold, ok := defaultTopicThresholds[cfg.Embedder]
+	if !ok {
+		return nil, fmt.Errorf("invalid topic embedder: %s", cfg.Embedder)
+	}
+	if cfg.Threshold == 0 {
+		cfg.Threshold = defaultThreshold
+	}
+	if cfg.Threshold <= 0 || cfg.Threshold > 1 {
+		return nil, fmt.Errorf("topic threshold must be in (0, 1], got %v", cfg.Threshold)
+	}
+
+	if len(cfg.Topics) == 0 {
+		return nil, fmt.Errorf("at least one topic is required")
+	}
+	topics := make(map[string]bool, len(cfg.Topics))
+	for i, topic := range cfg.Topics {
+		if topic.Name == "" {
+			return nil, fmt.Errorf("topic %d has no name", i)
+		}
+		if topics[topic.Name] {
+			return nil, fmt.Errorf("duplicate topic: %s", topic.Name)
+		}
+		topics[topic.Name] = true
+		if len(topic.Exemplars) == 0 {
+			return nil, fmt.Errorf("topic %s has no exemplars", topic.Name)
+		}
+		if topic.Threshold == 0 {
+			cfg.Topics[i].Threshold = cfg.Threshold
+		} else if topic.Threshold < 0 || topic.Threshold > 1 {
+			return nil, fmt.Errorf("topic %s: threshold must be in (0, 1], got %v", topic.Name, topic.Threshold)
+		}
+	}
+
+	if err := cfg.TopicPolicy.validate(topics); err != nil {
+		return nil, err
+	}
+	for teamID, policy := range cfg.TeamPolicies {
+		if policy.Action == "" {
+			policy.Action = cfg.Action
+		}
+		if policy.Allow == nil {
+			policy.Allow = cfg.Allow
+		}
+		if policy.Deny == nil {
+			policy.Deny = cfg.Deny
+		}
+		if err := policy.validate(topics); err != nil {
+			return nil, fmt.Errorf("team %s: %w", teamID, err)
+		}
+		cfg.TeamPolicies[teamID] = policy
+	}
+
+	return cfg, nil
+}
+
+func (p TopicPolicy) validate(topics map[string]bool) error {
+	switch p.Action {
+	case TopicPolicyActionBlock, TopicPolicyActionFlag:
+	default:
+		return fmt.Errorf("invalid topic policy action: %s", p.Action)
+	}
+	for _, name := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if !topics[name] {
+			return fmt.Errorf("unknown topic: %s", name)
+		}
+	}
+	return nil
+}
+
+// topicMatch is how close a prompt is to a topic
+type topicMatch struct {
+	topic      string
+	similarity float64
+	exemplar   string // Closest exemplar
+	matched    bool
+}
+
+// TopicPolicyGuardrail rejects prompts by topic. Topics are described by
+// exemplar prompts; the latest user message matches a topic when its
+// embedding is close enough to one of the topic's exemplars.
+type TopicPolicyGuardrail struct {
+	name     string
+	mode     types.GuardrailMode
+	enabled  bool
+	logger   *zap.Logger
+	config   *TopicPolicyConfig
+	embedder Embedder
+
+	mu        sync.Mutex
+	exemplars [][][]float32 // Per topic, per exemplar; embedded on first use
+}
+
+// NewTopicPolicyGuardrail creates a new topic policy guardrail
+func NewTopicPolicyGuardrail(name string, cfg *TopicPolicyConfig, embedder Embedder, mode types.GuardrailMode, enabled bool, logger *zap.Logger) *TopicPolicyGuardrail {
+	return &TopicPolicyGuardrail{
+		name:     name,
+		mode:     mode,
+		enabled:  enabled,
+		logger:   logger.Named("topic_policy"),
+		config:   cfg,
+		embedder: embedder,
+	}
+}
+
+// Execute implements the Guardrail interface
+func (g *TopicPolicyGuardrail) Execute(ctx context.Context, input *types.GuardrailInput) (*types.GuardrailResult, error) {
+	request, ok := input.Request.(*providers.ChatRequest)
+	prompt := ""
+	if ok && request != nil {
+		prompt = latestUserText(request.Messages)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return &types.GuardrailResult{
+			Passed: true,
+			Reason: "No prompt to classify",
+		}, nil
+	}
+
+	policy := g.policyFor(input.TeamID)
+	matches, err := g.classify(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64, len(matches))
+	for _, match := range matches {
+		scores[match.topic] = math.Round(match.similarity*1000) / 1000
+	}
+	result := &types.GuardrailResult{
+		Passed: true,
+		Details: map[string]interface{}{
+			"action": policy.Action,
+			"scores": scores,
+		},
+	}
+
+	violation, reason := g.evaluate(policy, matches)
+	if violation == nil {
+		return result, nil
+	}
+
+	result.Passed = false
+	result.Blocked = policy.Action == TopicPolicyActionBlock
+	result.Confidence = violation.similarity
+	result.Reason = reason
+	result.Details["topic"] = violation.topic
+	result.Details["similarity"] = scores[violation.topic]
+	result.Details["exemplar"] = violation.exemplar
+	topicPolicyViolations.WithLabelValues(g.name, violation.topic, policy.Action).Inc()
+
+	g.logger.Info("Topic policy guardrail triggered",
+		zap.String("guardrail", g.name),
+		zap.String("action", policy.Action),
+		zap.String("topic", violation.topic),
+		zap.Float64("similarity", violation.similarity),
+		zap.String("team_id", input.TeamID),
+		zap.String("key_id", input.KeyID))
+
+	return result, nil
+}
+
+// evaluate applies a policy to the prompt's topic matches. It returns the
+// match that explains the violation and the reason, or nil when the prompt
+// is allowed. Denied topics win over allowed ones.
+func (g *TopicPolicyGuardrail) evaluate(policy TopicPolicy, matches []topicMatch) (*topicMatch, string) {
+	var denied *topicMatch
+	for i, match := range matches {
+		if match.matched && containsString(policy.Deny, match.topic) && (denied == nil || match.similarity > denied.similarity) {
+			denied = &matches[i]
+		}
+	}
+	if denied != nil {
+		return denied, fmt.Sprintf("prompt matches denied topic %q (similarity %.2f to %q)", denied.topic, denied.similarity, denied.exemplar)
+	}
+	if len(policy.Allow) == 0 {
+		return nil, ""
+	}
+
+	var closest *topicMatch
+	for i, match := range matches {
+		if !containsString(policy.Allow, match.topic) {
+			continue
+		}
+		if match.matched {
+			return nil, ""
+		}
+		if closest == nil || match.similarity > closest.similarity {
+			closest = &matches[i]
+		}
+	}
+	return closest, fmt.Sprintf("prompt matches none of the allowed topics (%s); closest is %q with similarity %.2f",
+		strings.Join(policy.Allow, ", "), closest.topic, closest.similarity)
+}
+
+// classify returns how close the prompt is to each topic
+func (g *TopicPolicyGuardrail) classify(ctx context.Context, prompt string) ([]topicMatch, error) {
+	exemplars, err := g.exemplarVectors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := g.embedder.Embed(ctx, []string{prompt})
+	if err != nil {
+		return nil, fmt.Errorf("%s embedder failed: %w", g.embedder.Name(), err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("%s embedder returned %d vectors for 1 prompt", g.embedder.Name(), len(vectors))
+	}
+
+	matches := make([]topicMatch, len(g.config.Topics))
+	for i, topic := range g.config.Topics {
+		matches[i].topic = topic.Name
+		for j, exemplar := range exemplars[i] {
+			if similarity := cosineSimilarity(vectors[0], exemplar); j == 0 || similarity > matches[i].similarity {
+				matches[i].similarity = similarity
+				matches[i].exemplar = topic.Exemplars[j]
+			}
+		}
+		matches[i].matched = matches[i].similarity >= topic.Threshold
+	}
+	return matches, nil
+}
+
+// exemplarVectors embeds the topics' exemplars once. A failed embedding is
+// retried on the next request.
+func (g *TopicPolicyGuardrail) exemplarVectors(ctx context.Context) ([][][]float32, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.exemplars != nil {
+		return g.exemplars, nil
+	}
+
+	var texts []string
+	for _, topic := range g.config.Topics {
+		texts = append(texts, topic.Exemplars...)
+	}
+	vectors, err := g.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed topic exemplars: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("%s embedder returned %d vectors for %d exemplars", g.embedder.Name(), len(vectors), len(texts))
+	}
+
+	exemplars := make([][][]float32, len(g.config.Topics))
+	for i, topic := range g.config.Topics {
+		exemplars[i], vectors = vectors[:len(topic.Exemplars)], vectors[len(topic.Exemplars):]
+	}
+	g.exemplars = exemplars
+	return exemplars, nil
+}
+
+func (g *TopicPolicyGuardrail) policyFor(teamID string) TopicPolicy {
+	if teamID != "" {
+		if policy, ok := g.config.TeamPolicies[teamID]; ok {
+			return policy
+		}
+	}
+	return g.config.TopicPolicy
+}
+
+// GetName implements the Guardrail interface
+func (g *TopicPolicyGuardrail) GetName() string {
+	return g.name
+}
+
+// GetType implements the Guardrail interface
+func (g *TopicPolicyGuardrail) GetType() types.GuardrailType {
+	return types.Compliance
+}
+
+// GetMode implements the Guardrail interface
+func (g *TopicPolicyGuardrail) GetMode() types.GuardrailMode {
+	return g.mode
+}
+
+// IsEnabled implements the Guardrail interface
+func (g *TopicPolicyGuardrail) IsEnabled() bool {
+	return g.enabled
+}
+
+// HealthCheck implements the Guardrail interface by embedding the exemplars
+func (g *TopicPolicyGuardrail) HealthCheck(ctx context.Context) error {
+	_, err := g.exemplarVectors(ctx)
+	return err
+}
+
+// latestUserText returns the text of the last user message, the prompt the
+// model is asked to answer
+func latestUserText(messages []providers.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return choiceText(messages[i].Content)
+		}
+	}
+	return ""
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// localEmbeddingDims is the size of the local embedder's hashed vectors
+const localEmbeddingDims = 2048
+
+// topicStopWords carry no topic and are left out of local embeddings
+var topicStopWords = map[string]bool{
+	"a": true, "about": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"can": true, "could": true, "do": true, "does": true, "for": true, "from": true, "how": true,
+	"i": true, "if": true, "in": true, "is": true, "it": true, "me": true, "my": true, "of": true,
+	"on": true, "or": true, "please": true, "should": true, "some": true, "that": true, "the": true,
+	"this": true, "to": true, "what": true, "when": true, "which": true, "with": true, "would": true,
+	"you": true, "your": true,
+}
+
+// LocalEmbedder embeds text as hashed word and character trigram counts. It
+// needs no model or network, and matches prompts that share vocabulary with
+// a topic's exemplars, including inflections of the same words.
+type LocalEmbedder struct{}
+
+// NewLocalEmbedder creates the local hashing embedder
+func NewLocalEmbedder() *LocalEmbedder {
+	return &LocalEmbedder{}
+}
+
+// Name implements Embedder
+func (e *LocalEmbedder) Name() string {
+	return TopicEmbedderLocal
+}
+
+// Embed implements Embedder
+func (e *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, localEmbeddingDims)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		for _, word := range words {
+			if topicStopWords[word] {
+				continue
+			}
+			vector[hashFeature("w:"+word)] += 1
+			padded := []rune(" " + word + " ")
+			for j := 0; j+3 <= len(padded); j++ {
+				vector[hashFeature("c:"+string(padded[j:j+3]))] += 0.25
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func hashFeature(feature string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(feature))
+	return int(h.Sum32() % localEmbeddingDims)
+}
+
+// OpenAIEmbedder embeds text with an OpenAI-compatible embeddings API, such
+// as OpenAI's or the gateway's own
+type OpenAIEmbedder struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIEmbedder creates an embedder for the embeddings API at baseURL.
+// The model defaults to text-embedding-3-small.
+func NewOpenAIEmbedder(apiKey, baseURL, model string, timeout time.Duration) *OpenAIEmbedder {
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	return &OpenAIEmbedder{
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Name implements Embedder
+func (e *OpenAIEmbedder) Name() string {
+	return TopicEmbedderOpenAI
+}
+
+// Embed implements Embedder
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embeddings", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call embeddings API: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("embeddings API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var embeddings providers.EmbeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embeddings); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings response: %w", err)
+	}
+	sort.Slice(embeddings.Data, func(i, j int) bool { return embeddings.Data[i].Index < embeddings.Data[j].Index })
+
+	vectors := make([][]float32, len(embeddings.Data))
+	for i, data := range embeddings.Data {
+		vectors[i] = data.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/services/integrations/guardrails/types"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func testTopicConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"deny":  []interface{}{"medical_advice"},
+		"allow": []interface{}{"coding_help"},
+		"topics": []interface{}{
+			map[string]interface{}{
+				"name": "medical_advice",
+				"exemplars": []interface{}{
+					"What dose of ibuprofen should I take for a headache?",
+					"Can you diagnose my symptoms? I have a fever and a rash.",
+					"Is it safe to take these two medications together?",
+				},
+			},
+			map[string]interface{}{
+				"name": "coding_help",
+				"exemplars": []interface{}{
+					"How do I reverse a linked list in Python?",
+					"Fix this Go compile error: undefined variable",
+					"Write a SQL query that counts users per country",
+				},
+			},
+		},
+		"team_policies": map[string]interface{}{
+			"team-clinic": map[string]interface{}{"allow": []interface{}{}, "deny": []interface{}{}},
+			"team-audit":  map[string]interface{}{"action": "flag"},
+		},
+	}
+}
+
+func newTestTopicPolicyGuardrail(t *testing.T, raw map[string]interface{}, embedder Embedder) *TopicPolicyGuardrail {
+	t.Helper()
+
+	cfg, err := ParseTopicPolicyConfig(raw)
+	require.NoError(t, err)
+	return NewTopicPolicyGuardrail("topics", cfg, embedder, types.PreCall, true, zap.NewNop())
+}
+
+func promptInput(teamID, prompt string) *types.GuardrailInput {
+	return &types.GuardrailInput{
+		TeamID: teamID,
+		Request: &providers.ChatRequest{
+			Model: "gpt-4o",
+			Messages: []providers.Message{
+				{Role: "system", Content: "You are a helpful assistant."},
+				{Role: "user", Content: "Hello"},
+				{Role: "assistant", Content: "Hi! How can I help?"},
+				{Role: "user", Content: prompt},
+			},
+		},
+	}
+}
+
+func TestParseTopicPolicyConfig(t *testing.T) {
+	cfg, err := ParseTopicPolicyConfig(testTopicConfig())
+	require.NoError(t, err)
+	assert.Equal(t, TopicPolicyActionBlock, cfg.Action)
+	assert.Equal(t, TopicEmbedderLocal, cfg.Embedder)
+	assert.Equal(t, 0.3, cfg.Topics[0].Threshold, "topics inherit the embedder's default threshold")
+	assert.Equal(t, TopicPolicy{Action: "block", Allow: []string{}, Deny: []string{}}, cfg.TeamPolicies["team-clinic"])
+	assert.Equal(t, TopicPolicy{Action: "flag", Allow: []string{"coding_help"}, Deny: []string{"medical_advice"}}, cfg.TeamPolicies["team-audit"])
+
+	_, err = ParseTopicPolicyConfig(nil)
+	assert.Error(t, err, "topics are required")
+
+	raw := testTopicConfig()
+	raw["deny"] = []interface{}{"legal_advice"}
+	_, err = ParseTopicPolicyConfig(raw)
+	assert.ErrorContains(t, err, "unknown topic: legal_advice")
+
+	raw = testTopicConfig()
+	raw["embedder"] = "bert"
+	_, err = ParseTopicPolicyConfig(raw)
+	assert.Error(t, err)
+
+	raw = testTopicConfig()
+	raw["topics"] = []interface{}{map[string]interface{}{"name": "empty"}}
+	raw["deny"], raw["allow"], raw["team_policies"] = nil, nil, nil
+	_, err = ParseTopicPolicyConfig(raw)
+	assert.ErrorContains(t, err, "no exemplars")
+}
+
+func TestTopicPolicyGuardrail_LocalEmbedder(t *testing.T) {
+	g := newTestTopicPolicyGuardrail(t, testTopicConfig(), NewLocalEmbedder())
+
+	result, err := g.Execute(context.Background(), promptInput("", "How many ibuprofen tablets can I take for my back pain?"))
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+	assert.Equal(t, "medical_advice", result.Details["topic"])
+	assert.Equal(t, "What dose of ibuprofen should I take for a headache?", result.Details["exemplar"])
+	assert.Contains(t, result.Reason, `denied topic "medical_advice"`, "rejections name the topic and the closest exemplar")
+
+	result, err = g.Execute(context.Background(), promptInput("", "How can I reverse a string in Python?"))
+	require.NoError(t, err)
+	assert.True(t, result.Passed, result.Reason)
+
+	result, err = g.Execute(context.Background(), promptInput("", "Plan a weekend trip to Lisbon"))
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+	assert.Contains(t, result.Reason, "none of the allowed topics (coding_help)")
+
+	// Team policies override the default one
+	result, err = g.Execute(context.Background(), promptInput("team-clinic", "Is it safe to take ibuprofen with aspirin?"))
+	require.NoError(t, err)
+	assert.True(t, result.Passed)
+
+	result, err = g.Execute(context.Background(), promptInput("team-audit", "Plan a weekend trip to Lisbon"))
+	require.NoError(t, err)
+	assert.False(t, result.Passed)
+	assert.False(t, result.Blocked, "flagged prompts are forwarded")
+}
+
+func TestTopicPolicyGuardrail_OnlyLatestUserMessage(t *testing.T) {
+	g := newTestTopicPolicyGuardrail(t, testTopicConfig(), NewLocalEmbedder())
+
+	input := promptInput("", "Now write a SQL query that counts orders per country")
+	request := input.Request.(*providers.ChatRequest)
+	request.Messages[1].Content = "What dose of ibuprofen should I take for a headache?"
+
+	result, err := g.Execute(context.Background(), input)
+	require.NoError(t, err)
+	assert.False(t, result.Blocked, "earlier messages are not classified again")
+
+	result, err = g.Execute(context.Background(), &types.GuardrailInput{Request: &providers.ChatRequest{}})
+	require.NoError(t, err)
+	assert.True(t, result.Passed)
+}
+
+// keywordEmbedder maps texts to one dimension per keyword they contain
+type keywordEmbedder struct {
+	keywords []string
+	calls    int
+}
+
+func (e *keywordEmbedder) Name() string { return "keywords" }
+
+func (e *keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e.keywords))
+		for j, keyword := range e.keywords {
+			if strings.Contains(strings.ToLower(text), keyword) {
+				vectors[i][j] = 1
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestTopicPolicyGuardrail_ExemplarsEmbeddedOnce(t *testing.T) {
+	embedder := &keywordEmbedder{keywords: []string{"ibuprofen", "python", "sql"}}
+	g := newTestTopicPolicyGuardrail(t, testTopicConfig(), embedder)
+
+	for i := 0; i < 3; i++ {
+		result, err := g.Execute(context.Background(), promptInput("", "Write SQL for the orders table"))
+		require.NoError(t, err)
+		assert.True(t, result.Passed)
+	}
+	assert.Equal(t, 4, embedder.calls, "exemplars are embedded on the first request only")
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "text-embedding-3-small", body["model"])
+		assert.Equal(t, []interface{}{"a", "b"}, body["input"])
+
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	vectors, err := NewOpenAIEmbedder("sk-test", server.URL+"/v1/", "", time.Second).Embed(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors, "vectors are returned in input order")
+}
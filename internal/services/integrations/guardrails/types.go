@@ -6,13 +6,14 @@ import (
 
 // Re-export types for convenience
 type (
-	GuardrailMode   = types.GuardrailMode
-	GuardrailType   = types.GuardrailType
-	GuardrailInput  = types.GuardrailInput
-	GuardrailResult = types.GuardrailResult
-	Guardrail       = types.Guardrail
-	GuardrailStats  = types.GuardrailStats
-	HealthStatus    = types.HealthStatus
+	GuardrailMode      = types.GuardrailMode
+	GuardrailType      = types.GuardrailType
+	GuardrailInput     = types.GuardrailInput
+	GuardrailResult    = types.GuardrailResult
+	Guardrail          = types.Guardrail
+	StreamingGuardrail = types.StreamingGuardrail
+	GuardrailStats     = types.GuardrailStats
+	HealthStatus       = types.HealthStatus
 )
 
 // Re-export constants
@@ -58,4 +59,4 @@ func (e *GuardrailError) Error() string {
 		return "Request blocked by guardrail '" + e.GuardrailName + "': " + e.Reason
 	}
 	return "Guardrail '" + e.GuardrailName + "' failed: " + e.Reason
-}
\ No newline at end of file
+}
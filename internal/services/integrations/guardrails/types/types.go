@@ -85,11 +85,21 @@ type Guardrail interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// StreamingGuardrail is implemented by post-call guardrails that can screen
+// a response while it is streamed. ExecuteStream receives the output
+// generated so far and reports Blocked when the stream must stop; streamed
+// output cannot be modified.
+type StreamingGuardrail interface {
+	Guardrail
+	ExecuteStream(ctx context.Context, input *GuardrailInput) (*GuardrailResult, error)
+}
+
 // GuardrailStats contains statistics about guardrail execution
 type GuardrailStats struct {
 	TotalExecutions int64         `json:"total_executions"`
 	TotalPassed     int64         `json:"total_passed"`
 	TotalBlocked    int64         `json:"total_blocked"`
+	TotalTriggered  int64         `json:"total_triggered"` // Blocked, modified or flagged
 	TotalErrors     int64         `json:"total_errors"`
 	AverageLatency  time.Duration `json:"average_latency"`
 	LastExecuted    time.Time     `json:"last_executed"`
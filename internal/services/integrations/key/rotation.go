@@ -0,0 +1,338 @@
+package key
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/webhook"
+)
+
+// EventKeyRotated is the webhook event sent when a key is rotated
+const EventKeyRotated = "key.rotated"
+
+// webhookAttempts is how often a rotation webhook is delivered before giving up
+const webhookAttempts = 3
+
+var (
+	ErrKeyNotRotatable   = errors.New("only active keys can be rotated")
+	ErrOverlapTooLong    = errors.New("overlap exceeds the maximum allowed")
+	ErrKeyAlreadyRotated = errors.New("key was already rotated; rotate its replacement instead")
+	ErrInvalidWebhookURL = errors.New("invalid webhook URL")
+)
+
+// Rotation is the result of rotating a key
+type Rotation struct {
+	OldKey *models.Key
+	NewKey *models.Key
+	// KeyValue is the new key; it is only returned here and in the webhook
+	KeyValue string
+	// OldKeyExpiresAt is when the old key stops working
+	OldKeyExpiresAt time.Time
+	// Notified is true when a webhook delivery was started
+	Notified bool
+}
+
+// RotationEvent is the body of a key.rotated webhook
+type RotationEvent struct {
+	Event                string    `json:"event"`
+	KeyID                uuid.UUID `json:"key_id"`
+	NewKeyID             uuid.UUID `json:"new_key_id"`
+	Key                  string    `json:"key"`
+	KeyPrefix            string    `json:"key_prefix"`
+	PreviousKeyExpiresAt time.Time `json:"previous_key_expires_at"`
+	RotatedAt            time.Time `json:"rotated_at"`
+}
+
+// Rotator lets users replace their keys with new ones carrying the same
+// settings. The old key keeps working for an overlap window, and the new
+// key is posted to the key's rotation webhook so automated clients can
+// switch without downtime.
+type Rotator struct {
+	db             *gorm.DB
+	logger         *zap.Logger
+	defaultOverlap time.Duration
+	maxOverlap     time.Duration
+	guard          *webhook.Guard
+	client         *http.Client
+	retryDelay     time.Duration
+
+	wg sync.WaitGroup
+}
+
+// NewRotator creates a rotator
+func NewRotator(db *gorm.DB, cfg config.KeyRotationConfig, logger *zap.Logger) *Rotator {
+	if cfg.WebhookTimeout <= 0 {
+		cfg.WebhookTimeout = 10 * time.Second
+	}
+	guard := webhook.NewGuard(cfg.WebhookAllowedHosts)
+	return &Rotator{
+		db:             db,
+		logger:         logger,
+		defaultOverlap: cfg.DefaultOverlap,
+		maxOverlap:     cfg.MaxOverlap,
+		guard:          guard,
+		client:         guard.Client(cfg.WebhookTimeout),
+		retryDelay:     2 * time.Second,
+	}
+}
+
+// Rotate replaces a key owned by userID. The old key expires after overlap
+// (the configured default when nil) or is revoked at once when it is zero.
+func (r *Rotator) Rotate(ctx context.Context, userID, keyID uuid.UUID, overlap *time.Duration) (*Rotation, error) {
+	window := r.defaultOverlap
+	if overlap != nil {
+		window = *overlap
+	}
+	if window < 0 {
+		window = 0
+	}
+	if r.maxOverlap > 0 && window > r.maxOverlap {
+		return nil, fmt.Errorf("%w (%s)", ErrOverlapTooLong, r.maxOverlap)
+	}
+
+	var rotation *Rotation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locked so concurrent rotations of the same key cannot both succeed
+		var old models.Key
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", keyID, userID).First(&old).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrKeyNotFound
+			}
+			return err
+		}
+		if !old.CanUse() {
+			return ErrKeyNotRotatable
+		}
+		var successors int64
+		if err := tx.Model(&models.Key{}).Where("rotated_from_id = ?", old.ID).Count(&successors).Error; err != nil {
+			return err
+		}
+		if successors > 0 {
+			return ErrKeyAlreadyRotated
+		}
+
+		keyValue, keyHash, err := models.GenerateKey(old.Type)
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+
+		// The new key inherits everything but its identity and usage
+		newKey := &models.Key{
+			Key:                   keyValue,
+			KeyHash:               keyHash,
+			Name:                  old.Name,
+			Type:                  old.Type,
+			UserID:                old.UserID,
+			TeamID:                old.TeamID,
+			IsActive:              true,
+			ExpiresAt:             old.ExpiresAt,
+			Environment:           old.Environment,
+			MaxBudget:             old.MaxBudget,
+			BudgetDuration:        old.BudgetDuration,
+			CurrentSpend:          old.CurrentSpend,
+			BudgetResetAt:         old.BudgetResetAt,
+			MaxCostPerRequest:     old.MaxCostPerRequest,
+			TPM:                   old.TPM,
+			RPM:                   old.RPM,
+			MaxParallelCalls:      old.MaxParallelCalls,
+			AllowedModels:         old.AllowedModels,
+			BlockedModels:         old.BlockedModels,
+			Scopes:                old.Scopes,
+			RequireSignature:      old.RequireSignature,
+			SigningSecret:         old.SigningSecret,
+			CacheOutputs:          old.CacheOutputs,
+			ResponseMinimization:  old.ResponseMinimization,
+			RequestValidation:     old.RequestValidation,
+			Metadata:              old.Metadata,
+			Tags:                  old.Tags,
+			CreatedBy:             &userID,
+			RotatedFromID:         &old.ID,
+			RotationWebhookURL:    old.RotationWebhookURL,
+			RotationWebhookSecret: old.RotationWebhookSecret,
+		}
+		if err := tx.Create(newKey).Error; err != nil {
+			return fmt.Errorf("failed to create key: %w", err)
+		}
+
+		now := time.Now()
+		expiresAt := now.Add(window)
+		if old.ExpiresAt != nil && old.ExpiresAt.Before(expiresAt) {
+			expiresAt = *old.ExpiresAt
+		}
+		if window == 0 {
+			old.Revoke(userID, "Rotated")
+			expiresAt = now
+		} else {
+			old.ExpiresAt = &expiresAt
+		}
+		if err := tx.Save(&old).Error; err != nil {
+			return fmt.Errorf("failed to update rotated key: %w", err)
+		}
+
+		tx.Create(&models.Audit{
+			EventType:    models.AuditEventKeyCreate,
+			EventAction:  "api_key_rotate",
+			EventResult:  models.AuditResultSuccess,
+			UserID:       &userID,
+			KeyID:        &newKey.ID,
+			ResourceType: "key",
+			ResourceID:   &newKey.ID,
+			Message:      "API key rotated",
+			Metadata:     auditMetadata(map[string]interface{}{"rotated_from": old.ID, "previous_key_expires_at": expiresAt}),
+			Timestamp:    now,
+		})
+
+		rotation = &Rotation{OldKey: &old, NewKey: newKey, KeyValue: keyValue, OldKeyExpiresAt: expiresAt}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("API key rotated",
+		zap.String("key_id", rotation.OldKey.ID.String()),
+		zap.String("new_key_id", rotation.NewKey.ID.String()),
+		zap.Duration("overlap", window))
+
+	if rotation.NewKey.RotationWebhookURL != "" {
+		rotation.Notified = true
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.notify(rotation)
+		}()
+	}
+	return rotation, nil
+}
+
+// SetWebhook registers the endpoint notified when a key of userID is
+// rotated and returns the secret its deliveries are signed with. The URL
+// must be HTTPS unless its host is on the allowlist.
+func (r *Rotator) SetWebhook(ctx context.Context, userID, keyID uuid.UUID, rawURL string) (string, error) {
+	if err := r.ValidateWebhookURL(ctx, rawURL); err != nil {
+		return "", err
+	}
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.Key{}).
+		Where("id = ? AND user_id = ? AND is_active = ?", keyID, userID, true).
+		Updates(map[string]interface{}{"rotation_webhook_url": rawURL, "rotation_webhook_secret": secret})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrKeyNotFound
+	}
+	return secret, nil
+}
+
+// RemoveWebhook unregisters the rotation webhook of a key of userID
+func (r *Rotator) RemoveWebhook(ctx context.Context, userID, keyID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.Key{}).
+		Where("id = ? AND user_id = ?", keyID, userID).
+		Updates(map[string]interface{}{"rotation_webhook_url": "", "rotation_webhook_secret": ""})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// ValidateWebhookURL rejects URLs that are not HTTPS or that point to
+// private addresses, unless their host is on the allowlist
+func (r *Rotator) ValidateWebhookURL(ctx context.Context, rawURL string) error {
+	if err := r.guard.Validate(ctx, rawURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+	}
+	u, _ := url.Parse(rawURL)
+	if u.Scheme != "https" && !r.guard.Allowed(u.Hostname()) {
+		return fmt.Errorf("%w: webhook URL must use https", ErrInvalidWebhookURL)
+	}
+	return nil
+}
+
+// Stop waits for webhook deliveries in flight
+func (r *Rotator) Stop() {
+	r.wg.Wait()
+}
+
+// notify posts the new key to the rotation webhook, retrying failed
+// deliveries
+func (r *Rotator) notify(rotation *Rotation) {
+	body, err := json.Marshal(RotationEvent{
+		Event:                EventKeyRotated,
+		KeyID:                rotation.OldKey.ID,
+		NewKeyID:             rotation.NewKey.ID,
+		Key:                  rotation.KeyValue,
+		KeyPrefix:            rotation.NewKey.KeyPrefix,
+		PreviousKeyExpiresAt: rotation.OldKeyExpiresAt,
+		RotatedAt:            rotation.NewKey.CreatedAt,
+	})
+	if err != nil {
+		r.logger.Error("Failed to encode rotation webhook", zap.Error(err))
+		return
+	}
+
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = r.deliver(rotation.NewKey.RotationWebhookURL, rotation.NewKey.RotationWebhookSecret, body)
+		if err == nil {
+			return
+		}
+		r.logger.Warn("Rotation webhook delivery failed",
+			zap.String("key_id", rotation.NewKey.ID.String()),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+		if attempt < webhookAttempts {
+			time.Sleep(r.retryDelay * time.Duration(attempt))
+		}
+	}
+}
+
+func (r *Rotator) deliver(target, secret string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.EventHeader, EventKeyRotated)
+	req.Header.Set(webhook.TimestampHeader, fmt.Sprintf("%d", timestamp))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, timestamp, body))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func auditMetadata(metadata map[string]interface{}) datatypes.JSON {
+	data, _ := json.Marshal(metadata)
+	return data
+}
@@ -0,0 +1,152 @@
+package key
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+	"github.com/amerfu/pllm/internal/infrastructure/webhook"
+)
+
+func TestRotator_Rotate(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer hook.Close()
+
+	rotator := NewRotator(db, config.KeyRotationConfig{
+		DefaultOverlap:      time.Hour,
+		MaxOverlap:          24 * time.Hour,
+		WebhookAllowedHosts: []string{"127.0.0.1"},
+	}, zap.NewNop())
+	ctx := context.Background()
+
+	user := &models.User{Email: "rotate@example.com", Username: "rotate", DexID: "rotate"}
+	require.NoError(t, db.Create(user).Error)
+
+	keyValue, keyHash, err := models.GenerateKey(models.KeyTypeAPI)
+	require.NoError(t, err)
+	rpm := 60
+	old := &models.Key{Key: keyValue, KeyHash: keyHash, Name: "ci", UserID: &user.ID, IsActive: true, RPM: &rpm, Scopes: []string{"chat"}}
+	require.NoError(t, db.Create(old).Error)
+
+	secret, err := rotator.SetWebhook(ctx, user.ID, old.ID, hook.URL)
+	require.NoError(t, err)
+
+	t.Run("other users cannot rotate the key", func(t *testing.T) {
+		_, err := rotator.Rotate(ctx, uuid.New(), old.ID, nil)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("overlap is capped", func(t *testing.T) {
+		overlap := 48 * time.Hour
+		_, err := rotator.Rotate(ctx, user.ID, old.ID, &overlap)
+		assert.ErrorIs(t, err, ErrOverlapTooLong)
+	})
+
+	rotation, err := rotator.Rotate(ctx, user.ID, old.ID, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, keyValue, rotation.KeyValue)
+	assert.Equal(t, old.ID, *rotation.NewKey.RotatedFromID)
+	assert.Equal(t, 60, *rotation.NewKey.RPM)
+	assert.Equal(t, []string{"chat"}, []string(rotation.NewKey.Scopes))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), rotation.OldKeyExpiresAt, time.Minute)
+	assert.True(t, rotation.Notified)
+
+	// The old key keeps working during the overlap
+	var reloaded models.Key
+	require.NoError(t, db.First(&reloaded, "id = ?", old.ID).Error)
+	assert.True(t, reloaded.CanUse())
+
+	// A key is rotated once; its replacement is rotated next
+	_, err = rotator.Rotate(ctx, user.ID, old.ID, nil)
+	assert.ErrorIs(t, err, ErrKeyAlreadyRotated)
+
+	select {
+	case req := <-received:
+		body := <-bodies
+		assert.Equal(t, EventKeyRotated, req.Header.Get(webhook.EventHeader))
+		ts, err := strconv.ParseInt(req.Header.Get(webhook.TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, webhook.Sign(secret, ts, body), req.Header.Get(webhook.SignatureHeader))
+
+		var event RotationEvent
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, rotation.KeyValue, event.Key)
+		assert.Equal(t, old.ID, event.KeyID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("rotation webhook was not delivered")
+	}
+
+	// Rotating without overlap revokes the previous key at once
+	zero := time.Duration(0)
+	next, err := rotator.Rotate(ctx, user.ID, rotation.NewKey.ID, &zero)
+	require.NoError(t, err)
+	require.NoError(t, db.First(&reloaded, "id = ?", rotation.NewKey.ID).Error)
+	assert.False(t, reloaded.CanUse())
+	assert.True(t, next.NewKey.CanUse())
+	rotator.Stop()
+}
+
+func TestRotator_Deliver(t *testing.T) {
+	attempts := 0
+	var signature, timestamp string
+	var body []byte
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		signature = r.Header.Get(webhook.SignatureHeader)
+		timestamp = r.Header.Get(webhook.TimestampHeader)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer hook.Close()
+
+	rotator := NewRotator(nil, config.KeyRotationConfig{WebhookAllowedHosts: []string{"127.0.0.1"}}, zap.NewNop())
+	rotator.retryDelay = time.Millisecond
+
+	rotator.notify(&Rotation{
+		OldKey:          &models.Key{BaseModel: models.BaseModel{ID: uuid.New()}},
+		NewKey:          &models.Key{BaseModel: models.BaseModel{ID: uuid.New()}, RotationWebhookURL: hook.URL, RotationWebhookSecret: "pllm_whs_test"},
+		KeyValue:        "pllm_ak_new",
+		OldKeyExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	assert.Equal(t, 2, attempts, "a failed delivery is retried")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.Sign("pllm_whs_test", ts, body), signature)
+	assert.Contains(t, string(body), `"key":"pllm_ak_new"`)
+}
+
+func TestRotator_ValidateWebhookURL(t *testing.T) {
+	rotator := NewRotator(nil, config.KeyRotationConfig{WebhookAllowedHosts: []string{"hooks.internal"}}, zap.NewNop())
+	ctx := context.Background()
+
+	assert.ErrorIs(t, rotator.ValidateWebhookURL(ctx, "http://93.184.216.34/rotated"), ErrInvalidWebhookURL, "plain HTTP")
+	assert.ErrorIs(t, rotator.ValidateWebhookURL(ctx, "https://10.0.0.1/rotated"), ErrInvalidWebhookURL, "private address")
+	assert.NoError(t, rotator.ValidateWebhookURL(ctx, "https://93.184.216.34/rotated"))
+	assert.NoError(t, rotator.ValidateWebhookURL(ctx, "http://hooks.internal/rotated"))
+}
@@ -27,6 +27,13 @@ func NewService(db *gorm.DB, logger *zap.Logger) *Service {
 
 // CreateKey creates a new API key
 func (s *Service) CreateKey(ctx context.Context, req CreateKeyRequest) (*models.Key, error) {
+	if err := models.ValidateScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateKeyEnvironment(req.Environment); err != nil {
+		return nil, err
+	}
+
 	// Generate key value and hash
 	keyValue, keyHash, err := models.GenerateKey(req.Type)
 	if err != nil {
@@ -35,18 +42,20 @@ func (s *Service) CreateKey(ctx context.Context, req CreateKeyRequest) (*models.
 
 	// Create key model
 	key := &models.Key{
-		Name:      req.Name,
-		Key:       keyValue,
-		KeyHash:   keyHash,
-		Type:      req.Type,
-		UserID:    req.UserID,
-		TeamID:    req.TeamID,
-		ExpiresAt: req.ExpiresAt,
-		TPM:       req.TPM,
-		RPM:       req.RPM,
-		MaxBudget: req.MaxBudget,
-		Scopes:    req.Scopes,
-		IsActive:  true,
+		Name:              req.Name,
+		Key:               keyValue,
+		KeyHash:           keyHash,
+		Type:              req.Type,
+		UserID:            req.UserID,
+		TeamID:            req.TeamID,
+		ExpiresAt:         req.ExpiresAt,
+		TPM:               req.TPM,
+		RPM:               req.RPM,
+		MaxBudget:         req.MaxBudget,
+		MaxCostPerRequest: req.MaxCostPerRequest,
+		Scopes:            req.Scopes,
+		Environment:       req.Environment,
+		IsActive:          true,
 	}
 
 	// Set expiry if duration provided
@@ -154,6 +163,9 @@ func (s *Service) UpdateKey(ctx context.Context, keyID string, req UpdateKeyRequ
 		key.Name = req.Name
 	}
 	if req.Scopes != nil {
+		if err := models.ValidateScopes(req.Scopes); err != nil {
+			return nil, err
+		}
 		key.Scopes = req.Scopes
 	}
 	if req.ExpiresAt != nil {
@@ -168,9 +180,18 @@ func (s *Service) UpdateKey(ctx context.Context, keyID string, req UpdateKeyRequ
 	if req.MaxBudget != nil {
 		key.MaxBudget = req.MaxBudget
 	}
+	if req.MaxCostPerRequest != nil {
+		key.MaxCostPerRequest = req.MaxCostPerRequest
+	}
 	if req.IsActive != nil {
 		key.IsActive = *req.IsActive
 	}
+	if req.Environment != nil {
+		if err := models.ValidateKeyEnvironment(*req.Environment); err != nil {
+			return nil, err
+		}
+		key.Environment = *req.Environment
+	}
 
 	// Save changes
 	if err := s.db.WithContext(ctx).Save(key).Error; err != nil {
@@ -268,41 +289,32 @@ func (s *Service) RevokeKey(ctx context.Context, keyID string, userID uuid.UUID,
 	return nil
 }
 
-// CreateDefaultKeyForUser creates a default API key for a new user
-func (s *Service) CreateDefaultKeyForUser(ctx context.Context, userID uuid.UUID, teamID uuid.UUID) (*models.Key, error) {
-	req := CreateKeyRequest{
-		Name:   "Default API Key",
-		Type:   models.KeyTypeAPI,
-		UserID: &userID,
-		TeamID: &teamID,
-		Scopes: []string{"*"}, // Full access for default key
-	}
-
-	return s.CreateKey(ctx, req)
-}
-
 // Request and response types
 type CreateKeyRequest struct {
-	Name      string         `json:"name" binding:"required"`
-	Type      models.KeyType `json:"type"`
-	UserID    *uuid.UUID     `json:"user_id"`
-	TeamID    *uuid.UUID     `json:"team_id"`
-	Duration  *int           `json:"duration"` // in seconds
-	MaxBudget *float64       `json:"max_budget"`
-	TPM       *int           `json:"tpm"`
-	RPM       *int           `json:"rpm"`
-	Scopes    []string       `json:"scopes"`
-	ExpiresAt *time.Time     `json:"expires_at"`
+	Name              string         `json:"name" binding:"required"`
+	Type              models.KeyType `json:"type"`
+	UserID            *uuid.UUID     `json:"user_id"`
+	TeamID            *uuid.UUID     `json:"team_id"`
+	Duration          *int           `json:"duration"` // in seconds
+	MaxBudget         *float64       `json:"max_budget"`
+	MaxCostPerRequest *float64       `json:"max_cost_per_request"`
+	TPM               *int           `json:"tpm"`
+	RPM               *int           `json:"rpm"`
+	Scopes            []string       `json:"scopes"`
+	ExpiresAt         *time.Time     `json:"expires_at"`
+	Environment       string         `json:"environment"`
 }
 
 type UpdateKeyRequest struct {
-	Name      string     `json:"name"`
-	Scopes    []string   `json:"scopes"`
-	ExpiresAt *time.Time `json:"expires_at"`
-	TPM       *int       `json:"tpm"`
-	RPM       *int       `json:"rpm"`
-	MaxBudget *float64   `json:"max_budget"`
-	IsActive  *bool      `json:"is_active"`
+	Name              string     `json:"name"`
+	Scopes            []string   `json:"scopes"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	TPM               *int       `json:"tpm"`
+	RPM               *int       `json:"rpm"`
+	MaxBudget         *float64   `json:"max_budget"`
+	MaxCostPerRequest *float64   `json:"max_cost_per_request"`
+	IsActive          *bool      `json:"is_active"`
+	Environment       *string    `json:"environment"`
 }
 
 type ListKeysRequest struct {
@@ -0,0 +1,142 @@
+// Package litellm converts a LiteLLM proxy configuration into pllm
+// configuration: deployments and router settings for config.yaml, and teams
+// and budgets as a promotion bundle for the database.
+package litellm
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a LiteLLM proxy config.yaml. Keys the importer does not know are
+// collected in the Extra maps so they can be reported.
+type Config struct {
+	ModelList       []Deployment           `yaml:"model_list"`
+	RouterSettings  RouterSettings         `yaml:"router_settings"`
+	LiteLLMSettings Settings               `yaml:"litellm_settings"`
+	GeneralSettings GeneralSettings        `yaml:"general_settings"`
+	Extra           map[string]interface{} `yaml:",inline"`
+}
+
+// Deployment is an entry of model_list
+type Deployment struct {
+	ModelName string                 `yaml:"model_name"`
+	Params    Params                 `yaml:"litellm_params"`
+	ModelInfo ModelInfo              `yaml:"model_info"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+// Params are the litellm_params of a deployment
+type Params struct {
+	Model        string `yaml:"model"` // "provider/model", e.g. "azure/my-deployment"
+	APIKey       string `yaml:"api_key"`
+	APIBase      string `yaml:"api_base"`
+	APIVersion   string `yaml:"api_version"`
+	Organization string `yaml:"organization"`
+
+	RPM                int     `yaml:"rpm"`
+	TPM                int     `yaml:"tpm"`
+	Timeout            float64 `yaml:"timeout"` // Seconds
+	Weight             float64 `yaml:"weight"`
+	InputCostPerToken  float64 `yaml:"input_cost_per_token"`
+	OutputCostPerToken float64 `yaml:"output_cost_per_token"`
+
+	AWSAccessKeyID     string `yaml:"aws_access_key_id"`
+	AWSSecretAccessKey string `yaml:"aws_secret_access_key"`
+	AWSSessionToken    string `yaml:"aws_session_token"`
+	AWSRegionName      string `yaml:"aws_region_name"`
+	AWSProfileName     string `yaml:"aws_profile_name"`
+	AWSRoleName        string `yaml:"aws_role_name"`
+	AWSSessionName     string `yaml:"aws_session_name"`
+
+	VertexProject  string `yaml:"vertex_project"`
+	VertexLocation string `yaml:"vertex_location"`
+
+	ProjectID string `yaml:"project_id"` // watsonx.ai
+	SpaceID   string `yaml:"space_id"`
+
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// ModelInfo is the model_info of a deployment
+type ModelInfo struct {
+	ID                      string                 `yaml:"id"`
+	Mode                    string                 `yaml:"mode"`
+	BaseModel               string                 `yaml:"base_model"`
+	MaxTokens               int                    `yaml:"max_tokens"`
+	MaxInputTokens          int                    `yaml:"max_input_tokens"`
+	MaxOutputTokens         int                    `yaml:"max_output_tokens"`
+	SupportsVision          bool                   `yaml:"supports_vision"`
+	SupportsFunctionCalling bool                   `yaml:"supports_function_calling"`
+	Extra                   map[string]interface{} `yaml:",inline"`
+}
+
+// Fallbacks is LiteLLM's list of single-key maps from a model to the models
+// tried after it
+type Fallbacks []map[string][]string
+
+// RouterSettings are the router_settings of the proxy
+type RouterSettings struct {
+	RoutingStrategy string                 `yaml:"routing_strategy"`
+	NumRetries      *int                   `yaml:"num_retries"`
+	Timeout         float64                `yaml:"timeout"` // Seconds
+	AllowedFails    int                    `yaml:"allowed_fails"`
+	Fallbacks       Fallbacks              `yaml:"fallbacks"`
+	Extra           map[string]interface{} `yaml:",inline"`
+}
+
+// Settings are the litellm_settings of the proxy
+type Settings struct {
+	NumRetries          *int                   `yaml:"num_retries"`
+	RequestTimeout      float64                `yaml:"request_timeout"` // Seconds
+	Fallbacks           Fallbacks              `yaml:"fallbacks"`
+	MaxBudget           float64                `yaml:"max_budget"`
+	BudgetDuration      string                 `yaml:"budget_duration"`
+	DefaultTeamSettings []TeamSettings         `yaml:"default_team_settings"`
+	Extra               map[string]interface{} `yaml:",inline"`
+}
+
+// TeamSettings is an entry of default_team_settings
+type TeamSettings struct {
+	TeamID         string                 `yaml:"team_id"`
+	TeamAlias      string                 `yaml:"team_alias"`
+	MaxBudget      float64                `yaml:"max_budget"`
+	BudgetDuration string                 `yaml:"budget_duration"`
+	TPMLimit       int                    `yaml:"tpm_limit"`
+	RPMLimit       int                    `yaml:"rpm_limit"`
+	Models         []string               `yaml:"models"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+// GeneralSettings are the general_settings of the proxy
+type GeneralSettings struct {
+	MasterKey   string                 `yaml:"master_key"`
+	DatabaseURL string                 `yaml:"database_url"`
+	Extra       map[string]interface{} `yaml:",inline"`
+}
+
+// Parse reads a LiteLLM proxy config.yaml
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse LiteLLM config: %w", err)
+	}
+	if len(cfg.ModelList) == 0 {
+		return nil, fmt.Errorf("LiteLLM config has no model_list")
+	}
+	return &cfg, nil
+}
+
+// envPrefix is how LiteLLM refers to environment variables
+const envPrefix = "os.environ/"
+
+// envRef rewrites LiteLLM's os.environ/NAME references as ${NAME}, which pllm
+// expands when loading its configuration
+func envRef(value string) string {
+	if name, ok := strings.CutPrefix(value, envPrefix); ok {
+		return "${" + name + "}"
+	}
+	return value
+}
@@ -0,0 +1,464 @@
+package litellm
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/integrations/promotion"
+)
+
+// Finding is a part of the LiteLLM config that was not converted, or was
+// converted with a caveat
+type Finding struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Result is a converted LiteLLM config
+type Result struct {
+	Config   *GatewayConfig    `json:"config"`
+	Bundle   *promotion.Bundle `json:"bundle"` // Teams and budgets, applied like "pllm config import"
+	Findings []Finding         `json:"findings"`
+}
+
+// GatewayConfig is the part of a pllm config.yaml the importer writes
+type GatewayConfig struct {
+	Database  *DatabaseConfig `yaml:"database,omitempty"`
+	Auth      *AuthConfig     `yaml:"auth,omitempty"`
+	Router    RouterConfig    `yaml:"router"`
+	ModelList []ModelConfig   `yaml:"model_list"`
+}
+
+// DatabaseConfig is the database section of config.yaml
+type DatabaseConfig struct {
+	URL string `yaml:"url"`
+}
+
+// AuthConfig is the auth section of config.yaml
+type AuthConfig struct {
+	MasterKey string `yaml:"master_key"`
+}
+
+// RouterConfig is the router section of config.yaml
+type RouterConfig struct {
+	RoutingStrategy     string              `yaml:"routing_strategy,omitempty"`
+	MaxRetries          *int                `yaml:"max_retries,omitempty"`
+	DefaultTimeout      string              `yaml:"default_timeout,omitempty"`
+	AllowedFailures     int                 `yaml:"allowed_failures,omitempty"`
+	EnableLoadBalancing bool                `yaml:"enable_load_balancing"`
+	EnableModelFallback bool                `yaml:"enable_model_fallback,omitempty"`
+	Fallbacks           map[string][]string `yaml:"fallbacks,omitempty"`
+}
+
+// ModelConfig is a model_list entry of config.yaml
+type ModelConfig struct {
+	ModelName          string         `yaml:"model_name"`
+	Provider           ProviderConfig `yaml:"provider"`
+	ModelInfo          *ModelInfoSpec `yaml:"model_info,omitempty"`
+	RPM                int            `yaml:"rpm,omitempty"`
+	TPM                int            `yaml:"tpm,omitempty"`
+	Timeout            string         `yaml:"timeout,omitempty"`
+	Weight             float64        `yaml:"weight,omitempty"`
+	InputCostPerToken  float64        `yaml:"input_cost_per_token,omitempty"`
+	OutputCostPerToken float64        `yaml:"output_cost_per_token,omitempty"`
+}
+
+// ProviderConfig is the provider of a model_list entry
+type ProviderConfig struct {
+	Type               string `yaml:"type"`
+	Model              string `yaml:"model"`
+	APIKey             string `yaml:"api_key,omitempty"`
+	BaseURL            string `yaml:"base_url,omitempty"`
+	APIVersion         string `yaml:"api_version,omitempty"`
+	OrgID              string `yaml:"org_id,omitempty"`
+	ProjectID          string `yaml:"project_id,omitempty"`
+	SpaceID            string `yaml:"space_id,omitempty"`
+	AzureDeployment    string `yaml:"azure_deployment,omitempty"`
+	AzureEndpoint      string `yaml:"azure_endpoint,omitempty"`
+	AzureBaseModel     string `yaml:"azure_base_model,omitempty"`
+	AWSAccessKeyID     string `yaml:"aws_access_key_id,omitempty"`
+	AWSSecretAccessKey string `yaml:"aws_secret_access_key,omitempty"`
+	AWSSessionToken    string `yaml:"aws_session_token,omitempty"`
+	AWSRegionName      string `yaml:"aws_region_name,omitempty"`
+	AWSProfileName     string `yaml:"aws_profile_name,omitempty"`
+	AWSRoleARN         string `yaml:"aws_role_arn,omitempty"`
+	AWSRoleSessionName string `yaml:"aws_role_session_name,omitempty"`
+	VertexProject      string `yaml:"vertex_project,omitempty"`
+	VertexLocation     string `yaml:"vertex_location,omitempty"`
+}
+
+// ModelInfoSpec is the model_info of a model_list entry
+type ModelInfoSpec struct {
+	Mode              string `yaml:"mode"`
+	SupportsFunctions bool   `yaml:"supports_functions"`
+	SupportsVision    bool   `yaml:"supports_vision"`
+	SupportsStreaming bool   `yaml:"supports_streaming"`
+	MaxTokens         int    `yaml:"max_tokens,omitempty"`
+	MaxInputTokens    int    `yaml:"max_input_tokens,omitempty"`
+	MaxOutputTokens   int    `yaml:"max_output_tokens,omitempty"`
+}
+
+// routingStrategies maps LiteLLM routing strategies to pllm's
+var routingStrategies = map[string]string{
+	"simple-shuffle":          "weighted",
+	"least-busy":              "least-busy",
+	"usage-based-routing":     "usage-based",
+	"usage-based-routing-v2":  "usage-based",
+	"latency-based-routing":   "latency-based",
+	"cost-based-routing":      "",
+	"provider-budget-routing": "",
+}
+
+// nativeProviders maps LiteLLM provider prefixes to pllm provider types
+var nativeProviders = map[string]string{
+	"openai":      "openai",
+	"azure":       "azure",
+	"anthropic":   "anthropic",
+	"bedrock":     "bedrock",
+	"vertex_ai":   "vertex",
+	"openrouter":  "openrouter",
+	"nvidia_nim":  "nim",
+	"triton":      "triton",
+	"watsonx":     "watsonx",
+	"dashscope":   "dashscope",
+	"perplexity":  "perplexity",
+	"hosted_vllm": "openai",
+}
+
+// openAICompatible are LiteLLM providers served through pllm's openai
+// provider at their OpenAI-compatible endpoint
+var openAICompatible = map[string]string{
+	"groq":         "https://api.groq.com/openai/v1",
+	"together_ai":  "https://api.together.xyz/v1",
+	"deepseek":     "https://api.deepseek.com/v1",
+	"mistral":      "https://api.mistral.ai/v1",
+	"fireworks_ai": "https://api.fireworks.ai/inference/v1",
+	"xai":          "https://api.x.ai/v1",
+}
+
+// Convert converts a LiteLLM proxy config. Everything that cannot be
+// expressed in pllm is reported as a finding instead of failing.
+func Convert(cfg *Config) *Result {
+	c := &converter{
+		result: &Result{
+			Config: &GatewayConfig{Router: RouterConfig{EnableLoadBalancing: true}},
+			Bundle: &promotion.Bundle{Version: promotion.BundleVersion, ExportedAt: time.Now().UTC()},
+		},
+	}
+
+	c.unknown("", cfg.Extra)
+	for i, deployment := range cfg.ModelList {
+		c.deployment(fmt.Sprintf("model_list[%d]", i), deployment)
+	}
+	c.router(cfg.RouterSettings, cfg.LiteLLMSettings)
+	c.settings(cfg.LiteLLMSettings)
+	c.general(cfg.GeneralSettings)
+
+	c.report("keys", "virtual keys are stored in the LiteLLM database, not its config; issue new keys with \"pllm key generate\"")
+	return c.result
+}
+
+type converter struct {
+	result *Result
+}
+
+func (c *converter) report(path, format string, args ...interface{}) {
+	c.result.Findings = append(c.result.Findings, Finding{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// unknown reports the keys the importer does not convert, in key order
+func (c *converter) unknown(path string, extra map[string]interface{}) {
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c.report(joinPath(path, key), "not supported, ignored")
+	}
+}
+
+// secret rewrites an environment reference in a field pllm does not expand
+// variables in, reporting it
+func (c *converter) secret(path, value string) string {
+	ref := envRef(value)
+	if ref != value {
+		c.report(path, "pllm only expands %s in api_key; set the value here or in a user model", ref)
+	}
+	return ref
+}
+
+func (c *converter) deployment(path string, d Deployment) {
+	c.unknown(path, d.Extra)
+	c.unknown(path+".litellm_params", d.Params.Extra)
+	c.unknown(path+".model_info", d.ModelInfo.Extra)
+
+	if d.ModelName == "" || d.Params.Model == "" {
+		c.report(path, "model_name and litellm_params.model are required, deployment skipped")
+		return
+	}
+
+	p := d.Params
+	prefix, model, found := strings.Cut(p.Model, "/")
+	if !found {
+		prefix, model = "openai", p.Model
+	}
+
+	provider := ProviderConfig{
+		Model:      model,
+		APIKey:     envRef(p.APIKey),
+		BaseURL:    c.secret(path+".litellm_params.api_base", p.APIBase),
+		APIVersion: p.APIVersion,
+		OrgID:      c.secret(path+".litellm_params.organization", p.Organization),
+	}
+	if providerType, ok := nativeProviders[prefix]; ok {
+		provider.Type = providerType
+	} else if baseURL, ok := openAICompatible[prefix]; ok {
+		provider.Type = "openai"
+		if provider.BaseURL == "" {
+			provider.BaseURL = baseURL
+		}
+	} else {
+		c.report(path+".litellm_params.model", "provider %q is not supported, deployment skipped", prefix)
+		return
+	}
+
+	switch provider.Type {
+	case "openai":
+		if prefix == "hosted_vllm" && provider.BaseURL == "" {
+			c.report(path+".litellm_params.api_base", "hosted_vllm needs api_base, deployment skipped")
+			return
+		}
+	case "azure":
+		provider.AzureDeployment = model
+		provider.AzureEndpoint = provider.BaseURL
+		// LiteLLM's base_model carries the provider prefix, e.g. "azure/gpt-4o"
+		provider.AzureBaseModel = strings.TrimPrefix(d.ModelInfo.BaseModel, "azure/")
+	case "openrouter":
+		// pllm keeps the vendor prefix, e.g. "openai/gpt-4o"
+	case "bedrock":
+		provider.AWSAccessKeyID = c.secret(path+".litellm_params.aws_access_key_id", p.AWSAccessKeyID)
+		provider.AWSSecretAccessKey = c.secret(path+".litellm_params.aws_secret_access_key", p.AWSSecretAccessKey)
+		provider.AWSSessionToken = c.secret(path+".litellm_params.aws_session_token", p.AWSSessionToken)
+		provider.AWSRegionName = c.secret(path+".litellm_params.aws_region_name", p.AWSRegionName)
+		provider.AWSProfileName = p.AWSProfileName
+		provider.AWSRoleARN = p.AWSRoleName
+		provider.AWSRoleSessionName = p.AWSSessionName
+	case "vertex":
+		provider.VertexProject = c.secret(path+".litellm_params.vertex_project", p.VertexProject)
+		provider.VertexLocation = c.secret(path+".litellm_params.vertex_location", p.VertexLocation)
+	case "watsonx":
+		provider.ProjectID = c.secret(path+".litellm_params.project_id", p.ProjectID)
+		provider.SpaceID = p.SpaceID
+	}
+
+	entry := ModelConfig{
+		ModelName:          d.ModelName,
+		Provider:           provider,
+		RPM:                p.RPM,
+		TPM:                p.TPM,
+		Timeout:            seconds(p.Timeout),
+		Weight:             p.Weight,
+		InputCostPerToken:  p.InputCostPerToken,
+		OutputCostPerToken: p.OutputCostPerToken,
+	}
+	if info := d.ModelInfo; info.Mode != "" || info.MaxTokens != 0 || info.MaxInputTokens != 0 || info.SupportsVision {
+		mode := info.Mode
+		if mode == "" {
+			mode = "chat"
+		}
+		entry.ModelInfo = &ModelInfoSpec{
+			Mode:              mode,
+			SupportsFunctions: info.SupportsFunctionCalling,
+			SupportsVision:    info.SupportsVision,
+			SupportsStreaming: mode == "chat" || mode == "completion",
+			MaxTokens:         info.MaxTokens,
+			MaxInputTokens:    info.MaxInputTokens,
+			MaxOutputTokens:   info.MaxOutputTokens,
+		}
+		if entry.ModelInfo.MaxTokens == 0 {
+			entry.ModelInfo.MaxTokens = info.MaxInputTokens
+		}
+	}
+	c.result.Config.ModelList = append(c.result.Config.ModelList, entry)
+}
+
+// router converts the router settings; litellm_settings override them for
+// retries, timeout and fallbacks as in LiteLLM
+func (c *converter) router(r RouterSettings, s Settings) {
+	c.unknown("router_settings", r.Extra)
+	router := &c.result.Config.Router
+
+	if r.RoutingStrategy != "" {
+		strategy, ok := routingStrategies[r.RoutingStrategy]
+		switch {
+		case !ok:
+			c.report("router_settings.routing_strategy", "unknown strategy %q, pllm's default (priority) is used", r.RoutingStrategy)
+		case strategy == "":
+			c.report("router_settings.routing_strategy", "%q is not supported, pllm's default (priority) is used", r.RoutingStrategy)
+		default:
+			router.RoutingStrategy = strategy
+		}
+	}
+
+	router.MaxRetries = r.NumRetries
+	if s.NumRetries != nil {
+		router.MaxRetries = s.NumRetries
+	}
+	router.DefaultTimeout = seconds(r.Timeout)
+	if s.RequestTimeout > 0 {
+		router.DefaultTimeout = seconds(s.RequestTimeout)
+	}
+	router.AllowedFailures = r.AllowedFails
+
+	fallbacks := append(Fallbacks{}, r.Fallbacks...)
+	fallbacks = append(fallbacks, s.Fallbacks...)
+	for _, entry := range fallbacks {
+		for model, chain := range entry {
+			if router.Fallbacks == nil {
+				router.Fallbacks = make(map[string][]string)
+			}
+			router.Fallbacks[model] = chain
+		}
+	}
+	router.EnableModelFallback = len(router.Fallbacks) > 0
+}
+
+func (c *converter) settings(s Settings) {
+	c.unknown("litellm_settings", s.Extra)
+
+	if s.MaxBudget > 0 {
+		period, ok := budgetPeriod(s.BudgetDuration)
+		if !ok {
+			c.report("litellm_settings.budget_duration", "%q has no pllm budget period, monthly is used", s.BudgetDuration)
+		}
+		c.result.Bundle.Budgets = append(c.result.Bundle.Budgets, promotion.BudgetSpec{
+			Name:     "LiteLLM proxy budget",
+			Type:     string(models.BudgetTypeGlobal),
+			Amount:   s.MaxBudget,
+			Period:   string(period),
+			AlertAt:  80,
+			IsActive: true,
+		})
+	}
+
+	for i, team := range s.DefaultTeamSettings {
+		path := fmt.Sprintf("litellm_settings.default_team_settings[%d]", i)
+		c.unknown(path, team.Extra)
+
+		name := team.TeamAlias
+		if name == "" {
+			name = team.TeamID
+		}
+		if name == "" {
+			c.report(path, "team_id or team_alias is required, team skipped")
+			continue
+		}
+		period, ok := budgetPeriod(team.BudgetDuration)
+		if !ok {
+			c.report(path+".budget_duration", "%q has no pllm budget period, monthly is used", team.BudgetDuration)
+		}
+		c.result.Bundle.Teams = append(c.result.Bundle.Teams, promotion.TeamSpec{
+			Name:           name,
+			IsActive:       true,
+			MaxBudget:      team.MaxBudget,
+			BudgetDuration: string(period),
+			BudgetAlertAt:  80,
+			TPM:            team.TPMLimit,
+			RPM:            team.RPMLimit,
+			AllowedModels:  team.Models,
+		})
+	}
+}
+
+func (c *converter) general(g GeneralSettings) {
+	c.unknown("general_settings", g.Extra)
+
+	if g.MasterKey != "" {
+		if name, ok := strings.CutPrefix(g.MasterKey, envPrefix); ok {
+			c.report("general_settings.master_key", "set PLLM_MASTER_KEY to the value of %s", name)
+		} else {
+			c.result.Config.Auth = &AuthConfig{MasterKey: g.MasterKey}
+			c.report("general_settings.master_key", "copied as a literal; prefer setting PLLM_MASTER_KEY")
+		}
+	}
+	if g.DatabaseURL != "" {
+		if name, ok := strings.CutPrefix(g.DatabaseURL, envPrefix); ok {
+			if name != "DATABASE_URL" {
+				c.report("general_settings.database_url", "set DATABASE_URL to the value of %s", name)
+			}
+		} else {
+			c.result.Config.Database = &DatabaseConfig{URL: g.DatabaseURL}
+			c.report("general_settings.database_url", "pllm creates its own tables; use a separate database from LiteLLM's")
+		}
+	}
+}
+
+// budgetPeriod maps a LiteLLM budget duration ("30d", "1mo", "24h") to a pllm
+// budget period. Empty durations are monthly.
+func budgetPeriod(duration string) (models.BudgetPeriod, bool) {
+	switch strings.TrimSpace(duration) {
+	case "", "30d", "1mo", "31d":
+		return models.BudgetPeriodMonthly, true
+	case "1d", "24h":
+		return models.BudgetPeriodDaily, true
+	case "7d", "1w":
+		return models.BudgetPeriodWeekly, true
+	case "365d", "1y", "12mo":
+		return models.BudgetPeriodYearly, true
+	}
+	return models.BudgetPeriodMonthly, false
+}
+
+// seconds formats LiteLLM's timeouts in seconds as a duration
+func seconds(value float64) string {
+	if value <= 0 {
+		return ""
+	}
+	return (time.Duration(value * float64(time.Second))).String()
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// EncodeConfig writes the converted configuration as config.yaml
+func (r *Result) EncodeConfig(source string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Converted from LiteLLM config %s by \"pllm migrate from-litellm\".\n", source)
+	fmt.Fprintf(&buf, "# %d findings were reported; review them before deploying.\n", len(r.Findings))
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(r.Config); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// String summarizes the conversion and lists the findings
+func (r *Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Converted %d deployments, %d teams and %d budgets.\n",
+		len(r.Config.ModelList), len(r.Bundle.Teams), len(r.Bundle.Budgets))
+	if len(r.Findings) == 0 {
+		return b.String()
+	}
+	b.WriteString("\nNot converted or needing attention:\n")
+	for _, finding := range r.Findings {
+		b.WriteString("  " + finding.Path + ": " + finding.Message + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package litellm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const liteLLMConfig = `
+model_list:
+  - model_name: gpt-4o
+    litellm_params:
+      model: azure/gpt-4o-prod
+      api_base: https://example.openai.azure.com
+      api_key: os.environ/AZURE_API_KEY
+      api_version: "2024-06-01"
+      rpm: 600
+      timeout: 30
+    model_info:
+      base_model: azure/gpt-4o
+  - model_name: gpt-4o
+    litellm_params:
+      model: gpt-4o
+      api_key: os.environ/OPENAI_API_KEY
+  - model_name: llama
+    litellm_params:
+      model: groq/llama3-70b-8192
+      api_key: os.environ/GROQ_API_KEY
+  - model_name: titan
+    litellm_params:
+      model: bedrock/amazon.titan-text-express-v1
+      aws_region_name: os.environ/AWS_REGION
+  - model_name: command
+    litellm_params:
+      model: cohere/command-r
+router_settings:
+  routing_strategy: cost-based-routing
+  num_retries: 2
+  timeout: 30
+  redis_host: localhost
+litellm_settings:
+  num_retries: 3
+  request_timeout: 600
+  fallbacks: [{"gpt-4o": ["llama"]}]
+  max_budget: 1000
+  budget_duration: 30d
+  default_team_settings:
+    - team_id: research
+      max_budget: 100
+      budget_duration: 2d
+      rpm_limit: 60
+      models: [gpt-4o]
+      success_callback: ["langfuse"]
+general_settings:
+  master_key: sk-1234
+  database_url: os.environ/DATABASE_URL
+`
+
+func convertTestConfig(t *testing.T) *Result {
+	t.Helper()
+	cfg, err := Parse([]byte(liteLLMConfig))
+	require.NoError(t, err)
+	return Convert(cfg)
+}
+
+func findingPaths(result *Result) []string {
+	paths := make([]string, 0, len(result.Findings))
+	for _, finding := range result.Findings {
+		paths = append(paths, finding.Path)
+	}
+	return paths
+}
+
+func TestConvert_Deployments(t *testing.T) {
+	result := convertTestConfig(t)
+	deployments := result.Config.ModelList
+	require.Len(t, deployments, 4, "the cohere deployment is skipped")
+
+	azure := deployments[0]
+	assert.Equal(t, "gpt-4o", azure.ModelName)
+	assert.Equal(t, "azure", azure.Provider.Type)
+	assert.Equal(t, "gpt-4o-prod", azure.Provider.AzureDeployment)
+	assert.Equal(t, "https://example.openai.azure.com", azure.Provider.AzureEndpoint)
+	assert.Equal(t, "gpt-4o", azure.Provider.AzureBaseModel)
+	assert.Equal(t, "${AZURE_API_KEY}", azure.Provider.APIKey)
+	assert.Equal(t, 600, azure.RPM)
+	assert.Equal(t, "30s", azure.Timeout)
+
+	openai := deployments[1]
+	assert.Equal(t, "openai", openai.Provider.Type, "models without a prefix are OpenAI models")
+	assert.Equal(t, "gpt-4o", openai.Provider.Model)
+
+	groq := deployments[2]
+	assert.Equal(t, "openai", groq.Provider.Type)
+	assert.Equal(t, "https://api.groq.com/openai/v1", groq.Provider.BaseURL)
+	assert.Equal(t, "llama3-70b-8192", groq.Provider.Model)
+
+	bedrock := deployments[3]
+	assert.Equal(t, "bedrock", bedrock.Provider.Type)
+	assert.Equal(t, "${AWS_REGION}", bedrock.Provider.AWSRegionName)
+
+	paths := findingPaths(result)
+	assert.Contains(t, paths, "model_list[3].litellm_params.aws_region_name", "only api_key expands variables")
+	assert.Contains(t, paths, "model_list[4].litellm_params.model")
+}
+
+func TestConvert_Router(t *testing.T) {
+	result := convertTestConfig(t)
+	router := result.Config.Router
+
+	assert.Empty(t, router.RoutingStrategy, "cost-based routing has no pllm equivalent")
+	require.NotNil(t, router.MaxRetries)
+	assert.Equal(t, 3, *router.MaxRetries, "litellm_settings override router_settings")
+	assert.Equal(t, "10m0s", router.DefaultTimeout)
+	assert.Equal(t, map[string][]string{"gpt-4o": {"llama"}}, router.Fallbacks)
+	assert.True(t, router.EnableModelFallback)
+
+	paths := findingPaths(result)
+	assert.Contains(t, paths, "router_settings.routing_strategy")
+	assert.Contains(t, paths, "router_settings.redis_host")
+}
+
+func TestConvert_TeamsAndBudgets(t *testing.T) {
+	result := convertTestConfig(t)
+
+	require.Len(t, result.Bundle.Budgets, 1)
+	budget := result.Bundle.Budgets[0]
+	assert.Equal(t, "global", budget.Type)
+	assert.Equal(t, 1000.0, budget.Amount)
+	assert.Equal(t, "monthly", budget.Period)
+
+	require.Len(t, result.Bundle.Teams, 1)
+	team := result.Bundle.Teams[0]
+	assert.Equal(t, "research", team.Name)
+	assert.Equal(t, 100.0, team.MaxBudget)
+	assert.Equal(t, "monthly", team.BudgetDuration, "durations without a pllm period fall back to monthly")
+	assert.Equal(t, 60, team.RPM)
+	assert.Equal(t, []string{"gpt-4o"}, team.AllowedModels)
+
+	paths := findingPaths(result)
+	assert.Contains(t, paths, "litellm_settings.default_team_settings[0].budget_duration")
+	assert.Contains(t, paths, "litellm_settings.default_team_settings[0].success_callback")
+	assert.Contains(t, paths, "keys")
+}
+
+func TestConvert_GeneralSettings(t *testing.T) {
+	result := convertTestConfig(t)
+
+	require.NotNil(t, result.Config.Auth)
+	assert.Equal(t, "sk-1234", result.Config.Auth.MasterKey)
+	assert.Nil(t, result.Config.Database, "DATABASE_URL is read by pllm as well")
+}
+
+func TestResult_EncodeConfig(t *testing.T) {
+	result := convertTestConfig(t)
+
+	data, err := result.EncodeConfig("litellm.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Converted from LiteLLM config litellm.yaml")
+
+	var decoded GatewayConfig
+	require.NoError(t, yaml.Unmarshal(data, &decoded))
+	assert.Equal(t, *result.Config, decoded)
+}
+
+func TestParse_RequiresModelList(t *testing.T) {
+	_, err := Parse([]byte("general_settings:\n  master_key: sk-1234\n"))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte("model_list: ["))
+	assert.Error(t, err)
+}
@@ -175,12 +175,23 @@ func (s *Service) ConvertToModelInstance(um models.UserModel) config.ModelInstan
 		Location:           um.ProviderConfig.Location,
 		AzureDeployment:    um.ProviderConfig.AzureDeployment,
 		AzureEndpoint:      um.ProviderConfig.AzureEndpoint,
+		AzureBaseModel:     um.ProviderConfig.AzureBaseModel,
 		AWSAccessKeyID:     expandEnvVars(um.ProviderConfig.AWSAccessKeyID),
 		AWSSecretAccessKey: expandEnvVars(um.ProviderConfig.AWSSecretAccessKey),
 		AWSRegionName:      um.ProviderConfig.AWSRegionName,
+		AWSSessionToken:    expandEnvVars(um.ProviderConfig.AWSSessionToken),
+		AWSProfileName:     um.ProviderConfig.AWSProfileName,
+		AWSRoleARN:         um.ProviderConfig.AWSRoleARN,
+		AWSExternalID:      um.ProviderConfig.AWSExternalID,
+		AWSRoleSessionName: um.ProviderConfig.AWSRoleSessionName,
 		VertexProject:      um.ProviderConfig.VertexProject,
 		VertexLocation:     um.ProviderConfig.VertexLocation,
+		SpaceID:            um.ProviderConfig.SpaceID,
+		IAMURL:             um.ProviderConfig.IAMURL,
+		EmbeddingInputType: um.ProviderConfig.EmbeddingInputType,
 		ReasoningEffort:    um.ProviderConfig.ReasoningEffort,
+		Headers:            expandHeaders(um.ProviderConfig.Headers),
+		Paths:              um.ProviderConfig.Paths,
 	}
 
 	modelInfo := config.ModelInfo{
@@ -193,6 +204,17 @@ func (s *Service) ConvertToModelInstance(um models.UserModel) config.ModelInstan
 		MaxOutputTokens:    um.ModelInfoConfig.MaxOutputTokens,
 		DefaultMaxTokens:   um.ModelInfoConfig.DefaultMaxTokens,
 		SupportedLanguages: um.ModelInfoConfig.SupportedLanguages,
+
+		EmbeddingDimensions: um.ModelInfoConfig.EmbeddingDimensions,
+		SupportsDimensions:  um.ModelInfoConfig.SupportsDimensions,
+
+		Description: um.ModelInfoConfig.Description,
+		IntendedUse: um.ModelInfoConfig.IntendedUse,
+		Owner:       um.ModelInfoConfig.Owner,
+		Contacts:    um.ModelInfoConfig.Contacts,
+	}
+	for _, link := range um.ModelInfoConfig.Links {
+		modelInfo.Links = append(modelInfo.Links, config.ModelLink{Title: link.Title, URL: link.URL})
 	}
 
 	// Set defaults for model info if not specified
@@ -246,3 +268,16 @@ func expandEnvVars(s string) string {
 		return match // Return original if env var not set
 	})
 }
+
+// expandHeaders expands environment variable references in header values,
+// which often carry credentials
+func expandHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	expanded := make(map[string]string, len(headers))
+	for key, value := range headers {
+		expanded[key] = expandEnvVars(value)
+	}
+	return expanded
+}
@@ -0,0 +1,143 @@
+package onboarding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/integrations/key"
+	"github.com/amerfu/pllm/internal/services/integrations/team"
+)
+
+// Service onboards users auto-provisioned from Dex with the organization's
+// onboarding templates: the auto-join rules matching their email domain and
+// groups, or else the template selected by their groups, decide the teams
+// they join, their budget in each and the key they receive.
+type Service struct {
+	templates config.OnboardingConfig
+	teams     *team.TeamService
+	keys      *key.Service
+	logger    *zap.Logger
+}
+
+// NewService creates an onboarding service
+func NewService(templates config.OnboardingConfig, teams *team.TeamService, keys *key.Service, logger *zap.Logger) *Service {
+	return &Service{
+		templates: templates,
+		teams:     teams,
+		keys:      keys,
+		logger:    logger,
+	}
+}
+
+// ProvisionedRole returns the global role of a user about to be provisioned
+func (s *Service) ProvisionedRole(email string, groups []string) models.UserRole {
+	return models.UserRole(s.templates.RoleFor(email, groups))
+}
+
+// OnboardUser adds a newly provisioned user to the team of every auto-join
+// rule they match, or of the template matching their groups when they match
+// none. The first team joined gets the user's onboarding key.
+func (s *Service) OnboardUser(ctx context.Context, user *models.User, groups []string) error {
+	rules := s.templates.AutoJoinRules(user.Email, groups)
+	if len(rules) == 0 {
+		if s.templates.SkipUnmatched {
+			s.logger.Info("No auto-join rule matches user, skipping onboarding",
+				zap.String("email", user.Email))
+			return nil
+		}
+		name, _ := s.templates.TemplateForGroups(groups)
+		rules = []config.AutoJoinRule{{Template: name}}
+	}
+
+	var primary *models.TeamMember
+	var primaryTmpl config.OnboardingTemplate
+	joined := make(map[string]bool)
+	for _, rule := range rules {
+		name, tmpl := s.templates.Template(rule.Template)
+		if joined[name] {
+			continue
+		}
+		joined[name] = true
+
+		member, err := s.teams.AddUserFromTemplate(ctx, name, user.ID, teamRole(user, rule))
+		if err != nil {
+			return fmt.Errorf("failed to add user to team %q: %w", tmpl.TeamName, err)
+		}
+		s.logger.Info("Onboarded user",
+			zap.String("email", user.Email),
+			zap.String("template", name),
+			zap.String("team_id", member.TeamID.String()),
+			zap.String("team_role", string(member.Role)))
+
+		if primary == nil {
+			primary, primaryTmpl = member, tmpl
+		}
+	}
+
+	if primaryTmpl.Key.Disabled {
+		return nil
+	}
+
+	k, err := s.keys.CreateKey(ctx, keyRequest(primaryTmpl.Key, user.ID, primary.TeamID))
+	if err != nil {
+		return fmt.Errorf("failed to create onboarding key: %w", err)
+	}
+	s.logger.Info("Created onboarding key for user",
+		zap.String("email", user.Email),
+		zap.String("key_id", k.ID.String()))
+
+	return nil
+}
+
+// teamRole returns the role a user joins a rule's team with: the rule's
+// team_role, or admin for admins and member for everyone else
+func teamRole(user *models.User, rule config.AutoJoinRule) models.TeamRole {
+	if role := models.TeamRole(strings.ToLower(rule.TeamRole)); models.ValidTeamRole(role) {
+		return role
+	}
+	if user.Role == models.RoleAdmin {
+		return models.TeamRoleAdmin
+	}
+	return models.TeamRoleMember
+}
+
+// keyRequest builds the creation request for a user's onboarding key.
+// Zero limits are left unset.
+func keyRequest(tmpl config.KeyTemplate, userID, teamID uuid.UUID) key.CreateKeyRequest {
+	req := key.CreateKeyRequest{
+		Name:   tmpl.Name,
+		Type:   models.KeyTypeAPI,
+		UserID: &userID,
+		TeamID: &teamID,
+		Scopes: tmpl.Scopes,
+	}
+	if req.Name == "" {
+		req.Name = "Default API Key"
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{"*"}
+	}
+	if tmpl.MaxBudget > 0 {
+		req.MaxBudget = &tmpl.MaxBudget
+	}
+	if tmpl.MaxCostPerRequest > 0 {
+		req.MaxCostPerRequest = &tmpl.MaxCostPerRequest
+	}
+	if tmpl.TPM > 0 {
+		req.TPM = &tmpl.TPM
+	}
+	if tmpl.RPM > 0 {
+		req.RPM = &tmpl.RPM
+	}
+	if tmpl.Duration > 0 {
+		seconds := int(tmpl.Duration.Seconds())
+		req.Duration = &seconds
+	}
+	return req
+}
@@ -0,0 +1,154 @@
+package onboarding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+	"github.com/amerfu/pllm/internal/services/integrations/key"
+	"github.com/amerfu/pllm/internal/services/integrations/team"
+)
+
+func TestKeyRequest(t *testing.T) {
+	userID, teamID := uuid.New(), uuid.New()
+
+	req := keyRequest(config.KeyTemplate{}, userID, teamID)
+	assert.Equal(t, "Default API Key", req.Name)
+	assert.Equal(t, []string{"*"}, req.Scopes)
+	assert.Nil(t, req.MaxBudget)
+	assert.Nil(t, req.TPM)
+	assert.Nil(t, req.Duration)
+
+	req = keyRequest(config.KeyTemplate{
+		Name:      "Research key",
+		Scopes:    []string{"chat"},
+		MaxBudget: 20,
+		RPM:       30,
+		Duration:  24 * time.Hour,
+	}, userID, teamID)
+	assert.Equal(t, "Research key", req.Name)
+	assert.Equal(t, []string{"chat"}, req.Scopes)
+	require.NotNil(t, req.MaxBudget)
+	assert.Equal(t, 20.0, *req.MaxBudget)
+	require.NotNil(t, req.RPM)
+	assert.Equal(t, 30, *req.RPM)
+	require.NotNil(t, req.Duration)
+	assert.Equal(t, 86400, *req.Duration)
+	assert.Equal(t, &teamID, req.TeamID)
+}
+
+func TestService_OnboardUser(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	templates := config.OnboardingConfig{
+		Templates: map[string]config.OnboardingTemplate{
+			"research": {
+				Groups: []string{"ml-research"},
+				Team: config.TeamTemplate{
+					MaxBudget:      500,
+					BudgetDuration: "monthly",
+					RPM:            200,
+					AllowedModels:  []string{"gpt-4o"},
+				},
+				MemberBudgets: map[string]float64{"member": 25},
+				Key:           config.KeyTemplate{Name: "Research key", MaxBudget: 20},
+			},
+		},
+	}
+	teams := team.NewTeamService(db)
+	teams.SetTemplates(templates)
+	svc := NewService(templates, teams, key.NewService(db, zap.NewNop()), zap.NewNop())
+
+	user := &models.User{Email: "ada@example.com", Username: "ada", Role: models.RoleUser, IsActive: true}
+	require.NoError(t, db.Create(user).Error)
+	require.NoError(t, svc.OnboardUser(ctx, user, []string{"ML-Research"}))
+
+	researchTeam, err := teams.GetTeamByName(ctx, "research")
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, researchTeam.MaxBudget)
+	assert.Equal(t, 200, researchTeam.RPM)
+	assert.Equal(t, models.StringArray{"gpt-4o"}, researchTeam.AllowedModels)
+
+	var member models.TeamMember
+	require.NoError(t, db.Where("team_id = ? AND user_id = ?", researchTeam.ID, user.ID).First(&member).Error)
+	assert.Equal(t, models.TeamRoleMember, member.Role)
+	require.NotNil(t, member.MaxBudget)
+	assert.Equal(t, 25.0, *member.MaxBudget)
+
+	var k models.Key
+	require.NoError(t, db.Where("user_id = ?", user.ID).First(&k).Error)
+	assert.Equal(t, "Research key", k.Name)
+	require.NotNil(t, k.MaxBudget)
+	assert.Equal(t, 20.0, *k.MaxBudget)
+
+	// Users outside every template's groups get the built-in default
+	other := &models.User{Email: "bob@example.com", Username: "bob", Role: models.RoleAdmin, IsActive: true}
+	require.NoError(t, db.Create(other).Error)
+	require.NoError(t, svc.OnboardUser(ctx, other, nil))
+
+	defaultTeam, err := teams.GetTeamByName(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, defaultTeam.MaxBudget)
+	require.NoError(t, db.Where("team_id = ? AND user_id = ?", defaultTeam.ID, other.ID).First(&member).Error)
+	assert.Equal(t, models.TeamRoleAdmin, member.Role)
+	require.NotNil(t, member.MaxBudget)
+	assert.Equal(t, 50.0, *member.MaxBudget)
+}
+
+func TestService_OnboardUserAutoJoin(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	templates := config.OnboardingConfig{
+		Templates: map[string]config.OnboardingTemplate{
+			"acme":     {Key: config.KeyTemplate{Name: "Acme key"}},
+			"platform": {Key: config.KeyTemplate{Name: "Platform key"}},
+		},
+		AutoJoin: []config.AutoJoinRule{
+			{Domains: []string{"acme.com"}, Template: "acme"},
+			{Groups: []string{"platform"}, Template: "platform", TeamRole: "owner", Role: "manager"},
+		},
+		SkipUnmatched: true,
+	}
+	teams := team.NewTeamService(db)
+	teams.SetTemplates(templates)
+	svc := NewService(templates, teams, key.NewService(db, zap.NewNop()), zap.NewNop())
+
+	assert.Equal(t, models.RoleManager, svc.ProvisionedRole("ada@acme.com", []string{"platform"}))
+
+	user := &models.User{Email: "ada@acme.com", Username: "ada", Role: models.RoleManager, IsActive: true}
+	require.NoError(t, db.Create(user).Error)
+	require.NoError(t, svc.OnboardUser(ctx, user, []string{"platform"}))
+
+	var members []models.TeamMember
+	require.NoError(t, db.Preload("Team").Where("user_id = ?", user.ID).Find(&members).Error)
+	roles := make(map[string]models.TeamRole)
+	for _, member := range members {
+		roles[member.Team.Name] = member.Role
+	}
+	assert.Equal(t, map[string]models.TeamRole{"acme": models.TeamRoleMember, "platform": models.TeamRoleOwner}, roles)
+
+	var keys []models.Key
+	require.NoError(t, db.Where("user_id = ?", user.ID).Find(&keys).Error)
+	require.Len(t, keys, 1, "only the first team joined gets a key")
+	assert.Equal(t, "Acme key", keys[0].Name)
+
+	// Users matching no rule join no team
+	other := &models.User{Email: "bob@example.com", Username: "bob", Role: models.RoleUser, IsActive: true}
+	require.NoError(t, db.Create(other).Error)
+	require.NoError(t, svc.OnboardUser(ctx, other, nil))
+	var count int64
+	require.NoError(t, db.Model(&models.TeamMember{}).Where("user_id = ?", other.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,296 @@
+package promotion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// ApplyOptions controls Apply
+type ApplyOptions struct {
+	Prune  bool // Delete models, routes and budgets missing from the bundle
+	DryRun bool // Only compute the plan
+}
+
+// Apply brings the database to the state of the bundle in one transaction and
+// returns the plan it executed. Running gateways pick up model and route
+// changes on their next database sync.
+func Apply(db *gorm.DB, desired *Bundle, opts ApplyOptions) (*Plan, error) {
+	current, err := Export(db, ExportOptions{IncludeSecrets: true})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := Diff(current, desired, DiffOptions{Prune: opts.Prune})
+	if opts.DryRun || plan.IsEmpty() {
+		return plan, nil
+	}
+
+	modelSpecs := make(map[string]ModelSpec, len(desired.Models))
+	for _, spec := range desired.Models {
+		modelSpecs[spec.ModelName] = spec
+	}
+	routeSpecs := make(map[string]RouteSpec, len(desired.Routes))
+	for _, spec := range desired.Routes {
+		routeSpecs[spec.Slug] = spec
+	}
+	teamSpecs := make(map[string]TeamSpec, len(desired.Teams))
+	for _, spec := range desired.Teams {
+		teamSpecs[spec.Name] = spec
+	}
+	budgetSpecs := make(map[string]BudgetSpec, len(desired.Budgets))
+	for _, spec := range desired.Budgets {
+		budgetSpecs[spec.Key()] = spec
+	}
+	currentBudgets := make(map[string]BudgetSpec, len(current.Budgets))
+	for _, spec := range current.Budgets {
+		currentBudgets[spec.Key()] = spec
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, change := range plan.Changes {
+			var err error
+			switch change.Kind {
+			case KindModel:
+				if change.Action == ActionDelete {
+					err = tx.Where("model_name = ?", change.Name).Delete(&models.UserModel{}).Error
+				} else {
+					err = applyModel(tx, modelSpecs[change.Name])
+				}
+			case KindRoute:
+				if change.Action == ActionDelete {
+					err = deleteRoute(tx, change.Name)
+				} else {
+					err = applyRoute(tx, routeSpecs[change.Name])
+				}
+			case KindTeam:
+				err = applyTeam(tx, teamSpecs[change.Name])
+			case KindBudget:
+				if change.Action == ActionDelete {
+					err = deleteBudget(tx, currentBudgets[change.Name])
+				} else {
+					err = applyBudget(tx, budgetSpecs[change.Name])
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to %s %s %s: %w", change.Action, change.Kind, change.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func applyModel(tx *gorm.DB, spec ModelSpec) error {
+	var profileID *uuid.UUID
+	if spec.ProviderProfile != "" {
+		var profile models.ProviderProfile
+		if err := tx.Where("name = ?", spec.ProviderProfile).First(&profile).Error; err != nil {
+			return fmt.Errorf("provider profile %q not found", spec.ProviderProfile)
+		}
+		profileID = &profile.ID
+	}
+
+	var um models.UserModel
+	err := tx.Where("model_name = ?", spec.ModelName).First(&um).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		um = models.UserModel{ModelName: spec.ModelName, ProviderConfig: spec.Provider}
+		err = tx.Create(&um).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	keepSecrets(&spec.Provider, um.ProviderConfig)
+	return tx.Model(&um).Updates(map[string]interface{}{
+		"instance_name":         spec.InstanceName,
+		"provider_config":       spec.Provider,
+		"provider_profile_id":   profileID,
+		"model_info_config":     spec.ModelInfo,
+		"rpm":                   spec.RPM,
+		"tpm":                   spec.TPM,
+		"priority":              spec.Priority,
+		"weight":                spec.Weight,
+		"input_cost_per_token":  spec.InputCostPerToken,
+		"output_cost_per_token": spec.OutputCostPerToken,
+		"timeout_seconds":       spec.TimeoutSeconds,
+		"tags":                  models.StringArrayJSON(spec.Tags),
+		"enabled":               spec.Enabled,
+	}).Error
+}
+
+func applyRoute(tx *gorm.DB, spec RouteSpec) error {
+	var route models.Route
+	err := tx.Where("slug = ?", spec.Slug).First(&route).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		route = models.Route{Name: spec.Name, Slug: spec.Slug, Strategy: spec.Strategy, Source: "user"}
+		err = tx.Create(&route).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&route).Updates(map[string]interface{}{
+		"name":            spec.Name,
+		"description":     spec.Description,
+		"strategy":        spec.Strategy,
+		"fallback_models": models.StringArrayJSON(spec.FallbackModels),
+		"defaults":        spec.Defaults,
+		"enabled":         spec.Enabled,
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("route_id = ?", route.ID).Delete(&models.RouteModel{}).Error; err != nil {
+		return err
+	}
+	for _, modelSpec := range spec.Models {
+		routeModel := models.RouteModel{
+			RouteID:   route.ID,
+			ModelName: modelSpec.ModelName,
+			Weight:    modelSpec.Weight,
+			Priority:  modelSpec.Priority,
+			Enabled:   modelSpec.Enabled,
+		}
+		if err := tx.Create(&routeModel).Error; err != nil {
+			return err
+		}
+		// enabled defaults to true in the database, so false is not inserted
+		if !modelSpec.Enabled {
+			if err := tx.Model(&routeModel).Update("enabled", false).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func deleteRoute(tx *gorm.DB, slug string) error {
+	var route models.Route
+	if err := tx.Where("slug = ?", slug).First(&route).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("route_id = ?", route.ID).Delete(&models.RouteModel{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&route).Error
+}
+
+func applyTeam(tx *gorm.DB, spec TeamSpec) error {
+	var team models.Team
+	err := tx.Where("name = ?", spec.Name).First(&team).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		team = models.Team{Name: spec.Name, BudgetDuration: models.BudgetPeriod(spec.BudgetDuration)}
+		err = tx.Create(&team).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	var aliases datatypes.JSON
+	if len(spec.ModelAliases) > 0 {
+		data, err := json.Marshal(spec.ModelAliases)
+		if err != nil {
+			return err
+		}
+		aliases = datatypes.JSON(data)
+	}
+
+	return tx.Model(&team).Updates(map[string]interface{}{
+		"description":        spec.Description,
+		"is_active":          spec.IsActive,
+		"max_budget":         spec.MaxBudget,
+		"budget_duration":    models.BudgetPeriod(spec.BudgetDuration),
+		"budget_alert_at":    spec.BudgetAlertAt,
+		"tpm":                spec.TPM,
+		"rpm":                spec.RPM,
+		"max_parallel_calls": spec.MaxParallelCalls,
+		"allowed_models":     models.StringArray(spec.AllowedModels),
+		"blocked_models":     models.StringArray(spec.BlockedModels),
+		"model_aliases":      aliases,
+		"default_model":      spec.DefaultModel,
+	}).Error
+}
+
+// budgetQuery selects the budget a spec refers to
+func budgetQuery(tx *gorm.DB, spec BudgetSpec) (*gorm.DB, *uuid.UUID, *uuid.UUID, error) {
+	query := tx.Model(&models.Budget{}).Where("name = ? AND type = ?", spec.Name, spec.Type)
+
+	var teamID, userID *uuid.UUID
+	if spec.Team != "" {
+		var team models.Team
+		if err := tx.Where("name = ?", spec.Team).First(&team).Error; err != nil {
+			return nil, nil, nil, fmt.Errorf("team %q not found", spec.Team)
+		}
+		teamID = &team.ID
+		query = query.Where("team_id = ?", team.ID)
+	} else {
+		query = query.Where("team_id IS NULL")
+	}
+	if spec.User != "" {
+		var user models.User
+		if err := tx.Where("email = ?", spec.User).First(&user).Error; err != nil {
+			return nil, nil, nil, fmt.Errorf("user %q not found", spec.User)
+		}
+		userID = &user.ID
+		query = query.Where("user_id = ?", user.ID)
+	} else {
+		query = query.Where("user_id IS NULL")
+	}
+	return query, teamID, userID, nil
+}
+
+func applyBudget(tx *gorm.DB, spec BudgetSpec) error {
+	query, teamID, userID, err := budgetQuery(tx, spec)
+	if err != nil {
+		return err
+	}
+
+	var budget models.Budget
+	err = query.First(&budget).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		budget = models.Budget{
+			Name:   spec.Name,
+			Type:   models.BudgetType(spec.Type),
+			Amount: spec.Amount,
+			Period: models.BudgetPeriod(spec.Period),
+			TeamID: teamID,
+			UserID: userID,
+		}
+		budget.Reset()
+		err = tx.Create(&budget).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	actions := make(models.BudgetActions, 0, len(spec.Actions))
+	for _, action := range spec.Actions {
+		actions = append(actions, models.BudgetAction{Threshold: action.Threshold, Action: action.Action})
+	}
+
+	return tx.Model(&budget).Updates(map[string]interface{}{
+		"amount":    spec.Amount,
+		"period":    models.BudgetPeriod(spec.Period),
+		"alert_at":  spec.AlertAt,
+		"is_active": spec.IsActive,
+		"actions":   actions,
+	}).Error
+}
+
+func deleteBudget(tx *gorm.DB, spec BudgetSpec) error {
+	query, _, _, err := budgetQuery(tx, spec)
+	if err != nil {
+		return err
+	}
+	return query.Delete(&models.Budget{}).Error
+}
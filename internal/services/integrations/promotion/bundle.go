@@ -0,0 +1,329 @@
+package promotion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// BundleVersion is the format version written by Export
+const BundleVersion = 1
+
+// Bundle is a snapshot of the runtime-managed gateway state: user models,
+// routes, teams (with their model aliases) and budget policies. Objects are
+// keyed by name rather than ID so a bundle can be applied to another
+// environment.
+type Bundle struct {
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Models     []ModelSpec  `json:"models,omitempty"`
+	Routes     []RouteSpec  `json:"routes,omitempty"`
+	Teams      []TeamSpec   `json:"teams,omitempty"`
+	Budgets    []BudgetSpec `json:"budgets,omitempty"`
+}
+
+// ModelSpec is a user-created model deployment, keyed by model_name
+type ModelSpec struct {
+	ModelName          string                    `json:"model_name"`
+	InstanceName       string                    `json:"instance_name,omitempty"`
+	Provider           models.ProviderConfigJSON `json:"provider"`
+	ProviderProfile    string                    `json:"provider_profile,omitempty"` // Profile name
+	ModelInfo          models.ModelInfoJSON      `json:"model_info"`
+	RPM                int                       `json:"rpm"`
+	TPM                int                       `json:"tpm"`
+	Priority           int                       `json:"priority"`
+	Weight             float64                   `json:"weight"`
+	InputCostPerToken  float64                   `json:"input_cost_per_token"`
+	OutputCostPerToken float64                   `json:"output_cost_per_token"`
+	TimeoutSeconds     int                       `json:"timeout_seconds"`
+	Tags               []string                  `json:"tags,omitempty"`
+	Enabled            bool                      `json:"enabled"`
+}
+
+// RouteSpec is a route, keyed by slug
+type RouteSpec struct {
+	Slug           string               `json:"slug"`
+	Name           string               `json:"name"`
+	Description    string               `json:"description,omitempty"`
+	Strategy       string               `json:"strategy"`
+	FallbackModels []string             `json:"fallback_models,omitempty"`
+	Defaults       models.RouteDefaults `json:"defaults"`
+	Enabled        bool                 `json:"enabled"`
+	Models         []RouteModelSpec     `json:"models,omitempty"`
+}
+
+// RouteModelSpec is a model entry of a route
+type RouteModelSpec struct {
+	ModelName string `json:"model_name"`
+	Weight    int    `json:"weight"`
+	Priority  int    `json:"priority"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// TeamSpec holds the policy of a team, keyed by name. Members, keys and
+// spend are environment-specific and not part of the bundle.
+type TeamSpec struct {
+	Name             string            `json:"name"`
+	Description      string            `json:"description,omitempty"`
+	IsActive         bool              `json:"is_active"`
+	MaxBudget        float64           `json:"max_budget"`
+	BudgetDuration   string            `json:"budget_duration,omitempty"`
+	BudgetAlertAt    float64           `json:"budget_alert_at"`
+	TPM              int               `json:"tpm"`
+	RPM              int               `json:"rpm"`
+	MaxParallelCalls int               `json:"max_parallel_calls"`
+	AllowedModels    []string          `json:"allowed_models,omitempty"`
+	BlockedModels    []string          `json:"blocked_models,omitempty"`
+	ModelAliases     map[string]string `json:"model_aliases,omitempty"`
+	DefaultModel     string            `json:"default_model,omitempty"`
+}
+
+// BudgetSpec is a budget policy, keyed by type, owner and name. Team and user
+// budgets name their owner by team name and user email.
+type BudgetSpec struct {
+	Name     string                `json:"name"`
+	Type     string                `json:"type"`
+	Team     string                `json:"team,omitempty"`
+	User     string                `json:"user,omitempty"`
+	Amount   float64               `json:"amount"`
+	Period   string                `json:"period"`
+	AlertAt  float64               `json:"alert_at"`
+	IsActive bool                  `json:"is_active"`
+	Actions  []BudgetThresholdSpec `json:"actions,omitempty"`
+}
+
+// BudgetThresholdSpec is an action a budget takes at a spend threshold
+type BudgetThresholdSpec struct {
+	Threshold float64 `json:"threshold"`
+	Action    string  `json:"action"`
+}
+
+// Key identifies the budget within an environment
+func (b BudgetSpec) Key() string {
+	owner := b.Team
+	if b.User != "" {
+		owner = b.User
+	}
+	return b.Type + "/" + owner + "/" + b.Name
+}
+
+// ExportOptions controls what Export includes
+type ExportOptions struct {
+	// IncludeSecrets keeps literal provider credentials. By default they are
+	// redacted; ${ENV_VAR} references are always kept.
+	IncludeSecrets bool
+}
+
+// Export snapshots the runtime-managed state of the database
+func Export(db *gorm.DB, opts ExportOptions) (*Bundle, error) {
+	bundle := &Bundle{Version: BundleVersion, ExportedAt: time.Now().UTC()}
+
+	var userModels []models.UserModel
+	if err := db.Preload("ProviderProfile").Order("model_name").Find(&userModels).Error; err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	for _, um := range userModels {
+		spec := ModelSpec{
+			ModelName:          um.ModelName,
+			InstanceName:       um.InstanceName,
+			Provider:           um.ProviderConfig,
+			ModelInfo:          um.ModelInfoConfig,
+			RPM:                um.RPM,
+			TPM:                um.TPM,
+			Priority:           um.Priority,
+			Weight:             um.Weight,
+			InputCostPerToken:  um.InputCostPerToken,
+			OutputCostPerToken: um.OutputCostPerToken,
+			TimeoutSeconds:     um.TimeoutSeconds,
+			Tags:               []string(um.Tags),
+			Enabled:            um.Enabled,
+		}
+		if um.ProviderProfile != nil {
+			spec.ProviderProfile = um.ProviderProfile.Name
+		}
+		if !opts.IncludeSecrets {
+			redactSecrets(&spec.Provider)
+		}
+		bundle.Models = append(bundle.Models, spec)
+	}
+
+	var routes []models.Route
+	if err := db.Preload("Models").Where("source <> ?", "system").Order("slug").Find(&routes).Error; err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+	for _, route := range routes {
+		spec := RouteSpec{
+			Slug:           route.Slug,
+			Name:           route.Name,
+			Description:    route.Description,
+			Strategy:       route.Strategy,
+			FallbackModels: []string(route.FallbackModels),
+			Defaults:       route.Defaults,
+			Enabled:        route.Enabled,
+		}
+		for _, rm := range route.Models {
+			spec.Models = append(spec.Models, RouteModelSpec{
+				ModelName: rm.ModelName,
+				Weight:    rm.Weight,
+				Priority:  rm.Priority,
+				Enabled:   rm.Enabled,
+			})
+		}
+		sort.Slice(spec.Models, func(i, j int) bool { return spec.Models[i].ModelName < spec.Models[j].ModelName })
+		bundle.Routes = append(bundle.Routes, spec)
+	}
+
+	var teams []models.Team
+	if err := db.Order("name").Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+	for _, team := range teams {
+		spec := TeamSpec{
+			Name:             team.Name,
+			Description:      team.Description,
+			IsActive:         team.IsActive,
+			MaxBudget:        team.MaxBudget,
+			BudgetDuration:   string(team.BudgetDuration),
+			BudgetAlertAt:    team.BudgetAlertAt,
+			TPM:              team.TPM,
+			RPM:              team.RPM,
+			MaxParallelCalls: team.MaxParallelCalls,
+			AllowedModels:    []string(team.AllowedModels),
+			BlockedModels:    []string(team.BlockedModels),
+			DefaultModel:     team.DefaultModel,
+		}
+		if len(team.ModelAliases) > 0 {
+			if err := json.Unmarshal(team.ModelAliases, &spec.ModelAliases); err != nil {
+				return nil, fmt.Errorf("team %s has invalid model aliases: %w", team.Name, err)
+			}
+		}
+		bundle.Teams = append(bundle.Teams, spec)
+	}
+
+	var budgets []models.Budget
+	if err := db.Preload("Team").Preload("User").Order("name").Find(&budgets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	for _, budget := range budgets {
+		spec := BudgetSpec{
+			Name:     budget.Name,
+			Type:     string(budget.Type),
+			Amount:   budget.Amount,
+			Period:   string(budget.Period),
+			AlertAt:  budget.AlertAt,
+			IsActive: budget.IsActive,
+		}
+		if budget.Team != nil {
+			spec.Team = budget.Team.Name
+		}
+		if budget.User != nil {
+			spec.User = budget.User.Email
+		}
+		for _, action := range budget.Actions {
+			spec.Actions = append(spec.Actions, BudgetThresholdSpec{Threshold: action.Threshold, Action: action.Action})
+		}
+		bundle.Budgets = append(bundle.Budgets, spec)
+	}
+
+	return bundle, nil
+}
+
+// secretFields returns pointers to the credential fields of a provider config
+func secretFields(provider *models.ProviderConfigJSON) []*string {
+	return []*string{
+		&provider.APIKey,
+		&provider.APISecret,
+		&provider.AWSAccessKeyID,
+		&provider.AWSSecretAccessKey,
+		&provider.AWSSessionToken,
+		&provider.OAuthToken,
+	}
+}
+
+// redactSecrets clears literal credentials, keeping ${ENV_VAR} references.
+// Header values are treated as credentials too.
+func redactSecrets(provider *models.ProviderConfigJSON) {
+	for _, field := range secretFields(provider) {
+		if !isEnvReference(*field) {
+			*field = ""
+		}
+	}
+	if len(provider.Headers) > 0 {
+		headers := make(map[string]string, len(provider.Headers))
+		for name, value := range provider.Headers {
+			if isEnvReference(value) {
+				headers[name] = value
+			} else {
+				headers[name] = ""
+			}
+		}
+		provider.Headers = headers
+	}
+}
+
+func isEnvReference(value string) bool {
+	return strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}")
+}
+
+// Encode writes the bundle as YAML. Field names follow the JSON tags.
+func (b *Bundle) Encode() ([]byte, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	// JSON is valid YAML; decoding into a node keeps the field order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to convert bundle: %w", err)
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle resets the flow style and quoting inherited from JSON
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// Decode reads a YAML (or JSON) bundle
+func Decode(data []byte) (*Bundle, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	converted, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert bundle: %w", err)
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(converted, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	if bundle.Version == 0 || bundle.Version > BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (supported: %d)", bundle.Version, BundleVersion)
+	}
+	return &bundle, nil
+}
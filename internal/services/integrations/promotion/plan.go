@@ -0,0 +1,221 @@
+package promotion
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Object kinds in a plan
+const (
+	KindModel  = "model"
+	KindRoute  = "route"
+	KindTeam   = "team"
+	KindBudget = "budget"
+)
+
+// Action is what applying a plan does to an object
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change is one planned change. Fields lists the changed fields of an update.
+type Change struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Action Action   `json:"action"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Plan is the ordered set of changes that brings an environment to a bundle
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// IsEmpty reports whether the environment already matches the bundle
+func (p *Plan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count returns the number of changes with the given action
+func (p *Plan) Count(action Action) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// String renders the plan in a terraform-like format
+func (p *Plan) String() string {
+	if p.IsEmpty() {
+		return "No changes. The environment matches the bundle.\n"
+	}
+
+	var b strings.Builder
+	for _, change := range p.Changes {
+		symbol := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}[change.Action]
+		fmt.Fprintf(&b, "%s %s %s", symbol, change.Kind, change.Name)
+		if len(change.Fields) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(change.Fields, ", "))
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to delete.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDelete))
+	return b.String()
+}
+
+// DiffOptions controls how Diff treats objects missing from the bundle
+type DiffOptions struct {
+	// Prune deletes models, routes and budgets that are not in the bundle.
+	// Teams are never deleted because they own keys and members.
+	Prune bool
+}
+
+// Diff plans the changes that turn current into desired. Redacted (empty)
+// credentials in desired keep the current values and are not reported.
+func Diff(current, desired *Bundle, opts DiffOptions) *Plan {
+	plan := &Plan{}
+
+	currentModels := make(map[string]ModelSpec, len(current.Models))
+	for _, spec := range current.Models {
+		currentModels[spec.ModelName] = spec
+	}
+	desiredModels := make(map[string]ModelSpec, len(desired.Models))
+	for _, spec := range desired.Models {
+		desiredModels[spec.ModelName] = spec
+		existing, ok := currentModels[spec.ModelName]
+		if !ok {
+			plan.add(KindModel, spec.ModelName, ActionCreate, nil)
+			continue
+		}
+		keepSecrets(&spec.Provider, existing.Provider)
+		plan.add(KindModel, spec.ModelName, ActionUpdate, changedFields(existing, spec))
+	}
+
+	currentRoutes := make(map[string]RouteSpec, len(current.Routes))
+	for _, spec := range current.Routes {
+		currentRoutes[spec.Slug] = spec
+	}
+	desiredRoutes := make(map[string]RouteSpec, len(desired.Routes))
+	for _, spec := range desired.Routes {
+		desiredRoutes[spec.Slug] = spec
+		if existing, ok := currentRoutes[spec.Slug]; ok {
+			plan.add(KindRoute, spec.Slug, ActionUpdate, changedFields(existing, spec))
+		} else {
+			plan.add(KindRoute, spec.Slug, ActionCreate, nil)
+		}
+	}
+
+	currentTeams := make(map[string]TeamSpec, len(current.Teams))
+	for _, spec := range current.Teams {
+		currentTeams[spec.Name] = spec
+	}
+	for _, spec := range desired.Teams {
+		if existing, ok := currentTeams[spec.Name]; ok {
+			plan.add(KindTeam, spec.Name, ActionUpdate, changedFields(existing, spec))
+		} else {
+			plan.add(KindTeam, spec.Name, ActionCreate, nil)
+		}
+	}
+
+	currentBudgets := make(map[string]BudgetSpec, len(current.Budgets))
+	for _, spec := range current.Budgets {
+		currentBudgets[spec.Key()] = spec
+	}
+	desiredBudgets := make(map[string]BudgetSpec, len(desired.Budgets))
+	for _, spec := range desired.Budgets {
+		desiredBudgets[spec.Key()] = spec
+		if existing, ok := currentBudgets[spec.Key()]; ok {
+			plan.add(KindBudget, spec.Key(), ActionUpdate, changedFields(existing, spec))
+		} else {
+			plan.add(KindBudget, spec.Key(), ActionCreate, nil)
+		}
+	}
+
+	if opts.Prune {
+		for _, spec := range current.Models {
+			if _, ok := desiredModels[spec.ModelName]; !ok {
+				plan.add(KindModel, spec.ModelName, ActionDelete, nil)
+			}
+		}
+		for _, spec := range current.Routes {
+			if _, ok := desiredRoutes[spec.Slug]; !ok {
+				plan.add(KindRoute, spec.Slug, ActionDelete, nil)
+			}
+		}
+		for _, spec := range current.Budgets {
+			if _, ok := desiredBudgets[spec.Key()]; !ok {
+				plan.add(KindBudget, spec.Key(), ActionDelete, nil)
+			}
+		}
+	}
+
+	return plan
+}
+
+// add records a change; updates without changed fields are dropped
+func (p *Plan) add(kind, name string, action Action, fields []string) {
+	if action == ActionUpdate && len(fields) == 0 {
+		return
+	}
+	p.Changes = append(p.Changes, Change{Kind: kind, Name: name, Action: action, Fields: fields})
+}
+
+// keepSecrets fills redacted credentials of desired from current
+func keepSecrets(desired *models.ProviderConfigJSON, current models.ProviderConfigJSON) {
+	currentFields := secretFields(&current)
+	for i, field := range secretFields(desired) {
+		if *field == "" {
+			*field = *currentFields[i]
+		}
+	}
+	if len(desired.Headers) > 0 {
+		headers := make(map[string]string, len(desired.Headers))
+		for name, value := range desired.Headers {
+			if value == "" {
+				value = current.Headers[name]
+			}
+			headers[name] = value
+		}
+		desired.Headers = headers
+	}
+}
+
+// changedFields returns the top-level JSON fields that differ between two specs
+func changedFields(current, desired interface{}) []string {
+	currentMap := toMap(current)
+	desiredMap := toMap(desired)
+
+	var fields []string
+	for field, value := range desiredMap {
+		if !reflect.DeepEqual(currentMap[field], value) {
+			fields = append(fields, field)
+		}
+	}
+	for field := range currentMap {
+		if _, ok := desiredMap[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func toMap(value interface{}) map[string]interface{} {
+	data, _ := json.Marshal(value)
+	result := make(map[string]interface{})
+	_ = json.Unmarshal(data, &result)
+	return result
+}
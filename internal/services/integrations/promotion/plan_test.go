@@ -0,0 +1,102 @@
+package promotion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func testBundle() *Bundle {
+	return &Bundle{
+		Version: BundleVersion,
+		Models: []ModelSpec{{
+			ModelName: "gpt-4o",
+			Provider:  models.ProviderConfigJSON{Type: "openai", Model: "gpt-4o", APIKey: "sk-staging"},
+			RPM:       100,
+			Enabled:   true,
+		}},
+		Routes: []RouteSpec{{
+			Slug:     "smart",
+			Name:     "Smart",
+			Strategy: "priority",
+			Enabled:  true,
+			Models:   []RouteModelSpec{{ModelName: "gpt-4o", Weight: 50, Priority: 50, Enabled: true}},
+		}},
+		Teams: []TeamSpec{{
+			Name:         "research",
+			IsActive:     true,
+			MaxBudget:    100,
+			ModelAliases: map[string]string{"default": "gpt-4o"},
+		}},
+		Budgets: []BudgetSpec{{Name: "monthly", Type: "team", Team: "research", Amount: 100, Period: "monthly", IsActive: true}},
+	}
+}
+
+func TestDiff_NoChanges(t *testing.T) {
+	plan := Diff(testBundle(), testBundle(), DiffOptions{})
+	assert.True(t, plan.IsEmpty())
+}
+
+func TestDiff_CreateUpdateDelete(t *testing.T) {
+	current := testBundle()
+	current.Models = append(current.Models, ModelSpec{ModelName: "legacy"})
+
+	desired := testBundle()
+	desired.Models[0].RPM = 500
+	desired.Teams[0].ModelAliases["fast"] = "gpt-4o-mini"
+	desired.Routes = append(desired.Routes, RouteSpec{Slug: "cheap", Name: "Cheap", Strategy: "cost"})
+
+	plan := Diff(current, desired, DiffOptions{})
+	assert.Equal(t, []Change{
+		{Kind: KindModel, Name: "gpt-4o", Action: ActionUpdate, Fields: []string{"rpm"}},
+		{Kind: KindRoute, Name: "cheap", Action: ActionCreate},
+		{Kind: KindTeam, Name: "research", Action: ActionUpdate, Fields: []string{"model_aliases"}},
+	}, plan.Changes)
+
+	// Objects missing from the bundle are only deleted when pruning
+	plan = Diff(current, desired, DiffOptions{Prune: true})
+	assert.Equal(t, 1, plan.Count(ActionDelete))
+	assert.Equal(t, "legacy", plan.Changes[len(plan.Changes)-1].Name)
+}
+
+func TestDiff_RedactedSecretsKeepCurrentValues(t *testing.T) {
+	desired := testBundle()
+	redactSecrets(&desired.Models[0].Provider)
+	assert.Empty(t, desired.Models[0].Provider.APIKey)
+
+	plan := Diff(testBundle(), desired, DiffOptions{})
+	assert.True(t, plan.IsEmpty())
+
+	// Environment variable references survive redaction
+	provider := models.ProviderConfigJSON{APIKey: "${OPENAI_API_KEY}", AWSSecretAccessKey: "secret"}
+	redactSecrets(&provider)
+	assert.Equal(t, "${OPENAI_API_KEY}", provider.APIKey)
+	assert.Empty(t, provider.AWSSecretAccessKey)
+
+	// Header values are redacted like credentials and kept on import
+	provider = models.ProviderConfigJSON{Headers: map[string]string{"X-Api-Key": "secret", "X-Tenant": "${TENANT}"}}
+	redactSecrets(&provider)
+	assert.Equal(t, map[string]string{"X-Api-Key": "", "X-Tenant": "${TENANT}"}, provider.Headers)
+	keepSecrets(&provider, models.ProviderConfigJSON{Headers: map[string]string{"X-Api-Key": "secret"}})
+	assert.Equal(t, "secret", provider.Headers["X-Api-Key"])
+}
+
+func TestBundle_EncodeDecode(t *testing.T) {
+	bundle := testBundle()
+	bundle.Models[0].Tags = []string{"true", "123"} // Must stay strings
+
+	data, err := bundle.Encode()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "model_name: gpt-4o")
+
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	assert.True(t, Diff(bundle, decoded, DiffOptions{Prune: true}).IsEmpty())
+	assert.Equal(t, []string{"true", "123"}, decoded.Models[0].Tags)
+
+	_, err = Decode([]byte("version: 99\n"))
+	assert.Error(t, err)
+}
@@ -87,6 +87,7 @@ func (s *Service) Update(id uuid.UUID, route *models.Route) error {
 		"description":     route.Description,
 		"strategy":        route.Strategy,
 		"fallback_models": route.FallbackModels,
+		"defaults":        route.Defaults,
 		"enabled":         route.Enabled,
 	}
 	if err := s.db.Model(&existing).Updates(updates).Error; err != nil {
@@ -2,12 +2,18 @@ package team
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
+	"github.com/amerfu/pllm/internal/core/config"
 	"github.com/amerfu/pllm/internal/core/models"
 )
 
@@ -16,17 +22,34 @@ var (
 	ErrTeamNameExists   = errors.New("team name already exists")
 	ErrUserNotInTeam    = errors.New("user not in team")
 	ErrInsufficientRole = errors.New("insufficient role permissions")
+	ErrInvalidRole      = errors.New("role must be one of owner, admin, member, viewer, billing_admin or auditor")
 	ErrBudgetExceeded   = errors.New("team budget exceeded")
+	ErrInvalidAliases   = errors.New("model_aliases must map model names to model names")
+
+	ErrInvalidResponseMinimization = fmt.Errorf("response_minimization must list options among: %s",
+		strings.Join(models.ValidResponseMinimization, ", "))
+	ErrInvalidEnvironments = fmt.Errorf("environments must map %s to policies with non-negative limits",
+		strings.Join(models.ValidKeyEnvironments, ", "))
+	ErrInvalidSystemPrompt = fmt.Errorf("system_prompt must be a string and system_prompt_policy one of: %s",
+		strings.Join(models.ValidSystemPromptPolicies, ", "))
+	ErrInvalidBandwidthQuota = errors.New("max_monthly_bandwidth must be a non-negative number of bytes")
 )
 
 type TeamService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	templates config.OnboardingConfig
 }
 
 func NewTeamService(db *gorm.DB) *TeamService {
 	return &TeamService{db: db}
 }
 
+// SetTemplates sets the onboarding templates that fill in the limits of new
+// teams. Without them the built-in default template is used.
+func (s *TeamService) SetTemplates(templates config.OnboardingConfig) {
+	s.templates = templates
+}
+
 type CreateTeamRequest struct {
 	Name             string              `json:"name"`
 	Description      string              `json:"description"`
@@ -37,6 +60,32 @@ type CreateTeamRequest struct {
 	MaxParallelCalls int                 `json:"max_parallel_calls"`
 	AllowedModels    []string            `json:"allowed_models"`
 	BlockedModels    []string            `json:"blocked_models"`
+
+	// DefaultModel serves requests that omit the model or ask for
+	// "default"; ModelAliases rewrites requested models to others
+	DefaultModel string            `json:"default_model,omitempty"`
+	ModelAliases map[string]string `json:"model_aliases,omitempty"`
+
+	// ResponseMinimization strips fields from the completion responses of
+	// the team's keys; see models.ValidResponseMinimization
+	ResponseMinimization []string `json:"response_minimization,omitempty"`
+
+	// Environments sets the limits and models of the team's keys per key
+	// environment
+	Environments map[string]models.EnvironmentPolicy `json:"environments,omitempty"`
+
+	// SystemPrompt is put before the messages of the chat requests of the
+	// team's keys; SystemPromptPolicy handles clients' own system messages
+	SystemPrompt       string `json:"system_prompt,omitempty"`
+	SystemPromptPolicy string `json:"system_prompt_policy,omitempty"`
+
+	// MaxMonthlyBandwidth caps the request and response bytes of the team's
+	// keys per calendar month; 0 is unlimited
+	MaxMonthlyBandwidth int64 `json:"max_monthly_bandwidth,omitempty"`
+
+	// Template names the onboarding template whose limits fill in the
+	// fields left unset; the default template is used when empty
+	Template string `json:"template,omitempty"`
 }
 
 type AddMemberRequest struct {
@@ -47,8 +96,12 @@ type AddMemberRequest struct {
 	CustomRPM *int            `json:"custom_rpm,omitempty"`
 }
 
-// CreateTeam creates a new team
+// CreateTeam creates a new team. Limits the request leaves unset come from
+// its onboarding template.
 func (s *TeamService) CreateTeam(ctx context.Context, req *CreateTeamRequest, ownerID uuid.UUID) (*models.Team, error) {
+	_, tmpl := s.templates.Template(req.Template)
+	req = applyTeamTemplate(req, tmpl.Team)
+
 	// Check if team name already exists
 	var count int64
 	if err := s.db.Model(&models.Team{}).Where("name = ?", req.Name).Count(&count).Error; err != nil {
@@ -58,17 +111,43 @@ func (s *TeamService) CreateTeam(ctx context.Context, req *CreateTeamRequest, ow
 		return nil, ErrTeamNameExists
 	}
 
+	aliases, err := encodeModelAliases(req.ModelAliases)
+	if err != nil {
+		return nil, err
+	}
+	if models.ValidateResponseMinimization(req.ResponseMinimization) != nil {
+		return nil, ErrInvalidResponseMinimization
+	}
+	environments, err := encodeEnvironments(req.Environments)
+	if err != nil {
+		return nil, err
+	}
+	if models.ValidateSystemPromptPolicy(req.SystemPromptPolicy) != nil {
+		return nil, ErrInvalidSystemPrompt
+	}
+	if req.MaxMonthlyBandwidth < 0 {
+		return nil, ErrInvalidBandwidthQuota
+	}
+
 	team := &models.Team{
-		Name:             req.Name,
-		Description:      req.Description,
-		MaxBudget:        req.MaxBudget,
-		BudgetDuration:   req.BudgetDuration,
-		TPM:              req.TPM,
-		RPM:              req.RPM,
-		MaxParallelCalls: req.MaxParallelCalls,
-		AllowedModels:    models.StringArray(req.AllowedModels),
-		BlockedModels:    models.StringArray(req.BlockedModels),
-		IsActive:         true,
+		Name:                 req.Name,
+		Description:          req.Description,
+		MaxBudget:            req.MaxBudget,
+		BudgetDuration:       req.BudgetDuration,
+		TPM:                  req.TPM,
+		RPM:                  req.RPM,
+		MaxParallelCalls:     req.MaxParallelCalls,
+		AllowedModels:        models.StringArray(req.AllowedModels),
+		BlockedModels:        models.StringArray(req.BlockedModels),
+		DefaultModel:         req.DefaultModel,
+		ModelAliases:         aliases,
+		ResponseMinimization: models.StringArray(req.ResponseMinimization),
+		Environments:         environments,
+		SystemPrompt:         req.SystemPrompt,
+		SystemPromptPolicy:   req.SystemPromptPolicy,
+		MaxMonthlyBandwidth:  req.MaxMonthlyBandwidth,
+		BudgetAlertAt:        tmpl.Team.BudgetAlertAt,
+		IsActive:             true,
 	}
 
 	// Set budget reset time
@@ -87,7 +166,7 @@ func (s *TeamService) CreateTeam(ctx context.Context, req *CreateTeamRequest, ow
 	}
 
 	// Create team and add owner as first member
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(team).Error; err != nil {
 			return err
 		}
@@ -137,6 +216,43 @@ func (s *TeamService) UpdateTeam(ctx context.Context, teamID uuid.UUID, updates
 		return nil, err
 	}
 
+	// Aliases arrive as a JSON object and are stored as a JSON column
+	if raw, ok := updates["model_aliases"]; ok {
+		aliases, err := decodeModelAliases(raw)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := encodeModelAliases(aliases)
+		if err != nil {
+			return nil, err
+		}
+		updates["model_aliases"] = encoded
+	}
+	if raw, ok := updates["response_minimization"]; ok {
+		options, err := decodeResponseMinimization(raw)
+		if err != nil {
+			return nil, err
+		}
+		updates["response_minimization"] = models.StringArray(options)
+	}
+	if raw, ok := updates["environments"]; ok {
+		environments, err := decodeEnvironments(raw)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := encodeEnvironments(environments)
+		if err != nil {
+			return nil, err
+		}
+		updates["environments"] = encoded
+	}
+	if err := validateSystemPromptUpdates(updates); err != nil {
+		return nil, err
+	}
+	if err := validateBandwidthQuotaUpdate(updates); err != nil {
+		return nil, err
+	}
+
 	if err := s.db.Model(&team).Updates(updates).Error; err != nil {
 		return nil, err
 	}
@@ -167,6 +283,10 @@ func (s *TeamService) DeleteTeam(ctx context.Context, teamID uuid.UUID) error {
 
 // AddMember adds a user to a team
 func (s *TeamService) AddMember(ctx context.Context, teamID uuid.UUID, req *AddMemberRequest) (*models.TeamMember, error) {
+	if req.Role != "" && !models.ValidTeamRole(req.Role) {
+		return nil, ErrInvalidRole
+	}
+
 	// Check if team exists
 	var team models.Team
 	if err := s.db.First(&team, "id = ?", teamID).Error; err != nil {
@@ -220,6 +340,13 @@ func (s *TeamService) UpdateMember(ctx context.Context, teamID, userID uuid.UUID
 		return nil, err
 	}
 
+	if raw, ok := updates["role"]; ok {
+		role, _ := raw.(string)
+		if !models.ValidTeamRole(models.TeamRole(role)) {
+			return nil, ErrInvalidRole
+		}
+	}
+
 	if err := s.db.Model(&member).Updates(updates).Error; err != nil {
 		return nil, err
 	}
@@ -416,12 +543,13 @@ func (s *TeamService) GetTeamMembers(ctx context.Context, teamID uuid.UUID) ([]*
 	return members, nil
 }
 
-// GetOrCreateDefaultTeam gets or creates the default team for auto-provisioning
-func (s *TeamService) GetOrCreateDefaultTeam(ctx context.Context) (*models.Team, error) {
-	const defaultTeamName = "default"
+// GetOrCreateTemplateTeam returns the team users onboarded with the named
+// template join, creating it from the template when missing
+func (s *TeamService) GetOrCreateTemplateTeam(ctx context.Context, templateName string) (*models.Team, error) {
+	name, tmpl := s.templates.Template(templateName)
 
-	// Try to find existing default team
-	team, err := s.GetTeamByName(ctx, defaultTeamName)
+	// Try to find the existing team
+	team, err := s.GetTeamByName(ctx, tmpl.TeamName)
 	if err == nil {
 		return team, nil
 	}
@@ -429,17 +557,10 @@ func (s *TeamService) GetOrCreateDefaultTeam(ctx context.Context) (*models.Team,
 		return nil, err
 	}
 
-	// Create default team with reasonable defaults
 	req := &CreateTeamRequest{
-		Name:             defaultTeamName,
-		Description:      "Default team for auto-provisioned users",
-		MaxBudget:        100.0, // $100 per month
-		BudgetDuration:   models.BudgetPeriodMonthly,
-		TPM:              1000, // 1000 tokens per minute
-		RPM:              100,  // 100 requests per minute
-		MaxParallelCalls: 5,
-		AllowedModels:    models.StringArray{}, // Start with empty - can be configured later
-		BlockedModels:    models.StringArray{}, // No blocked models initially
+		Name:        tmpl.TeamName,
+		Description: fmt.Sprintf("Team for users onboarded with the %q template", name),
+		Template:    name,
 	}
 
 	// Use master key user ID as owner (won't be added as member due to special handling)
@@ -448,10 +569,10 @@ func (s *TeamService) GetOrCreateDefaultTeam(ctx context.Context) (*models.Team,
 	return s.CreateTeam(ctx, req, masterKeyUserID)
 }
 
-// AddUserToDefaultTeam adds a user to the default team with appropriate role
-func (s *TeamService) AddUserToDefaultTeam(ctx context.Context, userID uuid.UUID, role models.TeamRole) (*models.TeamMember, error) {
-	// Get or create default team
-	defaultTeam, err := s.GetOrCreateDefaultTeam(ctx)
+// AddUserFromTemplate adds a user to the named template's team, with the
+// template's member budget for the role
+func (s *TeamService) AddUserFromTemplate(ctx context.Context, templateName string, userID uuid.UUID, role models.TeamRole) (*models.TeamMember, error) {
+	team, err := s.GetOrCreateTemplateTeam(ctx, templateName)
 	if err != nil {
 		return nil, err
 	}
@@ -459,25 +580,19 @@ func (s *TeamService) AddUserToDefaultTeam(ctx context.Context, userID uuid.UUID
 	// Check if user is already a member
 	var count int64
 	if err := s.db.Model(&models.TeamMember{}).
-		Where("team_id = ? AND user_id = ?", defaultTeam.ID, userID).
+		Where("team_id = ? AND user_id = ?", team.ID, userID).
 		Count(&count).Error; err != nil {
 		return nil, err
 	}
 	if count > 0 {
-		// User already in default team
 		var member models.TeamMember
-		err := s.db.Preload("User").Where("team_id = ? AND user_id = ?", defaultTeam.ID, userID).First(&member).Error
+		err := s.db.Preload("User").Where("team_id = ? AND user_id = ?", team.ID, userID).First(&member).Error
 		return &member, err
 	}
 
-	// Set user budget based on role
+	_, tmpl := s.templates.Template(templateName)
 	var maxBudget *float64
-	switch role {
-	case models.TeamRoleMember:
-		budget := 10.0 // $10 per user
-		maxBudget = &budget
-	case models.TeamRoleAdmin:
-		budget := 50.0 // $50 for admins
+	if budget, ok := tmpl.MemberBudgets[string(role)]; ok {
 		maxBudget = &budget
 	}
 
@@ -489,5 +604,166 @@ func (s *TeamService) AddUserToDefaultTeam(ctx context.Context, userID uuid.UUID
 		CustomRPM: nil, // Use team defaults
 	}
 
-	return s.AddMember(ctx, defaultTeam.ID, req)
+	return s.AddMember(ctx, team.ID, req)
+}
+
+// applyTeamTemplate returns a copy of req with the fields it leaves unset
+// taken from the template
+func applyTeamTemplate(req *CreateTeamRequest, tmpl config.TeamTemplate) *CreateTeamRequest {
+	out := *req
+	if out.MaxBudget == 0 {
+		out.MaxBudget = tmpl.MaxBudget
+	}
+	if out.BudgetDuration == "" {
+		out.BudgetDuration = models.BudgetPeriod(tmpl.BudgetDuration)
+	}
+	if out.TPM == 0 {
+		out.TPM = tmpl.TPM
+	}
+	if out.RPM == 0 {
+		out.RPM = tmpl.RPM
+	}
+	if out.MaxParallelCalls == 0 {
+		out.MaxParallelCalls = tmpl.MaxParallelCalls
+	}
+	if len(out.AllowedModels) == 0 {
+		out.AllowedModels = tmpl.AllowedModels
+	}
+	if len(out.BlockedModels) == 0 {
+		out.BlockedModels = tmpl.BlockedModels
+	}
+	return &out
+}
+
+// decodeModelAliases reads the model_aliases of an update request
+func decodeModelAliases(raw interface{}) (map[string]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	object, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidAliases
+	}
+	aliases := make(map[string]string, len(object))
+	for from, to := range object {
+		target, ok := to.(string)
+		if !ok || from == "" || target == "" {
+			return nil, ErrInvalidAliases
+		}
+		aliases[from] = target
+	}
+	return aliases, nil
+}
+
+// decodeResponseMinimization validates the response minimization options
+// of an update, which arrive as a JSON array; null clears them
+func decodeResponseMinimization(raw interface{}) ([]string, error) {
+	if raw == nil {
+		return []string{}, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, ErrInvalidResponseMinimization
+	}
+	options := make([]string, 0, len(list))
+	for _, item := range list {
+		option, ok := item.(string)
+		if !ok {
+			return nil, ErrInvalidResponseMinimization
+		}
+		options = append(options, option)
+	}
+	if models.ValidateResponseMinimization(options) != nil {
+		return nil, ErrInvalidResponseMinimization
+	}
+	return options, nil
+}
+
+// validateSystemPromptUpdates checks the system prompt and policy of an
+// update; null clears them
+func validateSystemPromptUpdates(updates map[string]interface{}) error {
+	for _, field := range []string{"system_prompt", "system_prompt_policy"} {
+		raw, ok := updates[field]
+		if !ok {
+			continue
+		}
+		if raw == nil {
+			updates[field] = ""
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return ErrInvalidSystemPrompt
+		}
+		if field == "system_prompt_policy" && models.ValidateSystemPromptPolicy(value) != nil {
+			return ErrInvalidSystemPrompt
+		}
+	}
+	return nil
+}
+
+// validateBandwidthQuotaUpdate checks the bandwidth quota of an update; null
+// removes it
+func validateBandwidthQuotaUpdate(updates map[string]interface{}) error {
+	raw, ok := updates["max_monthly_bandwidth"]
+	if !ok {
+		return nil
+	}
+	switch value := raw.(type) {
+	case nil:
+		updates["max_monthly_bandwidth"] = 0
+	case float64:
+		if value < 0 || value != math.Trunc(value) {
+			return ErrInvalidBandwidthQuota
+		}
+		updates["max_monthly_bandwidth"] = int64(value)
+	default:
+		return ErrInvalidBandwidthQuota
+	}
+	return nil
+}
+
+func encodeModelAliases(aliases map[string]string) (datatypes.JSON, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(aliases)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
+// decodeEnvironments reads the environments of an update request, which
+// arrive as a JSON object; null clears them
+func decodeEnvironments(raw interface{}) (map[string]models.EnvironmentPolicy, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	if _, ok := raw.(map[string]interface{}); !ok {
+		return nil, ErrInvalidEnvironments
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, ErrInvalidEnvironments
+	}
+	var environments map[string]models.EnvironmentPolicy
+	if err := json.Unmarshal(data, &environments); err != nil {
+		return nil, ErrInvalidEnvironments
+	}
+	return environments, nil
+}
+
+func encodeEnvironments(environments map[string]models.EnvironmentPolicy) (datatypes.JSON, error) {
+	if models.ValidateEnvironmentPolicies(environments) != nil {
+		return nil, ErrInvalidEnvironments
+	}
+	if len(environments) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(environments)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
 }
@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Completer sends one chat request to the model
+type Completer func(ctx context.Context, request *providers.ChatRequest) (*providers.ChatResponse, error)
+
+// RuntimeConfig configures the tool runtime
+type RuntimeConfig struct {
+	Service        *Service
+	Logger         *zap.Logger
+	MaxDepth       int
+	Timeout        time.Duration
+	AllowedHosts   []string
+	MaxResultBytes int
+}
+
+// Runtime executes calls to registered tools and feeds the results back to
+// the model until it answers without calling one, or MaxDepth is reached.
+type Runtime struct {
+	service        *Service
+	logger         *zap.Logger
+	client         *http.Client
+	maxDepth       int
+	timeout        time.Duration
+	allowedHosts   []string
+	maxResultBytes int
+}
+
+// NewRuntime creates a tool runtime
+func NewRuntime(config *RuntimeConfig) *Runtime {
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = 5
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxResultBytes <= 0 {
+		config.MaxResultBytes = 64 * 1024
+	}
+
+	return &Runtime{
+		service: config.Service,
+		logger:  config.Logger,
+		client: &http.Client{
+			// A redirect could send the call to a host outside the allowlist
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxDepth:       config.MaxDepth,
+		timeout:        config.Timeout,
+		allowedHosts:   config.AllowedHosts,
+		maxResultBytes: config.MaxResultBytes,
+	}
+}
+
+// AllowedHosts returns the hosts tool endpoints may point to
+func (rt *Runtime) AllowedHosts() []string {
+	return rt.allowedHosts
+}
+
+// Prepare looks up the registered tools named in the request's tools and
+// fills in their description and parameters when the client omitted them.
+// Only tools the client listed are executed by the gateway. A nil runtime
+// or a request without registered tools returns nil.
+func (rt *Runtime) Prepare(ctx context.Context, request *providers.ChatRequest) map[string]*models.GatewayTool {
+	if rt == nil || len(request.Tools) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(request.Tools))
+	for _, tool := range request.Tools {
+		if tool.Type == "function" {
+			names = append(names, tool.Function.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	registered, err := rt.service.FindEnabled(ctx, names)
+	if err != nil {
+		rt.logger.Warn("Failed to load registered tools, passing tool calls through", zap.Error(err))
+		return nil
+	}
+	if len(registered) == 0 {
+		return nil
+	}
+
+	// Copy so the caller's request is left untouched across failover attempts
+	requestTools := make([]providers.Tool, len(request.Tools))
+	copy(requestTools, request.Tools)
+	for i, tool := range requestTools {
+		gatewayTool, ok := registered[tool.Function.Name]
+		if !ok {
+			continue
+		}
+		if tool.Function.Description == "" {
+			requestTools[i].Function.Description = gatewayTool.Description
+		}
+		if tool.Function.Parameters == nil && len(gatewayTool.Parameters) > 0 {
+			requestTools[i].Function.Parameters = json.RawMessage(gatewayTool.Parameters)
+		}
+	}
+	request.Tools = requestTools
+
+	return registered
+}
+
+// Run continues the conversation while the model calls only registered
+// tools. Responses with calls to client-side tools are returned unchanged so
+// the client can execute them. Token usage is summed over all rounds.
+func (rt *Runtime) Run(ctx context.Context, request *providers.ChatRequest, response *providers.ChatResponse,
+	registered map[string]*models.GatewayTool, complete Completer) (*providers.ChatResponse, error) {
+	if rt == nil || len(registered) == 0 {
+		return response, nil
+	}
+
+	messages := append([]providers.Message(nil), request.Messages...)
+	usage := response.Usage
+
+	for depth := 0; depth < rt.maxDepth; depth++ {
+		if len(response.Choices) != 1 || !allRegistered(response.Choices[0].Message.ToolCalls, registered) {
+			break
+		}
+
+		assistant := response.Choices[0].Message
+		messages = append(messages, assistant)
+		for _, call := range assistant.ToolCalls {
+			messages = append(messages, providers.Message{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    rt.execute(ctx, registered[call.Function.Name], call),
+			})
+		}
+
+		next := *request
+		next.Messages = messages
+		nextResponse, err := complete(ctx, &next)
+		if err != nil {
+			return nil, fmt.Errorf("tool runtime round %d failed: %w", depth+1, err)
+		}
+
+		response = nextResponse
+		usage = addUsage(usage, response.Usage)
+	}
+
+	response.Usage = usage
+	return response, nil
+}
+
+// execute calls a tool endpoint with the call's arguments as the JSON body.
+// Failures are returned to the model as an error object rather than failing
+// the request, so the model can recover or explain.
+func (rt *Runtime) execute(ctx context.Context, tool *models.GatewayTool, call providers.ToolCall) string {
+	logger := rt.logger.With(zap.String("tool", tool.Name), zap.String("tool_call_id", call.ID))
+
+	if err := ValidateEndpoint(tool.Endpoint, rt.allowedHosts); err != nil {
+		logger.Warn("Refusing to call tool endpoint", zap.Error(err))
+		return toolError("tool endpoint is not allowed")
+	}
+
+	arguments := call.Function.Arguments
+	if arguments == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return toolError("arguments are not valid JSON")
+	}
+
+	timeout := rt.timeout
+	if tool.TimeoutMs > 0 {
+		timeout = time.Duration(tool.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tool.Endpoint, bytes.NewBufferString(arguments))
+	if err != nil {
+		return toolError("failed to build tool request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-PLLM-Tool-Call-ID", call.ID)
+
+	start := time.Now()
+	resp, err := rt.client.Do(req)
+	if err != nil {
+		logger.Warn("Tool call failed", zap.Error(err))
+		return toolError("tool call failed: " + err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(rt.maxResultBytes)))
+	if err != nil {
+		logger.Warn("Failed to read tool response", zap.Error(err))
+		return toolError("failed to read tool response")
+	}
+
+	logger.Debug("Tool call finished",
+		zap.Int("status_code", resp.StatusCode),
+		zap.Duration("duration", time.Since(start)))
+
+	if resp.StatusCode >= 300 {
+		return toolError(fmt.Sprintf("tool returned status %d: %s", resp.StatusCode, body))
+	}
+	return string(body)
+}
+
+func allRegistered(calls []providers.ToolCall, registered map[string]*models.GatewayTool) bool {
+	if len(calls) == 0 {
+		return false
+	}
+	for _, call := range calls {
+		if _, ok := registered[call.Function.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func addUsage(total, round providers.Usage) providers.Usage {
+	reasoning := total.ReasoningTokens() + round.ReasoningTokens()
+
+	total.PromptTokens += round.PromptTokens
+	total.CompletionTokens += round.CompletionTokens
+	total.TotalTokens += round.TotalTokens
+	total.CompletionTokensDetails = nil
+	if reasoning > 0 {
+		total.CompletionTokensDetails = &providers.CompletionTokensDetails{ReasoningTokens: reasoning}
+	}
+	return total
+}
+
+func toolError(message string) string {
+	data, _ := json.Marshal(map[string]string{"error": message})
+	return string(data)
+}
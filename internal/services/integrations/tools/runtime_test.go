@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func toolCallResponse(name, arguments string) *providers.ChatResponse {
+	return &providers.ChatResponse{
+		Choices: []providers.Choice{{
+			Message: providers.Message{
+				Role: "assistant",
+				ToolCalls: []providers.ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: providers.FunctionCall{Name: name, Arguments: arguments},
+				}},
+			},
+			FinishReason: "tool_calls",
+		}},
+		Usage: providers.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+}
+
+func TestRuntime_ExecutesRegisteredToolCalls(t *testing.T) {
+	toolServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"city":"Paris"}`, string(body))
+		assert.Equal(t, "call_1", r.Header.Get("X-PLLM-Tool-Call-ID"))
+		_, _ = w.Write([]byte(`{"temp_c":21}`))
+	}))
+	defer toolServer.Close()
+
+	serverURL, _ := url.Parse(toolServer.URL)
+	runtime := NewRuntime(&RuntimeConfig{Logger: zap.NewNop(), AllowedHosts: []string{serverURL.Hostname()}})
+	registered := map[string]*models.GatewayTool{
+		"weather": {Name: "weather", Endpoint: toolServer.URL + "/weather", Enabled: true},
+	}
+
+	var seen []providers.Message
+	complete := func(ctx context.Context, request *providers.ChatRequest) (*providers.ChatResponse, error) {
+		seen = request.Messages
+		return &providers.ChatResponse{
+			Choices: []providers.Choice{{Message: providers.Message{Role: "assistant", Content: "It is 21C"}}},
+			Usage:   providers.Usage{PromptTokens: 30, CompletionTokens: 4, TotalTokens: 34},
+		}, nil
+	}
+
+	request := &providers.ChatRequest{Messages: []providers.Message{{Role: "user", Content: "Weather in Paris?"}}}
+	response, err := runtime.Run(context.Background(), request, toolCallResponse("weather", `{"city":"Paris"}`), registered, complete)
+	require.NoError(t, err)
+
+	assert.Equal(t, "It is 21C", response.Choices[0].Message.Content)
+	assert.Equal(t, 49, response.Usage.TotalTokens)
+	require.Len(t, seen, 3)
+	assert.Equal(t, "tool", seen[2].Role)
+	assert.Equal(t, "call_1", seen[2].ToolCallID)
+	assert.Equal(t, `{"temp_c":21}`, seen[2].Content)
+	assert.Len(t, request.Messages, 1, "caller's messages must not be modified")
+}
+
+func TestRuntime_StopsAtMaxDepth(t *testing.T) {
+	toolServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer toolServer.Close()
+
+	serverURL, _ := url.Parse(toolServer.URL)
+	runtime := NewRuntime(&RuntimeConfig{Logger: zap.NewNop(), MaxDepth: 2, AllowedHosts: []string{serverURL.Hostname()}})
+	registered := map[string]*models.GatewayTool{"loop": {Name: "loop", Endpoint: toolServer.URL}}
+
+	rounds := 0
+	complete := func(ctx context.Context, request *providers.ChatRequest) (*providers.ChatResponse, error) {
+		rounds++
+		return toolCallResponse("loop", `{}`), nil
+	}
+
+	response, err := runtime.Run(context.Background(), &providers.ChatRequest{}, toolCallResponse("loop", `{}`), registered, complete)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rounds)
+	assert.Len(t, response.Choices[0].Message.ToolCalls, 1, "last tool call is returned to the client")
+}
+
+func TestRuntime_PassesThroughClientTools(t *testing.T) {
+	runtime := NewRuntime(&RuntimeConfig{Logger: zap.NewNop()})
+	registered := map[string]*models.GatewayTool{"weather": {Name: "weather"}}
+
+	complete := func(ctx context.Context, request *providers.ChatRequest) (*providers.ChatResponse, error) {
+		t.Fatal("model should not be called again for client-side tools")
+		return nil, nil
+	}
+
+	original := toolCallResponse("client_lookup", `{}`)
+	response, err := runtime.Run(context.Background(), &providers.ChatRequest{}, original, registered, complete)
+	require.NoError(t, err)
+	assert.Same(t, original, response)
+}
+
+func TestRuntime_RejectsEndpointsOutsideAllowlist(t *testing.T) {
+	runtime := NewRuntime(&RuntimeConfig{Logger: zap.NewNop(), AllowedHosts: []string{"tools.internal"}})
+	tool := &models.GatewayTool{Name: "exfil", Endpoint: "http://169.254.169.254/latest"}
+
+	result := runtime.execute(context.Background(), tool, providers.ToolCall{ID: "call_1"})
+	assert.JSONEq(t, `{"error":"tool endpoint is not allowed"}`, result)
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	allowed := []string{"tools.internal", ".svc.example.com"}
+
+	assert.NoError(t, ValidateEndpoint("https://tools.internal/weather", allowed))
+	assert.NoError(t, ValidateEndpoint("http://search.svc.example.com:8080/q", allowed))
+	assert.Error(t, ValidateEndpoint("https://evil.example.com/", allowed))
+	assert.Error(t, ValidateEndpoint("ftp://tools.internal/", allowed))
+	assert.Error(t, ValidateEndpoint("https://tools.internal/", nil))
+}
+
+func TestRuntime_DoesNotFollowRedirects(t *testing.T) {
+	var followed bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed = true
+	}))
+	defer target.Close()
+	tool := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer tool.Close()
+	toolURL, err := url.Parse(tool.URL)
+	require.NoError(t, err)
+
+	runtime := NewRuntime(&RuntimeConfig{Logger: zap.NewNop(), AllowedHosts: []string{toolURL.Hostname()}})
+	result := runtime.execute(context.Background(), &models.GatewayTool{Name: "weather", Endpoint: tool.URL},
+		providers.ToolCall{ID: "call_1", Function: providers.FunctionCall{Arguments: "{}"}})
+
+	assert.False(t, followed)
+	assert.Contains(t, result, "tool returned status 307")
+}
@@ -0,0 +1,139 @@
+// Package tools implements the gateway tool runtime: admins register
+// HTTP-backed tools, and when a model calls one of them the gateway executes
+// the call and continues the conversation on the client's behalf.
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// Function names follow the OpenAI function calling rules
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Service manages registered tools in the database.
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+// NewService creates a new tool service.
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// List returns all registered tools.
+func (s *Service) List() ([]models.GatewayTool, error) {
+	var tools []models.GatewayTool
+	if err := s.db.Order("name").Find(&tools).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	return tools, nil
+}
+
+// Get returns a tool by its UUID.
+func (s *Service) Get(id uuid.UUID) (*models.GatewayTool, error) {
+	var tool models.GatewayTool
+	if err := s.db.First(&tool, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("tool not found: %w", err)
+	}
+	return &tool, nil
+}
+
+// FindEnabled returns the enabled tools with the given names, keyed by name.
+func (s *Service) FindEnabled(ctx context.Context, names []string) (map[string]*models.GatewayTool, error) {
+	var tools []models.GatewayTool
+	if err := s.db.WithContext(ctx).
+		Where("name IN ? AND enabled = ?", names, true).
+		Find(&tools).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tools: %w", err)
+	}
+
+	byName := make(map[string]*models.GatewayTool, len(tools))
+	for i := range tools {
+		byName[tools[i].Name] = &tools[i]
+	}
+	return byName, nil
+}
+
+// Create inserts a new tool.
+func (s *Service) Create(tool *models.GatewayTool) error {
+	if err := s.db.Create(tool).Error; err != nil {
+		return fmt.Errorf("failed to create tool: %w", err)
+	}
+	return nil
+}
+
+// Update replaces a tool's fields.
+func (s *Service) Update(id uuid.UUID, tool *models.GatewayTool) error {
+	updates := map[string]interface{}{
+		"name":        tool.Name,
+		"description": tool.Description,
+		"parameters":  tool.Parameters,
+		"endpoint":    tool.Endpoint,
+		"timeout_ms":  tool.TimeoutMs,
+		"enabled":     tool.Enabled,
+	}
+	result := s.db.Model(&models.GatewayTool{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update tool: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("tool not found")
+	}
+	return nil
+}
+
+// Delete removes a tool.
+func (s *Service) Delete(id uuid.UUID) error {
+	result := s.db.Delete(&models.GatewayTool{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete tool: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("tool not found")
+	}
+	return nil
+}
+
+// ValidateName checks that a tool name can be used as a function name.
+func ValidateName(name string) error {
+	if !toolNamePattern.MatchString(name) {
+		return fmt.Errorf("tool name must be 1-64 letters, digits, underscores or dashes")
+	}
+	return nil
+}
+
+// ValidateEndpoint checks that a tool endpoint is an http(s) URL whose host is
+// allowlisted. An entry starting with "." allows all subdomains of that
+// domain. With no allowlist, no endpoint is allowed.
+func ValidateEndpoint(endpoint string, allowedHosts []string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("tool endpoint must be an absolute http(s) URL")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("tool endpoint host %q is not in tools.allowed_hosts", host)
+}
@@ -0,0 +1,446 @@
+// Package jobs runs asynchronous inference requests in the background and
+// keeps their results so clients behind short load-balancer timeouts can
+// poll for them or receive a webhook on completion.
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/services/data/artifacts"
+	"github.com/amerfu/pllm/internal/infrastructure/webhook"
+)
+
+// ErrJobNotFound is returned when a job does not exist or has expired
+var ErrJobNotFound = errors.New("job not found")
+
+// retryLaterError marks an executor error the job may recover from
+type retryLaterError struct {
+	err error
+}
+
+func (e *retryLaterError) Error() string { return e.err.Error() }
+func (e *retryLaterError) Unwrap() error { return e.err }
+
+// RetryLater wraps an executor error so a job with a RetryUntil is tried
+// again after the retry interval instead of failing, as long as the next
+// attempt falls before its RetryUntil
+func RetryLater(err error) error {
+	return &retryLaterError{err: err}
+}
+
+// Executor runs a job's request and returns the response to store
+type Executor func(ctx context.Context, job *models.Job) (interface{}, error)
+
+// Service persists jobs and executes them on a pool of workers
+type Service struct {
+	db            *gorm.DB
+	logger        *zap.Logger
+	executor      Executor
+	workers       int
+	timeout       time.Duration
+	resultTTL     time.Duration
+	retryInterval time.Duration
+	sweepInterval time.Duration
+	webhooks      *webhook.Guard
+	webhookClient *http.Client
+	queue         chan uuid.UUID
+	stopCh        chan struct{}
+	stopOnce      sync.Once
+	wg            sync.WaitGroup
+}
+
+type Config struct {
+	DB             *gorm.DB
+	Logger         *zap.Logger
+	Executor       Executor
+	Workers        int
+	Timeout        time.Duration
+	ResultTTL      time.Duration
+	WebhookTimeout time.Duration
+	RetryInterval  time.Duration // Delay between attempts of jobs that retry later
+	SweepInterval  time.Duration // How often queued jobs are re-dispatched and expired jobs purged
+
+	// WebhookAllowedHosts may receive webhooks even when they resolve to
+	// private addresses; ".example.com" matches subdomains
+	WebhookAllowedHosts []string
+}
+
+func NewService(config *Config) *Service {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Minute
+	}
+	if config.ResultTTL == 0 {
+		config.ResultTTL = 24 * time.Hour
+	}
+	if config.WebhookTimeout == 0 {
+		config.WebhookTimeout = 10 * time.Second
+	}
+	if config.RetryInterval == 0 {
+		config.RetryInterval = 30 * time.Second
+	}
+	if config.SweepInterval == 0 {
+		config.SweepInterval = 30 * time.Second
+	}
+
+	webhooks := webhook.NewGuard(config.WebhookAllowedHosts)
+
+	return &Service{
+		db:            config.DB,
+		logger:        config.Logger,
+		executor:      config.Executor,
+		workers:       config.Workers,
+		timeout:       config.Timeout,
+		resultTTL:     config.ResultTTL,
+		retryInterval: config.RetryInterval,
+		sweepInterval: config.SweepInterval,
+		webhooks:      webhooks,
+		webhookClient: webhooks.Client(config.WebhookTimeout),
+		queue:         make(chan uuid.UUID, 1000),
+		stopCh:        make(chan struct{}),
+	}
+}
+
+// Start launches the workers and the sweep loop
+func (s *Service) Start(ctx context.Context) {
+	s.logger.Info("Starting async job workers",
+		zap.Int("workers", s.workers),
+		zap.Duration("timeout", s.timeout))
+
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.worker(ctx)
+	}
+
+	s.wg.Add(1)
+	go s.sweepLoop(ctx)
+}
+
+// Stop stops the workers after their current job. Jobs left running by an
+// instance that never stopped are re-queued by the sweep of another one.
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+	s.wg.Wait()
+}
+
+// ValidateWebhookURL rejects webhook URLs that are not absolute http(s) URLs
+// or that point at loopback, private or link-local addresses outside
+// jobs.webhook_allowed_hosts
+func (s *Service) ValidateWebhookURL(ctx context.Context, rawURL string) error {
+	return s.webhooks.Validate(ctx, rawURL)
+}
+
+// Submit stores a new job and schedules it for execution
+func (s *Service) Submit(ctx context.Context, job *models.Job) error {
+	job.Status = models.JobStatusQueued
+	job.ExpiresAt = time.Now().Add(s.resultTTL)
+
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	// A full queue is not an error: the sweep loop picks the job up later
+	select {
+	case s.queue <- job.ID:
+	default:
+		s.logger.Warn("Job queue full, deferring job to sweep", zap.String("job_id", job.ID.String()))
+	}
+	return nil
+}
+
+// Defer stores a job whose first attempt waits for the retry interval and
+// which keeps being retried while its executor asks to retry later, until
+// retryUntil. Its result can be polled for the result TTL after that.
+func (s *Service) Defer(ctx context.Context, job *models.Job, retryUntil time.Time) error {
+	next := time.Now().Add(s.retryInterval)
+	job.Status = models.JobStatusQueued
+	job.NextAttemptAt = &next
+	job.RetryUntil = &retryUntil
+	job.ExpiresAt = retryUntil.Add(s.resultTTL)
+
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	return nil
+}
+
+// Get returns a job that has not expired
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	var job models.Job
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND expires_at > ?", id, time.Now()).
+		First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *Service) worker(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case id := <-s.queue:
+			s.run(ctx, id)
+		}
+	}
+}
+
+// run claims a queued job and executes it. Claiming with a conditional update
+// keeps a job from running twice when several replicas sweep the same table.
+func (s *Service) run(ctx context.Context, id uuid.UUID) {
+	now := time.Now()
+	claim := s.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusQueued).
+		Updates(map[string]interface{}{
+			"status":     models.JobStatusRunning,
+			"started_at": now,
+			"attempts":   gorm.Expr("attempts + 1"),
+		})
+	if claim.Error != nil {
+		s.logger.Error("Failed to claim job", zap.String("job_id", id.String()), zap.Error(claim.Error))
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+
+	var job models.Job
+	if err := s.db.WithContext(ctx).First(&job, "id = ?", id).Error; err != nil {
+		s.logger.Error("Failed to load job", zap.String("job_id", id.String()), zap.Error(err))
+		return
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	response, err := s.executor(execCtx, &job)
+	cancel()
+
+	completedAt := time.Now()
+	if err != nil && s.retry(ctx, &job, err, completedAt) {
+		return
+	}
+
+	updates := map[string]interface{}{"completed_at": completedAt}
+	if err != nil {
+		updates["status"] = models.JobStatusFailed
+		updates["error"] = err.Error()
+		job.Status, job.Error = models.JobStatusFailed, err.Error()
+	} else if data, marshalErr := json.Marshal(response); marshalErr != nil {
+		updates["status"] = models.JobStatusFailed
+		updates["error"] = "failed to encode response: " + marshalErr.Error()
+		job.Status, job.Error = models.JobStatusFailed, updates["error"].(string)
+	} else {
+		updates["status"] = models.JobStatusSucceeded
+		updates["response"] = data
+		updates["error"] = "" // Left by attempts made during an outage
+		job.Status, job.Response, job.Error = models.JobStatusSucceeded, data, ""
+	}
+	job.CompletedAt = &completedAt
+
+	if err := s.db.WithContext(ctx).Model(&models.Job{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		s.logger.Error("Failed to store job result", zap.String("job_id", id.String()), zap.Error(err))
+		return
+	}
+
+	s.logger.Info("Async job finished",
+		zap.String("job_id", id.String()),
+		zap.String("status", string(job.Status)),
+		zap.Duration("duration", completedAt.Sub(now)))
+
+	if job.WebhookURL != "" {
+		s.deliverWebhook(ctx, &job)
+	}
+}
+
+// retry puts a job whose executor asked to retry later back in the queue for
+// its next attempt, and reports whether it did. Jobs without a RetryUntil,
+// or whose next attempt would fall after it, fail instead.
+func (s *Service) retry(ctx context.Context, job *models.Job, err error, now time.Time) bool {
+	var retryLater *retryLaterError
+	if !errors.As(err, &retryLater) || job.RetryUntil == nil {
+		return false
+	}
+	next := now.Add(s.retryInterval)
+	if next.After(*job.RetryUntil) {
+		return false
+	}
+
+	if updateErr := s.db.WithContext(ctx).Model(&models.Job{}).Where("id = ?", job.ID).
+		Updates(map[string]interface{}{
+			"status":          models.JobStatusQueued,
+			"started_at":      nil,
+			"next_attempt_at": next,
+			"error":           err.Error(),
+		}).Error; updateErr != nil {
+		s.logger.Error("Failed to reschedule job", zap.String("job_id", job.ID.String()), zap.Error(updateErr))
+		return false
+	}
+
+	s.logger.Info("Async job will be retried",
+		zap.String("job_id", job.ID.String()),
+		zap.Int("attempts", job.Attempts),
+		zap.Time("next_attempt_at", next),
+		zap.Error(err))
+	return true
+}
+
+// deliverWebhook posts the finished job to its webhook URL
+func (s *Service) deliverWebhook(ctx context.Context, job *models.Job) {
+	body, err := json.Marshal(NewView(job))
+	if err != nil {
+		s.logger.Error("Failed to encode job webhook", zap.String("job_id", job.ID.String()), zap.Error(err))
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		s.logger.Warn("Invalid job webhook URL", zap.String("job_id", job.ID.String()), zap.Error(err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		s.logger.Warn("Job webhook delivery failed", zap.String("job_id", job.ID.String()), zap.Error(err))
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		s.logger.Warn("Job webhook rejected",
+			zap.String("job_id", job.ID.String()),
+			zap.Int("status_code", resp.StatusCode))
+		return
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Job{}).Where("id = ?", job.ID).
+		Update("webhook_delivered", true).Error; err != nil {
+		s.logger.Warn("Failed to mark job webhook delivered", zap.String("job_id", job.ID.String()), zap.Error(err))
+	}
+}
+
+// sweepLoop re-dispatches queued jobs that missed the in-memory queue (full
+// queue, restarts), re-queues jobs orphaned by a crashed instance and purges
+// expired jobs
+func (s *Service) sweepLoop(ctx context.Context) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *Service) sweep(ctx context.Context) {
+	// A running job outlives its execution timeout only when the instance
+	// that claimed it died; hand it back to the queue
+	lease := time.Now().Add(-(s.timeout + s.sweepInterval))
+	requeue := s.db.WithContext(ctx).Model(&models.Job{}).
+		Where("status = ? AND started_at < ?", models.JobStatusRunning, lease).
+		Updates(map[string]interface{}{"status": models.JobStatusQueued, "started_at": nil})
+	if requeue.Error != nil {
+		s.logger.Error("Failed to requeue orphaned jobs", zap.Error(requeue.Error))
+	} else if requeue.RowsAffected > 0 {
+		s.logger.Warn("Requeued orphaned running jobs", zap.Int64("count", requeue.RowsAffected))
+	}
+
+	// Jobs waiting for a later attempt are dispatched once it is due
+	now := time.Now()
+	var ids []uuid.UUID
+	if err := s.db.WithContext(ctx).Model(&models.Job{}).
+		Where("status = ? AND ((next_attempt_at IS NULL AND created_at < ?) OR next_attempt_at <= ?)",
+			models.JobStatusQueued, now.Add(-s.sweepInterval), now).
+		Order("created_at").Limit(cap(s.queue)).
+		Pluck("id", &ids).Error; err != nil {
+		s.logger.Error("Failed to list queued jobs", zap.Error(err))
+	}
+	for _, id := range ids {
+		select {
+		case s.queue <- id:
+		default:
+			return
+		}
+	}
+
+	// Jobs of users and teams under legal hold are kept past expiry
+	if err := s.db.WithContext(ctx).Unscoped().
+		Scopes(artifacts.NotHeld("team_id", "user_id")).
+		Where("expires_at < ?", time.Now()).
+		Delete(&models.Job{}).Error; err != nil {
+		s.logger.Error("Failed to purge expired jobs", zap.Error(err))
+	}
+}
+
+// View is the client-facing representation of a job
+type View struct {
+	ID          uuid.UUID        `json:"id"`
+	Object      string           `json:"object"`
+	Status      models.JobStatus `json:"status"`
+	Model       string           `json:"model"`
+	CreatedAt   int64            `json:"created_at"`
+	CompletedAt *int64           `json:"completed_at,omitempty"`
+	ExpiresAt   int64            `json:"expires_at"`
+	Result      json.RawMessage  `json:"result,omitempty"`
+	Error       string           `json:"error,omitempty"`
+
+	// Jobs queued during an outage: attempts so far and the next one
+	Attempts      int    `json:"attempts,omitempty"`
+	NextAttemptAt *int64 `json:"next_attempt_at,omitempty"`
+}
+
+// NewView builds the client-facing representation of a job
+func NewView(job *models.Job) *View {
+	view := &View{
+		ID:        job.ID,
+		Object:    "job",
+		Status:    job.Status,
+		Model:     job.Model,
+		CreatedAt: job.CreatedAt.Unix(),
+		ExpiresAt: job.ExpiresAt.Unix(),
+		Error:     job.Error,
+	}
+	if job.RetryUntil != nil {
+		view.Attempts = job.Attempts
+		if job.NextAttemptAt != nil && job.Status == models.JobStatusQueued {
+			nextAttemptAt := job.NextAttemptAt.Unix()
+			view.NextAttemptAt = &nextAttemptAt
+		}
+	}
+	if job.CompletedAt != nil {
+		completedAt := job.CompletedAt.Unix()
+		view.CompletedAt = &completedAt
+	}
+	if len(job.Response) > 0 {
+		view.Result = json.RawMessage(job.Response)
+	}
+	return view
+}
@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+)
+
+func TestService_ExecutesJobsAndDeliversWebhooks(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+
+	webhooks := make(chan View, 2)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var view View
+		_ = json.NewDecoder(r.Body).Decode(&view)
+		webhooks <- view
+	}))
+	defer webhookServer.Close()
+
+	service := NewService(&Config{
+		DB:     db,
+		Logger: zap.NewNop(),
+		Executor: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			if job.Model == "broken" {
+				return nil, errors.New("provider unavailable")
+			}
+			return map[string]string{"model": job.Model}, nil
+		},
+		Workers:             2,
+		WebhookAllowedHosts: []string{"127.0.0.1"},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Start(ctx)
+	defer service.Stop()
+
+	ok := &models.Job{Endpoint: "chat.completions", Model: "gpt-4", Request: []byte(`{"model":"gpt-4"}`), WebhookURL: webhookServer.URL}
+	failed := &models.Job{Endpoint: "chat.completions", Model: "broken", Request: []byte(`{}`), WebhookURL: webhookServer.URL}
+	require.NoError(t, service.Submit(ctx, ok))
+	require.NoError(t, service.Submit(ctx, failed))
+
+	results := map[string]View{}
+	for i := 0; i < 2; i++ {
+		select {
+		case view := <-webhooks:
+			results[view.ID.String()] = view
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for job webhooks")
+		}
+	}
+
+	assert.Equal(t, models.JobStatusSucceeded, results[ok.ID.String()].Status)
+	assert.JSONEq(t, `{"model":"gpt-4"}`, string(results[ok.ID.String()].Result))
+	assert.Equal(t, models.JobStatusFailed, results[failed.ID.String()].Status)
+	assert.Equal(t, "provider unavailable", results[failed.ID.String()].Error)
+
+	stored, err := service.Get(ctx, ok.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusSucceeded, stored.Status)
+	assert.NotNil(t, stored.CompletedAt)
+}
+
+func TestService_RetriesJobsQueuedDuringOutage(t *testing.T) {
+	db, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+
+	webhooks := make(chan View, 2)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var view View
+		_ = json.NewDecoder(r.Body).Decode(&view)
+		webhooks <- view
+	}))
+	defer webhookServer.Close()
+
+	attempts := make(map[string]int)
+	service := NewService(&Config{
+		DB:     db,
+		Logger: zap.NewNop(),
+		Executor: func(ctx context.Context, job *models.Job) (interface{}, error) {
+			attempts[job.Model]++
+			if job.Model == "recovering" && attempts[job.Model] >= 3 {
+				return map[string]string{"model": job.Model}, nil
+			}
+			return nil, RetryLater(errors.New("no healthy instances available"))
+		},
+		Workers:             1,
+		RetryInterval:       100 * time.Millisecond,
+		SweepInterval:       50 * time.Millisecond,
+		WebhookAllowedHosts: []string{"127.0.0.1"},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Start(ctx)
+	defer service.Stop()
+
+	recovering := &models.Job{Endpoint: "chat.completions", Model: "recovering", Request: []byte(`{}`), WebhookURL: webhookServer.URL}
+	down := &models.Job{Endpoint: "chat.completions", Model: "down", Request: []byte(`{}`), WebhookURL: webhookServer.URL}
+	require.NoError(t, service.Defer(ctx, recovering, time.Now().Add(time.Minute)))
+	require.NoError(t, service.Defer(ctx, down, time.Now().Add(500*time.Millisecond)))
+
+	queued, err := service.Get(ctx, recovering.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusQueued, queued.Status)
+	assert.NotNil(t, NewView(queued).NextAttemptAt)
+
+	results := map[string]View{}
+	for i := 0; i < 2; i++ {
+		select {
+		case view := <-webhooks:
+			results[view.ID.String()] = view
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for job webhooks")
+		}
+	}
+
+	assert.Equal(t, models.JobStatusSucceeded, results[recovering.ID.String()].Status)
+	assert.Equal(t, 3, results[recovering.ID.String()].Attempts)
+	assert.Empty(t, results[recovering.ID.String()].Error)
+	assert.Equal(t, models.JobStatusFailed, results[down.ID.String()].Status, "jobs fail once the wait is over")
+	assert.Equal(t, "no healthy instances available", results[down.ID.String()].Error)
+}
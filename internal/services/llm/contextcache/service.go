@@ -0,0 +1,401 @@
+// Package contextcache manages reusable prompt prefixes created through
+// /v1/caches. Gemini models on Vertex store the context as a provider-side
+// cachedContents resource; for every other model the gateway keeps the
+// messages and prepends them to requests, where provider prompt caching
+// (Anthropic cache_control, OpenAI automatic prefix caching) applies.
+package contextcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+var (
+	// ErrCacheNotFound is returned when a cache does not exist or has expired
+	ErrCacheNotFound = errors.New("context cache not found")
+
+	// ErrInvalidRequest wraps validation failures of create and update requests
+	ErrInvalidRequest = errors.New("invalid context cache request")
+)
+
+const (
+	DefaultTTL = time.Hour
+	MinTTL     = time.Minute
+	MaxTTL     = 7 * 24 * time.Hour
+)
+
+// Owner identifies who a cache belongs to and which team pays for it
+type Owner struct {
+	UserID *uuid.UUID
+	KeyID  *uuid.UUID
+	TeamID *uuid.UUID
+}
+
+// CreateRequest is the body of POST /v1/caches
+type CreateRequest struct {
+	Model       string              `json:"model"`
+	DisplayName string              `json:"display_name,omitempty"`
+	Messages    []providers.Message `json:"messages"`
+	TTLSeconds  int                 `json:"ttl_seconds,omitempty"`
+}
+
+// UpdateRequest is the body of PATCH /v1/caches/{id}
+type UpdateRequest struct {
+	DisplayName *string `json:"display_name,omitempty"`
+	TTLSeconds  int     `json:"ttl_seconds,omitempty"` // New TTL, counted from now
+}
+
+// Charge is what a create or update cost, for usage tracking
+type Charge struct {
+	WriteTokens int
+	Cost        float64
+}
+
+// Service persists context caches and manages their provider-side resources
+type Service struct {
+	db             *gorm.DB
+	logger         *zap.Logger
+	modelManager   *llmModels.ModelManager
+	pricingManager *config.ModelPricingManager
+}
+
+type Config struct {
+	DB             *gorm.DB
+	Logger         *zap.Logger
+	ModelManager   *llmModels.ModelManager
+	PricingManager *config.ModelPricingManager
+}
+
+func NewService(cfg *Config) *Service {
+	return &Service{
+		db:             cfg.DB,
+		logger:         cfg.Logger,
+		modelManager:   cfg.ModelManager,
+		pricingManager: cfg.PricingManager,
+	}
+}
+
+// Create stores a new cache. The provider holds the context when the selected
+// instance supports it; otherwise the cache is gateway-managed.
+func (s *Service) Create(ctx context.Context, req *CreateRequest, owner Owner) (*models.ContextCache, *Charge, error) {
+	if req.Model == "" {
+		return nil, nil, fmt.Errorf("%w: model is required", ErrInvalidRequest)
+	}
+	if len(req.Messages) == 0 {
+		return nil, nil, fmt.Errorf("%w: messages are required", ErrInvalidRequest)
+	}
+	ttl, err := parseTTL(req.TTLSeconds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messages, err := json.Marshal(req.Messages)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	s.purgeExpired(ctx)
+
+	cache := &models.ContextCache{
+		DisplayName: req.DisplayName,
+		Model:       req.Model,
+		Messages:    messages,
+		TokenCount:  estimateTokens(req.Messages),
+		UserID:      owner.UserID,
+		KeyID:       owner.KeyID,
+		TeamID:      owner.TeamID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	charge := &Charge{}
+
+	// Routes spread requests over several deployments, so only plain models
+	// can hold a provider-side cache
+	if _, isRoute := s.modelManager.ResolveRoute(req.Model); !isRoute {
+		instance, err := s.modelManager.GetBestInstance(ctx, req.Model)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		if cacher, ok := instance.Provider.(providers.ContextCacher); ok {
+			created, err := cacher.CreateCachedContent(ctx, &providers.CachedContentRequest{
+				Model:       instance.Config.Provider.Model,
+				DisplayName: req.DisplayName,
+				Messages:    req.Messages,
+				TTL:         ttl,
+			})
+			switch {
+			case errors.Is(err, providers.ErrContextCachingUnsupported):
+			case err != nil:
+				return nil, nil, fmt.Errorf("failed to create provider cache: %w", err)
+			default:
+				cache.InstanceID = instance.Config.ID
+				cache.ProviderType = instance.Config.Provider.Type
+				cache.ProviderCacheName = created.Name
+				if created.TokenCount > 0 {
+					cache.TokenCount = created.TokenCount
+				}
+				if !created.ExpiresAt.IsZero() {
+					cache.ExpiresAt = created.ExpiresAt
+				}
+				charge.WriteTokens = cache.TokenCount
+				charge.Cost = s.creationCost(instance.Config.Provider.Model, cache.TokenCount, ttl)
+			}
+		}
+	}
+
+	cache.StorageCost = charge.Cost
+	if err := s.db.WithContext(ctx).Create(cache).Error; err != nil {
+		s.deleteProviderCache(ctx, cache)
+		return nil, nil, fmt.Errorf("failed to store context cache: %w", err)
+	}
+	return cache, charge, nil
+}
+
+// Get returns a cache that has not expired
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*models.ContextCache, error) {
+	var cache models.ContextCache
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND expires_at > ?", id, time.Now()).
+		First(&cache).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCacheNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// List returns the unexpired caches of an owner, newest first. A zero owner
+// lists every cache.
+func (s *Service) List(ctx context.Context, owner Owner) ([]models.ContextCache, error) {
+	query := s.db.WithContext(ctx).Where("expires_at > ?", time.Now())
+	switch {
+	case owner.KeyID != nil:
+		query = query.Where("key_id = ?", *owner.KeyID)
+	case owner.UserID != nil:
+		query = query.Where("user_id = ?", *owner.UserID)
+	}
+
+	var caches []models.ContextCache
+	if err := query.Order("created_at DESC").Find(&caches).Error; err != nil {
+		return nil, err
+	}
+	return caches, nil
+}
+
+// Update renames a cache or resets its TTL. Extending a provider-side cache is
+// charged for the storage time beyond what was already paid for.
+func (s *Service) Update(ctx context.Context, cache *models.ContextCache, req *UpdateRequest) (*Charge, error) {
+	updates := map[string]interface{}{}
+	charge := &Charge{}
+
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+		cache.DisplayName = *req.DisplayName
+	}
+	if req.TTLSeconds != 0 {
+		ttl, err := parseTTL(req.TTLSeconds)
+		if err != nil {
+			return nil, err
+		}
+		expiresAt := time.Now().Add(ttl)
+
+		if cache.IsProviderManaged() {
+			cacher, instance, err := s.providerCacher(cache)
+			if err != nil {
+				return nil, err
+			}
+			updated, err := cacher.UpdateCachedContentTTL(ctx, cache.ProviderCacheName, ttl)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update provider cache: %w", err)
+			}
+			if !updated.ExpiresAt.IsZero() {
+				expiresAt = updated.ExpiresAt
+			}
+			if extension := expiresAt.Sub(cache.ExpiresAt); extension > 0 {
+				charge.Cost = s.storageCost(instance.Config.Provider.Model, cache.TokenCount, extension)
+			}
+		}
+
+		updates["expires_at"] = expiresAt
+		cache.ExpiresAt = expiresAt
+	}
+	if len(updates) == 0 {
+		return charge, nil
+	}
+
+	if charge.Cost > 0 {
+		cache.StorageCost += charge.Cost
+		updates["storage_cost"] = cache.StorageCost
+	}
+	if err := s.db.WithContext(ctx).Model(cache).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update context cache: %w", err)
+	}
+	return charge, nil
+}
+
+// Delete removes a cache and its provider-side resource
+func (s *Service) Delete(ctx context.Context, cache *models.ContextCache) error {
+	if cache.IsProviderManaged() {
+		cacher, _, err := s.providerCacher(cache)
+		if err == nil {
+			err = cacher.DeleteCachedContent(ctx, cache.ProviderCacheName)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete provider cache: %w", err)
+		}
+	}
+	if err := s.db.WithContext(ctx).Delete(cache).Error; err != nil {
+		return fmt.Errorf("failed to delete context cache: %w", err)
+	}
+	return nil
+}
+
+// Apply rewrites a provider request for the instance it is sent to. The
+// provider cache is referenced when the request landed on the instance that
+// holds it; otherwise the cached messages are prepended.
+func Apply(request *providers.ChatRequest, cache *models.ContextCache, instance *llmModels.ModelInstance) error {
+	if cache.IsProviderManaged() && instance.Config.ID == cache.InstanceID {
+		request.CachedContent = cache.ProviderCacheName
+		return nil
+	}
+
+	var cached []providers.Message
+	if err := json.Unmarshal(cache.Messages, &cached); err != nil {
+		return fmt.Errorf("invalid cached messages: %w", err)
+	}
+	request.CachedContent = ""
+	request.CachedPrefix = len(cached)
+	request.Messages = append(cached, request.Messages...)
+	return nil
+}
+
+// providerCacher returns the provider holding a cache
+func (s *Service) providerCacher(cache *models.ContextCache) (providers.ContextCacher, *llmModels.ModelInstance, error) {
+	instance, ok := s.modelManager.GetRegistry().GetInstance(cache.InstanceID)
+	if !ok {
+		return nil, nil, fmt.Errorf("model instance %s no longer exists", cache.InstanceID)
+	}
+	cacher, ok := instance.Provider.(providers.ContextCacher)
+	if !ok {
+		return nil, nil, fmt.Errorf("model instance %s does not support context caching", cache.InstanceID)
+	}
+	return cacher, instance, nil
+}
+
+// deleteProviderCache cleans up a provider cache that could not be recorded
+func (s *Service) deleteProviderCache(ctx context.Context, cache *models.ContextCache) {
+	if !cache.IsProviderManaged() {
+		return
+	}
+	cacher, _, err := s.providerCacher(cache)
+	if err == nil {
+		err = cacher.DeleteCachedContent(ctx, cache.ProviderCacheName)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to delete orphaned provider cache",
+			zap.String("cache", cache.ProviderCacheName), zap.Error(err))
+	}
+}
+
+// purgeExpired drops expired cache records. Providers expire their own
+// resources, so only the database is cleaned up.
+func (s *Service) purgeExpired(ctx context.Context) {
+	if err := s.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.ContextCache{}).Error; err != nil {
+		s.logger.Warn("Failed to purge expired context caches", zap.Error(err))
+	}
+}
+
+// creationCost prices writing the cache plus storing it for ttl
+func (s *Service) creationCost(model string, tokens int, ttl time.Duration) float64 {
+	pricing := s.pricing(model)
+	if pricing == nil {
+		return 0
+	}
+	writeCost := pricing.CacheCreationInputTokenCost
+	if writeCost == 0 {
+		writeCost = pricing.InputCostPerToken
+	}
+	return float64(tokens)*writeCost + s.storageCost(model, tokens, ttl)
+}
+
+// storageCost prices keeping tokens cached for d
+func (s *Service) storageCost(model string, tokens int, d time.Duration) float64 {
+	pricing := s.pricing(model)
+	if pricing == nil {
+		return 0
+	}
+	return float64(tokens) * d.Hours() * pricing.CacheStorageCostPerTokenHour
+}
+
+func (s *Service) pricing(model string) *config.ModelPricingInfo {
+	if s.pricingManager == nil {
+		return nil
+	}
+	return s.pricingManager.GetPricing(model)
+}
+
+func parseTTL(seconds int) (time.Duration, error) {
+	if seconds == 0 {
+		return DefaultTTL, nil
+	}
+	ttl := time.Duration(seconds) * time.Second
+	if ttl < MinTTL || ttl > MaxTTL {
+		return 0, fmt.Errorf("%w: ttl_seconds must be between %d and %d", ErrInvalidRequest,
+			int(MinTTL.Seconds()), int(MaxTTL.Seconds()))
+	}
+	return ttl, nil
+}
+
+// estimateTokens roughly counts message tokens (1 token ≈ 4 characters) for
+// caches whose provider does not report a count
+func estimateTokens(messages []providers.Message) int {
+	data, _ := json.Marshal(messages)
+	return len(data) / 4
+}
+
+// View is the client-facing representation of a cache
+type View struct {
+	ID          uuid.UUID `json:"id"`
+	Object      string    `json:"object"`
+	Model       string    `json:"model"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Mode        string    `json:"mode"` // "provider" or "gateway"
+	Provider    string    `json:"provider,omitempty"`
+	TokenCount  int       `json:"token_count"`
+	StorageCost float64   `json:"storage_cost"`
+	CreatedAt   int64     `json:"created_at"`
+	ExpiresAt   int64     `json:"expires_at"`
+}
+
+// NewView builds the client-facing representation of a cache
+func NewView(cache *models.ContextCache) *View {
+	view := &View{
+		ID:          cache.ID,
+		Object:      "cached_content",
+		Model:       cache.Model,
+		DisplayName: cache.DisplayName,
+		Mode:        "gateway",
+		TokenCount:  cache.TokenCount,
+		StorageCost: cache.StorageCost,
+		CreatedAt:   cache.CreatedAt.Unix(),
+		ExpiresAt:   cache.ExpiresAt.Unix(),
+	}
+	if cache.IsProviderManaged() {
+		view.Mode = "provider"
+		view.Provider = cache.ProviderType
+	}
+	return view
+}
@@ -0,0 +1,74 @@
+package contextcache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func TestApply(t *testing.T) {
+	instance := &llmModels.ModelInstance{Config: config.ModelInstance{ID: "gemini-primary"}}
+	other := &llmModels.ModelInstance{Config: config.ModelInstance{ID: "gemini-secondary"}}
+
+	cache := &models.ContextCache{
+		Model:             "gemini-1.5-pro",
+		InstanceID:        "gemini-primary",
+		ProviderCacheName: "projects/p/locations/l/cachedContents/1",
+		Messages:          datatypes.JSON(`[{"role":"system","content":"The manual"}]`),
+	}
+	newRequest := func() *providers.ChatRequest {
+		return &providers.ChatRequest{
+			Model:         "gemini-1.5-pro",
+			Messages:      []providers.Message{{Role: "user", Content: "Question"}},
+			CachedContent: cache.ID.String(),
+		}
+	}
+
+	t.Run("provider cache on the same instance", func(t *testing.T) {
+		request := newRequest()
+		require.NoError(t, Apply(request, cache, instance))
+
+		assert.Equal(t, cache.ProviderCacheName, request.CachedContent)
+		assert.Len(t, request.Messages, 1)
+	})
+
+	t.Run("failover to another instance prepends the messages", func(t *testing.T) {
+		request := newRequest()
+		require.NoError(t, Apply(request, cache, other))
+
+		assert.Empty(t, request.CachedContent)
+		assert.Equal(t, 1, request.CachedPrefix)
+		require.Len(t, request.Messages, 2)
+		assert.Equal(t, "The manual", request.Messages[0].Content)
+		assert.Equal(t, "Question", request.Messages[1].Content)
+	})
+
+	t.Run("gateway-managed cache", func(t *testing.T) {
+		gateway := *cache
+		gateway.InstanceID, gateway.ProviderCacheName = "", ""
+
+		request := newRequest()
+		require.NoError(t, Apply(request, &gateway, instance))
+		assert.Empty(t, request.CachedContent)
+		assert.Len(t, request.Messages, 2)
+	})
+}
+
+func TestParseTTL(t *testing.T) {
+	ttl, err := parseTTL(0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTTL, ttl)
+
+	_, err = parseTTL(1)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	_, err = parseTTL(int(MaxTTL.Seconds()) + 1)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
@@ -0,0 +1,426 @@
+// Package contextwindow keeps chat prompts within the target model's context
+// window. A prompt that would not fit is truncated by dropping its oldest
+// turns, has those turns summarized by a cheaper model, or is rejected, so
+// that it never reaches a provider only to fail there.
+package contextwindow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// ErrContextLengthExceeded is returned when a prompt does not fit the model's
+// context window and the strategy cannot make it fit
+var ErrContextLengthExceeded = errors.New("prompt exceeds the model's context window")
+
+const (
+	// messageOverheadTokens approximates the role and framing tokens of a message
+	messageOverheadTokens = 4
+	// imageTokens approximates a high-detail image tile
+	imageTokens = 765
+	// summaryPrefix introduces the summary that replaces dropped turns
+	summaryPrefix = "Summary of the earlier conversation:\n"
+)
+
+// Result describes what Fit did to a request
+type Result struct {
+	Strategy        string // Strategy applied; empty when the prompt already fit
+	Limit           int    // Prompt token limit of the model, 0 when unknown
+	PromptTokens    int    // Estimated prompt tokens before fitting
+	DroppedMessages int    // Messages removed (or summarized) from the prompt
+}
+
+// Modified reports whether the request was changed
+func (r *Result) Modified() bool {
+	return r.Strategy != ""
+}
+
+// Manager fits chat requests into the context window of their model
+type Manager struct {
+	cfg          config.ContextWindowConfig
+	modelManager *llmModels.ModelManager
+	pricing      *config.ModelPricingManager
+	logger       *zap.Logger
+}
+
+// NewManager creates a context window manager. Model windows come from the
+// instance model_info, completed by the pricing catalog.
+func NewManager(cfg config.ContextWindowConfig, modelManager *llmModels.ModelManager, pricing *config.ModelPricingManager, logger *zap.Logger) *Manager {
+	if cfg.Strategy == "" {
+		cfg.Strategy = config.ContextWindowTruncate
+	}
+	if cfg.SummaryMaxTokens <= 0 {
+		cfg.SummaryMaxTokens = 512
+	}
+	return &Manager{
+		cfg:          cfg,
+		modelManager: modelManager,
+		pricing:      pricing,
+		logger:       logger.Named("context_window"),
+	}
+}
+
+// Fit applies the configured strategy when the request's prompt exceeds the
+// model's context window, modifying request in place. Requests for models
+// whose window is unknown are left alone. ErrContextLengthExceeded is
+// returned when the prompt is rejected or cannot be made to fit; a failed
+// summary falls back to truncation rather than failing the request.
+func (m *Manager) Fit(ctx context.Context, request *providers.ChatRequest) (*Result, error) {
+	result := &Result{
+		Limit:        m.Limit(request),
+		PromptTokens: EstimateTokens(request),
+	}
+	if result.Limit == 0 || result.PromptTokens <= result.Limit {
+		return result, nil
+	}
+
+	exceeded := fmt.Errorf("%w: about %d prompt tokens, %q accepts %d",
+		ErrContextLengthExceeded, result.PromptTokens, request.Model, result.Limit)
+
+	switch m.cfg.Strategy {
+	case config.ContextWindowReject:
+		return result, exceeded
+
+	case config.ContextWindowSummarize:
+		if m.cfg.SummaryModel != "" {
+			dropped, err := m.summarize(ctx, request, result.Limit)
+			if err == nil {
+				result.Strategy = config.ContextWindowSummarize
+				result.DroppedMessages = dropped
+				return result, nil
+			}
+			if !errors.Is(err, ErrContextLengthExceeded) {
+				m.logger.Warn("Failed to summarize conversation, truncating instead",
+					zap.String("model", request.Model),
+					zap.String("summary_model", m.cfg.SummaryModel),
+					zap.Error(err))
+			}
+		}
+	}
+
+	head, turns := splitTurns(request.Messages)
+	keep := fittingTurns(request, head, turns, result.Limit, 0)
+	if keep == 0 {
+		return result, exceeded
+	}
+	result.Strategy = config.ContextWindowTruncate
+	result.DroppedMessages = countMessages(turns[:len(turns)-keep])
+	request.Messages = joinTurns(head, turns[len(turns)-keep:])
+	return result, nil
+}
+
+// Limit returns the number of prompt tokens the request's model accepts, or
+// 0 when unknown. Routes and tiers use the smallest window of their models,
+// since any of them may serve the request.
+func (m *Manager) Limit(request *providers.ChatRequest) int {
+	requestedOutput := 0
+	if request.MaxTokens != nil && *request.MaxTokens > 0 {
+		requestedOutput = *request.MaxTokens
+	}
+
+	limit := 0
+	for _, name := range m.candidateModels(request.Model) {
+		modelLimit := m.modelLimit(name, requestedOutput)
+		if modelLimit > 0 && (limit == 0 || modelLimit < limit) {
+			limit = modelLimit
+		}
+	}
+	return limit
+}
+
+func (m *Manager) candidateModels(model string) []string {
+	if m.modelManager == nil {
+		return []string{model}
+	}
+	if route, ok := m.modelManager.ResolveRoute(model); ok && route != nil {
+		names := make([]string, 0, len(route.Models))
+		for _, rm := range route.Models {
+			if rm.Enabled {
+				names = append(names, rm.ModelName)
+			}
+		}
+		return names
+	}
+	if tier, ok := m.modelManager.ResolveTier(model); ok && tier != nil {
+		return tier.Models
+	}
+	return []string{model}
+}
+
+// modelLimit is the prompt limit of one model: its input limit, further
+// bounded by its total window minus the completion tokens requested
+func (m *Manager) modelLimit(model string, requestedOutput int) int {
+	maxInput, maxTotal := 0, 0
+	lookups := []string{model}
+
+	if m.modelManager != nil {
+		if instances, ok := m.modelManager.GetRegistry().GetModelInstances(model); ok && len(instances) > 0 {
+			info := instances[0].Config.ModelInfo
+			maxInput, maxTotal = info.MaxInputTokens, info.MaxTokens
+			lookups = append(lookups, instances[0].Config.Provider.Model)
+		}
+	}
+	if m.pricing != nil {
+		for _, name := range lookups {
+			if maxInput > 0 && maxTotal > 0 {
+				break
+			}
+			if pricing := m.pricing.GetPricing(name); pricing != nil {
+				if maxInput == 0 {
+					maxInput = pricing.MaxInputTokens
+				}
+				if maxTotal == 0 {
+					maxTotal = pricing.MaxTokens
+				}
+			}
+		}
+	}
+
+	limit := maxInput
+	if maxTotal > 0 && requestedOutput > 0 {
+		if remaining := maxTotal - requestedOutput; limit == 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+	if limit < 0 {
+		// The requested completion alone does not fit; every prompt is too long
+		return 1
+	}
+	return limit
+}
+
+// summarize replaces the oldest turns that do not fit with a summary written
+// by the summary model, returning how many messages it replaced
+func (m *Manager) summarize(ctx context.Context, request *providers.ChatRequest, limit int) (int, error) {
+	head, turns := splitTurns(request.Messages)
+	reserve := m.cfg.SummaryMaxTokens + messageOverheadTokens + EstimateText(summaryPrefix)
+	keep := fittingTurns(request, head, turns, limit, reserve)
+	if keep == 0 {
+		// Without room for the summary, plain truncation may still fit
+		return 0, ErrContextLengthExceeded
+	}
+	dropped := turns[:len(turns)-keep]
+
+	summary, err := m.complete(ctx, dropped)
+	if err != nil {
+		return 0, err
+	}
+
+	messages := append([]providers.Message{}, head...)
+	messages = append(messages, providers.Message{Role: "system", Content: summaryPrefix + summary})
+	request.Messages = joinTurns(messages, turns[len(turns)-keep:])
+	return countMessages(dropped), nil
+}
+
+func (m *Manager) complete(ctx context.Context, turns [][]providers.Message) (string, error) {
+	if m.modelManager == nil {
+		return "", errors.New("no model manager")
+	}
+
+	prompt := []providers.Message{
+		{
+			Role: "system",
+			Content: "Summarize the following conversation so it can replace the original in a later request. " +
+				"Keep facts, decisions, names, numbers and open questions; omit pleasantries. Reply with the summary only.",
+		},
+		{Role: "user", Content: transcript(turns)},
+	}
+	maxTokens := m.cfg.SummaryMaxTokens
+
+	result, err := m.modelManager.ExecuteWithFailover(ctx, &llmModels.FailoverRequest{
+		ModelName: m.cfg.SummaryModel,
+		ExecuteFunc: func(ctx context.Context, instance *llmModels.ModelInstance) (interface{}, error) {
+			response, err := instance.Provider.ChatCompletion(ctx, &providers.ChatRequest{
+				Model:     instance.Config.Provider.Model,
+				Messages:  prompt,
+				MaxTokens: &maxTokens,
+			})
+			if err != nil {
+				instance.RecordError(err)
+				return nil, err
+			}
+			return response, nil
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	response, ok := result.Response.(*providers.ChatResponse)
+	if !ok || len(response.Choices) == 0 {
+		return "", errors.New("summary model returned no choices")
+	}
+	summary := strings.TrimSpace(contentText(response.Choices[0].Message.Content))
+	if summary == "" {
+		return "", errors.New("summary model returned an empty summary")
+	}
+	return summary, nil
+}
+
+// splitTurns separates the leading system and developer messages, which are
+// always kept, from the conversation, grouped into turns that each start
+// with a user message. Turns are dropped whole so assistant tool calls never
+// lose their results.
+func splitTurns(messages []providers.Message) ([]providers.Message, [][]providers.Message) {
+	i := 0
+	for i < len(messages) && isInstruction(messages[i]) {
+		i++
+	}
+	head := messages[:i]
+
+	var turns [][]providers.Message
+	for _, msg := range messages[i:] {
+		if msg.Role == "user" || len(turns) == 0 {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], msg)
+	}
+	return head, turns
+}
+
+// fittingTurns returns how many of the most recent turns fit within limit
+// alongside head, the rest of the request and reserve tokens. The last turn
+// is the one being answered, so 0 means the prompt cannot be made to fit.
+func fittingTurns(request *providers.ChatRequest, head []providers.Message, turns [][]providers.Message, limit, reserve int) int {
+	used := reserve + estimateTools(request.Tools)
+	for _, msg := range head {
+		used += estimateMessage(msg)
+	}
+
+	keep := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		tokens := 0
+		for _, msg := range turns[i] {
+			tokens += estimateMessage(msg)
+		}
+		if used+tokens > limit {
+			break
+		}
+		used += tokens
+		keep++
+	}
+	return keep
+}
+
+func joinTurns(head []providers.Message, turns [][]providers.Message) []providers.Message {
+	messages := append([]providers.Message{}, head...)
+	for _, turn := range turns {
+		messages = append(messages, turn...)
+	}
+	return messages
+}
+
+func countMessages(turns [][]providers.Message) int {
+	n := 0
+	for _, turn := range turns {
+		n += len(turn)
+	}
+	return n
+}
+
+func isInstruction(msg providers.Message) bool {
+	return msg.Role == "system" || msg.Role == "developer"
+}
+
+// transcript renders turns as plain text for the summary model
+func transcript(turns [][]providers.Message) string {
+	var b strings.Builder
+	for _, turn := range turns {
+		for _, msg := range turn {
+			if text := contentText(msg.Content); text != "" {
+				fmt.Fprintf(&b, "%s: %s\n", msg.Role, text)
+			}
+			for _, call := range msg.ToolCalls {
+				fmt.Fprintf(&b, "%s called %s(%s)\n", msg.Role, call.Function.Name, call.Function.Arguments)
+			}
+		}
+	}
+	return b.String()
+}
+
+// EstimateTokens roughly counts the prompt tokens of a chat request
+// (1 token ≈ 4 characters), including message framing and tool definitions
+func EstimateTokens(request *providers.ChatRequest) int {
+	tokens := estimateTools(request.Tools)
+	for _, msg := range request.Messages {
+		tokens += estimateMessage(msg)
+	}
+	return tokens
+}
+
+// EstimateText roughly counts the tokens of a text (1 token ≈ 4 characters)
+func EstimateText(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func estimateMessage(msg providers.Message) int {
+	tokens := messageOverheadTokens + EstimateText(contentText(msg.Content)) + imageTokens*countImages(msg.Content)
+	for _, call := range msg.ToolCalls {
+		tokens += EstimateText(call.Function.Name) + EstimateText(call.Function.Arguments)
+	}
+	return tokens
+}
+
+func estimateTools(tools []providers.Tool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	data, _ := json.Marshal(tools)
+	return EstimateText(string(data))
+}
+
+// contentText returns the text of a message content, which is a string or a
+// list of content parts
+func contentText(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []providers.MessageContent:
+		parts := make([]string, 0, len(c))
+		for _, part := range c {
+			if part.Type == "text" {
+				parts = append(parts, part.Text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	case []interface{}:
+		parts := make([]string, 0, len(c))
+		for _, part := range c {
+			if p, ok := part.(map[string]interface{}); ok && p["type"] == "text" {
+				if text, ok := p["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+func countImages(content interface{}) int {
+	images := 0
+	switch c := content.(type) {
+	case []providers.MessageContent:
+		for _, part := range c {
+			if part.Type == "image_url" {
+				images++
+			}
+		}
+	case []interface{}:
+		for _, part := range c {
+			if p, ok := part.(map[string]interface{}); ok && p["type"] == "image_url" {
+				images++
+			}
+		}
+	}
+	return images
+}
@@ -0,0 +1,185 @@
+package contextwindow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// newTestModelManager registers "small" with a 200 token prompt limit and
+// "cheap", a summary model served by summaries
+func newTestModelManager(t *testing.T, summaries http.HandlerFunc) *llmModels.ModelManager {
+	t.Helper()
+	server := httptest.NewServer(summaries)
+	t.Cleanup(server.Close)
+
+	manager := llmModels.NewModelManager(zap.NewNop(), config.RouterSettings{RoutingStrategy: "priority"}, nil)
+	for _, instance := range []config.ModelInstance{
+		{
+			ID: "small-instance", ModelName: "small", Enabled: true,
+			Provider:  config.ProviderParams{Type: "openai", Model: "small-model", APIKey: "test-key", BaseURL: server.URL},
+			ModelInfo: config.ModelInfo{MaxTokens: 300, MaxInputTokens: 200},
+		},
+		{
+			ID: "cheap-instance", ModelName: "cheap", Enabled: true,
+			Provider: config.ProviderParams{Type: "openai", Model: "cheap-model", APIKey: "test-key", BaseURL: server.URL},
+		},
+	} {
+		require.NoError(t, manager.AddInstance(instance))
+	}
+	return manager
+}
+
+func summaryServer(t *testing.T, summary string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request providers.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "cheap-model", request.Model)
+		_ = json.NewEncoder(w).Encode(providers.ChatResponse{
+			ID:      "chatcmpl-summary",
+			Object:  "chat.completion",
+			Model:   request.Model,
+			Choices: []providers.Choice{{Message: providers.Message{Role: "assistant", Content: summary}, FinishReason: "stop"}},
+		})
+	}
+}
+
+// conversation builds a system prompt followed by turns user/assistant turns
+// of about 40 tokens each
+func conversation(turns int) []providers.Message {
+	messages := []providers.Message{{Role: "system", Content: "You are helpful."}}
+	for i := 0; i < turns; i++ {
+		messages = append(messages,
+			providers.Message{Role: "user", Content: strings.Repeat("q", 64)},
+			providers.Message{Role: "assistant", Content: strings.Repeat("a", 64)},
+		)
+	}
+	return append(messages, providers.Message{Role: "user", Content: "And now?"})
+}
+
+func TestManager_Limit(t *testing.T) {
+	m := NewManager(config.ContextWindowConfig{}, newTestModelManager(t, summaryServer(t, "")), nil, zap.NewNop())
+
+	assert.Equal(t, 200, m.Limit(&providers.ChatRequest{Model: "small"}))
+
+	// The requested completion must fit the total window as well
+	maxTokens := 150
+	assert.Equal(t, 150, m.Limit(&providers.ChatRequest{Model: "small", MaxTokens: &maxTokens}))
+
+	assert.Equal(t, 0, m.Limit(&providers.ChatRequest{Model: "cheap"}), "unknown window")
+	assert.Equal(t, 0, m.Limit(&providers.ChatRequest{Model: "missing"}))
+}
+
+func TestManager_FitsWithinWindow(t *testing.T) {
+	m := NewManager(config.ContextWindowConfig{Strategy: config.ContextWindowReject},
+		newTestModelManager(t, summaryServer(t, "")), nil, zap.NewNop())
+
+	request := &providers.ChatRequest{Model: "small", Messages: conversation(1)}
+	result, err := m.Fit(context.Background(), request)
+	require.NoError(t, err)
+	assert.False(t, result.Modified())
+	assert.Len(t, request.Messages, 4)
+}
+
+func TestManager_Reject(t *testing.T) {
+	m := NewManager(config.ContextWindowConfig{Strategy: config.ContextWindowReject},
+		newTestModelManager(t, summaryServer(t, "")), nil, zap.NewNop())
+
+	request := &providers.ChatRequest{Model: "small", Messages: conversation(10)}
+	_, err := m.Fit(context.Background(), request)
+	assert.ErrorIs(t, err, ErrContextLengthExceeded)
+	assert.Len(t, request.Messages, 22)
+}
+
+func TestManager_Truncate(t *testing.T) {
+	m := NewManager(config.ContextWindowConfig{}, newTestModelManager(t, summaryServer(t, "")), nil, zap.NewNop())
+
+	request := &providers.ChatRequest{Model: "small", Messages: []providers.Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: strings.Repeat("q", 400)},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "call_1", Type: "function", Function: providers.FunctionCall{Name: "lookup", Arguments: "{}"}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: strings.Repeat("r", 200)},
+		{Role: "assistant", Content: "Found it."},
+		{Role: "user", Content: strings.Repeat("q", 64)},
+		{Role: "assistant", Content: strings.Repeat("a", 64)},
+		{Role: "user", Content: "And now?"},
+	}}
+	result, err := m.Fit(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, config.ContextWindowTruncate, result.Strategy)
+	assert.Equal(t, 4, result.DroppedMessages, "the first turn is dropped whole, tool call included")
+
+	require.Len(t, request.Messages, 4)
+	assert.Equal(t, "system", request.Messages[0].Role)
+	assert.Equal(t, "user", request.Messages[1].Role)
+	assert.Equal(t, "And now?", request.Messages[3].Content)
+	assert.LessOrEqual(t, EstimateTokens(request), result.Limit)
+}
+
+func TestManager_TruncateCannotFit(t *testing.T) {
+	m := NewManager(config.ContextWindowConfig{}, newTestModelManager(t, summaryServer(t, "")), nil, zap.NewNop())
+
+	request := &providers.ChatRequest{Model: "small", Messages: []providers.Message{
+		{Role: "user", Content: strings.Repeat("q", 1000)},
+	}}
+	_, err := m.Fit(context.Background(), request)
+	assert.ErrorIs(t, err, ErrContextLengthExceeded)
+}
+
+func TestManager_Summarize(t *testing.T) {
+	m := NewManager(config.ContextWindowConfig{
+		Strategy:         config.ContextWindowSummarize,
+		SummaryModel:     "cheap",
+		SummaryMaxTokens: 40,
+	}, newTestModelManager(t, summaryServer(t, "The user asked many questions.")), nil, zap.NewNop())
+
+	request := &providers.ChatRequest{Model: "small", Messages: conversation(10)}
+	result, err := m.Fit(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, config.ContextWindowSummarize, result.Strategy)
+	assert.Positive(t, result.DroppedMessages)
+
+	assert.Equal(t, "You are helpful.", request.Messages[0].Content)
+	assert.Equal(t, "system", request.Messages[1].Role)
+	assert.Equal(t, summaryPrefix+"The user asked many questions.", request.Messages[1].Content)
+	assert.Equal(t, "And now?", request.Messages[len(request.Messages)-1].Content)
+	assert.Len(t, request.Messages, 22-result.DroppedMessages+1)
+	assert.LessOrEqual(t, EstimateTokens(request), result.Limit)
+}
+
+func TestManager_SummarizeFallsBackToTruncate(t *testing.T) {
+	m := NewManager(config.ContextWindowConfig{
+		Strategy:     config.ContextWindowSummarize,
+		SummaryModel: "cheap",
+	}, newTestModelManager(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"unavailable"}}`, http.StatusServiceUnavailable)
+	}), nil, zap.NewNop())
+
+	request := &providers.ChatRequest{Model: "small", Messages: conversation(10)}
+	result, err := m.Fit(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, config.ContextWindowTruncate, result.Strategy)
+	assert.LessOrEqual(t, EstimateTokens(request), result.Limit)
+}
+
+func TestEstimateTokens(t *testing.T) {
+	request := &providers.ChatRequest{Messages: []providers.Message{
+		{Role: "user", Content: "12345678"},
+		{Role: "user", Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "1234"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/png;base64,AAAA"}},
+		}},
+	}}
+	assert.Equal(t, (4+2)+(4+1+imageTokens), EstimateTokens(request))
+}
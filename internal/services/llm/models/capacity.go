@@ -0,0 +1,281 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// defaultOverflowThreshold is the utilization of reserved capacity above
+// which requests overflow to pay-as-you-go instances
+const defaultOverflowThreshold = 0.9
+
+// Reserved capacity routing outcomes
+const (
+	CapacityReserved  = "reserved"  // Sent to reserved capacity with headroom
+	CapacityOverflow  = "overflow"  // Reserved capacity was saturated, sent to pay-as-you-go
+	CapacitySaturated = "saturated" // Reserved capacity was saturated and no pay-as-you-go instance was left
+)
+
+// ReservedInstance is the utilization of a reserved capacity instance, as
+// this replica served it. Averages cover the period since the replica first
+// routed to or reported on the instance.
+type ReservedInstance struct {
+	InstanceID        string  `json:"instance_id"`
+	Provider          string  `json:"provider"`
+	CapacityUnits     int     `json:"capacity_units"`
+	CapacityTPM       int64   `json:"capacity_tpm"`
+	OverflowThreshold float64 `json:"overflow_threshold"`
+	TokensPerMinute   float64 `json:"tokens_per_minute"` // Over the last minute
+	Utilization       float64 `json:"utilization"`       // TokensPerMinute over CapacityTPM
+	AvgUtilization    float64 `json:"avg_utilization"`
+	PeakUtilization   float64 `json:"peak_utilization"`
+	TokensServed      int64   `json:"tokens_served"`
+	TrackedSeconds    float64 `json:"tracked_seconds"`
+
+	// What the reservation cost over the tracked period and per million
+	// tokens served, to compare with pay-as-you-go prices; 0 without
+	// hourly_cost_per_unit
+	ReservedCost         float64 `json:"reserved_cost"`
+	CostPerMillionTokens float64 `json:"cost_per_million_tokens"`
+}
+
+// ModelCapacity is a model's reserved capacity and how routing used it
+type ModelCapacity struct {
+	Model               string             `json:"model"`
+	Reserved            []ReservedInstance `json:"reserved"`
+	PayAsYouGoInstances int                `json:"pay_as_you_go_instances"`
+	CapacityTPM         int64              `json:"capacity_tpm"`
+	Utilization         float64            `json:"utilization"`
+
+	// Routing decisions per outcome, failover retries included, and the
+	// share of them that did not fit in reserved capacity
+	Decisions    map[string]int64 `json:"decisions"`
+	OverflowRate float64          `json:"overflow_rate"`
+}
+
+// capacityTracker keeps the reserved capacity routing decisions of each
+// model and the utilization history of reserved instances
+type capacityTracker struct {
+	mu        sync.Mutex
+	decisions map[string]map[string]int64  // model -> outcome -> count
+	usage     map[string]*reservationUsage // key: instance ID
+	now       func() time.Time
+}
+
+type reservationUsage struct {
+	since       time.Time
+	startTokens int64
+	peak        float64
+}
+
+func newCapacityTracker() *capacityTracker {
+	return &capacityTracker{
+		decisions: make(map[string]map[string]int64),
+		usage:     make(map[string]*reservationUsage),
+		now:       time.Now,
+	}
+}
+
+func (t *capacityTracker) record(model, outcome string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts, ok := t.decisions[model]
+	if !ok {
+		counts = make(map[string]int64)
+		t.decisions[model] = counts
+	}
+	counts[outcome]++
+}
+
+// observe records an instance's utilization and returns its tracked usage
+func (t *capacityTracker) observe(instance *ModelInstance, utilization float64) reservationUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage, ok := t.usage[instance.Config.ID]
+	if !ok || instance.TotalTokens.Load() < usage.startTokens {
+		usage = &reservationUsage{since: t.now(), startTokens: instance.TotalTokens.Load()}
+		t.usage[instance.Config.ID] = usage
+	}
+	if utilization > usage.peak {
+		usage.peak = utilization
+	}
+	return *usage
+}
+
+// overflowThreshold returns the utilization above which the instance
+// overflows
+func overflowThreshold(p config.ProvisionedConfig) float64 {
+	if p.OverflowThreshold <= 0 || p.OverflowThreshold > 1 {
+		return defaultOverflowThreshold
+	}
+	return p.OverflowThreshold
+}
+
+// reservedUtilization returns the tokens per minute a reserved instance
+// served over the last minute and their share of its capacity
+func (m *ModelManager) reservedUtilization(instance *ModelInstance) (float64, float64) {
+	m.scaling.mu.Lock()
+	rate := m.scaling.tokenRate(instance, m.scaling.now())
+	m.scaling.mu.Unlock()
+
+	return rate, rate / float64(instance.Config.Provisioned.CapacityTPM())
+}
+
+// preferReserved fills reserved capacity first. Of the model's instances it
+// keeps the reserved ones below their overflow threshold, otherwise the
+// pay-as-you-go ones, otherwise the saturated reserved ones. Tokens count
+// toward utilization when requests finish, so a burst can reach a full
+// reservation before it shows; the provider's 429s then fail over to the
+// remaining instances, which are pay-as-you-go.
+func (m *ModelManager) preferReserved(modelName string, instances []*ModelInstance) []*ModelInstance {
+	var reserved, headroom, payAsYouGo []*ModelInstance
+	for _, instance := range instances {
+		if instance.Config.Provisioned.CapacityTPM() <= 0 {
+			payAsYouGo = append(payAsYouGo, instance)
+			continue
+		}
+		reserved = append(reserved, instance)
+		_, utilization := m.reservedUtilization(instance)
+		m.capacity.observe(instance, utilization)
+		if utilization < overflowThreshold(instance.Config.Provisioned) {
+			headroom = append(headroom, instance)
+		}
+	}
+
+	switch {
+	case len(reserved) == 0:
+		return instances
+	case len(headroom) > 0:
+		m.capacity.record(modelName, CapacityReserved)
+		return headroom
+	case len(payAsYouGo) > 0:
+		m.capacity.record(modelName, CapacityOverflow)
+		return payAsYouGo
+	default:
+		m.capacity.record(modelName, CapacitySaturated)
+		return reserved
+	}
+}
+
+// ReservedCapacity reports the utilization of the reserved capacity of each
+// model that has some, sorted by model name
+func (m *ModelManager) ReservedCapacity() []ModelCapacity {
+	now := m.capacity.now()
+	seen := make(map[string]bool)
+
+	var result []ModelCapacity
+	for _, model := range m.registry.GetAvailableModels() {
+		instances, _ := m.registry.GetModelInstances(model)
+		mc := ModelCapacity{Model: model, Decisions: make(map[string]int64)}
+		var tokensPerMinute float64
+		for _, instance := range instances {
+			prov := instance.Config.Provisioned
+			capacity := prov.CapacityTPM()
+			if capacity <= 0 {
+				mc.PayAsYouGoInstances++
+				continue
+			}
+			seen[instance.Config.ID] = true
+
+			rate, utilization := m.reservedUtilization(instance)
+			usage := m.capacity.observe(instance, utilization)
+			tracked := now.Sub(usage.since)
+			reservation := ReservedInstance{
+				InstanceID:        instance.Config.ID,
+				Provider:          instance.Config.Provider.Type,
+				CapacityUnits:     prov.CapacityUnits,
+				CapacityTPM:       capacity,
+				OverflowThreshold: overflowThreshold(prov),
+				TokensPerMinute:   rate,
+				Utilization:       utilization,
+				PeakUtilization:   usage.peak,
+				TokensServed:      instance.TotalTokens.Load() - usage.startTokens,
+				TrackedSeconds:    tracked.Seconds(),
+				ReservedCost:      float64(prov.CapacityUnits) * prov.HourlyCostPerUnit * tracked.Hours(),
+			}
+			if tracked >= time.Minute {
+				reservation.AvgUtilization = float64(reservation.TokensServed) / (float64(capacity) * tracked.Minutes())
+			}
+			if reservation.TokensServed > 0 {
+				reservation.CostPerMillionTokens = reservation.ReservedCost / float64(reservation.TokensServed) * 1e6
+			}
+
+			mc.Reserved = append(mc.Reserved, reservation)
+			mc.CapacityTPM += capacity
+			tokensPerMinute += rate
+		}
+		if len(mc.Reserved) == 0 {
+			continue
+		}
+		mc.Utilization = tokensPerMinute / float64(mc.CapacityTPM)
+		result = append(result, mc)
+	}
+
+	m.capacity.mu.Lock()
+	for id := range m.capacity.usage {
+		if !seen[id] {
+			delete(m.capacity.usage, id)
+		}
+	}
+	for i := range result {
+		var total, overflowed int64
+		for outcome, count := range m.capacity.decisions[result[i].Model] {
+			result[i].Decisions[outcome] = count
+			total += count
+			if outcome != CapacityReserved {
+				overflowed += count
+			}
+		}
+		if total > 0 {
+			result[i].OverflowRate = float64(overflowed) / float64(total)
+		}
+	}
+	m.capacity.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Model < result[j].Model })
+	return result
+}
+
+var (
+	capacityTPMDesc = prometheus.NewDesc("pllm_reserved_capacity_tpm",
+		"Tokens per minute the reserved capacity instance serves", []string{"model", "instance"}, nil)
+	capacityUtilizationDesc = prometheus.NewDesc("pllm_reserved_capacity_utilization",
+		"Tokens served over the last minute over the reserved capacity of the instance", []string{"model", "instance"}, nil)
+	capacityDecisionsDesc = prometheus.NewDesc("pllm_reserved_capacity_decisions_total",
+		"Routing decisions for models with reserved capacity by outcome (reserved, overflow, saturated)",
+		[]string{"model", "outcome"}, nil)
+)
+
+// capacityCollector exports the reserved capacity utilization, computed
+// when scraped
+type capacityCollector struct {
+	manager *ModelManager
+}
+
+// CapacityCollector returns a Prometheus collector for reserved capacity
+func (m *ModelManager) CapacityCollector() prometheus.Collector {
+	return &capacityCollector{manager: m}
+}
+
+func (c *capacityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- capacityTPMDesc
+	ch <- capacityUtilizationDesc
+	ch <- capacityDecisionsDesc
+}
+
+func (c *capacityCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, mc := range c.manager.ReservedCapacity() {
+		for _, r := range mc.Reserved {
+			ch <- prometheus.MustNewConstMetric(capacityTPMDesc, prometheus.GaugeValue, float64(r.CapacityTPM), mc.Model, r.InstanceID)
+			ch <- prometheus.MustNewConstMetric(capacityUtilizationDesc, prometheus.GaugeValue, r.Utilization, mc.Model, r.InstanceID)
+		}
+		for outcome, count := range mc.Decisions {
+			ch <- prometheus.MustNewConstMetric(capacityDecisionsDesc, prometheus.CounterValue, float64(count), mc.Model, outcome)
+		}
+	}
+}
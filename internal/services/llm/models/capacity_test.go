@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func TestModelManager_PreferReserved(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	manager.scaling.now = func() time.Time { return now }
+	manager.capacity.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	azure := manager.registry.instances["gpt-4-azure"]
+	azure.Config.Provisioned = config.ProvisionedConfig{CapacityUnits: 2, TPMPerUnit: 500, HourlyCostPerUnit: 10}
+	openai := manager.registry.instances["gpt-4-openai"]
+
+	instance, err := manager.GetBestInstance(ctx, "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4-azure", instance.Config.ID, "reserved capacity is filled first")
+
+	// 480 tokens in 30 seconds is 96% of the 1000 TPM reserved
+	now = now.Add(30 * time.Second)
+	azure.TotalTokens.Add(480)
+	instance, err = manager.GetBestInstance(ctx, "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4-openai", instance.Config.ID, "saturated reservations overflow to pay-as-you-go")
+
+	openai.Healthy.Store(false)
+	instance, err = manager.GetBestInstance(ctx, "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4-azure", instance.Config.ID, "without pay-as-you-go the reservation serves anyway")
+
+	capacity := manager.ReservedCapacity()
+	require.Len(t, capacity, 1)
+	mc := capacity[0]
+	assert.Equal(t, "gpt-4", mc.Model)
+	assert.Equal(t, 1, mc.PayAsYouGoInstances)
+	assert.Equal(t, int64(1000), mc.CapacityTPM)
+	assert.InDelta(t, 0.96, mc.Utilization, 1e-9)
+	assert.Equal(t, map[string]int64{CapacityReserved: 1, CapacityOverflow: 1, CapacitySaturated: 1}, mc.Decisions)
+	assert.InDelta(t, 2/3.0, mc.OverflowRate, 1e-9)
+
+	require.Len(t, mc.Reserved, 1)
+	reservation := mc.Reserved[0]
+	assert.Equal(t, defaultOverflowThreshold, reservation.OverflowThreshold)
+	assert.InDelta(t, 0.96, reservation.PeakUtilization, 1e-9)
+	assert.Equal(t, int64(480), reservation.TokensServed)
+	assert.Zero(t, reservation.AvgUtilization, "averages need a minute of history")
+	assert.InDelta(t, 20*30/3600.0, reservation.ReservedCost, 1e-9)
+	assert.InDelta(t, reservation.ReservedCost/480*1e6, reservation.CostPerMillionTokens, 1e-9)
+
+	// Idle reservations average out over the tracked period
+	now = now.Add(90 * time.Second)
+	reservation = manager.ReservedCapacity()[0].Reserved[0]
+	assert.Zero(t, reservation.Utilization)
+	assert.InDelta(t, 480/(1000*2.0), reservation.AvgUtilization, 1e-9)
+}
+
+func TestModelManager_CapacityCollector(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	manager.registry.instances["gpt-4-azure"].Config.Provisioned = config.ProvisionedConfig{CapacityUnits: 1, TPMPerUnit: 1000}
+	_, err := manager.GetBestInstance(context.Background(), "gpt-4")
+	require.NoError(t, err)
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(manager.CapacityCollector()))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 3)
+	for _, family := range families {
+		require.Len(t, family.GetMetric(), 1, family.GetName())
+		if family.GetName() == "pllm_reserved_capacity_tpm" {
+			assert.Equal(t, float64(1000), family.GetMetric()[0].GetGauge().GetValue())
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// discoveryTimeout bounds listing the models of a provider's server when
+// instances are loaded
+const discoveryTimeout = 10 * time.Second
+
+// expandDiscovered replaces the instances whose provider model is "*" with
+// one instance per model their provider's server lists. A "*" in the model
+// name is replaced by the discovered model ("local/*" serves llama3.2 as
+// "local/llama3.2"); other names are taken as they are listed. Expanded
+// instances are identified as the configured ID followed by "/" and the
+// model. Instances whose server cannot be listed are dropped with an error
+// logged, like instances whose provider fails to create.
+func (r *ModelRegistry) expandDiscovered(instances []config.ModelInstance) []config.ModelInstance {
+	expanded := make([]config.ModelInstance, 0, len(instances))
+	for _, cfg := range instances {
+		if !cfg.Enabled || !cfg.DiscoversModels() {
+			expanded = append(expanded, cfg)
+			continue
+		}
+
+		discovered, err := r.discoverModels(cfg.Provider)
+		if err != nil {
+			r.logger.Error("Failed to discover models for instance",
+				zap.String("instance", cfg.ID),
+				zap.Error(err))
+			continue
+		}
+
+		for _, model := range discovered {
+			instance := cfg
+			instance.ID = cfg.ID + "/" + model
+			instance.Provider.Model = model
+			instance.ModelName = model
+			if strings.Contains(cfg.ModelName, config.DiscoverAllModels) {
+				instance.ModelName = strings.ReplaceAll(cfg.ModelName, config.DiscoverAllModels, model)
+			}
+			expanded = append(expanded, instance)
+		}
+		r.logger.Info("Discovered models for instance",
+			zap.String("instance", cfg.ID),
+			zap.Strings("models", discovered))
+	}
+	return expanded
+}
+
+// discoverModels lists the models of a provider's server
+func (r *ModelRegistry) discoverModels(params config.ProviderParams) ([]string, error) {
+	provider, err := r.CreateProvider(params)
+	if err != nil {
+		return nil, err
+	}
+	discoverer, ok := provider.(providers.ModelDiscoverer)
+	if !ok {
+		return nil, fmt.Errorf("%s provider cannot list its models", params.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	return discoverer.FetchAvailableModels(ctx)
+}
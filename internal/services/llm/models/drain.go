@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DrainStatus reports the progress of draining an instance
+type DrainStatus struct {
+	ID        string     `json:"id"`
+	Draining  bool       `json:"draining"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	InFlight  int64      `json:"in_flight"`
+	Drained   bool       `json:"drained"`              // Draining and no request is left
+	DrainedAt *time.Time `json:"drained_at,omitempty"` // When the last request finished
+}
+
+// Begin counts a request or stream the instance starts serving; the
+// returned func ends it and may be called more than once
+func (m *ModelInstance) Begin() func() {
+	if m.InFlight.Add(1) == 1 && m.IsDraining() {
+		m.DrainedAt.Store(time.Time{})
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if m.InFlight.Add(-1) == 0 && m.IsDraining() {
+				m.DrainedAt.Store(time.Now())
+			}
+		})
+	}
+}
+
+// IsDraining reports whether the instance is being drained
+func (m *ModelInstance) IsDraining() bool {
+	started, ok := m.DrainStarted.Load().(time.Time)
+	return ok && !started.IsZero()
+}
+
+// DrainStatus returns the draining state of the instance
+func (m *ModelInstance) DrainStatus() DrainStatus {
+	status := DrainStatus{
+		ID:       m.Config.ID,
+		Draining: m.IsDraining(),
+		InFlight: m.InFlight.Load(),
+	}
+	if !status.Draining {
+		return status
+	}
+	if started, ok := m.DrainStarted.Load().(time.Time); ok {
+		status.StartedAt = &started
+	}
+	if drained, ok := m.DrainedAt.Load().(time.Time); ok && !drained.IsZero() && status.InFlight == 0 {
+		status.Drained = true
+		status.DrainedAt = &drained
+	}
+	return status
+}
+
+// startDrain marks the instance draining; an idle instance is drained at
+// once
+func (m *ModelInstance) startDrain() {
+	if m.IsDraining() {
+		return
+	}
+	now := time.Now()
+	m.DrainedAt.Store(time.Time{})
+	m.DrainStarted.Store(now)
+	if m.InFlight.Load() == 0 {
+		m.DrainedAt.Store(now)
+	}
+}
+
+// stopDrain returns the instance to rotation
+func (m *ModelInstance) stopDrain() {
+	m.DrainStarted.Store(time.Time{})
+	m.DrainedAt.Store(time.Time{})
+}
+
+// lookup returns a registered instance, disabled ones included. The caller
+// must hold the read lock.
+func (r *ModelRegistry) lookup(instanceID string) (*ModelInstance, bool) {
+	if instance, exists := r.instances[instanceID]; exists {
+		return instance, true
+	}
+	instance, disabled := r.disabled[instanceID]
+	return instance, disabled
+}
+
+// DrainInstance starts draining an instance: requests it is serving and
+// open streams finish, and new requests avoid it unless no other instance
+// of its model can serve them. Thread-safe.
+func (r *ModelRegistry) DrainInstance(instanceID string) (DrainStatus, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	instance, exists := r.lookup(instanceID)
+	if !exists {
+		return DrainStatus{}, fmt.Errorf("instance %s not found", instanceID)
+	}
+	if !instance.IsDraining() {
+		instance.startDrain()
+		r.logger.Info("Draining instance",
+			zap.String("id", instanceID),
+			zap.String("model", instance.Config.ModelName),
+			zap.Int64("in_flight", instance.InFlight.Load()))
+	}
+	return instance.DrainStatus(), nil
+}
+
+// ResumeInstance stops draining an instance and returns it to rotation.
+// Thread-safe.
+func (r *ModelRegistry) ResumeInstance(instanceID string) (DrainStatus, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	instance, exists := r.lookup(instanceID)
+	if !exists {
+		return DrainStatus{}, fmt.Errorf("instance %s not found", instanceID)
+	}
+	if instance.IsDraining() {
+		instance.stopDrain()
+		r.logger.Info("Resumed drained instance",
+			zap.String("id", instanceID),
+			zap.String("model", instance.Config.ModelName))
+	}
+	return instance.DrainStatus(), nil
+}
+
+// InstanceDrainStatus returns the draining state of an instance
+func (r *ModelRegistry) InstanceDrainStatus(instanceID string) (DrainStatus, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	instance, exists := r.lookup(instanceID)
+	if !exists {
+		return DrainStatus{}, false
+	}
+	return instance.DrainStatus(), true
+}
+
+// DrainInstance starts draining an instance; see ModelRegistry.DrainInstance
+func (m *ModelManager) DrainInstance(instanceID string) (DrainStatus, error) {
+	return m.registry.DrainInstance(instanceID)
+}
+
+// ResumeInstance stops draining an instance
+func (m *ModelManager) ResumeInstance(instanceID string) (DrainStatus, error) {
+	return m.registry.ResumeInstance(instanceID)
+}
+
+// InstanceDrainStatus returns the draining state of an instance
+func (m *ModelManager) InstanceDrainStatus(instanceID string) (DrainStatus, bool) {
+	return m.registry.InstanceDrainStatus(instanceID)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func TestModelManager_DrainInstance(t *testing.T) {
+	manager := NewModelManager(zap.NewNop(), config.RouterSettings{RoutingStrategy: "priority"}, nil)
+	add := func(id string, priority int) *ModelInstance {
+		instance := &ModelInstance{Config: config.ModelInstance{
+			ID: id, ModelName: "gpt-4o", Enabled: true, Priority: priority, Timeout: 5 * time.Second,
+		}, Provider: &MockFailingProvider{}}
+		instance.Healthy.Store(true)
+		manager.registry.mu.Lock()
+		manager.registry.attach(instance)
+		manager.registry.mu.Unlock()
+		return instance
+	}
+	primary := add("primary", 100)
+	backup := add("backup", 50)
+	ctx := context.Background()
+
+	// A stream is open on the primary when the drain starts
+	meter := manager.StartStreamMeter(primary)
+	status, err := manager.DrainInstance("primary")
+	require.NoError(t, err)
+	assert.True(t, status.Draining)
+	assert.EqualValues(t, 1, status.InFlight)
+	assert.False(t, status.Drained, "the open stream keeps the drain going")
+
+	selected, err := manager.GetBestInstance(ctx, "gpt-4o")
+	require.NoError(t, err)
+	assert.Equal(t, "backup", selected.Config.ID, "new requests avoid the draining instance")
+
+	meter.Finish(0)
+	status, found := manager.InstanceDrainStatus("primary")
+	require.True(t, found)
+	assert.True(t, status.Drained)
+	require.NotNil(t, status.DrainedAt)
+	assert.Zero(t, status.InFlight)
+
+	// With no alternative left, the draining instance still serves
+	backup.Healthy.Store(false)
+	selected, err = manager.GetBestInstance(ctx, "gpt-4o")
+	require.NoError(t, err)
+	assert.Equal(t, "primary", selected.Config.ID)
+	end := selected.Begin()
+	status, _ = manager.InstanceDrainStatus("primary")
+	assert.False(t, status.Drained)
+	end()
+	end()
+	assert.Zero(t, primary.InFlight.Load(), "ending a request twice counts it once")
+
+	status, err = manager.ResumeInstance("primary")
+	require.NoError(t, err)
+	assert.False(t, status.Draining)
+	backup.Healthy.Store(true)
+	selected, err = manager.GetBestInstance(ctx, "gpt-4o")
+	require.NoError(t, err)
+	assert.Equal(t, "primary", selected.Config.ID)
+
+	_, err = manager.DrainInstance("missing")
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/amerfu/pllm/internal/core/config"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -373,3 +374,142 @@ func TestTransparentFailover(t *testing.T) {
 
 	assert.Greater(t, result.AttemptCount, 1, "Should have retried")
 }
+
+func TestHealthTracker_NotifiesWhenCircuitOpens(t *testing.T) {
+	hub := notifications.NewHub(nil, zap.NewNop())
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	tracker := NewHealthTracker(zap.NewNop())
+	tracker.SetNotifier(hub)
+	instance := NewModelInstance(config.ModelInstance{ID: "gpt-4-a", ModelName: "gpt-4"}, &MockFailingProvider{})
+
+	// Only the failure that opens the circuit is reported
+	for i := 0; i < 5; i++ {
+		tracker.RecordFailure(instance, errors.New("upstream error"))
+	}
+	require.Len(t, ch, 1)
+	n := <-ch
+	assert.Equal(t, notifications.KindCircuitBreakerOpen, n.Kind)
+	assert.Equal(t, "gpt-4-a", n.Data["instance_id"])
+	assert.Equal(t, "upstream error", n.Data["error"])
+
+	tracker.RecordSuccess(instance)
+	for i := 0; i < 3; i++ {
+		tracker.RecordFailure(instance, errors.New("upstream error"))
+	}
+	assert.Len(t, ch, 1)
+}
+
+func TestHealthTracker_ProviderIncidentRaisesThreshold(t *testing.T) {
+	tracker := NewHealthTracker(zap.NewNop())
+	openai := NewModelInstance(config.ModelInstance{ID: "gpt-4-a", Provider: config.ProviderParams{Type: "openai"}}, &MockFailingProvider{})
+	anthropic := NewModelInstance(config.ModelInstance{ID: "claude-a", Provider: config.ProviderParams{Type: "anthropic"}}, &MockFailingProvider{})
+
+	tracker.SetProviderIncident("openai", config.OutageActionRaiseThreshold, 5)
+	for i := 0; i < 4; i++ {
+		tracker.RecordFailure(openai, errors.New("upstream error"))
+		tracker.RecordFailure(anthropic, errors.New("upstream error"))
+	}
+	assert.True(t, openai.Healthy.Load(), "threshold raised during the incident")
+	assert.False(t, anthropic.Healthy.Load())
+
+	tracker.RecordFailure(openai, errors.New("upstream error"))
+	assert.False(t, openai.Healthy.Load())
+
+	tracker.ClearProviderIncident("openai")
+	tracker.RecordSuccess(openai)
+	for i := 0; i < 3; i++ {
+		tracker.RecordFailure(openai, errors.New("upstream error"))
+	}
+	assert.False(t, openai.Healthy.Load())
+}
+
+func TestModelManager_ProviderIncidentShiftsTraffic(t *testing.T) {
+	manager := NewModelManager(zap.NewNop(), config.RouterSettings{
+		RoutingStrategy:       "priority",
+		EnableFailover:        true,
+		InstanceRetryAttempts: 2,
+	}, nil)
+
+	add := func(id, providerType string, priority int) *ModelInstance {
+		instance := &ModelInstance{
+			Config: config.ModelInstance{
+				ID:        id,
+				ModelName: "gpt-4",
+				Priority:  priority,
+				Enabled:   true,
+				Provider:  config.ProviderParams{Type: providerType, Model: "gpt-4"},
+				Timeout:   5 * time.Second,
+			},
+			Provider: &MockFailingProvider{},
+		}
+		instance.Healthy.Store(true)
+		manager.registry.mu.Lock()
+		manager.registry.instances[id] = instance
+		manager.registry.modelMap["gpt-4"] = append(manager.registry.modelMap["gpt-4"], instance)
+		manager.registry.mu.Unlock()
+		return instance
+	}
+	add("openai", "openai", 100)
+	add("azure", "azure", 50)
+
+	instance, err := manager.GetBestInstance(context.Background(), "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", instance.Config.ID)
+
+	manager.SetProviderIncident("openai", config.OutageActionShiftRoutes, 0)
+	instance, err = manager.GetBestInstance(context.Background(), "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, "azure", instance.Config.ID)
+
+	// With every provider affected, the deprioritized instances still serve
+	manager.SetProviderIncident("azure", config.OutageActionShiftRoutes, 0)
+	instance, err = manager.GetBestInstance(context.Background(), "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", instance.Config.ID)
+
+	manager.ClearProviderIncident("openai")
+	manager.ClearProviderIncident("azure")
+	instance, err = manager.GetBestInstance(context.Background(), "gpt-4")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", instance.Config.ID)
+}
+
+func TestUpstreamProvider(t *testing.T) {
+	tests := []struct {
+		providerType string
+		baseURL      string
+		want         string
+	}{
+		{"openai", "", "openai"},
+		{"openai", "https://api.openai.com/v1", "openai"},
+		{"openai", "https://openrouter.ai/api/v1", ""},
+		{"anthropic", "http://localhost:8080", ""},
+		{"azure", "https://example.openai.azure.com", "azure"},
+	}
+	for _, tt := range tests {
+		got := UpstreamProvider(config.ModelInstance{Provider: config.ProviderParams{Type: tt.providerType, BaseURL: tt.baseURL}})
+		assert.Equal(t, tt.want, got, tt.baseURL)
+	}
+}
+
+func TestModelManager_KillSwitchSkipsDisabledModels(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	manager.SetModelKillSwitch(func(model string) bool { return model == "gpt-4" })
+
+	_, err := manager.GetBestInstance(context.Background(), "gpt-4")
+	assert.EqualError(t, err, "model gpt-4 is disabled")
+
+	// Routes move on to their other models
+	for i := 0; i < 5; i++ {
+		result, err := manager.ExecuteWithFailover(context.Background(), &FailoverRequest{
+			ModelName: "smart",
+			ExecuteFunc: func(ctx context.Context, instance *ModelInstance) (interface{}, error) {
+				return instance.Config.ModelName, nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "claude", result.Response)
+	}
+}
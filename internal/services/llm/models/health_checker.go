@@ -14,6 +14,7 @@ type HealthChecker struct {
 	registry      *ModelRegistry
 	healthTracker *HealthTracker
 	healthStore   *redisService.HealthStore
+	probeSpend    probeSpendStore
 	interval      time.Duration
 	timeout       time.Duration
 	logger        *zap.Logger
@@ -32,10 +33,15 @@ func NewHealthChecker(
 	if interval <= 0 {
 		interval = 30 * time.Second
 	}
+	var probeSpend probeSpendStore = newMemoryProbeSpend()
+	if healthStore != nil {
+		probeSpend = healthStore
+	}
 	return &HealthChecker{
 		registry:      registry,
 		healthTracker: healthTracker,
 		healthStore:   healthStore,
+		probeSpend:    probeSpend,
 		interval:      interval,
 		timeout:       10 * time.Second,
 		logger:        logger,
@@ -96,13 +102,22 @@ func (hc *HealthChecker) runAllChecks(ctx context.Context) {
 	hc.logger.Debug("Health checks complete", zap.Int("instances", len(instances)))
 }
 
-// checkInstance performs a health check on a single instance.
+// checkInstance performs a health check on a single instance: a canary
+// probe when the instance enables them, the provider's endpoint check
+// otherwise.
 func (hc *HealthChecker) checkInstance(ctx context.Context, instance *ModelInstance) {
 	checkCtx, cancel := context.WithTimeout(ctx, hc.timeout)
 	defer cancel()
 
 	start := time.Now()
-	err := instance.Provider.HealthCheck(checkCtx)
+	check := healthCheckEndpoint
+	var probeCost, probeSpend float64
+	var err error
+	if instance.Config.HealthProbe.Enabled {
+		check, probeCost, probeSpend, err = hc.probe(checkCtx, instance)
+	} else {
+		err = instance.Provider.HealthCheck(checkCtx)
+	}
 	latency := time.Since(start)
 
 	result := redisService.HealthCheckResult{
@@ -112,6 +127,10 @@ func (hc *HealthChecker) checkInstance(ctx context.Context, instance *ModelInsta
 		Healthy:      err == nil,
 		LatencyMs:    latency.Milliseconds(),
 		CheckedAt:    time.Now(),
+
+		Check:           check,
+		ProbeCost:       probeCost,
+		ProbeSpendToday: probeSpend,
 	}
 
 	if err != nil {
@@ -120,6 +139,7 @@ func (hc *HealthChecker) checkInstance(ctx context.Context, instance *ModelInsta
 		hc.logger.Debug("Health check failed",
 			zap.String("instance", instance.Config.ID),
 			zap.String("model", instance.Config.ModelName),
+			zap.String("check", check),
 			zap.Error(err))
 	} else {
 		hc.healthTracker.RecordSuccess(instance)
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Health probe defaults
+const (
+	defaultProbeMaxDailySpend = 0.10
+	defaultProbeMaxTokens     = 1
+	defaultProbePrompt        = "ping"
+
+	// probePromptTokens is the prompt size budgeted for a probe: the
+	// message plus the provider's chat formatting
+	probePromptTokens = 16
+)
+
+// Health check kinds reported in health check results
+const (
+	healthCheckProbe    = "probe"
+	healthCheckEndpoint = "endpoint"
+)
+
+var (
+	healthProbes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pllm_health_probes_total",
+			Help: "Canary health probes by outcome (passed, failed, over_budget, unpriced); over_budget and unpriced fall back to the endpoint check",
+		},
+		[]string{"model", "instance", "outcome"},
+	)
+
+	healthProbeCost = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pllm_health_probe_cost_usd_total",
+			Help: "Spend on canary health probes in USD, not included in usage records or budgets",
+		},
+		[]string{"model", "instance"},
+	)
+)
+
+// probeSpendStore keeps each instance's health probe spend per UTC day
+type probeSpendStore interface {
+	ProbeSpend(ctx context.Context, instanceID string, now time.Time) (float64, error)
+	AddProbeSpend(ctx context.Context, instanceID string, now time.Time, cost float64) (float64, error)
+}
+
+// memoryProbeSpend keeps probe spend in memory when Redis is unavailable.
+// Each replica then spends up to the cap.
+type memoryProbeSpend struct {
+	mu    sync.Mutex
+	spend map[string]dailyProbeSpend
+}
+
+type dailyProbeSpend struct {
+	day   string
+	spend float64
+}
+
+func newMemoryProbeSpend() *memoryProbeSpend {
+	return &memoryProbeSpend{spend: make(map[string]dailyProbeSpend)}
+}
+
+func (s *memoryProbeSpend) ProbeSpend(ctx context.Context, instanceID string, now time.Time) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry := s.spend[instanceID]; entry.day == now.UTC().Format("2006-01-02") {
+		return entry.spend, nil
+	}
+	return 0, nil
+}
+
+func (s *memoryProbeSpend) AddProbeSpend(ctx context.Context, instanceID string, now time.Time, cost float64) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	day := now.UTC().Format("2006-01-02")
+	entry := s.spend[instanceID]
+	if entry.day != day {
+		entry = dailyProbeSpend{day: day}
+	}
+	entry.spend += cost
+	s.spend[instanceID] = entry
+	return entry.spend, nil
+}
+
+// probeSettings fills in the defaults of an instance's probe configuration
+func probeSettings(cfg config.HealthProbeConfig) config.HealthProbeConfig {
+	if cfg.MaxDailySpend <= 0 {
+		cfg.MaxDailySpend = defaultProbeMaxDailySpend
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = defaultProbeMaxTokens
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = defaultProbePrompt
+	}
+	return cfg
+}
+
+// probe checks an instance with a tiny completion when its daily probe
+// budget allows and with the provider's endpoint check otherwise. Failed
+// probes are counted at their estimated cost, since a provider may still
+// bill them. Instances without pricing are never probed.
+func (hc *HealthChecker) probe(ctx context.Context, instance *ModelInstance) (check string, cost, spent float64, err error) {
+	cfg := probeSettings(instance.Config.HealthProbe)
+	id, model := instance.Config.ID, instance.Config.ModelName
+	now := time.Now()
+
+	inputCost, outputCost := instanceCostPerToken(instance)
+	if inputCost == 0 && outputCost == 0 {
+		healthProbes.WithLabelValues(model, id, "unpriced").Inc()
+		return healthCheckEndpoint, 0, 0, instance.Provider.HealthCheck(ctx)
+	}
+	estimate := float64(probePromptTokens)*inputCost + float64(cfg.MaxTokens)*outputCost
+
+	spent, err = hc.probeSpend.ProbeSpend(ctx, id, now)
+	if err != nil {
+		hc.logger.Warn("Failed to read health probe spend, using the endpoint check",
+			zap.String("instance", id),
+			zap.Error(err))
+		return healthCheckEndpoint, 0, 0, instance.Provider.HealthCheck(ctx)
+	}
+	if spent+estimate > cfg.MaxDailySpend {
+		healthProbes.WithLabelValues(model, id, "over_budget").Inc()
+		return healthCheckEndpoint, 0, spent, instance.Provider.HealthCheck(ctx)
+	}
+
+	maxTokens := cfg.MaxTokens
+	response, err := instance.Provider.ChatCompletion(ctx, &providers.ChatRequest{
+		Model:     instance.Config.Provider.Model,
+		Messages:  []providers.Message{{Role: "user", Content: cfg.Prompt}},
+		MaxTokens: &maxTokens,
+	})
+	cost = estimate
+	outcome := "failed"
+	if err == nil {
+		outcome = "passed"
+		if response.Usage.TotalTokens > 0 {
+			cost = float64(response.Usage.PromptTokens)*inputCost + float64(response.Usage.CompletionTokens)*outputCost
+		}
+	}
+	healthProbes.WithLabelValues(model, id, outcome).Inc()
+	healthProbeCost.WithLabelValues(model, id).Add(cost)
+
+	// The check's context may have timed out; the spend is recorded anyway
+	storeCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	total, addErr := hc.probeSpend.AddProbeSpend(storeCtx, id, now, cost)
+	if addErr != nil {
+		hc.logger.Warn("Failed to record health probe spend",
+			zap.String("instance", id),
+			zap.Error(addErr))
+		total = spent + cost
+	}
+
+	return healthCheckProbe, cost, total, err
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// probeTestProvider counts probe completions and endpoint checks
+type probeTestProvider struct {
+	MockFailingProvider
+	completions    []*providers.ChatRequest
+	endpointChecks int
+	err            error
+}
+
+func (p *probeTestProvider) ChatCompletion(ctx context.Context, req *providers.ChatRequest) (*providers.ChatResponse, error) {
+	p.completions = append(p.completions, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &providers.ChatResponse{
+		Choices: []providers.Choice{{Message: providers.Message{Role: "assistant", Content: "p"}}},
+		Usage:   providers.Usage{PromptTokens: 8, CompletionTokens: 1, TotalTokens: 9},
+	}, nil
+}
+
+func (p *probeTestProvider) HealthCheck(ctx context.Context) error {
+	p.endpointChecks++
+	return nil
+}
+
+func newProbeTestInstance(probe config.HealthProbeConfig) (*ModelInstance, *probeTestProvider) {
+	provider := &probeTestProvider{}
+	instance := &ModelInstance{
+		Config: config.ModelInstance{
+			ID:                 "probe-instance",
+			ModelName:          "expensive-model",
+			Provider:           config.ProviderParams{Type: "openai", Model: "o1-pro"},
+			InputCostPerToken:  0.0001,
+			OutputCostPerToken: 0.001,
+			HealthProbe:        probe,
+		},
+		Provider: provider,
+	}
+	instance.Healthy.Store(true)
+	return instance, provider
+}
+
+func newProbeTestChecker() *HealthChecker {
+	return NewHealthChecker(NewModelRegistry(zap.NewNop()), NewHealthTracker(zap.NewNop()), nil, time.Minute, zap.NewNop())
+}
+
+func TestHealthChecker_ProbesWithinDailyBudget(t *testing.T) {
+	// A probe is estimated at 16 * 0.0001 + 1 * 0.001 = 0.0026 and costs
+	// 8 * 0.0001 + 1 * 0.001 = 0.0018, so the budget fits three probes
+	instance, provider := newProbeTestInstance(config.HealthProbeConfig{Enabled: true, MaxDailySpend: 0.0065})
+	hc := newProbeTestChecker()
+
+	for i := 0; i < 3; i++ {
+		check, cost, spent, err := hc.probe(context.Background(), instance)
+		require.NoError(t, err)
+		assert.Equal(t, healthCheckProbe, check)
+		assert.InDelta(t, 0.0018, cost, 1e-12)
+		assert.InDelta(t, 0.0018*float64(i+1), spent, 1e-12)
+	}
+	require.Len(t, provider.completions, 3)
+	assert.Equal(t, "o1-pro", provider.completions[0].Model)
+	assert.Equal(t, 1, *provider.completions[0].MaxTokens)
+	assert.Equal(t, "ping", provider.completions[0].Messages[0].Content)
+	assert.Zero(t, provider.endpointChecks)
+
+	// 0.0054 spent: another probe could reach 0.008
+	check, cost, spent, err := hc.probe(context.Background(), instance)
+	require.NoError(t, err)
+	assert.Equal(t, healthCheckEndpoint, check)
+	assert.Zero(t, cost)
+	assert.InDelta(t, 0.0054, spent, 1e-12)
+	assert.Len(t, provider.completions, 3)
+	assert.Equal(t, 1, provider.endpointChecks)
+}
+
+func TestHealthChecker_FailedProbeCountsEstimate(t *testing.T) {
+	instance, provider := newProbeTestInstance(config.HealthProbeConfig{Enabled: true, MaxTokens: 4, Prompt: "hi"})
+	provider.err = errors.New("model overloaded")
+	hc := newProbeTestChecker()
+
+	hc.checkInstance(context.Background(), instance)
+	assert.Equal(t, int32(1), instance.FailureCount.Load())
+	require.Len(t, provider.completions, 1)
+	assert.Equal(t, 4, *provider.completions[0].MaxTokens)
+
+	spent, err := hc.probeSpend.ProbeSpend(context.Background(), instance.Config.ID, time.Now())
+	require.NoError(t, err)
+	assert.InDelta(t, 16*0.0001+4*0.001, spent, 1e-12)
+}
+
+func TestHealthChecker_ProbeFallsBackToEndpointCheck(t *testing.T) {
+	// Without pricing the probe cost cannot be budgeted
+	instance, provider := newProbeTestInstance(config.HealthProbeConfig{Enabled: true})
+	instance.Config.ModelName = "unpriced-model"
+	instance.Config.InputCostPerToken = 0
+	instance.Config.OutputCostPerToken = 0
+
+	check, _, _, err := newProbeTestChecker().probe(context.Background(), instance)
+	require.NoError(t, err)
+	assert.Equal(t, healthCheckEndpoint, check)
+	assert.Empty(t, provider.completions)
+
+	// Instances without probes keep the endpoint check
+	instance, provider = newProbeTestInstance(config.HealthProbeConfig{})
+	newProbeTestChecker().checkInstance(context.Background(), instance)
+	assert.Empty(t, provider.completions)
+	assert.Equal(t, 1, provider.endpointChecks)
+}
+
+func TestMemoryProbeSpend_ResetsDaily(t *testing.T) {
+	store := newMemoryProbeSpend()
+	day := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+
+	_, err := store.AddProbeSpend(context.Background(), "a", day, 0.5)
+	require.NoError(t, err)
+	total, err := store.AddProbeSpend(context.Background(), "a", day.Add(30*time.Minute), 0.25)
+	require.NoError(t, err)
+	assert.Equal(t, 0.75, total)
+
+	spent, err := store.ProbeSpend(context.Background(), "a", day.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, spent)
+	spent, err = store.ProbeSpend(context.Background(), "b", day)
+	require.NoError(t, err)
+	assert.Zero(t, spent)
+}
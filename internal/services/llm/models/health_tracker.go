@@ -1,14 +1,38 @@
 package models
 
 import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/amerfu/pllm/internal/core/config"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
 	"go.uber.org/zap"
 )
 
+// defaultFailureThreshold is the number of consecutive failures that opens
+// an instance's circuit breaker
+const defaultFailureThreshold = 3
+
 // HealthTracker monitors the health status of model instances
 type HealthTracker struct {
-	logger *zap.Logger
+	logger   *zap.Logger
+	notifier *notifications.Hub
+
+	// Declared upstream incidents, by provider type
+	incidentMu sync.RWMutex
+	incidents  map[string]providerIncident
+}
+
+// providerIncident is the mitigation applied to a provider's instances
+// during a declared upstream incident
+type providerIncident struct {
+	action           string
+	failureThreshold int32
 }
 
 // NewHealthTracker creates a new health tracker
@@ -18,6 +42,11 @@ func NewHealthTracker(logger *zap.Logger) *HealthTracker {
 	}
 }
 
+// SetNotifier sends a notification whenever an instance's circuit breaker opens
+func (h *HealthTracker) SetNotifier(notifier *notifications.Hub) {
+	h.notifier = notifier
+}
+
 // RecordSuccess records a successful request for the instance
 func (h *HealthTracker) RecordSuccess(instance *ModelInstance) {
 	instance.Healthy.Store(true)
@@ -34,13 +63,26 @@ func (h *HealthTracker) RecordFailure(instance *ModelInstance, err error) {
 	instance.LastFailure.Store(time.Now())
 	failureCount := instance.FailureCount.Add(1)
 
-	// Mark as unhealthy after 3 failures
-	if failureCount >= 3 {
-		instance.Healthy.Store(false)
+	// Mark as unhealthy after 3 failures, or more during an upstream incident
+	if failureCount >= h.failureThreshold(instance) {
+		wasHealthy := instance.Healthy.Swap(false)
 		h.logger.Warn("Instance marked as unhealthy",
 			zap.String("instance_id", instance.Config.ID),
 			zap.Int32("failure_count", failureCount),
 			zap.Error(err))
+		if wasHealthy {
+			h.notifier.Notify(context.Background(), notifications.Notification{
+				Kind:     notifications.KindCircuitBreakerOpen,
+				Severity: notifications.SeverityWarning,
+				Message:  fmt.Sprintf("Circuit breaker opened for instance %s", instance.Config.ID),
+				Data: map[string]interface{}{
+					"instance_id":   instance.Config.ID,
+					"model":         instance.Config.ModelName,
+					"failure_count": failureCount,
+					"error":         errorString(err),
+				},
+			})
+		}
 	} else {
 		h.logger.Debug("Recorded failure for instance",
 			zap.String("instance_id", instance.Config.ID),
@@ -49,6 +91,80 @@ func (h *HealthTracker) RecordFailure(instance *ModelInstance, err error) {
 	}
 }
 
+// SetProviderIncident applies a mitigation to every instance of the provider
+// type while an upstream incident is declared: raise_threshold opens their
+// circuit breakers only after failureThreshold failures, and shift_routes
+// routes traffic to other providers whenever they have a healthy instance
+func (h *HealthTracker) SetProviderIncident(provider, action string, failureThreshold int) {
+	h.incidentMu.Lock()
+	defer h.incidentMu.Unlock()
+
+	if h.incidents == nil {
+		h.incidents = make(map[string]providerIncident)
+	}
+	h.incidents[provider] = providerIncident{action: action, failureThreshold: int32(failureThreshold)}
+}
+
+// ClearProviderIncident lifts the mitigation of the provider type
+func (h *HealthTracker) ClearProviderIncident(provider string) {
+	h.incidentMu.Lock()
+	defer h.incidentMu.Unlock()
+
+	delete(h.incidents, provider)
+}
+
+func (h *HealthTracker) providerIncident(instance *ModelInstance) (providerIncident, bool) {
+	provider := UpstreamProvider(instance.Config)
+	if provider == "" {
+		return providerIncident{}, false
+	}
+
+	h.incidentMu.RLock()
+	defer h.incidentMu.RUnlock()
+
+	incident, ok := h.incidents[provider]
+	return incident, ok
+}
+
+func (h *HealthTracker) failureThreshold(instance *ModelInstance) int32 {
+	if incident, ok := h.providerIncident(instance); ok &&
+		incident.action == config.OutageActionRaiseThreshold && incident.failureThreshold > defaultFailureThreshold {
+		return incident.failureThreshold
+	}
+	return defaultFailureThreshold
+}
+
+// IsDeprioritized reports whether routing should avoid the instance because
+// its provider has a declared incident with the shift_routes mitigation
+func (h *HealthTracker) IsDeprioritized(instance *ModelInstance) bool {
+	incident, ok := h.providerIncident(instance)
+	return ok && incident.action == config.OutageActionShiftRoutes
+}
+
+// UpstreamProvider returns the provider whose status page covers the
+// instance: its provider type, or "" when an OpenAI or Anthropic compatible
+// instance points to another host
+func UpstreamProvider(cfg config.ModelInstance) string {
+	provider := cfg.Provider.Type
+	if cfg.Provider.BaseURL == "" {
+		return provider
+	}
+
+	var host string
+	switch provider {
+	case "openai":
+		host = "api.openai.com"
+	case "anthropic":
+		host = "api.anthropic.com"
+	default:
+		return provider
+	}
+	if u, err := url.Parse(cfg.Provider.BaseURL); err != nil || !strings.EqualFold(u.Hostname(), host) {
+		return ""
+	}
+	return provider
+}
+
 // healthRecoveryCooldown is the time after which an unhealthy instance is allowed
 // to receive traffic again (half-open circuit breaker). If the request succeeds,
 // RecordSuccess will mark it fully healthy; if it fails, RecordFailure will reset
@@ -113,3 +229,24 @@ func (h *HealthTracker) GetAllHealthStatuses(instances []*ModelInstance) []Healt
 	}
 	return statuses
 }
+
+// Restore applies health state saved by a previous process. The last error
+// is not restored; an unhealthy instance gets its retry after the usual
+// cooldown from the saved failure time.
+func (h *HealthTracker) Restore(instance *ModelInstance, snapshot redisService.InstanceSnapshot) {
+	instance.Healthy.Store(snapshot.Healthy)
+	instance.FailureCount.Store(snapshot.FailureCount)
+	if !snapshot.LastFailure.IsZero() {
+		instance.LastFailure.Store(snapshot.LastFailure)
+	}
+	if !snapshot.LastSuccess.IsZero() {
+		instance.LastSuccess.Store(snapshot.LastSuccess)
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -8,9 +8,11 @@ import (
 	"time"
 
 	"github.com/amerfu/pllm/internal/core/config"
+	coremodels "github.com/amerfu/pllm/internal/core/models"
 	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 	"github.com/amerfu/pllm/internal/services/llm/models/routing"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
@@ -21,7 +23,8 @@ type RouteEntry struct {
 	Strategy       routing.Strategy
 	Models         []RouteModelEntry
 	FallbackModels []string
-	rrCounter      atomic.Uint64 // route-level round-robin counter
+	Defaults       config.RouteDefaults // Parameters applied to requests that do not set them
+	rrCounter      atomic.Uint64        // route-level round-robin counter
 }
 
 // RouteModelEntry represents a model within a route.
@@ -39,6 +42,7 @@ type ModelManager struct {
 	metricsCollector *MetricsCollector
 	latencyTracker   *redisService.LatencyTracker // Distributed latency tracking
 	healthStore      *redisService.HealthStore    // Distributed health check results
+	snapshotStore    *redisService.SnapshotStore  // Instance state kept across restarts
 	routingStrategy  routing.Strategy              // Routing strategy (priority, latency, etc.)
 	router           config.RouterSettings
 	logger           *zap.Logger
@@ -46,6 +50,28 @@ type ModelManager struct {
 	// Route registry
 	routes  map[string]*RouteEntry // key: slug
 	routeMu sync.RWMutex
+
+	// Latency tiers, requested as "tier:<name>"
+	tiers  map[string]*TierEntry // key: tier name
+	tierMu sync.RWMutex
+
+	// Request pressure behind the autoscaling signals
+	scaling *scalingTracker
+
+	// Reserved capacity routing decisions and utilization
+	capacity *capacityTracker
+
+	// Quiet periods of finished streams, for diagnostics
+	streamGaps *streamGapTracker
+
+	// Emergency kill switches, consulted before any instance is used
+	modelDisabled func(model string) bool
+
+	// Reports whether the gateway sheds load under resource pressure
+	shouldShedLoad func() bool
+
+	// Provider keys teams brought for their own traffic
+	teamProviders TeamProviders
 }
 
 // NewModelManager creates a new refactored model manager
@@ -53,9 +79,12 @@ func NewModelManager(logger *zap.Logger, router config.RouterSettings, redisClie
 	// Initialize distributed latency tracker and health store
 	var latencyTracker *redisService.LatencyTracker
 	var healthStore *redisService.HealthStore
+	var snapshotStore *redisService.SnapshotStore
 	if redisClient != nil {
 		latencyTracker = redisService.NewLatencyTracker(redisClient, logger)
+		latencyTracker.SetDecay(router.LatencyHalfLife, router.LatencyWarmupSamples)
 		healthStore = redisService.NewHealthStore(redisClient, logger)
+		snapshotStore = redisService.NewSnapshotStore(redisClient, logger)
 	}
 
 	// Initialize model registry
@@ -78,10 +107,15 @@ func NewModelManager(logger *zap.Logger, router config.RouterSettings, redisClie
 		metricsCollector: NewMetricsCollector(logger),
 		latencyTracker:   latencyTracker,
 		healthStore:      healthStore,
+		snapshotStore:    snapshotStore,
 		routingStrategy:  strategy,
 		router:           router,
 		logger:           logger,
 		routes:           make(map[string]*RouteEntry),
+		tiers:            make(map[string]*TierEntry),
+		scaling:          newScalingTracker(),
+		capacity:         newCapacityTracker(),
+		streamGaps:       newStreamGapTracker(),
 	}
 }
 
@@ -92,6 +126,10 @@ func (m *ModelManager) LoadModelInstances(instances []config.ModelInstance) erro
 
 // GetBestInstance returns the best instance for a model based on routing strategy
 func (m *ModelManager) GetBestInstance(ctx context.Context, modelName string) (*ModelInstance, error) {
+	if m.isModelDisabled(modelName) {
+		return nil, fmt.Errorf("model %s is disabled", modelName)
+	}
+
 	// Get available instances for the model
 	instances, exists := m.registry.GetModelInstances(modelName)
 	if !exists || len(instances) == 0 {
@@ -99,17 +137,22 @@ func (m *ModelManager) GetBestInstance(ctx context.Context, modelName string) (*
 	}
 
 	// Filter healthy instances
-	var healthyInstances []routing.ModelInstance
+	var healthy []*ModelInstance
 	for _, instance := range instances {
 		if m.healthTracker.IsHealthy(instance) {
-			healthyInstances = append(healthyInstances, instance)
+			healthy = append(healthy, instance)
 		}
 	}
 
-	if len(healthyInstances) == 0 {
+	if len(healthy) == 0 {
 		return nil, fmt.Errorf("no healthy instances available for model: %s", modelName)
 	}
 
+	var healthyInstances []routing.ModelInstance
+	for _, instance := range m.preferReserved(modelName, m.preferUnaffected(healthy)) {
+		healthyInstances = append(healthyInstances, instance)
+	}
+
 	// Delegate to routing strategy
 	selected, err := m.routingStrategy.SelectInstance(ctx, healthyInstances)
 	if err != nil {
@@ -173,11 +216,19 @@ func (m *ModelManager) LoadRoutes(configs []config.RouteConfig) {
 			})
 		}
 
+		defaults := rc.Defaults
+		if err := ValidateRouteDefaults(defaults); err != nil {
+			m.logger.Warn("Ignoring invalid route defaults",
+				zap.String("route", rc.Slug), zap.Error(err))
+			defaults = config.RouteDefaults{}
+		}
+
 		entry := &RouteEntry{
 			Slug:           rc.Slug,
 			Strategy:       routeStrategy,
 			Models:         models,
 			FallbackModels: rc.FallbackModels,
+			Defaults:       defaults,
 		}
 
 		m.routeMu.Lock()
@@ -314,8 +365,9 @@ func (m *ModelManager) executeRouteWithFailover(ctx context.Context, route *Rout
 	}
 
 	for len(remaining) > 0 {
-		// Build proxies from remaining models
-		var proxies []routing.ModelInstance
+		// Build proxies from remaining models. Models served only by
+		// providers with a declared incident are used when nothing else is.
+		var proxies, deprioritized []routing.ModelInstance
 		for _, rm := range remaining {
 			instances, exists := m.registry.GetModelInstances(rm.ModelName)
 			if !exists || len(instances) == 0 {
@@ -324,11 +376,14 @@ func (m *ModelManager) executeRouteWithFailover(ctx context.Context, route *Rout
 					zap.String("model", rm.ModelName))
 				continue
 			}
-			hasHealthy := false
+			hasHealthy, hasPreferred := false, false
 			for _, inst := range instances {
 				if m.healthTracker.IsHealthy(inst) {
 					hasHealthy = true
-					break
+					if !m.healthTracker.IsDeprioritized(inst) {
+						hasPreferred = true
+						break
+					}
 				}
 			}
 			if !hasHealthy {
@@ -338,7 +393,14 @@ func (m *ModelManager) executeRouteWithFailover(ctx context.Context, route *Rout
 				continue
 			}
 			proxy := NewRouteModelProxy(rm.ModelName, float64(rm.Weight), rm.Priority)
-			proxies = append(proxies, proxy)
+			if hasPreferred {
+				proxies = append(proxies, proxy)
+			} else {
+				deprioritized = append(deprioritized, proxy)
+			}
+		}
+		if len(proxies) == 0 {
+			proxies = deprioritized
 		}
 
 		if len(proxies) == 0 {
@@ -395,6 +457,11 @@ func (m *ModelManager) ExecuteWithFailover(ctx context.Context, req *FailoverReq
 		return nil, fmt.Errorf("route %q: all models and fallbacks exhausted: %w", req.ModelName, err)
 	}
 
+	// Latency tiers pick the best model of the tier for this request
+	if tier, isTier := m.ResolveTier(req.ModelName); isTier {
+		return m.executeTierWithFailover(ctx, tier, req)
+	}
+
 	if !m.router.EnableFailover {
 		// Failover disabled - use simple execution
 		instance, err := m.GetBestInstance(ctx, req.ModelName)
@@ -402,7 +469,9 @@ func (m *ModelManager) ExecuteWithFailover(ctx context.Context, req *FailoverReq
 			return nil, err
 		}
 
+		done := instance.Begin()
 		response, err := req.ExecuteFunc(ctx, instance)
+		done()
 		if err != nil {
 			m.RecordFailure(instance, err)
 			return nil, err
@@ -484,6 +553,10 @@ func (m *ModelManager) tryModelInstances(
 ) (*FailoverResult, error) {
 	var lastErr error
 
+	if m.isModelDisabled(modelName) {
+		return nil, fmt.Errorf("model %s is disabled", modelName)
+	}
+
 	// Get all available instances for the model
 	instances, exists := m.registry.GetModelInstances(modelName)
 	if !exists || len(instances) == 0 {
@@ -509,7 +582,7 @@ func (m *ModelManager) tryModelInstances(
 		
 		// Convert to routing.ModelInstance interface for strategy
 		var routingInstances []routing.ModelInstance
-		for _, inst := range healthyInstances {
+		for _, inst := range m.preferReserved(modelName, m.preferUnaffected(healthyInstances)) {
 			routingInstances = append(routingInstances, inst)
 		}
 		
@@ -538,8 +611,11 @@ func (m *ModelManager) tryModelInstances(
 		timeout := time.Duration(float64(instance.Config.Timeout) * timeoutMultiple)
 		executeCtx, cancel := context.WithTimeout(ctx, timeout)
 		
-		// Execute request
+		// Execute request. A stream counts as in flight until its meter
+		// finishes.
+		done := instance.Begin()
 		response, err := req.ExecuteFunc(executeCtx, instance)
+		done()
 		cancel()
 
 		if err != nil {
@@ -588,6 +664,42 @@ func (m *ModelManager) tryModelInstances(
 	return nil, fmt.Errorf("all instance attempts failed for model %s: %w", modelName, lastErr)
 }
 
+// preferUnaffected drops draining instances, then instances deprioritized
+// by a declared upstream incident, each unless no other instance is left
+func (m *ModelManager) preferUnaffected(instances []*ModelInstance) []*ModelInstance {
+	instances = prefer(instances, func(instance *ModelInstance) bool {
+		return !instance.IsDraining()
+	})
+	return prefer(instances, func(instance *ModelInstance) bool {
+		return !m.healthTracker.IsDeprioritized(instance)
+	})
+}
+
+// prefer keeps the instances matching keep, or all of them when none does
+func prefer(instances []*ModelInstance, keep func(*ModelInstance) bool) []*ModelInstance {
+	preferred := make([]*ModelInstance, 0, len(instances))
+	for _, instance := range instances {
+		if keep(instance) {
+			preferred = append(preferred, instance)
+		}
+	}
+	if len(preferred) == 0 {
+		return instances
+	}
+	return preferred
+}
+
+// SetProviderIncident mitigates a declared upstream incident on every
+// instance of the provider type; see HealthTracker.SetProviderIncident
+func (m *ModelManager) SetProviderIncident(provider, action string, failureThreshold int) {
+	m.healthTracker.SetProviderIncident(provider, action, failureThreshold)
+}
+
+// ClearProviderIncident lifts the mitigation of the provider type
+func (m *ModelManager) ClearProviderIncident(provider string) {
+	m.healthTracker.ClearProviderIncident(provider)
+}
+
 // removeInstance removes an instance from a slice
 func removeInstance(instances []*ModelInstance, toRemove *ModelInstance) []*ModelInstance {
 	result := make([]*ModelInstance, 0, len(instances))
@@ -646,6 +758,7 @@ func (m *ModelManager) GetModelStats() map[string]interface{} {
 			"health_score":    healthScore,
 			"total_requests":  instanceMetrics.TotalRequests,
 			"avg_latency":     fmt.Sprintf("%.0f", float64(instanceMetrics.AverageLatency.Milliseconds())),
+			"avg_ttft":        fmt.Sprintf("%.0f", float64(instanceMetrics.AverageTTFT.Milliseconds())),
 			"requests_minute": instanceMetrics.RequestsThisMinute,
 			"tokens_minute":   instanceMetrics.TokensThisMinute,
 		}
@@ -667,7 +780,7 @@ func (m *ModelManager) GetModelStats() map[string]interface{} {
 	stats["total_tokens"] = totalTokens
 	stats["total_cost"] = float64(totalTokens) * 0.0001 // Rough cost estimate
 	stats["active_users"] = 0                           // TODO: Track active users
-	stats["should_shed_load"] = false                   // TODO: Implement load shedding logic
+	stats["should_shed_load"] = m.shouldShedLoad != nil && m.shouldShedLoad()
 	stats["active_models"] = activeModels
 
 	return stats
@@ -721,6 +834,29 @@ func (m *ModelManager) RecordRequestEnd(modelName string, latency time.Duration,
 	}
 }
 
+// RecordFirstToken records the time to first token of a streaming request on
+// the instance and, without blocking the stream, in the distributed latency
+// tracker
+func (m *ModelManager) RecordFirstToken(instance *ModelInstance, ttft time.Duration) {
+	instance.RecordFirstToken(ttft.Milliseconds())
+	if m.latencyTracker == nil {
+		return
+	}
+
+	modelName := instance.Config.ModelName
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		if err := m.latencyTracker.RecordTTFT(ctx, modelName, ttft); err != nil {
+			m.logger.Warn("Failed to record distributed time to first token",
+				zap.String("model", modelName),
+				zap.Duration("ttft", ttft),
+				zap.Error(err))
+		}
+	}()
+}
+
 // GetBestInstanceAdaptive returns the best instance (alias for GetBestInstance)
 func (m *ModelManager) GetBestInstanceAdaptive(ctx context.Context, modelName string) (*ModelInstance, error) {
 	return m.GetBestInstance(ctx, modelName)
@@ -759,6 +895,18 @@ func (m *ModelManager) GetDetailedModelInfo() []ModelInfo {
 				ownedBy = "google"
 			case "openrouter":
 				ownedBy = "openrouter"
+			case "nim", "triton":
+				ownedBy = "nvidia"
+			case "watsonx":
+				ownedBy = "ibm"
+			case "azure_mistral":
+				ownedBy = "mistralai"
+			case "dashscope":
+				ownedBy = "alibaba"
+			case "qianfan":
+				ownedBy = "baidu"
+			case "perplexity":
+				ownedBy = "perplexity"
 			default:
 				ownedBy = instance.Config.Provider.Type
 			}
@@ -832,6 +980,16 @@ func (m *ModelManager) UpdateInstance(instanceID string, cfg config.ModelInstanc
 	return m.registry.UpdateInstance(instanceID, cfg)
 }
 
+// DisableInstance takes an instance out of routing until EnableInstance
+func (m *ModelManager) DisableInstance(instanceID string) error {
+	return m.registry.DisableInstance(instanceID)
+}
+
+// EnableInstance returns a disabled instance to routing
+func (m *ModelManager) EnableInstance(instanceID string) error {
+	return m.registry.EnableInstance(instanceID)
+}
+
 // GetInstanceSource returns the source of an instance ("system" or "user")
 func (m *ModelManager) GetInstanceSource(instanceID string) string {
 	return m.registry.GetInstanceSource(instanceID)
@@ -863,6 +1021,41 @@ func (m *ModelManager) NewHealthChecker(interval time.Duration) *HealthChecker {
 	return NewHealthChecker(m.registry, m.healthTracker, m.healthStore, interval, m.logger)
 }
 
+// SetModelKillSwitch makes routes, fallbacks and tiers skip the models
+// disabled reports as switched off. Call it before serving requests.
+func (m *ModelManager) SetModelKillSwitch(disabled func(model string) bool) {
+	m.modelDisabled = disabled
+}
+
+// isModelDisabled reports whether the model is switched off. Switching off a
+// model also switches off its version-pinned names.
+func (m *ModelManager) isModelDisabled(model string) bool {
+	if m.modelDisabled == nil {
+		return false
+	}
+	name, _ := coremodels.SplitModelVersion(model)
+	return m.modelDisabled(model) || m.modelDisabled(name)
+}
+
+// SetLoadShedding reports shouldShed in the should_shed_load statistic
+func (m *ModelManager) SetLoadShedding(shouldShed func() bool) {
+	m.shouldShedLoad = shouldShed
+}
+
+// SetNotifier sends admin notifications when an instance's circuit breaker opens
+func (m *ModelManager) SetNotifier(notifier *notifications.Hub) {
+	m.healthTracker.SetNotifier(notifier)
+}
+
+// NewSnapshotter creates a Snapshotter that keeps instance state in Redis
+// across restarts. It returns nil when Redis is unavailable.
+func (m *ModelManager) NewSnapshotter(interval time.Duration) *Snapshotter {
+	if m.snapshotStore == nil {
+		return nil
+	}
+	return NewSnapshotter(m.registry, m.healthTracker, m.metricsCollector, m.snapshotStore, interval, m.logger)
+}
+
 // ModelInfo represents detailed model information for API responses
 type ModelInfo struct {
 	ID      string `json:"id"`
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 	"go.uber.org/zap"
 )
 
@@ -69,6 +70,7 @@ func (m *MetricsCollector) GetMetrics(instance *ModelInstance) InstanceMetrics {
 		TotalRequests:      instance.TotalRequests.Load(),
 		TotalTokens:        instance.TotalTokens.Load(),
 		AverageLatency:     time.Duration(instance.AverageLatency.Load()) * time.Millisecond,
+		AverageTTFT:        time.Duration(instance.AverageTTFT.Load()) * time.Millisecond,
 		RequestsThisMinute: instance.RequestsThisMinute.Load(),
 		TokensThisMinute:   instance.TokensThisMinute.Load(),
 		WindowStart:        windowStart,
@@ -81,6 +83,7 @@ type InstanceMetrics struct {
 	TotalRequests      int64         `json:"total_requests"`
 	TotalTokens        int64         `json:"total_tokens"`
 	AverageLatency     time.Duration `json:"average_latency"`
+	AverageTTFT        time.Duration `json:"average_ttft"` // Streaming requests only
 	RequestsThisMinute int32         `json:"requests_this_minute"`
 	TokensThisMinute   int32         `json:"tokens_this_minute"`
 	WindowStart        time.Time     `json:"window_start"`
@@ -120,3 +123,12 @@ func (m *MetricsCollector) CheckRateLimit(instance *ModelInstance, additionalTok
 func (m *MetricsCollector) UpdateTokenCount(instance *ModelInstance, tokens int32) {
 	instance.TokensThisMinute.Add(tokens)
 }
+
+// Restore applies performance counters saved by a previous process. The
+// per-minute rate limit window starts fresh.
+func (m *MetricsCollector) Restore(instance *ModelInstance, snapshot redisService.InstanceSnapshot) {
+	instance.TotalRequests.Store(snapshot.TotalRequests)
+	instance.TotalTokens.Store(snapshot.TotalTokens)
+	instance.AverageLatency.Store(snapshot.AverageLatencyMs)
+	instance.AverageTTFT.Store(snapshot.AverageTTFTMs)
+}
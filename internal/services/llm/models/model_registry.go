@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 
 	"github.com/amerfu/pllm/internal/core/config"
+	coremodels "github.com/amerfu/pllm/internal/core/models"
 	"github.com/amerfu/pllm/internal/services/llm/providers"
 	"go.uber.org/zap"
 )
@@ -17,6 +18,7 @@ type ModelRegistry struct {
 	modelMap           map[string][]*ModelInstance   // key: model name, value: instances for that model
 	providers          map[string]providers.Provider // Provider instances by unique key
 	roundRobinCounters map[string]*atomic.Uint64
+	disabled           map[string]*ModelInstance // instances disabled at runtime, key: instance ID
 	logger             *zap.Logger
 	mu                 sync.RWMutex
 }
@@ -28,12 +30,15 @@ func NewModelRegistry(logger *zap.Logger) *ModelRegistry {
 		modelMap:           make(map[string][]*ModelInstance),
 		providers:          make(map[string]providers.Provider),
 		roundRobinCounters: make(map[string]*atomic.Uint64),
+		disabled:           make(map[string]*ModelInstance),
 		logger:             logger,
 	}
 }
 
 // LoadModelInstances loads model instances from configuration
 func (r *ModelRegistry) LoadModelInstances(instances []config.ModelInstance) error {
+	instances = r.expandDiscovered(instances)
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -105,6 +110,36 @@ func (r *ModelRegistry) getOrCreateProvider(providerCfg config.ProviderParams) (
 		providerKey += ":" + providerCfg.AzureDeployment
 	}
 
+	// NIM/Triton/Ollama: health checks probe the server for the provider's model
+	if providerCfg.Type == "nim" || providerCfg.Type == "triton" || providerCfg.Type == "ollama" {
+		providerKey += ":" + providerCfg.Model
+	}
+
+	// watsonx: requests are scoped to a project or deployment space
+	if providerCfg.Type == "watsonx" {
+		providerKey += ":" + providerCfg.ProjectID + ":" + providerCfg.SpaceID
+	}
+
+	// Cohere: embeddings are sent with the deployment's input type
+	if providerCfg.Type == "cohere" {
+		providerKey += ":" + providerCfg.EmbeddingInputType
+	}
+
+	// DashScope: calls are billed to the workspace; Qianfan: the secret key
+	// is part of the credentials
+	switch providerCfg.Type {
+	case "dashscope":
+		providerKey += ":" + providerCfg.OrgID
+	case "qianfan":
+		providerKey += ":" + providerCfg.APISecret
+	}
+
+	// custom-openai: headers and paths are part of the endpoint, and health
+	// checks probe the server for the provider's model
+	if providerCfg.Type == "custom-openai" {
+		providerKey += fmt.Sprintf(":%s:%v:%v", providerCfg.Model, providerCfg.Headers, providerCfg.Paths)
+	}
+
 	// Check if provider already exists
 	if provider, exists := r.providers[providerKey]; exists {
 		return provider, nil
@@ -152,8 +187,14 @@ func (r *ModelRegistry) CreateProvider(cfg config.ProviderParams) (providers.Pro
 				cfg.Model: cfg.AzureDeployment,
 			}
 		}
+		if cfg.AzureBaseModel != "" {
+			extra["base_models"] = map[string]interface{}{
+				cfg.Model: cfg.AzureBaseModel,
+			}
+		}
 	case "bedrock":
-		// Bedrock uses APIKey/APISecret for AWS credentials
+		// Bedrock uses APIKey/APISecret for static AWS keys and falls back
+		// to the default credential chain when they are empty
 		if cfg.AWSAccessKeyID != "" {
 			providerCfg.APIKey = cfg.AWSAccessKeyID
 		}
@@ -163,6 +204,17 @@ func (r *ModelRegistry) CreateProvider(cfg config.ProviderParams) (providers.Pro
 		if cfg.AWSRegionName != "" {
 			providerCfg.Region = cfg.AWSRegionName
 		}
+		for key, value := range map[string]string{
+			"aws_session_token":     cfg.AWSSessionToken,
+			"aws_profile":           cfg.AWSProfileName,
+			"aws_role_arn":          cfg.AWSRoleARN,
+			"aws_external_id":       cfg.AWSExternalID,
+			"aws_role_session_name": cfg.AWSRoleSessionName,
+		} {
+			if value != "" {
+				extra[key] = value
+			}
+		}
 	case "vertex":
 		if cfg.VertexProject != "" {
 			extra["project_id"] = cfg.VertexProject
@@ -170,6 +222,55 @@ func (r *ModelRegistry) CreateProvider(cfg config.ProviderParams) (providers.Pro
 		if cfg.VertexLocation != "" {
 			providerCfg.Region = cfg.VertexLocation
 		}
+	case "nim", "triton":
+		if cfg.Model != "" {
+			providerCfg.Models = []string{cfg.Model}
+		}
+		if cfg.ServerURL != "" {
+			extra["server_url"] = cfg.ServerURL
+		}
+		if cfg.MetricsURL != "" {
+			extra["metrics_url"] = cfg.MetricsURL
+		}
+	case "watsonx":
+		providerCfg.APIVersion = cfg.APIVersion
+		for key, value := range map[string]string{
+			"project_id": cfg.ProjectID,
+			"space_id":   cfg.SpaceID,
+			"iam_url":    cfg.IAMURL,
+		} {
+			if value != "" {
+				extra[key] = value
+			}
+		}
+	case "azure_mistral":
+		providerCfg.APIVersion = cfg.APIVersion
+	case "cohere":
+		if cfg.EmbeddingInputType != "" {
+			extra["embedding_input_type"] = cfg.EmbeddingInputType
+		}
+	case "ollama":
+		if cfg.Model != "" {
+			providerCfg.Models = []string{cfg.Model}
+		}
+	case "custom-openai":
+		if cfg.Model != "" {
+			providerCfg.Models = []string{cfg.Model}
+		}
+		if len(cfg.Headers) > 0 {
+			headers := make(map[string]interface{}, len(cfg.Headers))
+			for key, value := range cfg.Headers {
+				headers[key] = value
+			}
+			extra["headers"] = headers
+		}
+		if len(cfg.Paths) > 0 {
+			paths := make(map[string]interface{}, len(cfg.Paths))
+			for endpoint, path := range cfg.Paths {
+				paths[endpoint] = path
+			}
+			extra["paths"] = paths
+		}
 	}
 
 	if len(extra) > 0 {
@@ -192,8 +293,24 @@ func (r *ModelRegistry) CreateProvider(cfg config.ProviderParams) (providers.Pro
 		return providers.NewVertexProvider(providerName, providerCfg)
 	case "openrouter":
 		return providers.NewOpenRouterProvider(providerName, providerCfg)
+	case "nim", "triton":
+		return providers.NewNvidiaProvider(providerName, providerCfg)
+	case "watsonx":
+		return providers.NewWatsonxProvider(providerName, providerCfg)
+	case "azure_mistral":
+		return providers.NewAzureMistralProvider(providerName, providerCfg)
 	case "cohere":
-		return nil, fmt.Errorf("cohere provider not implemented yet")
+		return providers.NewCohereProvider(providerName, providerCfg)
+	case "ollama":
+		return providers.NewOllamaProvider(providerName, providerCfg)
+	case "dashscope":
+		return providers.NewDashScopeProvider(providerName, providerCfg)
+	case "qianfan":
+		return providers.NewQianfanProvider(providerName, providerCfg)
+	case "perplexity":
+		return providers.NewPerplexityProvider(providerName, providerCfg)
+	case "custom-openai":
+		return providers.NewCustomOpenAIProvider(providerName, providerCfg)
 	case "huggingface":
 		return nil, fmt.Errorf("huggingface provider not implemented yet")
 	case "custom":
@@ -212,14 +329,16 @@ func (r *ModelRegistry) GetInstance(instanceID string) (*ModelInstance, bool) {
 	return instance, exists
 }
 
-// GetModelInstances returns all instances for a given model
+// GetModelInstances returns all instances for a given model. A
+// version-pinned name such as "gpt-4o@2024-08-06" returns only the instances
+// of the model serving that snapshot.
 func (r *ModelRegistry) GetModelInstances(modelName string) ([]*ModelInstance, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	instances, exists := r.modelMap[modelName]
 	if !exists {
-		return nil, false
+		return r.pinnedInstances(modelName)
 	}
 
 	// Return a copy to avoid concurrent access issues
@@ -228,6 +347,40 @@ func (r *ModelRegistry) GetModelInstances(modelName string) ([]*ModelInstance, b
 	return result, true
 }
 
+// pinnedInstances returns the instances serving the snapshot of a
+// version-pinned model name. The caller must hold the read lock.
+func (r *ModelRegistry) pinnedInstances(modelName string) ([]*ModelInstance, bool) {
+	name, version := coremodels.SplitModelVersion(modelName)
+	if version == "" {
+		return nil, false
+	}
+	var result []*ModelInstance
+	for _, instance := range r.modelMap[name] {
+		if instance.Config.SnapshotVersion() == version {
+			result = append(result, instance)
+		}
+	}
+	return result, len(result) > 0
+}
+
+// ModelVersions returns the snapshot versions served by a model's instances,
+// sorted. Instances with an unknown snapshot are not included.
+func (r *ModelRegistry) ModelVersions(modelName string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var versions []string
+	for _, instance := range r.modelMap[modelName] {
+		if version := instance.Config.SnapshotVersion(); version != "" && !seen[version] {
+			seen[version] = true
+			versions = append(versions, version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
 // GetAllInstances returns all registered instances
 func (r *ModelRegistry) GetAllInstances() []*ModelInstance {
 	r.mu.RLock()
@@ -288,6 +441,9 @@ func (r *ModelRegistry) AddInstance(cfg config.ModelInstance) error {
 	if _, exists := r.instances[cfg.ID]; exists {
 		return fmt.Errorf("instance with ID %s already exists", cfg.ID)
 	}
+	if _, exists := r.disabled[cfg.ID]; exists {
+		return fmt.Errorf("instance with ID %s already exists", cfg.ID)
+	}
 
 	if !cfg.Enabled {
 		return nil
@@ -298,20 +454,7 @@ func (r *ModelRegistry) AddInstance(cfg config.ModelInstance) error {
 		return fmt.Errorf("failed to create provider for instance %s: %w", cfg.ID, err)
 	}
 
-	instance := NewModelInstance(cfg, provider)
-	r.instances[cfg.ID] = instance
-
-	if r.modelMap[cfg.ModelName] == nil {
-		r.modelMap[cfg.ModelName] = make([]*ModelInstance, 0)
-		r.roundRobinCounters[cfg.ModelName] = &atomic.Uint64{}
-	}
-	r.modelMap[cfg.ModelName] = append(r.modelMap[cfg.ModelName], instance)
-
-	// Re-sort by priority
-	insts := r.modelMap[cfg.ModelName]
-	sort.Slice(insts, func(i, j int) bool {
-		return insts[i].Config.Priority > insts[j].Config.Priority
-	})
+	r.attach(NewModelInstance(cfg, provider))
 
 	r.logger.Info("Added instance",
 		zap.String("id", cfg.ID),
@@ -326,12 +469,96 @@ func (r *ModelRegistry) RemoveInstance(instanceID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if instance, disabled := r.disabled[instanceID]; disabled {
+		delete(r.disabled, instanceID)
+		r.logger.Info("Removed disabled instance",
+			zap.String("id", instanceID),
+			zap.String("model", instance.Config.ModelName))
+		return nil
+	}
+
+	instance, exists := r.instances[instanceID]
+	if !exists {
+		return fmt.Errorf("instance %s not found", instanceID)
+	}
+	r.detach(instance)
+
+	r.logger.Info("Removed instance",
+		zap.String("id", instanceID),
+		zap.String("model", instance.Config.ModelName))
+
+	return nil
+}
+
+// DisableInstance takes an instance out of routing without forgetting it, so
+// EnableInstance can put it back with its health and metrics. Thread-safe.
+func (r *ModelRegistry) DisableInstance(instanceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, disabled := r.disabled[instanceID]; disabled {
+		return nil
+	}
 	instance, exists := r.instances[instanceID]
 	if !exists {
 		return fmt.Errorf("instance %s not found", instanceID)
 	}
+	r.detach(instance)
+	r.disabled[instanceID] = instance
+
+	r.logger.Info("Disabled instance",
+		zap.String("id", instanceID),
+		zap.String("model", instance.Config.ModelName))
+
+	return nil
+}
+
+// EnableInstance returns an instance disabled with DisableInstance to
+// routing. Thread-safe.
+func (r *ModelRegistry) EnableInstance(instanceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.instances[instanceID]; exists {
+		return nil
+	}
+	instance, disabled := r.disabled[instanceID]
+	if !disabled {
+		return fmt.Errorf("instance %s not found", instanceID)
+	}
+	delete(r.disabled, instanceID)
+	r.attach(instance)
+
+	r.logger.Info("Enabled instance",
+		zap.String("id", instanceID),
+		zap.String("model", instance.Config.ModelName))
 
+	return nil
+}
+
+// attach adds an instance to routing, keeping its model's instances sorted
+// by priority. The caller must hold the write lock.
+func (r *ModelRegistry) attach(instance *ModelInstance) {
 	modelName := instance.Config.ModelName
+	r.instances[instance.Config.ID] = instance
+
+	if r.modelMap[modelName] == nil {
+		r.modelMap[modelName] = make([]*ModelInstance, 0)
+		r.roundRobinCounters[modelName] = &atomic.Uint64{}
+	}
+	r.modelMap[modelName] = append(r.modelMap[modelName], instance)
+
+	// Re-sort by priority
+	insts := r.modelMap[modelName]
+	sort.Slice(insts, func(i, j int) bool {
+		return insts[i].Config.Priority > insts[j].Config.Priority
+	})
+}
+
+// detach removes an instance from routing. The caller must hold the write
+// lock.
+func (r *ModelRegistry) detach(instance *ModelInstance) {
+	instanceID, modelName := instance.Config.ID, instance.Config.ModelName
 	delete(r.instances, instanceID)
 
 	// Remove from model map
@@ -349,12 +576,6 @@ func (r *ModelRegistry) RemoveInstance(instanceID string) error {
 			r.modelMap[modelName] = filtered
 		}
 	}
-
-	r.logger.Info("Removed instance",
-		zap.String("id", instanceID),
-		zap.String("model", modelName))
-
-	return nil
 }
 
 // UpdateInstance removes the old instance and adds the updated one. Thread-safe.
@@ -374,6 +595,9 @@ func (r *ModelRegistry) GetInstanceSource(instanceID string) string {
 	if instance, exists := r.instances[instanceID]; exists {
 		return instance.Config.Source
 	}
+	if instance, exists := r.disabled[instanceID]; exists {
+		return instance.Config.Source
+	}
 	return ""
 }
 
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func newPinnedTestManager(t *testing.T) *ModelManager {
+	t.Helper()
+	manager := NewModelManager(zap.NewNop(), config.RouterSettings{RoutingStrategy: "priority"}, nil)
+
+	add := func(id string, cfg config.ModelInstance) {
+		cfg.ID, cfg.ModelName, cfg.Enabled, cfg.Timeout = id, "gpt-4o", true, 5*time.Second
+		instance := &ModelInstance{Config: cfg, Provider: &MockFailingProvider{}}
+		instance.Healthy.Store(true)
+
+		manager.registry.mu.Lock()
+		manager.registry.attach(instance)
+		manager.registry.mu.Unlock()
+	}
+	add("gpt-4o-may", config.ModelInstance{Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o-2024-05-13"}})
+	add("gpt-4o-aug", config.ModelInstance{Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o-2024-08-06"}})
+	add("gpt-4o-azure", config.ModelInstance{Provider: config.ProviderParams{Type: "azure", Model: "prod", AzureBaseModel: "gpt-4o-2024-08-06"}})
+	add("gpt-4o-latest", config.ModelInstance{Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o"}})
+	add("gpt-4o-custom", config.ModelInstance{Version: "ft-1", Provider: config.ProviderParams{Type: "openai", Model: "ft:gpt-4o:acme"}})
+	return manager
+}
+
+func instanceIDs(instances []*ModelInstance) []string {
+	ids := make([]string, len(instances))
+	for i, instance := range instances {
+		ids[i] = instance.Config.ID
+	}
+	return ids
+}
+
+func TestModelRegistry_PinnedVersions(t *testing.T) {
+	registry := newPinnedTestManager(t).GetRegistry()
+
+	instances, found := registry.GetModelInstances("gpt-4o")
+	require.True(t, found)
+	assert.Len(t, instances, 5, "unpinned names route to every snapshot")
+
+	instances, found = registry.GetModelInstances("gpt-4o@2024-08-06")
+	require.True(t, found)
+	assert.ElementsMatch(t, []string{"gpt-4o-aug", "gpt-4o-azure"}, instanceIDs(instances))
+
+	instances, found = registry.GetModelInstances("gpt-4o@ft-1")
+	require.True(t, found)
+	assert.Equal(t, []string{"gpt-4o-custom"}, instanceIDs(instances))
+
+	_, found = registry.GetModelInstances("gpt-4o@2023-01-01")
+	assert.False(t, found, "snapshots nobody serves are not routable")
+	_, found = registry.GetModelInstances("claude@2024-08-06")
+	assert.False(t, found)
+
+	assert.Equal(t, []string{"2024-05-13", "2024-08-06", "ft-1"}, registry.ModelVersions("gpt-4o"))
+}
+
+func TestModelManager_PinnedVersionRouting(t *testing.T) {
+	manager := newPinnedTestManager(t)
+
+	for i := 0; i < 5; i++ {
+		instance, err := manager.GetBestInstance(context.Background(), "gpt-4o@2024-05-13")
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4o-may", instance.Config.ID)
+	}
+
+	manager.SetModelKillSwitch(func(model string) bool { return model == "gpt-4o" })
+	_, err := manager.GetBestInstance(context.Background(), "gpt-4o@2024-05-13")
+	assert.Error(t, err, "switching off a model switches off its pinned names")
+}
+
+func TestModelRegistry_DiscoveredModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"models": []map[string]string{{"name": "llama3.2:latest"}, {"name": "qwen2.5:7b"}},
+		})
+	}))
+	defer server.Close()
+
+	registry := NewModelRegistry(zap.NewNop())
+	require.NoError(t, registry.LoadModelInstances([]config.ModelInstance{
+		{ID: "local", ModelName: "local/*", Enabled: true, Provider: config.ProviderParams{Type: "ollama", Model: "*", BaseURL: server.URL}},
+		{ID: "unreachable", ModelName: "*", Enabled: true, Provider: config.ProviderParams{Type: "ollama", Model: "*", BaseURL: "http://127.0.0.1:1"}},
+		{ID: "mistral", ModelName: "mistral", Enabled: true, Provider: config.ProviderParams{Type: "ollama", Model: "mistral", BaseURL: server.URL}},
+	}))
+
+	assert.ElementsMatch(t, []string{"local/llama3.2", "local/qwen2.5:7b", "mistral"}, registry.GetAvailableModels())
+	instance, ok := registry.GetInstance("local/qwen2.5:7b")
+	require.True(t, ok)
+	assert.Equal(t, "qwen2.5:7b", instance.Config.Provider.Model)
+	assert.Equal(t, "local/qwen2.5:7b", instance.Config.ModelName)
+}
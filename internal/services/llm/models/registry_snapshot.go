@@ -0,0 +1,134 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// circuitStateNames names the values of ModelInstance.CircuitState
+var circuitStateNames = map[int32]string{
+	0: "closed",
+	1: "half-open",
+	2: "open",
+}
+
+// RegistryInstanceState is the in-memory state of a registered instance
+type RegistryInstanceState struct {
+	ID            string  `json:"id"`
+	ModelName     string  `json:"model_name"`
+	InstanceName  string  `json:"instance_name,omitempty"`
+	Version       string  `json:"version,omitempty"`
+	Source        string  `json:"source"`
+	ProviderType  string  `json:"provider_type"`
+	ProviderModel string  `json:"provider_model"`
+	Priority      int     `json:"priority"`
+	Weight        float64 `json:"weight"`
+	Enabled       bool    `json:"enabled"`
+	Draining      bool    `json:"draining"`
+
+	// Health
+	Healthy       bool       `json:"healthy"`
+	CircuitState  string     `json:"circuit_state"`
+	FailureCount  int32      `json:"failure_count"`
+	ConsecutiveOK int32      `json:"consecutive_ok"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailure   *time.Time `json:"last_failure,omitempty"`
+	LastSuccess   *time.Time `json:"last_success,omitempty"`
+
+	// Metrics counters
+	TotalRequests      int64 `json:"total_requests"`
+	TotalTokens        int64 `json:"total_tokens"`
+	AverageLatencyMs   int64 `json:"average_latency_ms"`
+	AverageTTFTMs      int64 `json:"average_ttft_ms"`
+	RequestsThisMinute int32 `json:"requests_this_minute"`
+	TokensThisMinute   int32 `json:"tokens_this_minute"`
+	InFlight           int64 `json:"in_flight"`
+}
+
+// RegistrySnapshot is the raw state of the model registry
+type RegistrySnapshot struct {
+	// Instances lists every registered instance, disabled ones included
+	Instances []RegistryInstanceState `json:"instances"`
+	// Models maps each routable model to its enabled instance IDs, in
+	// priority order
+	Models    map[string][]string `json:"models"`
+	Providers int                 `json:"providers"`
+}
+
+// Snapshot returns the registry's instances, models and instance metrics
+func (r *ModelRegistry) Snapshot() RegistrySnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := RegistrySnapshot{
+		Instances: make([]RegistryInstanceState, 0, len(r.instances)+len(r.disabled)),
+		Models:    make(map[string][]string, len(r.modelMap)),
+		Providers: len(r.providers),
+	}
+	for _, instance := range r.instances {
+		snapshot.Instances = append(snapshot.Instances, instanceState(instance, true))
+	}
+	for _, instance := range r.disabled {
+		snapshot.Instances = append(snapshot.Instances, instanceState(instance, false))
+	}
+	sort.Slice(snapshot.Instances, func(i, j int) bool {
+		a, b := snapshot.Instances[i], snapshot.Instances[j]
+		if a.ModelName != b.ModelName {
+			return a.ModelName < b.ModelName
+		}
+		return a.ID < b.ID
+	})
+
+	for modelName, instances := range r.modelMap {
+		ids := make([]string, len(instances))
+		for i, instance := range instances {
+			ids[i] = instance.Config.ID
+		}
+		snapshot.Models[modelName] = ids
+	}
+
+	return snapshot
+}
+
+func instanceState(instance *ModelInstance, enabled bool) RegistryInstanceState {
+	cfg := instance.Config
+	state := RegistryInstanceState{
+		ID:            cfg.ID,
+		ModelName:     cfg.ModelName,
+		InstanceName:  cfg.InstanceName,
+		Version:       cfg.SnapshotVersion(),
+		Source:        cfg.Source,
+		ProviderType:  cfg.Provider.Type,
+		ProviderModel: cfg.Provider.Model,
+		Priority:      cfg.Priority,
+		Weight:        cfg.Weight,
+		Enabled:       enabled,
+		Draining:      instance.IsDraining(),
+
+		Healthy:       instance.Healthy.Load(),
+		CircuitState:  circuitStateNames[instance.CircuitState.Load()],
+		FailureCount:  instance.FailureCount.Load(),
+		ConsecutiveOK: instance.ConsecutiveOK.Load(),
+
+		TotalRequests:      instance.TotalRequests.Load(),
+		TotalTokens:        instance.TotalTokens.Load(),
+		AverageLatencyMs:   instance.AverageLatency.Load(),
+		AverageTTFTMs:      instance.AverageTTFT.Load(),
+		RequestsThisMinute: instance.RequestsThisMinute.Load(),
+		TokensThisMinute:   instance.TokensThisMinute.Load(),
+		InFlight:           instance.InFlight.Load(),
+	}
+	if state.Source == "" {
+		state.Source = "system"
+	}
+	if err, ok := instance.LastError.Load().(error); ok && err != nil {
+		state.LastError = err.Error()
+	}
+	if t, ok := instance.LastFailure.Load().(time.Time); ok && !t.IsZero() {
+		state.LastFailure = &t
+	}
+	if t, ok := instance.LastSuccess.Load().(time.Time); ok && !t.IsZero() {
+		state.LastSuccess = &t
+	}
+	return state
+}
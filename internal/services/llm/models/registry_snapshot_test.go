@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelRegistry_DisableAndEnableInstance(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	registry := manager.GetRegistry()
+	instance, _ := registry.GetInstance("gpt-4-azure")
+	instance.TotalRequests.Store(42)
+
+	require.NoError(t, manager.DisableInstance("gpt-4-azure"))
+	require.NoError(t, manager.DisableInstance("gpt-4-azure"), "disabling twice is a no-op")
+	_, found := registry.GetInstance("gpt-4-azure")
+	assert.False(t, found)
+	instances, _ := registry.GetModelInstances("gpt-4")
+	require.Len(t, instances, 1)
+	assert.Equal(t, "gpt-4-openai", instances[0].Config.ID)
+	assert.Error(t, manager.AddInstance(instance.Config), "disabled instances keep their ID")
+
+	require.NoError(t, manager.DisableInstance("claude-anthropic"))
+	_, found = registry.GetModelInstances("claude")
+	assert.False(t, found, "models without enabled instances are not routable")
+
+	require.NoError(t, manager.EnableInstance("gpt-4-azure"))
+	require.NoError(t, manager.EnableInstance("gpt-4-azure"), "enabling twice is a no-op")
+	instances, _ = registry.GetModelInstances("gpt-4")
+	assert.Len(t, instances, 2)
+	restored, found := registry.GetInstance("gpt-4-azure")
+	require.True(t, found)
+	assert.Equal(t, int64(42), restored.TotalRequests.Load(), "metrics survive being disabled")
+
+	assert.Error(t, manager.EnableInstance("unknown"))
+	assert.Error(t, manager.DisableInstance("unknown"))
+
+	// Removing a disabled instance forgets it
+	require.NoError(t, manager.RemoveInstance("claude-anthropic"))
+	assert.Error(t, manager.EnableInstance("claude-anthropic"))
+}
+
+func TestModelRegistry_Snapshot(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	registry := manager.GetRegistry()
+	instance, _ := registry.GetInstance("gpt-4-openai")
+	instance.FailureCount.Store(3)
+	instance.CircuitState.Store(2)
+	instance.LastError.Store(errors.New("rate limited"))
+	require.NoError(t, manager.DisableInstance("mini-openai"))
+
+	snapshot := registry.Snapshot()
+	require.Len(t, snapshot.Instances, 4)
+	ids := make([]string, len(snapshot.Instances))
+	for i, state := range snapshot.Instances {
+		ids[i] = state.ID
+	}
+	assert.Equal(t, []string{"claude-anthropic", "gpt-4-azure", "gpt-4-openai", "mini-openai"}, ids)
+
+	openai := snapshot.Instances[2]
+	assert.Equal(t, "gpt-4", openai.ModelName)
+	assert.Equal(t, "system", openai.Source)
+	assert.Equal(t, "openai", openai.ProviderType)
+	assert.True(t, openai.Enabled)
+	assert.Equal(t, "open", openai.CircuitState)
+	assert.Equal(t, int32(3), openai.FailureCount)
+	assert.Equal(t, "rate limited", openai.LastError)
+	assert.Equal(t, int64(100), openai.AverageLatencyMs)
+
+	assert.False(t, snapshot.Instances[3].Enabled)
+	assert.Equal(t, []string{"gpt-4-openai", "gpt-4-azure"}, snapshot.Models["gpt-4"])
+	assert.NotContains(t, snapshot.Models, "mini")
+}
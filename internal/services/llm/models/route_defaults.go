@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// reasoningEfforts are the accepted reasoning_effort values
+var reasoningEfforts = map[string]bool{
+	"none":    true,
+	"minimal": true,
+	"low":     true,
+	"medium":  true,
+	"high":    true,
+}
+
+// ValidateRouteDefaults checks the parameter defaults of a route
+func ValidateRouteDefaults(defaults config.RouteDefaults) error {
+	if defaults.ReasoningEffort != "" && !reasoningEfforts[defaults.ReasoningEffort] {
+		return fmt.Errorf("invalid reasoning_effort %q: must be one of none, minimal, low, medium, high", defaults.ReasoningEffort)
+	}
+	if defaults.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must not be negative, got %d", defaults.MaxTokens)
+	}
+	if defaults.Temperature != nil && (*defaults.Temperature < 0 || *defaults.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %v", *defaults.Temperature)
+	}
+	return nil
+}
+
+// ApplyRouteDefaults sets the parameters the request leaves unset to the
+// defaults of the route it names. Requests for models are left unchanged.
+// Route defaults take precedence over a model's own reasoning_effort, which
+// is only applied to requests that still have none.
+func (m *ModelManager) ApplyRouteDefaults(request *providers.ChatRequest) {
+	route, isRoute := m.ResolveRoute(request.Model)
+	if !isRoute || route == nil {
+		return
+	}
+
+	defaults := route.Defaults
+	if request.ReasoningEffort == nil && defaults.ReasoningEffort != "" {
+		effort := defaults.ReasoningEffort
+		request.ReasoningEffort = &effort
+	}
+	if request.MaxTokens == nil && defaults.MaxTokens > 0 {
+		maxTokens := defaults.MaxTokens
+		request.MaxTokens = &maxTokens
+	}
+	if request.Temperature == nil && defaults.Temperature != nil {
+		temperature := float32(*defaults.Temperature)
+		request.Temperature = &temperature
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func TestApplyRouteDefaults(t *testing.T) {
+	manager := NewModelManager(zap.NewNop(), config.RouterSettings{RoutingStrategy: "priority"}, nil)
+	temperature := 0.2
+	manager.LoadRoutes([]config.RouteConfig{
+		{
+			Slug:     "deep-research",
+			Models:   []config.RouteModelConfig{{ModelName: "o3"}, {ModelName: "gpt-5"}},
+			Defaults: config.RouteDefaults{ReasoningEffort: "high", Temperature: &temperature},
+		},
+		{
+			Slug:     "fast",
+			Models:   []config.RouteModelConfig{{ModelName: "gpt-4o-mini"}},
+			Defaults: config.RouteDefaults{MaxTokens: 256},
+		},
+		{
+			Slug:     "broken",
+			Models:   []config.RouteModelConfig{{ModelName: "gpt-4o"}},
+			Defaults: config.RouteDefaults{ReasoningEffort: "extreme", MaxTokens: 100},
+		},
+	})
+
+	request := &providers.ChatRequest{Model: "deep-research"}
+	manager.ApplyRouteDefaults(request)
+	require.NotNil(t, request.ReasoningEffort)
+	assert.Equal(t, "high", *request.ReasoningEffort)
+	require.NotNil(t, request.Temperature)
+	assert.InDelta(t, 0.2, *request.Temperature, 1e-6)
+	assert.Nil(t, request.MaxTokens)
+
+	low, maxTokens := "low", 4096
+	request = &providers.ChatRequest{Model: "fast", ReasoningEffort: &low, MaxTokens: &maxTokens}
+	manager.ApplyRouteDefaults(request)
+	assert.Equal(t, "low", *request.ReasoningEffort)
+	assert.Equal(t, 4096, *request.MaxTokens, "parameters set by the client are kept")
+
+	request = &providers.ChatRequest{Model: "fast"}
+	manager.ApplyRouteDefaults(request)
+	require.NotNil(t, request.MaxTokens)
+	assert.Equal(t, 256, *request.MaxTokens)
+
+	request = &providers.ChatRequest{Model: "broken"}
+	manager.ApplyRouteDefaults(request)
+	assert.Nil(t, request.MaxTokens, "invalid defaults are ignored")
+
+	request = &providers.ChatRequest{Model: "gpt-4o-mini"}
+	manager.ApplyRouteDefaults(request)
+	assert.Equal(t, providers.ChatRequest{Model: "gpt-4o-mini"}, *request, "requests for models are unchanged")
+}
+
+func TestValidateRouteDefaults(t *testing.T) {
+	temperature, tooHot := 0.7, 2.5
+	assert.NoError(t, ValidateRouteDefaults(config.RouteDefaults{}))
+	assert.NoError(t, ValidateRouteDefaults(config.RouteDefaults{ReasoningEffort: "minimal", MaxTokens: 1024, Temperature: &temperature}))
+	assert.Error(t, ValidateRouteDefaults(config.RouteDefaults{ReasoningEffort: "max"}))
+	assert.Error(t, ValidateRouteDefaults(config.RouteDefaults{MaxTokens: -1}))
+	assert.Error(t, ValidateRouteDefaults(config.RouteDefaults{Temperature: &tooHot}))
+}
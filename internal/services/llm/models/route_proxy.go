@@ -14,6 +14,7 @@ type RouteModelProxy struct {
 	weight    float64
 	priority  int
 	latency   atomic.Int64 // populated from actual model instance metrics
+	ttft      atomic.Int64
 }
 
 // NewRouteModelProxy creates a new proxy for route-level model selection.
@@ -39,3 +40,8 @@ func (p *RouteModelProxy) GetConfig() config.ModelInstance {
 func (p *RouteModelProxy) GetAverageLatency() *atomic.Int64 {
 	return &p.latency
 }
+
+// GetAverageTTFT returns a pointer to the time to first token atomic value.
+func (p *RouteModelProxy) GetAverageTTFT() *atomic.Int64 {
+	return &p.ttft
+}
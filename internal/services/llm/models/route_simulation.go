@@ -0,0 +1,468 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/models/routing"
+)
+
+// Instance health states a simulation scenario can assign
+const (
+	SimulatedHealthy   = "healthy"
+	SimulatedDegraded  = "degraded" // healthy but deprioritized, as under a shift_routes incident
+	SimulatedUnhealthy = "unhealthy"
+)
+
+// Model statuses reported by a route simulation
+const (
+	SimulatedServing     = "serving"
+	SimulatedStandby     = "standby"
+	SimulatedUnavailable = "unavailable"
+	SimulatedNotFound    = "not_found"
+	SimulatedDisabled    = "disabled"
+)
+
+// HealthScenario assigns hypothetical health states to instances, keyed by
+// instance ID, model name or provider type. A more specific key wins, and
+// instances not covered keep their current state.
+type HealthScenario struct {
+	Instances map[string]string `json:"instances,omitempty"`
+	Models    map[string]string `json:"models,omitempty"`
+	Providers map[string]string `json:"providers,omitempty"`
+}
+
+// RouteSimulationResult compares how a route serves traffic now with how it
+// would under a health scenario
+type RouteSimulationResult struct {
+	Route     string           `json:"route"`
+	Strategy  string           `json:"strategy"`
+	Current   *RouteSimulation `json:"current"`
+	Simulated *RouteSimulation `json:"simulated"`
+	Changes   []string         `json:"changes"`
+}
+
+// RouteSimulation is the expected traffic split of a route. Latency and
+// costs are averages weighted by traffic share.
+type RouteSimulation struct {
+	Served               bool             `json:"served"`
+	FallbackTriggered    bool             `json:"fallback_triggered"`
+	Models               []SimulatedModel `json:"models"`
+	Fallbacks            []SimulatedModel `json:"fallbacks,omitempty"`
+	ExpectedLatencyMs    float64          `json:"expected_latency_ms"`
+	InputCostPerMillion  float64          `json:"input_cost_per_million"`
+	OutputCostPerMillion float64          `json:"output_cost_per_million"`
+}
+
+// SimulatedModel is a route model or fallback and its share of the route's
+// traffic. A fallback that is itself a route carries its own simulation.
+type SimulatedModel struct {
+	ModelName    string              `json:"model_name"`
+	Status       string              `json:"status"`
+	TrafficShare float64             `json:"traffic_share"`
+	Instances    []SimulatedInstance `json:"instances,omitempty"`
+	Route        *RouteSimulation    `json:"route,omitempty"`
+}
+
+// SimulatedInstance is a model instance and its share of the route's traffic
+type SimulatedInstance struct {
+	ID                   string  `json:"id"`
+	Provider             string  `json:"provider"`
+	State                string  `json:"state"`
+	Overridden           bool    `json:"overridden"`
+	TrafficShare         float64 `json:"traffic_share"`
+	AvgLatencyMs         int64   `json:"avg_latency_ms"`
+	InputCostPerMillion  float64 `json:"input_cost_per_million"`
+	OutputCostPerMillion float64 `json:"output_cost_per_million"`
+}
+
+// instanceStateFunc returns an instance's state and whether the scenario set it
+type instanceStateFunc func(instance *ModelInstance) (string, bool)
+
+// SimulateRoute reports which models and instances of the route would serve
+// traffic under the scenario, compared with the current health states. It
+// follows the routing of ExecuteWithFailover without sending requests or
+// advancing round-robin counters.
+func (m *ModelManager) SimulateRoute(ctx context.Context, slug string, scenario HealthScenario) (*RouteSimulationResult, error) {
+	route, ok := m.ResolveRoute(slug)
+	if !ok || route == nil {
+		return nil, fmt.Errorf("route %q not found", slug)
+	}
+	if err := m.validateScenario(scenario); err != nil {
+		return nil, err
+	}
+
+	current := m.simulateRoute(ctx, route, m.currentState, 1, map[string]bool{})
+	simulated := m.simulateRoute(ctx, route, m.scenarioState(scenario), 1, map[string]bool{})
+
+	return &RouteSimulationResult{
+		Route:     route.Slug,
+		Strategy:  route.Strategy.Name(),
+		Current:   current,
+		Simulated: simulated,
+		Changes:   simulationChanges(current, simulated),
+	}, nil
+}
+
+// validateScenario checks that every key names a registered instance, model
+// or provider type and every state is known
+func (m *ModelManager) validateScenario(scenario HealthScenario) error {
+	instances := make(map[string]bool)
+	models := make(map[string]bool)
+	providerTypes := make(map[string]bool)
+	for _, instance := range m.registry.GetAllInstances() {
+		instances[instance.Config.ID] = true
+		models[instance.Config.ModelName] = true
+		providerTypes[instance.Config.Provider.Type] = true
+	}
+
+	check := func(kind string, states map[string]string, known map[string]bool) error {
+		for key, state := range states {
+			if !known[key] {
+				return fmt.Errorf("unknown %s %q", kind, key)
+			}
+			switch state {
+			case SimulatedHealthy, SimulatedDegraded, SimulatedUnhealthy:
+			default:
+				return fmt.Errorf("invalid state %q for %s %q: must be healthy, degraded or unhealthy", state, kind, key)
+			}
+		}
+		return nil
+	}
+	if err := check("instance", scenario.Instances, instances); err != nil {
+		return err
+	}
+	if err := check("model", scenario.Models, models); err != nil {
+		return err
+	}
+	return check("provider", scenario.Providers, providerTypes)
+}
+
+// currentState maps the health tracker's view of an instance to a state
+func (m *ModelManager) currentState(instance *ModelInstance) (string, bool) {
+	switch {
+	case !m.healthTracker.IsHealthy(instance):
+		return SimulatedUnhealthy, false
+	case m.healthTracker.IsDeprioritized(instance):
+		return SimulatedDegraded, false
+	default:
+		return SimulatedHealthy, false
+	}
+}
+
+// scenarioState applies the scenario over the current states
+func (m *ModelManager) scenarioState(scenario HealthScenario) instanceStateFunc {
+	return func(instance *ModelInstance) (string, bool) {
+		if state, ok := scenario.Instances[instance.Config.ID]; ok {
+			return state, true
+		}
+		if state, ok := scenario.Models[instance.Config.ModelName]; ok {
+			return state, true
+		}
+		if state, ok := scenario.Providers[instance.Config.Provider.Type]; ok {
+			return state, true
+		}
+		return m.currentState(instance)
+	}
+}
+
+// simulateRoute splits share of the traffic over the route's models the way
+// executeRouteWithFailover selects them, then over its fallbacks when no
+// model is available. visited guards against fallback routes that loop.
+func (m *ModelManager) simulateRoute(ctx context.Context, route *RouteEntry, state instanceStateFunc, share float64, visited map[string]bool) *RouteSimulation {
+	visited[route.Slug] = true
+	sim := &RouteSimulation{Models: make([]SimulatedModel, 0, len(route.Models))}
+
+	var proxies, deprioritized []routing.ModelInstance
+	for _, rm := range route.Models {
+		model := m.simulateModel(rm.ModelName, state)
+		if !rm.Enabled {
+			model.Status = SimulatedDisabled
+		}
+		if model.Status == SimulatedStandby {
+			proxy := NewRouteModelProxy(rm.ModelName, float64(rm.Weight), rm.Priority)
+			if hasState(model.Instances, SimulatedHealthy) {
+				proxies = append(proxies, proxy)
+			} else {
+				deprioritized = append(deprioritized, proxy)
+			}
+		}
+		sim.Models = append(sim.Models, model)
+	}
+	if len(proxies) == 0 {
+		proxies = deprioritized
+	}
+
+	if len(proxies) > 0 {
+		shares := spreadShares(ctx, route.Strategy, proxies)
+		for i := range sim.Models {
+			if s, ok := shares[sim.Models[i].ModelName]; ok && s > 0 {
+				m.assignModelShare(ctx, &sim.Models[i], s*share)
+			}
+		}
+		sim.Served = true
+	} else {
+		sim.FallbackTriggered = len(route.FallbackModels) > 0
+		for _, name := range route.FallbackModels {
+			var fallback SimulatedModel
+			if fbRoute, isRoute := m.ResolveRoute(name); isRoute && fbRoute != nil && !visited[name] {
+				fallback = SimulatedModel{ModelName: name, Status: SimulatedStandby}
+				if !sim.Served {
+					fallback.Route = m.simulateRoute(ctx, fbRoute, state, share, visited)
+					if fallback.Route.Served {
+						fallback.Status = SimulatedServing
+						fallback.TrafficShare = share
+						sim.Served = true
+					} else {
+						fallback.Status = SimulatedUnavailable
+					}
+				}
+			} else {
+				fallback = m.simulateModel(name, state)
+				if fallback.Status == SimulatedStandby && !sim.Served {
+					m.assignModelShare(ctx, &fallback, share)
+					sim.Served = true
+				}
+			}
+			sim.Fallbacks = append(sim.Fallbacks, fallback)
+		}
+	}
+
+	sim.ExpectedLatencyMs, sim.InputCostPerMillion, sim.OutputCostPerMillion = simulationMix(sim, share)
+	return sim
+}
+
+// simulateModel reports a model's instances and whether any can serve
+func (m *ModelManager) simulateModel(modelName string, state instanceStateFunc) SimulatedModel {
+	model := SimulatedModel{ModelName: modelName, Status: SimulatedNotFound}
+	instances, exists := m.registry.GetModelInstances(modelName)
+	if !exists || len(instances) == 0 {
+		return model
+	}
+
+	model.Status = SimulatedUnavailable
+	for _, instance := range instances {
+		s, overridden := state(instance)
+		inputCost, outputCost := instanceCostPerToken(instance)
+		model.Instances = append(model.Instances, SimulatedInstance{
+			ID:                   instance.Config.ID,
+			Provider:             instance.Config.Provider.Type,
+			State:                s,
+			Overridden:           overridden,
+			AvgLatencyMs:         instance.AverageLatency.Load(),
+			InputCostPerMillion:  inputCost * 1e6,
+			OutputCostPerMillion: outputCost * 1e6,
+		})
+		if s != SimulatedUnhealthy {
+			model.Status = SimulatedStandby
+		}
+	}
+	return model
+}
+
+// assignModelShare marks the model as serving and splits its share over the
+// instances tryModelInstances would pick from
+func (m *ModelManager) assignModelShare(ctx context.Context, model *SimulatedModel, share float64) {
+	model.Status = SimulatedServing
+	model.TrafficShare = share
+
+	instances, _ := m.registry.GetModelInstances(model.ModelName)
+	byID := make(map[string]*ModelInstance, len(instances))
+	for _, instance := range instances {
+		byID[instance.Config.ID] = instance
+	}
+
+	var candidates, fallback []routing.ModelInstance
+	for _, si := range model.Instances {
+		instance, ok := byID[si.ID]
+		if !ok || si.State == SimulatedUnhealthy {
+			continue
+		}
+		fallback = append(fallback, instance)
+		if si.State == SimulatedHealthy {
+			candidates = append(candidates, instance)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = fallback
+	}
+
+	shares := m.instanceShares(ctx, candidates)
+	for i := range model.Instances {
+		model.Instances[i].TrafficShare = shares[model.Instances[i].ID] * share
+	}
+}
+
+// instanceShares returns each instance's share of a model's traffic under
+// the manager's routing strategy
+func (m *ModelManager) instanceShares(ctx context.Context, instances []routing.ModelInstance) map[string]float64 {
+	shares := make(map[string]float64, len(instances))
+	for i, s := range spreadSharesByIndex(ctx, m.routingStrategy, instances) {
+		shares[instances[i].GetConfig().ID] = s
+	}
+	return shares
+}
+
+// spreadShares returns each route model's share of the traffic. Weighted
+// round-robin and random spread it; the other strategies send it all to the
+// model they select.
+func spreadShares(ctx context.Context, strategy routing.Strategy, candidates []routing.ModelInstance) map[string]float64 {
+	shares := make(map[string]float64, len(candidates))
+	for i, s := range spreadSharesByIndex(ctx, strategy, candidates) {
+		shares[candidates[i].GetConfig().ModelName] += s
+	}
+	return shares
+}
+
+// spreadSharesByIndex splits a unit of traffic over the candidates as the
+// strategy would in the long run
+func spreadSharesByIndex(ctx context.Context, strategy routing.Strategy, candidates []routing.ModelInstance) []float64 {
+	shares := make([]float64, len(candidates))
+	if len(candidates) == 0 {
+		return shares
+	}
+
+	switch strategy.Name() {
+	case "weighted-round-robin":
+		var total float64
+		weights := make([]float64, len(candidates))
+		for i, c := range candidates {
+			w := c.GetConfig().Weight
+			if w <= 0 {
+				w = 1
+			}
+			weights[i] = w
+			total += w
+		}
+		for i, w := range weights {
+			shares[i] = w / total
+		}
+	case "random":
+		for i := range shares {
+			shares[i] = 1 / float64(len(candidates))
+		}
+	default:
+		selected, err := strategy.SelectInstance(ctx, candidates)
+		if err != nil || selected == nil {
+			shares[0] = 1
+			return shares
+		}
+		for i, c := range candidates {
+			if c == selected {
+				shares[i] = 1
+				return shares
+			}
+		}
+		shares[0] = 1
+	}
+	return shares
+}
+
+// instanceCostPerToken returns the instance's input and output price per
+// token, falling back to the pricing table
+func instanceCostPerToken(instance *ModelInstance) (float64, float64) {
+	input, output := instance.Config.InputCostPerToken, instance.Config.OutputCostPerToken
+	if input == 0 && output == 0 {
+		if pricing := config.GetPricingManager().GetPricing(instance.Config.ModelName); pricing != nil {
+			input, output = pricing.InputCostPerToken, pricing.OutputCostPerToken
+		}
+	}
+	return input, output
+}
+
+// simulationMix averages latency and costs over the serving instances,
+// weighted by their share of the route's traffic
+func simulationMix(sim *RouteSimulation, share float64) (latency, inputCost, outputCost float64) {
+	if share <= 0 {
+		return 0, 0, 0
+	}
+	add := func(models []SimulatedModel) {
+		for _, model := range models {
+			if model.Route != nil {
+				w := model.TrafficShare / share
+				latency += model.Route.ExpectedLatencyMs * w
+				inputCost += model.Route.InputCostPerMillion * w
+				outputCost += model.Route.OutputCostPerMillion * w
+				continue
+			}
+			for _, instance := range model.Instances {
+				w := instance.TrafficShare / share
+				latency += float64(instance.AvgLatencyMs) * w
+				inputCost += instance.InputCostPerMillion * w
+				outputCost += instance.OutputCostPerMillion * w
+			}
+		}
+	}
+	add(sim.Models)
+	add(sim.Fallbacks)
+	return latency, inputCost, outputCost
+}
+
+func hasState(instances []SimulatedInstance, state string) bool {
+	for _, instance := range instances {
+		if instance.State == state {
+			return true
+		}
+	}
+	return false
+}
+
+// simulationChanges describes how traffic would move between the current
+// and simulated runs
+func simulationChanges(current, simulated *RouteSimulation) []string {
+	changes := []string{}
+	if current.Served && !simulated.Served {
+		changes = append(changes, "route would fail: no model or fallback can serve traffic")
+	}
+	if !current.Served && simulated.Served {
+		changes = append(changes, "route would recover")
+	}
+	if !current.FallbackTriggered && simulated.FallbackTriggered {
+		changes = append(changes, "fallback models would be triggered")
+	}
+	if current.FallbackTriggered && !simulated.FallbackTriggered {
+		changes = append(changes, "fallback models would no longer be needed")
+	}
+
+	before, after := trafficShares(current), trafficShares(simulated)
+	names := make([]string, 0, len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if diff := after[name] - before[name]; diff > 0.0005 || diff < -0.0005 {
+			changes = append(changes, fmt.Sprintf("%s traffic %.1f%% -> %.1f%%", name, before[name]*100, after[name]*100))
+		}
+	}
+	return changes
+}
+
+// trafficShares flattens a simulation to instance ID traffic shares
+func trafficShares(sim *RouteSimulation) map[string]float64 {
+	shares := make(map[string]float64)
+	var walk func(sim *RouteSimulation)
+	walk = func(sim *RouteSimulation) {
+		for _, models := range [][]SimulatedModel{sim.Models, sim.Fallbacks} {
+			for _, model := range models {
+				if model.Route != nil {
+					walk(model.Route)
+				}
+				for _, instance := range model.Instances {
+					if instance.TrafficShare > 0 {
+						shares["instance "+instance.ID] += instance.TrafficShare
+					}
+				}
+			}
+		}
+	}
+	walk(sim)
+	return shares
+}
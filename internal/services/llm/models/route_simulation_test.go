@@ -0,0 +1,209 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func newSimulationTestManager(t *testing.T) *ModelManager {
+	t.Helper()
+	manager := NewModelManager(zap.NewNop(), config.RouterSettings{RoutingStrategy: "priority"}, nil)
+
+	add := func(id, modelName, providerType string, latencyMs int64, inputCost, outputCost float64) {
+		instance := &ModelInstance{
+			Config: config.ModelInstance{
+				ID:                 id,
+				ModelName:          modelName,
+				Enabled:            true,
+				Provider:           config.ProviderParams{Type: providerType, Model: modelName},
+				Timeout:            5 * time.Second,
+				InputCostPerToken:  inputCost,
+				OutputCostPerToken: outputCost,
+			},
+			Provider: &MockFailingProvider{},
+		}
+		instance.Healthy.Store(true)
+		instance.AverageLatency.Store(latencyMs)
+
+		manager.registry.mu.Lock()
+		manager.registry.instances[id] = instance
+		manager.registry.modelMap[modelName] = append(manager.registry.modelMap[modelName], instance)
+		manager.registry.mu.Unlock()
+	}
+	add("gpt-4-openai", "gpt-4", "openai", 100, 0.00003, 0.00006)
+	add("gpt-4-azure", "gpt-4", "azure", 200, 0.00003, 0.00006)
+	add("claude-anthropic", "claude", "anthropic", 300, 0.000003, 0.000015)
+	add("mini-openai", "mini", "openai", 50, 0.0000001, 0.0000004)
+
+	manager.RegisterRoute(&RouteEntry{
+		Slug: "smart",
+		Models: []RouteModelEntry{
+			{ModelName: "gpt-4", Weight: 70, Enabled: true},
+			{ModelName: "claude", Weight: 30, Enabled: true},
+		},
+		FallbackModels: []string{"mini"},
+	}, "weighted-round-robin")
+	manager.RegisterRoute(&RouteEntry{
+		Slug:           "outer",
+		Models:         []RouteModelEntry{{ModelName: "claude", Enabled: true}},
+		FallbackModels: []string{"smart"},
+	}, "priority")
+	return manager
+}
+
+func instanceShare(sim *RouteSimulation, id string) float64 {
+	return trafficShares(sim)["instance "+id]
+}
+
+func TestModelManager_SimulateRoute_CurrentState(t *testing.T) {
+	manager := newSimulationTestManager(t)
+
+	result, err := manager.SimulateRoute(context.Background(), "smart", HealthScenario{})
+	require.NoError(t, err)
+	assert.Equal(t, "smart", result.Route)
+	assert.Equal(t, "weighted-round-robin", result.Strategy)
+	assert.Equal(t, result.Current, result.Simulated)
+	assert.Empty(t, result.Changes)
+
+	sim := result.Simulated
+	assert.True(t, sim.Served)
+	assert.False(t, sim.FallbackTriggered)
+	assert.Empty(t, sim.Fallbacks)
+	require.Len(t, sim.Models, 2)
+	assert.Equal(t, SimulatedServing, sim.Models[0].Status)
+	assert.InDelta(t, 0.7, sim.Models[0].TrafficShare, 1e-9)
+	assert.InDelta(t, 0.7, instanceShare(sim, "gpt-4-openai"), 1e-9, "the priority strategy picks the first instance")
+	assert.Zero(t, instanceShare(sim, "gpt-4-azure"))
+	assert.InDelta(t, 0.3, instanceShare(sim, "claude-anthropic"), 1e-9)
+	assert.InDelta(t, 0.7*100+0.3*300, sim.ExpectedLatencyMs, 1e-9)
+	assert.InDelta(t, 0.7*30+0.3*3, sim.InputCostPerMillion, 1e-9)
+	assert.InDelta(t, 0.7*60+0.3*15, sim.OutputCostPerMillion, 1e-9)
+
+	// Simulation does not advance the route's round-robin counter
+	assert.Zero(t, manager.routes["smart"].rrCounter.Load())
+}
+
+func TestModelManager_SimulateRoute_ProviderOutage(t *testing.T) {
+	manager := newSimulationTestManager(t)
+
+	result, err := manager.SimulateRoute(context.Background(), "smart", HealthScenario{
+		Providers: map[string]string{"openai": SimulatedUnhealthy},
+	})
+	require.NoError(t, err)
+
+	sim := result.Simulated
+	assert.True(t, sim.Served)
+	assert.False(t, sim.FallbackTriggered)
+	assert.Zero(t, instanceShare(sim, "gpt-4-openai"))
+	assert.InDelta(t, 0.7, instanceShare(sim, "gpt-4-azure"), 1e-9)
+	assert.InDelta(t, 0.7*200+0.3*300, sim.ExpectedLatencyMs, 1e-9)
+
+	openai := sim.Models[0].Instances[0]
+	assert.Equal(t, "gpt-4-openai", openai.ID)
+	assert.Equal(t, SimulatedUnhealthy, openai.State)
+	assert.True(t, openai.Overridden)
+	assert.False(t, sim.Models[0].Instances[1].Overridden)
+
+	assert.Equal(t, []string{
+		"instance gpt-4-azure traffic 0.0% -> 70.0%",
+		"instance gpt-4-openai traffic 70.0% -> 0.0%",
+	}, result.Changes)
+}
+
+func TestModelManager_SimulateRoute_FallbackTriggered(t *testing.T) {
+	manager := newSimulationTestManager(t)
+
+	result, err := manager.SimulateRoute(context.Background(), "smart", HealthScenario{
+		Models: map[string]string{"gpt-4": SimulatedUnhealthy, "claude": SimulatedUnhealthy},
+		// The instance key is more specific than the provider key
+		Providers: map[string]string{"openai": SimulatedUnhealthy},
+		Instances: map[string]string{"mini-openai": SimulatedHealthy},
+	})
+	require.NoError(t, err)
+
+	sim := result.Simulated
+	assert.True(t, sim.Served)
+	assert.True(t, sim.FallbackTriggered)
+	assert.Equal(t, SimulatedUnavailable, sim.Models[0].Status)
+	require.Len(t, sim.Fallbacks, 1)
+	assert.Equal(t, SimulatedServing, sim.Fallbacks[0].Status)
+	assert.InDelta(t, 1, instanceShare(sim, "mini-openai"), 1e-9)
+	assert.InDelta(t, 50, sim.ExpectedLatencyMs, 1e-9)
+	assert.Contains(t, result.Changes, "fallback models would be triggered")
+
+	result, err = manager.SimulateRoute(context.Background(), "smart", HealthScenario{
+		Providers: map[string]string{"openai": SimulatedUnhealthy, "azure": SimulatedUnhealthy, "anthropic": SimulatedUnhealthy},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Simulated.Served)
+	assert.Equal(t, SimulatedUnavailable, result.Simulated.Fallbacks[0].Status)
+	assert.Zero(t, result.Simulated.ExpectedLatencyMs)
+	assert.Contains(t, result.Changes, "route would fail: no model or fallback can serve traffic")
+}
+
+func TestModelManager_SimulateRoute_Degraded(t *testing.T) {
+	manager := newSimulationTestManager(t)
+
+	// Degraded instances are avoided while the model has another
+	result, err := manager.SimulateRoute(context.Background(), "smart", HealthScenario{
+		Instances: map[string]string{"gpt-4-openai": SimulatedDegraded},
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.7, instanceShare(result.Simulated, "gpt-4-azure"), 1e-9)
+
+	// Models served only by degraded instances are used when no other is left
+	result, err = manager.SimulateRoute(context.Background(), "smart", HealthScenario{
+		Models: map[string]string{"claude": SimulatedDegraded},
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 1, instanceShare(result.Simulated, "gpt-4-openai"), 1e-9)
+	assert.Equal(t, SimulatedStandby, result.Simulated.Models[1].Status)
+
+	result, err = manager.SimulateRoute(context.Background(), "smart", HealthScenario{
+		Models: map[string]string{"claude": SimulatedDegraded, "gpt-4": SimulatedUnhealthy},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Simulated.FallbackTriggered)
+	assert.InDelta(t, 1, instanceShare(result.Simulated, "claude-anthropic"), 1e-9)
+}
+
+func TestModelManager_SimulateRoute_FallbackRoute(t *testing.T) {
+	manager := newSimulationTestManager(t)
+
+	result, err := manager.SimulateRoute(context.Background(), "outer", HealthScenario{
+		Providers: map[string]string{"anthropic": SimulatedUnhealthy},
+	})
+	require.NoError(t, err)
+
+	sim := result.Simulated
+	assert.True(t, sim.FallbackTriggered)
+	require.Len(t, sim.Fallbacks, 1)
+	require.NotNil(t, sim.Fallbacks[0].Route)
+	assert.Equal(t, SimulatedServing, sim.Fallbacks[0].Status)
+	assert.InDelta(t, 1, instanceShare(sim, "gpt-4-openai"), 1e-9, "the fallback route's models share the traffic")
+	assert.InDelta(t, 100, sim.ExpectedLatencyMs, 1e-9)
+}
+
+func TestModelManager_SimulateRoute_Validation(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	ctx := context.Background()
+
+	_, err := manager.SimulateRoute(ctx, "missing", HealthScenario{})
+	assert.EqualError(t, err, `route "missing" not found`)
+
+	_, err = manager.SimulateRoute(ctx, "smart", HealthScenario{Instances: map[string]string{"nope": SimulatedHealthy}})
+	assert.EqualError(t, err, `unknown instance "nope"`)
+
+	_, err = manager.SimulateRoute(ctx, "smart", HealthScenario{Providers: map[string]string{"vertex": SimulatedHealthy}})
+	assert.EqualError(t, err, `unknown provider "vertex"`)
+
+	_, err = manager.SimulateRoute(ctx, "smart", HealthScenario{Models: map[string]string{"gpt-4": "down"}})
+	assert.EqualError(t, err, `invalid state "down" for model "gpt-4": must be healthy, degraded or unhealthy`)
+}
@@ -2,6 +2,8 @@ package routing
 
 import (
 	"context"
+	"math"
+	"sync/atomic"
 	"time"
 
 	redisService "github.com/amerfu/pllm/internal/services/data/redis"
@@ -13,19 +15,50 @@ import (
 type LatencyStrategy struct {
 	latencyTracker *redisService.LatencyTracker
 	logger         *zap.Logger
+
+	// The latency measure the strategy minimizes
+	name        string
+	distributed func(ctx context.Context, modelName string) (redisService.LatencyEstimate, error)
+	local       func(instance ModelInstance) *atomic.Int64
+
+	// fallback selects when no instance has data for the measure
+	fallback Strategy
 }
 
 // NewLatencyStrategy creates a new latency-based routing strategy
 func NewLatencyStrategy(tracker *redisService.LatencyTracker, logger *zap.Logger) *LatencyStrategy {
-	return &LatencyStrategy{
+	s := &LatencyStrategy{
+		latencyTracker: tracker,
+		logger:         logger,
+		name:           "least-latency",
+		local:          ModelInstance.GetAverageLatency,
+	}
+	if tracker != nil {
+		s.distributed = tracker.GetLatencyEstimate
+	}
+	return s
+}
+
+// NewTTFTStrategy creates a latency strategy variant that minimizes time to
+// first token, for interactive streaming workloads. Until streaming requests
+// have been recorded it selects by total latency.
+func NewTTFTStrategy(tracker *redisService.LatencyTracker, logger *zap.Logger) *LatencyStrategy {
+	s := &LatencyStrategy{
 		latencyTracker: tracker,
 		logger:         logger,
+		name:           "least-ttft",
+		local:          ModelInstance.GetAverageTTFT,
+		fallback:       NewLatencyStrategy(tracker, logger),
 	}
+	if tracker != nil {
+		s.distributed = tracker.GetTTFTEstimate
+	}
+	return s
 }
 
 // Name returns the strategy name
 func (s *LatencyStrategy) Name() string {
-	return "least-latency"
+	return s.name
 }
 
 // SelectInstance selects the instance with the lowest average latency
@@ -49,57 +82,120 @@ func (s *LatencyStrategy) selectUsingDistributedLatency(ctx context.Context, ins
 	queryCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
 	defer cancel()
 
-	var bestInstance ModelInstance
-	var bestLatency time.Duration
-
-	for _, instance := range instances {
+	estimates := make([]redisService.LatencyEstimate, len(instances))
+	for i, instance := range instances {
 		config := instance.GetConfig()
-		
+
 		// Get distributed latency from Redis
-		latency, err := s.latencyTracker.GetAverageLatency(queryCtx, config.ModelName)
+		estimate, err := s.distributed(queryCtx, config.ModelName)
 		if err != nil {
 			// Fallback to in-memory if Redis fails for this instance
 			s.logger.Debug("Failed to get distributed latency, using in-memory",
 				zap.String("model", config.ModelName),
 				zap.Error(err))
-			latency = time.Duration(instance.GetAverageLatency().Load()) * time.Millisecond
+			estimate = s.localEstimate(instance)
 		}
+		estimates[i] = estimate
+	}
 
-		// Select instance with lowest latency
-		if bestInstance == nil || (latency > 0 && (bestLatency == 0 || latency < bestLatency)) {
-			bestInstance = instance
-			bestLatency = latency
-		}
+	bestInstance, bestLatency := selectByEstimate(instances, estimates, s.latencyTracker.WarmupSamples())
+	if bestLatency == 0 && s.fallback != nil {
+		return s.fallback.SelectInstance(ctx, instances)
+	}
+
+	config := bestInstance.GetConfig()
+	s.logger.Debug("Selected instance by distributed latency",
+		zap.String("strategy", s.name),
+		zap.String("instance_id", config.ID),
+		zap.Duration("latency", bestLatency))
+	return bestInstance, nil
+}
+
+// localEstimate returns the in-memory average of an instance. In-memory
+// averages have no sample count and are trusted as warmed up.
+func (s *LatencyStrategy) localEstimate(instance ModelInstance) redisService.LatencyEstimate {
+	latencyMs := s.local(instance).Load()
+	if latencyMs == 0 {
+		return redisService.LatencyEstimate{}
 	}
+	return redisService.LatencyEstimate{
+		Average: time.Duration(latencyMs) * time.Millisecond,
+		Samples: math.Inf(1),
+	}
+}
 
-	if bestInstance != nil {
-		config := bestInstance.GetConfig()
-		s.logger.Debug("Selected instance by distributed latency",
-			zap.String("instance_id", config.ID),
-			zap.Duration("latency", bestLatency))
-		return bestInstance, nil
+// selectByEstimate returns the instance with the lowest latency score and
+// that score, or the first instance and zero when none has data.
+//
+// Instances with fewer than warmupSamples effective samples are scored
+// between their own average and the best warmed-up average (the best
+// average of all before any is warmed up), weighted by their samples. An
+// instance without data thus ties with the best and, having fewer samples,
+// is tried rather than avoided, while one lucky fast sample only moves its
+// score a fraction of the way.
+func selectByEstimate(instances []ModelInstance, estimates []redisService.LatencyEstimate, warmupSamples int) (ModelInstance, time.Duration) {
+	warmup := float64(warmupSamples)
+
+	var bestWarm, bestAny time.Duration
+	for _, estimate := range estimates {
+		if estimate.Average <= 0 || estimate.Samples <= 0 {
+			continue
+		}
+		if bestAny == 0 || estimate.Average < bestAny {
+			bestAny = estimate.Average
+		}
+		if estimate.Samples >= warmup && (bestWarm == 0 || estimate.Average < bestWarm) {
+			bestWarm = estimate.Average
+		}
+	}
+	prior := bestWarm
+	if prior == 0 {
+		prior = bestAny
 	}
 
-	// If Redis failed for all instances, fallback to in-memory
-	s.logger.Warn("Distributed latency unavailable for all instances, falling back to in-memory")
-	return s.selectUsingInMemoryLatency(instances)
+	bestInstance, bestScore, bestSamples := instances[0], time.Duration(0), 0.0
+	for i, instance := range instances {
+		estimate := estimates[i]
+		if estimate.Average <= 0 {
+			estimate.Samples = 0
+		}
+
+		score := estimate.Average
+		if estimate.Samples < warmup {
+			score = time.Duration((estimate.Samples*float64(estimate.Average) +
+				(warmup-estimate.Samples)*float64(prior)) / warmup)
+		}
+		if score <= 0 {
+			continue
+		}
+
+		if bestScore == 0 || score < bestScore || (score == bestScore && estimate.Samples < bestSamples) {
+			bestInstance, bestScore, bestSamples = instance, score, estimate.Samples
+		}
+	}
+	return bestInstance, bestScore
 }
 
 // selectUsingInMemoryLatency uses local in-memory latency metrics
 func (s *LatencyStrategy) selectUsingInMemoryLatency(instances []ModelInstance) (ModelInstance, error) {
 	bestInstance := instances[0]
-	bestLatency := bestInstance.GetAverageLatency().Load()
+	bestLatency := s.local(bestInstance).Load()
 
 	for _, instance := range instances[1:] {
-		latency := instance.GetAverageLatency().Load()
+		latency := s.local(instance).Load()
 		if latency > 0 && (bestLatency == 0 || latency < bestLatency) {
 			bestInstance = instance
 			bestLatency = latency
 		}
 	}
 
+	if bestLatency == 0 && s.fallback != nil {
+		return s.fallback.SelectInstance(context.Background(), instances)
+	}
+
 	config := bestInstance.GetConfig()
 	s.logger.Debug("Selected instance by in-memory latency",
+		zap.String("strategy", s.name),
 		zap.String("instance_id", config.ID),
 		zap.Int64("latency_ms", bestLatency))
 
@@ -0,0 +1,147 @@
+package routing
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+type fakeInstance struct {
+	cfg     config.ModelInstance
+	latency atomic.Int64
+	ttft    atomic.Int64
+}
+
+func newFakeInstance(modelName string, latencyMs, ttftMs int64) *fakeInstance {
+	instance := &fakeInstance{cfg: config.ModelInstance{ID: modelName, ModelName: modelName}}
+	instance.latency.Store(latencyMs)
+	instance.ttft.Store(ttftMs)
+	return instance
+}
+
+func (f *fakeInstance) GetConfig() config.ModelInstance  { return f.cfg }
+func (f *fakeInstance) GetAverageLatency() *atomic.Int64 { return &f.latency }
+func (f *fakeInstance) GetAverageTTFT() *atomic.Int64    { return &f.ttft }
+
+func TestTTFTStrategy_InMemory(t *testing.T) {
+	strategy := NewTTFTStrategy(nil, zap.NewNop())
+	assert.Equal(t, "least-ttft", strategy.Name())
+
+	// The model with the faster first token wins over the faster total
+	slowStart := newFakeInstance("slow-start", 800, 600)
+	fastStart := newFakeInstance("fast-start", 2000, 150)
+	selected, err := strategy.SelectInstance(context.Background(), []ModelInstance{slowStart, fastStart})
+	require.NoError(t, err)
+	assert.Equal(t, "fast-start", selected.GetConfig().ID)
+
+	// Without streaming data, total latency decides
+	a := newFakeInstance("a", 900, 0)
+	b := newFakeInstance("b", 300, 0)
+	selected, err = strategy.SelectInstance(context.Background(), []ModelInstance{a, b})
+	require.NoError(t, err)
+	assert.Equal(t, "b", selected.GetConfig().ID)
+}
+
+func TestTTFTStrategy_Distributed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	tracker := redisService.NewLatencyTracker(client, zap.NewNop())
+	ctx := context.Background()
+	require.NoError(t, tracker.RecordTTFT(ctx, "model-a", 500*time.Millisecond))
+	require.NoError(t, tracker.RecordTTFT(ctx, "model-b", 100*time.Millisecond))
+	require.NoError(t, tracker.RecordLatency(ctx, "model-a", 1*time.Second))
+	require.NoError(t, tracker.RecordLatency(ctx, "model-b", 3*time.Second))
+
+	// Give time for async moving average update
+	time.Sleep(50 * time.Millisecond)
+
+	instances := []ModelInstance{newFakeInstance("model-a", 0, 0), newFakeInstance("model-b", 0, 0)}
+
+	selected, err := NewTTFTStrategy(tracker, zap.NewNop()).SelectInstance(ctx, instances)
+	require.NoError(t, err)
+	assert.Equal(t, "model-b", selected.GetConfig().ID)
+
+	selected, err = NewLatencyStrategy(tracker, zap.NewNop()).SelectInstance(ctx, instances)
+	require.NoError(t, err)
+	assert.Equal(t, "model-a", selected.GetConfig().ID)
+}
+
+func TestLatencyStrategy_WarmUp(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	tracker := redisService.NewLatencyTracker(client, zap.NewNop())
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, tracker.RecordLatency(ctx, "proven", 300*time.Millisecond))
+	}
+
+	strategy := NewLatencyStrategy(tracker, zap.NewNop())
+	instances := []ModelInstance{newFakeInstance("proven", 0, 0), newFakeInstance("new", 0, 0)}
+	selectInstance := func() string {
+		t.Helper()
+		selected, err := strategy.SelectInstance(ctx, instances)
+		require.NoError(t, err)
+		return selected.GetConfig().ID
+	}
+
+	// An instance without data is tried rather than avoided
+	assert.Equal(t, "new", selectInstance())
+
+	// One lucky sample only moves it part of the way: a slow one follows
+	// and the proven instance is preferred again
+	require.NoError(t, tracker.RecordLatency(ctx, "new", 50*time.Millisecond))
+	assert.Equal(t, "new", selectInstance())
+	require.NoError(t, tracker.RecordLatency(ctx, "new", 900*time.Millisecond))
+	assert.Equal(t, "proven", selectInstance())
+
+	// A faster instance wins once warmed up
+	for i := 0; i < 5; i++ {
+		require.NoError(t, tracker.RecordLatency(ctx, "new", 100*time.Millisecond))
+	}
+	assert.Equal(t, "new", selectInstance())
+
+	// Without warm-up, instances without data are avoided
+	tracker.SetDecay(0, 0)
+	instances = []ModelInstance{newFakeInstance("proven", 0, 0), newFakeInstance("unseen", 0, 0)}
+	assert.Equal(t, "proven", selectInstance())
+}
+
+func TestSelectByEstimate(t *testing.T) {
+	a, b := newFakeInstance("a", 0, 0), newFakeInstance("b", 0, 0)
+	instances := []ModelInstance{a, b}
+
+	// Before any instance is warmed up, scores lean toward the best average
+	selected, score := selectByEstimate(instances, []redisService.LatencyEstimate{
+		{Average: 400 * time.Millisecond, Samples: 2},
+		{Average: 200 * time.Millisecond, Samples: 1},
+	}, 4)
+	assert.Equal(t, "b", selected.GetConfig().ID)
+	assert.Equal(t, 200*time.Millisecond, score)
+
+	// No data at all leaves the first instance with a zero score
+	selected, score = selectByEstimate(instances, make([]redisService.LatencyEstimate, 2), 4)
+	assert.Equal(t, "a", selected.GetConfig().ID)
+	assert.Zero(t, score)
+}
+
+func TestValidateStrategy_LeastTTFT(t *testing.T) {
+	assert.NoError(t, ValidateStrategy("least-ttft"))
+
+	strategy, err := NewStrategy("least-ttft", StrategyDependencies{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	assert.Equal(t, "priority", strategy.Name())
+}
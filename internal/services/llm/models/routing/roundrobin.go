@@ -2,12 +2,18 @@ package routing
 
 import (
 	"context"
+	"math"
 
 	"go.uber.org/zap"
 )
 
-// RoundRobinStrategy distributes requests evenly across instances
-// Currently implements simple round-robin (weights not yet implemented)
+// minLoadFactor keeps a share of traffic on saturated instances, so their
+// load keeps being observed
+const minLoadFactor = 0.1
+
+// RoundRobinStrategy distributes requests across instances in proportion to
+// their weights. Instances that report their load (such as GPU utilization)
+// get their weight scaled down as they get busier.
 type RoundRobinStrategy struct {
 	registry ModelRegistry
 	logger   *zap.Logger
@@ -26,8 +32,7 @@ func (s *RoundRobinStrategy) Name() string {
 	return "weighted-round-robin"
 }
 
-// SelectInstance selects the next instance using round-robin
-// TODO: Implement weight support (currently ignores weights)
+// SelectInstance selects the next instance by weighted round-robin
 func (s *RoundRobinStrategy) SelectInstance(ctx context.Context, instances []ModelInstance) (ModelInstance, error) {
 	if len(instances) == 0 {
 		return nil, nil
@@ -45,15 +50,66 @@ func (s *RoundRobinStrategy) SelectInstance(ctx context.Context, instances []Mod
 		return instances[0], nil
 	}
 
-	// Simple round-robin: increment and modulo by instance count
-	index := counter.Add(1) % uint64(len(instances))
+	c := counter.Add(1)
+	weights, uniform := effectiveWeights(instances)
+
+	// Equal weights: plain round-robin
+	index := int(c % uint64(len(instances)))
+	if !uniform {
+		index = weightedIndex(weights, c)
+	}
 	selected := instances[index]
 	config := selected.GetConfig()
 
 	s.logger.Debug("Selected instance by round-robin",
 		zap.String("instance_id", config.ID),
-		zap.Uint64("counter", counter.Load()),
-		zap.Int("index", int(index)))
+		zap.Uint64("counter", c),
+		zap.Int("index", index),
+		zap.Float64("weight", weights[index]))
 
 	return selected, nil
 }
+
+// effectiveWeights returns the configured weights (1 when unset) scaled by
+// the free capacity of instances that report their load, and whether they
+// are all equal
+func effectiveWeights(instances []ModelInstance) ([]float64, bool) {
+	weights := make([]float64, len(instances))
+	uniform := true
+	for i, instance := range instances {
+		weight := instance.GetConfig().Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		if reporter, ok := instance.(LoadAware); ok {
+			if load, ok := reporter.GetLoad(); ok {
+				weight *= math.Max(1-load, minLoadFactor)
+			}
+		}
+		weights[i] = weight
+		if weight != weights[0] {
+			uniform = false
+		}
+	}
+	return weights, uniform
+}
+
+// weightedIndex maps the counter to an instance so that selections spread
+// evenly in proportion to the weights, even as weights change between
+// requests: the counter walks the weight range in golden-ratio steps.
+func weightedIndex(weights []float64, c uint64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	_, position := math.Modf(float64(c) * (math.Sqrt(5) - 1) / 2)
+	position *= total
+
+	for i, w := range weights {
+		if position < w {
+			return i
+		}
+		position -= w
+	}
+	return len(weights) - 1
+}
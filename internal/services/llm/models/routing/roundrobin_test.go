@@ -0,0 +1,65 @@
+package routing
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type counterRegistry struct {
+	counter atomic.Uint64
+}
+
+func (r *counterRegistry) GetRoundRobinCounter(modelName string) *atomic.Uint64 {
+	return &r.counter
+}
+
+type loadedInstance struct {
+	*fakeInstance
+	load float64
+}
+
+func (l *loadedInstance) GetLoad() (float64, bool) { return l.load, true }
+
+func selections(t *testing.T, strategy *RoundRobinStrategy, instances []ModelInstance, n int) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		selected, err := strategy.SelectInstance(context.Background(), instances)
+		require.NoError(t, err)
+		counts[selected.GetConfig().ID]++
+	}
+	return counts
+}
+
+func TestRoundRobinStrategy_Weights(t *testing.T) {
+	strategy := NewRoundRobinStrategy(&counterRegistry{}, zap.NewNop())
+
+	// Equal weights alternate exactly
+	a, b := newFakeInstance("a", 0, 0), newFakeInstance("b", 0, 0)
+	assert.Equal(t, map[string]int{"a": 50, "b": 50}, selections(t, strategy, []ModelInstance{a, b}, 100))
+
+	// Weights 3:1
+	a.cfg.Weight = 3
+	counts := selections(t, strategy, []ModelInstance{a, b}, 1000)
+	assert.InDelta(t, 750, counts["a"], 10)
+}
+
+func TestRoundRobinStrategy_GPULoad(t *testing.T) {
+	strategy := NewRoundRobinStrategy(&counterRegistry{}, zap.NewNop())
+
+	// An idle server gets more traffic than one at 80% utilization
+	idle := &loadedInstance{fakeInstance: newFakeInstance("idle", 0, 0), load: 0}
+	busy := &loadedInstance{fakeInstance: newFakeInstance("busy", 0, 0), load: 0.8}
+	counts := selections(t, strategy, []ModelInstance{idle, busy}, 1200)
+	assert.InDelta(t, 1000, counts["idle"], 10)
+
+	// A saturated server keeps a share of traffic
+	busy.load = 1
+	counts = selections(t, strategy, []ModelInstance{idle, busy}, 1100)
+	assert.InDelta(t, 100, counts["busy"], 10)
+}
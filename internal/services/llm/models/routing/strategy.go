@@ -43,6 +43,13 @@ func NewStrategy(name string, deps StrategyDependencies) (Strategy, error) {
 		}
 		return NewLatencyStrategy(deps.LatencyTracker, deps.Logger), nil
 
+	case "least-ttft":
+		if deps.LatencyTracker == nil {
+			deps.Logger.Warn("LatencyTracker not available, falling back to priority strategy")
+			return NewPriorityStrategy(deps.Logger), nil
+		}
+		return NewTTFTStrategy(deps.LatencyTracker, deps.Logger), nil
+
 	case "weighted-round-robin":
 		if deps.Registry == nil {
 			deps.Logger.Warn("Registry not available, falling back to priority strategy")
@@ -61,7 +68,7 @@ func NewStrategy(name string, deps StrategyDependencies) (Strategy, error) {
 
 // ValidateStrategy checks if a strategy name is valid
 func ValidateStrategy(name string) error {
-	validStrategies := []string{"priority", "least-latency", "weighted-round-robin", "random"}
+	validStrategies := []string{"priority", "least-latency", "least-ttft", "weighted-round-robin", "random"}
 	for _, valid := range validStrategies {
 		if name == valid {
 			return nil
@@ -11,4 +11,12 @@ import (
 type ModelInstance interface {
 	GetConfig() config.ModelInstance
 	GetAverageLatency() *atomic.Int64
+	GetAverageTTFT() *atomic.Int64
+}
+
+// LoadAware is implemented by instances that know how busy their server is.
+// GetLoad returns a load between 0 (idle) and 1 (saturated), or false when
+// it is unknown.
+type LoadAware interface {
+	GetLoad() (float64, bool)
 }
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// scalingWindow is how far back scaling signals look
+	scalingWindow = time.Minute
+	// scalingSampleInterval is the minimum time between token counter samples
+	scalingSampleInterval = 5 * time.Second
+)
+
+// ScalingSignal is the pressure the gateway puts on a model, for autoscaling
+// the deployments serving it (KEDA, HPA)
+type ScalingSignal struct {
+	Model string `json:"model"`
+	// Requests in flight for the model, including streams still running
+	InFlight int64 `json:"in_flight"`
+	// Average time streaming requests waited for their first token over the
+	// last minute. pllm does not queue requests, so this is the wait in the
+	// backend's queue plus prefill.
+	QueueWaitSeconds float64 `json:"queue_wait_seconds"`
+	// Tokens served over the last minute
+	TokensPerMinute float64 `json:"tokens_per_minute"`
+	// Sum of the TPM limits of the model's enabled instances; 0 when any of
+	// them is unlimited
+	TPMLimit int64 `json:"tpm_limit"`
+	// TokensPerMinute over TPMLimit; 0 without a limit
+	TPMUtilization   float64 `json:"tpm_utilization"`
+	HealthyInstances int     `json:"healthy_instances"`
+}
+
+// scalingTracker keeps the per-model request counts and waits behind the
+// scaling signals, and samples of the instances' token counters to turn
+// them into a rate
+type scalingTracker struct {
+	mu     sync.Mutex
+	models map[string]*modelPressure
+	tokens map[string][]tokenSample // key: instance ID
+	now    func() time.Time
+}
+
+type modelPressure struct {
+	inFlight int64
+	waits    []waitSample
+}
+
+type waitSample struct {
+	at   time.Time
+	wait time.Duration
+}
+
+type tokenSample struct {
+	at    time.Time
+	total int64
+}
+
+func newScalingTracker() *scalingTracker {
+	return &scalingTracker{
+		models: make(map[string]*modelPressure),
+		tokens: make(map[string][]tokenSample),
+		now:    time.Now,
+	}
+}
+
+func (t *scalingTracker) pressure(model string) *modelPressure {
+	p, ok := t.models[model]
+	if !ok {
+		p = &modelPressure{}
+		t.models[model] = p
+	}
+	return p
+}
+
+// pruneWaits drops the waits older than the window
+func (p *modelPressure) pruneWaits(now time.Time) {
+	cutoff := now.Add(-scalingWindow)
+	i := 0
+	for i < len(p.waits) && p.waits[i].at.Before(cutoff) {
+		i++
+	}
+	p.waits = p.waits[i:]
+}
+
+// tokenRate samples an instance's token counter and returns its tokens per
+// minute since the newest sample at least a window old (or the oldest one
+// while the window fills)
+func (t *scalingTracker) tokenRate(instance *ModelInstance, now time.Time) float64 {
+	total := instance.TotalTokens.Load()
+	samples := t.tokens[instance.Config.ID]
+	if len(samples) == 0 || now.Sub(samples[len(samples)-1].at) >= scalingSampleInterval {
+		samples = append(samples, tokenSample{at: now, total: total})
+	}
+
+	cutoff := now.Add(-scalingWindow)
+	i := 0
+	for i+1 < len(samples) && !samples[i+1].at.After(cutoff) {
+		i++
+	}
+	samples = samples[i:]
+	t.tokens[instance.Config.ID] = samples
+
+	elapsed := now.Sub(samples[0].at)
+	if elapsed < scalingSampleInterval || total < samples[0].total {
+		return 0
+	}
+	return float64(total-samples[0].total) / elapsed.Minutes()
+}
+
+// RequestStarted counts a request for the model as in flight
+func (m *ModelManager) RequestStarted(model string) {
+	m.scaling.mu.Lock()
+	defer m.scaling.mu.Unlock()
+	m.scaling.pressure(model).inFlight++
+}
+
+// RequestFirstToken records how long a streaming request for the model
+// waited for its first token
+func (m *ModelManager) RequestFirstToken(model string, wait time.Duration) {
+	m.scaling.mu.Lock()
+	defer m.scaling.mu.Unlock()
+	now := m.scaling.now()
+	p := m.scaling.pressure(model)
+	p.pruneWaits(now)
+	p.waits = append(p.waits, waitSample{at: now, wait: wait})
+}
+
+// RequestFinished stops counting a request for the model as in flight
+func (m *ModelManager) RequestFinished(model string) {
+	m.scaling.mu.Lock()
+	defer m.scaling.mu.Unlock()
+	if p, ok := m.scaling.models[model]; ok && p.inFlight > 0 {
+		p.inFlight--
+	}
+}
+
+// ScalingSignals returns the scaling signals of the registered models, and
+// of any other requested model (such as a route) with recent traffic, sorted
+// by model name. In-flight counts and waits are kept under the model name
+// clients asked for; token rates are those of the instances that served them.
+func (m *ModelManager) ScalingSignals() []ScalingSignal {
+	m.scaling.mu.Lock()
+	defer m.scaling.mu.Unlock()
+	now := m.scaling.now()
+
+	signals := make(map[string]*ScalingSignal)
+	signal := func(model string) *ScalingSignal {
+		s, ok := signals[model]
+		if !ok {
+			s = &ScalingSignal{Model: model}
+			signals[model] = s
+		}
+		return s
+	}
+
+	seen := make(map[string]bool)
+	for _, model := range m.registry.GetAvailableModels() {
+		s := signal(model)
+		instances, _ := m.registry.GetModelInstances(model)
+		unlimited := false
+		for _, instance := range instances {
+			seen[instance.Config.ID] = true
+			s.TokensPerMinute += m.scaling.tokenRate(instance, now)
+			if !instance.Config.Enabled {
+				continue
+			}
+			if instance.Healthy.Load() {
+				s.HealthyInstances++
+			}
+			if instance.Config.TPM <= 0 {
+				unlimited = true
+			}
+			s.TPMLimit += int64(instance.Config.TPM)
+		}
+		if unlimited {
+			s.TPMLimit = 0
+		}
+		if s.TPMLimit > 0 {
+			s.TPMUtilization = s.TokensPerMinute / float64(s.TPMLimit)
+		}
+	}
+	for id := range m.scaling.tokens {
+		if !seen[id] {
+			delete(m.scaling.tokens, id)
+		}
+	}
+
+	for model, p := range m.scaling.models {
+		p.pruneWaits(now)
+		if p.inFlight == 0 && len(p.waits) == 0 {
+			delete(m.scaling.models, model)
+			continue
+		}
+		s := signal(model)
+		s.InFlight = p.inFlight
+		if len(p.waits) > 0 {
+			var total time.Duration
+			for _, w := range p.waits {
+				total += w.wait
+			}
+			s.QueueWaitSeconds = (total / time.Duration(len(p.waits))).Seconds()
+		}
+	}
+
+	result := make([]ScalingSignal, 0, len(signals))
+	for _, s := range signals {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Model < result[j].Model })
+	return result
+}
+
+var (
+	scalingInFlightDesc = prometheus.NewDesc("pllm_model_in_flight_requests",
+		"Requests in flight for the model", []string{"model"}, nil)
+	scalingQueueWaitDesc = prometheus.NewDesc("pllm_model_queue_wait_seconds",
+		"Average time streaming requests for the model waited for their first token over the last minute",
+		[]string{"model"}, nil)
+	scalingTokensDesc = prometheus.NewDesc("pllm_model_tokens_per_minute",
+		"Tokens the model served over the last minute", []string{"model"}, nil)
+	scalingTPMUtilizationDesc = prometheus.NewDesc("pllm_model_tpm_utilization",
+		"Tokens per minute over the summed TPM limits of the model's instances (0 when unlimited)",
+		[]string{"model"}, nil)
+	scalingHealthyInstancesDesc = prometheus.NewDesc("pllm_model_healthy_instances",
+		"Healthy enabled instances of the model", []string{"model"}, nil)
+)
+
+// scalingCollector exports the scaling signals as Prometheus gauges,
+// computed when scraped
+type scalingCollector struct {
+	manager *ModelManager
+}
+
+// ScalingCollector returns a Prometheus collector for the scaling signals
+func (m *ModelManager) ScalingCollector() prometheus.Collector {
+	return &scalingCollector{manager: m}
+}
+
+func (c *scalingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- scalingInFlightDesc
+	ch <- scalingQueueWaitDesc
+	ch <- scalingTokensDesc
+	ch <- scalingTPMUtilizationDesc
+	ch <- scalingHealthyInstancesDesc
+}
+
+func (c *scalingCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.manager.ScalingSignals() {
+		ch <- prometheus.MustNewConstMetric(scalingInFlightDesc, prometheus.GaugeValue, float64(s.InFlight), s.Model)
+		ch <- prometheus.MustNewConstMetric(scalingQueueWaitDesc, prometheus.GaugeValue, s.QueueWaitSeconds, s.Model)
+		ch <- prometheus.MustNewConstMetric(scalingTokensDesc, prometheus.GaugeValue, s.TokensPerMinute, s.Model)
+		ch <- prometheus.MustNewConstMetric(scalingTPMUtilizationDesc, prometheus.GaugeValue, s.TPMUtilization, s.Model)
+		ch <- prometheus.MustNewConstMetric(scalingHealthyInstancesDesc, prometheus.GaugeValue, float64(s.HealthyInstances), s.Model)
+	}
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scalingSignal(t *testing.T, manager *ModelManager, model string) ScalingSignal {
+	t.Helper()
+	for _, signal := range manager.ScalingSignals() {
+		if signal.Model == model {
+			return signal
+		}
+	}
+	require.Failf(t, "no scaling signal", "model %q", model)
+	return ScalingSignal{}
+}
+
+func TestModelManager_ScalingSignals(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	manager.scaling.now = func() time.Time { return now }
+
+	manager.registry.instances["gpt-4-openai"].Config.TPM = 1000
+	manager.registry.instances["gpt-4-azure"].Config.TPM = 3000
+	manager.registry.instances["gpt-4-azure"].Healthy.Store(false)
+
+	signals := manager.ScalingSignals()
+	require.Len(t, signals, 3)
+	assert.Equal(t, []string{"claude", "gpt-4", "mini"}, []string{signals[0].Model, signals[1].Model, signals[2].Model})
+	assert.Equal(t, ScalingSignal{Model: "gpt-4", TPMLimit: 4000, HealthyInstances: 1}, signals[1])
+
+	// Requests in flight, and the waits of streams within the last minute
+	manager.RequestStarted("gpt-4")
+	manager.RequestStarted("gpt-4")
+	manager.RequestStarted("smart")
+	manager.RequestFirstToken("gpt-4", 3*time.Second)
+	now = now.Add(30 * time.Second)
+	manager.RequestFirstToken("gpt-4", time.Second)
+	manager.RequestFinished("gpt-4")
+
+	// Tokens served over the last 30 seconds, as a per-minute rate
+	manager.registry.instances["gpt-4-openai"].TotalTokens.Add(600)
+	manager.registry.instances["gpt-4-azure"].TotalTokens.Add(400)
+
+	signal := scalingSignal(t, manager, "gpt-4")
+	assert.Equal(t, int64(1), signal.InFlight)
+	assert.InDelta(t, 2, signal.QueueWaitSeconds, 1e-9)
+	assert.InDelta(t, 2000, signal.TokensPerMinute, 1e-9)
+	assert.InDelta(t, 0.5, signal.TPMUtilization, 1e-9)
+
+	smart := scalingSignal(t, manager, "smart")
+	assert.Equal(t, int64(1), smart.InFlight, "requests count against the model name clients asked for")
+	assert.Zero(t, smart.HealthyInstances)
+
+	// An unlimited instance leaves the model without a TPM limit
+	assert.Zero(t, scalingSignal(t, manager, "claude").TPMLimit)
+
+	// The window moves on
+	now = now.Add(50 * time.Second)
+	manager.RequestFinished("gpt-4")
+	manager.RequestFinished("smart")
+	signal = scalingSignal(t, manager, "gpt-4")
+	assert.Zero(t, signal.InFlight)
+	assert.InDelta(t, 1, signal.QueueWaitSeconds, 1e-9, "only the later wait is within the last minute")
+	assert.InDelta(t, 1000*60/80.0, signal.TokensPerMinute, 1e-9)
+
+	now = now.Add(time.Minute)
+	signal = scalingSignal(t, manager, "gpt-4")
+	assert.Zero(t, signal.QueueWaitSeconds)
+	assert.Zero(t, signal.TokensPerMinute)
+	for _, signal := range manager.ScalingSignals() {
+		assert.NotEqual(t, "smart", signal.Model, "requested models drop out once idle")
+	}
+}
+
+func TestModelManager_ScalingCollector(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	manager.RequestStarted("mini")
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(manager.ScalingCollector()))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 5)
+
+	inFlight := make(map[string]float64)
+	for _, family := range families {
+		require.Len(t, family.GetMetric(), 3, family.GetName())
+		if family.GetName() != "pllm_model_in_flight_requests" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			inFlight[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{"claude": 0, "gpt-4": 0, "mini": 1}, inFlight)
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"go.uber.org/zap"
+)
+
+// Snapshotter periodically saves the health and performance state of all
+// registered instances to Redis and restores it on startup, so a rolling
+// restart does not reset dashboards or the health and latency data routing
+// relies on.
+type Snapshotter struct {
+	registry         *ModelRegistry
+	healthTracker    *HealthTracker
+	metricsCollector *MetricsCollector
+	store            *redisService.SnapshotStore
+	interval         time.Duration
+	logger           *zap.Logger
+}
+
+// NewSnapshotter creates a Snapshotter.
+func NewSnapshotter(
+	registry *ModelRegistry,
+	healthTracker *HealthTracker,
+	metricsCollector *MetricsCollector,
+	store *redisService.SnapshotStore,
+	interval time.Duration,
+	logger *zap.Logger,
+) *Snapshotter {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Snapshotter{
+		registry:         registry,
+		healthTracker:    healthTracker,
+		metricsCollector: metricsCollector,
+		store:            store,
+		interval:         interval,
+		logger:           logger,
+	}
+}
+
+// Restore loads saved snapshots into the matching registered instances.
+// Instances without a snapshot keep their fresh state.
+func (s *Snapshotter) Restore(ctx context.Context) error {
+	snapshots, err := s.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	restored := 0
+	for _, instance := range s.registry.GetAllInstances() {
+		snapshot, ok := snapshots[instance.Config.ID]
+		if !ok {
+			continue
+		}
+		s.healthTracker.Restore(instance, snapshot)
+		s.metricsCollector.Restore(instance, snapshot)
+		restored++
+	}
+
+	s.logger.Info("Restored instance snapshots", zap.Int("instances", restored))
+	return nil
+}
+
+// Save writes a snapshot of every registered instance.
+func (s *Snapshotter) Save(ctx context.Context) error {
+	instances := s.registry.GetAllInstances()
+	snapshots := make([]redisService.InstanceSnapshot, 0, len(instances))
+	now := time.Now()
+	for _, instance := range instances {
+		snapshots = append(snapshots, snapshotInstance(instance, now))
+	}
+	return s.store.Save(ctx, snapshots)
+}
+
+// Start saves snapshots every interval. It blocks until ctx is cancelled.
+// The final snapshot at shutdown is taken by calling Save directly.
+func (s *Snapshotter) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Save(ctx); err != nil {
+				s.logger.Warn("Failed to snapshot instance state", zap.Error(err))
+			}
+		}
+	}
+}
+
+func snapshotInstance(instance *ModelInstance, now time.Time) redisService.InstanceSnapshot {
+	snapshot := redisService.InstanceSnapshot{
+		InstanceID:       instance.Config.ID,
+		Healthy:          instance.Healthy.Load(),
+		FailureCount:     instance.FailureCount.Load(),
+		TotalRequests:    instance.TotalRequests.Load(),
+		TotalTokens:      instance.TotalTokens.Load(),
+		AverageLatencyMs: instance.AverageLatency.Load(),
+		AverageTTFTMs:    instance.AverageTTFT.Load(),
+		SavedAt:          now,
+	}
+	if ts, ok := instance.LastFailure.Load().(time.Time); ok {
+		snapshot.LastFailure = ts
+	}
+	if ts, ok := instance.LastSuccess.Load().(time.Time); ok {
+		snapshot.LastSuccess = ts
+	}
+	return snapshot
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func newSnapshotTestManager(t *testing.T, client *redis.Client) (*ModelManager, *ModelInstance) {
+	t.Helper()
+	manager := NewModelManager(zap.NewNop(), config.RouterSettings{RoutingStrategy: "priority"}, client)
+
+	instance := NewModelInstance(config.ModelInstance{
+		ID:        "gpt-4-instance",
+		ModelName: "gpt-4",
+		Enabled:   true,
+		Provider:  config.ProviderParams{Type: "mock", Model: "gpt-4"},
+	}, &MockFailingProvider{})
+	manager.registry.mu.Lock()
+	manager.registry.instances[instance.Config.ID] = instance
+	manager.registry.modelMap["gpt-4"] = []*ModelInstance{instance}
+	manager.registry.mu.Unlock()
+	return manager, instance
+}
+
+func TestSnapshotter_RestoresAcrossRestart(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	before, instance := newSnapshotTestManager(t, client)
+	before.metricsCollector.RecordRequest(instance, 100, 250*time.Millisecond)
+	before.metricsCollector.RecordRequest(instance, 50, 250*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		before.healthTracker.RecordFailure(instance, errors.New("upstream error"))
+	}
+	require.NoError(t, before.NewSnapshotter(time.Minute).Save(ctx))
+
+	after, restored := newSnapshotTestManager(t, client)
+	require.NoError(t, after.NewSnapshotter(time.Minute).Restore(ctx))
+
+	assert.Equal(t, int64(2), restored.TotalRequests.Load())
+	assert.Equal(t, int64(150), restored.TotalTokens.Load())
+	assert.Equal(t, int64(250), restored.AverageLatency.Load())
+	assert.False(t, restored.Healthy.Load())
+	assert.Equal(t, int32(3), restored.FailureCount.Load())
+	assert.False(t, after.healthTracker.IsHealthy(restored), "restored failure keeps the instance in cooldown")
+}
+
+func TestSnapshotter_ExpiredSnapshotsAreIgnored(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	before, instance := newSnapshotTestManager(t, client)
+	before.metricsCollector.RecordRequest(instance, 100, 250*time.Millisecond)
+	require.NoError(t, before.NewSnapshotter(time.Minute).Save(ctx))
+	mr.FastForward(2 * time.Hour)
+
+	after, restored := newSnapshotTestManager(t, client)
+	require.NoError(t, after.NewSnapshotter(time.Minute).Restore(ctx))
+	assert.Zero(t, restored.TotalRequests.Load())
+	assert.True(t, restored.Healthy.Load())
+}
+
+func TestModelManager_NewSnapshotterWithoutRedis(t *testing.T) {
+	manager := NewModelManager(zap.NewNop(), config.RouterSettings{RoutingStrategy: "priority"}, nil)
+	assert.Nil(t, manager.NewSnapshotter(time.Minute))
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultStreamKeepAliveInterval is how long a stream may stay quiet before
+// the gateway writes a keep-alive comment to the client
+const DefaultStreamKeepAliveInterval = 15 * time.Second
+
+var streamKeepAlives = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_llm_stream_keepalives_total",
+		Help: "Keep-alive comments written to streaming clients while the provider sent nothing",
+	},
+	[]string{"model"},
+)
+
+// StreamGapStats summarizes the quiet periods of a model's streams since the
+// gateway started, to tell slow reasoning models from stalling backends
+type StreamGapStats struct {
+	Model   string `json:"model"`
+	Streams int64  `json:"streams"`
+	// Streams that needed at least one keep-alive, and the keep-alives sent
+	KeptAlive  int64 `json:"kept_alive"`
+	KeepAlives int64 `json:"keep_alives"`
+	Stalls     int64 `json:"stalls"`
+
+	// Longest wait for the first chunk, where reasoning models think
+	MaxFirstChunkWaitSeconds float64 `json:"max_first_chunk_wait_seconds"`
+	// Longest and average longest gap between two chunks of a stream
+	MaxChunkGapSeconds float64   `json:"max_chunk_gap_seconds"`
+	AvgChunkGapSeconds float64   `json:"avg_max_chunk_gap_seconds"`
+	LastStreamAt       time.Time `json:"last_stream_at"`
+
+	totalMaxGap time.Duration
+}
+
+// streamGapTracker keeps the gap statistics of finished streams per model
+type streamGapTracker struct {
+	mu     sync.Mutex
+	models map[string]*StreamGapStats
+}
+
+func newStreamGapTracker() *streamGapTracker {
+	return &streamGapTracker{models: make(map[string]*StreamGapStats)}
+}
+
+func (t *streamGapTracker) record(model string, stats StreamStats, firstChunkWait time.Duration, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.models[model]
+	if !ok {
+		s = &StreamGapStats{Model: model}
+		t.models[model] = s
+	}
+	s.Streams++
+	if stats.KeepAlives > 0 {
+		s.KeptAlive++
+		s.KeepAlives += int64(stats.KeepAlives)
+	}
+	if stats.Stalled {
+		s.Stalls++
+	}
+	if wait := firstChunkWait.Seconds(); wait > s.MaxFirstChunkWaitSeconds {
+		s.MaxFirstChunkWaitSeconds = wait
+	}
+	if gap := stats.MaxChunkGap.Seconds(); gap > s.MaxChunkGapSeconds {
+		s.MaxChunkGapSeconds = gap
+	}
+	s.totalMaxGap += stats.MaxChunkGap
+	s.AvgChunkGapSeconds = (s.totalMaxGap / time.Duration(s.Streams)).Seconds()
+	s.LastStreamAt = at
+}
+
+func (t *streamGapTracker) snapshot() []StreamGapStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := make([]StreamGapStats, 0, len(t.models))
+	for _, s := range t.models {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Model < stats[j].Model })
+	return stats
+}
+
+// StreamGapStats returns the gap statistics of the streams served by this
+// replica, per model
+func (m *ModelManager) StreamGapStats() []StreamGapStats {
+	return m.streamGaps.snapshot()
+}
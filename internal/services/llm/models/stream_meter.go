@@ -0,0 +1,180 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultStreamStallThreshold is the longest gap between two chunks of a
+// stream before it counts as stalled
+const DefaultStreamStallThreshold = 30 * time.Second
+
+// StreamStats describes the generation of one streamed response
+type StreamStats struct {
+	Chunks          int
+	TokensPerSecond float64       // Completion tokens over the time from the first chunk to the last
+	MaxChunkGap     time.Duration // Longest gap between two chunks
+	Stalled         bool
+	KeepAlives      int // Keep-alive comments written while the provider was quiet
+}
+
+// StreamMeter measures the throughput and inter-chunk gaps of a stream. A
+// gap longer than the stall threshold records a failure on the instance as
+// soon as it happens, so the health tracker penalizes flaky streaming
+// backends even when the stream never resumes.
+type StreamMeter struct {
+	manager   *ModelManager
+	instance  *ModelInstance
+	threshold time.Duration
+	keepAlive time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	started time.Time
+	first   time.Time
+	last    time.Time
+	chunks  int
+	maxGap  time.Duration
+	stall   *time.Timer
+	done    bool
+	end     func() // Ends the stream's in-flight count on the instance
+
+	stalled    bool
+	keepAlives int
+}
+
+// StartStreamMeter starts measuring a stream served by instance
+func (m *ModelManager) StartStreamMeter(instance *ModelInstance) *StreamMeter {
+	threshold := m.router.StreamStallThreshold
+	if threshold <= 0 {
+		threshold = DefaultStreamStallThreshold
+	}
+	return &StreamMeter{
+		manager:   m,
+		instance:  instance,
+		threshold: threshold,
+		keepAlive: m.StreamKeepAliveInterval(),
+		now:       time.Now,
+		started:   time.Now(),
+		end:       instance.Begin(),
+	}
+}
+
+// StreamKeepAliveInterval is how long a stream may stay quiet before a
+// keep-alive is written to the client; 0 when keep-alives are disabled
+func (m *ModelManager) StreamKeepAliveInterval() time.Duration {
+	switch interval := m.router.StreamKeepAliveInterval; {
+	case interval == 0:
+		return DefaultStreamKeepAliveInterval
+	case interval < 0:
+		return 0
+	default:
+		return interval
+	}
+}
+
+// KeepAliveInterval is how long the stream may stay quiet before a
+// keep-alive is written to the client; 0 when keep-alives are disabled
+func (s *StreamMeter) KeepAliveInterval() time.Duration {
+	return s.keepAlive
+}
+
+// KeepAlive reports whether a keep-alive should be written now that the
+// stream has been quiet for the keep-alive interval, and counts it. Keep-
+// alives are not chunks: they hold the client connection open but do not
+// reset the stall timer, so a stalled backend still trips its circuit
+// breaker. Once the stream has stalled and the instance's circuit breaker
+// has opened, the backend is not expected to resume and keep-alives stop,
+// leaving the connection to the client's and load balancer's timeouts.
+func (s *StreamMeter) KeepAlive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done || s.keepAlive <= 0 {
+		return false
+	}
+	if s.stalled && !s.instance.Healthy.Load() { // Its circuit breaker is open
+		return false
+	}
+	s.keepAlives++
+	streamKeepAlives.WithLabelValues(s.instance.Config.ModelName).Inc()
+	return true
+}
+
+// Chunk records a chunk written to the client
+func (s *StreamMeter) Chunk() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.chunks == 0 {
+		s.first = now
+		// The wait for the first chunk is time to first token, not a stall
+		s.stall = time.AfterFunc(s.threshold, s.onStall)
+	} else {
+		if gap := now.Sub(s.last); gap > s.maxGap {
+			s.maxGap = gap
+		}
+		s.stall.Reset(s.threshold)
+	}
+	s.last = now
+	s.chunks++
+}
+
+// Finish stops measuring and returns the stream's statistics, given the
+// number of completion tokens it generated
+func (s *StreamMeter) Finish(completionTokens int64) StreamStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.done = true
+	s.end()
+	if s.stall != nil {
+		s.stall.Stop()
+	}
+
+	stats := StreamStats{
+		Chunks:      s.chunks,
+		MaxChunkGap: s.maxGap,
+		Stalled:     s.stalled,
+		KeepAlives:  s.keepAlives,
+	}
+	if elapsed := s.last.Sub(s.first); elapsed > 0 && completionTokens > 0 {
+		stats.TokensPerSecond = float64(completionTokens) / elapsed.Seconds()
+	}
+
+	now := s.now()
+	firstChunkWait := now.Sub(s.started)
+	if s.chunks > 0 {
+		firstChunkWait = s.first.Sub(s.started)
+	}
+	s.manager.streamGaps.record(s.instance.Config.ModelName, stats, firstChunkWait, now)
+	return stats
+}
+
+// onStall records a failure the first time the stream goes quiet for the
+// stall threshold
+func (s *StreamMeter) onStall() {
+	s.mu.Lock()
+	if s.done || s.stalled {
+		s.mu.Unlock()
+		return
+	}
+	s.stalled = true
+	s.mu.Unlock()
+
+	s.manager.RecordStreamStall(s.instance, s.threshold)
+}
+
+// RecordStreamStall records a failure on an instance whose stream sent no
+// chunk for the given time
+func (m *ModelManager) RecordStreamStall(instance *ModelInstance, gap time.Duration) {
+	m.logger.Warn("Stream stalled",
+		zap.String("instance_id", instance.Config.ID),
+		zap.String("model", instance.Config.ModelName),
+		zap.Duration("gap", gap))
+	m.healthTracker.RecordFailure(instance, fmt.Errorf("stream stalled: no chunk for %s", gap))
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamMeter_Throughput(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	instance := manager.registry.instances["gpt-4-openai"]
+
+	meter := manager.StartStreamMeter(instance)
+	assert.Equal(t, DefaultStreamStallThreshold, meter.threshold)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	meter.now = func() time.Time { return now }
+
+	meter.Chunk()
+	now = now.Add(500 * time.Millisecond)
+	meter.Chunk()
+	now = now.Add(1500 * time.Millisecond)
+	meter.Chunk()
+
+	stats := meter.Finish(40)
+	assert.Equal(t, StreamStats{Chunks: 3, TokensPerSecond: 20, MaxChunkGap: 1500 * time.Millisecond}, stats)
+	assert.Zero(t, instance.FailureCount.Load())
+
+	// A single chunk has no generation time to measure
+	meter = manager.StartStreamMeter(instance)
+	meter.Chunk()
+	assert.Zero(t, meter.Finish(10).TokensPerSecond)
+	assert.Zero(t, manager.StartStreamMeter(instance).Finish(0))
+}
+
+func TestStreamMeter_Stall(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	manager.router.StreamStallThreshold = 20 * time.Millisecond
+	instance := manager.registry.instances["claude-anthropic"]
+
+	meter := manager.StartStreamMeter(instance)
+	meter.Chunk()
+	require.Eventually(t, func() bool { return instance.FailureCount.Load() == 1 },
+		time.Second, 5*time.Millisecond, "a stream gone quiet records a failure before it resumes")
+
+	time.Sleep(30 * time.Millisecond)
+	meter.Chunk()
+	stats := meter.Finish(5)
+	assert.True(t, stats.Stalled)
+	assert.GreaterOrEqual(t, stats.MaxChunkGap, 40*time.Millisecond)
+	assert.Equal(t, int32(1), instance.FailureCount.Load(), "a stall is recorded once per stream")
+	assert.ErrorContains(t, instance.LastError.Load().(error), "stream stalled")
+
+	// Finished streams never stall
+	meter = manager.StartStreamMeter(instance)
+	meter.Chunk()
+	assert.False(t, meter.Finish(5).Stalled)
+	time.Sleep(40 * time.Millisecond)
+	assert.Equal(t, int32(1), instance.FailureCount.Load())
+}
+
+func TestStreamMeter_KeepAlive(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	instance := manager.registry.instances["gpt-4-openai"]
+	assert.Equal(t, DefaultStreamKeepAliveInterval, manager.StartStreamMeter(instance).KeepAliveInterval())
+
+	manager.router.StreamStallThreshold = 10 * time.Millisecond
+	meter := manager.StartStreamMeter(instance)
+	assert.True(t, meter.KeepAlive(), "keep-alives while waiting for the first chunk")
+	meter.Chunk()
+	assert.True(t, meter.KeepAlive())
+	require.Eventually(t, func() bool { return instance.FailureCount.Load() == 1 },
+		time.Second, 2*time.Millisecond, "keep-alives do not hide a stall")
+	assert.True(t, meter.KeepAlive(), "stalled, but the circuit breaker is still closed")
+
+	instance.Healthy.Store(false)
+	assert.False(t, meter.KeepAlive(), "no keep-alives once the circuit breaker opened")
+	stats := meter.Finish(3)
+	assert.Equal(t, 3, stats.KeepAlives)
+	assert.False(t, meter.KeepAlive(), "finished")
+
+	gaps := manager.StreamGapStats()
+	require.Len(t, gaps, 1)
+	assert.Equal(t, "gpt-4", gaps[0].Model)
+	assert.Equal(t, int64(1), gaps[0].Streams)
+	assert.Equal(t, int64(1), gaps[0].KeptAlive)
+	assert.Equal(t, int64(3), gaps[0].KeepAlives)
+	assert.Equal(t, int64(1), gaps[0].Stalls)
+
+	manager.router.StreamKeepAliveInterval = -1
+	meter = manager.StartStreamMeter(instance)
+	assert.Zero(t, meter.KeepAliveInterval())
+	assert.False(t, meter.KeepAlive(), "disabled")
+	meter.Finish(0)
+}
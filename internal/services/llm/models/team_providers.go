@@ -0,0 +1,36 @@
+package models
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// TeamProviders resolves the providers teams registered with their own API
+// keys. ProviderFor reports false when the team has no active key for the
+// instance's provider type.
+type TeamProviders interface {
+	ProviderFor(ctx context.Context, teamID uuid.UUID, instance config.ModelInstance) (providers.Provider, uuid.UUID, bool)
+}
+
+// SetTeamProviders makes ProviderFor send the traffic of teams that brought
+// their own provider keys with those keys. Call it before serving requests.
+func (m *ModelManager) SetTeamProviders(teamProviders TeamProviders) {
+	m.teamProviders = teamProviders
+}
+
+// ProviderFor returns the provider a team's request on instance is sent
+// with: the team's own key for the instance's provider type when it
+// registered one, otherwise the instance's shared provider. keyID is the
+// team key used, or uuid.Nil for the shared credentials.
+func (m *ModelManager) ProviderFor(ctx context.Context, teamID uuid.UUID, instance *ModelInstance) (provider providers.Provider, keyID uuid.UUID) {
+	if m.teamProviders != nil && teamID != uuid.Nil {
+		if provider, keyID, ok := m.teamProviders.ProviderFor(ctx, teamID, instance.Config); ok {
+			return provider, keyID
+		}
+	}
+	return instance.Provider, uuid.Nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+type fakeTeamProviders struct {
+	teamID   uuid.UUID
+	keyID    uuid.UUID
+	provider providers.Provider
+}
+
+func (f *fakeTeamProviders) ProviderFor(ctx context.Context, teamID uuid.UUID, instance config.ModelInstance) (providers.Provider, uuid.UUID, bool) {
+	if teamID != f.teamID || instance.Provider.Type != "openai" {
+		return nil, uuid.Nil, false
+	}
+	return f.provider, f.keyID, true
+}
+
+func TestModelManager_ProviderFor(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	openai := manager.registry.instances["gpt-4-openai"]
+	azure := manager.registry.instances["gpt-4-azure"]
+	teamID := uuid.New()
+
+	provider, keyID := manager.ProviderFor(context.Background(), teamID, openai)
+	assert.Same(t, openai.Provider, provider, "without team providers the shared provider is used")
+	assert.Equal(t, uuid.Nil, keyID)
+
+	teamProviders := &fakeTeamProviders{teamID: teamID, keyID: uuid.New(), provider: &MockFailingProvider{}}
+	manager.SetTeamProviders(teamProviders)
+
+	provider, keyID = manager.ProviderFor(context.Background(), teamID, openai)
+	assert.Same(t, teamProviders.provider, provider)
+	assert.Equal(t, teamProviders.keyID, keyID)
+
+	provider, keyID = manager.ProviderFor(context.Background(), teamID, azure)
+	assert.Same(t, azure.Provider, provider, "other provider types keep the shared provider")
+	assert.Equal(t, uuid.Nil, keyID)
+
+	provider, _ = manager.ProviderFor(context.Background(), uuid.Nil, openai)
+	assert.Same(t, openai.Provider, provider, "requests without a team keep the shared provider")
+}
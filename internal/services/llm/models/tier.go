@@ -0,0 +1,260 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// TierPrefix marks a model name as a latency tier ("tier:fast")
+const TierPrefix = "tier:"
+
+const (
+	defaultTierLatencyWeight = 0.7
+	defaultTierCostWeight    = 0.3
+)
+
+// TierEntry is a latency tier in the model manager
+type TierEntry struct {
+	Name          string
+	Description   string
+	Models        []string
+	LatencyWeight float64
+	CostWeight    float64
+}
+
+// TierCandidate is a tier model ranked for the current request. Latency and
+// cost are zero when unknown.
+type TierCandidate struct {
+	ModelName    string        `json:"model"`
+	Latency      time.Duration `json:"-"`
+	LatencyMs    int64         `json:"latency_ms"`
+	CostPerToken float64       `json:"cost_per_token"`
+	Score        float64       `json:"score"`
+
+	// deprioritized is set when all the model's healthy instances belong to
+	// providers with a declared upstream incident
+	deprioritized bool
+}
+
+// LoadTiers loads latency tiers from configuration
+func (m *ModelManager) LoadTiers(configs []config.TierConfig) {
+	tiers := make(map[string]*TierEntry, len(configs))
+	for _, tc := range configs {
+		if tc.Name == "" || len(tc.Models) == 0 {
+			m.logger.Warn("Skipping tier without name or models", zap.String("tier", tc.Name))
+			continue
+		}
+
+		entry := &TierEntry{
+			Name:          tc.Name,
+			Description:   tc.Description,
+			Models:        tc.Models,
+			LatencyWeight: defaultTierLatencyWeight,
+			CostWeight:    defaultTierCostWeight,
+		}
+		if tc.LatencyWeight != nil {
+			entry.LatencyWeight = *tc.LatencyWeight
+		}
+		if tc.CostWeight != nil {
+			entry.CostWeight = *tc.CostWeight
+		}
+		tiers[tc.Name] = entry
+
+		m.logger.Info("Loaded tier from config",
+			zap.String("tier", tc.Name),
+			zap.Strings("models", tc.Models))
+	}
+
+	m.tierMu.Lock()
+	m.tiers = tiers
+	m.tierMu.Unlock()
+}
+
+// ResolveTier returns the tier named by a "tier:<name>" model name
+func (m *ModelManager) ResolveTier(modelName string) (*TierEntry, bool) {
+	name, ok := strings.CutPrefix(modelName, TierPrefix)
+	if !ok {
+		return nil, false
+	}
+
+	m.tierMu.RLock()
+	defer m.tierMu.RUnlock()
+
+	entry, exists := m.tiers[name]
+	return entry, exists
+}
+
+// GetTiers returns all configured tiers
+func (m *ModelManager) GetTiers() map[string]*TierEntry {
+	m.tierMu.RLock()
+	defer m.tierMu.RUnlock()
+
+	result := make(map[string]*TierEntry, len(m.tiers))
+	for k, v := range m.tiers {
+		result[k] = v
+	}
+	return result
+}
+
+// RankTier orders the tier's models with a healthy instance from best to
+// worst. Latency and cost are normalized to the slowest and most expensive
+// candidate; models without data score as the candidate average so new
+// models are neither favored nor starved. Models served only by providers
+// with a declared upstream incident come last.
+func (m *ModelManager) RankTier(ctx context.Context, tier *TierEntry) []TierCandidate {
+	var candidates []TierCandidate
+	for _, modelName := range tier.Models {
+		instances, exists := m.registry.GetModelInstances(modelName)
+		if !exists {
+			continue
+		}
+
+		var healthy []*ModelInstance
+		for _, instance := range instances {
+			if m.healthTracker.IsHealthy(instance) {
+				healthy = append(healthy, instance)
+			}
+		}
+		if len(healthy) == 0 {
+			continue
+		}
+
+		latency := m.tierModelLatency(ctx, modelName, healthy)
+		candidates = append(candidates, TierCandidate{
+			ModelName:     modelName,
+			Latency:       latency,
+			LatencyMs:     latency.Milliseconds(),
+			CostPerToken:  tierModelCost(healthy),
+			deprioritized: m.healthTracker.IsDeprioritized(m.preferUnaffected(healthy)[0]),
+		})
+	}
+
+	latencies := make([]float64, len(candidates))
+	costs := make([]float64, len(candidates))
+	for i, c := range candidates {
+		latencies[i] = float64(c.Latency)
+		costs[i] = c.CostPerToken
+	}
+	latencyScores := normalizeTierMetric(latencies)
+	costScores := normalizeTierMetric(costs)
+
+	for i := range candidates {
+		candidates[i].Score = tier.LatencyWeight*latencyScores[i] + tier.CostWeight*costScores[i]
+	}
+
+	// Stable sort keeps config order for ties, so a tier with no metrics yet
+	// behaves like a priority list
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].deprioritized != candidates[j].deprioritized {
+			return !candidates[i].deprioritized
+		}
+		return candidates[i].Score < candidates[j].Score
+	})
+	return candidates
+}
+
+// executeTierWithFailover tries the tier's models from best to worst ranking
+func (m *ModelManager) executeTierWithFailover(ctx context.Context, tier *TierEntry, req *FailoverRequest) (*FailoverResult, error) {
+	instanceRetries := m.router.InstanceRetryAttempts
+	if instanceRetries <= 0 {
+		instanceRetries = 2
+	}
+
+	candidates := m.RankTier(ctx, tier)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("tier %q: no healthy models available", tier.Name)
+	}
+
+	var failovers []string
+	attemptCount := 0
+	for _, candidate := range candidates {
+		m.logger.Info("Tier selected model",
+			zap.String("tier", tier.Name),
+			zap.String("model", candidate.ModelName),
+			zap.Int64("latency_ms", candidate.LatencyMs),
+			zap.Float64("score", candidate.Score))
+
+		result, err := m.tryModelInstances(ctx, candidate.ModelName, req, instanceRetries, &attemptCount, &failovers)
+		if err == nil {
+			return result, nil
+		}
+		failovers = append(failovers, fmt.Sprintf("tier-model:%s(failed)", candidate.ModelName))
+	}
+
+	return nil, fmt.Errorf("tier %q: all models exhausted", tier.Name)
+}
+
+// tierModelLatency returns the model's average latency, preferring the
+// distributed tracker over the instances' in-memory averages
+func (m *ModelManager) tierModelLatency(ctx context.Context, modelName string, instances []*ModelInstance) time.Duration {
+	if m.latencyTracker != nil {
+		queryCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		latency, err := m.latencyTracker.GetAverageLatency(queryCtx, modelName)
+		cancel()
+		if err == nil && latency > 0 {
+			return latency
+		}
+	}
+
+	var best int64
+	for _, instance := range instances {
+		if avg := instance.AverageLatency.Load(); avg > 0 && (best == 0 || avg < best) {
+			best = avg
+		}
+	}
+	return time.Duration(best) * time.Millisecond
+}
+
+// tierModelCost returns the cheapest instance's combined input and output
+// price per token, falling back to the pricing table
+func tierModelCost(instances []*ModelInstance) float64 {
+	var best float64
+	for _, instance := range instances {
+		cost := instance.Config.InputCostPerToken + instance.Config.OutputCostPerToken
+		if cost == 0 {
+			if pricing := config.GetPricingManager().GetPricing(instance.Config.ModelName); pricing != nil {
+				cost = pricing.InputCostPerToken + pricing.OutputCostPerToken
+			}
+		}
+		if cost > 0 && (best == 0 || cost < best) {
+			best = cost
+		}
+	}
+	return best
+}
+
+// normalizeTierMetric scales values to 0..1 of the maximum, replacing unknown
+// (zero) values with the mean of the known ones
+func normalizeTierMetric(values []float64) []float64 {
+	var sum, max float64
+	known := 0
+	for _, v := range values {
+		if v > 0 {
+			sum += v
+			known++
+			if v > max {
+				max = v
+			}
+		}
+	}
+
+	scores := make([]float64, len(values))
+	if known == 0 {
+		return scores
+	}
+	mean := sum / float64(known)
+	for i, v := range values {
+		if v <= 0 {
+			v = mean
+		}
+		scores[i] = v / max
+	}
+	return scores
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func newTierTestManager(t *testing.T) *ModelManager {
+	t.Helper()
+	manager := NewModelManager(zap.NewNop(), config.RouterSettings{
+		RoutingStrategy:       "priority",
+		EnableFailover:        true,
+		InstanceRetryAttempts: 1,
+	}, nil)
+
+	add := func(modelName string, latencyMs int64, costPerToken float64, failCount int) {
+		instance := &ModelInstance{
+			Config: config.ModelInstance{
+				ID:                modelName + "-instance",
+				ModelName:         modelName,
+				Enabled:           true,
+				Provider:          config.ProviderParams{Type: "mock", Model: modelName},
+				Timeout:           5 * time.Second,
+				InputCostPerToken: costPerToken,
+			},
+			Provider: &MockFailingProvider{failCount: failCount},
+		}
+		instance.Healthy.Store(true)
+		instance.AverageLatency.Store(latencyMs)
+
+		manager.registry.mu.Lock()
+		manager.registry.instances[instance.Config.ID] = instance
+		manager.registry.modelMap[modelName] = []*ModelInstance{instance}
+		manager.registry.mu.Unlock()
+	}
+	add("slow-cheap", 2000, 0.000001, 0)
+	add("fast-pricey", 300, 0.00001, 0)
+	add("fast-cheap", 400, 0.000002, 100)
+
+	latencyOnly, costOnly := 1.0, 0.0
+	manager.LoadTiers([]config.TierConfig{
+		{Name: "balanced", Models: []string{"slow-cheap", "fast-pricey", "fast-cheap"}},
+		{Name: "fast", Models: []string{"slow-cheap", "fast-pricey", "fast-cheap"}, LatencyWeight: &latencyOnly, CostWeight: &costOnly},
+		{Name: "empty"},
+	})
+	return manager
+}
+
+func TestModelManager_ResolveTier(t *testing.T) {
+	manager := newTierTestManager(t)
+
+	tier, ok := manager.ResolveTier("tier:fast")
+	require.True(t, ok)
+	assert.Equal(t, "fast", tier.Name)
+
+	_, ok = manager.ResolveTier("fast")
+	assert.False(t, ok, "tiers need the tier: prefix")
+
+	_, ok = manager.ResolveTier("tier:empty")
+	assert.False(t, ok, "tiers without models are skipped")
+}
+
+func TestModelManager_RankTier(t *testing.T) {
+	manager := newTierTestManager(t)
+	ctx := context.Background()
+
+	names := func(candidates []TierCandidate) []string {
+		var result []string
+		for _, c := range candidates {
+			result = append(result, c.ModelName)
+		}
+		return result
+	}
+
+	fast, _ := manager.ResolveTier("tier:fast")
+	assert.Equal(t, []string{"fast-pricey", "fast-cheap", "slow-cheap"}, names(manager.RankTier(ctx, fast)))
+
+	balanced, _ := manager.ResolveTier("tier:balanced")
+	assert.Equal(t, []string{"fast-cheap", "fast-pricey", "slow-cheap"}, names(manager.RankTier(ctx, balanced)))
+
+	t.Run("unhealthy models are skipped", func(t *testing.T) {
+		instances, _ := manager.registry.GetModelInstances("fast-cheap")
+		instances[0].Healthy.Store(false)
+		defer instances[0].Healthy.Store(true)
+
+		assert.Equal(t, []string{"fast-pricey", "slow-cheap"}, names(manager.RankTier(ctx, balanced)))
+	})
+}
+
+func TestModelManager_ExecuteTierWithFailover(t *testing.T) {
+	manager := newTierTestManager(t)
+
+	result, err := manager.ExecuteWithFailover(context.Background(), &FailoverRequest{
+		ModelName: "tier:balanced",
+		ExecuteFunc: func(ctx context.Context, instance *ModelInstance) (interface{}, error) {
+			return instance.Provider.ChatCompletion(ctx, &providers.ChatRequest{
+				Model:    instance.Config.Provider.Model,
+				Messages: []providers.Message{{Role: "user", Content: "test"}},
+			})
+		},
+	})
+
+	// The best-ranked model fails, so the next one in the ranking serves it
+	require.NoError(t, err)
+	assert.Equal(t, "fast-pricey", result.Instance.Config.ModelName)
+	assert.Contains(t, result.Failovers, "tier-model:fast-cheap(failed)")
+}
+
+func TestNormalizeTierMetric(t *testing.T) {
+	assert.Equal(t, []float64{0.5, 1, 0.75}, normalizeTierMetric([]float64{100, 200, 0}))
+	assert.Equal(t, []float64{0, 0}, normalizeTierMetric([]float64{0, 0}))
+}
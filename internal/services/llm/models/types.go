@@ -29,6 +29,7 @@ type ModelInstance struct {
 	TotalRequests      atomic.Int64
 	TotalTokens        atomic.Int64
 	AverageLatency     atomic.Int64 // in milliseconds
+	AverageTTFT        atomic.Int64 // time to first token of streaming requests, in milliseconds
 	RequestsThisMinute atomic.Int32
 	TokensThisMinute   atomic.Int32
 	WindowStart        atomic.Value // time.Time
@@ -36,6 +37,12 @@ type ModelInstance struct {
 	// Circuit breaker state
 	CircuitState     atomic.Int32 // 0=closed, 1=half-open, 2=open
 	LastCircuitCheck atomic.Value // time.Time
+
+	// Requests being served, open streams included, and the draining state
+	// while the instance is taken out of rotation
+	InFlight     atomic.Int64
+	DrainStarted atomic.Value // time.Time, zero when not draining
+	DrainedAt    atomic.Value // time.Time the last in-flight request finished
 }
 
 // NewModelInstance creates a new runtime model instance from configuration
@@ -65,6 +72,30 @@ func (m *ModelInstance) GetAverageLatency() *atomic.Int64 {
 	return &m.AverageLatency
 }
 
+// GetAverageTTFT returns a pointer to the average time to first token atomic value
+func (m *ModelInstance) GetAverageTTFT() *atomic.Int64 {
+	return &m.AverageTTFT
+}
+
+// GetLoad returns the load its provider reports, such as the GPU
+// utilization of a NIM or Triton server
+func (m *ModelInstance) GetLoad() (float64, bool) {
+	if reporter, ok := m.Provider.(providers.LoadReporter); ok {
+		return reporter.Load()
+	}
+	return 0, false
+}
+
+// RecordFirstToken records the time to first token of a streaming request
+func (m *ModelInstance) RecordFirstToken(ttftMs int64) {
+	currentAvg := m.AverageTTFT.Load()
+	if currentAvg == 0 {
+		m.AverageTTFT.Store(ttftMs)
+	} else {
+		m.AverageTTFT.Store(int64(float64(currentAvg)*0.9 + float64(ttftMs)*0.1))
+	}
+}
+
 // Legacy methods for backward compatibility with handlers
 // TODO: Update handlers to use manager methods instead
 
@@ -0,0 +1,58 @@
+package models
+
+// ModelUnavailable reports whether no instance that could serve modelName is
+// healthy: the model's own instances, the models of a route or tier and the
+// fallbacks tried after them. Models switched off by the kill switch and
+// names without instances do not count as down, so a request failing for
+// those reasons is not mistaken for an outage.
+func (m *ModelManager) ModelUnavailable(modelName string) bool {
+	checked := make(map[string]bool)
+	down := false
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		if checked[name] {
+			return true
+		}
+		checked[name] = true
+
+		if route, isRoute := m.ResolveRoute(name); isRoute && route != nil {
+			for _, rm := range route.Models {
+				if rm.Enabled && !visit(rm.ModelName) {
+					return false
+				}
+			}
+			for _, fallback := range route.FallbackModels {
+				if !visit(fallback) {
+					return false
+				}
+			}
+			return true
+		}
+		if tier, isTier := m.ResolveTier(name); isTier {
+			for _, model := range tier.Models {
+				if !visit(model) {
+					return false
+				}
+			}
+			return true
+		}
+
+		if !m.isModelDisabled(name) {
+			instances, _ := m.registry.GetModelInstances(name)
+			for _, instance := range instances {
+				if m.healthTracker.IsHealthy(instance) {
+					return false
+				}
+				down = true
+			}
+		}
+		if m.router.EnableModelFallback {
+			if fallback, ok := m.router.ModelFallbacks[name]; ok {
+				return visit(fallback)
+			}
+		}
+		return true
+	}
+
+	return visit(modelName) && down
+}
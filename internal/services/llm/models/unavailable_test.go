@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelManager_ModelUnavailable(t *testing.T) {
+	manager := newSimulationTestManager(t)
+	down := func(ids ...string) {
+		for _, id := range ids {
+			instance := manager.registry.instances[id]
+			instance.Healthy.Store(false)
+			instance.LastFailure.Store(time.Now())
+		}
+	}
+
+	assert.False(t, manager.ModelUnavailable("gpt-4"))
+	assert.False(t, manager.ModelUnavailable("unknown"), "models without instances are not down")
+
+	down("gpt-4-openai")
+	assert.False(t, manager.ModelUnavailable("gpt-4"), "one healthy instance keeps the model up")
+
+	down("gpt-4-azure")
+	assert.True(t, manager.ModelUnavailable("gpt-4"))
+	assert.False(t, manager.ModelUnavailable("smart"), "the route still has claude")
+
+	down("claude-anthropic")
+	assert.False(t, manager.ModelUnavailable("smart"), "the route's fallback is up")
+	assert.False(t, manager.ModelUnavailable("outer"), "nested route fallbacks count")
+
+	down("mini-openai")
+	assert.True(t, manager.ModelUnavailable("smart"))
+	assert.True(t, manager.ModelUnavailable("outer"))
+
+	manager.SetModelKillSwitch(func(model string) bool { return model == "claude" })
+	assert.False(t, manager.ModelUnavailable("claude"), "switched-off models are not an outage")
+	assert.True(t, manager.ModelUnavailable("smart"))
+}
@@ -0,0 +1,379 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Severity of a validation issue
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Connectivity check results
+const (
+	ConnectivityOK      = "ok"
+	ConnectivityFailed  = "failed"
+	ConnectivitySkipped = "skipped"
+)
+
+// envVarPattern matches ${VAR} references left in provider parameters
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ValidationOptions controls ValidateConfig
+type ValidationOptions struct {
+	// CheckConnectivity runs each enabled instance's provider health check
+	CheckConnectivity bool
+	// Timeout of each connectivity check (default 10s)
+	Timeout time.Duration
+	// Strict fails validation on warnings too
+	Strict bool
+}
+
+// ValidationIssue is a problem found in the configuration
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+// InstanceReport is the validation result of one model_list entry
+type InstanceReport struct {
+	Index        int               `json:"index"`
+	ModelName    string            `json:"model_name"`
+	Provider     string            `json:"provider"`
+	Model        string            `json:"model"`
+	Enabled      bool              `json:"enabled"`
+	Connectivity string            `json:"connectivity,omitempty"`
+	Latency      string            `json:"latency,omitempty"`
+	Issues       []ValidationIssue `json:"issues,omitempty"`
+}
+
+// ValidationReport is the machine-readable result of ValidateConfig
+type ValidationReport struct {
+	Valid     bool              `json:"valid"`
+	Errors    int               `json:"errors"`
+	Warnings  int               `json:"warnings"`
+	Instances []InstanceReport  `json:"instances"`
+	Issues    []ValidationIssue `json:"issues,omitempty"` // Issues not tied to one instance
+}
+
+// ValidateConfig checks every model instance of a loaded configuration:
+// provider parameters, environment variables the parameters reference, Azure
+// deployment mappings and the models referenced by aliases, routes, tiers and
+// fallbacks. With CheckConnectivity, each enabled instance's provider is
+// created and health checked.
+func ValidateConfig(ctx context.Context, cfg *config.Config, opts ValidationOptions) *ValidationReport {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	report := &ValidationReport{Instances: make([]InstanceReport, 0, len(cfg.ModelList))}
+	registry := NewModelRegistry(zap.NewNop())
+	modelNames := make(map[string]bool, len(cfg.ModelList))
+
+	for i, inst := range cfg.ModelList {
+		modelNames[inst.ModelName] = true
+		report.Instances = append(report.Instances, InstanceReport{
+			Index:     i,
+			ModelName: inst.ModelName,
+			Provider:  inst.Provider.Type,
+			Model:     inst.Provider.Model,
+			Enabled:   inst.Enabled,
+			Issues:    validateInstance(registry, inst),
+		})
+	}
+
+	if opts.CheckConnectivity {
+		checkConnectivity(ctx, registry, cfg.ModelList, report.Instances, opts.Timeout)
+	}
+
+	report.Issues = validateReferences(cfg, modelNames)
+
+	for _, inst := range report.Instances {
+		report.count(inst.Issues)
+	}
+	report.count(report.Issues)
+	report.Valid = report.Errors == 0 && (!opts.Strict || report.Warnings == 0)
+	return report
+}
+
+func (r *ValidationReport) count(issues []ValidationIssue) {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			r.Errors++
+		} else {
+			r.Warnings++
+		}
+	}
+}
+
+// validateInstance checks the parameters of one instance without network access
+func validateInstance(registry *ModelRegistry, inst config.ModelInstance) []ValidationIssue {
+	var issues []ValidationIssue
+	add := func(severity, field, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: severity, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	p := inst.Provider
+	if inst.ModelName == "" {
+		add(SeverityError, "model_name", "model_name is required")
+	}
+	if p.Type == "" {
+		add(SeverityError, "provider.type", "provider type is required")
+	}
+	if p.Model == "" {
+		add(SeverityError, "provider.model", "provider model is required")
+	}
+
+	// The loader only expands ${VAR} in api_key; unset variables stay literal
+	forEachStringParam(p, func(field, value string) {
+		for _, match := range envVarPattern.FindAllStringSubmatch(value, -1) {
+			if _, ok := os.LookupEnv(match[1]); !ok {
+				add(SeverityError, "provider."+field, "environment variable %s is not set", match[1])
+			} else {
+				add(SeverityWarning, "provider."+field, "%s is not expanded here; only api_key supports ${VAR}", match[0])
+			}
+		}
+	})
+
+	switch p.Type {
+	case "openai":
+		if p.APIKey == "" && p.BaseURL == "" {
+			add(SeverityWarning, "provider.api_key", "no API key is set for OpenAI")
+		}
+	case "anthropic":
+		if p.APIKey == "" && p.OAuthToken == "" {
+			add(SeverityError, "provider.api_key", "anthropic requires either api_key or oauth_token")
+		}
+	case "azure":
+		if p.BaseURL == "" && p.AzureEndpoint == "" {
+			add(SeverityError, "provider.azure_endpoint", "endpoint URL is required for Azure OpenAI (set base_url or azure_endpoint)")
+		}
+		if p.APIKey == "" {
+			add(SeverityWarning, "provider.api_key", "no API key is set for Azure OpenAI")
+		}
+		if p.AzureDeployment == "" {
+			add(SeverityError, "provider.azure_deployment", "no deployment is mapped to model %s", p.Model)
+		}
+		if p.APIVersion == "" {
+			add(SeverityWarning, "provider.api_version", "no API version is set; the provider default is used")
+		}
+	case "bedrock":
+		if (p.AWSAccessKeyID == "") != (p.AWSSecretAccessKey == "") {
+			add(SeverityError, "provider.aws_access_key_id", "AWS access key ID and secret access key must be set together for Bedrock")
+		}
+	case "vertex":
+		if p.APIKey == "" {
+			add(SeverityError, "provider.api_key", "service account credentials (api_key) are required for Vertex AI")
+		}
+	case "openrouter":
+		if p.APIKey == "" {
+			add(SeverityError, "provider.api_key", "API key is required for OpenRouter")
+		}
+	case "nim", "triton":
+		if p.BaseURL == "" {
+			add(SeverityError, "provider.base_url", "base URL of the inference server is required for %s", p.Type)
+		}
+	case "watsonx":
+		if p.APIKey == "" {
+			add(SeverityError, "provider.api_key", "IBM Cloud API key is required for watsonx.ai")
+		}
+		if p.BaseURL == "" {
+			add(SeverityError, "provider.base_url", "regional endpoint URL is required for watsonx.ai")
+		}
+		if p.ProjectID == "" && p.SpaceID == "" {
+			add(SeverityError, "provider.project_id", "project_id or space_id is required for watsonx.ai")
+		}
+	case "azure_mistral":
+		if p.APIKey == "" {
+			add(SeverityError, "provider.api_key", "API key is required for Azure Mistral")
+		}
+		if p.BaseURL == "" {
+			add(SeverityError, "provider.base_url", "endpoint URL is required for Azure Mistral")
+		}
+	case "ollama":
+		if p.BaseURL == "" {
+			add(SeverityWarning, "provider.base_url", "no base URL is set; the local server at http://localhost:11434 is used")
+		}
+	case "dashscope":
+		if p.APIKey == "" {
+			add(SeverityError, "provider.api_key", "API key is required for DashScope")
+		}
+	case "qianfan":
+		if p.APIKey == "" {
+			add(SeverityError, "provider.api_key", "API key is required for Qianfan")
+		}
+		if p.APISecret == "" {
+			add(SeverityError, "provider.api_secret", "secret key (api_secret) is required for Qianfan")
+		}
+	case "perplexity":
+		if p.APIKey == "" {
+			add(SeverityError, "provider.api_key", "API key is required for Perplexity")
+		}
+	case "custom-openai":
+		if p.BaseURL == "" {
+			add(SeverityError, "provider.base_url", "base URL of the server is required for custom-openai")
+		}
+		for endpoint := range p.Paths {
+			if _, ok := providers.CustomOpenAIPaths[endpoint]; !ok {
+				add(SeverityError, "provider.paths."+endpoint, "unknown endpoint %s; paths can be set for chat_completions, completions, embeddings and models", endpoint)
+			}
+		}
+	case "":
+	default:
+		add(SeverityError, "provider.type", "unsupported provider type: %s", p.Type)
+	}
+
+	if inst.Weight < 0 {
+		add(SeverityError, "weight", "weight must not be negative")
+	}
+	if prov := inst.Provisioned; prov.CapacityUnits > 0 || prov.TPMPerUnit > 0 {
+		if prov.CapacityUnits <= 0 || prov.TPMPerUnit <= 0 {
+			add(SeverityError, "provisioned", "capacity_units and tpm_per_unit must both be set for reserved capacity")
+		}
+		if prov.OverflowThreshold < 0 || prov.OverflowThreshold > 1 {
+			add(SeverityError, "provisioned.overflow_threshold", "overflow_threshold must be between 0 and 1")
+		}
+	}
+	if inst.ModelInfo.MaxOutputTokens > 0 && inst.ModelInfo.MaxTokens > 0 && inst.ModelInfo.MaxOutputTokens > inst.ModelInfo.MaxTokens {
+		add(SeverityWarning, "model_info.max_output_tokens", "max_output_tokens is larger than the context window")
+	}
+
+	// Constructors catch what the field checks cannot, such as malformed
+	// credentials
+	if !hasErrors(issues) {
+		provider, err := registry.CreateProvider(p)
+		if err != nil {
+			add(SeverityError, "provider", "failed to create provider: %v", err)
+		} else if _, ok := provider.(providers.ModelDiscoverer); inst.DiscoversModels() && !ok {
+			add(SeverityError, "provider.model", "%s cannot list its models; set the model to serve instead of %q", p.Type, config.DiscoverAllModels)
+		}
+	}
+	return issues
+}
+
+// checkConnectivity health checks the providers of enabled instances that
+// passed validation, in parallel
+func checkConnectivity(ctx context.Context, registry *ModelRegistry, instances []config.ModelInstance, reports []InstanceReport, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for i := range reports {
+		report := &reports[i]
+		if !report.Enabled || hasErrors(report.Issues) {
+			report.Connectivity = ConnectivitySkipped
+			continue
+		}
+
+		provider, err := registry.CreateProvider(instances[i].Provider)
+		if err != nil {
+			report.Connectivity = ConnectivityFailed
+			report.Issues = append(report.Issues, ValidationIssue{Severity: SeverityError, Field: "provider", Message: err.Error()})
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := provider.HealthCheck(checkCtx)
+			report.Latency = time.Since(start).Round(time.Millisecond).String()
+			if err != nil {
+				report.Connectivity = ConnectivityFailed
+				report.Issues = append(report.Issues, ValidationIssue{
+					Severity: SeverityError,
+					Field:    "connectivity",
+					Message:  fmt.Sprintf("health check failed: %v", err),
+				})
+				return
+			}
+			report.Connectivity = ConnectivityOK
+		}()
+	}
+	wg.Wait()
+}
+
+// validateReferences checks that aliases, routes, tiers and fallbacks name
+// models of the model list. Models can also be added at runtime, so unknown
+// names are warnings. Invalid route defaults are errors.
+func validateReferences(cfg *config.Config, modelNames map[string]bool) []ValidationIssue {
+	var issues []ValidationIssue
+	check := func(field, model string) {
+		if model != "" && !modelNames[model] {
+			issues = append(issues, ValidationIssue{
+				Severity: SeverityWarning,
+				Field:    field,
+				Message:  fmt.Sprintf("model %s is not in model_list", model),
+			})
+		}
+	}
+
+	for alias, targets := range cfg.ModelAliases {
+		for _, model := range targets {
+			check("model_aliases."+alias, model)
+		}
+	}
+	for model, fallbacks := range cfg.Router.Fallbacks {
+		check("router.fallbacks", model)
+		for _, fallback := range fallbacks {
+			check("router.fallbacks."+model, fallback)
+		}
+	}
+	for _, route := range cfg.Routes {
+		if route.Slug == "" {
+			issues = append(issues, ValidationIssue{Severity: SeverityError, Field: "routes", Message: fmt.Sprintf("route %q has no slug", route.Name)})
+		}
+		for _, model := range route.Models {
+			check("routes."+route.Slug, model.ModelName)
+		}
+		for _, model := range route.FallbackModels {
+			check("routes."+route.Slug+".fallback_models", model)
+		}
+		if err := ValidateRouteDefaults(route.Defaults); err != nil {
+			issues = append(issues, ValidationIssue{Severity: SeverityError, Field: "routes." + route.Slug + ".defaults", Message: err.Error()})
+		}
+	}
+	for _, tier := range cfg.Tiers {
+		for _, model := range tier.Models {
+			check("tiers."+tier.Name, model)
+		}
+	}
+	return issues
+}
+
+// forEachStringParam calls fn with the mapstructure name and value of every
+// non-empty string field of the provider parameters
+func forEachStringParam(p config.ProviderParams, fn func(field, value string)) {
+	v := reflect.ValueOf(p)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() != reflect.String {
+			continue
+		}
+		if value := v.Field(i).String(); value != "" {
+			fn(t.Field(i).Tag.Get("mapstructure"), value)
+		}
+	}
+}
+
+func hasErrors(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
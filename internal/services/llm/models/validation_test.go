@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func issueFields(issues []ValidationIssue) []string {
+	fields := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		fields = append(fields, issue.Severity+":"+issue.Field)
+	}
+	return fields
+}
+
+func TestValidateConfig(t *testing.T) {
+	t.Setenv("PLLM_TEST_OPENAI_KEY", "sk-test")
+
+	cfg := &config.Config{
+		ModelList: []config.ModelInstance{
+			{ModelName: "gpt-4o", Enabled: true, Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o", APIKey: "sk-test"}},
+			{ModelName: "azure-gpt-4o", Enabled: true, Provider: config.ProviderParams{
+				Type: "azure", Model: "gpt-4o", APIKey: "${PLLM_TEST_MISSING_KEY}",
+				AzureEndpoint: "https://example.openai.azure.com", APIVersion: "2024-02-01",
+			}},
+			{ModelName: "claude", Enabled: true, Provider: config.ProviderParams{
+				Type: "anthropic", Model: "claude-3-5-sonnet", APIKey: "sk-ant", BaseURL: "${PLLM_TEST_OPENAI_KEY}",
+			}},
+			{ModelName: "mystery", Enabled: false, Provider: config.ProviderParams{Type: "mystery", Model: "m"}},
+		},
+		ModelAliases: map[string][]string{"smart": {"gpt-4o", "gpt-5"}},
+		Routes: []config.RouteConfig{
+			{Name: "Default", Slug: "default", Models: []config.RouteModelConfig{{ModelName: "gpt-4o"}}},
+		},
+	}
+
+	report := ValidateConfig(context.Background(), cfg, ValidationOptions{})
+	require.Len(t, report.Instances, 4)
+
+	assert.Empty(t, report.Instances[0].Issues)
+	assert.ElementsMatch(t, []string{
+		"error:provider.api_key",          // ${PLLM_TEST_MISSING_KEY} is not set
+		"error:provider.azure_deployment", // no deployment mapping
+	}, issueFields(report.Instances[1].Issues))
+	assert.Equal(t, []string{"warning:provider.base_url"}, issueFields(report.Instances[2].Issues))
+	assert.Equal(t, []string{"error:provider.type"}, issueFields(report.Instances[3].Issues))
+	assert.Equal(t, []string{"warning:model_aliases.smart"}, issueFields(report.Issues))
+
+	assert.False(t, report.Valid)
+	assert.Equal(t, 3, report.Errors)
+	assert.Equal(t, 2, report.Warnings)
+	assert.Empty(t, report.Instances[0].Connectivity, "connectivity is not checked by default")
+}
+
+func TestValidateConfig_Strict(t *testing.T) {
+	cfg := &config.Config{
+		ModelList: []config.ModelInstance{
+			{ModelName: "gpt-4o", Enabled: true, Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o", APIKey: "sk-test"}},
+		},
+		Tiers: []config.TierConfig{{Name: "fast", Models: []string{"gpt-4o-mini"}}},
+	}
+
+	assert.True(t, ValidateConfig(context.Background(), cfg, ValidationOptions{}).Valid)
+	assert.False(t, ValidateConfig(context.Background(), cfg, ValidationOptions{Strict: true}).Valid)
+}
+
+func TestValidateConfig_Connectivity(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o","object":"model"}]}`))
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer down.Close()
+
+	cfg := &config.Config{
+		ModelList: []config.ModelInstance{
+			{ModelName: "up", Enabled: true, Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o", APIKey: "sk-test", BaseURL: up.URL}},
+			{ModelName: "down", Enabled: true, Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o", APIKey: "sk-test", BaseURL: down.URL}},
+			{ModelName: "disabled", Enabled: false, Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o", APIKey: "sk-test", BaseURL: down.URL}},
+		},
+	}
+
+	report := ValidateConfig(context.Background(), cfg, ValidationOptions{CheckConnectivity: true})
+
+	assert.Equal(t, ConnectivityOK, report.Instances[0].Connectivity)
+	assert.Equal(t, ConnectivityFailed, report.Instances[1].Connectivity)
+	assert.Equal(t, []string{"error:connectivity"}, issueFields(report.Instances[1].Issues))
+	assert.Equal(t, ConnectivitySkipped, report.Instances[2].Connectivity)
+	assert.False(t, report.Valid)
+}
@@ -1,7 +1,6 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -49,7 +48,8 @@ func NewAnthropicProvider(name string, cfg ProviderConfig) (*AnthropicProvider,
 		oauthToken:   cfg.OAuthToken,
 		baseURL:      baseURL,
 		client: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: newCaptureTransport("anthropic"),
 		},
 	}, nil
 }
@@ -212,8 +212,10 @@ func (p *AnthropicProvider) ChatCompletionStream(ctx context.Context, request *C
 			return // Just close channel on error
 		}
 
-		// Parse SSE stream
-		p.parseStreamResponse(resp.Body, streamChan)
+		// Convert the event stream to chat completion chunks
+		if err := NewStreamTranscoder(StreamFormatAnthropic, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			log.Printf("Anthropic stream failed: %v", err)
+		}
 	}()
 
 	return streamChan, nil
@@ -277,7 +279,7 @@ type AnthropicRequest struct {
 	Model       string             `json:"model"`
 	MaxTokens   int                `json:"max_tokens"`
 	Messages    []AnthropicMessage `json:"messages"`
-	System      string             `json:"system,omitempty"`
+	System      interface{}        `json:"system,omitempty"` // string, or []AnthropicContent with a cache breakpoint
 	Temperature *float32           `json:"temperature,omitempty"`
 	TopP        *float32           `json:"top_p,omitempty"`
 	Stream      bool               `json:"stream,omitempty"`
@@ -290,9 +292,15 @@ type AnthropicMessage struct {
 }
 
 type AnthropicContent struct {
-	Type   string                `json:"type"`
-	Text   string                `json:"text,omitempty"`
-	Source *AnthropicImageSource `json:"source,omitempty"`
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text,omitempty"`
+	Source       *AnthropicImageSource  `json:"source,omitempty"`
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// AnthropicCacheControl marks the end of a prompt prefix to cache
+type AnthropicCacheControl struct {
+	Type string `json:"type"` // "ephemeral"
 }
 
 type AnthropicImageSource struct {
@@ -315,11 +323,33 @@ type AnthropicResponse struct {
 type AnthropicContentBlock struct {
 	Type string `json:"type"`
 	Text string `json:"text"`
+	ID   string `json:"id,omitempty"`   // tool_use blocks
+	Name string `json:"name,omitempty"` // tool_use blocks
 }
 
 type AnthropicUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// PromptTokens returns all input tokens; input_tokens excludes cached ones
+func (u AnthropicUsage) PromptTokens() int {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// ToUsage converts to OpenAI usage, reporting cache reads as cached tokens
+func (u AnthropicUsage) ToUsage() Usage {
+	usage := Usage{
+		PromptTokens:     u.PromptTokens(),
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.PromptTokens() + u.OutputTokens,
+	}
+	if u.CacheReadInputTokens > 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{CachedTokens: u.CacheReadInputTokens}
+	}
+	return usage
 }
 
 type AnthropicStreamResponse struct {
@@ -328,15 +358,25 @@ type AnthropicStreamResponse struct {
 	Index        int                    `json:"index,omitempty"`
 	ContentBlock *AnthropicContentBlock `json:"content_block,omitempty"`
 	Delta        *AnthropicDelta        `json:"delta,omitempty"`
+	Usage        *AnthropicUsage        `json:"usage,omitempty"` // message_delta: output tokens so far
+	Error        *AnthropicStreamError  `json:"error,omitempty"`
 }
 
 type AnthropicDelta struct {
 	Type         string `json:"type"`
 	Text         string `json:"text"`
+	PartialJSON  string `json:"partial_json,omitempty"` // input_json_delta: a piece of the tool input
 	StopReason   string `json:"stop_reason"`
 	StopSequence string `json:"stop_sequence"`
 }
 
+// AnthropicStreamError is the error event of a stream that failed after it
+// started, such as overloaded_error
+type AnthropicStreamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
 // transformToAnthropicRequest converts OpenAI format to Anthropic format
 func (p *AnthropicProvider) transformToAnthropicRequest(req *ChatRequest) (*AnthropicRequest, error) {
 	antReq := &AnthropicRequest{
@@ -359,11 +399,19 @@ func (p *AnthropicProvider) transformToAnthropicRequest(req *ChatRequest) (*Anth
 	}
 
 	// Convert messages and handle system messages
-	for _, msg := range req.Messages {
+	for i, msg := range req.Messages {
+		breakpoint := i == req.CachedPrefix-1
 		if msg.Role == "system" {
 			// Anthropic handles system messages differently
 			if contentStr, ok := msg.Content.(string); ok {
 				antReq.System = contentStr
+				if breakpoint {
+					antReq.System = []AnthropicContent{{
+						Type:         "text",
+						Text:         contentStr,
+						CacheControl: &AnthropicCacheControl{Type: "ephemeral"},
+					}}
+				}
 			}
 			continue
 		}
@@ -425,6 +473,11 @@ func (p *AnthropicProvider) transformToAnthropicRequest(req *ChatRequest) (*Anth
 			}
 		}
 
+		// The last block of the cached prefix becomes the cache breakpoint
+		if breakpoint && len(antMsg.Content) > 0 {
+			antMsg.Content[len(antMsg.Content)-1].CacheControl = &AnthropicCacheControl{Type: "ephemeral"}
+		}
+
 		antReq.Messages = append(antReq.Messages, antMsg)
 	}
 
@@ -453,81 +506,6 @@ func (p *AnthropicProvider) transformToOpenAIResponse(antResp *AnthropicResponse
 				FinishReason: antResp.StopReason,
 			},
 		},
-		Usage: Usage{
-			PromptTokens:     antResp.Usage.InputTokens,
-			CompletionTokens: antResp.Usage.OutputTokens,
-			TotalTokens:      antResp.Usage.InputTokens + antResp.Usage.OutputTokens,
-		},
+		Usage: antResp.Usage.ToUsage(),
 	}
 }
-
-// parseStreamResponse parses the SSE stream response from Anthropic
-func (p *AnthropicProvider) parseStreamResponse(body io.Reader, streamChan chan<- StreamResponse) {
-	bufReader := bufio.NewReader(body)
-
-	for {
-		line, err := bufReader.ReadString('\n')
-		if err != nil {
-			if err != io.EOF {
-				// Log error but continue
-				log.Printf("Error reading Anthropic response: %v", err)
-			}
-			return
-		}
-
-		line = strings.TrimSpace(line)
-
-		// Skip empty lines
-		if line == "" {
-			continue
-		}
-
-		// Check for SSE data prefix
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-
-		// Extract data
-		data := strings.TrimPrefix(line, "data: ")
-
-		// Check for end of stream
-		if data == "[DONE]" {
-			return
-		}
-
-		// Parse JSON data
-		var antStreamResp AnthropicStreamResponse
-		if err := json.Unmarshal([]byte(data), &antStreamResp); err != nil {
-			// Skip malformed data
-			continue
-		}
-
-		// Convert to OpenAI format and send
-		if streamResp := p.convertStreamResponse(&antStreamResp); streamResp != nil {
-			streamChan <- *streamResp
-		}
-	}
-}
-
-// convertStreamResponse converts Anthropic stream response to OpenAI format
-func (p *AnthropicProvider) convertStreamResponse(antResp *AnthropicStreamResponse) *StreamResponse {
-	if antResp.Type == "content_block_delta" && antResp.Delta != nil {
-		return &StreamResponse{
-			ID:      GenerateID(),
-			Object:  "chat.completion.chunk",
-			Created: time.Now().Unix(),
-			Model:   "claude",
-			Choices: []StreamChoice{
-				{
-					Index: 0,
-					Delta: Message{
-						Role:    "assistant",
-						Content: antResp.Delta.Text,
-					},
-					FinishReason: antResp.Delta.StopReason,
-				},
-			},
-		}
-	}
-	return nil
-}
\ No newline at end of file
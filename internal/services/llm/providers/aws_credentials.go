@@ -0,0 +1,586 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// awsCredentialRefreshWindow refreshes temporary credentials this long
+	// before they expire
+	awsCredentialRefreshWindow = 5 * time.Minute
+
+	defaultAWSRoleSessionName = "pllm"
+	defaultIMDSEndpoint       = "http://169.254.169.254"
+	ecsCredentialsEndpoint    = "http://169.254.170.2"
+)
+
+// AWSCredentials are AWS access keys, either long-lived or temporary
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time // Zero for credentials that do not expire
+	Source          string
+}
+
+func (c AWSCredentials) expiresWithin(d time.Duration) bool {
+	return !c.Expires.IsZero() && time.Until(c.Expires) < d
+}
+
+// AWSCredentialsProvider retrieves AWS credentials
+type AWSCredentialsProvider interface {
+	Retrieve(ctx context.Context) (AWSCredentials, error)
+}
+
+// AWSCredentialsOptions configures NewAWSCredentialsProvider
+type AWSCredentialsOptions struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Profile         string // Shared config profile; defaults to AWS_PROFILE or "default"
+	Region          string // Region for STS calls
+
+	// RoleARN is assumed on top of the base credentials
+	RoleARN         string
+	ExternalID      string
+	RoleSessionName string
+
+	HTTPClient *http.Client
+}
+
+// NewAWSCredentialsProvider returns the credentials for an instance: the
+// static keys when configured, otherwise the default AWS credential chain,
+// optionally assuming a role on top. Temporary credentials are cached and
+// refreshed shortly before they expire.
+func NewAWSCredentialsProvider(opts AWSCredentialsOptions) (AWSCredentialsProvider, error) {
+	if (opts.AccessKeyID == "") != (opts.SecretAccessKey == "") {
+		return nil, fmt.Errorf("AWS access key ID and secret access key must be set together")
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	sts := &awsSTSClient{client: client, endpoint: awsSTSEndpoint(opts.Region)}
+
+	var provider AWSCredentialsProvider
+	if opts.AccessKeyID != "" {
+		provider = staticAWSCredentials{creds: AWSCredentials{
+			AccessKeyID:     opts.AccessKeyID,
+			SecretAccessKey: opts.SecretAccessKey,
+			SessionToken:    opts.SessionToken,
+			Source:          "static",
+		}}
+	} else {
+		provider = &awsDefaultChain{client: client, sts: sts, profile: opts.Profile}
+	}
+
+	if opts.RoleARN != "" {
+		sessionName := opts.RoleSessionName
+		if sessionName == "" {
+			sessionName = defaultAWSRoleSessionName
+		}
+		provider = &awsAssumeRoleProvider{
+			base:        newCachedAWSCredentials(provider),
+			sts:         sts,
+			region:      opts.Region,
+			roleARN:     opts.RoleARN,
+			externalID:  opts.ExternalID,
+			sessionName: sessionName,
+		}
+	}
+
+	return newCachedAWSCredentials(provider), nil
+}
+
+type staticAWSCredentials struct {
+	creds AWSCredentials
+}
+
+func (s staticAWSCredentials) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	return s.creds, nil
+}
+
+// cachedAWSCredentials serves credentials until they are about to expire
+type cachedAWSCredentials struct {
+	mu       sync.Mutex
+	provider AWSCredentialsProvider
+	creds    AWSCredentials
+	loaded   bool
+}
+
+func newCachedAWSCredentials(provider AWSCredentialsProvider) *cachedAWSCredentials {
+	return &cachedAWSCredentials{provider: provider}
+}
+
+func (c *cachedAWSCredentials) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loaded && !c.creds.expiresWithin(awsCredentialRefreshWindow) {
+		return c.creds, nil
+	}
+
+	creds, err := c.provider.Retrieve(ctx)
+	if err != nil {
+		// Keep serving credentials that have not actually expired yet
+		if c.loaded && !c.creds.expiresWithin(0) {
+			return c.creds, nil
+		}
+		return AWSCredentials{}, err
+	}
+	c.creds, c.loaded = creds, true
+	return creds, nil
+}
+
+// awsDefaultChain resolves credentials the way the AWS SDKs do: environment
+// variables, web identity (EKS IRSA), the shared config profile (static keys
+// or IAM Identity Center SSO), container credentials (ECS, EKS Pod Identity)
+// and finally the EC2 instance profile
+type awsDefaultChain struct {
+	client  *http.Client
+	sts     *awsSTSClient
+	profile string
+}
+
+func (c *awsDefaultChain) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return AWSCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: secret,
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			Source:          "environment",
+		}, nil
+	}
+
+	if tokenFile, roleARN := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN"); tokenFile != "" && roleARN != "" {
+		return c.webIdentity(ctx, tokenFile, roleARN)
+	}
+
+	if creds, ok, err := c.sharedProfile(ctx); ok || err != nil {
+		return creds, err
+	}
+
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return c.container(ctx)
+	}
+
+	if !strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		creds, err := c.instanceProfile(ctx)
+		if err == nil {
+			return creds, nil
+		}
+		return AWSCredentials{}, fmt.Errorf("no AWS credentials found in the default chain: %w", err)
+	}
+	return AWSCredentials{}, fmt.Errorf("no AWS credentials found in the default chain")
+}
+
+func (c *awsDefaultChain) webIdentity(ctx context.Context, tokenFile, roleARN string) (AWSCredentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to read web identity token: %w", err)
+	}
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = defaultAWSRoleSessionName
+	}
+
+	creds, err := c.sts.call(ctx, url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}, nil, "")
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("web identity: %w", err)
+	}
+	creds.Source = "web_identity"
+	return creds, nil
+}
+
+// sharedProfile reads static keys from the shared credentials file or SSO
+// settings from the shared config file. ok is false when the profile does
+// not exist or holds neither.
+func (c *awsDefaultChain) sharedProfile(ctx context.Context) (AWSCredentials, bool, error) {
+	profile := c.profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if home, err := os.UserHomeDir(); err == nil {
+		if credentialsFile == "" {
+			credentialsFile = filepath.Join(home, ".aws", "credentials")
+		}
+		if configFile == "" {
+			configFile = filepath.Join(home, ".aws", "config")
+		}
+	}
+
+	if section := readINISection(credentialsFile, profile); section["aws_access_key_id"] != "" {
+		return AWSCredentials{
+			AccessKeyID:     section["aws_access_key_id"],
+			SecretAccessKey: section["aws_secret_access_key"],
+			SessionToken:    section["aws_session_token"],
+			Source:          "shared_credentials:" + profile,
+		}, true, nil
+	}
+
+	configSection := "profile " + profile
+	if profile == "default" {
+		configSection = "default"
+	}
+	section := readINISection(configFile, configSection)
+	if section["aws_access_key_id"] != "" {
+		return AWSCredentials{
+			AccessKeyID:     section["aws_access_key_id"],
+			SecretAccessKey: section["aws_secret_access_key"],
+			SessionToken:    section["aws_session_token"],
+			Source:          "shared_config:" + profile,
+		}, true, nil
+	}
+
+	if section["sso_account_id"] == "" || section["sso_role_name"] == "" {
+		return AWSCredentials{}, false, nil
+	}
+	creds, err := c.sso(ctx, configFile, section)
+	if err != nil {
+		return AWSCredentials{}, true, fmt.Errorf("sso profile %q: %w", profile, err)
+	}
+	creds.Source = "sso:" + profile
+	return creds, true, nil
+}
+
+// sso exchanges the cached IAM Identity Center token (from `aws sso login`)
+// for role credentials
+func (c *awsDefaultChain) sso(ctx context.Context, configFile string, profile map[string]string) (AWSCredentials, error) {
+	startURL, ssoRegion, cacheKey := profile["sso_start_url"], profile["sso_region"], profile["sso_start_url"]
+	if sessionName := profile["sso_session"]; sessionName != "" {
+		session := readINISection(configFile, "sso-session "+sessionName)
+		startURL, ssoRegion, cacheKey = session["sso_start_url"], session["sso_region"], sessionName
+	}
+	if startURL == "" || ssoRegion == "" {
+		return AWSCredentials{}, fmt.Errorf("sso_start_url and sso_region are required")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	hash := sha1.Sum([]byte(cacheKey))
+	data, err := os.ReadFile(filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(hash[:])+".json"))
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("no cached SSO token, run `aws sso login`: %w", err)
+	}
+	var token struct {
+		AccessToken string    `json:"accessToken"`
+		ExpiresAt   time.Time `json:"expiresAt"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return AWSCredentials{}, fmt.Errorf("invalid cached SSO token: %w", err)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return AWSCredentials{}, fmt.Errorf("cached SSO token expired, run `aws sso login`")
+	}
+
+	endpoint := fmt.Sprintf("https://portal.sso.%s.amazonaws.com/federation/credentials?%s", ssoRegion, url.Values{
+		"account_id": {profile["sso_account_id"]},
+		"role_name":  {profile["sso_role_name"]},
+	}.Encode())
+	var result struct {
+		RoleCredentials struct {
+			AccessKeyID     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			Expiration      int64  `json:"expiration"` // Milliseconds since epoch
+		} `json:"roleCredentials"`
+	}
+	if err := c.getJSON(ctx, endpoint, map[string]string{"x-amz-sso_bearer_token": token.AccessToken}, &result); err != nil {
+		return AWSCredentials{}, err
+	}
+	return AWSCredentials{
+		AccessKeyID:     result.RoleCredentials.AccessKeyID,
+		SecretAccessKey: result.RoleCredentials.SecretAccessKey,
+		SessionToken:    result.RoleCredentials.SessionToken,
+		Expires:         time.UnixMilli(result.RoleCredentials.Expiration),
+	}, nil
+}
+
+// container reads credentials from the ECS task role or EKS Pod Identity agent
+func (c *awsDefaultChain) container(ctx context.Context) (AWSCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		endpoint = ecsCredentialsEndpoint + relative
+	}
+
+	headers := map[string]string{}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("failed to read container authorization token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		headers["Authorization"] = token
+	}
+
+	var result awsMetadataCredentials
+	if err := c.getJSON(ctx, endpoint, headers, &result); err != nil {
+		return AWSCredentials{}, fmt.Errorf("container credentials: %w", err)
+	}
+	return result.credentials("container"), nil
+}
+
+// instanceProfile reads the EC2 instance role through IMDSv2
+func (c *awsDefaultChain) instanceProfile(ctx context.Context) (AWSCredentials, error) {
+	endpoint := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = defaultIMDSEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	// Off EC2 the metadata address does not answer; fail fast
+	imdsCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(imdsCtx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("instance metadata unavailable: %w", err)
+	}
+	tokenBytes, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return AWSCredentials{}, fmt.Errorf("instance metadata token request failed: status %d", resp.StatusCode)
+	}
+	headers := map[string]string{"X-aws-ec2-metadata-token": string(tokenBytes)}
+
+	roleBytes, err := c.get(imdsCtx, endpoint+"/latest/meta-data/iam/security-credentials/", headers)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("no instance profile role: %w", err)
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roleBytes), "\n", 2)[0])
+
+	var result awsMetadataCredentials
+	if err := c.getJSON(imdsCtx, endpoint+"/latest/meta-data/iam/security-credentials/"+role, headers, &result); err != nil {
+		return AWSCredentials{}, fmt.Errorf("instance profile credentials: %w", err)
+	}
+	return result.credentials("instance_profile"), nil
+}
+
+func (c *awsDefaultChain) get(ctx context.Context, endpoint string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func (c *awsDefaultChain) getJSON(ctx context.Context, endpoint string, headers map[string]string, v interface{}) error {
+	body, err := c.get(ctx, endpoint, headers)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// awsMetadataCredentials is the credential document served by IMDS and the
+// container credential endpoints
+type awsMetadataCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (m awsMetadataCredentials) credentials(source string) AWSCredentials {
+	return AWSCredentials{
+		AccessKeyID:     m.AccessKeyID,
+		SecretAccessKey: m.SecretAccessKey,
+		SessionToken:    m.Token,
+		Expires:         m.Expiration,
+		Source:          source,
+	}
+}
+
+// awsAssumeRoleProvider assumes a role with the base credentials
+type awsAssumeRoleProvider struct {
+	base        AWSCredentialsProvider
+	sts         *awsSTSClient
+	region      string
+	roleARN     string
+	externalID  string
+	sessionName string
+}
+
+func (p *awsAssumeRoleProvider) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	base, err := p.base.Retrieve(ctx)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	params := url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {"2011-06-15"},
+		"RoleArn":         {p.roleARN},
+		"RoleSessionName": {p.sessionName},
+	}
+	if p.externalID != "" {
+		params.Set("ExternalId", p.externalID)
+	}
+
+	creds, err := p.sts.call(ctx, params, &base, p.region)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("assume role %s: %w", p.roleARN, err)
+	}
+	creds.Source = "assume_role:" + base.Source
+	return creds, nil
+}
+
+// awsSTSClient calls the STS query API
+type awsSTSClient struct {
+	client   *http.Client
+	endpoint string
+}
+
+func awsSTSEndpoint(region string) string {
+	if region == "" {
+		return "https://sts.amazonaws.com"
+	}
+	return fmt.Sprintf("https://sts.%s.amazonaws.com", region)
+}
+
+// call posts an STS action and returns the credentials in its result. The
+// request is signed when signer credentials are given.
+func (s *awsSTSClient) call(ctx context.Context, params url.Values, signer *AWSCredentials, region string) (AWSCredentials, error) {
+	body := []byte(params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/", strings.NewReader(string(body)))
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	if signer != nil {
+		if region == "" {
+			region = "us-east-1"
+		}
+		signAWSRequest(req, body, *signer, region, "sts", time.Now())
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return AWSCredentials{}, fmt.Errorf("sts error: status %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	// <AssumeRoleResponse><AssumeRoleResult><Credentials>…, same shape for
+	// AssumeRoleWithWebIdentity
+	var result struct {
+		Result struct {
+			Credentials struct {
+				AccessKeyID     string    `xml:"AccessKeyId"`
+				SecretAccessKey string    `xml:"SecretAccessKey"`
+				SessionToken    string    `xml:"SessionToken"`
+				Expiration      time.Time `xml:"Expiration"`
+			} `xml:"Credentials"`
+		} `xml:",any"`
+	}
+	if err := xml.Unmarshal(respBody, &result); err != nil {
+		return AWSCredentials{}, fmt.Errorf("invalid sts response: %w", err)
+	}
+	creds := result.Result.Credentials
+	if creds.AccessKeyID == "" {
+		return AWSCredentials{}, fmt.Errorf("sts response contains no credentials")
+	}
+	return AWSCredentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Expires:         creds.Expiration,
+	}, nil
+}
+
+// readINISection returns the key/value pairs of one [section] of an AWS
+// shared config or credentials file, or nil when missing
+func readINISection(path, name string) map[string]string {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	var section map[string]string
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.Join(strings.Fields(line[1:len(line)-1]), " ") == name
+			if inSection && section == nil {
+				section = map[string]string{}
+			}
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return section
+}
@@ -0,0 +1,129 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAWSRequest_ReferenceVector(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWSRequest(req, nil, creds, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		req.Header.Get("Authorization"))
+}
+
+func TestSignAWSRequest_SessionToken(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-3-haiku-20240307-v1:0/invoke", nil)
+	require.NoError(t, err)
+
+	signAWSRequest(req, []byte("{}"), AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "token"}, "us-east-1", "bedrock", time.Now())
+
+	assert.Equal(t, "token", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token")
+}
+
+func TestNewAWSCredentialsProvider_Static(t *testing.T) {
+	provider, err := NewAWSCredentialsProvider(AWSCredentialsOptions{AccessKeyID: "id", SecretAccessKey: "secret"})
+	require.NoError(t, err)
+
+	creds, err := provider.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "id", creds.AccessKeyID)
+	assert.Equal(t, "static", creds.Source)
+
+	_, err = NewAWSCredentialsProvider(AWSCredentialsOptions{AccessKeyID: "id"})
+	assert.Error(t, err)
+}
+
+func TestNewAWSCredentialsProvider_EnvironmentChain(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "env-token")
+
+	provider, err := NewAWSCredentialsProvider(AWSCredentialsOptions{})
+	require.NoError(t, err)
+
+	creds, err := provider.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "env-id", creds.AccessKeyID)
+	assert.Equal(t, "env-token", creds.SessionToken)
+	assert.Equal(t, "environment", creds.Source)
+}
+
+func TestNewAWSCredentialsProvider_AssumeRoleRefresh(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "AssumeRole", r.Form.Get("Action"))
+		assert.Equal(t, "arn:aws:iam::123456789012:role/bedrock", r.Form.Get("RoleArn"))
+		assert.Equal(t, "external", r.Form.Get("ExternalId"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=base-id/"))
+
+		n := calls.Add(1)
+		// The first credentials fall inside the refresh window
+		expires := time.Now().Add(time.Minute)
+		if n > 1 {
+			expires = time.Now().Add(time.Hour)
+		}
+		_, _ = fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>role-id-%d</AccessKeyId><SecretAccessKey>role-secret</SecretAccessKey>
+<SessionToken>role-token</SessionToken><Expiration>%s</Expiration>
+</Credentials></AssumeRoleResult></AssumeRoleResponse>`, n, expires.UTC().Format(time.RFC3339))
+	}))
+	defer server.Close()
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	provider, err := NewAWSCredentialsProvider(AWSCredentialsOptions{
+		AccessKeyID:     "base-id",
+		SecretAccessKey: "base-secret",
+		Region:          "us-west-2",
+		RoleARN:         "arn:aws:iam::123456789012:role/bedrock",
+		ExternalID:      "external",
+		HTTPClient:      &http.Client{Transport: &rewriteTransport{target: target}},
+	})
+	require.NoError(t, err)
+
+	creds, err := provider.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "role-id-1", creds.AccessKeyID)
+	assert.Equal(t, "assume_role:static", creds.Source)
+
+	creds, err = provider.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "role-id-2", creds.AccessKeyID)
+
+	creds, err = provider.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "role-id-2", creds.AccessKeyID)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestNewBedrockProvider_WithoutStaticKeys(t *testing.T) {
+	provider, err := NewBedrockProvider("bedrock", ProviderConfig{
+		Type:   "bedrock",
+		Region: "eu-west-1",
+		Extra:  map[string]interface{}{"aws_role_arn": "arn:aws:iam::123456789012:role/bedrock"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://bedrock-runtime.eu-west-1.amazonaws.com", provider.config.BaseURL)
+}
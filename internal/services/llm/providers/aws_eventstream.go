@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// maxEventStreamMessage bounds a single event stream message, well above
+// the 16 MB AWS allows for a payload
+const maxEventStreamMessage = 24 << 20
+
+// readEventStream decodes an AWS event stream (application/vnd.amazon.eventstream)
+// and calls handle with the payload of each chunk event. Bedrock wraps the
+// model's own event in the payload as {"bytes": "<base64>"}. Exception
+// messages end the stream with their error.
+func readEventStream(body io.Reader, handle func(payload []byte) error) error {
+	reader := bufio.NewReader(body)
+	prelude := make([]byte, 12)
+
+	for {
+		if _, err := io.ReadFull(reader, prelude); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read event stream: %w", err)
+		}
+
+		// Prelude: total length, headers length, prelude CRC
+		total := binary.BigEndian.Uint32(prelude[0:4])
+		headersLen := binary.BigEndian.Uint32(prelude[4:8])
+		if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+			return errors.New("event stream prelude checksum mismatch")
+		}
+		if total < 16 || total > maxEventStreamMessage || headersLen > total-16 {
+			return fmt.Errorf("invalid event stream message length %d", total)
+		}
+
+		message := make([]byte, total)
+		copy(message, prelude)
+		if _, err := io.ReadFull(reader, message[12:]); err != nil {
+			return fmt.Errorf("failed to read event stream: %w", err)
+		}
+		if crc32.ChecksumIEEE(message[:total-4]) != binary.BigEndian.Uint32(message[total-4:]) {
+			return errors.New("event stream message checksum mismatch")
+		}
+
+		headers, err := parseEventStreamHeaders(message[12 : 12+headersLen])
+		if err != nil {
+			return err
+		}
+		payload := message[12+headersLen : total-4]
+
+		switch headers[":message-type"] {
+		case "exception", "error":
+			return eventStreamError(headers, payload)
+		}
+		if headers[":event-type"] != "chunk" {
+			continue
+		}
+
+		var chunk struct {
+			Bytes []byte `json:"bytes"` // Base64 in JSON
+		}
+		if err := json.Unmarshal(payload, &chunk); err != nil {
+			return fmt.Errorf("invalid event stream chunk: %w", err)
+		}
+		if err := handle(chunk.Bytes); err != nil {
+			return err
+		}
+	}
+}
+
+// parseEventStreamHeaders returns the string headers of an event stream
+// message; headers of other types are skipped
+func parseEventStreamHeaders(data []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	invalid := errors.New("invalid event stream headers")
+
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		if len(data) < 1+nameLen+1 {
+			return nil, invalid
+		}
+		name := string(data[1 : 1+nameLen])
+		valueType := data[1+nameLen]
+		data = data[2+nameLen:]
+
+		var size int
+		switch valueType {
+		case 0, 1: // Bool true, false
+			size = 0
+		case 2: // Byte
+			size = 1
+		case 3: // Short
+			size = 2
+		case 4: // Int
+			size = 4
+		case 5, 8: // Long, timestamp
+			size = 8
+		case 9: // UUID
+			size = 16
+		case 6, 7: // Bytes, string
+			if len(data) < 2 {
+				return nil, invalid
+			}
+			size = int(binary.BigEndian.Uint16(data[:2]))
+			data = data[2:]
+		default:
+			return nil, fmt.Errorf("unknown event stream header type %d", valueType)
+		}
+		if len(data) < size {
+			return nil, invalid
+		}
+		if valueType == 7 {
+			headers[name] = string(data[:size])
+		}
+		data = data[size:]
+	}
+	return headers, nil
+}
+
+// eventStreamError builds the error of an exception message
+func eventStreamError(headers map[string]string, payload []byte) error {
+	kind := headers[":exception-type"]
+	if kind == "" {
+		kind = headers[":error-code"]
+	}
+	message := headers[":error-message"]
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(payload, &body) == nil && body.Message != "" {
+		message = body.Message
+	}
+	return fmt.Errorf("bedrock stream error %s: %s", kind, message)
+}
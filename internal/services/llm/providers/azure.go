@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
 	"strings"
 	"sync"
@@ -22,6 +21,7 @@ type AzureProvider struct {
 	client      *http.Client
 	healthy     bool
 	deployments map[string]string // model -> deployment name mapping
+	baseModels  map[string]string // model -> underlying model, for deployment aliases
 	apiVersion  string
 }
 
@@ -47,7 +47,8 @@ func NewAzureProvider(name string, config ProviderConfig) (*AzureProvider, error
 	}
 
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: newCaptureTransport("azure"),
 	}
 
 	// Parse deployments from config
@@ -62,12 +63,26 @@ func NewAzureProvider(name string, config ProviderConfig) (*AzureProvider, error
 		}
 	}
 
+	// Deployments are often named after the team or use case rather than
+	// the model, so reasoning model detection uses the configured base model
+	baseModels := make(map[string]string)
+	if config.Extra != nil {
+		if bases, ok := config.Extra["base_models"].(map[string]interface{}); ok {
+			for model, base := range bases {
+				if baseStr, ok := base.(string); ok {
+					baseModels[model] = baseStr
+				}
+			}
+		}
+	}
+
 	p := &AzureProvider{
 		name:        name,
 		config:      config,
 		client:      client,
 		healthy:     true,
 		deployments: deployments,
+		baseModels:  baseModels,
 		apiVersion:  apiVersion,
 	}
 
@@ -195,7 +210,9 @@ func (p *AzureProvider) ChatCompletionStream(ctx context.Context, request *ChatR
 		}
 
 		// Parse SSE stream
-		p.parseStreamResponse(resp.Body, streamChan)
+		if err := NewStreamTranscoder(StreamFormatOpenAI, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			sendStreamError(err.Error())
+		}
 	}()
 
 	return streamChan, nil
@@ -203,6 +220,9 @@ func (p *AzureProvider) ChatCompletionStream(ctx context.Context, request *ChatR
 
 // transformRequest transforms the request to Azure format
 func (p *AzureProvider) transformRequest(request *ChatRequest) map[string]interface{} {
+	baseModel := p.getBaseModel(request.Model)
+	request = adaptReasoningRequestFor(request, baseModel)
+
 	// Azure uses the same format as OpenAI
 	azureReq := map[string]interface{}{
 		"messages": request.Messages,
@@ -219,7 +239,7 @@ func (p *AzureProvider) transformRequest(request *ChatRequest) map[string]interf
 	}
 
 	if request.MaxTokens != nil {
-		if useMaxCompletionTokens(request.Model) {
+		if useMaxCompletionTokens(baseModel) {
 			azureReq["max_completion_tokens"] = *request.MaxTokens
 		} else {
 			azureReq["max_tokens"] = *request.MaxTokens
@@ -294,6 +314,15 @@ func (p *AzureProvider) AddDeployment(model, deployment string) {
 	p.deployments[model] = deployment
 }
 
+// getBaseModel returns the underlying model of a request model, which is the
+// model itself unless a base model is configured for it
+func (p *AzureProvider) getBaseModel(model string) string {
+	if base, ok := p.baseModels[model]; ok && base != "" {
+		return base
+	}
+	return model
+}
+
 // getDeploymentName gets the deployment name for a model
 func (p *AzureProvider) getDeploymentName(model string) string {
 	p.mu.RLock()
@@ -326,47 +355,6 @@ func (p *AzureProvider) getDeploymentName(model string) string {
 	return model
 }
 
-// parseStreamResponse parses the SSE stream response
-func (p *AzureProvider) parseStreamResponse(body io.Reader, streamChan chan<- StreamResponse) {
-	// Read the response body
-	bodyBytes, err := io.ReadAll(body)
-	if err != nil {
-		return // Just close on error
-	}
-
-	// Split by double newline (SSE format)
-	events := strings.Split(string(bodyBytes), "\n\n")
-
-	for _, event := range events {
-		if event == "" {
-			continue
-		}
-
-		// Parse SSE event
-		lines := strings.Split(event, "\n")
-		var data string
-
-		for _, line := range lines {
-			if strings.HasPrefix(line, "data: ") {
-				data = strings.TrimPrefix(line, "data: ")
-				break
-			}
-		}
-
-		if data == "" || data == "[DONE]" {
-			continue
-		}
-
-		// Parse JSON data
-		var streamResp StreamResponse
-		if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-			continue // Skip malformed data
-		}
-
-		streamChan <- streamResp
-	}
-}
-
 // Completion implements the Provider interface (legacy)
 func (p *AzureProvider) Completion(ctx context.Context, request *CompletionRequest) (*CompletionResponse, error) {
 	// Get deployment name
@@ -477,56 +465,17 @@ func (p *AzureProvider) AudioTranscription(ctx context.Context, request *Transcr
 	url := fmt.Sprintf("%s/openai/deployments/%s/audio/transcriptions?api-version=%s",
 		p.config.BaseURL, deployment, p.apiVersion)
 
-	// Create multipart form
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	// Add file field
-	fileWriter, err := writer.CreateFormFile("file", "audio.wav")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
-	}
-	if _, err := io.Copy(fileWriter, request.File); err != nil {
-		return nil, fmt.Errorf("failed to copy file: %w", err)
-	}
-
-	// Add other fields
-	if err := writer.WriteField("model", request.Model); err != nil {
-		return nil, fmt.Errorf("failed to write model field: %w", err)
-	}
-	if request.Language != "" {
-		if err := writer.WriteField("language", request.Language); err != nil {
-			return nil, fmt.Errorf("failed to write language field: %w", err)
-		}
-	}
-	if request.Prompt != "" {
-		if err := writer.WriteField("prompt", request.Prompt); err != nil {
-			return nil, fmt.Errorf("failed to write prompt field: %w", err)
-		}
-	}
-	if request.ResponseFormat != "" {
-		if err := writer.WriteField("response_format", request.ResponseFormat); err != nil {
-			return nil, fmt.Errorf("failed to write response_format field: %w", err)
-		}
-	}
-	if request.Temperature != nil {
-		if err := writer.WriteField("temperature", fmt.Sprintf("%.2f", *request.Temperature)); err != nil {
-			return nil, fmt.Errorf("failed to write temperature field: %w", err)
-		}
-	}
-
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
-	}
+	body, contentType := transcriptionBody(request, false)
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", url, &buf)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
+		_ = body.Close()
 		return nil, err
 	}
 
 	// Set headers
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Type", contentType)
 	p.setHeaders(req, ctx)
 
 	// Send request
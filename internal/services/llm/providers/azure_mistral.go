@@ -0,0 +1,253 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AzureMistralProvider serves Mistral models deployed on Azure AI Foundry,
+// either as a serverless endpoint (https://<name>.<region>.models.ai.azure.com)
+// or through a Foundry resource's model inference endpoint
+// (https://<resource>.services.ai.azure.com/models, which needs an API
+// version). The API is OpenAI compatible apart from a few Mistral request
+// parameters.
+type AzureMistralProvider struct {
+	*OpenAIProvider
+	apiVersion string
+}
+
+// NewAzureMistralProvider creates a provider for an Azure AI Foundry Mistral
+// endpoint. BaseURL is the endpoint and APIKey its key.
+func NewAzureMistralProvider(name string, cfg ProviderConfig) (*AzureMistralProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("Azure Mistral API key is required")
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("Azure Mistral endpoint is required")
+	}
+
+	openai, err := NewOpenAIProvider(name, ProviderConfig{
+		APIKey:  cfg.APIKey,
+		BaseURL: strings.TrimSuffix(strings.TrimSuffix(cfg.BaseURL, "/"), "/v1"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Deployed models are only known from the configuration
+	openai.BaseProvider = NewBaseProvider(name, "azure_mistral", cfg.Priority, cfg.Models)
+	openai.client.Transport = newCaptureTransport("azure_mistral")
+	if cfg.Timeout > 0 {
+		openai.client.Timeout = cfg.Timeout
+	}
+
+	return &AzureMistralProvider{
+		OpenAIProvider: openai,
+		apiVersion:     cfg.APIVersion,
+	}, nil
+}
+
+// newRequest creates a request to an endpoint path, authenticated with the
+// key in both headers Azure AI endpoints accept
+func (p *AzureMistralProvider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	target := p.baseURL + path
+	if p.apiVersion != "" {
+		target += "?api-version=" + url.QueryEscape(p.apiVersion)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("api-key", p.apiKey)
+	return req, nil
+}
+
+// mistralChatRequest marshals a chat request with the parameters Mistral
+// names differently: seed is random_seed and a required tool call is "any".
+// user and logit_bias are not accepted and are dropped.
+func mistralChatRequest(request *ChatRequest) ([]byte, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	if seed, ok := m["seed"]; ok {
+		m["random_seed"] = seed
+		delete(m, "seed")
+	}
+	if m["tool_choice"] == "required" {
+		m["tool_choice"] = "any"
+	}
+	delete(m, "user")
+	delete(m, "logit_bias")
+	return json.Marshal(m)
+}
+
+func (p *AzureMistralProvider) ChatCompletion(ctx context.Context, request *ChatRequest) (*ChatResponse, error) {
+	reqBody, err := mistralChatRequest(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/chat/completions", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, azureMistralAPIError(resp.StatusCode, body)
+	}
+
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &chatResp, nil
+}
+
+func (p *AzureMistralProvider) ChatCompletionStream(ctx context.Context, request *ChatRequest) (<-chan StreamResponse, error) {
+	streamRequest := *request
+	streamRequest.Stream = true
+	reqBody, err := mistralChatRequest(&streamRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/chat/completions", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, azureMistralAPIError(resp.StatusCode, body)
+	}
+
+	streamChan := make(chan StreamResponse, 100)
+	go func() {
+		defer close(streamChan)
+		defer func() { _ = resp.Body.Close() }()
+		if err := NewStreamTranscoder(StreamFormatOpenAI, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			log.Printf("Azure Mistral stream failed: %v", err)
+		}
+	}()
+
+	return streamChan, nil
+}
+
+func (p *AzureMistralProvider) Embeddings(ctx context.Context, request *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/embeddings", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, azureMistralAPIError(resp.StatusCode, body)
+	}
+
+	var embResp EmbeddingsResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &embResp, nil
+}
+
+func (p *AzureMistralProvider) AudioTranscription(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by Azure Mistral provider")
+}
+
+func (p *AzureMistralProvider) AudioTranscriptionStream(ctx context.Context, request *TranscriptionRequest) (<-chan TranscriptionStreamEvent, error) {
+	return nil, fmt.Errorf("audio transcription not supported by Azure Mistral provider")
+}
+
+func (p *AzureMistralProvider) AudioSpeech(ctx context.Context, request *SpeechRequest) ([]byte, error) {
+	return nil, fmt.Errorf("audio speech not supported by Azure Mistral provider")
+}
+
+func (p *AzureMistralProvider) ImageGeneration(ctx context.Context, request *ImageRequest) (*ImageResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by Azure Mistral provider")
+}
+
+// SupportsRealtime reports false: Mistral endpoints have no realtime API
+func (p *AzureMistralProvider) SupportsRealtime() bool {
+	return false
+}
+
+// HealthCheck reads the endpoint's model info, which checks the key
+func (p *AzureMistralProvider) HealthCheck(ctx context.Context) error {
+	req, err := p.newRequest(ctx, "GET", "/info", nil)
+	if err != nil {
+		p.SetHealthy(false)
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+	}
+
+	p.SetHealthy(true)
+	return nil
+}
+
+// azureMistralAPIError formats an error response, which Azure AI endpoints
+// return in the OpenAI format
+func azureMistralAPIError(status int, body []byte) error {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Message == "" {
+		return fmt.Errorf("request failed with status %d: %s", status, string(body))
+	}
+	return fmt.Errorf("Azure Mistral API error: %s", errResp.Error.Message)
+}
@@ -0,0 +1,117 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAzureMistralProvider(t *testing.T) {
+	_, err := NewAzureMistralProvider("mistral", ProviderConfig{BaseURL: "https://mistral-large.eastus2.models.ai.azure.com"})
+	assert.Error(t, err, "API key is required")
+
+	_, err = NewAzureMistralProvider("mistral", ProviderConfig{APIKey: "key"})
+	assert.Error(t, err, "endpoint is required")
+
+	provider, err := NewAzureMistralProvider("mistral", ProviderConfig{
+		APIKey:  "key",
+		BaseURL: "https://mistral-large.eastus2.models.ai.azure.com/v1/",
+		Models:  []string{"mistral-large-2411"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "azure_mistral", provider.GetType())
+	assert.Equal(t, "https://mistral-large.eastus2.models.ai.azure.com", provider.baseURL)
+	assert.Equal(t, []string{"mistral-large-2411"}, provider.ListModels())
+	assert.False(t, provider.SupportsRealtime())
+}
+
+func TestMistralChatRequest(t *testing.T) {
+	seed := 42
+	data, err := mistralChatRequest(&ChatRequest{
+		Model:      "mistral-large-2411",
+		Messages:   []Message{{Role: "user", Content: "Hi"}},
+		Seed:       &seed,
+		User:       "user-1",
+		LogitBias:  map[string]int{"50256": -100},
+		ToolChoice: "required",
+	})
+	require.NoError(t, err)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &body))
+	assert.Equal(t, float64(42), body["random_seed"])
+	assert.Equal(t, "any", body["tool_choice"])
+	assert.NotContains(t, body, "seed")
+	assert.NotContains(t, body, "user")
+	assert.NotContains(t, body, "logit_bias")
+}
+
+func TestAzureMistralProvider_ChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/chat/completions", r.URL.Path)
+		assert.Equal(t, "2024-05-01-preview", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "Bearer azure-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "azure-key", r.Header.Get("api-key"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"id":"c1","object":"chat.completion.chunk","model":"mistral-large-2411","choices":[{"index":0,"delta":{"content":"Bonjour"}}]}` + "\n\ndata: [DONE]\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"c1","object":"chat.completion","model":"mistral-large-2411",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"Bonjour"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewAzureMistralProvider("mistral", ProviderConfig{
+		APIKey:     "azure-key",
+		BaseURL:    server.URL + "/models",
+		APIVersion: "2024-05-01-preview",
+	})
+	require.NoError(t, err)
+
+	request := &ChatRequest{Model: "mistral-large-2411", Messages: []Message{{Role: "user", Content: "Salut"}}}
+	resp, err := provider.ChatCompletion(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "Bonjour", resp.Choices[0].Message.Content)
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+
+	stream, err := provider.ChatCompletionStream(context.Background(), request)
+	require.NoError(t, err)
+	var chunks []StreamResponse
+	for chunk := range stream {
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "Bonjour", chunks[0].Choices[0].Delta.Content)
+	assert.False(t, request.Stream, "the caller's request is not modified")
+}
+
+func TestAzureMistralProvider_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"code":"Unauthorized","message":"Access denied due to invalid subscription key"}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewAzureMistralProvider("mistral", ProviderConfig{APIKey: "bad", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	_, err = provider.ChatCompletion(context.Background(), &ChatRequest{Model: "mistral-large-2411"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid subscription key")
+
+	_, err = provider.ChatCompletionStream(context.Background(), &ChatRequest{Model: "mistral-large-2411"})
+	assert.Error(t, err)
+
+	assert.Error(t, provider.HealthCheck(context.Background()))
+	assert.False(t, provider.IsHealthy())
+}
@@ -1,15 +1,14 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
-	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"sync"
@@ -18,12 +17,13 @@ import (
 
 // BedrockProvider implements AWS Bedrock LLM provider
 type BedrockProvider struct {
-	mu      sync.RWMutex
-	name    string
-	config  ProviderConfig
-	client  *http.Client
-	healthy bool
-	models  []string
+	mu          sync.RWMutex
+	name        string
+	config      ProviderConfig
+	client      *http.Client
+	credentials AWSCredentialsProvider
+	healthy     bool
+	models      []string
 }
 
 // BedrockAuth contains AWS authentication details
@@ -34,17 +34,39 @@ type BedrockAuth struct {
 	Region          string `mapstructure:"region"`
 }
 
-// NewBedrockProvider creates a new AWS Bedrock provider
+// NewBedrockProvider creates a new AWS Bedrock provider. APIKey and
+// APISecret hold static AWS keys; when they are empty the default AWS
+// credential chain (environment, IRSA, SSO, ECS/EKS, instance profile) is
+// used. Extra may set aws_session_token, aws_profile, aws_role_arn,
+// aws_external_id and aws_role_session_name.
 func NewBedrockProvider(name string, config ProviderConfig) (*BedrockProvider, error) {
-	if config.APIKey == "" || config.APISecret == "" {
-		return nil, fmt.Errorf("AWS access key and secret key are required")
-	}
-
 	region := config.Region
 	if region == "" {
 		region = "us-east-1"
 	}
 
+	sessionToken, _ := config.Extra["aws_session_token"].(string)
+	profile, _ := config.Extra["aws_profile"].(string)
+	roleARN, _ := config.Extra["aws_role_arn"].(string)
+	externalID, _ := config.Extra["aws_external_id"].(string)
+	roleSessionName, _ := config.Extra["aws_role_session_name"].(string)
+	if sessionToken == "" {
+		sessionToken = config.OrgID // Legacy location of the session token
+	}
+	credentials, err := NewAWSCredentialsProvider(AWSCredentialsOptions{
+		AccessKeyID:     config.APIKey,
+		SecretAccessKey: config.APISecret,
+		SessionToken:    sessionToken,
+		Profile:         profile,
+		Region:          region,
+		RoleARN:         roleARN,
+		ExternalID:      externalID,
+		RoleSessionName: roleSessionName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	baseURL := config.BaseURL
 	if baseURL == "" {
 		baseURL = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
@@ -54,14 +76,16 @@ func NewBedrockProvider(name string, config ProviderConfig) (*BedrockProvider, e
 	config.Region = region
 
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: newCaptureTransport("bedrock"),
 	}
 
 	p := &BedrockProvider{
-		name:    name,
-		config:  config,
-		client:  client,
-		healthy: true,
+		name:        name,
+		config:      config,
+		client:      client,
+		credentials: credentials,
+		healthy:     true,
 		models: []string{
 			// Anthropic Claude models
 			"anthropic.claude-3-opus-20240229",
@@ -185,92 +209,32 @@ func (p *BedrockProvider) ChatCompletionStream(ctx context.Context, request *Cha
 			return // Just close channel on error
 		}
 
-		// Parse streaming response
-		p.parseStreamResponse(resp.Body, streamChan)
+		// Bedrock wraps the Anthropic stream events in an AWS event stream
+		if err := NewStreamTranscoder(StreamFormatBedrock, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			log.Printf("Bedrock stream failed: %v", err)
+		}
 	}()
 
 	return streamChan, nil
 }
 
-// signRequest signs the HTTP request with AWS Signature V4
+// signRequest signs the HTTP request with AWS Signature V4 using the
+// current credentials, refreshing temporary ones as needed
 func (p *BedrockProvider) signRequest(req *http.Request, body []byte) error {
-	// Set required headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	// Calculate body hash
-	bodyHash := sha256.Sum256(body)
-	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(bodyHash[:]))
-
-	// Set date
-	now := time.Now().UTC()
-	dateTime := now.Format("20060102T150405Z")
-	date := now.Format("20060102")
-	req.Header.Set("X-Amz-Date", dateTime)
-
-	// Add session token if present
-	if p.config.OrgID != "" { // Using OrgID to store session token
-		req.Header.Set("X-Amz-Security-Token", p.config.OrgID)
+	creds, err := p.credentials.Retrieve(req.Context())
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
 	}
 
-	// Create canonical request
-	canonicalRequest := p.createCanonicalRequest(req, hex.EncodeToString(bodyHash[:]))
-
-	// Create string to sign
-	stringToSign := p.createStringToSign(dateTime, date, canonicalRequest)
-
-	// Calculate signature
-	signature := p.calculateSignature(date, stringToSign)
-
-	// Add authorization header
-	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s/%s/bedrock/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature=%s",
-		p.config.APIKey, date, p.config.Region, signature)
-	req.Header.Set("Authorization", authHeader)
-
+	bodyHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(bodyHash[:]))
+	signAWSRequest(req, body, creds, p.config.Region, "bedrock", time.Now())
 	return nil
 }
 
-func (p *BedrockProvider) createCanonicalRequest(req *http.Request, bodyHash string) string {
-	canonicalHeaders := fmt.Sprintf("content-type:%s\nhost:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n",
-		req.Header.Get("Content-Type"),
-		req.Host,
-		bodyHash,
-		req.Header.Get("X-Amz-Date"))
-
-	return fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s",
-		req.Method,
-		req.URL.Path,
-		req.URL.RawQuery,
-		canonicalHeaders,
-		"content-type;host;x-amz-content-sha256;x-amz-date",
-		bodyHash)
-}
-
-func (p *BedrockProvider) createStringToSign(dateTime, date, canonicalRequest string) string {
-	requestHash := sha256.Sum256([]byte(canonicalRequest))
-	return fmt.Sprintf("AWS4-HMAC-SHA256\n%s\n%s/%s/bedrock/aws4_request\n%s",
-		dateTime,
-		date,
-		p.config.Region,
-		hex.EncodeToString(requestHash[:]))
-}
-
-func (p *BedrockProvider) calculateSignature(date, stringToSign string) string {
-	key := []byte("AWS4" + p.config.APISecret)
-	dateKey := hmacSHA256(key, date)
-	regionKey := hmacSHA256(dateKey, p.config.Region)
-	serviceKey := hmacSHA256(regionKey, "bedrock")
-	signingKey := hmacSHA256(serviceKey, "aws4_request")
-	signature := hmacSHA256(signingKey, stringToSign)
-	return hex.EncodeToString(signature)
-}
-
-func hmacSHA256(key []byte, data string) []byte {
-	h := hmac.New(sha256.New, key)
-	h.Write([]byte(data))
-	return h.Sum(nil)
-}
-
 // Transform request for Claude models
 func (p *BedrockProvider) transformClaudeRequest(request *ChatRequest) ([]byte, error) {
 	claudeReq := map[string]interface{}{
@@ -537,45 +501,6 @@ func (p *BedrockProvider) parseMistralResponse(body io.Reader, model string) (*C
 	}, nil
 }
 
-// Parse streaming response
-func (p *BedrockProvider) parseStreamResponse(body io.Reader, streamChan chan<- StreamResponse) {
-	scanner := bufio.NewScanner(body)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// AWS event stream format
-		if strings.HasPrefix(line, ":event-type:") {
-			// Skip metadata lines
-			continue
-		}
-
-		// Parse JSON payload
-		if strings.HasPrefix(line, "{") {
-			var event map[string]interface{}
-			if err := json.Unmarshal([]byte(line), &event); err != nil {
-				continue
-			}
-
-			// Extract content delta
-			if delta, ok := event["delta"].(map[string]interface{}); ok {
-				if text, ok := delta["text"].(string); ok {
-					streamChan <- StreamResponse{
-						Choices: []StreamChoice{
-							{
-								Index: 0,
-								Delta: Message{
-									Content: text,
-								},
-							},
-						},
-					}
-				}
-			}
-		}
-	}
-}
-
 func (p *BedrockProvider) mapStopReason(reason string) string {
 	switch reason {
 	case "end_turn", "stop_sequence":
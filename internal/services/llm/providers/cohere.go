@@ -0,0 +1,589 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	cohereDefaultBaseURL = "https://api.cohere.com"
+
+	// cohereDefaultInputType is the embedding input type of Command R
+	// retrieval: documents stored in a vector index
+	cohereDefaultInputType = "search_document"
+)
+
+// CohereProvider serves Cohere models through the v2 API: Command chat
+// models, Embed models and Rerank models
+type CohereProvider struct {
+	*BaseProvider
+	apiKey    string
+	baseURL   string
+	inputType string
+	client    *http.Client
+}
+
+// cohereMessage is a v2 chat message. Content is a string or text and
+// image_url blocks, as in OpenAI requests.
+type cohereMessage struct {
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content,omitempty"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+	ToolPlan   string      `json:"tool_plan,omitempty"`
+}
+
+// cohereChatRequest is the body of the v2 chat API
+type cohereChatRequest struct {
+	Model            string                `json:"model"`
+	Messages         []cohereMessage       `json:"messages"`
+	Stream           bool                  `json:"stream,omitempty"`
+	MaxTokens        *int                  `json:"max_tokens,omitempty"`
+	Temperature      *float32              `json:"temperature,omitempty"`
+	P                *float32              `json:"p,omitempty"`
+	StopSequences    []string              `json:"stop_sequences,omitempty"`
+	PresencePenalty  *float32              `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32              `json:"frequency_penalty,omitempty"`
+	Seed             *int                  `json:"seed,omitempty"`
+	ResponseFormat   *cohereResponseFormat `json:"response_format,omitempty"`
+	Tools            []Tool                `json:"tools,omitempty"`
+	ToolChoice       string                `json:"tool_choice,omitempty"`
+}
+
+type cohereResponseFormat struct {
+	Type       string                 `json:"type"`
+	JSONSchema map[string]interface{} `json:"json_schema,omitempty"`
+}
+
+// cohereContent is a content block of a v2 response message
+type cohereContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type cohereResponseMessage struct {
+	Role      string          `json:"role"`
+	Content   []cohereContent `json:"content"`
+	ToolCalls []ToolCall      `json:"tool_calls"`
+	ToolPlan  string          `json:"tool_plan"`
+}
+
+// cohereUsage reports the tokens of a request; billed_units are the tokens
+// Cohere charges for, without the prompt template
+type cohereUsage struct {
+	BilledUnits struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		SearchUnits  int `json:"search_units"`
+	} `json:"billed_units"`
+	Tokens struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"tokens"`
+}
+
+func (u cohereUsage) usage() Usage {
+	input, output := u.BilledUnits.InputTokens, u.BilledUnits.OutputTokens
+	if input == 0 && output == 0 {
+		input, output = u.Tokens.InputTokens, u.Tokens.OutputTokens
+	}
+	return Usage{PromptTokens: input, CompletionTokens: output, TotalTokens: input + output}
+}
+
+type cohereChatResponse struct {
+	ID           string                `json:"id"`
+	FinishReason string                `json:"finish_reason"`
+	Message      cohereResponseMessage `json:"message"`
+	Usage        cohereUsage           `json:"usage"`
+}
+
+// cohereStreamEvent is an event of the v2 chat stream. Deltas carry the
+// fields of the message being built.
+type cohereStreamEvent struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Delta struct {
+		Message struct {
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+			ToolPlan  string   `json:"tool_plan"`
+			ToolCalls ToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string       `json:"finish_reason"`
+		Usage        *cohereUsage `json:"usage"`
+	} `json:"delta"`
+}
+
+type cohereError struct {
+	Message string `json:"message"`
+}
+
+// NewCohereProvider creates a Cohere provider. BaseURL defaults to the
+// Cohere API; Extra may set embedding_input_type, the input_type sent with
+// embeddings requests (search_document, search_query, classification or
+// clustering).
+func NewCohereProvider(name string, cfg ProviderConfig) (*CohereProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("cohere API key is required")
+	}
+
+	p := &CohereProvider{
+		BaseProvider: NewBaseProvider(name, "cohere", cfg.Priority, cfg.Models),
+		apiKey:       cfg.APIKey,
+		baseURL:      strings.TrimSuffix(strings.TrimSuffix(cfg.BaseURL, "/"), "/v2"),
+		inputType:    cohereDefaultInputType,
+		client: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: newCaptureTransport("cohere"),
+		},
+	}
+	if p.baseURL == "" {
+		p.baseURL = cohereDefaultBaseURL
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = cfg.Timeout
+	}
+	if cfg.Extra != nil {
+		if v, ok := cfg.Extra["embedding_input_type"].(string); ok && v != "" {
+			p.inputType = v
+		}
+	}
+	return p, nil
+}
+
+// newRequest creates an authenticated request to a Cohere API path
+func (p *CohereProvider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	return req, nil
+}
+
+// do sends a request and returns the body of a successful response
+func (p *CohereProvider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, cohereAPIError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// cohereChatBody converts an OpenAI chat request to the Cohere v2 format
+func cohereChatBody(request *ChatRequest, stream bool) (*cohereChatRequest, error) {
+	if request.N != nil && *request.N > 1 {
+		return nil, fmt.Errorf("cohere does not support n > 1")
+	}
+
+	body := &cohereChatRequest{
+		Model:            request.Model,
+		Stream:           stream,
+		MaxTokens:        request.MaxTokens,
+		Temperature:      request.Temperature,
+		P:                request.TopP,
+		StopSequences:    request.Stop,
+		PresencePenalty:  request.PresencePenalty,
+		FrequencyPenalty: request.FrequencyPenalty,
+		Seed:             request.Seed,
+		Tools:            request.Tools,
+	}
+	for _, msg := range request.Messages {
+		role := msg.Role
+		if role == "developer" {
+			role = "system"
+		}
+		body.Messages = append(body.Messages, cohereMessage{
+			Role:       role,
+			Content:    msg.Content,
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+
+	if format := request.ResponseFormat; format != nil && format.Type != "text" {
+		body.ResponseFormat = &cohereResponseFormat{Type: "json_object"}
+		if format.JSONSchema != nil {
+			body.ResponseFormat.JSONSchema = format.JSONSchema.Schema
+		}
+	}
+
+	// Cohere forces or forbids tool calls; "auto" is its default and it
+	// cannot force a named function
+	switch choice := request.ToolChoice.(type) {
+	case string:
+		switch choice {
+		case "required":
+			body.ToolChoice = "REQUIRED"
+		case "none":
+			body.ToolChoice = "NONE"
+		}
+	case map[string]interface{}:
+		body.ToolChoice = "REQUIRED"
+	}
+	return body, nil
+}
+
+// cohereFinishReason maps a Cohere finish reason to the OpenAI one
+func cohereFinishReason(reason string) string {
+	switch reason {
+	case "COMPLETE", "STOP_SEQUENCE":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "TOOL_CALL":
+		return "tool_calls"
+	case "":
+		return ""
+	}
+	return strings.ToLower(reason)
+}
+
+func (p *CohereProvider) ChatCompletion(ctx context.Context, request *ChatRequest) (*ChatResponse, error) {
+	chatBody, err := cohereChatBody(request, false)
+	if err != nil {
+		return nil, err
+	}
+	reqBody, err := json.Marshal(chatBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/v2/chat", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var cohereResp cohereChatResponse
+	if err := json.Unmarshal(body, &cohereResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	var text strings.Builder
+	for _, content := range cohereResp.Message.Content {
+		if content.Type == "text" {
+			text.WriteString(content.Text)
+		}
+	}
+	message := Message{Role: "assistant", Content: text.String(), ToolCalls: cohereResp.Message.ToolCalls}
+	if len(message.ToolCalls) > 0 && text.Len() == 0 {
+		message.Content = nil
+	}
+
+	return &ChatResponse{
+		ID:      cohereResp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   request.Model,
+		Choices: []Choice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: cohereFinishReason(cohereResp.FinishReason),
+		}},
+		Usage: cohereResp.Usage.usage(),
+	}, nil
+}
+
+func (p *CohereProvider) ChatCompletionStream(ctx context.Context, request *ChatRequest) (<-chan StreamResponse, error) {
+	chatBody, err := cohereChatBody(request, true)
+	if err != nil {
+		return nil, err
+	}
+	reqBody, err := json.Marshal(chatBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/v2/chat", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, cohereAPIError(resp.StatusCode, body)
+	}
+
+	streamChan := make(chan StreamResponse, 100)
+	go func() {
+		defer close(streamChan)
+		defer func() { _ = resp.Body.Close() }()
+		parseCohereStream(resp.Body, request.Model, streamChan)
+	}()
+
+	return streamChan, nil
+}
+
+// parseCohereStream converts the v2 chat stream events to OpenAI chunks.
+// Tool plans, Cohere's reasoning before tool calls, are not forwarded.
+func parseCohereStream(body io.Reader, model string, streamChan chan<- StreamResponse) {
+	id := GenerateID()
+	created := time.Now().Unix()
+	chunk := func(delta Message, finishReason string, usage *Usage) StreamResponse {
+		return StreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []StreamChoice{{Delta: delta, FinishReason: finishReason}},
+			Usage:   usage,
+		}
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	toolCalls := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var event cohereStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "message-start":
+			if event.ID != "" {
+				id = event.ID
+			}
+			streamChan <- chunk(Message{Role: "assistant"}, "", nil)
+		case "content-delta":
+			if text := event.Delta.Message.Content.Text; text != "" {
+				streamChan <- chunk(Message{Content: text}, "", nil)
+			}
+		case "tool-call-start":
+			call := event.Delta.Message.ToolCalls
+			index := toolCalls
+			toolCalls++
+			call.Index = &index
+			if call.Type == "" {
+				call.Type = "function"
+			}
+			streamChan <- chunk(Message{ToolCalls: []ToolCall{call}}, "", nil)
+		case "tool-call-delta":
+			index := toolCalls - 1
+			if index < 0 {
+				continue
+			}
+			streamChan <- chunk(Message{ToolCalls: []ToolCall{{
+				Index:    &index,
+				Function: FunctionCall{Arguments: event.Delta.Message.ToolCalls.Function.Arguments},
+			}}}, "", nil)
+		case "message-end":
+			var usage *Usage
+			if event.Delta.Usage != nil {
+				u := event.Delta.Usage.usage()
+				usage = &u
+			}
+			streamChan <- chunk(Message{}, cohereFinishReason(event.Delta.FinishReason), usage)
+		}
+	}
+}
+
+func (p *CohereProvider) Completion(ctx context.Context, request *CompletionRequest) (*CompletionResponse, error) {
+	return nil, fmt.Errorf("completion API not supported by cohere provider")
+}
+
+func (p *CohereProvider) CompletionStream(ctx context.Context, request *CompletionRequest) (<-chan StreamResponse, error) {
+	return nil, fmt.Errorf("completion stream API not supported by cohere provider")
+}
+
+func (p *CohereProvider) Embeddings(ctx context.Context, request *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	var texts []string
+	switch input := request.Input.(type) {
+	case string:
+		texts = []string{input}
+	case []string:
+		texts = input
+	case []interface{}:
+		for _, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("cohere embeddings only support text input")
+			}
+			texts = append(texts, text)
+		}
+	default:
+		return nil, fmt.Errorf("cohere embeddings only support text input")
+	}
+
+	payload := map[string]interface{}{
+		"model":           request.Model,
+		"texts":           texts,
+		"input_type":      p.inputType,
+		"embedding_types": []string{"float"},
+	}
+	if request.Dimensions != nil {
+		payload["output_dimension"] = *request.Dimensions
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/v2/embed", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var cohereResp struct {
+		Embeddings struct {
+			Float [][]float32 `json:"float"`
+		} `json:"embeddings"`
+		Meta cohereUsage `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &cohereResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	usage := cohereResp.Meta.usage()
+	embResp := &EmbeddingsResponse{
+		Object: "list",
+		Model:  request.Model,
+		Usage:  Usage{PromptTokens: usage.PromptTokens, TotalTokens: usage.PromptTokens},
+	}
+	for i, embedding := range cohereResp.Embeddings.Float {
+		embResp.Data = append(embResp.Data, Embedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: embedding,
+		})
+	}
+	return embResp, nil
+}
+
+// Rerank orders documents by their relevance to the query
+func (p *CohereProvider) Rerank(ctx context.Context, request *RerankRequest) (*RerankResponse, error) {
+	payload := map[string]interface{}{
+		"model":     request.Model,
+		"query":     request.Query,
+		"documents": request.Documents,
+	}
+	if request.TopN != nil {
+		payload["top_n"] = *request.TopN
+	}
+	if request.MaxTokensPerDoc != nil {
+		payload["max_tokens_per_doc"] = *request.MaxTokensPerDoc
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/v2/rerank", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var cohereResp struct {
+		ID      string         `json:"id"`
+		Results []RerankResult `json:"results"`
+		Meta    cohereUsage    `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &cohereResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	usage := cohereResp.Meta.usage()
+	return &RerankResponse{
+		ID:      cohereResp.ID,
+		Object:  "list",
+		Model:   request.Model,
+		Results: cohereResp.Results,
+		Usage: RerankUsage{
+			PromptTokens: usage.PromptTokens,
+			SearchUnits:  cohereResp.Meta.BilledUnits.SearchUnits,
+		},
+	}, nil
+}
+
+func (p *CohereProvider) AudioTranscription(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by cohere provider")
+}
+
+func (p *CohereProvider) AudioSpeech(ctx context.Context, request *SpeechRequest) ([]byte, error) {
+	return nil, fmt.Errorf("audio speech not supported by cohere provider")
+}
+
+func (p *CohereProvider) ImageGeneration(ctx context.Context, request *ImageRequest) (*ImageResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by cohere provider")
+}
+
+// HealthCheck lists one model, which checks the API key
+func (p *CohereProvider) HealthCheck(ctx context.Context) error {
+	req, err := p.newRequest(ctx, "GET", "/v1/models?page_size=1", nil)
+	if err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+	}
+
+	p.SetHealthy(true)
+	return nil
+}
+
+// cohereAPIError formats a Cohere error response
+func cohereAPIError(status int, body []byte) error {
+	var errResp cohereError
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Message == "" {
+		return fmt.Errorf("request failed with status %d: %s", status, string(body))
+	}
+	return fmt.Errorf("cohere API error (status %d): %s", status, errResp.Message)
+}
@@ -0,0 +1,207 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCohereProvider(t *testing.T, handler http.HandlerFunc) *CohereProvider {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	provider, err := NewCohereProvider("cohere-test", ProviderConfig{
+		Type:    "cohere",
+		APIKey:  "co-key",
+		BaseURL: server.URL + "/v2",
+		Models:  []string{"command-r-plus"},
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestNewCohereProvider(t *testing.T) {
+	_, err := NewCohereProvider("cohere", ProviderConfig{})
+	assert.Error(t, err, "API key is required")
+
+	provider, err := NewCohereProvider("cohere", ProviderConfig{
+		APIKey: "key",
+		Extra:  map[string]interface{}{"embedding_input_type": "search_query"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cohere", provider.GetType())
+	assert.Equal(t, cohereDefaultBaseURL, provider.baseURL)
+	assert.Equal(t, "search_query", provider.inputType)
+}
+
+func TestCohereProvider_ChatCompletion(t *testing.T) {
+	provider := newTestCohereProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/chat", r.URL.Path)
+		assert.Equal(t, "Bearer co-key", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "command-r-plus", body["model"])
+		assert.InDelta(t, 0.9, body["p"], 0.001)
+		assert.Equal(t, []interface{}{"END"}, body["stop_sequences"])
+		assert.Equal(t, "REQUIRED", body["tool_choice"])
+		assert.Equal(t, map[string]interface{}{"type": "json_object"}, body["response_format"])
+		messages := body["messages"].([]interface{})
+		require.Len(t, messages, 3)
+		assert.Equal(t, "system", messages[0].(map[string]interface{})["role"], "developer messages are system messages")
+		assert.Equal(t, "call_1", messages[2].(map[string]interface{})["tool_call_id"])
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":            "resp-1",
+			"finish_reason": "TOOL_CALL",
+			"message": map[string]interface{}{
+				"role": "assistant",
+				"tool_calls": []map[string]interface{}{{
+					"id":       "call_2",
+					"type":     "function",
+					"function": map[string]string{"name": "lookup", "arguments": `{"q":"pllm"}`},
+				}},
+			},
+			"usage": map[string]interface{}{
+				"billed_units": map[string]int{"input_tokens": 12, "output_tokens": 5},
+				"tokens":       map[string]int{"input_tokens": 80, "output_tokens": 5},
+			},
+		})
+	})
+
+	topP := float32(0.9)
+	resp, err := provider.ChatCompletion(context.Background(), &ChatRequest{
+		Model: "command-r-plus",
+		Messages: []Message{
+			{Role: "developer", Content: "Be brief"},
+			{Role: "user", Content: "Look it up"},
+			{Role: "tool", Content: "found", ToolCallID: "call_1"},
+		},
+		TopP:           &topP,
+		Stop:           []string{"END"},
+		ToolChoice:     "required",
+		ResponseFormat: &ResponseFormat{Type: "json_object"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "resp-1", resp.ID)
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	assert.Nil(t, resp.Choices[0].Message.Content)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	assert.Equal(t, "lookup", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	assert.Equal(t, Usage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17}, resp.Usage)
+}
+
+func TestCohereProvider_ChatCompletionError(t *testing.T) {
+	provider := newTestCohereProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message":"trial key rate limit"}`))
+	})
+
+	_, err := provider.ChatCompletion(context.Background(), &ChatRequest{Model: "command-r", Messages: []Message{{Role: "user", Content: "Hi"}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 429")
+	assert.Contains(t, err.Error(), "trial key rate limit")
+}
+
+func TestParseCohereStream(t *testing.T) {
+	events := []string{
+		`{"type":"message-start","id":"resp-1","delta":{"message":{"role":"assistant"}}}`,
+		`{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":"Hel"}}}}`,
+		`{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":"lo"}}}}`,
+		`{"type":"tool-call-start","index":0,"delta":{"message":{"tool_calls":{"id":"call_1","type":"function","function":{"name":"lookup","arguments":""}}}}}`,
+		`{"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"{\"q\":1}"}}}}}`,
+		`{"type":"tool-call-end","index":0}`,
+		`{"type":"message-end","delta":{"finish_reason":"TOOL_CALL","usage":{"billed_units":{"input_tokens":3,"output_tokens":4}}}}`,
+	}
+	var body strings.Builder
+	for _, event := range events {
+		body.WriteString("event: x\ndata: " + event + "\n\n")
+	}
+
+	streamChan := make(chan StreamResponse, 10)
+	parseCohereStream(strings.NewReader(body.String()), "command-r", streamChan)
+	close(streamChan)
+
+	var chunks []StreamResponse
+	for chunk := range streamChan {
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 6)
+	assert.Equal(t, "resp-1", chunks[0].ID)
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "Hel", chunks[1].Choices[0].Delta.Content)
+	assert.Equal(t, "lo", chunks[2].Choices[0].Delta.Content)
+
+	start := chunks[3].Choices[0].Delta.ToolCalls[0]
+	assert.Equal(t, 0, *start.Index)
+	assert.Equal(t, "call_1", start.ID)
+	assert.Equal(t, "lookup", start.Function.Name)
+	assert.Equal(t, `{"q":1}`, chunks[4].Choices[0].Delta.ToolCalls[0].Function.Arguments)
+
+	assert.Equal(t, "tool_calls", chunks[5].Choices[0].FinishReason)
+	assert.Equal(t, &Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7}, chunks[5].Usage)
+}
+
+func TestCohereProvider_Embeddings(t *testing.T) {
+	provider := newTestCohereProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/embed", r.URL.Path)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, []interface{}{"a", "b"}, body["texts"])
+		assert.Equal(t, cohereDefaultInputType, body["input_type"])
+		assert.Equal(t, []interface{}{"float"}, body["embedding_types"])
+		assert.EqualValues(t, 256, body["output_dimension"])
+
+		_, _ = w.Write([]byte(`{"embeddings":{"float":[[0.1,0.2],[0.3,0.4]]},"meta":{"billed_units":{"input_tokens":2}}}`))
+	})
+
+	dimensions := 256
+	resp, err := provider.Embeddings(context.Background(), &EmbeddingsRequest{
+		Model:      "embed-english-v3.0",
+		Input:      []interface{}{"a", "b"},
+		Dimensions: &dimensions,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, 1, resp.Data[1].Index)
+	assert.Equal(t, []float32{0.3, 0.4}, resp.Data[1].Embedding)
+	assert.Equal(t, 2, resp.Usage.PromptTokens)
+
+	_, err = provider.Embeddings(context.Background(), &EmbeddingsRequest{Model: "embed-english-v3.0", Input: []int{1, 2}})
+	assert.Error(t, err, "token inputs are not supported")
+}
+
+func TestCohereProvider_Rerank(t *testing.T) {
+	provider := newTestCohereProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/rerank", r.URL.Path)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "capital of France", body["query"])
+		assert.EqualValues(t, 1, body["top_n"])
+
+		_, _ = w.Write([]byte(`{"id":"rr-1","results":[{"index":1,"relevance_score":0.98}],"meta":{"billed_units":{"search_units":1}}}`))
+	})
+
+	var reranker Reranker = provider
+	topN := 1
+	resp, err := reranker.Rerank(context.Background(), &RerankRequest{
+		Model:     "rerank-v3.5",
+		Query:     "capital of France",
+		Documents: []string{"Berlin is in Germany", "Paris is the capital of France"},
+		TopN:      &topN,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "rr-1", resp.ID)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, 1, resp.Results[0].Index)
+	assert.InDelta(t, 0.98, resp.Results[0].RelevanceScore, 0.0001)
+	assert.Equal(t, 1, resp.Usage.SearchUnits)
+}
@@ -0,0 +1,317 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CustomOpenAIPaths are the endpoints of a custom-openai provider and their
+// default paths below the base URL. Each can be overridden in the provider's
+// paths; "{model}" in an override is replaced with the requested model.
+var CustomOpenAIPaths = map[string]string{
+	"chat_completions": "/chat/completions",
+	"completions":      "/completions",
+	"embeddings":       "/embeddings",
+	"models":           "/models",
+}
+
+// CustomOpenAIProvider serves any server with an OpenAI-compatible API, such
+// as vLLM, LM Studio, LocalAI or an internal gateway. Extra headers are sent
+// with every request and endpoint paths can be overridden for servers that
+// do not follow OpenAI's layout, e.g. /openai/deployments/{model}/chat/completions.
+type CustomOpenAIProvider struct {
+	*OpenAIProvider
+	headers map[string]string
+	paths   map[string]string
+}
+
+// NewCustomOpenAIProvider creates a provider for the OpenAI-compatible server
+// at the base URL. The API key is optional and sent as a bearer token. Extra
+// may set headers, sent with every request and overriding the default ones,
+// and paths, keyed by the endpoints in CustomOpenAIPaths.
+func NewCustomOpenAIProvider(name string, cfg ProviderConfig) (*CustomOpenAIProvider, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required for custom-openai")
+	}
+
+	paths := make(map[string]string, len(CustomOpenAIPaths))
+	for endpoint, path := range CustomOpenAIPaths {
+		paths[endpoint] = path
+	}
+	headers := make(map[string]string)
+	if cfg.Extra != nil {
+		if overrides, ok := cfg.Extra["paths"].(map[string]interface{}); ok {
+			for endpoint, path := range overrides {
+				pathStr, ok := path.(string)
+				if !ok || pathStr == "" {
+					continue
+				}
+				if _, known := CustomOpenAIPaths[endpoint]; !known {
+					return nil, fmt.Errorf("unknown custom-openai endpoint: %s", endpoint)
+				}
+				if !strings.HasPrefix(pathStr, "/") && !isAbsoluteURL(pathStr) {
+					pathStr = "/" + pathStr
+				}
+				paths[endpoint] = pathStr
+			}
+		}
+		if extraHeaders, ok := cfg.Extra["headers"].(map[string]interface{}); ok {
+			for key, value := range extraHeaders {
+				if valueStr, ok := value.(string); ok {
+					headers[key] = valueStr
+				}
+			}
+		}
+	}
+
+	openai, err := NewOpenAIProvider(name, ProviderConfig{
+		APIKey:  cfg.APIKey,
+		BaseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Served models are only known from the configuration
+	openai.BaseProvider = NewBaseProvider(name, "custom-openai", cfg.Priority, cfg.Models)
+	openai.client.Transport = newCaptureTransport("custom-openai")
+	if cfg.Timeout > 0 {
+		openai.client.Timeout = cfg.Timeout
+	}
+
+	return &CustomOpenAIProvider{
+		OpenAIProvider: openai,
+		headers:        headers,
+		paths:          paths,
+	}, nil
+}
+
+func isAbsoluteURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// endpointURL returns the URL of an endpoint for a model
+func (p *CustomOpenAIProvider) endpointURL(endpoint, model string) string {
+	path := strings.ReplaceAll(p.paths[endpoint], "{model}", url.PathEscape(model))
+	if isAbsoluteURL(path) {
+		return path
+	}
+	return p.baseURL + path
+}
+
+// newRequest creates a request to an endpoint with the API key and the
+// configured headers
+func (p *CustomOpenAIProvider) newRequest(ctx context.Context, method, endpoint, model string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.endpointURL(endpoint, model), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	for key, value := range p.headers {
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
+
+// post sends a JSON request to an endpoint and reads the response
+func (p *CustomOpenAIProvider) post(ctx context.Context, endpoint, model string, request interface{}, response interface{}) error {
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, http.MethodPost, endpoint, model, reqBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return customOpenAIAPIError(resp.StatusCode, body)
+	}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// stream sends a streaming request to an endpoint and passes the chunks on
+func (p *CustomOpenAIProvider) stream(ctx context.Context, endpoint, model string, request interface{}) (<-chan StreamResponse, error) {
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, http.MethodPost, endpoint, model, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, customOpenAIAPIError(resp.StatusCode, body)
+	}
+
+	streamChan := make(chan StreamResponse, 100)
+	go func() {
+		defer close(streamChan)
+		defer func() { _ = resp.Body.Close() }()
+		if err := NewStreamTranscoder(StreamFormatOpenAI, model).Transcode(resp.Body, streamChan); err != nil {
+			log.Printf("%s stream failed: %v", p.GetName(), err)
+		}
+	}()
+
+	return streamChan, nil
+}
+
+func (p *CustomOpenAIProvider) ChatCompletion(ctx context.Context, request *ChatRequest) (*ChatResponse, error) {
+	var chatResp ChatResponse
+	if err := p.post(ctx, "chat_completions", request.Model, request, &chatResp); err != nil {
+		return nil, err
+	}
+	return &chatResp, nil
+}
+
+func (p *CustomOpenAIProvider) ChatCompletionStream(ctx context.Context, request *ChatRequest) (<-chan StreamResponse, error) {
+	streamRequest := *request
+	streamRequest.Stream = true
+	return p.stream(ctx, "chat_completions", request.Model, &streamRequest)
+}
+
+func (p *CustomOpenAIProvider) Completion(ctx context.Context, request *CompletionRequest) (*CompletionResponse, error) {
+	var compResp CompletionResponse
+	if err := p.post(ctx, "completions", request.Model, request, &compResp); err != nil {
+		return nil, err
+	}
+	return &compResp, nil
+}
+
+func (p *CustomOpenAIProvider) CompletionStream(ctx context.Context, request *CompletionRequest) (<-chan StreamResponse, error) {
+	streamRequest := *request
+	streamRequest.Stream = true
+	return p.stream(ctx, "completions", request.Model, &streamRequest)
+}
+
+func (p *CustomOpenAIProvider) Embeddings(ctx context.Context, request *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	var embResp EmbeddingsResponse
+	if err := p.post(ctx, "embeddings", request.Model, request, &embResp); err != nil {
+		return nil, err
+	}
+	return &embResp, nil
+}
+
+func (p *CustomOpenAIProvider) AudioTranscription(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by custom-openai provider")
+}
+
+func (p *CustomOpenAIProvider) AudioTranscriptionStream(ctx context.Context, request *TranscriptionRequest) (<-chan TranscriptionStreamEvent, error) {
+	return nil, fmt.Errorf("audio transcription not supported by custom-openai provider")
+}
+
+func (p *CustomOpenAIProvider) AudioSpeech(ctx context.Context, request *SpeechRequest) ([]byte, error) {
+	return nil, fmt.Errorf("audio speech not supported by custom-openai provider")
+}
+
+func (p *CustomOpenAIProvider) ImageGeneration(ctx context.Context, request *ImageRequest) (*ImageResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by custom-openai provider")
+}
+
+// SupportsRealtime reports false: realtime sessions are only proxied to
+// OpenAI and Azure
+func (p *CustomOpenAIProvider) SupportsRealtime() bool {
+	return false
+}
+
+// errNoModelListing is returned by FetchAvailableModels for servers that do
+// not list their models
+var errNoModelListing = errors.New("server does not list its models")
+
+// HealthCheck lists the server's models. Servers without a model listing
+// answer 404 and are healthy as long as they answer.
+func (p *CustomOpenAIProvider) HealthCheck(ctx context.Context) error {
+	_, err := p.FetchAvailableModels(ctx)
+	if err != nil && !errors.Is(err, errNoModelListing) {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	p.SetHealthy(true)
+	return nil
+}
+
+// FetchAvailableModels implements ModelDiscoverer with the server's
+// /models listing
+func (p *CustomOpenAIProvider) FetchAvailableModels(ctx context.Context) ([]string, error) {
+	req, err := p.newRequest(ctx, http.MethodGet, "models", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNoModelListing
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing models failed with status %d", resp.StatusCode)
+	}
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+	ids := make([]string, 0, len(models.Data))
+	for _, m := range models.Data {
+		ids = append(ids, m.ID)
+	}
+	return ids, nil
+}
+
+// customOpenAIAPIError builds the error of a failed request. Servers usually
+// report errors in OpenAI's format; anything else is returned as it is.
+func customOpenAIAPIError(status int, body []byte) error {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Message == "" {
+		return fmt.Errorf("request failed with status %d: %s", status, string(body))
+	}
+	return fmt.Errorf("API error (status %d): %s", status, errResp.Error.Message)
+}
@@ -0,0 +1,166 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCustomOpenAIProvider(t *testing.T, extra map[string]interface{}, handler http.HandlerFunc) *CustomOpenAIProvider {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	provider, err := NewCustomOpenAIProvider("custom-test", ProviderConfig{
+		Type:    "custom-openai",
+		BaseURL: server.URL + "/",
+		Models:  []string{"llama-3.1-8b"},
+		Extra:   extra,
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestNewCustomOpenAIProvider(t *testing.T) {
+	_, err := NewCustomOpenAIProvider("custom", ProviderConfig{})
+	assert.Error(t, err, "base URL is required")
+
+	_, err = NewCustomOpenAIProvider("custom", ProviderConfig{
+		BaseURL: "http://localhost:8000/v1",
+		Extra:   map[string]interface{}{"paths": map[string]interface{}{"chat": "/chat"}},
+	})
+	assert.Error(t, err, "unknown endpoints are rejected")
+
+	provider, err := NewCustomOpenAIProvider("custom", ProviderConfig{
+		BaseURL: "http://localhost:8000/v1/",
+		Extra: map[string]interface{}{"paths": map[string]interface{}{
+			"chat_completions": "openai/deployments/{model}/chat/completions?api-version=2024-10-21",
+			"embeddings":       "https://embeddings.internal/v1/embed",
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "custom-openai", provider.GetType())
+	assert.Equal(t, "http://localhost:8000/v1/openai/deployments/gpt-4o/chat/completions?api-version=2024-10-21",
+		provider.endpointURL("chat_completions", "gpt-4o"))
+	assert.Equal(t, "https://embeddings.internal/v1/embed", provider.endpointURL("embeddings", "bge-m3"))
+	assert.Equal(t, "http://localhost:8000/v1/completions", provider.endpointURL("completions", "gpt-4o"))
+}
+
+func TestCustomOpenAIProvider_ChatCompletion(t *testing.T) {
+	provider := newTestCustomOpenAIProvider(t, map[string]interface{}{
+		"headers": map[string]interface{}{"X-Gateway-Key": "gw-secret", "X-Tenant": "research"},
+		"paths":   map[string]interface{}{"chat_completions": "/openai/deployments/{model}/chat/completions"},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/llama-3.1-8b/chat/completions", r.URL.Path)
+		assert.Equal(t, "gw-secret", r.Header.Get("X-Gateway-Key"))
+		assert.Equal(t, "research", r.Header.Get("X-Tenant"))
+		assert.Empty(t, r.Header.Get("Authorization"), "no API key is configured")
+
+		var body ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "llama-3.1-8b", body.Model)
+
+		_, _ = w.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"model": "llama-3.1-8b",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}],
+			"usage": {"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4}
+		}`))
+	})
+
+	resp, err := provider.ChatCompletion(context.Background(), &ChatRequest{
+		Model:    "llama-3.1-8b",
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "Hi", resp.Choices[0].Message.Content)
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+}
+
+func TestCustomOpenAIProvider_ChatCompletionError(t *testing.T) {
+	provider := newTestCustomOpenAIProvider(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"object":"error","message":"This model's maximum context length is 8192 tokens.","type":"BadRequestError","code":400}`))
+	})
+
+	_, err := provider.ChatCompletion(context.Background(), &ChatRequest{Model: "llama-3.1-8b"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "maximum context length", "errors in vLLM's format are returned as they are")
+	assert.Contains(t, err.Error(), "400")
+}
+
+func TestCustomOpenAIProvider_ChatCompletionStream(t *testing.T) {
+	provider := newTestCustomOpenAIProvider(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, true, body["stream"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(
+			`data: {"id":"chatcmpl-2","object":"chat.completion.chunk","model":"llama-3.1-8b","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}` + "\n\n" +
+				`data: {"id":"chatcmpl-2","object":"chat.completion.chunk","model":"llama-3.1-8b","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}` + "\n\n" +
+				"data: [DONE]\n\n"))
+	})
+
+	stream, err := provider.ChatCompletionStream(context.Background(), &ChatRequest{
+		Model:    "llama-3.1-8b",
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	})
+	require.NoError(t, err)
+
+	var chunks []StreamResponse
+	for chunk := range stream {
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "Hi", chunks[0].Choices[0].Delta.Content)
+	require.NotNil(t, chunks[1].Usage)
+	assert.Equal(t, 4, chunks[1].Usage.TotalTokens)
+}
+
+func TestCustomOpenAIProvider_Embeddings(t *testing.T) {
+	provider := newTestCustomOpenAIProvider(t, map[string]interface{}{
+		"paths": map[string]interface{}{"embeddings": "/v1/embed"},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embed", r.URL.Path)
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],"model":"bge-m3","usage":{"prompt_tokens":2,"total_tokens":2}}`))
+	})
+
+	resp, err := provider.Embeddings(context.Background(), &EmbeddingsRequest{Model: "bge-m3", Input: "hello"})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, []float32{0.1, 0.2}, resp.Data[0].Embedding)
+}
+
+func TestCustomOpenAIProvider_HealthCheck(t *testing.T) {
+	status := http.StatusOK
+	provider := newTestCustomOpenAIProvider(t, map[string]interface{}{
+		"headers": map[string]interface{}{"Authorization": "Basic dXNlcjpwYXNz"},
+	}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models", r.URL.Path)
+		assert.Equal(t, "Basic dXNlcjpwYXNz", r.Header.Get("Authorization"), "headers override the default ones")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"llama-3.1-8b","object":"model"}]}`))
+		}
+	})
+
+	models, err := provider.FetchAvailableModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"llama-3.1-8b"}, models)
+	assert.NoError(t, provider.HealthCheck(context.Background()))
+
+	status = http.StatusNotFound
+	assert.NoError(t, provider.HealthCheck(context.Background()), "servers without a model listing are healthy when they answer")
+	assert.True(t, provider.IsHealthy())
+
+	status = http.StatusUnauthorized
+	assert.Error(t, provider.HealthCheck(context.Background()))
+	assert.False(t, provider.IsHealthy())
+}
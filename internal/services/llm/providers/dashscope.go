@@ -0,0 +1,515 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// dashscopeDefaultBaseURL is the Beijing region; international accounts
+	// use https://dashscope-intl.aliyuncs.com
+	dashscopeDefaultBaseURL = "https://dashscope.aliyuncs.com"
+
+	dashscopeGenerationPath = "/api/v1/services/aigc/text-generation/generation"
+	dashscopeEmbeddingPath  = "/api/v1/services/embeddings/text-embedding/text-embedding"
+)
+
+// DashScopeProvider serves Qwen models from Alibaba Cloud Model Studio
+// through the native DashScope API. Requests are authenticated with a
+// DashScope API key, optionally scoped to a workspace (the OrgID).
+type DashScopeProvider struct {
+	*BaseProvider
+	apiKey    string
+	baseURL   string
+	workspace string
+	client    *http.Client
+}
+
+// dashscopeMessage is a message of the generation API, which takes text
+// content only
+type dashscopeMessage struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// dashscopeParameters are the generation parameters; result_format
+// "message" returns OpenAI-style choices
+type dashscopeParameters struct {
+	ResultFormat      string                 `json:"result_format"`
+	IncrementalOutput bool                   `json:"incremental_output,omitempty"`
+	MaxTokens         *int                   `json:"max_tokens,omitempty"`
+	Temperature       *float32               `json:"temperature,omitempty"`
+	TopP              *float32               `json:"top_p,omitempty"`
+	N                 *int                   `json:"n,omitempty"`
+	Seed              *int                   `json:"seed,omitempty"`
+	Stop              []string               `json:"stop,omitempty"`
+	PresencePenalty   *float32               `json:"presence_penalty,omitempty"`
+	ResponseFormat    map[string]interface{} `json:"response_format,omitempty"`
+	Tools             []Tool                 `json:"tools,omitempty"`
+	ToolChoice        interface{}            `json:"tool_choice,omitempty"`
+}
+
+// dashscopeChatRequest is the body of the text generation API
+type dashscopeChatRequest struct {
+	Model string `json:"model"`
+	Input struct {
+		Messages []dashscopeMessage `json:"messages"`
+	} `json:"input"`
+	Parameters dashscopeParameters `json:"parameters"`
+}
+
+type dashscopeChoice struct {
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// dashscopeUsage reports the tokens of a request; streamed responses carry
+// the running total on every event
+type dashscopeUsage struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+func (u dashscopeUsage) usage() Usage {
+	usage := Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = u.InputTokens + u.OutputTokens
+	}
+	if u.PromptTokensDetails != nil && u.PromptTokensDetails.CachedTokens > 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{CachedTokens: u.PromptTokensDetails.CachedTokens}
+	}
+	return usage
+}
+
+// dashscopeChatResponse is a generation response, or an event of a
+// generation stream
+type dashscopeChatResponse struct {
+	RequestID string `json:"request_id"`
+	Output    struct {
+		Choices []dashscopeChoice `json:"choices"`
+	} `json:"output"`
+	Usage dashscopeUsage `json:"usage"`
+
+	// Set on error events of a stream
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type dashscopeError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+}
+
+// NewDashScopeProvider creates a DashScope provider. BaseURL defaults to the
+// Beijing region endpoint; OrgID may set the Model Studio workspace the
+// key's calls are billed to.
+func NewDashScopeProvider(name string, cfg ProviderConfig) (*DashScopeProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("dashscope API key is required")
+	}
+
+	p := &DashScopeProvider{
+		BaseProvider: NewBaseProvider(name, "dashscope", cfg.Priority, cfg.Models),
+		apiKey:       cfg.APIKey,
+		baseURL:      strings.TrimSuffix(strings.TrimSuffix(cfg.BaseURL, "/"), "/api/v1"),
+		workspace:    cfg.OrgID,
+		client: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: newCaptureTransport("dashscope"),
+		},
+	}
+	if p.baseURL == "" {
+		p.baseURL = dashscopeDefaultBaseURL
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = cfg.Timeout
+	}
+	return p, nil
+}
+
+// newRequest creates an authenticated request to a DashScope API path
+func (p *DashScopeProvider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if p.workspace != "" {
+		req.Header.Set("X-DashScope-WorkSpace", p.workspace)
+	}
+	return req, nil
+}
+
+// do sends a request and returns the body of a successful response
+func (p *DashScopeProvider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, dashscopeAPIError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// dashscopeChatBody converts an OpenAI chat request to the DashScope format
+func dashscopeChatBody(request *ChatRequest, stream bool) *dashscopeChatRequest {
+	body := &dashscopeChatRequest{Model: request.Model}
+	body.Parameters = dashscopeParameters{
+		ResultFormat:    "message",
+		MaxTokens:       request.MaxTokens,
+		Temperature:     request.Temperature,
+		TopP:            request.TopP,
+		N:               request.N,
+		Seed:            request.Seed,
+		Stop:            request.Stop,
+		PresencePenalty: request.PresencePenalty,
+		Tools:           request.Tools,
+		ToolChoice:      request.ToolChoice,
+	}
+	// Streams send each piece once rather than the text so far
+	if stream {
+		body.Parameters.IncrementalOutput = true
+	}
+	if format := request.ResponseFormat; format != nil && format.Type != "text" {
+		body.Parameters.ResponseFormat = map[string]interface{}{"type": "json_object"}
+	}
+
+	for _, msg := range request.Messages {
+		role := msg.Role
+		if role == "developer" {
+			role = "system"
+		}
+		body.Input.Messages = append(body.Input.Messages, dashscopeMessage{
+			Role:       role,
+			Content:    messageText(msg.Content),
+			Name:       msg.Name,
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+	return body
+}
+
+// dashscopeFinishReason maps a DashScope finish reason to the OpenAI one;
+// events before the last report "null"
+func dashscopeFinishReason(reason string) string {
+	if reason == "null" {
+		return ""
+	}
+	return reason
+}
+
+func (p *DashScopeProvider) ChatCompletion(ctx context.Context, request *ChatRequest) (*ChatResponse, error) {
+	reqBody, err := json.Marshal(dashscopeChatBody(request, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", dashscopeGenerationPath, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var dsResp dashscopeChatResponse
+	if err := json.Unmarshal(body, &dsResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	chatResp := &ChatResponse{
+		ID:      dsResp.RequestID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   request.Model,
+		Usage:   dsResp.Usage.usage(),
+	}
+	if chatResp.ID == "" {
+		chatResp.ID = GenerateID()
+	}
+	for i, choice := range dsResp.Output.Choices {
+		message := choice.Message
+		if message.Role == "" {
+			message.Role = "assistant"
+		}
+		chatResp.Choices = append(chatResp.Choices, Choice{
+			Index:        i,
+			Message:      message,
+			FinishReason: dashscopeFinishReason(choice.FinishReason),
+		})
+	}
+	return chatResp, nil
+}
+
+func (p *DashScopeProvider) ChatCompletionStream(ctx context.Context, request *ChatRequest) (<-chan StreamResponse, error) {
+	reqBody, err := json.Marshal(dashscopeChatBody(request, true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", dashscopeGenerationPath, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-DashScope-SSE", "enable")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, dashscopeAPIError(resp.StatusCode, body)
+	}
+
+	streamChan := make(chan StreamResponse, 100)
+	go func() {
+		defer close(streamChan)
+		defer func() { _ = resp.Body.Close() }()
+		if err := parseDashScopeStream(resp.Body, request.Model, streamChan); err != nil {
+			log.Printf("DashScope stream failed: %v", err)
+		}
+	}()
+
+	return streamChan, nil
+}
+
+// parseDashScopeStream converts the generation stream to OpenAI chunks. With
+// incremental output each event carries the new text and tool call pieces,
+// and the usage so far, which is sent with the finish reason.
+func parseDashScopeStream(body io.Reader, model string, streamChan chan<- StreamResponse) error {
+	id := GenerateID()
+	created := time.Now().Unix()
+	roleSent := false
+
+	return readSSE(body, func(data []byte) error {
+		var event dashscopeChatResponse
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil // Skip malformed data
+		}
+		if event.Code != "" && len(event.Output.Choices) == 0 {
+			return fmt.Errorf("dashscope stream error (%s): %s", event.Code, event.Message)
+		}
+
+		for i, choice := range event.Output.Choices {
+			delta := Message{Content: choice.Message.Content, ToolCalls: choice.Message.ToolCalls}
+			for j := range delta.ToolCalls {
+				if delta.ToolCalls[j].Index == nil {
+					index := j
+					delta.ToolCalls[j].Index = &index
+				}
+			}
+			if !roleSent {
+				delta.Role = "assistant"
+				roleSent = true
+			}
+
+			chunk := StreamResponse{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   model,
+				Choices: []StreamChoice{{
+					Index:        i,
+					Delta:        delta,
+					FinishReason: dashscopeFinishReason(choice.FinishReason),
+				}},
+			}
+			if chunk.Choices[0].FinishReason != "" {
+				usage := event.Usage.usage()
+				chunk.Usage = &usage
+			}
+			streamChan <- chunk
+		}
+		return nil
+	})
+}
+
+func (p *DashScopeProvider) Completion(ctx context.Context, request *CompletionRequest) (*CompletionResponse, error) {
+	return nil, fmt.Errorf("completion API not supported by dashscope provider")
+}
+
+func (p *DashScopeProvider) CompletionStream(ctx context.Context, request *CompletionRequest) (<-chan StreamResponse, error) {
+	return nil, fmt.Errorf("completion stream API not supported by dashscope provider")
+}
+
+func (p *DashScopeProvider) Embeddings(ctx context.Context, request *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	var texts []string
+	switch input := request.Input.(type) {
+	case string:
+		texts = []string{input}
+	case []string:
+		texts = input
+	case []interface{}:
+		for _, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("dashscope embeddings only support text input")
+			}
+			texts = append(texts, text)
+		}
+	default:
+		return nil, fmt.Errorf("dashscope embeddings only support text input")
+	}
+
+	payload := map[string]interface{}{
+		"model": request.Model,
+		"input": map[string]interface{}{"texts": texts},
+	}
+	if request.Dimensions != nil {
+		payload["parameters"] = map[string]interface{}{"dimension": *request.Dimensions}
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", dashscopeEmbeddingPath, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var dsResp struct {
+		Output struct {
+			Embeddings []struct {
+				TextIndex int       `json:"text_index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"embeddings"`
+		} `json:"output"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &dsResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	embResp := &EmbeddingsResponse{
+		Object: "list",
+		Model:  request.Model,
+		Usage: Usage{
+			PromptTokens: dsResp.Usage.TotalTokens,
+			TotalTokens:  dsResp.Usage.TotalTokens,
+		},
+	}
+	for _, result := range dsResp.Output.Embeddings {
+		embResp.Data = append(embResp.Data, Embedding{
+			Object:    "embedding",
+			Index:     result.TextIndex,
+			Embedding: result.Embedding,
+		})
+	}
+	return embResp, nil
+}
+
+func (p *DashScopeProvider) AudioTranscription(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by dashscope provider")
+}
+
+func (p *DashScopeProvider) AudioSpeech(ctx context.Context, request *SpeechRequest) ([]byte, error) {
+	return nil, fmt.Errorf("audio speech not supported by dashscope provider")
+}
+
+func (p *DashScopeProvider) ImageGeneration(ctx context.Context, request *ImageRequest) (*ImageResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by dashscope provider")
+}
+
+// HealthCheck lists the models of the compatible-mode API, which checks the
+// API key without generating
+func (p *DashScopeProvider) HealthCheck(ctx context.Context) error {
+	req, err := p.newRequest(ctx, "GET", "/compatible-mode/v1/models", nil)
+	if err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+	}
+
+	p.SetHealthy(true)
+	return nil
+}
+
+// dashscopeAPIError formats a DashScope error response
+func dashscopeAPIError(status int, body []byte) error {
+	var errResp dashscopeError
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Code == "" {
+		return fmt.Errorf("request failed with status %d: %s", status, string(body))
+	}
+	return fmt.Errorf("dashscope API error (%s): %s", errResp.Code, errResp.Message)
+}
+
+// messageText returns the text of a message content, a string or a list of
+// content parts, for providers that only take text
+func messageText(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []MessageContent:
+		parts := make([]string, 0, len(c))
+		for _, part := range c {
+			if part.Type == "text" {
+				parts = append(parts, part.Text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	case []interface{}:
+		parts := make([]string, 0, len(c))
+		for _, part := range c {
+			if p, ok := part.(map[string]interface{}); ok && p["type"] == "text" {
+				if text, ok := p["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
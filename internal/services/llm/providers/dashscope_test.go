@@ -0,0 +1,172 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDashScopeProvider(t *testing.T, handler http.HandlerFunc) *DashScopeProvider {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	provider, err := NewDashScopeProvider("dashscope-test", ProviderConfig{
+		Type:    "dashscope",
+		APIKey:  "sk-test",
+		BaseURL: server.URL,
+		OrgID:   "ws-1",
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestNewDashScopeProvider(t *testing.T) {
+	_, err := NewDashScopeProvider("ds", ProviderConfig{})
+	assert.Error(t, err, "API key is required")
+
+	provider, err := NewDashScopeProvider("ds", ProviderConfig{APIKey: "sk"})
+	require.NoError(t, err)
+	assert.Equal(t, "dashscope", provider.GetType())
+	assert.Equal(t, dashscopeDefaultBaseURL, provider.baseURL)
+
+	provider, err = NewDashScopeProvider("ds", ProviderConfig{APIKey: "sk", BaseURL: "https://dashscope-intl.aliyuncs.com/api/v1/"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://dashscope-intl.aliyuncs.com", provider.baseURL)
+}
+
+func TestDashScopeProvider_ChatCompletion(t *testing.T) {
+	provider := newTestDashScopeProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, dashscopeGenerationPath, r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		assert.Equal(t, "ws-1", r.Header.Get("X-DashScope-WorkSpace"))
+
+		var body dashscopeChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "qwen-plus", body.Model)
+		assert.Equal(t, "message", body.Parameters.ResultFormat)
+		assert.False(t, body.Parameters.IncrementalOutput)
+		require.Len(t, body.Input.Messages, 2)
+		assert.Equal(t, "system", body.Input.Messages[0].Role)
+		assert.Equal(t, "Describe\nthis", body.Input.Messages[1].Content, "text parts are joined")
+
+		_, _ = w.Write([]byte(`{
+			"request_id": "req-1",
+			"output": {"choices": [{"finish_reason": "stop", "message": {"role": "assistant", "content": "A cat"}}]},
+			"usage": {"input_tokens": 10, "output_tokens": 2, "total_tokens": 12, "prompt_tokens_details": {"cached_tokens": 8}}
+		}`))
+	})
+
+	resp, err := provider.ChatCompletion(context.Background(), &ChatRequest{
+		Model: "qwen-plus",
+		Messages: []Message{
+			{Role: "developer", Content: "Be brief"},
+			{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "Describe"},
+				map[string]interface{}{"type": "text", "text": "this"},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", resp.ID)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "A cat", resp.Choices[0].Message.Content)
+	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+	assert.Equal(t, 12, resp.Usage.TotalTokens)
+	require.NotNil(t, resp.Usage.PromptTokensDetails)
+	assert.Equal(t, 8, resp.Usage.PromptTokensDetails.CachedTokens)
+}
+
+func TestDashScopeProvider_ChatCompletionError(t *testing.T) {
+	provider := newTestDashScopeProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code":"InvalidApiKey","message":"Invalid API-key provided.","request_id":"req-2"}`))
+	})
+
+	_, err := provider.ChatCompletion(context.Background(), &ChatRequest{Model: "qwen-plus"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "InvalidApiKey")
+
+	assert.Error(t, provider.HealthCheck(context.Background()))
+	assert.False(t, provider.IsHealthy())
+}
+
+func TestDashScopeProvider_ChatCompletionStream(t *testing.T) {
+	provider := newTestDashScopeProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "enable", r.Header.Get("X-DashScope-SSE"))
+		var body dashscopeChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.True(t, body.Parameters.IncrementalOutput)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("id:1\nevent:result\n:HTTP_STATUS/200\n" +
+			`data:{"output":{"choices":[{"message":{"content":"","role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":"}}]},"finish_reason":"null"}]},"usage":{"input_tokens":20,"output_tokens":3,"total_tokens":23},"request_id":"req-3"}` + "\n\n" +
+			"id:2\nevent:result\n:HTTP_STATUS/200\n" +
+			`data:{"output":{"choices":[{"message":{"content":"","role":"assistant","tool_calls":[{"index":0,"function":{"arguments":"\"Hangzhou\"}"}}]},"finish_reason":"tool_calls"}]},"usage":{"input_tokens":20,"output_tokens":9,"total_tokens":29},"request_id":"req-3"}` + "\n\n"))
+	})
+
+	stream, err := provider.ChatCompletionStream(context.Background(), &ChatRequest{
+		Model:    "qwen-plus",
+		Messages: []Message{{Role: "user", Content: "Weather in Hangzhou?"}},
+	})
+	require.NoError(t, err)
+
+	var chunks []StreamResponse
+	var arguments strings.Builder
+	for chunk := range stream {
+		chunks = append(chunks, chunk)
+		for _, call := range chunk.Choices[0].Delta.ToolCalls {
+			arguments.WriteString(call.Function.Arguments)
+		}
+	}
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "get_weather", chunks[0].Choices[0].Delta.ToolCalls[0].Function.Name)
+	assert.Empty(t, chunks[0].Choices[0].FinishReason, `"null" is no finish reason`)
+	assert.Nil(t, chunks[0].Usage)
+	assert.Equal(t, `{"city":"Hangzhou"}`, arguments.String())
+	assert.Equal(t, "tool_calls", chunks[1].Choices[0].FinishReason)
+	require.NotNil(t, chunks[1].Usage)
+	assert.Equal(t, 9, chunks[1].Usage.CompletionTokens)
+}
+
+func TestParseDashScopeStream_Error(t *testing.T) {
+	body := "id:1\nevent:error\n:HTTP_STATUS/400\n" +
+		`data:{"code":"DataInspectionFailed","message":"Input data may contain inappropriate content.","request_id":"req-4"}` + "\n\n"
+
+	streamChan := make(chan StreamResponse, 10)
+	err := parseDashScopeStream(strings.NewReader(body), "qwen-plus", streamChan)
+	close(streamChan)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DataInspectionFailed")
+	assert.Empty(t, streamChan)
+}
+
+func TestDashScopeProvider_Embeddings(t *testing.T) {
+	provider := newTestDashScopeProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, dashscopeEmbeddingPath, r.URL.Path)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"texts": []interface{}{"a", "b"}}, body["input"])
+		assert.Equal(t, map[string]interface{}{"dimension": float64(512)}, body["parameters"])
+
+		_, _ = w.Write([]byte(`{"output":{"embeddings":[{"text_index":0,"embedding":[0.1,0.2]},{"text_index":1,"embedding":[0.3,0.4]}]},"usage":{"total_tokens":4},"request_id":"req-5"}`))
+	})
+
+	dimensions := 512
+	resp, err := provider.Embeddings(context.Background(), &EmbeddingsRequest{
+		Model:      "text-embedding-v3",
+		Input:      []string{"a", "b"},
+		Dimensions: &dimensions,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, 1, resp.Data[1].Index)
+	assert.Equal(t, []float32{0.3, 0.4}, resp.Data[1].Embedding)
+	assert.Equal(t, 4, resp.Usage.PromptTokens)
+}
@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"fmt"
+	"math"
+)
+
+// TruncateDimensions reduces every embedding to its first dimensions
+// components and rescales it to unit length. This is the Matryoshka-style
+// reduction that providers supporting the dimensions parameter apply, so
+// vectors of one size can be used across providers that do not.
+func (r *EmbeddingsResponse) TruncateDimensions(dimensions int) error {
+	if dimensions <= 0 {
+		return fmt.Errorf("dimensions must be positive, got %d", dimensions)
+	}
+
+	for i := range r.Data {
+		vector := r.Data[i].Embedding
+		if len(vector) < dimensions {
+			return fmt.Errorf("model %s returns %d dimensions, cannot reduce to %d", r.Model, len(vector), dimensions)
+		}
+		r.Data[i].Embedding = normalize(vector[:dimensions:dimensions])
+	}
+	return nil
+}
+
+// normalize scales the vector in place to unit length. Zero vectors are
+// returned unchanged.
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+
+	norm := math.Sqrt(sum)
+	for i, v := range vector {
+		vector[i] = float32(float64(v) / norm)
+	}
+	return vector
+}
@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func vectorNorm(vector []float32) float64 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
+}
+
+func TestEmbeddingsResponse_TruncateDimensions(t *testing.T) {
+	response := &EmbeddingsResponse{
+		Model: "text-embedding",
+		Data: []Embedding{
+			{Index: 0, Embedding: []float32{3, 4, 12, 5}},
+			{Index: 1, Embedding: []float32{0, 0, 1, 0}},
+		},
+	}
+
+	require.NoError(t, response.TruncateDimensions(2))
+
+	assert.InDeltaSlice(t, []float32{0.6, 0.8}, response.Data[0].Embedding, 1e-6)
+	assert.InDelta(t, 1.0, vectorNorm(response.Data[0].Embedding), 1e-6)
+	// A vector whose leading components are zero stays zero
+	assert.Equal(t, []float32{0, 0}, response.Data[1].Embedding)
+}
+
+func TestEmbeddingsResponse_TruncateDimensionsErrors(t *testing.T) {
+	response := &EmbeddingsResponse{
+		Model: "text-embedding",
+		Data:  []Embedding{{Embedding: []float32{1, 2, 3}}},
+	}
+
+	assert.Error(t, response.TruncateDimensions(0))
+
+	err := response.TruncateDimensions(4)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "returns 3 dimensions")
+}
@@ -0,0 +1,319 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Defaults of the invocation capture
+const (
+	DefaultInvocationRetention    = 15 * time.Minute
+	DefaultInvocationMaxEntries   = 1000
+	DefaultInvocationMaxBodyBytes = 64 * 1024
+)
+
+// redacted replaces secrets in captured invocations
+const redacted = "[REDACTED]"
+
+// Invocation is one HTTP request sent to a provider, as it left the gateway
+// after translation, with secrets removed, and the status and headers of
+// the provider's response. Response bodies are never kept.
+type Invocation struct {
+	RequestID      string            `json:"request_id,omitempty"`
+	Provider       string            `json:"provider"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	RequestHeaders map[string]string `json:"request_headers,omitempty"`
+	// RequestBody is the JSON payload with secret fields redacted, cut at
+	// the capture's body limit; other payloads are only described
+	RequestBody     string `json:"request_body,omitempty"`
+	RequestBodySize int64  `json:"request_body_size"`
+	BodyTruncated   bool   `json:"body_truncated,omitempty"`
+
+	StatusCode      int               `json:"status_code,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	Error           string            `json:"error,omitempty"`
+
+	StartedAt time.Time `json:"started_at"`
+	// Time until the response headers arrived
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// InvocationCaptureOptions configures the invocation capture
+type InvocationCaptureOptions struct {
+	Retention    time.Duration // How long invocations are kept
+	MaxEntries   int           // Most recent invocations kept
+	MaxBodyBytes int           // Longest request body kept
+	// RequestID returns the gateway request ID of a provider call's context
+	RequestID func(context.Context) string
+}
+
+// InvocationCapture keeps the most recent provider invocations of this
+// replica in memory, for debugging translation bugs
+type InvocationCapture struct {
+	opts InvocationCaptureOptions
+
+	mu      sync.Mutex
+	entries []Invocation // Ring buffer
+	next    int
+	full    bool
+}
+
+var invocationCapture atomic.Pointer[InvocationCapture]
+
+// EnableInvocationCapture starts capturing the requests of every provider
+// and returns the capture
+func EnableInvocationCapture(opts InvocationCaptureOptions) *InvocationCapture {
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultInvocationRetention
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultInvocationMaxEntries
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultInvocationMaxBodyBytes
+	}
+	c := &InvocationCapture{opts: opts, entries: make([]Invocation, opts.MaxEntries)}
+	invocationCapture.Store(c)
+	return c
+}
+
+// DisableInvocationCapture stops capturing and drops the captured invocations
+func DisableInvocationCapture() {
+	invocationCapture.Store(nil)
+}
+
+// Invocations returns the active capture, or nil when capture is disabled
+func Invocations() *InvocationCapture {
+	return invocationCapture.Load()
+}
+
+// InvocationFilter selects captured invocations; empty fields match all
+type InvocationFilter struct {
+	RequestID string
+	Provider  string
+	// Only invocations whose response status is at least MinStatus, or
+	// that failed without one
+	MinStatus int
+	Limit     int
+}
+
+// List returns the retained invocations matching the filter, newest first
+func (c *InvocationCapture) List(filter InvocationFilter) []Invocation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cutoff := time.Now().Add(-c.opts.Retention)
+	size := c.next
+	if c.full {
+		size = len(c.entries)
+	}
+	result := make([]Invocation, 0)
+	for i := 1; i <= size; i++ {
+		inv := c.entries[(c.next-i+len(c.entries))%len(c.entries)]
+		if inv.StartedAt.Before(cutoff) {
+			break // Older entries are older still
+		}
+		if filter.RequestID != "" && inv.RequestID != filter.RequestID {
+			continue
+		}
+		if filter.Provider != "" && inv.Provider != filter.Provider {
+			continue
+		}
+		if filter.MinStatus > 0 && inv.Error == "" && inv.StatusCode < filter.MinStatus {
+			continue
+		}
+		result = append(result, inv)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// Retention returns how long invocations are kept
+func (c *InvocationCapture) Retention() time.Duration {
+	return c.opts.Retention
+}
+
+func (c *InvocationCapture) add(inv Invocation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[c.next] = inv
+	c.next = (c.next + 1) % len(c.entries)
+	if c.next == 0 {
+		c.full = true
+	}
+}
+
+// captureTransport records the requests of a provider's HTTP client while
+// the invocation capture is enabled
+type captureTransport struct {
+	provider string
+	base     http.RoundTripper
+}
+
+// newCaptureTransport returns the transport of a provider's HTTP client
+func newCaptureTransport(provider string) http.RoundTripper {
+	return &captureTransport{provider: provider}
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c := invocationCapture.Load()
+	if c == nil {
+		return base.RoundTrip(req)
+	}
+
+	inv := c.request(t.provider, req)
+	resp, err := base.RoundTrip(req)
+	inv.DurationMs = time.Since(inv.StartedAt).Milliseconds()
+	if err != nil {
+		inv.Error = err.Error()
+	} else {
+		inv.StatusCode = resp.StatusCode
+		inv.ResponseHeaders = sanitizeHeaders(resp.Header)
+	}
+	c.add(inv)
+	return resp, err
+}
+
+// request captures a request without consuming its body
+func (c *InvocationCapture) request(provider string, req *http.Request) Invocation {
+	inv := Invocation{
+		Provider:        provider,
+		Method:          req.Method,
+		URL:             sanitizeURL(req.URL),
+		RequestHeaders:  sanitizeHeaders(req.Header),
+		RequestBodySize: req.ContentLength,
+		StartedAt:       time.Now(),
+	}
+	if c.opts.RequestID != nil {
+		inv.RequestID = c.opts.RequestID(req.Context())
+	}
+	if req.GetBody == nil || req.ContentLength == 0 {
+		return inv
+	}
+
+	contentType := req.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "json") {
+		inv.RequestBody = fmt.Sprintf("[%d bytes of %s]", req.ContentLength, contentType)
+		return inv
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return inv
+	}
+	defer func() { _ = body.Close() }()
+	var payload interface{}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		inv.RequestBody = fmt.Sprintf("[%d bytes of invalid JSON]", req.ContentLength)
+		return inv
+	}
+	sanitized, err := json.Marshal(sanitizeJSON(payload))
+	if err != nil {
+		return inv
+	}
+	inv.RequestBody, inv.BodyTruncated = truncateUTF8(string(sanitized), c.opts.MaxBodyBytes)
+	return inv
+}
+
+// secretFields are the JSON fields redacted from request bodies
+var secretFields = map[string]bool{
+	"api_key": true, "apikey": true, "key": true, "secret": true, "client_secret": true,
+	"password": true, "token": true, "access_token": true, "refresh_token": true,
+	"authorization": true, "private_key": true, "credentials": true,
+}
+
+// sanitizeJSON redacts the values of secret fields at any depth
+func sanitizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, item := range v {
+			if secretFields[strings.ToLower(field)] {
+				v[field] = redacted
+				continue
+			}
+			v[field] = sanitizeJSON(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = sanitizeJSON(item)
+		}
+	}
+	return value
+}
+
+// secretHeader reports whether a header or query parameter carries a secret
+func secretHeader(name string) bool {
+	name = strings.ToLower(name)
+	if name == "cookie" || name == "set-cookie" || name == "sig" {
+		return true
+	}
+	for _, part := range []string{"auth", "key", "token", "secret", "signature", "credential"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sanitized := make(map[string]string, len(header))
+	for _, name := range names {
+		if secretHeader(name) {
+			sanitized[name] = redacted
+			continue
+		}
+		sanitized[name] = strings.Join(header[name], ", ")
+	}
+	return sanitized
+}
+
+func sanitizeURL(u *url.URL) string {
+	sanitized := *u
+	sanitized.User = nil
+	if sanitized.RawQuery != "" {
+		query := sanitized.Query()
+		for name := range query {
+			if secretHeader(name) {
+				query.Set(name, redacted)
+			}
+		}
+		sanitized.RawQuery = query.Encode()
+	}
+	return sanitized.String()
+}
+
+// truncateUTF8 cuts s to at most max bytes without splitting a character
+func truncateUTF8(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
+}
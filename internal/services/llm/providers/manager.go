@@ -94,13 +94,28 @@ func (m *Manager) createProvider(name string, cfg ProviderConfig) (Provider, err
 	case "vertex":
 		return NewVertexProvider(name, cfg)
 	case "cohere":
-		// TODO: Implement CohereProvider
-		return nil, fmt.Errorf("cohere provider not implemented yet")
+		return NewCohereProvider(name, cfg)
 	case "huggingface":
 		// TODO: Implement HuggingFaceProvider
 		return nil, fmt.Errorf("huggingface provider not implemented yet")
 	case "openrouter":
 		return NewOpenRouterProvider(name, cfg)
+	case NvidiaServerNIM, NvidiaServerTriton:
+		return NewNvidiaProvider(name, cfg)
+	case "watsonx":
+		return NewWatsonxProvider(name, cfg)
+	case "azure_mistral":
+		return NewAzureMistralProvider(name, cfg)
+	case "ollama":
+		return NewOllamaProvider(name, cfg)
+	case "dashscope":
+		return NewDashScopeProvider(name, cfg)
+	case "qianfan":
+		return NewQianfanProvider(name, cfg)
+	case "perplexity":
+		return NewPerplexityProvider(name, cfg)
+	case "custom-openai":
+		return NewCustomOpenAIProvider(name, cfg)
 	case "custom":
 		// TODO: Implement CustomProvider
 		return nil, fmt.Errorf("custom provider not implemented yet")
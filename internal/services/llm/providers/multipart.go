@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+)
+
+// transcriptionFilename is sent when the client did not name the audio file
+const transcriptionFilename = "audio.wav"
+
+// transcriptionBody returns a multipart transcription form that is written
+// as the provider reads it, so the audio file streams through without being
+// held in memory, and its content type. The body must be closed if it is
+// never sent; http.Client closes it otherwise.
+func transcriptionBody(request *TranscriptionRequest, stream bool) (io.ReadCloser, string) {
+	reader, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	go func() {
+		pipe.CloseWithError(writeTranscriptionForm(writer, request, stream))
+	}()
+	return reader, writer.FormDataContentType()
+}
+
+// writeTranscriptionForm writes the fields before the file, so that the
+// provider can validate them before the upload completes
+func writeTranscriptionForm(writer *multipart.Writer, request *TranscriptionRequest, stream bool) error {
+	fields := [][2]string{{"model", request.Model}}
+	if request.Language != "" {
+		fields = append(fields, [2]string{"language", request.Language})
+	}
+	if request.Prompt != "" {
+		fields = append(fields, [2]string{"prompt", request.Prompt})
+	}
+	if request.ResponseFormat != "" {
+		fields = append(fields, [2]string{"response_format", request.ResponseFormat})
+	}
+	if request.Temperature != nil {
+		fields = append(fields, [2]string{"temperature", fmt.Sprintf("%.2f", *request.Temperature)})
+	}
+	if stream {
+		fields = append(fields, [2]string{"stream", "true"})
+	}
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return fmt.Errorf("failed to write %s field: %w", field[0], err)
+		}
+	}
+
+	filename := request.Filename
+	if filename == "" {
+		filename = transcriptionFilename
+	}
+	fileWriter, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(fileWriter, request.File); err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return writer.Close()
+}
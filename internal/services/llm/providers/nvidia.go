@@ -0,0 +1,333 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Inference servers served by NvidiaProvider
+const (
+	NvidiaServerNIM    = "nim"
+	NvidiaServerTriton = "triton"
+)
+
+// nvidiaLoadTTL is how long a scraped GPU utilization is used for routing
+const nvidiaLoadTTL = 2 * time.Minute
+
+// LoadReporter is implemented by providers that know how busy the server
+// behind them is. Load is between 0 (idle) and 1 (saturated); ok is false
+// when the load is unknown.
+type LoadReporter interface {
+	Load() (load float64, ok bool)
+}
+
+// NvidiaProvider serves models from NVIDIA NIM microservices and Triton
+// Inference Server's OpenAI-compatible frontend. Inference goes through the
+// OpenAI-compatible API; health checks probe the server's readiness and
+// model repository, and GPU utilization is scraped from its Prometheus
+// metrics when they are exposed.
+type NvidiaProvider struct {
+	*OpenAIProvider
+	server     string
+	serverURL  string
+	metricsURL string
+
+	mu        sync.RWMutex
+	load      float64
+	loadAt    time.Time
+	noMetrics bool
+}
+
+// NewNvidiaProvider creates a provider for a NIM or Triton server. The type
+// is "nim" or "triton". Extra may set server_url, the server root used for
+// health and repository probes (default: base_url without /v1), and
+// metrics_url (default: <server_url>/v1/metrics for NIM and port 8002 of
+// the server for Triton).
+func NewNvidiaProvider(name string, cfg ProviderConfig) (*NvidiaProvider, error) {
+	server := cfg.Type
+	if server != NvidiaServerNIM && server != NvidiaServerTriton {
+		return nil, fmt.Errorf("unknown NVIDIA inference server: %s", server)
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required for %s", server)
+	}
+
+	openai, err := NewOpenAIProvider(name, ProviderConfig{
+		APIKey:  cfg.APIKey,
+		BaseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Served models are only known from the configuration
+	openai.BaseProvider = NewBaseProvider(name, server, cfg.Priority, cfg.Models)
+	openai.client.Transport = newCaptureTransport(server)
+	if cfg.Timeout > 0 {
+		openai.client.Timeout = cfg.Timeout
+	}
+
+	serverURL := strings.TrimSuffix(strings.TrimSuffix(cfg.BaseURL, "/"), "/v1")
+	metricsURL := ""
+	if cfg.Extra != nil {
+		if v, ok := cfg.Extra["server_url"].(string); ok && v != "" {
+			serverURL = strings.TrimSuffix(v, "/")
+		}
+		if v, ok := cfg.Extra["metrics_url"].(string); ok && v != "" {
+			metricsURL = v
+		}
+	}
+	if metricsURL == "" {
+		metricsURL = defaultNvidiaMetricsURL(server, serverURL)
+	}
+
+	return &NvidiaProvider{
+		OpenAIProvider: openai,
+		server:         server,
+		serverURL:      serverURL,
+		metricsURL:     metricsURL,
+	}, nil
+}
+
+// defaultNvidiaMetricsURL returns where the server usually exposes its
+// metrics: NIM on its API port, Triton on port 8002
+func defaultNvidiaMetricsURL(server, serverURL string) string {
+	if server == NvidiaServerNIM {
+		return serverURL + "/v1/metrics"
+	}
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	u.Host = u.Hostname() + ":8002"
+	u.Path = "/metrics"
+	return u.String()
+}
+
+// HealthCheck checks that the server is ready and that its model repository
+// serves the configured models, then refreshes the GPU utilization
+func (p *NvidiaProvider) HealthCheck(ctx context.Context) error {
+	var err error
+	if p.server == NvidiaServerTriton {
+		err = p.checkTriton(ctx)
+	} else {
+		err = p.checkNIM(ctx)
+	}
+	if err != nil {
+		p.SetHealthy(false)
+		return err
+	}
+	p.SetHealthy(true)
+
+	p.refreshLoad(ctx)
+	return nil
+}
+
+func (p *NvidiaProvider) checkNIM(ctx context.Context) error {
+	if err := p.probe(ctx, http.MethodGet, p.serverURL+"/v1/health/ready", nil, nil); err != nil {
+		return err
+	}
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := p.probe(ctx, http.MethodGet, p.baseURL+"/models", nil, &models); err != nil {
+		return err
+	}
+	served := make(map[string]bool, len(models.Data))
+	for _, m := range models.Data {
+		served[m.ID] = true
+	}
+	return p.checkServed(served)
+}
+
+func (p *NvidiaProvider) checkTriton(ctx context.Context) error {
+	if err := p.probe(ctx, http.MethodGet, p.serverURL+"/v2/health/ready", nil, nil); err != nil {
+		return err
+	}
+
+	var repository []struct {
+		Name  string `json:"name"`
+		State string `json:"state"`
+	}
+	if err := p.probe(ctx, http.MethodPost, p.serverURL+"/v2/repository/index", []byte(`{"ready":true}`), &repository); err != nil {
+		return err
+	}
+	served := make(map[string]bool, len(repository))
+	for _, m := range repository {
+		if m.State == "" || m.State == "READY" {
+			served[m.Name] = true
+		}
+	}
+	return p.checkServed(served)
+}
+
+// checkServed fails when a configured model is missing from the repository
+func (p *NvidiaProvider) checkServed(served map[string]bool) error {
+	if len(served) == 0 {
+		return fmt.Errorf("%s model repository has no ready models", p.server)
+	}
+	for _, model := range p.ListModels() {
+		if !served[model] {
+			return fmt.Errorf("model %s is not ready on the %s server", model, p.server)
+		}
+	}
+	return nil
+}
+
+// probe sends a health request and decodes the JSON response into out
+func (p *NvidiaProvider) probe(ctx context.Context, method, target string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check %s failed with status %d", req.URL.Path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// refreshLoad scrapes the GPU utilization. Servers without metrics are not
+// asked again.
+func (p *NvidiaProvider) refreshLoad(ctx context.Context) {
+	p.mu.RLock()
+	skip := p.metricsURL == "" || p.noMetrics
+	p.mu.RUnlock()
+	if skip {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.metricsURL, nil)
+	if err != nil {
+		return
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		p.mu.Lock()
+		p.noMetrics = true
+		p.mu.Unlock()
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	if load, ok := parseGPUUtilization(resp.Body); ok {
+		p.mu.Lock()
+		p.load = load
+		p.loadAt = time.Now()
+		p.mu.Unlock()
+	}
+}
+
+// Load implements LoadReporter with the last scraped GPU utilization
+func (p *NvidiaProvider) Load() (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.loadAt.IsZero() || time.Since(p.loadAt) > nvidiaLoadTTL {
+		return 0, false
+	}
+	return p.load, true
+}
+
+// gpuUtilizationMetrics are read in order of preference: Triton's GPU
+// utilization, then the KV cache usage NIM LLM servers report
+var gpuUtilizationMetrics = []string{
+	"nv_gpu_utilization",
+	"gpu_cache_usage_perc",
+	"vllm:gpu_cache_usage_perc",
+}
+
+// parseGPUUtilization averages the first known utilization metric over all
+// GPUs in a Prometheus text exposition
+func parseGPUUtilization(r io.Reader) (float64, bool) {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := line
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name = line[:i]
+		}
+		if !containsMetric(name) {
+			continue
+		}
+		rest := line[len(name):]
+		if i := strings.LastIndex(rest, "}"); i >= 0 {
+			rest = rest[i+1:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		sums[name] += value
+		counts[name]++
+	}
+
+	for _, name := range gpuUtilizationMetrics {
+		if counts[name] > 0 {
+			load := sums[name] / float64(counts[name])
+			if load > 1 {
+				load = 1
+			}
+			if load < 0 {
+				load = 0
+			}
+			return load, true
+		}
+	}
+	return 0, false
+}
+
+func containsMetric(name string) bool {
+	for _, metric := range gpuUtilizationMetrics {
+		if metric == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNvidiaProvider_NIMHealthAndLoad(t *testing.T) {
+	ready := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/health/ready":
+			if !ready {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/v1/models":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]string{{"id": "meta/llama-3.1-8b-instruct"}},
+			})
+		case "/v1/metrics":
+			_, _ = w.Write([]byte("# HELP gpu_cache_usage_perc GPU KV-cache usage\n" +
+				"# TYPE gpu_cache_usage_perc gauge\n" +
+				"gpu_cache_usage_perc{model_name=\"meta/llama-3.1-8b-instruct\"} 0.25\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewNvidiaProvider("nim", ProviderConfig{
+		Type:    "nim",
+		BaseURL: server.URL + "/v1",
+		Models:  []string{"meta/llama-3.1-8b-instruct"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "nim", p.GetType())
+
+	_, ok := p.Load()
+	assert.False(t, ok, "load is unknown before the first health check")
+
+	require.NoError(t, p.HealthCheck(context.Background()))
+	assert.True(t, p.IsHealthy())
+	load, ok := p.Load()
+	require.True(t, ok)
+	assert.InDelta(t, 0.25, load, 1e-9)
+
+	ready = false
+	assert.Error(t, p.HealthCheck(context.Background()))
+	assert.False(t, p.IsHealthy())
+}
+
+func TestNvidiaProvider_TritonRepository(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/health/ready":
+		case "/v2/repository/index":
+			assert.Equal(t, http.MethodPost, r.Method)
+			_ = json.NewEncoder(w).Encode([]map[string]string{
+				{"name": "llama", "version": "1", "state": "READY"},
+				{"name": "mistral", "version": "1", "state": "UNAVAILABLE"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	newProvider := func(model string) *NvidiaProvider {
+		p, err := NewNvidiaProvider("triton", ProviderConfig{
+			Type:    "triton",
+			BaseURL: server.URL + "/v1",
+			Models:  []string{model},
+			Extra:   map[string]interface{}{"metrics_url": server.URL + "/metrics"},
+		})
+		require.NoError(t, err)
+		return p
+	}
+
+	assert.NoError(t, newProvider("llama").HealthCheck(context.Background()))
+
+	err := newProvider("mistral").HealthCheck(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mistral is not ready")
+
+	// Metrics are not exposed: the load stays unknown
+	p := newProvider("llama")
+	require.NoError(t, p.HealthCheck(context.Background()))
+	_, ok := p.Load()
+	assert.False(t, ok)
+}
+
+func TestNewNvidiaProvider_Validation(t *testing.T) {
+	_, err := NewNvidiaProvider("nim", ProviderConfig{Type: "nim"})
+	assert.Error(t, err, "base URL is required")
+
+	p, err := NewNvidiaProvider("triton", ProviderConfig{Type: "triton", BaseURL: "http://triton:9000/v1"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://triton:9000", p.serverURL)
+	assert.Equal(t, "http://triton:8002/metrics", p.metricsURL)
+}
+
+func TestParseGPUUtilization(t *testing.T) {
+	metrics := `# HELP nv_gpu_utilization GPU utilization rate [0.0 - 1.0)
+# TYPE nv_gpu_utilization gauge
+nv_gpu_utilization{gpu_uuid="GPU-0"} 0.5
+nv_gpu_utilization{gpu_uuid="GPU-1"} 0.9
+gpu_cache_usage_perc 0.1
+`
+	load, ok := parseGPUUtilization(strings.NewReader(metrics))
+	require.True(t, ok)
+	assert.InDelta(t, 0.7, load, 1e-9)
+
+	load, ok = parseGPUUtilization(strings.NewReader("gpu_cache_usage_perc 0.4\n"))
+	require.True(t, ok)
+	assert.InDelta(t, 0.4, load, 1e-9)
+
+	_, ok = parseGPUUtilization(strings.NewReader("process_cpu_seconds_total 12\n"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultOllamaURL is where a local Ollama server listens
+const defaultOllamaURL = "http://localhost:11434"
+
+// OllamaProvider serves models pulled on an Ollama server. Chat, streaming
+// and embeddings go through Ollama's OpenAI-compatible API; health checks and
+// model listing use its native /api/tags.
+type OllamaProvider struct {
+	*OpenAIProvider
+	serverURL string
+
+	mu     sync.RWMutex
+	pulled []string // Models on the server as of the last listing
+}
+
+// NewOllamaProvider creates a provider for the Ollama server at the base URL
+// (default: http://localhost:11434). The API key is optional, for servers
+// behind an authenticating proxy.
+func NewOllamaProvider(name string, cfg ProviderConfig) (*OllamaProvider, error) {
+	serverURL := strings.TrimSuffix(cfg.BaseURL, "/")
+	serverURL = strings.TrimSuffix(serverURL, "/v1")
+	if serverURL == "" {
+		serverURL = defaultOllamaURL
+	}
+
+	openai, err := NewOpenAIProvider(name, ProviderConfig{
+		APIKey:  cfg.APIKey,
+		BaseURL: serverURL + "/v1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Pulled models are only known once the server is asked
+	openai.BaseProvider = NewBaseProvider(name, "ollama", cfg.Priority, cfg.Models)
+	openai.client.Transport = newCaptureTransport("ollama")
+	if cfg.Timeout > 0 {
+		openai.client.Timeout = cfg.Timeout
+	}
+
+	return &OllamaProvider{
+		OpenAIProvider: openai,
+		serverURL:      serverURL,
+	}, nil
+}
+
+// HealthCheck lists the server's models and fails when a configured model
+// has not been pulled
+func (p *OllamaProvider) HealthCheck(ctx context.Context) error {
+	pulled, err := p.FetchAvailableModels(ctx)
+	if err != nil {
+		p.SetHealthy(false)
+		return err
+	}
+
+	available := make(map[string]bool, len(pulled))
+	for _, model := range pulled {
+		available[model] = true
+	}
+	for _, model := range p.BaseProvider.ListModels() {
+		if model != "*" && !available[model] && !available[OllamaModelName(model)] {
+			p.SetHealthy(false)
+			return fmt.Errorf("model %s is not pulled on the Ollama server", model)
+		}
+	}
+
+	p.SetHealthy(true)
+	return nil
+}
+
+// FetchAvailableModels lists the models pulled on the server from /api/tags.
+// The default ":latest" tag is left out of the names.
+func (p *OllamaProvider) FetchAvailableModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.serverURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Ollama models: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama /api/tags returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse Ollama models: %w", err)
+	}
+
+	models := make([]string, 0, len(result.Models))
+	for _, m := range result.Models {
+		name := m.Name
+		if name == "" {
+			name = m.Model
+		}
+		if name != "" {
+			models = append(models, OllamaModelName(name))
+		}
+	}
+	sort.Strings(models)
+
+	p.mu.Lock()
+	p.pulled = models
+	p.mu.Unlock()
+	return models, nil
+}
+
+// ListModels returns the models pulled on the server as of the last health
+// check, or the configured ones before the server was first asked
+func (p *OllamaProvider) ListModels() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.pulled != nil {
+		return append([]string(nil), p.pulled...)
+	}
+	return p.BaseProvider.ListModels()
+}
+
+// SupportsModel reports whether the model is configured or pulled on the
+// server
+func (p *OllamaProvider) SupportsModel(model string) bool {
+	if p.BaseProvider.SupportsModel(model) {
+		return true
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, pulled := range p.pulled {
+		if pulled == OllamaModelName(model) {
+			return true
+		}
+	}
+	return false
+}
+
+// OllamaModelName returns a model name without the default ":latest" tag,
+// which Ollama resolves either way
+func OllamaModelName(model string) string {
+	return strings.TrimSuffix(model, ":latest")
+}
@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOllamaTestServer(t *testing.T, pulled ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			models := make([]map[string]string, 0, len(pulled))
+			for _, name := range pulled {
+				models = append(models, map[string]string{"name": name, "model": name})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"models": models})
+		case "/v1/chat/completions":
+			var req ChatRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":      "chatcmpl-1",
+				"object":  "chat.completion",
+				"model":   req.Model,
+				"choices": []map[string]interface{}{{"index": 0, "message": map[string]string{"role": "assistant", "content": "hi"}, "finish_reason": "stop"}},
+				"usage":   map[string]int{"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4},
+			})
+		case "/v1/embeddings":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"object": "list",
+				"model":  "nomic-embed-text",
+				"data":   []map[string]interface{}{{"object": "embedding", "index": 0, "embedding": []float64{0.1, 0.2}}},
+				"usage":  map[string]int{"prompt_tokens": 2, "total_tokens": 2},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOllamaProvider_ModelsAndHealth(t *testing.T) {
+	server := newOllamaTestServer(t, "llama3.2:latest", "nomic-embed-text:latest", "qwen2.5:7b")
+
+	p, err := NewOllamaProvider("ollama", ProviderConfig{Type: "ollama", BaseURL: server.URL + "/v1/", Models: []string{"llama3.2"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ollama", p.GetType())
+	assert.Equal(t, []string{"llama3.2"}, p.ListModels(), "configured models until the server is listed")
+	assert.False(t, p.SupportsModel("qwen2.5:7b"))
+
+	require.NoError(t, p.HealthCheck(context.Background()))
+	assert.True(t, p.IsHealthy())
+	assert.Equal(t, []string{"llama3.2", "nomic-embed-text", "qwen2.5:7b"}, p.ListModels())
+	assert.True(t, p.SupportsModel("qwen2.5:7b"))
+	assert.True(t, p.SupportsModel("llama3.2:latest"))
+
+	missing, err := NewOllamaProvider("ollama", ProviderConfig{Type: "ollama", BaseURL: server.URL, Models: []string{"mistral"}})
+	require.NoError(t, err)
+	err = missing.HealthCheck(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not pulled")
+	assert.False(t, missing.IsHealthy())
+}
+
+func TestOllamaProvider_OpenAICompatibleAPI(t *testing.T) {
+	server := newOllamaTestServer(t, "llama3.2:latest")
+
+	p, err := NewOllamaProvider("ollama", ProviderConfig{Type: "ollama", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := p.ChatCompletion(context.Background(), &ChatRequest{
+		Model:    "llama3.2",
+		Messages: []Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "llama3.2", resp.Model)
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+
+	embeddings, err := p.Embeddings(context.Background(), &EmbeddingsRequest{Model: "nomic-embed-text", Input: "hello"})
+	require.NoError(t, err)
+	require.Len(t, embeddings.Data, 1)
+	assert.Len(t, embeddings.Data[0].Embedding, 2)
+}
+
+func TestNewOllamaProvider_DefaultURL(t *testing.T) {
+	p, err := NewOllamaProvider("ollama", ProviderConfig{Type: "ollama"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultOllamaURL, p.serverURL)
+	assert.Equal(t, "llama3.2", OllamaModelName("llama3.2:latest"))
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
@@ -42,7 +41,8 @@ func NewOpenAIProvider(name string, cfg ProviderConfig) (*OpenAIProvider, error)
 		baseURL:      baseURL,
 		orgID:        cfg.OrgID,
 		client: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: newCaptureTransport("openai"),
 		},
 	}, nil
 }
@@ -181,7 +181,9 @@ func (p *OpenAIProvider) ChatCompletionStream(ctx context.Context, request *Chat
 		}
 
 		// Parse SSE stream
-		p.parseStreamResponse(resp.Body, streamChan)
+		if err := NewStreamTranscoder(StreamFormatOpenAI, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			sendStreamError(err.Error())
+		}
 	}()
 
 	return streamChan, nil
@@ -249,59 +251,10 @@ func (p *OpenAIProvider) Embeddings(ctx context.Context, request *EmbeddingsRequ
 }
 
 func (p *OpenAIProvider) AudioTranscription(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
-	// Create multipart form
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	// Add file field
-	fileWriter, err := writer.CreateFormFile("file", "audio.wav")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
-	}
-	if _, err := io.Copy(fileWriter, request.File); err != nil {
-		return nil, fmt.Errorf("failed to copy file: %w", err)
-	}
-
-	// Add other fields
-	if err := writer.WriteField("model", request.Model); err != nil {
-		return nil, fmt.Errorf("failed to write model field: %w", err)
-	}
-	if request.Language != "" {
-		if err := writer.WriteField("language", request.Language); err != nil {
-			return nil, fmt.Errorf("failed to write language field: %w", err)
-		}
-	}
-	if request.Prompt != "" {
-		if err := writer.WriteField("prompt", request.Prompt); err != nil {
-			return nil, fmt.Errorf("failed to write prompt field: %w", err)
-		}
-	}
-	if request.ResponseFormat != "" {
-		if err := writer.WriteField("response_format", request.ResponseFormat); err != nil {
-			return nil, fmt.Errorf("failed to write response_format field: %w", err)
-		}
-	}
-	if request.Temperature != nil {
-		if err := writer.WriteField("temperature", fmt.Sprintf("%.2f", *request.Temperature)); err != nil {
-			return nil, fmt.Errorf("failed to write temperature field: %w", err)
-		}
-	}
-
-	if err := writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
-	}
-
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/audio/transcriptions", &buf)
+	req, err := p.newTranscriptionRequest(ctx, request, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
-	if p.orgID != "" && p.orgID != "0" && p.orgID != "null" {
-		req.Header.Set("OpenAI-Organization", p.orgID)
+		return nil, err
 	}
 
 	// Make the request
@@ -335,6 +288,104 @@ func (p *OpenAIProvider) AudioTranscription(ctx context.Context, request *Transc
 	return &transcResp, nil
 }
 
+// AudioTranscriptionStream streams partial transcripts as server-sent events.
+// OpenAI streams for the gpt-4o transcription models; whisper-1 ignores the
+// stream flag and answers with a single JSON body.
+func (p *OpenAIProvider) AudioTranscriptionStream(ctx context.Context, request *TranscriptionRequest) (<-chan TranscriptionStreamEvent, error) {
+	req, err := p.newTranscriptionRequest(ctx, request, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		var errResp ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err != nil {
+			return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("OpenAI API error: %s", errResp.Error.Message)
+	}
+
+	eventChan := make(chan TranscriptionStreamEvent, 100)
+	go func() {
+		defer close(eventChan)
+		defer func() { _ = resp.Body.Close() }()
+
+		// Models without streaming support answer with the full transcript
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			var transcResp TranscriptionResponse
+			if err := json.NewDecoder(resp.Body).Decode(&transcResp); err != nil {
+				eventChan <- TranscriptionStreamEvent{Type: "error", Error: &APIError{Message: "failed to parse response: " + err.Error(), Type: "api_error"}}
+				return
+			}
+			usage := transcResp.Usage
+			if usage == nil && transcResp.Duration > 0 {
+				usage = &TranscriptionUsage{Type: "duration", Seconds: transcResp.Duration}
+			}
+			eventChan <- TranscriptionStreamEvent{Type: "transcript.text.done", Text: transcResp.Text, Usage: usage}
+			return
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			data := strings.TrimPrefix(line, "data: ")
+			if data == "[DONE]" {
+				return
+			}
+
+			var event TranscriptionStreamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				continue
+			}
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			eventChan <- TranscriptionStreamEvent{Type: "error", Error: &APIError{Message: "stream read failed: " + err.Error(), Type: "api_error"}}
+		}
+	}()
+
+	return eventChan, nil
+}
+
+// newTranscriptionRequest builds the multipart transcription request
+func (p *OpenAIProvider) newTranscriptionRequest(ctx context.Context, request *TranscriptionRequest, stream bool) (*http.Request, error) {
+	body, contentType := transcriptionBody(request, stream)
+
+	// Create HTTP request
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/audio/transcriptions", body)
+	if err != nil {
+		_ = body.Close()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	if p.orgID != "" && p.orgID != "0" && p.orgID != "null" {
+		req.Header.Set("OpenAI-Organization", p.orgID)
+	}
+
+	return req, nil
+}
+
 func (p *OpenAIProvider) AudioSpeech(ctx context.Context, request *SpeechRequest) ([]byte, error) {
 	// Prepare the request body
 	reqBody, err := json.Marshal(request)
@@ -432,52 +483,6 @@ func (p *OpenAIProvider) ImageGeneration(ctx context.Context, request *ImageRequ
 	return &imgResp, nil
 }
 
-// parseStreamResponse parses the SSE stream response from OpenAI
-func (p *OpenAIProvider) parseStreamResponse(body io.Reader, streamChan chan<- StreamResponse) {
-	bufReader := bufio.NewReader(body)
-
-	for {
-		line, err := bufReader.ReadString('\n')
-		if err != nil {
-			if err != io.EOF {
-				// Log error but continue
-				log.Printf("Error reading OpenAI response: %v", err)
-			}
-			return
-		}
-
-		line = strings.TrimSpace(line)
-
-		// Skip empty lines
-		if line == "" {
-			continue
-		}
-
-		// Check for SSE data prefix
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-
-		// Extract data
-		data := strings.TrimPrefix(line, "data: ")
-
-		// Check for end of stream
-		if data == "[DONE]" {
-			return
-		}
-
-		// Parse JSON data
-		var streamResp StreamResponse
-		if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-			// Skip malformed data
-			continue
-		}
-
-		// Send to channel
-		streamChan <- streamResp
-	}
-}
-
 // useMaxCompletionTokens returns true for models that require max_completion_tokens
 // instead of the deprecated max_tokens parameter.
 func useMaxCompletionTokens(model string) bool {
@@ -490,9 +495,49 @@ func useMaxCompletionTokens(model string) bool {
 	return false
 }
 
+// isReasoningModel returns true for OpenAI reasoning models (o-series, gpt-5),
+// which only accept default sampling parameters.
+func isReasoningModel(model string) bool {
+	if strings.HasPrefix(model, "gpt-5-chat") {
+		return false
+	}
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// adaptReasoningRequest returns a copy of the request without the sampling
+// parameters reasoning models reject, so clients written for chat models keep
+// working. Requests for other models are returned unchanged.
+func adaptReasoningRequest(request *ChatRequest) *ChatRequest {
+	return adaptReasoningRequestFor(request, request.Model)
+}
+
+// adaptReasoningRequestFor is adaptReasoningRequest for providers whose
+// request model is an alias, such as an Azure deployment name, with baseModel
+// being the model behind it
+func adaptReasoningRequestFor(request *ChatRequest, baseModel string) *ChatRequest {
+	if !isReasoningModel(baseModel) {
+		return request
+	}
+
+	adapted := *request
+	adapted.Temperature = nil
+	adapted.TopP = nil
+	adapted.PresencePenalty = nil
+	adapted.FrequencyPenalty = nil
+	adapted.LogitBias = nil
+	return &adapted
+}
+
 // marshalChatRequest marshals a ChatRequest, converting max_tokens to
-// max_completion_tokens for models that require it.
+// max_completion_tokens for models that require it and dropping parameters
+// reasoning models reject.
 func marshalChatRequest(request *ChatRequest) ([]byte, error) {
+	request = adaptReasoningRequest(request)
 	if request.MaxTokens != nil && useMaxCompletionTokens(request.Model) {
 		// Build a map so we can swap the field name without changing the struct.
 		data, err := json.Marshal(request)
@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReasoningModel(t *testing.T) {
+	for model, want := range map[string]bool{
+		"o1-mini":           true,
+		"o3":                true,
+		"o4-mini":           true,
+		"gpt-5":             true,
+		"gpt-5-chat-latest": false,
+		"gpt-4o":            false,
+		"gpt-4.1":           false,
+	} {
+		assert.Equal(t, want, isReasoningModel(model), model)
+	}
+}
+
+func TestMarshalChatRequest_ReasoningModel(t *testing.T) {
+	temperature := float32(0.7)
+	maxTokens := 256
+	effort := "high"
+	request := &ChatRequest{
+		Model:           "o3-mini",
+		Messages:        []Message{{Role: "user", Content: "hi"}},
+		Temperature:     &temperature,
+		TopP:            &temperature,
+		MaxTokens:       &maxTokens,
+		ReasoningEffort: &effort,
+	}
+
+	data, err := marshalChatRequest(request)
+	require.NoError(t, err)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &body))
+	assert.NotContains(t, body, "temperature")
+	assert.NotContains(t, body, "top_p")
+	assert.NotContains(t, body, "max_tokens")
+	assert.Equal(t, float64(256), body["max_completion_tokens"])
+	assert.Equal(t, "high", body["reasoning_effort"])
+
+	// The caller's request is left untouched
+	assert.NotNil(t, request.Temperature)
+}
+
+func TestMarshalChatRequest_ChatModelKeepsSampling(t *testing.T) {
+	temperature := float32(0.7)
+	data, err := marshalChatRequest(&ChatRequest{Model: "gpt-4o", Temperature: &temperature})
+	require.NoError(t, err)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &body))
+	assert.Contains(t, body, "temperature")
+}
+
+func TestUsageReasoningTokens(t *testing.T) {
+	var usage Usage
+	require.NoError(t, json.Unmarshal([]byte(`{"prompt_tokens":10,"completion_tokens":50,"total_tokens":60,"completion_tokens_details":{"reasoning_tokens":32}}`), &usage))
+	assert.Equal(t, 32, usage.ReasoningTokens())
+	assert.Equal(t, 0, Usage{}.ReasoningTokens())
+}
+
+func TestAzureTransformRequest_BaseModel(t *testing.T) {
+	temperature := float32(0.7)
+	maxTokens := 256
+	request := &ChatRequest{Model: "prod-reasoner", Temperature: &temperature, MaxTokens: &maxTokens}
+
+	provider, err := NewAzureProvider("azure", ProviderConfig{
+		BaseURL: "https://example.openai.azure.com",
+		Extra: map[string]interface{}{
+			"deployments": map[string]interface{}{"prod-reasoner": "prod-reasoner"},
+			"base_models": map[string]interface{}{"prod-reasoner": "o3-mini"},
+		},
+	})
+	require.NoError(t, err)
+
+	body := provider.transformRequest(request)
+	assert.NotContains(t, body, "temperature")
+	assert.NotContains(t, body, "max_tokens")
+	assert.Equal(t, 256, body["max_completion_tokens"])
+
+	// Without a base model the deployment name is all there is to go on
+	plain, err := NewAzureProvider("azure", ProviderConfig{BaseURL: "https://example.openai.azure.com"})
+	require.NoError(t, err)
+	assert.Contains(t, plain.transformRequest(request), "temperature")
+}
@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collectTranscriptionEvents(t *testing.T, handler http.HandlerFunc) []TranscriptionStreamEvent {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider("openai", ProviderConfig{APIKey: "test", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	events, err := provider.AudioTranscriptionStream(context.Background(), &TranscriptionRequest{
+		File:  strings.NewReader("audio"),
+		Model: "gpt-4o-transcribe",
+	})
+	require.NoError(t, err)
+
+	var collected []TranscriptionStreamEvent
+	for event := range events {
+		collected = append(collected, event)
+	}
+	return collected
+}
+
+func TestOpenAIAudioTranscriptionStream(t *testing.T) {
+	events := collectTranscriptionEvents(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "true", r.FormValue("stream"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: {\"type\":\"transcript.text.delta\",\"delta\":\"Hello\"}\n\n")
+		_, _ = fmt.Fprint(w, "data: {\"type\":\"transcript.text.delta\",\"delta\":\" world\"}\n\n")
+		_, _ = fmt.Fprint(w, "data: {\"type\":\"transcript.text.done\",\"text\":\"Hello world\",\"usage\":{\"type\":\"tokens\",\"input_tokens\":12,\"output_tokens\":3,\"total_tokens\":15}}\n\n")
+	})
+
+	require.Len(t, events, 3)
+	assert.Equal(t, "Hello", events[0].Delta)
+	assert.Equal(t, "transcript.text.done", events[2].Type)
+	assert.Equal(t, "Hello world", events[2].Text)
+	require.NotNil(t, events[2].Usage)
+	assert.Equal(t, 12, events[2].Usage.InputTokens)
+}
+
+func TestOpenAIAudioTranscriptionStream_NonStreamingModel(t *testing.T) {
+	events := collectTranscriptionEvents(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"text":"Hello world","usage":{"type":"duration","seconds":7.5}}`)
+	})
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "transcript.text.done", events[0].Type)
+	assert.Equal(t, "Hello world", events[0].Text)
+	require.NotNil(t, events[0].Usage)
+	assert.Equal(t, 7.5, events[0].Usage.Seconds)
+}
+
+func TestOpenAIAudioTranscription_StreamsForm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, "en", r.FormValue("language"))
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		defer func() { _ = file.Close() }()
+		assert.Equal(t, "speech.mp3", header.Filename, "the client's file name tells the provider the format")
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"text":"Hello world"}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider("openai", ProviderConfig{APIKey: "test", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	response, err := provider.AudioTranscription(context.Background(), &TranscriptionRequest{
+		File:     strings.NewReader("audio"),
+		Filename: "speech.mp3",
+		Model:    "whisper-1",
+		Language: "en",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", response.Text)
+}
@@ -1,14 +1,13 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -116,7 +115,8 @@ func NewOpenRouterProvider(name string, cfg ProviderConfig) (*OpenRouterProvider
 	}
 
 	client := &http.Client{
-		Timeout: 120 * time.Second, // OpenRouter can be slower due to routing
+		Timeout:   120 * time.Second, // OpenRouter can be slower due to routing
+		Transport: newCaptureTransport("openrouter"),
 	}
 
 	return &OpenRouterProvider{
@@ -248,7 +248,9 @@ func (p *OpenRouterProvider) ChatCompletionStream(ctx context.Context, request *
 		}
 
 		// Parse SSE stream
-		p.parseStreamResponse(resp.Body, streamChan)
+		if err := NewStreamTranscoder(StreamFormatOpenAI, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			log.Printf("OpenRouter stream failed: %v", err)
+		}
 	}()
 
 	return streamChan, nil
@@ -271,61 +273,6 @@ func (p *OpenRouterProvider) setHeaders(req *http.Request) {
 	req.Header.Set("User-Agent", p.appName+"/1.0")
 }
 
-// parseStreamResponse parses the SSE stream response from OpenRouter
-func (p *OpenRouterProvider) parseStreamResponse(body io.Reader, streamChan chan<- StreamResponse) {
-	bufReader := bufio.NewReader(body)
-	chunkCount := 0
-
-	for {
-		line, err := bufReader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				// Normal end of stream
-				return
-			}
-			// Log error but don't send to client
-			fmt.Printf("OpenRouter stream read error after %d chunks: %v\n", chunkCount, err)
-			return
-		}
-
-		line = strings.TrimSpace(line)
-
-		// Skip empty lines
-		if line == "" {
-			continue
-		}
-
-		// Skip SSE comment lines (starting with :)
-		if strings.HasPrefix(line, ":") {
-			continue
-		}
-
-		// Only process data lines
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-
-		// Extract data
-		data := strings.TrimPrefix(line, "data: ")
-
-		// Check for end of stream
-		if data == "[DONE]" {
-			return
-		}
-
-		// Parse JSON data
-		var streamResp StreamResponse
-		if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
-			fmt.Printf("OpenRouter parse error for data '%s': %v\n", data, err)
-			continue
-		}
-
-		chunkCount++
-		// Send valid response to channel
-		streamChan <- streamResp
-	}
-}
-
 // Completion implements the Provider interface (legacy)
 func (p *OpenRouterProvider) Completion(ctx context.Context, request *CompletionRequest) (*CompletionResponse, error) {
 	// Prepare the request body
@@ -407,7 +354,9 @@ func (p *OpenRouterProvider) CompletionStream(ctx context.Context, request *Comp
 			return
 		}
 
-		p.parseStreamResponse(resp.Body, streamChan)
+		if err := NewStreamTranscoder(StreamFormatOpenAI, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			log.Printf("OpenRouter stream failed: %v", err)
+		}
 	}()
 
 	return streamChan, nil
@@ -0,0 +1,205 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const perplexityDefaultBaseURL = "https://api.perplexity.ai"
+
+// PerplexityProvider serves Perplexity's Sonar models. The chat API is
+// OpenAI compatible; answers are grounded in a web search and carry the
+// sources they cite in citations and search_results, which are passed
+// through to the client in responses and stream chunks.
+type PerplexityProvider struct {
+	*OpenAIProvider
+}
+
+// NewPerplexityProvider creates a Perplexity provider. BaseURL defaults to
+// https://api.perplexity.ai.
+func NewPerplexityProvider(name string, cfg ProviderConfig) (*PerplexityProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("Perplexity API key is required")
+	}
+
+	baseURL := strings.TrimSuffix(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = perplexityDefaultBaseURL
+	}
+
+	models := cfg.Models
+	if len(models) == 0 {
+		models = []string{"sonar", "sonar-pro", "sonar-reasoning", "sonar-reasoning-pro", "sonar-deep-research"}
+	}
+
+	openai, err := NewOpenAIProvider(name, ProviderConfig{
+		APIKey:  cfg.APIKey,
+		BaseURL: baseURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	openai.BaseProvider = NewBaseProvider(name, "perplexity", cfg.Priority, models)
+	openai.client.Transport = newCaptureTransport("perplexity")
+	if cfg.Timeout > 0 {
+		openai.client.Timeout = cfg.Timeout
+	}
+
+	return &PerplexityProvider{OpenAIProvider: openai}, nil
+}
+
+// newRequest creates an authenticated request to an endpoint path
+func (p *PerplexityProvider) newRequest(ctx context.Context, path string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	return req, nil
+}
+
+func (p *PerplexityProvider) ChatCompletion(ctx context.Context, request *ChatRequest) (*ChatResponse, error) {
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "/chat/completions", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, perplexityAPIError(resp.StatusCode, body)
+	}
+
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &chatResp, nil
+}
+
+func (p *PerplexityProvider) ChatCompletionStream(ctx context.Context, request *ChatRequest) (<-chan StreamResponse, error) {
+	streamRequest := *request
+	streamRequest.Stream = true
+	reqBody, err := json.Marshal(&streamRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "/chat/completions", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, perplexityAPIError(resp.StatusCode, body)
+	}
+
+	// Chunks are passed through as they are, so the citations and search
+	// results Perplexity repeats on every chunk reach the client
+	streamChan := make(chan StreamResponse, 100)
+	go func() {
+		defer close(streamChan)
+		defer func() { _ = resp.Body.Close() }()
+		if err := NewStreamTranscoder(StreamFormatOpenAI, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			log.Printf("Perplexity stream failed: %v", err)
+		}
+	}()
+
+	return streamChan, nil
+}
+
+func (p *PerplexityProvider) Completion(ctx context.Context, request *CompletionRequest) (*CompletionResponse, error) {
+	return nil, fmt.Errorf("text completions not supported by perplexity provider")
+}
+
+func (p *PerplexityProvider) CompletionStream(ctx context.Context, request *CompletionRequest) (<-chan StreamResponse, error) {
+	return nil, fmt.Errorf("text completions not supported by perplexity provider")
+}
+
+func (p *PerplexityProvider) Embeddings(ctx context.Context, request *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	return nil, fmt.Errorf("embeddings not supported by perplexity provider")
+}
+
+func (p *PerplexityProvider) AudioTranscription(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by perplexity provider")
+}
+
+func (p *PerplexityProvider) AudioTranscriptionStream(ctx context.Context, request *TranscriptionRequest) (<-chan TranscriptionStreamEvent, error) {
+	return nil, fmt.Errorf("audio transcription not supported by perplexity provider")
+}
+
+func (p *PerplexityProvider) AudioSpeech(ctx context.Context, request *SpeechRequest) ([]byte, error) {
+	return nil, fmt.Errorf("audio speech not supported by perplexity provider")
+}
+
+func (p *PerplexityProvider) ImageGeneration(ctx context.Context, request *ImageRequest) (*ImageResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by perplexity provider")
+}
+
+// SupportsRealtime reports false: Perplexity has no realtime API
+func (p *PerplexityProvider) SupportsRealtime() bool {
+	return false
+}
+
+// HealthCheck checks the key without running a billed search: Perplexity
+// has no models endpoint, so an empty chat request is sent, which an
+// accepted key gets a validation error for
+func (p *PerplexityProvider) HealthCheck(ctx context.Context) error {
+	req, err := p.newRequest(ctx, "/chat/completions", []byte(`{}`))
+	if err != nil {
+		p.SetHealthy(false)
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode >= 500 {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+	}
+
+	p.SetHealthy(true)
+	return nil
+}
+
+// perplexityAPIError builds the error of a failed request. Perplexity
+// reports errors in OpenAI's format, or as an HTML page for rejected keys.
+func perplexityAPIError(status int, body []byte) error {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Message == "" {
+		return fmt.Errorf("request failed with status %d: %s", status, string(body))
+	}
+	return fmt.Errorf("Perplexity API error: %s", errResp.Error.Message)
+}
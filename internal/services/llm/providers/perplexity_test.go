@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPerplexityProvider(t *testing.T, handler http.HandlerFunc) *PerplexityProvider {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	provider, err := NewPerplexityProvider("perplexity-test", ProviderConfig{
+		Type:    "perplexity",
+		APIKey:  "pplx-test",
+		BaseURL: server.URL,
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestNewPerplexityProvider(t *testing.T) {
+	_, err := NewPerplexityProvider("pplx", ProviderConfig{})
+	assert.Error(t, err, "API key is required")
+
+	provider, err := NewPerplexityProvider("pplx", ProviderConfig{APIKey: "pplx"})
+	require.NoError(t, err)
+	assert.Equal(t, "perplexity", provider.GetType())
+	assert.Equal(t, perplexityDefaultBaseURL, provider.baseURL)
+	assert.Contains(t, provider.ListModels(), "sonar-pro")
+	assert.False(t, provider.SupportsRealtime())
+}
+
+func TestPerplexityProvider_ChatCompletionCitations(t *testing.T) {
+	provider := newTestPerplexityProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer pplx-test", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{
+			"id": "pplx-1",
+			"object": "chat.completion",
+			"created": 1760000000,
+			"model": "sonar",
+			"citations": ["https://go.dev/doc", "https://go.dev/blog"],
+			"search_results": [{"title": "Documentation", "url": "https://go.dev/doc", "date": "2025-08-13"}],
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Go is a language [1]."}}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 7, "total_tokens": 12}
+		}`))
+	})
+
+	resp, err := provider.ChatCompletion(context.Background(), &ChatRequest{
+		Model:    "sonar",
+		Messages: []Message{{Role: "user", Content: "What is Go?"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://go.dev/doc", "https://go.dev/blog"}, resp.Citations)
+	require.Len(t, resp.SearchResults, 1)
+	assert.Equal(t, SearchResult{Title: "Documentation", URL: "https://go.dev/doc", Date: "2025-08-13"}, resp.SearchResults[0])
+
+	encoded, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"citations":["https://go.dev/doc","https://go.dev/blog"]`, "citations are passed through to clients")
+	assert.Contains(t, string(encoded), `"search_results":[{"title":"Documentation"`)
+}
+
+func TestPerplexityProvider_ChatCompletionError(t *testing.T) {
+	provider := newTestPerplexityProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"Invalid model 'gpt-4o'","type":"invalid_model","code":400}}`))
+	})
+
+	_, err := provider.ChatCompletion(context.Background(), &ChatRequest{Model: "gpt-4o"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid model")
+
+	assert.NoError(t, provider.HealthCheck(context.Background()), "validation errors mean the key was accepted")
+	assert.True(t, provider.IsHealthy())
+}
+
+func TestPerplexityProvider_ChatCompletionStream(t *testing.T) {
+	provider := newTestPerplexityProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, true, body["stream"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(
+			`data: {"id":"pplx-2","object":"chat.completion.chunk","model":"sonar","citations":["https://go.dev"],"choices":[{"index":0,"delta":{"role":"assistant","content":"Go"}}]}` + "\n\n" +
+				`data: {"id":"pplx-2","object":"chat.completion.chunk","model":"sonar","citations":["https://go.dev"],"search_results":[{"title":"The Go Programming Language","url":"https://go.dev"}],"choices":[{"index":0,"delta":{"content":"."},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}` + "\n\n" +
+				"data: [DONE]\n\n"))
+	})
+
+	stream, err := provider.ChatCompletionStream(context.Background(), &ChatRequest{
+		Model:    "sonar",
+		Messages: []Message{{Role: "user", Content: "What is Go?"}},
+	})
+	require.NoError(t, err)
+
+	var chunks []StreamResponse
+	for chunk := range stream {
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 2)
+	assert.Equal(t, []string{"https://go.dev"}, chunks[0].Citations)
+	require.Len(t, chunks[1].SearchResults, 1)
+	assert.Equal(t, "https://go.dev", chunks[1].SearchResults[0].URL)
+	require.NotNil(t, chunks[1].Usage)
+	assert.Equal(t, 5, chunks[1].Usage.TotalTokens)
+}
+
+func TestPerplexityProvider_HealthCheckRejectedKey(t *testing.T) {
+	provider := newTestPerplexityProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`<html><title>401 Authorization Required</title></html>`))
+	})
+
+	assert.Error(t, provider.HealthCheck(context.Background()))
+	assert.False(t, provider.IsHealthy())
+}
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"io"
 	"time"
 )
@@ -57,6 +58,20 @@ type ChatRequest struct {
 	Tools            []Tool          `json:"tools,omitempty"`
 	ToolChoice       interface{}     `json:"tool_choice,omitempty"`
 	ReasoningEffort  *string         `json:"reasoning_effort,omitempty"`
+
+	// MaxCost is a gateway-only worst-case cost ceiling in USD. It is enforced
+	// before the request is routed and never forwarded to the provider.
+	MaxCost *float64 `json:"max_cost,omitempty"`
+
+	// CachedContent references a context cache created through /v1/caches.
+	// The gateway replaces it with the provider's cache resource name, or
+	// clears it after prepending the cached messages.
+	CachedContent string `json:"cached_content,omitempty"`
+
+	// CachedPrefix is the number of leading messages that come from a
+	// gateway-managed context cache. Providers with prompt caching mark the
+	// end of the prefix as a cache breakpoint.
+	CachedPrefix int `json:"-"`
 }
 
 type Message struct {
@@ -104,13 +119,14 @@ type Function struct {
 }
 
 type ToolCall struct {
-	ID       string       `json:"id"`
-	Type     string       `json:"type"`
+	Index    *int         `json:"index,omitempty"` // Position of the call in stream deltas
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
 	Function FunctionCall `json:"function"`
 }
 
 type FunctionCall struct {
-	Name      string `json:"name"`
+	Name      string `json:"name,omitempty"`
 	Arguments string `json:"arguments"`
 }
 
@@ -122,6 +138,43 @@ type ChatResponse struct {
 	Choices           []Choice `json:"choices"`
 	Usage             Usage    `json:"usage,omitempty"`
 	SystemFingerprint string   `json:"system_fingerprint,omitempty"`
+
+	// Provenance is a gateway extension field, only set when provenance is enabled
+	Provenance *Provenance `json:"pllm_provenance,omitempty"`
+
+	// GuardrailFlags is a gateway extension field set by annotating guardrails
+	GuardrailFlags []GuardrailFlag `json:"pllm_guardrail_flags,omitempty"`
+
+	// Citations and SearchResults are the sources of search-augmented
+	// answers (Perplexity), passed through to the client
+	Citations     []string       `json:"citations,omitempty"`
+	SearchResults []SearchResult `json:"search_results,omitempty"`
+}
+
+// SearchResult is a web source a search-augmented answer is based on
+type SearchResult struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Date        string `json:"date,omitempty"`
+	LastUpdated string `json:"last_updated,omitempty"`
+	Snippet     string `json:"snippet,omitempty"`
+}
+
+// GuardrailFlag records a guardrail finding on a response that was let through
+type GuardrailFlag struct {
+	Guardrail   string             `json:"guardrail"`
+	ChoiceIndex int                `json:"choice_index"`
+	Categories  []string           `json:"categories"`
+	Scores      map[string]float64 `json:"scores,omitempty"`
+}
+
+// Provenance describes which model produced a response and how to verify it
+type Provenance struct {
+	Model       string `json:"model"`
+	Provider    string `json:"provider,omitempty"`
+	RequestID   string `json:"request_id"`
+	Timestamp   int64  `json:"timestamp"`
+	ContentHash string `json:"content_hash"`
 }
 
 type Choice struct {
@@ -133,17 +186,74 @@ type Choice struct {
 }
 
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails breaks down prompt tokens. Cached tokens were read from
+// the provider's prompt cache and are billed at the cache read rate.
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CompletionTokensDetails breaks down completion tokens. Reasoning tokens are
+// billed as completion tokens but never returned as content.
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// ReasoningTokens returns the number of completion tokens spent on reasoning
+func (u Usage) ReasoningTokens() int {
+	if u.CompletionTokensDetails == nil {
+		return 0
+	}
+	return u.CompletionTokensDetails.ReasoningTokens
+}
+
+// CachedTokens returns the number of prompt tokens read from the prompt cache
+func (u Usage) CachedTokens() int {
+	if u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.CachedTokens
+}
+
+// CompareRequest sends the same chat request to several models
+type CompareRequest struct {
+	ChatRequest
+	Models []string `json:"models"`
+}
+
+// CompareResponse holds one result per requested model, in request order
+type CompareResponse struct {
+	Object    string          `json:"object"`
+	Created   int64           `json:"created"`
+	Results   []CompareResult `json:"results"`
+	TotalCost float64         `json:"total_cost"`
+}
+
+// CompareResult is the outcome of a single model in a comparison
+type CompareResult struct {
+	Model     string        `json:"model"`
+	LatencyMs int64         `json:"latency_ms"`
+	Cost      *float64      `json:"cost,omitempty"` // Unset when the model has no pricing
+	Response  *ChatResponse `json:"response,omitempty"`
+	Error     *APIError     `json:"error,omitempty"`
 }
 
 type StreamResponse struct {
-	ID      string         `json:"id"`
-	Object  string         `json:"object"`
-	Created int64          `json:"created"`
-	Model   string         `json:"model"`
-	Choices []StreamChoice `json:"choices"`
+	ID            string         `json:"id"`
+	Object        string         `json:"object"`
+	Created       int64          `json:"created"`
+	Model         string         `json:"model"`
+	Choices       []StreamChoice `json:"choices"`
+	Usage         *Usage         `json:"usage,omitempty"` // Token usage, on the last chunk when the provider reports it
+	Provenance    *Provenance    `json:"pllm_provenance,omitempty"`
+	Citations     []string       `json:"citations,omitempty"`
+	SearchResults []SearchResult `json:"search_results,omitempty"`
 }
 
 type StreamChoice struct {
@@ -192,6 +302,7 @@ type EmbeddingsRequest struct {
 	Input          interface{} `json:"input"`
 	User           string      `json:"user,omitempty"`
 	EncodingFormat string      `json:"encoding_format,omitempty"`
+	Dimensions     *int        `json:"dimensions,omitempty"` // Reduced at the gateway for providers without native support
 }
 
 type EmbeddingsResponse struct {
@@ -207,6 +318,42 @@ type Embedding struct {
 	Embedding []float32 `json:"embedding"`
 }
 
+// Rerank types
+
+type RerankRequest struct {
+	Model           string   `json:"model"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            *int     `json:"top_n,omitempty"`
+	MaxTokensPerDoc *int     `json:"max_tokens_per_doc,omitempty"`
+	ReturnDocuments bool     `json:"return_documents,omitempty"` // Echo each document in its result, done at the gateway
+}
+
+type RerankResponse struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Model   string         `json:"model"`
+	Results []RerankResult `json:"results"`
+	Usage   RerankUsage    `json:"usage"`
+}
+
+type RerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+	Document       *string `json:"document,omitempty"`
+}
+
+type RerankUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	SearchUnits  int `json:"search_units,omitempty"` // Billed rerank searches
+}
+
+// Reranker is implemented by providers that order documents by their
+// relevance to a query (e.g. Cohere Rerank)
+type Reranker interface {
+	Rerank(ctx context.Context, request *RerankRequest) (*RerankResponse, error)
+}
+
 // Image generation types
 
 type ImageRequest struct {
@@ -235,15 +382,83 @@ type ImageData struct {
 
 type TranscriptionRequest struct {
 	File           io.Reader `json:"file"`
+	Filename       string    `json:"-"` // Name of the uploaded file, whose extension tells providers the format
 	Model          string    `json:"model"`
 	Language       string    `json:"language,omitempty"`
 	Prompt         string    `json:"prompt,omitempty"`
 	ResponseFormat string    `json:"response_format,omitempty"`
 	Temperature    *float32  `json:"temperature,omitempty"`
+	Stream         bool      `json:"stream,omitempty"`
 }
 
 type TranscriptionResponse struct {
-	Text string `json:"text"`
+	Text     string              `json:"text"`
+	Language string              `json:"language,omitempty"`
+	Duration float64             `json:"duration,omitempty"` // Seconds, returned with verbose_json
+	Usage    *TranscriptionUsage `json:"usage,omitempty"`
+}
+
+// TranscriptionUsage is the usage reported for a transcription. Type is
+// "duration" for models billed per second of audio and "tokens" otherwise.
+type TranscriptionUsage struct {
+	Type         string  `json:"type"`
+	Seconds      float64 `json:"seconds,omitempty"`
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	TotalTokens  int     `json:"total_tokens,omitempty"`
+}
+
+// AudioSeconds returns the transcribed audio duration, if the provider reported it
+func (r *TranscriptionResponse) AudioSeconds() float64 {
+	if r.Usage != nil && r.Usage.Seconds > 0 {
+		return r.Usage.Seconds
+	}
+	return r.Duration
+}
+
+// TranscriptionStreamEvent is a server-sent event of a streaming transcription:
+// "transcript.text.delta" carries a partial transcript in Delta, the final
+// "transcript.text.done" carries the full Text and the usage, and "error"
+// ends a stream that failed midway.
+type TranscriptionStreamEvent struct {
+	Type  string              `json:"type"`
+	Delta string              `json:"delta,omitempty"`
+	Text  string              `json:"text,omitempty"`
+	Usage *TranscriptionUsage `json:"usage,omitempty"`
+	Error *APIError           `json:"error,omitempty"` // Set on "error" events
+}
+
+// TranscriptionStreamer is implemented by providers that can stream partial
+// transcripts while the audio is being transcribed
+type TranscriptionStreamer interface {
+	AudioTranscriptionStream(ctx context.Context, request *TranscriptionRequest) (<-chan TranscriptionStreamEvent, error)
+}
+
+// ErrContextCachingUnsupported is returned by a ContextCacher that cannot
+// cache context for the requested model
+var ErrContextCachingUnsupported = errors.New("context caching not supported for this model")
+
+// CachedContentRequest creates a provider-side context cache
+type CachedContentRequest struct {
+	Model       string
+	DisplayName string
+	Messages    []Message
+	TTL         time.Duration
+}
+
+// CachedContent is a provider-side context cache
+type CachedContent struct {
+	Name       string // Provider resource name, passed back as ChatRequest.CachedContent
+	TokenCount int
+	ExpiresAt  time.Time
+}
+
+// ContextCacher is implemented by providers that store cached context as a
+// resource referenced from later requests (e.g. Gemini cachedContents)
+type ContextCacher interface {
+	CreateCachedContent(ctx context.Context, request *CachedContentRequest) (*CachedContent, error)
+	UpdateCachedContentTTL(ctx context.Context, name string, ttl time.Duration) (*CachedContent, error)
+	DeleteCachedContent(ctx context.Context, name string) error
 }
 
 type TranslationResponse struct {
@@ -409,7 +624,7 @@ type MessagesAPIResponse struct {
 	Content      []MessagesAPIContent  `json:"content"`
 	Model        string                `json:"model"`
 	StopReason   string                `json:"stop_reason,omitempty"`
-	StopSequence string                `json:"stop_sequence,omitempty"`
+	StopSequence *string               `json:"stop_sequence"` // null unless a stop sequence ended the message
 	Usage        MessagesAPIUsage      `json:"usage"`
 }
 
@@ -0,0 +1,604 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	qianfanDefaultBaseURL = "https://aip.baidubce.com"
+
+	qianfanChatPath      = "/rpc/2.0/ai_custom/v1/wenxinworkshop/chat/"
+	qianfanEmbeddingPath = "/rpc/2.0/ai_custom/v1/wenxinworkshop/embeddings/"
+
+	// qianfanTokenRefreshMargin is how long before expiry an access token is
+	// refreshed; tokens are valid for 30 days
+	qianfanTokenRefreshMargin = time.Hour
+)
+
+// qianfanEndpoints maps the ERNIE model names to the endpoints serving them.
+// Other models, such as custom deployments, are served at an endpoint of
+// their own name.
+var qianfanEndpoints = map[string]string{
+	"ernie-4.0-8k":       "completions_pro",
+	"ernie-4.0-turbo-8k": "ernie-4.0-turbo-8k",
+	"ernie-3.5-8k":       "completions",
+	"ernie-3.5-128k":     "ernie-3.5-128k",
+	"ernie-speed-8k":     "ernie_speed",
+	"ernie-speed-128k":   "ernie-speed-128k",
+	"ernie-lite-8k":      "ernie-lite-8k",
+	"ernie-tiny-8k":      "ernie-tiny-8k",
+	"embedding-v1":       "embedding-v1",
+	"bge-large-zh":       "bge_large_zh",
+	"bge-large-en":       "bge_large_en",
+	"tao-8k":             "tao_8k",
+}
+
+// Qianfan error codes of an invalid or expired access token
+const (
+	qianfanErrInvalidToken = 110
+	qianfanErrExpiredToken = 111
+)
+
+// QianfanProvider serves Baidu ERNIE models from the Qianfan platform.
+// Requests are authenticated with an access token exchanged for the
+// application's API key and secret key and refreshed before it expires.
+type QianfanProvider struct {
+	*BaseProvider
+	apiKey    string
+	secretKey string
+	baseURL   string
+	client    *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// qianfanMessage is a chat message. Messages alternate between user and
+// assistant, starting and ending with the user; function results are
+// function messages.
+type qianfanMessage struct {
+	Role         string               `json:"role"`
+	Content      string               `json:"content"`
+	Name         string               `json:"name,omitempty"`
+	FunctionCall *qianfanFunctionCall `json:"function_call,omitempty"`
+}
+
+type qianfanFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Thoughts  string `json:"thoughts,omitempty"`
+}
+
+// qianfanChatRequest is the body of the ERNIE chat API
+type qianfanChatRequest struct {
+	Messages        []qianfanMessage `json:"messages"`
+	System          string           `json:"system,omitempty"`
+	Stream          bool             `json:"stream,omitempty"`
+	Temperature     *float32         `json:"temperature,omitempty"`
+	TopP            *float32         `json:"top_p,omitempty"`
+	PenaltyScore    *float32         `json:"penalty_score,omitempty"`
+	MaxOutputTokens *int             `json:"max_output_tokens,omitempty"`
+	Stop            []string         `json:"stop,omitempty"`
+	UserID          string           `json:"user_id,omitempty"`
+	ResponseFormat  string           `json:"response_format,omitempty"`
+	Functions       []Function       `json:"functions,omitempty"`
+}
+
+// qianfanChatResponse is a chat response, or an event of a chat stream
+type qianfanChatResponse struct {
+	ID           string               `json:"id"`
+	Created      int64                `json:"created"`
+	Result       string               `json:"result"`
+	IsEnd        bool                 `json:"is_end"`
+	IsTruncated  bool                 `json:"is_truncated"`
+	FinishReason string               `json:"finish_reason"`
+	FunctionCall *qianfanFunctionCall `json:"function_call"`
+	Usage        Usage                `json:"usage"`
+
+	// Errors are returned with status 200
+	ErrorCode int    `json:"error_code"`
+	ErrorMsg  string `json:"error_msg"`
+}
+
+// NewQianfanProvider creates a Qianfan provider. APIKey and APISecret are the
+// API key and secret key of a Qianfan application; BaseURL defaults to the
+// Baidu AI Cloud endpoint, which also issues the access tokens.
+func NewQianfanProvider(name string, cfg ProviderConfig) (*QianfanProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("qianfan API key is required")
+	}
+	if cfg.APISecret == "" {
+		return nil, fmt.Errorf("qianfan secret key is required")
+	}
+
+	p := &QianfanProvider{
+		BaseProvider: NewBaseProvider(name, "qianfan", cfg.Priority, cfg.Models),
+		apiKey:       cfg.APIKey,
+		secretKey:    cfg.APISecret,
+		baseURL:      strings.TrimSuffix(cfg.BaseURL, "/"),
+		client: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: newCaptureTransport("qianfan"),
+		},
+	}
+	if p.baseURL == "" {
+		p.baseURL = qianfanDefaultBaseURL
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = cfg.Timeout
+	}
+	return p, nil
+}
+
+// accessToken returns a valid access token, exchanging the API key and
+// secret key for a new one when the cached token is about to expire
+func (p *QianfanProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Until(p.tokenExpiry) > qianfanTokenRefreshMargin {
+		return p.token, nil
+	}
+
+	query := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.apiKey},
+		"client_secret": {p.secretKey},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/oauth/2.0/token?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create access token request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("access token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read access token response: %w", err)
+	}
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("access token request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("access token request failed (%s): %s", token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("access token response has no access token")
+	}
+
+	p.token = token.AccessToken
+	if token.ExpiresIn > 0 {
+		p.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	} else {
+		p.tokenExpiry = time.Now().Add(30 * 24 * time.Hour)
+	}
+	return p.token, nil
+}
+
+// invalidateToken drops a token the API rejected, so the next request
+// fetches a new one
+func (p *QianfanProvider) invalidateToken(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == token {
+		p.token = ""
+	}
+}
+
+// post sends a body to an API path with the access token. A response that
+// reports the token invalid is retried once with a new token; the caller
+// closes the returned response.
+func (p *QianfanProvider) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := p.accessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		target := p.baseURL + path + "?access_token=" + url.QueryEscape(token)
+		req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make request: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			data, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(data))
+		}
+		if isEventStream(resp) {
+			return resp, nil
+		}
+
+		// Errors come back as JSON with status 200, also for streams
+		data, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		var errResp qianfanChatResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.ErrorCode != 0 {
+			if attempt == 0 && (errResp.ErrorCode == qianfanErrInvalidToken || errResp.ErrorCode == qianfanErrExpiredToken) {
+				p.invalidateToken(token)
+				continue
+			}
+			return nil, fmt.Errorf("qianfan API error (%d): %s", errResp.ErrorCode, errResp.ErrorMsg)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		return resp, nil
+	}
+}
+
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// qianfanEndpoint returns the endpoint name of a model
+func qianfanEndpoint(model string) string {
+	if endpoint, ok := qianfanEndpoints[strings.ToLower(model)]; ok {
+		return endpoint
+	}
+	return model
+}
+
+// qianfanChatBody converts an OpenAI chat request to the ERNIE format. System
+// messages become the system field and consecutive messages of the same role
+// are merged, as ERNIE requires alternating turns.
+func qianfanChatBody(request *ChatRequest, stream bool) (*qianfanChatRequest, error) {
+	if request.N != nil && *request.N > 1 {
+		return nil, fmt.Errorf("qianfan does not support n > 1")
+	}
+
+	body := &qianfanChatRequest{
+		Stream:          stream,
+		Temperature:     request.Temperature,
+		TopP:            request.TopP,
+		MaxOutputTokens: request.MaxTokens,
+		Stop:            request.Stop,
+		UserID:          request.User,
+	}
+	// ERNIE takes temperatures in (0, 1]
+	if t := request.Temperature; t != nil && (*t <= 0 || *t > 1) {
+		clamped := float32(1)
+		if *t <= 0 {
+			clamped = 0.01
+		}
+		body.Temperature = &clamped
+	}
+	// The presence penalty maps to ERNIE's penalty score in [1, 2]
+	if penalty := request.PresencePenalty; penalty != nil && *penalty > 0 {
+		score := 1 + *penalty/2
+		if score > 2 {
+			score = 2
+		}
+		body.PenaltyScore = &score
+	}
+	if format := request.ResponseFormat; format != nil && format.Type != "text" {
+		body.ResponseFormat = "json_object"
+	}
+	for _, tool := range request.Tools {
+		body.Functions = append(body.Functions, tool.Function)
+	}
+
+	// Tool results are answered by name
+	toolNames := make(map[string]string)
+	var systems []string
+	for _, msg := range request.Messages {
+		var next qianfanMessage
+		switch msg.Role {
+		case "system", "developer":
+			systems = append(systems, messageText(msg.Content))
+			continue
+		case "assistant":
+			next = qianfanMessage{Role: "assistant", Content: messageText(msg.Content)}
+			if len(msg.ToolCalls) > 0 {
+				call := msg.ToolCalls[0]
+				next.FunctionCall = &qianfanFunctionCall{Name: call.Function.Name, Arguments: call.Function.Arguments}
+				for _, call := range msg.ToolCalls {
+					toolNames[call.ID] = call.Function.Name
+				}
+			}
+		case "tool":
+			name := msg.Name
+			if name == "" {
+				name = toolNames[msg.ToolCallID]
+			}
+			next = qianfanMessage{Role: "function", Name: name, Content: messageText(msg.Content)}
+		default:
+			next = qianfanMessage{Role: "user", Content: messageText(msg.Content)}
+		}
+
+		if n := len(body.Messages); n > 0 && next.Role != "function" && body.Messages[n-1].Role == next.Role && body.Messages[n-1].FunctionCall == nil && next.FunctionCall == nil {
+			body.Messages[n-1].Content += "\n\n" + next.Content
+			continue
+		}
+		body.Messages = append(body.Messages, next)
+	}
+	body.System = strings.Join(systems, "\n\n")
+
+	if len(body.Messages) == 0 || body.Messages[0].Role != "user" {
+		return nil, fmt.Errorf("qianfan requires the conversation to start with a user message")
+	}
+	return body, nil
+}
+
+// qianfanFinishReason maps an ERNIE finish reason to the OpenAI one
+func qianfanFinishReason(reason string, truncated bool) string {
+	switch {
+	case truncated || reason == "length":
+		return "length"
+	case reason == "function_call":
+		return "tool_calls"
+	case reason == "content_filter":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+// toolCall converts an ERNIE function call to a tool call
+func (c *qianfanFunctionCall) toolCall(id string) ToolCall {
+	return ToolCall{
+		ID:       "call_" + id,
+		Type:     "function",
+		Function: FunctionCall{Name: c.Name, Arguments: c.Arguments},
+	}
+}
+
+func (p *QianfanProvider) ChatCompletion(ctx context.Context, request *ChatRequest) (*ChatResponse, error) {
+	chatBody, err := qianfanChatBody(request, false)
+	if err != nil {
+		return nil, err
+	}
+	reqBody, err := json.Marshal(chatBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.post(ctx, qianfanChatPath+qianfanEndpoint(request.Model), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var qfResp qianfanChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&qfResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	message := Message{Role: "assistant", Content: qfResp.Result}
+	if call := qfResp.FunctionCall; call != nil {
+		message.ToolCalls = []ToolCall{call.toolCall(qfResp.ID)}
+		if qfResp.Result == "" {
+			message.Content = nil
+		}
+	}
+	finishReason := qianfanFinishReason(qfResp.FinishReason, qfResp.IsTruncated)
+	if qfResp.FunctionCall != nil {
+		finishReason = "tool_calls"
+	}
+
+	chatResp := &ChatResponse{
+		ID:      qfResp.ID,
+		Object:  "chat.completion",
+		Created: qfResp.Created,
+		Model:   request.Model,
+		Choices: []Choice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: finishReason,
+		}},
+		Usage: qfResp.Usage,
+	}
+	if chatResp.Created == 0 {
+		chatResp.Created = time.Now().Unix()
+	}
+	return chatResp, nil
+}
+
+func (p *QianfanProvider) ChatCompletionStream(ctx context.Context, request *ChatRequest) (<-chan StreamResponse, error) {
+	chatBody, err := qianfanChatBody(request, true)
+	if err != nil {
+		return nil, err
+	}
+	reqBody, err := json.Marshal(chatBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.post(ctx, qianfanChatPath+qianfanEndpoint(request.Model), reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	streamChan := make(chan StreamResponse, 100)
+	go func() {
+		defer close(streamChan)
+		defer func() { _ = resp.Body.Close() }()
+		if err := parseQianfanStream(resp.Body, request.Model, streamChan); err != nil {
+			log.Printf("Qianfan stream failed: %v", err)
+		}
+	}()
+
+	return streamChan, nil
+}
+
+// parseQianfanStream converts the ERNIE chat stream to OpenAI chunks. Each
+// event carries the next piece of the result; function calls are sent whole
+// and the last event, marked is_end, carries the usage.
+func parseQianfanStream(body io.Reader, model string, streamChan chan<- StreamResponse) error {
+	id := GenerateID()
+	created := time.Now().Unix()
+	roleSent := false
+	chunk := func(delta Message, finishReason string, usage *Usage) StreamResponse {
+		if !roleSent {
+			delta.Role = "assistant"
+			roleSent = true
+		}
+		return StreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []StreamChoice{{Delta: delta, FinishReason: finishReason}},
+			Usage:   usage,
+		}
+	}
+
+	return readSSE(body, func(data []byte) error {
+		var event qianfanChatResponse
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil // Skip malformed data
+		}
+		if event.ErrorCode != 0 {
+			return fmt.Errorf("qianfan stream error (%d): %s", event.ErrorCode, event.ErrorMsg)
+		}
+		if event.Created != 0 {
+			created = event.Created
+		}
+
+		if event.Result != "" {
+			streamChan <- chunk(Message{Content: event.Result}, "", nil)
+		}
+		if call := event.FunctionCall; call != nil {
+			index := 0
+			toolCall := call.toolCall(event.ID)
+			toolCall.Index = &index
+			streamChan <- chunk(Message{ToolCalls: []ToolCall{toolCall}}, "", nil)
+		}
+		if event.IsEnd {
+			reason := qianfanFinishReason(event.FinishReason, event.IsTruncated)
+			if event.FunctionCall != nil || event.FinishReason == "function_call" {
+				reason = "tool_calls"
+			}
+			usage := event.Usage
+			streamChan <- chunk(Message{}, reason, &usage)
+		}
+		return nil
+	})
+}
+
+func (p *QianfanProvider) Completion(ctx context.Context, request *CompletionRequest) (*CompletionResponse, error) {
+	return nil, fmt.Errorf("completion API not supported by qianfan provider")
+}
+
+func (p *QianfanProvider) CompletionStream(ctx context.Context, request *CompletionRequest) (<-chan StreamResponse, error) {
+	return nil, fmt.Errorf("completion stream API not supported by qianfan provider")
+}
+
+func (p *QianfanProvider) Embeddings(ctx context.Context, request *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	var texts []string
+	switch input := request.Input.(type) {
+	case string:
+		texts = []string{input}
+	case []string:
+		texts = input
+	case []interface{}:
+		for _, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("qianfan embeddings only support text input")
+			}
+			texts = append(texts, text)
+		}
+	default:
+		return nil, fmt.Errorf("qianfan embeddings only support text input")
+	}
+
+	payload := map[string]interface{}{"input": texts}
+	if request.User != "" {
+		payload["user_id"] = request.User
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.post(ctx, qianfanEmbeddingPath+qianfanEndpoint(request.Model), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var qfResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage Usage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&qfResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	embResp := &EmbeddingsResponse{
+		Object: "list",
+		Model:  request.Model,
+		Usage: Usage{
+			PromptTokens: qfResp.Usage.PromptTokens,
+			TotalTokens:  qfResp.Usage.TotalTokens,
+		},
+	}
+	for _, result := range qfResp.Data {
+		embResp.Data = append(embResp.Data, Embedding{
+			Object:    "embedding",
+			Index:     result.Index,
+			Embedding: result.Embedding,
+		})
+	}
+	return embResp, nil
+}
+
+func (p *QianfanProvider) AudioTranscription(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by qianfan provider")
+}
+
+func (p *QianfanProvider) AudioSpeech(ctx context.Context, request *SpeechRequest) ([]byte, error) {
+	return nil, fmt.Errorf("audio speech not supported by qianfan provider")
+}
+
+func (p *QianfanProvider) ImageGeneration(ctx context.Context, request *ImageRequest) (*ImageResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by qianfan provider")
+}
+
+// HealthCheck fetches an access token unless one is cached, which checks the
+// API key and secret key; the chat API has no call that does not generate
+func (p *QianfanProvider) HealthCheck(ctx context.Context) error {
+	if _, err := p.accessToken(ctx); err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	p.SetHealthy(true)
+	return nil
+}
@@ -0,0 +1,211 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newQianfanTestServer(t *testing.T, handler http.HandlerFunc) (*QianfanProvider, *int32) {
+	var tokens int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/2.0/token" {
+			assert.Equal(t, "client_credentials", r.URL.Query().Get("grant_type"))
+			assert.Equal(t, "qf-key", r.URL.Query().Get("client_id"))
+			assert.Equal(t, "qf-secret", r.URL.Query().Get("client_secret"))
+			n := atomic.AddInt32(&tokens, 1)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": fmt.Sprintf("token-%d", n),
+				"expires_in":   2592000,
+			})
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewQianfanProvider("qianfan-test", ProviderConfig{
+		Type:      "qianfan",
+		APIKey:    "qf-key",
+		APISecret: "qf-secret",
+		BaseURL:   server.URL,
+	})
+	require.NoError(t, err)
+	return provider, &tokens
+}
+
+func TestNewQianfanProvider(t *testing.T) {
+	_, err := NewQianfanProvider("qf", ProviderConfig{APISecret: "secret"})
+	assert.Error(t, err, "API key is required")
+
+	_, err = NewQianfanProvider("qf", ProviderConfig{APIKey: "key"})
+	assert.Error(t, err, "secret key is required")
+
+	provider, err := NewQianfanProvider("qf", ProviderConfig{APIKey: "key", APISecret: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "qianfan", provider.GetType())
+	assert.Equal(t, qianfanDefaultBaseURL, provider.baseURL)
+}
+
+func TestQianfanChatBody(t *testing.T) {
+	temperature := float32(1.5)
+	body, err := qianfanChatBody(&ChatRequest{
+		Model:       "ernie-4.0-8k",
+		Temperature: &temperature,
+		Messages: []Message{
+			{Role: "system", Content: "Be brief"},
+			{Role: "user", Content: "Hi"},
+			{Role: "user", Content: "Weather in Beijing?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Function: FunctionCall{Name: "get_weather", Arguments: `{"city":"Beijing"}`}}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "Sunny"},
+		},
+		Tools: []Tool{{Type: "function", Function: Function{Name: "get_weather"}}},
+	}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Be brief", body.System)
+	require.Len(t, body.Messages, 3)
+	assert.Equal(t, "Hi\n\nWeather in Beijing?", body.Messages[0].Content, "consecutive user messages are merged")
+	require.NotNil(t, body.Messages[1].FunctionCall)
+	assert.Equal(t, "get_weather", body.Messages[1].FunctionCall.Name)
+	assert.Equal(t, qianfanMessage{Role: "function", Name: "get_weather", Content: "Sunny"}, body.Messages[2])
+	require.Len(t, body.Functions, 1)
+	assert.Equal(t, float32(1), *body.Temperature, "temperatures are clamped to (0, 1]")
+
+	_, err = qianfanChatBody(&ChatRequest{Messages: []Message{{Role: "assistant", Content: "Hello"}}}, false)
+	assert.Error(t, err, "conversations start with the user")
+}
+
+func TestQianfanProvider_ChatCompletion(t *testing.T) {
+	provider, tokens := newQianfanTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, qianfanChatPath+"completions_pro", r.URL.Path)
+		assert.Equal(t, "token-1", r.URL.Query().Get("access_token"))
+		_, _ = w.Write([]byte(`{
+			"id": "as-1",
+			"object": "chat.completion",
+			"created": 1760000000,
+			"result": "你好",
+			"is_truncated": false,
+			"finish_reason": "normal",
+			"usage": {"prompt_tokens": 2, "completion_tokens": 2, "total_tokens": 4}
+		}`))
+	})
+
+	request := &ChatRequest{Model: "ERNIE-4.0-8K", Messages: []Message{{Role: "user", Content: "Hi"}}}
+	for i := 0; i < 2; i++ {
+		resp, err := provider.ChatCompletion(context.Background(), request)
+		require.NoError(t, err)
+		assert.Equal(t, "as-1", resp.ID)
+		assert.Equal(t, "ERNIE-4.0-8K", resp.Model)
+		require.Len(t, resp.Choices, 1)
+		assert.Equal(t, "你好", resp.Choices[0].Message.Content)
+		assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+		assert.Equal(t, 4, resp.Usage.TotalTokens)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(tokens), "the access token is reused")
+}
+
+func TestQianfanProvider_RetriesExpiredToken(t *testing.T) {
+	var seen []string
+	provider, tokens := newQianfanTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("access_token")
+		seen = append(seen, token)
+		if token == "token-1" {
+			_, _ = w.Write([]byte(`{"error_code": 111, "error_msg": "Access token expired"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "as-2", "result": "ok", "function_call": {"name": "get_weather", "arguments": "{}"}, "usage": {"total_tokens": 3}}`))
+	})
+
+	resp, err := provider.ChatCompletion(context.Background(), &ChatRequest{Model: "my-endpoint", Messages: []Message{{Role: "user", Content: "Hi"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token-1", "token-2"}, seen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(tokens))
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+}
+
+func TestQianfanProvider_ChatCompletionError(t *testing.T) {
+	provider, _ := newQianfanTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"error_code": 336003, "error_msg": "the first message must be from the user"}`))
+	})
+
+	_, err := provider.ChatCompletionStream(context.Background(), &ChatRequest{Model: "ernie-3.5-8k", Messages: []Message{{Role: "user", Content: "Hi"}}})
+	require.Error(t, err, "errors of streamed requests are returned before streaming")
+	assert.Contains(t, err.Error(), "336003")
+}
+
+func TestQianfanProvider_ChatCompletionStream(t *testing.T) {
+	provider, _ := newQianfanTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body qianfanChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.True(t, body.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(
+			`data: {"id":"as-3","created":1760000000,"sentence_id":0,"is_end":false,"result":"你"}` + "\n\n" +
+				`data: {"id":"as-3","created":1760000000,"sentence_id":1,"is_end":true,"result":"好","finish_reason":"normal","usage":{"prompt_tokens":2,"completion_tokens":2,"total_tokens":4}}` + "\n\n"))
+	})
+
+	stream, err := provider.ChatCompletionStream(context.Background(), &ChatRequest{
+		Model:    "ernie-speed-128k",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var content strings.Builder
+	var chunks []StreamResponse
+	for chunk := range stream {
+		chunks = append(chunks, chunk)
+		if text, ok := chunk.Choices[0].Delta.Content.(string); ok {
+			content.WriteString(text)
+		}
+	}
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "你好", content.String())
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "stop", chunks[2].Choices[0].FinishReason)
+	require.NotNil(t, chunks[2].Usage)
+	assert.Equal(t, 4, chunks[2].Usage.TotalTokens)
+}
+
+func TestQianfanProvider_Embeddings(t *testing.T) {
+	provider, _ := newQianfanTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, qianfanEmbeddingPath+"bge_large_zh", r.URL.Path)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, []interface{}{"a", "b"}, body["input"])
+
+		_, _ = w.Write([]byte(`{"id":"as-4","object":"embedding_list","data":[{"object":"embedding","embedding":[0.1,0.2],"index":0},{"object":"embedding","embedding":[0.3,0.4],"index":1}],"usage":{"prompt_tokens":4,"total_tokens":4}}`))
+	})
+
+	resp, err := provider.Embeddings(context.Background(), &EmbeddingsRequest{Model: "bge-large-zh", Input: []interface{}{"a", "b"}})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, []float32{0.3, 0.4}, resp.Data[1].Embedding)
+	assert.Equal(t, 4, resp.Usage.PromptTokens)
+}
+
+func TestQianfanProvider_HealthCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"error": "invalid_client", "error_description": "unknown client id"}`))
+	}))
+	defer server.Close()
+	provider, err := NewQianfanProvider("qf", ProviderConfig{APIKey: "key", APISecret: "secret", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	err = provider.HealthCheck(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_client")
+	assert.False(t, provider.IsHealthy())
+}
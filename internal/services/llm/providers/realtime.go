@@ -3,6 +3,8 @@ package providers
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/amerfu/pllm/internal/core/models"
 	"github.com/gorilla/websocket"
@@ -30,7 +32,12 @@ type RealtimeConnectionConfig struct {
 	QueryParams map[string]string             `json:"query_params,omitempty"`
 }
 
-
+// RealtimeEventObserver sees every event routed through a session, e.g. to
+// meter its usage
+type RealtimeEventObserver interface {
+	ObserveClientEvent(event *models.RealtimeEvent)
+	ObserveProviderEvent(event *models.RealtimeEvent)
+}
 
 // RealtimeMessageHandler handles bidirectional message routing between client and provider
 type RealtimeMessageHandler struct {
@@ -39,6 +46,11 @@ type RealtimeMessageHandler struct {
 	providerConn   *websocket.Conn
 	sessionConfig  *models.RealtimeSessionConfig
 	stopChan       chan struct{}
+	stopOnce       sync.Once
+	done           chan struct{}
+	doneOnce       sync.Once
+	clientMu       sync.Mutex // Serializes writes to the client connection
+	observer       RealtimeEventObserver
 	model          string
 }
 
@@ -56,10 +68,21 @@ func NewRealtimeMessageHandler(
 		providerConn:  providerConn,
 		sessionConfig: sessionConfig,
 		stopChan:      make(chan struct{}),
+		done:          make(chan struct{}),
 		model:         model,
 	}
 }
 
+// SetObserver sets the observer of routed events; call it before Start
+func (h *RealtimeMessageHandler) SetObserver(observer RealtimeEventObserver) {
+	h.observer = observer
+}
+
+// Done is closed when either side of the session disconnects
+func (h *RealtimeMessageHandler) Done() <-chan struct{} {
+	return h.done
+}
+
 // Start begins message routing between client and provider
 func (h *RealtimeMessageHandler) Start(ctx context.Context) error {
 	// Start client->provider message routing
@@ -89,12 +112,52 @@ func (h *RealtimeMessageHandler) Start(ctx context.Context) error {
 
 // Stop stops message routing
 func (h *RealtimeMessageHandler) Stop() {
-	close(h.stopChan)
+	h.stopOnce.Do(func() { close(h.stopChan) })
+}
+
+// Terminate ends the session from the gateway side: the client gets an error
+// event with the code and message, then a close frame with the close code,
+// and routing stops
+func (h *RealtimeMessageHandler) Terminate(code, message string, closeCode int) {
+	errorEvent, err := models.NewRealtimeEvent("error", &models.ErrorEvent{
+		Type:    "error",
+		Code:    code,
+		Message: message,
+	})
+	if err == nil {
+		h.clientMu.Lock()
+		err = h.clientConn.WriteJSON(errorEvent)
+		h.clientMu.Unlock()
+	}
+	if err != nil {
+		h.logger.Warn("Failed to send termination error to client",
+			zap.String("code", code),
+			zap.Error(err))
+	}
+
+	// Close frame reasons are limited to 123 bytes
+	reason := message
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	if err := h.clientConn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(closeCode, reason), time.Now().Add(time.Second)); err != nil {
+		h.logger.Debug("Failed to send close frame to client", zap.Error(err))
+	}
+
+	h.Stop()
+	_ = h.providerConn.Close()
+	h.finish()
+}
+
+func (h *RealtimeMessageHandler) finish() {
+	h.doneOnce.Do(func() { close(h.done) })
 }
 
 // routeClientToProvider routes messages from client to provider
 func (h *RealtimeMessageHandler) routeClientToProvider(ctx context.Context) {
 	defer h.logger.Debug("Client->Provider routing stopped")
+	defer h.finish()
 
 	for {
 		select {
@@ -139,6 +202,7 @@ func (h *RealtimeMessageHandler) routeClientToProvider(ctx context.Context) {
 // routeProviderToClient routes messages from provider to client
 func (h *RealtimeMessageHandler) routeProviderToClient(ctx context.Context) {
 	defer h.logger.Debug("Provider->Client routing stopped")
+	defer h.finish()
 
 	for {
 		select {
@@ -171,7 +235,10 @@ func (h *RealtimeMessageHandler) routeProviderToClient(ctx context.Context) {
 		}
 
 		// Forward to client
-		if err := h.clientConn.WriteJSON(&event); err != nil {
+		h.clientMu.Lock()
+		err := h.clientConn.WriteJSON(&event)
+		h.clientMu.Unlock()
+		if err != nil {
 			h.logger.Error("Failed to send event to client", 
 				zap.String("type", event.Type),
 				zap.Error(err))
@@ -189,6 +256,10 @@ func (h *RealtimeMessageHandler) processClientEvent(event *models.RealtimeEvent)
 		zap.String("type", event.Type),
 		zap.String("model", h.model))
 
+	if h.observer != nil {
+		h.observer.ObserveClientEvent(event)
+	}
+
 	return nil
 }
 
@@ -201,6 +272,10 @@ func (h *RealtimeMessageHandler) processProviderEvent(event *models.RealtimeEven
 		zap.String("type", event.Type),
 		zap.String("model", h.model))
 
+	if h.observer != nil {
+		h.observer.ObserveProviderEvent(event)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,373 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// StreamFormat is the native wire format of a provider's streaming API
+type StreamFormat string
+
+const (
+	StreamFormatOpenAI    StreamFormat = "openai"    // SSE with chat.completion.chunk data
+	StreamFormatAnthropic StreamFormat = "anthropic" // SSE with Messages API events
+	StreamFormatBedrock   StreamFormat = "bedrock"   // AWS event stream wrapping Anthropic events
+	StreamFormatGemini    StreamFormat = "gemini"    // SSE with streamGenerateContent responses
+)
+
+// errStreamDone ends an SSE stream at its [DONE] sentinel
+var errStreamDone = errors.New("stream done")
+
+// StreamTranscoder converts a provider's native stream into OpenAI
+// chat.completion.chunk responses. The first chunk carries the assistant
+// role, tool calls are streamed as indexed deltas (id and name first, then
+// argument pieces), and the chunk with the finish reason carries the usage
+// when the provider reports it. A transcoder handles a single stream.
+type StreamTranscoder struct {
+	format  StreamFormat
+	id      string
+	model   string
+	created int64
+
+	roleSent  bool
+	toolCalls map[int]int // Anthropic content block index -> tool call index
+	nextTool  int
+	usage     AnthropicUsage
+	gemini    *Usage
+}
+
+// NewStreamTranscoder creates a transcoder for a stream in the given format.
+// The model names the chunks until the stream reports its own.
+func NewStreamTranscoder(format StreamFormat, model string) *StreamTranscoder {
+	return &StreamTranscoder{
+		format:    format,
+		id:        GenerateID(),
+		model:     model,
+		created:   time.Now().Unix(),
+		toolCalls: make(map[int]int),
+	}
+}
+
+// Transcode reads the native stream from body and sends the converted chunks
+// to out until the stream ends. It returns read errors and the errors the
+// provider reports inside the stream.
+func (t *StreamTranscoder) Transcode(body io.Reader, out chan<- StreamResponse) error {
+	handle := func(payload []byte) error {
+		chunks, err := t.Event(payload)
+		for _, chunk := range chunks {
+			out <- chunk
+		}
+		return err
+	}
+	if t.format == StreamFormatBedrock {
+		return readEventStream(body, handle)
+	}
+	return readSSE(body, handle)
+}
+
+// Event converts one native event, the data of an SSE event or the payload
+// of a Bedrock chunk, into chunks
+func (t *StreamTranscoder) Event(payload []byte) ([]StreamResponse, error) {
+	switch t.format {
+	case StreamFormatAnthropic, StreamFormatBedrock:
+		return t.anthropicEvent(payload)
+	case StreamFormatGemini:
+		return t.geminiEvent(payload)
+	default:
+		return t.openAIEvent(payload)
+	}
+}
+
+// chunk builds a chunk with a single choice, adding the assistant role to
+// the first one
+func (t *StreamTranscoder) chunk(delta Message, finishReason string) StreamResponse {
+	if !t.roleSent {
+		delta.Role = "assistant"
+		t.roleSent = true
+	}
+	return StreamResponse{
+		ID:      t.id,
+		Object:  "chat.completion.chunk",
+		Created: t.created,
+		Model:   t.model,
+		Choices: []StreamChoice{{Index: 0, Delta: delta, FinishReason: finishReason}},
+	}
+}
+
+// toolCallDelta builds the delta of a tool call; id and name are only sent
+// with its first piece
+func toolCallDelta(index int, id, name, arguments string) Message {
+	call := ToolCall{Index: &index, ID: id, Function: FunctionCall{Name: name, Arguments: arguments}}
+	if id != "" {
+		call.Type = "function"
+	}
+	return Message{ToolCalls: []ToolCall{call}}
+}
+
+// openAIEvent passes OpenAI-compatible chunks through, surfacing error
+// events
+func (t *StreamTranscoder) openAIEvent(payload []byte) ([]StreamResponse, error) {
+	var event struct {
+		StreamResponse
+		Error *APIError `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, nil // Skip malformed data
+	}
+	if event.Error != nil {
+		return nil, fmt.Errorf("stream error: %s", event.Error.Message)
+	}
+	return []StreamResponse{event.StreamResponse}, nil
+}
+
+// anthropicEvent converts a Messages API stream event
+func (t *StreamTranscoder) anthropicEvent(payload []byte) ([]StreamResponse, error) {
+	var event AnthropicStreamResponse
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, nil // Skip malformed data
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			if event.Message.Model != "" {
+				t.model = event.Message.Model
+			}
+			t.usage = event.Message.Usage
+		}
+		return []StreamResponse{t.chunk(Message{Content: ""}, "")}, nil
+
+	case "content_block_start":
+		block := event.ContentBlock
+		if block == nil {
+			return nil, nil
+		}
+		switch block.Type {
+		case "tool_use":
+			index := t.nextTool
+			t.nextTool++
+			t.toolCalls[event.Index] = index
+			return []StreamResponse{t.chunk(toolCallDelta(index, block.ID, block.Name, ""), "")}, nil
+		case "text":
+			if block.Text != "" {
+				return []StreamResponse{t.chunk(Message{Content: block.Text}, "")}, nil
+			}
+		}
+		return nil, nil
+
+	case "content_block_delta":
+		delta := event.Delta
+		if delta == nil {
+			return nil, nil
+		}
+		switch delta.Type {
+		case "text_delta":
+			return []StreamResponse{t.chunk(Message{Content: delta.Text}, "")}, nil
+		case "input_json_delta":
+			index, ok := t.toolCalls[event.Index]
+			if !ok || delta.PartialJSON == "" {
+				return nil, nil
+			}
+			return []StreamResponse{t.chunk(toolCallDelta(index, "", "", delta.PartialJSON), "")}, nil
+		}
+		return nil, nil // Thinking and signature deltas have no chat completion equivalent
+
+	case "message_delta":
+		if event.Usage != nil {
+			t.usage.OutputTokens = event.Usage.OutputTokens
+		}
+		if event.Delta == nil || event.Delta.StopReason == "" {
+			return nil, nil
+		}
+		chunk := t.chunk(Message{}, anthropicFinishReason(event.Delta.StopReason))
+		usage := t.usage.ToUsage()
+		chunk.Usage = &usage
+		return []StreamResponse{chunk}, nil
+
+	case "error":
+		if event.Error != nil {
+			return nil, fmt.Errorf("anthropic stream error %s: %s", event.Error.Type, event.Error.Message)
+		}
+		return nil, errors.New("anthropic stream error")
+	}
+	return nil, nil // ping, content_block_stop, message_stop
+}
+
+// anthropicFinishReason maps an Anthropic stop reason to a finish reason
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return reason
+	}
+}
+
+// geminiStreamResponse is one streamGenerateContent response
+type geminiStreamResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text         string `json:"text"`
+				Thought      bool   `json:"thought"`
+				FunctionCall *struct {
+					Name string          `json:"name"`
+					Args json.RawMessage `json:"args"`
+				} `json:"functionCall"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+	Error        *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// geminiEvent converts a streamGenerateContent response. Gemini sends each
+// function call whole, so it becomes a single tool call delta.
+func (t *StreamTranscoder) geminiEvent(payload []byte) ([]StreamResponse, error) {
+	var event geminiStreamResponse
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, nil // Skip malformed data
+	}
+	if event.Error != nil {
+		return nil, fmt.Errorf("gemini stream error %d: %s", event.Error.Code, event.Error.Message)
+	}
+	if event.ModelVersion != "" {
+		t.model = event.ModelVersion
+	}
+	if meta := event.UsageMetadata; meta != nil {
+		t.gemini = &Usage{
+			PromptTokens:     meta.PromptTokenCount,
+			CompletionTokens: meta.CandidatesTokenCount,
+			TotalTokens:      meta.TotalTokenCount,
+		}
+		if meta.CachedContentTokenCount > 0 {
+			t.gemini.PromptTokensDetails = &PromptTokensDetails{CachedTokens: meta.CachedContentTokenCount}
+		}
+	}
+	if len(event.Candidates) == 0 {
+		return nil, nil
+	}
+
+	candidate := event.Candidates[0]
+	var chunks []StreamResponse
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			args := string(part.FunctionCall.Args)
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			index := t.nextTool
+			t.nextTool++
+			id := fmt.Sprintf("call_%s_%d", t.id[len("chatcmpl-"):], index)
+			chunks = append(chunks, t.chunk(toolCallDelta(index, id, part.FunctionCall.Name, args), ""))
+		case part.Text != "" && !part.Thought:
+			chunks = append(chunks, t.chunk(Message{Content: part.Text}, ""))
+		}
+	}
+
+	if candidate.FinishReason != "" && candidate.FinishReason != "FINISH_REASON_UNSPECIFIED" {
+		reason := geminiFinishReason(candidate.FinishReason)
+		if reason == "stop" && t.nextTool > 0 {
+			reason = "tool_calls"
+		}
+		chunk := t.chunk(Message{}, reason)
+		chunk.Usage = t.gemini
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// geminiFinishReason maps a Gemini finish reason to a finish reason
+func geminiFinishReason(reason string) string {
+	switch reason {
+	case "STOP":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	default:
+		return reason
+	}
+}
+
+// readSSE reads server-sent events and calls handle with the data of each
+// event, until the stream ends or sends [DONE]
+func readSSE(body io.Reader, handle func(data []byte) error) error {
+	reader := bufio.NewReader(body)
+	var data bytes.Buffer
+
+	dispatch := func() error {
+		if data.Len() == 0 {
+			return nil
+		}
+		payload := bytes.TrimSpace(data.Bytes())
+		defer data.Reset()
+		if string(payload) == "[DONE]" {
+			return errStreamDone
+		}
+		return handle(payload)
+	}
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case len(line) == 0 && readErr == nil:
+			// A blank line ends the event
+			if err := dispatch(); err != nil {
+				if err == errStreamDone {
+					return nil
+				}
+				return err
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			// Some servers omit the blank line between events
+			if data.Len() > 0 && json.Valid(data.Bytes()) {
+				if err := dispatch(); err != nil {
+					if err == errStreamDone {
+						return nil
+					}
+					return err
+				}
+			}
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(line[len("data:"):], []byte(" ")))
+		}
+		// Comments and event, id and retry fields are not needed
+
+		if readErr != nil {
+			if readErr != io.EOF {
+				return fmt.Errorf("failed to read stream: %w", readErr)
+			}
+			if err := dispatch(); err != nil && err != errStreamDone {
+				return err
+			}
+			return nil
+		}
+	}
+}
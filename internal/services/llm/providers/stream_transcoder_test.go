@@ -0,0 +1,227 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transcode runs a transcoder over body and collects its chunks
+func transcode(t *testing.T, format StreamFormat, body []byte) ([]StreamResponse, error) {
+	t.Helper()
+	out := make(chan StreamResponse, 100)
+	err := NewStreamTranscoder(format, "test-model").Transcode(bytes.NewReader(body), out)
+	close(out)
+
+	var chunks []StreamResponse
+	for chunk := range out {
+		chunks = append(chunks, chunk)
+	}
+	return chunks, err
+}
+
+func sse(events ...string) []byte {
+	var b strings.Builder
+	for _, event := range events {
+		b.WriteString("data: " + event + "\n\n")
+	}
+	return []byte(b.String())
+}
+
+func TestStreamTranscoder_AnthropicToolUse(t *testing.T) {
+	body := []byte(`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","model":"claude-3-5-sonnet","usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":40}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+
+	chunks, err := transcode(t, StreamFormatAnthropic, body)
+	require.NoError(t, err)
+	require.Len(t, chunks, 6)
+
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "claude-3-5-sonnet", chunks[1].Model)
+	assert.Equal(t, "Checking.", chunks[1].Choices[0].Delta.Content)
+	assert.Empty(t, chunks[1].Choices[0].Delta.Role, "only the first chunk carries the role")
+
+	start := chunks[2].Choices[0].Delta.ToolCalls
+	require.Len(t, start, 1)
+	require.NotNil(t, start[0].Index)
+	assert.Equal(t, 0, *start[0].Index)
+	assert.Equal(t, "toolu_1", start[0].ID)
+	assert.Equal(t, "function", start[0].Type)
+	assert.Equal(t, "get_weather", start[0].Function.Name)
+
+	var args string
+	for _, chunk := range chunks[3:5] {
+		call := chunk.Choices[0].Delta.ToolCalls[0]
+		assert.Equal(t, 0, *call.Index)
+		assert.Empty(t, call.ID)
+		args += call.Function.Arguments
+	}
+	assert.Equal(t, `{"city":"Paris"}`, args)
+
+	finish := chunks[5]
+	assert.Equal(t, "tool_calls", finish.Choices[0].FinishReason)
+	require.NotNil(t, finish.Usage)
+	assert.Equal(t, 25, finish.Usage.PromptTokens)
+	assert.Equal(t, 40, finish.Usage.CompletionTokens)
+	assert.Equal(t, 65, finish.Usage.TotalTokens)
+
+	for _, chunk := range chunks {
+		assert.Equal(t, chunks[0].ID, chunk.ID)
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+	}
+}
+
+func TestStreamTranscoder_AnthropicError(t *testing.T) {
+	_, err := transcode(t, StreamFormatAnthropic, sse(
+		`{"type":"message_start","message":{"model":"claude"}}`,
+		`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+	))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Overloaded")
+}
+
+func TestStreamTranscoder_Gemini(t *testing.T) {
+	chunks, err := transcode(t, StreamFormatGemini, sse(
+		`{"candidates":[{"content":{"parts":[{"text":"thinking","thought":true},{"text":"Sure"}]}}],"modelVersion":"gemini-1.5-pro"}`,
+		`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	assert.Equal(t, "Sure", chunks[0].Choices[0].Delta.Content, "thoughts are not streamed")
+	assert.Equal(t, "gemini-1.5-pro", chunks[0].Model)
+
+	call := chunks[1].Choices[0].Delta.ToolCalls[0]
+	assert.Equal(t, 0, *call.Index)
+	assert.NotEmpty(t, call.ID)
+	assert.Equal(t, "get_weather", call.Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, call.Function.Arguments)
+
+	assert.Equal(t, "tool_calls", chunks[2].Choices[0].FinishReason)
+	require.NotNil(t, chunks[2].Usage)
+	assert.Equal(t, 15, chunks[2].Usage.TotalTokens)
+}
+
+func TestStreamTranscoder_OpenAI(t *testing.T) {
+	chunks, err := transcode(t, StreamFormatOpenAI, []byte(": keep-alive\n\n"+string(sse(
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":1,"id":"call_1","type":"function","function":{"name":"lookup","arguments":""}}]}}]}`,
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`,
+		`[DONE]`,
+		`{"id":"ignored"}`,
+	))))
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+
+	assert.Equal(t, "chatcmpl-1", chunks[0].ID)
+	assert.Equal(t, 1, *chunks[0].Choices[0].Delta.ToolCalls[0].Index)
+	assert.Equal(t, "{}", chunks[1].Choices[0].Delta.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "tool_calls", chunks[1].Choices[0].FinishReason)
+}
+
+func TestStreamTranscoder_OpenAIError(t *testing.T) {
+	_, err := transcode(t, StreamFormatOpenAI, sse(`{"error":{"message":"rate limited"}}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rate limited")
+}
+
+func TestReadSSE_MultiLineData(t *testing.T) {
+	var events []string
+	err := readSSE(strings.NewReader("event: x\ndata: {\"a\":\ndata: 1}\n\nid: 7\ndata: {\"b\":2}\r\n\r\n"), func(data []byte) error {
+		events = append(events, string(data))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"{\"a\":\n1}", `{"b":2}`}, events)
+}
+
+// eventStreamMessage encodes an AWS event stream message with string headers
+func eventStreamMessage(headers map[string]string, payload []byte) []byte {
+	var h bytes.Buffer
+	for _, name := range []string{":message-type", ":event-type", ":exception-type"} {
+		value, ok := headers[name]
+		if !ok {
+			continue
+		}
+		h.WriteByte(byte(len(name)))
+		h.WriteString(name)
+		h.WriteByte(7)
+		_ = binary.Write(&h, binary.BigEndian, uint16(len(value)))
+		h.WriteString(value)
+	}
+
+	total := 12 + h.Len() + len(payload) + 4
+	msg := make([]byte, 12, total)
+	binary.BigEndian.PutUint32(msg[0:4], uint32(total))
+	binary.BigEndian.PutUint32(msg[4:8], uint32(h.Len()))
+	binary.BigEndian.PutUint32(msg[8:12], crc32.ChecksumIEEE(msg[:8]))
+	msg = append(msg, h.Bytes()...)
+	msg = append(msg, payload...)
+	return binary.BigEndian.AppendUint32(msg, crc32.ChecksumIEEE(msg))
+}
+
+func bedrockChunk(event string) []byte {
+	payload := `{"bytes":"` + base64.StdEncoding.EncodeToString([]byte(event)) + `"}`
+	return eventStreamMessage(map[string]string{":message-type": "event", ":event-type": "chunk"}, []byte(payload))
+}
+
+func TestStreamTranscoder_Bedrock(t *testing.T) {
+	var body []byte
+	body = append(body, bedrockChunk(`{"type":"message_start","message":{"model":"claude","usage":{"input_tokens":3}}}`)...)
+	body = append(body, bedrockChunk(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`)...)
+	body = append(body, bedrockChunk(`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`)...)
+
+	chunks, err := transcode(t, StreamFormatBedrock, body)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "Hi", chunks[1].Choices[0].Delta.Content)
+	assert.Equal(t, "stop", chunks[2].Choices[0].FinishReason)
+	assert.Equal(t, 5, chunks[2].Usage.TotalTokens)
+}
+
+func TestReadEventStream_Errors(t *testing.T) {
+	exception := eventStreamMessage(
+		map[string]string{":message-type": "exception", ":exception-type": "throttlingException"},
+		[]byte(`{"message":"Too many requests"}`),
+	)
+	err := readEventStream(bytes.NewReader(exception), func([]byte) error { return nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "throttlingException")
+	assert.Contains(t, err.Error(), "Too many requests")
+
+	corrupt := bedrockChunk(`{"type":"ping"}`)
+	corrupt[len(corrupt)-6] ^= 0xff
+	err = readEventStream(bytes.NewReader(corrupt), func([]byte) error { return nil })
+	assert.ErrorContains(t, err, "checksum")
+}
@@ -12,6 +12,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"sync"
@@ -113,7 +114,8 @@ func NewVertexProvider(name string, config ProviderConfig) (*VertexProvider, err
 	}
 
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: newCaptureTransport("vertex"),
 	}
 
 	p := &VertexProvider{
@@ -332,12 +334,18 @@ func (p *VertexProvider) transformClaudeRequest(request *ChatRequest) ([]byte, e
 
 	// Extract system message if present
 	var systemMsg string
+	var system interface{}
 	messages := []map[string]interface{}{}
 
-	for _, msg := range request.Messages {
+	for i, msg := range request.Messages {
+		breakpoint := i == request.CachedPrefix-1
 		if msg.Role == "system" {
 			if content, ok := msg.Content.(string); ok {
 				systemMsg = content
+				system = content
+				if breakpoint {
+					system = []AnthropicContent{{Type: "text", Text: content, CacheControl: &AnthropicCacheControl{Type: "ephemeral"}}}
+				}
 			}
 			continue
 		}
@@ -349,6 +357,10 @@ func (p *VertexProvider) transformClaudeRequest(request *ChatRequest) ([]byte, e
 		// Handle content
 		if content, ok := msg.Content.(string); ok {
 			claudeMsg["content"] = content
+			if breakpoint {
+				// The end of a gateway-managed cached prefix is a cache breakpoint
+				claudeMsg["content"] = []AnthropicContent{{Type: "text", Text: content, CacheControl: &AnthropicCacheControl{Type: "ephemeral"}}}
+			}
 		} else if contentArray, ok := msg.Content.([]interface{}); ok {
 			claudeMsg["content"] = contentArray
 		}
@@ -357,7 +369,7 @@ func (p *VertexProvider) transformClaudeRequest(request *ChatRequest) ([]byte, e
 	}
 
 	if systemMsg != "" {
-		claudeReq["system"] = systemMsg
+		claudeReq["system"] = system
 	}
 	claudeReq["messages"] = messages
 
@@ -380,15 +392,55 @@ func (p *VertexProvider) transformClaudeRequest(request *ChatRequest) ([]byte, e
 		claudeReq["stop_sequences"] = request.Stop
 	}
 
+	if request.Stream {
+		claudeReq["stream"] = true
+	}
+
 	return json.Marshal(claudeReq)
 }
 
 // transformGeminiRequest transforms request for Gemini models
 func (p *VertexProvider) transformGeminiRequest(request *ChatRequest) ([]byte, error) {
-	// Convert OpenAI messages to Gemini format
+	geminiReq := map[string]interface{}{
+		"contents": geminiContents(request.Messages),
+	}
+
+	// Context cached through CreateCachedContent is referenced by name
+	if request.CachedContent != "" {
+		geminiReq["cachedContent"] = request.CachedContent
+	}
+
+	// Add generation config
+	genConfig := map[string]interface{}{}
+
+	if request.Temperature != nil {
+		genConfig["temperature"] = *request.Temperature
+	}
+
+	if request.TopP != nil {
+		genConfig["topP"] = *request.TopP
+	}
+
+	if request.MaxTokens != nil {
+		genConfig["maxOutputTokens"] = *request.MaxTokens
+	}
+
+	if len(request.Stop) > 0 {
+		genConfig["stopSequences"] = request.Stop
+	}
+
+	if len(genConfig) > 0 {
+		geminiReq["generationConfig"] = genConfig
+	}
+
+	return json.Marshal(geminiReq)
+}
+
+// geminiContents converts OpenAI messages to Gemini contents
+func geminiContents(messages []Message) []map[string]interface{} {
 	contents := make([]map[string]interface{}, 0)
 	
-	for _, msg := range request.Messages {
+	for _, msg := range messages {
 		if msg.Role == "system" {
 			// System messages in Gemini are handled differently
 			// We'll add them as user messages with special formatting
@@ -465,35 +517,7 @@ func (p *VertexProvider) transformGeminiRequest(request *ChatRequest) ([]byte, e
 		}
 		contents = append(contents, geminiContent)
 	}
-
-	geminiReq := map[string]interface{}{
-		"contents": contents,
-	}
-
-	// Add generation config
-	genConfig := map[string]interface{}{}
-
-	if request.Temperature != nil {
-		genConfig["temperature"] = *request.Temperature
-	}
-
-	if request.TopP != nil {
-		genConfig["topP"] = *request.TopP
-	}
-
-	if request.MaxTokens != nil {
-		genConfig["maxOutputTokens"] = *request.MaxTokens
-	}
-
-	if len(request.Stop) > 0 {
-		genConfig["stopSequences"] = request.Stop
-	}
-
-	if len(genConfig) > 0 {
-		geminiReq["generationConfig"] = genConfig
-	}
-
-	return json.Marshal(geminiReq)
+	return contents
 }
 
 // parseClaudeResponse parses Claude model response
@@ -503,11 +527,8 @@ func (p *VertexProvider) parseClaudeResponse(body io.Reader, model string) (*Cha
 			Type string `json:"type"`
 			Text string `json:"text"`
 		} `json:"content"`
-		StopReason string `json:"stop_reason"`
-		Usage      struct {
-			InputTokens  int `json:"input_tokens"`
-			OutputTokens int `json:"output_tokens"`
-		} `json:"usage"`
+		StopReason string         `json:"stop_reason"`
+		Usage      AnthropicUsage `json:"usage"`
 	}
 
 	if err := json.NewDecoder(body).Decode(&claudeResp); err != nil {
@@ -537,11 +558,7 @@ func (p *VertexProvider) parseClaudeResponse(body io.Reader, model string) (*Cha
 				FinishReason: p.mapStopReason(claudeResp.StopReason),
 			},
 		},
-		Usage: Usage{
-			PromptTokens:     claudeResp.Usage.InputTokens,
-			CompletionTokens: claudeResp.Usage.OutputTokens,
-			TotalTokens:      claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens,
-		},
+		Usage: claudeResp.Usage.ToUsage(),
 	}, nil
 }
 
@@ -557,9 +574,10 @@ func (p *VertexProvider) parseGeminiResponse(body io.Reader, model string) (*Cha
 			FinishReason string `json:"finishReason"`
 		} `json:"candidates"`
 		UsageMetadata struct {
-			PromptTokenCount     int `json:"promptTokenCount"`
-			CandidatesTokenCount int `json:"candidatesTokenCount"`
-			TotalTokenCount      int `json:"totalTokenCount"`
+			PromptTokenCount        int `json:"promptTokenCount"`
+			CandidatesTokenCount    int `json:"candidatesTokenCount"`
+			TotalTokenCount         int `json:"totalTokenCount"`
+			CachedContentTokenCount int `json:"cachedContentTokenCount"`
 		} `json:"usageMetadata"`
 	}
 
@@ -577,7 +595,16 @@ func (p *VertexProvider) parseGeminiResponse(body io.Reader, model string) (*Cha
 
 	finishReason := ""
 	if len(geminiResp.Candidates) > 0 {
-		finishReason = p.mapGeminiFinishReason(geminiResp.Candidates[0].FinishReason)
+		finishReason = geminiFinishReason(geminiResp.Candidates[0].FinishReason)
+	}
+
+	usage := Usage{
+		PromptTokens:     geminiResp.UsageMetadata.PromptTokenCount,
+		CompletionTokens: geminiResp.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      geminiResp.UsageMetadata.TotalTokenCount,
+	}
+	if cached := geminiResp.UsageMetadata.CachedContentTokenCount; cached > 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{CachedTokens: cached}
 	}
 
 	return &ChatResponse{
@@ -595,11 +622,7 @@ func (p *VertexProvider) parseGeminiResponse(body io.Reader, model string) (*Cha
 				FinishReason: finishReason,
 			},
 		},
-		Usage: Usage{
-			PromptTokens:     geminiResp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: geminiResp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      geminiResp.UsageMetadata.TotalTokenCount,
-		},
+		Usage: usage,
 	}, nil
 }
 
@@ -614,24 +637,73 @@ func (p *VertexProvider) mapStopReason(reason string) string {
 	}
 }
 
-func (p *VertexProvider) mapGeminiFinishReason(reason string) string {
-	switch reason {
-	case "STOP":
-		return "stop"
-	case "MAX_TOKENS":
-		return "length"
-	case "SAFETY":
-		return "content_filter"
-	default:
-		return reason
-	}
-}
-
 // ChatCompletionStream implements streaming for Provider interface
 func (p *VertexProvider) ChatCompletionStream(ctx context.Context, request *ChatRequest) (<-chan StreamResponse, error) {
-	// Streaming implementation would be similar to ChatCompletion
-	// but parsing SSE events. For now, return not implemented.
-	return nil, fmt.Errorf("streaming not yet implemented for Vertex AI provider")
+	streamChan := make(chan StreamResponse, 100)
+
+	go func() {
+		defer close(streamChan)
+
+		token, err := p.getAccessToken(ctx)
+		if err != nil {
+			log.Printf("Vertex AI stream failed: %v", err)
+			return
+		}
+
+		// Each model family streams in its native format
+		streamRequest := *request
+		streamRequest.Stream = true
+		var url string
+		var body []byte
+		var format StreamFormat
+
+		if strings.Contains(request.Model, "claude") {
+			url = fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/anthropic/models/%s:streamRawPredict",
+				p.region, p.projectID, p.region, request.Model)
+			body, err = p.transformClaudeRequest(&streamRequest)
+			format = StreamFormatAnthropic
+		} else if strings.Contains(request.Model, "gemini") {
+			url = fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/%s:streamGenerateContent?alt=sse",
+				p.region, p.projectID, p.region, request.Model)
+			body, err = p.transformGeminiRequest(&streamRequest)
+			format = StreamFormatGemini
+		} else {
+			url = fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/endpoints/openapi/chat/completions",
+				p.region, p.projectID, p.region)
+			body, err = json.Marshal(&streamRequest)
+			format = StreamFormatOpenAI
+		}
+		if err != nil {
+			return // Just close channel on error
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return // Just close channel on error
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			log.Printf("Vertex AI stream failed: %v", err)
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			log.Printf("Vertex AI stream failed: status %d, body: %s", resp.StatusCode, string(bodyBytes))
+			return
+		}
+
+		if err := NewStreamTranscoder(format, request.Model).Transcode(resp.Body, streamChan); err != nil {
+			log.Printf("Vertex AI stream failed: %v", err)
+		}
+	}()
+
+	return streamChan, nil
 }
 
 // Completion implements the Provider interface (legacy)
@@ -782,3 +854,110 @@ func (p *VertexProvider) ImageGeneration(ctx context.Context, request *ImageRequ
 	// For now, return not implemented - would require Imagen-specific request format
 	return nil, fmt.Errorf("image generation not yet implemented for Vertex AI - use OpenAI DALL-E instead")
 }
+
+// locationURL returns the API root for the provider's project and region
+func (p *VertexProvider) locationURL() string {
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s",
+		p.region, p.projectID, p.region)
+}
+
+// vertexCachedContent is a Vertex AI cachedContents resource
+type vertexCachedContent struct {
+	Name          string    `json:"name"`
+	ExpireTime    time.Time `json:"expireTime"`
+	UsageMetadata struct {
+		TotalTokenCount int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// CreateCachedContent stores the messages as a Gemini cachedContents resource.
+// Claude models on Vertex use prompt caching instead and are not supported.
+func (p *VertexProvider) CreateCachedContent(ctx context.Context, request *CachedContentRequest) (*CachedContent, error) {
+	if !strings.Contains(request.Model, "gemini") {
+		return nil, ErrContextCachingUnsupported
+	}
+
+	body := map[string]interface{}{
+		"model":    fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s", p.projectID, p.region, request.Model),
+		"contents": geminiContents(request.Messages),
+		"ttl":      formatVertexDuration(request.TTL),
+	}
+	if request.DisplayName != "" {
+		body["displayName"] = request.DisplayName
+	}
+	return p.cachedContentCall(ctx, http.MethodPost, p.locationURL()+"/cachedContents", body)
+}
+
+// UpdateCachedContentTTL sets the cache to expire ttl from now
+func (p *VertexProvider) UpdateCachedContentTTL(ctx context.Context, name string, ttl time.Duration) (*CachedContent, error) {
+	body := map[string]interface{}{
+		"ttl": formatVertexDuration(ttl),
+	}
+	return p.cachedContentCall(ctx, http.MethodPatch, p.resourceURL(name)+"?updateMask=ttl", body)
+}
+
+// DeleteCachedContent deletes the cache. Deleting an expired cache succeeds.
+func (p *VertexProvider) DeleteCachedContent(ctx context.Context, name string) error {
+	_, err := p.cachedContentCall(ctx, http.MethodDelete, p.resourceURL(name), nil)
+	return err
+}
+
+// resourceURL returns the URL of a resource given its full name
+func (p *VertexProvider) resourceURL(name string) string {
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/%s", p.region, name)
+}
+
+func (p *VertexProvider) cachedContentCall(ctx context.Context, method, url string, body interface{}) (*CachedContent, error) {
+	token, err := p.getAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("vertex AI API error: status %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+	if method == http.MethodDelete {
+		return nil, nil
+	}
+
+	var cached vertexCachedContent
+	if err := json.NewDecoder(resp.Body).Decode(&cached); err != nil {
+		return nil, err
+	}
+	return &CachedContent{
+		Name:       cached.Name,
+		TokenCount: cached.UsageMetadata.TotalTokenCount,
+		ExpiresAt:  cached.ExpireTime,
+	}, nil
+}
+
+// formatVertexDuration formats a duration as a protobuf JSON duration ("3600s")
+func formatVertexDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
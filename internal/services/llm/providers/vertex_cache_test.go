@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rewriteTransport sends every request to a test server
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestVertexProvider(t *testing.T, handler http.HandlerFunc) *VertexProvider {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	return &VertexProvider{
+		name:      "vertex-test",
+		client:    &http.Client{Transport: &rewriteTransport{target: target}},
+		projectID: "test-project",
+		region:    "us-central1",
+		token:     "test-token",
+		tokenExp:  time.Now().Add(time.Hour),
+	}
+}
+
+func TestVertexProvider_CachedContents(t *testing.T) {
+	expires := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	const resource = "projects/test-project/locations/us-central1/cachedContents/123"
+
+	var got struct {
+		method, path, query string
+		body                map[string]interface{}
+	}
+	p := newTestVertexProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		got.method, got.path, got.query = r.Method, r.URL.Path, r.URL.RawQuery
+		got.body = nil
+		_ = json.NewDecoder(r.Body).Decode(&got.body)
+
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"name":          resource,
+			"expireTime":    expires,
+			"usageMetadata": map[string]int{"totalTokenCount": 4096},
+		})
+	})
+	ctx := context.Background()
+
+	t.Run("create", func(t *testing.T) {
+		cached, err := p.CreateCachedContent(ctx, &CachedContentRequest{
+			Model:       "gemini-1.5-pro",
+			DisplayName: "docs",
+			Messages:    []Message{{Role: "user", Content: "The manual"}},
+			TTL:         time.Hour,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, http.MethodPost, got.method)
+		assert.Equal(t, "/v1/projects/test-project/locations/us-central1/cachedContents", got.path)
+		assert.Equal(t, "projects/test-project/locations/us-central1/publishers/google/models/gemini-1.5-pro", got.body["model"])
+		assert.Equal(t, "3600s", got.body["ttl"])
+		assert.Equal(t, "docs", got.body["displayName"])
+
+		assert.Equal(t, resource, cached.Name)
+		assert.Equal(t, 4096, cached.TokenCount)
+		assert.True(t, expires.Equal(cached.ExpiresAt))
+	})
+
+	t.Run("update ttl", func(t *testing.T) {
+		_, err := p.UpdateCachedContentTTL(ctx, resource, 2*time.Hour)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.MethodPatch, got.method)
+		assert.Equal(t, "/v1/"+resource, got.path)
+		assert.Equal(t, "updateMask=ttl", got.query)
+		assert.Equal(t, "7200s", got.body["ttl"])
+	})
+
+	t.Run("delete missing cache", func(t *testing.T) {
+		require.NoError(t, p.DeleteCachedContent(ctx, resource))
+		assert.Equal(t, http.MethodDelete, got.method)
+	})
+
+	t.Run("claude unsupported", func(t *testing.T) {
+		_, err := p.CreateCachedContent(ctx, &CachedContentRequest{Model: "claude-3-5-sonnet@20240620"})
+		assert.ErrorIs(t, err, ErrContextCachingUnsupported)
+	})
+}
+
+func TestVertexProvider_CachedContentReference(t *testing.T) {
+	p := &VertexProvider{}
+
+	t.Run("gemini references the cache", func(t *testing.T) {
+		data, err := p.transformGeminiRequest(&ChatRequest{
+			Model:         "gemini-1.5-pro",
+			Messages:      []Message{{Role: "user", Content: "Question"}},
+			CachedContent: "projects/p/locations/l/cachedContents/1",
+		})
+		require.NoError(t, err)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &body))
+		assert.Equal(t, "projects/p/locations/l/cachedContents/1", body["cachedContent"])
+	})
+
+	t.Run("claude marks the cached prefix", func(t *testing.T) {
+		data, err := p.transformClaudeRequest(&ChatRequest{
+			Model: "claude-3-5-sonnet@20240620",
+			Messages: []Message{
+				{Role: "user", Content: "The manual"},
+				{Role: "assistant", Content: "Read it"},
+				{Role: "user", Content: "Question"},
+			},
+			CachedPrefix: 2,
+		})
+		require.NoError(t, err)
+
+		var body struct {
+			Messages []struct {
+				Content interface{} `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.Unmarshal(data, &body))
+		require.Len(t, body.Messages, 3)
+		assert.Equal(t, "ephemeral", cacheControlType(body.Messages[1].Content))
+		assert.Empty(t, cacheControlType(body.Messages[0].Content))
+		assert.Empty(t, cacheControlType(body.Messages[2].Content))
+	})
+}
+
+// cacheControlType returns the cache_control type of a message's last block
+func cacheControlType(content interface{}) string {
+	blocks, ok := content.([]interface{})
+	if !ok || len(blocks) == 0 {
+		return ""
+	}
+	last, _ := blocks[len(blocks)-1].(map[string]interface{})
+	control, _ := last["cache_control"].(map[string]interface{})
+	typ, _ := control["type"].(string)
+	return typ
+}
@@ -0,0 +1,505 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	watsonxDefaultIAMURL     = "https://iam.cloud.ibm.com/identity/token"
+	watsonxDefaultAPIVersion = "2024-05-31"
+
+	// watsonxTokenRefreshMargin is how long before expiry an IAM token is
+	// refreshed, so that requests in flight never carry an expired token
+	watsonxTokenRefreshMargin = 5 * time.Minute
+)
+
+// WatsonxProvider serves foundation models from IBM watsonx.ai. Requests are
+// authenticated with an IAM access token exchanged for the API key and
+// refreshed before it expires.
+type WatsonxProvider struct {
+	*BaseProvider
+	apiKey     string
+	baseURL    string
+	iamURL     string
+	projectID  string
+	spaceID    string
+	apiVersion string
+	client     *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// watsonxChatRequest is the body of the watsonx.ai text chat API
+type watsonxChatRequest struct {
+	ModelID          string          `json:"model_id"`
+	ProjectID        string          `json:"project_id,omitempty"`
+	SpaceID          string          `json:"space_id,omitempty"`
+	Messages         []Message       `json:"messages"`
+	MaxTokens        *int            `json:"max_tokens,omitempty"`
+	Temperature      *float32        `json:"temperature,omitempty"`
+	TopP             *float32        `json:"top_p,omitempty"`
+	N                *int            `json:"n,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`
+	PresencePenalty  *float32        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32        `json:"frequency_penalty,omitempty"`
+	LogitBias        map[string]int  `json:"logit_bias,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	Tools            []Tool          `json:"tools,omitempty"`
+	ToolChoice       interface{}     `json:"tool_choice,omitempty"`
+	ToolChoiceOption string          `json:"tool_choice_option,omitempty"`
+}
+
+// watsonxChatChunk is a chat stream chunk; watsonx.ai names the model
+// model_id
+type watsonxChatChunk struct {
+	ID      string         `json:"id"`
+	ModelID string         `json:"model_id"`
+	Created int64          `json:"created"`
+	Choices []StreamChoice `json:"choices"`
+}
+
+type watsonxChatChoice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+type watsonxError struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	StatusCode int `json:"status_code"`
+}
+
+// NewWatsonxProvider creates a watsonx.ai provider. BaseURL is the regional
+// endpoint (e.g. https://us-south.ml.cloud.ibm.com) and APIKey an IBM Cloud
+// API key. Extra must set project_id or space_id and may set iam_url for
+// private or dedicated IAM endpoints.
+func NewWatsonxProvider(name string, cfg ProviderConfig) (*WatsonxProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("watsonx API key is required")
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("watsonx base URL is required")
+	}
+
+	p := &WatsonxProvider{
+		BaseProvider: NewBaseProvider(name, "watsonx", cfg.Priority, cfg.Models),
+		apiKey:       cfg.APIKey,
+		baseURL:      strings.TrimSuffix(cfg.BaseURL, "/"),
+		iamURL:       watsonxDefaultIAMURL,
+		apiVersion:   cfg.APIVersion,
+		client: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: newCaptureTransport("watsonx"),
+		},
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = cfg.Timeout
+	}
+	if p.apiVersion == "" {
+		p.apiVersion = watsonxDefaultAPIVersion
+	}
+	if cfg.Extra != nil {
+		if v, ok := cfg.Extra["project_id"].(string); ok {
+			p.projectID = v
+		}
+		if v, ok := cfg.Extra["space_id"].(string); ok {
+			p.spaceID = v
+		}
+		if v, ok := cfg.Extra["iam_url"].(string); ok && v != "" {
+			p.iamURL = v
+		}
+	}
+	if p.projectID == "" && p.spaceID == "" {
+		return nil, fmt.Errorf("watsonx project_id or space_id is required")
+	}
+
+	return p, nil
+}
+
+// accessToken returns a valid IAM access token, exchanging the API key for a
+// new one when the cached token is about to expire
+func (p *WatsonxProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Until(p.tokenExpiry) > watsonxTokenRefreshMargin {
+		return p.token, nil
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"},
+		"apikey":     {p.apiKey},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.iamURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create IAM token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("IAM token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read IAM token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("IAM token request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		Expiration  int64  `json:"expiration"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("failed to parse IAM token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("IAM token response has no access token")
+	}
+
+	p.token = token.AccessToken
+	switch {
+	case token.Expiration > 0:
+		p.tokenExpiry = time.Unix(token.Expiration, 0)
+	case token.ExpiresIn > 0:
+		p.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	default:
+		// IAM tokens are valid for an hour
+		p.tokenExpiry = time.Now().Add(time.Hour)
+	}
+	return p.token, nil
+}
+
+// newRequest creates an authenticated request to a watsonx.ai API path
+func (p *WatsonxProvider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := fmt.Sprintf("%s%s?version=%s", p.baseURL, path, url.QueryEscape(p.apiVersion))
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req, nil
+}
+
+// chatRequest converts an OpenAI chat request to the watsonx.ai format
+func (p *WatsonxProvider) chatRequest(request *ChatRequest) watsonxChatRequest {
+	body := watsonxChatRequest{
+		ModelID:          request.Model,
+		ProjectID:        p.projectID,
+		SpaceID:          p.spaceID,
+		Messages:         request.Messages,
+		MaxTokens:        request.MaxTokens,
+		Temperature:      request.Temperature,
+		TopP:             request.TopP,
+		N:                request.N,
+		Stop:             request.Stop,
+		PresencePenalty:  request.PresencePenalty,
+		FrequencyPenalty: request.FrequencyPenalty,
+		LogitBias:        request.LogitBias,
+		Seed:             request.Seed,
+		ResponseFormat:   request.ResponseFormat,
+		Tools:            request.Tools,
+	}
+	if body.ProjectID != "" {
+		body.SpaceID = ""
+	}
+	// watsonx.ai takes "auto" and "required" as tool_choice_option and a
+	// named function as tool_choice
+	switch choice := request.ToolChoice.(type) {
+	case string:
+		if choice != "" {
+			body.ToolChoiceOption = choice
+		}
+	case nil:
+	default:
+		body.ToolChoice = choice
+	}
+	return body
+}
+
+func (p *WatsonxProvider) ChatCompletion(ctx context.Context, request *ChatRequest) (*ChatResponse, error) {
+	reqBody, err := json.Marshal(p.chatRequest(request))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/ml/v1/text/chat", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, watsonxAPIError(resp.StatusCode, body)
+	}
+
+	var wxResp struct {
+		ID      string              `json:"id"`
+		ModelID string              `json:"model_id"`
+		Created int64               `json:"created"`
+		Choices []watsonxChatChoice `json:"choices"`
+		Usage   Usage               `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &wxResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	chatResp := &ChatResponse{
+		ID:      wxResp.ID,
+		Object:  "chat.completion",
+		Created: wxResp.Created,
+		Model:   wxResp.ModelID,
+		Usage:   wxResp.Usage,
+	}
+	if chatResp.Model == "" {
+		chatResp.Model = request.Model
+	}
+	for _, choice := range wxResp.Choices {
+		chatResp.Choices = append(chatResp.Choices, Choice{
+			Index:        choice.Index,
+			Message:      choice.Message,
+			FinishReason: choice.FinishReason,
+		})
+	}
+	return chatResp, nil
+}
+
+func (p *WatsonxProvider) ChatCompletionStream(ctx context.Context, request *ChatRequest) (<-chan StreamResponse, error) {
+	reqBody, err := json.Marshal(p.chatRequest(request))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/ml/v1/text/chat_stream", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, watsonxAPIError(resp.StatusCode, body)
+	}
+
+	streamChan := make(chan StreamResponse, 100)
+	go func() {
+		defer close(streamChan)
+		defer func() { _ = resp.Body.Close() }()
+		parseWatsonxStream(resp.Body, request.Model, streamChan)
+	}()
+
+	return streamChan, nil
+}
+
+// parseWatsonxStream converts the watsonx.ai chat stream, whose data lines
+// carry OpenAI-style chunks with model_id instead of model
+func parseWatsonxStream(body io.Reader, model string, streamChan chan<- StreamResponse) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var chunk watsonxChatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if chunk.ModelID != "" {
+			model = chunk.ModelID
+		}
+		streamChan <- StreamResponse{
+			ID:      chunk.ID,
+			Object:  "chat.completion.chunk",
+			Created: chunk.Created,
+			Model:   model,
+			Choices: chunk.Choices,
+		}
+	}
+}
+
+func (p *WatsonxProvider) Completion(ctx context.Context, request *CompletionRequest) (*CompletionResponse, error) {
+	return nil, fmt.Errorf("completion API not supported by watsonx provider")
+}
+
+func (p *WatsonxProvider) CompletionStream(ctx context.Context, request *CompletionRequest) (<-chan StreamResponse, error) {
+	return nil, fmt.Errorf("completion stream API not supported by watsonx provider")
+}
+
+func (p *WatsonxProvider) Embeddings(ctx context.Context, request *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	var inputs []string
+	switch input := request.Input.(type) {
+	case string:
+		inputs = []string{input}
+	case []string:
+		inputs = input
+	case []interface{}:
+		for _, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("watsonx embeddings only support text input")
+			}
+			inputs = append(inputs, text)
+		}
+	default:
+		return nil, fmt.Errorf("watsonx embeddings only support text input")
+	}
+
+	payload := map[string]interface{}{
+		"model_id": request.Model,
+		"inputs":   inputs,
+	}
+	if p.projectID != "" {
+		payload["project_id"] = p.projectID
+	} else {
+		payload["space_id"] = p.spaceID
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", "/ml/v1/text/embeddings", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, watsonxAPIError(resp.StatusCode, body)
+	}
+
+	var wxResp struct {
+		ModelID string `json:"model_id"`
+		Results []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"results"`
+		InputTokenCount int `json:"input_token_count"`
+	}
+	if err := json.Unmarshal(body, &wxResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	embResp := &EmbeddingsResponse{
+		Object: "list",
+		Model:  request.Model,
+		Usage: Usage{
+			PromptTokens: wxResp.InputTokenCount,
+			TotalTokens:  wxResp.InputTokenCount,
+		},
+	}
+	for i, result := range wxResp.Results {
+		embResp.Data = append(embResp.Data, Embedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: result.Embedding,
+		})
+	}
+	return embResp, nil
+}
+
+func (p *WatsonxProvider) AudioTranscription(ctx context.Context, request *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by watsonx provider")
+}
+
+func (p *WatsonxProvider) AudioSpeech(ctx context.Context, request *SpeechRequest) ([]byte, error) {
+	return nil, fmt.Errorf("audio speech not supported by watsonx provider")
+}
+
+func (p *WatsonxProvider) ImageGeneration(ctx context.Context, request *ImageRequest) (*ImageResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by watsonx provider")
+}
+
+// HealthCheck refreshes the IAM token and lists the foundation models, which
+// checks both the API key and the regional endpoint
+func (p *WatsonxProvider) HealthCheck(ctx context.Context) error {
+	req, err := p.newRequest(ctx, "GET", "/ml/v1/foundation_model_specs", nil)
+	if err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	req.URL.RawQuery += "&limit=1"
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		p.SetHealthy(false)
+		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+	}
+
+	p.SetHealthy(true)
+	return nil
+}
+
+// watsonxAPIError formats a watsonx.ai error response
+func watsonxAPIError(status int, body []byte) error {
+	var errResp watsonxError
+	if err := json.Unmarshal(body, &errResp); err != nil || len(errResp.Errors) == 0 {
+		return fmt.Errorf("request failed with status %d: %s", status, string(body))
+	}
+	return fmt.Errorf("watsonx API error (%s): %s", errResp.Errors[0].Code, errResp.Errors[0].Message)
+}
@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWatsonxTestServer(t *testing.T, expiresIn int, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	var tokens int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/identity/token" {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "urn:ibm:params:oauth:grant-type:apikey", r.Form.Get("grant_type"))
+			assert.Equal(t, "ibm-key", r.Form.Get("apikey"))
+			n := atomic.AddInt32(&tokens, 1)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": fmt.Sprintf("token-%d", n),
+				"expires_in":   expiresIn,
+			})
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &tokens
+}
+
+func newTestWatsonxProvider(t *testing.T, serverURL string) *WatsonxProvider {
+	provider, err := NewWatsonxProvider("watsonx-test", ProviderConfig{
+		Type:    "watsonx",
+		APIKey:  "ibm-key",
+		BaseURL: serverURL,
+		Models:  []string{"ibm/granite-3-8b-instruct"},
+		Extra: map[string]interface{}{
+			"project_id": "project-1",
+			"iam_url":    serverURL + "/identity/token",
+		},
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestNewWatsonxProvider_Validation(t *testing.T) {
+	_, err := NewWatsonxProvider("wx", ProviderConfig{BaseURL: "https://us-south.ml.cloud.ibm.com", Extra: map[string]interface{}{"project_id": "p"}})
+	assert.Error(t, err, "API key is required")
+
+	_, err = NewWatsonxProvider("wx", ProviderConfig{APIKey: "key", Extra: map[string]interface{}{"project_id": "p"}})
+	assert.Error(t, err, "base URL is required")
+
+	_, err = NewWatsonxProvider("wx", ProviderConfig{APIKey: "key", BaseURL: "https://us-south.ml.cloud.ibm.com"})
+	assert.Error(t, err, "project or space is required")
+
+	provider, err := NewWatsonxProvider("wx", ProviderConfig{APIKey: "key", BaseURL: "https://us-south.ml.cloud.ibm.com/", Extra: map[string]interface{}{"space_id": "s"}})
+	require.NoError(t, err)
+	assert.Equal(t, "watsonx", provider.GetType())
+	assert.Equal(t, "https://us-south.ml.cloud.ibm.com", provider.baseURL)
+	assert.Equal(t, watsonxDefaultIAMURL, provider.iamURL)
+	assert.Equal(t, watsonxDefaultAPIVersion, provider.apiVersion)
+}
+
+func TestWatsonxProvider_ChatCompletion(t *testing.T) {
+	server, tokens := newWatsonxTestServer(t, 3600, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ml/v1/text/chat", r.URL.Path)
+		assert.Equal(t, watsonxDefaultAPIVersion, r.URL.Query().Get("version"))
+		assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "ibm/granite-3-8b-instruct", body["model_id"])
+		assert.Equal(t, "project-1", body["project_id"])
+		assert.NotContains(t, body, "space_id")
+		assert.NotContains(t, body, "model")
+		assert.Equal(t, "required", body["tool_choice_option"])
+		assert.NotContains(t, body, "tool_choice")
+
+		_, _ = w.Write([]byte(`{
+			"id": "chat-1",
+			"model_id": "ibm/granite-3-8b-instruct",
+			"created": 1760000000,
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hello"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 1, "total_tokens": 6}
+		}`))
+	})
+	provider := newTestWatsonxProvider(t, server.URL)
+
+	request := &ChatRequest{
+		Model:      "ibm/granite-3-8b-instruct",
+		Messages:   []Message{{Role: "user", Content: "Hi"}},
+		ToolChoice: "required",
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := provider.ChatCompletion(context.Background(), request)
+		require.NoError(t, err)
+		assert.Equal(t, "chat.completion", resp.Object)
+		assert.Equal(t, "ibm/granite-3-8b-instruct", resp.Model)
+		require.Len(t, resp.Choices, 1)
+		assert.Equal(t, "Hello", resp.Choices[0].Message.Content)
+		assert.Equal(t, 6, resp.Usage.TotalTokens)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(tokens), "the IAM token is reused until it nears expiry")
+}
+
+func TestWatsonxProvider_RefreshesExpiringToken(t *testing.T) {
+	var seen []string
+	server, tokens := newWatsonxTestServer(t, 60, func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"resources": []}`))
+	})
+	provider := newTestWatsonxProvider(t, server.URL)
+
+	require.NoError(t, provider.HealthCheck(context.Background()))
+	require.NoError(t, provider.HealthCheck(context.Background()))
+	assert.True(t, provider.IsHealthy())
+	assert.Equal(t, int32(2), atomic.LoadInt32(tokens), "tokens within the refresh margin are renewed")
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, seen)
+}
+
+func TestWatsonxProvider_IAMFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errorCode": "BXNIM0415E", "errorMessage": "Provided API key could not be found"}`))
+	}))
+	defer server.Close()
+	provider := newTestWatsonxProvider(t, server.URL)
+
+	_, err := provider.ChatCompletion(context.Background(), &ChatRequest{Model: "ibm/granite-3-8b-instruct"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "IAM token request failed with status 400")
+
+	assert.Error(t, provider.HealthCheck(context.Background()))
+	assert.False(t, provider.IsHealthy())
+}
+
+func TestWatsonxProvider_ChatCompletionStream(t *testing.T) {
+	server, _ := newWatsonxTestServer(t, 3600, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ml/v1/text/chat_stream", r.URL.Path)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("id: 1\nevent: message\n" +
+			`data: {"id":"chat-1","model_id":"ibm/granite-3-8b-instruct","created":1760000000,"choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}` + "\n\n" +
+			"id: 2\nevent: message\n" +
+			`data: {"id":"chat-1","model_id":"ibm/granite-3-8b-instruct","created":1760000000,"choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}` + "\n\n"))
+	})
+	provider := newTestWatsonxProvider(t, server.URL)
+
+	stream, err := provider.ChatCompletionStream(context.Background(), &ChatRequest{
+		Model:    "ibm/granite-3-8b-instruct",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	var content strings.Builder
+	var chunks []StreamResponse
+	for chunk := range stream {
+		chunks = append(chunks, chunk)
+		content.WriteString(chunk.Choices[0].Delta.Content.(string))
+	}
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "Hello", content.String())
+	assert.Equal(t, "chat.completion.chunk", chunks[0].Object)
+	assert.Equal(t, "ibm/granite-3-8b-instruct", chunks[0].Model)
+	assert.Equal(t, "stop", chunks[1].Choices[0].FinishReason)
+}
+
+func TestWatsonxProvider_Embeddings(t *testing.T) {
+	server, _ := newWatsonxTestServer(t, 3600, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ml/v1/text/embeddings", r.URL.Path)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, []interface{}{"a", "b"}, body["inputs"])
+		assert.Equal(t, "project-1", body["project_id"])
+
+		_, _ = w.Write([]byte(`{"model_id":"ibm/slate-30m-english-rtrvr","results":[{"embedding":[0.1,0.2]},{"embedding":[0.3,0.4]}],"input_token_count":4}`))
+	})
+	provider := newTestWatsonxProvider(t, server.URL)
+
+	resp, err := provider.Embeddings(context.Background(), &EmbeddingsRequest{
+		Model: "ibm/slate-30m-english-rtrvr",
+		Input: []interface{}{"a", "b"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, 1, resp.Data[1].Index)
+	assert.Equal(t, []float32{0.3, 0.4}, resp.Data[1].Embedding)
+	assert.Equal(t, 4, resp.Usage.PromptTokens)
+
+	_, err = provider.Embeddings(context.Background(), &EmbeddingsRequest{Model: "m", Input: []interface{}{1, 2}})
+	assert.Error(t, err, "token arrays are not supported")
+}
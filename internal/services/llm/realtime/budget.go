@@ -0,0 +1,383 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// Metering defaults
+const (
+	defaultBudgetCheckInterval = time.Second
+	defaultFlushInterval       = 10 * time.Second
+)
+
+var (
+	realtimeSpend = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pllm_realtime_spend_usd_total",
+			Help: "Spend of realtime sessions in USD, as recorded in usage records",
+		},
+		[]string{"model"},
+	)
+
+	realtimeBudgetTerminations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pllm_realtime_budget_terminations_total",
+			Help: "Total number of realtime sessions closed because a key or team budget ran out",
+		},
+		[]string{"model", "entity_type"},
+	)
+)
+
+// Owner identifies who a session is billed to, as in the usage records of
+// regular requests
+type Owner struct {
+	KeyID        string
+	KeyType      string
+	KeyOwnerID   string
+	TeamID       string
+	UserID       string
+	ActualUserID string
+	Environment  string
+
+	// Client location and library, for the geo and client analytics
+	Country       string
+	Continent     string
+	ClientName    string
+	ClientVersion string
+
+	// Unlimited skips budget checks; the master key's usage is still recorded
+	Unlimited bool
+}
+
+// budgetEntity is a cached budget a session is checked against
+type budgetEntity struct {
+	entityType string
+	entityID   string
+}
+
+// budgetEntities returns the budgets that cap the owner's spend: the key's
+// and its team's, or the user's for sessions opened without a key
+func (o Owner) budgetEntities() []budgetEntity {
+	var entities []budgetEntity
+	if o.KeyID != "" {
+		entities = append(entities, budgetEntity{"key", o.KeyID})
+		if o.TeamID != "" {
+			entities = append(entities, budgetEntity{"team", o.TeamID})
+		}
+	} else if o.UserID != "" {
+		entities = append(entities, budgetEntity{"user", o.UserID})
+	}
+	return entities
+}
+
+// spendEntity returns the entity whose cached spend is incremented, matching
+// the budget middleware
+func (o Owner) spendEntity() budgetEntity {
+	switch {
+	case o.Unlimited:
+		return budgetEntity{"master_key", ""}
+	case o.KeyID != "":
+		return budgetEntity{"key", o.KeyID}
+	default:
+		return budgetEntity{"user", o.UserID}
+	}
+}
+
+// MeteredSession is a realtime session billed while it runs
+type MeteredSession struct {
+	ID            string
+	Model         string
+	ProviderModel string
+	Provider      string
+	Path          string
+	Owner         Owner
+	Meter         *Meter
+
+	mu           sync.Mutex
+	flushed      Usage
+	flushedAt    time.Time
+	flushes      []flushedSpend
+	segment      int
+	recordedCost float64
+}
+
+// flushedSpend is spend written as a usage record, which cached budgets only
+// reflect once the usage worker has processed it
+type flushedSpend struct {
+	at   time.Time
+	cost float64
+}
+
+// RecordedCost returns the spend recorded for the session so far
+func (s *MeteredSession) RecordedCost() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recordedCost
+}
+
+// BudgetEnforcerConfig holds the dependencies of a BudgetEnforcer
+type BudgetEnforcerConfig struct {
+	Logger         *zap.Logger
+	BudgetCache    redisService.BudgetCacheBackend
+	UsageQueue     redisService.UsageQueueBackend
+	PricingManager *config.ModelPricingManager
+	MarkupPercent  float64
+	Metering       config.RealtimeMeteringConfig
+}
+
+// BudgetEnforcer bills realtime sessions while they run: spend is written
+// as usage records every flush interval and checked against the cached key
+// and team budgets every check interval, so a session is closed soon after
+// its budget runs out rather than when it ends
+type BudgetEnforcer struct {
+	logger         *zap.Logger
+	budgetCache    redisService.BudgetCacheBackend
+	usageQueue     redisService.UsageQueueBackend
+	pricingManager *config.ModelPricingManager
+	markupPercent  float64
+	checkInterval  time.Duration
+	flushInterval  time.Duration
+}
+
+// NewBudgetEnforcer creates a new realtime budget enforcer
+func NewBudgetEnforcer(cfg *BudgetEnforcerConfig) *BudgetEnforcer {
+	e := &BudgetEnforcer{
+		logger:         cfg.Logger,
+		budgetCache:    cfg.BudgetCache,
+		usageQueue:     cfg.UsageQueue,
+		pricingManager: cfg.PricingManager,
+		markupPercent:  cfg.MarkupPercent,
+		checkInterval:  cfg.Metering.BudgetCheckInterval,
+		flushInterval:  cfg.Metering.FlushInterval,
+	}
+	if e.checkInterval <= 0 {
+		e.checkInterval = defaultBudgetCheckInterval
+	}
+	if e.flushInterval <= 0 {
+		e.flushInterval = defaultFlushInterval
+	}
+	return e
+}
+
+// Watch bills the session until ctx is done, then records its remaining
+// usage. When the owner's budget runs out, terminate is called with the
+// exhausted entity type ("key", "team" or "user"); it is expected to close
+// the session and cancel ctx.
+func (e *BudgetEnforcer) Watch(ctx context.Context, session *MeteredSession, terminate func(entityType string)) {
+	defer func() { e.flush(session, time.Now()) }()
+
+	ticker := time.NewTicker(e.checkInterval)
+	defer ticker.Stop()
+
+	session.mu.Lock()
+	session.flushedAt = time.Now()
+	session.mu.Unlock()
+
+	terminated := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			session.mu.Lock()
+			due := now.Sub(session.flushedAt) >= e.flushInterval
+			session.mu.Unlock()
+			if due {
+				e.flush(session, now)
+			}
+			if terminated {
+				continue
+			}
+			if entityType, exceeded := e.exceeded(ctx, session); exceeded {
+				terminated = true
+				realtimeBudgetTerminations.WithLabelValues(session.Model, entityType).Inc()
+				e.logger.Warn("Closing realtime session, budget exceeded",
+					zap.String("session_id", session.ID),
+					zap.String("model", session.Model),
+					zap.String("entity_type", entityType),
+					zap.Float64("session_cost", session.RecordedCost()+e.unflushedCost(session)))
+				e.flush(session, now)
+				terminate(entityType)
+			}
+		}
+	}
+}
+
+// CheckBudget reports whether the owner may open a session, and otherwise
+// which budget is exhausted
+func (e *BudgetEnforcer) CheckBudget(ctx context.Context, owner Owner) (string, bool) {
+	entityType, exceeded := e.exceededBudget(ctx, owner, func(time.Time) float64 { return 0 })
+	return entityType, !exceeded
+}
+
+// exceeded checks the owner's cached budgets. Spend a budget does not yet
+// reflect (recorded after its last update, or not recorded yet) is counted
+// against it.
+func (e *BudgetEnforcer) exceeded(ctx context.Context, session *MeteredSession) (string, bool) {
+	unflushed := e.unflushedCost(session)
+	return e.exceededBudget(ctx, session.Owner, func(lastUpdated time.Time) float64 {
+		return unflushed + session.spendSince(lastUpdated)
+	})
+}
+
+// exceededBudget returns the first of the owner's budgets that pending spend
+// exhausts. Budgets missing from the cache allow the session, as they do
+// regular requests.
+func (e *BudgetEnforcer) exceededBudget(ctx context.Context, owner Owner, pending func(lastUpdated time.Time) float64) (string, bool) {
+	if owner.Unlimited || e.budgetCache == nil {
+		return "", false
+	}
+
+	for _, entity := range owner.budgetEntities() {
+		status, err := e.budgetCache.GetBudgetStats(ctx, entity.entityType, entity.entityID)
+		if err != nil {
+			e.logger.Warn("Realtime budget check failed, allowing session",
+				zap.String("entity", fmt.Sprintf("%s:%s", entity.entityType, entity.entityID)),
+				zap.Error(err))
+			continue
+		}
+		if status == nil {
+			continue
+		}
+		if status.IsExceeded || status.Available-pending(status.LastUpdated) < 0 {
+			return entity.entityType, true
+		}
+	}
+	return "", false
+}
+
+// spendSince returns the spend recorded after t
+func (s *MeteredSession) spendSince(t time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cost float64
+	for _, spend := range s.flushes {
+		if spend.at.After(t) {
+			cost += spend.cost
+		}
+	}
+	return cost
+}
+
+// unflushedCost prices the usage not yet written as a usage record
+func (e *BudgetEnforcer) unflushedCost(session *MeteredSession) float64 {
+	session.mu.Lock()
+	delta := session.Meter.Usage().Sub(session.flushed)
+	session.mu.Unlock()
+	if delta.IsZero() {
+		return 0
+	}
+	if breakdown := e.price(session, delta); breakdown != nil {
+		return breakdown.TotalCost
+	}
+	return 0
+}
+
+// price computes the cost of usage, or nil when the model has no pricing
+func (e *BudgetEnforcer) price(session *MeteredSession, usage Usage) *config.CostBreakdown {
+	if e.pricingManager == nil {
+		return nil
+	}
+	model := session.ProviderModel
+	pricing := e.pricingManager.GetPricing(model)
+	if pricing == nil {
+		model = session.Model
+		pricing = e.pricingManager.GetPricing(model)
+	}
+	if pricing == nil {
+		return nil
+	}
+	return config.NewCostBreakdown(model, pricing, config.CostUsage{
+		InputTokens:        usage.InputTextTokens,
+		OutputTokens:       usage.OutputTextTokens,
+		AudioSeconds:       usage.InputAudioSeconds,
+		OutputAudioSeconds: usage.OutputAudioSeconds,
+	}, e.markupPercent)
+}
+
+// flush writes the usage since the last flush as a usage record. Each flush
+// is a segment of the session with its own request ID; a failed enqueue
+// leaves the usage for the next flush.
+func (e *BudgetEnforcer) flush(session *MeteredSession, now time.Time) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	usage := session.Meter.Usage()
+	delta := usage.Sub(session.flushed)
+	if delta.IsZero() {
+		return
+	}
+
+	var cost float64
+	breakdown := e.price(session, delta)
+	if breakdown != nil {
+		cost = breakdown.TotalCost
+	}
+
+	record := &redisService.UsageRecord{
+		RequestID:          fmt.Sprintf("rt_%s_%d", session.ID, session.segment),
+		Timestamp:          session.flushedAt,
+		Model:              session.Model,
+		Provider:           session.Provider,
+		ProviderModel:      session.ProviderModel,
+		EndpointType:       models.EndpointRealtime,
+		Method:             "GET",
+		Path:               session.Path,
+		StatusCode:         101, // Switching Protocols
+		InputTokens:        delta.InputTextTokens,
+		OutputTokens:       delta.OutputTextTokens,
+		TotalTokens:        delta.InputTextTokens + delta.OutputTextTokens,
+		TotalCost:          cost,
+		CostBreakdown:      breakdown,
+		AudioSeconds:       delta.InputAudioSeconds,
+		OutputAudioSeconds: delta.OutputAudioSeconds,
+		Latency:            now.Sub(session.flushedAt).Milliseconds(),
+		KeyID:              session.Owner.KeyID,
+		KeyType:            session.Owner.KeyType,
+		KeyOwnerID:         session.Owner.KeyOwnerID,
+		TeamID:             session.Owner.TeamID,
+		Environment:        session.Owner.Environment,
+		UserID:             session.Owner.UserID,
+		ActualUserID:       session.Owner.ActualUserID,
+		Country:            session.Owner.Country,
+		Continent:          session.Owner.Continent,
+		ClientName:         session.Owner.ClientName,
+		ClientVersion:      session.Owner.ClientVersion,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.usageQueue.EnqueueUsage(ctx, record); err != nil {
+		e.logger.Error("Failed to enqueue realtime usage record",
+			zap.String("session_id", session.ID),
+			zap.Error(err))
+		return
+	}
+
+	session.flushed = usage
+	session.flushedAt = now
+	session.flushes = append(session.flushes, flushedSpend{at: now, cost: cost})
+	session.segment++
+	session.recordedCost += cost
+	realtimeSpend.WithLabelValues(session.Model).Add(cost)
+
+	if e.budgetCache != nil && cost > 0 {
+		entity := session.Owner.spendEntity()
+		if err := e.budgetCache.IncrementSpent(ctx, entity.entityType, entity.entityID, cost); err != nil {
+			e.logger.Error("Failed to increment cached budget spent",
+				zap.String("session_id", session.ID),
+				zap.Error(err))
+		}
+	}
+}
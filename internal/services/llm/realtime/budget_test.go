@@ -0,0 +1,253 @@
+package realtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// testBudgetCache serves fixed budget statuses and counts spend increments
+type testBudgetCache struct {
+	redisService.BudgetCacheBackend
+	mu       sync.Mutex
+	statuses map[string]*redisService.BudgetStatus
+	spent    map[string]float64
+}
+
+func newTestBudgetCache() *testBudgetCache {
+	return &testBudgetCache{
+		statuses: make(map[string]*redisService.BudgetStatus),
+		spent:    make(map[string]float64),
+	}
+}
+
+func (c *testBudgetCache) GetBudgetStats(ctx context.Context, entityType, entityID string) (*redisService.BudgetStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.statuses[entityType+":"+entityID], nil
+}
+
+func (c *testBudgetCache) IncrementSpent(ctx context.Context, entityType, entityID string, amount float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spent[entityType+":"+entityID] += amount
+	return nil
+}
+
+// testUsageQueue collects enqueued usage records
+type testUsageQueue struct {
+	redisService.UsageQueueBackend
+	mu      sync.Mutex
+	records []*redisService.UsageRecord
+}
+
+func (q *testUsageQueue) EnqueueUsage(ctx context.Context, record *redisService.UsageRecord) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.records = append(q.records, record)
+	return nil
+}
+
+func (q *testUsageQueue) Records() []*redisService.UsageRecord {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*redisService.UsageRecord(nil), q.records...)
+}
+
+func newTestBudgetEnforcer(cache *testBudgetCache, queue *testUsageQueue, flushInterval time.Duration) *BudgetEnforcer {
+	// $0.06 per minute of input audio, $0.24 per minute of output audio
+	config.GetPricingManager().RegisterModel("realtime-test-model", &config.ModelPricingInfo{
+		InputCostPerToken:   0.000005,
+		OutputCostPerToken:  0.00002,
+		InputCostPerSecond:  0.001,
+		OutputCostPerSecond: 0.004,
+	})
+	return NewBudgetEnforcer(&BudgetEnforcerConfig{
+		Logger:         zap.NewNop(),
+		BudgetCache:    cache,
+		UsageQueue:     queue,
+		PricingManager: config.GetPricingManager(),
+		Metering: config.RealtimeMeteringConfig{
+			BudgetCheckInterval: 5 * time.Millisecond,
+			FlushInterval:       flushInterval,
+		},
+	})
+}
+
+func newTestMeteredSession(owner Owner) *MeteredSession {
+	return &MeteredSession{
+		ID:            "session-1",
+		Model:         "realtime",
+		ProviderModel: "realtime-test-model",
+		Provider:      "openai",
+		Path:          "/v1/realtime",
+		Owner:         owner,
+		Meter:         NewMeter(nil),
+	}
+}
+
+func appendAudio(t *testing.T, meter *Meter, seconds int) {
+	meter.ObserveClientEvent(realtimeEvent(t, "input_audio_buffer.append",
+		&models.InputAudioBufferAppendEvent{Audio: encodedAudio(48000 * seconds)}))
+}
+
+func TestBudgetEnforcer_RecordsSessionSegments(t *testing.T) {
+	cache, queue := newTestBudgetCache(), &testUsageQueue{}
+	enforcer := newTestBudgetEnforcer(cache, queue, 20*time.Millisecond)
+	session := newTestMeteredSession(Owner{KeyID: "key-1", TeamID: "team-1", UserID: "user-1", KeyType: "api"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		enforcer.Watch(ctx, session, func(string) { t.Error("session terminated without a budget") })
+	}()
+
+	appendAudio(t, session.Meter, 10)
+	require.Eventually(t, func() bool { return len(queue.Records()) == 1 }, time.Second, 5*time.Millisecond)
+
+	session.Meter.ObserveProviderEvent(realtimeEvent(t, "response.audio.delta", &models.ResponseAudioDeltaEvent{Delta: encodedAudio(48000 * 5)}))
+	session.Meter.ObserveProviderEvent(realtimeEvent(t, "response.done", &models.ResponseDoneEvent{
+		Response: models.RealtimeResponseObject{Usage: &models.RealtimeUsage{
+			InputTokens:        100,
+			OutputTokens:       50,
+			InputTokenDetails:  &models.RealtimeInputTokenDetails{TextTokens: 100},
+			OutputTokenDetails: &models.RealtimeOutputTokenDetails{TextTokens: 50},
+		}},
+	}))
+	cancel()
+	<-done
+
+	records := queue.Records()
+	require.Len(t, records, 2, "the rest of the session is recorded when it ends")
+
+	first := records[0]
+	assert.Equal(t, "rt_session-1_0", first.RequestID)
+	assert.Equal(t, "/v1/realtime", first.Path)
+	assert.Equal(t, "key-1", first.KeyID)
+	assert.Equal(t, "team-1", first.TeamID)
+	assert.InDelta(t, 10.0, first.AudioSeconds, 1e-9)
+	assert.InDelta(t, 0.01, first.TotalCost, 1e-9)
+
+	second := records[1]
+	assert.Equal(t, "rt_session-1_1", second.RequestID)
+	assert.Zero(t, second.AudioSeconds)
+	assert.InDelta(t, 5.0, second.OutputAudioSeconds, 1e-9)
+	assert.Equal(t, 100, second.InputTokens)
+	assert.Equal(t, 50, second.OutputTokens)
+	assert.InDelta(t, 5*0.004+100*0.000005+50*0.00002, second.TotalCost, 1e-9)
+	require.NotNil(t, second.CostBreakdown)
+	assert.InDelta(t, 5*0.004+50*0.00002, second.CostBreakdown.OutputCost(), 1e-9)
+
+	assert.InDelta(t, first.TotalCost+second.TotalCost, session.RecordedCost(), 1e-9)
+	assert.InDelta(t, session.RecordedCost(), cache.spent["key:key-1"], 1e-9)
+}
+
+func TestBudgetEnforcer_TerminatesOverBudget(t *testing.T) {
+	tests := []struct {
+		name       string
+		entityType string
+		entityID   string
+	}{
+		{name: "key budget", entityType: "key", entityID: "key-1"},
+		{name: "team budget", entityType: "team", entityID: "team-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, queue := newTestBudgetCache(), &testUsageQueue{}
+			enforcer := newTestBudgetEnforcer(cache, queue, time.Hour)
+			owner := Owner{KeyID: "key-1", TeamID: "team-1"}
+
+			// $0.05 left: 50 seconds of input audio
+			cache.statuses[tt.entityType+":"+tt.entityID] = &redisService.BudgetStatus{Available: 0.05, Limit: 1, LastUpdated: time.Now()}
+			_, ok := enforcer.CheckBudget(context.Background(), owner)
+			require.True(t, ok)
+
+			session := newTestMeteredSession(owner)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			terminated := make(chan string, 1)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				enforcer.Watch(ctx, session, func(entityType string) {
+					terminated <- entityType
+					cancel()
+				})
+			}()
+
+			appendAudio(t, session.Meter, 40)
+			select {
+			case <-terminated:
+				t.Fatal("session terminated within budget")
+			case <-time.After(30 * time.Millisecond):
+			}
+
+			appendAudio(t, session.Meter, 20)
+			select {
+			case entityType := <-terminated:
+				assert.Equal(t, tt.entityType, entityType)
+			case <-time.After(time.Second):
+				t.Fatal("session was not terminated")
+			}
+			<-done
+
+			// The spend up to the termination is recorded
+			records := queue.Records()
+			require.Len(t, records, 1)
+			assert.InDelta(t, 60.0, records[0].AudioSeconds, 1e-9)
+		})
+	}
+}
+
+func TestBudgetEnforcer_CountsSpendBudgetsHaveNotSeen(t *testing.T) {
+	cache, queue := newTestBudgetCache(), &testUsageQueue{}
+	enforcer := newTestBudgetEnforcer(cache, queue, time.Hour)
+	session := newTestMeteredSession(Owner{KeyID: "key-1"})
+	updated := time.Now()
+	cache.statuses["key:key-1"] = &redisService.BudgetStatus{Available: 0.05, Limit: 1, LastUpdated: updated}
+
+	// 30 seconds recorded after the budget was cached, 30 not recorded yet
+	appendAudio(t, session.Meter, 30)
+	session.flushedAt = updated
+	enforcer.flush(session, updated.Add(time.Second))
+	appendAudio(t, session.Meter, 30)
+
+	entityType, exceeded := enforcer.exceeded(context.Background(), session)
+	assert.True(t, exceeded)
+	assert.Equal(t, "key", entityType)
+
+	// Once the budget reflects the recorded spend, only the rest is pending
+	cache.statuses["key:key-1"] = &redisService.BudgetStatus{Available: 0.05 - 0.03, Limit: 1, LastUpdated: updated.Add(2 * time.Second)}
+	_, exceeded = enforcer.exceeded(context.Background(), session)
+	assert.True(t, exceeded)
+	cache.statuses["key:key-1"] = &redisService.BudgetStatus{Available: 0.04, Limit: 1, LastUpdated: updated.Add(2 * time.Second)}
+	_, exceeded = enforcer.exceeded(context.Background(), session)
+	assert.False(t, exceeded)
+}
+
+func TestBudgetEnforcer_CheckBudget(t *testing.T) {
+	cache := newTestBudgetCache()
+	enforcer := newTestBudgetEnforcer(cache, &testUsageQueue{}, time.Hour)
+	cache.statuses["team:team-1"] = &redisService.BudgetStatus{Available: 0, Spent: 10, Limit: 10, IsExceeded: true}
+
+	entityType, ok := enforcer.CheckBudget(context.Background(), Owner{KeyID: "key-1", TeamID: "team-1"})
+	assert.False(t, ok)
+	assert.Equal(t, "team", entityType)
+
+	_, ok = enforcer.CheckBudget(context.Background(), Owner{KeyID: "key-2"})
+	assert.True(t, ok, "budgets missing from the cache allow the session")
+
+	_, ok = enforcer.CheckBudget(context.Background(), Owner{KeyID: "key-1", TeamID: "team-1", Unlimited: true})
+	assert.True(t, ok, "the master key has no budget")
+}
@@ -0,0 +1,155 @@
+package realtime
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// audioBytesPerSecond is the byte rate of each realtime audio format: pcm16
+// is 16-bit mono at 24kHz, the G.711 formats are 8-bit at 8kHz
+var audioBytesPerSecond = map[string]float64{
+	"pcm16":     48000,
+	"g711_ulaw": 8000,
+	"g711_alaw": 8000,
+}
+
+const defaultAudioFormat = "pcm16"
+
+// Usage is the billable usage of a realtime session
+type Usage struct {
+	InputAudioSeconds  float64
+	OutputAudioSeconds float64
+	InputTextTokens    int
+	OutputTextTokens   int
+}
+
+// Sub returns the usage added since earlier
+func (u Usage) Sub(earlier Usage) Usage {
+	return Usage{
+		InputAudioSeconds:  u.InputAudioSeconds - earlier.InputAudioSeconds,
+		OutputAudioSeconds: u.OutputAudioSeconds - earlier.OutputAudioSeconds,
+		InputTextTokens:    u.InputTextTokens - earlier.InputTextTokens,
+		OutputTextTokens:   u.OutputTextTokens - earlier.OutputTextTokens,
+	}
+}
+
+// IsZero reports whether nothing was used
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// Meter measures a session's usage from the events routed through it. Audio
+// is measured from the audio itself, as appended by the client and streamed
+// by the provider; text tokens come from the usage the provider reports with
+// each completed response.
+type Meter struct {
+	mu           sync.Mutex
+	usage        Usage
+	inputFormat  string
+	outputFormat string
+}
+
+// NewMeter creates a meter for a session with the given configuration
+func NewMeter(config *models.RealtimeSessionConfig) *Meter {
+	m := &Meter{inputFormat: defaultAudioFormat, outputFormat: defaultAudioFormat}
+	if config != nil {
+		m.setFormats(*config)
+	}
+	return m
+}
+
+// Usage returns the usage measured so far
+func (m *Meter) Usage() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage
+}
+
+// ObserveClientEvent meters an event sent by the client
+func (m *Meter) ObserveClientEvent(event *models.RealtimeEvent) {
+	switch event.Type {
+	case "session.update":
+		var update models.SessionUpdateEvent
+		if json.Unmarshal(event.Data, &update) == nil {
+			m.setFormats(update.Session)
+		}
+	case "input_audio_buffer.append":
+		var appended models.InputAudioBufferAppendEvent
+		if json.Unmarshal(event.Data, &appended) == nil {
+			m.mu.Lock()
+			m.usage.InputAudioSeconds += audioSeconds(appended.Audio, m.inputFormat)
+			m.mu.Unlock()
+		}
+	}
+}
+
+// ObserveProviderEvent meters an event sent by the provider
+func (m *Meter) ObserveProviderEvent(event *models.RealtimeEvent) {
+	switch event.Type {
+	case "session.created", "session.updated":
+		var updated models.SessionUpdatedEvent
+		if json.Unmarshal(event.Data, &updated) == nil {
+			m.setFormats(updated.Session)
+		}
+	case "response.audio.delta":
+		var delta models.ResponseAudioDeltaEvent
+		if json.Unmarshal(event.Data, &delta) == nil {
+			m.mu.Lock()
+			m.usage.OutputAudioSeconds += audioSeconds(delta.Delta, m.outputFormat)
+			m.mu.Unlock()
+		}
+	case "response.done":
+		var done models.ResponseDoneEvent
+		if json.Unmarshal(event.Data, &done) != nil || done.Response.Usage == nil {
+			return
+		}
+		input, output := textTokens(done.Response.Usage)
+		m.mu.Lock()
+		m.usage.InputTextTokens += input
+		m.usage.OutputTextTokens += output
+		m.mu.Unlock()
+	}
+}
+
+func (m *Meter) setFormats(config models.RealtimeSessionConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if config.InputAudioFormat != "" {
+		m.inputFormat = config.InputAudioFormat
+	}
+	if config.OutputAudioFormat != "" {
+		m.outputFormat = config.OutputAudioFormat
+	}
+}
+
+// textTokens returns the text tokens of a response. Audio is metered by
+// duration, so audio tokens are left out; without a breakdown every token
+// is billed as text.
+func textTokens(usage *models.RealtimeUsage) (input, output int) {
+	input, output = usage.InputTokens, usage.OutputTokens
+	if usage.InputTokenDetails != nil {
+		input = usage.InputTokenDetails.TextTokens
+	}
+	if usage.OutputTokenDetails != nil {
+		output = usage.OutputTokenDetails.TextTokens
+	}
+	return input, output
+}
+
+// audioSeconds returns the duration of base64 encoded audio
+func audioSeconds(encoded, format string) float64 {
+	rate, ok := audioBytesPerSecond[format]
+	if !ok {
+		rate = audioBytesPerSecond[defaultAudioFormat]
+	}
+	padding := len(encoded) - len(strings.TrimRight(encoded, "="))
+	size := base64.StdEncoding.DecodedLen(len(encoded)) - padding
+	if size <= 0 {
+		return 0
+	}
+	return float64(size) / rate
+}
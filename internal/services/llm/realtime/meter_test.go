@@ -0,0 +1,81 @@
+package realtime
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func realtimeEvent(t *testing.T, eventType string, data interface{}) *models.RealtimeEvent {
+	t.Helper()
+	event, err := models.NewRealtimeEvent(eventType, data)
+	require.NoError(t, err)
+	return event
+}
+
+// encodedAudio returns base64 audio of the given size in bytes
+func encodedAudio(size int) string {
+	return base64.StdEncoding.EncodeToString(make([]byte, size))
+}
+
+func TestMeter_MeasuresAudioAndTextTokens(t *testing.T) {
+	meter := NewMeter(&models.RealtimeSessionConfig{})
+
+	// One second of pcm16 in, half a second out
+	meter.ObserveClientEvent(realtimeEvent(t, "input_audio_buffer.append", &models.InputAudioBufferAppendEvent{Audio: encodedAudio(24000)}))
+	meter.ObserveClientEvent(realtimeEvent(t, "input_audio_buffer.append", &models.InputAudioBufferAppendEvent{Audio: encodedAudio(24001)}))
+	meter.ObserveProviderEvent(realtimeEvent(t, "response.audio.delta", &models.ResponseAudioDeltaEvent{Delta: encodedAudio(24000)}))
+	meter.ObserveProviderEvent(realtimeEvent(t, "response.done", &models.ResponseDoneEvent{
+		Response: models.RealtimeResponseObject{Usage: &models.RealtimeUsage{
+			InputTokens:        120,
+			OutputTokens:       80,
+			InputTokenDetails:  &models.RealtimeInputTokenDetails{TextTokens: 20, AudioTokens: 100},
+			OutputTokenDetails: &models.RealtimeOutputTokenDetails{TextTokens: 15, AudioTokens: 65},
+		}},
+	}))
+
+	usage := meter.Usage()
+	assert.InDelta(t, 48001.0/48000, usage.InputAudioSeconds, 1e-12)
+	assert.InDelta(t, 0.5, usage.OutputAudioSeconds, 1e-12)
+	assert.Equal(t, 20, usage.InputTextTokens, "audio tokens are billed by duration")
+	assert.Equal(t, 15, usage.OutputTextTokens)
+
+	// Events the meter does not bill leave the usage unchanged
+	meter.ObserveClientEvent(realtimeEvent(t, "input_audio_buffer.commit", &models.InputAudioBufferCommitEvent{}))
+	meter.ObserveProviderEvent(realtimeEvent(t, "response.done", &models.ResponseDoneEvent{}))
+	assert.Equal(t, usage, meter.Usage())
+}
+
+func TestMeter_FollowsAudioFormat(t *testing.T) {
+	meter := NewMeter(&models.RealtimeSessionConfig{InputAudioFormat: "g711_ulaw"})
+
+	meter.ObserveClientEvent(realtimeEvent(t, "input_audio_buffer.append", &models.InputAudioBufferAppendEvent{Audio: encodedAudio(8000)}))
+	assert.InDelta(t, 1.0, meter.Usage().InputAudioSeconds, 1e-12)
+
+	// The provider confirms G.711 output and the client switches input to pcm16
+	meter.ObserveProviderEvent(realtimeEvent(t, "session.updated", &models.SessionUpdatedEvent{
+		Session: models.RealtimeSessionConfig{OutputAudioFormat: "g711_alaw"},
+	}))
+	meter.ObserveClientEvent(realtimeEvent(t, "session.update", &models.SessionUpdateEvent{
+		Session: models.RealtimeSessionConfig{InputAudioFormat: "pcm16"},
+	}))
+	meter.ObserveClientEvent(realtimeEvent(t, "input_audio_buffer.append", &models.InputAudioBufferAppendEvent{Audio: encodedAudio(48000)}))
+	meter.ObserveProviderEvent(realtimeEvent(t, "response.audio.delta", &models.ResponseAudioDeltaEvent{Delta: encodedAudio(4000)}))
+
+	usage := meter.Usage()
+	assert.InDelta(t, 2.0, usage.InputAudioSeconds, 1e-12)
+	assert.InDelta(t, 0.5, usage.OutputAudioSeconds, 1e-12)
+}
+
+func TestMeter_TextTokensWithoutBreakdown(t *testing.T) {
+	meter := NewMeter(nil)
+	meter.ObserveProviderEvent(realtimeEvent(t, "response.done", &models.ResponseDoneEvent{
+		Response: models.RealtimeResponseObject{Usage: &models.RealtimeUsage{InputTokens: 30, OutputTokens: 12}},
+	}))
+
+	assert.Equal(t, Usage{InputTextTokens: 30, OutputTextTokens: 12}, meter.Usage())
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 )
 
 // RedisSessionStore implements distributed session storage using Redis
@@ -35,7 +37,7 @@ func NewRedisSessionStore(client *redis.Client, logger *zap.Logger) *RedisSessio
 	return &RedisSessionStore{
 		client: client,
 		logger: logger,
-		prefix: "pllm:realtime:session:",
+		prefix: redisService.Key("pllm:realtime:session:"),
 	}
 }
 
@@ -0,0 +1,188 @@
+// Package requestschema checks chat completion requests against the request
+// schema the gateway understands: the fields of providers.ChatRequest and the
+// accepted values of its enum fields.
+package requestschema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Violation is a field of a request outside the schema
+type Violation struct {
+	// Path locates the field, e.g. "messages[2].role"
+	Path    string
+	Message string
+
+	// Stripped reports that the field was removed from the request
+	Stripped bool
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// node is the schema of a value. Objects list their known fields; values
+// the gateway forwards as they are (free-form objects, tool parameters,
+// message content) have no fields and are not looked into.
+type node struct {
+	fields map[string]*node
+	items  *node
+	enum   []string
+
+	// required enum values cannot be stripped: a request without them is
+	// as invalid as one with a wrong value
+	required bool
+}
+
+// enumRule lists the accepted values of the string field at path. Arrays
+// are never shortened, so rules on array items must be required.
+type enumRule struct {
+	path     string
+	values   []string
+	required bool
+}
+
+var chatEnums = []enumRule{
+	{path: "messages[].role", values: []string{"system", "developer", "user", "assistant", "tool", "function"}, required: true},
+	{path: "messages[].tool_calls[].type", values: []string{"function"}, required: true},
+	{path: "tools[].type", values: []string{"function"}, required: true},
+	{path: "tool_choice", values: []string{"none", "auto", "required"}},
+	{path: "response_format.type", values: []string{"text", "json_object", "json_schema"}},
+	{path: "reasoning_effort", values: []string{"none", "minimal", "low", "medium", "high"}},
+}
+
+// chat is the chat completion request schema
+var chat = newSchema(reflect.TypeOf(providers.ChatRequest{}), chatEnums)
+
+func newSchema(t reflect.Type, enums []enumRule) *node {
+	root := build(t)
+	for _, rule := range enums {
+		n := root.at(rule.path)
+		n.enum = rule.values
+		n.required = rule.required
+	}
+	return root
+}
+
+// build derives the schema of a Go type from its JSON encoding
+func build(t reflect.Type) *node {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return build(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return &node{items: build(t.Elem())}
+	case reflect.Struct:
+		n := &node{fields: make(map[string]*node)}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			n.fields[name] = build(field.Type)
+		}
+		return n
+	default:
+		return &node{}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// at returns the node at a path of field names, "[]" marking array items
+func (n *node) at(path string) *node {
+	for _, segment := range strings.Split(path, ".") {
+		name, isArray := strings.CutSuffix(segment, "[]")
+		child, ok := n.fields[name]
+		if !ok {
+			panic("requestschema: unknown path " + path)
+		}
+		n = child
+		if isArray {
+			n = n.items
+		}
+	}
+	return n
+}
+
+// CheckChat returns the fields of a decoded chat completion request outside
+// the schema: unknown fields and invalid enum values. With strip set it
+// removes them from the request, except for invalid required values, which
+// are reported without Stripped.
+func CheckChat(request map[string]interface{}, strip bool) []Violation {
+	var violations []Violation
+	chat.checkObject(request, "", strip, &violations)
+	return violations
+}
+
+func (n *node) check(value interface{}, path string, strip bool, violations *[]Violation) (remove bool) {
+	if n.enum != nil {
+		if s, ok := value.(string); ok && !contains(n.enum, s) {
+			remove = strip && !n.required
+			*violations = append(*violations, Violation{
+				Path:     path,
+				Message:  fmt.Sprintf("invalid value %q (expected one of: %s)", s, strings.Join(n.enum, ", ")),
+				Stripped: remove,
+			})
+			return remove
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if n.fields != nil {
+			n.checkObject(v, path, strip, violations)
+		}
+	case []interface{}:
+		if n.items != nil {
+			for i, item := range v {
+				n.items.check(item, fmt.Sprintf("%s[%d]", path, i), strip, violations)
+			}
+		}
+	}
+	return false
+}
+
+func (n *node) checkObject(object map[string]interface{}, path string, strip bool, violations *[]Violation) {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		field, known := n.fields[name]
+		if !known {
+			*violations = append(*violations, Violation{Path: fieldPath, Message: "unknown field", Stripped: strip})
+			if strip {
+				delete(object, name)
+			}
+			continue
+		}
+		if field.check(object[name], fieldPath, strip, violations) {
+			delete(object, name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package requestschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeRequest(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+	var request map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &request))
+	return request
+}
+
+func paths(violations []Violation) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.Path)
+	}
+	return result
+}
+
+func TestCheckChat_ValidRequest(t *testing.T) {
+	request := decodeRequest(t, `{
+		"model": "gpt-4o",
+		"messages": [
+			{"role": "system", "content": "Be brief"},
+			{"role": "user", "content": [{"type": "text", "text": "Hi", "anything": true}]},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "c1", "type": "function", "function": {"name": "f", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "c1", "content": "42"}
+		],
+		"tools": [{"type": "function", "function": {"name": "f", "parameters": {"type": "object", "custom": 1}}}],
+		"tool_choice": {"type": "function", "function": {"name": "f"}},
+		"response_format": {"type": "json_schema", "json_schema": {"name": "s", "schema": {"x-extra": 1}}},
+		"reasoning_effort": "low",
+		"logit_bias": {"50256": -100},
+		"max_cost": 0.5
+	}`)
+
+	assert.Empty(t, CheckChat(request, false))
+}
+
+func TestCheckChat_Strict(t *testing.T) {
+	request := decodeRequest(t, `{
+		"model": "gpt-4o",
+		"messages": [{"role": "user", "content": "Hi"}, {"role": "bot", "content": "Hello", "mood": "happy"}],
+		"reasoning_effort": "extreme",
+		"tool_choice": "always",
+		"stream_options": {"include_usage": true}
+	}`)
+
+	violations := CheckChat(request, false)
+	assert.Equal(t, []string{"messages[1].mood", "messages[1].role", "reasoning_effort", "stream_options", "tool_choice"}, paths(violations))
+	assert.Equal(t, `messages[1].role: invalid value "bot" (expected one of: system, developer, user, assistant, tool, function)`,
+		violations[1].String())
+	assert.Equal(t, "stream_options: unknown field", violations[3].String())
+	for _, v := range violations {
+		assert.False(t, v.Stripped)
+	}
+	assert.Contains(t, request, "stream_options", "strict checks leave the request alone")
+}
+
+func TestCheckChat_Lenient(t *testing.T) {
+	request := decodeRequest(t, `{
+		"model": "gpt-4o",
+		"messages": [{"role": "user", "content": "Hi", "mood": "happy"}],
+		"response_format": {"type": "yaml"},
+		"reasoning_effort": "extreme",
+		"stream_options": {"include_usage": true}
+	}`)
+
+	violations := CheckChat(request, true)
+	assert.Equal(t, []string{"messages[0].mood", "reasoning_effort", "response_format.type", "stream_options"}, paths(violations))
+	for _, v := range violations {
+		assert.True(t, v.Stripped, v.Path)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"model":           "gpt-4o",
+		"messages":        []interface{}{map[string]interface{}{"role": "user", "content": "Hi"}},
+		"response_format": map[string]interface{}{},
+	}, request)
+}
+
+func TestCheckChat_LenientKeepsRequiredValues(t *testing.T) {
+	request := decodeRequest(t, `{"model": "gpt-4o", "messages": [{"role": "bot", "content": "Hi"}]}`)
+
+	violations := CheckChat(request, true)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "messages[0].role", violations[0].Path)
+	assert.False(t, violations[0].Stripped)
+}
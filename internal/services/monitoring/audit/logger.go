@@ -161,6 +161,7 @@ const (
 	ResourceSession    = "session"
 	ResourceAPI        = "api"
 	ResourceLLM        = "llm"
+	ResourceSettings   = "settings"
 )
 
 // Convenience methods for common audit events
@@ -283,6 +284,15 @@ func (l *Logger) GetAuditLogs(ctx context.Context, filters AuditLogFilters) ([]m
 	if filters.TeamID != nil {
 		query = query.Where("team_id = ?", *filters.TeamID)
 	}
+	if filters.ScopeTeamID != nil {
+		// Events of the team, of its keys, and of its members outside any team
+		query = query.Where(
+			"team_id = ? OR key_id IN (?) OR (team_id IS NULL AND user_id IN (?))",
+			*filters.ScopeTeamID,
+			l.db.Model(&models.Key{}).Unscoped().Select("id").Where("team_id = ?", *filters.ScopeTeamID),
+			l.db.Model(&models.TeamMember{}).Select("user_id").Where("team_id = ?", *filters.ScopeTeamID),
+		)
+	}
 	if filters.Action != "" {
 		query = query.Where("event_action = ?", filters.Action)
 	}
@@ -330,6 +340,9 @@ func (l *Logger) GetAuditLogs(ctx context.Context, filters AuditLogFilters) ([]m
 type AuditLogFilters struct {
 	UserID     *uuid.UUID `json:"user_id,omitempty"`
 	TeamID     *uuid.UUID `json:"team_id,omitempty"`
+	// ScopeTeamID restricts results to what a team admin may see: events of
+	// the team and its keys, and events of its members not tied to a team
+	ScopeTeamID *uuid.UUID `json:"scope_team_id,omitempty"`
 	Action     string     `json:"action,omitempty"`
 	Resource   string     `json:"resource,omitempty"`
 	ResourceID *uuid.UUID `json:"resource_id,omitempty"`
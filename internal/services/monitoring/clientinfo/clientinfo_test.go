@@ -0,0 +1,158 @@
+package clientinfo
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want Client
+	}{
+		{"OpenAI/Python 1.35.3", Client{"openai-python", "1.35.3"}},
+		{"OpenAI/JS 4.52.0", Client{"openai-node", "4.52.0"}},
+		{"Anthropic/Python 0.34.1", Client{"anthropic-python", "0.34.1"}},
+		{"AsyncOpenAI/Python 1.40.0", Client{"openai-python", "1.40.0"}},
+		{"langchain-core/0.2.1 OpenAI/Python 1.35.3 langchain/0.2.5", Client{"langchain", "0.2.5"}},
+		{"litellm/1.40.2", Client{"litellm", "1.40.2"}},
+		{"python-requests/2.32.3", Client{"python-requests", "2.32.3"}},
+		{"curl/8.7.1", Client{"curl", "8.7.1"}},
+		{"axios/1.7.2", Client{"axios", "1.7.2"}},
+		{"Go-http-client/2.0", Client{"go-http-client", ""}},
+		{"PostmanRuntime/7.39.0", Client{"postman", "7.39.0"}},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36", Client{"browser", ""}},
+		{"", Client{Unknown, ""}},
+		{"  (only a comment)  ", Client{Unknown, ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ua, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseUserAgent(tt.ua))
+		})
+	}
+}
+
+// encoder writes MaxMind DB data section fields
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) control(kind, size int) {
+	if kind > 7 {
+		e.WriteByte(byte(size))
+		e.WriteByte(byte(kind - 7))
+		return
+	}
+	e.WriteByte(byte(kind<<5 | size))
+}
+
+func (e *encoder) value(v interface{}) {
+	switch v := v.(type) {
+	case string:
+		e.control(typeString, len(v))
+		e.WriteString(v)
+	case uint16:
+		e.control(typeUint16, 2)
+		e.Write([]byte{byte(v >> 8), byte(v)})
+	case uint32:
+		e.control(typeUint32, 4)
+		e.Write([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+	case map[string]interface{}:
+		e.control(typeMap, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			e.value(key)
+			e.value(v[key])
+		}
+	}
+}
+
+// buildDB builds an IPv4 database with 24 bit records that has one record
+// for the given /24 network
+func buildDB(t *testing.T, network net.IP, record map[string]interface{}) []byte {
+	t.Helper()
+	const nodeCount = 24
+	dataPointer := uint32(nodeCount + 16) // The record is at the start of the data section
+
+	var tree bytes.Buffer
+	ip := network.To4()
+	for i := 0; i < nodeCount; i++ {
+		next := uint32(i + 1)
+		if i == nodeCount-1 {
+			next = dataPointer
+		}
+		left, right := uint32(nodeCount), uint32(nodeCount)
+		if (ip[i/8]>>(7-uint(i%8)))&1 == 0 {
+			left = next
+		} else {
+			right = next
+		}
+		tree.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+	}
+
+	var data, meta encoder
+	data.value(record)
+	meta.value(map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(24),
+		"ip_version":    uint16(4),
+		"database_type": "GeoLite2-Country",
+	})
+
+	var db bytes.Buffer
+	db.Write(tree.Bytes())
+	db.Write(make([]byte, 16))
+	db.Write(data.Bytes())
+	db.Write(metadataMarker)
+	db.Write(meta.Bytes())
+	return db.Bytes()
+}
+
+func TestLocator(t *testing.T) {
+	db := buildDB(t, net.ParseIP("81.2.69.0"), map[string]interface{}{
+		"continent": map[string]interface{}{"code": "EU", "geoname_id": uint32(6255148)},
+		"country":   map[string]interface{}{"iso_code": "GB"},
+	})
+	path := filepath.Join(t.TempDir(), "GeoLite2-Country.mmdb")
+	require.NoError(t, os.WriteFile(path, db, 0o600))
+
+	locator, err := OpenLocator(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, Location{Country: "GB", Continent: "EU"}, locator.Locate("81.2.69.142"))
+	assert.Equal(t, Location{Country: "GB", Continent: "EU"}, locator.Locate("81.2.69.1:53211"))
+	assert.Equal(t, Location{}, locator.Locate("81.2.70.1"), "outside the network")
+	assert.Equal(t, Location{}, locator.Locate("10.0.0.1"), "private addresses are not located")
+	assert.Equal(t, Location{}, locator.Locate("2001:db8::1"), "no IPv6 in an IPv4 database")
+	assert.Equal(t, Location{}, locator.Locate("not an ip"))
+
+	var nilLocator *Locator
+	assert.Equal(t, Location{}, nilLocator.Locate("81.2.69.142"))
+}
+
+func TestOpenLocator_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.mmdb")
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o600))
+	_, err := OpenLocator(path)
+	assert.ErrorContains(t, err, "metadata marker")
+
+	_, err = OpenLocator(filepath.Join(t.TempDir(), "missing.mmdb"))
+	assert.Error(t, err)
+}
+
+func TestDecoder_PointerCycle(t *testing.T) {
+	// A pointer to itself
+	_, _, err := (&decoder{buf: []byte{typePointer << 5, 0}}).decode(0)
+	assert.ErrorContains(t, err, "nested too deeply")
+}
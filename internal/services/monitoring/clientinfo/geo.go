@@ -0,0 +1,74 @@
+// Package clientinfo derives coarse facts about the clients calling the
+// gateway for usage analytics: the country and continent of the client IP,
+// from a MaxMind GeoIP2 or GeoLite2 database, and the SDK or tool that made
+// the request, from its User-Agent.
+package clientinfo
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Location is the coarse location of a client IP, as ISO 3166 country and
+// two-letter continent codes. It is empty when the IP is private or not in
+// the database.
+type Location struct {
+	Country   string `json:"country,omitempty"`
+	Continent string `json:"continent,omitempty"`
+}
+
+// Locator looks up the location of client IPs. A nil Locator finds nothing,
+// so geo enrichment is off when no database is configured.
+type Locator struct {
+	db *mmdb
+}
+
+// OpenLocator loads a MaxMind country or city database, such as
+// GeoLite2-Country.mmdb
+func OpenLocator(path string) (*Locator, error) {
+	db, err := openMMDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database %s: %w", path, err)
+	}
+	if db.dbType != "" && !strings.Contains(db.dbType, "Country") && !strings.Contains(db.dbType, "City") {
+		return nil, fmt.Errorf("GeoIP database %s is a %s database, not a country or city database", path, db.dbType)
+	}
+	return &Locator{db: db}, nil
+}
+
+// Locate returns the location of ip, which may carry a port as in
+// http.Request.RemoteAddr
+func (l *Locator) Locate(ip string) Location {
+	if l == nil || ip == "" {
+		return Location{}
+	}
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.IsPrivate() || parsed.IsLoopback() || parsed.IsLinkLocalUnicast() || parsed.IsUnspecified() {
+		return Location{}
+	}
+
+	record, err := l.db.lookup(parsed)
+	if err != nil {
+		return Location{}
+	}
+	fields, _ := record.(map[string]interface{})
+	location := Location{
+		Country:   code(fields, "country", "iso_code"),
+		Continent: code(fields, "continent", "code"),
+	}
+	if location.Country == "" {
+		// Anycast and satellite ranges only have a registered country
+		location.Country = code(fields, "registered_country", "iso_code")
+	}
+	return location
+}
+
+func code(fields map[string]interface{}, section, key string) string {
+	values, _ := fields[section].(map[string]interface{})
+	value, _ := values[key].(string)
+	return value
+}
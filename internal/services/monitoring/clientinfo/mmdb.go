@@ -0,0 +1,319 @@
+package clientinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// metadataMarker starts the metadata section at the end of a MaxMind DB file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdb is a minimal reader for the MaxMind DB format (GeoIP2 and GeoLite2
+// databases). It keeps the whole file in memory and decodes records into
+// generic values: maps, slices, strings, numbers and booleans.
+type mmdb struct {
+	buf        []byte
+	data       []byte // The data section
+	nodeCount  uint32
+	recordSize uint16
+	ipVersion  uint16
+	ipv4Start  uint32 // Node of ::/96 in an IPv6 tree, where IPv4 lookups start
+	dbType     string
+}
+
+func openMMDB(path string) (*mmdb, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseMMDB(buf)
+}
+
+func parseMMDB(buf []byte) (*mmdb, error) {
+	at := bytes.LastIndex(buf, metadataMarker)
+	if at < 0 {
+		return nil, errors.New("not a MaxMind DB: metadata marker not found")
+	}
+	metaStart := at + len(metadataMarker)
+	value, _, err := (&decoder{buf: buf[metaStart:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata: %w", err)
+	}
+	meta, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid MaxMind DB metadata")
+	}
+
+	db := &mmdb{buf: buf}
+	db.nodeCount = uint32(toUint(meta["node_count"]))
+	db.recordSize = uint16(toUint(meta["record_size"]))
+	db.ipVersion = uint16(toUint(meta["ip_version"]))
+	db.dbType, _ = meta["database_type"].(string)
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported MaxMind DB record size %d", db.recordSize)
+	}
+
+	treeSize := int(db.nodeCount) * int(db.recordSize) / 4
+	if treeSize+16 > at {
+		return nil, errors.New("invalid MaxMind DB: search tree exceeds the file")
+	}
+	db.data = buf[treeSize+16 : at]
+
+	if db.ipVersion == 6 {
+		node := uint32(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// lookup returns the record of the network containing ip, or nil when the
+// database has none
+func (db *mmdb) lookup(ip net.IP) (interface{}, error) {
+	node := uint32(0)
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		return nil, nil // IPv6 addresses are not in an IPv4 database
+	} else {
+		ip = ip.To16()
+	}
+
+	for i := 0; i < bits && node < db.nodeCount; i++ {
+		bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+		node = db.record(node, bit)
+	}
+	if node == db.nodeCount {
+		return nil, nil
+	}
+	if node < db.nodeCount {
+		return nil, errors.New("invalid MaxMind DB: address deeper than the tree")
+	}
+
+	offset := int(node-db.nodeCount) - 16
+	if offset < 0 || offset >= len(db.data) {
+		return nil, errors.New("invalid MaxMind DB: data pointer out of range")
+	}
+	value, _, err := (&decoder{buf: db.data}).decode(offset)
+	return value, err
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a tree node
+func (db *mmdb) record(node uint32, bit byte) uint32 {
+	switch db.recordSize {
+	case 24:
+		b := db.buf[node*6 : node*6+6]
+		if bit == 0 {
+			return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3])<<16 | uint32(b[4])<<8 | uint32(b[5])
+	case 28:
+		b := db.buf[node*7 : node*7+7]
+		if bit == 0 {
+			return uint32(b[3]&0xF0)<<20 | uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3]&0x0F)<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	default:
+		b := db.buf[node*8 : node*8+8]
+		if bit == 0 {
+			return binary.BigEndian.Uint32(b[0:4])
+		}
+		return binary.BigEndian.Uint32(b[4:8])
+	}
+}
+
+// Data section field types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decoder decodes fields of a data section; pointers are offsets into buf
+type decoder struct {
+	buf   []byte
+	depth int
+}
+
+// maxDepth bounds the nesting of decoded values, so a corrupt file whose
+// pointers form a cycle cannot recurse forever
+const maxDepth = 32
+
+var errTruncated = errors.New("truncated MaxMind DB data")
+
+// decode decodes the field at offset and returns it with the offset after it
+func (d *decoder) decode(offset int) (interface{}, int, error) {
+	if offset >= len(d.buf) {
+		return nil, 0, errTruncated
+	}
+	if d.depth >= maxDepth {
+		return nil, 0, errors.New("invalid MaxMind DB data: nested too deeply")
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+
+	ctrl := d.buf[offset]
+	offset++
+	kind := int(ctrl >> 5)
+
+	if kind == typePointer {
+		pointer, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+
+	if kind == typeExtended {
+		if offset >= len(d.buf) {
+			return nil, 0, errTruncated
+		}
+		kind = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size := int(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > len(d.buf) {
+			return nil, 0, errTruncated
+		}
+		extra := int(uintBytes(d.buf[offset : offset+n]))
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := 0; i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("invalid MaxMind DB data: map key is not a string")
+			}
+			value, after, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+			offset = after
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEndMarker:
+		return nil, offset, nil
+	}
+
+	if offset+size > len(d.buf) {
+		return nil, 0, errTruncated
+	}
+	raw := d.buf[offset : offset+size]
+	offset += size
+	switch kind {
+	case typeString:
+		return string(raw), offset, nil
+	case typeBytes:
+		return append([]byte(nil), raw...), offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid MaxMind DB data: double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid MaxMind DB data: float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), offset, nil
+	case typeInt32:
+		return int64(int32(uint32(uintBytes(raw)))), offset, nil
+	case typeUint16, typeUint32, typeUint64:
+		return uintBytes(raw), offset, nil
+	case typeUint128:
+		return append([]byte(nil), raw...), offset, nil // Not needed for geo lookups
+	}
+	return nil, 0, fmt.Errorf("invalid MaxMind DB data: unknown type %d", kind)
+}
+
+// pointer decodes a pointer field and returns its target and the offset
+// after it
+func (d *decoder) pointer(ctrl byte, offset int) (int, int, error) {
+	n := int((ctrl>>3)&0x3) + 1
+	if offset+n > len(d.buf) {
+		return 0, 0, errTruncated
+	}
+	b := d.buf[offset : offset+n]
+	vvv := uint64(ctrl & 0x7)
+	var pointer uint64
+	switch n {
+	case 1:
+		pointer = vvv<<8 | uintBytes(b)
+	case 2:
+		pointer = (vvv<<16 | uintBytes(b)) + 2048
+	case 3:
+		pointer = (vvv<<24 | uintBytes(b)) + 526336
+	default:
+		pointer = uintBytes(b)
+	}
+	return int(pointer), offset + n, nil
+}
+
+func uintBytes(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func toUint(value interface{}) uint64 {
+	v, _ := value.(uint64)
+	return v
+}
@@ -0,0 +1,131 @@
+package clientinfo
+
+import "strings"
+
+// Client is the SDK or tool that made a request, from its User-Agent
+type Client struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Unknown is the client name of requests without a User-Agent
+const Unknown = "unknown"
+
+// The OpenAI and Anthropic SDKs send "OpenAI/Python 1.35.0" style agents,
+// with the language after the slash and the version as the next token
+var sdkLanguages = map[string]string{
+	"python": "python",
+	"js":     "node",
+	"node":   "node",
+	"java":   "java",
+	"go":     "go",
+	"ruby":   "ruby",
+	".net":   "dotnet",
+	"dotnet": "dotnet",
+}
+
+// Well-known products whose name is not the first token
+var embedded = []string{"langchain", "litellm", "llamaindex", "llama-index", "openai-agents"}
+
+// ParseUserAgent fingerprints the client library of a request. Names are
+// lower case, like "openai-python", "anthropic-node", "curl" or
+// "python-requests"; browsers are reported as "browser" without a version.
+func ParseUserAgent(ua string) Client {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return Client{Name: Unknown}
+	}
+
+	products := productTokens(ua)
+	if len(products) == 0 {
+		return Client{Name: Unknown}
+	}
+
+	for _, name := range embedded {
+		for _, product := range products {
+			if strings.EqualFold(product.name, name) {
+				return truncate(Client{Name: name, Version: product.version})
+			}
+		}
+	}
+
+	first := products[0]
+	name := strings.ToLower(first.name)
+	if sdk := strings.TrimPrefix(name, "async"); sdk == "openai" || sdk == "anthropic" {
+		name = sdk // The async Python clients
+	}
+	switch {
+	case name == "openai" || name == "anthropic":
+		// "OpenAI/Python 1.35.0": the version is the next bare token
+		language := sdkLanguages[strings.ToLower(first.version)]
+		if language == "" {
+			language = strings.ToLower(first.version)
+		}
+		client := Client{Name: name + "-" + language}
+		if len(products) > 1 && products[1].version == "" && startsWithDigit(products[1].name) {
+			client.Version = products[1].name
+		}
+		return truncate(client)
+	case name == "mozilla":
+		return Client{Name: "browser"}
+	case name == "go-http-client":
+		return Client{Name: name} // The version is the HTTP version
+	case name == "postmanruntime":
+		name = "postman"
+	}
+	return truncate(Client{Name: name, Version: first.version})
+}
+
+type product struct {
+	name    string
+	version string
+}
+
+// productTokens splits a User-Agent into name/version tokens, skipping the
+// parenthesized comments
+func productTokens(ua string) []product {
+	var products []product
+	depth := 0
+	var token strings.Builder
+	flush := func() {
+		if token.Len() == 0 {
+			return
+		}
+		name, version, _ := strings.Cut(token.String(), "/")
+		products = append(products, product{name: name, version: version})
+		token.Reset()
+	}
+	for _, r := range ua {
+		switch {
+		case r == '(':
+			flush()
+			depth++
+		case r == ')':
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+		case r == ' ' || r == '\t' || r == ';' || r == ',':
+			flush()
+		default:
+			token.WriteRune(r)
+		}
+	}
+	flush()
+	return products
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// truncate bounds the name and version, which are indexed columns
+func truncate(client Client) Client {
+	if len(client.Name) > 64 {
+		client.Name = client.Name[:64]
+	}
+	if len(client.Version) > 32 {
+		client.Version = client.Version[:32]
+	}
+	return client
+}
@@ -0,0 +1,297 @@
+// Package cluster registers each gateway replica in the coordination backend
+// and reports the replicas of the deployment, their health and whether they
+// run the same configuration.
+package cluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// Version is the gateway version replicas report. Release builds set it with
+// -ldflags "-X github.com/amerfu/pllm/internal/services/monitoring/cluster.Version=v1.2.3";
+// otherwise the VCS revision the binary was built from is used.
+var Version string
+
+// Replica statuses
+const (
+	StatusHealthy   = "healthy"
+	StatusUnhealthy = "unhealthy" // Heartbeating, but a dependency check failed
+	StatusStale     = "stale"     // No heartbeat for three intervals
+)
+
+// Config configures a Registrar
+type Config struct {
+	Backend           redisService.ReplicaRegistryBackend
+	Logger            *zap.Logger
+	ConfigHash        string
+	HeartbeatInterval time.Duration
+	ReplicaTTL        time.Duration
+
+	// HealthCheck reports whether the replica can serve requests; nil means
+	// always healthy
+	HealthCheck func(ctx context.Context) error
+}
+
+// Registrar registers this replica and refreshes its registration with
+// periodic heartbeats
+type Registrar struct {
+	cfg  Config
+	self redisService.Replica
+
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// ReplicaStatus is a registered replica with its derived status
+type ReplicaStatus struct {
+	redisService.Replica
+	Status      string `json:"status"`
+	Self        bool   `json:"self"`         // The replica that served the request
+	ConfigDrift bool   `json:"config_drift"` // Runs another configuration than the cluster
+}
+
+// Status is the view of the cluster from one replica
+type Status struct {
+	Replicas []ReplicaStatus `json:"replicas"`
+	Total    int             `json:"total"`
+	Healthy  int             `json:"healthy"`
+
+	// ConfigHash is the configuration most live replicas run; replicas with
+	// another hash are drifted, e.g. during a rollout
+	ConfigHash    string         `json:"config_hash"`
+	ConfigHashes  map[string]int `json:"config_hashes"`
+	ConfigDrift   bool           `json:"config_drift"`
+	Versions      map[string]int `json:"versions"`
+	HeartbeatSecs float64        `json:"heartbeat_interval_seconds"`
+}
+
+// NewRegistrar creates a registrar for this process
+func NewRegistrar(cfg *Config) *Registrar {
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 15 * time.Second
+	}
+	if cfg.ReplicaTTL < 3*cfg.HeartbeatInterval {
+		cfg.ReplicaTTL = 3 * cfg.HeartbeatInterval
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return &Registrar{
+		cfg: *cfg,
+		self: redisService.Replica{
+			ID:         fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+			Hostname:   hostname,
+			Version:    buildVersion(),
+			StartedAt:  time.Now().UTC(),
+			ConfigHash: cfg.ConfigHash,
+		},
+	}
+}
+
+// ID returns the ID this replica registers under
+func (r *Registrar) ID() string {
+	return r.self.ID
+}
+
+// Start registers the replica and keeps its registration fresh until Stop
+func (r *Registrar) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	r.Heartbeat(ctx)
+	go r.run(ctx)
+
+	r.cfg.Logger.Info("Registered gateway replica",
+		zap.String("replica", r.self.ID),
+		zap.String("version", r.self.Version),
+		zap.String("config_hash", r.self.ConfigHash))
+}
+
+// Stop stops the heartbeats and removes the registration, so the replica
+// leaves the cluster view right away instead of going stale
+func (r *Registrar) Stop() {
+	r.stopOnce.Do(func() {
+		r.mu.Lock()
+		cancel, done := r.cancel, r.done
+		r.mu.Unlock()
+		if cancel == nil {
+			return
+		}
+		cancel()
+		<-done
+
+		ctx, cancelDeregister := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelDeregister()
+		if err := r.cfg.Backend.DeregisterReplica(ctx, r.self.ID); err != nil {
+			r.cfg.Logger.Warn("Failed to deregister gateway replica", zap.Error(err))
+		}
+	})
+}
+
+func (r *Registrar) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Heartbeat(ctx)
+		}
+	}
+}
+
+// Heartbeat checks the replica's health and refreshes its registration
+func (r *Registrar) Heartbeat(ctx context.Context) {
+	replica := r.self
+	replica.HeartbeatAt = time.Now().UTC()
+	replica.Healthy = true
+	if r.cfg.HealthCheck != nil {
+		if err := r.cfg.HealthCheck(ctx); err != nil {
+			replica.Healthy = false
+			replica.Error = err.Error()
+		}
+	}
+
+	if err := r.cfg.Backend.RegisterReplica(ctx, &replica, r.cfg.ReplicaTTL); err != nil && ctx.Err() == nil {
+		r.cfg.Logger.Warn("Failed to refresh gateway replica registration", zap.Error(err))
+	}
+}
+
+// Status lists the registered replicas with their status and configuration
+// drift
+func (r *Registrar) Status(ctx context.Context) (*Status, error) {
+	replicas, err := r.cfg.Backend.ListReplicas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildStatus(replicas, r.self.ID, r.cfg.HeartbeatInterval, time.Now()), nil
+}
+
+// buildStatus derives the replica statuses. The cluster's configuration is
+// the hash most live replicas run; ties go to the most recently started
+// replica, the likely target of a rollout.
+func buildStatus(replicas []redisService.Replica, selfID string, interval time.Duration, now time.Time) *Status {
+	status := &Status{
+		Replicas:      make([]ReplicaStatus, 0, len(replicas)),
+		ConfigHashes:  make(map[string]int),
+		Versions:      make(map[string]int),
+		HeartbeatSecs: interval.Seconds(),
+	}
+
+	newest := make(map[string]time.Time)
+	for _, replica := range replicas {
+		rs := ReplicaStatus{Replica: replica, Self: replica.ID == selfID}
+		switch {
+		case now.Sub(replica.HeartbeatAt) > 3*interval:
+			rs.Status = StatusStale
+		case !replica.Healthy:
+			rs.Status = StatusUnhealthy
+		default:
+			rs.Status = StatusHealthy
+			status.Healthy++
+		}
+		status.Replicas = append(status.Replicas, rs)
+		status.Versions[replica.Version]++
+
+		if rs.Status != StatusStale {
+			status.ConfigHashes[replica.ConfigHash]++
+			if replica.StartedAt.After(newest[replica.ConfigHash]) {
+				newest[replica.ConfigHash] = replica.StartedAt
+			}
+		}
+	}
+	status.Total = len(status.Replicas)
+
+	hashes := make([]string, 0, len(status.ConfigHashes))
+	for hash := range status.ConfigHashes {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		a, b := hashes[i], hashes[j]
+		if status.ConfigHashes[a] != status.ConfigHashes[b] {
+			return status.ConfigHashes[a] > status.ConfigHashes[b]
+		}
+		return newest[a].After(newest[b])
+	})
+	if len(hashes) > 0 {
+		status.ConfigHash = hashes[0]
+		status.ConfigDrift = len(hashes) > 1
+	}
+	for i := range status.Replicas {
+		status.Replicas[i].ConfigDrift = status.ConfigHash != "" && status.Replicas[i].ConfigHash != status.ConfigHash
+	}
+	return status
+}
+
+// ConfigHash returns a short hash of a configuration, equal on replicas
+// loaded with the same settings. The configuration is hashed as JSON, so
+// secrets are part of the hash but cannot be read back from it.
+func ConfigHash(cfg interface{}) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// buildVersion returns Version, or the module version or VCS revision
+// recorded in the binary
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "dev"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+func TestRegistrar(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	backend := redisService.NewReplicaRegistry(client, zap.NewNop())
+	ctx := context.Background()
+
+	healthErr := errors.New("database unreachable")
+	registrar := NewRegistrar(&Config{
+		Backend:           backend,
+		Logger:            zap.NewNop(),
+		ConfigHash:        ConfigHash(map[string]string{"port": "8080"}),
+		HeartbeatInterval: time.Hour,
+		HealthCheck:       func(context.Context) error { return healthErr },
+	})
+	registrar.Start(ctx)
+
+	other := &redisService.Replica{ID: "gw-other", ConfigHash: "other", HeartbeatAt: time.Now(), Healthy: true}
+	require.NoError(t, backend.RegisterReplica(ctx, other, time.Hour))
+
+	status, err := registrar.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, status.Total)
+	assert.Equal(t, 1, status.Healthy)
+	assert.True(t, status.ConfigDrift)
+
+	var self ReplicaStatus
+	for _, replica := range status.Replicas {
+		if replica.Self {
+			self = replica
+		}
+	}
+	assert.Equal(t, registrar.ID(), self.ID)
+	assert.Equal(t, StatusUnhealthy, self.Status)
+	assert.Equal(t, "database unreachable", self.Error)
+	assert.NotEmpty(t, self.Version)
+
+	healthErr = nil
+	registrar.Heartbeat(ctx)
+	status, err = registrar.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.Healthy)
+
+	registrar.Stop()
+	replicas, err := backend.ListReplicas(ctx)
+	require.NoError(t, err)
+	require.Len(t, replicas, 1, "stopped replicas deregister")
+	assert.Equal(t, "gw-other", replicas[0].ID)
+}
+
+func TestBuildStatus(t *testing.T) {
+	now := time.Now()
+	interval := 15 * time.Second
+	replicas := []redisService.Replica{
+		{ID: "a", ConfigHash: "old", Version: "v1", StartedAt: now.Add(-time.Hour), HeartbeatAt: now, Healthy: true},
+		{ID: "b", ConfigHash: "new", Version: "v2", StartedAt: now.Add(-time.Minute), HeartbeatAt: now, Healthy: true},
+		{ID: "c", ConfigHash: "new", Version: "v2", StartedAt: now.Add(-time.Hour), HeartbeatAt: now.Add(-time.Minute), Healthy: true},
+	}
+
+	status := buildStatus(replicas, "a", interval, now)
+	assert.Equal(t, StatusStale, status.Replicas[2].Status, "no heartbeat for three intervals")
+	assert.Equal(t, 2, status.Healthy)
+	assert.Equal(t, map[string]int{"old": 1, "new": 1}, status.ConfigHashes, "stale replicas do not count")
+	assert.Equal(t, "new", status.ConfigHash, "ties go to the newest replica")
+	assert.True(t, status.ConfigDrift)
+	assert.True(t, status.Replicas[0].ConfigDrift)
+	assert.False(t, status.Replicas[1].ConfigDrift)
+	assert.Equal(t, map[string]int{"v1": 1, "v2": 2}, status.Versions)
+	assert.True(t, status.Replicas[0].Self)
+
+	status = buildStatus(replicas[:1], "a", interval, now)
+	assert.False(t, status.ConfigDrift)
+	assert.Equal(t, "old", status.ConfigHash)
+
+	assert.Equal(t, ConfigHash(map[string]int{"a": 1}), ConfigHash(map[string]int{"a": 1}))
+	assert.NotEqual(t, ConfigHash(map[string]int{"a": 1}), ConfigHash(map[string]int{"a": 2}))
+}
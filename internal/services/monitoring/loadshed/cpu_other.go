@@ -0,0 +1,10 @@
+//go:build !unix
+
+package loadshed
+
+import "time"
+
+// processCPUTime is unavailable on this platform; CPU is not sampled
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package loadshed
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime returns the CPU time used by the process so far
+func processCPUTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
@@ -0,0 +1,341 @@
+// Package loadshed samples the resources of the gateway process and sheds
+// LLM requests by priority while they run short.
+package loadshed
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// PressureLevel describes how short of resources the process runs
+type PressureLevel int32
+
+const (
+	PressureNormal   PressureLevel = iota
+	PressureElevated               // Low-priority requests are shed
+	PressureCritical               // Every request but the master key's is shed
+)
+
+func (l PressureLevel) String() string {
+	switch l {
+	case PressureElevated:
+		return "elevated"
+	case PressureCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// Priority is how important a request is to keep serving under pressure
+type Priority int
+
+const (
+	PriorityLow    Priority = iota // Requested by the client
+	PriorityNormal                 // The default
+	PriorityMaster                 // The master key, never shed
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityMaster:
+		return "master"
+	default:
+		return "normal"
+	}
+}
+
+var (
+	processCPUPercent = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_process_cpu_percent",
+		Help: "Gateway process CPU use as a percentage of the cores Go may use",
+	})
+
+	processMemoryBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_process_memory_bytes",
+		Help: "Memory obtained from the OS by the Go runtime and not released",
+	})
+
+	processMemoryRatio = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_process_memory_limit_ratio",
+		Help: "Gateway process memory as a fraction of its memory limit",
+	})
+
+	processGoroutines = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_process_goroutines",
+		Help: "Live goroutines of the gateway process",
+	})
+
+	processGCPauseRatio = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_process_gc_pause_ratio",
+		Help: "Fraction of time the gateway process spent in GC pauses",
+	})
+
+	loadPressureLevel = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_load_pressure_level",
+		Help: "Gateway resource pressure level (0=normal, 1=elevated, 2=critical)",
+	})
+
+	loadSheddingDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_load_shedding_decisions_total",
+		Help: "Load shedding decisions on LLM requests",
+	}, []string{"priority", "decision"}) // decision: admitted, shed
+)
+
+// Sample is the latest resource sample taken by the monitor
+type Sample struct {
+	Level        string    `json:"level"`
+	CPUPercent   float64   `json:"cpu_percent"`
+	MemoryBytes  uint64    `json:"memory_bytes"`
+	MemoryLimit  uint64    `json:"memory_limit,omitempty"`
+	MemoryRatio  float64   `json:"memory_ratio"`
+	Goroutines   int       `json:"goroutines"`
+	GCPauseRatio float64   `json:"gc_pause_ratio"`
+	Exceeded     []string  `json:"exceeded,omitempty"` // Thresholds crossed
+	SampledAt    time.Time `json:"sampled_at"`
+}
+
+// reading is a raw snapshot of the process counters
+type reading struct {
+	at          time.Time
+	cpu         time.Duration
+	cpuOK       bool
+	memory      uint64
+	memoryLimit uint64 // 0 when unlimited
+	goroutines  int
+	gcPause     time.Duration
+}
+
+// Monitor samples the CPU, memory, goroutines and GC pauses of the gateway
+// process and decides which requests to shed. Resources are sampled and
+// exported whether or not shedding is enabled.
+type Monitor struct {
+	config config.LoadSheddingConfig
+	logger *zap.Logger
+	read   func() reading
+
+	level atomic.Int32
+
+	mu     sync.RWMutex
+	last   *reading
+	sample Sample
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewMonitor creates a resource monitor
+func NewMonitor(cfg config.LoadSheddingConfig, logger *zap.Logger) *Monitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 10 * time.Second
+	}
+	return &Monitor{
+		config: cfg,
+		logger: logger,
+		read:   readProcess,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start samples the process periodically until the context is cancelled or
+// Stop is called
+func (m *Monitor) Start(ctx context.Context) {
+	m.logger.Info("Starting resource monitor",
+		zap.Duration("interval", m.config.Interval),
+		zap.Bool("load_shedding", m.config.Enabled))
+
+	go func() {
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			m.Check()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the sampling loop
+func (m *Monitor) Stop() {
+	m.stopOnce.Do(func() { close(m.stopCh) })
+}
+
+// Check samples the process once and updates the pressure level. Rates
+// (CPU and GC pauses) cover the time since the previous sample, so the
+// first sample only reports memory and goroutines.
+func (m *Monitor) Check() PressureLevel {
+	current := m.read()
+
+	m.mu.Lock()
+	previous := m.last
+	m.last = &current
+	m.mu.Unlock()
+
+	sample := Sample{
+		MemoryBytes: current.memory,
+		MemoryLimit: current.memoryLimit,
+		Goroutines:  current.goroutines,
+		SampledAt:   current.at,
+	}
+	if m.config.MemoryLimit > 0 {
+		sample.MemoryLimit = uint64(m.config.MemoryLimit)
+	}
+	if sample.MemoryLimit > 0 {
+		sample.MemoryRatio = float64(sample.MemoryBytes) / float64(sample.MemoryLimit)
+	}
+	if previous != nil {
+		if elapsed := current.at.Sub(previous.at); elapsed > 0 {
+			if current.cpuOK && previous.cpuOK {
+				cores := float64(runtime.GOMAXPROCS(0))
+				sample.CPUPercent = 100 * float64(current.cpu-previous.cpu) / (float64(elapsed) * cores)
+			}
+			sample.GCPauseRatio = float64(current.gcPause-previous.gcPause) / float64(elapsed)
+		}
+	}
+
+	level := PressureNormal
+	if exceeded := exceededThresholds(sample, m.config.Critical); len(exceeded) > 0 {
+		level = PressureCritical
+		sample.Exceeded = exceeded
+	} else if exceeded := exceededThresholds(sample, m.config.Elevated); len(exceeded) > 0 {
+		level = PressureElevated
+		sample.Exceeded = exceeded
+	}
+	sample.Level = level.String()
+
+	m.mu.Lock()
+	m.sample = sample
+	m.mu.Unlock()
+
+	processCPUPercent.Set(sample.CPUPercent)
+	processMemoryBytes.Set(float64(sample.MemoryBytes))
+	processMemoryRatio.Set(sample.MemoryRatio)
+	processGoroutines.Set(float64(sample.Goroutines))
+	processGCPauseRatio.Set(sample.GCPauseRatio)
+	loadPressureLevel.Set(float64(level))
+
+	if old := PressureLevel(m.level.Swap(int32(level))); old != level {
+		fields := []zap.Field{
+			zap.String("from", old.String()),
+			zap.String("to", level.String()),
+			zap.Strings("exceeded", sample.Exceeded),
+			zap.Float64("cpu_percent", sample.CPUPercent),
+			zap.Float64("memory_ratio", sample.MemoryRatio),
+			zap.Int("goroutines", sample.Goroutines),
+			zap.Float64("gc_pause_ratio", sample.GCPauseRatio),
+			zap.Bool("load_shedding", m.config.Enabled),
+		}
+		if level > old {
+			m.logger.Error("ALERT: Gateway resource pressure increased", fields...)
+		} else {
+			m.logger.Info("Gateway resource pressure decreased", fields...)
+		}
+	}
+	return level
+}
+
+// exceededThresholds names the thresholds a sample crosses
+func exceededThresholds(sample Sample, thresholds config.LoadSheddingThresholds) []string {
+	var exceeded []string
+	if thresholds.CPUPercent > 0 && sample.CPUPercent >= thresholds.CPUPercent {
+		exceeded = append(exceeded, "cpu")
+	}
+	if thresholds.MemoryRatio > 0 && sample.MemoryLimit > 0 && sample.MemoryRatio >= thresholds.MemoryRatio {
+		exceeded = append(exceeded, "memory")
+	}
+	if thresholds.Goroutines > 0 && sample.Goroutines >= thresholds.Goroutines {
+		exceeded = append(exceeded, "goroutines")
+	}
+	if thresholds.GCPauseRatio > 0 && sample.GCPauseRatio >= thresholds.GCPauseRatio {
+		exceeded = append(exceeded, "gc_pause")
+	}
+	return exceeded
+}
+
+// Level returns the current pressure level. A nil monitor is always normal.
+func (m *Monitor) Level() PressureLevel {
+	if m == nil {
+		return PressureNormal
+	}
+	return PressureLevel(m.level.Load())
+}
+
+// Sample returns the latest sample
+func (m *Monitor) Sample() Sample {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sample
+}
+
+// ShouldShedLoad reports whether requests are being shed
+func (m *Monitor) ShouldShedLoad() bool {
+	return m != nil && m.config.Enabled && m.Level() > PressureNormal
+}
+
+// Shed decides whether to reject a request of the given priority and
+// records the decision
+func (m *Monitor) Shed(priority Priority) bool {
+	shed := false
+	if m.ShouldShedLoad() {
+		switch m.Level() {
+		case PressureElevated:
+			shed = priority <= PriorityLow
+		case PressureCritical:
+			shed = priority <= PriorityNormal
+		}
+	}
+
+	decision := "admitted"
+	if shed {
+		decision = "shed"
+	}
+	loadSheddingDecisions.WithLabelValues(priority.String(), decision).Inc()
+	return shed
+}
+
+// RetryAfter is how long shed clients are asked to wait
+func (m *Monitor) RetryAfter() time.Duration {
+	return m.config.RetryAfter
+}
+
+// readProcess reads the process counters. ReadMemStats briefly stops the
+// world, which is negligible at the sampling interval.
+func readProcess() reading {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	r := reading{
+		at:         time.Now(),
+		memory:     stats.Sys - stats.HeapReleased,
+		goroutines: runtime.NumGoroutine(),
+		gcPause:    time.Duration(stats.PauseTotalNs),
+	}
+	r.cpu, r.cpuOK = processCPUTime()
+	if limit := debug.SetMemoryLimit(-1); limit > 0 && limit < math.MaxInt64 {
+		r.memoryLimit = uint64(limit)
+	}
+	return r
+}
@@ -0,0 +1,79 @@
+package loadshed
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func TestMonitor_Check(t *testing.T) {
+	monitor := NewMonitor(config.LoadSheddingConfig{
+		Enabled:     true,
+		MemoryLimit: 1000,
+		Elevated:    config.LoadSheddingThresholds{CPUPercent: 80, MemoryRatio: 0.8, Goroutines: 100, GCPauseRatio: 0.05},
+		Critical:    config.LoadSheddingThresholds{CPUPercent: 95, MemoryRatio: 0.9, Goroutines: 200},
+	}, zap.NewNop())
+
+	cores := time.Duration(runtime.GOMAXPROCS(0))
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	current := reading{at: now, cpuOK: true, memory: 100, goroutines: 10}
+	monitor.read = func() reading { return current }
+
+	assert.Equal(t, PressureNormal, monitor.Check())
+	assert.Zero(t, monitor.Sample().CPUPercent, "rates need a previous sample")
+
+	// 85% of every core over ten seconds
+	current = reading{at: now.Add(10 * time.Second), cpuOK: true, cpu: cores * 8500 * time.Millisecond,
+		memory: 500, goroutines: 10, gcPause: 100 * time.Millisecond}
+	assert.Equal(t, PressureElevated, monitor.Check())
+	sample := monitor.Sample()
+	assert.InDelta(t, 85, sample.CPUPercent, 1e-6)
+	assert.InDelta(t, 0.01, sample.GCPauseRatio, 1e-9)
+	assert.InDelta(t, 0.5, sample.MemoryRatio, 1e-9)
+	assert.Equal(t, []string{"cpu"}, sample.Exceeded)
+	assert.Equal(t, "elevated", sample.Level)
+
+	assert.True(t, monitor.Shed(PriorityLow))
+	assert.False(t, monitor.Shed(PriorityNormal))
+	assert.False(t, monitor.Shed(PriorityMaster))
+
+	// Critical memory, while CPU is back to idle
+	current = reading{at: now.Add(20 * time.Second), cpuOK: true, cpu: cores * 8500 * time.Millisecond,
+		memory: 950, goroutines: 150, gcPause: 100 * time.Millisecond}
+	assert.Equal(t, PressureCritical, monitor.Check())
+	assert.Equal(t, []string{"memory"}, monitor.Sample().Exceeded, "only the thresholds of the level reached are reported")
+	assert.True(t, monitor.Shed(PriorityNormal))
+	assert.False(t, monitor.Shed(PriorityMaster))
+	assert.True(t, monitor.ShouldShedLoad())
+
+	current = reading{at: now.Add(30 * time.Second), cpuOK: true, cpu: cores * 8500 * time.Millisecond, memory: 100}
+	assert.Equal(t, PressureNormal, monitor.Check())
+	assert.False(t, monitor.Shed(PriorityLow))
+}
+
+func TestMonitor_SheddingDisabled(t *testing.T) {
+	monitor := NewMonitor(config.LoadSheddingConfig{
+		Elevated: config.LoadSheddingThresholds{Goroutines: 1},
+	}, zap.NewNop())
+	monitor.read = func() reading { return reading{at: time.Now(), goroutines: 10} }
+
+	assert.Equal(t, PressureElevated, monitor.Check(), "resources are monitored anyway")
+	assert.False(t, monitor.ShouldShedLoad())
+	assert.False(t, monitor.Shed(PriorityLow))
+	assert.Equal(t, 10*time.Second, monitor.RetryAfter())
+
+	var missing *Monitor
+	assert.Equal(t, PressureNormal, missing.Level())
+	assert.False(t, missing.ShouldShedLoad())
+}
+
+func TestReadProcess(t *testing.T) {
+	r := readProcess()
+	assert.Positive(t, r.memory)
+	assert.Positive(t, r.goroutines)
+}
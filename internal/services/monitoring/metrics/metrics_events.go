@@ -7,6 +7,8 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 )
 
 // MetricEventType defines the type of metric event
@@ -49,12 +51,18 @@ type MetricEvent struct {
 	CacheHit bool `json:"cache_hit,omitempty"`
 }
 
+// LoadShedder tells the emitter to drop analytic events under backpressure
+type LoadShedder interface {
+	ShedAnalytics() bool
+}
+
 // MetricEventEmitter emits metric events to Redis queue
 type MetricEventEmitter struct {
 	redis    *redis.Client
 	logger   *zap.Logger
 	queueKey string
 	ctx      context.Context
+	shedder  LoadShedder
 }
 
 // NewMetricEventEmitter creates a new metric event emitter
@@ -62,11 +70,16 @@ func NewMetricEventEmitter(redisClient *redis.Client, logger *zap.Logger) *Metri
 	return &MetricEventEmitter{
 		redis:    redisClient,
 		logger:   logger,
-		queueKey: "pllm:metrics:events",
+		queueKey: redisService.Key("pllm:metrics:events"),
 		ctx:      context.Background(),
 	}
 }
 
+// SetLoadShedder drops events while the shedder reports pressure
+func (e *MetricEventEmitter) SetLoadShedder(shedder LoadShedder) {
+	e.shedder = shedder
+}
+
 // EmitRequest emits a request metric event (non-blocking)
 func (e *MetricEventEmitter) EmitRequest(modelName, userID, teamID, keyID, requestID string) {
 	event := MetricEvent{
@@ -121,6 +134,11 @@ func (e *MetricEventEmitter) emitEvent(event MetricEvent) {
 	ctx, cancel := context.WithTimeout(e.ctx, 100*time.Millisecond)
 	defer cancel()
 
+	// Analytics are the first thing to go when Redis is under pressure
+	if e.shedder != nil && e.shedder.ShedAnalytics() {
+		return
+	}
+
 	eventData, err := json.Marshal(event)
 	if err != nil {
 		e.logger.Error("Failed to marshal metric event", zap.Error(err))
@@ -10,6 +10,8 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 )
 
 // MetricsServiceConfig configures the metrics service
@@ -85,7 +87,7 @@ func NewMetricsService(config *MetricsServiceConfig) (*MetricsService, error) {
 		BatchSize:         config.BatchSize,
 		BatchTimeout:      config.BatchTimeout,
 		WorkerCount:       config.WorkerCount,
-		QueueKey:          "pllm:metrics:events",
+		QueueKey:          redisService.Key("pllm:metrics:events"),
 		AggregateInterval: config.AggregateInterval,
 	}
 
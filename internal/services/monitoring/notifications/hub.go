@@ -0,0 +1,213 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// Kinds of notifications pushed to the admin dashboard
+const (
+	KindBudgetAlert        = "budget_alert"
+	KindBudgetExceeded     = "budget_exceeded"
+	KindCircuitBreakerOpen = "circuit_breaker_open"
+	KindWorkerFailure      = "worker_failure"
+	KindUserSignup         = "user_signup"
+	KindProviderIncident   = "provider_incident"
+	KindIncidentResolved   = "incident_resolved"
+)
+
+// Notification severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// subscriberBuffer is how many notifications a slow subscriber may fall
+// behind before further ones are dropped for it
+const subscriberBuffer = 64
+
+// Notification is a single event delivered to dashboard subscribers
+type Notification struct {
+	ID        string                 `json:"id"`
+	Kind      string                 `json:"kind"`
+	Severity  string                 `json:"severity"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Hub fans notifications out to connected dashboard subscribers. With an
+// event publisher, notifications go through a Redis stream so events raised
+// by other replicas and the standalone worker reach every dashboard;
+// without one they are delivered in process only.
+//
+// A nil *Hub is valid and drops every notification, so producers can call
+// Notify without checking whether notifications are wired up.
+type Hub struct {
+	eventPub *redisService.EventPublisher
+	logger   *zap.Logger
+
+	mu          sync.Mutex
+	subscribers map[chan Notification]struct{}
+	closed      bool
+}
+
+// NewHub creates a notification hub. eventPub may be nil.
+func NewHub(eventPub *redisService.EventPublisher, logger *zap.Logger) *Hub {
+	return &Hub{
+		eventPub:    eventPub,
+		logger:      logger,
+		subscribers: make(map[chan Notification]struct{}),
+	}
+}
+
+// Notify publishes a notification. It never blocks on subscribers.
+func (h *Hub) Notify(ctx context.Context, n Notification) {
+	if h == nil {
+		return
+	}
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+	if n.Severity == "" {
+		n.Severity = SeverityInfo
+	}
+
+	if h.eventPub != nil {
+		err := h.eventPub.PublishNotificationEvent(ctx, n.ID, map[string]interface{}{
+			"kind":      n.Kind,
+			"severity":  n.Severity,
+			"message":   n.Message,
+			"data":      n.Data,
+			"timestamp": n.Timestamp,
+		})
+		if err == nil {
+			// Delivered to local subscribers by Run
+			return
+		}
+		h.logger.Warn("Failed to publish notification, delivering locally only",
+			zap.String("kind", n.Kind), zap.Error(err))
+	}
+	h.broadcast(n)
+}
+
+// Subscribe registers a subscriber. The channel is closed when the hub is
+// closed; call the returned function to unsubscribe.
+func (h *Hub) Subscribe() (<-chan Notification, func()) {
+	ch := make(chan Notification, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.closed {
+		close(ch)
+	} else {
+		h.subscribers[ch] = struct{}{}
+	}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Run relays notifications published through the event stream, including
+// those of other processes, to local subscribers until ctx is done. It
+// returns immediately when the hub has no event publisher.
+func (h *Hub) Run(ctx context.Context) {
+	if h.eventPub == nil {
+		return
+	}
+
+	lastID := "$"
+	for ctx.Err() == nil {
+		events, next, err := h.eventPub.ReadEvents(ctx, redisService.NotificationStream, lastID, 5*time.Second)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			h.logger.Warn("Failed to read notification stream", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		lastID = next
+
+		for _, event := range events {
+			h.broadcast(notificationFromEvent(event))
+		}
+	}
+}
+
+// Close disconnects all subscribers, ending their streams
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (h *Hub) broadcast(n Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- n:
+		default:
+			h.logger.Debug("Dropping notification for slow subscriber", zap.String("kind", n.Kind))
+		}
+	}
+}
+
+// notificationFromEvent rebuilds a notification from its stream event
+func notificationFromEvent(event redisService.Event) Notification {
+	n := Notification{ID: event.ID, Timestamp: event.Timestamp}
+	n.Kind, _ = event.Data["kind"].(string)
+	n.Severity, _ = event.Data["severity"].(string)
+	n.Message, _ = event.Data["message"].(string)
+	n.Data, _ = event.Data["data"].(map[string]interface{})
+	if ts, ok := event.Data["timestamp"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			n.Timestamp = parsed
+		}
+	}
+	return n
+}
+
+// UserSignup builds the notification for a newly provisioned user. source
+// names how the account was created, e.g. "dex".
+func UserSignup(userID, email, source string) Notification {
+	return Notification{
+		Kind:     KindUserSignup,
+		Severity: SeverityInfo,
+		Message:  fmt.Sprintf("New user %s signed up", email),
+		Data: map[string]interface{}{
+			"user_id": userID,
+			"email":   email,
+			"source":  source,
+		},
+	}
+}
@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+func receive(t *testing.T, ch <-chan Notification) Notification {
+	t.Helper()
+	select {
+	case n, ok := <-ch:
+		require.True(t, ok, "subscription closed")
+		return n
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification received")
+		return Notification{}
+	}
+}
+
+func TestHub_InProcess(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	ch, unsubscribe := hub.Subscribe()
+
+	hub.Notify(context.Background(), UserSignup("u1", "ada@example.com", "dex"))
+
+	n := receive(t, ch)
+	assert.Equal(t, KindUserSignup, n.Kind)
+	assert.Equal(t, SeverityInfo, n.Severity)
+	assert.Equal(t, "ada@example.com", n.Data["email"])
+	assert.NotEmpty(t, n.ID)
+	assert.False(t, n.Timestamp.IsZero())
+
+	unsubscribe()
+	_, ok := <-ch
+	assert.False(t, ok)
+	unsubscribe()
+}
+
+func TestHub_CloseEndsSubscriptions(t *testing.T) {
+	hub := NewHub(nil, zap.NewNop())
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	hub.Close()
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	late, _ := hub.Subscribe()
+	_, ok = <-late
+	assert.False(t, ok)
+}
+
+func TestHub_NilIsNoop(t *testing.T) {
+	var hub *Hub
+	assert.NotPanics(t, func() {
+		hub.Notify(context.Background(), Notification{Kind: KindWorkerFailure})
+		hub.Close()
+	})
+}
+
+func TestHub_RelaysThroughRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	// The worker publishes, the server delivers to its dashboards
+	worker := NewHub(redisService.NewEventPublisher(client, zap.NewNop()), zap.NewNop())
+	server := NewHub(redisService.NewEventPublisher(client, zap.NewNop()), zap.NewNop())
+	ch, unsubscribe := server.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Run only reads events published after it starts
+	require.Eventually(t, func() bool {
+		worker.Notify(context.Background(), Notification{
+			Kind:     KindWorkerFailure,
+			Severity: SeverityCritical,
+			Message:  "batch failed",
+			Data:     map[string]interface{}{"worker": "usage_processor"},
+		})
+		select {
+		case n := <-ch:
+			assert.Equal(t, KindWorkerFailure, n.Kind)
+			assert.Equal(t, SeverityCritical, n.Severity)
+			assert.Equal(t, "batch failed", n.Message)
+			assert.Equal(t, "usage_processor", n.Data["worker"])
+			assert.False(t, n.Timestamp.IsZero())
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
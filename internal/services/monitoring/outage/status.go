@@ -0,0 +1,168 @@
+package outage
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// Status feed formats
+const (
+	FormatStatuspage = "statuspage"
+	FormatRSS        = "rss"
+)
+
+// maxStatusBytes bounds the size of a status feed response
+const maxStatusBytes = 4 << 20
+
+// DefaultStatusPages are the status feeds watched for each provider type
+// unless overridden in outage.status_pages
+func DefaultStatusPages() map[string]config.StatusPageConfig {
+	return map[string]config.StatusPageConfig{
+		"openai":    {URL: "https://status.openai.com/api/v2/incidents/unresolved.json", Format: FormatStatuspage},
+		"anthropic": {URL: "https://status.anthropic.com/api/v2/incidents/unresolved.json", Format: FormatStatuspage},
+		"azure":     {URL: "https://azure.status.microsoft/en-us/status/feed/", Format: FormatRSS},
+	}
+}
+
+// UpstreamIncident is an unresolved incident reported by a status feed
+type UpstreamIncident struct {
+	ID        string
+	Title     string
+	Impact    string // none, minor, major, critical; empty when the feed does not say
+	URL       string
+	StartedAt time.Time
+}
+
+// StatusSource reports a provider's unresolved incidents
+type StatusSource interface {
+	Fetch(ctx context.Context) ([]UpstreamIncident, error)
+}
+
+// NewStatusSource creates the source for a status feed
+func NewStatusSource(page config.StatusPageConfig, client *http.Client) (StatusSource, error) {
+	switch page.Format {
+	case "", FormatStatuspage:
+		return &statuspageSource{url: page.URL, client: client}, nil
+	case FormatRSS:
+		return &rssSource{url: page.URL, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown status page format %q", page.Format)
+	}
+}
+
+// statuspageSource reads an Atlassian Statuspage incidents/unresolved.json
+// endpoint. Incidents with no impact, such as notices, are ignored.
+type statuspageSource struct {
+	url    string
+	client *http.Client
+}
+
+func (s *statuspageSource) Fetch(ctx context.Context) ([]UpstreamIncident, error) {
+	body, err := fetch(ctx, s.client, s.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var page struct {
+		Incidents []struct {
+			ID        string    `json:"id"`
+			Name      string    `json:"name"`
+			Impact    string    `json:"impact"`
+			Shortlink string    `json:"shortlink"`
+			StartedAt time.Time `json:"started_at"`
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"incidents"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, fmt.Errorf("invalid status page response: %w", err)
+	}
+
+	var incidents []UpstreamIncident
+	for _, inc := range page.Incidents {
+		if inc.Impact == "none" {
+			continue
+		}
+		started := inc.StartedAt
+		if started.IsZero() {
+			started = inc.CreatedAt
+		}
+		incidents = append(incidents, UpstreamIncident{
+			ID:        inc.ID,
+			Title:     inc.Name,
+			Impact:    inc.Impact,
+			URL:       inc.Shortlink,
+			StartedAt: started,
+		})
+	}
+	return incidents, nil
+}
+
+// rssSource reads a feed listing only active incidents, like the Azure
+// status feed, where every item is an ongoing incident
+type rssSource struct {
+	url    string
+	client *http.Client
+}
+
+func (s *rssSource) Fetch(ctx context.Context) ([]UpstreamIncident, error) {
+	body, err := fetch(ctx, s.client, s.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed struct {
+		Items []struct {
+			GUID    string `xml:"guid"`
+			Title   string `xml:"title"`
+			Link    string `xml:"link"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("invalid status feed: %w", err)
+	}
+
+	incidents := make([]UpstreamIncident, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		id := item.GUID
+		if id == "" {
+			id = item.Link
+		}
+		started, _ := time.Parse(time.RFC1123Z, item.PubDate)
+		if started.IsZero() {
+			started, _ = time.Parse(time.RFC1123, item.PubDate)
+		}
+		incidents = append(incidents, UpstreamIncident{
+			ID:        id,
+			Title:     strings.TrimSpace(item.Title),
+			URL:       item.Link,
+			StartedAt: started,
+		})
+	}
+	return incidents, nil
+}
+
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status page returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxStatusBytes))
+}
@@ -0,0 +1,85 @@
+package outage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+const statuspageBody = `{
+  "page": {"id": "abc", "name": "OpenAI"},
+  "incidents": [
+    {"id": "inc1", "name": "Elevated error rates", "status": "investigating", "impact": "major",
+     "shortlink": "https://stspg.io/inc1", "started_at": "2026-10-16T08:00:00Z"},
+    {"id": "inc2", "name": "Scheduled notice", "status": "identified", "impact": "none",
+     "shortlink": "https://stspg.io/inc2", "started_at": "2026-10-16T07:00:00Z"}
+  ]
+}`
+
+const rssBody = `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Azure Status</title>
+    <item>
+      <guid>azure-1</guid>
+      <title> Azure OpenAI Service - East US - Degraded </title>
+      <link>https://azure.status.microsoft/en-us/status/</link>
+      <pubDate>Fri, 16 Oct 2026 08:00:00 +0000</pubDate>
+    </item>
+  </channel>
+</rss>`
+
+func serve(t *testing.T, body string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestStatuspageSource(t *testing.T) {
+	source, err := NewStatusSource(config.StatusPageConfig{URL: serve(t, statuspageBody)}, http.DefaultClient)
+	require.NoError(t, err)
+
+	incidents, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, incidents, 1, "incidents without impact are ignored")
+	assert.Equal(t, "inc1", incidents[0].ID)
+	assert.Equal(t, "Elevated error rates", incidents[0].Title)
+	assert.Equal(t, "major", incidents[0].Impact)
+	assert.Equal(t, "https://stspg.io/inc1", incidents[0].URL)
+	assert.Equal(t, time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC), incidents[0].StartedAt.UTC())
+}
+
+func TestRSSSource(t *testing.T) {
+	source, err := NewStatusSource(config.StatusPageConfig{URL: serve(t, rssBody), Format: FormatRSS}, http.DefaultClient)
+	require.NoError(t, err)
+
+	incidents, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, incidents, 1)
+	assert.Equal(t, "azure-1", incidents[0].ID)
+	assert.Equal(t, "Azure OpenAI Service - East US - Degraded", incidents[0].Title)
+	assert.Equal(t, time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC), incidents[0].StartedAt.UTC())
+}
+
+func TestStatusSource_Errors(t *testing.T) {
+	_, err := NewStatusSource(config.StatusPageConfig{URL: "https://example.com", Format: "atom"}, http.DefaultClient)
+	assert.Error(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	source, err := NewStatusSource(config.StatusPageConfig{URL: server.URL}, http.DefaultClient)
+	require.NoError(t, err)
+	_, err = source.Fetch(context.Background())
+	assert.ErrorContains(t, err, "503")
+}
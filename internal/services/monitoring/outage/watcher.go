@@ -0,0 +1,381 @@
+// Package outage watches provider status pages for declared upstream
+// incidents. While one lasts, the provider's instances get the configured
+// mitigation (a higher circuit breaker threshold, or routing that prefers
+// other providers) and the incident is recorded, together with whether our
+// own instances of the provider were failing at the same time.
+package outage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+)
+
+// impactRank orders Statuspage impacts; unknown impacts rank as major
+var impactRank = map[string]int{"minor": 1, "major": 2, "critical": 3}
+
+// Watcher polls provider status pages and mitigates declared incidents
+type Watcher struct {
+	cfg          config.OutageConfig
+	sources      map[string]StatusSource
+	modelManager *llmModels.ModelManager
+	db           *gorm.DB
+	notifier     *notifications.Hub
+	logger       *zap.Logger
+
+	mu     sync.Mutex
+	active map[string]*models.Incident // By provider type
+}
+
+// NewWatcher creates a Watcher. Incidents are recorded in db when it is not
+// nil; notifier may be nil.
+func NewWatcher(cfg config.OutageConfig, modelManager *llmModels.ModelManager, db *gorm.DB, notifier *notifications.Hub, logger *zap.Logger) *Watcher {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Minute
+	}
+	if cfg.Action == "" {
+		cfg.Action = config.OutageActionRaiseThreshold
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 10
+	}
+	if cfg.SpikeFailureRate <= 0 {
+		cfg.SpikeFailureRate = 0.5
+	}
+	logger = logger.Named("outage_watcher")
+
+	pages := DefaultStatusPages()
+	for provider, page := range cfg.StatusPages {
+		pages[provider] = page
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	sources := make(map[string]StatusSource, len(pages))
+	for provider, page := range pages {
+		if page.URL == "" {
+			continue
+		}
+		source, err := NewStatusSource(page, client)
+		if err != nil {
+			logger.Warn("Ignoring status page", zap.String("provider", provider), zap.Error(err))
+			continue
+		}
+		sources[provider] = source
+	}
+
+	return &Watcher{
+		cfg:          cfg,
+		sources:      sources,
+		modelManager: modelManager,
+		db:           db,
+		notifier:     notifier,
+		logger:       logger,
+		active:       make(map[string]*models.Incident),
+	}
+}
+
+// Run restores incidents still active from a previous run, then polls until
+// ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	w.restore(ctx)
+	w.Poll(ctx)
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Poll(ctx)
+		}
+	}
+}
+
+// Poll checks the status page of every provider we have instances of, or an
+// active incident for, once
+func (w *Watcher) Poll(ctx context.Context) {
+	for _, provider := range w.watchedProviders() {
+		incidents, err := w.sources[provider].Fetch(ctx)
+		if err != nil {
+			// An unreachable status page changes nothing
+			w.logger.Warn("Failed to fetch provider status", zap.String("provider", provider), zap.Error(err))
+			continue
+		}
+		w.update(ctx, provider, incidents, w.failureRate(provider))
+	}
+}
+
+// Active returns the incidents currently mitigated
+func (w *Watcher) Active() []models.Incident {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	incidents := make([]models.Incident, 0, len(w.active))
+	for _, incident := range w.active {
+		incidents = append(incidents, *incident)
+	}
+	sort.Slice(incidents, func(i, j int) bool { return incidents[i].Provider < incidents[j].Provider })
+	return incidents
+}
+
+func (w *Watcher) watchedProviders() []string {
+	used := make(map[string]bool)
+	for _, instance := range w.modelManager.GetRegistry().GetAllInstances() {
+		used[llmModels.UpstreamProvider(instance.Config)] = true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var providers []string
+	for provider := range w.sources {
+		if used[provider] || w.active[provider] != nil {
+			providers = append(providers, provider)
+		}
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// failureRate is the share of the provider's instances that are unhealthy
+// or whose latest request failed
+func (w *Watcher) failureRate(provider string) float64 {
+	total, failing := 0, 0
+	for _, instance := range w.modelManager.GetRegistry().GetAllInstances() {
+		if llmModels.UpstreamProvider(instance.Config) != provider {
+			continue
+		}
+		total++
+		if !instance.Healthy.Load() || instance.FailureCount.Load() > 0 {
+			failing++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(failing) / float64(total)
+}
+
+func (w *Watcher) update(ctx context.Context, provider string, upstream []UpstreamIncident, failureRate float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	active := w.active[provider]
+	switch {
+	case len(upstream) > 0 && active == nil:
+		w.declare(ctx, provider, worstIncident(upstream), failureRate)
+	case len(upstream) > 0:
+		w.observe(ctx, active, failureRate)
+	case active != nil:
+		w.resolve(ctx, active)
+	}
+}
+
+// declare records and mitigates a new incident. Another replica may have
+// recorded it already; only the replica that records it notifies.
+func (w *Watcher) declare(ctx context.Context, provider string, upstream UpstreamIncident, failureRate float64) {
+	incident := w.findActive(ctx, provider)
+	recorded := incident == nil
+	if incident == nil {
+		incident = &models.Incident{
+			Provider:    provider,
+			ExternalID:  upstream.ID,
+			Title:       upstream.Title,
+			Impact:      upstream.Impact,
+			URL:         upstream.URL,
+			Status:      models.IncidentStatusActive,
+			Action:      w.cfg.Action,
+			Correlated:  failureRate >= w.cfg.SpikeFailureRate,
+			FailureRate: failureRate,
+			StartedAt:   upstream.StartedAt,
+		}
+		if incident.StartedAt.IsZero() {
+			incident.StartedAt = time.Now()
+		}
+		if w.db != nil {
+			if err := w.db.WithContext(ctx).Create(incident).Error; err != nil {
+				w.logger.Error("Failed to record incident", zap.String("provider", provider), zap.Error(err))
+			}
+		}
+	}
+	w.active[provider] = incident
+	w.mitigate(incident)
+
+	w.logger.Warn("Upstream incident declared",
+		zap.String("provider", provider),
+		zap.String("title", incident.Title),
+		zap.String("impact", incident.Impact),
+		zap.String("action", incident.Action),
+		zap.Float64("failure_rate", failureRate))
+
+	if !recorded {
+		return
+	}
+	severity := notifications.SeverityWarning
+	if incident.Correlated {
+		severity = notifications.SeverityCritical
+	}
+	w.notifier.Notify(ctx, notifications.Notification{
+		Kind:     notifications.KindProviderIncident,
+		Severity: severity,
+		Message:  fmt.Sprintf("%s reports an incident: %s", provider, incident.Title),
+		Data:     incidentData(incident),
+	})
+}
+
+// observe tracks how our instances fare while an incident is active
+func (w *Watcher) observe(ctx context.Context, incident *models.Incident, failureRate float64) {
+	updates := map[string]interface{}{}
+	if failureRate > incident.FailureRate {
+		incident.FailureRate = failureRate
+		updates["failure_rate"] = failureRate
+	}
+	if !incident.Correlated && failureRate >= w.cfg.SpikeFailureRate {
+		incident.Correlated = true
+		updates["correlated"] = true
+		w.logger.Warn("Upstream incident confirmed by failing instances",
+			zap.String("provider", incident.Provider),
+			zap.Float64("failure_rate", failureRate))
+	}
+	w.save(ctx, incident, updates)
+}
+
+// resolve lifts the mitigation of an incident no longer reported. Only the
+// replica that marks it resolved notifies.
+func (w *Watcher) resolve(ctx context.Context, incident *models.Incident) {
+	now := time.Now()
+	incident.Status = models.IncidentStatusResolved
+	incident.ResolvedAt = &now
+
+	recorded := true
+	if w.db != nil && incident.ID != uuid.Nil {
+		result := w.db.WithContext(ctx).Model(&models.Incident{}).
+			Where("id = ? AND status = ?", incident.ID, models.IncidentStatusActive).
+			Updates(map[string]interface{}{
+				"status":      models.IncidentStatusResolved,
+				"resolved_at": now,
+			})
+		if result.Error != nil {
+			w.logger.Error("Failed to resolve incident", zap.String("incident_id", incident.ID.String()), zap.Error(result.Error))
+		}
+		recorded = result.Error == nil && result.RowsAffected > 0
+	}
+
+	delete(w.active, incident.Provider)
+	w.modelManager.ClearProviderIncident(incident.Provider)
+
+	w.logger.Info("Upstream incident resolved",
+		zap.String("provider", incident.Provider),
+		zap.String("title", incident.Title),
+		zap.Duration("duration", now.Sub(incident.StartedAt)))
+	if !recorded {
+		return
+	}
+	w.notifier.Notify(ctx, notifications.Notification{
+		Kind:     notifications.KindIncidentResolved,
+		Severity: notifications.SeverityInfo,
+		Message:  fmt.Sprintf("%s incident resolved: %s", incident.Provider, incident.Title),
+		Data:     incidentData(incident),
+	})
+}
+
+func (w *Watcher) mitigate(incident *models.Incident) {
+	if incident.Action == config.OutageActionNone {
+		return
+	}
+	w.modelManager.SetProviderIncident(incident.Provider, incident.Action, w.cfg.FailureThreshold)
+}
+
+// findActive returns the provider's active incident recorded by another
+// replica, if any
+func (w *Watcher) findActive(ctx context.Context, provider string) *models.Incident {
+	if w.db == nil {
+		return nil
+	}
+	var incident models.Incident
+	err := w.db.WithContext(ctx).
+		Where("provider = ? AND status = ?", provider, models.IncidentStatusActive).
+		Order("started_at DESC").
+		First(&incident).Error
+	if err != nil {
+		return nil
+	}
+	return &incident
+}
+
+// restore re-applies the mitigation of incidents still active when the
+// gateway restarted
+func (w *Watcher) restore(ctx context.Context) {
+	if w.db == nil {
+		return
+	}
+	var incidents []models.Incident
+	if err := w.db.WithContext(ctx).Where("status = ?", models.IncidentStatusActive).Find(&incidents).Error; err != nil {
+		w.logger.Warn("Failed to load active incidents", zap.Error(err))
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i := range incidents {
+		incident := &incidents[i]
+		if w.sources[incident.Provider] == nil {
+			continue
+		}
+		w.active[incident.Provider] = incident
+		w.mitigate(incident)
+	}
+}
+
+func (w *Watcher) save(ctx context.Context, incident *models.Incident, updates map[string]interface{}) {
+	if w.db == nil || incident.ID == uuid.Nil || len(updates) == 0 {
+		return
+	}
+	if err := w.db.WithContext(ctx).Model(incident).Updates(updates).Error; err != nil {
+		w.logger.Error("Failed to update incident", zap.String("incident_id", incident.ID.String()), zap.Error(err))
+	}
+}
+
+// worstIncident picks the incident with the highest impact; unknown impacts
+// rank as major, ties keep feed order
+func worstIncident(incidents []UpstreamIncident) UpstreamIncident {
+	worst := incidents[0]
+	for _, incident := range incidents[1:] {
+		if rank(incident.Impact) > rank(worst.Impact) {
+			worst = incident
+		}
+	}
+	return worst
+}
+
+func rank(impact string) int {
+	if r, ok := impactRank[impact]; ok {
+		return r
+	}
+	return impactRank["major"]
+}
+
+func incidentData(incident *models.Incident) map[string]interface{} {
+	return map[string]interface{}{
+		"incident_id":  incident.ID.String(),
+		"provider":     incident.Provider,
+		"title":        incident.Title,
+		"impact":       incident.Impact,
+		"url":          incident.URL,
+		"action":       incident.Action,
+		"correlated":   incident.Correlated,
+		"failure_rate": incident.FailureRate,
+	}
+}
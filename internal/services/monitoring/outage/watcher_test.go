@@ -0,0 +1,155 @@
+package outage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+	"github.com/amerfu/pllm/internal/infrastructure/testutil"
+	llmModels "github.com/amerfu/pllm/internal/services/llm/models"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
+)
+
+// statusPage serves statuspageBody while down is set, and no incidents otherwise
+func statusPage(t *testing.T, down *atomic.Bool) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			_, _ = w.Write([]byte(statuspageBody))
+			return
+		}
+		_, _ = w.Write([]byte(`{"incidents": []}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func newTestWatcher(t *testing.T, cfg config.OutageConfig, db *gorm.DB, notifier *notifications.Hub) (*Watcher, *llmModels.ModelManager) {
+	t.Helper()
+	manager := llmModels.NewModelManager(zap.NewNop(), config.RouterSettings{RoutingStrategy: "priority"}, nil)
+	for _, instance := range []config.ModelInstance{
+		{ID: "openai-1", ModelName: "gpt-4o", Enabled: true, Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o", APIKey: "test-key"}},
+		{ID: "openai-2", ModelName: "gpt-4o", Enabled: true, Provider: config.ProviderParams{Type: "openai", Model: "gpt-4o", APIKey: "test-key"}},
+	} {
+		require.NoError(t, manager.AddInstance(instance))
+	}
+	if cfg.StatusPages == nil {
+		cfg.StatusPages = map[string]config.StatusPageConfig{}
+	}
+	// Only the test status page is watched
+	for provider := range DefaultStatusPages() {
+		if _, ok := cfg.StatusPages[provider]; !ok {
+			cfg.StatusPages[provider] = config.StatusPageConfig{}
+		}
+	}
+	return NewWatcher(cfg, manager, db, notifier, zap.NewNop()), manager
+}
+
+func TestWatcher_DeclaresAndResolvesIncidents(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+
+	hub := notifications.NewHub(nil, zap.NewNop())
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	watcher, manager := newTestWatcher(t, config.OutageConfig{
+		Action:           config.OutageActionShiftRoutes,
+		StatusPages:      map[string]config.StatusPageConfig{"openai": {URL: statusPage(t, &down)}},
+		SpikeFailureRate: 0.5,
+	}, nil, hub)
+	instances, _ := manager.GetRegistry().GetModelInstances("gpt-4o")
+	tracker := manager.GetHealthTracker()
+
+	// Half of our instances are failing: the incident is correlated
+	manager.RecordFailure(instances[0], errors.New("upstream error"))
+	watcher.Poll(context.Background())
+
+	active := watcher.Active()
+	require.Len(t, active, 1)
+	assert.Equal(t, "openai", active[0].Provider)
+	assert.Equal(t, "inc1", active[0].ExternalID)
+	assert.Equal(t, "Elevated error rates", active[0].Title)
+	assert.Equal(t, config.OutageActionShiftRoutes, active[0].Action)
+	assert.True(t, active[0].Correlated)
+	assert.Equal(t, 0.5, active[0].FailureRate)
+	assert.True(t, tracker.IsDeprioritized(instances[1]))
+
+	require.Len(t, ch, 1)
+	n := <-ch
+	assert.Equal(t, notifications.KindProviderIncident, n.Kind)
+	assert.Equal(t, notifications.SeverityCritical, n.Severity)
+
+	// Still reported: nothing new is declared
+	watcher.Poll(context.Background())
+	assert.Len(t, ch, 0)
+
+	down.Store(false)
+	watcher.Poll(context.Background())
+	assert.Empty(t, watcher.Active())
+	assert.False(t, tracker.IsDeprioritized(instances[1]))
+	require.Len(t, ch, 1)
+	assert.Equal(t, notifications.KindIncidentResolved, (<-ch).Kind)
+}
+
+func TestWatcher_UnreachableStatusPageKeepsState(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	url := statusPage(t, &down)
+
+	watcher, _ := newTestWatcher(t, config.OutageConfig{
+		StatusPages: map[string]config.StatusPageConfig{"openai": {URL: url}},
+	}, nil, nil)
+	watcher.Poll(context.Background())
+	require.Len(t, watcher.Active(), 1)
+	assert.Equal(t, config.OutageActionRaiseThreshold, watcher.Active()[0].Action)
+	assert.False(t, watcher.Active()[0].Correlated)
+
+	watcher.sources["openai"] = &statuspageSource{url: "http://127.0.0.1:0", client: http.DefaultClient}
+	watcher.Poll(context.Background())
+	assert.Len(t, watcher.Active(), 1)
+}
+
+func TestWatcher_RecordsIncidents(t *testing.T) {
+	gormDB, cleanup := testutil.NewTestDB(t)
+	defer cleanup()
+	require.NoError(t, gormDB.AutoMigrate(&models.Incident{}))
+
+	var down atomic.Bool
+	down.Store(true)
+	cfg := config.OutageConfig{StatusPages: map[string]config.StatusPageConfig{"openai": {URL: statusPage(t, &down)}}}
+
+	watcher, _ := newTestWatcher(t, cfg, gormDB, nil)
+	watcher.Poll(context.Background())
+
+	var incident models.Incident
+	require.NoError(t, gormDB.Where("provider = ?", "openai").First(&incident).Error)
+	assert.Equal(t, models.IncidentStatusActive, incident.Status)
+	assert.Equal(t, "inc1", incident.ExternalID)
+
+	// A restarted gateway re-applies the mitigation of active incidents
+	restarted, manager := newTestWatcher(t, cfg, gormDB, nil)
+	restarted.restore(context.Background())
+	require.Len(t, restarted.Active(), 1)
+	assert.Equal(t, incident.ID, restarted.Active()[0].ID)
+	instances, _ := manager.GetRegistry().GetModelInstances("gpt-4o")
+	for i := 0; i < 5; i++ {
+		manager.RecordFailure(instances[0], errors.New("upstream error"))
+	}
+	assert.True(t, instances[0].Healthy.Load(), "raised threshold restored")
+
+	down.Store(false)
+	restarted.Poll(context.Background())
+	require.NoError(t, gormDB.First(&incident, "id = ?", incident.ID).Error)
+	assert.Equal(t, models.IncidentStatusResolved, incident.Status)
+	assert.NotNil(t, incident.ResolvedAt)
+}
@@ -0,0 +1,86 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// defaultMinGroupSize is the k-anonymity threshold when none is configured
+const defaultMinGroupSize = 5
+
+// pseudonymPrefix marks identifiers that were pseudonymized
+const pseudonymPrefix = "anon_"
+
+// Anonymizer applies the anonymized analytics mode: aggregates are withheld
+// for groups of fewer than MinGroupSize distinct users, and user identifiers
+// are replaced with stable pseudonyms. A nil Anonymizer leaves analytics
+// unchanged.
+type Anonymizer struct {
+	minGroupSize int
+	key          []byte
+}
+
+// NewAnonymizer returns the anonymizer for the configuration, or nil when the
+// mode is disabled. Pseudonyms are keyed with the configured secret, or
+// fallbackSecret when none is set, so they cannot be reversed by hashing
+// known user IDs.
+func NewAnonymizer(cfg config.AnalyticsPrivacyConfig, fallbackSecret string) *Anonymizer {
+	if !cfg.Enabled {
+		return nil
+	}
+	a := &Anonymizer{minGroupSize: cfg.MinGroupSize, key: []byte(cfg.PseudonymSecret)}
+	if a.minGroupSize <= 0 {
+		a.minGroupSize = defaultMinGroupSize
+	}
+	if len(a.key) == 0 {
+		a.key = []byte(fallbackSecret)
+	}
+	return a
+}
+
+// Enabled reports whether analytics are anonymized
+func (a *Anonymizer) Enabled() bool {
+	return a != nil
+}
+
+// MinGroupSize returns the smallest number of distinct users an aggregate
+// may describe, 0 when analytics are not anonymized
+func (a *Anonymizer) MinGroupSize() int {
+	if a == nil {
+		return 0
+	}
+	return a.minGroupSize
+}
+
+// Allows reports whether an aggregate over the given number of distinct
+// users may be returned
+func (a *Anonymizer) Allows(users int) bool {
+	return a == nil || users >= a.minGroupSize
+}
+
+// Pseudonym replaces a user identifier with a stable pseudonym. The same ID
+// always maps to the same pseudonym, so per-user rows can still be compared
+// across requests.
+func (a *Anonymizer) Pseudonym(id string) string {
+	if a == nil || id == "" {
+		return id
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(id))
+	return pseudonymPrefix + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// HavingMinUsers returns a HAVING clause that drops SQL groups with fewer
+// than MinGroupSize distinct values of userColumn, or "" when analytics are
+// not anonymized. Without GROUP BY it applies to the whole result, so a
+// total over too few users returns no row.
+func (a *Anonymizer) HavingMinUsers(userColumn string) string {
+	if a == nil {
+		return ""
+	}
+	return fmt.Sprintf("HAVING COUNT(DISTINCT %s) >= %d", userColumn, a.minGroupSize)
+}
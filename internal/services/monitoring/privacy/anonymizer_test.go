@@ -0,0 +1,49 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func TestAnonymizer_Disabled(t *testing.T) {
+	a := NewAnonymizer(config.AnalyticsPrivacyConfig{MinGroupSize: 10}, "secret")
+	require.Nil(t, a)
+
+	assert.False(t, a.Enabled())
+	assert.True(t, a.Allows(1))
+	assert.Zero(t, a.MinGroupSize())
+	assert.Equal(t, "user-1", a.Pseudonym("user-1"))
+	assert.Empty(t, a.HavingMinUsers("actual_user_id"))
+}
+
+func TestAnonymizer_MinGroupSize(t *testing.T) {
+	a := NewAnonymizer(config.AnalyticsPrivacyConfig{Enabled: true}, "secret")
+	assert.True(t, a.Enabled())
+	assert.Equal(t, 5, a.MinGroupSize(), "defaults to 5")
+	assert.False(t, a.Allows(4))
+	assert.True(t, a.Allows(5))
+
+	a = NewAnonymizer(config.AnalyticsPrivacyConfig{Enabled: true, MinGroupSize: 20}, "secret")
+	assert.False(t, a.Allows(19))
+	assert.Equal(t, "HAVING COUNT(DISTINCT actual_user_id) >= 20", a.HavingMinUsers("actual_user_id"))
+}
+
+func TestAnonymizer_Pseudonym(t *testing.T) {
+	a := NewAnonymizer(config.AnalyticsPrivacyConfig{Enabled: true, PseudonymSecret: "one"}, "fallback")
+
+	pseudonym := a.Pseudonym("user-1")
+	assert.Regexp(t, `^anon_[0-9a-f]{16}$`, pseudonym)
+	assert.Equal(t, pseudonym, a.Pseudonym("user-1"), "pseudonyms are stable")
+	assert.NotEqual(t, pseudonym, a.Pseudonym("user-2"))
+	assert.Empty(t, a.Pseudonym(""))
+
+	other := NewAnonymizer(config.AnalyticsPrivacyConfig{Enabled: true, PseudonymSecret: "two"}, "fallback")
+	assert.NotEqual(t, pseudonym, other.Pseudonym("user-1"), "pseudonyms depend on the secret")
+
+	fallback := NewAnonymizer(config.AnalyticsPrivacyConfig{Enabled: true}, "fallback")
+	assert.NotEqual(t, pseudonym, fallback.Pseudonym("user-1"))
+}
@@ -0,0 +1,200 @@
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+// Mode controls where provenance metadata is attached to a response
+type Mode string
+
+const (
+	ModeHeaders Mode = "headers" // X-PLLM-Provenance-* response headers
+	ModeField   Mode = "field"   // pllm_provenance extension field in the body
+	ModeBoth    Mode = "both"
+)
+
+// Response headers used in headers mode
+const (
+	HeaderModel       = "X-PLLM-Provenance-Model"
+	HeaderProvider    = "X-PLLM-Provenance-Provider"
+	HeaderRequestID   = "X-PLLM-Provenance-Request-Id"
+	HeaderTimestamp   = "X-PLLM-Provenance-Timestamp"
+	HeaderContentHash = "X-PLLM-Provenance-Content-Hash"
+)
+
+// hashPrefix identifies the hash algorithm so it can be changed later
+const hashPrefix = "sha256:"
+
+// Stamper attaches provenance metadata to LLM responses
+type Stamper struct {
+	enabled bool
+	mode    Mode
+}
+
+// NewStamper creates a stamper from the provenance configuration
+func NewStamper(cfg *config.ProvenanceConfig) *Stamper {
+	if cfg == nil {
+		return &Stamper{}
+	}
+
+	mode := Mode(strings.ToLower(cfg.Mode))
+	switch mode {
+	case ModeHeaders, ModeField, ModeBoth:
+	default:
+		mode = ModeHeaders
+	}
+
+	return &Stamper{
+		enabled: cfg.Enabled,
+		mode:    mode,
+	}
+}
+
+// IsEnabled returns whether provenance stamping is enabled
+func (s *Stamper) IsEnabled() bool {
+	return s != nil && s.enabled
+}
+
+// UsesHeaders returns whether provenance is sent as response headers
+func (s *Stamper) UsesHeaders() bool {
+	return s.IsEnabled() && (s.mode == ModeHeaders || s.mode == ModeBoth)
+}
+
+// UsesField returns whether provenance is sent as an extension field
+func (s *Stamper) UsesField() bool {
+	return s.IsEnabled() && (s.mode == ModeField || s.mode == ModeBoth)
+}
+
+// New builds a provenance record for the given content
+func New(model, provider, requestID, content string) *providers.Provenance {
+	return &providers.Provenance{
+		Model:       model,
+		Provider:    provider,
+		RequestID:   requestID,
+		Timestamp:   time.Now().Unix(),
+		ContentHash: HashContent(content),
+	}
+}
+
+// Stamp attaches provenance to a non-streaming chat response according to the
+// configured mode. It must be called before the response body is written.
+func (s *Stamper) Stamp(w http.ResponseWriter, response *providers.ChatResponse, p *providers.Provenance) {
+	if !s.IsEnabled() || p == nil {
+		return
+	}
+
+	if s.UsesHeaders() {
+		WriteHeaders(w.Header(), p)
+	}
+	if s.UsesField() {
+		response.Provenance = p
+	}
+}
+
+// WriteHeaders sets the provenance response headers. The content hash is only
+// written when known, which is not the case for streamed responses.
+func WriteHeaders(h http.Header, p *providers.Provenance) {
+	h.Set(HeaderModel, p.Model)
+	if p.Provider != "" {
+		h.Set(HeaderProvider, p.Provider)
+	}
+	h.Set(HeaderRequestID, p.RequestID)
+	h.Set(HeaderTimestamp, strconv.FormatInt(p.Timestamp, 10))
+	if p.ContentHash != "" {
+		h.Set(HeaderContentHash, p.ContentHash)
+	}
+}
+
+// HashContent returns the provenance hash for a piece of generated content
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// NormalizeHash accepts a hash with or without the algorithm prefix
+func NormalizeHash(hash string) string {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if !strings.HasPrefix(hash, hashPrefix) {
+		hash = hashPrefix + hash
+	}
+	return hash
+}
+
+// ResponseContent extracts the generated text from a chat response. Multiple
+// choices are joined with a newline, in index order.
+func ResponseContent(response *providers.ChatResponse) string {
+	parts := make([]string, 0, len(response.Choices))
+	for _, choice := range response.Choices {
+		parts = append(parts, MessageText(choice.Message.Content))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// MessageText converts message content (string or content blocks) to text
+func MessageText(content interface{}) string {
+	switch c := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return c
+	default:
+		data, err := json.Marshal(c)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// StreamContent accumulates streamed deltas per choice so a streamed response
+// hashes the same as the equivalent non-streaming one
+type StreamContent struct {
+	choices map[int]*strings.Builder
+}
+
+// Add appends the content deltas of a stream chunk
+func (c *StreamContent) Add(chunk *providers.StreamResponse) {
+	for _, choice := range chunk.Choices {
+		text := MessageText(choice.Delta.Content)
+		if text == "" {
+			continue
+		}
+		if c.choices == nil {
+			c.choices = make(map[int]*strings.Builder)
+		}
+		builder, ok := c.choices[choice.Index]
+		if !ok {
+			builder = &strings.Builder{}
+			c.choices[choice.Index] = builder
+		}
+		builder.WriteString(text)
+	}
+}
+
+// Content returns the streamed text in the form ResponseContent produces
+func (c *StreamContent) Content() string {
+	indexes := make([]int, 0, len(c.choices))
+	for index := range c.choices {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	response := &providers.ChatResponse{Choices: make([]providers.Choice, 0, len(indexes))}
+	for _, index := range indexes {
+		response.Choices = append(response.Choices, providers.Choice{
+			Index:   index,
+			Message: providers.Message{Role: "assistant", Content: c.choices[index].String()},
+		})
+	}
+	return ResponseContent(response)
+}
@@ -0,0 +1,93 @@
+package provenance
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/services/llm/providers"
+)
+
+func TestHashContent(t *testing.T) {
+	hash := HashContent("hello")
+	assert.Equal(t, "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", hash)
+	assert.Equal(t, hash, NormalizeHash("2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"))
+	assert.Equal(t, hash, NormalizeHash(hash))
+}
+
+func TestResponseContent(t *testing.T) {
+	response := &providers.ChatResponse{
+		Choices: []providers.Choice{
+			{Message: providers.Message{Role: "assistant", Content: "first"}},
+			{Message: providers.Message{Role: "assistant", Content: "second"}},
+		},
+	}
+	assert.Equal(t, "first\nsecond", ResponseContent(response))
+}
+
+func TestStamperModes(t *testing.T) {
+	p := New("gpt-4o", "openai", "req-1", "hello")
+
+	t.Run("disabled", func(t *testing.T) {
+		s := NewStamper(&config.ProvenanceConfig{Enabled: false, Mode: "both"})
+		w := httptest.NewRecorder()
+		response := &providers.ChatResponse{}
+		s.Stamp(w, response, p)
+		assert.Nil(t, response.Provenance)
+		assert.Empty(t, w.Header().Get(HeaderContentHash))
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		s := NewStamper(&config.ProvenanceConfig{Enabled: true, Mode: "headers"})
+		w := httptest.NewRecorder()
+		response := &providers.ChatResponse{}
+		s.Stamp(w, response, p)
+		assert.Nil(t, response.Provenance)
+		assert.Equal(t, p.ContentHash, w.Header().Get(HeaderContentHash))
+		assert.Equal(t, "gpt-4o", w.Header().Get(HeaderModel))
+		assert.Equal(t, "req-1", w.Header().Get(HeaderRequestID))
+	})
+
+	t.Run("field", func(t *testing.T) {
+		s := NewStamper(&config.ProvenanceConfig{Enabled: true, Mode: "field"})
+		w := httptest.NewRecorder()
+		response := &providers.ChatResponse{}
+		s.Stamp(w, response, p)
+		assert.Equal(t, p, response.Provenance)
+		assert.Empty(t, w.Header().Get(HeaderContentHash))
+	})
+
+	t.Run("unknown mode falls back to headers", func(t *testing.T) {
+		s := NewStamper(&config.ProvenanceConfig{Enabled: true, Mode: "bogus"})
+		assert.True(t, s.UsesHeaders())
+		assert.False(t, s.UsesField())
+	})
+}
+
+func TestStreamContentMatchesResponseContent(t *testing.T) {
+	chunks := []providers.StreamResponse{
+		{Choices: []providers.StreamChoice{{Index: 1, Delta: providers.Message{Content: "sec"}}}},
+		{Choices: []providers.StreamChoice{{Index: 0, Delta: providers.Message{Role: "assistant", Content: "fir"}}}},
+		{Choices: []providers.StreamChoice{
+			{Index: 0, Delta: providers.Message{Content: "st"}},
+			{Index: 1, Delta: providers.Message{Content: "ond"}},
+		}},
+		{Choices: []providers.StreamChoice{{Index: 0, FinishReason: "stop"}}},
+	}
+
+	var content StreamContent
+	for i := range chunks {
+		content.Add(&chunks[i])
+	}
+
+	response := &providers.ChatResponse{
+		Choices: []providers.Choice{
+			{Index: 0, Message: providers.Message{Role: "assistant", Content: "first"}},
+			{Index: 1, Message: providers.Message{Role: "assistant", Content: "second"}},
+		},
+	}
+	assert.Equal(t, ResponseContent(response), content.Content())
+	assert.Equal(t, HashContent(ResponseContent(response)), HashContent(content.Content()))
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// ConcurrencyLimiter caps the number of in-flight requests per key. Every
+// successful Acquire must be paired with a Release when the request ends.
+type ConcurrencyLimiter interface {
+	Acquire(ctx context.Context, key string, limit int) (bool, error)
+	Release(ctx context.Context, key string) error
+	InFlight(ctx context.Context, key string) (int, error)
+}
+
+// concurrencyCounterTTL bounds how long a counter leaked by a crashed
+// instance survives once its key stops receiving traffic
+const concurrencyCounterTTL = 10 * time.Minute
+
+// acquireScript increments the counter and backs out when over the limit
+var acquireScript = redis.NewScript(`
+local current = redis.call('INCR', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ARGV[2])
+if current > tonumber(ARGV[1]) then
+	redis.call('DECR', KEYS[1])
+	return 0
+end
+return 1
+`)
+
+// releaseScript decrements the counter, removing it when it reaches zero
+var releaseScript = redis.NewScript(`
+local current = redis.call('DECR', KEYS[1])
+if current <= 0 then
+	redis.call('DEL', KEYS[1])
+end
+return current
+`)
+
+// RedisConcurrencyLimiter shares in-flight counters between gateway replicas
+type RedisConcurrencyLimiter struct {
+	client *redis.Client
+	log    *zap.Logger
+}
+
+func NewRedisConcurrencyLimiter(client *redis.Client, log *zap.Logger) *RedisConcurrencyLimiter {
+	return &RedisConcurrencyLimiter{
+		client: client,
+		log:    log,
+	}
+}
+
+func (r *RedisConcurrencyLimiter) Acquire(ctx context.Context, key string, limit int) (bool, error) {
+	result, err := acquireScript.Run(ctx, r.client, []string{redisService.Key(key)}, limit, int(concurrencyCounterTTL.Seconds())).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire concurrency slot: %w", err)
+	}
+	return result == 1, nil
+}
+
+func (r *RedisConcurrencyLimiter) Release(ctx context.Context, key string) error {
+	if err := releaseScript.Run(ctx, r.client, []string{redisService.Key(key)}).Err(); err != nil {
+		return fmt.Errorf("failed to release concurrency slot: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisConcurrencyLimiter) InFlight(ctx context.Context, key string) (int, error) {
+	count, err := r.client.Get(ctx, redisService.Key(key)).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
+// InMemoryConcurrencyLimiter for lite mode
+type InMemoryConcurrencyLimiter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func NewInMemoryConcurrencyLimiter() *InMemoryConcurrencyLimiter {
+	return &InMemoryConcurrencyLimiter{
+		counts: make(map[string]int),
+	}
+}
+
+func (l *InMemoryConcurrencyLimiter) Acquire(ctx context.Context, key string, limit int) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.counts[key] >= limit {
+		return false, nil
+	}
+	l.counts[key]++
+	return true, nil
+}
+
+func (l *InMemoryConcurrencyLimiter) Release(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.counts[key]--
+	if l.counts[key] <= 0 {
+		delete(l.counts, key)
+	}
+	return nil
+}
+
+func (l *InMemoryConcurrencyLimiter) InFlight(ctx context.Context, key string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.counts[key], nil
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testConcurrencyLimiter(t *testing.T, limiter ConcurrencyLimiter) {
+	ctx := context.Background()
+	key := "concurrency:key:test"
+
+	for i := 0; i < 2; i++ {
+		ok, err := limiter.Acquire(ctx, key, 2)
+		require.NoError(t, err)
+		assert.True(t, ok, "slot %d should be granted", i+1)
+	}
+
+	ok, err := limiter.Acquire(ctx, key, 2)
+	require.NoError(t, err)
+	assert.False(t, ok, "third concurrent request should be rejected")
+
+	inFlight, err := limiter.InFlight(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inFlight, "rejected attempts must not hold a slot")
+
+	require.NoError(t, limiter.Release(ctx, key))
+	ok, err = limiter.Acquire(ctx, key, 2)
+	require.NoError(t, err)
+	assert.True(t, ok, "released slot should be reusable")
+
+	require.NoError(t, limiter.Release(ctx, key))
+	require.NoError(t, limiter.Release(ctx, key))
+	inFlight, err = limiter.InFlight(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, 0, inFlight)
+}
+
+func TestInMemoryConcurrencyLimiter(t *testing.T) {
+	testConcurrencyLimiter(t, NewInMemoryConcurrencyLimiter())
+}
+
+func TestRedisConcurrencyLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	testConcurrencyLimiter(t, NewRedisConcurrencyLimiter(client, zap.NewNop()))
+	assert.False(t, mr.Exists("concurrency:key:test"), "counter should be removed when idle")
+}
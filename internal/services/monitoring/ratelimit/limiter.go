@@ -8,6 +8,8 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
 )
 
 type RateLimiter interface {
@@ -34,6 +36,7 @@ func (r *RedisLimiter) Allow(ctx context.Context, key string, limit int, window
 }
 
 func (r *RedisLimiter) AllowN(ctx context.Context, key string, n int, limit int, window time.Duration) (bool, error) {
+	key = redisService.Key(key)
 	now := time.Now().UnixNano()
 	windowStart := now - window.Nanoseconds()
 
@@ -80,10 +83,11 @@ func (r *RedisLimiter) AllowN(ctx context.Context, key string, n int, limit int,
 }
 
 func (r *RedisLimiter) Reset(ctx context.Context, key string) error {
-	return r.client.Del(ctx, key).Err()
+	return r.client.Del(ctx, redisService.Key(key)).Err()
 }
 
 func (r *RedisLimiter) GetRemaining(ctx context.Context, key string, limit int, window time.Duration) (int, error) {
+	key = redisService.Key(key)
 	now := time.Now().UnixNano()
 	windowStart := now - window.Nanoseconds()
 
@@ -269,7 +273,7 @@ func (f *FixedWindowLimiter) AllowN(ctx context.Context, key string, n int, limi
 }
 
 func (f *FixedWindowLimiter) Reset(ctx context.Context, key string) error {
-	pattern := fmt.Sprintf("%s:*", key)
+	pattern := redisService.Key(fmt.Sprintf("%s:*", key))
 	keys, err := f.client.Keys(ctx, pattern).Result()
 	if err != nil {
 		return err
@@ -303,5 +307,5 @@ func (f *FixedWindowLimiter) GetRemaining(ctx context.Context, key string, limit
 
 func (f *FixedWindowLimiter) getWindowKey(key string, window time.Duration) string {
 	windowStart := time.Now().Truncate(window).Unix()
-	return fmt.Sprintf("%s:%d", key, windowStart)
+	return redisService.Key(fmt.Sprintf("%s:%d", key, windowStart))
 }
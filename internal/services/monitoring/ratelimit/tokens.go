@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// TokenCounter counts tokens per key in fixed windows, for tokens-per-minute
+// limits. Token counts are only known once a response is complete, so usage
+// is added after the fact and checked before the next request.
+type TokenCounter interface {
+	Add(ctx context.Context, key string, tokens int, window time.Duration) error
+	Used(ctx context.Context, key string, window time.Duration) (int, error)
+}
+
+func windowKey(key string, window time.Duration, now time.Time) string {
+	return fmt.Sprintf("%s:%d", key, now.UnixNano()/int64(window))
+}
+
+// RedisTokenCounter shares token counts across instances
+type RedisTokenCounter struct {
+	client *redis.Client
+}
+
+func NewRedisTokenCounter(client *redis.Client) *RedisTokenCounter {
+	return &RedisTokenCounter{client: client}
+}
+
+func (c *RedisTokenCounter) Add(ctx context.Context, key string, tokens int, window time.Duration) error {
+	counterKey := redisService.Key(windowKey(key, window, time.Now()))
+	pipe := c.client.Pipeline()
+	pipe.IncrBy(ctx, counterKey, int64(tokens))
+	pipe.Expire(ctx, counterKey, 2*window)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisTokenCounter) Used(ctx context.Context, key string, window time.Duration) (int, error) {
+	used, err := c.client.Get(ctx, redisService.Key(windowKey(key, window, time.Now()))).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return used, err
+}
+
+// InMemoryTokenCounter counts tokens of a single instance
+type InMemoryTokenCounter struct {
+	mu       sync.Mutex
+	counters map[string]int
+	window   map[string]string
+}
+
+func NewInMemoryTokenCounter() *InMemoryTokenCounter {
+	return &InMemoryTokenCounter{
+		counters: make(map[string]int),
+		window:   make(map[string]string),
+	}
+}
+
+func (c *InMemoryTokenCounter) Add(ctx context.Context, key string, tokens int, window time.Duration) error {
+	current := windowKey(key, window, time.Now())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.window[key] != current {
+		c.window[key] = current
+		c.counters[key] = 0
+	}
+	c.counters[key] += tokens
+	return nil
+}
+
+func (c *InMemoryTokenCounter) Used(ctx context.Context, key string, window time.Duration) (int, error) {
+	current := windowKey(key, window, time.Now())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.window[key] != current {
+		return 0, nil
+	}
+	return c.counters[key], nil
+}
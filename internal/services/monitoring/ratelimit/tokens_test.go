@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTokenCounter(t *testing.T, counter TokenCounter) {
+	ctx := context.Background()
+
+	used, err := counter.Used(ctx, "tpm:key", time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, used)
+
+	require.NoError(t, counter.Add(ctx, "tpm:key", 120, time.Hour))
+	require.NoError(t, counter.Add(ctx, "tpm:key", 30, time.Hour))
+	require.NoError(t, counter.Add(ctx, "tpm:other", 7, time.Hour))
+
+	used, err = counter.Used(ctx, "tpm:key", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 150, used)
+
+	// Counts start over in every window
+	require.NoError(t, counter.Add(ctx, "tpm:short", 50, 50*time.Millisecond))
+	time.Sleep(60 * time.Millisecond)
+	used, err = counter.Used(ctx, "tpm:short", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Zero(t, used)
+}
+
+func TestInMemoryTokenCounter(t *testing.T) {
+	testTokenCounter(t, NewInMemoryTokenCounter())
+}
+
+func TestRedisTokenCounter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testTokenCounter(t, NewRedisTokenCounter(client))
+}
@@ -0,0 +1,84 @@
+// Package usernotify emails users about their own usage: alerts when a
+// personal key crosses a spend threshold and a weekly usage digest. Admin
+// alerts go through the notifications hub instead.
+package usernotify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer creates a mailer for the SMTP server
+func NewSMTPMailer(cfg config.SMTPConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, errors.New("smtp host and from address are required")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+
+	m := &SMTPMailer{addr: net.JoinHostPort(cfg.Host, strconv.Itoa(port)), from: cfg.From}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return m, nil
+}
+
+// Send sends the message, upgrading the connection with STARTTLS when the
+// server offers it
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, encodeMessage(m.from, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// encodeMessage formats the message with its headers. Header values have
+// line breaks removed so user-controlled text cannot add headers.
+func encodeMessage(from string, msg Message, date time.Time) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", clean.Replace(from))
+	fmt.Fprintf(&buf, "To: %s\r\n", clean.Replace(msg.To))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", clean.Replace(msg.Subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes()
+}
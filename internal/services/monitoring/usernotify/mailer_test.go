@@ -0,0 +1,51 @@
+package usernotify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amerfu/pllm/internal/core/config"
+)
+
+func TestEncodeMessage(t *testing.T) {
+	date := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	msg := Message{
+		To:      "ada@example.com",
+		Subject: "Budget \"laptop\"\r\nBcc: attacker@example.com",
+		Body:    "line one\nline two\r\n",
+	}
+
+	encoded := string(encodeMessage("pllm <noreply@example.com>", msg, date))
+	headers, body, found := strings.Cut(encoded, "\r\n\r\n")
+	require.True(t, found)
+
+	assert.Contains(t, headers, "From: pllm <noreply@example.com>\r\n")
+	assert.Contains(t, headers, "To: ada@example.com\r\n")
+	assert.Contains(t, headers, "Date: Mon, 12 Oct 2026 09:00:00 +0000\r\n")
+	assert.NotContains(t, headers, "\r\nBcc:", "line breaks in the subject cannot add headers")
+	assert.Equal(t, "line one\r\nline two\r\n", body)
+}
+
+func TestEncodeMessage_NonASCIISubject(t *testing.T) {
+	encoded := string(encodeMessage("noreply@example.com", Message{To: "a@example.com", Subject: "Budget über 80%"}, time.Now()))
+	assert.Contains(t, encoded, "Subject: =?utf-8?q?")
+}
+
+func TestNewSMTPMailer(t *testing.T) {
+	_, err := NewSMTPMailer(config.SMTPConfig{Host: "smtp.example.com"})
+	assert.Error(t, err, "a from address is required")
+
+	m, err := NewSMTPMailer(config.SMTPConfig{Host: "smtp.example.com", From: "noreply@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.example.com:587", m.addr)
+	assert.Nil(t, m.auth)
+
+	m, err = NewSMTPMailer(config.SMTPConfig{Host: "smtp.example.com", Port: 465, Username: "pllm", From: "noreply@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.example.com:465", m.addr)
+	assert.NotNil(t, m.auth)
+}
@@ -0,0 +1,489 @@
+package usernotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+// ErrInvalidThreshold is returned for thresholds that are not a percentage of
+// the key budget
+var ErrInvalidThreshold = errors.New("thresholds must be percentages between 0 and 1000")
+
+// defaultThresholds are the spend alert thresholds, in percent of a key's
+// budget, when none are configured
+var defaultThresholds = []float64{50, 80, 100}
+
+var emailsSent = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pllm_user_notifications_total",
+		Help: "Total number of notification emails sent to users by kind and result",
+	},
+	[]string{"kind", "result"},
+)
+
+// Service sends spend alerts and weekly digests. Every notification is
+// claimed in the database before it is sent, so several workers can run the
+// service without emailing a user twice.
+type Service struct {
+	db      *gorm.DB
+	mailer  Mailer
+	cfg     config.UserNotificationsConfig
+	weekday time.Weekday
+	logger  *zap.Logger
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewService creates the notification service
+func NewService(db *gorm.DB, mailer Mailer, cfg config.UserNotificationsConfig, logger *zap.Logger) (*Service, error) {
+	weekday, err := ParseWeekday(cfg.DigestWeekday)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DigestHour < 0 || cfg.DigestHour > 23 {
+		return nil, fmt.Errorf("digest hour must be between 0 and 23, got %d", cfg.DigestHour)
+	}
+	if len(cfg.Thresholds) == 0 {
+		cfg.Thresholds = defaultThresholds
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = 5 * time.Minute
+	}
+	return &Service{
+		db:      db,
+		mailer:  mailer,
+		cfg:     cfg,
+		weekday: weekday,
+		logger:  logger,
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// ParseWeekday parses a weekday name such as "monday"; empty is Monday
+func ParseWeekday(name string) (time.Weekday, error) {
+	if name == "" {
+		return time.Monday, nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid digest weekday %q", name)
+}
+
+// Start checks for notifications every check interval until Stop
+func (s *Service) Start(ctx context.Context) {
+	s.logger.Info("Starting user notifications",
+		zap.Duration("check_interval", s.cfg.CheckInterval),
+		zap.Float64s("thresholds", s.cfg.Thresholds),
+		zap.String("digest_weekday", s.weekday.String()),
+		zap.Int("digest_hour", s.cfg.DigestHour))
+
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.Run(ctx, time.Now())
+		}
+	}
+}
+
+// Stop stops the service
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// Run sends the spend alerts and digests that are due
+func (s *Service) Run(ctx context.Context, now time.Time) {
+	if err := s.CheckSpend(ctx); err != nil {
+		s.logger.Error("Failed to check key spend for user notifications", zap.Error(err))
+	}
+	if err := s.SendDigests(ctx, now); err != nil {
+		s.logger.Error("Failed to send weekly digests", zap.Error(err))
+	}
+}
+
+// CheckSpend emails the owners of personal keys whose spend crossed a
+// threshold of the key budget. Each threshold is sent once per budget
+// period; when several were crossed at once only the highest is sent.
+func (s *Service) CheckSpend(ctx context.Context) error {
+	var keys []models.Key
+	if err := s.db.WithContext(ctx).Preload("User").
+		Where("is_active = ? AND user_id IS NOT NULL AND team_id IS NULL", true).
+		Where("max_budget > 0 AND current_spend > 0").
+		Find(&keys).Error; err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(keys))
+	for _, key := range keys {
+		userIDs = append(userIDs, *key.UserID)
+	}
+	prefs, err := s.preferences(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range keys {
+		key := &keys[i]
+		if key.User == nil || !key.User.IsActive || key.User.Email == "" {
+			continue
+		}
+		pref := prefs[*key.UserID]
+		if !pref.SpendAlerts {
+			continue
+		}
+		thresholds := s.cfg.Thresholds
+		if len(pref.Thresholds) > 0 {
+			thresholds = pref.Thresholds
+		}
+		crossed := CrossedThresholds(key.CurrentSpend, *key.MaxBudget, thresholds)
+		if len(crossed) == 0 {
+			continue
+		}
+		if err := s.alert(ctx, key, crossed); err != nil {
+			s.logger.Warn("Failed to send spend alert",
+				zap.String("key_id", key.ID.String()),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// alert claims the crossed thresholds for the key's budget period and emails
+// the highest one if it was not sent before. A failed email releases the
+// claim so it is retried.
+func (s *Service) alert(ctx context.Context, key *models.Key, crossed []float64) error {
+	period := key.CreatedAt
+	if key.BudgetResetAt != nil {
+		period = *key.BudgetResetAt
+	}
+
+	var claimed *models.SpendAlert
+	for _, threshold := range crossed {
+		record := &models.SpendAlert{
+			KeyID:       key.ID,
+			Threshold:   threshold,
+			PeriodStart: period,
+			UserID:      *key.UserID,
+			Spend:       key.CurrentSpend,
+		}
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			claimed = record
+		} else {
+			claimed = nil
+		}
+	}
+	if claimed == nil {
+		return nil
+	}
+
+	msg := s.spendAlertMessage(key, claimed.Threshold)
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		emailsSent.WithLabelValues("spend_alert", "error").Inc()
+		s.db.WithContext(ctx).Unscoped().Delete(claimed)
+		return err
+	}
+	emailsSent.WithLabelValues("spend_alert", "sent").Inc()
+	s.logger.Info("Sent spend alert",
+		zap.String("key_id", key.ID.String()),
+		zap.Float64("threshold", claimed.Threshold))
+	return nil
+}
+
+// CrossedThresholds returns, in increasing order, the thresholds (percent of
+// limit) the spend has reached
+func CrossedThresholds(spend, limit float64, thresholds []float64) []float64 {
+	if limit <= 0 {
+		return nil
+	}
+	var crossed []float64
+	for _, threshold := range thresholds {
+		if threshold > 0 && spend >= limit*threshold/100 {
+			crossed = append(crossed, threshold)
+		}
+	}
+	sort.Float64s(crossed)
+	return crossed
+}
+
+func (s *Service) spendAlertMessage(key *models.Key, threshold float64) Message {
+	subject := fmt.Sprintf("Your key %q has used %.0f%% of its budget", key.Name, threshold)
+	if threshold >= 100 {
+		subject = fmt.Sprintf("Your key %q has reached its budget", key.Name)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\n", displayName(key.User))
+	fmt.Fprintf(&body, "Your API key %q (%s...) has spent $%.2f of its $%.2f budget (%.0f%%).\n",
+		key.Name, key.KeyPrefix, key.CurrentSpend, *key.MaxBudget, key.CurrentSpend / *key.MaxBudget * 100)
+	if threshold >= 100 {
+		body.WriteString("Requests with this key are rejected until its budget resets or is raised.\n")
+	}
+	if key.BudgetResetAt != nil {
+		fmt.Fprintf(&body, "The budget resets on %s.\n", key.BudgetResetAt.UTC().Format("Monday, January 2 at 15:04 UTC"))
+	}
+	s.footer(&body)
+	return Message{To: key.User.Email, Subject: subject, Body: body.String()}
+}
+
+// SendDigests emails the weekly digest to the users who want it and have not
+// had the digest of the current week
+func (s *Service) SendDigests(ctx context.Context, now time.Time) error {
+	slot := DigestSlot(now, s.weekday, s.cfg.DigestHour)
+
+	var users []models.User
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Joins("LEFT JOIN user_notification_preferences p ON p.user_id = users.id AND p.deleted_at IS NULL").
+		Where("users.is_active = ? AND users.email <> ''", true).
+		Where("(p.id IS NULL AND ?) OR (p.weekly_digest AND (p.last_digest_at IS NULL OR p.last_digest_at < ?))",
+			s.cfg.DefaultWeeklyDigest, slot).
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for i := range users {
+		claimed, err := s.claimDigest(ctx, users[i].ID, now, slot)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.sendDigest(ctx, &users[i], slot); err != nil {
+			emailsSent.WithLabelValues("weekly_digest", "error").Inc()
+			s.logger.Warn("Failed to send weekly digest",
+				zap.String("user_id", users[i].ID.String()),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// claimDigest marks the user's digest for the week as sent, reporting false
+// when another worker already did
+func (s *Service) claimDigest(ctx context.Context, userID uuid.UUID, now, slot time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.NotificationPreference{}).
+		Where("user_id = ? AND (last_digest_at IS NULL OR last_digest_at < ?)", userID, slot).
+		Update("last_digest_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// Users without preferences get a row with the defaults
+	pref := &models.NotificationPreference{
+		UserID:       userID,
+		SpendAlerts:  s.cfg.DefaultSpendAlerts,
+		WeeklyDigest: s.cfg.DefaultWeeklyDigest,
+		LastDigestAt: &now,
+	}
+	result = s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(pref)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Digest is a user's usage over a week
+type Digest struct {
+	From, To time.Time
+	Requests int64
+	Tokens   int64
+	Cost     float64
+	Models   []DigestModel
+	Keys     []models.Key // Personal keys with a budget
+}
+
+// DigestModel is the usage of one model in a digest
+type DigestModel struct {
+	Model    string
+	Requests int64
+	Cost     float64
+}
+
+func (s *Service) sendDigest(ctx context.Context, user *models.User, slot time.Time) error {
+	digest := &Digest{From: slot.AddDate(0, 0, -7), To: slot}
+	db := s.db.WithContext(ctx)
+
+	// Rows stored under usage sampling stand for sample_rate requests
+	usage := db.Model(&models.Usage{}).
+		Where("actual_user_id = ? AND timestamp >= ? AND timestamp < ?", user.ID, digest.From, digest.To)
+	var totals struct {
+		Requests int64
+		Tokens   int64
+		Cost     float64
+	}
+	if err := usage.Session(&gorm.Session{}).
+		Select("COALESCE(SUM(sample_rate), 0) AS requests, COALESCE(SUM(total_tokens * sample_rate), 0) AS tokens, COALESCE(SUM(total_cost * sample_rate), 0) AS cost").
+		Scan(&totals).Error; err != nil {
+		return err
+	}
+	if totals.Requests == 0 {
+		return nil // Nothing to report this week
+	}
+	digest.Requests, digest.Tokens, digest.Cost = totals.Requests, totals.Tokens, totals.Cost
+
+	if err := usage.Session(&gorm.Session{}).
+		Select("model, SUM(sample_rate) AS requests, SUM(total_cost * sample_rate) AS cost").
+		Group("model").Order("cost DESC").Limit(5).
+		Scan(&digest.Models).Error; err != nil {
+		return err
+	}
+	if err := db.Where("user_id = ? AND team_id IS NULL AND is_active = ? AND max_budget > 0", user.ID, true).
+		Order("name").Find(&digest.Keys).Error; err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, s.digestMessage(user, digest)); err != nil {
+		return err
+	}
+	emailsSent.WithLabelValues("weekly_digest", "sent").Inc()
+	return nil
+}
+
+func (s *Service) digestMessage(user *models.User, digest *Digest) Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\n", displayName(user))
+	fmt.Fprintf(&body, "Your usage from %s to %s:\n\n", digest.From.Format("Jan 2"), digest.To.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+	fmt.Fprintf(&body, "  Requests: %d\n  Tokens:   %d\n  Cost:     $%.2f\n", digest.Requests, digest.Tokens, digest.Cost)
+
+	if len(digest.Models) > 0 {
+		body.WriteString("\nTop models:\n")
+		for _, model := range digest.Models {
+			fmt.Fprintf(&body, "  %-30s %8d requests  $%.2f\n", model.Model, model.Requests, model.Cost)
+		}
+	}
+	if len(digest.Keys) > 0 {
+		body.WriteString("\nKey budgets:\n")
+		for _, key := range digest.Keys {
+			fmt.Fprintf(&body, "  %-30s $%.2f of $%.2f\n", key.Name, key.CurrentSpend, *key.MaxBudget)
+		}
+	}
+	s.footer(&body)
+	return Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Your weekly usage: $%.2f across %d requests", digest.Cost, digest.Requests),
+		Body:    body.String(),
+	}
+}
+
+func (s *Service) footer(body *strings.Builder) {
+	body.WriteString("\n")
+	if s.cfg.DashboardURL != "" {
+		fmt.Fprintf(body, "Usage details: %s\n", s.cfg.DashboardURL)
+	}
+	body.WriteString("You can change which emails you get in your notification preferences.\n")
+}
+
+// DigestSlot returns the latest weekday at hour:00 UTC not after now, which
+// identifies the digest week
+func DigestSlot(now time.Time, weekday time.Weekday, hour int) time.Time {
+	now = now.UTC()
+	slot := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	slot = slot.AddDate(0, 0, -((int(now.Weekday()) - int(weekday) + 7) % 7))
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -7)
+	}
+	return slot
+}
+
+func displayName(user *models.User) string {
+	if user == nil {
+		return "there"
+	}
+	if user.FirstName != "" {
+		return user.FirstName
+	}
+	if user.Username != "" {
+		return user.Username
+	}
+	return "there"
+}
+
+// preferences returns the preferences of the users, the defaults for users
+// who have not set any
+func (s *Service) preferences(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]models.NotificationPreference, error) {
+	var stored []models.NotificationPreference
+	if err := s.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	prefs := make(map[uuid.UUID]models.NotificationPreference, len(userIDs))
+	for _, id := range userIDs {
+		prefs[id] = DefaultPreferences(s.cfg, id)
+	}
+	for _, pref := range stored {
+		prefs[pref.UserID] = pref
+	}
+	return prefs, nil
+}
+
+// DefaultPreferences returns the preferences of a user who has not set any
+func DefaultPreferences(cfg config.UserNotificationsConfig, userID uuid.UUID) models.NotificationPreference {
+	return models.NotificationPreference{
+		UserID:       userID,
+		SpendAlerts:  cfg.DefaultSpendAlerts,
+		WeeklyDigest: cfg.DefaultWeeklyDigest,
+	}
+}
+
+// LoadPreferences returns a user's notification preferences, the defaults
+// when they have not set any
+func LoadPreferences(ctx context.Context, db *gorm.DB, cfg config.UserNotificationsConfig, userID uuid.UUID) (*models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := db.WithContext(ctx).Where("user_id = ?", userID).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		pref = DefaultPreferences(cfg, userID)
+		return &pref, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// SavePreferences stores a user's notification preferences
+func SavePreferences(ctx context.Context, db *gorm.DB, pref *models.NotificationPreference) error {
+	for _, threshold := range pref.Thresholds {
+		if threshold <= 0 || threshold > 1000 {
+			return fmt.Errorf("%w, got %g", ErrInvalidThreshold, threshold)
+		}
+	}
+	if pref.ID != uuid.Nil {
+		return db.WithContext(ctx).Model(pref).
+			Select("spend_alerts", "weekly_digest", "thresholds").
+			Updates(pref).Error
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"spend_alerts", "weekly_digest", "thresholds", "updated_at"}),
+	}).Create(pref).Error
+}
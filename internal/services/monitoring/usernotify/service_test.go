@@ -0,0 +1,122 @@
+package usernotify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/amerfu/pllm/internal/core/config"
+	"github.com/amerfu/pllm/internal/core/models"
+)
+
+func TestCrossedThresholds(t *testing.T) {
+	thresholds := []float64{100, 50, 80}
+
+	assert.Empty(t, CrossedThresholds(40, 100, thresholds))
+	assert.Equal(t, []float64{50}, CrossedThresholds(50, 100, thresholds))
+	assert.Equal(t, []float64{50, 80}, CrossedThresholds(90, 100, thresholds))
+	assert.Equal(t, []float64{50, 80, 100}, CrossedThresholds(120, 100, thresholds))
+	assert.Empty(t, CrossedThresholds(120, 0, thresholds), "keys without a budget have no thresholds")
+}
+
+func TestDigestSlot(t *testing.T) {
+	// 2026-10-12 is a Monday
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"after the hour", time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC), time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)},
+		{"before the hour", time.Date(2026, 10, 12, 8, 59, 0, 0, time.UTC), time.Date(2026, 10, 5, 9, 0, 0, 0, time.UTC)},
+		{"later in the week", time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC), time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)},
+		{"sunday", time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC), time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)},
+		{"other time zone", time.Date(2026, 10, 12, 11, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DigestSlot(tt.now, time.Monday, 9))
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	day, err := ParseWeekday("Friday")
+	require.NoError(t, err)
+	assert.Equal(t, time.Friday, day)
+
+	day, err = ParseWeekday("")
+	require.NoError(t, err)
+	assert.Equal(t, time.Monday, day)
+
+	_, err = ParseWeekday("someday")
+	assert.Error(t, err)
+}
+
+func TestNewService_Validation(t *testing.T) {
+	_, err := NewService(nil, nil, config.UserNotificationsConfig{DigestHour: 24}, zap.NewNop())
+	assert.Error(t, err)
+
+	s, err := NewService(nil, nil, config.UserNotificationsConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, defaultThresholds, s.cfg.Thresholds)
+	assert.Equal(t, 5*time.Minute, s.cfg.CheckInterval)
+}
+
+func TestSpendAlertMessage(t *testing.T) {
+	s, err := NewService(nil, nil, config.UserNotificationsConfig{DashboardURL: "https://pllm.example.com/usage"}, zap.NewNop())
+	require.NoError(t, err)
+
+	budget := 20.0
+	key := &models.Key{
+		Name:         "laptop",
+		KeyPrefix:    "sk-abc12",
+		MaxBudget:    &budget,
+		CurrentSpend: 16.5,
+		User:         &models.User{Email: "ada@example.com", FirstName: "Ada"},
+	}
+
+	msg := s.spendAlertMessage(key, 80)
+	assert.Equal(t, "ada@example.com", msg.To)
+	assert.Equal(t, `Your key "laptop" has used 80% of its budget`, msg.Subject)
+	assert.Contains(t, msg.Body, "Hi Ada,")
+	assert.Contains(t, msg.Body, "$16.50 of its $20.00 budget (82%)")
+	assert.Contains(t, msg.Body, "https://pllm.example.com/usage")
+	assert.NotContains(t, msg.Body, "rejected")
+
+	key.CurrentSpend = 20
+	msg = s.spendAlertMessage(key, 100)
+	assert.Equal(t, `Your key "laptop" has reached its budget`, msg.Subject)
+	assert.Contains(t, msg.Body, "rejected")
+}
+
+func TestDigestMessage(t *testing.T) {
+	s, err := NewService(nil, nil, config.UserNotificationsConfig{}, zap.NewNop())
+	require.NoError(t, err)
+
+	budget := 50.0
+	slot := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	digest := &Digest{
+		From:     slot.AddDate(0, 0, -7),
+		To:       slot,
+		Requests: 1200,
+		Tokens:   3400000,
+		Cost:     12.345,
+		Models: []DigestModel{
+			{Model: "gpt-4o", Requests: 200, Cost: 10},
+			{Model: "gpt-4o-mini", Requests: 1000, Cost: 2.345},
+		},
+		Keys: []models.Key{{Name: "laptop", MaxBudget: &budget, CurrentSpend: 30}},
+	}
+
+	msg := s.digestMessage(&models.User{Email: "grace@example.com", Username: "grace"}, digest)
+	assert.Equal(t, "grace@example.com", msg.To)
+	assert.Equal(t, "Your weekly usage: $12.35 across 1200 requests", msg.Subject)
+	assert.Contains(t, msg.Body, "Hi grace,")
+	assert.Contains(t, msg.Body, "from Oct 5 to Oct 11, 2026")
+	assert.Contains(t, msg.Body, "gpt-4o-mini")
+	assert.Contains(t, msg.Body, "$30.00 of $50.00")
+	assert.NotContains(t, msg.Body, "Usage details:")
+}
@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+// Health is the health of the usage processor
+type Health struct {
+	Healthy          bool       `json:"healthy"`
+	Reason           string     `json:"reason,omitempty"`
+	StartedAt        time.Time  `json:"started_at"`
+	LastRoundAt      *time.Time `json:"last_round_at,omitempty"`
+	LastSuccessAt    *time.Time `json:"last_success_at,omitempty"`
+	LastRetryAt      *time.Time `json:"last_retry_at,omitempty"`
+	LastFailureAt    *time.Time `json:"last_failure_at,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+	LastBatchSize    int        `json:"last_batch_size"`
+	BatchLagSeconds  float64    `json:"batch_lag_seconds"`
+	RecordsProcessed int64      `json:"records_processed"`
+}
+
+// Health reports whether the processor is running and completing rounds.
+// A round completes when the queue was drained without a failed batch, or
+// when another worker holds the processing lock.
+func (up *UsageProcessor) Health() Health {
+	up.health.mu.Lock()
+	defer up.health.mu.Unlock()
+
+	h := Health{
+		Healthy:          true,
+		StartedAt:        up.health.startedAt,
+		LastRoundAt:      timePtr(up.health.lastRoundAt),
+		LastSuccessAt:    timePtr(up.health.lastSuccessAt),
+		LastRetryAt:      timePtr(up.health.lastRetryAt),
+		LastFailureAt:    timePtr(up.health.lastFailureAt),
+		LastError:        up.health.lastError,
+		LastBatchSize:    up.health.lastBatchSize,
+		BatchLagSeconds:  up.health.lastBatchLag.Seconds(),
+		RecordsProcessed: up.health.recordsProcessed,
+	}
+
+	since := up.health.lastRoundAt
+	if since.IsZero() {
+		since = up.health.startedAt
+	}
+	switch {
+	case !up.isRunning():
+		h.Healthy, h.Reason = false, "processor stopped"
+	case up.health.startedAt.IsZero():
+		h.Healthy, h.Reason = false, "processor not started"
+	case time.Since(since) > up.staleAfter:
+		h.Healthy, h.Reason = false, fmt.Sprintf("no processing round completed in %s", up.staleAfter)
+	}
+	return h
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// NewHealthHandler serves the worker's health and metrics endpoints:
+//
+//	GET /health          - 200 while the processor is healthy, 503 otherwise
+//	GET /health/detailed - processor health, queue depth and batch lag
+//	GET /metrics         - Prometheus metrics
+func NewHealthHandler(processor *UsageProcessor, logger *zap.Logger) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		health := processor.Health()
+		response := map[string]interface{}{"status": "healthy", "service": "worker"}
+		status := http.StatusOK
+		if !health.Healthy {
+			response["status"] = "unhealthy"
+			response["reason"] = health.Reason
+			status = http.StatusServiceUnavailable
+		}
+		writeHealthJSON(w, status, response, logger)
+	})
+
+	mux.HandleFunc("GET /health/detailed", func(w http.ResponseWriter, r *http.Request) {
+		health := processor.Health()
+		response := map[string]interface{}{
+			"status":              "healthy",
+			"service":             "worker",
+			"processor":           health,
+			"batch_size":          processor.batchSize,
+			"processing_interval": processor.processingInterval.String(),
+		}
+		status := http.StatusOK
+		if !health.Healthy {
+			response["status"] = "unhealthy"
+			status = http.StatusServiceUnavailable
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		if stats, err := processor.queueStats(ctx); err != nil {
+			response["queue_error"] = err.Error()
+		} else {
+			response["queue"] = stats
+		}
+		writeHealthJSON(w, status, response, logger)
+	})
+
+	// Queue depth is refreshed on scrape so it is current with any backend
+	metrics := promhttp.Handler()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		if _, err := processor.queueStats(ctx); err != nil {
+			logger.Warn("Failed to read usage queue stats", zap.Error(err))
+		}
+		metrics.ServeHTTP(w, r)
+	})
+
+	return mux
+}
+
+// queueStats reads the usage queue depth and exports it
+func (up *UsageProcessor) queueStats(ctx context.Context) (*redisService.QueueStats, error) {
+	stats, err := up.usageQueue.GetQueueStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	observeQueueStats(stats)
+	return stats, nil
+}
+
+func writeHealthJSON(w http.ResponseWriter, status int, body interface{}, logger *zap.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Warn("Failed to write health response", zap.Error(err))
+	}
+}
@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+func newHealthTestProcessor(t *testing.T) *UsageProcessor {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	queue := redisService.NewUsageQueue(&redisService.UsageQueueConfig{Client: client, Logger: zap.NewNop()})
+	require.NoError(t, queue.EnqueueUsage(context.Background(), &redisService.UsageRecord{ID: "1", RequestID: "req-1", Timestamp: time.Now()}))
+
+	return NewUsageProcessor(&UsageProcessorConfig{
+		Logger:             zap.NewNop(),
+		UsageQueue:         queue,
+		ProcessingInterval: time.Minute,
+	})
+}
+
+func TestUsageProcessor_Health(t *testing.T) {
+	up := newHealthTestProcessor(t)
+	assert.False(t, up.Health().Healthy, "not started")
+
+	up.health.startedAt = time.Now()
+	assert.True(t, up.Health().Healthy)
+
+	// A round with a failed batch does not count as completed
+	up.startRound()
+	up.recordFailure(errors.New("database is down"))
+	up.completeRound()
+	health := up.Health()
+	assert.Nil(t, health.LastRoundAt)
+	assert.Equal(t, "database is down", health.LastError)
+
+	up.health.startedAt = time.Now().Add(-time.Hour)
+	health = up.Health()
+	assert.False(t, health.Healthy)
+	assert.Contains(t, health.Reason, "no processing round completed")
+
+	up.startRound()
+	up.recordBatch([]*redisService.UsageRecord{{Timestamp: time.Now().Add(-10 * time.Second)}, {Timestamp: time.Now()}})
+	up.completeRound()
+	health = up.Health()
+	assert.True(t, health.Healthy)
+	assert.Equal(t, int64(2), health.RecordsProcessed)
+	assert.InDelta(t, 10, health.BatchLagSeconds, 1)
+
+	require.NoError(t, up.Stop())
+	assert.Equal(t, "processor stopped", up.Health().Reason)
+}
+
+func TestHealthHandler(t *testing.T) {
+	up := newHealthTestProcessor(t)
+	up.health.startedAt = time.Now()
+	handler := NewHealthHandler(up, zap.NewNop())
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, get("/health").Code)
+
+	rec := get("/health/detailed")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var detailed struct {
+		Status    string                   `json:"status"`
+		Processor Health                   `json:"processor"`
+		Queue     *redisService.QueueStats `json:"queue"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &detailed))
+	assert.Equal(t, "healthy", detailed.Status)
+	require.NotNil(t, detailed.Queue)
+	assert.Equal(t, int64(1), detailed.Queue.MainQueue)
+
+	rec = get("/metrics")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `pllm_worker_queue_depth{queue="main"} 1`)
+
+	up.health.startedAt = time.Now().Add(-time.Hour)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health/detailed").Code)
+}
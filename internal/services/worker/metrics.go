@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+var (
+	workerBatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pllm_worker_batches_total",
+		Help: "Usage batches stored by the worker, by result",
+	}, []string{"result"}) // result: success, failure
+
+	workerRecords = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pllm_worker_records_processed_total",
+		Help: "Usage records stored by the worker",
+	})
+
+	workerBatchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pllm_worker_batch_duration_seconds",
+		Help:    "Time spent storing a usage batch",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+
+	workerBatchLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pllm_worker_batch_lag_seconds",
+		Help: "Age of the oldest record in the last stored usage batch",
+	})
+
+	workerLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pllm_worker_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful run of a worker loop",
+	}, []string{"loop"}) // loop: usage, retry
+
+	workerQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pllm_worker_queue_depth",
+		Help: "Usage records waiting in the usage queue",
+	}, []string{"queue"}) // queue: main, retry, dead_letter, held
+)
+
+// observeQueueStats exports the depth of the usage queues. It works with
+// every coordination backend, unlike the Redis queue gauges.
+func observeQueueStats(stats *redisService.QueueStats) {
+	workerQueueDepth.WithLabelValues("main").Set(float64(stats.MainQueue))
+	workerQueueDepth.WithLabelValues("retry").Set(float64(stats.RetryQueue))
+	workerQueueDepth.WithLabelValues("dead_letter").Set(float64(stats.DeadLetterQueue))
+	workerQueueDepth.WithLabelValues("held").Set(float64(stats.HeldQueue))
+}
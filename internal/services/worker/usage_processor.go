@@ -2,37 +2,93 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
 	"github.com/amerfu/pllm/internal/core/models"
 	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+	"github.com/amerfu/pllm/internal/services/integrations/billing"
+	"github.com/amerfu/pllm/internal/services/monitoring/notifications"
 )
 
-// UsageProcessor handles batch processing of usage records from Redis queue
+// UsageProcessor handles batch processing of usage records from the usage queue
 type UsageProcessor struct {
 	db                 *gorm.DB
 	logger             *zap.Logger
-	usageQueue         *redisService.UsageQueue
-	budgetCache        *redisService.BudgetCache
-	lockManager        *redisService.LockManager
+	usageQueue         redisService.UsageQueueBackend
+	budgetCache        redisService.BudgetCacheBackend
+	lockManager        redisService.LockBackend
 	batchSize          int
 	processingInterval time.Duration
+	maxBatchesPerRound int
+	notifier           *notifications.Hub
+	sampleRate         int
+	keepErrors         bool
+	counters           *redisService.UsageCounters
+	credits            *billing.Service
+	staleAfter         time.Duration
 	stopCh             chan struct{}
+
+	health processorHealth
+}
+
+// processingLockTTL bounds how long one worker holds the processing lock
+const processingLockTTL = 2 * time.Minute
+
+// processorHealth is the state reported by the health endpoints
+type processorHealth struct {
+	mu               sync.Mutex
+	startedAt        time.Time
+	lastRoundAt      time.Time // Last tick that finished without a failed batch
+	roundFailed      bool
+	lastSuccessAt    time.Time // Last stored batch
+	lastRetryAt      time.Time // Last successful retry queue run
+	lastFailureAt    time.Time
+	lastError        string
+	lastBatchSize    int
+	lastBatchLag     time.Duration
+	recordsProcessed int64
+}
+
+// usageAcker is implemented by usage queues that keep dequeued records until
+// they are acknowledged. The acknowledgement runs in the transaction that
+// stores the usage, so a failed or interrupted batch is neither lost nor
+// counted twice.
+type usageAcker interface {
+	AckUsage(tx *gorm.DB, records []*redisService.UsageRecord) error
 }
 
 type UsageProcessorConfig struct {
 	DB                 *gorm.DB
 	Logger             *zap.Logger
-	UsageQueue         *redisService.UsageQueue
-	BudgetCache        *redisService.BudgetCache
-	LockManager        *redisService.LockManager
+	UsageQueue         redisService.UsageQueueBackend
+	BudgetCache        redisService.BudgetCacheBackend
+	LockManager        redisService.LockBackend
 	BatchSize          int
 	ProcessingInterval time.Duration
+	MaxBatchesPerRound int                // Queue batches drained per tick while a backlog remains
+	Notifier           *notifications.Hub // Optional, receives budget alerts and batch failures
+
+	// Usage sampling: store 1 in SampleRate detailed rows. Sampling needs
+	// Counters to keep exact totals and is disabled without them.
+	SampleRate int
+	KeepErrors bool                        // Always store rows of failed requests
+	Counters   *redisService.UsageCounters // Optional, exact per-day usage totals
+
+	Credits *billing.Service // Optional, debits team usage from prepaid credits
+
+	// StaleAfter is how long the processor may go without completing a
+	// processing round before it reports unhealthy. Defaults to three
+	// processing intervals, and never less than the processing lock TTL.
+	StaleAfter time.Duration
 }
 
 func NewUsageProcessor(config *UsageProcessorConfig) *UsageProcessor {
@@ -42,6 +98,23 @@ func NewUsageProcessor(config *UsageProcessorConfig) *UsageProcessor {
 	if config.ProcessingInterval == 0 {
 		config.ProcessingInterval = 30 * time.Second
 	}
+	if config.MaxBatchesPerRound == 0 {
+		config.MaxBatchesPerRound = 10
+	}
+	if config.StaleAfter == 0 {
+		config.StaleAfter = 3 * config.ProcessingInterval
+	}
+	if config.StaleAfter < processingLockTTL {
+		config.StaleAfter = processingLockTTL
+	}
+	if config.SampleRate < 1 {
+		config.SampleRate = 1
+	}
+	if config.SampleRate > 1 && config.Counters == nil {
+		config.Logger.Warn("Usage sampling needs the Redis usage counters, storing every usage record",
+			zap.Int("sample_rate", config.SampleRate))
+		config.SampleRate = 1
+	}
 
 	return &UsageProcessor{
 		db:                 config.DB,
@@ -51,6 +124,13 @@ func NewUsageProcessor(config *UsageProcessorConfig) *UsageProcessor {
 		lockManager:        config.LockManager,
 		batchSize:          config.BatchSize,
 		processingInterval: config.ProcessingInterval,
+		maxBatchesPerRound: config.MaxBatchesPerRound,
+		notifier:           config.Notifier,
+		sampleRate:         config.SampleRate,
+		keepErrors:         config.KeepErrors,
+		counters:           config.Counters,
+		credits:            config.Credits,
+		staleAfter:         config.StaleAfter,
 		stopCh:             make(chan struct{}),
 	}
 }
@@ -59,7 +139,12 @@ func NewUsageProcessor(config *UsageProcessorConfig) *UsageProcessor {
 func (up *UsageProcessor) Start(ctx context.Context) error {
 	up.logger.Info("Starting usage processor",
 		zap.Int("batch_size", up.batchSize),
-		zap.Duration("processing_interval", up.processingInterval))
+		zap.Duration("processing_interval", up.processingInterval),
+		zap.Int("sample_rate", up.sampleRate))
+
+	up.health.mu.Lock()
+	up.health.startedAt = time.Now()
+	up.health.mu.Unlock()
 
 	// Start the main processing loop
 	go up.processLoop(ctx)
@@ -93,6 +178,7 @@ func (up *UsageProcessor) processLoop(ctx context.Context) {
 		case <-ticker.C:
 			if err := up.processBatch(ctx); err != nil {
 				up.logger.Error("Error processing usage batch", zap.Error(err))
+				up.notifyWorkerFailure(ctx, "Usage processor failed to process the queue", err)
 			}
 		}
 	}
@@ -112,6 +198,13 @@ func (up *UsageProcessor) retryLoop(ctx context.Context) {
 		case <-ticker.C:
 			if err := up.usageQueue.ProcessRetryQueue(ctx); err != nil {
 				up.logger.Error("Error processing retry queue", zap.Error(err))
+				up.recordRetryFailure(err)
+			} else {
+				up.recordRetrySuccess()
+			}
+			// Also refreshes the queue depth gauges, including retry and dead letter queues
+			if _, err := up.queueStats(ctx); err != nil {
+				up.logger.Warn("Failed to read usage queue stats", zap.Error(err))
 			}
 		}
 	}
@@ -119,36 +212,72 @@ func (up *UsageProcessor) retryLoop(ctx context.Context) {
 
 // processBatch processes a batch of usage records
 func (up *UsageProcessor) processBatch(ctx context.Context) error {
+	up.startRound()
+
 	// Use distributed lock to ensure only one instance processes at a time
 	lockKey := "usage_processor_lock"
-	lock, err := up.lockManager.AcquireLock(ctx, lockKey, 2*time.Minute)
+	lock, err := up.lockManager.AcquireLock(ctx, lockKey, processingLockTTL)
 	if err != nil {
 		// Another instance is processing, skip this round
 		up.logger.Debug("Could not acquire processing lock, skipping batch")
+		up.completeRound()
 		return nil
 	}
 	defer func() { _ = lock.Release(ctx) }()
 
+	// Keep draining while the queue has a backlog, so a burst is absorbed in
+	// wider rounds instead of piling up in Redis between ticks
+	for round := 0; round < up.maxBatchesPerRound; round++ {
+		processed, err := up.processQueueBatch(ctx)
+		if err != nil {
+			up.recordFailure(err)
+			return err
+		}
+		if processed == 0 {
+			break
+		}
+		if round > 0 {
+			up.logger.Debug("Draining usage queue backlog", zap.Int("round", round+1))
+		}
+	}
+
+	up.completeRound()
+	return nil
+}
+
+// processQueueBatch dequeues and stores one queue batch, returning its size.
+// Zero is returned after a failed write so draining stops until the next tick.
+func (up *UsageProcessor) processQueueBatch(ctx context.Context) (int, error) {
 	// Get batch of records from queue
 	records, err := up.usageQueue.DequeueUsageBatch(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to dequeue usage batch: %w", err)
+		return 0, fmt.Errorf("failed to dequeue usage batch: %w", err)
 	}
 
 	if len(records) == 0 {
-		return nil // No records to process
+		return 0, nil // No records to process
 	}
 
 	up.logger.Info("Processing usage batch", zap.Int("count", len(records)))
 
 	// Process records in batches for database efficiency
 	batchesToProcess := up.groupRecordsByBatch(records)
+	processed := len(records)
 
 	for _, batch := range batchesToProcess {
-		if err := up.processBatchTransactional(ctx, batch); err != nil {
+		start := time.Now()
+		err := up.processBatchTransactional(ctx, batch)
+		workerBatchDuration.Observe(time.Since(start).Seconds())
+		if err == nil {
+			up.recordBatch(batch)
+		} else {
+			processed = 0
+			workerBatches.WithLabelValues("failure").Inc()
+			up.recordFailure(err)
 			up.logger.Error("Failed to process batch",
 				zap.Error(err),
 				zap.Int("batch_size", len(batch)))
+			up.notifyWorkerFailure(ctx, fmt.Sprintf("Usage processor failed to store %d records, re-queued for retry", len(batch)), err)
 
 			// Re-queue failed records for retry
 			for _, record := range batch {
@@ -161,11 +290,55 @@ func (up *UsageProcessor) processBatch(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	return processed, nil
+}
+
+// shouldStore reports whether the detailed row of a record is written to the
+// database. The decision is derived from the request ID, so a record retried
+// after a failed batch is sampled the same way.
+func (up *UsageProcessor) shouldStore(record *redisService.UsageRecord) bool {
+	if up.sampleRate <= 1 {
+		return true
+	}
+	if up.keepErrors && record.StatusCode >= 400 {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(record.RequestID))
+	return h.Sum32()%uint32(up.sampleRate) == 0
+}
+
+// rowSampleRate returns the number of requests a stored row of the record
+// stands for
+func (up *UsageProcessor) rowSampleRate(record *redisService.UsageRecord) int {
+	if up.keepErrors && record.StatusCode >= 400 {
+		return 1
+	}
+	return up.sampleRate
 }
 
-// processBatchTransactional processes a batch of records in a database transaction
+// processBatchTransactional processes a batch of records in a database
+// transaction. Spend is charged for every record, while only sampled records
+// are stored as detailed rows; the exact totals go to the usage counters once
+// the transaction has committed.
 func (up *UsageProcessor) processBatchTransactional(ctx context.Context, records []*redisService.UsageRecord) error {
+	if err := up.storeBatch(records); err != nil {
+		return err
+	}
+
+	if up.counters != nil {
+		if err := up.counters.Add(ctx, records, up.shouldStore); err != nil {
+			up.logger.Warn("Usage counters missed a stored batch",
+				zap.Int("records", len(records)),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// storeBatch stores a batch of records and charges their spend in one
+// database transaction
+func (up *UsageProcessor) storeBatch(records []*redisService.UsageRecord) error {
 	return up.db.Transaction(func(tx *gorm.DB) error {
 		// Convert Redis records to database models
 		usageModels := make([]*models.Usage, 0, len(records))
@@ -173,6 +346,7 @@ func (up *UsageProcessor) processBatchTransactional(ctx context.Context, records
 		userBudgetUpdates := make(map[uuid.UUID]float64) // user_id -> amount to add
 		teamBudgetUpdates := make(map[uuid.UUID]float64) // team_id -> amount to add
 		keyBudgetUpdates := make(map[uuid.UUID]float64)  // key_id -> amount to add
+		teamCreditDebits := make(map[uuid.UUID]float64)  // team_id -> amount to debit
 
 		for _, record := range records {
 			// Convert to database model
@@ -184,7 +358,10 @@ func (up *UsageProcessor) processBatchTransactional(ctx context.Context, records
 				continue
 			}
 
-			usageModels = append(usageModels, usage)
+			if up.shouldStore(record) {
+				usage.SampleRate = up.rowSampleRate(record)
+				usageModels = append(usageModels, usage)
+			}
 
 			// Collect budget updates from the Budget table
 			budgets, err := up.findActivebudgets(tx, usage)
@@ -206,6 +383,12 @@ func (up *UsageProcessor) processBatchTransactional(ctx context.Context, records
 			// Update team-level budgets (stored directly in teams table)
 			if usage.TeamID != nil {
 				teamBudgetUpdates[*usage.TeamID] += record.TotalCost
+
+				// Requests sent with the team's own provider key were billed
+				// to its provider account, not paid from its credits
+				if usage.ProviderKeyID == nil {
+					teamCreditDebits[*usage.TeamID] += record.TotalCost
+				}
 			}
 
 			// Update key-level budgets (stored directly in keys table)
@@ -242,6 +425,13 @@ func (up *UsageProcessor) processBatchTransactional(ctx context.Context, records
 			}
 		}
 
+		// Debit team usage from prepaid credits
+		if up.credits != nil && len(teamCreditDebits) > 0 {
+			if err := up.credits.ChargeUsage(tx, teamCreditDebits); err != nil {
+				return fmt.Errorf("failed to debit team credits: %w", err)
+			}
+		}
+
 		// Batch update key-level budgets (keys.current_spend)
 		if len(keyBudgetUpdates) > 0 {
 			if err := up.updateKeyBudgetsBatch(tx, keyBudgetUpdates); err != nil {
@@ -249,6 +439,13 @@ func (up *UsageProcessor) processBatchTransactional(ctx context.Context, records
 			}
 		}
 
+		// Remove the records from the queue together with storing them
+		if acker, ok := up.usageQueue.(usageAcker); ok {
+			if err := acker.AckUsage(tx, records); err != nil {
+				return err
+			}
+		}
+
 		// Update cache with latest budget information
 		go up.refreshBudgetCaches(context.Background(), budgetUpdates)
 		go up.refreshUserBudgetCaches(context.Background(), userBudgetUpdates)
@@ -256,6 +453,7 @@ func (up *UsageProcessor) processBatchTransactional(ctx context.Context, records
 		go up.refreshKeyBudgetCaches(context.Background(), keyBudgetUpdates)
 
 		up.logger.Info("Successfully processed usage batch",
+			zap.Int("records", len(records)),
 			zap.Int("usage_records", len(usageModels)),
 			zap.Int("budget_updates", len(budgetUpdates)),
 			zap.Int("user_budget_updates", len(userBudgetUpdates)),
@@ -269,20 +467,51 @@ func (up *UsageProcessor) processBatchTransactional(ctx context.Context, records
 // convertToUsageModel converts Redis usage record to database model
 func (up *UsageProcessor) convertToUsageModel(record *redisService.UsageRecord) (*models.Usage, error) {
 	usage := &models.Usage{
-		RequestID:     record.RequestID,
-		Timestamp:     record.Timestamp,
-		Model:         record.Model,
-		Provider:      record.Provider,
-		RouteSlug:     record.RouteSlug,
-		ProviderModel: record.ProviderModel,
-		Method:        record.Method,
-		Path:          record.Path,
-		StatusCode:    record.StatusCode,
-		InputTokens:   record.InputTokens,
-		OutputTokens:  record.OutputTokens,
-		TotalTokens:   record.TotalTokens,
-		TotalCost:     record.TotalCost,
-		Latency:       record.Latency,
+		RequestID:          record.RequestID,
+		Timestamp:          record.Timestamp,
+		Model:              record.Model,
+		Provider:           record.Provider,
+		RouteSlug:          record.RouteSlug,
+		ProviderModel:      record.ProviderModel,
+		EndpointType:       record.EndpointType,
+		Method:             record.Method,
+		Path:               record.Path,
+		StatusCode:         record.StatusCode,
+		InputTokens:        record.InputTokens,
+		OutputTokens:       record.OutputTokens,
+		ReasoningTokens:    record.ReasoningTokens,
+		TotalTokens:        record.TotalTokens,
+		TotalCost:          record.TotalCost,
+		AudioSeconds:       record.AudioSeconds,
+		OutputAudioSeconds: record.OutputAudioSeconds,
+		ImagesGenerated:    record.ImagesGenerated,
+		Characters:         record.Characters,
+		RequestBytes:       record.RequestBytes,
+		ResponseBytes:      record.ResponseBytes,
+		Latency:            record.Latency,
+		TTFT:               record.TTFT,
+		TokensPerSecond:    record.TokensPerSecond,
+		MaxChunkGap:        record.MaxChunkGap,
+		Stalled:            record.Stalled,
+		ContentHash:        record.ContentHash,
+		Environment:        record.Environment,
+		Country:            record.Country,
+		Continent:          record.Continent,
+		ClientName:         record.ClientName,
+		ClientVersion:      record.ClientVersion,
+	}
+
+	// Records queued before endpoint types were recorded
+	if usage.EndpointType == "" {
+		usage.EndpointType = models.EndpointTypeForPath(record.Path)
+	}
+
+	if record.CostBreakdown != nil {
+		usage.InputCost = record.CostBreakdown.InputCost()
+		usage.OutputCost = record.CostBreakdown.OutputCost()
+		if breakdown, err := json.Marshal(record.CostBreakdown); err == nil {
+			usage.CostBreakdown = datatypes.JSON(breakdown)
+		}
 	}
 
 	// Parse UUIDs for key entities
@@ -310,6 +539,12 @@ func (up *UsageProcessor) convertToUsageModel(record *redisService.UsageRecord)
 		}
 	}
 
+	if record.ProviderKeyID != "" {
+		if providerKeyUUID, err := uuid.Parse(record.ProviderKeyID); err == nil {
+			usage.ProviderKeyID = &providerKeyUUID
+		}
+	}
+
 	// Parse KeyOwnerID
 	if record.KeyOwnerID != "" {
 		if keyOwnerUUID, err := uuid.Parse(record.KeyOwnerID); err == nil {
@@ -468,7 +703,7 @@ func (up *UsageProcessor) groupRecordsByBatch(records []*redisService.UsageRecor
 
 // GetProcessorStats returns statistics about the processor
 func (up *UsageProcessor) GetProcessorStats(ctx context.Context) (*ProcessorStats, error) {
-	queueStats, err := up.usageQueue.GetQueueStats(ctx)
+	queueStats, err := up.queueStats(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -597,6 +832,9 @@ func (up *UsageProcessor) refreshUserBudgetCaches(ctx context.Context, userUpdat
 				zap.String("user_id", userID.String()),
 				zap.Error(err))
 		}
+
+		up.notifyBudget(ctx, "user", userID.String(), user.Email,
+			user.CurrentSpend, userUpdates[userID], user.MaxBudget, 80)
 	}
 }
 
@@ -660,6 +898,9 @@ func (up *UsageProcessor) refreshTeamBudgetCaches(ctx context.Context, teamUpdat
 				zap.String("team_id", teamID.String()),
 				zap.Error(err))
 		}
+
+		up.notifyBudget(ctx, "team", teamID.String(), team.Name,
+			team.CurrentSpend, teamUpdates[teamID], team.MaxBudget, team.BudgetAlertAt)
 	}
 }
 
@@ -668,24 +909,141 @@ func (up *UsageProcessor) refreshKeyBudgetCaches(ctx context.Context, keyUpdates
 	for keyID := range keyUpdates {
 		// Fetch latest key from database
 		var key models.Key
-		if err := up.db.First(&key, "id = ?", keyID).Error; err != nil {
+		if err := up.db.Preload("Team").First(&key, "id = ?", keyID).Error; err != nil {
 			up.logger.Error("Failed to fetch key for cache refresh",
 				zap.String("key_id", keyID.String()),
 				zap.Error(err))
 			continue
 		}
 
-		// Update cache (only if key has budget)
-		if key.MaxBudget != nil && *key.MaxBudget > 0 {
-			available := *key.MaxBudget - key.CurrentSpend
-			isExceeded := key.CurrentSpend >= *key.MaxBudget
+		// Update cache (only if key has budget, its own or its environment's)
+		if maxBudget := key.GetMaxBudget(); maxBudget != nil && *maxBudget > 0 {
+			available := *maxBudget - key.CurrentSpend
+			isExceeded := key.CurrentSpend >= *maxBudget
 
 			if err := up.budgetCache.UpdateBudgetCache(ctx, "key", keyID.String(),
-				available, key.CurrentSpend, *key.MaxBudget, isExceeded); err != nil {
+				available, key.CurrentSpend, *maxBudget, isExceeded); err != nil {
 				up.logger.Error("Failed to update key budget cache",
 					zap.String("key_id", keyID.String()),
 					zap.Error(err))
 			}
+
+			// Keys have no alert threshold, only the limit is reported
+			up.notifyBudget(ctx, "key", keyID.String(), key.Name,
+				key.CurrentSpend, keyUpdates[keyID], *maxBudget, 0)
 		}
 	}
 }
+
+// notifyWorkerFailure reports a processing failure to the admin dashboard
+func (up *UsageProcessor) notifyWorkerFailure(ctx context.Context, message string, err error) {
+	up.notifier.Notify(ctx, notifications.Notification{
+		Kind:     notifications.KindWorkerFailure,
+		Severity: notifications.SeverityCritical,
+		Message:  message,
+		Data:     map[string]interface{}{"worker": "usage_processor", "error": err.Error()},
+	})
+}
+
+// notifyBudget sends a budget notification when the spend added by the last
+// batch (delta) crossed the entity's limit or its alert threshold, given as a
+// percentage of the limit (0 for none)
+func (up *UsageProcessor) notifyBudget(ctx context.Context, entityType, entityID, name string, spend, delta, limit, alertPercent float64) {
+	if up.notifier == nil || limit <= 0 || delta <= 0 {
+		return
+	}
+
+	previous := spend - delta
+	threshold := limit * alertPercent / 100
+	var n notifications.Notification
+	switch {
+	case previous < limit && spend >= limit:
+		n = notifications.Notification{
+			Kind:     notifications.KindBudgetExceeded,
+			Severity: notifications.SeverityCritical,
+			Message:  fmt.Sprintf("%s %s exceeded its budget of $%.2f", entityType, name, limit),
+		}
+	case alertPercent > 0 && previous < threshold && spend >= threshold:
+		n = notifications.Notification{
+			Kind:     notifications.KindBudgetAlert,
+			Severity: notifications.SeverityWarning,
+			Message:  fmt.Sprintf("%s %s has used %.0f%% of its budget of $%.2f", entityType, name, alertPercent, limit),
+		}
+	default:
+		return
+	}
+
+	n.Data = map[string]interface{}{
+		"entity_type": entityType,
+		"entity_id":   entityID,
+		"name":        name,
+		"spend":       spend,
+		"limit":       limit,
+	}
+	up.notifier.Notify(ctx, n)
+}
+
+// startRound marks the start of a processing round
+func (up *UsageProcessor) startRound() {
+	up.health.mu.Lock()
+	defer up.health.mu.Unlock()
+	up.health.roundFailed = false
+}
+
+// completeRound marks the end of a processing round; rounds in which a batch
+// failed do not count as completed
+func (up *UsageProcessor) completeRound() {
+	up.health.mu.Lock()
+	defer up.health.mu.Unlock()
+	if !up.health.roundFailed {
+		up.health.lastRoundAt = time.Now()
+		workerLastSuccess.WithLabelValues("usage").Set(float64(up.health.lastRoundAt.Unix()))
+	}
+}
+
+// recordBatch records a stored batch and the age of its oldest record
+func (up *UsageProcessor) recordBatch(records []*redisService.UsageRecord) {
+	now := time.Now()
+	var lag time.Duration
+	for _, record := range records {
+		if !record.Timestamp.IsZero() && now.Sub(record.Timestamp) > lag {
+			lag = now.Sub(record.Timestamp)
+		}
+	}
+
+	workerBatches.WithLabelValues("success").Inc()
+	workerRecords.Add(float64(len(records)))
+	workerBatchLag.Set(lag.Seconds())
+
+	up.health.mu.Lock()
+	defer up.health.mu.Unlock()
+	up.health.lastSuccessAt = now
+	up.health.lastBatchSize = len(records)
+	up.health.lastBatchLag = lag
+	up.health.recordsProcessed += int64(len(records))
+}
+
+// recordRetrySuccess records a successful run of the retry queue
+func (up *UsageProcessor) recordRetrySuccess() {
+	up.health.mu.Lock()
+	defer up.health.mu.Unlock()
+	up.health.lastRetryAt = time.Now()
+	workerLastSuccess.WithLabelValues("retry").Set(float64(up.health.lastRetryAt.Unix()))
+}
+
+// recordFailure records a failure of the current processing round
+func (up *UsageProcessor) recordFailure(err error) {
+	up.health.mu.Lock()
+	defer up.health.mu.Unlock()
+	up.health.roundFailed = true
+	up.health.lastFailureAt = time.Now()
+	up.health.lastError = err.Error()
+}
+
+// recordRetryFailure records a failed retry queue run
+func (up *UsageProcessor) recordRetryFailure(err error) {
+	up.health.mu.Lock()
+	defer up.health.mu.Unlock()
+	up.health.lastFailureAt = time.Now()
+	up.health.lastError = err.Error()
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	redisService "github.com/amerfu/pllm/internal/services/data/redis"
+)
+
+func TestUsageProcessor_Sampling(t *testing.T) {
+	up := NewUsageProcessor(&UsageProcessorConfig{
+		Logger:     zap.NewNop(),
+		SampleRate: 10,
+		KeepErrors: true,
+		Counters:   &redisService.UsageCounters{},
+	})
+
+	stored := 0
+	for i := 0; i < 10000; i++ {
+		record := &redisService.UsageRecord{RequestID: fmt.Sprintf("req-%d", i), StatusCode: 200}
+		if up.shouldStore(record) {
+			stored++
+			assert.Equal(t, 10, up.rowSampleRate(record))
+		}
+		// The decision is stable across retries of the same record
+		assert.Equal(t, up.shouldStore(record), up.shouldStore(record))
+	}
+	assert.InDelta(t, 1000, stored, 150)
+
+	failed := &redisService.UsageRecord{RequestID: "req-failed", StatusCode: 502}
+	assert.True(t, up.shouldStore(failed))
+	assert.Equal(t, 1, up.rowSampleRate(failed))
+}
+
+func TestUsageProcessor_SamplingRequiresCounters(t *testing.T) {
+	up := NewUsageProcessor(&UsageProcessorConfig{
+		Logger:     zap.NewNop(),
+		SampleRate: 10,
+	})
+
+	assert.Equal(t, 1, up.sampleRate)
+	for i := 0; i < 100; i++ {
+		assert.True(t, up.shouldStore(&redisService.UsageRecord{RequestID: fmt.Sprintf("req-%d", i)}))
+	}
+}
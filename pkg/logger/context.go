@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// requestFields holds the fields attached to every log line of a request.
+// It is shared by pointer so fields learned deep in the handler chain (the
+// authenticated key, the resolved model) reach log lines written by outer
+// middleware as well.
+type requestFields struct {
+	mu     sync.RWMutex
+	fields []zap.Field
+}
+
+// NewContext returns a context that collects request log fields, starting
+// with the given ones
+func NewContext(ctx context.Context, fields ...zap.Field) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestFields{fields: fields})
+}
+
+// AddFields attaches fields to the request in ctx, replacing earlier fields
+// with the same key. It is a no-op outside a request context.
+func AddFields(ctx context.Context, fields ...zap.Field) {
+	rf, ok := ctx.Value(contextKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	for _, field := range fields {
+		replaced := false
+		for i := range rf.fields {
+			if rf.fields[i].Key == field.Key {
+				rf.fields[i] = field
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			rf.fields = append(rf.fields, field)
+		}
+	}
+}
+
+// Fields returns the request fields collected in ctx
+func Fields(ctx context.Context) []zap.Field {
+	rf, ok := ctx.Value(contextKey{}).(*requestFields)
+	if !ok {
+		return nil
+	}
+
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	return append([]zap.Field(nil), rf.fields...)
+}
+
+// FromContext decorates l with the request fields in ctx, so components keep
+// their own configured logger while every line carries request_id, key_id,
+// team_id, model and route
+func FromContext(ctx context.Context, l *zap.Logger) *zap.Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
+
+// Ctx returns the global logger decorated with the request fields in ctx
+func Ctx(ctx context.Context) *zap.Logger {
+	return FromContext(ctx, Get())
+}
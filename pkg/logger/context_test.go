@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	base := zap.New(core)
+
+	ctx := NewContext(context.Background(), zap.String("request_id", "req-1"))
+	AddFields(ctx, zap.String("key_id", "key-1"), zap.String("model", "gpt-4"))
+	AddFields(ctx, zap.String("model", "gpt-4o")) // Later values replace earlier ones
+
+	FromContext(ctx, base).Info("handled")
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]interface{}{
+		"request_id": "req-1",
+		"key_id":     "key-1",
+		"model":      "gpt-4o",
+	}, logs.All()[0].ContextMap())
+}
+
+func TestFromContext_NoRequest(t *testing.T) {
+	base := zap.NewNop()
+	ctx := context.Background()
+
+	AddFields(ctx, zap.String("model", "gpt-4"))
+	assert.Empty(t, Fields(ctx))
+	assert.Same(t, base, FromContext(ctx, base))
+}
@@ -40,6 +40,7 @@ export function CreateKeyDialog({ isAdmin, userTeams, onCreateKey }: CreateKeyDi
     ownership: "user",
     teamId: "",
     expiration: "never",
+    environment: "none",
     maxBudget: "",
     budgetPeriod: "monthly",
     tpm: "",
@@ -56,6 +57,7 @@ export function CreateKeyDialog({ isAdmin, userTeams, onCreateKey }: CreateKeyDi
       budget_duration: formData.budgetPeriod,
       tpm: formData.tpm ? parseInt(formData.tpm) : undefined,
       rpm: formData.rpm ? parseInt(formData.rpm) : undefined,
+      environment: formData.environment !== 'none' ? formData.environment : undefined,
     }
 
     // Handle ownership for admin users
@@ -85,6 +87,7 @@ export function CreateKeyDialog({ isAdmin, userTeams, onCreateKey }: CreateKeyDi
       ownership: "user",
       teamId: "",
       expiration: "never",
+      environment: "none",
       maxBudget: "",
       budgetPeriod: "monthly",
       tpm: "",
@@ -226,6 +229,27 @@ export function CreateKeyDialog({ isAdmin, userTeams, onCreateKey }: CreateKeyDi
                   </SelectContent>
                 </Select>
               </div>
+
+              <div>
+                <Label>Environment</Label>
+                <Select 
+                  value={formData.environment} 
+                  onValueChange={(value) => setFormData({ ...formData, environment: value })}
+                >
+                  <SelectTrigger>
+                    <SelectValue />
+                  </SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="none">None</SelectItem>
+                    <SelectItem value="dev">Development</SelectItem>
+                    <SelectItem value="staging">Staging</SelectItem>
+                    <SelectItem value="prod">Production</SelectItem>
+                  </SelectContent>
+                </Select>
+                <p className="text-xs text-muted-foreground mt-1">
+                  Team keys follow the team's limits and models for their environment
+                </p>
+              </div>
             </TabsContent>
 
             <TabsContent value="limits" className="space-y-4">
@@ -321,6 +345,12 @@ export function CreateKeyDialog({ isAdmin, userTeams, onCreateKey }: CreateKeyDi
                   {formData.expiration === 'never' ? 'Never' : `${formData.expiration} days`}
                 </span>
               </div>
+              {formData.environment !== 'none' && (
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Environment:</span>
+                  <span>{formData.environment}</span>
+                </div>
+              )}
               {formData.maxBudget && (
                 <div className="flex justify-between">
                   <span className="text-muted-foreground">Budget:</span>
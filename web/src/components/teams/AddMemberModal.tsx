@@ -92,7 +92,7 @@ export function AddMemberModal({ open, onOpenChange, teamId }: AddMemberModalPro
               <Label htmlFor="role">Role</Label>
               <Select
                 value={selectedRole}
-                onValueChange={(value) => setValue('role', value as 'admin' | 'member' | 'viewer')}
+                onValueChange={(value) => setValue('role', value as 'admin' | 'member' | 'viewer' | 'billing_admin' | 'auditor')}
               >
                 <SelectTrigger>
                   <SelectValue placeholder="Select a role" />
@@ -101,6 +101,8 @@ export function AddMemberModal({ open, onOpenChange, teamId }: AddMemberModalPro
                   <SelectItem value="admin">Admin</SelectItem>
                   <SelectItem value="member">Member</SelectItem>
                   <SelectItem value="viewer">Viewer</SelectItem>
+                  <SelectItem value="billing_admin">Billing admin</SelectItem>
+                  <SelectItem value="auditor">Auditor</SelectItem>
                 </SelectContent>
               </Select>
               {errors.role && (
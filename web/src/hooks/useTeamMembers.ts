@@ -14,7 +14,7 @@ export interface TeamMember {
   id: string;
   user_id: string;
   team_id: string;
-  role: 'owner' | 'admin' | 'member' | 'viewer';
+  role: 'owner' | 'admin' | 'member' | 'viewer' | 'billing_admin' | 'auditor';
   user?: TeamMemberUser;
   joined_at?: string;
   created_at?: string;
@@ -23,11 +23,11 @@ export interface TeamMember {
 
 export interface AddMemberInput {
   user_id: string;
-  role: 'admin' | 'member' | 'viewer';
+  role: 'admin' | 'member' | 'viewer' | 'billing_admin' | 'auditor';
 }
 
 export interface UpdateMemberInput {
-  role: 'admin' | 'member' | 'viewer';
+  role: 'admin' | 'member' | 'viewer' | 'billing_admin' | 'auditor';
 }
 
 export function useTeamMembers(teamId: string | null) {
@@ -245,8 +245,18 @@ export const getDailyUsage = () =>
 export const getMonthlyUsage = () =>
   axiosInstance.get("/api/admin/analytics/usage/monthly");
 export const getCosts = () => axiosInstance.get("/api/admin/analytics/costs");
-export const getCostBreakdown = () =>
-  axiosInstance.get("/api/admin/analytics/costs/breakdown");
+export const getCostBreakdown = (hours?: number) =>
+  axiosInstance.get(`/api/admin/analytics/costs/breakdown${hours ? `?hours=${hours}` : ""}`);
+export const getGeoBreakdown = (hours?: number) =>
+  axiosInstance.get(`/api/admin/analytics/geo${hours ? `?hours=${hours}` : ""}`);
+export const getClientBreakdown = (hours?: number) =>
+  axiosInstance.get(`/api/admin/analytics/clients${hours ? `?hours=${hours}` : ""}`);
+export const getEnvironmentBreakdown = (hours?: number, teamId?: string) => {
+  const params = new URLSearchParams();
+  if (hours) params.append("hours", String(hours));
+  if (teamId) params.append("team_id", teamId);
+  return axiosInstance.get(`/api/admin/analytics/environments${params.toString() ? `?${params}` : ""}`);
+};
 export const getPerformance = () =>
   axiosInstance.get("/api/admin/analytics/performance");
 export const getErrors = () => axiosInstance.get("/api/admin/analytics/errors");
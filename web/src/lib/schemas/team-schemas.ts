@@ -12,13 +12,13 @@ export const updateTeamSchema = z.object({
 
 export const addMemberSchema = z.object({
   user_id: z.string().min(1, 'User ID is required'),
-  role: z.enum(['admin', 'member', 'viewer'], {
+  role: z.enum(['admin', 'member', 'viewer', 'billing_admin', 'auditor'], {
     required_error: 'Role is required',
   }),
 });
 
 export const updateMemberSchema = z.object({
-  role: z.enum(['admin', 'member', 'viewer'], {
+  role: z.enum(['admin', 'member', 'viewer', 'billing_admin', 'auditor'], {
     required_error: 'Role is required',
   }),
 });
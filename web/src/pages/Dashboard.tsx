@@ -6,8 +6,8 @@ import { Icon } from "@iconify/react"
 import { icons } from "@/lib/icons"
 import { getProviderLogo } from "@/lib/provider-logos"
 import { detectProvider } from "@/lib/providers"
-import { getDashboardMetrics, getUsageTrends, getAdminModels, getModelsHealth } from "@/lib/api"
-import type { AdminModelsResponse, ModelsHealthResponse } from "@/types/api"
+import { getDashboardMetrics, getUsageTrends, getAdminModels, getModelsHealth, getCostBreakdown } from "@/lib/api"
+import type { AdminModelsResponse, ModelsHealthResponse, CostBreakdownResponse, EndpointCost } from "@/types/api"
 import { formatChartLabel, fillTimeGaps } from "@/lib/date-utils"
 
 import { Button } from "@/components/ui/button"
@@ -486,6 +486,100 @@ function ProviderBreakdown() {
   )
 }
 
+// ─── Endpoint Cost Breakdown ─────────────────────────────────────────────────
+
+const ENDPOINT_LABELS: Record<string, string> = {
+  chat: "Chat",
+  completions: "Completions",
+  embeddings: "Embeddings",
+  images: "Images",
+  transcription: "Transcription",
+  speech: "Speech",
+  realtime: "Realtime",
+  context_cache: "Context caches",
+}
+
+// Each endpoint is shown in the units it is billed by
+function endpointUnits(endpoint: EndpointCost): string {
+  switch (endpoint.endpoint_type) {
+    case "images":
+      return `${endpoint.images_generated.toLocaleString()} images`
+    case "speech":
+      return `${endpoint.characters.toLocaleString()} chars`
+    case "transcription":
+    case "realtime":
+      if (endpoint.audio_seconds > 0) {
+        return `${Math.round(endpoint.audio_seconds / 60).toLocaleString()} min audio`
+      }
+      break
+  }
+  return `${(endpoint.input_tokens + endpoint.output_tokens).toLocaleString()} tokens`
+}
+
+function EndpointCostBreakdown() {
+  const { data: rawData, isLoading } = useQuery({
+    queryKey: ["cost-breakdown", { hours: 24 * 7 }],
+    queryFn: () => getCostBreakdown(24 * 7),
+    refetchInterval: 60000,
+  })
+
+  const data = rawData as CostBreakdownResponse | undefined
+  const endpoints = data?.breakdown || []
+
+  return (
+    <Card className="pt-0">
+      <CardHeader className="flex items-center gap-2 space-y-0 border-b py-4 sm:flex-row">
+        <CardTitle className="flex-1 text-base">Cost by Endpoint</CardTitle>
+        <span className="text-xs text-muted-foreground">Last 7 days</span>
+      </CardHeader>
+      <CardContent className="p-0">
+        {isLoading ? (
+          <div className="space-y-3 p-6">
+            {[1, 2, 3].map((i) => (
+              <div key={i} className="h-4 w-full bg-muted animate-pulse rounded" />
+            ))}
+          </div>
+        ) : endpoints.length === 0 ? (
+          <p className="text-sm text-muted-foreground py-8 text-center">No usage recorded</p>
+        ) : (
+          <Table>
+            <TableHeader>
+              <TableRow>
+                <TableHead>Endpoint</TableHead>
+                <TableHead className="text-right">Requests</TableHead>
+                <TableHead className="text-right">Usage</TableHead>
+                <TableHead className="text-right">Cost</TableHead>
+                <TableHead className="text-right">Share</TableHead>
+              </TableRow>
+            </TableHeader>
+            <TableBody>
+              {endpoints.map((endpoint) => (
+                <TableRow key={endpoint.endpoint_type}>
+                  <TableCell className="font-medium">
+                    {ENDPOINT_LABELS[endpoint.endpoint_type] || endpoint.endpoint_type}
+                  </TableCell>
+                  <TableCell className="text-right font-mono text-xs">
+                    {endpoint.requests.toLocaleString()}
+                  </TableCell>
+                  <TableCell className="text-right font-mono text-xs text-muted-foreground">
+                    {endpointUnits(endpoint)}
+                  </TableCell>
+                  <TableCell className="text-right font-mono text-xs">
+                    ${endpoint.cost.toFixed(2)}
+                  </TableCell>
+                  <TableCell className="text-right font-mono text-xs text-muted-foreground">
+                    {data && data.total_cost > 0 ? `${((endpoint.cost / data.total_cost) * 100).toFixed(1)}%` : "—"}
+                  </TableCell>
+                </TableRow>
+              ))}
+            </TableBody>
+          </Table>
+        )}
+      </CardContent>
+    </Card>
+  )
+}
+
 // ─── System Health Strip ──────────────────────────────────────────────────────
 
 function SystemHealthStrip() {
@@ -728,6 +822,9 @@ export default function Dashboard() {
         <ProviderBreakdown />
       </div>
 
+      {/* Cost per Endpoint */}
+      <EndpointCostBreakdown />
+
       {/* Models Activity Table */}
       <ModelsActivityTable />
     </div>
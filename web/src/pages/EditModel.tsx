@@ -178,6 +178,12 @@ export default function EditModel() {
   const [outputCost, setOutputCost] = useState("");
   const [timeoutSeconds, setTimeoutSeconds] = useState("");
   const [tags, setTags] = useState("");
+
+  // Documentation
+  const [description, setDescription] = useState("");
+  const [intendedUse, setIntendedUse] = useState("");
+  const [owner, setOwner] = useState("");
+  const [contacts, setContacts] = useState("");
   const [defaultReasoningEffort, setDefaultReasoningEffort] = useState("");
 
   // Test connection state
@@ -224,6 +230,10 @@ export default function EditModel() {
       setSupportsStreaming(adminModel.model_info?.supports_streaming ?? true);
       setSupportsVision(adminModel.model_info?.supports_vision ?? false);
       setSupportsFunctions(adminModel.model_info?.supports_functions ?? true);
+      setDescription(adminModel.model_info?.description || "");
+      setIntendedUse(adminModel.model_info?.intended_use || "");
+      setOwner(adminModel.model_info?.owner || "");
+      setContacts(adminModel.model_info?.contacts?.join(", ") || "");
       setLoaded(true);
     }
   }, [adminModel, loaded]);
@@ -359,6 +369,11 @@ export default function EditModel() {
         supports_streaming: supportsStreaming,
         supports_vision: supportsVision,
         supports_functions: supportsFunctions,
+        description: description || undefined,
+        intended_use: intendedUse || undefined,
+        owner: owner || undefined,
+        contacts: contacts ? contacts.split(",").map((c) => c.trim()).filter(Boolean) : undefined,
+        links: adminModel?.model_info?.links,
       },
     };
 
@@ -996,6 +1011,48 @@ export default function EditModel() {
           </div>
         </div>
 
+        {/* Documentation */}
+        <div>
+          <h3 className="text-xs uppercase tracking-widest text-muted-foreground font-semibold mb-3">Documentation</h3>
+          <div className="space-y-4 max-w-xl">
+            <div className="space-y-2">
+              <Label className="text-sm">Description</Label>
+              <Input
+                placeholder="Fast general-purpose chat model"
+                value={description}
+                onChange={(e) => setDescription(e.target.value)}
+              />
+            </div>
+            <div className="space-y-2">
+              <Label className="text-sm">Intended Use</Label>
+              <Input
+                placeholder="Summaries, classification, internal tools"
+                value={intendedUse}
+                onChange={(e) => setIntendedUse(e.target.value)}
+              />
+            </div>
+            <div className="grid grid-cols-2 gap-4">
+              <div className="space-y-2">
+                <Label className="text-sm">Owner</Label>
+                <Input
+                  placeholder="ml-platform"
+                  value={owner}
+                  onChange={(e) => setOwner(e.target.value)}
+                />
+              </div>
+              <div className="space-y-2">
+                <Label className="text-sm">Contacts</Label>
+                <Input
+                  placeholder="ml-platform@example.com"
+                  value={contacts}
+                  onChange={(e) => setContacts(e.target.value)}
+                />
+              </div>
+            </div>
+            <p className="text-xs text-muted-foreground">Shown to callers in the model catalog. Contacts are comma-separated</p>
+          </div>
+        </div>
+
         {/* Tags + Reasoning Effort */}
         <div>
           <h3 className="text-xs uppercase tracking-widest text-muted-foreground font-semibold mb-3">Tags & Reasoning</h3>
@@ -1083,6 +1140,7 @@ export default function EditModel() {
     if (inputCost) summaryRows.push({ label: "Input Cost/Token", value: inputCost });
     if (outputCost) summaryRows.push({ label: "Output Cost/Token", value: outputCost });
     if (tags) summaryRows.push({ label: "Tags", value: tags });
+    if (owner) summaryRows.push({ label: "Owner", value: owner });
     if (defaultReasoningEffort) summaryRows.push({ label: "Reasoning Effort", value: defaultReasoningEffort });
 
     return (
@@ -398,6 +398,21 @@ export default function ModelDetail() {
                     </Badge>
                   )}
                 </div>
+                {adminModel?.model_info?.description && (
+                  <p className="text-sm text-muted-foreground mt-3 max-w-2xl">
+                    {adminModel.model_info.description}
+                  </p>
+                )}
+                {(adminModel?.model_info?.owner || adminModel?.model_info?.links?.length) && (
+                  <div className="flex flex-wrap items-center gap-3 mt-2 text-xs text-muted-foreground">
+                    {adminModel.model_info.owner && <span>Owner: {adminModel.model_info.owner}</span>}
+                    {adminModel.model_info.links?.map((link) => (
+                      <a key={link.url} href={link.url} target="_blank" rel="noreferrer" className="underline hover:text-foreground">
+                        {link.title || link.url}
+                      </a>
+                    ))}
+                  </div>
+                )}
               </div>
             </div>
 
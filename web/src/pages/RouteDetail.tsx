@@ -36,6 +36,7 @@ import { RouteFlowDiagram } from "@/components/routes/RouteFlowDiagram";
 const strategies = [
   { value: "priority", label: "Priority", icon: "solar:arrow-to-top-left-linear", desc: "Highest priority first" },
   { value: "least-latency", label: "Fastest", icon: "solar:bolt-linear", desc: "Lowest latency" },
+  { value: "least-ttft", label: "First Token", icon: "solar:stopwatch-linear", desc: "Lowest time to first token" },
   { value: "weighted-round-robin", label: "Weighted", icon: "solar:chart-2-linear", desc: "By weight" },
   { value: "random", label: "Random", icon: "solar:shuffle-linear", desc: "Random pick" },
 ];
@@ -30,6 +30,7 @@ import { useToast } from "@/hooks/use-toast";
 const strategyLabels: Record<string, string> = {
   priority: "Priority",
   "least-latency": "Least Latency",
+  "least-ttft": "Least TTFT",
   "weighted-round-robin": "Weighted RR",
   random: "Random",
 };
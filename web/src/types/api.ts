@@ -78,9 +78,15 @@ export interface ProviderConfig {
   location?: string;
   azure_deployment?: string;
   azure_endpoint?: string;
+  azure_base_model?: string;
   aws_access_key_id?: string;
   aws_secret_access_key?: string;
   aws_region_name?: string;
+  aws_session_token?: string;
+  aws_profile_name?: string;
+  aws_role_arn?: string;
+  aws_external_id?: string;
+  aws_role_session_name?: string;
   vertex_project?: string;
   vertex_location?: string;
   reasoning_effort?: string;
@@ -95,6 +101,16 @@ export interface ModelInfoConfig {
   max_input_tokens?: number;
   max_output_tokens?: number;
   default_max_tokens?: number;
+  description?: string;
+  intended_use?: string;
+  owner?: string;
+  contacts?: string[];
+  links?: ModelLink[];
+}
+
+export interface ModelLink {
+  title: string;
+  url: string;
 }
 
 export interface CreateModelRequest {
@@ -328,7 +344,7 @@ export interface Route {
   name: string;
   slug: string;
   description?: string;
-  strategy: 'priority' | 'least-latency' | 'weighted-round-robin' | 'random';
+  strategy: 'priority' | 'least-latency' | 'least-ttft' | 'weighted-round-robin' | 'random';
   models: RouteModel[];
   fallback_models?: string[];
   enabled: boolean;
@@ -365,4 +381,24 @@ export interface RouteStatsResponse {
   total_tokens: number;
   total_cost: number;
   models: RouteModelStats[];
-}
\ No newline at end of file
+}
+export interface EndpointCost {
+  endpoint_type: string;
+  model?: string;
+  requests: number;
+  input_tokens: number;
+  output_tokens: number;
+  images_generated: number;
+  audio_seconds: number;
+  characters: number;
+  input_cost: number;
+  output_cost: number;
+  cost: number;
+}
+
+export interface CostBreakdownResponse {
+  breakdown: EndpointCost[];
+  models: EndpointCost[];
+  total_cost: number;
+  period_hours: number;
+}